@@ -4,18 +4,23 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/applylock"
 	"github.com/lockplane/lockplane/internal/config"
 	"github.com/lockplane/lockplane/internal/executor"
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/lockplane/lockplane/internal/sqliteutil"
+	"github.com/lockplane/lockplane/internal/state"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/lockplane/lockplane/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -27,28 +32,126 @@ var applyCmd = &cobra.Command{
 
 Three modes of operation:
   1. Apply a pre-generated plan file: lockplane apply plan.json
+     (or --plan-file plan.json, --plan-file - for stdin, --plan-file https://... to fetch it)
   2. Generate and apply from schema: lockplane apply --schema schema/ --target-environment local
-  3. Auto-detect and apply: lockplane apply --target-environment local (auto-detects schema/)`,
+  3. Auto-detect and apply: lockplane apply --target-environment local (auto-detects schema/)
+
+Operations classified as dangerous or data-losing (DROP TABLE, DROP COLUMN,
+narrowing type changes, ...) are refused unless --allow-destructive is passed
+or allow_destructive = true is set in lockplane.toml. Use --dry-run to review
+them without applying anything.
+
+A plan generated from an empty schema is refused when the target database
+already has tables (other than lockplane's own and those listed in
+exclude_tables), since it would try to recreate them. Regenerate the plan
+against the target, or pass --force-from-empty if this is intended.
+
+Only one apply runs against a database at a time. PostgreSQL targets take an
+advisory lock keyed on the database name; SQLite targets lock a
+<database>.lockplane-lock file next to the database. A second apply fails
+with "another migration is in progress" unless --lock-timeout gives it time
+to wait.
+
+--statement-timeout caps how long each statement may run on PostgreSQL (by
+default the database or role setting applies). Steps the planner tags as long
+running (index builds, column type changes, tablespace moves, constraint
+validation) always run with statement_timeout disabled, so neither this flag
+nor a role-level timeout cancels them; the timeout is restored for later steps.
+
+Steps that cannot run in a transaction (CREATE/DROP INDEX CONCURRENTLY,
+VACUUM, ...) split the apply: the steps before them are committed first and
+the rest run in a new transaction. Progress is recorded in
+.lockplane-state.json, and an apply that stops partway blocks later applies to
+the same database until it is finished with --resume (same plan) or undone
+with --abort, which prints a plan back to the schema from before the apply.
+
+An environment can restrict when apply runs with apply_window (e.g.
+"02:00-04:00 UTC") and abort it with pre_apply_check, a SQL query that fails
+the apply when it returns any rows (e.g. replication lag over a limit). Both
+run before the shadow database is touched. --override-window applies outside
+the window; its reason is recorded in .lockplane-state.json.
+
+With --interactive, apply shows the plan and its safety details in a terminal
+UI and asks to apply the whole plan or to step through it, applying or
+skipping each step. Aborting applies nothing. Skipped steps are listed under
+skipped_steps in the result and show up again in the next plan. --interactive
+needs a terminal; non-interactive runs must pass --auto-approve instead.
+
+With --with-seeds, the .sql files in seeds/ (or --seeds-dir) are run against
+the target in lexical order, in one transaction, after the migration succeeds.
+See lockplane seed.`,
 	Example: `  # Apply a pre-generated plan
   lockplane apply migration.json --target-environment local
 
+  # Apply a plan produced earlier in the pipeline, without a temp file
+  lockplane plan --from-environment local --to schema/ | lockplane apply --plan-file - --target-environment local
+
+  # Apply a plan published as a build artifact
+  lockplane apply --plan-file https://ci.example.com/artifacts/migration.json --target-environment production
+
   # Generate and apply from schema
   lockplane apply --schema schema/ --target-environment local --auto-approve
 
   # Auto-detect schema and apply
-  lockplane apply --target-environment local`,
+  lockplane apply --target-environment local
+
+  # Preview a plan that drops a column, then apply it
+  lockplane apply migration.json --target-environment local --dry-run
+  lockplane apply migration.json --target-environment local --allow-destructive
+
+  # In CI, wait up to 10 minutes for a concurrent apply to finish
+  lockplane apply migration.json --target-environment production --auto-approve --lock-timeout 10m
+
+  # Approve or skip each step of an ad-hoc production change
+  lockplane apply --schema schema/ --target-environment production --interactive
+
+  # Apply the schema, then load seeds/*.sql
+  lockplane apply --target-environment local --with-seeds
+
+  # Apply outside the environment's apply_window
+  lockplane apply migration.json --target-environment production --override-window "hotfix for incident 123"
+
+  # Finish an apply that stopped after a CREATE INDEX CONCURRENTLY step
+  lockplane apply migration.json --target-environment production --resume
+
+  # Or undo it
+  lockplane apply --target-environment production --abort > cleanup.json
+  lockplane apply cleanup.json --target-environment production --allow-destructive`,
 	Run: runApply,
 }
 
 var (
-	applyTarget       string
-	applyTargetEnv    string
-	applySchema       string
-	applyAutoApprove  bool
-	applySkipShadow   bool
-	applyShadowDB     string
-	applyShadowSchema string
-	applyVerbose      bool
+	applyTarget               string
+	applyPlanFile             string
+	applyTargetEnv            string
+	applySchema               string
+	applyAutoApprove          bool
+	applyInteractive          bool
+	applySkipShadow           bool
+	applyShadowDB             string
+	applyShadowSchema         string
+	applyShadowPerRun         bool
+	applyForceDirtyShadow     bool
+	applyVerbose              bool
+	applyCascade              bool
+	applyIdempotent           bool
+	applyAllowDestructive     bool
+	applyDryRun               bool
+	applyForceFromEmpty       bool
+	applyWithSeeds            bool
+	applySeedsDir             string
+	applyConsistencyWait      time.Duration
+	applyLockTimeout          time.Duration
+	applyStatementTimeout     time.Duration
+	applyResume               bool
+	applyAbort                bool
+	applyAllowEmptyPlan       bool
+	applyAllowInsecurePlanURL bool
+	applyOverrideWindow       string
+	applyAllowImmutableChange string
+	applyBackfill             []string
+	applyBackfillBatch        int
+	applyBackfillPause        time.Duration
 )
 
 func init() {
@@ -57,72 +160,193 @@ func init() {
 	applyCmd.Flags().StringVar(&applyTarget, "target", "", "Target database URL")
 	applyCmd.Flags().StringVar(&applyTargetEnv, "target-environment", "", "Target environment name")
 	applyCmd.Flags().StringVar(&applySchema, "schema", "", "Schema file/directory")
+	applyCmd.Flags().StringVar(&applyPlanFile, "plan-file", "", "Plan to apply: a file path, - for stdin, or an https:// URL")
 	applyCmd.Flags().BoolVar(&applyAutoApprove, "auto-approve", false, "Skip interactive approval")
+	applyCmd.Flags().BoolVar(&applyInteractive, "interactive", false, "Approve the whole plan or each step (apply/skip/abort) in an interactive terminal UI")
 	applyCmd.Flags().BoolVar(&applySkipShadow, "skip-shadow", false, "Skip shadow DB validation (not recommended)")
 	applyCmd.Flags().StringVar(&applyShadowDB, "shadow-db", "", "Shadow database URL")
 	applyCmd.Flags().StringVar(&applyShadowSchema, "shadow-schema", "", "Shadow schema name (PostgreSQL only)")
+	applyCmd.Flags().BoolVar(&applyForceDirtyShadow, "force-dirty-shadow", false, "Validate even when objects the shadow database cleanup could not drop remain")
+	applyCmd.Flags().BoolVar(&applyShadowPerRun, "shadow-schema-per-run", false, "Test in a unique shadow schema that is dropped afterwards (PostgreSQL only)")
 	applyCmd.Flags().BoolVarP(&applyVerbose, "verbose", "v", false, "Verbose logging")
+	applyCmd.Flags().BoolVar(&applyCascade, "cascade", false, "Drop removed tables with CASCADE instead of dropping dependent foreign keys explicitly")
+	applyCmd.Flags().BoolVar(&applyIdempotent, "idempotent", false, "Guard foreign key and index additions so a partially applied migration can be re-run (PostgreSQL only)")
+	applyCmd.Flags().BoolVar(&applyAllowDestructive, "allow-destructive", false, "Allow dangerous or data-loss operations (e.g. DROP TABLE, DROP COLUMN)")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the plan and any destructive operations without applying changes")
+	applyCmd.Flags().BoolVar(&applyForceFromEmpty, "force-from-empty", false, "Apply a plan generated from an empty schema even though the target already has tables")
+	applyCmd.Flags().BoolVar(&applyWithSeeds, "with-seeds", false, "Run the .sql files in the seeds directory after the migration is applied")
+	applyCmd.Flags().StringVar(&applySeedsDir, "seeds-dir", "", "Directory of .sql seed files for --with-seeds (default: seeds/)")
+	applyCmd.Flags().DurationVar(&applyConsistencyWait, "consistency-timeout", executor.DefaultConsistencyTimeout, "How long to wait for libSQL/Turso replicas to show schema changes after apply")
+	applyCmd.Flags().DurationVar(&applyLockTimeout, "lock-timeout", 0, "How long to wait for another migration on the same database to finish (0 fails immediately)")
+	applyCmd.Flags().DurationVar(&applyStatementTimeout, "statement-timeout", 0, "Maximum run time of each statement, except long-running steps (PostgreSQL; 0 keeps the database setting)")
+	applyCmd.Flags().BoolVar(&applyResume, "resume", false, "Finish an interrupted apply of the same plan from the first uncommitted step")
+	applyCmd.Flags().BoolVar(&applyAllowEmptyPlan, "allow-empty-plan", false, "Accept a plan file without steps and apply nothing, instead of failing")
+	applyCmd.Flags().BoolVar(&applyAllowInsecurePlanURL, "allow-insecure-plan-url", false, "Allow --plan-file to fetch the plan over plain http:// (only https:// is allowed by default)")
+	applyCmd.Flags().BoolVar(&applyAbort, "abort", false, "Print a plan that undoes an interrupted apply, and forget it")
+	applyCmd.Flags().StringArrayVar(&applyBackfill, "backfill", nil, "Fill existing rows of an added column with a SQL expression, as table.column=expression, when planning from --schema (repeatable)")
+	applyCmd.Flags().IntVar(&applyBackfillBatch, "backfill-batch-size", 0, "Run --backfill updates as their own step in batches of this many rows, ordered by the table's primary key and committed one by one (0 runs a single UPDATE)")
+	applyCmd.Flags().DurationVar(&applyBackfillPause, "backfill-pause", 0, "With --backfill-batch-size, how long to pause between batches")
+	applyCmd.Flags().StringVar(&applyOverrideWindow, "override-window", "", "Apply outside the environment's apply_window; the reason is recorded in the state file")
+	applyCmd.Flags().StringVar(&applyAllowImmutableChange, "allow-immutable-change", "", "Allow altering or dropping tables declared immutable; the reason is recorded in the apply result")
 }
 
 func runApply(cmd *cobra.Command, args []string) {
-	ctx := context.Background()
+	ctx := cmd.Context()
 
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	// Resolve target environment first (needed for error messages)
 	resolvedTarget, err := config.ResolveEnvironment(cfg, applyTargetEnv)
 	if err != nil {
-		log.Fatalf("Failed to resolve target environment: %v", err)
+		fatalf(exitError, "Failed to resolve target environment: %v", err)
 	}
 
 	// Validate target flag value
 	if strings.TrimSpace(applyTarget) != "" && strings.HasPrefix(strings.TrimSpace(applyTarget), "--") {
-		fmt.Fprintf(os.Stderr, "Error: --target flag is missing its value. Provide a database URL or remove the flag to use --target-environment.\n\n")
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: --target flag is missing its value. Provide a database URL or remove the flag to use --target-environment.\n\n")
+		exit(1)
 	}
 
 	// Validate schema flag value
 	if strings.TrimSpace(applySchema) != "" && strings.HasPrefix(strings.TrimSpace(applySchema), "--") {
-		fmt.Fprintf(os.Stderr, "Error: --schema flag has invalid value %q\n\n", applySchema)
-		fmt.Fprintf(os.Stderr, "Check that the preceding flag has its argument.\n\n")
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: --schema flag has invalid value %q\n\n", applySchema)
+		fmt.Fprintf(style.Stderr, "Check that the preceding flag has its argument.\n\n")
+		exit(1)
+	}
+
+	// Resolve the seeds directory up front so a missing one fails before migrating
+	var seedsDir string
+	if applyWithSeeds {
+		seedsDir, err = resolveSeedsDir(applySeedsDir)
+		if err != nil {
+			fmt.Fprintf(style.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
 	}
 
+	if applyAbort {
+		if len(args) > 0 || applyPlanFile != "" || applyResume {
+			fmt.Fprintf(style.Stderr, "Error: --abort takes no plan and cannot be combined with --resume.\n\n")
+			exit(1)
+		}
+		runApplyAbort(ctx, resolvedTarget)
+		return
+	}
+	if applyResume && len(args) == 0 && applyPlanFile == "" {
+		fmt.Fprintf(style.Stderr, "Error: --resume needs the plan file of the interrupted apply.\n\n")
+		exit(1)
+	}
+
+	if applyInteractive {
+		checkInteractiveApplyFlags(applyAutoApprove, applyResume, applyDryRun)
+	}
+
+	backfill, err := planner.ParseBackfills(applyBackfill)
+	if err != nil {
+		fmt.Fprintf(style.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	checkBackfillBatchFlags(applyBackfillBatch, applyBackfillPause)
+
+	// Check the apply window before doing any work. A dry run changes
+	// nothing, so it may run at any time.
+	var windowWarning string
+	if !applyDryRun {
+		windowWarning = enforceApplyWindow(resolvedTarget, time.Now(), cmd.Flags().Changed("override-window"), applyOverrideWindow)
+	}
+
+	immutableReason := immutableOverrideReason(cmd.Flags().Changed("allow-immutable-change"), applyAllowImmutableChange)
+	var immutableWarning string
+
 	var plan *planner.Plan
+	var planDiff *schema.SchemaDiff
+	allowDestructive := applyAllowDestructive || resolvedTarget.AllowDestructive
+
+	if len(args) > 0 && applyPlanFile != "" {
+		fmt.Fprintf(style.Stderr, "Error: provide the plan either as an argument or with --plan-file, not both.\n\n")
+		exit(1)
+	}
 
 	// Mode 1: Apply pre-generated plan file
-	if len(args) > 0 {
-		planPath := args[0]
+	if len(args) > 0 || applyPlanFile != "" {
+		planPath := applyPlanFile
+		if len(args) > 0 {
+			planPath = args[0]
+		}
+		planLabel := planPath
+		if planPath == "-" {
+			planLabel = "stdin"
+		}
 
 		// Check if user accidentally passed a schema file instead of a plan file
 		if strings.HasSuffix(planPath, ".sql") || strings.HasSuffix(planPath, ".lp.sql") {
-			fmt.Fprintf(os.Stderr, "Error: '%s' appears to be a schema file, not a migration plan.\n\n", planPath)
-			fmt.Fprintf(os.Stderr, "Did you mean to use --schema?\n\n")
-			fmt.Fprintf(os.Stderr, "  lockplane apply --target-environment %s --schema %s\n\n", resolvedTarget.Name, planPath)
-			fmt.Fprintf(os.Stderr, "Or to generate and save a plan first:\n\n")
-			fmt.Fprintf(os.Stderr, "  lockplane plan --from-environment %s --to %s > plan.json\n", resolvedTarget.Name, planPath)
-			fmt.Fprintf(os.Stderr, "  lockplane apply plan.json --target-environment %s\n\n", resolvedTarget.Name)
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Error: '%s' appears to be a schema file, not a migration plan.\n\n", planPath)
+			fmt.Fprintf(style.Stderr, "Did you mean to use --schema?\n\n")
+			fmt.Fprintf(style.Stderr, "  lockplane apply --target-environment %s --schema %s\n\n", resolvedTarget.Name, planPath)
+			fmt.Fprintf(style.Stderr, "Or to generate and save a plan first:\n\n")
+			fmt.Fprintf(style.Stderr, "  lockplane plan --from-environment %s --to %s > plan.json\n", resolvedTarget.Name, planPath)
+			fmt.Fprintf(style.Stderr, "  lockplane apply plan.json --target-environment %s\n\n", resolvedTarget.Name)
+			exit(1)
 		}
 
 		// Warn if --schema was also provided
 		if applySchema != "" {
-			fmt.Fprintf(os.Stderr, "Warning: Ignoring --schema flag when applying a pre-generated plan file\n")
-			fmt.Fprintf(os.Stderr, "         The plan file (%s) already contains the migration steps\n\n", planPath)
+			fmt.Fprintf(style.Stderr, "Warning: Ignoring --schema flag when applying a pre-generated plan file\n")
+			fmt.Fprintf(style.Stderr, "         The plan file (%s) already contains the migration steps\n\n", planLabel)
 		}
 
 		if applyVerbose {
-			fmt.Fprintf(os.Stderr, "📄 Loading plan from: %s\n", planPath)
+			fmt.Fprintf(style.Stderr, "📄 Loading plan from: %s\n", planLabel)
+		}
+		plan, err = planner.LoadJSONPlanFromSourceWithOptions(ctx, planPath, os.Stdin, planner.PlanLoadOptions{
+			AllowEmpty:        applyAllowEmptyPlan,
+			AllowInsecureHTTP: applyAllowInsecurePlanURL,
+		})
+		if errors.Is(err, planner.ErrEmptyPlan) {
+			fmt.Fprintf(style.Stderr, "Error: %s has no steps. Check the file for a misspelled \"steps\" key,\n", planLabel)
+			fmt.Fprintf(style.Stderr, "or pass --allow-empty-plan if an empty plan is expected.\n")
+			exit(1)
+		}
+		if errors.Is(err, planner.ErrInsecurePlanURL) {
+			fmt.Fprintf(style.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(style.Stderr, "A plan fetched over plain HTTP could be altered in transit. Serve it over https://,\n")
+			fmt.Fprintf(style.Stderr, "or pass --allow-insecure-plan-url if the network is trusted.\n")
+			exit(1)
 		}
-		plan, err = planner.LoadJSONPlan(planPath)
 		if err != nil {
-			log.Fatalf("Failed to load migration plan: %v", err)
+			fatalf(exitError, "Failed to load migration plan: %v", err)
+		}
+		if len(plan.Steps) == 0 {
+			_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ No changes to apply: %s has no steps\n", planLabel)
+			if applyWithSeeds && !applyDryRun {
+				targetConnStr := strings.TrimSpace(applyTarget)
+				if targetConnStr == "" {
+					targetConnStr = resolvedTarget.DatabaseURL
+				}
+				seedDatabase(ctx, targetConnStr, seedsDir, applyVerbose)
+			}
+			exit(0)
+		}
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "📋 Loaded migration plan with %d steps from %s\n", len(plan.Steps), planLabel)
+		if applyDryRun || applyInteractive {
+			printApplyPlanSteps(plan)
+		}
+
+		immutableSchemaPath := config.GetSchemaPath(strings.TrimSpace(applySchema), cfg, resolvedTarget, "")
+		if immutableSchemaPath == "" {
+			immutableSchemaPath, _ = detectDefaultSchemaDir()
+		}
+		immutable := resolveImmutableTables(cfg, resolvedTarget, immutableSchemaPath)
+		immutableWarning = enforceImmutableGate(plan, immutable, immutableReason, applyDryRun)
+		enforceDestructiveGate(plan, nil, allowDestructive, applyDryRun)
+		if applyDryRun {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Dry run: no changes were applied\n")
+			exit(0)
 		}
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "📋 Loaded migration plan with %d steps from %s\n", len(plan.Steps), planPath)
 	} else {
 		// Mode 2 or 3: Generate plan from schema
 		// Determine schema path
@@ -137,14 +361,14 @@ func runApply(cmd *cobra.Command, args []string) {
 			// Mode 3: Auto-detect schema directory
 			if detectedPath, label := detectDefaultSchemaDir(); detectedPath != "" {
 				schemaPath = detectedPath
-				fmt.Fprintf(os.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
+				fmt.Fprintf(style.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
 			}
 		}
 
 		if schemaPath == "" {
-			fmt.Fprintf(os.Stderr, "Error: --schema required when generating a plan.\n\n")
-			fmt.Fprintf(os.Stderr, "Set schema_path in lockplane.toml or provide the flag explicitly.\n\n")
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Error: --schema required when generating a plan.\n\n")
+			fmt.Fprintf(style.Stderr, "Set schema_path in lockplane.toml or provide the flag explicitly.\n\n")
+			exit(1)
 		}
 
 		// Resolve target database connection
@@ -153,23 +377,23 @@ func runApply(cmd *cobra.Command, args []string) {
 			targetConnStr = resolvedTarget.DatabaseURL
 		}
 		if targetConnStr == "" {
-			fmt.Fprintf(os.Stderr, "Error: no target database configured.\n\n")
-			fmt.Fprintf(os.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Error: no target database configured.\n\n")
+			fmt.Fprintf(style.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
+			exit(1)
 		}
 
 		// Load current schema from database
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Introspecting target database (%s)...\n", resolvedTarget.Name)
-		before, err := executor.LoadSchemaFromConnectionString(targetConnStr)
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Introspecting target database (%s)...\n", resolvedTarget.Name)
+		before, err := executor.LoadSchemaFromConnectionStringContext(ctx, targetConnStr, nil)
 		if err != nil {
-			log.Fatalf("Failed to introspect target database: %v", err)
+			fatalf(exitConnectionError, "Failed to introspect target database: %v", err)
 		}
 
 		// Load desired schema
 		driverType := executor.DetectDriver(targetConnStr)
 		driver, err := executor.NewDriver(driverType)
 		if err != nil {
-			log.Fatalf("Failed to create database driver: %v", err)
+			fatalf(exitError, "Failed to create database driver: %v", err)
 		}
 
 		// Use config dialect if available, otherwise detect from driver type
@@ -179,130 +403,161 @@ func runApply(cmd *cobra.Command, args []string) {
 		} else {
 			dialect = schema.DriverNameToDialect(driverType)
 		}
-		opts := executor.BuildSchemaLoadOptions(schemaPath, dialect)
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "📖 Loading desired schema from %s...\n", schemaPath)
+		opts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(schemaPath, dialect), schemaPath, cfg, resolvedTarget)
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "📖 Loading desired schema from %s...\n", schemaPath)
+		warnDialectIncompatibilities(schemaPath, opts, resolvedTarget)
 		after, err := executor.LoadSchemaOrIntrospectWithOptions(schemaPath, opts)
 		if err != nil {
-			log.Fatalf("Failed to load schema: %v", err)
+			fatalf(exitError, "Failed to load schema: %v", err)
 		}
 
-		// Generate diff
-		diff := schema.DiffSchemas(before, after)
+		// Generate diff, mapping types when the schema targets another dialect
+		after = schema.AlignDialectsWithEquivalents(before, after, resolveTypeMap(cfg), resolveTypeEquivalents(cfg))
+		printPartialSchemaWarning(before, after)
+		softDrop := softDropColumns(cfg, resolvedTarget)
+		diffOpts := resolveDiffOptions(cfg)
+		diffOpts.IgnoreTombstones = softDrop
+		diff := schema.DiffSchemasWithOptions(before, after, diffOpts)
 
-		validationResults := validation.ValidateSchemaDiffWithSchema(diff, after)
+		immutable := resolveImmutableTables(cfg, resolvedTarget, schemaPath)
+		validationResults := validation.ValidateSchemaDiffWithOptions(diff, before, after, validation.ValidationOptions{
+			Cascade:              applyCascade,
+			Backfill:             backfill,
+			SoftDropColumns:      softDrop,
+			ImmutableTables:      immutable,
+			AllowImmutableChange: immutableReason,
+			ServerVersion:        targetServerVersion(before, configuredPostgresVersion(cfg, resolvedTarget)),
+		})
 		if len(validationResults) > 0 {
 			printValidationReport(validationResults, "=== Migration Safety Report ===")
 			if !validation.AllValid(validationResults) {
-				fmt.Fprintf(os.Stderr, "❌ Validation FAILED: Some operations are not safe\n\n")
-				os.Exit(1)
+				fmt.Fprintf(style.Stderr, "❌ Validation FAILED: Some operations are not safe\n\n")
+				exit(validationExitCode(validationResults))
 			}
 			if validation.HasDangerousOperations(validationResults) {
-				fmt.Fprintf(os.Stderr, "⚠️  WARNING: This migration contains dangerous operations.\n")
-				fmt.Fprintf(os.Stderr, "   Review safer alternatives above before proceeding.\n\n")
+				fmt.Fprintf(style.Stderr, "⚠️  WARNING: This migration contains dangerous operations.\n")
+				fmt.Fprintf(style.Stderr, "   Review safer alternatives above before proceeding.\n\n")
 			}
 			if validation.AllReversible(validationResults) {
-				fmt.Fprintf(os.Stderr, "✓ All operations are reversible\n\n")
+				fmt.Fprintf(style.Stderr, "✓ All operations are reversible\n\n")
 			} else {
-				fmt.Fprintf(os.Stderr, "⚠️  Warning: Some operations are NOT reversible\n")
-				fmt.Fprintf(os.Stderr, "   Data loss may be permanent. Test on shadow DB first.\n\n")
+				fmt.Fprintf(style.Stderr, "⚠️  Warning: Some operations are NOT reversible\n")
+				fmt.Fprintf(style.Stderr, "   Data loss may be permanent. Test on shadow DB first.\n\n")
 			}
 		}
 
 		// Check if there are any changes
 		if diff.IsEmpty() {
-			_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "\n✓ No changes detected - database already matches desired schema\n")
-			os.Exit(0)
+			_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "\n✓ No changes detected - database already matches desired schema\n")
+			if applyWithSeeds && !applyDryRun {
+				seedDatabase(ctx, targetConnStr, seedsDir, applyVerbose)
+			}
+			exit(0)
 		}
 
 		// Generate plan with source hash
-		generatedPlan, err := planner.GeneratePlanWithHash(diff, before, driver)
+		generatedPlan, err := planner.GeneratePlanWithOptions(diff, before, driver, planner.PlanOptions{Cascade: applyCascade, Idempotent: applyIdempotent, Backfill: backfill, BackfillBatchSize: applyBackfillBatch, BackfillPause: applyBackfillPause, SoftDropColumns: softDrop})
 		if err != nil {
-			log.Fatalf("Failed to generate plan: %v", err)
+			fatalf(exitError, "Failed to generate plan: %v", err)
+		}
+		printColumnOrderWarnings(diff, driver)
+		if len(generatedPlan.Steps) == 0 {
+			_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "\n✓ No changes to apply\n")
+			if applyWithSeeds && !applyDryRun {
+				seedDatabase(ctx, targetConnStr, seedsDir, applyVerbose)
+			}
+			exit(0)
 		}
 
+		// The target hash tells when libSQL replicas show the applied schema
+		targetHash, err := schema.ComputeSchemaHash(planner.WithTombstones(after, before, generatedPlan.Tombstones, driver))
+		if err != nil {
+			fatalf(exitError, "Failed to compute target schema hash: %v", err)
+		}
+		generatedPlan.TargetHash = targetHash
+
 		plan = generatedPlan
+		planDiff = diff
+		plan.Summary = validation.SummarizeImpact(plan, diff)
 
-		// Print plan details with colors
-		cyan := color.New(color.FgCyan, color.Bold)
-		green := color.New(color.FgGreen)
-		yellow := color.New(color.FgYellow)
-		gray := color.New(color.FgHiBlack)
-
-		_, _ = cyan.Fprintf(os.Stderr, "\n📋 Migration plan (%d steps):\n\n", len(plan.Steps))
-
-		for i, step := range plan.Steps {
-			_, _ = green.Fprintf(os.Stderr, "  %d. ", i+1)
-			fmt.Fprintf(os.Stderr, "%s\n", step.Description)
-			if len(step.SQL) > 0 {
-				if len(step.SQL) == 1 {
-					sql := step.SQL[0]
-					if len(sql) > 100 {
-						sql = sql[:100] + "..."
-					}
-					_, _ = gray.Fprintf(os.Stderr, "     SQL: ")
-					_, _ = yellow.Fprintf(os.Stderr, "%s\n", sql)
-				} else {
-					_, _ = gray.Fprintf(os.Stderr, "     SQL: ")
-					_, _ = yellow.Fprintf(os.Stderr, "%d statements\n", len(step.SQL))
-				}
-			}
+		printApplyPlanSteps(plan)
+
+		immutableWarning = enforceImmutableGate(plan, immutable, immutableReason, applyDryRun)
+		enforceDestructiveGate(plan, diff, allowDestructive, applyDryRun)
+		if applyDryRun {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Dry run: no changes were applied\n")
+			exit(0)
 		}
-		fmt.Fprintf(os.Stderr, "\n")
 
-		// Ask for confirmation unless --auto-approve
-		if !applyAutoApprove {
+		// Ask for confirmation unless --auto-approve; --interactive asks below
+		if !applyAutoApprove && !applyInteractive {
 			bold := color.New(color.Bold)
 			red := color.New(color.FgRed)
-			_, _ = bold.Fprintf(os.Stderr, "Do you want to perform these actions?\n")
-			fmt.Fprintf(os.Stderr, "  Lockplane will perform the actions described above.\n")
-			_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "  Only 'yes' will be accepted to approve.\n\n")
-			fmt.Fprintf(os.Stderr, "  Enter a value: ")
+			_, _ = bold.Fprintf(style.Stderr, "Do you want to perform these actions?\n")
+			fmt.Fprintf(style.Stderr, "  Lockplane will perform the actions described above.\n")
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "  Only 'yes' will be accepted to approve.\n\n")
+			fmt.Fprintf(style.Stderr, "  Enter a value: ")
 
 			var response string
 			_, err := fmt.Scanln(&response)
 			if err != nil {
-				_, _ = red.Fprintf(os.Stderr, "\nApply cancelled.\n")
-				os.Exit(0)
+				_, _ = red.Fprintf(style.Stderr, "\nApply cancelled.\n")
+				exit(0)
 			}
 			if response != "yes" {
-				_, _ = red.Fprintf(os.Stderr, "\nApply cancelled.\n")
-				os.Exit(0)
+				_, _ = red.Fprintf(style.Stderr, "\nApply cancelled.\n")
+				exit(0)
 			}
-			fmt.Fprintf(os.Stderr, "\n")
+			fmt.Fprintf(style.Stderr, "\n")
 		}
 	}
 
+	var skippedSteps []planner.SkippedStep
+	if applyInteractive {
+		plan, skippedSteps = confirmApplyInteractively(plan, planDiff, resolvedTarget.Name)
+	}
+
 	// Resolve target database connection
 	targetConnStr := strings.TrimSpace(applyTarget)
 	if targetConnStr == "" {
 		targetConnStr = resolvedTarget.DatabaseURL
 	}
 	if targetConnStr == "" {
-		fmt.Fprintf(os.Stderr, "Error: no target database configured.\n\n")
-		fmt.Fprintf(os.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: no target database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
+		exit(1)
 	}
 
 	// Detect database driver
 	driverType := executor.DetectDriver(targetConnStr)
 	driver, err := executor.NewDriver(driverType)
 	if err != nil {
-		log.Fatalf("Failed to create driver: %v", err)
+		fatalf(exitError, "Failed to create driver: %v", err)
 	}
 
 	// Open target database connection
 	sqlDriverName := executor.GetSQLDriverName(driverType)
 	targetDB, err := sql.Open(sqlDriverName, targetConnStr)
 	if err != nil {
-		log.Fatalf("Failed to connect to target database: %v", err)
+		fatalf(exitConnectionError, "Failed to connect to target database: %v", err)
 	}
 	defer func() { _ = targetDB.Close() }()
 
 	// Ping to verify connection
 	if err := targetDB.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping target database: %v", err)
+		fatalf(exitConnectionError, "Failed to ping target database: %v", err)
 	}
 
+	// The read-only and pre-apply checks run before the lock and the shadow
+	// database, so a failed check leaves nothing behind
+	enforceWritableTarget(ctx, targetDB, driverType, resolvedTarget)
+	enforcePreApplyCheck(ctx, targetDB, resolvedTarget)
+
+	// Serialize applies against the same database. The lock is held until the
+	// command exits; the database or OS releases it if the process dies.
+	applyLock := acquireApplyLock(ctx, targetDB, driverType, targetConnStr, applyLockTimeout)
+	defer func() { _ = applyLock.Release() }()
+
 	// Connect to shadow database if not skipped
 	var shadowDB *sql.DB
 	var shadowSchema string
@@ -325,14 +580,14 @@ func runApply(cmd *cobra.Command, args []string) {
 		// For SQLite/libSQL, default to :memory: if no shadow DB configured
 		if shadowConnStr == "" && (driverType == "sqlite" || driverType == "sqlite3" || driverType == "libsql") {
 			shadowConnStr = ":memory:"
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "ℹ️  Using in-memory shadow database (fast, zero config)\n")
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "ℹ️  Using in-memory shadow database (fast, zero config)\n")
 		} else if shadowConnStr == "" {
-			fmt.Fprintf(os.Stderr, "Error: no shadow database configured for environment %q.\n", resolvedShadow.Name)
-			fmt.Fprintf(os.Stderr, "Options:\n")
-			fmt.Fprintf(os.Stderr, "  - Add SHADOW_DATABASE_URL to .env.%s\n", resolvedShadow.Name)
-			fmt.Fprintf(os.Stderr, "  - Add/override SHADOW_SCHEMA (or --shadow-schema) to reuse the primary database\n")
-			fmt.Fprintf(os.Stderr, "  - Provide --shadow-db flag\n")
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Error: no shadow database configured for environment %q.\n", resolvedShadow.Name)
+			fmt.Fprintf(style.Stderr, "Options:\n")
+			fmt.Fprintf(style.Stderr, "  - Add SHADOW_DATABASE_URL to .env.%s\n", resolvedShadow.Name)
+			fmt.Fprintf(style.Stderr, "  - Add/override SHADOW_SCHEMA (or --shadow-schema) to reuse the primary database\n")
+			fmt.Fprintf(style.Stderr, "  - Provide --shadow-db flag\n")
+			exit(1)
 		}
 
 		// Detect shadow database driver type
@@ -341,114 +596,444 @@ func runApply(cmd *cobra.Command, args []string) {
 		// For SQLite shadow DB (not :memory:), check if the database file exists and create it if needed
 		if (shadowDriverType == "sqlite" || shadowDriverType == "sqlite3") && shadowConnStr != ":memory:" {
 			if err := sqliteutil.EnsureSQLiteDatabase(shadowConnStr, "shadow", false); err != nil {
-				log.Fatalf("Failed to ensure shadow database: %v", err)
+				fatalf(exitConnectionError, "Failed to ensure shadow database: %v", err)
 			}
 		}
 
 		shadowDriverName := executor.GetSQLDriverName(shadowDriverType)
 		shadowDB, err = sql.Open(shadowDriverName, shadowConnStr)
 		if err != nil {
-			log.Fatalf("Failed to connect to shadow database: %v", err)
+			fatalf(exitConnectionError, "Failed to connect to shadow database: %v", err)
 		}
 		defer func() { _ = shadowDB.Close() }()
 
 		if err := shadowDB.PingContext(ctx); err != nil {
-			log.Fatalf("Failed to ping shadow database: %v", err)
+			fatalf(exitConnectionError, "Failed to ping shadow database: %v", err)
+		}
+
+		if err := applyShadowSettings(ctx, shadowDB, driver.Name(), resolvedShadow, applyVerbose); err != nil {
+			fatalf(exitError, "Failed to configure shadow database: %v", err)
+		}
+
+		// Give this run its own schema so concurrent runs sharing the database
+		// don't drop each other's objects
+		if settings := runSchemaSettings(resolvedShadow, applyShadowPerRun); settings.Enabled && driver.SupportsSchemas() {
+			name, err := setupRunSchema(ctx, shadowDB, driver, shadowSchema, settings)
+			if err != nil {
+				fatalf(exitError, "Failed to create shadow schema: %v", err)
+			}
+			defer releaseRunSchema()
+			shadowSchema = name
 		}
 
 		// If shadow schema is configured and driver supports it, set up the schema
 		if shadowSchema != "" && driver.SupportsSchemas() {
 			// Create shadow schema if it doesn't exist
 			if err := driver.CreateSchema(ctx, shadowDB, shadowSchema); err != nil {
-				log.Fatalf("Failed to create shadow schema: %v", err)
+				fatalf(exitError, "Failed to create shadow schema: %v", err)
 			}
 
 			// Set search path to shadow schema
 			if err := driver.SetSchema(ctx, shadowDB, shadowSchema); err != nil {
-				log.Fatalf("Failed to set shadow schema: %v", err)
+				fatalf(exitError, "Failed to set shadow schema: %v", err)
 			}
 
 			// Show clear message about what we're doing
 			if shadowConnStr == targetConnStr {
-				_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing migration on shadow schema %q (same database)...\n", shadowSchema)
+				_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing migration on shadow schema %q (same database)...\n", shadowSchema)
 			} else {
-				_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing migration on shadow schema %q in separate database...\n", shadowSchema)
+				_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing migration on shadow schema %q in separate database...\n", shadowSchema)
 			}
 		} else if shadowConnStr == ":memory:" {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing migration on in-memory shadow database...\n")
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing migration on in-memory shadow database...\n")
 		} else {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing migration on shadow database...\n")
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing migration on shadow database...\n")
 		}
 	} else {
-		_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "⚠️  Skipping shadow DB validation (--skip-shadow)\n")
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Skipping shadow DB validation (--skip-shadow)\n")
 	}
 
 	// Introspect current database state (needed for shadow DB validation and source hash check)
 	currentSchema, err := driver.IntrospectSchema(ctx, targetDB)
 	if err != nil {
-		log.Fatalf("Failed to introspect current database schema: %v", err)
+		fatalf(exitConnectionError, "Failed to introspect current database schema: %v", err)
+	}
+
+	// An apply that stopped partway must be resumed or aborted first
+	st, err := state.Load()
+	if err != nil {
+		fatalf(exitError, "Failed to load state: %v", err)
+	}
+	stateKey := applyTargetKey(targetConnStr)
+	checkpoint := st.ApplyCheckpoint(stateKey)
+	planHash, err := planFingerprint(plan)
+	if err != nil {
+		fatalf(exitError, "Failed to fingerprint plan: %v", err)
+	}
+	currentSchemaHash, err := schema.ComputeSchemaHash(currentSchema)
+	if err != nil {
+		fatalf(exitError, "Failed to compute current schema hash: %v", err)
+	}
+	startStep, err := resumeStep(checkpoint, planHash, currentSchemaHash, applyResume)
+	if errors.Is(err, errApplyInterrupted) {
+		printInterruptedApply(checkpoint, resolvedTarget.Name)
+		exit(1)
+	}
+	if err != nil {
+		_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Cannot resume: %v\n\n", err)
+		if checkpoint != nil {
+			fmt.Fprintf(style.Stderr, "Undo the interrupted apply instead: lockplane apply --target-environment %s --abort > cleanup.json\n", resolvedTarget.Name)
+		}
+		exit(1)
+	}
+	if applyResume {
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "↩️  Resuming interrupted apply at step %d of %d\n", startStep+1, len(plan.Steps))
+	}
+
+	// A plan generated from an empty schema only makes sense on an empty target
+	fromEmpty := planFromEmptySchema(plan)
+	if fromEmpty && startStep == 0 {
+		enforceEmptyTargetGuard(currentSchema, resolvedTarget, applyForceFromEmpty)
 	}
 
-	// Validate source hash if present in plan
-	if plan.SourceHash != "" {
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔐 Validating source schema hash...\n")
+	// Validate source hash if present in plan. Plans from an empty schema were
+	// checked by the guard above, which ignores bookkeeping and excluded tables.
+	// A resumed apply was checked by the first run.
+	if plan.SourceHash != "" && !fromEmpty && startStep == 0 {
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔐 Validating source schema hash...\n")
 
 		// Compute hash of current state
 		currentHash, err := schema.ComputeSchemaHash((*database.Schema)(currentSchema))
 		if err != nil {
-			log.Fatalf("Failed to compute current schema hash: %v", err)
+			fatalf(exitError, "Failed to compute current schema hash: %v", err)
 		}
 
-		// Compare hashes
-		if currentHash != plan.SourceHash {
+		// Compare hashes, accepting legacy (version 1) hashes from older plans
+		matches, err := schema.SchemaHashMatches((*database.Schema)(currentSchema), plan.SourceHash)
+		if err != nil {
+			fatalf(exitError, "Failed to compute current schema hash: %v", err)
+		}
+		if !matches {
 			red := color.New(color.FgRed, color.Bold)
 			yellow := color.New(color.FgYellow)
 
-			_, _ = red.Fprintf(os.Stderr, "\n❌ Source schema mismatch!\n\n")
-			fmt.Fprintf(os.Stderr, "The migration plan was generated for a different database state.\n")
-			fmt.Fprintf(os.Stderr, "This usually happens when:\n")
-			fmt.Fprintf(os.Stderr, "  - The plan is being applied to the wrong database\n")
-			fmt.Fprintf(os.Stderr, "  - The database has been modified since the plan was generated\n")
-			fmt.Fprintf(os.Stderr, "  - The plan is being applied out of order\n\n")
-			_, _ = yellow.Fprintf(os.Stderr, "Expected source hash: %s\n", plan.SourceHash)
-			_, _ = yellow.Fprintf(os.Stderr, "Current database hash: %s\n\n", currentHash)
-			_, _ = color.New(color.FgCyan, color.Bold).Fprintf(os.Stderr, "To fix this:\n")
-			fmt.Fprintf(os.Stderr, "  1. Introspect the current database: lockplane introspect > current.json\n")
-			fmt.Fprintf(os.Stderr, "  2. Generate a new plan: lockplane plan --from current.json --to desired.lp.sql\n")
-			fmt.Fprintf(os.Stderr, "  3. Apply the new plan: lockplane apply --plan migration.json\n\n")
-			os.Exit(1)
+			_, _ = red.Fprintf(style.Stderr, "\n❌ Source schema mismatch!\n\n")
+			fmt.Fprintf(style.Stderr, "The migration plan was generated for a different database state.\n")
+			fmt.Fprintf(style.Stderr, "This usually happens when:\n")
+			fmt.Fprintf(style.Stderr, "  - The plan is being applied to the wrong database\n")
+			fmt.Fprintf(style.Stderr, "  - The database has been modified since the plan was generated\n")
+			fmt.Fprintf(style.Stderr, "  - The plan is being applied out of order\n\n")
+			_, _ = yellow.Fprintf(style.Stderr, "Expected source hash: %s\n", plan.SourceHash)
+			_, _ = yellow.Fprintf(style.Stderr, "Current database hash: %s\n\n", currentHash)
+			_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "To fix this:\n")
+			fmt.Fprintf(style.Stderr, "  1. Introspect the current database: lockplane introspect > current.json\n")
+			fmt.Fprintf(style.Stderr, "  2. Generate a new plan: lockplane plan --from current.json --to desired.lp.sql\n")
+			fmt.Fprintf(style.Stderr, "  3. Apply the new plan: lockplane apply --plan migration.json\n\n")
+			exit(exitValidationFailed)
 		}
 
-		_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "✓ Source schema hash matches (hash: %s...)\n", currentHash[:12])
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ Source schema hash matches (hash: %s...)\n", currentHash[:12])
+	}
+
+	// Batched updates need an ordering key in the database they run on. A
+	// resumed apply was checked by the first run.
+	if startStep == 0 {
+		enforceBatchOrderingKeys(plan, currentSchema)
 	}
 
 	// Apply the plan
 	if applyVerbose {
-		_, _ = color.New(color.FgCyan, color.Bold).Fprintf(os.Stderr, "\n🚀 Applying migration...\n\n")
+		_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "\n🚀 Applying migration...\n\n")
 	}
 
-	result, err := executor.ApplyPlan(ctx, targetDB, plan, shadowDB, (*database.Schema)(currentSchema), driver, applyVerbose)
+	// Turso replicas can lag behind acknowledged DDL, so wait until reads
+	// return the schema the plan targets. Plans without DDL, or without a
+	// target hash to compare against, have nothing to wait for.
+	var fingerprinter executor.SchemaFingerprinter
+	if driverType == "libsql" && plan.TargetHash != "" && executor.PlanChangesSchema(plan) {
+		fingerprinter = &executor.SchemaHashFingerprinter{DB: targetDB, Driver: driver}
+	}
+
+	// Plans that commit in parts record their progress so an interrupted run
+	// can be resumed or aborted
+	opts := executor.ApplyOptions{StatementTimeout: applyStatementTimeout, StartStep: startStep, AllowDirtyShadow: applyForceDirtyShadow, OnBatch: printBatchProgress}
+	var checkpointer *applyCheckpointer
+	if hasNonTransactionalSteps(plan) {
+		if checkpoint == nil || startStep == 0 {
+			checkpoint = &state.ApplyCheckpoint{
+				PlanHash:     planHash,
+				Target:       resolvedTarget.Name,
+				TotalSteps:   len(plan.Steps),
+				SchemaHash:   currentSchemaHash,
+				SourceSchema: currentSchema,
+				StartedAt:    time.Now(),
+			}
+			if err := st.SaveApplyCheckpoint(stateKey, checkpoint); err != nil {
+				fatalf(exitError, "Failed to record apply progress: %v", err)
+			}
+		}
+		checkpointer = &applyCheckpointer{ctx: ctx, db: targetDB, driver: driver, state: st, key: stateKey, checkpoint: checkpoint}
+		opts.Checkpoint = checkpointer.record
+	}
+
+	applyCtx, stopOnInterrupt := cancelBatchesOnInterrupt(ctx, plan)
+	defer stopOnInterrupt()
+	result, err := executor.ApplyPlanWithOptions(applyCtx, targetDB, plan, shadowDB, (*database.Schema)(currentSchema), driver, applyVerbose, opts)
+	if err == nil {
+		if err := st.ClearApplyCheckpoint(stateKey); err != nil {
+			fatalf(exitError, "Failed to clear apply progress: %v", err)
+		}
+		if windowWarning != "" {
+			result.Warnings = append(result.Warnings, windowWarning)
+		}
+		if immutableWarning != "" {
+			result.Warnings = append(result.Warnings, immutableWarning)
+		}
+		if len(skippedSteps) > 0 {
+			result.SkippedSteps = skippedSteps
+			result.Warnings = append(result.Warnings, skippedStepsWarning(skippedSteps))
+		}
+	}
 	if err != nil {
 		red := color.New(color.FgRed, color.Bold)
-		_, _ = red.Fprintf(os.Stderr, "\n❌ Migration failed: %v\n\n", err)
+		_, _ = red.Fprintf(style.Stderr, "\n❌ Migration failed: %v\n\n", err)
 		if len(result.Errors) > 0 {
-			_, _ = red.Fprintf(os.Stderr, "Errors:\n")
+			_, _ = red.Fprintf(style.Stderr, "Errors:\n")
 			for _, e := range result.Errors {
-				fmt.Fprintf(os.Stderr, "  - %s\n", e)
+				fmt.Fprintf(style.Stderr, "  - %s\n", e)
+			}
+		}
+		if leftovers, dirty := dirtyShadowDetails(err); dirty {
+			_, _ = red.Fprintf(style.Stderr, "Objects left in the shadow database:\n")
+			for _, leftover := range leftovers {
+				fmt.Fprintf(style.Stderr, "  - %s\n", leftover)
 			}
+			fmt.Fprintf(style.Stderr, "\n%s.\n", dirtyShadowHint)
+		}
+		if checkpointer != nil && checkpointer.checkpoint.StepsApplied > 0 {
+			fmt.Fprintf(style.Stderr, "\n%d of %d steps were committed before the failure.\n", checkpointer.checkpoint.StepsApplied, len(plan.Steps))
+			printApplyRecoveryHint(resolvedTarget.Name)
+		}
+		if errors.Is(err, executor.ErrDryRunFailed) || errors.Is(err, executor.ErrSourceHashMismatch) {
+			exit(exitValidationFailed)
+		}
+		exit(1)
+	}
+
+	if fingerprinter != nil {
+		if applyVerbose {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "⏳ Waiting for schema changes to become visible on libSQL...\n")
+		}
+		consistency, err := executor.WaitForSchemaConsistency(ctx, fingerprinter, plan.TargetHash, executor.ConsistencyOptions{Timeout: applyConsistencyWait})
+		if err != nil {
+			fatalf(exitError, "Failed to verify applied schema: %v", err)
+		}
+		if !consistency.Consistent {
+			warning := fmt.Sprintf("replica not yet consistent: the migration was acknowledged, but schema reads still didn't return the target schema after %s", consistency.Elapsed.Round(time.Millisecond))
+			result.Warnings = append(result.Warnings, warning)
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "\n⚠️  Replica not yet consistent\n")
+			fmt.Fprintf(style.Stderr, "   The migration was acknowledged, but reads still don't return the target schema after %s.\n", consistency.Elapsed.Round(time.Millisecond))
+			fmt.Fprintf(style.Stderr, "   This is usually Turso replica lag. Re-run introspect shortly to confirm, or raise --consistency-timeout.\n")
+		} else if applyVerbose {
+			_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ Schema changes visible after %d read(s)\n", consistency.Attempts)
 		}
-		os.Exit(1)
 	}
 
 	// Success!
 	green := color.New(color.FgGreen, color.Bold)
-	_, _ = green.Fprintf(os.Stderr, "\n✅ Migration applied successfully!\n")
-	_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "   Steps applied: %d\n", result.StepsApplied)
+	_, _ = green.Fprintf(style.Stderr, "\n✅ Migration applied successfully!\n")
+	_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "   Steps applied: %d\n", result.StepsApplied)
+
+	if applyWithSeeds {
+		fmt.Fprintf(style.Stderr, "\n")
+		seedDatabase(ctx, targetConnStr, seedsDir, applyVerbose)
+	}
 
 	// Output result as JSON
 	jsonBytes, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal result to JSON: %v", err)
+		fatalf(exitError, "Failed to marshal result to JSON: %v", err)
 	}
 	fmt.Println(string(jsonBytes))
 }
+
+// acquireApplyLock takes the apply lock for the target database, exiting with
+// a clear message when another migration holds it
+func acquireApplyLock(ctx context.Context, db *sql.DB, driverType, connStr string, timeout time.Duration) *applylock.Lock {
+	if timeout > 0 {
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔒 Waiting up to %s for the apply lock...\n", timeout)
+	}
+	lock, err := applylock.Acquire(ctx, db, driverType, connStr, timeout)
+	if errors.Is(err, applylock.ErrInProgress) {
+		_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Another migration is in progress on this database\n\n")
+		fmt.Fprintf(style.Stderr, "%v\n\n", err)
+		fmt.Fprintf(style.Stderr, "Wait for it to finish and try again, or pass --lock-timeout to wait for it.\n")
+		exit(1)
+	}
+	if err != nil {
+		fatalf(exitError, "Failed to acquire apply lock: %v", err)
+	}
+	if applyVerbose {
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔒 Acquired apply lock: %s\n", lock.Description)
+	}
+	return lock
+}
+
+// printApplyPlanSteps prints the steps of a migration plan to stderr
+func printApplyPlanSteps(plan *planner.Plan) {
+	cyan := color.New(color.FgCyan, color.Bold)
+	green := color.New(color.FgGreen)
+	yellow := color.New(color.FgYellow)
+	gray := color.New(color.FgHiBlack)
+
+	printImpactSummary(plan.Summary)
+	_, _ = cyan.Fprintf(style.Stderr, "\n📋 Migration plan (%d steps):\n\n", len(plan.Steps))
+
+	for i, step := range plan.Steps {
+		_, _ = green.Fprintf(style.Stderr, "  %d. ", i+1)
+		fmt.Fprintf(style.Stderr, "%s\n", step.Description)
+		if step.Source != nil {
+			_, _ = gray.Fprintf(style.Stderr, "     Source: %s\n", step.Source)
+		}
+		if step.Reason != "" {
+			_, _ = gray.Fprintf(style.Stderr, "     Why: %s\n", step.Reason)
+		}
+		if step.LongRunning {
+			_, _ = gray.Fprintf(style.Stderr, "     Long-running: runs without statement_timeout\n")
+		}
+		if step.Batch != nil {
+			_, _ = gray.Fprintf(style.Stderr, "     Batched: %d rows per batch ordered by %s, each batch committed on its own\n", step.Batch.Size(), step.Batch.Key)
+		}
+		for _, warning := range step.Warnings {
+			_, _ = yellow.Fprintf(style.Stderr, "     ⚠️  %s\n", warning)
+		}
+		if len(step.SQL) > 0 {
+			if len(step.SQL) == 1 {
+				sql := step.SQL[0]
+				if len(sql) > 100 {
+					sql = sql[:100] + "..."
+				}
+				_, _ = gray.Fprintf(style.Stderr, "     SQL: ")
+				_, _ = yellow.Fprintf(style.Stderr, "%s\n", sql)
+			} else {
+				_, _ = gray.Fprintf(style.Stderr, "     SQL: ")
+				_, _ = yellow.Fprintf(style.Stderr, "%d statements\n", len(step.SQL))
+			}
+		}
+	}
+	fmt.Fprintf(style.Stderr, "\n")
+}
+
+// bookkeepingTablePrefix marks tables lockplane creates for its own use
+const bookkeepingTablePrefix = "_lockplane"
+
+// planFromEmptySchema reports whether the plan's source hash is that of an
+// empty schema, i.e. it was generated to build a database from scratch
+func planFromEmptySchema(plan *planner.Plan) bool {
+	if plan == nil || plan.SourceHash == "" {
+		return false
+	}
+	matches, err := schema.SchemaHashMatches(&database.Schema{}, plan.SourceHash)
+	return err == nil && matches
+}
+
+// userTableNames returns the tables in current that lockplane manages, skipping
+// its own bookkeeping tables and those matched by an exclude_tables pattern
+func userTableNames(current *database.Schema, exclude []string) []string {
+	if current == nil {
+		return nil
+	}
+
+	var names []string
+	for _, table := range current.Tables {
+		if strings.HasPrefix(table.Name, bookkeepingTablePrefix) || tableExcluded(table, exclude) {
+			continue
+		}
+		name := table.Name
+		if table.Schema != "" {
+			name = table.Schema + "." + table.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// tableExcluded reports whether a pattern matches the table's name, with or
+// without its schema qualifier
+func tableExcluded(table database.Table, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table.Name); ok {
+			return true
+		}
+		if table.Schema != "" {
+			if ok, _ := path.Match(pattern, table.Schema+"."+table.Name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enforceEmptyTargetGuard exits when a plan generated from an empty schema is
+// about to run against a database that already has user tables, unless forced
+func enforceEmptyTargetGuard(current *database.Schema, target *config.ResolvedEnvironment, force bool) {
+	tables := userTableNames(current, target.ExcludeTables)
+	if len(tables) == 0 {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ Plan was generated from an empty schema and the target has no tables\n")
+		return
+	}
+
+	if force {
+		_, _ = color.New(color.FgYellow, color.Bold).Fprintf(style.Stderr, "⚠️  Applying a plan generated from an empty schema to a database with %d table(s) (--force-from-empty)\n", len(tables))
+		return
+	}
+
+	_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Target database is not empty!\n\n")
+	fmt.Fprintf(style.Stderr, "The migration plan was generated from an empty schema, but the target already has %d table(s):\n", len(tables))
+	const maxListed = 10
+	for i, name := range tables {
+		if i == maxListed {
+			fmt.Fprintf(style.Stderr, "  ... and %d more\n", len(tables)-maxListed)
+			break
+		}
+		fmt.Fprintf(style.Stderr, "  - %s\n", name)
+	}
+	fmt.Fprintf(style.Stderr, "\nApplying it would try to create tables that already exist.\n\n")
+	_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "To fix this:\n")
+	fmt.Fprintf(style.Stderr, "  - Regenerate the plan against this database: lockplane plan --from-environment %s --to <schema> > migration.json\n", target.Name)
+	fmt.Fprintf(style.Stderr, "  - List tables lockplane should ignore under exclude_tables in lockplane.toml\n")
+	fmt.Fprintf(style.Stderr, "  - Or re-run with --force-from-empty if applying this plan is intended\n\n")
+	exit(1)
+}
+
+// enforceDestructiveGate lists the plan steps that are dangerous or lose data and
+// exits unless they are allowed. In dry-run mode the steps are only reported.
+func enforceDestructiveGate(plan *planner.Plan, diff *schema.SchemaDiff, allowed, dryRun bool) {
+	destructive := validation.FindDestructiveSteps(plan, diff)
+	if len(destructive) == 0 {
+		return
+	}
+
+	switch {
+	case dryRun:
+		_, _ = color.New(color.FgYellow, color.Bold).Fprintf(style.Stderr, "⚠️  %d destructive operation(s) would require --allow-destructive:\n\n", len(destructive))
+	case allowed:
+		_, _ = color.New(color.FgYellow, color.Bold).Fprintf(style.Stderr, "⚠️  Applying %d destructive operation(s) (allowed by --allow-destructive or allow_destructive):\n\n", len(destructive))
+	default:
+		_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "❌ Refusing to apply %d destructive operation(s):\n\n", len(destructive))
+	}
+
+	for _, d := range destructive {
+		fmt.Fprintf(style.Stderr, "  %d. %s %s\n", d.Index+1, d.Result.Safety.Level.Icon(), d.Step.Description)
+		for _, warning := range d.Result.Warnings {
+			fmt.Fprintf(style.Stderr, "     ⚠️  %s\n", warning)
+		}
+	}
+	fmt.Fprintf(style.Stderr, "\n")
+
+	if !allowed && !dryRun {
+		fmt.Fprintf(style.Stderr, "Re-run with --allow-destructive, or set allow_destructive = true in lockplane.toml, to apply them.\n")
+		fmt.Fprintf(style.Stderr, "Use --dry-run to review the full plan without applying it.\n\n")
+		exit(exitDestructiveBlocked)
+	}
+}
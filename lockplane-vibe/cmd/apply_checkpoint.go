@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/state"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// errApplyInterrupted means an earlier apply on the target database stopped
+// partway and must be resumed or aborted first
+var errApplyInterrupted = errors.New("an earlier apply on this database was interrupted")
+
+// applyTargetKey identifies the target database in the state file without
+// storing its connection string, which may contain credentials
+func applyTargetKey(connStr string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(connStr)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// planFingerprint identifies a plan by what it executes, so a resumed apply
+// can check it was given the plan of the interrupted run
+func planFingerprint(plan *planner.Plan) (string, error) {
+	type stepKey struct {
+		SQL              []string `json:"sql"`
+		NonTransactional bool     `json:"non_transactional"`
+	}
+	steps := make([]stepKey, 0, len(plan.Steps))
+	for _, step := range plan.Steps {
+		steps = append(steps, stepKey{SQL: step.SQL, NonTransactional: planner.RequiresNoTransaction(step)})
+	}
+	data, err := json.Marshal(struct {
+		SourceHash string    `json:"source_hash"`
+		Steps      []stepKey `json:"steps"`
+	}{plan.SourceHash, steps})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hasNonTransactionalSteps reports whether applying the plan commits in
+// several parts, which is when progress needs a checkpoint
+func hasNonTransactionalSteps(plan *planner.Plan) bool {
+	for _, step := range plan.Steps {
+		if planner.RequiresNoTransaction(step) {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeStep returns the step to start applying at, given the checkpoint of
+// an earlier apply on the target (or nil). A checkpoint that committed
+// nothing and left the database unchanged is stale and ignored.
+func resumeStep(checkpoint *state.ApplyCheckpoint, planHash, currentHash string, resume bool) (int, error) {
+	if checkpoint == nil {
+		if resume {
+			return 0, errors.New("no interrupted apply is recorded for this database")
+		}
+		return 0, nil
+	}
+
+	unchanged := checkpoint.SchemaHash == currentHash
+	if !resume {
+		if checkpoint.StepsApplied == 0 && unchanged {
+			return 0, nil
+		}
+		return 0, errApplyInterrupted
+	}
+
+	if checkpoint.PlanHash != planHash {
+		return 0, errors.New("the plan differs from the plan of the interrupted apply")
+	}
+	if !unchanged {
+		return 0, fmt.Errorf("the database changed after step %d was committed; the next step may have partially run (e.g. an INVALID index left by CREATE INDEX CONCURRENTLY)", checkpoint.StepsApplied)
+	}
+	return checkpoint.StepsApplied, nil
+}
+
+// applyCheckpointer records apply progress in the state file
+type applyCheckpointer struct {
+	ctx        context.Context
+	db         *sql.DB
+	driver     database.Driver
+	state      *state.State
+	key        string
+	checkpoint *state.ApplyCheckpoint
+}
+
+// record saves that stepsApplied steps are committed, along with the schema
+// hash of the database at that point
+func (c *applyCheckpointer) record(stepsApplied int) error {
+	current, err := c.driver.IntrospectSchema(c.ctx, c.db)
+	if err != nil {
+		return fmt.Errorf("failed to introspect database: %w", err)
+	}
+	hash, err := schema.ComputeSchemaHash(current)
+	if err != nil {
+		return fmt.Errorf("failed to compute schema hash: %w", err)
+	}
+	c.checkpoint.StepsApplied = stepsApplied
+	c.checkpoint.SchemaHash = hash
+	return c.state.SaveApplyCheckpoint(c.key, c.checkpoint)
+}
+
+// printInterruptedApply explains how to continue after an interrupted apply
+func printInterruptedApply(checkpoint *state.ApplyCheckpoint, target string) {
+	_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ An earlier apply on this database was interrupted\n\n")
+	fmt.Fprintf(style.Stderr, "It committed %d of %d steps (started %s).\n\n", checkpoint.StepsApplied, checkpoint.TotalSteps, checkpoint.StartedAt.Format(time.RFC3339))
+	printApplyRecoveryHint(target)
+}
+
+// printApplyRecoveryHint prints the --resume and --abort commands
+func printApplyRecoveryHint(target string) {
+	_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "To continue:\n")
+	fmt.Fprintf(style.Stderr, "  - Finish it: lockplane apply <plan.json> --target-environment %s --resume\n", target)
+	fmt.Fprintf(style.Stderr, "  - Undo it:   lockplane apply --target-environment %s --abort > cleanup.json\n", target)
+	fmt.Fprintf(style.Stderr, "               then review and apply cleanup.json\n")
+}
+
+// runApplyAbort prints a plan that returns the target database to the schema
+// it had before an interrupted apply, and forgets the interrupted apply
+func runApplyAbort(ctx context.Context, resolvedTarget *config.ResolvedEnvironment) {
+	targetConnStr := strings.TrimSpace(applyTarget)
+	if targetConnStr == "" {
+		targetConnStr = resolvedTarget.DatabaseURL
+	}
+	if targetConnStr == "" {
+		fmt.Fprintf(style.Stderr, "Error: no target database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
+		exit(1)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		fatalf(exitError, "Failed to load state: %v", err)
+	}
+	key := applyTargetKey(targetConnStr)
+	checkpoint := st.ApplyCheckpoint(key)
+	if checkpoint == nil {
+		fmt.Fprintf(style.Stderr, "Error: no interrupted apply is recorded for this database.\n")
+		exit(1)
+	}
+
+	driverType := executor.DetectDriver(targetConnStr)
+	driver, err := executor.NewDriver(driverType)
+	if err != nil {
+		fatalf(exitError, "Failed to create driver: %v", err)
+	}
+	targetDB, err := sql.Open(executor.GetSQLDriverName(driverType), targetConnStr)
+	if err != nil {
+		fatalf(exitConnectionError, "Failed to connect to target database: %v", err)
+	}
+	defer func() { _ = targetDB.Close() }()
+	if err := targetDB.PingContext(ctx); err != nil {
+		fatalf(exitConnectionError, "Failed to ping target database: %v", err)
+	}
+
+	// Don't plan against a database another apply is changing
+	applyLock := acquireApplyLock(ctx, targetDB, driverType, targetConnStr, applyLockTimeout)
+	defer func() { _ = applyLock.Release() }()
+
+	currentSchema, err := driver.IntrospectSchema(ctx, targetDB)
+	if err != nil {
+		fatalf(exitConnectionError, "Failed to introspect current database schema: %v", err)
+	}
+	if checkpoint.SourceSchema == nil {
+		fatalf(exitError, "Interrupted apply has no recorded source schema")
+	}
+
+	diff := schema.DiffSchemas(currentSchema, checkpoint.SourceSchema)
+	cleanup, err := planner.GeneratePlanWithHash(diff, currentSchema, driver)
+	if err != nil {
+		fatalf(exitError, "Failed to generate cleanup plan: %v", err)
+	}
+
+	if err := st.ClearApplyCheckpoint(key); err != nil {
+		fatalf(exitError, "Failed to clear interrupted apply: %v", err)
+	}
+
+	if len(cleanup.Steps) == 0 {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ The database already has the schema it had before the interrupted apply\n")
+	} else {
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🧹 Cleanup plan with %d steps reverts the interrupted apply (%d of %d steps committed)\n",
+			len(cleanup.Steps), checkpoint.StepsApplied, checkpoint.TotalSteps)
+		fmt.Fprintf(style.Stderr, "   Review it, then apply it: lockplane apply cleanup.json --target-environment %s\n", resolvedTarget.Name)
+	}
+
+	jsonBytes, err := json.MarshalIndent(cleanup, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"os/user"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/introspect"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/state"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+// maxPreApplyCheckRows caps how many rows of a failed pre_apply_check are shown
+const maxPreApplyCheckRows = 5
+
+// enforceApplyWindow exits unless the target environment's apply window is
+// open, or --override-window gave a reason, which is recorded in the state
+// file. It returns a warning for the apply result when the window was
+// overridden.
+func enforceApplyWindow(env *config.ResolvedEnvironment, now time.Time, overridden bool, reason string) string {
+	if overridden && strings.TrimSpace(reason) == "" {
+		fmt.Fprintf(style.Stderr, "Error: --override-window needs a reason, e.g. --override-window \"hotfix for incident 123\".\n\n")
+		exit(1)
+	}
+
+	window := env.ApplyWindow
+	if window == nil || window.Contains(now) {
+		return ""
+	}
+
+	if !overridden {
+		_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Outside the apply window of environment %q\n\n", env.Name)
+		fmt.Fprintf(style.Stderr, "Schema changes are only allowed %s; it is now %s.\n", window, now.In(window.Location).Format("15:04 MST"))
+		fmt.Fprintf(style.Stderr, "The window next opens at %s.\n\n", window.NextOpen(now).Format("2006-01-02 15:04 MST"))
+		fmt.Fprintf(style.Stderr, "To apply anyway, pass --override-window with the reason; it is recorded in %s.\n", state.StateFile)
+		exit(1)
+	}
+
+	override := state.WindowOverride{
+		Target:    env.Name,
+		Window:    window.String(),
+		Reason:    strings.TrimSpace(reason),
+		AppliedAt: now,
+	}
+	if current, err := user.Current(); err == nil {
+		override.User = current.Username
+	}
+	st, err := state.Load()
+	if err != nil {
+		fatalf(exitError, "Failed to load state: %v", err)
+	}
+	if err := st.RecordWindowOverride(override); err != nil {
+		fatalf(exitError, "Failed to record apply window override: %v", err)
+	}
+
+	warning := fmt.Sprintf("applied outside the apply window %s of environment %q: %s", window, env.Name, override.Reason)
+	_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Overriding the apply window %s: %s\n", window, override.Reason)
+	return warning
+}
+
+// resolveImmutableTables returns the tables declared immutable in
+// lockplane.toml, for the environment the plan applies to or, without one,
+// globally, and with -- lockplane:immutable in the schema files at paths.
+// Each maps to where it was declared.
+func resolveImmutableTables(cfg *config.Config, env *config.ResolvedEnvironment, paths ...string) map[string]string {
+	tables := map[string]string{}
+	if env != nil {
+		maps.Copy(tables, env.ImmutableTables)
+	} else {
+		maps.Copy(tables, cfg.GlobalImmutableTables())
+	}
+	for _, path := range paths {
+		if path == "" || introspect.IsConnectionString(path) {
+			continue
+		}
+		found, err := schema.FindImmutableTables(path)
+		if err != nil {
+			fatalf(exitError, "Failed to read immutable table declarations: %v", err)
+		}
+		for table, declaredAt := range found {
+			if _, ok := tables[table]; !ok {
+				tables[table] = declaredAt
+			}
+		}
+	}
+	return tables
+}
+
+// immutableOverrideReason returns the reason --allow-immutable-change gave,
+// exiting when the flag was set without one
+func immutableOverrideReason(overridden bool, reason string) string {
+	reason = strings.TrimSpace(reason)
+	if overridden && reason == "" {
+		fmt.Fprintf(style.Stderr, "Error: --allow-immutable-change needs a reason, e.g. --allow-immutable-change \"backfill approved in ticket 42\".\n\n")
+		exit(1)
+	}
+	return reason
+}
+
+// enforceImmutableGate lists the plan steps that alter or drop an immutable
+// table and exits unless --allow-immutable-change gave a reason. In dry-run
+// mode the steps are only reported. It returns a warning for the apply result
+// when the change was allowed.
+func enforceImmutableGate(plan *planner.Plan, immutable map[string]string, reason string, dryRun bool) string {
+	steps := validation.FindImmutableSteps(plan, immutable)
+	if len(steps) == 0 {
+		return ""
+	}
+
+	switch {
+	case dryRun && reason == "":
+		_, _ = color.New(color.FgYellow, color.Bold).Fprintf(style.Stderr, "⚠️  %d operation(s) on immutable tables would require --allow-immutable-change:\n\n", len(steps))
+	case reason != "":
+		_, _ = color.New(color.FgYellow, color.Bold).Fprintf(style.Stderr, "⚠️  Changing immutable tables in %d operation(s) (allowed by --allow-immutable-change: %s):\n\n", len(steps), reason)
+	default:
+		_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "❌ Refusing to change immutable tables in %d operation(s):\n\n", len(steps))
+	}
+
+	tables := []string{}
+	for _, s := range steps {
+		fmt.Fprintf(style.Stderr, "  %d. %s\n", s.Index+1, s.Step.Description)
+		fmt.Fprintf(style.Stderr, "     🔒 %s is immutable (declared at %s)\n", s.Table, s.DeclaredAt)
+		if !slices.Contains(tables, s.Table) {
+			tables = append(tables, s.Table)
+		}
+	}
+	fmt.Fprintf(style.Stderr, "\n")
+
+	if reason == "" {
+		if !dryRun {
+			fmt.Fprintf(style.Stderr, "Immutable tables may only be created. Re-run with --allow-immutable-change and a reason to change them anyway.\n\n")
+			exit(exitImmutableBlocked)
+		}
+		return ""
+	}
+	return fmt.Sprintf("changed immutable table(s) %s: %s", strings.Join(tables, ", "), reason)
+}
+
+// enforceWritableTarget exits before any step runs when the target database
+// is read-only, e.g. a read replica, instead of failing partway through the
+// migration with "cannot execute ... in a read-only transaction"
+func enforceWritableTarget(ctx context.Context, db *sql.DB, driverType string, env *config.ResolvedEnvironment) {
+	reason, err := readOnlyReason(ctx, db, driverType)
+	if err != nil {
+		fatalf(exitConnectionError, "Failed to check whether the target database is read-only: %v", err)
+	}
+	if reason == "" {
+		return
+	}
+
+	_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Target database of environment %q is read-only\n\n", env.Name)
+	fmt.Fprintf(style.Stderr, "The target %s, so no migration step could run.\n", reason)
+	fmt.Fprintf(style.Stderr, "Point the environment at the primary database and try again. Nothing was changed.\n")
+	exit(1)
+}
+
+// readOnlyReason returns why the database can't be written to, or "" when it
+// can. A PostgreSQL read replica (hot standby) is in recovery; a primary can
+// still default every transaction to read-only, e.g. for the connecting role.
+// SQLite refuses writes with PRAGMA query_only.
+func readOnlyReason(ctx context.Context, db *sql.DB, driverType string) (string, error) {
+	switch driverType {
+	case "postgres", "postgresql":
+		var inRecovery bool
+		if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+			return "", err
+		}
+		if inRecovery {
+			return "is a read replica (pg_is_in_recovery() is true)", nil
+		}
+		var readOnly string
+		if err := db.QueryRowContext(ctx, "SHOW transaction_read_only").Scan(&readOnly); err != nil {
+			return "", err
+		}
+		if readOnly == "on" {
+			return "only allows read-only transactions (transaction_read_only is on)", nil
+		}
+	case "sqlite", "sqlite3":
+		var queryOnly bool
+		if err := db.QueryRowContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+			return "", err
+		}
+		if queryOnly {
+			return "only allows queries (PRAGMA query_only is on)", nil
+		}
+	}
+	return "", nil
+}
+
+// enforcePreApplyCheck runs the environment's pre_apply_check query against
+// the target and exits when it returns any rows
+func enforcePreApplyCheck(ctx context.Context, db *sql.DB, env *config.ResolvedEnvironment) {
+	if env.PreApplyCheck == "" {
+		return
+	}
+
+	_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔎 Running pre-apply check...\n")
+	rows, err := runPreApplyCheck(ctx, db, env.PreApplyCheck)
+	if err != nil {
+		fatalf(exitError, "Pre-apply check of environment %q failed: %v", env.Name, err)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Pre-apply check of environment %q returned rows\n\n", env.Name)
+	for _, row := range rows {
+		fmt.Fprintf(style.Stderr, "  - %s\n", row)
+	}
+	fmt.Fprintf(style.Stderr, "\nThe apply was aborted before any changes. Query:\n  %s\n", env.PreApplyCheck)
+	exit(1)
+}
+
+// runPreApplyCheck runs query and returns up to maxPreApplyCheckRows of its
+// rows formatted as "column=value, ...", followed by a count of the rest
+func runPreApplyCheck(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var formatted []string
+	total := 0
+	for rows.Next() {
+		total++
+		if total > maxPreApplyCheckRows {
+			continue
+		}
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			value := values[i]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			parts[i] = fmt.Sprintf("%s=%v", column, value)
+		}
+		formatted = append(formatted, strings.Join(parts, ", "))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if total > maxPreApplyCheckRows {
+		formatted = append(formatted, fmt.Sprintf("... and %d more", total-maxPreApplyCheckRows))
+	}
+	return formatted, nil
+}
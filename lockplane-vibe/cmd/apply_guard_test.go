@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestRunPreApplyCheck(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE locks (pid INTEGER, query TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	rows, err := runPreApplyCheck(ctx, db, "SELECT pid, query FROM locks")
+	if err != nil {
+		t.Fatalf("runPreApplyCheck returned error: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("expected an empty result to pass, got %v", rows)
+	}
+
+	for i := 1; i <= 7; i++ {
+		if _, err := db.ExecContext(ctx, "INSERT INTO locks VALUES (?, 'VACUUM')", i); err != nil {
+			t.Fatalf("Failed to insert row: %v", err)
+		}
+	}
+	rows, err = runPreApplyCheck(ctx, db, "SELECT pid, query FROM locks ORDER BY pid")
+	if err != nil {
+		t.Fatalf("runPreApplyCheck returned error: %v", err)
+	}
+	if len(rows) != maxPreApplyCheckRows+1 {
+		t.Fatalf("expected %d rows and a summary, got %v", maxPreApplyCheckRows, rows)
+	}
+	if rows[0] != "pid=1, query=VACUUM" {
+		t.Errorf("unexpected first row: %q", rows[0])
+	}
+	if rows[maxPreApplyCheckRows] != "... and 2 more" {
+		t.Errorf("unexpected summary: %q", rows[maxPreApplyCheckRows])
+	}
+
+	if _, err := runPreApplyCheck(ctx, db, "SELECT * FROM missing"); err == nil {
+		t.Error("expected an invalid query to fail")
+	}
+}
+
+func TestReadOnlyReason(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	reason, err := readOnlyReason(ctx, db, "sqlite")
+	if err != nil {
+		t.Fatalf("readOnlyReason returned error: %v", err)
+	}
+	if reason != "" {
+		t.Fatalf("expected a writable database, got %q", reason)
+	}
+
+	if _, err := db.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		t.Fatalf("Failed to make database read-only: %v", err)
+	}
+	reason, err = readOnlyReason(ctx, db, "sqlite")
+	if err != nil {
+		t.Fatalf("readOnlyReason returned error: %v", err)
+	}
+	if reason != "only allows queries (PRAGMA query_only is on)" {
+		t.Errorf("unexpected reason: %q", reason)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/review"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// checkInteractiveApplyFlags exits when --interactive is combined with flags
+// it contradicts, or when there is no terminal to ask on
+func checkInteractiveApplyFlags(autoApprove, resume, dryRun bool) {
+	if autoApprove {
+		fmt.Fprintf(style.Stderr, "Error: --interactive and --auto-approve cannot be combined.\n\n")
+		exit(1)
+	}
+	if resume {
+		fmt.Fprintf(style.Stderr, "Error: --interactive cannot be combined with --resume; a resumed apply finishes the steps approved the first time.\n\n")
+		exit(1)
+	}
+	if !dryRun && !isInteractiveTerminal() {
+		fmt.Fprintf(style.Stderr, "Error: --interactive requires an interactive terminal.\n")
+		fmt.Fprintf(style.Stderr, "Pass --auto-approve (after reviewing the plan with --dry-run) when running non-interactively.\n")
+		exit(1)
+	}
+}
+
+// confirmApplyInteractively asks the operator to approve the whole plan or
+// each of its steps, and returns the plan of approved steps and the skipped
+// ones. Exits when the operator aborts or skips every step.
+func confirmApplyInteractively(plan *planner.Plan, diff *schema.SchemaDiff, target string) (*planner.Plan, []planner.SkippedStep) {
+	approved, skipped, err := review.RunApproval(plan, diff, target)
+	if errors.Is(err, review.ErrCancelled) {
+		_, _ = color.New(color.FgRed).Fprintf(style.Stderr, "\nApply cancelled.\n")
+		exit(0)
+	}
+	if err != nil {
+		fatalf(exitError, "Failed to run apply confirmation: %v", err)
+	}
+	if len(approved.Steps) == 0 {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "\nAll %d steps skipped; nothing was applied.\n", len(plan.Steps))
+		exit(0)
+	}
+
+	if len(skipped) > 0 {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Applying %d of %d steps; skipped:\n", len(approved.Steps), len(plan.Steps))
+		for _, step := range skipped {
+			fmt.Fprintf(style.Stderr, "  %d. %s\n", step.Step, step.Description)
+		}
+		fmt.Fprintf(style.Stderr, "\n")
+	} else {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ All %d steps approved\n\n", len(plan.Steps))
+	}
+	return approved, skipped
+}
+
+// skippedStepsWarning describes the steps skipped during an interactive
+// apply for the warnings of the apply result
+func skippedStepsWarning(skipped []planner.SkippedStep) string {
+	return fmt.Sprintf("%d step(s) skipped interactively and not applied; the database does not match the plan's target schema until they are", len(skipped))
+}
@@ -1,11 +1,9 @@
 package cmd
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"time"
 
@@ -13,6 +11,7 @@ import (
 	"github.com/lockplane/lockplane/internal/executor"
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/state"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -77,47 +76,47 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	// Load multi-phase plan
 	multiPhasePlan, err := loadMultiPhasePlan(planPath)
 	if err != nil {
-		log.Fatalf("Failed to load multi-phase plan: %v", err)
+		fatalf(exitError, "Failed to load multi-phase plan: %v", err)
 	}
 
 	// Load state
 	st, err := state.Load()
 	if err != nil {
-		log.Fatalf("Failed to load state: %v", err)
+		fatalf(exitError, "Failed to load state: %v", err)
 	}
 
 	// Determine which phase to execute
 	phaseNumber := apPhase
 	if apNext {
 		if apPhase != 0 {
-			log.Fatal("Cannot use both --phase and --next")
+			fatal("Cannot use both --phase and --next")
 		}
 		phaseNumber = st.GetNextPhase()
 		if phaseNumber == 0 {
-			fmt.Println("✅ All phases complete!")
+			fmt.Fprintln(style.Stdout, "✅ All phases complete!")
 			return
 		}
 		fmt.Printf("Next phase to execute: %d\n", phaseNumber)
 	}
 
 	if phaseNumber == 0 {
-		log.Fatal("Must specify --phase <number> or --next")
+		fatal("Must specify --phase <number> or --next")
 	}
 
 	if phaseNumber < 1 || phaseNumber > multiPhasePlan.TotalPhases {
-		log.Fatalf("Invalid phase number %d (plan has %d phases)", phaseNumber, multiPhasePlan.TotalPhases)
+		fatalf(exitError, "Invalid phase number %d (plan has %d phases)", phaseNumber, multiPhasePlan.TotalPhases)
 	}
 
 	// Check if we can execute this phase (unless --force)
 	if !apForce {
 		if err := st.CanExecutePhase(phaseNumber); err != nil {
-			log.Fatalf("Cannot execute phase %d: %v\nUse --force to override (dangerous)", phaseNumber, err)
+			fatalf(exitError, "Cannot execute phase %d: %v\nUse --force to override (dangerous)", phaseNumber, err)
 		}
 	}
 
@@ -136,26 +135,26 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 		}
 
 		if err := st.StartMigration(migrationID, multiPhasePlan.Operation, multiPhasePlan.Pattern, table, column, multiPhasePlan.TotalPhases, planPath); err != nil {
-			log.Fatalf("Failed to start migration: %v", err)
+			fatalf(exitError, "Failed to start migration: %v", err)
 		}
 		fmt.Printf("Started multi-phase migration: %s\n", migrationID)
 	}
 
 	// Display phase information
 	fmt.Printf("\n")
-	fmt.Printf("📋 Multi-Phase Migration: %s\n", multiPhasePlan.Description)
+	fmt.Fprintf(style.Stdout, "📋 Multi-Phase Migration: %s\n", multiPhasePlan.Description)
 	fmt.Printf("Pattern: %s\n", multiPhasePlan.Pattern)
 	fmt.Printf("Total Phases: %d\n", multiPhasePlan.TotalPhases)
 	fmt.Printf("\n")
-	fmt.Printf("🎯 Executing Phase %d/%d: %s\n", phaseNumber, multiPhasePlan.TotalPhases, phase.Name)
+	fmt.Fprintf(style.Stdout, "🎯 Executing Phase %d/%d: %s\n", phaseNumber, multiPhasePlan.TotalPhases, phase.Name)
 	fmt.Printf("Description: %s\n", phase.Description)
 	fmt.Printf("\n")
 
 	// Show code changes required
 	if phase.RequiresCodeDeploy && len(phase.CodeChangesRequired) > 0 {
-		fmt.Printf("⚠️  Code Changes Required:\n")
+		fmt.Fprintf(style.Stdout, "⚠️  Code Changes Required:\n")
 		for _, change := range phase.CodeChangesRequired {
-			fmt.Printf("  • %s\n", change)
+			fmt.Fprintf(style.Stdout, "  • %s\n", change)
 		}
 		fmt.Printf("\n")
 	}
@@ -178,14 +177,14 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	if len(phase.Verification) > 0 {
 		fmt.Printf("Verification Steps:\n")
 		for _, v := range phase.Verification {
-			fmt.Printf("  • %s\n", v)
+			fmt.Fprintf(style.Stdout, "  • %s\n", v)
 		}
 		fmt.Printf("\n")
 	}
 
 	// Dry run mode
 	if apDryRun {
-		fmt.Println("🔍 DRY RUN: No changes will be applied")
+		fmt.Fprintln(style.Stdout, "🔍 DRY RUN: No changes will be applied")
 		return
 	}
 
@@ -195,7 +194,7 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 		var response string
 		_, err = fmt.Scanln(&response)
 		if err != nil {
-			log.Fatalf("Failed to read input: %v", err)
+			fatalf(exitError, "Failed to read input: %v", err)
 		}
 		if response != "yes" && response != "y" {
 			fmt.Println("Cancelled")
@@ -208,10 +207,10 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 		fmt.Println("No SQL to execute. Mark this phase complete after code deployment.")
 
 		if err := st.CompletePhase(phaseNumber); err != nil {
-			log.Fatalf("Failed to update state: %v", err)
+			fatalf(exitError, "Failed to update state: %v", err)
 		}
 
-		fmt.Printf("✅ Phase %d marked as complete\n", phaseNumber)
+		fmt.Fprintf(style.Stdout, "✅ Phase %d marked as complete\n", phaseNumber)
 		showNextSteps(st, multiPhasePlan, phaseNumber)
 		return
 	}
@@ -219,7 +218,7 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	// Resolve target database
 	targetConnStr, err := resolveConnection(cfg, apTarget, apTargetEnv, "target")
 	if err != nil {
-		log.Fatalf("Failed to resolve target database: %v", err)
+		fatalf(exitError, "Failed to resolve target database: %v", err)
 	}
 
 	// Resolve shadow database (if not skipped)
@@ -227,7 +226,7 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	if !apSkipShadowDB {
 		shadowConnStr, err = resolveConnection(cfg, apShadowDB, apShadowDBEnv, "shadow_db")
 		if err != nil {
-			log.Fatalf("Failed to resolve shadow database: %v", err)
+			fatalf(exitError, "Failed to resolve shadow database: %v", err)
 		}
 	}
 
@@ -235,27 +234,27 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	driverName := executor.DetectDriver(targetConnStr)
 	driver, err := executor.NewDriver(driverName)
 	if err != nil {
-		log.Fatalf("Failed to create driver: %v", err)
+		fatalf(exitError, "Failed to create driver: %v", err)
 	}
 
 	// Open target database connection
-	ctx := context.Background()
+	ctx := cmd.Context()
 	sqlDriverName := executor.GetSQLDriverName(driverName)
 	targetDB, err := sql.Open(sqlDriverName, targetConnStr)
 	if err != nil {
-		log.Fatalf("Failed to connect to target database: %v", err)
+		fatalf(exitError, "Failed to connect to target database: %v", err)
 	}
 	defer func() { _ = targetDB.Close() }()
 
 	// Ping to verify connection
 	if err := targetDB.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping target database: %v", err)
+		fatalf(exitError, "Failed to ping target database: %v", err)
 	}
 
 	// Introspect current schema
-	currentSchema, err := executor.LoadSchemaFromConnectionString(targetConnStr)
+	currentSchema, err := executor.LoadSchemaFromConnectionStringContext(ctx, targetConnStr, nil)
 	if err != nil {
-		log.Fatalf("Failed to introspect current schema: %v", err)
+		fatalf(exitError, "Failed to introspect current schema: %v", err)
 	}
 
 	// Open shadow database connection (if not skipped)
@@ -263,12 +262,20 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	if !apSkipShadowDB && shadowConnStr != "" {
 		shadowDB, err = sql.Open(sqlDriverName, shadowConnStr)
 		if err != nil {
-			log.Fatalf("Failed to connect to shadow database: %v", err)
+			fatalf(exitError, "Failed to connect to shadow database: %v", err)
 		}
 		defer func() { _ = shadowDB.Close() }()
 
 		if err := shadowDB.PingContext(ctx); err != nil {
-			log.Fatalf("Failed to ping shadow database: %v", err)
+			fatalf(exitError, "Failed to ping shadow database: %v", err)
+		}
+
+		resolvedShadow, err := config.ResolveEnvironment(cfg, apShadowDBEnv)
+		if err != nil {
+			fatalf(exitError, "Failed to resolve shadow environment: %v", err)
+		}
+		if err := applyShadowSettings(ctx, shadowDB, driverName, resolvedShadow, apVerbose); err != nil {
+			fatalf(exitError, "Failed to configure shadow database: %v", err)
 		}
 	}
 
@@ -277,26 +284,26 @@ func runApplyPhase(cmd *cobra.Command, args []string) {
 	result, err := executor.ApplyPlan(ctx, targetDB, phase.Plan, shadowDB, currentSchema, driver, apVerbose)
 	if err != nil {
 		handlePhaseExecutionError(err, phaseNumber, st, phase)
-		log.Fatalf("Failed to execute phase: %v", err)
+		fatalf(exitError, "Failed to execute phase: %v", err)
 	}
 
 	if !result.Success {
-		fmt.Printf("❌ Phase %d execution failed:\n", phaseNumber)
+		fmt.Fprintf(style.Stdout, "❌ Phase %d execution failed:\n", phaseNumber)
 		for _, errMsg := range result.Errors {
 			fmt.Printf("  - %s\n", errMsg)
 		}
 		fmt.Printf("\n")
 		printPhaseRecoveryInstructions(phaseNumber, phase)
-		log.Fatal("Phase execution failed")
+		fatal("Phase execution failed")
 	}
 
 	// Update state
 	if err := st.CompletePhase(phaseNumber); err != nil {
-		log.Fatalf("Failed to update state: %v", err)
+		fatalf(exitError, "Failed to update state: %v", err)
 	}
 
 	// Success
-	fmt.Printf("\n✅ Phase %d complete!\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "\n✅ Phase %d complete!\n", phaseNumber)
 	fmt.Printf("Executed %d steps successfully\n", result.StepsApplied)
 
 	showNextSteps(st, multiPhasePlan, phaseNumber)
@@ -389,7 +396,7 @@ func showNextSteps(st *state.State, plan *planner.MultiPhasePlan, completedPhase
 	fmt.Printf("\n")
 
 	if completedPhase >= plan.TotalPhases {
-		fmt.Println("🎉 All phases complete! Multi-phase migration finished.")
+		fmt.Fprintln(style.Stdout, "🎉 All phases complete! Multi-phase migration finished.")
 		fmt.Println("\nTo clean up state:")
 		fmt.Printf("  rm %s\n", state.StateFile)
 		return
@@ -404,14 +411,14 @@ func showNextSteps(st *state.State, plan *planner.MultiPhasePlan, completedPhase
 		if nextPhase.RequiresCodeDeploy {
 			fmt.Printf("  1. Deploy code changes:\n")
 			for _, change := range nextPhase.CodeChangesRequired {
-				fmt.Printf("     • %s\n", change)
+				fmt.Fprintf(style.Stdout, "     • %s\n", change)
 			}
 		}
 
 		if len(nextPhase.Verification) > 0 {
 			fmt.Printf("  %d. Verify phase %d:\n", 2, completedPhase)
 			for _, v := range nextPhase.Verification {
-				fmt.Printf("     • %s\n", v)
+				fmt.Fprintf(style.Stdout, "     • %s\n", v)
 			}
 		}
 
@@ -422,14 +429,14 @@ func showNextSteps(st *state.State, plan *planner.MultiPhasePlan, completedPhase
 }
 
 func handlePhaseExecutionError(err error, phaseNumber int, st *state.State, phase planner.Phase) {
-	fmt.Printf("\n⚠️  Phase %d execution encountered an error\n\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "\n⚠️  Phase %d execution encountered an error\n\n", phaseNumber)
 
 	fmt.Printf("Error: %v\n\n", err)
 
 	fmt.Printf("Current State:\n")
-	fmt.Printf("  • Phase %d has NOT been marked as complete\n", phaseNumber)
-	fmt.Printf("  • Database may be in a partial state\n")
-	fmt.Printf("  • State file preserved at: %s\n\n", state.StateFile)
+	fmt.Fprintf(style.Stdout, "  • Phase %d has NOT been marked as complete\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "  • Database may be in a partial state\n")
+	fmt.Fprintf(style.Stdout, "  • State file preserved at: %s\n\n", state.StateFile)
 
 	printPhaseRecoveryInstructions(phaseNumber, phase)
 }
@@ -448,7 +455,7 @@ func printPhaseRecoveryInstructions(phaseNumber int, phase planner.Phase) {
 			}
 		}
 	} else {
-		fmt.Printf("  ⚠️  No rollback available for this phase\n")
+		fmt.Fprintf(style.Stdout, "  ⚠️  No rollback available for this phase\n")
 	}
 	fmt.Printf("\n")
 
@@ -459,12 +466,12 @@ func printPhaseRecoveryInstructions(phaseNumber int, phase planner.Phase) {
 	fmt.Printf("\n")
 
 	fmt.Printf("Option 3: Force skip this phase (DANGEROUS)\n")
-	fmt.Printf("  ⚠️  Only use if you've manually applied the changes\n")
+	fmt.Fprintf(style.Stdout, "  ⚠️  Only use if you've manually applied the changes\n")
 	fmt.Printf("  lockplane apply-phase <plan-file> --phase %d --force\n", phaseNumber+1)
 	fmt.Printf("\n")
 
 	fmt.Printf("For more help:\n")
-	fmt.Printf("  • Check phase status: lockplane phase-status\n")
-	fmt.Printf("  • View state file: cat %s\n", state.StateFile)
-	fmt.Printf("  • Review plan: cat <plan-file>\n")
+	fmt.Fprintf(style.Stdout, "  • Check phase status: lockplane phase-status\n")
+	fmt.Fprintf(style.Stdout, "  • View state file: cat %s\n", state.StateFile)
+	fmt.Fprintf(style.Stdout, "  • Review plan: cat <plan-file>\n")
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+// checkBackfillBatchFlags exits on a negative --backfill-batch-size or
+// --backfill-pause
+func checkBackfillBatchFlags(batchSize int, pause time.Duration) {
+	if batchSize < 0 {
+		fmt.Fprintf(style.Stderr, "Error: --backfill-batch-size must not be negative.\n")
+		exit(1)
+	}
+	if pause < 0 {
+		fmt.Fprintf(style.Stderr, "Error: --backfill-pause must not be negative.\n")
+		exit(1)
+	}
+}
+
+// enforceBatchOrderingKeys exits before anything is applied when a batched
+// update step can't be ordered by its key in the target database
+func enforceBatchOrderingKeys(plan *planner.Plan, current *database.Schema) {
+	results := validation.ValidateBatchedUpdates(plan, current)
+	if len(results) == 0 {
+		return
+	}
+	printValidationReport(results, "=== Batched Updates ===")
+	fmt.Fprintf(style.Stderr, "❌ %d batched update(s) have no usable ordering key. Order them by a unique, NOT NULL column such as the primary key.\n\n", len(results))
+	exit(validationExitCode(results))
+}
+
+// printBatchProgress reports the batches a batched update step has committed
+func printBatchProgress(progress executor.BatchProgress) {
+	status := "running"
+	if progress.Done {
+		status = "done"
+	}
+	_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "  ↻ Step %d: %d batch(es) committed, %d row(s) updated (%s)\n",
+		progress.Step, progress.Batches, progress.Rows, status)
+}
+
+// cancelBatchesOnInterrupt returns a context that Ctrl-C or SIGTERM cancels
+// when plan has batched update steps. The running batch is then rolled back
+// and the apply fails like any other, keeping the committed batches and the
+// checkpoint to resume from. Other plans keep the default signal handling.
+func cancelBatchesOnInterrupt(ctx context.Context, plan *planner.Plan) (context.Context, context.CancelFunc) {
+	for _, step := range plan.Steps {
+		if step.Batch != nil {
+			return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		}
+	}
+	return ctx, func() {}
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/graph"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities",
+	Short: "Describe what this lockplane build supports",
+	Long: `Describe the database drivers, schema object kinds, output formats and
+command flags this lockplane build supports.
+
+Editor extensions and other integrations can read --output json to adapt to
+the installed version instead of comparing version numbers.`,
+	Example: `  # Summary for humans
+  lockplane capabilities
+
+  # Machine-readable descriptor
+  lockplane capabilities --output json`,
+	Args: cobra.NoArgs,
+	Run:  runCapabilities,
+}
+
+var capabilitiesOutput string
+
+func init() {
+	capabilitiesCmd.Flags().StringVar(&capabilitiesOutput, "output", "text", "Output format: text or json")
+	rootCmd.AddCommand(capabilitiesCmd)
+}
+
+// capabilities describes what this build supports, as printed by
+// lockplane capabilities --output json
+type capabilities struct {
+	Version       string                `json:"version"`
+	Drivers       []driverCapability    `json:"drivers"`
+	ObjectKinds   []string              `json:"object_kinds"`
+	OutputFormats []outputCapability    `json:"output_formats"`
+	GlobalFlags   []flagCapability      `json:"global_flags"`
+	Commands      []commandCapabilities `json:"commands"`
+}
+
+// driverCapability describes a database driver and the schema object kinds
+// it manages
+type driverCapability struct {
+	Name        string   `json:"name"`
+	Dialect     string   `json:"dialect"`
+	ObjectKinds []string `json:"object_kinds"`
+}
+
+// outputCapability lists the values a command's output format flag accepts
+type outputCapability struct {
+	Command string   `json:"command"`
+	Flag    string   `json:"flag"`
+	Formats []string `json:"formats"`
+}
+
+type commandCapabilities struct {
+	Name  string           `json:"name"` // Command path without "lockplane", e.g. "config print"
+	Short string           `json:"short"`
+	Flags []flagCapability `json:"flags,omitempty"`
+}
+
+type flagCapability struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default,omitempty"`
+	Usage     string `json:"usage"`
+}
+
+// Schema object kinds lockplane introspects, diffs and migrates. Add new
+// kinds here, and to the drivers that manage them, as they land.
+var (
+	sqliteObjectKinds = []string{"table", "column", "index", "foreign_key"}
+
+	postgresObjectKinds = append(append([]string{}, sqliteObjectKinds...),
+		"row_level_security", "policy", "tablespace", "replica_identity",
+		"storage_parameters", "column_storage", "column_statistics")
+)
+
+// supportedDrivers lists the database drivers in this build
+var supportedDrivers = []driverCapability{
+	{Name: "postgres", Dialect: "postgres", ObjectKinds: postgresObjectKinds},
+	{Name: "sqlite", Dialect: "sqlite", ObjectKinds: sqliteObjectKinds},
+	{Name: "libsql", Dialect: "sqlite", ObjectKinds: sqliteObjectKinds},
+}
+
+// supportedOutputFormats lists the formats of commands with a selectable output
+var supportedOutputFormats = []outputCapability{
+	{Command: "plan", Flag: "output", Formats: []string{"text", "json", "json-full", "sarif", "patch"}},
+	{Command: "introspect", Flag: "format", Formats: []string{"json", "sql"}},
+	{Command: "graph", Flag: "format", Formats: []string{graph.FormatDot, graph.FormatMermaid}},
+	{Command: "config print", Flag: "format", Formats: []string{"text", "json"}},
+	{Command: "capabilities", Flag: "output", Formats: []string{"text", "json"}},
+}
+
+func runCapabilities(cmd *cobra.Command, args []string) {
+	caps := buildCapabilities(rootCmd)
+	switch capabilitiesOutput {
+	case "json":
+		jsonBytes, err := json.MarshalIndent(caps, "", "  ")
+		if err != nil {
+			fatalf(exitError, "Failed to marshal capabilities to JSON: %v", err)
+		}
+		fmt.Println(string(jsonBytes))
+	case "text":
+		printCapabilities(os.Stdout, caps)
+	default:
+		fmt.Fprintf(style.Stderr, "Error: unsupported output %q (use text or json)\n", capabilitiesOutput)
+		exit(1)
+	}
+}
+
+// buildCapabilities describes this build. Commands and flags are read from
+// the command tree, so they can't go out of date.
+func buildCapabilities(root *cobra.Command) capabilities {
+	caps := capabilities{
+		Version:       root.Version,
+		Drivers:       supportedDrivers,
+		ObjectKinds:   postgresObjectKinds,
+		OutputFormats: supportedOutputFormats,
+		GlobalFlags:   describeFlags(root.PersistentFlags()),
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if sub.Hidden || !sub.IsAvailableCommand() {
+				continue
+			}
+			caps.Commands = append(caps.Commands, commandCapabilities{
+				Name:  strings.TrimPrefix(sub.CommandPath(), root.Name()+" "),
+				Short: sub.Short,
+				Flags: describeFlags(sub.NonInheritedFlags()),
+			})
+			walk(sub)
+		}
+	}
+	walk(root)
+	return caps
+}
+
+// describeFlags lists the visible flags of a flag set, sorted by name
+func describeFlags(flags *pflag.FlagSet) []flagCapability {
+	var described []flagCapability
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Name == "help" {
+			return
+		}
+		described = append(described, flagCapability{
+			Name:      flag.Name,
+			Shorthand: flag.Shorthand,
+			Type:      flag.Value.Type(),
+			Default:   flag.DefValue,
+			Usage:     flag.Usage,
+		})
+	})
+	return described
+}
+
+// printCapabilities writes a short human-readable summary; the flags of each
+// command are left to --help
+func printCapabilities(w io.Writer, caps capabilities) {
+	_, _ = fmt.Fprintf(w, "lockplane %s\n\nDrivers:\n", caps.Version)
+	for _, driver := range caps.Drivers {
+		_, _ = fmt.Fprintf(w, "  %-9s %s\n", driver.Name, strings.Join(driver.ObjectKinds, ", "))
+	}
+	_, _ = fmt.Fprintf(w, "\nOutput formats:\n")
+	for _, output := range caps.OutputFormats {
+		_, _ = fmt.Fprintf(w, "  %-13s --%s %s\n", output.Command, output.Flag, strings.Join(output.Formats, ", "))
+	}
+	_, _ = fmt.Fprintf(w, "\nCommands:\n")
+	width := 0
+	for _, command := range caps.Commands {
+		width = max(width, len(command.Name))
+	}
+	for _, command := range caps.Commands {
+		_, _ = fmt.Fprintf(w, "  %-*s  %s\n", width, command.Name, command.Short)
+	}
+}
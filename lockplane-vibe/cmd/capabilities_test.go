@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildCapabilities(t *testing.T) {
+	caps := buildCapabilities(rootCmd)
+
+	var drivers []string
+	for _, driver := range caps.Drivers {
+		drivers = append(drivers, driver.Name)
+	}
+	if want := []string{"postgres", "sqlite", "libsql"}; !slices.Equal(drivers, want) {
+		t.Errorf("expected drivers %v, got %v", want, drivers)
+	}
+
+	commands := map[string]commandCapabilities{}
+	for _, command := range caps.Commands {
+		commands[command.Name] = command
+	}
+	plan, ok := commands["plan"]
+	if !ok {
+		t.Fatalf("expected the plan command, got %v", caps.Commands)
+	}
+	if !slices.ContainsFunc(plan.Flags, func(f flagCapability) bool { return f.Name == "check-schema" && f.Type == "bool" }) {
+		t.Errorf("expected plan to advertise --check-schema, got %+v", plan.Flags)
+	}
+	if _, ok := commands["config print"]; !ok {
+		t.Error("expected subcommands to be listed by their path")
+	}
+	if !slices.ContainsFunc(caps.GlobalFlags, func(f flagCapability) bool { return f.Name == "config" }) {
+		t.Errorf("expected the global --config flag, got %+v", caps.GlobalFlags)
+	}
+
+	// Every command with an advertised output format exists
+	for _, output := range caps.OutputFormats {
+		command, ok := commands[output.Command]
+		if !ok {
+			t.Errorf("output formats name unknown command %q", output.Command)
+			continue
+		}
+		if !slices.ContainsFunc(command.Flags, func(f flagCapability) bool { return f.Name == output.Flag }) {
+			t.Errorf("command %q has no --%s flag", output.Command, output.Flag)
+		}
+	}
+
+	jsonBytes, err := json.Marshal(caps)
+	if err != nil {
+		t.Fatalf("failed to marshal capabilities: %v", err)
+	}
+	if !strings.Contains(string(jsonBytes), `"object_kinds":["table","column"`) {
+		t.Errorf("unexpected JSON: %s", jsonBytes)
+	}
+}
+
+func TestPrintCapabilities(t *testing.T) {
+	var buf bytes.Buffer
+	printCapabilities(&buf, buildCapabilities(rootCmd))
+	for _, want := range []string{"Drivers:\n  postgres", "--output text, json, json-full, sarif, patch", "capabilities"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/spf13/cobra"
+)
+
+var cleanupTombstonesCmd = &cobra.Command{
+	Use:   "cleanup-tombstones",
+	Short: "Generate a plan that drops old soft-dropped columns",
+	Long: `Find the tombstone columns left by column_drop_strategy = "soft" and
+generate a plan that drops the ones soft-dropped before --older-than.
+
+Soft-dropped columns are renamed to deleted_<name>_<YYYYMMDD> instead of
+being dropped, so their data can be recovered. Once nothing needs them, this
+command drops them for good. The plan loses data, so apply it with
+--allow-destructive.`,
+	Example: `  # Drop columns soft-dropped more than 30 days ago
+  lockplane cleanup-tombstones --target-environment production --older-than 30d > cleanup.json
+  lockplane apply cleanup.json --target-environment production --allow-destructive`,
+	Args: cobra.NoArgs,
+	Run:  runCleanupTombstones,
+}
+
+var (
+	cleanupTarget    string
+	cleanupTargetEnv string
+	cleanupOlderThan string
+)
+
+func init() {
+	cleanupTombstonesCmd.Flags().StringVar(&cleanupTarget, "target", "", "Target database URL (overrides --target-environment)")
+	cleanupTombstonesCmd.Flags().StringVar(&cleanupTargetEnv, "target-environment", "", "Environment providing the target database")
+	cleanupTombstonesCmd.Flags().StringVar(&cleanupOlderThan, "older-than", "30d", "Drop tombstones soft-dropped at least this long ago (e.g. 30d, 720h)")
+	rootCmd.AddCommand(cleanupTombstonesCmd)
+}
+
+func runCleanupTombstones(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	age, err := parseAge(cleanupOlderThan)
+	if err != nil {
+		fmt.Fprintf(style.Stderr, "Error: invalid --older-than: %v\n", err)
+		exit(1)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf(exitError, "Failed to load config: %v", err)
+	}
+	resolvedTarget, err := config.ResolveEnvironment(cfg, cleanupTargetEnv)
+	if err != nil {
+		fatalf(exitError, "Failed to resolve target environment: %v", err)
+	}
+
+	targetConnStr := strings.TrimSpace(cleanupTarget)
+	if targetConnStr == "" {
+		targetConnStr = resolvedTarget.DatabaseURL
+	}
+	if targetConnStr == "" {
+		fmt.Fprintf(style.Stderr, "Error: no target database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
+		exit(1)
+	}
+
+	_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Introspecting target database (%s)...\n", resolvedTarget.Name)
+	current, err := executor.LoadSchemaFromConnectionStringContext(ctx, targetConnStr, nil)
+	if err != nil {
+		fatalf(exitConnectionError, "Failed to introspect target database: %v", err)
+	}
+	driver, err := executor.NewDriver(executor.DetectDriver(targetConnStr))
+	if err != nil {
+		fatalf(exitError, "Failed to create database driver: %v", err)
+	}
+
+	// Tombstones are dated by day, so the cutoff is too
+	cutoff := time.Now().UTC().Add(-age).Truncate(24 * time.Hour)
+	plan, err := planner.GenerateTombstoneCleanupPlan(current, cutoff, driver)
+	if err != nil {
+		fatalf(exitError, "Failed to generate plan: %v", err)
+	}
+
+	if len(plan.Steps) == 0 {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ No tombstone columns soft-dropped before %s\n", cutoff.Format("2006-01-02"))
+	}
+	for _, step := range plan.Steps {
+		if step.Operation == nil {
+			continue
+		}
+		original, date, _ := schema.ParseTombstoneColumnName(step.Operation.Column)
+		fmt.Fprintf(style.Stderr, "  %s.%s (column %s, soft-dropped %s)\n",
+			step.Operation.Table, step.Operation.Column, original, date.Format("2006-01-02"))
+	}
+
+	jsonBytes, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
+	}
+	fmt.Println(string(jsonBytes))
+}
+
+// parseAge parses a duration that may also be given in whole days, like "30d"
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a number of days", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if age < 0 {
+		return 0, fmt.Errorf("%q is negative", value)
+	}
+	return age, nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/internal/config"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		"0d":   0,
+		"720h": 720 * time.Hour,
+		"90m":  90 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := parseAge(value)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "d", "thirty", "-1d", "-5h", "1.5d"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("Expected parseAge(%q) to fail", value)
+		}
+	}
+}
+
+func TestSoftDropColumns(t *testing.T) {
+	cfg := &config.Config{ColumnDropStrategy: config.ColumnDropSoft}
+	if !softDropColumns(cfg, nil) {
+		t.Error("Expected lockplane.toml to enable soft drops without an environment")
+	}
+	if softDropColumns(cfg, &config.ResolvedEnvironment{ColumnDropStrategy: config.ColumnDropHard}) {
+		t.Error("Expected the environment's strategy to win")
+	}
+	if softDropColumns(nil, nil) {
+		t.Error("Expected columns to be dropped without a config")
+	}
+}
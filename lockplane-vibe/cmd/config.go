@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/dburl"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect lockplane configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration of an environment",
+	Long: `Print the configuration lockplane uses for an environment, after merging the
+top-level lockplane.toml settings, the environment's [environments.<name>]
+section and its .env.<name> file.
+
+Passwords and auth tokens in connection strings are replaced with xxxxx.`,
+	Example: `  # Show the default environment
+  lockplane config print
+
+  # Show another environment as JSON
+  lockplane config print --environment production --format json`,
+	Args: cobra.NoArgs,
+	Run:  runConfigPrint,
+}
+
+var (
+	configPrintEnv    string
+	configPrintFormat string
+)
+
+func init() {
+	configPrintCmd.Flags().StringVar(&configPrintEnv, "environment", "", "Environment to print (default: default_environment)")
+	configPrintCmd.Flags().StringVar(&configPrintFormat, "format", "text", "Output format: text or json")
+
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// effectiveConfig is the resolved configuration of an environment as printed
+// by config print
+type effectiveConfig struct {
+	ConfigFile        string            `json:"config_file,omitempty"`
+	Environment       string            `json:"environment"`
+	DotenvFile        string            `json:"dotenv_file,omitempty"`
+	DotenvFiles       []string          `json:"dotenv_files,omitempty"`
+	Overrides         []string          `json:"overrides,omitempty"`
+	SecretSource      string            `json:"secret_source"`
+	SchemaPath        string            `json:"schema_path,omitempty"`
+	Dialect           string            `json:"dialect,omitempty"`
+	Schemas           []string          `json:"schemas,omitempty"`
+	DatabaseURL       string            `json:"database_url"`
+	ShadowDatabaseURL string            `json:"shadow_database_url,omitempty"`
+	ShadowSchema      string            `json:"shadow_schema,omitempty"`
+	ShadowSchemaRun   effectiveRunInfo  `json:"shadow_schema_run"`
+	AllowDestructive  bool              `json:"allow_destructive"`
+	ExcludeTables     []string          `json:"exclude_tables,omitempty"`
+	Variables         map[string]string `json:"variables,omitempty"`
+	StrictVariables   bool              `json:"strict_variables"`
+	ApplyWindow       string            `json:"apply_window,omitempty"`
+	PreApplyCheck     string            `json:"pre_apply_check,omitempty"`
+	Warnings          []string          `json:"warnings,omitempty"`
+}
+
+type effectiveRunInfo struct {
+	Enabled  bool   `json:"enabled"`
+	Template string `json:"template,omitempty"`
+	TTL      string `json:"ttl,omitempty"`
+}
+
+func runConfigPrint(cmd *cobra.Command, args []string) {
+	if configPrintFormat != "text" && configPrintFormat != "json" {
+		fmt.Fprintf(style.Stderr, "Error: unsupported format %q (use text or json)\n", configPrintFormat)
+		exit(1)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf(exitError, "Failed to load config: %v", err)
+	}
+	env, err := config.ResolveEnvironment(cfg, configPrintEnv)
+	if err != nil {
+		fatalf(exitError, "Failed to resolve environment: %v", err)
+	}
+
+	effective := newEffectiveConfig(cfg, env)
+	if configPrintFormat == "json" {
+		jsonBytes, err := json.MarshalIndent(effective, "", "  ")
+		if err != nil {
+			fatalf(exitError, "Failed to marshal config to JSON: %v", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+	printEffectiveConfig(os.Stdout, effective)
+}
+
+// newEffectiveConfig collects the resolved settings with credentials redacted
+func newEffectiveConfig(cfg *config.Config, env *config.ResolvedEnvironment) effectiveConfig {
+	effective := effectiveConfig{
+		ConfigFile:        cfg.ConfigFile(),
+		Environment:       env.Name,
+		SchemaPath:        env.SchemaPath,
+		Dialect:           env.Dialect,
+		Schemas:           env.Schemas,
+		DatabaseURL:       dburl.Redact(env.DatabaseURL),
+		ShadowDatabaseURL: dburl.Redact(env.ShadowDatabaseURL),
+		ShadowSchema:      env.ShadowSchema,
+		ShadowSchemaRun: effectiveRunInfo{
+			Enabled:  env.ShadowSchemaRun.Enabled,
+			Template: env.ShadowSchemaRun.Template,
+		},
+		AllowDestructive: env.AllowDestructive,
+		ExcludeTables:    env.ExcludeTables,
+		Variables:        env.Variables,
+		StrictVariables:  env.StrictVariables,
+		PreApplyCheck:    env.PreApplyCheck,
+		Overrides:        env.Overrides,
+		SecretSource:     env.SecretSource,
+		Warnings:         env.Warnings,
+	}
+	if env.FromDotenv {
+		effective.DotenvFile = env.DotenvPath
+	}
+	effective.DotenvFiles = env.DotenvFiles
+	if env.ApplyWindow != nil {
+		effective.ApplyWindow = env.ApplyWindow.String()
+	}
+	if env.ShadowSchemaRun.TTL > 0 {
+		effective.ShadowSchemaRun.TTL = env.ShadowSchemaRun.TTL.String()
+	}
+	return effective
+}
+
+// printEffectiveConfig writes the configuration as aligned key/value lines.
+// Warnings are left out; ResolveEnvironment already printed them to stderr.
+func printEffectiveConfig(w io.Writer, c effectiveConfig) {
+	orNone := func(value string) string {
+		if value == "" {
+			return "(none)"
+		}
+		return value
+	}
+	list := func(values []string) string {
+		if len(values) == 0 {
+			return "(none)"
+		}
+		return strings.Join(values, ", ")
+	}
+
+	rows := [][2]string{
+		{"config_file", orNone(c.ConfigFile)},
+		{"environment", c.Environment},
+		{"dotenv_files", list(c.DotenvFiles)},
+	}
+	if len(c.Overrides) > 0 {
+		rows = append(rows, [2]string{"overrides", list(c.Overrides)})
+	}
+	rows = append(rows, [][2]string{
+		{"secret_source", c.SecretSource},
+		{"schema_path", orNone(c.SchemaPath)},
+		{"dialect", orNone(c.Dialect)},
+		{"schemas", list(c.Schemas)},
+		{"database_url", orNone(c.DatabaseURL)},
+		{"shadow_database_url", orNone(c.ShadowDatabaseURL)},
+		{"shadow_schema", orNone(c.ShadowSchema)},
+		{"shadow_schema_run", fmt.Sprintf("%t", c.ShadowSchemaRun.Enabled)},
+	}...)
+	if c.ShadowSchemaRun.Template != "" {
+		rows = append(rows, [2]string{"shadow_schema_run.template", c.ShadowSchemaRun.Template})
+	}
+	if c.ShadowSchemaRun.TTL != "" {
+		rows = append(rows, [2]string{"shadow_schema_run.ttl", c.ShadowSchemaRun.TTL})
+	}
+	rows = append(rows,
+		[2]string{"allow_destructive", fmt.Sprintf("%t", c.AllowDestructive)},
+		[2]string{"exclude_tables", list(c.ExcludeTables)},
+		[2]string{"strict_variables", fmt.Sprintf("%t", c.StrictVariables)},
+		[2]string{"apply_window", orNone(c.ApplyWindow)},
+		[2]string{"pre_apply_check", orNone(c.PreApplyCheck)},
+	)
+
+	keys := make([]string, 0, len(c.Variables))
+	for key := range c.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rows = append(rows, [2]string{"variables." + key, c.Variables[key]})
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row[0]))
+	}
+	for _, row := range rows {
+		_, _ = fmt.Fprintf(w, "%-*s  %s\n", width, row[0], row[1])
+	}
+}
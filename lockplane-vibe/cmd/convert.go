@@ -3,10 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"strings"
 
+	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/database/postgres"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/spf13/cobra"
@@ -58,13 +58,13 @@ func init() {
 func runConvert(cmd *cobra.Command, args []string) {
 	if convertInput == "" {
 		_ = cmd.Usage()
-		log.Fatal("--input is required")
+		fatal("--input is required")
 	}
 
 	// Load the schema
 	loadedSchema, err := schema.LoadSchema(convertInput)
 	if err != nil {
-		log.Fatalf("Failed to load schema: %v", err)
+		fatalf(exitError, "Failed to load schema: %v", err)
 	}
 
 	// Convert to target format
@@ -73,48 +73,15 @@ func runConvert(cmd *cobra.Command, args []string) {
 	case "json":
 		outputData, err = json.MarshalIndent(loadedSchema, "", "  ")
 		if err != nil {
-			log.Fatalf("Failed to marshal JSON: %v", err)
+			fatalf(exitError, "Failed to marshal JSON: %v", err)
 		}
 
 	case "sql":
-		// Generate SQL DDL from schema
-		driver := postgres.NewDriver() // Use PostgreSQL SQL generator
-		var sqlBuilder strings.Builder
-
-		for _, table := range loadedSchema.Tables {
-			sql, _ := driver.CreateTable(table)
-			sqlBuilder.WriteString(sql)
-			sqlBuilder.WriteString(";\n\n")
-
-			// Add indexes
-			for _, idx := range table.Indexes {
-				sql, _ := driver.AddIndex(table.Name, idx)
-				sqlBuilder.WriteString(sql)
-				sqlBuilder.WriteString(";\n")
-			}
-
-			if len(table.Indexes) > 0 {
-				sqlBuilder.WriteString("\n")
-			}
-
-			// Add foreign keys
-			for _, fk := range table.ForeignKeys {
-				sql, _ := driver.AddForeignKey(table.Name, fk)
-				if !strings.HasPrefix(sql, "--") { // Skip comment-only SQL
-					sqlBuilder.WriteString(sql)
-					sqlBuilder.WriteString(";\n")
-				}
-			}
-
-			if len(table.ForeignKeys) > 0 {
-				sqlBuilder.WriteString("\n")
-			}
-		}
-
-		outputData = []byte(sqlBuilder.String())
+		// Generate SQL DDL from schema using the PostgreSQL SQL generator
+		outputData = []byte(schemaSQL(loadedSchema, postgres.NewDriver()))
 
 	default:
-		log.Fatalf("Unsupported output format: %s (use 'json' or 'sql')", convertTo)
+		fatalf(exitError, "Unsupported output format: %s (use 'json' or 'sql')", convertTo)
 	}
 
 	// Write output
@@ -123,8 +90,46 @@ func runConvert(cmd *cobra.Command, args []string) {
 		fmt.Print(string(outputData))
 	} else {
 		if err := os.WriteFile(convertOutput, outputData, 0644); err != nil {
-			log.Fatalf("Failed to write output file: %v", err)
+			fatalf(exitError, "Failed to write output file: %v", err)
 		}
 		fmt.Printf("Converted %s to %s: %s\n", convertInput, convertTo, convertOutput)
 	}
 }
+
+// schemaSQL renders a schema as SQL DDL: each table's CREATE TABLE followed by
+// its indexes and foreign keys
+func schemaSQL(s *database.Schema, driver database.Driver) string {
+	var sqlBuilder strings.Builder
+
+	for _, table := range s.Tables {
+		sql, _ := driver.CreateTable(table)
+		sqlBuilder.WriteString(sql)
+		sqlBuilder.WriteString(";\n\n")
+
+		// Add indexes
+		for _, idx := range table.Indexes {
+			sql, _ := driver.AddIndex(table.Name, idx)
+			sqlBuilder.WriteString(sql)
+			sqlBuilder.WriteString(";\n")
+		}
+
+		if len(table.Indexes) > 0 {
+			sqlBuilder.WriteString("\n")
+		}
+
+		// Add foreign keys
+		for _, fk := range table.ForeignKeys {
+			sql, _ := driver.AddForeignKey(table.Name, fk)
+			if !strings.HasPrefix(sql, "--") { // Skip comment-only SQL
+				sqlBuilder.WriteString(sql)
+				sqlBuilder.WriteString(";\n")
+			}
+		}
+
+		if len(table.ForeignKeys) > 0 {
+			sqlBuilder.WriteString("\n")
+		}
+	}
+
+	return sqlBuilder.String()
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/introspect"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+// Exit codes of plan and apply. CI pipelines depend on them, so existing
+// values must not change; see "Exit codes" in the README.
+const (
+	exitSuccess            = 0 // success, or no changes
+	exitError              = 1 // any other failure
+	exitChangesPresent     = 2 // plan --exit-code: the plan has steps
+	exitValidationFailed   = 3 // the schema or plan failed validation
+	exitConnectionError    = 4 // a database could not be reached or introspected
+	exitDestructiveBlocked = 5 // apply refused to run dangerous steps
+	exitImmutableBlocked   = 6 // the plan alters or drops an immutable table
+)
+
+// beforeExit, when set, runs before exit terminates the process. The root
+// command uses it to end its span and flush traces, which deferred calls
+// cannot do once os.Exit is called.
+var beforeExit func(code int)
+
+// exit terminates the process with code after running beforeExit. Commands
+// call it instead of os.Exit.
+func exit(code int) {
+	if hook := beforeExit; hook != nil {
+		beforeExit = nil
+		hook(code)
+	}
+	os.Exit(code)
+}
+
+// fatal logs like log.Fatal and exits with exitError
+func fatal(args ...any) {
+	log.Print(args...)
+	exit(exitError)
+}
+
+// fatalf logs like log.Fatalf and exits with code
+func fatalf(code int, format string, args ...any) {
+	log.Printf(format, args...)
+	exit(code)
+}
+
+// loadErrorExitCode classifies a failure to load a schema from input, which
+// is introspected when it is a connection string
+func loadErrorExitCode(input string) int {
+	if introspect.IsConnectionString(input) {
+		return exitConnectionError
+	}
+	return exitError
+}
+
+// validationExitCode classifies failed validation results: changes to
+// immutable tables have their own code
+func validationExitCode(results []validation.ValidationResult) int {
+	if validation.HasImmutableViolations(results) {
+		return exitImmutableBlocked
+	}
+	return exitValidationFailed
+}
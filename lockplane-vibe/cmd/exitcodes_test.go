@@ -0,0 +1,39 @@
+package cmd
+
+import "testing"
+
+// The exit codes are documented for CI pipelines and must not change
+func TestExitCodeContract(t *testing.T) {
+	codes := map[string]int{
+		"success":             exitSuccess,
+		"error":               exitError,
+		"changes present":     exitChangesPresent,
+		"validation failed":   exitValidationFailed,
+		"connection error":    exitConnectionError,
+		"destructive blocked": exitDestructiveBlocked,
+		"immutable blocked":   exitImmutableBlocked,
+	}
+	want := map[string]int{
+		"success":             0,
+		"error":               1,
+		"changes present":     2,
+		"validation failed":   3,
+		"connection error":    4,
+		"destructive blocked": 5,
+		"immutable blocked":   6,
+	}
+	for name, code := range want {
+		if codes[name] != code {
+			t.Errorf("exit code for %s = %d; want %d", name, codes[name], code)
+		}
+	}
+}
+
+func TestLoadErrorExitCode(t *testing.T) {
+	if got := loadErrorExitCode("postgres://localhost:5432/app"); got != exitConnectionError {
+		t.Errorf("expected connection error for a connection string, got %d", got)
+	}
+	if got := loadErrorExitCode("schema/"); got != exitError {
+		t.Errorf("expected generic error for a schema path, got %d", got)
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/graph"
+	"github.com/lockplane/lockplane/internal/introspect"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/spf13/cobra"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [schema-dir|connection-string]",
+	Short: "Draw an entity-relationship diagram of a schema",
+	Long: `Draw an entity-relationship diagram of tables, columns and foreign keys.
+
+The schema can come from:
+  1. A schema file, directory or connection string argument
+  2. --source-environment (introspects that environment's database)
+  3. An auto-detected schema directory (schema/ or supabase/schema/)
+
+Primary key columns are bold (dot) or marked PK (mermaid). Foreign key edges
+are labeled with their ON DELETE behavior; nullable foreign keys are drawn as
+optional relationships. Output is sorted so diagrams can be committed and diffed.`,
+	Example: `  # Graphviz diagram of the schema directory
+  lockplane graph --format dot schema/ | dot -Tsvg > schema.svg
+
+  # Mermaid diagram for a GitHub README
+  lockplane graph --format mermaid > docs/schema.mmd
+
+  # Only the billing tables of the production database
+  lockplane graph --source-environment production --tables 'billing_*,invoices'`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runGraph,
+}
+
+var (
+	graphFormat    string
+	graphTables    []string
+	graphSourceEnv string
+	graphVerbose   bool
+)
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVar(&graphFormat, "format", graph.FormatDot, "Output format: dot or mermaid")
+	graphCmd.Flags().StringSliceVar(&graphTables, "tables", nil, "Only draw tables matching these glob patterns (e.g. 'users,billing_*')")
+	graphCmd.Flags().StringVar(&graphSourceEnv, "source-environment", "", "Named environment whose database to draw")
+	graphCmd.Flags().BoolVarP(&graphVerbose, "verbose", "v", false, "Enable verbose logging")
+}
+
+func runGraph(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf(exitError, "Failed to load config file: %v", err)
+	}
+
+	input := ""
+	if len(args) > 0 {
+		input = strings.TrimSpace(args[0])
+	}
+
+	var resolvedEnv *config.ResolvedEnvironment
+	if input == "" && graphSourceEnv != "" {
+		resolvedEnv, err = config.ResolveEnvironment(cfg, graphSourceEnv)
+		if err != nil {
+			fatalf(exitError, "Failed to resolve source environment: %v", err)
+		}
+		input = resolvedEnv.DatabaseURL
+	}
+	if input == "" {
+		if detectedPath, label := detectDefaultSchemaDir(); detectedPath != "" {
+			input = detectedPath
+			if graphVerbose {
+				fmt.Fprintf(style.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
+			}
+		}
+	}
+	if input == "" {
+		fmt.Fprintf(style.Stderr, "Error: No schema specified.\n\n")
+		fmt.Fprintf(style.Stderr, "Usage: lockplane graph <schema-dir|connection-string>\n")
+		fmt.Fprintf(style.Stderr, "   Or: lockplane graph --source-environment <name>\n\n")
+		exit(1)
+	}
+
+	if resolvedEnv == nil {
+		if env, err := config.ResolveEnvironment(cfg, ""); err == nil {
+			resolvedEnv = env
+		}
+	}
+
+	var dialect database.Dialect
+	if resolvedEnv != nil && resolvedEnv.Dialect != "" {
+		dialect = database.Dialect(resolvedEnv.Dialect)
+	} else if introspect.IsConnectionString(input) {
+		dialect = schema.DriverNameToDialect(executor.DetectDriver(input))
+	}
+
+	if graphVerbose {
+		fmt.Fprintf(style.Stderr, "🔍 Loading schema: %s\n", input)
+	}
+	opts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(input, dialect), input, cfg, resolvedEnv)
+	loaded, err := executor.LoadSchemaOrIntrospectWithOptions(input, opts)
+	if err != nil {
+		fatalf(exitError, "Failed to load schema: %v", err)
+	}
+
+	output, err := graph.Render(loaded, graphFormat, graph.Options{Tables: graphTables})
+	if err != nil {
+		fatalf(exitError, "Failed to render graph: %v", err)
+	}
+	fmt.Print(output)
+}
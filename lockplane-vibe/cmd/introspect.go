@@ -1,17 +1,19 @@
 package cmd
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/database/postgres"
 	"github.com/lockplane/lockplane/internal/config"
 	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +32,15 @@ The database can be specified via:
   # Introspect to SQL DDL
   lockplane introspect --format sql > lockplane/schema.lp.sql
 
+  # Only capture the tables you're working on (glob patterns allowed)
+  lockplane introspect --tables 'users,orders,billing_*' > partial.json
+
+  # Include approximate row counts and sizes, and list the largest tables
+  lockplane introspect --with-stats --stats-sort rows > schema.json
+
+  # Introspect some of the configured PostgreSQL schemas
+  lockplane introspect --schemas public,billing > schema.json
+
   # Specify database connection directly
   lockplane introspect --db postgresql://localhost:5432/myapp?sslmode=disable > schema.json
 
@@ -44,6 +55,10 @@ var (
 	introspectSourceEnv string
 	introspectUseShadow bool
 	introspectVerbose   bool
+	introspectTables    []string
+	introspectSchemas   []string
+	introspectWithStats bool
+	introspectStatsSort string
 )
 
 func init() {
@@ -53,6 +68,10 @@ func init() {
 	introspectCmd.Flags().StringVar(&introspectFormat, "format", "json", "Output format: json or sql")
 	introspectCmd.Flags().StringVar(&introspectSourceEnv, "source-environment", "", "Named environment to introspect (defaults to config default)")
 	introspectCmd.Flags().BoolVar(&introspectUseShadow, "shadow", false, "Use the shadow database URL for the selected environment")
+	introspectCmd.Flags().StringSliceVar(&introspectTables, "tables", nil, "Only introspect tables matching these glob patterns (e.g. 'users,billing_*')")
+	introspectCmd.Flags().StringSliceVar(&introspectSchemas, "schemas", nil, "PostgreSQL schemas to introspect (default: the environment's configured schemas, or the current schema)")
+	introspectCmd.Flags().BoolVar(&introspectWithStats, "with-stats", false, "Record approximate row counts and sizes of each table under \"stats\" and list the largest tables on stderr (PostgreSQL only; never hashed or diffed)")
+	introspectCmd.Flags().StringVar(&introspectStatsSort, "stats-sort", statsSortSize, "Order of the --with-stats table: size, rows or indexes")
 	introspectCmd.Flags().BoolVarP(&introspectVerbose, "verbose", "v", false, "Enable verbose logging")
 }
 
@@ -60,7 +79,10 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 	// Load config file (if it exists)
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config file: %v", err)
+		fatalf(exitError, "Failed to load config file: %v", err)
+	}
+	if _, err := statsSortKey(introspectStatsSort); err != nil {
+		fatalf(exitError, "%v", err)
 	}
 
 	connStr := strings.TrimSpace(introspectDB)
@@ -74,19 +96,19 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 				envName = "local"
 			}
 			if introspectVerbose {
-				fmt.Fprintf(os.Stderr, "ℹ️  Using default environment: %s\n", envName)
+				fmt.Fprintf(style.Stderr, "ℹ️  Using default environment: %s\n", envName)
 			}
 		}
 
 		resolvedEnv, err = config.ResolveEnvironment(cfg, envName)
 		if err != nil {
-			log.Fatalf("Failed to resolve source environment: %v", err)
+			fatalf(exitError, "Failed to resolve source environment: %v", err)
 		}
 		connStr = resolvedEnv.DatabaseURL
 		if introspectUseShadow {
 			connStr = resolvedEnv.ShadowDatabaseURL
 			if connStr == "" {
-				log.Fatalf("Environment %q does not define a shadow database URL", resolvedEnv.Name)
+				fatalf(exitError, "Environment %q does not define a shadow database URL", resolvedEnv.Name)
 			}
 		}
 	}
@@ -98,18 +120,18 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 		} else if cfg != nil && cfg.DefaultEnvironment != "" {
 			envName = cfg.DefaultEnvironment
 		}
-		log.Fatalf("No database connection configured. Provide --db or configure environment %q in lockplane.toml / .env.%s.", envName, envName)
+		fatalf(exitError, "No database connection configured. Provide --db or configure environment %q in lockplane.toml / .env.%s.", envName, envName)
 	}
 
 	if introspectVerbose {
-		fmt.Fprintf(os.Stderr, "🔍 Introspecting database: %s\n", connStr)
+		fmt.Fprintf(style.Stderr, "🔍 Introspecting database: %s\n", connStr)
 	}
 
 	// Detect database driver from connection string
 	driverType := executor.DetectDriver(connStr)
 	driver, err := executor.NewDriver(driverType)
 	if err != nil {
-		log.Fatalf("Failed to create database driver: %v", err)
+		fatalf(exitError, "Failed to create database driver: %v", err)
 	}
 
 	// Get the SQL driver name (use detected type, not driver.Name())
@@ -117,18 +139,42 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 
 	db, err := sql.Open(sqlDriverName, connStr)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatalf(exitError, "Failed to connect to database: %v", err)
 	}
 	defer func() { _ = db.Close() }()
 
-	ctx := context.Background()
+	ctx := cmd.Context()
 	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		fatalf(exitError, "Failed to ping database: %v", err)
 	}
 
-	schema, err := driver.IntrospectSchema(ctx, db)
+	schemas := introspectSchemas
+	if !cmd.Flags().Changed("schemas") && resolvedEnv != nil {
+		schemas = resolvedEnv.Schemas
+	}
+	schema, err := executor.IntrospectTables(ctx, db, driver, schemas, introspectTables)
 	if err != nil {
-		log.Fatalf("Failed to introspect schema: %v", err)
+		fatalf(exitError, "Failed to introspect schema: %v", err)
+	}
+	if len(introspectTables) > 0 {
+		if len(schema.Tables) == 0 {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  No tables match %s\n", strings.Join(introspectTables, ", "))
+		} else if introspectVerbose {
+			fmt.Fprintf(style.Stderr, "ℹ️  Introspected %d tables matching %s\n", len(schema.Tables), strings.Join(introspectTables, ", "))
+		}
+	}
+
+	if introspectWithStats {
+		if driverType != "postgres" {
+			fatalf(exitError, "--with-stats reads table statistics from PostgreSQL; %s is not supported", driverType)
+		}
+		stats, err := postgres.NewIntrospector().GetTableStats(ctx, db, schemas)
+		if err != nil {
+			fatalf(exitError, "Failed to read table statistics: %v", err)
+		}
+		attachTableStats(schema, stats)
+		tables, _ := sortTablesByStats(schema, introspectStatsSort)
+		printTableStats(style.Stderr, tables, introspectStatsSort)
 	}
 
 	// Output in requested format
@@ -136,7 +182,7 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 	case "json":
 		jsonBytes, err := json.MarshalIndent(schema, "", "  ")
 		if err != nil {
-			log.Fatalf("Failed to marshal schema to JSON: %v", err)
+			fatalf(exitError, "Failed to marshal schema to JSON: %v", err)
 		}
 		fmt.Println(string(jsonBytes))
 
@@ -145,15 +191,27 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 		// Use PostgreSQL driver for SQL generation (works for most databases)
 		sqlDriver := postgres.NewDriver()
 		var sqlBuilder strings.Builder
+		if len(schema.TableFilter) > 0 {
+			fmt.Fprintf(&sqlBuilder, "-- Partial schema: only tables matching %s\n\n", strings.Join(schema.TableFilter, ", "))
+		}
+
+		// Sequences come first, since column defaults may use them
+		createdSchemas := map[string]bool{}
+		ownedSequences := writeSequencesSQL(&sqlBuilder, sqlDriver, schema, createdSchemas)
 
 		for _, table := range schema.Tables {
+			if table.Schema != "" && table.Schema != database.DefaultSchema && !createdSchemas[table.Schema] {
+				createdSchemas[table.Schema] = true
+				fmt.Fprintf(&sqlBuilder, "CREATE SCHEMA IF NOT EXISTS %s;\n\n", sqlDriver.QuoteIdentifier(table.Schema))
+			}
+
 			sql, _ := sqlDriver.CreateTable(table)
 			sqlBuilder.WriteString(sql)
 			sqlBuilder.WriteString(";\n\n")
 
 			// Add indexes
 			for _, idx := range table.Indexes {
-				sql, _ := sqlDriver.AddIndex(table.Name, idx)
+				sql, _ := sqlDriver.AddIndex(table.QualifiedName(), idx)
 				sqlBuilder.WriteString(sql)
 				sqlBuilder.WriteString(";\n")
 			}
@@ -164,7 +222,7 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 
 			// Add foreign keys
 			for _, fk := range table.ForeignKeys {
-				sql, _ := sqlDriver.AddForeignKey(table.Name, fk)
+				sql, _ := sqlDriver.AddForeignKey(table.QualifiedName(), fk)
 				if !strings.HasPrefix(sql, "--") { // Skip comment-only SQL
 					sqlBuilder.WriteString(sql)
 					sqlBuilder.WriteString(";\n")
@@ -176,9 +234,36 @@ func runIntrospect(cmd *cobra.Command, args []string) {
 			}
 		}
 
+		// Owners are set once the owning columns exist
+		for _, owner := range ownedSequences {
+			fmt.Fprintf(&sqlBuilder, "%s;\n", owner)
+		}
+
 		fmt.Print(sqlBuilder.String())
 
 	default:
-		log.Fatalf("Unsupported format: %s (use 'json' or 'sql')", introspectFormat)
+		fatalf(exitError, "Unsupported format: %s (use 'json' or 'sql')", introspectFormat)
+	}
+}
+
+// writeSequencesSQL writes the CREATE SEQUENCE statements of s, creating the
+// schemas not in createdSchemas first, and returns the statements that set
+// their owners, to write once the tables exist. Sequences of serial columns
+// are created by their column and left out.
+func writeSequencesSQL(b *strings.Builder, driver database.Driver, s *database.Schema, createdSchemas map[string]bool) []string {
+	var owners []string
+	for _, seq := range s.Sequences {
+		if schema.OwnedBySerialColumn(s, seq) {
+			continue
+		}
+		if seq.Schema != "" && seq.Schema != database.DefaultSchema && !createdSchemas[seq.Schema] {
+			createdSchemas[seq.Schema] = true
+			fmt.Fprintf(b, "CREATE SCHEMA IF NOT EXISTS %s;\n\n", driver.QuoteIdentifier(seq.Schema))
+		}
+		fmt.Fprintf(b, "%s;\n\n", planner.CreateSequenceSQL(driver, seq))
+		if owner := planner.SequenceOwnerSQL(driver, seq); owner != "" {
+			owners = append(owners, owner)
+		}
 	}
+	return owners
 }
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// Orders of the introspect --with-stats table (--stats-sort)
+const (
+	statsSortSize    = "size"
+	statsSortRows    = "rows"
+	statsSortIndexes = "indexes"
+)
+
+// maxStatsRows caps the tables listed by introspect --with-stats
+const maxStatsRows = 20
+
+// attachTableStats sets the stats of the tables of schema found in stats
+func attachTableStats(schema *database.Schema, stats map[string]database.TableStats) {
+	for i := range schema.Tables {
+		if s, ok := stats[schema.Tables[i].QualifiedName()]; ok {
+			schema.Tables[i].Stats = &s
+		}
+	}
+}
+
+// statsSortKey returns the value introspect --with-stats orders tables by
+// for sortBy, largest first
+func statsSortKey(sortBy string) (func(s *database.TableStats) int64, error) {
+	switch sortBy {
+	case statsSortSize:
+		return func(s *database.TableStats) int64 { return s.TotalBytes }, nil
+	case statsSortRows:
+		return func(s *database.TableStats) int64 {
+			if s.ApproximateRows == nil {
+				return -1
+			}
+			return *s.ApproximateRows
+		}, nil
+	case statsSortIndexes:
+		return func(s *database.TableStats) int64 { return s.IndexBytes }, nil
+	default:
+		return nil, fmt.Errorf("unsupported --stats-sort %q (use %q, %q or %q)", sortBy, statsSortSize, statsSortRows, statsSortIndexes)
+	}
+}
+
+// sortTablesByStats returns the tables of schema that have stats, largest
+// first by sortBy. Ties are broken by name.
+func sortTablesByStats(schema *database.Schema, sortBy string) ([]database.Table, error) {
+	key, err := statsSortKey(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []database.Table
+	for _, table := range schema.Tables {
+		if table.Stats != nil {
+			tables = append(tables, table)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		a, b := key(tables[i].Stats), key(tables[j].Stats)
+		if a != b {
+			return a > b
+		}
+		return tables[i].QualifiedName() < tables[j].QualifiedName()
+	})
+	return tables, nil
+}
+
+// printTableStats writes the largest tables as a table, at most
+// maxStatsRows of them
+func printTableStats(w io.Writer, tables []database.Table, sortBy string) {
+	_, _ = fmt.Fprintf(w, "📊 Largest tables by %s (approximate; row counts are as of the last ANALYZE)\n", sortBy)
+	if len(tables) == 0 {
+		_, _ = fmt.Fprintf(w, "   No tables\n")
+		return
+	}
+
+	shown := tables[:min(len(tables), maxStatsRows)]
+	width := len("TABLE")
+	for _, table := range shown {
+		width = max(width, len(table.QualifiedName()))
+	}
+	_, _ = fmt.Fprintf(w, "   %-*s  %12s  %10s  %10s\n", width, "TABLE", "ROWS", "TOTAL", "INDEXES")
+	for _, table := range shown {
+		rows := "unknown"
+		if table.Stats.ApproximateRows != nil {
+			rows = "~" + strconv.FormatInt(*table.Stats.ApproximateRows, 10)
+		}
+		_, _ = fmt.Fprintf(w, "   %-*s  %12s  %10s  %10s\n", width, table.QualifiedName(), rows,
+			formatBytes(table.Stats.TotalBytes), formatBytes(table.Stats.IndexBytes))
+	}
+	if hidden := len(tables) - len(shown); hidden > 0 {
+		_, _ = fmt.Fprintf(w, "   ... and %d smaller tables\n", hidden)
+	}
+}
+
+// formatBytes formats a size in bytes for display
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestIntrospectTableStats(t *testing.T) {
+	rows := func(n int64) *int64 { return &n }
+	schema := &database.Schema{Tables: []database.Table{
+		{Name: "users", Schema: "public"},
+		{Name: "events", Schema: "analytics"},
+		{Name: "orders", Schema: "public"},
+		{Name: "unanalyzed", Schema: "public"},
+	}}
+	attachTableStats(schema, map[string]database.TableStats{
+		"users":            {ApproximateRows: rows(5000), TotalBytes: 2 << 20, IndexBytes: 512 << 10},
+		"analytics.events": {ApproximateRows: rows(90000), TotalBytes: 3 << 30, IndexBytes: 100 << 20},
+		"orders":           {ApproximateRows: rows(80000), TotalBytes: 16 << 20, IndexBytes: 200 << 20},
+		"unanalyzed":       {TotalBytes: 8192},
+		"dropped":          {TotalBytes: 1},
+	})
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{statsSortSize, []string{"analytics.events", "orders", "users", "unanalyzed"}},
+		{statsSortRows, []string{"analytics.events", "orders", "users", "unanalyzed"}},
+		{statsSortIndexes, []string{"orders", "analytics.events", "users", "unanalyzed"}},
+	}
+	for _, tt := range tests {
+		tables, err := sortTablesByStats(schema, tt.sortBy)
+		if err != nil {
+			t.Fatalf("sortTablesByStats(%s) returned error: %v", tt.sortBy, err)
+		}
+		var got []string
+		for _, table := range tables {
+			got = append(got, table.QualifiedName())
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("sortTablesByStats(%s) = %v, want %v", tt.sortBy, got, tt.want)
+		}
+	}
+
+	if _, err := sortTablesByStats(schema, "name"); err == nil {
+		t.Error("Expected an error for an unsupported sort")
+	}
+
+	tables, _ := sortTablesByStats(schema, statsSortSize)
+	var out bytes.Buffer
+	printTableStats(&out, tables, statsSortSize)
+	for _, want := range []string{"approximate", "analytics.events", "~90000", "3.0 GiB", "100.0 MiB", "unknown"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the stats table to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/planner/multiphase"
 )
 
 func TestPlanMultiphaseCommand(t *testing.T) {
@@ -47,6 +49,9 @@ func TestPlanMultiphaseCommandFlags(t *testing.T) {
 		"constraint",
 		"source-hash",
 		"archive-data",
+		"schema",
+		"referenced-by",
+		"batch-size",
 	}
 
 	for _, flagName := range requiredFlags {
@@ -61,7 +66,7 @@ func TestPlanMultiphaseCommandFlagTypes(t *testing.T) {
 	flags := planMultiphaseCmd.Flags()
 
 	// Test string flags
-	stringFlags := []string{"pattern", "table", "column", "old-column", "new-column", "type", "old-type", "new-type", "constraint", "source-hash"}
+	stringFlags := []string{"pattern", "table", "column", "old-column", "new-column", "type", "old-type", "new-type", "constraint", "source-hash", "schema"}
 	for _, flagName := range stringFlags {
 		flag := flags.Lookup(flagName)
 		if flag != nil && flag.Value.Type() != "string" {
@@ -79,6 +84,24 @@ func TestPlanMultiphaseCommandFlagTypes(t *testing.T) {
 	}
 }
 
+func TestParseReferencedBy(t *testing.T) {
+	refs, err := parseReferencedBy([]string{"orders.user_id", " comments.author_id "})
+	if err != nil {
+		t.Fatalf("Failed to parse --referenced-by: %v", err)
+	}
+	want := []multiphase.ReferencingColumn{
+		{Table: "orders", Column: "user_id", ForeignKey: "orders_user_id_fkey"},
+		{Table: "comments", Column: "author_id", ForeignKey: "comments_author_id_fkey"},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("expected %+v, got %+v", want, refs)
+	}
+
+	if _, err := parseReferencedBy([]string{"user_id"}); err == nil {
+		t.Error("expected error for entry without a table")
+	}
+}
+
 func TestApplyPhaseCommand(t *testing.T) {
 	if applyPhaseCmd == nil {
 		t.Fatal("applyPhaseCmd should not be nil")
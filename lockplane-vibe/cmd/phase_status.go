@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/lockplane/lockplane/internal/state"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -35,7 +35,7 @@ func runPhaseStatus(cmd *cobra.Command, args []string) {
 	// Load state
 	st, err := state.Load()
 	if err != nil {
-		log.Fatalf("Failed to load state: %v", err)
+		fatalf(exitError, "Failed to load state: %v", err)
 	}
 
 	if st.ActiveMigration == nil {
@@ -49,7 +49,7 @@ func runPhaseStatus(cmd *cobra.Command, args []string) {
 	// Display active migration status
 	m := st.ActiveMigration
 
-	fmt.Printf("📋 Active Multi-Phase Migration\n\n")
+	fmt.Fprintf(style.Stdout, "📋 Active Multi-Phase Migration\n\n")
 	fmt.Printf("ID:          %s\n", m.ID)
 	fmt.Printf("Operation:   %s\n", m.Operation)
 	fmt.Printf("Pattern:     %s\n", m.Pattern)
@@ -76,7 +76,7 @@ func runPhaseStatus(cmd *cobra.Command, args []string) {
 
 	// Next steps
 	if m.CurrentPhase >= m.TotalPhases {
-		fmt.Println("✅ All phases complete!")
+		fmt.Fprintln(style.Stdout, "✅ All phases complete!")
 		fmt.Println("\nTo clean up:")
 		fmt.Printf("  rm %s\n", state.StateFile)
 	} else {
@@ -116,18 +116,18 @@ func getPhaseIcon(m *state.ActiveMigration, phaseNum int) string {
 	// Check if completed
 	for _, completed := range m.PhasesCompleted {
 		if completed == phaseNum {
-			return "✅"
+			return style.Icon("✅", "✓")
 		}
 	}
 
 	// Check if next
 	if phaseNum == m.CurrentPhase+1 {
-		return "▶️"
+		return style.Icon("▶️", "▶")
 	}
 
 	if phaseNum <= m.CurrentPhase {
-		return "✅"
+		return style.Icon("✅", "✓")
 	}
 
-	return "⏸️"
+	return style.Icon("⏸️", "·")
 }
@@ -4,20 +4,30 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
-	"unicode"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/dburl"
 	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/gitref"
 	"github.com/lockplane/lockplane/internal/introspect"
+	schemaparser "github.com/lockplane/lockplane/internal/parser"
 	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/review"
 	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/shadow"
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/lockplane/lockplane/internal/validation"
+	"github.com/mattn/go-isatty"
 	pg_query "github.com/pganalyze/pg_query_go/v6"
 	"github.com/pganalyze/pg_query_go/v6/parser"
 	"github.com/spf13/cobra"
@@ -34,7 +44,13 @@ Schemas can be:
   • Database connection strings (will introspect)
 
 The plan shows all required SQL operations to transform the source schema
-into the target schema.`,
+into the target schema.
+
+With --diff-base <ref>, the source schema is the target schema's files as they
+were at a git revision, so the plan shows the migration implied by the changes
+on the current branch without a live database. Files added or removed since
+the revision are handled; if the schema did not exist at the revision, every
+table is planned as new.`,
 	Example: `  # Generate plan from database to schema file
   lockplane plan --from postgresql://localhost/db --to schema.json > plan.json
 
@@ -44,24 +60,60 @@ into the target schema.`,
   # Use environments from lockplane.toml
   lockplane plan --from-environment production --to schema/ > plan.json
 
+  # Plan the schema changes made on this branch since main
+  lockplane plan --diff-base main --to schema/ > plan.json
+
+  # Fail a CI job when a migration is pending (exit code 2)
+  lockplane plan --from-environment production --to schema/ --exit-code > plan.json
+
   # Validate migration safety
-  lockplane plan --from db.json --to new.json --validate > plan.json`,
+  lockplane plan --from db.json --to new.json --validate > plan.json
+
+  # Review a plan interactively and save the decisions
+  lockplane plan --from db.json --to new.json --review > reviewed.json
+  lockplane plan --review plan.json > reviewed.json`,
 	Run: runPlan,
 }
 
 var (
-	planFrom            string
-	planTo              string
-	planFromEnvironment string
-	planToEnvironment   string
-	planCheckSchema     bool
-	planVerbose         bool
-	planOutput          string
-	planShadowDB        string
-	planShadowSchema    string
-	planCacheDir        string
+	planFrom             string
+	planTo               string
+	planFromEnvironment  string
+	planToEnvironment    string
+	planCheckSchema      bool
+	planVerbose          bool
+	planOutput           string
+	planShadowDB         string
+	planShadowSchema     string
+	planShadowPerRun     bool
+	planShadowReuse      bool
+	planShadowFresh      bool
+	planCacheDir         string
+	planReview           bool
+	planCascade          bool
+	planIdempotent       bool
+	planDiffBase         string
+	planSummaryOnly      bool
+	planExitCode         bool
+	planOnlyChanged      bool
+	planForceDirtyShadow bool
+	planProfile          bool
+	planBackfill         []string
+	planBackfillBatch    int
+	planBackfillPause    time.Duration
+	planAllowImmutable   string
+	planAdvise           bool
+	planSchemas          []string
+	planExplain          bool
+	planIntrospectCache  bool
+	planNoCache          bool
 )
 
+// defaultCacheDir is where --plan-only-changed, --shadow-reuse and the
+// introspection cache keep their caches, relative to the config directory,
+// when --cache-dir is not set
+const defaultCacheDir = ".lockplane-cache"
+
 func init() {
 	rootCmd.AddCommand(planCmd)
 
@@ -71,16 +123,37 @@ func init() {
 	planCmd.Flags().StringVar(&planToEnvironment, "to-environment", "", "Environment providing the target database connection")
 	planCmd.Flags().BoolVar(&planCheckSchema, "check-schema", false, "Check schema files for SQL validity by applying them to a clean shadow database")
 	planCmd.Flags().BoolVarP(&planVerbose, "verbose", "v", false, "Enable verbose logging")
-	planCmd.Flags().StringVar(&planOutput, "output", "", "Output format (default: text, set to 'json' for IDE integration)")
+	planCmd.Flags().StringVar(&planOutput, "output", "", "Output format (default: text, set to 'json' for IDE integration, 'json-full' for the diff, plan steps and diagnostics in one document, 'sarif' for code scanning, or 'patch' for a unified diff of the schemas' SQL)")
 	planCmd.Flags().StringVar(&planShadowDB, "shadow-db", "", "Shadow database URL for validation")
 	planCmd.Flags().StringVar(&planShadowSchema, "shadow-schema", "", "Shadow schema name when reusing an existing database")
-	planCmd.Flags().StringVar(&planCacheDir, "cache-dir", "", "Directory for caching shadow DB state (for incremental validation)")
+	planCmd.Flags().BoolVar(&planShadowPerRun, "shadow-schema-per-run", false, "Validate in a unique shadow schema that is dropped afterwards (PostgreSQL only)")
+	planCmd.Flags().BoolVar(&planShadowReuse, "shadow-reuse", false, "With --check-schema, keep the shadow database between runs and skip cleaning and re-applying it while the schema is unchanged")
+	planCmd.Flags().BoolVar(&planShadowFresh, "shadow-fresh", false, "With --check-schema, clean the shadow database and apply the schema even when shadow_reuse could skip it")
+	planCmd.Flags().StringVar(&planCacheDir, "cache-dir", "", "Directory for caches kept between runs (default: .lockplane-cache next to lockplane.toml)")
+	planCmd.Flags().BoolVar(&planReview, "review", false, "Review the plan step by step in an interactive terminal UI")
+	planCmd.Flags().BoolVar(&planCascade, "cascade", false, "Drop removed tables with CASCADE instead of dropping dependent foreign keys explicitly")
+	planCmd.Flags().BoolVar(&planIdempotent, "idempotent", false, "Guard foreign key and index additions so a partially applied plan can be re-run (PostgreSQL only)")
+	planCmd.Flags().BoolVar(&planExitCode, "exit-code", false, "Exit with code 2 when the plan has changes (0 when there are none)")
+	planCmd.Flags().BoolVar(&planIntrospectCache, "introspection-cache", false, "Reuse the schema introspected from a database by the last run while its catalog fingerprint is unchanged, using the cache directory")
+	planCmd.Flags().BoolVar(&planNoCache, "no-cache", false, "Introspect databases fully, even when introspection_cache is enabled")
+	planCmd.Flags().BoolVar(&planOnlyChanged, "plan-only-changed", false, "Reparse only the schema files that changed since the last run, using per-file hashes in the cache directory")
+	planCmd.Flags().BoolVar(&planForceDirtyShadow, "force-dirty-shadow", false, "Validate even when objects the shadow database cleanup could not drop remain")
+	planCmd.Flags().BoolVar(&planProfile, "profile", false, "With --check-schema, time each statement on the shadow database, capture query plans of data-changing statements, and report the slowest")
+	planCmd.Flags().BoolVar(&planSummaryOnly, "summary-only", false, "With --check-schema, print only the safety report's counts and overall result instead of every operation")
+	planCmd.Flags().StringArrayVar(&planBackfill, "backfill", nil, "Fill existing rows of an added column with a SQL expression, as table.column=expression, so a NOT NULL column without a DEFAULT can be added (repeatable)")
+	planCmd.Flags().IntVar(&planBackfillBatch, "backfill-batch-size", 0, "Run --backfill updates as their own step in batches of this many rows, ordered by the table's primary key and committed one by one (0 runs a single UPDATE)")
+	planCmd.Flags().DurationVar(&planBackfillPause, "backfill-pause", 0, "With --backfill-batch-size, how long to pause between batches")
+	planCmd.Flags().StringVar(&planAllowImmutable, "allow-immutable-change", "", "With --check-schema, allow altering or dropping tables declared immutable; takes the reason")
+	planCmd.Flags().BoolVar(&planAdvise, "advise", false, "Report foreign keys without an index and indexes that are never scanned in the live source database, with SQL for the schema files (advice only; never changes the plan or exit code)")
+	planCmd.Flags().StringSliceVar(&planSchemas, "schemas", nil, "PostgreSQL schemas to plan, narrowing the source environment's configured schemas for this run (e.g. public,billing)")
+	planCmd.Flags().BoolVar(&planExplain, "explain", false, "Annotate each step with the schema difference it carries out, as a reason in the text and JSON output")
+	planCmd.Flags().StringVar(&planDiffBase, "diff-base", "", "Git revision whose copy of the --to schema files is the source schema (e.g. main)")
 }
 
 func runPlan(cmd *cobra.Command, args []string) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config file: %v", err)
+		fatalf(exitError, "Failed to load config file: %v", err)
 	}
 
 	// NEW MODE: plan --validate <schema-dir>
@@ -88,25 +161,102 @@ func runPlan(cmd *cobra.Command, args []string) {
 	fromInput := strings.TrimSpace(planFrom)
 	toInput := strings.TrimSpace(planTo)
 
+	if planReview {
+		if !isInteractiveTerminal() {
+			fmt.Fprintf(style.Stderr, "Error: --review requires an interactive terminal.\n")
+			fmt.Fprintf(style.Stderr, "Use --output json (or drop --review) when running non-interactively.\n")
+			exit(1)
+		}
+
+		// plan --review <plan.json>: review an existing plan file
+		if len(args) == 1 && fromInput == "" && toInput == "" && planFromEnvironment == "" && planToEnvironment == "" {
+			plan, err := planner.LoadJSONPlan(args[0])
+			if err != nil {
+				fatalf(exitError, "Failed to load plan: %v", err)
+			}
+			runPlanReview(plan, nil)
+			return
+		}
+	}
+
 	if planCheckSchema && fromInput == "" && toInput == "" && planFromEnvironment == "" && planToEnvironment == "" {
+		if isFullJSONOutput() || isPatchOutput() {
+			fmt.Fprintf(style.Stderr, "Error: --output %s describes a diff; run it without --check-schema (or use --output json).\n", planOutput)
+			exit(1)
+		}
+		if planAdvise {
+			fmt.Fprintf(style.Stderr, "Error: %s\n", adviseNeedsLiveSource)
+			exit(1)
+		}
+		if planShadowReuse && planShadowFresh {
+			fmt.Fprintf(style.Stderr, "Error: --shadow-reuse and --shadow-fresh contradict each other; pass one.\n")
+			exit(1)
+		}
+		if planIntrospectCache {
+			fmt.Fprintf(style.Stderr, "Error: --introspection-cache caches the schema of a source or target database; validating schema files alone introspects none.\n")
+			exit(1)
+		}
 		// This is the new shadow DB validation mode
-		runShadowDBValidation(cfg, args)
+		runShadowDBValidation(cmd.Context(), cfg, args)
 		return
 	}
 
+	if planProfile {
+		fmt.Fprintf(style.Stderr, "Error: --profile times the statements of a shadow database check; use it with --check-schema and a schema directory.\n")
+		exit(1)
+	}
+
+	if planShadowReuse || planShadowFresh {
+		fmt.Fprintf(style.Stderr, "Error: --shadow-reuse and --shadow-fresh choose how --check-schema prepares the shadow database; use them with --check-schema and a schema directory.\n")
+		exit(1)
+	}
+
+	if planIntrospectCache && planNoCache {
+		fmt.Fprintf(style.Stderr, "Error: --introspection-cache and --no-cache contradict each other; pass one.\n")
+		exit(1)
+	}
+
+	if planAdvise && (planDiffBase != "" || fromInput != "" && !introspect.IsConnectionString(fromInput)) {
+		fmt.Fprintf(style.Stderr, "Error: %s\n", adviseNeedsLiveSource)
+		exit(1)
+	}
+
+	if planSummaryOnly && !planCheckSchema {
+		fmt.Fprintf(style.Stderr, "Error: --summary-only shortens the safety report of --check-schema; add --check-schema.\n")
+		exit(1)
+	}
+
+	if planReview && isPatchOutput() {
+		fmt.Fprintf(style.Stderr, "Error: --output patch shows the schema diff without a plan; it cannot be combined with --review.\n")
+		exit(1)
+	}
+
+	backfill, err := planner.ParseBackfills(planBackfill)
+	if err != nil {
+		fmt.Fprintf(style.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	checkBackfillBatchFlags(planBackfillBatch, planBackfillPause)
+	immutableReason := immutableOverrideReason(cmd.Flags().Changed("allow-immutable-change"), planAllowImmutable)
+
+	if planDiffBase != "" && (fromInput != "" || planFromEnvironment != "") {
+		fmt.Fprintf(style.Stderr, "Error: --diff-base cannot be combined with --from or --from-environment.\n")
+		exit(1)
+	}
+
 	// Resolve environments and track them for dialect information
 	var resolvedFrom, resolvedTo *config.ResolvedEnvironment
 
-	if fromInput == "" {
+	if fromInput == "" && planDiffBase == "" {
 		var err error
 		resolvedFrom, err = config.ResolveEnvironment(cfg, planFromEnvironment)
 		if err != nil {
-			log.Fatalf("Failed to resolve source environment: %v", err)
+			fatalf(exitError, "Failed to resolve source environment: %v", err)
 		}
 		fromInput = resolvedFrom.DatabaseURL
 		if fromInput == "" {
-			fmt.Fprintf(os.Stderr, "Error: environment %q does not define a source database. Provide --from or configure .env.%s.\n", resolvedFrom.Name, resolvedFrom.Name)
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Error: environment %q does not define a source database. Provide --from or configure .env.%s.\n", resolvedFrom.Name, resolvedFrom.Name)
+			exit(1)
 		}
 	}
 
@@ -115,29 +265,47 @@ func runPlan(cmd *cobra.Command, args []string) {
 		if detectedPath, label := detectDefaultSchemaDir(); detectedPath != "" {
 			toInput = detectedPath
 			if planVerbose {
-				fmt.Fprintf(os.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
+				fmt.Fprintf(style.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
 			}
-			// Resolve environment to get dialect for schema directory
-			if env, err := config.ResolveEnvironment(cfg, planToEnvironment); err == nil {
-				resolvedTo = env
+			// Resolve environment to get dialect for schema directory. Without
+			// --to-environment the files are loaded for the source
+			// environment's dialect instead of the default environment's.
+			if planToEnvironment != "" || resolvedFrom == nil {
+				if env, err := config.ResolveEnvironment(cfg, planToEnvironment); err == nil {
+					resolvedTo = env
+				}
 			}
 		} else {
 			// Fall back to environment resolution
 			var err error
 			resolvedTo, err = config.ResolveEnvironment(cfg, planToEnvironment)
 			if err != nil {
-				log.Fatalf("Failed to resolve target environment: %v", err)
+				fatalf(exitError, "Failed to resolve target environment: %v", err)
 			}
 			toInput = resolvedTo.DatabaseURL
 			if toInput == "" {
-				fmt.Fprintf(os.Stderr, "Error: environment %q does not define a target database. Provide --to or configure .env.%s.\n", resolvedTo.Name, resolvedTo.Name)
-				os.Exit(1)
+				fmt.Fprintf(style.Stderr, "Error: environment %q does not define a target database. Provide --to or configure .env.%s.\n", resolvedTo.Name, resolvedTo.Name)
+				exit(1)
 			}
 		}
 	}
 
+	// --diff-base: the source schema is the target schema files at a git revision
+	var baseSnapshot *gitref.Snapshot
+	if planDiffBase != "" {
+		if introspect.IsConnectionString(toInput) {
+			fmt.Fprintf(style.Stderr, "Error: --diff-base needs schema files as the target, not a database connection.\n")
+			exit(1)
+		}
+		baseSnapshot, err = gitref.Export(planDiffBase, toInput)
+		if err != nil {
+			fatalf(exitError, "Failed to read schema at %s: %v", planDiffBase, err)
+		}
+		fromInput = baseSnapshot.Path
+	}
+
 	if fromInput == "" || toInput == "" {
-		log.Fatalf("Usage: lockplane plan --from <before.json|db> --to <after.json|db> [--validate]\n\n       lockplane plan --from-environment <name> --to <schema.json>\n       lockplane plan --from <schema.json> --to-environment <name>")
+		fatalf(exitError, "Usage: lockplane plan --from <before.json|db> --to <after.json|db> [--validate]\n\n       lockplane plan --from-environment <name> --to <schema.json>\n       lockplane plan --from <schema.json> --to-environment <name>")
 	}
 
 	// Generate diff first
@@ -145,6 +313,13 @@ func runPlan(cmd *cobra.Command, args []string) {
 	var before *database.Schema
 	var after *database.Schema
 
+	// PostgreSQL schemas in scope: the source environment's configured
+	// schemas, unless --schemas narrows them for this run
+	schemas := planSchemas
+	if !cmd.Flags().Changed("schemas") && resolvedFrom != nil {
+		schemas = resolvedFrom.Schemas
+	}
+
 	// Build dialect fallbacks with precedence: config > connection string detection
 	var fromFallback, toFallback database.Dialect
 
@@ -172,64 +347,129 @@ func runPlan(cmd *cobra.Command, args []string) {
 
 	var loadErr error
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "🔍 Loading 'from' schema: %s\n", fromInput)
+		if baseSnapshot != nil {
+			fmt.Fprintf(style.Stderr, "🔍 Loading 'from' schema: %s at %s (%d files)\n", toInput, planDiffBase, len(baseSnapshot.Files))
+		} else {
+			fmt.Fprintf(style.Stderr, "🔍 Loading 'from' schema: %s\n", fromInput)
+		}
+	}
+	if baseSnapshot != nil && len(baseSnapshot.Files) == 0 {
+		// The schema didn't exist at the base revision, so all of it is new
+		before = &database.Schema{Tables: []database.Table{}, Dialect: fromFallback}
+	} else {
+		fromOpts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(fromInput, fromFallback), fromInput, cfg, resolvedFrom, resolvedTo)
+		if baseSnapshot == nil {
+			warnDialectIncompatibilities(fromInput, fromOpts, resolvedFrom, resolvedTo)
+		}
+		before, loadErr = loadSchemaInScope(cmd.Context(), fromInput, fromOpts, schemas, introspectionCacheFor(cfg, resolvedFrom))
+	}
+	if baseSnapshot != nil {
+		_ = baseSnapshot.Close()
 	}
-	before, loadErr = executor.LoadSchemaOrIntrospectWithOptions(fromInput, executor.BuildSchemaLoadOptions(fromInput, fromFallback))
 	if loadErr != nil {
 		if planVerbose {
-			fmt.Fprintf(os.Stderr, "❌ Failed to load from schema\n")
-			fmt.Fprintf(os.Stderr, "   Input: %s\n", fromInput)
-			fmt.Fprintf(os.Stderr, "   isConnectionString: %v\n", introspect.IsConnectionString(fromInput))
-			fmt.Fprintf(os.Stderr, "   Error: %v\n", loadErr)
+			fmt.Fprintf(style.Stderr, "❌ Failed to load from schema\n")
+			fmt.Fprintf(style.Stderr, "   Input: %s\n", fromInput)
+			fmt.Fprintf(style.Stderr, "   isConnectionString: %v\n", introspect.IsConnectionString(fromInput))
+			fmt.Fprintf(style.Stderr, "   Error: %v\n", loadErr)
 		}
-		log.Fatalf("Failed to load from schema: %v", loadErr)
+		fatalf(loadErrorExitCode(fromInput), "Failed to load from schema: %v", loadErr)
 	}
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "✓ Loaded 'from' schema (%d tables)\n", len(before.Tables))
+		fmt.Fprintf(style.Stderr, "✓ Loaded 'from' schema (%d tables)\n", len(before.Tables))
 	}
 
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "🔍 Loading 'to' schema: %s\n", toInput)
+		fmt.Fprintf(style.Stderr, "🔍 Loading 'to' schema: %s\n", toInput)
+	}
+	toOpts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(toInput, toFallback), toInput, cfg, resolvedTo, resolvedFrom)
+	warnDialectIncompatibilities(toInput, toOpts, resolvedTo, resolvedFrom)
+	if planOnlyChanged && !introspect.IsConnectionString(toInput) {
+		after, loadErr = loadChangedSchema(toInput, toOpts, cfg)
+		if loadErr == nil {
+			database.FilterSchemas(after, schemas)
+		}
+	} else {
+		after, loadErr = loadSchemaInScope(cmd.Context(), toInput, toOpts, schemas, introspectionCacheFor(cfg, resolvedTo))
 	}
-	after, loadErr = executor.LoadSchemaOrIntrospectWithOptions(toInput, executor.BuildSchemaLoadOptions(toInput, toFallback))
 	if loadErr != nil {
 		if planVerbose {
-			fmt.Fprintf(os.Stderr, "❌ Failed to load to schema\n")
-			fmt.Fprintf(os.Stderr, "   Input: %s\n", toInput)
-			fmt.Fprintf(os.Stderr, "   isConnectionString: %v\n", introspect.IsConnectionString(toInput))
-			fmt.Fprintf(os.Stderr, "   Error: %v\n", loadErr)
+			fmt.Fprintf(style.Stderr, "❌ Failed to load to schema\n")
+			fmt.Fprintf(style.Stderr, "   Input: %s\n", toInput)
+			fmt.Fprintf(style.Stderr, "   isConnectionString: %v\n", introspect.IsConnectionString(toInput))
+			fmt.Fprintf(style.Stderr, "   Error: %v\n", loadErr)
 		}
-		log.Fatalf("Failed to load to schema: %v", loadErr)
+		fatalf(loadErrorExitCode(toInput), "Failed to load to schema: %v", loadErr)
 	}
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "✓ Loaded 'to' schema (%d tables)\n", len(after.Tables))
-	}
-
-	diff = schema.DiffSchemas(before, after)
-
-	// Validate the diff if requested
-	if planCheckSchema {
-		validationResults := validation.ValidateSchemaDiffWithSchema(diff, after)
+		fmt.Fprintf(style.Stderr, "✓ Loaded 'to' schema (%d tables)\n", len(after.Tables))
+	}
+
+	if planAdvise && before.Dialect != database.DialectPostgres {
+		fmt.Fprintf(style.Stderr, "Error: --advise needs a PostgreSQL source database.\n")
+		exit(1)
+	}
+
+	// Map types into the 'from' dialect so equivalent types don't show as drift
+	after = schema.AlignDialectsWithEquivalents(before, after, resolveTypeMap(cfg), resolveTypeEquivalents(cfg))
+	printPartialSchemaWarning(before, after)
+	softDrop := softDropColumns(cfg, resolvedFrom)
+	diffOpts := resolveDiffOptions(cfg)
+	diffOpts.IgnoreTombstones = softDrop
+	diff = schema.DiffSchemasWithOptions(before, after, diffOpts)
+	validationOpts := validation.ValidationOptions{
+		Cascade:              planCascade,
+		Backfill:             backfill,
+		SoftDropColumns:      softDrop,
+		ImmutableTables:      resolveImmutableTables(cfg, resolvedFrom, toInput, fromInput),
+		AllowImmutableChange: immutableReason,
+		ServerVersion:        targetServerVersion(before, configuredPostgresVersion(cfg, resolvedFrom)),
+	}
+
+	// Validate the diff if requested. SARIF output is the safety report
+	// instead of the plan.
+	var safetySummary *planner.SafetySummary
+	if planCheckSchema || isSARIFOutput() {
+		validationResults := validation.ValidateSchemaDiffWithOptions(diff, before, after, validationOpts)
+		safetySummary = validation.SummarizeSafety(validationResults)
+
+		if isSARIFOutput() {
+			printSARIF(safetyDiagnostics(validationResults))
+			if !validation.AllValid(validationResults) {
+				exit(validationExitCode(validationResults))
+			}
+			return
+		}
 
 		if len(validationResults) > 0 {
-			printValidationReport(validationResults, "=== Migration Safety Report ===")
+			if planSummaryOnly {
+				printValidationSummary(validationResults, "")
+			} else {
+				printValidationReport(validationResults, "=== Migration Safety Report ===")
+			}
 			if !validation.AllValid(validationResults) {
-				fmt.Fprintf(os.Stderr, "❌ Validation FAILED: Some operations are not safe\n\n")
-				os.Exit(1)
+				if !isPatchOutput() && !isFullJSONOutput() {
+					printFailedPlanSummary(diff, safetySummary)
+				}
+				fmt.Fprintf(style.Stderr, "❌ Validation FAILED: Some operations are not safe\n\n")
+				exit(validationExitCode(validationResults))
 			}
 			if validation.HasDangerousOperations(validationResults) {
-				fmt.Fprintf(os.Stderr, "⚠️  WARNING: This migration contains dangerous operations.\n")
-				fmt.Fprintf(os.Stderr, "   Review safer alternatives above before proceeding.\n\n")
+				fmt.Fprintf(style.Stderr, "⚠️  WARNING: This migration contains dangerous operations.\n")
+				fmt.Fprintf(style.Stderr, "   Review safer alternatives above before proceeding.\n\n")
 			}
 			if validation.AllReversible(validationResults) {
-				fmt.Fprintf(os.Stderr, "✓ All operations are reversible\n\n")
+				fmt.Fprintf(style.Stderr, "✓ All operations are reversible\n\n")
 			} else {
-				fmt.Fprintf(os.Stderr, "⚠️  Warning: Some operations are NOT reversible\n")
-				fmt.Fprintf(os.Stderr, "   Data loss may be permanent. Test on shadow DB first.\n\n")
+				fmt.Fprintf(style.Stderr, "⚠️  Warning: Some operations are NOT reversible\n")
+				fmt.Fprintf(style.Stderr, "   Data loss may be permanent. Test on shadow DB first.\n\n")
 			}
 		}
 	}
 
+	// Fail before generating SQL the target server would reject
+	checkServerSupport(diff, before, validationOpts)
+
 	// Detect database driver from target schema (the "to" state)
 	// We generate SQL for the target database type
 	// First check if the schema has a dialect set (from SQL file or JSON)
@@ -243,112 +483,172 @@ func runPlan(cmd *cobra.Command, args []string) {
 	}
 	targetDriver, err := executor.NewDriver(targetDriverType)
 	if err != nil {
-		log.Fatalf("Failed to create database driver: %v", err)
+		fatalf(exitError, "Failed to create database driver: %v", err)
+	}
+
+	if isPatchOutput() {
+		fromLabel := dburl.Redact(fromInput)
+		if planDiffBase != "" {
+			fromLabel = toInput + "@" + planDiffBase
+		}
+		patch := schemaPatch(before, after, targetDriver, fromLabel, dburl.Redact(toInput), diffOpts)
+		if patch == "" {
+			fmt.Fprintf(style.Stderr, "✓ No schema changes\n")
+			return
+		}
+		fmt.Print(patch)
+		if planExitCode {
+			exit(exitChangesPresent)
+		}
+		return
 	}
 
 	// Generate plan with source hash
-	plan, err := planner.GeneratePlanWithHash(diff, before, targetDriver)
+	plan, err := planner.GeneratePlanWithOptions(diff, before, targetDriver, planner.PlanOptions{Cascade: planCascade, Idempotent: planIdempotent, Backfill: backfill, BackfillBatchSize: planBackfillBatch, BackfillPause: planBackfillPause, SoftDropColumns: softDrop})
+	if err != nil {
+		fatalf(exitError, "Failed to generate plan: %v", err)
+	}
+	printColumnOrderWarnings(diff, targetDriver)
+	printStepWarnings(plan)
+	if planExplain {
+		labels := explainLabels{source: schemaInputLabel(fromInput), target: schemaInputLabel(toInput)}
+		if planDiffBase != "" {
+			labels.source = toInput + "@" + planDiffBase
+		}
+		explainPlanSteps(plan, diff, before, after, labels)
+		if !isStructuredOutput() && !planReview {
+			printStepReasons(plan)
+		}
+	}
+
+	// Record the target hash so plans generated in sequence can be merged
+	// later. Soft-dropped columns stay in the database as tombstones.
+	targetHash, err := schema.ComputeSchemaHash(planner.WithTombstones(after, before, plan.Tombstones, targetDriver))
 	if err != nil {
-		log.Fatalf("Failed to generate plan: %v", err)
+		fatalf(exitError, "Failed to compute target schema hash: %v", err)
+	}
+	plan.TargetHash = targetHash
+
+	// The summary is always written so automation can read just it
+	plan.Summary = validation.SummarizeImpact(plan, diff)
+	plan.Summary.Safety = safetySummary
+	if len(plan.Steps) > 0 && !isStructuredOutput() {
+		printImpactSummary(plan.Summary)
+	}
+	if planAdvise {
+		plan.Advisories = loadAdvisories(cmd.Context(), fromInput, before, after, targetDriver)
+		if !isStructuredOutput() {
+			printAdvisories(plan.Advisories)
+		}
+	}
+
+	if planReview {
+		runPlanReview(plan, diff)
+		exitIfChangesPresent(plan)
+		return
+	}
+
+	if isFullJSONOutput() {
+		validationResults := validation.ValidateSchemaDiffWithOptions(diff, before, after, validationOpts)
+		diagnostics := append(safetyDiagnostics(validationResults), stepWarningDiagnostics(plan)...)
+		printFullPlanJSON(newFullPlanOutput(diff, before, after, plan, diagnostics))
+		exitIfChangesPresent(plan)
+		return
 	}
 
 	// Output plan as JSON
 	jsonBytes, err := json.MarshalIndent(plan, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal plan to JSON: %v", err)
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
 	}
 
 	fmt.Println(string(jsonBytes))
+	exitIfChangesPresent(plan)
 }
 
-// SyntaxError represents a SQL syntax error in a specific file
-type SyntaxError struct {
-	File     string
-	Line     int
-	Column   int
-	Message  string
-	Severity string // "error" or "warning"
+// printFailedPlanSummary writes the summary of a plan that failed the safety
+// check to stdout, since no plan is written
+func printFailedPlanSummary(diff *schema.SchemaDiff, safety *planner.SafetySummary) {
+	summary := validation.SummarizeImpact(nil, diff)
+	summary.Safety = safety
+	jsonBytes, err := json.MarshalIndent(struct {
+		Summary *planner.ImpactSummary `json:"summary"`
+	}{summary}, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal summary to JSON: %v", err)
+	}
+	fmt.Println(string(jsonBytes))
 }
 
-type SQLStatement struct {
-	Text      string
-	StartLine int
+// exitIfChangesPresent exits with exitChangesPresent when --exit-code is set
+// and the plan has steps
+func exitIfChangesPresent(plan *planner.Plan) {
+	if planExitCode && len(plan.Steps) > 0 {
+		exit(exitChangesPresent)
+	}
 }
 
-// splitSQLStatements splits SQL text into individual statements.
-// It does a simple split on semicolons, tracking line numbers for each statement.
-func splitSQLStatements(sqlText string) []SQLStatement {
-	var statements []SQLStatement
-	var currentStmt strings.Builder
-	currentLine := 1
-	stmtStartLine := 1
-	inString := false
-	inComment := false
-	var stringDelim rune
-	seenNonWhitespace := false
-
-	for i, ch := range sqlText {
-		// Track newlines
-		if ch == '\n' {
-			currentLine++
-		}
-
-		// Track first non-whitespace character for accurate line numbers
-		if !seenNonWhitespace && !unicode.IsSpace(ch) {
-			stmtStartLine = currentLine
-			seenNonWhitespace = true
-		}
-
-		// Handle string literals
-		if !inComment && (ch == '\'' || ch == '"') {
-			if !inString {
-				inString = true
-				stringDelim = ch
-			} else if ch == stringDelim {
-				// Check for escaped quote
-				if i > 0 && sqlText[i-1] != '\\' {
-					inString = false
-				}
-			}
-		}
-
-		// Handle line comments
-		if !inString && ch == '-' && i+1 < len(sqlText) && sqlText[i+1] == '-' {
-			inComment = true
-		}
-		if inComment && ch == '\n' {
-			inComment = false
-		}
-
-		// Add character to current statement
-		currentStmt.WriteRune(ch)
+// runPlanReview opens the review UI and prints the annotated plan as JSON
+func runPlanReview(plan *planner.Plan, diff *schema.SchemaDiff) {
+	reviewed, err := review.Run(plan, diff)
+	if errors.Is(err, review.ErrCancelled) {
+		fmt.Fprintf(style.Stderr, "Review discarded; no plan written.\n")
+		exit(1)
+	}
+	if err != nil {
+		fatalf(exitError, "Failed to run plan review: %v", err)
+	}
 
-		// Check for statement terminator (semicolon outside strings/comments)
-		if !inString && !inComment && ch == ';' {
-			stmt := currentStmt.String()
-			if strings.TrimSpace(stmt) != "" {
-				statements = append(statements, SQLStatement{
-					Text:      stmt,
-					StartLine: stmtStartLine,
-				})
-			}
-			currentStmt.Reset()
-			seenNonWhitespace = false
+	flagged := 0
+	pending := 0
+	for _, step := range reviewed.Steps {
+		switch step.Review.Decision {
+		case review.DecisionFlagged:
+			flagged++
+		case review.DecisionPending:
+			pending++
 		}
 	}
+	if flagged > 0 {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  %d step(s) flagged for follow-up\n", flagged)
+	}
+	if pending > 0 {
+		fmt.Fprintf(style.Stderr, "ℹ️  %d step(s) not reviewed\n", pending)
+	}
+	if flagged == 0 && pending == 0 {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ All %d steps approved\n", len(reviewed.Steps))
+	}
 
-	// Add any remaining statement
-	if currentStmt.Len() > 0 {
-		stmt := currentStmt.String()
-		if strings.TrimSpace(stmt) != "" {
-			statements = append(statements, SQLStatement{
-				Text:      stmt,
-				StartLine: stmtStartLine,
-			})
-		}
+	jsonBytes, err := json.MarshalIndent(reviewed, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
 	}
 
-	return statements
+	fmt.Println(string(jsonBytes))
+}
+
+// isInteractiveTerminal reports whether stdin and stderr are attached to a terminal
+func isInteractiveTerminal() bool {
+	return isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stderr.Fd())
+}
+
+// SyntaxError represents a SQL syntax error in a specific file
+type SyntaxError struct {
+	File     string
+	Line     int
+	Column   int
+	Message  string
+	Severity string // "error", "warning", or "info"
+	Code     string // Diagnostic code; defaults to syntax_error/schema_warning
+}
+
+// SQLStatement is one statement of a SQL file, see sqlsplit.Split
+type SQLStatement = sqlsplit.Statement
+
+// splitSQLStatements splits SQL text into individual statements on semicolons,
+// tracking the line each statement starts on
+func splitSQLStatements(sqlText string) []SQLStatement {
+	return sqlsplit.Split(sqlText)
 }
 
 // detectTrailingComma checks if a syntax error is caused by a trailing comma
@@ -440,7 +740,7 @@ func detectTrailingComma(sqlText string, errMsg string, cursorPos int, startLine
 
 // preValidateSQLSyntax checks all SQL files for syntax errors before hitting the database.
 // Returns all syntax errors and warnings found across all files.
-func preValidateSQLSyntax(schemaDir string, dialect database.Dialect) []SyntaxError {
+func preValidateSQLSyntax(schemaDir string, dialect database.Dialect, opts *schema.SchemaLoadOptions) []SyntaxError {
 	var errors []SyntaxError
 
 	// Find all .sql files in the schema directory
@@ -450,7 +750,7 @@ func preValidateSQLSyntax(schemaDir string, dialect database.Dialect) []SyntaxEr
 		}
 
 		// Skip directories and non-.sql files
-		if info.IsDir() || !strings.HasSuffix(path, ".sql") {
+		if info.IsDir() || !isSQLFile(path) {
 			return nil
 		}
 
@@ -467,20 +767,63 @@ func preValidateSQLSyntax(schemaDir string, dialect database.Dialect) []SyntaxEr
 			return nil // Continue processing other files
 		}
 
+		// Expand ${name} references; undefined ones are left in place and reported
+		expanded, expandErr := schema.ExpandSchemaFile(path, string(content), opts)
+		undefinedVariables := schema.UndefinedVariables(expandErr)
+		for _, undefined := range undefinedVariables {
+			errors = append(errors, SyntaxError{
+				File:     path,
+				Line:     undefined.Location.Line,
+				Column:   undefined.Location.Column,
+				Message:  fmt.Sprintf("undefined variable ${%s}; define it under [variables] in lockplane.toml", undefined.Name),
+				Severity: "error",
+				Code:     "undefined_variable",
+			})
+		}
+
+		// Diagnostics below are found in the expanded text; report them where
+		// the user wrote them
+		firstDiagnostic := len(errors)
+		defer func() {
+			for i := firstDiagnostic; i < len(errors); i++ {
+				errors[i].Line, errors[i].Column = expanded.OriginalPosition(errors[i].Line, errors[i].Column)
+			}
+		}()
+
 		// Parse the SQL based on dialect
 		if dialect == database.DialectPostgres || dialect == database.DialectUnknown {
 			// Split SQL into individual statements to catch multiple errors
-			// We do a simple split on semicolon + newline to separate statements
-			sqlText := string(content)
+			// Semicolons inside strings, dollar quotes and comments are not split on.
+			// COPY rows and psql meta-commands in a pg_dump script aren't SQL.
+			sqlText := sqlsplit.BlankDumpData(expanded.Text)
 			statements := splitSQLStatements(sqlText)
+			temps := schemaparser.NewTemporaryTables()
 
 			for _, stmt := range statements {
 				stmt.Text = strings.TrimSpace(stmt.Text)
 				if stmt.Text == "" {
 					continue
 				}
+				// The undefined variable is already reported; parsing the
+				// unexpanded reference would only add a confusing syntax error
+				if referencesUndefinedVariable(stmt.Text, undefinedVariables) {
+					continue
+				}
 
 				parseResult, parseErr := pg_query.Parse(stmt.Text)
+				if parseErr == nil {
+					if notes, ignored := dumpStatementDiagnostics(path, stmt, parseResult); ignored {
+						errors = append(errors, notes...)
+						continue
+					}
+					// Temporary tables are left out of the schema, so the
+					// notes on declarative statements don't apply to them
+					if notes, temporary := temporaryTableDiagnostics(path, stmt, parseResult, temps); temporary {
+						errors = append(errors, notes...)
+						continue
+					}
+					errors = append(errors, idempotentClauseDiagnostics(path, stmt, parseResult)...)
+				}
 
 				// Check for ALTER TABLE statements (warn even if parse succeeds)
 				if parseErr == nil && parseResult != nil {
@@ -500,13 +843,7 @@ func preValidateSQLSyntax(schemaDir string, dialect database.Dialect) []SyntaxEr
 								line := stmt.StartLine
 								column := 1
 								if alterPos >= 0 {
-									line = stmt.StartLine + strings.Count(stmt.Text[:alterPos], "\n")
-									lastNewline := strings.LastIndex(stmt.Text[:alterPos], "\n")
-									if lastNewline >= 0 {
-										column = alterPos - lastNewline
-									} else {
-										column = alterPos + 1
-									}
+									line, column = statementPosition(stmt, alterPos)
 								}
 
 								warningMsg := fmt.Sprintf("ALTER TABLE %s detected in schema file. Lockplane treats schema files as declarative (desired end state). The ALTER TABLE will be merged into the CREATE TABLE definition. Recommendation: Use only CREATE TABLE statements with final desired columns.", tableName)
@@ -586,10 +923,203 @@ func preValidateSQLSyntax(schemaDir string, dialect database.Dialect) []SyntaxEr
 	return errors
 }
 
+// idempotentClausePattern finds IF EXISTS / IF NOT EXISTS in a statement
+var idempotentClausePattern = regexp.MustCompile(`(?i)\bIF\s+(NOT\s+)?EXISTS\b`)
+
+// idempotentClauseDiagnostics returns informational notes for IF NOT EXISTS
+// and IF EXISTS clauses on tables, columns, constraints and indexes. Migration
+// scripts need them to be re-runnable; a declarative schema is always diffed
+// against the database, so they can be removed.
+func idempotentClauseDiagnostics(path string, stmt SQLStatement, parsed *pg_query.ParseResult) []SyntaxError {
+	if parsed == nil || !hasIdempotentClause(parsed) {
+		return nil
+	}
+
+	var diagnostics []SyntaxError
+	for _, match := range idempotentClausePattern.FindAllStringSubmatchIndex(stmt.Text, -1) {
+		clause := "IF EXISTS"
+		reason := "Lockplane plans drops by comparing the schema with the database"
+		if match[2] >= 0 {
+			clause = "IF NOT EXISTS"
+			reason = "Lockplane compares the schema with the database and only creates what is missing"
+		}
+		line, column := statementPosition(stmt, match[0])
+		diagnostics = append(diagnostics, SyntaxError{
+			File:     path,
+			Line:     line,
+			Column:   column,
+			Message:  fmt.Sprintf("%s isn't needed in declarative schema files: %s. The statement is read the same way without it.", clause, reason),
+			Severity: "info",
+			Code:     "idempotent_clause",
+		})
+	}
+	return diagnostics
+}
+
+// hasIdempotentClause reports whether a parsed statement uses IF [NOT] EXISTS
+// on an object the schema models
+func hasIdempotentClause(parsed *pg_query.ParseResult) bool {
+	for _, raw := range parsed.Stmts {
+		if raw.Stmt == nil {
+			continue
+		}
+		switch node := raw.Stmt.Node.(type) {
+		case *pg_query.Node_CreateStmt:
+			if node.CreateStmt.IfNotExists {
+				return true
+			}
+		case *pg_query.Node_IndexStmt:
+			if node.IndexStmt.IfNotExists {
+				return true
+			}
+		case *pg_query.Node_DropStmt:
+			if node.DropStmt.MissingOk {
+				return true
+			}
+		case *pg_query.Node_AlterTableStmt:
+			if node.AlterTableStmt.MissingOk {
+				return true
+			}
+			for _, cmd := range node.AlterTableStmt.Cmds {
+				if alterCmd := cmd.GetAlterTableCmd(); alterCmd != nil && alterCmd.MissingOk {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// temporaryTableDiagnostics returns an informational note for each temporary
+// table a statement creates, and reports whether the statement only touches
+// temporary tables. They last for a session, so the schema leaves them out
+// rather than planning them as permanent tables.
+func temporaryTableDiagnostics(path string, stmt SQLStatement, parsed *pg_query.ParseResult, temps *schemaparser.TemporaryTables) ([]SyntaxError, bool) {
+	if parsed == nil || len(parsed.Stmts) == 0 {
+		return nil, false
+	}
+	var diagnostics []SyntaxError
+	for _, raw := range parsed.Stmts {
+		if !temps.Skip(raw.Stmt) {
+			return nil, false
+		}
+		create := raw.Stmt.GetCreateStmt()
+		if create == nil {
+			continue
+		}
+		offset := int(create.Relation.Location) - int(raw.StmtLocation)
+		line, column := stmt.StartLine, 1
+		if offset >= 0 && offset <= len(stmt.Text) {
+			line, column = statementPosition(stmt, offset)
+		}
+		diagnostics = append(diagnostics, SyntaxError{
+			File:     path,
+			Line:     line,
+			Column:   column,
+			Message:  fmt.Sprintf("Temporary table %s is left out of the schema: it only lasts for a session, so Lockplane does not create or compare it. Statements on it are ignored too.", create.Relation.Relname),
+			Severity: "info",
+			Code:     "temporary_table",
+		})
+	}
+	return diagnostics, true
+}
+
+// dumpStatementDiagnostics returns an informational note for each session
+// setting or ownership change in a statement, and reports whether the
+// statement only holds those. pg_dump scripts are full of them; they aren't
+// part of the schema, so the schema ignores them.
+func dumpStatementDiagnostics(path string, stmt SQLStatement, parsed *pg_query.ParseResult) ([]SyntaxError, bool) {
+	if parsed == nil || len(parsed.Stmts) == 0 {
+		return nil, false
+	}
+	var diagnostics []SyntaxError
+	for _, raw := range parsed.Stmts {
+		var message string
+		switch {
+		case schemaparser.IsSessionSetting(raw.Stmt):
+			message = "Session setting is ignored: it only applies to the session that runs the script, so it isn't part of the schema."
+		case schemaparser.IsOwnerChange(raw.Stmt):
+			message = "Ownership change is ignored: Lockplane does not manage object owners."
+		default:
+			return nil, false
+		}
+		line, column := stmt.StartLine, 1
+		if offset := int(raw.StmtLocation); offset <= len(stmt.Text) {
+			line, column = statementPosition(stmt, offset)
+		}
+		diagnostics = append(diagnostics, SyntaxError{
+			File:     path,
+			Line:     line,
+			Column:   column,
+			Message:  message,
+			Severity: "info",
+			Code:     "dump_statement",
+		})
+	}
+	return diagnostics, true
+}
+
+// statementPosition converts a byte offset within a statement to a line and
+// column in its file
+func statementPosition(stmt SQLStatement, offset int) (int, int) {
+	line := stmt.StartLine + strings.Count(stmt.Text[:offset], "\n")
+	if lastNewline := strings.LastIndex(stmt.Text[:offset], "\n"); lastNewline >= 0 {
+		return line, offset - lastNewline
+	}
+	return line, offset + 1
+}
+
+// referencesUndefinedVariable reports whether a statement still contains a
+// reference to one of the undefined variables
+func referencesUndefinedVariable(stmt string, undefined []*schema.UndefinedVariableError) bool {
+	for _, u := range undefined {
+		if strings.Contains(stmt, "${"+u.Name+"}") {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateDefinitionDiagnostics reports tables, indexes and constraints defined
+// more than once across the schema files, at the location of each later definition
+func duplicateDefinitionDiagnostics(schemaDir string, opts *schema.SchemaLoadOptions) []SyntaxError {
+	info, err := os.Stat(schemaDir)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+
+	duplicates, err := schema.FindDuplicateDefinitionsInDir(schemaDir, opts)
+	if err != nil {
+		// Missing or unreadable files are reported by the other checks
+		return nil
+	}
+
+	diagnostics := make([]SyntaxError, 0, len(duplicates))
+	for _, dup := range duplicates {
+		diag := SyntaxError{
+			File:     dup.Duplicate.File,
+			Line:     dup.Duplicate.Line,
+			Column:   dup.Duplicate.Column,
+			Message:  fmt.Sprintf("%s %q is already defined differently at %s", dup.Kind, dup.Name, dup.First),
+			Severity: "error",
+			Code:     "duplicate_definition",
+		}
+		if dup.Identical {
+			diag.Message = fmt.Sprintf("%s %q is already defined identically at %s", dup.Kind, dup.Name, dup.First)
+			if opts != nil && opts.AllowIdenticalDuplicates {
+				diag.Severity = "warning"
+			} else {
+				diag.Message += " (pass --allow-duplicate-identical to keep the first copy)"
+			}
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+	return diagnostics
+}
+
 // runShadowDBValidation validates schema files by applying them to a shadow database.
 // This is the new validation mode: plan --check-schema <schema-dir>
-func runShadowDBValidation(cfg *config.Config, args []string) {
-	ctx := context.Background()
+func runShadowDBValidation(ctx context.Context, cfg *config.Config, args []string) {
 
 	// Step 1: Determine schema directory
 	schemaDir := ""
@@ -602,95 +1132,44 @@ func runShadowDBValidation(cfg *config.Config, args []string) {
 		if detectedPath, label := detectDefaultSchemaDir(); detectedPath != "" {
 			schemaDir = detectedPath
 			if planVerbose {
-				fmt.Fprintf(os.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
+				fmt.Fprintf(style.Stderr, "ℹ️  Auto-detected schema directory: %s\n", label)
 			}
 		}
 	}
 
 	if schemaDir == "" {
-		fmt.Fprintf(os.Stderr, "Error: No schema directory specified.\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: lockplane plan --check-schema <schema-dir>\n")
-		fmt.Fprintf(os.Stderr, "   Or: lockplane plan --check-schema (will auto-detect schema/ directory)\n\n")
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: No schema directory specified.\n\n")
+		fmt.Fprintf(style.Stderr, "Usage: lockplane plan --check-schema <schema-dir>\n")
+		fmt.Fprintf(style.Stderr, "   Or: lockplane plan --check-schema (will auto-detect schema/ directory)\n\n")
+		exit(1)
 	}
 
 	// Step 1.5: Pre-validate SQL syntax (fast fail before connecting to DB)
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "📋 Pre-validating SQL syntax...\n")
+		fmt.Fprintf(style.Stderr, "📋 Pre-validating SQL syntax...\n")
 	}
 
 	// Detect dialect based on connection string (we'll infer it)
 	// For now, use Postgres as the default since that's our primary dialect
 	dialect := database.DialectPostgres
 
-	syntaxDiagnostics := preValidateSQLSyntax(schemaDir, dialect)
+	// Schema files are expanded with the default environment's variables
+	variableOpts := withSchemaFileOptions(nil, schemaDir, cfg)
 
-	// Separate errors from warnings
-	var syntaxErrors []SyntaxError
-	var syntaxWarnings []SyntaxError
-	for _, diag := range syntaxDiagnostics {
-		if diag.Severity == "warning" {
-			syntaxWarnings = append(syntaxWarnings, diag)
-		} else {
-			syntaxErrors = append(syntaxErrors, diag)
-		}
-	}
-
-	// Show warnings in human-readable mode
-	if len(syntaxWarnings) > 0 && !isJSONOutput() {
-		fmt.Fprintf(os.Stderr, "\n")
-		for _, warn := range syntaxWarnings {
-			fmt.Fprintf(os.Stderr, "⚠️  %s:%d:%d: %s\n", warn.File, warn.Line, warn.Column, warn.Message)
-		}
-		fmt.Fprintf(os.Stderr, "\n")
-	}
-
-	// Fail validation only if there are errors (not warnings)
-	if len(syntaxErrors) > 0 {
-		// Report all syntax errors with structured diagnostics
-		syntaxValidationFailure(syntaxDiagnostics)
-	}
+	syntaxDiagnostics := preValidateSQLSyntax(schemaDir, dialect, variableOpts)
+	syntaxDiagnostics = append(syntaxDiagnostics, duplicateDefinitionDiagnostics(schemaDir, variableOpts)...)
+	syntaxWarnings := reportSyntaxDiagnostics(syntaxDiagnostics)
 
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "✓ SQL syntax validation passed\n")
+		fmt.Fprintf(style.Stderr, "✓ SQL syntax validation passed\n")
 	}
 
 	// Step 2: Resolve shadow DB connection
-	shadowConnStr := strings.TrimSpace(planShadowDB)
-	shadowSchema := strings.TrimSpace(planShadowSchema)
-
-	var resolvedShadow *config.ResolvedEnvironment
-	if shadowConnStr == "" || shadowSchema == "" {
-		if env, err := config.ResolveEnvironment(cfg, ""); err == nil {
-			resolvedShadow = env
-			if shadowConnStr == "" {
-				shadowConnStr = env.ShadowDatabaseURL
-			}
-			if shadowSchema == "" {
-				shadowSchema = env.ShadowSchema
-			}
-			if shadowSchema != "" && shadowConnStr == "" {
-				shadowConnStr = env.DatabaseURL
-			}
-		}
-	}
-
-	if shadowConnStr == "" {
-		exampleEnv := "local"
-		if resolvedShadow != nil && resolvedShadow.Name != "" {
-			exampleEnv = resolvedShadow.Name
-		}
-		fmt.Fprintf(os.Stderr, "Error: No shadow database configured.\n\n")
-		fmt.Fprintf(os.Stderr, "Provide shadow DB via:\n")
-		fmt.Fprintf(os.Stderr, "  - --shadow-db flag\n")
-		fmt.Fprintf(os.Stderr, "  - SHADOW_DATABASE_URL or SHADOW_SCHEMA in .env.%s\n", exampleEnv)
-		fmt.Fprintf(os.Stderr, "  - lockplane init (auto-configures shadow DB settings)\n\n")
-		os.Exit(1)
-	}
+	shadowConnStr, shadowSchema, resolvedShadow := resolveShadowTarget(cfg, planShadowDB, planShadowSchema)
 
 	// Step 3: Connect to shadow DB
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "🔗 Connecting to shadow database...\n")
+		fmt.Fprintf(style.Stderr, "🔗 Connecting to shadow database...\n")
 	}
 
 	driverType := executor.DetectDriver(shadowConnStr)
@@ -707,6 +1186,22 @@ func runShadowDBValidation(cfg *config.Config, args []string) {
 		_ = shadowDB.Close()
 	}()
 
+	if err := applyShadowSettings(ctx, shadowDB, driver.Name(), resolvedShadow, planVerbose); err != nil {
+		validationFailure(fmt.Sprintf("Failed to configure shadow database: %v", err), nil)
+	}
+
+	settings := runSchemaSettings(resolvedShadow, planShadowPerRun)
+	perRun := settings.Enabled && driver.SupportsSchemas()
+	reuse := resolveShadowReusePolicy(resolvedShadow, planShadowReuse, planShadowFresh, perRun, planProfile)
+	if perRun {
+		name, err := setupRunSchema(ctx, shadowDB, driver, shadowSchema, settings)
+		if err != nil {
+			validationFailure(fmt.Sprintf("Failed to create shadow schema: %v", err), nil)
+		}
+		defer releaseRunSchema()
+		shadowSchema = name
+	}
+
 	if shadowSchema != "" && driver.SupportsSchemas() {
 		if err := driver.CreateSchema(ctx, shadowDB, shadowSchema); err != nil {
 			validationFailure(fmt.Sprintf("Failed to create shadow schema: %v", err), nil)
@@ -714,30 +1209,70 @@ func runShadowDBValidation(cfg *config.Config, args []string) {
 		if err := driver.SetSchema(ctx, shadowDB, shadowSchema); err != nil {
 			validationFailure(fmt.Sprintf("Failed to set shadow schema: %v", err), nil)
 		}
-		if !isJSONOutput() {
-			fmt.Fprintf(os.Stderr, "ℹ️  Using shadow schema %q for validation\n", shadowSchema)
+		if !isStructuredOutput() {
+			fmt.Fprintf(style.Stderr, "ℹ️  Using shadow schema %q for validation\n", shadowSchema)
 		}
 	}
 
-	// Step 4: Clean shadow DB
+	// Step 4: Load schema files
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "🧹 Cleaning shadow database...\n")
+		fmt.Fprintf(style.Stderr, "📖 Loading schema from %s...\n", schemaDir)
 	}
 
-	if err := executor.CleanupShadowDB(ctx, shadowDB, driver, planVerbose); err != nil {
-		validationFailure(fmt.Sprintf("Failed to clean shadow database: %v", err), nil)
+	dialect = schema.DriverNameToDialect(driverType)
+	opts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(schemaDir, dialect), schemaDir, cfg, resolvedShadow)
+	var desiredSchema *database.Schema
+	if planOnlyChanged && !introspect.IsConnectionString(schemaDir) {
+		desiredSchema, err = loadChangedSchema(schemaDir, opts, cfg)
+	} else {
+		desiredSchema, err = executor.LoadSchemaOrIntrospectWithOptions(schemaDir, opts)
+	}
+	if err != nil {
+		validationFailure(fmt.Sprintf("Failed to load schema: %v", err), nil)
+	}
+
+	// Step 5: Reuse the shadow DB left by the last run if it holds this schema,
+	// or else clean it
+	cacheDir := resolveCacheDir(cfg)
+	reuseTarget := shadow.ReuseTarget(shadowConnStr, shadowSchema)
+	schemaHash := ""
+	if reuse.Record {
+		if schemaHash, err = schema.ComputeSchemaHash(desiredSchema); err != nil {
+			validationFailure(fmt.Sprintf("Failed to hash schema: %v", err), nil)
+		}
+	}
+	if reuse.Reuse {
+		if state, ok := reusableShadowState(ctx, shadowDB, driver, shadowSchema, cacheDir, reuseTarget, schemaHash); ok {
+			if !isStructuredOutput() {
+				fmt.Fprintf(style.Stderr, "♻️  Shadow database already holds this schema (validated %s); skipped cleaning and applying it. Pass --shadow-fresh to validate from scratch.\n",
+					state.ValidatedAt.Local().Format("2006-01-02 15:04"))
+			}
+			validationSuccess(&planner.ExecutionResult{Success: true, StepsApplied: state.StepsApplied}, syntaxWarnings)
+			return
+		}
+	}
+	if reuse.Record {
+		// The shadow DB is about to change, so the recorded state no longer holds
+		if err := shadow.ClearReuseState(cacheDir, reuseTarget); err != nil {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Failed to clear shadow state in %s: %v\n", cacheDir, err)
+		}
 	}
 
-	// Step 5: Load schema files
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "📖 Loading schema from %s...\n", schemaDir)
+		fmt.Fprintf(style.Stderr, "🧹 Cleaning shadow database...\n")
 	}
 
-	dialect = schema.DriverNameToDialect(driverType)
-	opts := executor.BuildSchemaLoadOptions(schemaDir, dialect)
-	desiredSchema, err := executor.LoadSchemaOrIntrospectWithOptions(schemaDir, opts)
-	if err != nil {
-		validationFailure(fmt.Sprintf("Failed to load schema: %v", err), nil)
+	if err := executor.CleanupShadowDB(ctx, shadowDB, driver, planVerbose); err != nil {
+		leftovers, dirty := dirtyShadowDetails(err)
+		if !dirty {
+			validationFailure(fmt.Sprintf("Failed to clean shadow database: %v", err), nil)
+		}
+		if !planForceDirtyShadow {
+			validationFailure("Shadow database is not empty after cleanup; validation would run against leftover objects.\n"+dirtyShadowHint+".", leftovers)
+		}
+		if !isStructuredOutput() {
+			fmt.Fprintf(style.Stderr, "⚠️  Validating against a dirty shadow database (--force-dirty-shadow): %d leftover object(s)\n", len(leftovers))
+		}
 	}
 
 	// Step 6: Generate a plan from empty schema to desired schema
@@ -750,15 +1285,17 @@ func runShadowDBValidation(cfg *config.Config, args []string) {
 	}
 
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "✓ Generated plan with %d steps\n", len(plan.Steps))
+		fmt.Fprintf(style.Stderr, "✓ Generated plan with %d steps\n", len(plan.Steps))
 	}
 
 	// Step 7: Execute plan on shadow DB (this validates the schema)
 	if planVerbose {
-		fmt.Fprintf(os.Stderr, "🧪 Validating schema by applying to shadow database...\n")
+		fmt.Fprintf(style.Stderr, "🧪 Validating schema by applying to shadow database...\n")
 	}
 
-	result, err := executor.ApplyPlan(ctx, shadowDB, plan, nil, emptySchema, driver, planVerbose)
+	result, err := executor.ApplyPlanWithOptions(ctx, shadowDB, plan, nil, emptySchema, driver, planVerbose,
+		executor.ApplyOptions{Profile: planProfile})
+	err = executor.ShadowLimitError(err)
 
 	// Step 8: Output results
 	if err != nil {
@@ -776,9 +1313,87 @@ func runShadowDBValidation(cfg *config.Config, args []string) {
 		validationFailure(fmt.Sprintf("Schema validation failed: %v", err), extras)
 	}
 
+	if reuse.Record {
+		recordShadowState(ctx, shadowDB, driver, shadowSchema, cacheDir, &shadow.ReuseState{
+			Target:       reuseTarget,
+			SchemaHash:   schemaHash,
+			StepsApplied: result.StepsApplied,
+		})
+	}
+
 	validationSuccess(result, syntaxWarnings)
 }
 
+// reportSyntaxDiagnostics shows the warnings and notes among the syntax
+// diagnostics and returns them, or reports every diagnostic and exits when
+// any is an error
+func reportSyntaxDiagnostics(syntaxDiagnostics []SyntaxError) []SyntaxError {
+	// Separate errors from warnings and informational notes
+	var syntaxErrors []SyntaxError
+	var syntaxWarnings []SyntaxError
+	for _, diag := range syntaxDiagnostics {
+		if isErrorDiagnostic(diag) {
+			syntaxErrors = append(syntaxErrors, diag)
+		} else {
+			syntaxWarnings = append(syntaxWarnings, diag)
+		}
+	}
+
+	// Show warnings in human-readable mode
+	if len(syntaxWarnings) > 0 && !isStructuredOutput() {
+		fmt.Fprintf(style.Stderr, "\n")
+		for _, warn := range syntaxWarnings {
+			fmt.Fprintf(style.Stderr, "%s  %s:%d:%d: %s\n", diagnosticIcon(warn), warn.File, warn.Line, warn.Column, warn.Message)
+		}
+		fmt.Fprintf(style.Stderr, "\n")
+	}
+
+	// Fail validation only if there are errors (not warnings or notes)
+	if len(syntaxErrors) > 0 {
+		// Report all syntax errors with structured diagnostics
+		syntaxValidationFailure(syntaxDiagnostics)
+	}
+	return syntaxWarnings
+}
+
+// resolveShadowTarget returns the shadow database URL and schema given by
+// flags, filling in what they leave out from the default environment, and
+// that environment. It exits when no shadow database is configured.
+func resolveShadowTarget(cfg *config.Config, flagURL, flagSchema string) (string, string, *config.ResolvedEnvironment) {
+	shadowConnStr := strings.TrimSpace(flagURL)
+	shadowSchema := strings.TrimSpace(flagSchema)
+
+	var resolvedShadow *config.ResolvedEnvironment
+	if shadowConnStr == "" || shadowSchema == "" {
+		if env, err := config.ResolveEnvironment(cfg, ""); err == nil {
+			resolvedShadow = env
+			if shadowConnStr == "" {
+				shadowConnStr = env.ShadowDatabaseURL
+			}
+			if shadowSchema == "" {
+				shadowSchema = env.ShadowSchema
+			}
+			if shadowSchema != "" && shadowConnStr == "" {
+				shadowConnStr = env.DatabaseURL
+			}
+		}
+	}
+
+	if shadowConnStr == "" {
+		exampleEnv := "local"
+		if resolvedShadow != nil && resolvedShadow.Name != "" {
+			exampleEnv = resolvedShadow.Name
+		}
+		fmt.Fprintf(style.Stderr, "Error: No shadow database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Provide shadow DB via:\n")
+		fmt.Fprintf(style.Stderr, "  - --shadow-db flag\n")
+		fmt.Fprintf(style.Stderr, "  - SHADOW_DATABASE_URL or SHADOW_SCHEMA in .env.%s\n", exampleEnv)
+		fmt.Fprintf(style.Stderr, "  - lockplane init (auto-configures shadow DB settings)\n\n")
+		exit(1)
+	}
+	return shadowConnStr, shadowSchema, resolvedShadow
+}
+
 // RuntimeError represents an error that occurred during plan execution with source location
 type RuntimeError struct {
 	File    string
@@ -843,7 +1458,7 @@ func findEntityInSQLFiles(schemaDir string, entityName string) *SyntaxError {
 	var result *SyntaxError
 
 	_ = filepath.Walk(schemaDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".sql") {
+		if err != nil || info.IsDir() || !isSQLFile(path) {
 			return nil
 		}
 
@@ -854,7 +1469,7 @@ func findEntityInSQLFiles(schemaDir string, entityName string) *SyntaxError {
 
 		// Search for CREATE statements mentioning this entity
 		// Look for patterns like: CREATE INDEX entity_name, CREATE TABLE entity_name, etc.
-		lines := strings.Split(string(content), "\n")
+		lines := strings.Split(sqlsplit.NormalizeLineEndings(string(content)), "\n")
 		for lineNum, line := range lines {
 			upperLine := strings.ToUpper(line)
 			if (strings.Contains(upperLine, "CREATE INDEX") ||
@@ -866,7 +1481,7 @@ func findEntityInSQLFiles(schemaDir string, entityName string) *SyntaxError {
 					Line:   lineNum + 1,
 					Column: strings.Index(line, entityName) + 1,
 				}
-				return filepath.SkipDir // Found it, stop searching
+				return filepath.SkipAll // Found it, stop searching
 			}
 		}
 		return nil
@@ -877,6 +1492,22 @@ func findEntityInSQLFiles(schemaDir string, entityName string) *SyntaxError {
 
 // runtimeValidationFailure outputs structured diagnostics for runtime errors
 func runtimeValidationFailure(errors []RuntimeError) {
+	if isSARIFOutput() {
+		var diagnostics []SyntaxError
+		for _, err := range errors {
+			diagnostics = append(diagnostics, SyntaxError{
+				File:     err.File,
+				Line:     err.Line,
+				Column:   err.Column,
+				Message:  err.Message,
+				Severity: "error",
+				Code:     "runtime_error",
+			})
+		}
+		printSARIF(diagnostics)
+		releaseRunSchema()
+		exit(exitValidationFailed)
+	}
 	if !isJSONOutput() {
 		return // Let the regular error handler take over for non-JSON output
 	}
@@ -902,26 +1533,272 @@ func runtimeValidationFailure(errors []RuntimeError) {
 	}
 	jsonBytes, _ := json.MarshalIndent(output, "", "  ")
 	fmt.Println(string(jsonBytes))
-	os.Exit(1)
+	releaseRunSchema()
+	exit(exitValidationFailed)
+}
+
+// resolveTypeMap combines the built-in cross-dialect type mappings with the
+// [type_map.<dialect>] overrides from lockplane.toml
+func resolveTypeMap(cfg *config.Config) schema.TypeMap {
+	overrides := schema.TypeMap{}
+	if cfg != nil {
+		for dialect, types := range cfg.TypeMap {
+			switch d := database.Dialect(strings.ToLower(dialect)); d {
+			case database.DialectPostgres, database.DialectSQLite:
+				overrides[d] = types
+			default:
+				fatalf(exitError, "Invalid type_map dialect %q in lockplane.toml (expected postgres or sqlite)", dialect)
+			}
+		}
+	}
+	return schema.DefaultTypeMap().Merge(overrides)
+}
+
+// resolveTypeEquivalents combines the built-in cross-dialect type equivalences
+// with the [type_equivalents.<dialect>] overrides from lockplane.toml
+func resolveTypeEquivalents(cfg *config.Config) schema.TypeEquivalents {
+	overrides := schema.TypeEquivalents{}
+	if cfg != nil {
+		for dialect, types := range cfg.TypeEquivalents {
+			switch d := database.Dialect(strings.ToLower(dialect)); d {
+			case database.DialectPostgres, database.DialectSQLite:
+				overrides[d] = types
+			default:
+				fatalf(exitError, "Invalid type_equivalents dialect %q in lockplane.toml (expected postgres or sqlite)", dialect)
+			}
+		}
+	}
+	return schema.DefaultTypeEquivalents().Merge(overrides)
+}
+
+// resolveDiffOptions builds the schema comparison options from lockplane.toml
+func resolveDiffOptions(cfg *config.Config) schema.DiffOptions {
+	if cfg == nil {
+		return schema.DiffOptions{}
+	}
+	return schema.DiffOptions{
+		EnforceColumnOrder:    cfg.EnforceColumnOrder,
+		IgnoreConstraintNames: cfg.IgnoreConstraintNames,
+	}
+}
+
+// softDropColumns reports whether removed columns are renamed to tombstones
+// instead of dropped, from the environment the plan applies to or, without
+// one, lockplane.toml
+func softDropColumns(cfg *config.Config, env *config.ResolvedEnvironment) bool {
+	if env != nil {
+		return env.ColumnDropStrategy == config.ColumnDropSoft
+	}
+	return cfg != nil && cfg.ColumnDropStrategy == config.ColumnDropSoft
+}
+
+// printPartialSchemaWarning warns when only one side of the diff was captured
+// with introspect --tables
+func printPartialSchemaWarning(before, after *database.Schema) {
+	if warning := schema.PartialSchemaWarning(before, after); warning != "" {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  %s\n", warning)
+	}
+}
+
+// printColumnOrderWarnings reports enforced column order changes the plan can't apply
+func printColumnOrderWarnings(diff *schema.SchemaDiff, driver database.Driver) {
+	for _, warning := range planner.ColumnOrderWarnings(diff, driver) {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  %s\n", warning)
+	}
+}
+
+// printStepWarnings reports plan steps with side effects, such as a recreated
+// table losing its owner and grants
+func printStepWarnings(plan *planner.Plan) {
+	for i, step := range plan.Steps {
+		for _, warning := range step.Warnings {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Step %d (%s): %s\n", i+1, step.Description, warning)
+		}
+	}
+}
+
+// withSchemaFileOptions adds the template variables of the first resolved
+// environment to the load options of a schema file or directory, falling back
+// to the default environment, along with the global --allow-duplicate-identical
+// setting
+func withSchemaFileOptions(opts *schema.SchemaLoadOptions, input string, cfg *config.Config, envs ...*config.ResolvedEnvironment) *schema.SchemaLoadOptions {
+	if introspect.IsConnectionString(input) {
+		return opts
+	}
+
+	if opts == nil {
+		opts = &schema.SchemaLoadOptions{}
+	}
+	opts.AllowIdenticalDuplicates = allowDuplicateIdentical
+
+	var env *config.ResolvedEnvironment
+	for _, candidate := range envs {
+		if candidate != nil {
+			env = candidate
+			break
+		}
+	}
+	if env == nil {
+		resolved, err := config.ResolveEnvironment(cfg, "")
+		if err != nil {
+			return opts
+		}
+		env = resolved
+	}
+
+	opts.Variables = env.Variables
+	opts.AllowUndefinedVariables = !env.StrictVariables
+	return opts
+}
+
+// warnDialectIncompatibilities warns about syntax in schema files that the
+// dialect they are loaded with does not support, naming the first environment
+// that configures a dialect
+func warnDialectIncompatibilities(input string, opts *schema.SchemaLoadOptions, envs ...*config.ResolvedEnvironment) {
+	if introspect.IsConnectionString(input) || opts == nil || opts.Dialect == database.DialectUnknown {
+		return
+	}
+
+	var env *config.ResolvedEnvironment
+	for _, candidate := range envs {
+		if candidate != nil && candidate.Dialect != "" {
+			env = candidate
+			break
+		}
+	}
+	if env == nil {
+		return
+	}
+
+	// Load errors are reported when the schema itself is loaded
+	found, err := schema.FindDialectIncompatibilitiesInPath(input, opts)
+	if err != nil {
+		return
+	}
+	for _, incompatibility := range found {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Warning: %s (environment %q uses the %s dialect)\n",
+			incompatibility, env.Name, incompatibility.Dialect)
+	}
+}
+
+// resolveCacheDir returns --cache-dir, or the default cache directory next to
+// lockplane.toml
+func resolveCacheDir(cfg *config.Config) string {
+	if planCacheDir != "" {
+		return planCacheDir
+	}
+	return filepath.Join(cfg.ConfigDir(), defaultCacheDir)
+}
+
+// loadChangedSchema loads schema files for --plan-only-changed, reparsing
+// only the files that changed since the cached run
+func loadChangedSchema(path string, opts *schema.SchemaLoadOptions, cfg *config.Config) (*database.Schema, error) {
+	cacheDir := resolveCacheDir(cfg)
+	loaded, result, err := schema.LoadSchemaIncremental(path, opts, schema.IncrementalOptions{CacheDir: cacheDir, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	if result.CacheErr != nil {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Failed to update schema cache in %s: %v\n", cacheDir, result.CacheErr)
+	}
+	if planVerbose {
+		if result.Incremental {
+			fmt.Fprintf(style.Stderr, "⚡ Parsed %d of %d schema files (%d changed since the cached run)\n",
+				result.ParsedFiles, result.TotalFiles, len(result.ChangedFiles))
+		} else {
+			fmt.Fprintf(style.Stderr, "ℹ️  Parsed all schema files: %s\n", result.FallbackReason)
+		}
+	}
+	return loaded, nil
+}
+
+// useIntrospectionCache reports whether a database of env is introspected
+// through the introspection cache. The cache is opt-in: --introspection-cache
+// (enable) or the environment's introspection_cache turn it on, and
+// --no-cache (disable) overrides both.
+func useIntrospectionCache(env *config.ResolvedEnvironment, enable, disable bool) bool {
+	return !disable && (enable || env != nil && env.IntrospectionCache)
+}
+
+// introspectionCacheFor returns the introspection cache settings for a
+// database of env, or nil when plan introspects it fully
+func introspectionCacheFor(cfg *config.Config, env *config.ResolvedEnvironment) *executor.IntrospectionCacheOptions {
+	if !useIntrospectionCache(env, planIntrospectCache, planNoCache) {
+		return nil
+	}
+	return &executor.IntrospectionCacheOptions{CacheDir: resolveCacheDir(cfg), Version: version}
+}
+
+// loadIntrospectionCached introspects a database through the introspection
+// cache, reporting whether the cached schema was reused when verbose
+func loadIntrospectionCached(ctx context.Context, connStr string, schemas []string, cache *executor.IntrospectionCacheOptions) (*database.Schema, error) {
+	loaded, result, err := executor.LoadSchemaFromConnectionStringCached(ctx, connStr, schemas, *cache)
+	if err != nil {
+		return nil, err
+	}
+	if result.CacheErr != nil {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Failed to update introspection cache in %s: %v\n", cache.CacheDir, result.CacheErr)
+	}
+	if planVerbose {
+		if result.Hit {
+			fmt.Fprintf(style.Stderr, "⚡ Reused the cached schema (database catalog unchanged since the last run)\n")
+		} else {
+			fmt.Fprintf(style.Stderr, "ℹ️  Introspected the database: %s\n", result.FallbackReason)
+		}
+	}
+	return loaded, nil
+}
+
+// loadSchemaInScope loads a schema file or introspects a database like
+// executor.LoadSchemaOrIntrospectWithOptions, limited to the PostgreSQL
+// schemas in scope. Databases are introspected in those schemas, through the
+// introspection cache when cache is set, and files keep only their tables.
+// An empty scope introspects the current schema and keeps every table of a
+// file.
+func loadSchemaInScope(ctx context.Context, input string, opts *schema.SchemaLoadOptions, schemas []string, cache *executor.IntrospectionCacheOptions) (*database.Schema, error) {
+	if introspect.IsConnectionString(input) {
+		if cache != nil {
+			return loadIntrospectionCached(ctx, input, schemas, cache)
+		}
+		return executor.LoadSchemaFromConnectionStringContext(ctx, input, schemas)
+	}
+	loaded, err := schema.LoadSchemaWithOptions(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	database.FilterSchemas(loaded, schemas)
+	return loaded, nil
 }
 
 func isJSONOutput() bool {
 	return strings.EqualFold(strings.TrimSpace(planOutput), "json")
 }
 
+func isSARIFOutput() bool {
+	return strings.EqualFold(strings.TrimSpace(planOutput), "sarif")
+}
+
+// isStructuredOutput reports whether results go to stdout as JSON, SARIF or
+// a patch, so progress messages are left out
+func isStructuredOutput() bool {
+	return isJSONOutput() || isFullJSONOutput() || isSARIFOutput() || isPatchOutput()
+}
+
 func syntaxValidationFailure(syntaxDiagnostics []SyntaxError) {
-	// Separate errors from warnings
+	// Separate errors from warnings and informational notes
 	var errors []SyntaxError
 	var warnings []SyntaxError
 	for _, diag := range syntaxDiagnostics {
-		if diag.Severity == "warning" {
-			warnings = append(warnings, diag)
-		} else {
+		if isErrorDiagnostic(diag) {
 			errors = append(errors, diag)
+		} else {
+			warnings = append(warnings, diag)
 		}
 	}
 
-	if isJSONOutput() {
+	if isSARIFOutput() {
+		printSARIF(syntaxDiagnostics)
+	} else if isJSONOutput() {
 		// Create separate diagnostic for each syntax error/warning with proper file/line/column
 		var diagnostics []map[string]interface{}
 		for _, syntaxDiag := range syntaxDiagnostics {
@@ -929,14 +1806,10 @@ func syntaxValidationFailure(syntaxDiagnostics []SyntaxError) {
 			if severity == "" {
 				severity = "error"
 			}
-			code := "syntax_error"
-			if severity == "warning" {
-				code = "schema_warning"
-			}
 			diagnostics = append(diagnostics, map[string]interface{}{
 				"severity": severity,
 				"message":  syntaxDiag.Message,
-				"code":     code,
+				"code":     diagnosticCode(syntaxDiag),
 				"file":     syntaxDiag.File,
 				"line":     syntaxDiag.Line,
 				"column":   syntaxDiag.Column,
@@ -947,28 +1820,29 @@ func syntaxValidationFailure(syntaxDiagnostics []SyntaxError) {
 			"diagnostics": diagnostics,
 			"summary": map[string]interface{}{
 				"errors":   len(errors),
-				"warnings": len(warnings),
+				"warnings": countSeverity(warnings, "warning"),
+				"info":     countSeverity(warnings, "info"),
 				"valid":    false,
 			},
 		}
 		jsonBytes, _ := json.MarshalIndent(output, "", "  ")
 		fmt.Println(string(jsonBytes))
 	} else {
-		fmt.Fprintf(os.Stderr, "❌ Schema validation FAILED\n\n")
+		fmt.Fprintf(style.Stderr, "❌ Schema validation FAILED\n\n")
 		if len(errors) > 0 {
-			fmt.Fprintf(os.Stderr, "Found %d syntax error(s) in schema files:\n", len(errors))
+			fmt.Fprintf(style.Stderr, "Found %d error(s) in schema files:\n", len(errors))
 			for _, syntaxErr := range errors {
-				fmt.Fprintf(os.Stderr, "  - %s:%d:%d: %s\n", syntaxErr.File, syntaxErr.Line, syntaxErr.Column, syntaxErr.Message)
+				fmt.Fprintf(style.Stderr, "  - %s:%d:%d: %s\n", syntaxErr.File, syntaxErr.Line, syntaxErr.Column, syntaxErr.Message)
 			}
 		}
 		if len(warnings) > 0 {
-			fmt.Fprintf(os.Stderr, "\nWarnings:\n")
+			fmt.Fprintf(style.Stderr, "\nWarnings:\n")
 			for _, warn := range warnings {
-				fmt.Fprintf(os.Stderr, "  ⚠️  %s:%d:%d: %s\n", warn.File, warn.Line, warn.Column, warn.Message)
+				fmt.Fprintf(style.Stderr, "  %s  %s:%d:%d: %s\n", diagnosticIcon(warn), warn.File, warn.Line, warn.Column, warn.Message)
 			}
 		}
 	}
-	os.Exit(1)
+	exit(exitValidationFailed)
 }
 
 func validationFailure(message string, details []string) {
@@ -978,8 +1852,10 @@ func validationFailure(message string, details []string) {
 	}
 
 	// Add helpful debugging context for connection errors
+	code := exitValidationFailed
 	if isConnectionError(mainMsg) {
 		mainMsg = enhanceConnectionError(mainMsg)
+		code = exitConnectionError
 	}
 
 	formatted := mainMsg
@@ -987,7 +1863,9 @@ func validationFailure(message string, details []string) {
 		formatted = fmt.Sprintf("%s\n%s", mainMsg, strings.Join(details, "\n"))
 	}
 
-	if isJSONOutput() {
+	if isSARIFOutput() {
+		printSARIF([]SyntaxError{{Message: formatted, Severity: "error", Code: "validation_error"}})
+	} else if isJSONOutput() {
 		diagnostics := map[string]interface{}{
 			"diagnostics": []map[string]interface{}{
 				{
@@ -1004,13 +1882,14 @@ func validationFailure(message string, details []string) {
 		jsonBytes, _ := json.MarshalIndent(diagnostics, "", "  ")
 		fmt.Println(string(jsonBytes))
 	} else {
-		fmt.Fprintf(os.Stderr, "❌ Schema validation FAILED\n\n")
-		fmt.Fprintf(os.Stderr, "%s\n", mainMsg)
+		fmt.Fprintf(style.Stderr, "❌ Schema validation FAILED\n\n")
+		fmt.Fprintf(style.Stderr, "%s\n", mainMsg)
 		for _, detail := range details {
-			fmt.Fprintf(os.Stderr, "  - %s\n", detail)
+			fmt.Fprintf(style.Stderr, "  - %s\n", detail)
 		}
 	}
-	os.Exit(1)
+	releaseRunSchema()
+	exit(code)
 }
 
 // isConnectionError checks if the error message is related to database connection issues
@@ -1091,41 +1970,90 @@ func enhanceConnectionError(msg string) string {
 	return msg + helpText
 }
 
+// isErrorDiagnostic reports whether a diagnostic fails validation. Warnings
+// and informational notes don't.
+func isErrorDiagnostic(diag SyntaxError) bool {
+	return diag.Severity != "warning" && diag.Severity != "info"
+}
+
+// diagnosticCode returns the diagnostic's code, defaulting by severity
+func diagnosticCode(diag SyntaxError) string {
+	if diag.Code != "" {
+		return diag.Code
+	}
+	switch diag.Severity {
+	case "warning":
+		return "schema_warning"
+	case "info":
+		return "schema_info"
+	default:
+		return "syntax_error"
+	}
+}
+
+func diagnosticIcon(diag SyntaxError) string {
+	if diag.Severity == "info" {
+		return "ℹ️ "
+	}
+	return "⚠️ "
+}
+
+func countSeverity(diagnostics []SyntaxError, severity string) int {
+	count := 0
+	for _, diag := range diagnostics {
+		if diag.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
 func validationSuccess(result *planner.ExecutionResult, warnings []SyntaxError) {
 	steps := 0
 	if result != nil {
 		steps = result.StepsApplied
 	}
-	if isJSONOutput() {
+	if isSARIFOutput() {
+		printSARIF(warnings)
+	} else if isJSONOutput() {
 		// Include warnings in the diagnostics array
 		var diagnostics []map[string]interface{}
 		for _, warn := range warnings {
 			diagnostics = append(diagnostics, map[string]interface{}{
-				"severity": "warning",
+				"severity": warn.Severity,
 				"message":  warn.Message,
-				"code":     "schema_warning",
+				"code":     diagnosticCode(warn),
 				"file":     warn.File,
 				"line":     warn.Line,
 				"column":   warn.Column,
 			})
 		}
 
+		summary := map[string]interface{}{
+			"errors":        0,
+			"warnings":      countSeverity(warnings, "warning"),
+			"info":          countSeverity(warnings, "info"),
+			"valid":         true,
+			"steps_applied": steps,
+		}
+		if result != nil && result.Profile != nil {
+			summary["profile"] = result.Profile
+		}
 		output := map[string]interface{}{
 			"diagnostics": diagnostics,
-			"summary": map[string]interface{}{
-				"errors":        0,
-				"warnings":      len(warnings),
-				"valid":         true,
-				"steps_applied": steps,
-			},
+			"summary":     summary,
 		}
 		jsonBytes, _ := json.MarshalIndent(output, "", "  ")
 		fmt.Println(string(jsonBytes))
 	} else {
-		fmt.Fprintf(os.Stderr, "✅ Schema validation PASSED\n")
-		fmt.Fprintf(os.Stderr, "   Applied %d steps successfully\n", steps)
-		if len(warnings) > 0 {
-			fmt.Fprintf(os.Stderr, "\n⚠️  %d warning(s) found (see above)\n", len(warnings))
+		fmt.Fprintf(style.Stderr, "✅ Schema validation PASSED\n")
+		// Without a result, nothing was applied to a shadow database
+		if result != nil {
+			fmt.Fprintf(style.Stderr, "   Applied %d steps successfully\n", steps)
+			printStatementProfile(result.Profile)
+		}
+		if count := countSeverity(warnings, "warning"); count > 0 {
+			fmt.Fprintf(style.Stderr, "\n⚠️  %d warning(s) found (see above)\n", count)
 		}
 	}
 }
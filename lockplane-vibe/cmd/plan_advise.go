@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/advisor"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// adviseNeedsLiveSource is the error for --advise without a live source database
+const adviseNeedsLiveSource = "--advise reads index statistics from a live source database; plan from --from-environment or a --from connection string."
+
+// loadAdvisories reads the index usage of the live source database and
+// returns the index advice for planning it to after. Advice never affects the
+// plan or the exit code, so failing to read it only warns.
+func loadAdvisories(ctx context.Context, connStr string, before, after *database.Schema, generator database.SQLGenerator) []planner.Advisory {
+	warn := func(format string, args ...any) []planner.Advisory {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Skipping --advise: "+format+"\n", args...)
+		return nil
+	}
+
+	db, err := sql.Open(executor.GetSQLDriverName(executor.DetectDriver(connStr)), connStr)
+	if err != nil {
+		return warn("failed to connect to the source database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var schemas []string
+	for _, table := range before.Tables {
+		if table.Schema != "" && !slices.Contains(schemas, table.Schema) {
+			schemas = append(schemas, table.Schema)
+		}
+	}
+	usage, err := postgres.NewIntrospector().GetIndexUsage(ctx, db, schemas)
+	if err != nil {
+		return warn("%v", err)
+	}
+	return advisor.Advise(before, after, usage, generator)
+}
+
+// printAdvisories renders the index advice of plan --advise to stderr
+func printAdvisories(advisories []planner.Advisory) {
+	_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "\n💡 Index advice (not part of the plan)\n")
+	if len(advisories) == 0 {
+		fmt.Fprintf(style.Stderr, "   No missing foreign key indexes or unused indexes found\n\n")
+		return
+	}
+	for _, advisory := range advisories {
+		fmt.Fprintf(style.Stderr, "   • %s\n", advisory.Message)
+		fmt.Fprintf(style.Stderr, "       %s\n", advisory.SQL)
+	}
+	fmt.Fprintf(style.Stderr, "   Scan counts start over when statistics are reset and are kept per server; check replicas before dropping an index.\n\n")
+}
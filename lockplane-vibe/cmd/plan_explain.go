@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/introspect"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// explainLabels name the source and target schemas in step reasons
+type explainLabels struct {
+	source string // e.g. "the database" or "current.json"
+	target string // e.g. "schema/"
+}
+
+// schemaInputLabel names a plan input in step reasons: "the database" for a
+// connection string, the path otherwise
+func schemaInputLabel(input string) string {
+	if introspect.IsConnectionString(input) {
+		return "the database"
+	}
+	return input
+}
+
+// explainPlanSteps sets the Reason of each plan step to the schema difference
+// it carries out, pointing at the target schema files when they have source
+// locations
+func explainPlanSteps(plan *planner.Plan, diff *schema.SchemaDiff, before, after *database.Schema, labels explainLabels) {
+	changes := fullPlanChanges(diff, before, after)
+	for i, idx := range stepChanges(changes, plan.Steps) {
+		step := &plan.Steps[i]
+		if idx >= 0 {
+			step.Reason = changeReason(changes[idx], after, labels)
+			continue
+		}
+		step.Reason = unlinkedStepReason(*step, diff, before, labels)
+	}
+}
+
+// changeReason describes a schema difference, e.g. "index idx_users_email on
+// users exists in the database but not in schema/users.lp.sql"
+func changeReason(change fullPlanChange, after *database.Schema, labels explainLabels) string {
+	subject := changeSubject(change)
+	switch change.Action {
+	case changeActionAdd:
+		return fmt.Sprintf("%s is defined in %s but does not exist in %s", subject, targetLocation(change.Source, labels), labels.source)
+	case changeActionRemove:
+		return fmt.Sprintf("%s exists in %s but not in %s", subject, labels.source, removedFrom(change, after, labels))
+	case changeActionRename:
+		return fmt.Sprintf("%s is named %s in %s (%s)", subject, renamedFrom(change), labels.source, targetLocation(change.Source, labels))
+	default:
+		return fmt.Sprintf("%s %s (%s)", subject, strings.Join(describeChanges(change), ", "), targetLocation(change.Source, labels))
+	}
+}
+
+// changeSubject names the object of a change, e.g. "column orders.status"
+func changeSubject(change fullPlanChange) string {
+	switch change.Object {
+	case changeObjectColumn:
+		return fmt.Sprintf("column %s.%s", change.Table, change.Name)
+	case changeObjectIndex:
+		return fmt.Sprintf("index %s on %s", change.Name, change.Table)
+	case changeObjectForeignKey:
+		return fmt.Sprintf("foreign key %s on %s", change.Name, change.Table)
+	case changeObjectSequence:
+		return "sequence " + change.Name
+	default:
+		return "table " + change.Table
+	}
+}
+
+// targetLocation is where the target schema defines an object: its file
+// location when known, the target label otherwise
+func targetLocation(loc *database.SourceLocation, labels explainLabels) string {
+	if loc != nil {
+		return loc.String()
+	}
+	return labels.target
+}
+
+// removedFrom names where a removed object is missing: the file of its table
+// when the table is still defined, the target label otherwise
+func removedFrom(change fullPlanChange, after *database.Schema, labels explainLabels) string {
+	if change.Object == changeObjectTable || change.Object == changeObjectSequence {
+		return labels.target
+	}
+	if table := findSchemaTable(after, change.Table); table != nil && table.Source != nil {
+		return table.Source.File
+	}
+	return labels.target
+}
+
+// renamedFrom returns the previous name of a renamed index or foreign key
+func renamedFrom(change fullPlanChange) string {
+	switch before := change.Before.(type) {
+	case database.Index:
+		return before.Name
+	case database.ForeignKey:
+		return before.Name
+	}
+	return "differently"
+}
+
+// describeChanges describes each changed attribute of a modified object,
+// with the old and new values of column types, nullability and defaults
+func describeChanges(change fullPlanChange) []string {
+	oldCol, oldOK := change.Before.(database.Column)
+	newCol, newOK := change.After.(database.Column)
+	columns := change.Object == changeObjectColumn && oldOK && newOK
+
+	var descriptions []string
+	for _, attr := range change.Changes {
+		switch {
+		case columns && attr == "type":
+			descriptions = append(descriptions, fmt.Sprintf("type changed from %s to %s", oldCol.Type, newCol.Type))
+		case columns && attr == "nullable" && newCol.Nullable:
+			descriptions = append(descriptions, "became nullable")
+		case columns && attr == "nullable":
+			descriptions = append(descriptions, "became NOT NULL")
+		case columns && attr == "default":
+			descriptions = append(descriptions, fmt.Sprintf("default changed from %s to %s", defaultLabel(oldCol.Default), defaultLabel(newCol.Default)))
+		default:
+			descriptions = append(descriptions, strings.ReplaceAll(attr, "_", " ")+" changed")
+		}
+	}
+	if len(descriptions) == 0 {
+		descriptions = append(descriptions, "changed")
+	}
+	return descriptions
+}
+
+func defaultLabel(value *string) string {
+	if value == nil {
+		return "none"
+	}
+	return *value
+}
+
+// unlinkedStepReason explains a step that carries out no difference of its
+// own: schemas created for new objects, foreign keys dropped ahead of the
+// table they reference, and steps that rebuild a changed table
+func unlinkedStepReason(step planner.PlanStep, diff *schema.SchemaDiff, before *database.Schema, labels explainLabels) string {
+	if step.Operation == nil {
+		return ""
+	}
+	op := step.Operation
+	switch op.Kind {
+	case planner.OperationCreateSchema:
+		return fmt.Sprintf("schema %s holds objects defined in %s but does not exist in %s", op.Details["schema"], labels.target, labels.source)
+	case planner.OperationDropForeignKey:
+		fk := findForeignKey(findSchemaTable(before, op.Table), op.Details["name"])
+		if fk != nil && database.FindTable(&database.Schema{Tables: diff.RemovedTables}, fk.ReferencedTable) != nil {
+			return fmt.Sprintf("foreign key %s on %s references table %s, which exists in %s but not in %s",
+				fk.Name, op.Table, fk.ReferencedTable, labels.source, labels.target)
+		}
+	}
+	if op.Table != "" {
+		return fmt.Sprintf("table %s changes between %s and %s", op.Table, labels.source, labels.target)
+	}
+	return ""
+}
+
+// printStepReasons writes the reason of each plan step to stderr
+func printStepReasons(plan *planner.Plan) {
+	if len(plan.Steps) == 0 {
+		return
+	}
+	cyan := color.New(color.FgCyan, color.Bold)
+	gray := color.New(color.FgHiBlack)
+	_, _ = cyan.Fprintf(style.Stderr, "\n🔎 Why each step is in the plan:\n\n")
+	for i, step := range plan.Steps {
+		fmt.Fprintf(style.Stderr, "  %d. %s\n", i+1, step.Description)
+		if step.Reason != "" {
+			_, _ = gray.Fprintf(style.Stderr, "     Why: %s\n", step.Reason)
+		}
+	}
+	fmt.Fprintf(style.Stderr, "\n")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestExplainPlanSteps(t *testing.T) {
+	before, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "before"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load before schema: %v", err)
+	}
+	after, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "after"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load after schema: %v", err)
+	}
+	diff := schema.DiffSchemas(before, after)
+	plan, err := planner.GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	explainPlanSteps(plan, diff, before, after, explainLabels{source: "the database", target: "schema/"})
+
+	file := filepath.Join("testdata", "json-full", "after", "schema.lp.sql")
+	want := map[string]string{
+		"Create index comments_post_id_idx on table comments":                   "table comments is defined in " + file + ":16:14 but does not exist in the database",
+		"Set NOT NULL on users.created_at now that existing rows have a value":  "column users.created_at is defined in " + file + ":4:3 but does not exist in the database",
+		"Change type of users.email from text to varchar(255)":                  "column users.email type changed from text to varchar(255) (" + file + ":3:3)",
+		"Drop column nickname from table users":                                 "column users.nickname exists in the database but not in " + file,
+		"Rename index posts_title_idx on table posts to posts_title_lookup_idx": "index posts_title_lookup_idx on posts is named posts_title_idx in the database (" + file + ":14:1)",
+	}
+	for _, step := range plan.Steps {
+		if step.Reason == "" {
+			t.Errorf("Step %q has no reason", step.Description)
+		}
+		if reason, ok := want[step.Description]; ok && step.Reason != reason {
+			t.Errorf("Step %q:\ngot  %q\nwant %q", step.Description, step.Reason, reason)
+		}
+	}
+}
+
+func TestExplainPlanSteps_WithoutSchemaFiles(t *testing.T) {
+	before := &database.Schema{Tables: []database.Table{
+		{Name: "users", Columns: []database.Column{{Name: "id", Type: "bigint"}}},
+		{
+			Name:    "posts",
+			Columns: []database.Column{{Name: "id", Type: "bigint"}, {Name: "user_id", Type: "bigint"}},
+			Indexes: []database.Index{{Name: "posts_user_id_idx", Columns: []string{"user_id"}}},
+			ForeignKeys: []database.ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"},
+				ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+		},
+	}}
+	after := &database.Schema{Tables: []database.Table{
+		{Name: "posts", Columns: []database.Column{{Name: "id", Type: "bigint"}, {Name: "user_id", Type: "bigint"}}},
+		{Name: "events", Schema: "audit", Columns: []database.Column{{Name: "id", Type: "bigint"}}},
+	}}
+	diff := schema.DiffSchemas(before, after)
+	plan, err := planner.GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	explainPlanSteps(plan, diff, before, after, explainLabels{source: "current.json", target: "desired.json"})
+
+	want := map[string]string{
+		planner.OperationCreateSchema:   "schema audit holds objects defined in desired.json but does not exist in current.json",
+		planner.OperationCreateTable:    "table audit.events is defined in desired.json but does not exist in current.json",
+		planner.OperationDropIndex:      "index posts_user_id_idx on posts exists in current.json but not in desired.json",
+		planner.OperationDropForeignKey: "foreign key posts_user_id_fkey on posts exists in current.json but not in desired.json",
+		planner.OperationDropTable:      "table users exists in current.json but not in desired.json",
+	}
+	for _, step := range plan.Steps {
+		if reason := want[step.Operation.Kind]; step.Reason != reason {
+			t.Errorf("Step %q:\ngot  %q\nwant %q", step.Description, step.Reason, reason)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// fullPlanFormatVersion is the version of the plan --output json-full
+// document. New fields may be added within a version; removing, renaming or
+// changing the meaning of a field bumps it.
+const fullPlanFormatVersion = 1
+
+// Objects and actions of fullPlanChange
+const (
+	changeObjectTable      = "table"
+	changeObjectColumn     = "column"
+	changeObjectIndex      = "index"
+	changeObjectForeignKey = "foreign_key"
+	changeObjectSequence   = "sequence"
+
+	changeActionAdd    = "add"
+	changeActionRemove = "remove"
+	changeActionModify = "modify"
+	changeActionRename = "rename"
+)
+
+// fullPlanOutput is the plan --output json-full document: the schema
+// differences, the plan steps that carry them out and the safety diagnostics,
+// for editor integrations
+type fullPlanOutput struct {
+	FormatVersion int                    `json:"format_version"`
+	SourceHash    string                 `json:"source_hash"`
+	TargetHash    string                 `json:"target_hash,omitempty"`
+	Summary       *planner.ImpactSummary `json:"summary,omitempty"`
+	Changes       []fullPlanChange       `json:"changes"`
+	Steps         []planner.PlanStep     `json:"steps"`
+	Diagnostics   []fullPlanDiagnostic   `json:"diagnostics"`
+	Advisories    []planner.Advisory     `json:"advisories,omitempty"` // plan --advise findings
+}
+
+// fullPlanChange is one difference between the source and target schemas
+type fullPlanChange struct {
+	// ID identifies the change within the document, e.g. "column:users.email"
+	ID     string `json:"id"`
+	Object string `json:"object"`         // table, column, index, foreign_key or sequence
+	Action string `json:"action"`         // add, remove, modify or rename
+	Table  string `json:"table"`          // Empty for sequences
+	Name   string `json:"name,omitempty"` // Column, index, foreign key or sequence name
+	// Changes lists the modified attributes (e.g. "type", "nullable", "rls")
+	Changes []string `json:"changes,omitempty"`
+	Before  any      `json:"before,omitempty"`
+	After   any      `json:"after,omitempty"`
+	// Source is where the object is defined in the target schema files
+	Source *database.SourceLocation `json:"source,omitempty"`
+	// Steps are the indexes of the plan steps that carry out the change
+	Steps []int `json:"steps"`
+}
+
+// fullPlanDiagnostic is a safety finding about the plan
+type fullPlanDiagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+func isFullJSONOutput() bool {
+	return strings.EqualFold(strings.TrimSpace(planOutput), "json-full")
+}
+
+// printFullPlanJSON writes the json-full document to stdout
+func printFullPlanJSON(output fullPlanOutput) {
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
+	}
+	fmt.Println(string(jsonBytes))
+}
+
+// newFullPlanOutput combines the diff, the plan generated from it and the
+// safety diagnostics into one document
+func newFullPlanOutput(diff *schema.SchemaDiff, before, after *database.Schema, plan *planner.Plan, diagnostics []SyntaxError) fullPlanOutput {
+	output := fullPlanOutput{
+		FormatVersion: fullPlanFormatVersion,
+		SourceHash:    plan.SourceHash,
+		TargetHash:    plan.TargetHash,
+		Summary:       plan.Summary,
+		Changes:       fullPlanChanges(diff, before, after),
+		Steps:         plan.Steps,
+		Diagnostics:   []fullPlanDiagnostic{},
+		Advisories:    plan.Advisories,
+	}
+	if output.Steps == nil {
+		output.Steps = []planner.PlanStep{}
+	}
+
+	for i, idx := range stepChanges(output.Changes, plan.Steps) {
+		if idx >= 0 {
+			output.Changes[idx].Steps = append(output.Changes[idx].Steps, i)
+		}
+	}
+
+	for _, diag := range diagnostics {
+		severity := diag.Severity
+		if severity == "" {
+			severity = "error"
+		}
+		output.Diagnostics = append(output.Diagnostics, fullPlanDiagnostic{
+			Severity: severity,
+			Code:     diagnosticCode(diag),
+			Message:  diag.Message,
+			File:     diag.File,
+			Line:     diag.Line,
+			Column:   diag.Column,
+		})
+	}
+	return output
+}
+
+// stepChanges returns the index in changes of the change each plan step
+// carries out, or -1 for steps that belong to no change
+func stepChanges(changes []fullPlanChange, steps []planner.PlanStep) []int {
+	changeIndex := make(map[string]int, len(changes))
+	for i, change := range changes {
+		changeIndex[change.ID] = i
+	}
+	indexes := make([]int, len(steps))
+	for i, step := range steps {
+		indexes[i] = -1
+		if step.Operation == nil {
+			continue
+		}
+		id := operationChangeID(step.Operation)
+		if _, ok := changeIndex[id]; !ok {
+			// Indexes and foreign keys of a created table belong to the table
+			id = changeID(changeObjectTable, step.Operation.Table, "")
+		}
+		if idx, ok := changeIndex[id]; ok {
+			indexes[i] = idx
+		}
+	}
+	return indexes
+}
+
+// stepWarningDiagnostics turns the warnings of plan steps into diagnostics
+func stepWarningDiagnostics(plan *planner.Plan) []SyntaxError {
+	var diagnostics []SyntaxError
+	for _, step := range plan.Steps {
+		for _, warning := range step.Warnings {
+			diag := SyntaxError{
+				Message:  warning,
+				Severity: "warning",
+				Code:     "step_warning",
+			}
+			if step.Source != nil {
+				diag.File = step.Source.File
+				diag.Line = step.Source.Line
+				diag.Column = step.Source.Column
+			}
+			diagnostics = append(diagnostics, diag)
+		}
+	}
+	return diagnostics
+}
+
+// changeID returns the ID of a change to a table or to one of its columns,
+// indexes or foreign keys
+func changeID(object, table, name string) string {
+	if name == "" {
+		return object + ":" + table
+	}
+	return object + ":" + table + "." + name
+}
+
+// operationChangeID returns the ID of the change a plan step operation belongs to
+func operationChangeID(op *planner.Operation) string {
+	switch op.Kind {
+	case planner.OperationAddColumn, planner.OperationDropColumn, planner.OperationAlterColumn, planner.OperationSoftDropColumn:
+		return changeID(changeObjectColumn, op.Table, op.Column)
+	case planner.OperationAddIndex, planner.OperationDropIndex, planner.OperationRenameIndex:
+		return changeID(changeObjectIndex, op.Table, op.Details["name"])
+	case planner.OperationAddForeignKey, planner.OperationDropForeignKey, planner.OperationRenameForeignKey:
+		return changeID(changeObjectForeignKey, op.Table, op.Details["name"])
+	case planner.OperationSetTablespace:
+		if index := op.Details["index"]; index != "" {
+			return changeID(changeObjectIndex, op.Table, index)
+		}
+	case planner.OperationCreateSequence, planner.OperationAlterSequence, planner.OperationDropSequence:
+		return changeID(changeObjectSequence, op.Details["name"], "")
+	}
+	return changeID(changeObjectTable, op.Table, "")
+}
+
+// fullPlanChanges flattens the diff into one change per table, column, index
+// and foreign key, in diff order
+func fullPlanChanges(diff *schema.SchemaDiff, before, after *database.Schema) []fullPlanChange {
+	changes := []fullPlanChange{}
+	byID := make(map[string]int)
+	add := func(change fullPlanChange) {
+		change.Steps = []int{}
+		if i, ok := byID[change.ID]; ok {
+			// A constraint dropped and re-added under the same name is modified
+			existing := &changes[i]
+			existing.Action = changeActionModify
+			if change.Before != nil {
+				existing.Before = change.Before
+			}
+			if change.After != nil {
+				existing.After = change.After
+				existing.Source = change.Source
+			}
+			return
+		}
+		byID[change.ID] = len(changes)
+		changes = append(changes, change)
+	}
+
+	for _, table := range diff.AddedTables {
+		add(fullPlanChange{ID: changeID(changeObjectTable, table.QualifiedName(), ""), Object: changeObjectTable, Action: changeActionAdd,
+			Table: table.QualifiedName(), After: table, Source: table.Source})
+	}
+	for _, table := range diff.RemovedTables {
+		add(fullPlanChange{ID: changeID(changeObjectTable, table.QualifiedName(), ""), Object: changeObjectTable, Action: changeActionRemove,
+			Table: table.QualifiedName(), Before: table})
+	}
+
+	for _, tableDiff := range diff.ModifiedTables {
+		name := tableDiff.TableName
+		beforeTable := findSchemaTable(before, name)
+		afterTable := findSchemaTable(after, name)
+
+		if settings := tableSettingChanges(tableDiff); len(settings) > 0 {
+			change := fullPlanChange{ID: changeID(changeObjectTable, name, ""), Object: changeObjectTable, Action: changeActionModify,
+				Table: name, Changes: settings, Source: tableDiff.Source}
+			if beforeTable != nil {
+				change.Before = *beforeTable
+			}
+			if afterTable != nil {
+				change.After = *afterTable
+			}
+			add(change)
+		}
+
+		column := func(action string, col database.Column) fullPlanChange {
+			return fullPlanChange{ID: changeID(changeObjectColumn, name, col.Name), Object: changeObjectColumn, Action: action, Table: name, Name: col.Name}
+		}
+		for _, col := range tableDiff.AddedColumns {
+			change := column(changeActionAdd, col)
+			change.After, change.Source = col, col.Source
+			add(change)
+		}
+		for _, col := range tableDiff.RemovedColumns {
+			change := column(changeActionRemove, col)
+			change.Before = col
+			add(change)
+		}
+		for _, colDiff := range tableDiff.ModifiedColumns {
+			change := column(changeActionModify, colDiff.New)
+			change.Changes, change.Before, change.After, change.Source = colDiff.Changes, colDiff.Old, colDiff.New, colDiff.New.Source
+			add(change)
+		}
+
+		index := func(action string, idx database.Index) fullPlanChange {
+			return fullPlanChange{ID: changeID(changeObjectIndex, name, idx.Name), Object: changeObjectIndex, Action: action, Table: name, Name: idx.Name}
+		}
+		for _, idx := range tableDiff.AddedIndexes {
+			change := index(changeActionAdd, idx)
+			change.After, change.Source = idx, idx.Source
+			add(change)
+		}
+		for _, idx := range tableDiff.RemovedIndexes {
+			change := index(changeActionRemove, idx)
+			change.Before = idx
+			add(change)
+		}
+		for _, idxDiff := range tableDiff.RecreatedIndexes {
+			change := index(changeActionModify, idxDiff.New)
+			change.Changes, change.Before, change.After, change.Source = idxDiff.Changes, idxDiff.Old, idxDiff.New, idxDiff.New.Source
+			add(change)
+		}
+		for _, idxDiff := range tableDiff.RenamedIndexes {
+			change := index(changeActionRename, idxDiff.New)
+			change.Changes, change.Before, change.After, change.Source = idxDiff.Changes, idxDiff.Old, idxDiff.New, idxDiff.New.Source
+			add(change)
+		}
+		for _, idx := range tableDiff.MovedIndexes {
+			change := index(changeActionModify, idx)
+			change.Changes, change.After, change.Source = []string{"tablespace"}, idx, idx.Source
+			if beforeTable != nil {
+				for _, old := range beforeTable.Indexes {
+					if old.Name == idx.Name {
+						change.Before = old
+					}
+				}
+			}
+			add(change)
+		}
+
+		foreignKey := func(action string, fkName string) fullPlanChange {
+			return fullPlanChange{ID: changeID(changeObjectForeignKey, name, fkName), Object: changeObjectForeignKey, Action: action, Table: name, Name: fkName}
+		}
+		for _, fk := range tableDiff.RemovedForeignKeys {
+			change := foreignKey(changeActionRemove, fk.Name)
+			change.Before = fk
+			add(change)
+		}
+		for _, fk := range tableDiff.AddedForeignKeys {
+			change := foreignKey(changeActionAdd, fk.Name)
+			change.After, change.Source = fk, fk.Source
+			add(change)
+		}
+		for _, rename := range tableDiff.RenamedForeignKeys {
+			change := foreignKey(changeActionRename, rename.NewName)
+			change.Changes = []string{"name"}
+			if fk := findForeignKey(beforeTable, rename.OldName); fk != nil {
+				change.Before = *fk
+			}
+			if fk := findForeignKey(afterTable, rename.NewName); fk != nil {
+				change.After, change.Source = *fk, fk.Source
+			}
+			add(change)
+		}
+	}
+
+	sequence := func(action, name string) fullPlanChange {
+		return fullPlanChange{ID: changeID(changeObjectSequence, name, ""), Object: changeObjectSequence, Action: action, Name: name}
+	}
+	for _, seq := range diff.AddedSequences {
+		change := sequence(changeActionAdd, seq.QualifiedName())
+		change.After = seq
+		add(change)
+	}
+	for _, seqDiff := range diff.ModifiedSequences {
+		change := sequence(changeActionModify, seqDiff.SequenceName)
+		change.Changes, change.Before, change.After = seqDiff.Changes, seqDiff.Old, seqDiff.New
+		add(change)
+	}
+	for _, seq := range diff.RemovedSequences {
+		change := sequence(changeActionRemove, seq.QualifiedName())
+		change.Before = seq
+		add(change)
+	}
+	return changes
+}
+
+// tableSettingChanges lists the table-level attributes a table diff changes
+func tableSettingChanges(tableDiff schema.TableDiff) []string {
+	var settings []string
+	if tableDiff.RLSChanged {
+		settings = append(settings, "rls")
+	}
+	if tableDiff.TablespaceChanged {
+		settings = append(settings, "tablespace")
+	}
+	if tableDiff.ReplicaIdentityChanged {
+		settings = append(settings, "replica_identity")
+	}
+	if len(tableDiff.SetStorageParameters) > 0 || len(tableDiff.ResetStorageParameters) > 0 {
+		settings = append(settings, "storage_parameters")
+	}
+	if tableDiff.ColumnOrderChanged {
+		settings = append(settings, "column_order")
+	}
+	return settings
+}
+
+// findSchemaTable returns the named table of s, or nil
+func findSchemaTable(s *database.Schema, name string) *database.Table {
+	return database.FindTable(s, name)
+}
+
+// findForeignKey returns the named foreign key of table, or nil
+func findForeignKey(table *database.Table, name string) *database.ForeignKey {
+	if table == nil {
+		return nil
+	}
+	for i := range table.ForeignKeys {
+		if table.ForeignKeys[i].Name == name {
+			return &table.ForeignKeys[i]
+		}
+	}
+	return nil
+}
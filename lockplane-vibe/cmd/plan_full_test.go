@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+func TestFullPlanOutput(t *testing.T) {
+	before, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "before"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load before schema: %v", err)
+	}
+	after, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "after"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load after schema: %v", err)
+	}
+	diff := schema.DiffSchemas(before, after)
+	plan, err := planner.GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	results := validation.ValidateSchemaDiffWithSchemas(diff, before, after, false)
+	output := newFullPlanOutput(diff, before, after, plan, safetyDiagnostics(results))
+
+	// Every step belongs to exactly one change
+	owners := make(map[int]string)
+	for _, change := range output.Changes {
+		for _, step := range change.Steps {
+			if owner, ok := owners[step]; ok {
+				t.Errorf("Step %d belongs to both %s and %s", step, owner, change.ID)
+			}
+			owners[step] = change.ID
+		}
+	}
+	for i, step := range output.Steps {
+		if _, ok := owners[i]; !ok {
+			t.Errorf("Step %d (%s) belongs to no change", i, step.Description)
+		}
+	}
+
+	got, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal output: %v", err)
+	}
+	got = append(got, '\n')
+
+	goldenPath := filepath.Join("testdata", "json-full", "plan.json")
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Output differs from %s (run with -update to accept):\n%s", goldenPath, got)
+	}
+}
+
+func TestFullPlanOutput_NoChanges(t *testing.T) {
+	before, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "before"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	diff := schema.DiffSchemas(before, before)
+	plan, err := planner.GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	got, err := json.Marshal(newFullPlanOutput(diff, before, before, plan, nil))
+	if err != nil {
+		t.Fatalf("Failed to marshal output: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	// Arrays are always present so consumers don't have to handle null
+	for _, key := range []string{"changes", "steps", "diagnostics"} {
+		if _, ok := decoded[key].([]any); !ok {
+			t.Errorf("Expected %s to be an array, got %v", key, decoded[key])
+		}
+	}
+	if decoded["format_version"] != float64(fullPlanFormatVersion) {
+		t.Errorf("Expected format_version %d, got %v", fullPlanFormatVersion, decoded["format_version"])
+	}
+}
+
+func TestStepWarningDiagnostics(t *testing.T) {
+	plan := &planner.Plan{Steps: []planner.PlanStep{
+		{Description: "Create table users"},
+		{
+			Description: "Add foreign key posts_user_id_fkey to table posts",
+			Source:      &database.SourceLocation{File: "posts.lp.sql", Line: 3, Column: 5},
+			Warnings:    []string{"Recreating table posts loses its grants reporting=r/app"},
+		},
+	}}
+
+	diagnostics := stepWarningDiagnostics(plan)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	diag := diagnostics[0]
+	if diag.Severity != "warning" || diag.Code != "step_warning" || diag.File != "posts.lp.sql" || diag.Line != 3 || diag.Column != 5 {
+		t.Errorf("Unexpected diagnostic: %+v", diag)
+	}
+	if diag.Message != "Recreating table posts loses its grants reporting=r/app" {
+		t.Errorf("Unexpected message: %q", diag.Message)
+	}
+}
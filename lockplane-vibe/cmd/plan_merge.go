@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/spf13/cobra"
+)
+
+var planMergeCmd = &cobra.Command{
+	Use:   "merge <plan.json> <plan.json> [plan.json...]",
+	Short: "Combine several migration plans into one",
+	Long: `Combine several plan files, generated one after another, into a single plan.
+
+Steps are concatenated in the order the files are given, and objects that are
+created in one plan and dropped in a later one are removed entirely.
+
+Plans must chain: each plan's source hash has to match the schema the previous
+plan produces. Plans generated against the same starting schema are rejected.`,
+	Example: `  # Combine development plans into a release migration
+  lockplane plan merge 001_users.json 002_posts.json 003_indexes.json > release.json`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  runPlanMerge,
+}
+
+var planMergeVerbose bool
+
+func init() {
+	planCmd.AddCommand(planMergeCmd)
+
+	planMergeCmd.Flags().BoolVarP(&planMergeVerbose, "verbose", "v", false, "Enable verbose logging")
+}
+
+func runPlanMerge(cmd *cobra.Command, args []string) {
+	plans := make([]*planner.Plan, 0, len(args))
+	totalSteps := 0
+	for _, path := range args {
+		plan, err := planner.LoadJSONPlan(path)
+		if err != nil {
+			fatalf(exitError, "Failed to load plan %s: %v", path, err)
+		}
+		if planMergeVerbose {
+			fmt.Fprintf(style.Stderr, "📋 Loaded %s (%d steps)\n", path, len(plan.Steps))
+		}
+		totalSteps += len(plan.Steps)
+		plans = append(plans, plan)
+	}
+
+	merged, err := planner.MergePlans(plans)
+	if err != nil {
+		_, _ = color.New(color.FgRed).Fprintf(style.Stderr, "❌ Cannot merge plans: %v\n", err)
+		exit(1)
+	}
+
+	if planMergeVerbose {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ Merged %d plans: %d steps (%d removed)\n",
+			len(plans), len(merged.Steps), totalSteps-len(merged.Steps))
+	}
+
+	jsonBytes, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"strings"
 
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/executor"
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/planner/multiphase"
 	"github.com/spf13/cobra"
@@ -24,7 +26,9 @@ Supported patterns:
   • deprecation: Safe removal of columns with deprecation period
   • drop_table: Safe removal of entire tables with deprecation period
   • validation: Add constraints with backfill and validation phases
-  • type_change: Incompatible column type changes with dual-write`,
+  • type_change: Incompatible column type changes with dual-write
+  • pk_to_uuid: Serial/identity integer primary key to UUID, including the
+    foreign keys that reference it`,
 	Example: `  # Generate expand/contract plan for column rename
   lockplane plan-multiphase \
     --pattern expand_contract \
@@ -58,28 +62,37 @@ Supported patterns:
     --table users \
     --column age \
     --old-type TEXT \
-    --new-type INTEGER > type-change-plan.json`,
+    --new-type INTEGER > type-change-plan.json
+
+  # Generate primary key to UUID plan, finding referencing foreign keys in the schema
+  lockplane plan-multiphase \
+    --pattern pk_to_uuid \
+    --table users \
+    --schema schema/ > users-uuid-plan.json`,
 	Run: runPlanMultiphase,
 }
 
 var (
-	mpPattern     string
-	mpTable       string
-	mpColumn      string
-	mpOldColumn   string
-	mpNewColumn   string
-	mpType        string
-	mpOldType     string
-	mpNewType     string
-	mpConstraint  string
-	mpSourceHash  string
-	mpArchiveData bool
+	mpPattern      string
+	mpTable        string
+	mpColumn       string
+	mpOldColumn    string
+	mpNewColumn    string
+	mpType         string
+	mpOldType      string
+	mpNewType      string
+	mpConstraint   string
+	mpSourceHash   string
+	mpArchiveData  bool
+	mpSchema       string
+	mpReferencedBy []string
+	mpBatchSize    int
 )
 
 func init() {
 	rootCmd.AddCommand(planMultiphaseCmd)
 
-	planMultiphaseCmd.Flags().StringVar(&mpPattern, "pattern", "", "Migration pattern: expand_contract, deprecation, drop_table, validation, type_change (required)")
+	planMultiphaseCmd.Flags().StringVar(&mpPattern, "pattern", "", "Migration pattern: expand_contract, deprecation, drop_table, validation, type_change, pk_to_uuid (required)")
 	planMultiphaseCmd.Flags().StringVar(&mpTable, "table", "", "Table name (required)")
 	planMultiphaseCmd.Flags().StringVar(&mpColumn, "column", "", "Column name")
 	planMultiphaseCmd.Flags().StringVar(&mpOldColumn, "old-column", "", "Old column name (for expand_contract)")
@@ -90,6 +103,9 @@ func init() {
 	planMultiphaseCmd.Flags().StringVar(&mpConstraint, "constraint", "", "Constraint definition (for validation pattern)")
 	planMultiphaseCmd.Flags().StringVar(&mpSourceHash, "source-hash", "", "Source schema hash (optional)")
 	planMultiphaseCmd.Flags().BoolVar(&mpArchiveData, "archive-data", false, "Archive data before dropping (for deprecation and drop_table patterns)")
+	planMultiphaseCmd.Flags().StringVar(&mpSchema, "schema", "", "Schema file, directory or connection string to find the primary key and referencing foreign keys in (for pk_to_uuid)")
+	planMultiphaseCmd.Flags().StringSliceVar(&mpReferencedBy, "referenced-by", nil, "Foreign key columns referencing the primary key, as table.column (for pk_to_uuid without --schema)")
+	planMultiphaseCmd.Flags().IntVar(&mpBatchSize, "batch-size", multiphase.DefaultBackfillBatchSize, "Rows to backfill per transaction (for pk_to_uuid)")
 
 	_ = planMultiphaseCmd.MarkFlagRequired("pattern")
 	_ = planMultiphaseCmd.MarkFlagRequired("table")
@@ -107,10 +123,10 @@ func runPlanMultiphase(cmd *cobra.Command, args []string) {
 	switch mpPattern {
 	case "expand_contract":
 		if mpOldColumn == "" || mpNewColumn == "" {
-			log.Fatal("expand_contract pattern requires --old-column and --new-column")
+			fatal("expand_contract pattern requires --old-column and --new-column")
 		}
 		if mpType == "" {
-			log.Fatal("expand_contract pattern requires --type")
+			fatal("expand_contract pattern requires --type")
 		}
 
 		multiPhasePlan, err = multiphase.GenerateExpandContractPlan(
@@ -123,10 +139,10 @@ func runPlanMultiphase(cmd *cobra.Command, args []string) {
 
 	case "deprecation":
 		if mpColumn == "" {
-			log.Fatal("deprecation pattern requires --column")
+			fatal("deprecation pattern requires --column")
 		}
 		if mpType == "" {
-			log.Fatal("deprecation pattern requires --type")
+			fatal("deprecation pattern requires --type")
 		}
 
 		multiPhasePlan, err = multiphase.GenerateDeprecationPlan(
@@ -146,13 +162,13 @@ func runPlanMultiphase(cmd *cobra.Command, args []string) {
 
 	case "validation":
 		if mpConstraint == "" {
-			log.Fatal("validation pattern requires --constraint")
+			fatal("validation pattern requires --constraint")
 		}
 		if mpColumn == "" {
-			log.Fatal("validation pattern requires --column")
+			fatal("validation pattern requires --column")
 		}
 		if mpType == "" {
-			log.Fatal("validation pattern requires --type")
+			fatal("validation pattern requires --type")
 		}
 
 		// Determine constraint type from the constraint string
@@ -171,7 +187,7 @@ func runPlanMultiphase(cmd *cobra.Command, args []string) {
 			constraintType = "unique"
 			backfillValue = ""
 		} else {
-			log.Fatal("Unsupported constraint type. Use 'NOT NULL', 'CHECK (...)', or 'UNIQUE'")
+			fatal("Unsupported constraint type. Use 'NOT NULL', 'CHECK (...)', or 'UNIQUE'")
 		}
 
 		multiPhasePlan, err = multiphase.GenerateValidationPhasePlan(
@@ -186,7 +202,7 @@ func runPlanMultiphase(cmd *cobra.Command, args []string) {
 
 	case "type_change":
 		if mpColumn == "" || mpOldType == "" || mpNewType == "" {
-			log.Fatal("type_change pattern requires --column, --old-type, and --new-type")
+			fatal("type_change pattern requires --column, --old-type, and --new-type")
 		}
 
 		// Generate default conversion expression
@@ -201,19 +217,83 @@ func runPlanMultiphase(cmd *cobra.Command, args []string) {
 			mpSourceHash,
 		)
 
+	case "pk_to_uuid":
+		column, refs := pkToUUIDTarget()
+		multiPhasePlan, err = multiphase.GeneratePKToUUIDPlan(
+			mpTable,
+			column,
+			refs,
+			mpBatchSize,
+			mpSourceHash,
+		)
+
 	default:
-		log.Fatalf("Unknown pattern: %s. Supported patterns: expand_contract, deprecation, drop_table, validation, type_change", mpPattern)
+		fatalf(exitError, "Unknown pattern: %s. Supported patterns: expand_contract, deprecation, drop_table, validation, type_change, pk_to_uuid", mpPattern)
 	}
 
 	if err != nil {
-		log.Fatalf("Failed to generate multi-phase plan: %v", err)
+		fatalf(exitError, "Failed to generate multi-phase plan: %v", err)
 	}
 
 	// Output as JSON
 	output, err := json.MarshalIndent(multiPhasePlan, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal plan to JSON: %v", err)
+		fatalf(exitError, "Failed to marshal plan to JSON: %v", err)
 	}
 
 	fmt.Println(string(output))
 }
+
+// pkToUUIDTarget returns the primary key column to convert and the foreign
+// key columns referencing it, read from --schema or given by --column and
+// --referenced-by
+func pkToUUIDTarget() (string, []multiphase.ReferencingColumn) {
+	if mpSchema == "" {
+		if mpColumn == "" {
+			fatal("pk_to_uuid pattern requires --schema, or --column with --referenced-by")
+		}
+		refs, err := parseReferencedBy(mpReferencedBy)
+		if err != nil {
+			fatalf(exitError, "Invalid --referenced-by: %v", err)
+		}
+		return mpColumn, refs
+	}
+	if len(mpReferencedBy) > 0 {
+		fatal("--referenced-by cannot be combined with --schema, which finds the referencing foreign keys")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf(exitError, "Failed to load config file: %v", err)
+	}
+	loaded, err := executor.LoadSchemaOrIntrospectWithOptions(mpSchema, withSchemaFileOptions(nil, mpSchema, cfg))
+	if err != nil {
+		fatalf(exitError, "Failed to load schema: %v", err)
+	}
+	column, refs, err := multiphase.PrimaryKeyReferences(loaded, mpTable)
+	if err != nil {
+		fatalf(exitError, "Failed to find primary key: %v", err)
+	}
+	if mpColumn != "" && mpColumn != column {
+		fatalf(exitError, "--column %s is not the primary key of %s (%s)", mpColumn, mpTable, column)
+	}
+	return column, refs
+}
+
+// parseReferencedBy parses table.column entries into referencing columns
+// whose foreign keys have PostgreSQL's default names
+func parseReferencedBy(entries []string) ([]multiphase.ReferencingColumn, error) {
+	var refs []multiphase.ReferencingColumn
+	for _, entry := range entries {
+		table, column, ok := strings.Cut(strings.TrimSpace(entry), ".")
+		if !ok || table == "" || column == "" {
+			return nil, fmt.Errorf("%q is not table.column", entry)
+		}
+		refs = append(refs, multiphase.ReferencingColumn{
+			Table:      table,
+			Column:     column,
+			ForeignKey: fmt.Sprintf("%s_%s_fkey", table, column),
+		})
+	}
+	return refs, nil
+}
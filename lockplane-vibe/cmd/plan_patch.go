@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/textdiff"
+)
+
+func isPatchOutput() bool {
+	return strings.EqualFold(strings.TrimSpace(planOutput), "patch")
+}
+
+// schemaPatch returns the unified diff of the canonical SQL of the two
+// schemas, or "" when they are equal. No plan is generated.
+func schemaPatch(before, after *database.Schema, driver database.Driver, fromLabel, toLabel string, opts schema.DiffOptions) string {
+	if !opts.EnforceColumnOrder {
+		// Columns are matched by name, so a different order is not a change
+		before = alignColumnOrder(before, after)
+	}
+	beforeSQL := schemaSQL(canonicalOrder(before), driver)
+	afterSQL := schemaSQL(canonicalOrder(after), driver)
+	return textdiff.Unified(fromLabel, toLabel, beforeSQL, afterSQL, textdiff.DefaultContext)
+}
+
+// canonicalOrder returns a copy of s with its tables, indexes and foreign keys
+// sorted by name, so that equal schemas render identically whatever order
+// they were defined or introspected in. Columns keep their order.
+func canonicalOrder(s *database.Schema) *database.Schema {
+	sorted := *s
+	sorted.Tables = make([]database.Table, len(s.Tables))
+	for i, table := range s.Tables {
+		table.Indexes = slices.Clone(table.Indexes)
+		slices.SortFunc(table.Indexes, func(a, b database.Index) int { return strings.Compare(a.Name, b.Name) })
+		table.ForeignKeys = slices.Clone(table.ForeignKeys)
+		slices.SortFunc(table.ForeignKeys, func(a, b database.ForeignKey) int { return strings.Compare(a.Name, b.Name) })
+		sorted.Tables[i] = table
+	}
+	slices.SortFunc(sorted.Tables, func(a, b database.Table) int { return strings.Compare(a.Name, b.Name) })
+	return &sorted
+}
+
+// alignColumnOrder returns a copy of before whose tables list the columns
+// also in after in after's order, followed by the columns after doesn't have
+func alignColumnOrder(before, after *database.Schema) *database.Schema {
+	aligned := *before
+	aligned.Tables = make([]database.Table, len(before.Tables))
+	for i, table := range before.Tables {
+		aligned.Tables[i] = table
+		target := findSchemaTable(after, table.Name)
+		if target == nil {
+			continue
+		}
+
+		remaining := make(map[string]database.Column, len(table.Columns))
+		for _, col := range table.Columns {
+			remaining[col.Name] = col
+		}
+		columns := make([]database.Column, 0, len(table.Columns))
+		for _, col := range target.Columns {
+			if existing, ok := remaining[col.Name]; ok {
+				columns = append(columns, existing)
+				delete(remaining, col.Name)
+			}
+		}
+		for _, col := range table.Columns {
+			if _, ok := remaining[col.Name]; ok {
+				columns = append(columns, col)
+			}
+		}
+		aligned.Tables[i].Columns = columns
+	}
+	return &aligned
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestSchemaPatch(t *testing.T) {
+	before, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "before"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load before schema: %v", err)
+	}
+	after, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "after"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load after schema: %v", err)
+	}
+
+	got := schemaPatch(before, after, postgres.NewDriver(), "before", "after", schema.DiffOptions{})
+
+	goldenPath := filepath.Join("testdata", "patch", "schema.patch")
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("Output differs from %s (run with -update to accept):\n%s", goldenPath, got)
+	}
+}
+
+func TestSchemaPatch_IgnoresOrdering(t *testing.T) {
+	after, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "after"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+
+	// The same schema with tables and columns in a different order
+	reordered, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "json-full", "after"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	slices.Reverse(reordered.Tables)
+	for i := range reordered.Tables {
+		slices.Reverse(reordered.Tables[i].Columns)
+	}
+
+	if patch := schemaPatch(reordered, after, postgres.NewDriver(), "a", "b", schema.DiffOptions{}); patch != "" {
+		t.Errorf("Expected no patch for reordered tables and columns, got:\n%s", patch)
+	}
+	if patch := schemaPatch(reordered, after, postgres.NewDriver(), "a", "b", schema.DiffOptions{EnforceColumnOrder: true}); patch == "" {
+		t.Error("Expected column order changes in the patch when column order is enforced")
+	}
+}
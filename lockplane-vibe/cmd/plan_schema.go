@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/spf13/cobra"
+)
+
+var planSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for plan files",
+	Long: `Print a JSON Schema (draft-07) document describing the plan file format.
+
+The schema is generated from the plan types of this lockplane version, so it
+accepts exactly what apply accepts: unknown fields are rejected and every
+field has the JSON type lockplane writes. Point your editor at it to validate
+hand-edited plan files on save, either with a "$schema" key in the plan or
+through the editor's JSON Schema settings.`,
+	Example: `  # Save the schema and reference it from a plan file
+  lockplane plan schema > plan.schema.json
+  # then add "$schema": "./plan.schema.json" to migration.json`,
+	Args: cobra.NoArgs,
+	Run:  runPlanSchema,
+}
+
+func init() {
+	planCmd.AddCommand(planSchemaCmd)
+}
+
+func runPlanSchema(cmd *cobra.Command, args []string) {
+	jsonBytes, err := json.MarshalIndent(planner.PlanJSONSchema(), "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal plan schema: %v", err)
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -34,7 +34,7 @@ func TestPlanCommandFlags(t *testing.T) {
 	flags := planCmd.Flags()
 
 	// Test that required flags exist
-	requiredFlags := []string{"from", "to", "from-environment", "to-environment", "check-schema", "verbose"}
+	requiredFlags := []string{"from", "to", "from-environment", "to-environment", "check-schema", "verbose", "review", "cascade"}
 
 	for _, flagName := range requiredFlags {
 		flag := flags.Lookup(flagName)
@@ -57,7 +57,7 @@ func TestPlanCommandFlagTypes(t *testing.T) {
 	}
 
 	// Test boolean flags
-	boolFlags := []string{"check-schema", "verbose"}
+	boolFlags := []string{"check-schema", "verbose", "review", "cascade"}
 	for _, flagName := range boolFlags {
 		flag := flags.Lookup(flagName)
 		if flag != nil && flag.Value.Type() != "bool" {
@@ -3,11 +3,14 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"unicode"
 
 	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
 )
 
 func TestSplitSQLStatements(t *testing.T) {
@@ -66,6 +69,76 @@ CREATE TABLE posts(id int);`,
 				{Text: " -- comment\nCREATE TABLE posts(id int);", StartLine: 1}, // Space after semicolon is on line 1
 			},
 		},
+		{
+			name: "dollar-quoted function body",
+			sql: `CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TABLE posts(id int);`,
+			expected: []SQLStatement{
+				{Text: "CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at := now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;", StartLine: 1},
+				{Text: "\nCREATE TABLE posts(id int);", StartLine: 7},
+			},
+		},
+		{
+			name: "tagged dollar quote containing $$",
+			sql:  `SELECT $fn$ a; $$ b; $fn$; SELECT 1;`,
+			expected: []SQLStatement{
+				{Text: "SELECT $fn$ a; $$ b; $fn$;", StartLine: 1},
+				{Text: " SELECT 1;", StartLine: 1},
+			},
+		},
+		{
+			name: "positional parameter is not a dollar quote",
+			sql:  `SELECT $1; SELECT $2;`,
+			expected: []SQLStatement{
+				{Text: "SELECT $1;", StartLine: 1},
+				{Text: " SELECT $2;", StartLine: 1},
+			},
+		},
+		{
+			name: "block comment with semicolons",
+			sql: `/* setup; teardown;
+   /* nested; */ still comment; */
+CREATE TABLE users(id int);`,
+			expected: []SQLStatement{
+				{Text: "/* setup; teardown;\n   /* nested; */ still comment; */\nCREATE TABLE users(id int);", StartLine: 1},
+			},
+		},
+		{
+			name: "doubled quote in string literal",
+			sql:  `INSERT INTO t VALUES ('it''s; fine'); SELECT 1;`,
+			expected: []SQLStatement{
+				{Text: "INSERT INTO t VALUES ('it''s; fine');", StartLine: 1},
+				{Text: " SELECT 1;", StartLine: 1},
+			},
+		},
+		{
+			name: "backslash is literal in standard strings",
+			sql:  `SELECT 'C:\'; SELECT 1;`,
+			expected: []SQLStatement{
+				{Text: `SELECT 'C:\';`, StartLine: 1},
+				{Text: " SELECT 1;", StartLine: 1},
+			},
+		},
+		{
+			name: "backslash escapes in E strings",
+			sql:  `SELECT E'it\'s; fine'; SELECT 1;`,
+			expected: []SQLStatement{
+				{Text: `SELECT E'it\'s; fine';`, StartLine: 1},
+				{Text: " SELECT 1;", StartLine: 1},
+			},
+		},
+		{
+			name: "semicolon in quoted identifier",
+			sql:  `CREATE TABLE "odd;name"(id int);`,
+			expected: []SQLStatement{
+				{Text: `CREATE TABLE "odd;name"(id int);`, StartLine: 1},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -89,6 +162,53 @@ CREATE TABLE posts(id int);`,
 	}
 }
 
+func FuzzSplitSQLStatements(f *testing.F) {
+	for _, seed := range []string{
+		"CREATE TABLE users(id int);\nCREATE TABLE posts(id int);",
+		"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;",
+		"SELECT $tag$ ; $tag$; SELECT $1;",
+		"/* a; /* b; */ c; */ SELECT 1; -- trailing;\n",
+		"SELECT 'it''s;'; SELECT E'\\';' ; SELECT \"a;b\";",
+		"SELECT 'unterminated; ",
+		"\u00a0\n;\xff;",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, sqlText string) {
+		statements := splitSQLStatements(sqlText)
+
+		// Statements are contiguous slices of the input; only trailing
+		// whitespace may be dropped
+		var joined strings.Builder
+		prevLine := 1
+		for i, stmt := range statements {
+			if strings.TrimSpace(stmt.Text) == "" {
+				t.Fatalf("statement %d is blank: %q", i, stmt.Text)
+			}
+			if stmt.StartLine < prevLine {
+				t.Fatalf("statement %d starts on line %d, before previous statement line %d", i, stmt.StartLine, prevLine)
+			}
+			prevLine = stmt.StartLine
+
+			offset := joined.Len()
+			leading := len(stmt.Text) - len(strings.TrimLeftFunc(stmt.Text, unicode.IsSpace))
+			wantLine := 1 + strings.Count(sqlText[:offset+leading], "\n")
+			if stmt.StartLine != wantLine {
+				t.Fatalf("statement %d: expected start line %d, got %d", i, wantLine, stmt.StartLine)
+			}
+			joined.WriteString(stmt.Text)
+		}
+
+		if !strings.HasPrefix(sqlText, joined.String()) {
+			t.Fatalf("statements do not reproduce the input:\ninput:  %q\njoined: %q", sqlText, joined.String())
+		}
+		if rest := sqlText[joined.Len():]; strings.TrimSpace(rest) != "" {
+			t.Fatalf("non-whitespace input dropped: %q", rest)
+		}
+	})
+}
+
 func TestPreValidateSQLSyntax_PostgreSQL(t *testing.T) {
 	// Create temporary directory with test SQL files
 	tmpDir := t.TempDir()
@@ -206,7 +326,7 @@ CEATE INDEX idx2 ON users(id);`,
 			}
 
 			// Run validation
-			errors := preValidateSQLSyntax(testDir, database.DialectPostgres)
+			errors := preValidateSQLSyntax(testDir, database.DialectPostgres, nil)
 
 			// Check error count
 			if len(errors) != tt.expectedCount {
@@ -246,7 +366,7 @@ CEATE INDEX idx_posts_id ON posts(id);`
 		t.Fatal(err)
 	}
 
-	errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres)
+	errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres, nil)
 
 	if len(errors) != 2 {
 		t.Fatalf("expected 2 errors, got %d", len(errors))
@@ -263,6 +383,250 @@ CEATE INDEX idx_posts_id ON posts(id);`
 	}
 }
 
+func TestPreValidateSQLSyntax_IdempotentClauses(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `CREATE TABLE IF NOT EXISTS users (
+    id serial PRIMARY KEY,
+    email text
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS name text;
+DROP INDEX IF EXISTS idx_old;
+
+CREATE TABLE posts (id serial PRIMARY KEY);`
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "schema.sql"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var notes []SyntaxError
+	for _, diag := range preValidateSQLSyntax(tmpDir, database.DialectPostgres, nil) {
+		if diag.Code == "idempotent_clause" {
+			notes = append(notes, diag)
+		}
+	}
+
+	wantLines := []int{1, 6, 8, 9}
+	if len(notes) != len(wantLines) {
+		t.Fatalf("expected %d idempotent clause notes, got %d: %+v", len(wantLines), len(notes), notes)
+	}
+	for i, note := range notes {
+		if note.Severity != "info" {
+			t.Errorf("note %d: expected info severity, got %q", i, note.Severity)
+		}
+		if isErrorDiagnostic(note) {
+			t.Errorf("note %d: expected note not to fail validation", i)
+		}
+		if note.Line != wantLines[i] {
+			t.Errorf("note %d: expected line %d, got %d", i, wantLines[i], note.Line)
+		}
+	}
+	if notes[0].Column != 14 || !strings.HasPrefix(notes[0].Message, "IF NOT EXISTS isn't needed") {
+		t.Errorf("unexpected first note: %+v", notes[0])
+	}
+	if !strings.HasPrefix(notes[3].Message, "IF EXISTS isn't needed") {
+		t.Errorf("unexpected DROP note: %+v", notes[3])
+	}
+}
+
+func TestPreValidateSQLSyntax_TemporaryTables(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `CREATE TABLE users (id serial PRIMARY KEY);
+
+CREATE TEMP TABLE IF NOT EXISTS staging (id integer);
+ALTER TABLE staging ADD COLUMN email text;
+
+ALTER TABLE users ADD COLUMN name text;`
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "schema.sql"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics := preValidateSQLSyntax(tmpDir, database.DialectPostgres, nil)
+	var notes []SyntaxError
+	alterWarnings := 0
+	for _, diag := range diagnostics {
+		switch {
+		case diag.Code == "temporary_table":
+			notes = append(notes, diag)
+		case diag.Code == "idempotent_clause":
+			t.Errorf("expected no idempotent clause note on a temporary table: %+v", diag)
+		case strings.HasPrefix(diag.Message, "ALTER TABLE"):
+			alterWarnings++
+		}
+	}
+
+	if len(notes) != 1 {
+		t.Fatalf("expected 1 temporary table note, got %d: %+v", len(notes), diagnostics)
+	}
+	if notes[0].Severity != "info" || notes[0].Line != 3 || notes[0].Column != 33 {
+		t.Errorf("unexpected temporary table note: %+v", notes[0])
+	}
+	if !strings.Contains(notes[0].Message, "Temporary table staging is left out of the schema") {
+		t.Errorf("unexpected message: %s", notes[0].Message)
+	}
+	if alterWarnings != 1 {
+		t.Errorf("expected only the ALTER TABLE on users to be flagged, got %d warnings: %+v", alterWarnings, diagnostics)
+	}
+}
+
+func TestPreValidateSQLSyntax_PgDump(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `\restrict abc123
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE TABLE public.users (id integer NOT NULL, bio text);
+ALTER TABLE public.users OWNER TO app;
+COMMENT ON TABLE public.users IS 'Registered users';
+
+COPY public.users (id, bio) FROM stdin;
+1	it's; not SQL
+\.
+
+ALTER TABLE ONLY public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+\unrestrict abc123
+`
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "dump.sql"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var notes []SyntaxError
+	alterWarnings := 0
+	for _, diag := range preValidateSQLSyntax(tmpDir, database.DialectPostgres, nil) {
+		switch {
+		case diag.Code == "dump_statement":
+			notes = append(notes, diag)
+		case isErrorDiagnostic(diag):
+			t.Errorf("unexpected error: %+v", diag)
+		case strings.HasPrefix(diag.Message, "ALTER TABLE"):
+			alterWarnings++
+		}
+	}
+
+	if len(notes) != 3 {
+		t.Fatalf("expected 3 dump statement notes, got %d: %+v", len(notes), notes)
+	}
+	wantLines := []int{2, 3, 6}
+	for i, note := range notes {
+		if note.Severity != "info" || note.Line != wantLines[i] || note.Column != 1 {
+			t.Errorf("unexpected note %d: %+v", i, note)
+		}
+	}
+	if !strings.HasPrefix(notes[2].Message, "Ownership change is ignored") {
+		t.Errorf("unexpected OWNER TO note: %s", notes[2].Message)
+	}
+	if alterWarnings != 1 {
+		t.Errorf("expected only the ADD CONSTRAINT to be flagged as ALTER TABLE, got %d", alterWarnings)
+	}
+}
+
+func TestDuplicateDefinitionDiagnostics(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"001_users.lp.sql": "CREATE TABLE users (\n    id serial PRIMARY KEY\n);\n",
+		"002_posts.lp.sql": "CREATE TABLE posts (\n    id serial PRIMARY KEY\n);\n\nCREATE TABLE users (\n    id serial PRIMARY KEY\n);\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diagnostics := duplicateDefinitionDiagnostics(tmpDir, nil)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+	}
+
+	diag := diagnostics[0]
+	if diag.File != filepath.Join(tmpDir, "002_posts.lp.sql") || diag.Line != 5 {
+		t.Errorf("expected diagnostic at 002_posts.lp.sql:5, got %s:%d", diag.File, diag.Line)
+	}
+	if diag.Severity != "error" || diag.Code != "duplicate_definition" {
+		t.Errorf("unexpected severity/code: %s/%s", diag.Severity, diag.Code)
+	}
+	if !strings.Contains(diag.Message, "001_users.lp.sql:1:14") {
+		t.Errorf("expected message to point at the first definition, got %q", diag.Message)
+	}
+	if !strings.Contains(diag.Message, "--allow-duplicate-identical") {
+		t.Errorf("expected identical copies to suggest --allow-duplicate-identical, got %q", diag.Message)
+	}
+
+	diagnostics = duplicateDefinitionDiagnostics(tmpDir, &schema.SchemaLoadOptions{AllowIdenticalDuplicates: true})
+	if len(diagnostics) != 1 || diagnostics[0].Severity != "warning" {
+		t.Errorf("expected an allowed identical copy to be a warning, got %v", diagnostics)
+	}
+}
+
+func TestPreValidateSQLSyntax_Variables(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := `/* ${header} */
+CREATE TABLE users (
+    id serial PRIMARY KEY
+);
+
+GRANT SELECT ON users TO ${reader};
+
+CEATE INDEX idx_users_id ON users(id);`
+
+	testFile := filepath.Join(tmpDir, "schema.lp.sql")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &schema.SchemaLoadOptions{Variables: map[string]string{"header": "line one\nline two\nline three"}}
+	errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres, opts)
+
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errors), errors)
+	}
+
+	if errors[0].Code != "undefined_variable" || errors[0].Line != 6 || errors[0].Column != 26 {
+		t.Errorf("expected undefined_variable at 6:26, got %s at %d:%d", errors[0].Code, errors[0].Line, errors[0].Column)
+	}
+
+	// The syntax error is reported against the original file, not the expanded text
+	if errors[1].Line != 8 {
+		t.Errorf("expected syntax error on line 8, got line %d", errors[1].Line)
+	}
+}
+
+func TestPreValidateSQLSyntax_CRLF(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Saved on Windows, with an upper-case extension
+	content := "CREATE TABLE users (\r\n    id serial PRIMARY KEY,\r\n    email text,\r\n);\r\n\r\nALTER TABLE users ADD COLUMN ${missing} text;\r\n"
+	testFile := filepath.Join(tmpDir, "SCHEMA.SQL")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &schema.SchemaLoadOptions{Variables: map[string]string{}}
+	errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres, opts)
+
+	want := map[string][2]int{"undefined_variable": {6, 30}, "": {3, 15}}
+	if len(errors) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errors), errors)
+	}
+	for _, err := range errors {
+		if pos := want[err.Code]; err.Line != pos[0] || err.Column != pos[1] {
+			t.Errorf("expected %q at %d:%d, got %d:%d (%s)", err.Code, pos[0], pos[1], err.Line, err.Column, err.Message)
+		}
+	}
+
+	if location := findEntityInSQLFiles(tmpDir, "users"); location == nil || location.Line != 1 || location.Column != 14 {
+		t.Errorf("expected users at 1:14, got %+v", location)
+	}
+}
+
 func TestPreValidateSQLSyntax_StringLiterals(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -300,7 +664,7 @@ func TestPreValidateSQLSyntax_StringLiterals(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres)
+			errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres, nil)
 
 			if len(errors) != tt.expectedCount {
 				t.Errorf("expected %d errors, got %d", tt.expectedCount, len(errors))
@@ -697,7 +1061,7 @@ func TestTrailingCommaIntegration(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres)
+			errors := preValidateSQLSyntax(tmpDir, database.DialectPostgres, nil)
 
 			if len(errors) != 1 {
 				t.Fatalf("expected 1 error, got %d", len(errors))
@@ -718,3 +1082,24 @@ func TestTrailingCommaIntegration(t *testing.T) {
 		})
 	}
 }
+
+func TestSlowestStatements(t *testing.T) {
+	var profile []planner.StatementProfile
+	for i, ms := range []float64{3, 90, 1, 12, 40, 7, 12} {
+		profile = append(profile, planner.StatementProfile{Step: i + 1, DurationMS: ms})
+	}
+
+	var steps []int
+	for _, p := range slowestStatements(profile, 5) {
+		steps = append(steps, p.Step)
+	}
+	if want := []int{2, 5, 4, 7, 6}; !slices.Equal(steps, want) {
+		t.Errorf("expected steps %v, slowest first, got %v", want, steps)
+	}
+	if profile[0].Step != 1 {
+		t.Error("expected the profile to be left in execution order")
+	}
+	if got := slowestStatements(profile[:2], 5); len(got) != 2 {
+		t.Errorf("expected every statement of a short profile, got %d", len(got))
+	}
+}
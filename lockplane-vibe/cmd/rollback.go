@@ -1,12 +1,9 @@
 package cmd
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 
 	"github.com/fatih/color"
@@ -16,6 +13,7 @@ import (
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/lockplane/lockplane/internal/sqliteutil"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -62,6 +60,7 @@ var (
 	rollbackSkipShadow   bool
 	rollbackShadowDB     string
 	rollbackShadowSchema string
+	rollbackShadowPerRun bool
 	rollbackVerbose      bool
 
 	planRollbackPlan    string
@@ -84,6 +83,7 @@ func init() {
 	rollbackCmd.Flags().BoolVar(&rollbackSkipShadow, "skip-shadow", false, "Skip shadow DB validation (not recommended)")
 	rollbackCmd.Flags().StringVar(&rollbackShadowDB, "shadow-db", "", "Shadow database URL")
 	rollbackCmd.Flags().StringVar(&rollbackShadowSchema, "shadow-schema", "", "Shadow schema name (PostgreSQL only)")
+	rollbackCmd.Flags().BoolVar(&rollbackShadowPerRun, "shadow-schema-per-run", false, "Test in a unique shadow schema that is dropped afterwards (PostgreSQL only)")
 	rollbackCmd.Flags().BoolVarP(&rollbackVerbose, "verbose", "v", false, "Verbose logging")
 	_ = rollbackCmd.MarkFlagRequired("plan")
 
@@ -96,27 +96,27 @@ func init() {
 }
 
 func runRollback(cmd *cobra.Command, args []string) {
-	ctx := context.Background()
+	ctx := cmd.Context()
 
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	// Resolve target environment
 	resolvedTarget, err := config.ResolveEnvironment(cfg, rollbackTargetEnv)
 	if err != nil {
-		log.Fatalf("Failed to resolve target environment: %v", err)
+		fatalf(exitError, "Failed to resolve target environment: %v", err)
 	}
 
 	// Load the forward plan
 	if rollbackVerbose {
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "📖 Loading forward plan: %s\n", rollbackPlan)
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "📖 Loading forward plan: %s\n", rollbackPlan)
 	}
 	forwardPlan, err := planner.LoadJSONPlan(rollbackPlan)
 	if err != nil {
-		log.Fatalf("Failed to load forward plan: %v", err)
+		fatalf(exitError, "Failed to load forward plan: %v", err)
 	}
 
 	// Resolve target database connection
@@ -125,9 +125,9 @@ func runRollback(cmd *cobra.Command, args []string) {
 		targetConnStr = resolvedTarget.DatabaseURL
 	}
 	if targetConnStr == "" {
-		fmt.Fprintf(os.Stderr, "Error: no target database configured.\n\n")
-		fmt.Fprintf(os.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: no target database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
+		exit(1)
 	}
 
 	// Determine source ("before") schema for rollback generation
@@ -137,7 +137,7 @@ func runRollback(cmd *cobra.Command, args []string) {
 		var err error
 		resolvedFrom, err = config.ResolveEnvironment(cfg, rollbackFromEnv)
 		if err != nil {
-			log.Fatalf("Failed to resolve from environment: %v", err)
+			fatalf(exitError, "Failed to resolve from environment: %v", err)
 		}
 		sourceInput = resolvedFrom.DatabaseURL
 	}
@@ -146,7 +146,7 @@ func runRollback(cmd *cobra.Command, args []string) {
 	if sourceInput != "" {
 		// Load schema from file or database
 		if rollbackVerbose {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Loading 'before' schema from: %s\n", sourceInput)
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Loading 'before' schema from: %s\n", sourceInput)
 		}
 		// Use config dialect if available, otherwise detect from connection string
 		var rollbackFallback database.Dialect
@@ -155,37 +155,37 @@ func runRollback(cmd *cobra.Command, args []string) {
 		} else {
 			rollbackFallback = schema.DriverNameToDialect(executor.DetectDriver(sourceInput))
 		}
-		beforeSchema, err = executor.LoadSchemaOrIntrospectWithOptions(sourceInput, executor.BuildSchemaLoadOptions(sourceInput, rollbackFallback))
+		beforeSchema, err = executor.LoadSchemaOrIntrospectWithOptions(sourceInput, withSchemaFileOptions(executor.BuildSchemaLoadOptions(sourceInput, rollbackFallback), sourceInput, cfg, resolvedFrom, resolvedTarget))
 		if err != nil {
-			log.Fatalf("Failed to load before schema: %v", err)
+			fatalf(exitError, "Failed to load before schema: %v", err)
 		}
 	} else {
 		// No --from provided, show helpful error
-		fmt.Fprintf(os.Stderr, "Error: --from or --from-environment is required for rollback generation.\n\n")
-		fmt.Fprintf(os.Stderr, "The rollback command needs the schema that existed BEFORE the forward migration.\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		fmt.Fprintf(os.Stderr, "  1. Provide --from with a schema file/directory saved before the migration\n")
-		fmt.Fprintf(os.Stderr, "  2. Provide --from-environment pointing to a database with the original state\n")
-		fmt.Fprintf(os.Stderr, "  3. Use plan-rollback to generate rollback plan first:\n")
-		fmt.Fprintf(os.Stderr, "     lockplane plan-rollback --plan %s --from <before.json> > rollback.json\n", rollbackPlan)
-		fmt.Fprintf(os.Stderr, "     lockplane apply rollback.json --target-environment %s\n\n", resolvedTarget.Name)
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: --from or --from-environment is required for rollback generation.\n\n")
+		fmt.Fprintf(style.Stderr, "The rollback command needs the schema that existed BEFORE the forward migration.\n")
+		fmt.Fprintf(style.Stderr, "Options:\n")
+		fmt.Fprintf(style.Stderr, "  1. Provide --from with a schema file/directory saved before the migration\n")
+		fmt.Fprintf(style.Stderr, "  2. Provide --from-environment pointing to a database with the original state\n")
+		fmt.Fprintf(style.Stderr, "  3. Use plan-rollback to generate rollback plan first:\n")
+		fmt.Fprintf(style.Stderr, "     lockplane plan-rollback --plan %s --from <before.json> > rollback.json\n", rollbackPlan)
+		fmt.Fprintf(style.Stderr, "     lockplane apply rollback.json --target-environment %s\n\n", resolvedTarget.Name)
+		exit(1)
 	}
 
 	// Detect database driver from target connection string
 	mainDriverType := executor.DetectDriver(targetConnStr)
 	mainDriver, err := executor.NewDriver(mainDriverType)
 	if err != nil {
-		log.Fatalf("Failed to create database driver: %v", err)
+		fatalf(exitError, "Failed to create database driver: %v", err)
 	}
 
 	// Generate rollback plan
 	if rollbackVerbose {
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "⚙️  Generating rollback plan...\n")
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "⚙️  Generating rollback plan...\n")
 	}
 	rollbackPlan, err := planner.GenerateRollback(forwardPlan, beforeSchema, mainDriver)
 	if err != nil {
-		log.Fatalf("Failed to generate rollback: %v", err)
+		fatalf(exitError, "Failed to generate rollback: %v", err)
 	}
 
 	// Display rollback plan with colors
@@ -194,60 +194,60 @@ func runRollback(cmd *cobra.Command, args []string) {
 	gray := color.New(color.FgHiBlack)
 	red := color.New(color.FgRed, color.Bold)
 
-	_, _ = red.Fprintf(os.Stderr, "\n🔄 Rollback plan (%d steps):\n\n", len(rollbackPlan.Steps))
-	fmt.Fprintf(os.Stderr, "This will UNDO the changes from the forward migration.\n\n")
+	_, _ = red.Fprintf(style.Stderr, "\n🔄 Rollback plan (%d steps):\n\n", len(rollbackPlan.Steps))
+	fmt.Fprintf(style.Stderr, "This will UNDO the changes from the forward migration.\n\n")
 
 	for i, step := range rollbackPlan.Steps {
-		_, _ = green.Fprintf(os.Stderr, "  %d. ", i+1)
-		fmt.Fprintf(os.Stderr, "%s\n", step.Description)
+		_, _ = green.Fprintf(style.Stderr, "  %d. ", i+1)
+		fmt.Fprintf(style.Stderr, "%s\n", step.Description)
 		if len(step.SQL) > 0 {
 			if len(step.SQL) == 1 {
 				sql := step.SQL[0]
 				if len(sql) > 100 {
 					sql = sql[:100] + "..."
 				}
-				_, _ = gray.Fprintf(os.Stderr, "     SQL: ")
-				_, _ = yellow.Fprintf(os.Stderr, "%s\n", sql)
+				_, _ = gray.Fprintf(style.Stderr, "     SQL: ")
+				_, _ = yellow.Fprintf(style.Stderr, "%s\n", sql)
 			} else {
-				_, _ = gray.Fprintf(os.Stderr, "     SQL: ")
-				_, _ = yellow.Fprintf(os.Stderr, "%d statements\n", len(step.SQL))
+				_, _ = gray.Fprintf(style.Stderr, "     SQL: ")
+				_, _ = yellow.Fprintf(style.Stderr, "%d statements\n", len(step.SQL))
 			}
 		}
 	}
-	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(style.Stderr, "\n")
 
 	// Ask for confirmation unless --auto-approve
 	if !rollbackAutoApprove {
 		bold := color.New(color.Bold)
-		_, _ = bold.Fprintf(os.Stderr, "Do you want to perform this rollback?\n")
-		fmt.Fprintf(os.Stderr, "  Lockplane will UNDO the forward migration described above.\n")
-		_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "  Only 'yes' will be accepted to approve.\n\n")
-		fmt.Fprintf(os.Stderr, "  Enter a value: ")
+		_, _ = bold.Fprintf(style.Stderr, "Do you want to perform this rollback?\n")
+		fmt.Fprintf(style.Stderr, "  Lockplane will UNDO the forward migration described above.\n")
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "  Only 'yes' will be accepted to approve.\n\n")
+		fmt.Fprintf(style.Stderr, "  Enter a value: ")
 
 		var response string
 		_, err := fmt.Scanln(&response)
 		if err != nil {
-			_, _ = red.Fprintf(os.Stderr, "\nRollback cancelled.\n")
-			os.Exit(0)
+			_, _ = red.Fprintf(style.Stderr, "\nRollback cancelled.\n")
+			exit(0)
 		}
 
 		if response != "yes" {
-			_, _ = red.Fprintf(os.Stderr, "\nRollback cancelled.\n")
-			os.Exit(0)
+			_, _ = red.Fprintf(style.Stderr, "\nRollback cancelled.\n")
+			exit(0)
 		}
-		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(style.Stderr, "\n")
 	}
 
 	// Connect to main database
 	mainDriverName := executor.GetSQLDriverName(mainDriverType)
 	mainDB, err := sql.Open(mainDriverName, targetConnStr)
 	if err != nil {
-		log.Fatalf("Failed to connect to target database: %v", err)
+		fatalf(exitError, "Failed to connect to target database: %v", err)
 	}
 	defer func() { _ = mainDB.Close() }()
 
 	if err := mainDB.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping target database: %v", err)
+		fatalf(exitError, "Failed to ping target database: %v", err)
 	}
 
 	// Connect to shadow database if not skipped
@@ -271,15 +271,15 @@ func runRollback(cmd *cobra.Command, args []string) {
 		// For SQLite/libSQL, default to :memory: if no shadow DB configured
 		if shadowConnStr == "" && (mainDriverType == "sqlite" || mainDriverType == "sqlite3" || mainDriverType == "libsql") {
 			shadowConnStr = ":memory:"
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "ℹ️  Using in-memory shadow database (fast, zero config)\n")
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "ℹ️  Using in-memory shadow database (fast, zero config)\n")
 		} else if shadowConnStr == "" {
-			fmt.Fprintf(os.Stderr, "Error: no shadow database configured for environment %q.\n", resolvedShadow.Name)
-			fmt.Fprintf(os.Stderr, "Options:\n")
-			fmt.Fprintf(os.Stderr, "  - Add SHADOW_DATABASE_URL to .env.%s\n", resolvedShadow.Name)
-			fmt.Fprintf(os.Stderr, "  - Add/override SHADOW_SCHEMA (or --shadow-schema) to reuse the primary database\n")
-			fmt.Fprintf(os.Stderr, "  - Provide --shadow-db flag\n")
-			fmt.Fprintf(os.Stderr, "  - Use --skip-shadow to skip shadow DB validation (not recommended)\n")
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Error: no shadow database configured for environment %q.\n", resolvedShadow.Name)
+			fmt.Fprintf(style.Stderr, "Options:\n")
+			fmt.Fprintf(style.Stderr, "  - Add SHADOW_DATABASE_URL to .env.%s\n", resolvedShadow.Name)
+			fmt.Fprintf(style.Stderr, "  - Add/override SHADOW_SCHEMA (or --shadow-schema) to reuse the primary database\n")
+			fmt.Fprintf(style.Stderr, "  - Provide --shadow-db flag\n")
+			fmt.Fprintf(style.Stderr, "  - Use --skip-shadow to skip shadow DB validation (not recommended)\n")
+			exit(1)
 		}
 
 		// Detect shadow database driver type
@@ -288,75 +288,90 @@ func runRollback(cmd *cobra.Command, args []string) {
 		// For SQLite shadow DB (not :memory:), check if the database file exists and create it if needed
 		if (shadowDriverType == "sqlite" || shadowDriverType == "sqlite3") && shadowConnStr != ":memory:" {
 			if err := sqliteutil.EnsureSQLiteDatabase(shadowConnStr, "shadow", false); err != nil {
-				log.Fatalf("Failed to ensure shadow database: %v", err)
+				fatalf(exitError, "Failed to ensure shadow database: %v", err)
 			}
 		}
 
 		shadowDriverName := executor.GetSQLDriverName(shadowDriverType)
 		shadowDB, err = sql.Open(shadowDriverName, shadowConnStr)
 		if err != nil {
-			log.Fatalf("Failed to connect to shadow database: %v", err)
+			fatalf(exitError, "Failed to connect to shadow database: %v", err)
 		}
 		defer func() { _ = shadowDB.Close() }()
 
 		if err := shadowDB.PingContext(ctx); err != nil {
-			log.Fatalf("Failed to ping shadow database: %v", err)
+			fatalf(exitError, "Failed to ping shadow database: %v", err)
+		}
+
+		if err := applyShadowSettings(ctx, shadowDB, mainDriver.Name(), resolvedShadow, rollbackVerbose); err != nil {
+			fatalf(exitError, "Failed to configure shadow database: %v", err)
+		}
+
+		// Give this run its own schema so concurrent runs sharing the database
+		// don't drop each other's objects
+		if settings := runSchemaSettings(resolvedShadow, rollbackShadowPerRun); settings.Enabled && mainDriver.SupportsSchemas() {
+			name, err := setupRunSchema(ctx, shadowDB, mainDriver, shadowSchema, settings)
+			if err != nil {
+				fatalf(exitError, "Failed to create shadow schema: %v", err)
+			}
+			defer releaseRunSchema()
+			shadowSchema = name
 		}
 
 		// If shadow schema is configured and driver supports it, set up the schema
 		if shadowSchema != "" && mainDriver.SupportsSchemas() {
 			// Create shadow schema if it doesn't exist
 			if err := mainDriver.CreateSchema(ctx, shadowDB, shadowSchema); err != nil {
-				log.Fatalf("Failed to create shadow schema: %v", err)
+				fatalf(exitError, "Failed to create shadow schema: %v", err)
 			}
 
 			// Set search path to shadow schema
 			if err := mainDriver.SetSchema(ctx, shadowDB, shadowSchema); err != nil {
-				log.Fatalf("Failed to set shadow schema: %v", err)
+				fatalf(exitError, "Failed to set shadow schema: %v", err)
 			}
 
 			// Show clear message about what we're doing
 			if shadowConnStr == targetConnStr {
-				_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing rollback on shadow schema %q (same database)...\n", shadowSchema)
+				_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing rollback on shadow schema %q (same database)...\n", shadowSchema)
 			} else {
-				_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing rollback on shadow schema %q in separate database...\n", shadowSchema)
+				_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing rollback on shadow schema %q in separate database...\n", shadowSchema)
 			}
 		} else if shadowConnStr == ":memory:" {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing rollback on in-memory shadow database...\n")
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing rollback on in-memory shadow database...\n")
 		} else {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "🔍 Testing rollback on shadow database...\n")
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔍 Testing rollback on shadow database...\n")
 		}
 	} else {
-		_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "⚠️  Skipping shadow DB validation (--skip-shadow)\n")
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Skipping shadow DB validation (--skip-shadow)\n")
 	}
 
 	// Introspect current database state (needed for shadow DB validation)
 	currentSchema, err := mainDriver.IntrospectSchema(ctx, mainDB)
 	if err != nil {
-		log.Fatalf("Failed to introspect current database schema: %v", err)
+		fatalf(exitError, "Failed to introspect current database schema: %v", err)
 	}
 
 	// Apply the rollback plan
 	if rollbackVerbose {
-		_, _ = color.New(color.FgCyan, color.Bold).Fprintf(os.Stderr, "\n🚀 Executing rollback...\n\n")
+		_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "\n🚀 Executing rollback...\n\n")
 	}
 	result, err := executor.ApplyPlan(ctx, mainDB, rollbackPlan, shadowDB, (*database.Schema)(currentSchema), mainDriver, rollbackVerbose)
 	if err != nil {
-		_, _ = red.Fprintf(os.Stderr, "\n❌ Rollback failed: %v\n\n", err)
+		_, _ = red.Fprintf(style.Stderr, "\n❌ Rollback failed: %v\n\n", err)
 		if len(result.Errors) > 0 {
-			_, _ = red.Fprintf(os.Stderr, "Errors:\n")
+			_, _ = red.Fprintf(style.Stderr, "Errors:\n")
 			for _, e := range result.Errors {
-				fmt.Fprintf(os.Stderr, "  - %s\n", e)
+				fmt.Fprintf(style.Stderr, "  - %s\n", e)
 			}
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Success!
 	if result.Success {
 		green := color.New(color.FgGreen, color.Bold)
-		_, _ = green.Fprintf(os.Stderr, "\n✓ Rollback completed successfully!\n\n")
-		fmt.Fprintf(os.Stderr, "  Steps completed: %d/%d\n", result.StepsApplied, len(rollbackPlan.Steps))
+		_, _ = green.Fprintf(style.Stderr, "\n✓ Rollback completed successfully!\n\n")
+		fmt.Fprintf(style.Stderr, "  Steps completed: %d/%d\n", result.StepsApplied, len(rollbackPlan.Steps))
 	}
 }
 
@@ -364,19 +379,19 @@ func runPlanRollback(cmd *cobra.Command, args []string) {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	// Load forward plan
 	if planRollbackVerbose {
-		fmt.Fprintf(os.Stderr, "📄 Loading forward plan from: %s\n", planRollbackPlan)
+		fmt.Fprintf(style.Stderr, "📄 Loading forward plan from: %s\n", planRollbackPlan)
 	}
 	forwardPlan, err := planner.LoadJSONPlan(planRollbackPlan)
 	if err != nil {
-		log.Fatalf("Failed to load forward plan: %v", err)
+		fatalf(exitError, "Failed to load forward plan: %v", err)
 	}
 	if planRollbackVerbose {
-		fmt.Fprintf(os.Stderr, "✓ Loaded forward plan with %d steps\n", len(forwardPlan.Steps))
+		fmt.Fprintf(style.Stderr, "✓ Loaded forward plan with %d steps\n", len(forwardPlan.Steps))
 	}
 
 	// Resolve before schema
@@ -387,11 +402,11 @@ func runPlanRollback(cmd *cobra.Command, args []string) {
 			var err error
 			resolvedFrom, err = config.ResolveEnvironment(cfg, planRollbackFromEnv)
 			if err != nil {
-				log.Fatalf("Failed to resolve from environment: %v", err)
+				fatalf(exitError, "Failed to resolve from environment: %v", err)
 			}
 			fromInput = resolvedFrom.DatabaseURL
 		} else {
-			log.Fatal("--from or --from-environment is required")
+			fatal("--from or --from-environment is required")
 		}
 	}
 
@@ -403,7 +418,7 @@ func runPlanRollback(cmd *cobra.Command, args []string) {
 
 	driver, err := executor.NewDriver(driverType)
 	if err != nil {
-		log.Fatalf("Failed to create driver: %v", err)
+		fatalf(exitError, "Failed to create driver: %v", err)
 	}
 
 	// Use config dialect if available, otherwise detect from driver type
@@ -413,29 +428,29 @@ func runPlanRollback(cmd *cobra.Command, args []string) {
 	} else {
 		dialect = schema.DriverNameToDialect(driverType)
 	}
-	opts := executor.BuildSchemaLoadOptions(fromInput, dialect)
+	opts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(fromInput, dialect), fromInput, cfg, resolvedFrom)
 	if planRollbackVerbose {
-		fmt.Fprintf(os.Stderr, "🔍 Loading before schema from: %s\n", fromInput)
+		fmt.Fprintf(style.Stderr, "🔍 Loading before schema from: %s\n", fromInput)
 	}
 	beforeSchema, err := executor.LoadSchemaOrIntrospectWithOptions(fromInput, opts)
 	if err != nil {
-		log.Fatalf("Failed to load before schema: %v", err)
+		fatalf(exitError, "Failed to load before schema: %v", err)
 	}
 	if planRollbackVerbose {
-		fmt.Fprintf(os.Stderr, "✓ Before schema has %d tables\n", len(beforeSchema.Tables))
+		fmt.Fprintf(style.Stderr, "✓ Before schema has %d tables\n", len(beforeSchema.Tables))
 	}
 
 	// Generate rollback plan
 	if planRollbackVerbose {
-		fmt.Fprintf(os.Stderr, "🔍 Generating rollback plan...\n")
+		fmt.Fprintf(style.Stderr, "🔍 Generating rollback plan...\n")
 	}
 	rollbackPlan, err := planner.GenerateRollback(forwardPlan, beforeSchema, driver)
 	if err != nil {
-		log.Fatalf("Failed to generate rollback plan: %v", err)
+		fatalf(exitError, "Failed to generate rollback plan: %v", err)
 	}
 
 	if len(rollbackPlan.Steps) == 0 {
-		fmt.Fprintf(os.Stderr, "⚠️  No rollback steps generated - forward plan has no reversible operations\n")
+		fmt.Fprintf(style.Stderr, "⚠️  No rollback steps generated - forward plan has no reversible operations\n")
 		// Output empty plan
 		rollbackPlan = &planner.Plan{Steps: []planner.PlanStep{}}
 	}
@@ -443,7 +458,7 @@ func runPlanRollback(cmd *cobra.Command, args []string) {
 	// Output rollback plan as JSON
 	jsonBytes, err := json.MarshalIndent(rollbackPlan, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal rollback plan: %v", err)
+		fatalf(exitError, "Failed to marshal rollback plan: %v", err)
 	}
 	fmt.Println(string(jsonBytes))
 }
@@ -1,15 +1,14 @@
 package cmd
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
-	"log"
 
 	"github.com/lockplane/lockplane/internal/config"
 	"github.com/lockplane/lockplane/internal/executor"
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/state"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -63,47 +62,47 @@ func runRollbackPhase(cmd *cobra.Command, args []string) {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	// Load multi-phase plan
 	multiPhasePlan, err := loadMultiPhasePlan(planPath)
 	if err != nil {
-		log.Fatalf("Failed to load multi-phase plan: %v", err)
+		fatalf(exitError, "Failed to load multi-phase plan: %v", err)
 	}
 
 	// Load state
 	st, err := state.Load()
 	if err != nil {
-		log.Fatalf("Failed to load state: %v", err)
+		fatalf(exitError, "Failed to load state: %v", err)
 	}
 
 	// Determine which phase to rollback
 	phaseNumber := rbPhase
 	if phaseNumber == 0 {
 		if st.ActiveMigration == nil {
-			log.Fatal("No active migration to rollback")
+			fatal("No active migration to rollback")
 		}
 		phaseNumber = st.ActiveMigration.CurrentPhase
 		if phaseNumber == 0 {
-			log.Fatal("No phases have been executed yet")
+			fatal("No phases have been executed yet")
 		}
 		fmt.Printf("Rolling back current phase: %d\n", phaseNumber)
 	}
 
 	if phaseNumber < 1 || phaseNumber > multiPhasePlan.TotalPhases {
-		log.Fatalf("Invalid phase number %d (plan has %d phases)", phaseNumber, multiPhasePlan.TotalPhases)
+		fatalf(exitError, "Invalid phase number %d (plan has %d phases)", phaseNumber, multiPhasePlan.TotalPhases)
 	}
 
 	// Get the phase
 	phase := multiPhasePlan.Phases[phaseNumber-1]
 	if phase.Rollback == nil {
-		log.Fatalf("Phase %d does not have rollback instructions", phaseNumber)
+		fatalf(exitError, "Phase %d does not have rollback instructions", phaseNumber)
 	}
 
 	// Display rollback information
 	fmt.Printf("\n")
-	fmt.Printf("⚠️  Rolling Back Phase %d/%d: %s\n", phaseNumber, multiPhasePlan.TotalPhases, phase.Name)
+	fmt.Fprintf(style.Stdout, "⚠️  Rolling Back Phase %d/%d: %s\n", phaseNumber, multiPhasePlan.TotalPhases, phase.Name)
 	fmt.Printf("Description: %s\n", phase.Description)
 	fmt.Printf("\n")
 	fmt.Printf("Rollback: %s\n", phase.Rollback.Description)
@@ -111,13 +110,13 @@ func runRollbackPhase(cmd *cobra.Command, args []string) {
 		fmt.Printf("Note: %s\n", phase.Rollback.Note)
 	}
 	if phase.Rollback.Warning != "" {
-		fmt.Printf("⚠️  WARNING: %s\n", phase.Rollback.Warning)
+		fmt.Fprintf(style.Stdout, "⚠️  WARNING: %s\n", phase.Rollback.Warning)
 	}
 	fmt.Printf("\n")
 
 	// Show code changes required for rollback
 	if phase.Rollback.RequiresCode {
-		fmt.Printf("⚠️  Code Rollback Required:\n")
+		fmt.Fprintf(style.Stdout, "⚠️  Code Rollback Required:\n")
 		fmt.Printf("  This rollback requires reverting code changes from this phase.\n")
 		fmt.Printf("  Redeploy the previous version of your application before proceeding.\n")
 		fmt.Printf("\n")
@@ -136,17 +135,17 @@ func runRollbackPhase(cmd *cobra.Command, args []string) {
 
 	// Dry run mode
 	if rbDryRun {
-		fmt.Println("🔍 DRY RUN: No changes will be applied")
+		fmt.Fprintln(style.Stdout, "🔍 DRY RUN: No changes will be applied")
 		return
 	}
 
 	// Require approval unless auto-approved
 	if !rbAutoApprove {
-		fmt.Printf("⚠️  Proceed with phase %d rollback? This may cause data loss. (yes/no): ", phaseNumber)
+		fmt.Fprintf(style.Stdout, "⚠️  Proceed with phase %d rollback? This may cause data loss. (yes/no): ", phaseNumber)
 		var response string
 		_, err = fmt.Scanln(&response)
 		if err != nil {
-			log.Fatalf("Failed to read input: %v", err)
+			fatalf(exitError, "Failed to read input: %v", err)
 		}
 		if response != "yes" && response != "y" {
 			fmt.Println("Cancelled")
@@ -173,45 +172,45 @@ func runRollbackPhase(cmd *cobra.Command, args []string) {
 			st.ActiveMigration.PhasesCompleted = newCompleted
 			st.ActiveMigration.CurrentPhase = phaseNumber - 1
 			if err := st.Save(); err != nil {
-				log.Fatalf("Failed to update state: %v", err)
+				fatalf(exitError, "Failed to update state: %v", err)
 			}
 		}
 
-		fmt.Printf("✅ Phase %d rollback complete\n", phaseNumber)
+		fmt.Fprintf(style.Stdout, "✅ Phase %d rollback complete\n", phaseNumber)
 		return
 	}
 
 	// Resolve target database
 	targetConnStr, err := resolveConnection(cfg, rbTarget, rbTargetEnv, "target")
 	if err != nil {
-		log.Fatalf("Failed to resolve target database: %v", err)
+		fatalf(exitError, "Failed to resolve target database: %v", err)
 	}
 
 	// Detect database driver
 	driverName := executor.DetectDriver(targetConnStr)
 	driver, err := executor.NewDriver(driverName)
 	if err != nil {
-		log.Fatalf("Failed to create driver: %v", err)
+		fatalf(exitError, "Failed to create driver: %v", err)
 	}
 
 	// Open target database connection
-	ctx := context.Background()
+	ctx := cmd.Context()
 	sqlDriverName := executor.GetSQLDriverName(driverName)
 	targetDB, err := sql.Open(sqlDriverName, targetConnStr)
 	if err != nil {
-		log.Fatalf("Failed to connect to target database: %v", err)
+		fatalf(exitError, "Failed to connect to target database: %v", err)
 	}
 	defer func() { _ = targetDB.Close() }()
 
 	// Ping to verify connection
 	if err := targetDB.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping target database: %v", err)
+		fatalf(exitError, "Failed to ping target database: %v", err)
 	}
 
 	// Introspect current schema
-	currentSchema, err := executor.LoadSchemaFromConnectionString(targetConnStr)
+	currentSchema, err := executor.LoadSchemaFromConnectionStringContext(ctx, targetConnStr, nil)
 	if err != nil {
-		log.Fatalf("Failed to introspect current schema: %v", err)
+		fatalf(exitError, "Failed to introspect current schema: %v", err)
 	}
 
 	// Create a rollback plan
@@ -229,17 +228,17 @@ func runRollbackPhase(cmd *cobra.Command, args []string) {
 	result, err := executor.ApplyPlan(ctx, targetDB, rollbackPlan, nil, currentSchema, driver, rbVerbose)
 	if err != nil {
 		handleRollbackError(err, phaseNumber, phase)
-		log.Fatalf("Failed to execute rollback: %v", err)
+		fatalf(exitError, "Failed to execute rollback: %v", err)
 	}
 
 	if !result.Success {
-		fmt.Printf("❌ Rollback failed:\n")
+		fmt.Fprintf(style.Stdout, "❌ Rollback failed:\n")
 		for _, errMsg := range result.Errors {
 			fmt.Printf("  - %s\n", errMsg)
 		}
 		fmt.Printf("\n")
 		handleRollbackError(fmt.Errorf("rollback execution failed"), phaseNumber, phase)
-		log.Fatal("Rollback failed")
+		fatal("Rollback failed")
 	}
 
 	// Update state
@@ -254,35 +253,35 @@ func runRollbackPhase(cmd *cobra.Command, args []string) {
 		st.ActiveMigration.PhasesCompleted = newCompleted
 		st.ActiveMigration.CurrentPhase = phaseNumber - 1
 		if err := st.Save(); err != nil {
-			log.Fatalf("Failed to update state: %v", err)
+			fatalf(exitError, "Failed to update state: %v", err)
 		}
 	}
 
 	// Success
-	fmt.Printf("\n✅ Phase %d rollback complete\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "\n✅ Phase %d rollback complete\n", phaseNumber)
 
 	if phase.Rollback.RequiresCode {
-		fmt.Println("\n⚠️  Don't forget to redeploy the previous version of your application!")
+		fmt.Fprintln(style.Stdout, "\n⚠️  Don't forget to redeploy the previous version of your application!")
 	}
 }
 
 func handleRollbackError(err error, phaseNumber int, phase planner.Phase) {
-	fmt.Printf("\n⚠️  Phase %d rollback encountered an error\n\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "\n⚠️  Phase %d rollback encountered an error\n\n", phaseNumber)
 
 	fmt.Printf("Error: %v\n\n", err)
 
 	fmt.Printf("Current State:\n")
-	fmt.Printf("  • Rollback of phase %d FAILED\n", phaseNumber)
-	fmt.Printf("  • Database may be in an inconsistent state\n")
-	fmt.Printf("  • Phase %d is still marked as complete in state\n", phaseNumber)
-	fmt.Printf("  • State file: %s\n\n", state.StateFile)
+	fmt.Fprintf(style.Stdout, "  • Rollback of phase %d FAILED\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "  • Database may be in an inconsistent state\n")
+	fmt.Fprintf(style.Stdout, "  • Phase %d is still marked as complete in state\n", phaseNumber)
+	fmt.Fprintf(style.Stdout, "  • State file: %s\n\n", state.StateFile)
 
 	fmt.Printf("Recovery Actions:\n\n")
 
 	fmt.Printf("1. Investigate the error\n")
-	fmt.Printf("   • Check database connectivity and permissions\n")
-	fmt.Printf("   • Review database logs for details\n")
-	fmt.Printf("   • Verify rollback SQL is valid\n")
+	fmt.Fprintf(style.Stdout, "   • Check database connectivity and permissions\n")
+	fmt.Fprintf(style.Stdout, "   • Review database logs for details\n")
+	fmt.Fprintf(style.Stdout, "   • Verify rollback SQL is valid\n")
 	fmt.Printf("\n")
 
 	if phase.Rollback != nil && len(phase.Rollback.SQL) > 0 {
@@ -308,6 +307,6 @@ func handleRollbackError(err error, phaseNumber int, phase planner.Phase) {
 	fmt.Printf("\n")
 
 	fmt.Printf("For more help:\n")
-	fmt.Printf("  • Check phase status: lockplane phase-status\n")
-	fmt.Printf("  • View state: cat %s\n", state.StateFile)
+	fmt.Fprintf(style.Stdout, "  • Check phase status: lockplane phase-status\n")
+	fmt.Fprintf(style.Stdout, "  • View state: cat %s\n", state.StateFile)
 }
@@ -5,15 +5,34 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime/debug"
+	"sync"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/tracing"
 	"github.com/spf13/cobra"
 )
 
 var version = "0.9.0"
 
+// configFile is the global --config flag
+var configFile string
+
+// allowDuplicateIdentical is the global --allow-duplicate-identical flag
+var allowDuplicateIdentical bool
+
+// noColor and noEmoji are the global --no-color and --no-emoji flags
+var (
+	noColor bool
+	noEmoji bool
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "lockplane",
@@ -27,16 +46,112 @@ It provides:
   • Automatic rollback generation
   • SQL validation and safety checks`,
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		style.Configure(noColor, noEmoji)
+		config.SetConfigFile(configFile)
+		if !verboseEnabled(cmd) {
+			return nil
+		}
+		config.SetVerboseOutput(style.Stderr)
+		path, err := config.FindConfigFile()
+		if err != nil {
+			return err
+		}
+		if path != "" {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "📄 Using config file: %s\n", path)
+		} else {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "📄 No lockplane.toml found, using defaults\n")
+		}
+		for _, name := range config.OverrideVariables() {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🔀 Connection override active: %s takes precedence over .env files and lockplane.toml\n", name)
+		}
+		return nil
+	},
+}
+
+// verboseEnabled reports whether the command being run has --verbose set
+func verboseEnabled(cmd *cobra.Command) bool {
+	flag := cmd.Flags().Lookup("verbose")
+	return flag != nil && flag.Value.String() == "true"
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	if err := ExecuteContext(context.Background()); err != nil {
+		exit(1)
 	}
 }
 
+// ExecuteContext runs the root command with ctx as the context of every
+// subcommand. Spans recorded while the command runs become children of the
+// span carried by ctx (see tracing.ContextWithRemoteParent), so programs
+// embedding lockplane can link migration runs to their own traces.
+//
+// Unless the caller already installed a tracer with tracing.SetTracer, tracing
+// is configured from the standard OTEL_EXPORTER_OTLP_* environment variables
+// and stays disabled when they are unset.
+func ExecuteContext(ctx context.Context) error {
+	shutdown := func(context.Context) error { return nil }
+	if !tracing.Enabled() {
+		var err error
+		shutdown, err = tracing.InitFromEnv()
+		if err != nil {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Tracing disabled: %v\n", err)
+		}
+	}
+
+	// Join a trace started by whatever launched us (e.g. a CI job)
+	if _, ok := tracing.SpanContextFromContext(ctx); !ok {
+		if traceparent := os.Getenv(tracing.EnvTraceparent); traceparent != "" {
+			if parent, err := tracing.ContextWithRemoteParent(ctx, traceparent); err == nil {
+				ctx = parent
+			}
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, commandSpanName(os.Args[1:]))
+
+	// Commands that fail call exit rather than returning, so the span is
+	// ended and the queued spans flushed from beforeExit as well
+	finish := sync.OnceFunc(func() {
+		span.End()
+		flushCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(flushCtx); err != nil {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  %v\n", err)
+		}
+	})
+	defer finish()
+	beforeExit = func(code int) {
+		span.SetAttributes(tracing.Int("lockplane.exit_code", code))
+		if code != exitSuccess && code != exitChangesPresent {
+			span.RecordError(fmt.Errorf("exited with code %d", code))
+		}
+		finish()
+	}
+	defer func() { beforeExit = nil }()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// tracingShutdownTimeout bounds how long the CLI waits for queued spans to
+// reach the collector before exiting
+const tracingShutdownTimeout = 5 * time.Second
+
+// commandSpanName names the root span after the subcommand being run (e.g.
+// "lockplane apply")
+func commandSpanName(args []string) string {
+	if c, _, err := rootCmd.Find(args); err == nil && c != nil {
+		return c.CommandPath()
+	}
+	return rootCmd.Name()
+}
+
 func init() {
 	// Set version from build info if available
 	if info, ok := debug.ReadBuildInfo(); ok {
@@ -77,4 +192,9 @@ func init() {
 	}
 
 	rootCmd.Version = version
+
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to lockplane.toml (default: search the current directory and its parents)")
+	rootCmd.PersistentFlags().BoolVar(&allowDuplicateIdentical, "allow-duplicate-identical", false, "Accept tables, indexes and constraints defined more than once in schema files when the copies are identical")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also disabled by NO_COLOR, TERM=dumb and when stdout is not a terminal)")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Leave emoji out of the output")
 }
@@ -46,6 +46,8 @@ func TestCommandsRegistered(t *testing.T) {
 		"apply-phase":     false,
 		"rollback-phase":  false,
 		"phase-status":    false,
+		"graph":           false,
+		"config":          false,
 	}
 
 	for _, cmd := range commands {
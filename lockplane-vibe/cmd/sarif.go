@@ -0,0 +1,382 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+// SARIF 2.1.0 output for code scanning tools such as GitHub code scanning.
+// Only the subset of the format lockplane needs is modeled.
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolURI   = "https://github.com/lockplane/lockplane"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      sarifMessage       `json:"fullDescription"`
+	Help                 sarifMessage       `json:"help"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifRuleInfo describes a diagnostic code for the rule metadata shown by
+// code scanning tools
+type sarifRuleInfo struct {
+	short string
+	full  string
+	help  string
+	level string // Default SARIF level
+}
+
+// sarifRules describes the diagnostic codes lockplane reports. Codes missing
+// here still get a rule, with a description derived from the code.
+var sarifRules = map[string]sarifRuleInfo{
+	"syntax_error": {
+		short: "SQL syntax error",
+		full:  "The schema file contains SQL that PostgreSQL cannot parse.",
+		help:  "Fix the statement at the reported position. Run `lockplane plan --check-schema` to re-check.",
+		level: "error",
+	},
+	"schema_warning": {
+		short: "Schema warning",
+		full:  "The schema file contains a construct that is valid but likely a mistake.",
+		help:  "Review the statement at the reported position.",
+		level: "warning",
+	},
+	"schema_info": {
+		short: "Schema note",
+		full:  "Informational note about the schema file.",
+		help:  "No action is required.",
+		level: "note",
+	},
+	"runtime_error": {
+		short: "Schema failed to apply",
+		full:  "The schema parsed, but applying it to the shadow database failed.",
+		help:  "Fix the statement the database rejected, for example an object defined twice or a reference to a missing object.",
+		level: "error",
+	},
+	"validation_error": {
+		short: "Schema validation failed",
+		full:  "Lockplane could not validate the schema, for example because the shadow database is unreachable.",
+		help:  "Check the message for details and the shadow database settings in lockplane.toml.",
+		level: "error",
+	},
+	"undefined_variable": {
+		short: "Undefined schema variable",
+		full:  "The schema file references a ${name} variable that is not defined.",
+		help:  "Define the variable under [variables] in lockplane.toml, or under the environment's variables.",
+		level: "error",
+	},
+	"duplicate_definition": {
+		short: "Object defined more than once",
+		full:  "The same table or index is defined in more than one place in the schema files.",
+		help:  "Keep a single definition of each object.",
+		level: "error",
+	},
+	"idempotent_clause": {
+		short: "Imperative clause in declarative schema",
+		full:  "IF NOT EXISTS and similar clauses have no effect in a declarative schema.",
+		help:  "Remove the clause; lockplane plans the changes needed to reach the schema.",
+		level: "note",
+	},
+	"temporary_table": {
+		short: "Temporary table in schema",
+		full:  "CREATE TEMP TABLE only lasts for a session, so the table is not part of the schema and is never created or compared.",
+		help:  "Move transient setup statements out of the schema files, or keep them knowing lockplane ignores them.",
+		level: "note",
+	},
+	"dump_statement": {
+		short: "Session setting or ownership change in schema",
+		full:  "SET, set_config and ALTER ... OWNER TO statements, as written by pg_dump, are not part of the schema and are ignored.",
+		help:  "Remove them from the schema files, or keep them knowing lockplane ignores them.",
+		level: "note",
+	},
+	"dangerous_operation": {
+		short: "Dangerous operation",
+		full:  "The migration contains an operation that is hard or impossible to roll back.",
+		help:  "Review the safer alternatives, such as an expand/contract migration, before applying.",
+		level: "warning",
+	},
+	"data_loss": {
+		short: "Permanent data loss",
+		full:  "The migration drops data that cannot be recovered by rolling back.",
+		help:  "Archive the data first, or use a deprecation period: stop writes, stop reads, then drop.",
+		level: "warning",
+	},
+	"lossy_rollback": {
+		short: "Lossy rollback",
+		full:  "The migration can be rolled back, but the rollback loses data.",
+		help:  "Check that the rollback behavior is acceptable before applying.",
+		level: "warning",
+	},
+	"multi_phase_required": {
+		short: "Multi-phase migration required",
+		full:  "The change breaks running application versions unless it is split across several deployments.",
+		help:  "Split the change into expand and contract phases deployed separately.",
+		level: "warning",
+	},
+	"requires_review": {
+		short: "Operation requires review",
+		full:  "The migration contains an operation that may lock tables or otherwise needs a closer look.",
+		help:  "Review the operation and when it is applied.",
+		level: "warning",
+	},
+	"immutable_table": {
+		short: "Change to an immutable table",
+		full:  "The migration alters or drops a table declared immutable in lockplane.toml or with -- lockplane:immutable.",
+		help:  "Create a new table instead, or pass --allow-immutable-change with the reason the change is needed.",
+		level: "error",
+	},
+	"unsafe_operation": {
+		short: "Unsafe operation",
+		full:  "The migration contains an operation that lockplane blocks.",
+		help:  "Change the schema so the operation can be applied safely.",
+		level: "error",
+	},
+}
+
+// newSARIFLog converts diagnostics into a SARIF log with one run. Rules are
+// listed in the order their codes first appear.
+func newSARIFLog(diagnostics []SyntaxError) sarifLog {
+	rules := []sarifRule{}
+	ruleIndex := map[string]int{}
+	results := []sarifResult{}
+
+	for _, diag := range diagnostics {
+		code := diagnosticCode(diag)
+		index, ok := ruleIndex[code]
+		if !ok {
+			index = len(rules)
+			ruleIndex[code] = index
+			rules = append(rules, newSARIFRule(code, sarifLevel(diag.Severity)))
+		}
+
+		result := sarifResult{
+			RuleID:    code,
+			RuleIndex: index,
+			Level:     sarifLevel(diag.Severity),
+			Message:   sarifMessage{Text: diag.Message},
+		}
+		if diag.File != "" {
+			location := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: sarifURI(diag.File)},
+				},
+			}
+			if diag.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: diag.Line, StartColumn: diag.Column}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		results = append(results, result)
+	}
+
+	return sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "lockplane",
+						Version:        version,
+						InformationURI: sarifToolURI,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// newSARIFRule returns the rule for a diagnostic code. level is the default
+// level for codes without metadata.
+func newSARIFRule(code, level string) sarifRule {
+	info, ok := sarifRules[code]
+	if !ok {
+		text := strings.ReplaceAll(code, "_", " ")
+		info = sarifRuleInfo{
+			short: strings.ToUpper(text[:1]) + text[1:],
+			full:  fmt.Sprintf("Lockplane reported %s.", text),
+			help:  "Review the reported statement.",
+			level: level,
+		}
+	}
+
+	// Rule names are the code in PascalCase, e.g. SyntaxError
+	var name strings.Builder
+	for _, part := range strings.Split(code, "_") {
+		if part != "" {
+			name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	return sarifRule{
+		ID:                   code,
+		Name:                 name.String(),
+		ShortDescription:     sarifMessage{Text: info.short},
+		FullDescription:      sarifMessage{Text: info.full},
+		Help:                 sarifMessage{Text: info.help},
+		DefaultConfiguration: sarifConfiguration{Level: info.level},
+	}
+}
+
+// sarifLevel maps a diagnostic severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case "warning":
+		return "warning"
+	case "info":
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// sarifURI returns path relative to the working directory with forward
+// slashes, so code scanning can match it to a file in the repository
+func sarifURI(path string) string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// safetyDiagnostics converts a migration safety report into diagnostics.
+// Safe operations without warnings are left out.
+func safetyDiagnostics(results []validation.ValidationResult) []SyntaxError {
+	var diagnostics []SyntaxError
+	for _, result := range results {
+		safe := result.Safety == nil || result.Safety.Level == validation.SafetyLevelSafe
+		if result.Valid && safe && len(result.Warnings) == 0 {
+			continue
+		}
+
+		severity := "warning"
+		if !result.Valid {
+			severity = "error"
+		}
+
+		messages := append(append([]string{}, result.Errors...), result.Warnings...)
+		if len(messages) == 0 {
+			messages = result.Reasons
+		}
+		if result.Safety != nil && len(result.Safety.SaferAlternatives) > 0 {
+			messages = append(messages, "Safer alternatives: "+strings.Join(result.Safety.SaferAlternatives, "; "))
+		}
+
+		diag := SyntaxError{
+			Message:  strings.Join(messages, "\n"),
+			Severity: severity,
+			Code:     safetyCode(result),
+		}
+		if result.Source != nil {
+			diag.File = result.Source.File
+			diag.Line = result.Source.Line
+			diag.Column = result.Source.Column
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+	return diagnostics
+}
+
+// safetyCode returns the diagnostic code for a safety report entry
+func safetyCode(result validation.ValidationResult) string {
+	if result.Code != "" {
+		return result.Code
+	}
+	if result.Safety != nil {
+		if result.Safety.DataLoss {
+			return "data_loss"
+		}
+		switch result.Safety.Level {
+		case validation.SafetyLevelDangerous:
+			return "dangerous_operation"
+		case validation.SafetyLevelLossy:
+			return "lossy_rollback"
+		case validation.SafetyLevelMultiPhase:
+			return "multi_phase_required"
+		case validation.SafetyLevelReview:
+			return "requires_review"
+		}
+	}
+	if !result.Valid {
+		return "unsafe_operation"
+	}
+	return "schema_warning"
+}
+
+// printSARIF writes diagnostics to stdout as a SARIF log
+func printSARIF(diagnostics []SyntaxError) {
+	jsonBytes, _ := json.MarshalIndent(newSARIFLog(diagnostics), "", "  ")
+	fmt.Println(string(jsonBytes))
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/validation"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var updateGolden = flag.Bool("update", false, "Rewrite golden files in testdata")
+
+// checkSARIFGolden validates diagnostics rendered as SARIF against the SARIF
+// schema and compares them with testdata/sarif/<name>.sarif
+func checkSARIFGolden(t *testing.T, name string, diagnostics []SyntaxError) {
+	t.Helper()
+
+	savedVersion := version
+	version = "test"
+	t.Cleanup(func() { version = savedVersion })
+
+	got, err := json.MarshalIndent(newSARIFLog(diagnostics), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal SARIF: %v", err)
+	}
+	got = append(got, '\n')
+
+	schemaPath, err := filepath.Abs(filepath.Join("testdata", "sarif", "sarif-schema-2.1.0.json"))
+	if err != nil {
+		t.Fatalf("Failed to resolve schema path: %v", err)
+	}
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewReferenceLoader("file://"+filepath.ToSlash(schemaPath)),
+		gojsonschema.NewBytesLoader(got),
+	)
+	if err != nil {
+		t.Fatalf("Failed to validate SARIF: %v", err)
+	}
+	for _, desc := range result.Errors() {
+		t.Errorf("SARIF schema violation: %s", desc)
+	}
+
+	goldenPath := filepath.Join("testdata", "sarif", name+".sarif")
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("SARIF differs from %s (run with -update to accept):\n%s", goldenPath, got)
+	}
+}
+
+func TestSARIFSyntaxDiagnostics(t *testing.T) {
+	dir := filepath.Join("testdata", "sarif", "syntax", "schema")
+	diagnostics := preValidateSQLSyntax(dir, database.DialectPostgres, nil)
+	if len(diagnostics) == 0 {
+		t.Fatal("Expected diagnostics for the test schema")
+	}
+	checkSARIFGolden(t, "syntax", diagnostics)
+}
+
+func TestSARIFSafetyFindings(t *testing.T) {
+	before, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "sarif", "safety", "before"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load before schema: %v", err)
+	}
+	after, err := schema.LoadSchemaWithOptions(filepath.Join("testdata", "sarif", "safety", "after"), nil)
+	if err != nil {
+		t.Fatalf("Failed to load after schema: %v", err)
+	}
+	diff := schema.DiffSchemas(before, after)
+	results := validation.ValidateSchemaDiffWithSchemas(diff, before, after, false)
+
+	checkSARIFGolden(t, "safety", safetyDiagnostics(results))
+}
+
+func TestSARIFRuntimeAndValidationErrors(t *testing.T) {
+	diagnostics := []SyntaxError{
+		{
+			File:     filepath.Join("schema", "indexes.lp.sql"),
+			Line:     4,
+			Column:   14,
+			Message:  `step 3 failed: pq: relation "idx_users_email" already exists`,
+			Severity: "error",
+			Code:     "runtime_error",
+		},
+		{Message: "Failed to connect to shadow database: dial tcp 127.0.0.1:5433: connect: connection refused", Severity: "error", Code: "validation_error"},
+	}
+	checkSARIFGolden(t, "errors", diagnostics)
+}
+
+func TestSARIFLevelAndURI(t *testing.T) {
+	for severity, want := range map[string]string{"": "error", "error": "error", "warning": "warning", "info": "note"} {
+		if got := sarifLevel(severity); got != want {
+			t.Errorf("sarifLevel(%q) = %q, want %q", severity, got, want)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if got := sarifURI(filepath.Join(wd, "schema", "users.lp.sql")); got != "schema/users.lp.sql" {
+		t.Errorf("Expected path relative to the working directory, got %q", got)
+	}
+}
@@ -41,3 +41,9 @@ func detectDefaultSchemaDir() (path string, label string) {
 	}
 	return "", ""
 }
+
+// isSQLFile reports whether path has a .sql extension, in any case, as
+// editors on Windows may save it
+func isSQLFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".sql")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/spf13/cobra"
+)
+
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Load seed data into a database",
+	Long: `Run the .sql files in the seeds directory against the target database.
+
+Files run in lexical order of their names (e.g. 001_roles.sql before
+002_users.sql) inside a single transaction: if any file fails, none of the
+seed data is kept. Seeds run against the target database only, never the
+shadow database.
+
+Seeds run every time, so write them to be idempotent, for example with
+INSERT ... ON CONFLICT DO NOTHING.
+
+The seeds directory defaults to seeds/ and can be set with --seeds-dir. To seed
+right after a migration, use lockplane apply --with-seeds.`,
+	Example: `  # Load seeds/*.sql into the local environment
+  lockplane seed --target-environment local
+
+  # Use a different seeds directory
+  lockplane seed --seeds-dir db/seeds --target-environment local
+
+  # Apply the schema and then the seeds
+  lockplane apply --target-environment local --with-seeds`,
+	Run: runSeed,
+}
+
+var (
+	seedTarget    string
+	seedTargetEnv string
+	seedDir       string
+	seedVerbose   bool
+)
+
+func init() {
+	rootCmd.AddCommand(seedCmd)
+
+	seedCmd.Flags().StringVar(&seedTarget, "target", "", "Target database URL")
+	seedCmd.Flags().StringVar(&seedTargetEnv, "target-environment", "", "Target environment name")
+	seedCmd.Flags().StringVar(&seedDir, "seeds-dir", "", "Directory of .sql seed files (default: seeds/)")
+	seedCmd.Flags().BoolVarP(&seedVerbose, "verbose", "v", false, "Verbose logging")
+}
+
+func runSeed(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf(exitError, "Failed to load config: %v", err)
+	}
+
+	resolvedTarget, err := config.ResolveEnvironment(cfg, seedTargetEnv)
+	if err != nil {
+		fatalf(exitError, "Failed to resolve target environment: %v", err)
+	}
+
+	dir, err := resolveSeedsDir(seedDir)
+	if err != nil {
+		fmt.Fprintf(style.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+
+	targetConnStr := strings.TrimSpace(seedTarget)
+	if targetConnStr == "" {
+		targetConnStr = resolvedTarget.DatabaseURL
+	}
+	if targetConnStr == "" {
+		fmt.Fprintf(style.Stderr, "Error: no target database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Provide --target or configure environment %q via lockplane.toml/.env.%s.\n", resolvedTarget.Name, resolvedTarget.Name)
+		exit(1)
+	}
+
+	result := seedDatabase(ctx, targetConnStr, dir, seedVerbose)
+
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fatalf(exitError, "Failed to marshal result to JSON: %v", err)
+	}
+	fmt.Println(string(jsonBytes))
+}
+
+// resolveSeedsDir returns the seeds directory: the explicit path if given,
+// otherwise the conventional seeds/ directory
+func resolveSeedsDir(explicit string) (string, error) {
+	dir := strings.TrimSpace(explicit)
+	if dir == "" {
+		dir = executor.DefaultSeedsDir
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		if explicit == "" {
+			return "", fmt.Errorf("no seeds directory found (expected %s/ or --seeds-dir)", executor.DefaultSeedsDir)
+		}
+		return "", fmt.Errorf("seeds directory %q does not exist", dir)
+	}
+	return dir, nil
+}
+
+// seedDatabase runs the seed files in dir against the database and exits on failure
+func seedDatabase(ctx context.Context, connStr, dir string, verbose bool) *executor.SeedResult {
+	files, err := executor.LoadSeedFiles(dir)
+	if err != nil {
+		fatalf(exitError, "Failed to load seed files: %v", err)
+	}
+	if len(files) == 0 {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  No .sql seed files found in %s\n", dir)
+		return &executor.SeedResult{Success: true, SeedsApplied: []string{}}
+	}
+
+	db, err := sql.Open(executor.GetSQLDriverName(executor.DetectDriver(connStr)), connStr)
+	if err != nil {
+		fatalf(exitError, "Failed to connect to target database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.PingContext(ctx); err != nil {
+		fatalf(exitError, "Failed to ping target database: %v", err)
+	}
+
+	_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "🌱 Applying %d seed file(s) from %s...\n", len(files), dir)
+	if verbose {
+		for _, file := range files {
+			fmt.Fprintf(style.Stderr, "   - %s\n", file.Name)
+		}
+	}
+
+	result, err := executor.ApplySeeds(ctx, db, files)
+	if err != nil {
+		_, _ = color.New(color.FgRed, color.Bold).Fprintf(style.Stderr, "\n❌ Seeding failed: %v\n", err)
+		fmt.Fprintf(style.Stderr, "   The seed transaction was rolled back; no seed data was written.\n\n")
+		exit(1)
+	}
+
+	_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ Applied %d seed file(s)\n", len(result.SeedsApplied))
+	return result
+}
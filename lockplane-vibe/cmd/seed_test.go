@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSeedCommand(t *testing.T) {
+	if seedCmd == nil {
+		t.Fatal("seedCmd should not be nil")
+	}
+
+	if seedCmd.Use != "seed" {
+		t.Errorf("expected Use to be 'seed', got %q", seedCmd.Use)
+	}
+
+	if seedCmd.Short == "" || seedCmd.Long == "" || seedCmd.Example == "" {
+		t.Error("seedCmd should have Short, Long and Example text")
+	}
+
+	if seedCmd.Run == nil {
+		t.Error("seedCmd.Run should not be nil")
+	}
+}
+
+func TestSeedCommandFlags(t *testing.T) {
+	flags := seedCmd.Flags()
+
+	for _, flagName := range []string{"target", "target-environment", "seeds-dir", "verbose"} {
+		if flags.Lookup(flagName) == nil {
+			t.Errorf("expected flag %q to exist", flagName)
+		}
+	}
+}
+
+func TestResolveSeedsDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("chdir temp: %v", err)
+	}
+
+	if _, err := resolveSeedsDir(""); err == nil {
+		t.Fatal("expected error when no seeds directory exists")
+	}
+	if _, err := resolveSeedsDir("db/seeds"); err == nil {
+		t.Fatal("expected error for missing explicit seeds directory")
+	}
+
+	if err := os.Mkdir("seeds", 0o755); err != nil {
+		t.Fatalf("mkdir seeds: %v", err)
+	}
+	if dir, err := resolveSeedsDir(""); err != nil || dir != "seeds" {
+		t.Fatalf("expected conventional seeds directory, got %q (%v)", dir, err)
+	}
+
+	if err := os.MkdirAll("db/seeds", 0o755); err != nil {
+		t.Fatalf("mkdir db/seeds: %v", err)
+	}
+	if dir, err := resolveSeedsDir("db/seeds"); err != nil || dir != "db/seeds" {
+		t.Fatalf("expected explicit seeds directory, got %q (%v)", dir, err)
+	}
+}
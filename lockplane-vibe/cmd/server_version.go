@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+// configuredPostgresVersion returns the target_postgres_version of env, or
+// the global one when no environment was resolved (0 = not pinned)
+func configuredPostgresVersion(cfg *config.Config, env *config.ResolvedEnvironment) int {
+	if env != nil {
+		return env.TargetPostgresVersion
+	}
+	version, err := cfg.GlobalTargetPostgresVersion()
+	if err != nil {
+		fatalf(exitError, "Invalid lockplane.toml: %v", err)
+	}
+	return version
+}
+
+// targetServerVersion returns the server_version_num a plan from source is
+// checked against. A source introspected from a live server is checked
+// against that server, with a warning when it isn't the configured release;
+// schema files are checked against the configured release. SQLite sources
+// have no server version.
+func targetServerVersion(source *database.Schema, configured int) int {
+	if source == nil || source.Dialect == database.DialectSQLite {
+		return 0
+	}
+	if source.ServerVersion == 0 {
+		return configured
+	}
+	if configured != 0 && serverVersionMismatch(configured, source.ServerVersion) {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr,
+			"⚠️  target_postgres_version is %s, but the database runs PostgreSQL %s; checking the plan against the database. Update target_postgres_version to match the server.\n",
+			postgres.FormatServerVersion(configured), postgres.FormatServerVersion(source.ServerVersion))
+	}
+	return source.ServerVersion
+}
+
+// serverVersionMismatch reports whether a detected server isn't the
+// configured release. A configured major release ("15") matches any of its
+// minor releases.
+func serverVersionMismatch(configured, detected int) bool {
+	if postgres.MajorVersion(configured) != postgres.MajorVersion(detected) {
+		return true
+	}
+	return configured != postgres.MajorVersion(configured) && configured != detected
+}
+
+// checkServerSupport exits before a plan is generated when the server it
+// targets can't run the SQL the diff needs, e.g. UNIQUE NULLS NOT DISTINCT on
+// PostgreSQL 13, instead of letting apply fail halfway
+func checkServerSupport(diff *schema.SchemaDiff, before *database.Schema, opts validation.ValidationOptions) {
+	results := validation.ValidateServerSupport(diff, before, opts)
+	if len(results) == 0 {
+		return
+	}
+	printValidationReport(results, "=== Server Compatibility ===")
+	fmt.Fprintf(style.Stderr, "❌ The target server can't run %d operation(s) in this plan. Upgrade the server, change the schema, or correct target_postgres_version in lockplane.toml.\n\n", len(results))
+	exit(validationExitCode(results))
+}
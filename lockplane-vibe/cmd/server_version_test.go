@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestServerVersionMismatch(t *testing.T) {
+	cases := []struct {
+		configured, detected int
+		want                 bool
+	}{
+		{150000, 150004, false},
+		{150004, 150004, false},
+		{150004, 150006, true},
+		{130000, 150004, true},
+		{90600, 90624, false},
+	}
+	for _, tc := range cases {
+		if got := serverVersionMismatch(tc.configured, tc.detected); got != tc.want {
+			t.Errorf("serverVersionMismatch(%d, %d) = %v, want %v", tc.configured, tc.detected, got, tc.want)
+		}
+	}
+}
+
+func TestTargetServerVersion(t *testing.T) {
+	files := &database.Schema{Dialect: database.DialectPostgres}
+	if got := targetServerVersion(files, 130000); got != 130000 {
+		t.Errorf("Expected schema files to be checked against the configured version, got %d", got)
+	}
+
+	live := &database.Schema{Dialect: database.DialectPostgres, ServerVersion: 150004}
+	if got := targetServerVersion(live, 130000); got != 150004 {
+		t.Errorf("Expected a live database to be checked against its own version, got %d", got)
+	}
+	if got := targetServerVersion(live, 0); got != 150004 {
+		t.Errorf("Expected the detected version when none is configured, got %d", got)
+	}
+
+	sqlite := &database.Schema{Dialect: database.DialectSQLite, ServerVersion: 150004}
+	if got := targetServerVersion(sqlite, 130000); got != 0 {
+		t.Errorf("Expected SQLite to have no server version, got %d", got)
+	}
+}
@@ -4,18 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/internal/config"
 	"github.com/lockplane/lockplane/internal/executor"
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/lockplane/lockplane/internal/shadow"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -67,12 +68,12 @@ func init() {
 func runShadowPrepare(cmd *cobra.Command, args []string) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	env, err := config.ResolveEnvironment(cfg, shadowEnv)
 	if err != nil {
-		log.Fatalf("Failed to resolve environment: %v", err)
+		fatalf(exitError, "Failed to resolve environment: %v", err)
 	}
 
 	shadowURL := strings.TrimSpace(shadowShadowDB)
@@ -88,16 +89,16 @@ func runShadowPrepare(cmd *cobra.Command, args []string) {
 	}
 
 	if shadowURL == "" {
-		fmt.Fprintf(os.Stderr, "Error: No shadow database configured.\n\n")
-		fmt.Fprintf(os.Stderr, "Set shadow_database_url in lockplane.toml or pass --shadow-db.\n")
-		fmt.Fprintf(os.Stderr, "Use 'lockplane init' to scaffold shadow DB settings.\n")
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Error: No shadow database configured.\n\n")
+		fmt.Fprintf(style.Stderr, "Set shadow_database_url in lockplane.toml or pass --shadow-db.\n")
+		fmt.Fprintf(style.Stderr, "Use 'lockplane init' to scaffold shadow DB settings.\n")
+		exit(1)
 	}
 
 	if !shadowForcePrepare {
 		if existing, err := shadow.LoadReservation(); err == nil && existing != nil {
-			fmt.Fprintf(os.Stderr, "Shadow DB already prepared for environment %q.\nUse --force or 'lockplane shadow release' to overwrite.\n", existing.Environment)
-			os.Exit(1)
+			fmt.Fprintf(style.Stderr, "Shadow DB already prepared for environment %q.\nUse --force or 'lockplane shadow release' to overwrite.\n", existing.Environment)
+			exit(1)
 		}
 	}
 
@@ -105,31 +106,31 @@ func runShadowPrepare(cmd *cobra.Command, args []string) {
 	sqlDriverName := executor.GetSQLDriverName(driverType)
 	db, err := sql.Open(sqlDriverName, shadowURL)
 	if err != nil {
-		log.Fatalf("Failed to open shadow database: %v", err)
+		fatalf(exitError, "Failed to open shadow database: %v", err)
 	}
 	defer func() { _ = db.Close() }()
 
-	ctx := context.Background()
+	ctx := cmd.Context()
 	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Failed to ping shadow database: %v", err)
+		fatalf(exitError, "Failed to ping shadow database: %v", err)
 	}
 
 	driver, err := executor.NewDriver(driverType)
 	if err != nil {
-		log.Fatalf("Failed to create database driver: %v", err)
+		fatalf(exitError, "Failed to create database driver: %v", err)
 	}
 
 	if shadowSchema != "" && driver.SupportsSchemas() {
 		if err := driver.CreateSchema(ctx, db, shadowSchema); err != nil {
-			log.Fatalf("Failed to create shadow schema: %v", err)
+			fatalf(exitError, "Failed to create shadow schema: %v", err)
 		}
 		if err := driver.SetSchema(ctx, db, shadowSchema); err != nil {
-			log.Fatalf("Failed to set shadow schema: %v", err)
+			fatalf(exitError, "Failed to set shadow schema: %v", err)
 		}
 	}
 
 	if err := executor.CleanupShadowDB(ctx, db, driver, shadowVerbose); err != nil {
-		log.Fatalf("Failed to clean shadow database: %v", err)
+		fatalf(exitError, "Failed to clean shadow database: %v", err)
 	}
 
 	reservation := &shadow.Reservation{
@@ -139,16 +140,16 @@ func runShadowPrepare(cmd *cobra.Command, args []string) {
 		CreatedAt:    time.Now().UTC(),
 	}
 	if err := shadow.SaveReservation(reservation); err != nil {
-		log.Fatalf("Failed to save reservation: %v", err)
+		fatalf(exitError, "Failed to save reservation: %v", err)
 	}
 
 	if shadowOutputJSON {
 		output, _ := json.MarshalIndent(reservation, "", "  ")
 		fmt.Println(string(output))
 	} else {
-		fmt.Fprintf(os.Stderr, "Shadow DB prepared for %s\nConnection: %s\n", env.Name, shadowURL)
+		fmt.Fprintf(style.Stderr, "Shadow DB prepared for %s\nConnection: %s\n", env.Name, shadowURL)
 		if shadowSchema != "" {
-			fmt.Fprintf(os.Stderr, "Schema: %s\n", shadowSchema)
+			fmt.Fprintf(style.Stderr, "Schema: %s\n", shadowSchema)
 		}
 	}
 }
@@ -156,16 +157,16 @@ func runShadowPrepare(cmd *cobra.Command, args []string) {
 func runShadowDiff(cmd *cobra.Command, args []string) {
 	reservation, err := shadow.LoadReservation()
 	if err != nil {
-		log.Fatalf("Failed to load reservation: %v", err)
+		fatalf(exitError, "Failed to load reservation: %v", err)
 	}
 	if reservation == nil {
-		fmt.Fprintf(os.Stderr, "No active shadow reservation. Run 'lockplane shadow prepare' first.\n")
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "No active shadow reservation. Run 'lockplane shadow prepare' first.\n")
+		exit(1)
 	}
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatalf(exitError, "Failed to load config: %v", err)
 	}
 
 	targetEnv := shadowDiffEnv
@@ -175,52 +176,203 @@ func runShadowDiff(cmd *cobra.Command, args []string) {
 
 	resolvedEnv, err := config.ResolveEnvironment(cfg, targetEnv)
 	if err != nil {
-		log.Fatalf("Failed to resolve environment: %v", err)
+		fatalf(exitError, "Failed to resolve environment: %v", err)
 	}
 
 	fromConn := strings.TrimSpace(resolvedEnv.DatabaseURL)
 	if fromConn == "" {
-		fmt.Fprintf(os.Stderr, "Environment %q does not define a database_url.\n", resolvedEnv.Name)
-		os.Exit(1)
+		fmt.Fprintf(style.Stderr, "Environment %q does not define a database_url.\n", resolvedEnv.Name)
+		exit(1)
 	}
 
-	before, err := executor.LoadSchemaFromConnectionString(fromConn)
+	before, err := executor.LoadSchemaFromConnectionStringContext(cmd.Context(), fromConn, nil)
 	if err != nil {
-		log.Fatalf("Failed to introspect %s: %v", resolvedEnv.Name, err)
+		fatalf(exitError, "Failed to introspect %s: %v", resolvedEnv.Name, err)
 	}
 
-	after, err := executor.LoadSchemaFromConnectionString(reservation.ShadowURL)
+	after, err := executor.LoadSchemaFromConnectionStringContext(cmd.Context(), reservation.ShadowURL, nil)
 	if err != nil {
-		log.Fatalf("Failed to load shadow schema: %v", err)
+		fatalf(exitError, "Failed to load shadow schema: %v", err)
 	}
 
-	diff := schema.DiffSchemas(before, after)
+	diff := schema.DiffSchemasWithOptions(before, after, resolveDiffOptions(cfg))
 	if diff.IsEmpty() {
-		_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "✓ No differences between %s and prepared shadow\n", resolvedEnv.Name)
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "✓ No differences between %s and prepared shadow\n", resolvedEnv.Name)
 		return
 	}
 
 	driverType := executor.DetectDriver(fromConn)
 	driver, err := executor.NewDriver(driverType)
 	if err != nil {
-		log.Fatalf("Failed to create database driver: %v", err)
+		fatalf(exitError, "Failed to create database driver: %v", err)
 	}
 
 	plan, err := planner.GeneratePlanWithHash(diff, before, driver)
 	if err != nil {
-		log.Fatalf("Failed to generate plan: %v", err)
+		fatalf(exitError, "Failed to generate plan: %v", err)
 	}
 
 	output, err := json.MarshalIndent(plan, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal plan: %v", err)
+		fatalf(exitError, "Failed to marshal plan: %v", err)
 	}
 	fmt.Println(string(output))
 }
 
 func runShadowRelease(cmd *cobra.Command, args []string) {
 	if err := shadow.ClearReservation(); err != nil {
-		log.Fatalf("Failed to release reservation: %v", err)
+		fatalf(exitError, "Failed to release reservation: %v", err)
+	}
+	fmt.Fprintln(style.Stderr, "Shadow reservation cleared.")
+}
+
+// activeRunSchema is the per-run shadow schema created by the current command.
+// Commands drop it with a deferred releaseRunSchema; paths that call exit
+// directly must call releaseRunSchema first.
+var activeRunSchema *shadow.RunSchema
+
+// runSchemaSettings returns the environment's per-run shadow schema settings,
+// enabled when forced by a --shadow-schema-per-run flag
+func runSchemaSettings(env *config.ResolvedEnvironment, force bool) config.ResolvedShadowSchemaRun {
+	var settings config.ResolvedShadowSchemaRun
+	if env != nil {
+		settings = env.ShadowSchemaRun
+	}
+	if force {
+		settings.Enabled = true
+	}
+	return settings
+}
+
+// applyShadowSettings applies the shadow_settings of env to a shadow database
+// connection before anything runs on it
+func applyShadowSettings(ctx context.Context, shadowDB *sql.DB, driverName string, env *config.ResolvedEnvironment, verbose bool) error {
+	if env == nil {
+		return nil
+	}
+	settings := env.ShadowSettings
+	return executor.ApplyShadowSettings(ctx, shadowDB, driverName, executor.ShadowSettings{
+		TempFileLimit:      settings.TempFileLimit,
+		WorkMem:            settings.WorkMem,
+		MaintenanceWorkMem: settings.MaintenanceWorkMem,
+		Tablespace:         settings.Tablespace,
+		CacheSize:          settings.CacheSize,
+		TempStore:          settings.TempStore,
+	}, verbose)
+}
+
+// setupRunSchema drops per-run shadow schemas abandoned by earlier runs, then
+// creates a unique schema for this run derived from base and returns its name.
+func setupRunSchema(ctx context.Context, db *sql.DB, driver database.Driver, base string, settings config.ResolvedShadowSchemaRun) (string, error) {
+	dropped, err := shadow.DropStaleRunSchemas(ctx, db, driver, settings.TTL)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range dropped {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Dropped stale shadow schema %q left behind by an earlier run\n", name)
+	}
+
+	runSchema, err := shadow.CreateRunSchema(ctx, db, driver, shadow.RunSchemaName(settings.Template, base))
+	if err != nil {
+		return "", err
+	}
+	activeRunSchema = runSchema
+	return runSchema.Name, nil
+}
+
+// releaseRunSchema drops the per-run shadow schema, if one was created
+func releaseRunSchema() {
+	if activeRunSchema == nil {
+		return
+	}
+	if err := activeRunSchema.Drop(context.Background()); err != nil {
+		fmt.Fprintf(style.Stderr, "⚠️  Failed to drop shadow schema %q: %v\n", activeRunSchema.Name, err)
+	}
+}
+
+// dirtyShadowHint suggests how to avoid leftovers in the shadow database
+const dirtyShadowHint = "Use a dedicated shadow database that only lockplane writes to, or pass --force-dirty-shadow if the leftovers are harmless"
+
+// dirtyShadowDetails lists the objects left in the shadow database when err
+// is or wraps an *executor.DirtyShadowError
+func dirtyShadowDetails(err error) ([]string, bool) {
+	var dirty *executor.DirtyShadowError
+	if !errors.As(err, &dirty) {
+		return nil, false
+	}
+	details := make([]string, 0, len(dirty.Leftovers))
+	for _, leftover := range dirty.Leftovers {
+		details = append(details, leftover.String())
+	}
+	return details, true
+}
+
+// shadowReusePolicy decides what --check-schema does with the shadow database
+// left by the previous run
+type shadowReusePolicy struct {
+	Record bool // Record the validated schema, so a later run can reuse the shadow database
+	Reuse  bool // Skip cleaning and applying when the shadow database holds the recorded schema
+}
+
+// resolveShadowReusePolicy combines the environment's shadow_reuse with the
+// --shadow-reuse and --shadow-fresh flags. --shadow-fresh and --profile still
+// record the schema they validate, but never skip applying it. Per-run shadow
+// schemas are dropped after each run, so there is nothing to reuse.
+func resolveShadowReusePolicy(env *config.ResolvedEnvironment, reuse, fresh, perRun, profile bool) shadowReusePolicy {
+	enabled := reuse || (env != nil && env.ShadowReuse)
+	if !enabled || perRun {
+		return shadowReusePolicy{}
+	}
+	return shadowReusePolicy{Record: true, Reuse: !fresh && !profile}
+}
+
+// shadowSchemaHash introspects the shadow database and hashes its schema
+func shadowSchemaHash(ctx context.Context, db *sql.DB, driver database.Driver, shadowSchema string) (string, error) {
+	var schemas []string
+	if shadowSchema != "" && driver.SupportsSchemas() {
+		schemas = []string{shadowSchema}
+	}
+	current, err := driver.IntrospectSchemas(ctx, db, schemas)
+	if err != nil {
+		return "", err
+	}
+	return schema.ComputeSchemaHash(current)
+}
+
+// reusableShadowState returns the state recorded by the last run when the
+// shadow database still holds the schema hashed schemaHash, exactly as that
+// run left it
+func reusableShadowState(ctx context.Context, db *sql.DB, driver database.Driver, shadowSchema, cacheDir, target, schemaHash string) (*shadow.ReuseState, bool) {
+	state, err := shadow.LoadReuseState(cacheDir, target)
+	if err != nil {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Ignoring shadow state in %s: %v\n", cacheDir, err)
+		return nil, false
+	}
+	shadowHash := ""
+	if state != nil {
+		if shadowHash, err = shadowSchemaHash(ctx, db, driver, shadowSchema); err != nil {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Failed to introspect the shadow database for reuse: %v\n", err)
+			return nil, false
+		}
+	}
+	ok, reason := state.Reusable(version, schemaHash, shadowHash)
+	if !ok && planVerbose {
+		fmt.Fprintf(style.Stderr, "ℹ️  Cleaning the shadow database: %s\n", reason)
+	}
+	return state, ok
+}
+
+// recordShadowState records the schema just applied to the shadow database,
+// so the next run can reuse it. Failing to record only costs that run a clean.
+func recordShadowState(ctx context.Context, db *sql.DB, driver database.Driver, shadowSchema, cacheDir string, state *shadow.ReuseState) {
+	shadowHash, err := shadowSchemaHash(ctx, db, driver, shadowSchema)
+	if err == nil {
+		state.LockplaneVersion = version
+		state.ShadowHash = shadowHash
+		state.ValidatedAt = time.Now().UTC()
+		err = shadow.SaveReuseState(cacheDir, state)
+	}
+	if err != nil {
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "⚠️  Failed to record shadow state in %s: %v\n", cacheDir, err)
 	}
-	fmt.Fprintln(os.Stderr, "Shadow reservation cleared.")
 }
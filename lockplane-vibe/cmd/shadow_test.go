@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/shadow"
+	_ "modernc.org/sqlite"
+)
+
+func TestResolveShadowReusePolicy(t *testing.T) {
+	configured := &config.ResolvedEnvironment{ShadowReuse: true}
+
+	tests := []struct {
+		name                          string
+		env                           *config.ResolvedEnvironment
+		reuse, fresh, perRun, profile bool
+		want                          shadowReusePolicy
+	}{
+		{name: "off by default", env: &config.ResolvedEnvironment{}},
+		{name: "no environment", reuse: true, want: shadowReusePolicy{Record: true, Reuse: true}},
+		{name: "configured", env: configured, want: shadowReusePolicy{Record: true, Reuse: true}},
+		{name: "fresh still records", env: configured, fresh: true, want: shadowReusePolicy{Record: true}},
+		{name: "profile still records", reuse: true, profile: true, want: shadowReusePolicy{Record: true}},
+		{name: "per-run schemas", env: configured, perRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveShadowReusePolicy(tt.env, tt.reuse, tt.fresh, tt.perRun, tt.profile)
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUseIntrospectionCache(t *testing.T) {
+	configured := &config.ResolvedEnvironment{IntrospectionCache: true}
+
+	tests := []struct {
+		name            string
+		env             *config.ResolvedEnvironment
+		enable, disable bool
+		want            bool
+	}{
+		{name: "off by default", env: &config.ResolvedEnvironment{}},
+		{name: "no environment"},
+		{name: "flag", enable: true, want: true},
+		{name: "configured", env: configured, want: true},
+		{name: "no-cache overrides config", env: configured, disable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := useIntrospectionCache(tt.env, tt.enable, tt.disable); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReusableShadowState(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	driver := sqlite.NewDriver()
+	cacheDir := t.TempDir()
+	target := shadow.ReuseTarget(":memory:", "")
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	if _, ok := reusableShadowState(ctx, db, driver, "", cacheDir, target, "schema-1"); ok {
+		t.Fatal("Expected nothing to reuse before a run was recorded")
+	}
+
+	recordShadowState(ctx, db, driver, "", cacheDir, &shadow.ReuseState{Target: target, SchemaHash: "schema-1", StepsApplied: 1})
+	state, ok := reusableShadowState(ctx, db, driver, "", cacheDir, target, "schema-1")
+	if !ok || state.StepsApplied != 1 {
+		t.Fatalf("Expected the recorded run to be reused, got %+v", state)
+	}
+	if _, ok := reusableShadowState(ctx, db, driver, "", cacheDir, target, "schema-2"); ok {
+		t.Fatal("Expected a changed schema to clean the shadow database")
+	}
+
+	// Someone else changed the shadow database since the recorded run
+	if _, err := db.ExecContext(ctx, "CREATE TABLE notes (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, ok := reusableShadowState(ctx, db, driver, "", cacheDir, target, "schema-1"); ok {
+		t.Fatal("Expected a modified shadow database to be cleaned")
+	}
+}
@@ -0,0 +1,23 @@
+CREATE TABLE users (
+  id bigint PRIMARY KEY,
+  email varchar(255) NOT NULL,
+  created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE TABLE posts (
+  id bigint PRIMARY KEY,
+  user_id bigint NOT NULL,
+  title text NOT NULL,
+  CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX posts_title_lookup_idx ON posts (title);
+
+CREATE TABLE comments (
+  id bigint PRIMARY KEY,
+  post_id bigint NOT NULL,
+  body text NOT NULL,
+  CONSTRAINT comments_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id)
+);
+
+CREATE INDEX comments_post_id_idx ON comments (post_id);
@@ -0,0 +1,13 @@
+CREATE TABLE users (
+  id bigint PRIMARY KEY,
+  email text NOT NULL,
+  nickname text
+);
+
+CREATE TABLE posts (
+  id bigint PRIMARY KEY,
+  user_id bigint NOT NULL,
+  title text NOT NULL
+);
+
+CREATE INDEX posts_title_idx ON posts (title);
@@ -0,0 +1,5 @@
+CREATE TABLE users (
+  id bigint PRIMARY KEY,
+  email varchar(255) NOT NULL,
+  created_at timestamp NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE users (
+  id bigint PRIMARY KEY,
+  email text NOT NULL,
+  nickname text
+);
+
+CREATE TABLE sessions (
+  id bigint PRIMARY KEY,
+  token text NOT NULL
+);
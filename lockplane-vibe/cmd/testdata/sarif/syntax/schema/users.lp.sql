@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS users (
+  id bigint PRIMARY KEY,
+  email text NOT NULL
+);
+
+CREATE TABLE posts (
+  id bigint PRIMARY KEY,
+  title text NOT NULL,
+  body tex t
+);
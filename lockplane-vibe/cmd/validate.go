@@ -1,30 +1,52 @@
 package cmd
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
 	"os"
 
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/lockplane/lockplane/internal/executor"
+	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/lockplane/lockplane/internal/validation"
 	"github.com/spf13/cobra"
 )
 
 var validateCmd = &cobra.Command{
 	Use:   "validate",
-	Short: "Validate schema and plan files",
-	Long: `Validate schema and plan files in different formats.
+	Short: "Validate SQL schema files, schema JSON and plan files",
+	Long: `Validate SQL schema files, schema JSON and plan files.
+
+Given SQL files, checks their syntax and reports the same diagnostics as
+lockplane plan --check-schema, without needing a schema directory. With
+--shadow, the files are also applied, as one schema, to a clean shadow
+database. This suits pre-commit hooks that only check the staged files.
 
 Subcommands:
   schema - Validate JSON schema file against JSON Schema
   plan   - Validate migration plan JSON file
 
-For SQL validation, use: lockplane plan --validate <schema-dir>`,
-	Example: `  # Validate JSON schema
+To validate a whole schema directory, use: lockplane plan --check-schema <schema-dir>`,
+	Example: `  # Check the syntax of SQL files
+  lockplane validate schema/users.lp.sql schema/posts.lp.sql
+
+  # Also apply them to a clean shadow database
+  lockplane validate --shadow schema/users.lp.sql
+
+  # Check the staged schema files before committing
+  git diff --cached --name-only --diff-filter=ACM -- '*.sql' | xargs -r lockplane validate
+
+  # Validate JSON schema
   lockplane validate schema schema.json
 
   # Validate migration plan
   lockplane validate plan migration.json`,
+	Args: cobra.ArbitraryArgs,
+	Run:  runValidateFiles,
 }
 
 var validateSchemaCmd = &cobra.Command{
@@ -46,11 +68,14 @@ var validatePlanCmd = &cobra.Command{
 	Use:   "plan [file]",
 	Short: "Validate migration plan JSON file",
 	Long:  `Validate a migration plan JSON file for correctness and completeness.`,
-	Run:   runValidatePlan,
+	// RunValidatePlan parses its own flags (--format, --allow-empty-plan)
+	DisableFlagParsing: true,
+	Run:                runValidatePlan,
 }
 
 var (
 	validateSchemaFile string
+	validateShadow     bool
 )
 
 func init() {
@@ -59,6 +84,153 @@ func init() {
 	validateCmd.AddCommand(validatePlanCmd)
 
 	validateSchemaCmd.Flags().StringVarP(&validateSchemaFile, "file", "f", "", "Path to schema JSON file")
+
+	// Validating files reports through the same helpers as plan
+	// --check-schema, so the shared options set the plan flags' variables
+	validateCmd.Flags().BoolVar(&validateShadow, "shadow", false, "Also apply the files, as one schema, to a clean shadow database")
+	validateCmd.Flags().StringVar(&planShadowDB, "shadow-db", "", "Shadow database URL for --shadow")
+	validateCmd.Flags().StringVar(&planShadowSchema, "shadow-schema", "", "Shadow schema name when reusing an existing database")
+	validateCmd.Flags().BoolVar(&planForceDirtyShadow, "force-dirty-shadow", false, "Validate even when objects the shadow database cleanup could not drop remain")
+	validateCmd.Flags().StringVar(&planOutput, "output", "", "Output format (default: text, set to 'json' for IDE integration or 'sarif' for code scanning)")
+	validateCmd.Flags().BoolVarP(&planVerbose, "verbose", "v", false, "Enable verbose logging")
+}
+
+// runValidateFiles validates the SQL files given to lockplane validate
+func runValidateFiles(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		_ = cmd.Help()
+		return
+	}
+	if isFullJSONOutput() || isPatchOutput() {
+		fmt.Fprintf(style.Stderr, "Error: --output %s describes a diff; use json or sarif to validate files.\n", planOutput)
+		exit(1)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf(exitError, "Failed to load config file: %v", err)
+	}
+
+	for _, path := range args {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(style.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		if info.IsDir() {
+			fmt.Fprintf(style.Stderr, "Error: %s is a directory; validate a schema directory with: lockplane plan --check-schema %s\n", path, path)
+			exit(1)
+		}
+		if !isSQLFile(path) {
+			fmt.Fprintf(style.Stderr, "Error: %s is not a .sql file; validate schema JSON with: lockplane validate schema %s\n", path, path)
+			exit(1)
+		}
+	}
+
+	if planVerbose {
+		fmt.Fprintf(style.Stderr, "📋 Pre-validating SQL syntax...\n")
+	}
+	var diagnostics []SyntaxError
+	for _, path := range args {
+		diagnostics = append(diagnostics, preValidateSQLSyntax(path, database.DialectPostgres, withSchemaFileOptions(nil, path, cfg))...)
+	}
+	warnings := reportSyntaxDiagnostics(diagnostics)
+
+	if !validateShadow {
+		validationSuccess(nil, warnings)
+		return
+	}
+	validateFilesOnShadow(cmd.Context(), cfg, args, warnings)
+}
+
+// validateFilesOnShadow applies the files, as one schema, to a clean shadow
+// database and reports the result
+func validateFilesOnShadow(ctx context.Context, cfg *config.Config, files []string, warnings []SyntaxError) {
+	shadowConnStr, shadowSchema, resolvedShadow := resolveShadowTarget(cfg, planShadowDB, planShadowSchema)
+
+	if planVerbose {
+		fmt.Fprintf(style.Stderr, "🔗 Connecting to shadow database...\n")
+	}
+	driverType := executor.DetectDriver(shadowConnStr)
+	driver, err := executor.NewDriver(driverType)
+	if err != nil {
+		validationFailure(fmt.Sprintf("Failed to create database driver: %v", err), nil)
+	}
+	shadowDB, err := sql.Open(driverType, shadowConnStr)
+	if err != nil {
+		validationFailure(fmt.Sprintf("Failed to connect to shadow database: %v", err), nil)
+	}
+	defer func() {
+		_ = shadowDB.Close()
+	}()
+
+	if err := applyShadowSettings(ctx, shadowDB, driver.Name(), resolvedShadow, planVerbose); err != nil {
+		validationFailure(fmt.Sprintf("Failed to configure shadow database: %v", err), nil)
+	}
+	if settings := runSchemaSettings(resolvedShadow, false); settings.Enabled && driver.SupportsSchemas() {
+		name, err := setupRunSchema(ctx, shadowDB, driver, shadowSchema, settings)
+		if err != nil {
+			validationFailure(fmt.Sprintf("Failed to create shadow schema: %v", err), nil)
+		}
+		defer releaseRunSchema()
+		shadowSchema = name
+	}
+	if shadowSchema != "" && driver.SupportsSchemas() {
+		if err := driver.CreateSchema(ctx, shadowDB, shadowSchema); err != nil {
+			validationFailure(fmt.Sprintf("Failed to create shadow schema: %v", err), nil)
+		}
+		if err := driver.SetSchema(ctx, shadowDB, shadowSchema); err != nil {
+			validationFailure(fmt.Sprintf("Failed to set shadow schema: %v", err), nil)
+		}
+	}
+
+	dialect := schema.DriverNameToDialect(driverType)
+	opts := withSchemaFileOptions(executor.BuildSchemaLoadOptions(files[0], dialect), files[0], cfg, resolvedShadow)
+	desiredSchema, err := schema.LoadSQLFiles(files, opts)
+	if err != nil {
+		validationFailure(fmt.Sprintf("Failed to load schema: %v", err), nil)
+	}
+
+	if planVerbose {
+		fmt.Fprintf(style.Stderr, "🧹 Cleaning shadow database...\n")
+	}
+	if err := executor.CleanupShadowDB(ctx, shadowDB, driver, planVerbose); err != nil {
+		leftovers, dirty := dirtyShadowDetails(err)
+		if !dirty {
+			validationFailure(fmt.Sprintf("Failed to clean shadow database: %v", err), nil)
+		}
+		if !planForceDirtyShadow {
+			validationFailure("Shadow database is not empty after cleanup; validation would run against leftover objects.\n"+dirtyShadowHint+".", leftovers)
+		}
+	}
+
+	emptySchema := &database.Schema{Tables: []database.Table{}, Dialect: dialect}
+	plan, err := planner.GeneratePlanWithHash(schema.DiffSchemas(emptySchema, desiredSchema), emptySchema, driver)
+	if err != nil {
+		validationFailure(fmt.Sprintf("Failed to generate plan: %v", err), nil)
+	}
+
+	if planVerbose {
+		fmt.Fprintf(style.Stderr, "🧪 Applying %d steps to the shadow database...\n", len(plan.Steps))
+	}
+	result, err := executor.ApplyPlanWithOptions(ctx, shadowDB, plan, nil, emptySchema, driver, planVerbose, executor.ApplyOptions{})
+	if err = executor.ShadowLimitError(err); err != nil {
+		var runtimeErrors []RuntimeError
+		for _, file := range files {
+			runtimeErrors = append(runtimeErrors, findSourceLocationsForErrors(file, result, err)...)
+		}
+		if len(runtimeErrors) > 0 {
+			runtimeValidationFailure(runtimeErrors)
+		}
+
+		var extras []string
+		if result != nil {
+			extras = result.Errors
+		}
+		validationFailure(fmt.Sprintf("Schema validation failed: %v", err), extras)
+	}
+
+	validationSuccess(result, warnings)
 }
 
 func runValidateSchema(cmd *cobra.Command, args []string) {
@@ -68,14 +240,14 @@ func runValidateSchema(cmd *cobra.Command, args []string) {
 	}
 	if path == "" {
 		_ = cmd.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	if err := schema.ValidateJSONSchema(path); err != nil {
-		log.Fatalf("Schema validation failed: %v", err)
+		fatalf(exitError, "Schema validation failed: %v", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "✓ Schema JSON is valid: %s\n", path)
+	fmt.Fprintf(style.Stderr, "✓ Schema JSON is valid: %s\n", path)
 }
 
 func runValidatePlan(cmd *cobra.Command, args []string) {
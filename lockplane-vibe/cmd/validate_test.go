@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/lockplane/lockplane/internal/config"
 )
 
 func TestValidateCommand(t *testing.T) {
@@ -94,3 +100,47 @@ func TestValidateSchemaFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateFileFlags(t *testing.T) {
+	flags := validateCmd.Flags()
+	for _, name := range []string{"shadow", "shadow-db", "shadow-schema", "force-dirty-shadow", "output", "verbose"} {
+		if flags.Lookup(name) == nil {
+			t.Errorf("expected --%s flag to exist on validate command", name)
+		}
+	}
+	if validateCmd.Run == nil {
+		t.Error("validateCmd.Run should validate the SQL files it is given")
+	}
+}
+
+func TestValidateFilesOnShadow(t *testing.T) {
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.sql")
+	posts := filepath.Join(dir, "posts.sql")
+	if err := os.WriteFile(users, []byte("CREATE TABLE users (id integer PRIMARY KEY);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(posts, []byte("CREATE TABLE posts (id integer PRIMARY KEY, user_id integer REFERENCES users (id));\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldShadowDB := planShadowDB
+	planShadowDB = filepath.Join(dir, "shadow.db")
+	defer func() { planShadowDB = oldShadowDB }()
+
+	// Validation failures exit, so returning means both files applied
+	validateFilesOnShadow(context.Background(), &config.Config{}, []string{users, posts}, nil)
+
+	db, err := sql.Open("sqlite", planShadowDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('users', 'posts')").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected users and posts in the shadow database, got %d tables", count)
+	}
+}
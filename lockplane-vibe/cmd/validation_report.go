@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"fmt"
-	"os"
+	"slices"
+	"strings"
 
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/locks"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/style"
 	"github.com/lockplane/lockplane/internal/validation"
 )
 
@@ -17,91 +22,199 @@ func printValidationReport(results []validation.ValidationResult, heading string
 		heading = "=== Migration Safety Report ==="
 	}
 
-	fmt.Fprintf(os.Stderr, "\n%s\n\n", heading)
+	fmt.Fprintf(style.Stderr, "\n%s\n\n", heading)
 
 	for i, result := range results {
 		if result.Safety != nil {
-			fmt.Fprintf(os.Stderr, "%s %s", result.Safety.Level.Icon(), result.Safety.Level.String())
+			fmt.Fprintf(style.Stderr, "%s %s", result.Safety.Level.Icon(), result.Safety.Level.String())
 			if result.Valid {
-				fmt.Fprintf(os.Stderr, " (Operation %d)\n", i+1)
+				fmt.Fprintf(style.Stderr, " (Operation %d)\n", i+1)
 			} else {
-				fmt.Fprintf(os.Stderr, " - BLOCKED (Operation %d)\n", i+1)
+				fmt.Fprintf(style.Stderr, " - BLOCKED (Operation %d)\n", i+1)
 			}
 		} else if result.Valid {
-			fmt.Fprintf(os.Stderr, "✓ Operation %d: PASS\n", i+1)
+			fmt.Fprintf(style.Stderr, "✓ Operation %d: PASS\n", i+1)
 		} else {
-			fmt.Fprintf(os.Stderr, "✗ Operation %d: FAIL\n", i+1)
+			fmt.Fprintf(style.Stderr, "✗ Operation %d: FAIL\n", i+1)
 		}
 
 		if result.Safety != nil {
 			if result.Safety.BreakingChange {
-				fmt.Fprintf(os.Stderr, "  ⚠️  Breaking change - will affect running applications\n")
+				fmt.Fprintf(style.Stderr, "  ⚠️  Breaking change - will affect running applications\n")
 			}
 			if result.Safety.DataLoss {
-				fmt.Fprintf(os.Stderr, "  💥 Permanent data loss\n")
+				fmt.Fprintf(style.Stderr, "  💥 Permanent data loss\n")
+			}
+			if result.Safety.LockLevel != "" {
+				if result.Safety.Rewrite {
+					fmt.Fprintf(style.Stderr, "  🔒 Lock: %s, rewrites the table\n", result.Safety.LockLevel)
+				} else {
+					fmt.Fprintf(style.Stderr, "  🔒 Lock: %s\n", result.Safety.LockLevel)
+				}
 			}
 			if !result.Reversible && result.Safety.RollbackDescription != "" {
-				fmt.Fprintf(os.Stderr, "  ↩️  Rollback: %s\n", result.Safety.RollbackDescription)
+				fmt.Fprintf(style.Stderr, "  ↩️  Rollback: %s\n", result.Safety.RollbackDescription)
 			} else if result.Reversible && result.Safety.RollbackDataLoss {
-				fmt.Fprintf(os.Stderr, "  ↩️  Rollback: %s\n", result.Safety.RollbackDescription)
+				fmt.Fprintf(style.Stderr, "  ↩️  Rollback: %s\n", result.Safety.RollbackDescription)
+			}
+			if len(result.Safety.AffectedObjects) > 0 {
+				fmt.Fprintf(style.Stderr, "  🔗 Affected dependent objects:\n")
+				for _, obj := range result.Safety.AffectedObjects {
+					fmt.Fprintf(style.Stderr, "     • %s\n", obj)
+				}
 			}
 		} else if !result.Reversible {
-			fmt.Fprintf(os.Stderr, "  ⚠️  NOT REVERSIBLE\n")
+			fmt.Fprintf(style.Stderr, "  ⚠️  NOT REVERSIBLE\n")
 		}
 
 		for _, err := range result.Errors {
-			fmt.Fprintf(os.Stderr, "  ❌ Error: %s\n", err)
+			fmt.Fprintf(style.Stderr, "  ❌ Error: %s\n", err)
 		}
 		for _, warning := range result.Warnings {
-			fmt.Fprintf(os.Stderr, "  ⚠️  Warning: %s\n", warning)
+			fmt.Fprintf(style.Stderr, "  ⚠️  Warning: %s\n", warning)
 		}
 
 		if result.Safety != nil && len(result.Safety.SaferAlternatives) > 0 {
-			fmt.Fprintf(os.Stderr, "\n  💡 Safer alternatives:\n")
+			fmt.Fprintf(style.Stderr, "\n  💡 Safer alternatives:\n")
 			for _, alt := range result.Safety.SaferAlternatives {
-				fmt.Fprintf(os.Stderr, "     • %s\n", alt)
+				fmt.Fprintf(style.Stderr, "     • %s\n", alt)
 			}
 		}
 
-		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(style.Stderr, "\n")
 	}
 
-	fmt.Fprintf(os.Stderr, "=== Summary ===\n\n")
+	fmt.Fprintf(style.Stderr, "=== Summary ===\n\n")
+	printSafetyCounts(validation.SummarizeSafety(results))
+	fmt.Fprintf(style.Stderr, "\n")
+}
 
-	safeCnt, reviewCnt, lossyCnt, dangerousCnt, multiPhaseCnt := 0, 0, 0, 0, 0
-	for _, r := range results {
-		if r.Safety == nil {
-			continue
-		}
-		switch r.Safety.Level {
-		case validation.SafetyLevelSafe:
-			safeCnt++
-		case validation.SafetyLevelReview:
-			reviewCnt++
-		case validation.SafetyLevelLossy:
-			lossyCnt++
-		case validation.SafetyLevelDangerous:
-			dangerousCnt++
-		case validation.SafetyLevelMultiPhase:
-			multiPhaseCnt++
-		}
+// printValidationSummary renders only the aggregate counts and the overall
+// result of a safety report, for plan --summary-only
+func printValidationSummary(results []validation.ValidationResult, heading string) {
+	if len(results) == 0 {
+		return
+	}
+
+	if heading == "" {
+		heading = "=== Migration Safety Summary ==="
+	}
+	summary := validation.SummarizeSafety(results)
+
+	fmt.Fprintf(style.Stderr, "\n%s\n\n", heading)
+	fmt.Fprintf(style.Stderr, "  %d operation(s) checked\n", summary.Operations)
+	printSafetyCounts(summary)
+	if summary.Irreversible > 0 {
+		fmt.Fprintf(style.Stderr, "  ↩️  %d irreversible operation(s)\n", summary.Irreversible)
+	}
+	if n := summary.LockLevels[locks.LockAccessExclusive.String()]; n > 0 {
+		fmt.Fprintf(style.Stderr, "  🔒 %d operation(s) take an ACCESS EXCLUSIVE lock\n", n)
+	}
+	if summary.Rewrites > 0 {
+		fmt.Fprintf(style.Stderr, "  🔒 %d operation(s) rewrite a table\n", summary.Rewrites)
+	}
+	if summary.Valid {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "\n  ✓ PASS\n\n")
+	} else {
+		_, _ = color.New(color.FgRed).Fprintf(style.Stderr, "\n  ✗ FAIL: %d operation(s) blocked\n\n", summary.Blocked)
+	}
+}
+
+// printSafetyCounts lists the non-zero safety level counts of a summary
+func printSafetyCounts(summary *planner.SafetySummary) {
+	if summary.Safe > 0 {
+		fmt.Fprintf(style.Stderr, "  ✅ %d safe operation(s)\n", summary.Safe)
+	}
+	if summary.Review > 0 {
+		fmt.Fprintf(style.Stderr, "  ⚠️  %d operation(s) require review\n", summary.Review)
+	}
+	if summary.Lossy > 0 {
+		fmt.Fprintf(style.Stderr, "  🔶 %d lossy operation(s)\n", summary.Lossy)
+	}
+	if summary.Dangerous > 0 {
+		fmt.Fprintf(style.Stderr, "  ❌ %d dangerous operation(s)\n", summary.Dangerous)
+	}
+	if summary.MultiPhase > 0 {
+		fmt.Fprintf(style.Stderr, "  🔄 %d operation(s) require multi-phase migration\n", summary.MultiPhase)
+	}
+}
+
+// printImpactSummary renders a plan's impact summary to stderr, ahead of the
+// detailed steps
+func printImpactSummary(summary *planner.ImpactSummary) {
+	if summary == nil {
+		return
 	}
 
-	if safeCnt > 0 {
-		fmt.Fprintf(os.Stderr, "  ✅ %d safe operation(s)\n", safeCnt)
+	counts := []struct {
+		n     int
+		label string
+	}{
+		{summary.TablesCreated, "table(s) created"},
+		{summary.TablesDropped, "table(s) dropped"},
+		{summary.ColumnsAdded, "column(s) added"},
+		{summary.ColumnsDropped, "column(s) dropped"},
+		{summary.ColumnsModified, "column(s) modified"},
+		{summary.IndexesAdded, "index(es) added"},
+		{summary.IndexesDropped, "index(es) dropped"},
+		{summary.ForeignKeysAdded, "foreign key(s) added"},
+		{summary.ForeignKeysDropped, "foreign key(s) dropped"},
+	}
+	var parts []string
+	for _, c := range counts {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
 	}
-	if reviewCnt > 0 {
-		fmt.Fprintf(os.Stderr, "  ⚠️  %d operation(s) require review\n", reviewCnt)
+	if len(parts) == 0 {
+		parts = append(parts, "no schema object changes")
+	}
+
+	_, _ = color.New(color.FgCyan, color.Bold).Fprintf(style.Stderr, "\n📊 Impact: %s\n", strings.Join(parts, ", "))
+	if summary.HighestSafety != "" {
+		fmt.Fprintf(style.Stderr, "   Highest safety level: %s\n", summary.HighestSafety)
 	}
-	if lossyCnt > 0 {
-		fmt.Fprintf(os.Stderr, "  🔶 %d lossy operation(s)\n", lossyCnt)
+	if summary.EstimatedRows > 0 {
+		fmt.Fprintf(style.Stderr, "   Estimated rows affected: %d\n", summary.EstimatedRows)
 	}
-	if dangerousCnt > 0 {
-		fmt.Fprintf(os.Stderr, "  ❌ %d dangerous operation(s)\n", dangerousCnt)
+}
+
+// slowestStatements returns up to n profiled statements, slowest first
+func slowestStatements(profile []planner.StatementProfile, n int) []planner.StatementProfile {
+	sorted := slices.Clone(profile)
+	slices.SortStableFunc(sorted, func(a, b planner.StatementProfile) int {
+		switch {
+		case a.DurationMS > b.DurationMS:
+			return -1
+		case a.DurationMS < b.DurationMS:
+			return 1
+		}
+		return 0
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
 	}
-	if multiPhaseCnt > 0 {
-		fmt.Fprintf(os.Stderr, "  🔄 %d operation(s) require multi-phase migration\n", multiPhaseCnt)
+	return sorted
+}
+
+// printStatementProfile lists the slowest statements of a profiled shadow run
+// to stderr
+func printStatementProfile(profile []planner.StatementProfile) {
+	if len(profile) == 0 {
+		return
 	}
 
-	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(style.Stderr, "\n⏱  Slowest statements on the shadow database (advisory: shadow data volumes differ from production)\n")
+	for _, p := range slowestStatements(profile, 5) {
+		sqlPreview := strings.Join(strings.Fields(p.SQL), " ")
+		if len(sqlPreview) > 80 {
+			sqlPreview = sqlPreview[:80] + "..."
+		}
+		fmt.Fprintf(style.Stderr, "   %8.1f ms  step %d: %s\n", p.DurationMS, p.Step, sqlPreview)
+		for _, line := range strings.Split(p.Explain, "\n") {
+			if line != "" {
+				_, _ = color.New(color.FgHiBlack).Fprintf(style.Stderr, "                %s\n", line)
+			}
+		}
+	}
 }
@@ -0,0 +1,189 @@
+// Package drivertest holds the conformance tests every database.Driver must
+// pass. Each driver's tests call Run with a function that checks generated
+// SQL against its database or parser.
+package drivertest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// Check reports whether the database accepts a statement, e.g. by running it
+// against an empty database or parsing it with the database's own parser
+type Check func(sql string) error
+
+// Run runs the conformance tests against driver
+func Run(t *testing.T, driver database.Driver, check Check) {
+	t.Helper()
+	t.Run("QuoteIdentifier", func(t *testing.T) { testQuoteIdentifier(t, driver, check) })
+	t.Run("RenderType", func(t *testing.T) { testRenderType(t, driver, check) })
+	t.Run("Lifecycle", func(t *testing.T) { testLifecycle(t, driver, check) })
+}
+
+// awkwardNames need quoting in every supported database
+var awkwardNames = []string{"order", "select", "group", "first name", `say "hi"`, "2fa"}
+
+func testQuoteIdentifier(t *testing.T, driver database.Driver, check Check) {
+	for _, name := range []string{"users", "created_at", "_internal"} {
+		if got := driver.QuoteIdentifier(name); got != name {
+			t.Errorf("QuoteIdentifier(%q) = %q, want it bare", name, got)
+		}
+	}
+	for _, name := range awkwardNames {
+		if got := driver.QuoteIdentifier(name); got == name {
+			t.Errorf("QuoteIdentifier(%q) left it bare", name)
+		}
+	}
+
+	// Every quoted name is accepted as a table and a column name
+	for _, name := range awkwardNames {
+		table := database.Table{
+			Name:    name,
+			Columns: []database.Column{{Name: name, Type: "integer", Nullable: true}},
+		}
+		createSQL, _ := driver.CreateTable(table)
+		if err := check(createSQL); err != nil {
+			t.Errorf("CREATE TABLE with name %q was rejected: %v\n%s", name, err, createSQL)
+		}
+		addSQL, _ := driver.AddColumn(name, database.Column{Name: name + "_2", Type: "text", Nullable: true})
+		if err := check(addSQL); err != nil {
+			t.Errorf("ADD COLUMN on table %q was rejected: %v\n%s", name, err, addSQL)
+		}
+		dropSQL, _ := driver.DropTable(table)
+		if err := check(dropSQL); err != nil {
+			t.Errorf("DROP TABLE %q was rejected: %v\n%s", name, err, dropSQL)
+		}
+	}
+}
+
+// lifecycleNames are a reserved word and a mixed-case name, used for every
+// object a plan can create, change and drop
+var lifecycleNames = []string{"order", "UserAccounts"}
+
+// testLifecycle checks the statements of a table's whole life, in the order
+// a plan runs them, with every identifier named awkwardly. A check that runs
+// statements sees each one against the objects the earlier ones created.
+func testLifecycle(t *testing.T, driver database.Driver, check Check) {
+	for _, name := range lifecycleNames {
+		run := func(what, sql string) {
+			t.Helper()
+			if strings.HasPrefix(sql, "--") {
+				return
+			}
+			if err := check(sql); err != nil {
+				t.Errorf("%s with name %q was rejected: %v\n%s", what, name, err, sql)
+			}
+		}
+
+		parent := database.Table{
+			Name:    name + "_parent",
+			Columns: []database.Column{{Name: name, Type: "integer", IsPrimaryKey: true}},
+		}
+		table := database.Table{
+			Name: name,
+			Columns: []database.Column{
+				{Name: "id", Type: "integer", IsPrimaryKey: true},
+				{Name: name, Type: "integer", Nullable: true},
+			},
+		}
+		fk := database.ForeignKey{Name: name + "_fk", Columns: []string{name}, ReferencedTable: parent.Name, ReferencedColumns: []string{name}}
+		index := database.Index{Name: name + "_idx", Columns: []string{name}}
+		unique := database.Index{Name: name + "_key", Columns: []string{name}, Unique: true}
+		added := database.Column{Name: name + "_2", Type: "text", Nullable: true}
+
+		sql, _ := driver.CreateTable(parent)
+		run("CREATE TABLE", sql)
+		sql, _ = driver.CreateTable(table)
+		run("CREATE TABLE", sql)
+		sql, _ = driver.AddColumn(table.Name, added)
+		run("ADD COLUMN", sql)
+		sql, _ = driver.AddIndex(table.Name, index)
+		run("CREATE INDEX", sql)
+		sql, _ = driver.AddIndex(table.Name, unique)
+		run("CREATE UNIQUE INDEX", sql)
+		// Drivers that rebuild tables for foreign keys return a comment
+		sql, _ = driver.AddForeignKey(table.Name, fk)
+		run("ADD FOREIGN KEY", sql)
+
+		changed := added
+		changed.Type, changed.Nullable = "varchar(20)", false
+		defaultValue := "'x'"
+		changed.Default = &defaultValue
+		for _, step := range driver.ModifyColumn(table.Name, database.ColumnDiff{
+			ColumnName: added.Name, Old: added, New: changed, Changes: []string{"type", "nullable", "default"},
+		}) {
+			for _, sql := range step.SQL {
+				run("ALTER COLUMN", sql)
+			}
+		}
+
+		sql, _ = driver.DropForeignKey(table.Name, fk)
+		run("DROP FOREIGN KEY", sql)
+		sql, _ = driver.DropIndex(table.Name, unique)
+		run("DROP UNIQUE INDEX", sql)
+		sql, _ = driver.DropIndex(table.Name, index)
+		run("DROP INDEX", sql)
+		sql, _ = driver.DropColumn(table.Name, added)
+		run("DROP COLUMN", sql)
+		sql, _ = driver.DropTable(table)
+		run("DROP TABLE", sql)
+		sql, _ = driver.DropTable(parent)
+		run("DROP TABLE", sql)
+	}
+}
+
+// foreignTypes are column types written for one dialect that the other
+// dialect has to spell differently
+var foreignTypes = []struct {
+	dialect database.Dialect
+	types   []string
+}{
+	{database.DialectPostgres, []string{
+		"uuid", "jsonb", "json", "timestamptz", "timestamp(3) with time zone", "serial",
+		"bigserial", "text[]", "integer[]", "bytea", "interval", "inet", "numeric(10,2)",
+	}},
+	{database.DialectSQLite, []string{"BLOB", "DATETIME", "DOUBLE", "TINYINT", "INTEGER", "TEXT", "REAL"}},
+}
+
+func testRenderType(t *testing.T, driver database.Driver, check Check) {
+	// Portable types are spelled as written
+	for _, columnType := range []string{"integer", "text", "boolean", "real"} {
+		if got := driver.RenderType(database.Column{Name: "c", Type: columnType}); got != columnType {
+			t.Errorf("RenderType(%q) = %q, want it unchanged", columnType, got)
+		}
+	}
+
+	for _, foreign := range foreignTypes {
+		table := database.Table{Name: "typed"}
+		for i, columnType := range foreign.types {
+			col := database.Column{
+				Name:         fmt.Sprintf("c%d", i),
+				Type:         columnType,
+				Nullable:     true,
+				TypeMetadata: &database.TypeMetadata{Logical: strings.ToLower(columnType), Raw: columnType, Dialect: foreign.dialect},
+			}
+			rendered := driver.RenderType(col)
+			if rendered == "" {
+				t.Errorf("RenderType(%q from %s) is empty", columnType, foreign.dialect)
+			}
+			// Column definitions use the rendered type
+			want := driver.QuoteIdentifier(col.Name) + " " + rendered
+			if def := driver.FormatColumnDefinition(col); !strings.HasPrefix(def, want) {
+				t.Errorf("FormatColumnDefinition(%q from %s) = %q, want it to start with %q", columnType, foreign.dialect, def, want)
+			}
+			table.Columns = append(table.Columns, col)
+		}
+
+		createSQL, _ := driver.CreateTable(table)
+		if err := check(createSQL); err != nil {
+			t.Errorf("CREATE TABLE with %s types was rejected: %v\n%s", foreign.dialect, err, createSQL)
+		}
+		dropSQL, _ := driver.DropTable(table)
+		if err := check(dropSQL); err != nil {
+			t.Errorf("DROP TABLE was rejected: %v\n%s", err, dropSQL)
+		}
+	}
+}
@@ -3,13 +3,28 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
 )
 
 // Schema represents a database schema
 type Schema struct {
-	Tables  []Table `json:"tables"`
-	Dialect Dialect `json:"dialect,omitempty"`
+	Tables        []Table `json:"tables"`
+	Dialect       Dialect `json:"dialect,omitempty"`
+	ServerVersion int     `json:"server_version,omitempty"` // server_version_num of the introspected server (0 = unknown)
+	// TableFilter holds the glob patterns a partial introspection was limited
+	// to. Empty means the schema covers every table.
+	TableFilter []string `json:"table_filter,omitempty"`
+	// CurrentUser is the role the schema was introspected as, which owns
+	// any table the migration creates (empty = unknown)
+	CurrentUser string `json:"current_user,omitempty"`
+	// DefaultTablespace is the database's default tablespace, where objects
+	// without a tablespace are stored (PostgreSQL only; empty = unknown,
+	// assumed to be pg_default)
+	DefaultTablespace string `json:"default_tablespace,omitempty"`
+	// Sequences are the standalone and column-owned sequences (PostgreSQL
+	// only). Sequences of identity columns are part of their column.
+	Sequences []Sequence `json:"sequences,omitempty"`
 }
 
 // Table represents a database table
@@ -20,7 +35,63 @@ type Table struct {
 	Indexes     []Index      `json:"indexes"`
 	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
 	RLSEnabled  bool         `json:"rls_enabled,omitempty"`
-	Policies    []Policy     `json:"policies,omitempty"` // Row Level Security policies
+	Policies    []Policy     `json:"policies,omitempty"`   // Row Level Security policies
+	Tablespace  *string      `json:"tablespace,omitempty"` // Tablespace (nil = database default)
+	// ReplicaIdentity is the REPLICA IDENTITY setting used by logical
+	// replication, e.g. "FULL" or "USING INDEX users_email_key" (nil = DEFAULT)
+	ReplicaIdentity *string `json:"replica_identity,omitempty"`
+	// StorageParameters are the table's storage parameters (reloptions), e.g.
+	// {"fillfactor": "70"}. TOAST parameters are prefixed with "toast.".
+	// Values are kept as opaque strings.
+	StorageParameters map[string]string `json:"storage_parameters,omitempty"`
+	// Owner is the role that owns the table (introspected; PostgreSQL only).
+	// Ownership is not managed, but is lost when a table is recreated.
+	Owner string `json:"owner,omitempty"`
+	// ACL lists the table's explicit privileges as aclitem strings, e.g.
+	// "reporting=r/app" (introspected; nil = default privileges)
+	ACL []string `json:"acl,omitempty"`
+	// Comment is the table's COMMENT ON TABLE text read from a schema file.
+	// Comments are not managed.
+	Comment string `json:"comment,omitempty"`
+	// Stats is approximate size metadata, only set by introspect --with-stats.
+	// It is never part of the schema hash or the diff.
+	Stats *TableStats `json:"stats,omitempty"`
+	// Source is where the table is defined when loaded from SQL files
+	Source *SourceLocation `json:"-"`
+}
+
+// TableStats is the approximate size of an introspected table, read from the
+// catalog statistics at introspection time (PostgreSQL only)
+type TableStats struct {
+	// ApproximateRows is pg_class.reltuples, as of the last VACUUM or ANALYZE
+	// (nil when the table has never been analyzed)
+	ApproximateRows *int64 `json:"approximate_rows,omitempty"`
+	TotalBytes      int64  `json:"total_bytes"` // Table, TOAST and indexes
+	IndexBytes      int64  `json:"index_bytes"`
+}
+
+// SourceLocation points at a position in a schema file (1-based)
+type SourceLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// String formats the location as file:line:column
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// Replica identity settings for Table.ReplicaIdentity
+const (
+	ReplicaIdentityDefault = "DEFAULT"
+	ReplicaIdentityFull    = "FULL"
+	ReplicaIdentityNothing = "NOTHING"
+)
+
+// ReplicaIdentityUsingIndex returns the replica identity setting for a unique index
+func ReplicaIdentityUsingIndex(indexName string) string {
+	return "USING INDEX " + indexName
 }
 
 // Column represents a table column
@@ -32,13 +103,127 @@ type Column struct {
 	IsPrimaryKey    bool             `json:"is_primary_key"`
 	TypeMetadata    *TypeMetadata    `json:"type_metadata,omitempty"`
 	DefaultMetadata *DefaultMetadata `json:"default_metadata,omitempty"`
+	// Storage is the PostgreSQL storage mode (PLAIN, EXTERNAL, EXTENDED or
+	// MAIN); nil uses the default storage of the column's type
+	Storage *string `json:"storage,omitempty"`
+	// StatisticsTarget is the PostgreSQL per-column statistics target set with
+	// ALTER COLUMN ... SET STATISTICS; nil uses default_statistics_target
+	StatisticsTarget *int `json:"statistics_target,omitempty"`
+	// Comment is the column's COMMENT ON COLUMN text read from a schema file.
+	// Comments are not managed.
+	Comment string `json:"comment,omitempty"`
+	// Source is where the column is defined when loaded from SQL files
+	Source *SourceLocation `json:"-"`
 }
 
 // Index represents a table index
 type Index struct {
-	Name    string   `json:"name"`
-	Columns []string `json:"columns"`
-	Unique  bool     `json:"unique"`
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	Unique     bool     `json:"unique"`
+	Tablespace *string  `json:"tablespace,omitempty"` // Tablespace (nil = database default)
+	// NullsNotDistinct makes NULLs compare equal for uniqueness (PostgreSQL 15+)
+	NullsNotDistinct bool `json:"nulls_not_distinct,omitempty"`
+	// Ordering holds the sort order of each entry in Columns. It is omitted
+	// when every column uses the default (ASC, NULLS LAST).
+	Ordering []IndexColumn `json:"ordering,omitempty"`
+	// IncludeColumns are non-key columns stored in the index (INCLUDE,
+	// PostgreSQL 11+), so index-only scans can return them
+	IncludeColumns []string `json:"include_columns,omitempty"`
+	// Constraint marks an index backing a UNIQUE constraint
+	// (pg_constraint.conindid). It is added and dropped through the
+	// constraint, never directly.
+	Constraint bool `json:"constraint,omitempty"`
+	// Source is where the index is defined when loaded from SQL files
+	Source *SourceLocation `json:"-"`
+}
+
+// Nulls ordering values for IndexColumn.NullsOrder
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// IndexColumn describes the sort order of one index key column
+type IndexColumn struct {
+	Name       string `json:"name"`
+	Descending bool   `json:"descending,omitempty"`
+	// NullsOrder is NullsFirst or NullsLast; empty means the default for the
+	// direction (NULLS LAST for ASC, NULLS FIRST for DESC)
+	NullsOrder string `json:"nulls_order,omitempty"`
+}
+
+// normalized clears NullsOrder when it matches the default for the direction
+func (c IndexColumn) normalized() IndexColumn {
+	if (c.Descending && c.NullsOrder == NullsFirst) || (!c.Descending && c.NullsOrder == NullsLast) {
+		c.NullsOrder = ""
+	}
+	return c
+}
+
+// IsDefault reports whether the column uses ASC NULLS LAST
+func (c IndexColumn) IsDefault() bool {
+	n := c.normalized()
+	return !n.Descending && n.NullsOrder == ""
+}
+
+// SQL formats the column with any non-default ordering clauses,
+// e.g. "created_at DESC NULLS LAST"
+func (c IndexColumn) SQL() string {
+	n := c.normalized()
+	sql := n.Name
+	if n.Descending {
+		sql += " DESC"
+	}
+	switch n.NullsOrder {
+	case NullsFirst:
+		sql += " NULLS FIRST"
+	case NullsLast:
+		sql += " NULLS LAST"
+	}
+	return sql
+}
+
+// KeyColumns returns the index columns with their sort order
+func (idx Index) KeyColumns() []IndexColumn {
+	cols := make([]IndexColumn, len(idx.Columns))
+	for i, name := range idx.Columns {
+		cols[i] = IndexColumn{Name: name}
+		if i < len(idx.Ordering) && idx.Ordering[i].Name == name {
+			cols[i] = idx.Ordering[i].normalized()
+		}
+	}
+	return cols
+}
+
+// SetKeyColumns sets Columns and Ordering from cols, leaving Ordering empty
+// when every column uses the default order
+func (idx *Index) SetKeyColumns(cols []IndexColumn) {
+	idx.Columns = make([]string, 0, len(cols))
+	idx.Ordering = nil
+	custom := false
+	for _, col := range cols {
+		idx.Columns = append(idx.Columns, col.Name)
+		if !col.IsDefault() {
+			custom = true
+		}
+	}
+	if custom {
+		idx.Ordering = make([]IndexColumn, len(cols))
+		for i, col := range cols {
+			idx.Ordering[i] = col.normalized()
+		}
+	}
+}
+
+// HasCustomOrdering reports whether any column is not ASC NULLS LAST
+func (idx Index) HasCustomOrdering() bool {
+	for _, col := range idx.KeyColumns() {
+		if !col.IsDefault() {
+			return true
+		}
+	}
+	return false
 }
 
 // ForeignKey represents a foreign key constraint
@@ -49,6 +234,11 @@ type ForeignKey struct {
 	ReferencedColumns []string `json:"referenced_columns"`
 	OnDelete          *string  `json:"on_delete,omitempty"`
 	OnUpdate          *string  `json:"on_update,omitempty"`
+	// External is set when the referenced table was left out of a partial
+	// introspection (see Schema.TableFilter)
+	External bool `json:"external,omitempty"`
+	// Source is where the foreign key is defined when loaded from SQL files
+	Source *SourceLocation `json:"-"`
 }
 
 // Policy represents a Row Level Security (RLS) policy
@@ -61,6 +251,30 @@ type Policy struct {
 	WithCheck  *string  `json:"with_check,omitempty"` // WITH CHECK clause (for INSERT, UPDATE)
 }
 
+// Sequence represents a sequence (PostgreSQL only). Unset options take the
+// PostgreSQL defaults for the sequence's type and direction.
+type Sequence struct {
+	Name      string `json:"name"`
+	Schema    string `json:"schema,omitempty"`    // Schema name (empty = default schema)
+	DataType  string `json:"data_type,omitempty"` // smallint, integer or bigint (empty = bigint)
+	Increment *int64 `json:"increment,omitempty"` // nil = 1
+	MinValue  *int64 `json:"min_value,omitempty"`
+	MaxValue  *int64 `json:"max_value,omitempty"`
+	Start     *int64 `json:"start,omitempty"`
+	Cache     *int64 `json:"cache,omitempty"` // nil = 1
+	Cycle     bool   `json:"cycle,omitempty"`
+	// OwnedBy is the column the sequence belongs to, as "table.column" with
+	// the table qualified as QualifiedTableName does (empty = none). An owned
+	// sequence is dropped with its column.
+	OwnedBy string `json:"owned_by,omitempty"`
+	// Restart is the value an ALTER SEQUENCE ... RESTART in the schema files
+	// asks to restart the sequence at (desired schemas only)
+	Restart *int64 `json:"restart,omitempty"`
+	// LastValue is the last value the sequence returned when it was
+	// introspected (nil = never used since it was created or restarted)
+	LastValue *int64 `json:"last_value,omitempty"`
+}
+
 // Dialect represents the database dialect associated with a schema
 type Dialect string
 
@@ -161,6 +375,15 @@ type SQLGenerator interface {
 	// FormatColumnDefinition formats a column definition for CREATE TABLE
 	FormatColumnDefinition(col Column) string
 
+	// QuoteIdentifier returns a table or column name as it must be written in
+	// this database's SQL: bare when the database reads it back unchanged,
+	// quoted otherwise (e.g. reserved words)
+	QuoteIdentifier(name string) string
+
+	// RenderType returns the type of a column as this database spells it,
+	// e.g. TEXT for a PostgreSQL uuid column on SQLite
+	RenderType(col Column) string
+
 	// ParameterPlaceholder returns the parameter placeholder for this database
 	// PostgreSQL: $1, $2, etc.
 	// SQLite: ?, ?, etc.
@@ -187,6 +410,12 @@ type Driver interface {
 	// Returns a combined Schema with tables from all specified schemas
 	IntrospectSchemas(ctx context.Context, db *sql.DB, schemas []string) (*Schema, error)
 
+	// IntrospectTables is IntrospectSchemas limited to tables matching one of
+	// the glob patterns (see MatchTable). Tables are filtered before their
+	// columns and indexes are read. Foreign keys to tables outside the set are
+	// marked External, and the patterns are recorded in Schema.TableFilter.
+	IntrospectTables(ctx context.Context, db *sql.DB, schemas []string, patterns []string) (*Schema, error)
+
 	// CreateSchema creates a schema in the database (no-op if not supported)
 	CreateSchema(ctx context.Context, db *sql.DB, schemaName string) error
 
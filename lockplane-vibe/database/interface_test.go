@@ -271,3 +271,26 @@ func TestIndexWithMultipleColumns(t *testing.T) {
 		t.Error("Expected unique index")
 	}
 }
+
+func TestIndexSetKeyColumns(t *testing.T) {
+	var idx Index
+	idx.SetKeyColumns([]IndexColumn{{Name: "a"}, {Name: "b", NullsOrder: NullsLast}})
+	if idx.Ordering != nil {
+		t.Errorf("Expected no ordering for default ASC NULLS LAST columns, got %+v", idx.Ordering)
+	}
+	if len(idx.Columns) != 2 || idx.Columns[0] != "a" || idx.Columns[1] != "b" {
+		t.Errorf("Expected columns [a b], got %v", idx.Columns)
+	}
+
+	// DESC defaults to NULLS FIRST, so the explicit clause is dropped
+	idx.SetKeyColumns([]IndexColumn{{Name: "a"}, {Name: "b", Descending: true, NullsOrder: NullsFirst}})
+	if !idx.HasCustomOrdering() {
+		t.Fatal("Expected custom ordering for a DESC column")
+	}
+	if got := idx.KeyColumns()[1]; got != (IndexColumn{Name: "b", Descending: true}) {
+		t.Errorf("Expected normalized DESC column, got %+v", got)
+	}
+	if got := idx.KeyColumns()[1].SQL(); got != "b DESC" {
+		t.Errorf("Expected \"b DESC\", got %q", got)
+	}
+}
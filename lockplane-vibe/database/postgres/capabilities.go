@@ -0,0 +1,53 @@
+package postgres
+
+import "fmt"
+
+// Feature is SQL lockplane generates that older PostgreSQL servers reject
+type Feature struct {
+	Name       string // As written in SQL, e.g. "UNIQUE NULLS NOT DISTINCT"
+	MinVersion int    // First server_version_num supporting the feature
+}
+
+// The capability matrix: features plans are checked against before they run
+var (
+	FeatureIncludeColumns   = Feature{Name: "INCLUDE columns", MinVersion: IndexKeyAttsMinVersion}
+	FeatureSequenceDataType = Feature{Name: "CREATE SEQUENCE ... AS", MinVersion: SequenceDataTypeMinVersion}
+	FeatureNullsNotDistinct = Feature{Name: "UNIQUE NULLS NOT DISTINCT", MinVersion: NullsNotDistinctMinVersion}
+	FeatureStorageDefault   = Feature{Name: "SET STORAGE DEFAULT", MinVersion: StorageDefaultMinVersion}
+)
+
+// SupportedBy reports whether a server supports the feature. An unknown
+// version (0) is assumed to.
+func (f Feature) SupportedBy(serverVersion int) bool {
+	return serverVersion == 0 || serverVersion >= f.MinVersion
+}
+
+// MinVersionLabel names the first release with the feature, e.g. "15"
+func (f Feature) MinVersionLabel() string {
+	return FormatServerVersion(f.MinVersion)
+}
+
+// FormatServerVersion renders a server_version_num as a version string,
+// leaving out a zero minor release (140009 → "14.9", 150000 → "15",
+// 90624 → "9.6.24")
+func FormatServerVersion(versionNum int) string {
+	if versionNum < 100000 {
+		if versionNum%100 == 0 {
+			return fmt.Sprintf("%d.%d", versionNum/10000, versionNum/100%100)
+		}
+		return fmt.Sprintf("%d.%d.%d", versionNum/10000, versionNum/100%100, versionNum%100)
+	}
+	if versionNum%10000 == 0 {
+		return fmt.Sprintf("%d", versionNum/10000)
+	}
+	return fmt.Sprintf("%d.%d", versionNum/10000, versionNum%10000)
+}
+
+// MajorVersion returns the major release of a server_version_num as a
+// server_version_num: 150004 → 150000, 90624 → 90600
+func MajorVersion(versionNum int) int {
+	if versionNum < 100000 {
+		return versionNum / 100 * 100
+	}
+	return versionNum / 10000 * 10000
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import "testing"
+
+func TestFormatServerVersion(t *testing.T) {
+	cases := map[int]string{
+		150000: "15",
+		140009: "14.9",
+		100023: "10.23",
+		90600:  "9.6",
+		90624:  "9.6.24",
+	}
+	for versionNum, want := range cases {
+		if got := FormatServerVersion(versionNum); got != want {
+			t.Errorf("FormatServerVersion(%d) = %q, want %q", versionNum, got, want)
+		}
+	}
+}
+
+func TestFeatureSupportedBy(t *testing.T) {
+	if FeatureNullsNotDistinct.SupportedBy(140009) {
+		t.Error("Expected PostgreSQL 14 not to support NULLS NOT DISTINCT")
+	}
+	if !FeatureNullsNotDistinct.SupportedBy(150000) || !FeatureNullsNotDistinct.SupportedBy(0) {
+		t.Error("Expected PostgreSQL 15 and an unknown version to support NULLS NOT DISTINCT")
+	}
+	if got := FeatureNullsNotDistinct.MinVersionLabel(); got != "15" {
+		t.Errorf("MinVersionLabel() = %q, want 15", got)
+	}
+	if MajorVersion(150004) != 150000 || MajorVersion(90624) != 90600 {
+		t.Error("Expected MajorVersion to drop the minor release")
+	}
+}
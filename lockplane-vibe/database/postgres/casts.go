@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+)
+
+// typeCategories groups PostgreSQL types whose values convert into each other
+// with an assignment cast
+var typeCategories = map[string]string{
+	"smallint": "numeric", "int2": "numeric", "integer": "numeric", "int": "numeric",
+	"int4": "numeric", "bigint": "numeric", "int8": "numeric", "numeric": "numeric",
+	"decimal": "numeric", "real": "numeric", "float4": "numeric", "double precision": "numeric",
+	"float8": "numeric", "float": "numeric", "smallserial": "numeric", "serial": "numeric",
+	"bigserial": "numeric",
+
+	"text": "string", "varchar": "string", "character varying": "string", "char": "string",
+	"character": "string", "bpchar": "string", "citext": "string", "name": "string",
+
+	"timestamp": "datetime", "timestamp without time zone": "datetime", "timestamptz": "datetime",
+	"timestamp with time zone": "datetime", "date": "datetime",
+
+	"time": "time", "time without time zone": "time", "timetz": "time", "time with time zone": "time",
+
+	"json": "json", "jsonb": "json",
+	"bit": "bit", "bit varying": "bit", "varbit": "bit",
+	"inet": "network", "cidr": "network",
+}
+
+// nullDefaultPattern matches a NULL default, optionally cast to a type
+var nullDefaultPattern = regexp.MustCompile(`(?i)^\s*\(?\s*null\s*\)?\s*(::.*)?$`)
+
+// DefaultSurvivesTypeChange reports whether PostgreSQL can carry defaultExpr
+// over when a column's type changes from oldType to newType. ALTER COLUMN TYPE
+// converts the default with an assignment cast from the old type and fails
+// when there is none; the USING clause doesn't apply to the default.
+func DefaultSurvivesTypeChange(defaultExpr, oldType, newType string) bool {
+	if nullDefaultPattern.MatchString(defaultExpr) {
+		return true
+	}
+	oldBase, newBase := castBaseType(oldType), castBaseType(newType)
+	if oldBase == newBase {
+		return true
+	}
+	// Every type converts to a string type through its output function
+	newCategory := typeCategories[newBase]
+	if newCategory == "string" {
+		return true
+	}
+	oldCategory := typeCategories[oldBase]
+	return oldCategory != "" && oldCategory == newCategory
+}
+
+// castBaseType lowercases a type and strips its modifiers, e.g.
+// "VARCHAR(255)" becomes "varchar" and "timestamp(3) with time zone" becomes
+// "timestamp with time zone"
+func castBaseType(columnType string) string {
+	key := strings.ToLower(columnType)
+	for {
+		open := strings.Index(key, "(")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(key[open:], ")")
+		if end < 0 {
+			key = key[:open]
+			break
+		}
+		key = key[:open] + key[open+end+1:]
+	}
+	return strings.Join(strings.Fields(key), " ")
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import "testing"
+
+func TestDefaultSurvivesTypeChange(t *testing.T) {
+	tests := []struct {
+		defaultExpr string
+		oldType     string
+		newType     string
+		want        bool
+	}{
+		{"0", "integer", "bigint", true},
+		{"0", "INTEGER", "numeric(10,2)", true},
+		{"0", "integer", "text", true},
+		{"'draft'::text", "text", "varchar(20)", true},
+		{"now()", "timestamp", "timestamp with time zone", true},
+		{"'{}'::jsonb", "jsonb", "json", true},
+		{"'active'::status", "status", "status", true},
+		{"NULL::text", "text", "integer", true},
+		{"'0'::text", "text", "integer", false},
+		{"gen_random_uuid()", "uuid", "bigint", false},
+		{"nextval('users_id_seq'::regclass)", "integer", "uuid", false},
+		{"false", "boolean", "integer", false},
+		{"'active'::text", "text", "status", false},
+		{"'{}'::integer[]", "integer[]", "bigint[]", false},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultSurvivesTypeChange(tt.defaultExpr, tt.oldType, tt.newType); got != tt.want {
+			t.Errorf("DefaultSurvivesTypeChange(%q, %q, %q) = %v, expected %v",
+				tt.defaultExpr, tt.oldType, tt.newType, got, tt.want)
+		}
+	}
+}
@@ -9,6 +9,31 @@ import (
 	"github.com/lockplane/lockplane/database"
 )
 
+// NullsNotDistinctMinVersion is the first server_version_num supporting
+// UNIQUE NULLS NOT DISTINCT (PostgreSQL 15)
+const NullsNotDistinctMinVersion = 150000
+
+// StorageDefaultMinVersion is the first server_version_num accepting
+// ALTER COLUMN ... SET STORAGE DEFAULT (PostgreSQL 16)
+const StorageDefaultMinVersion = 160000
+
+// IndexKeyAttsMinVersion is the first server_version_num with
+// pg_index.indnkeyatts (PostgreSQL 11, alongside INCLUDE columns)
+const IndexKeyAttsMinVersion = 110000
+
+// SequenceDataTypeMinVersion is the first server_version_num accepting
+// CREATE SEQUENCE ... AS (PostgreSQL 10)
+const SequenceDataTypeMinVersion = 100000
+
+// FastDefaultMinVersion is the first server_version_num that adds a column
+// with a non-volatile DEFAULT without rewriting the table (PostgreSQL 11)
+const FastDefaultMinVersion = 110000
+
+// ForeignKeyShareRowExclusiveMinVersion is the first server_version_num that
+// adds a foreign key under SHARE ROW EXCLUSIVE instead of ACCESS EXCLUSIVE
+// (PostgreSQL 9.5)
+const ForeignKeyShareRowExclusiveMinVersion = 90500
+
 // Driver implements database.Driver for PostgreSQL
 type Driver struct {
 	*Introspector
@@ -43,6 +68,22 @@ func (d *Driver) SupportsFeature(feature string) bool {
 		return true
 	case "information_schema":
 		return true
+	case "TABLESPACE":
+		return true
+	case "REPLICA_IDENTITY":
+		return true
+	case "COLUMN_STORAGE":
+		return true
+	case "COLUMN_STATISTICS":
+		return true
+	case "STORAGE_PARAMETERS":
+		return true
+	case "RENAME_CONSTRAINT":
+		return true
+	case "STATEMENT_TIMEOUT":
+		return true
+	case "SEQUENCES":
+		return true
 	default:
 		return false
 	}
@@ -67,6 +108,10 @@ func (d *Driver) IntrospectSchemas(ctx context.Context, db *sql.DB, schemas []st
 	return d.Introspector.IntrospectSchemas(ctx, db, schemas)
 }
 
+func (d *Driver) IntrospectTables(ctx context.Context, db *sql.DB, schemas []string, patterns []string) (*database.Schema, error) {
+	return d.Introspector.IntrospectTables(ctx, db, schemas, patterns)
+}
+
 func (d *Driver) GetTables(ctx context.Context, db *sql.DB) ([]string, error) {
 	return d.Introspector.GetTables(ctx, db)
 }
@@ -123,6 +168,14 @@ func (d *Driver) FormatColumnDefinition(col database.Column) string {
 	return d.Generator.FormatColumnDefinition(col)
 }
 
+func (d *Driver) QuoteIdentifier(name string) string {
+	return d.Generator.QuoteIdentifier(name)
+}
+
+func (d *Driver) RenderType(col database.Column) string {
+	return d.Generator.RenderType(col)
+}
+
 func (d *Driver) ParameterPlaceholder(position int) string {
 	return d.Generator.ParameterPlaceholder(position)
 }
@@ -44,6 +44,8 @@ func TestDriver_SupportsFeature(t *testing.T) {
 		{"ALTER_COLUMN_DEFAULT", true},
 		{"FOREIGN_KEYS", true},
 		{"information_schema", true},
+		{"TABLESPACE", true},
+		{"REPLICA_IDENTITY", true},
 		{"UNSUPPORTED_FEATURE", false},
 		{"", false},
 	}
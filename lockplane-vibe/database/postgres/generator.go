@@ -19,7 +19,7 @@ func NewGenerator() *Generator {
 func (g *Generator) CreateTable(table database.Table) (string, string) {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", table.Name))
+	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", g.quoteTableName(table.QualifiedName())))
 
 	// Add columns
 	for i, col := range table.Columns {
@@ -32,22 +32,29 @@ func (g *Generator) CreateTable(table database.Table) (string, string) {
 	}
 
 	sb.WriteString(")")
+	if len(table.StorageParameters) > 0 {
+		sb.WriteString(fmt.Sprintf(" WITH (%s)", database.FormatStorageParameters(table.StorageParameters)))
+	}
+	if table.Tablespace != nil && *table.Tablespace != "" {
+		sb.WriteString(fmt.Sprintf(" TABLESPACE %s", *table.Tablespace))
+	}
 
-	description := fmt.Sprintf("Create table %s", table.Name)
+	description := fmt.Sprintf("Create table %s", table.QualifiedName())
 	return sb.String(), description
 }
 
-// DropTable generates PostgreSQL SQL to drop a table
+// DropTable generates PostgreSQL SQL to drop a table.
+// CASCADE is never emitted here; the planner drops dependent objects explicitly.
 func (g *Generator) DropTable(table database.Table) (string, string) {
-	sql := fmt.Sprintf("DROP TABLE %s CASCADE", table.Name)
-	description := fmt.Sprintf("Drop table %s", table.Name)
+	sql := fmt.Sprintf("DROP TABLE %s", g.quoteTableName(table.QualifiedName()))
+	description := fmt.Sprintf("Drop table %s", table.QualifiedName())
 	return sql, description
 }
 
 // AddColumn generates PostgreSQL SQL to add a column
 func (g *Generator) AddColumn(tableName string, col database.Column) (string, string) {
 	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s",
-		tableName,
+		g.quoteTableName(tableName),
 		g.FormatColumnDefinition(col))
 	description := fmt.Sprintf("Add column %s to table %s", col.Name, tableName)
 	return sql, description
@@ -55,7 +62,7 @@ func (g *Generator) AddColumn(tableName string, col database.Column) (string, st
 
 // DropColumn generates PostgreSQL SQL to drop a column
 func (g *Generator) DropColumn(tableName string, col database.Column) (string, string) {
-	sql := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, col.Name)
+	sql := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", g.quoteTableName(tableName), g.QuoteIdentifier(col.Name))
 	description := fmt.Sprintf("Drop column %s from table %s", col.Name, tableName)
 	return sql, description
 }
@@ -63,11 +70,24 @@ func (g *Generator) DropColumn(tableName string, col database.Column) (string, s
 // ModifyColumn generates PostgreSQL SQL to modify a column
 func (g *Generator) ModifyColumn(tableName string, diff database.ColumnDiff) []database.PlanStep {
 	steps := []database.PlanStep{}
+	table, column := g.quoteTableName(tableName), g.QuoteIdentifier(diff.ColumnName)
 
-	// Handle type changes
+	// Handle type changes. PostgreSQL converts an existing default with an
+	// assignment cast and fails when there is none, so such a default is
+	// dropped and set again in the same statement.
+	defaultReset := false
 	if contains(diff.Changes, "type") {
+		newType := g.RenderType(diff.New)
 		sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s",
-			tableName, diff.ColumnName, diff.New.Type)
+			table, column, newType)
+		if diff.Old.Default != nil && !DefaultSurvivesTypeChange(*diff.Old.Default, diff.Old.Type, diff.New.Type) {
+			sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT, ALTER COLUMN %s TYPE %s",
+				table, column, column, newType)
+			if diff.New.Default != nil {
+				sql += fmt.Sprintf(", ALTER COLUMN %s SET DEFAULT %s", column, *diff.New.Default)
+			}
+			defaultReset = true
+		}
 		steps = append(steps, database.PlanStep{
 			Description: fmt.Sprintf("Change type of %s.%s from %s to %s",
 				tableName, diff.ColumnName, diff.Old.Type, diff.New.Type),
@@ -80,10 +100,10 @@ func (g *Generator) ModifyColumn(tableName string, diff database.ColumnDiff) []d
 		var sql string
 		if diff.New.Nullable {
 			sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL",
-				tableName, diff.ColumnName)
+				table, column)
 		} else {
 			sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL",
-				tableName, diff.ColumnName)
+				table, column)
 		}
 		steps = append(steps, database.PlanStep{
 			Description: fmt.Sprintf("Change nullability of %s.%s to %t",
@@ -93,14 +113,14 @@ func (g *Generator) ModifyColumn(tableName string, diff database.ColumnDiff) []d
 	}
 
 	// Handle default value changes
-	if contains(diff.Changes, "default") {
+	if contains(diff.Changes, "default") && !defaultReset {
 		var sql string
 		if diff.New.Default == nil {
 			sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT",
-				tableName, diff.ColumnName)
+				table, column)
 		} else {
 			sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s",
-				tableName, diff.ColumnName, *diff.New.Default)
+				table, column, *diff.New.Default)
 		}
 		steps = append(steps, database.PlanStep{
 			Description: fmt.Sprintf("Change default of %s.%s",
@@ -114,24 +134,72 @@ func (g *Generator) ModifyColumn(tableName string, diff database.ColumnDiff) []d
 
 // AddIndex generates PostgreSQL SQL to add an index
 func (g *Generator) AddIndex(tableName string, idx database.Index) (string, string) {
+	if idx.Constraint {
+		return g.addUniqueConstraint(tableName, idx)
+	}
+
 	uniqueStr := ""
 	if idx.Unique {
 		uniqueStr = "UNIQUE "
 	}
 
-	// Format column list
-	columns := strings.Join(idx.Columns, ", ")
+	// Format column list, including any DESC / NULLS FIRST|LAST clauses
+	keyColumns := idx.KeyColumns()
+	columnSQL := make([]string, 0, len(keyColumns))
+	for _, col := range keyColumns {
+		quoted := col
+		quoted.Name = g.QuoteIdentifier(col.Name)
+		columnSQL = append(columnSQL, quoted.SQL())
+	}
+	columns := strings.Join(columnSQL, ", ")
 
 	sql := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)",
-		uniqueStr, idx.Name, tableName, columns)
+		uniqueStr, g.QuoteIdentifier(idx.Name), g.quoteTableName(tableName), columns)
+	if len(idx.IncludeColumns) > 0 {
+		sql += fmt.Sprintf(" INCLUDE (%s)", g.quoteIdentifiers(idx.IncludeColumns))
+	}
+	if idx.Unique && idx.NullsNotDistinct {
+		sql += " NULLS NOT DISTINCT"
+	}
+	if idx.Tablespace != nil && *idx.Tablespace != "" {
+		sql += fmt.Sprintf(" TABLESPACE %s", g.QuoteIdentifier(*idx.Tablespace))
+	}
 
 	description := fmt.Sprintf("Create index %s on table %s", idx.Name, tableName)
 	return sql, description
 }
 
-// DropIndex generates PostgreSQL SQL to drop an index
+// addUniqueConstraint generates PostgreSQL SQL to add the UNIQUE constraint
+// an index backs, which creates the index
+func (g *Generator) addUniqueConstraint(tableName string, idx database.Index) (string, string) {
+	nullsStr := ""
+	if idx.NullsNotDistinct {
+		nullsStr = "NULLS NOT DISTINCT "
+	}
+
+	sql := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE %s(%s)",
+		g.quoteTableName(tableName), g.QuoteIdentifier(idx.Name), nullsStr, g.quoteIdentifiers(idx.Columns))
+	if len(idx.IncludeColumns) > 0 {
+		sql += fmt.Sprintf(" INCLUDE (%s)", g.quoteIdentifiers(idx.IncludeColumns))
+	}
+	if idx.Tablespace != nil && *idx.Tablespace != "" {
+		sql += fmt.Sprintf(" USING INDEX TABLESPACE %s", g.QuoteIdentifier(*idx.Tablespace))
+	}
+
+	description := fmt.Sprintf("Add unique constraint %s to table %s", idx.Name, tableName)
+	return sql, description
+}
+
+// DropIndex generates PostgreSQL SQL to drop an index. An index backing a
+// constraint can't be dropped directly, so its constraint is dropped instead.
 func (g *Generator) DropIndex(tableName string, idx database.Index) (string, string) {
-	sql := fmt.Sprintf("DROP INDEX %s", idx.Name)
+	if idx.Constraint {
+		sql := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", g.quoteTableName(tableName), g.QuoteIdentifier(idx.Name))
+		description := fmt.Sprintf("Drop unique constraint %s from table %s", idx.Name, tableName)
+		return sql, description
+	}
+
+	sql := fmt.Sprintf("DROP INDEX %s", g.quoteTableName(database.QualifiedIndexName(tableName, idx.Name)))
 	description := fmt.Sprintf("Drop index %s from table %s", idx.Name, tableName)
 	return sql, description
 }
@@ -139,11 +207,11 @@ func (g *Generator) DropIndex(tableName string, idx database.Index) (string, str
 // AddForeignKey generates PostgreSQL SQL to add a foreign key
 func (g *Generator) AddForeignKey(tableName string, fk database.ForeignKey) (string, string) {
 	// Format column lists
-	columns := strings.Join(fk.Columns, ", ")
-	refColumns := strings.Join(fk.ReferencedColumns, ", ")
+	columns := g.quoteIdentifiers(fk.Columns)
+	refColumns := g.quoteIdentifiers(fk.ReferencedColumns)
 
 	sql := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
-		tableName, fk.Name, columns, fk.ReferencedTable, refColumns)
+		g.quoteTableName(tableName), g.QuoteIdentifier(fk.Name), columns, g.quoteTableName(fk.ReferencedTable), refColumns)
 
 	// Add ON DELETE and ON UPDATE actions if specified
 	if fk.OnDelete != nil {
@@ -159,7 +227,7 @@ func (g *Generator) AddForeignKey(tableName string, fk database.ForeignKey) (str
 
 // DropForeignKey generates PostgreSQL SQL to drop a foreign key
 func (g *Generator) DropForeignKey(tableName string, fk database.ForeignKey) (string, string) {
-	sql := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", tableName, fk.Name)
+	sql := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", g.quoteTableName(tableName), g.QuoteIdentifier(fk.Name))
 	description := fmt.Sprintf("Drop foreign key %s from table %s", fk.Name, tableName)
 	return sql, description
 }
@@ -169,7 +237,7 @@ func (g *Generator) FormatColumnDefinition(col database.Column) string {
 	var sb strings.Builder
 
 	// Column name and type
-	sb.WriteString(fmt.Sprintf("%s %s", col.Name, col.Type))
+	sb.WriteString(fmt.Sprintf("%s %s", g.QuoteIdentifier(col.Name), g.RenderType(col)))
 
 	// Nullability
 	if !col.Nullable {
@@ -47,14 +47,30 @@ func TestGenerator_CreateTable(t *testing.T) {
 	}
 }
 
+func TestGenerator_CreateTable_StorageParameters(t *testing.T) {
+	gen := NewGenerator()
+	tablespace := "fast_ssd"
+
+	sql, _ := gen.CreateTable(database.Table{
+		Name:              "orders",
+		Columns:           []database.Column{{Name: "id", Type: "bigint"}},
+		Tablespace:        &tablespace,
+		StorageParameters: map[string]string{"fillfactor": "70", "autovacuum_enabled": "false"},
+	})
+
+	if !strings.HasSuffix(sql, ") WITH (autovacuum_enabled = false, fillfactor = 70) TABLESPACE fast_ssd") {
+		t.Errorf("Expected storage parameters before the tablespace, got: %s", sql)
+	}
+}
+
 func TestGenerator_DropTable(t *testing.T) {
 	gen := NewGenerator()
 
 	table := database.Table{Name: "old_table"}
 	sql, desc := gen.DropTable(table)
 
-	if sql != "DROP TABLE old_table CASCADE" {
-		t.Errorf("Expected 'DROP TABLE old_table CASCADE', got: %s", sql)
+	if sql != "DROP TABLE old_table" {
+		t.Errorf("Expected 'DROP TABLE old_table', got: %s", sql)
 	}
 
 	if !strings.Contains(desc, "Drop table old_table") {
@@ -144,6 +160,45 @@ func TestGenerator_ModifyColumn_Type(t *testing.T) {
 	}
 }
 
+func TestGenerator_ModifyColumn_TypeRestoresDefault(t *testing.T) {
+	gen := NewGenerator()
+
+	oldDefault := "'0'::text"
+	newDefault := "0"
+	diff := database.ColumnDiff{
+		ColumnName: "score",
+		Old:        database.Column{Name: "score", Type: "text", Default: &oldDefault},
+		New:        database.Column{Name: "score", Type: "integer", Default: &newDefault},
+		Changes:    []string{"type", "default"},
+	}
+
+	steps := gen.ModifyColumn("users", diff)
+	if len(steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d: %+v", len(steps), steps)
+	}
+	want := "ALTER TABLE users ALTER COLUMN score DROP DEFAULT, ALTER COLUMN score TYPE integer, ALTER COLUMN score SET DEFAULT 0"
+	if len(steps[0].SQL) != 1 || steps[0].SQL[0] != want {
+		t.Errorf("Expected the default to be dropped and set again around the type change, got: %v", steps[0].SQL)
+	}
+
+	// A removed default is only dropped
+	diff.New.Default = nil
+	steps = gen.ModifyColumn("users", diff)
+	want = "ALTER TABLE users ALTER COLUMN score DROP DEFAULT, ALTER COLUMN score TYPE integer"
+	if len(steps) != 1 || steps[0].SQL[0] != want {
+		t.Errorf("Expected a single DROP DEFAULT, got: %+v", steps)
+	}
+
+	// A default the new type can cast is left to PostgreSQL
+	diff.Old = database.Column{Name: "score", Type: "integer", Default: &newDefault}
+	diff.New = database.Column{Name: "score", Type: "bigint", Default: &newDefault}
+	diff.Changes = []string{"type"}
+	steps = gen.ModifyColumn("users", diff)
+	if len(steps) != 1 || steps[0].SQL[0] != "ALTER TABLE users ALTER COLUMN score TYPE bigint" {
+		t.Errorf("Expected a plain type change, got: %+v", steps)
+	}
+}
+
 func TestGenerator_ModifyColumn_Nullable(t *testing.T) {
 	gen := NewGenerator()
 
@@ -420,3 +475,142 @@ func TestGenerator_ParameterPlaceholder(t *testing.T) {
 func ptrString(s string) *string {
 	return &s
 }
+
+func TestGenerator_Tablespace(t *testing.T) {
+	gen := NewGenerator()
+	tablespace := "fast_ssd"
+
+	table := database.Table{
+		Name:       "events",
+		Columns:    []database.Column{{Name: "id", Type: "bigint", Nullable: false}},
+		Tablespace: &tablespace,
+	}
+	sql, _ := gen.CreateTable(table)
+	if !strings.HasSuffix(sql, ") TABLESPACE fast_ssd") {
+		t.Errorf("Expected CREATE TABLE to end with TABLESPACE clause, got: %s", sql)
+	}
+
+	idx := database.Index{Name: "idx_events_id", Columns: []string{"id"}, Tablespace: &tablespace}
+	sql, _ = gen.AddIndex("events", idx)
+	if sql != "CREATE INDEX idx_events_id ON events (id) TABLESPACE fast_ssd" {
+		t.Errorf("Expected CREATE INDEX with TABLESPACE, got: %s", sql)
+	}
+}
+
+func TestGenerator_NullsNotDistinct(t *testing.T) {
+	gen := NewGenerator()
+	tablespace := "fast_ssd"
+
+	idx := database.Index{Name: "users_email_key", Columns: []string{"email"}, Unique: true, NullsNotDistinct: true, Tablespace: &tablespace}
+	sql, _ := gen.AddIndex("users", idx)
+	if sql != "CREATE UNIQUE INDEX users_email_key ON users (email) NULLS NOT DISTINCT TABLESPACE fast_ssd" {
+		t.Errorf("Expected CREATE UNIQUE INDEX with NULLS NOT DISTINCT, got: %s", sql)
+	}
+}
+
+func TestGenerator_AddIndex_IncludeColumns(t *testing.T) {
+	gen := NewGenerator()
+
+	idx := database.Index{Name: "orders_user_id_key", Columns: []string{"user_id"}, Unique: true, NullsNotDistinct: true, IncludeColumns: []string{"status", "total"}}
+	sql, _ := gen.AddIndex("orders", idx)
+	if sql != "CREATE UNIQUE INDEX orders_user_id_key ON orders (user_id) INCLUDE (status, total) NULLS NOT DISTINCT" {
+		t.Errorf("Expected CREATE INDEX with INCLUDE clause, got: %s", sql)
+	}
+}
+
+func TestGenerator_UniqueConstraintIndex(t *testing.T) {
+	gen := NewGenerator()
+
+	tablespace := "fast_ssd"
+	idx := database.Index{Name: "orders_user_id_key", Columns: []string{"user_id"}, Unique: true, NullsNotDistinct: true,
+		IncludeColumns: []string{"status"}, Tablespace: &tablespace, Constraint: true}
+	sql, desc := gen.AddIndex("orders", idx)
+	if sql != "ALTER TABLE orders ADD CONSTRAINT orders_user_id_key UNIQUE NULLS NOT DISTINCT (user_id) INCLUDE (status) USING INDEX TABLESPACE fast_ssd" {
+		t.Errorf("Expected ADD CONSTRAINT ... UNIQUE, got: %s", sql)
+	}
+	if desc != "Add unique constraint orders_user_id_key to table orders" {
+		t.Errorf("Unexpected description: %s", desc)
+	}
+
+	// The backing index can't be dropped on its own
+	sql, _ = gen.DropIndex("orders", idx)
+	if sql != "ALTER TABLE orders DROP CONSTRAINT orders_user_id_key" {
+		t.Errorf("Expected DROP CONSTRAINT, got: %s", sql)
+	}
+}
+
+func TestGenerator_AddIndex_Ordering(t *testing.T) {
+	gen := NewGenerator()
+
+	idx := database.Index{Name: "idx_events_recent", Columns: []string{"tenant_id", "created_at"}}
+	idx.SetKeyColumns([]database.IndexColumn{
+		{Name: "tenant_id"},
+		{Name: "created_at", Descending: true, NullsOrder: database.NullsLast},
+	})
+	sql, _ := gen.AddIndex("events", idx)
+	if sql != "CREATE INDEX idx_events_recent ON events (tenant_id, created_at DESC NULLS LAST)" {
+		t.Errorf("Expected CREATE INDEX with ordering clauses, got: %s", sql)
+	}
+}
+
+func TestGenerator_AddForeignKeyIfNotExists(t *testing.T) {
+	gen := NewGenerator()
+	onDelete := "cascade"
+	fk := database.ForeignKey{
+		Name:              "fk_posts_user",
+		Columns:           []string{"user_id"},
+		ReferencedTable:   "users",
+		ReferencedColumns: []string{"id"},
+		OnDelete:          &onDelete,
+	}
+
+	sql, desc := gen.AddForeignKeyIfNotExists("posts", fk)
+
+	for _, want := range []string{
+		"DO $lockplane$",
+		"WHERE conname = 'fk_posts_user' AND conrelid = 'posts'::regclass",
+		"IF NOT FOUND THEN\n    ALTER TABLE posts ADD CONSTRAINT fk_posts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE cascade;",
+		"existing.confrelid <> 'users'::regclass",
+		"<> ARRAY['user_id']::text[]",
+		"<> ARRAY['id']::text[]",
+		"existing.confdeltype <> 'c'",
+		"existing.confupdtype <> 'a'",
+		"RAISE EXCEPTION 'constraint % on table % already exists with a different definition', 'fk_posts_user', 'posts'",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", want, sql)
+		}
+	}
+	if !strings.HasSuffix(sql, "END\n$lockplane$") {
+		t.Errorf("Expected the DO block to be closed, got:\n%s", sql)
+	}
+	if desc != "Add foreign key fk_posts_user to table posts (if not exists)" {
+		t.Errorf("Unexpected description: %s", desc)
+	}
+}
+
+func TestGenerator_AddIndexIfNotExists(t *testing.T) {
+	gen := NewGenerator()
+	idx := database.Index{Name: "idx_users_email", Columns: []string{"email", "tenant_id"}, Unique: true}
+
+	sql, _ := gen.AddIndexIfNotExists("users", idx)
+
+	for _, want := range []string{
+		"WHERE indexrelid = to_regclass('idx_users_email')",
+		"IF NOT FOUND THEN\n    CREATE UNIQUE INDEX idx_users_email ON users (email, tenant_id);",
+		"existing.indrelid <> 'users'::regclass",
+		"existing.indisunique <> true",
+		"<> ARRAY['email', 'tenant_id']::text[]",
+		"RAISE EXCEPTION 'index % on table % already exists with a different definition'",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", want, sql)
+		}
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	if got := quoteLiteral("it's"); got != "'it''s'" {
+		t.Errorf("Expected 'it''s', got %s", got)
+	}
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// guardTag is the dollar-quote tag around generated DO blocks
+const guardTag = "$lockplane$"
+
+// foreignKeyActionCodes maps ON DELETE/ON UPDATE actions to pg_constraint.confdeltype/confupdtype
+var foreignKeyActionCodes = map[string]string{
+	"NO ACTION":   "a",
+	"RESTRICT":    "r",
+	"CASCADE":     "c",
+	"SET NULL":    "n",
+	"SET DEFAULT": "d",
+}
+
+// AddForeignKeyIfNotExists generates SQL that adds a foreign key unless an
+// identical one already exists, so a partially applied plan can be re-run.
+// A same-named constraint with different columns, referenced table or
+// actions raises an error rather than being silently accepted.
+func (g *Generator) AddForeignKeyIfNotExists(tableName string, fk database.ForeignKey) (string, string) {
+	addSQL, _ := g.AddForeignKey(tableName, fk)
+
+	var sb strings.Builder
+	sb.WriteString("DO " + guardTag + "\nDECLARE\n  existing pg_constraint%ROWTYPE;\nBEGIN\n")
+	fmt.Fprintf(&sb, "  SELECT * INTO existing FROM pg_constraint WHERE conname = %s AND conrelid = %s::regclass;\n",
+		quoteLiteral(fk.Name), quoteLiteral(g.quoteTableName(tableName)))
+	sb.WriteString("  IF NOT FOUND THEN\n")
+	fmt.Fprintf(&sb, "    %s;\n", addSQL)
+	sb.WriteString("  ELSIF existing.contype <> 'f'\n")
+	fmt.Fprintf(&sb, "    OR existing.confrelid <> %s::regclass\n", quoteLiteral(g.quoteTableName(fk.ReferencedTable)))
+	fmt.Fprintf(&sb, "    OR %s <> %s\n", constraintColumnsSQL("conrelid", "conkey"), textArray(fk.Columns))
+	fmt.Fprintf(&sb, "    OR %s <> %s\n", constraintColumnsSQL("confrelid", "confkey"), textArray(fk.ReferencedColumns))
+	fmt.Fprintf(&sb, "    OR existing.confdeltype <> %s\n", quoteLiteral(foreignKeyActionCode(fk.OnDelete)))
+	fmt.Fprintf(&sb, "    OR existing.confupdtype <> %s THEN\n", quoteLiteral(foreignKeyActionCode(fk.OnUpdate)))
+	fmt.Fprintf(&sb, "    RAISE EXCEPTION 'constraint %% on table %% already exists with a different definition', %s, %s;\n",
+		quoteLiteral(fk.Name), quoteLiteral(tableName))
+	sb.WriteString("  END IF;\nEND\n" + guardTag)
+
+	description := fmt.Sprintf("Add foreign key %s to table %s (if not exists)", fk.Name, tableName)
+	return sb.String(), description
+}
+
+// AddIndexIfNotExists generates SQL that creates an index unless an index
+// with the same name, table, key columns and uniqueness already exists.
+// A same-named index with a different definition raises an error.
+func (g *Generator) AddIndexIfNotExists(tableName string, idx database.Index) (string, string) {
+	addSQL, _ := g.AddIndex(tableName, idx)
+
+	keyColumns := idx.KeyColumns()
+	names := make([]string, 0, len(keyColumns))
+	for _, col := range keyColumns {
+		names = append(names, col.Name)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("DO " + guardTag + "\nDECLARE\n  existing pg_index%ROWTYPE;\nBEGIN\n")
+	fmt.Fprintf(&sb, "  SELECT * INTO existing FROM pg_index WHERE indexrelid = to_regclass(%s);\n", quoteLiteral(g.quoteTableName(database.QualifiedIndexName(tableName, idx.Name))))
+	sb.WriteString("  IF NOT FOUND THEN\n")
+	fmt.Fprintf(&sb, "    %s;\n", addSQL)
+	fmt.Fprintf(&sb, "  ELSIF existing.indrelid <> %s::regclass\n", quoteLiteral(g.quoteTableName(tableName)))
+	fmt.Fprintf(&sb, "    OR existing.indisunique <> %t\n", idx.Unique)
+	fmt.Fprintf(&sb, "    OR ARRAY(SELECT pg_get_indexdef(existing.indexrelid, k, true) FROM generate_series(1, existing.indnkeyatts) AS k) <> %s THEN\n",
+		textArray(names))
+	fmt.Fprintf(&sb, "    RAISE EXCEPTION 'index %% on table %% already exists with a different definition', %s, %s;\n",
+		quoteLiteral(idx.Name), quoteLiteral(tableName))
+	sb.WriteString("  END IF;\nEND\n" + guardTag)
+
+	description := fmt.Sprintf("Create index %s on table %s (if not exists)", idx.Name, tableName)
+	return sb.String(), description
+}
+
+// constraintColumnsSQL returns an expression listing, in key order, the names
+// of the columns in existing.<keyColumn> on relation existing.<relColumn>
+func constraintColumnsSQL(relColumn, keyColumn string) string {
+	return fmt.Sprintf("ARRAY(SELECT a.attname::text FROM unnest(existing.%s) WITH ORDINALITY AS k(attnum, ord) "+
+		"JOIN pg_attribute a ON a.attrelid = existing.%s AND a.attnum = k.attnum ORDER BY k.ord)", keyColumn, relColumn)
+}
+
+// foreignKeyActionCode returns the pg_constraint code for a referential action (NO ACTION when unset)
+func foreignKeyActionCode(action *string) string {
+	if action == nil {
+		return "a"
+	}
+	if code, ok := foreignKeyActionCodes[strings.ToUpper(strings.TrimSpace(*action))]; ok {
+		return code
+	}
+	return "a"
+}
+
+// textArray formats values as a PostgreSQL text[] literal expression
+func textArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, quoteLiteral(v))
+	}
+	return fmt.Sprintf("ARRAY[%s]::text[]", strings.Join(quoted, ", "))
+}
+
+// quoteLiteral quotes a string as a PostgreSQL literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
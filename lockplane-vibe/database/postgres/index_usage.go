@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// IndexUsage describes an index of a live database and how often it has been
+// scanned, for advice that needs every index, including the ones backing
+// primary keys and unique constraints that IntrospectTables leaves out
+type IndexUsage struct {
+	Schema string
+	Table  string
+	Name   string
+	// Columns are the key columns in index order; expression columns are
+	// empty strings
+	Columns []string
+	Unique  bool
+	// Constraint is set when the index backs a primary key, unique or
+	// exclusion constraint
+	Constraint bool
+	Partial    bool  // The index has a WHERE clause
+	Scans      int64 // pg_stat_user_indexes.idx_scan since the statistics were last reset
+	SizeBytes  int64
+}
+
+// GetIndexUsage returns the valid indexes of the tables in schemas (the
+// current schema when schemas is empty) with their scan counts, ordered by
+// schema, table and index name
+func (i *Introspector) GetIndexUsage(ctx context.Context, db *sql.DB, schemas []string) ([]IndexUsage, error) {
+	if len(schemas) == 0 {
+		currentSchema, err := i.getCurrentSchema(ctx, db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current schema: %w", err)
+		}
+		schemas = []string{currentSchema}
+	}
+	serverVersion, err := i.GetServerVersion(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server version: %w", err)
+	}
+
+	// pg_index.indnkeyatts (key columns, excluding INCLUDE columns) only exists from PostgreSQL 11
+	keyColumnCount := "ix.indnatts"
+	if serverVersion >= IndexKeyAttsMinVersion {
+		keyColumnCount = "ix.indnkeyatts"
+	}
+
+	query := `
+		SELECT
+			n.nspname,
+			t.relname,
+			c.relname,
+			ix.indisunique,
+			ix.indisprimary OR EXISTS (
+				SELECT 1
+				FROM pg_constraint con
+				WHERE con.conindid = ix.indexrelid
+				  AND con.contype IN ('p', 'u', 'x')
+			),
+			ix.indpred IS NOT NULL,
+			COALESCE(s.idx_scan, 0),
+			pg_relation_size(c.oid),
+			COALESCE((
+				SELECT json_agg(COALESCE(a.attname, '') ORDER BY k.ord)
+				FROM generate_series(0, ` + keyColumnCount + ` - 1) AS k(ord)
+				LEFT JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ix.indkey[k.ord]
+			), '[]')::text
+		FROM pg_index ix
+		JOIN pg_class c ON c.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		LEFT JOIN pg_stat_user_indexes s ON s.indexrelid = ix.indexrelid
+		WHERE n.nspname = $1
+		  AND t.relkind IN ('r', 'p')
+		  AND ix.indisvalid
+		ORDER BY t.relname, c.relname
+	`
+
+	var usage []IndexUsage
+	for _, schemaName := range schemas {
+		found, err := queryIndexUsage(ctx, db, query, schemaName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query index usage in schema %s: %w", schemaName, err)
+		}
+		usage = append(usage, found...)
+	}
+	return usage, nil
+}
+
+func queryIndexUsage(ctx context.Context, db *sql.DB, query, schemaName string) ([]IndexUsage, error) {
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var usage []IndexUsage
+	for rows.Next() {
+		var u IndexUsage
+		var columnsJSON string
+		if err := rows.Scan(&u.Schema, &u.Table, &u.Name, &u.Unique, &u.Constraint, &u.Partial, &u.Scans, &u.SizeBytes, &columnsJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(columnsJSON), &u.Columns); err != nil {
+			return nil, fmt.Errorf("failed to parse columns of index %s: %w", u.Name, err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
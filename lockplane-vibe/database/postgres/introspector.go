@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -26,10 +27,31 @@ func (i *Introspector) IntrospectSchema(ctx context.Context, db *sql.DB) (*datab
 // IntrospectSchemas reads tables from multiple PostgreSQL schemas
 // If schemas is nil or empty, uses current_schema()
 func (i *Introspector) IntrospectSchemas(ctx context.Context, db *sql.DB, schemas []string) (*database.Schema, error) {
+	return i.IntrospectTables(ctx, db, schemas, nil)
+}
+
+// IntrospectTables reads the tables matching patterns from multiple PostgreSQL
+// schemas. An empty pattern list reads every table.
+func (i *Introspector) IntrospectTables(ctx context.Context, db *sql.DB, schemas []string, patterns []string) (*database.Schema, error) {
 	schema := &database.Schema{
 		Tables: make([]database.Table, 0),
 	}
 
+	serverVersion, err := i.GetServerVersion(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server version: %w", err)
+	}
+	schema.ServerVersion = serverVersion
+
+	if err := db.QueryRowContext(ctx, "SELECT current_user").Scan(&schema.CurrentUser); err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	schema.DefaultTablespace, err = i.getDefaultTablespace(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default tablespace: %w", err)
+	}
+
 	// If no schemas specified, use current_schema()
 	if len(schemas) == 0 {
 		currentSchema, err := i.getCurrentSchema(ctx, db)
@@ -41,15 +63,20 @@ func (i *Introspector) IntrospectSchemas(ctx context.Context, db *sql.DB, schema
 
 	// Introspect each schema
 	for _, schemaName := range schemas {
-		tables, err := i.GetTablesInSchema(ctx, db, schemaName)
+		tables, err := i.getTablesInSchema(ctx, db, schemaName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get tables in schema %s: %w", schemaName, err)
 		}
 
-		for _, tableName := range tables {
+		for _, t := range tables {
+			tableName := t.name
+			if !database.MatchTable(schemaName, tableName, patterns) {
+				continue
+			}
 			table := database.Table{
-				Name:   tableName,
-				Schema: schemaName,
+				Name:       tableName,
+				Schema:     schemaName,
+				Tablespace: t.tablespace,
 			}
 
 			columns, err := i.GetColumnsInSchema(ctx, db, schemaName, tableName)
@@ -58,7 +85,7 @@ func (i *Introspector) IntrospectSchemas(ctx context.Context, db *sql.DB, schema
 			}
 			table.Columns = columns
 
-			indexes, err := i.GetIndexesInSchema(ctx, db, schemaName, tableName)
+			indexes, err := i.getIndexesInSchema(ctx, db, schemaName, tableName, serverVersion)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get indexes for table %s.%s: %w", schemaName, tableName, err)
 			}
@@ -77,6 +104,25 @@ func (i *Introspector) IntrospectSchemas(ctx context.Context, db *sql.DB, schema
 			}
 			table.RLSEnabled = rlsEnabled
 
+			replicaIdentity, err := i.GetReplicaIdentityInSchema(ctx, db, schemaName, tableName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get replica identity for table %s.%s: %w", schemaName, tableName, err)
+			}
+			table.ReplicaIdentity = replicaIdentity
+
+			storageParameters, err := i.GetStorageParametersInSchema(ctx, db, schemaName, tableName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get storage parameters for table %s.%s: %w", schemaName, tableName, err)
+			}
+			table.StorageParameters = storageParameters
+
+			owner, acl, err := i.GetOwnershipInSchema(ctx, db, schemaName, tableName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get owner for table %s.%s: %w", schemaName, tableName, err)
+			}
+			table.Owner = owner
+			table.ACL = acl
+
 			// Get RLS policies if RLS is enabled
 			if rlsEnabled {
 				policies, err := i.GetPoliciesInSchema(ctx, db, schemaName, tableName)
@@ -88,12 +134,41 @@ func (i *Introspector) IntrospectSchemas(ctx context.Context, db *sql.DB, schema
 
 			schema.Tables = append(schema.Tables, table)
 		}
+
+		if serverVersion >= 100000 {
+			sequences, err := i.GetSequencesInSchema(ctx, db, schemaName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get sequences in schema %s: %w", schemaName, err)
+			}
+			schema.Sequences = append(schema.Sequences, sequences...)
+		}
 	}
 
+	database.MarkPartial(schema, patterns)
 	schema.Dialect = database.DialectPostgres
 	return schema, nil
 }
 
+// GetServerVersion returns the server's version as server_version_num (e.g. 150004 for 15.4)
+func (i *Introspector) GetServerVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&version)
+	return version, err
+}
+
+// getDefaultTablespace returns the tablespace the current database stores
+// objects in when they don't name one
+func (i *Introspector) getDefaultTablespace(ctx context.Context, db *sql.DB) (string, error) {
+	var tablespace string
+	err := db.QueryRowContext(ctx, `
+		SELECT t.spcname
+		FROM pg_catalog.pg_database d
+		JOIN pg_catalog.pg_tablespace t ON t.oid = d.dattablespace
+		WHERE d.datname = current_database()
+	`).Scan(&tablespace)
+	return tablespace, err
+}
+
 // getCurrentSchema gets the current PostgreSQL schema
 func (i *Introspector) getCurrentSchema(ctx context.Context, db *sql.DB) (string, error) {
 	var schemaName string
@@ -115,28 +190,55 @@ func (i *Introspector) GetTables(ctx context.Context, db *sql.DB) ([]string, err
 
 // GetTablesInSchema returns all table names in a specific PostgreSQL schema
 func (i *Introspector) GetTablesInSchema(ctx context.Context, db *sql.DB, schemaName string) ([]string, error) {
+	tables, err := i.getTablesInSchema(ctx, db, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	tableNames := make([]string, 0, len(tables))
+	for _, t := range tables {
+		tableNames = append(tableNames, t.name)
+	}
+	return tableNames, nil
+}
+
+// schemaTable is a table found by getTablesInSchema
+type schemaTable struct {
+	name       string
+	tablespace *string // nil = database default
+}
+
+// getTablesInSchema returns the tables in a PostgreSQL schema with the
+// tablespace each is stored in
+func (i *Introspector) getTablesInSchema(ctx context.Context, db *sql.DB, schemaName string) ([]schemaTable, error) {
 	rows, err := db.QueryContext(ctx, `
-		SELECT table_name
-		FROM information_schema.tables
-		WHERE table_schema = $1
-		AND table_type = 'BASE TABLE'
-		ORDER BY table_name
+		SELECT t.table_name, ts.spcname
+		FROM information_schema.tables t
+		JOIN pg_catalog.pg_namespace n ON n.nspname = t.table_schema
+		JOIN pg_catalog.pg_class c ON c.relnamespace = n.oid AND c.relname = t.table_name
+		LEFT JOIN pg_catalog.pg_tablespace ts ON ts.oid = c.reltablespace
+		WHERE t.table_schema = $1
+		AND t.table_type = 'BASE TABLE'
+		ORDER BY t.table_name
 	`, schemaName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tables in schema %s: %w", schemaName, err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var tableNames []string
+	var tables []schemaTable
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var table schemaTable
+		var tablespace sql.NullString
+		if err := rows.Scan(&table.name, &tablespace); err != nil {
 			return nil, fmt.Errorf("failed to scan table name: %w", err)
 		}
-		tableNames = append(tableNames, tableName)
+		if tablespace.Valid {
+			table.tablespace = &tablespace.String
+		}
+		tables = append(tables, table)
 	}
 
-	return tableNames, nil
+	return tables, rows.Err()
 }
 
 // GetColumns returns all columns for a given PostgreSQL table in current_schema()
@@ -167,7 +269,20 @@ func (i *Introspector) GetColumnsInSchema(ctx context.Context, db *sql.DB, schem
 				   AND tc.constraint_type = 'PRIMARY KEY'
 				   AND kcu.column_name = c.column_name),
 				false
-			) as is_primary_key
+			) as is_primary_key,
+			(SELECT CASE WHEN a.attstorage <> t.typstorage THEN a.attstorage::text END
+			 FROM pg_catalog.pg_attribute a
+			 JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+			 WHERE a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+			   AND a.attname = c.column_name) as storage,
+			(SELECT format_type(a.atttypid, a.atttypmod)
+			 FROM pg_catalog.pg_attribute a
+			 WHERE a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+			   AND a.attname = c.column_name) as formatted_type,
+			(SELECT CASE WHEN a.attstattarget >= 0 THEN a.attstattarget::integer END
+			 FROM pg_catalog.pg_attribute a
+			 WHERE a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+			   AND a.attname = c.column_name) as statistics_target
 		FROM information_schema.columns c
 		WHERE c.table_schema = $1
 		  AND c.table_name = $2
@@ -185,12 +300,15 @@ func (i *Introspector) GetColumnsInSchema(ctx context.Context, db *sql.DB, schem
 		var col database.Column
 		var nullable string
 		var defaultVal sql.NullString
+		var storage sql.NullString
+		var formattedType sql.NullString
+		var statisticsTarget sql.NullInt64
 
-		if err := rows.Scan(&col.Name, &col.Type, &nullable, &defaultVal, &col.IsPrimaryKey); err != nil {
+		if err := rows.Scan(&col.Name, &col.Type, &nullable, &defaultVal, &col.IsPrimaryKey, &storage, &formattedType, &statisticsTarget); err != nil {
 			return nil, err
 		}
 
-		col.Type = strings.TrimSpace(col.Type)
+		col.Type = columnType(col.Type, formattedType)
 
 		// Detect SERIAL/BIGSERIAL pseudo-types
 		// PostgreSQL converts BIGSERIAL to BIGINT with nextval() default
@@ -233,14 +351,67 @@ func (i *Introspector) GetColumnsInSchema(ctx context.Context, db *sql.DB, schem
 			col.DefaultMetadata = nil
 		}
 
+		// Storage is only recorded when it differs from the type's default
+		if storage.Valid {
+			col.Storage = storageName(storage.String)
+		}
+		// The default target is -1 (NULL from PostgreSQL 17) and not recorded
+		if statisticsTarget.Valid {
+			target := int(statisticsTarget.Int64)
+			col.StatisticsTarget = &target
+		}
+
 		columns = append(columns, col)
 	}
 
 	return columns, nil
 }
 
+// columnType returns a column's type from its information_schema data_type
+// and format_type. information_schema drops the precision of timestamp, time
+// and interval columns, and names every array "ARRAY" and every extension or
+// enum type "USER-DEFINED"; format_type keeps them (e.g. "timestamp(3) with
+// time zone", "tsvector[]", "citext"). Built-in types are reported without
+// their pg_catalog qualification, as the parser does.
+func columnType(dataType string, formattedType sql.NullString) string {
+	dataType = strings.TrimSpace(dataType)
+	if !formattedType.Valid {
+		return dataType
+	}
+	if isTemporalType(dataType) || dataType == "ARRAY" || dataType == "USER-DEFINED" {
+		return strings.TrimPrefix(strings.TrimSpace(formattedType.String), "pg_catalog.")
+	}
+	return dataType
+}
+
+// isTemporalType reports whether an information_schema data_type is a
+// timestamp, time or interval type
+func isTemporalType(dataType string) bool {
+	lower := strings.ToLower(dataType)
+	return strings.HasPrefix(lower, "timestamp") || strings.HasPrefix(lower, "time ") || lower == "time" || strings.HasPrefix(lower, "interval")
+}
+
+// storageName maps a pg_attribute.attstorage code to its storage mode
+func storageName(code string) *string {
+	var name string
+	switch code {
+	case "p":
+		name = "PLAIN"
+	case "e":
+		name = "EXTERNAL"
+	case "x":
+		name = "EXTENDED"
+	case "m":
+		name = "MAIN"
+	default:
+		return nil
+	}
+	return &name
+}
+
 // GetIndexes returns all indexes for a given PostgreSQL table in current_schema()
-// Excludes indexes that are automatically created by PRIMARY KEY or UNIQUE constraints
+// Excludes indexes backing PRIMARY KEY constraints; indexes backing UNIQUE
+// constraints are marked Constraint
 func (i *Introspector) GetIndexes(ctx context.Context, db *sql.DB, tableName string) ([]database.Index, error) {
 	currentSchema, err := i.getCurrentSchema(ctx, db)
 	if err != nil {
@@ -250,13 +421,56 @@ func (i *Introspector) GetIndexes(ctx context.Context, db *sql.DB, tableName str
 }
 
 // GetIndexesInSchema returns all indexes for a given PostgreSQL table in a specific schema
-// Excludes indexes that are automatically created by PRIMARY KEY or UNIQUE constraints
+// Excludes indexes backing PRIMARY KEY constraints; indexes backing UNIQUE
+// constraints are marked Constraint
 func (i *Introspector) GetIndexesInSchema(ctx context.Context, db *sql.DB, schemaName, tableName string) ([]database.Index, error) {
+	serverVersion, err := i.GetServerVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return i.getIndexesInSchema(ctx, db, schemaName, tableName, serverVersion)
+}
+
+func (i *Introspector) getIndexesInSchema(ctx context.Context, db *sql.DB, schemaName, tableName string, serverVersion int) ([]database.Index, error) {
+	// pg_index.indnullsnotdistinct only exists from PostgreSQL 15
+	nullsNotDistinct := "false"
+	if serverVersion >= NullsNotDistinctMinVersion {
+		nullsNotDistinct = "ix.indnullsnotdistinct"
+	}
+
+	// pg_index.indnkeyatts (key columns, excluding INCLUDE columns) only exists from PostgreSQL 11
+	keyColumnCount := "ix.indnatts"
+	if serverVersion >= IndexKeyAttsMinVersion {
+		keyColumnCount = "ix.indnkeyatts"
+	}
+
+	// Key columns as a JSON array of [name, indoption] pairs, then the
+	// INCLUDE columns (positions after the key columns) as a JSON array of
+	// names. Expression columns (indkey = 0) have no pg_attribute row and are
+	// left out.
 	query := `
 		SELECT
 			i.indexname,
 			i.indexdef,
-			ix.indisunique
+			ix.indisunique,
+			i.tablespace,
+			` + nullsNotDistinct + `,
+			COALESCE((
+				SELECT json_agg(json_build_array(a.attname, ix.indoption[k.ord - 1]) ORDER BY k.ord)
+				FROM generate_series(1, ` + keyColumnCount + `) AS k(ord)
+				JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ix.indkey[k.ord - 1]
+			), '[]')::text,
+			COALESCE((
+				SELECT json_agg(a.attname ORDER BY k.ord)
+				FROM generate_series(` + keyColumnCount + ` + 1, ix.indnatts) AS k(ord)
+				JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ix.indkey[k.ord - 1]
+			), '[]')::text,
+			EXISTS (
+				SELECT 1
+				FROM pg_constraint con
+				WHERE con.conindid = ix.indexrelid
+				  AND con.contype = 'u'
+			)
 		FROM pg_indexes i
 		JOIN pg_class c ON c.relname = i.tablename
 		JOIN pg_index ix ON ix.indexrelid = (
@@ -271,7 +485,7 @@ func (i *Introspector) GetIndexesInSchema(ctx context.Context, db *sql.DB, schem
 			SELECT 1
 			FROM pg_constraint con
 			WHERE con.conindid = ix.indexrelid
-			  AND con.contype IN ('p', 'u')
+			  AND con.contype = 'p'
 		  )
 		ORDER BY i.indexname
 	`
@@ -287,14 +501,27 @@ func (i *Introspector) GetIndexesInSchema(ctx context.Context, db *sql.DB, schem
 	for rows.Next() {
 		var idx database.Index
 		var indexDef string
+		var tablespace sql.NullString
+		var keyColumnsJSON, includeColumnsJSON string
 
-		if err := rows.Scan(&idx.Name, &indexDef, &idx.Unique); err != nil {
+		if err := rows.Scan(&idx.Name, &indexDef, &idx.Unique, &tablespace, &idx.NullsNotDistinct, &keyColumnsJSON, &includeColumnsJSON, &idx.Constraint); err != nil {
 			return nil, err
 		}
+		if tablespace.Valid {
+			idx.Tablespace = &tablespace.String
+		}
 
-		// TODO: Parse indexDef to extract column names properly
-		// For now, just storing the index name and unique flag
-		idx.Columns = []string{} // Simplified for MVP
+		keyColumns, err := parseIndexKeyColumns(keyColumnsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse columns of index %s: %w", idx.Name, err)
+		}
+		idx.SetKeyColumns(keyColumns)
+		if err := json.Unmarshal([]byte(includeColumnsJSON), &idx.IncludeColumns); err != nil {
+			return nil, fmt.Errorf("failed to parse INCLUDE columns of index %s: %w", idx.Name, err)
+		}
+		if len(idx.IncludeColumns) == 0 {
+			idx.IncludeColumns = nil
+		}
 
 		indexes = append(indexes, idx)
 	}
@@ -302,6 +529,41 @@ func (i *Introspector) GetIndexesInSchema(ctx context.Context, db *sql.DB, schem
 	return indexes, nil
 }
 
+// pg_index.indoption bits (see INDOPTION_DESC and INDOPTION_NULLS_FIRST)
+const (
+	indOptionDesc       = 0x01
+	indOptionNullsFirst = 0x02
+)
+
+// parseIndexKeyColumns decodes the [name, indoption] pairs selected for an index
+func parseIndexKeyColumns(data string) ([]database.IndexColumn, error) {
+	var pairs [][2]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &pairs); err != nil {
+		return nil, err
+	}
+
+	columns := make([]database.IndexColumn, 0, len(pairs))
+	for _, pair := range pairs {
+		var name string
+		var option int
+		if err := json.Unmarshal(pair[0], &name); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(pair[1], &option); err != nil {
+			return nil, err
+		}
+
+		col := database.IndexColumn{Name: name, Descending: option&indOptionDesc != 0}
+		if option&indOptionNullsFirst != 0 {
+			col.NullsOrder = database.NullsFirst
+		} else {
+			col.NullsOrder = database.NullsLast
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
 // GetForeignKeys returns all foreign keys for a given PostgreSQL table in current_schema()
 func (i *Introspector) GetForeignKeys(ctx context.Context, db *sql.DB, tableName string) ([]database.ForeignKey, error) {
 	currentSchema, err := i.getCurrentSchema(ctx, db)
@@ -317,6 +579,7 @@ func (i *Introspector) GetForeignKeysInSchema(ctx context.Context, db *sql.DB, s
 		SELECT
 			tc.constraint_name,
 			kcu.column_name,
+			ccu.table_schema AS foreign_table_schema,
 			ccu.table_name AS foreign_table_name,
 			ccu.column_name AS foreign_column_name,
 			rc.update_rule,
@@ -327,7 +590,7 @@ func (i *Introspector) GetForeignKeysInSchema(ctx context.Context, db *sql.DB, s
 			AND tc.table_schema = kcu.table_schema
 		JOIN information_schema.constraint_column_usage AS ccu
 			ON ccu.constraint_name = tc.constraint_name
-			AND ccu.table_schema = tc.table_schema
+			AND ccu.constraint_schema = tc.constraint_schema
 		JOIN information_schema.referential_constraints AS rc
 			ON rc.constraint_name = tc.constraint_name
 			AND rc.constraint_schema = tc.table_schema
@@ -348,10 +611,10 @@ func (i *Introspector) GetForeignKeysInSchema(ctx context.Context, db *sql.DB, s
 	var fkNames []string
 
 	for rows.Next() {
-		var constraintName, columnName, foreignTableName, foreignColumnName string
+		var constraintName, columnName, foreignTableSchema, foreignTableName, foreignColumnName string
 		var updateRule, deleteRule string
 
-		if err := rows.Scan(&constraintName, &columnName, &foreignTableName, &foreignColumnName, &updateRule, &deleteRule); err != nil {
+		if err := rows.Scan(&constraintName, &columnName, &foreignTableSchema, &foreignTableName, &foreignColumnName, &updateRule, &deleteRule); err != nil {
 			return nil, err
 		}
 
@@ -359,7 +622,7 @@ func (i *Introspector) GetForeignKeysInSchema(ctx context.Context, db *sql.DB, s
 			fk := &database.ForeignKey{
 				Name:              constraintName,
 				Columns:           []string{},
-				ReferencedTable:   foreignTableName,
+				ReferencedTable:   database.QualifiedTableName(foreignTableSchema, foreignTableName),
 				ReferencedColumns: []string{},
 			}
 
@@ -442,6 +705,128 @@ func (i *Introspector) GetRLSEnabledInSchema(ctx context.Context, db *sql.DB, sc
 	return rlsEnabled, nil
 }
 
+// GetStorageParametersInSchema returns a table's storage parameters, with
+// those of its TOAST table prefixed with "toast.", or nil when none are set
+func (i *Introspector) GetStorageParametersInSchema(ctx context.Context, db *sql.DB, schemaName, tableName string) (map[string]string, error) {
+	query := `
+		SELECT option, false
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL unnest(c.reloptions) AS option
+		WHERE c.relname = $1
+		  AND n.nspname = $2
+		  AND c.relkind = 'r'
+		UNION ALL
+		SELECT option, true
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_class t ON t.oid = c.reltoastrelid
+		CROSS JOIN LATERAL unnest(t.reloptions) AS option
+		WHERE c.relname = $1
+		  AND n.nspname = $2
+		  AND c.relkind = 'r'
+	`
+
+	rows, err := db.QueryContext(ctx, query, tableName, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var params map[string]string
+	for rows.Next() {
+		var option string
+		var toast bool
+		if err := rows.Scan(&option, &toast); err != nil {
+			return nil, err
+		}
+		name, value, _ := strings.Cut(option, "=")
+		if toast {
+			name = "toast." + name
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[name] = value
+	}
+	return params, rows.Err()
+}
+
+// GetOwnershipInSchema returns a table's owner and its explicit privileges as
+// aclitem strings, or nil privileges when the table has the default ones
+func (i *Introspector) GetOwnershipInSchema(ctx context.Context, db *sql.DB, schemaName, tableName string) (string, []string, error) {
+	query := `
+		SELECT pg_catalog.pg_get_userbyid(c.relowner), acl.item::text
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN LATERAL unnest(c.relacl) WITH ORDINALITY AS acl(item, position) ON true
+		WHERE c.relname = $1
+		  AND n.nspname = $2
+		  AND c.relkind = 'r'
+		ORDER BY acl.position
+	`
+
+	rows, err := db.QueryContext(ctx, query, tableName, schemaName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var owner string
+	var acl []string
+	for rows.Next() {
+		var item sql.NullString
+		if err := rows.Scan(&owner, &item); err != nil {
+			return "", nil, err
+		}
+		if item.Valid {
+			acl = append(acl, item.String)
+		}
+	}
+	return owner, acl, rows.Err()
+}
+
+// GetReplicaIdentityInSchema returns a table's REPLICA IDENTITY setting, or nil for DEFAULT
+func (i *Introspector) GetReplicaIdentityInSchema(ctx context.Context, db *sql.DB, schemaName, tableName string) (*string, error) {
+	query := `
+		SELECT
+			c.relreplident::text,
+			(
+				SELECT ic.relname
+				FROM pg_catalog.pg_index ix
+				JOIN pg_catalog.pg_class ic ON ic.oid = ix.indexrelid
+				WHERE ix.indrelid = c.oid AND ix.indisreplident
+			)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1
+		  AND n.nspname = $2
+		  AND c.relkind = 'r'
+	`
+
+	var relReplIdent string
+	var indexName sql.NullString
+	if err := db.QueryRowContext(ctx, query, tableName, schemaName).Scan(&relReplIdent, &indexName); err != nil {
+		return nil, err
+	}
+
+	var identity string
+	switch relReplIdent {
+	case "f":
+		identity = database.ReplicaIdentityFull
+	case "n":
+		identity = database.ReplicaIdentityNothing
+	case "i":
+		if !indexName.Valid {
+			return nil, fmt.Errorf("replica identity index not found")
+		}
+		identity = database.ReplicaIdentityUsingIndex(indexName.String)
+	default:
+		return nil, nil
+	}
+	return &identity, nil
+}
+
 // GetPolicies returns all RLS policies for a table in current_schema()
 func (i *Introspector) GetPolicies(ctx context.Context, db *sql.DB, tableName string) ([]database.Policy, error) {
 	currentSchema, err := i.getCurrentSchema(ctx, db)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"os"
+	"slices"
+	"strconv"
 	"testing"
 
 	_ "github.com/lib/pq"
@@ -90,6 +92,41 @@ func TestIntrospector_GetTables(t *testing.T) {
 	}
 }
 
+func TestIntrospector_Tablespaces(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS test_introspect_tablespace (id integer PRIMARY KEY)`)
+	if err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_introspect_tablespace") }()
+
+	var expected string
+	if err := db.QueryRowContext(ctx, `
+		SELECT t.spcname FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace
+		WHERE d.datname = current_database()
+	`).Scan(&expected); err != nil {
+		t.Fatalf("Failed to query default tablespace: %v", err)
+	}
+
+	schema, err := introspector.IntrospectSchema(ctx, db)
+	if err != nil {
+		t.Fatalf("IntrospectSchema failed: %v", err)
+	}
+	if schema.DefaultTablespace != expected {
+		t.Errorf("Expected default tablespace %q, got %q", expected, schema.DefaultTablespace)
+	}
+	for _, table := range schema.Tables {
+		if table.Name == "test_introspect_tablespace" && table.Tablespace != nil {
+			t.Errorf("Expected a table in the default tablespace to have none, got %q", *table.Tablespace)
+		}
+	}
+}
+
 func TestIntrospector_GetColumns(t *testing.T) {
 	db := getTestDB(t)
 	defer func() { _ = db.Close() }()
@@ -171,6 +208,229 @@ func TestIntrospector_GetColumns(t *testing.T) {
 	}
 }
 
+func TestIntrospector_GetColumnsTemporalPrecision(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS test_introspect_temporal (
+			plain timestamp,
+			ts3 timestamp(3),
+			tstz3 timestamptz(3),
+			t2 time(2),
+			ttz2 timetz(2),
+			iv interval,
+			iv3 interval(3),
+			ivds interval day to second(3),
+			ivym interval year to month
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_introspect_temporal") }()
+
+	columns, err := introspector.GetColumns(ctx, db, "test_introspect_temporal")
+	if err != nil {
+		t.Fatalf("GetColumns failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"plain": "timestamp without time zone",
+		"ts3":   "timestamp(3) without time zone",
+		"tstz3": "timestamp(3) with time zone",
+		"t2":    "time(2) without time zone",
+		"ttz2":  "time(2) with time zone",
+		"iv":    "interval",
+		"iv3":   "interval(3)",
+		"ivds":  "interval day to second(3)",
+		"ivym":  "interval year to month",
+	}
+	for name, want := range expected {
+		col := findColumn(columns, name)
+		if col == nil {
+			t.Fatalf("Expected to find %q column", name)
+		}
+		if col.Type != want {
+			t.Errorf("column %s: expected type %q, got %q", name, want, col.Type)
+		}
+	}
+}
+
+func TestIntrospector_GetColumnsNonStandardTypes(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS test_introspect_types (
+			doc tsvector,
+			query tsquery,
+			span int4range,
+			spans int4multirange,
+			location point,
+			bounds box,
+			area circle,
+			addr inet,
+			net cidr,
+			mac macaddr,
+			mac8 macaddr8,
+			tags tsvector[],
+			points point[]
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_introspect_types") }()
+
+	columns, err := introspector.GetColumns(ctx, db, "test_introspect_types")
+	if err != nil {
+		t.Fatalf("GetColumns failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"doc":      "tsvector",
+		"query":    "tsquery",
+		"span":     "int4range",
+		"spans":    "int4multirange",
+		"location": "point",
+		"bounds":   "box",
+		"area":     "circle",
+		"addr":     "inet",
+		"net":      "cidr",
+		"mac":      "macaddr",
+		"mac8":     "macaddr8",
+		"tags":     "tsvector[]",
+		"points":   "point[]",
+	}
+	for name, want := range expected {
+		col := findColumn(columns, name)
+		if col == nil {
+			t.Fatalf("Expected to find %q column", name)
+		}
+		if col.Type != want {
+			t.Errorf("column %s: expected type %q, got %q", name, want, col.Type)
+		}
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	formatted := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	tests := []struct {
+		dataType  string
+		formatted sql.NullString
+		want      string
+	}{
+		{"integer", formatted("integer"), "integer"},
+		{"tsvector", formatted("tsvector"), "tsvector"},
+		{"character varying", formatted("character varying(255)"), "character varying"},
+		{"timestamp with time zone", formatted("timestamp(3) with time zone"), "timestamp(3) with time zone"},
+		{"ARRAY", formatted("point[]"), "point[]"},
+		{"ARRAY", formatted("pg_catalog.int4range[]"), "int4range[]"},
+		{"USER-DEFINED", formatted("citext"), "citext"},
+		{"USER-DEFINED", formatted("extensions.citext"), "extensions.citext"},
+		{"USER-DEFINED", sql.NullString{}, "USER-DEFINED"},
+	}
+	for _, tt := range tests {
+		if got := columnType(tt.dataType, tt.formatted); got != tt.want {
+			t.Errorf("columnType(%q, %q) = %q, want %q", tt.dataType, tt.formatted.String, got, tt.want)
+		}
+	}
+}
+
+func TestIntrospector_GetColumnsStatisticsTarget(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS test_introspect_statistics (
+			id bigint,
+			properties jsonb,
+			kind text
+		);
+		ALTER TABLE test_introspect_statistics ALTER COLUMN properties SET STATISTICS 1000;
+		ALTER TABLE test_introspect_statistics ALTER COLUMN kind SET STATISTICS 0;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_introspect_statistics") }()
+
+	columns, err := introspector.GetColumns(ctx, db, "test_introspect_statistics")
+	if err != nil {
+		t.Fatalf("GetColumns failed: %v", err)
+	}
+
+	// The default target is not recorded; 0 disables statistics and is
+	expected := map[string]string{"id": "default", "properties": "1000", "kind": "0"}
+	for name, want := range expected {
+		col := findColumn(columns, name)
+		if col == nil {
+			t.Fatalf("Expected to find %q column", name)
+		}
+		got := "default"
+		if col.StatisticsTarget != nil {
+			got = strconv.Itoa(*col.StatisticsTarget)
+		}
+		if got != want {
+			t.Errorf("column %s: expected statistics target %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestIntrospector_GetOwnership(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS test_introspect_ownership (id bigint);
+		CREATE TABLE IF NOT EXISTS test_introspect_default_acl (id bigint);
+		GRANT SELECT ON test_introspect_ownership TO PUBLIC;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test tables: %v", err)
+	}
+	defer func() {
+		_, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_introspect_ownership, test_introspect_default_acl")
+	}()
+
+	var currentUser, currentSchema string
+	if err := db.QueryRowContext(ctx, "SELECT current_user, current_schema()").Scan(&currentUser, &currentSchema); err != nil {
+		t.Fatalf("Failed to query current user: %v", err)
+	}
+
+	owner, acl, err := introspector.GetOwnershipInSchema(ctx, db, currentSchema, "test_introspect_ownership")
+	if err != nil {
+		t.Fatalf("GetOwnershipInSchema failed: %v", err)
+	}
+	if owner != currentUser {
+		t.Errorf("expected owner %q, got %q", currentUser, owner)
+	}
+	if !slices.Contains(acl, "=r/"+currentUser) {
+		t.Errorf("expected the PUBLIC SELECT grant in %v", acl)
+	}
+
+	owner, acl, err = introspector.GetOwnershipInSchema(ctx, db, currentSchema, "test_introspect_default_acl")
+	if err != nil {
+		t.Fatalf("GetOwnershipInSchema failed: %v", err)
+	}
+	if owner != currentUser || acl != nil {
+		t.Errorf("expected owner %q with default privileges, got %q %v", currentUser, owner, acl)
+	}
+}
+
 func TestIntrospector_GetIndexes(t *testing.T) {
 	db := getTestDB(t)
 	defer func() { _ = db.Close() }()
@@ -196,6 +456,10 @@ func TestIntrospector_GetIndexes(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create index: %v", err)
 	}
+	_, err = db.ExecContext(ctx, "ALTER TABLE test_introspect_indexes ADD CONSTRAINT test_introspect_indexes_username_key UNIQUE (username)")
+	if err != nil {
+		t.Fatalf("Failed to add unique constraint: %v", err)
+	}
 
 	// Get indexes
 	indexes, err := introspector.GetIndexes(ctx, db, "test_introspect_indexes")
@@ -211,6 +475,15 @@ func TestIntrospector_GetIndexes(t *testing.T) {
 			if !idx.Unique {
 				t.Error("Expected test_idx_email to be unique")
 			}
+			if idx.Constraint {
+				t.Error("Expected test_idx_email not to back a constraint")
+			}
+		}
+		if idx.Name == "test_introspect_indexes_pkey" {
+			t.Error("Expected the primary key index to be left out")
+		}
+		if idx.Name == "test_introspect_indexes_username_key" && !idx.Constraint {
+			t.Error("Expected test_introspect_indexes_username_key to back a constraint")
 		}
 	}
 
@@ -219,6 +492,83 @@ func TestIntrospector_GetIndexes(t *testing.T) {
 	}
 }
 
+func TestIntrospector_GetIndexUsage(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS test_index_usage (
+			id integer PRIMARY KEY,
+			account_id integer,
+			created_at timestamp
+		);
+		CREATE INDEX IF NOT EXISTS test_index_usage_account ON test_index_usage (account_id, created_at);
+		CREATE INDEX IF NOT EXISTS test_index_usage_recent ON test_index_usage (created_at) WHERE created_at > '2020-01-01'
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_index_usage") }()
+
+	usage, err := introspector.GetIndexUsage(ctx, db, nil)
+	if err != nil {
+		t.Fatalf("GetIndexUsage failed: %v", err)
+	}
+
+	found := map[string]IndexUsage{}
+	for _, u := range usage {
+		if u.Table == "test_index_usage" {
+			found[u.Name] = u
+		}
+	}
+	if pkey := found["test_index_usage_pkey"]; !pkey.Constraint || !pkey.Unique {
+		t.Errorf("Expected the primary key index to back a constraint, got %+v", pkey)
+	}
+	if account := found["test_index_usage_account"]; account.Constraint || !slices.Equal(account.Columns, []string{"account_id", "created_at"}) {
+		t.Errorf("Expected the account index on account_id, created_at, got %+v", account)
+	}
+	if recent := found["test_index_usage_recent"]; !recent.Partial {
+		t.Errorf("Expected the recent index to be partial, got %+v", recent)
+	}
+}
+
+func TestIntrospector_GetTableStats(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS test_table_stats (id integer PRIMARY KEY, note text);
+		INSERT INTO test_table_stats SELECT g, 'note' FROM generate_series(1, 100) AS g ON CONFLICT DO NOTHING;
+		ANALYZE test_table_stats
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_table_stats") }()
+
+	stats, err := introspector.GetTableStats(ctx, db, nil)
+	if err != nil {
+		t.Fatalf("GetTableStats failed: %v", err)
+	}
+
+	s, ok := stats["test_table_stats"]
+	if !ok {
+		t.Fatalf("Expected stats for test_table_stats, got %+v", stats)
+	}
+	if s.ApproximateRows == nil || *s.ApproximateRows != 100 {
+		t.Errorf("Expected about 100 rows after ANALYZE, got %v", s.ApproximateRows)
+	}
+	if s.IndexBytes <= 0 || s.TotalBytes <= s.IndexBytes {
+		t.Errorf("Expected the total size to include the primary key index, got %+v", s)
+	}
+}
+
 func TestIntrospector_GetForeignKeys(t *testing.T) {
 	db := getTestDB(t)
 	defer func() { _ = db.Close() }()
@@ -373,3 +723,31 @@ func findColumn(columns []database.Column, name string) *database.Column {
 	}
 	return nil
 }
+
+func TestParseIndexKeyColumns(t *testing.T) {
+	// indoption: 0 = ASC, 3 = DESC (NULLS FIRST), 1 = DESC NULLS LAST, 2 = ASC NULLS FIRST
+	cols, err := parseIndexKeyColumns(`[["a", 0], ["b", 3], ["c", 1], ["d", 2]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse key columns: %v", err)
+	}
+
+	idx := database.Index{}
+	idx.SetKeyColumns(cols)
+	var got []string
+	for _, col := range idx.KeyColumns() {
+		got = append(got, col.SQL())
+	}
+	want := []string{"a", "b DESC", "c DESC NULLS LAST", "d NULLS FIRST"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("column %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+
+	if cols, err := parseIndexKeyColumns(`[]`); err != nil || len(cols) != 0 {
+		t.Errorf("Expected no columns for an expression-only index, got %v (err %v)", cols, err)
+	}
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// bareIdentifierPattern matches identifiers PostgreSQL reads back unchanged
+// without quotes. Unquoted identifiers are folded to lower case, so any upper
+// case letter needs quoting.
+var bareIdentifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// reservedKeywords are the PostgreSQL keywords that can't be used as table or
+// column names without quoting (reserved, and reserved that can be a function
+// or type name)
+var reservedKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "authorization": true,
+	"binary": true, "both": true, "case": true, "cast": true, "check": true,
+	"collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true,
+	"current_date": true, "current_role": true, "current_schema": true,
+	"current_time": true, "current_timestamp": true, "current_user": true,
+	"default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "freeze": true, "from": true, "full": true,
+	"grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "left": true,
+	"like": true, "limit": true, "localtime": true, "localtimestamp": true,
+	"natural": true, "not": true, "notnull": true, "null": true, "offset": true,
+	"on": true, "only": true, "or": true, "order": true, "outer": true,
+	"overlaps": true, "placing": true, "primary": true, "references": true,
+	"returning": true, "right": true, "select": true, "session_user": true,
+	"similar": true, "some": true, "symmetric": true, "system_user": true,
+	"table": true, "tablesample": true, "then": true, "to": true, "trailing": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true,
+	"variadic": true, "verbose": true, "when": true, "where": true, "window": true,
+	"with": true,
+}
+
+// QuoteIdentifier returns name as it must be written in PostgreSQL SQL.
+// Names that read back unchanged without quotes are left bare, so generated
+// SQL stays readable; others, such as mixed-case names or reserved words, are
+// double-quoted.
+func (g *Generator) QuoteIdentifier(name string) string {
+	if bareIdentifierPattern.MatchString(name) && !reservedKeywords[name] {
+		return name
+	}
+	return quoteIdentifier(name)
+}
+
+// quoteTableName quotes each part of a table or index name returned by
+// database.QualifiedTableName or database.QualifiedIndexName
+func (g *Generator) quoteTableName(name string) string {
+	return database.QuoteQualifiedName(name, g.QuoteIdentifier)
+}
+
+// quoteIdentifiers quotes names and joins them into a column list
+func (g *Generator) quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = g.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// sqliteOnlyTypes maps type names PostgreSQL doesn't have, as written in
+// SQLite schemas, to the PostgreSQL type storing the same values
+var sqliteOnlyTypes = map[string]string{
+	"blob":     "bytea",
+	"datetime": "timestamp",
+	"double":   "double precision",
+	"tinyint":  "smallint",
+}
+
+// RenderType returns the type of col as PostgreSQL spells it. Types written
+// for SQLite that PostgreSQL doesn't have are translated; anything else is
+// used as written.
+func (g *Generator) RenderType(col database.Column) string {
+	if mapped, ok := sqliteOnlyTypes[strings.ToLower(strings.TrimSpace(col.Type))]; ok {
+		return mapped
+	}
+	return col.Type
+}
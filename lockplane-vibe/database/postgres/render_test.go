@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/drivertest"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+func TestDriverConformance(t *testing.T) {
+	drivertest.Run(t, NewDriver(), func(sql string) error {
+		_, err := pg_query.Parse(sql)
+		return err
+	})
+}
+
+func TestGenerator_QuoteIdentifier(t *testing.T) {
+	gen := NewGenerator()
+	tests := map[string]string{
+		"users":     "users",
+		"user":      `"user"`,
+		"UserID":    `"UserID"`,
+		`say "hi"`:  `"say ""hi"""`,
+		"name$1":    "name$1",
+		"public.id": `"public.id"`,
+	}
+	for name, want := range tests {
+		if got := gen.QuoteIdentifier(name); got != want {
+			t.Errorf("QuoteIdentifier(%q) = %s, want %s", name, got, want)
+		}
+	}
+
+	if got := gen.quoteTableName("Billing.order"); got != `"Billing"."order"` {
+		t.Errorf("quoteTableName = %s", got)
+	}
+}
+
+func TestGenerator_RenderType(t *testing.T) {
+	gen := NewGenerator()
+	tests := map[string]string{
+		"DATETIME":                 "timestamp",
+		"blob":                     "bytea",
+		"uuid":                     "uuid",
+		"timestamp with time zone": "timestamp with time zone",
+		"varchar(255)":             "varchar(255)",
+	}
+	for columnType, want := range tests {
+		if got := gen.RenderType(database.Column{Type: columnType}); got != want {
+			t.Errorf("RenderType(%q) = %q, want %q", columnType, got, want)
+		}
+	}
+}
+
+func TestGenerator_QuotesReservedNames(t *testing.T) {
+	gen := NewGenerator()
+	sql, desc := gen.CreateTable(database.Table{
+		Name:    "order",
+		Schema:  "billing",
+		Columns: []database.Column{{Name: "user", Type: "DATETIME"}},
+	})
+	if want := "CREATE TABLE billing.\"order\" (\n  \"user\" timestamp NOT NULL\n)"; sql != want {
+		t.Errorf("CreateTable SQL = %q, want %q", sql, want)
+	}
+	if desc != "Create table billing.order" {
+		t.Errorf("description should keep the bare name, got %q", desc)
+	}
+
+	steps := gen.ModifyColumn("order", database.ColumnDiff{
+		ColumnName: "user",
+		Old:        database.Column{Name: "user", Type: "text"},
+		New:        database.Column{Name: "user", Type: "blob"},
+		Changes:    []string{"type"},
+	})
+	if want := `ALTER TABLE "order" ALTER COLUMN "user" TYPE bytea`; len(steps) != 1 || steps[0].SQL[0] != want {
+		t.Errorf("ModifyColumn = %+v, want %s", steps, want)
+	}
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// GetSequencesInSchema returns the sequences of a schema with their options,
+// owning column and last value. Sequences of identity columns belong to
+// their column and are left out. pg_sequences needs PostgreSQL 10 or later.
+func (i *Introspector) GetSequencesInSchema(ctx context.Context, db *sql.DB, schemaName string) ([]database.Sequence, error) {
+	// last_value is NULL for a sequence that was never used, or when the
+	// current user lacks privileges on it
+	query := `
+		SELECT
+			s.sequencename,
+			format_type(s.data_type, NULL),
+			s.start_value,
+			s.min_value,
+			s.max_value,
+			s.increment_by,
+			s.cycle,
+			s.cache_size,
+			s.last_value,
+			tn.nspname,
+			t.relname,
+			a.attname
+		FROM pg_sequences s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = s.sequencename
+		LEFT JOIN pg_depend d ON d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.refclassid = 'pg_class'::regclass
+			AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_class t ON t.oid = d.refobjid
+		LEFT JOIN pg_namespace tn ON tn.oid = t.relnamespace
+		LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE s.schemaname = $1
+		  AND (d.deptype IS NULL OR d.deptype <> 'i')
+		ORDER BY s.sequencename
+	`
+
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sequences []database.Sequence
+	for rows.Next() {
+		seq := database.Sequence{Schema: schemaName}
+		var start, minValue, maxValue, increment, cache int64
+		var lastValue sql.NullInt64
+		var ownerSchema, ownerTable, ownerColumn sql.NullString
+		if err := rows.Scan(&seq.Name, &seq.DataType, &start, &minValue, &maxValue, &increment, &seq.Cycle, &cache,
+			&lastValue, &ownerSchema, &ownerTable, &ownerColumn); err != nil {
+			return nil, err
+		}
+		seq.Start, seq.MinValue, seq.MaxValue, seq.Increment, seq.Cache = &start, &minValue, &maxValue, &increment, &cache
+		if lastValue.Valid {
+			seq.LastValue = &lastValue.Int64
+		}
+		if ownerTable.Valid && ownerColumn.Valid {
+			seq.OwnedBy = database.QualifiedTableName(ownerSchema.String, ownerTable.String) + "." + ownerColumn.String
+		}
+		sequences = append(sequences, seq)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sequences: %w", err)
+	}
+	return sequences, nil
+}
+
+// RefreshSequenceLastValues re-reads the last value of sequences. Unlike the
+// rest of a sequence, it changes without DDL, so a cached schema can't hold it.
+func (i *Introspector) RefreshSequenceLastValues(ctx context.Context, db *sql.DB, sequences []database.Sequence) error {
+	rows, err := db.QueryContext(ctx, `SELECT schemaname, sequencename, last_value FROM pg_sequences`)
+	if err != nil {
+		return fmt.Errorf("failed to read sequence last values: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	lastValues := make(map[[2]string]*int64)
+	for rows.Next() {
+		var schemaName, name string
+		var lastValue sql.NullInt64
+		if err := rows.Scan(&schemaName, &name, &lastValue); err != nil {
+			return fmt.Errorf("failed to read sequence last values: %w", err)
+		}
+		if lastValue.Valid {
+			lastValues[[2]string{schemaName, name}] = &lastValue.Int64
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read sequence last values: %w", err)
+	}
+	for idx := range sequences {
+		sequences[idx].LastValue = lastValues[[2]string{sequences[idx].Schema, sequences[idx].Name}]
+	}
+	return nil
+}
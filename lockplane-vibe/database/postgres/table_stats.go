@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// GetTableStats returns the approximate size of the tables in schemas (the
+// current schema when schemas is empty), keyed by their qualified name
+func (i *Introspector) GetTableStats(ctx context.Context, db *sql.DB, schemas []string) (map[string]database.TableStats, error) {
+	if len(schemas) == 0 {
+		currentSchema, err := i.getCurrentSchema(ctx, db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current schema: %w", err)
+		}
+		schemas = []string{currentSchema}
+	}
+
+	// reltuples is -1 for tables never vacuumed or analyzed on PostgreSQL 14+,
+	// and 0 on earlier versions, where an empty table looks the same
+	query := `
+		SELECT
+			n.nspname,
+			c.relname,
+			c.reltuples::bigint,
+			pg_total_relation_size(c.oid),
+			pg_indexes_size(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		  AND c.relkind IN ('r', 'p')
+	`
+
+	stats := map[string]database.TableStats{}
+	for _, schemaName := range schemas {
+		if err := queryTableStats(ctx, db, query, schemaName, stats); err != nil {
+			return nil, fmt.Errorf("failed to query table statistics in schema %s: %w", schemaName, err)
+		}
+	}
+	return stats, nil
+}
+
+func queryTableStats(ctx context.Context, db *sql.DB, query, schemaName string, stats map[string]database.TableStats) error {
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var schema, table string
+		var rowCount int64
+		var s database.TableStats
+		if err := rows.Scan(&schema, &table, &rowCount, &s.TotalBytes, &s.IndexBytes); err != nil {
+			return err
+		}
+		if rowCount >= 0 {
+			s.ApproximateRows = &rowCount
+		}
+		stats[database.QualifiedTableName(schema, table)] = s
+	}
+	return rows.Err()
+}
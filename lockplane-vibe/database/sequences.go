@@ -0,0 +1,33 @@
+package database
+
+import "strings"
+
+// QualifiedName returns the sequence's name qualified with its schema, as
+// QualifiedTableName does for tables
+func (s Sequence) QualifiedName() string {
+	return QualifiedTableName(s.Schema, s.Name)
+}
+
+// SplitSequenceOwner splits a Sequence.OwnedBy value into the qualified
+// table name and the column name. ok is false when ownedBy names no column.
+func SplitSequenceOwner(ownedBy string) (tableName, columnName string, ok bool) {
+	i := strings.LastIndex(ownedBy, ".")
+	if i <= 0 || i == len(ownedBy)-1 {
+		return "", "", false
+	}
+	return ownedBy[:i], ownedBy[i+1:], true
+}
+
+// FindSequence returns the sequence of schema with the qualified name name,
+// or nil
+func FindSequence(schema *Schema, name string) *Sequence {
+	if schema == nil {
+		return nil
+	}
+	for i := range schema.Sequences {
+		if schema.Sequences[i].QualifiedName() == name {
+			return &schema.Sequences[i]
+		}
+	}
+	return nil
+}
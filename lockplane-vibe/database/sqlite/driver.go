@@ -68,6 +68,10 @@ func (d *Driver) IntrospectSchemas(ctx context.Context, db *sql.DB, schemas []st
 	return d.Introspector.IntrospectSchema(ctx, db)
 }
 
+func (d *Driver) IntrospectTables(ctx context.Context, db *sql.DB, schemas []string, patterns []string) (*database.Schema, error) {
+	return d.Introspector.IntrospectTables(ctx, db, patterns)
+}
+
 func (d *Driver) GetTables(ctx context.Context, db *sql.DB) ([]string, error) {
 	return d.Introspector.GetTables(ctx, db)
 }
@@ -124,6 +128,14 @@ func (d *Driver) FormatColumnDefinition(col database.Column) string {
 	return d.Generator.FormatColumnDefinition(col)
 }
 
+func (d *Driver) QuoteIdentifier(name string) string {
+	return d.Generator.QuoteIdentifier(name)
+}
+
+func (d *Driver) RenderType(col database.Column) string {
+	return d.Generator.RenderType(col)
+}
+
 func (d *Driver) ParameterPlaceholder(position int) string {
 	return d.Generator.ParameterPlaceholder(position)
 }
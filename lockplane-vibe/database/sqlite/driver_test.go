@@ -46,6 +46,8 @@ func TestDriver_SupportsFeature(t *testing.T) {
 		{"ALTER_ADD_FOREIGN_KEY", false}, // Foreign keys must be defined at table creation
 		{"FOREIGN_KEYS", true},           // Supports foreign keys at table creation
 		{"DROP_COLUMN", true},            // SQLite 3.35.0+
+		{"TABLESPACE", false},            // No tablespaces
+		{"REPLICA_IDENTITY", false},      // No logical replication
 		{"UNSUPPORTED_FEATURE", false},
 		{"", false},
 	}
@@ -19,7 +19,7 @@ func NewGenerator() *Generator {
 func (g *Generator) CreateTable(table database.Table) (string, string) {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", table.Name))
+	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", g.QuoteIdentifier(table.Name)))
 
 	// Add columns
 	for i, col := range table.Columns {
@@ -50,7 +50,7 @@ func (g *Generator) CreateTable(table database.Table) (string, string) {
 // DropTable generates SQLite SQL to drop a table
 func (g *Generator) DropTable(table database.Table) (string, string) {
 	// SQLite doesn't support CASCADE, but will fail if there are dependencies
-	sql := fmt.Sprintf("DROP TABLE %s", table.Name)
+	sql := fmt.Sprintf("DROP TABLE %s", g.QuoteIdentifier(table.Name))
 	description := fmt.Sprintf("Drop table %s", table.Name)
 	return sql, description
 }
@@ -58,7 +58,7 @@ func (g *Generator) DropTable(table database.Table) (string, string) {
 // AddColumn generates SQLite SQL to add a column
 func (g *Generator) AddColumn(tableName string, col database.Column) (string, string) {
 	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s",
-		tableName,
+		g.QuoteIdentifier(tableName),
 		g.FormatColumnDefinition(col))
 	description := fmt.Sprintf("Add column %s to table %s", col.Name, tableName)
 	return sql, description
@@ -67,7 +67,7 @@ func (g *Generator) AddColumn(tableName string, col database.Column) (string, st
 // DropColumn generates SQLite SQL to drop a column
 func (g *Generator) DropColumn(tableName string, col database.Column) (string, string) {
 	// SQLite 3.35.0+ supports DROP COLUMN, but we'll use it directly
-	sql := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, col.Name)
+	sql := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", g.QuoteIdentifier(tableName), g.QuoteIdentifier(col.Name))
 	description := fmt.Sprintf("Drop column %s from table %s", col.Name, tableName)
 	return sql, description
 }
@@ -105,11 +105,20 @@ func (g *Generator) AddIndex(tableName string, idx database.Index) (string, stri
 		uniqueStr = "UNIQUE "
 	}
 
-	// Format column list
-	columns := strings.Join(idx.Columns, ", ")
+	// Format column list. SQLite indexes accept ASC/DESC but no NULLS
+	// FIRST/LAST clause (NULLs always sort first in ascending order). There
+	// are no INCLUDE columns either; validation rejects indexes that have them.
+	columns := make([]string, 0, len(idx.Columns))
+	for _, col := range idx.KeyColumns() {
+		if col.Descending {
+			columns = append(columns, g.QuoteIdentifier(col.Name)+" DESC")
+		} else {
+			columns = append(columns, g.QuoteIdentifier(col.Name))
+		}
+	}
 
 	sql := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)",
-		uniqueStr, idx.Name, tableName, columns)
+		uniqueStr, g.QuoteIdentifier(idx.Name), g.QuoteIdentifier(tableName), strings.Join(columns, ", "))
 
 	description := fmt.Sprintf("Create index %s on table %s", idx.Name, tableName)
 	return sql, description
@@ -117,7 +126,7 @@ func (g *Generator) AddIndex(tableName string, idx database.Index) (string, stri
 
 // DropIndex generates SQLite SQL to drop an index
 func (g *Generator) DropIndex(tableName string, idx database.Index) (string, string) {
-	sql := fmt.Sprintf("DROP INDEX %s", idx.Name)
+	sql := fmt.Sprintf("DROP INDEX %s", g.QuoteIdentifier(idx.Name))
 	description := fmt.Sprintf("Drop index %s from table %s", idx.Name, tableName)
 	return sql, description
 }
@@ -150,7 +159,7 @@ func (g *Generator) FormatColumnDefinition(col database.Column) string {
 	var sb strings.Builder
 
 	// Column name and type
-	sb.WriteString(fmt.Sprintf("%s %s", col.Name, col.Type))
+	sb.WriteString(fmt.Sprintf("%s %s", g.QuoteIdentifier(col.Name), g.RenderType(col)))
 
 	// Primary key (must come before NOT NULL in SQLite)
 	if col.IsPrimaryKey {
@@ -176,10 +185,10 @@ func (g *Generator) FormatForeignKeyConstraint(fk database.ForeignKey) string {
 
 	// CONSTRAINT name FOREIGN KEY (columns) REFERENCES table (columns)
 	sb.WriteString(fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
-		fk.Name,
-		strings.Join(fk.Columns, ", "),
-		fk.ReferencedTable,
-		strings.Join(fk.ReferencedColumns, ", ")))
+		g.QuoteIdentifier(fk.Name),
+		g.quoteIdentifiers(fk.Columns),
+		g.QuoteIdentifier(fk.ReferencedTable),
+		g.quoteIdentifiers(fk.ReferencedColumns)))
 
 	// Add ON DELETE and ON UPDATE actions if specified
 	if fk.OnDelete != nil {
@@ -202,24 +211,10 @@ func (g *Generator) RecreateTableWithForeignKey(table database.Table, fk databas
 	newTable.Name = tmpTableName
 	newTable.ForeignKeys = append(newTable.ForeignKeys, fk)
 
-	createSQL, _ := g.CreateTable(newTable)
-
-	// Build column list for data copy
-	columnNames := make([]string, len(table.Columns))
-	for i, col := range table.Columns {
-		columnNames[i] = col.Name
-	}
-	columnsStr := strings.Join(columnNames, ", ")
-
 	// Return single step with all SQL statements
 	return database.PlanStep{
 		Description: fmt.Sprintf("Add foreign key %s to table %s", fk.Name, table.Name),
-		SQL: []string{
-			createSQL,
-			fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmpTableName, columnsStr, columnsStr, table.Name),
-			fmt.Sprintf("DROP TABLE %s", table.Name),
-			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmpTableName, table.Name),
-		},
+		SQL:         g.recreateTable(table.Name, newTable),
 	}
 }
 
@@ -238,25 +233,57 @@ func (g *Generator) RecreateTableWithoutForeignKey(table database.Table, fkName
 	}
 	newTable.ForeignKeys = newForeignKeys
 
-	createSQL, _ := g.CreateTable(newTable)
+	// Return single step with all SQL statements
+	return database.PlanStep{
+		Description: fmt.Sprintf("Drop foreign key %s from table %s", fkName, table.Name),
+		SQL:         g.recreateTable(table.Name, newTable),
+	}
+}
 
-	// Build column list for data copy
-	columnNames := make([]string, len(table.Columns))
-	for i, col := range table.Columns {
-		columnNames[i] = col.Name
+// ReorderColumns generates a single atomic step that recreates a table with its
+// columns in the order of table.Columns. SQLite drops a table's indexes with
+// the table, so they are created again afterwards.
+func (g *Generator) ReorderColumns(table database.Table) database.PlanStep {
+	newTable := table
+	newTable.Name = fmt.Sprintf("%s_new", table.Name)
+
+	sql := g.recreateTable(table.Name, newTable)
+	for _, idx := range table.Indexes {
+		indexSQL, _ := g.AddIndex(table.Name, idx)
+		sql = append(sql, indexSQL)
 	}
-	columnsStr := strings.Join(columnNames, ", ")
 
-	// Return single step with all SQL statements
 	return database.PlanStep{
-		Description: fmt.Sprintf("Drop foreign key %s from table %s", fkName, table.Name),
-		SQL: []string{
-			createSQL,
-			fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmpTableName, columnsStr, columnsStr, table.Name),
-			fmt.Sprintf("DROP TABLE %s", table.Name),
-			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmpTableName, table.Name),
-		},
+		Description: fmt.Sprintf("Reorder columns of table %s (%s)", table.Name, strings.Join(columnNames(table.Columns), ", ")),
+		SQL:         sql,
+	}
+}
+
+// recreateTable returns the statements that replace tableName with newTable,
+// copying rows by column name. newTable.Name is the temporary table name.
+func (g *Generator) recreateTable(tableName string, newTable database.Table) []string {
+	createSQL, _ := g.CreateTable(newTable)
+	columns := make([]string, len(newTable.Columns))
+	for i, col := range newTable.Columns {
+		columns[i] = g.QuoteIdentifier(col.Name)
+	}
+	columnsStr := strings.Join(columns, ", ")
+	newName, oldName := g.QuoteIdentifier(newTable.Name), g.QuoteIdentifier(tableName)
+
+	return []string{
+		createSQL,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", newName, columnsStr, columnsStr, oldName),
+		fmt.Sprintf("DROP TABLE %s", oldName),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", newName, oldName),
+	}
+}
+
+func columnNames(columns []database.Column) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
 	}
+	return names
 }
 
 // ParameterPlaceholder returns the SQLite parameter placeholder (?)
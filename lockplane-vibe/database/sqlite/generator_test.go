@@ -176,6 +176,25 @@ func TestGenerator_AddIndex_MultiColumn(t *testing.T) {
 	}
 }
 
+func TestGenerator_AddIndex_Descending(t *testing.T) {
+	gen := NewGenerator()
+
+	idx := database.Index{
+		Name:    "idx_events_created_at",
+		Columns: []string{"created_at"},
+		Ordering: []database.IndexColumn{
+			{Name: "created_at", Descending: true, NullsOrder: database.NullsLast},
+		},
+	}
+
+	sql, _ := gen.AddIndex("events", idx)
+
+	// SQLite has no NULLS FIRST/LAST in CREATE INDEX
+	if sql != "CREATE INDEX idx_events_created_at ON events (created_at DESC)" {
+		t.Errorf("Expected descending index without NULLS clause, got: %s", sql)
+	}
+}
+
 func TestGenerator_DropIndex(t *testing.T) {
 	gen := NewGenerator()
 
@@ -552,3 +571,34 @@ func TestGenerator_ParameterPlaceholder(t *testing.T) {
 func ptrString(s string) *string {
 	return &s
 }
+
+func TestGenerator_ReorderColumns(t *testing.T) {
+	gen := NewGenerator()
+	table := database.Table{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+			{Name: "name", Type: "TEXT", Nullable: true},
+			{Name: "email", Type: "TEXT"},
+		},
+		Indexes: []database.Index{{Name: "idx_users_email", Columns: []string{"email"}, Unique: true}},
+	}
+
+	step := gen.ReorderColumns(table)
+
+	if len(step.SQL) != 5 {
+		t.Fatalf("Expected 4 rebuild statements and 1 index, got %d: %v", len(step.SQL), step.SQL)
+	}
+	if !strings.HasPrefix(step.SQL[0], "CREATE TABLE users_new") {
+		t.Errorf("Expected temporary table first, got %s", step.SQL[0])
+	}
+	if step.SQL[1] != "INSERT INTO users_new (id, name, email) SELECT id, name, email FROM users" {
+		t.Errorf("Unexpected copy statement: %s", step.SQL[1])
+	}
+	if step.SQL[3] != "ALTER TABLE users_new RENAME TO users" {
+		t.Errorf("Unexpected rename statement: %s", step.SQL[3])
+	}
+	if !strings.Contains(step.SQL[4], "CREATE UNIQUE INDEX idx_users_email") {
+		t.Errorf("Expected index to be recreated, got %s", step.SQL[4])
+	}
+}
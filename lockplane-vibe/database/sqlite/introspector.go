@@ -19,6 +19,12 @@ func NewIntrospector() *Introspector {
 
 // IntrospectSchema reads the entire SQLite database schema
 func (i *Introspector) IntrospectSchema(ctx context.Context, db *sql.DB) (*database.Schema, error) {
+	return i.IntrospectTables(ctx, db, nil)
+}
+
+// IntrospectTables reads the tables matching patterns. An empty pattern list
+// reads every table.
+func (i *Introspector) IntrospectTables(ctx context.Context, db *sql.DB, patterns []string) (*database.Schema, error) {
 	schema := &database.Schema{
 		Tables: make([]database.Table, 0),
 	}
@@ -29,6 +35,9 @@ func (i *Introspector) IntrospectSchema(ctx context.Context, db *sql.DB) (*datab
 	}
 
 	for _, tableName := range tables {
+		if !database.MatchTable("", tableName, patterns) {
+			continue
+		}
 		table := database.Table{Name: tableName}
 
 		columns, err := i.GetColumns(ctx, db, tableName)
@@ -52,6 +61,7 @@ func (i *Introspector) IntrospectSchema(ctx context.Context, db *sql.DB) (*datab
 		schema.Tables = append(schema.Tables, table)
 	}
 
+	database.MarkPartial(schema, patterns)
 	schema.Dialect = database.DialectSQLite
 	return schema, nil
 }
@@ -85,7 +95,7 @@ func (i *Introspector) GetTables(ctx context.Context, db *sql.DB) ([]string, err
 // GetColumns returns all columns for a given SQLite table
 func (i *Introspector) GetColumns(ctx context.Context, db *sql.DB, tableName string) ([]database.Column, error) {
 	// SQLite uses PRAGMA table_info
-	query := fmt.Sprintf("PRAGMA table_info(%s)", tableName)
+	query := fmt.Sprintf("PRAGMA table_info(%s)", quoteSQLiteString(tableName))
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -135,7 +145,7 @@ func (i *Introspector) GetColumns(ctx context.Context, db *sql.DB, tableName str
 // GetIndexes returns all indexes for a given SQLite table
 func (i *Introspector) GetIndexes(ctx context.Context, db *sql.DB, tableName string) ([]database.Index, error) {
 	// Get index list
-	query := fmt.Sprintf("PRAGMA index_list(%s)", tableName)
+	query := fmt.Sprintf("PRAGMA index_list(%s)", quoteSQLiteString(tableName))
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -180,31 +190,34 @@ func (i *Introspector) GetIndexes(ctx context.Context, db *sql.DB, tableName str
 
 	var indexes []database.Index
 	for _, raw := range rawIndexes {
-		indexInfoQuery := fmt.Sprintf("PRAGMA index_info(%s)", quoteSQLiteString(raw.index.Name))
+		indexInfoQuery := fmt.Sprintf("PRAGMA index_xinfo(%s)", quoteSQLiteString(raw.index.Name))
 		indexRows, indexErr := db.QueryContext(ctx, indexInfoQuery)
 		if indexErr != nil {
-			return nil, fmt.Errorf("failed to query index_info for %s: %w", raw.index.Name, indexErr)
+			return nil, fmt.Errorf("failed to query index_xinfo for %s: %w", raw.index.Name, indexErr)
 		}
 
+		var keyColumns []database.IndexColumn
 		for indexRows.Next() {
-			var seqno, cid int
-			var name sql.NullString
+			var seqno, cid, desc, key int
+			var name, coll sql.NullString
 
-			// PRAGMA index_info returns: seqno, cid, name
-			if err := indexRows.Scan(&seqno, &cid, &name); err != nil {
+			// PRAGMA index_xinfo returns: seqno, cid, name, desc, coll, key
+			if err := indexRows.Scan(&seqno, &cid, &name, &desc, &coll, &key); err != nil {
 				_ = indexRows.Close()
-				return nil, fmt.Errorf("failed to scan index_info for %s: %w", raw.index.Name, err)
+				return nil, fmt.Errorf("failed to scan index_xinfo for %s: %w", raw.index.Name, err)
 			}
 
-			if name.Valid {
-				raw.index.Columns = append(raw.index.Columns, name.String)
+			// key = 0 rows are the rowid and other auxiliary columns
+			if key == 1 && name.Valid {
+				keyColumns = append(keyColumns, database.IndexColumn{Name: name.String, Descending: desc == 1})
 			}
 		}
 		if err := indexRows.Err(); err != nil {
 			_ = indexRows.Close()
-			return nil, fmt.Errorf("error iterating index_info for %s: %w", raw.index.Name, err)
+			return nil, fmt.Errorf("error iterating index_xinfo for %s: %w", raw.index.Name, err)
 		}
 		_ = indexRows.Close()
+		raw.index.SetKeyColumns(keyColumns)
 
 		if raw.origin == "c" {
 			indexes = append(indexes, raw.index)
@@ -222,7 +235,7 @@ func quoteSQLiteString(value string) string {
 // GetForeignKeys returns all foreign keys for a given SQLite table
 func (i *Introspector) GetForeignKeys(ctx context.Context, db *sql.DB, tableName string) ([]database.ForeignKey, error) {
 	// SQLite uses PRAGMA foreign_key_list
-	query := fmt.Sprintf("PRAGMA foreign_key_list(%s)", tableName)
+	query := fmt.Sprintf("PRAGMA foreign_key_list(%s)", quoteSQLiteString(tableName))
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -222,6 +222,38 @@ func TestIntrospector_GetIndexes(t *testing.T) {
 	}
 }
 
+func TestIntrospector_GetIndexes_Descending(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+        CREATE TABLE events (id INTEGER PRIMARY KEY, tenant_id INTEGER, created_at TEXT);
+        CREATE INDEX idx_events_recent ON events (tenant_id, created_at DESC);
+    `)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	indexes, err := introspector.GetIndexes(ctx, db, "events")
+	if err != nil {
+		t.Fatalf("GetIndexes failed: %v", err)
+	}
+	if len(indexes) != 1 {
+		t.Fatalf("Expected 1 index, got %d", len(indexes))
+	}
+
+	cols := indexes[0].KeyColumns()
+	if len(cols) != 2 || cols[0].Name != "tenant_id" || cols[1].Name != "created_at" {
+		t.Fatalf("Expected key columns [tenant_id created_at], got %+v", cols)
+	}
+	if cols[0].Descending || !cols[1].Descending {
+		t.Errorf("Expected only created_at to be descending, got %+v", cols)
+	}
+}
+
 func TestIntrospector_GetForeignKeys(t *testing.T) {
 	db := getTestDB(t)
 	defer func() { _ = db.Close() }()
@@ -383,6 +415,69 @@ func TestIntrospector_IntrospectSchema(t *testing.T) {
 	}
 }
 
+func TestIntrospector_IntrospectTables(t *testing.T) {
+	db := getTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	introspector := NewIntrospector()
+
+	_, err := db.ExecContext(ctx, `
+        CREATE TABLE users (id INTEGER PRIMARY KEY);
+        CREATE TABLE orders (
+            id INTEGER PRIMARY KEY,
+            user_id INTEGER REFERENCES users (id)
+        );
+        CREATE TABLE billing_invoices (
+            id INTEGER PRIMARY KEY,
+            order_id INTEGER REFERENCES orders (id)
+        );
+        CREATE TABLE audit_log (id INTEGER PRIMARY KEY);
+    `)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	schema, err := introspector.IntrospectTables(ctx, db, []string{"orders", "billing_*"})
+	if err != nil {
+		t.Fatalf("IntrospectTables failed: %v", err)
+	}
+
+	if len(schema.Tables) != 2 {
+		t.Fatalf("Expected 2 tables, got %d", len(schema.Tables))
+	}
+	if findTable(schema.Tables, "users") != nil || findTable(schema.Tables, "audit_log") != nil {
+		t.Error("Expected tables outside the filter to be skipped")
+	}
+	if len(schema.TableFilter) != 2 || schema.TableFilter[1] != "billing_*" {
+		t.Errorf("Expected table filter to be recorded, got %v", schema.TableFilter)
+	}
+
+	orders := findTable(schema.Tables, "orders")
+	if orders == nil || len(orders.ForeignKeys) != 1 {
+		t.Fatal("Expected orders table with 1 foreign key")
+	}
+	if !orders.ForeignKeys[0].External {
+		t.Error("Expected foreign key to users to be marked external")
+	}
+
+	invoices := findTable(schema.Tables, "billing_invoices")
+	if invoices == nil || len(invoices.ForeignKeys) != 1 {
+		t.Fatal("Expected billing_invoices table with 1 foreign key")
+	}
+	if invoices.ForeignKeys[0].External {
+		t.Error("Expected foreign key to orders not to be marked external")
+	}
+
+	full, err := introspector.IntrospectSchema(ctx, db)
+	if err != nil {
+		t.Fatalf("IntrospectSchema failed: %v", err)
+	}
+	if len(full.Tables) != 4 || len(full.TableFilter) != 0 {
+		t.Errorf("Expected full introspection of 4 tables without a filter, got %d tables and %v", len(full.Tables), full.TableFilter)
+	}
+}
+
 func TestIntrospector_EmptyDatabase(t *testing.T) {
 	db := getTestDB(t)
 	defer func() { _ = db.Close() }()
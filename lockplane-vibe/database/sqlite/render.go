@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// bareIdentifierPattern matches identifiers SQLite accepts without quotes.
+// SQLite compares names case-insensitively, so case doesn't need quoting.
+var bareIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedKeywords are the SQLite keywords that can't be used as table or
+// column names without quoting. SQLite accepts its other keywords as names.
+var reservedKeywords = map[string]bool{
+	"add": true, "all": true, "alter": true, "and": true, "as": true,
+	"autoincrement": true, "between": true, "case": true, "check": true,
+	"collate": true, "commit": true, "constraint": true, "create": true,
+	"cross": true, "default": true, "deferrable": true, "delete": true,
+	"distinct": true, "drop": true, "else": true, "escape": true, "except": true,
+	"exists": true, "filter": true, "foreign": true, "from": true, "full": true,
+	"glob": true, "group": true, "having": true, "in": true, "index": true,
+	"indexed": true, "inner": true, "insert": true, "intersect": true, "into": true,
+	"is": true, "isnull": true, "join": true, "left": true, "limit": true,
+	"natural": true, "not": true, "notnull": true, "null": true, "on": true,
+	"or": true, "order": true, "outer": true, "over": true, "primary": true,
+	"references": true, "regexp": true, "returning": true, "right": true,
+	"rollback": true, "select": true, "set": true, "table": true, "then": true,
+	"to": true, "transaction": true, "union": true, "unique": true, "update": true,
+	"using": true, "values": true, "when": true, "where": true, "window": true,
+}
+
+// QuoteIdentifier returns name as it must be written in SQLite SQL. Names
+// SQLite accepts without quotes are left bare, so generated SQL stays
+// readable; others, such as reserved words or names with spaces, are
+// double-quoted.
+func (g *Generator) QuoteIdentifier(name string) string {
+	if bareIdentifierPattern.MatchString(name) && !reservedKeywords[strings.ToLower(name)] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteIdentifiers quotes names and joins them into a column list
+func (g *Generator) quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = g.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// postgresOnlyTypes maps PostgreSQL types that SQLite has no equivalent for
+// to the SQLite type storing their values, as schema.DefaultTypeMap does.
+// Left as written, most would get NUMERIC affinity, which turns text that
+// looks like a number into one, and serial columns wouldn't be assigned.
+var postgresOnlyTypes = map[string]string{
+	"uuid":                        "TEXT",
+	"json":                        "TEXT",
+	"jsonb":                       "TEXT",
+	"timestamptz":                 "TEXT",
+	"timestamp with time zone":    "TEXT",
+	"timestamp":                   "TEXT",
+	"timestamp without time zone": "TEXT",
+	"timetz":                      "TEXT",
+	"time with time zone":         "TEXT",
+	"time without time zone":      "TEXT",
+	"interval":                    "TEXT",
+	"inet":                        "TEXT",
+	"cidr":                        "TEXT",
+	"macaddr":                     "TEXT",
+	"tsvector":                    "TEXT",
+	"bytea":                       "BLOB",
+	"smallserial":                 "INTEGER",
+	"serial":                      "INTEGER",
+	"bigserial":                   "INTEGER",
+}
+
+// typeModifierPattern matches type modifiers such as "(3)" or "(10, 2)"
+var typeModifierPattern = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// RenderType returns the type of col as SQLite spells it. Types of columns
+// read from PostgreSQL that reach the generator untranslated, including
+// arrays, are replaced by the SQLite type storing their values; anything
+// else is used as written.
+func (g *Generator) RenderType(col database.Column) string {
+	if col.TypeMetadata == nil || col.TypeMetadata.Dialect != database.DialectPostgres {
+		return col.Type
+	}
+	base := strings.ToLower(strings.TrimSpace(typeModifierPattern.ReplaceAllString(col.Type, "")))
+	if strings.HasSuffix(base, "]") {
+		return "TEXT"
+	}
+	if mapped, ok := postgresOnlyTypes[base]; ok {
+		return mapped
+	}
+	return col.Type
+}
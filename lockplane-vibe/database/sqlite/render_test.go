@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/drivertest"
+)
+
+func TestDriverConformance(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	drivertest.Run(t, NewDriver(), func(statement string) error {
+		_, err := db.Exec(statement)
+		return err
+	})
+}
+
+func TestGenerator_QuoteIdentifier(t *testing.T) {
+	gen := NewGenerator()
+	tests := map[string]string{
+		"users":      "users",
+		"UserID":     "UserID",
+		"user":       "user",
+		"Order":      `"Order"`,
+		"index":      `"index"`,
+		"first name": `"first name"`,
+		`say "hi"`:   `"say ""hi"""`,
+	}
+	for name, want := range tests {
+		if got := gen.QuoteIdentifier(name); got != want {
+			t.Errorf("QuoteIdentifier(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestGenerator_RenderType(t *testing.T) {
+	gen := NewGenerator()
+	fromPostgres := func(columnType string) database.Column {
+		return database.Column{Type: columnType, TypeMetadata: &database.TypeMetadata{Dialect: database.DialectPostgres}}
+	}
+
+	tests := []struct {
+		col  database.Column
+		want string
+	}{
+		{fromPostgres("uuid"), "TEXT"},
+		{fromPostgres("timestamp(3) with time zone"), "TEXT"},
+		{fromPostgres("serial"), "INTEGER"},
+		{fromPostgres("bytea"), "BLOB"},
+		{fromPostgres("text[]"), "TEXT"},
+		{fromPostgres("varchar(255)"), "varchar(255)"},
+		{fromPostgres("integer"), "integer"},
+		// Types written for SQLite are its own to interpret
+		{database.Column{Type: "JSON"}, "JSON"},
+		{database.Column{Type: "uuid", TypeMetadata: &database.TypeMetadata{Dialect: database.DialectSQLite}}, "uuid"},
+	}
+	for _, tt := range tests {
+		if got := gen.RenderType(tt.col); got != tt.want {
+			t.Errorf("RenderType(%q) = %q, want %q", tt.col.Type, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_RecreateTableQuotesNames(t *testing.T) {
+	gen := NewGenerator()
+	step := gen.ReorderColumns(database.Table{
+		Name: "order",
+		Columns: []database.Column{
+			{Name: "index", Type: "INTEGER", Nullable: true},
+			{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+		},
+	})
+
+	want := []string{
+		"CREATE TABLE order_new (\n  \"index\" INTEGER,\n  id INTEGER PRIMARY KEY NOT NULL\n)",
+		`INSERT INTO order_new ("index", id) SELECT "index", id FROM "order"`,
+		`DROP TABLE "order"`,
+		`ALTER TABLE order_new RENAME TO "order"`,
+	}
+	if len(step.SQL) != len(want) {
+		t.Fatalf("expected %d statements, got %q", len(want), step.SQL)
+	}
+	for i := range want {
+		if step.SQL[i] != want[i] {
+			t.Errorf("statement %d = %q, want %q", i, step.SQL[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultSchema is the PostgreSQL schema unqualified table names resolve to.
+// Tables in it are named without their schema.
+const DefaultSchema = "public"
+
+// QualifiedTableName returns schemaName.tableName, or tableName alone when
+// schemaName is empty or DefaultSchema
+func QualifiedTableName(schemaName, tableName string) string {
+	if schemaName == "" || schemaName == DefaultSchema {
+		return tableName
+	}
+	return schemaName + "." + tableName
+}
+
+// QualifiedName returns the table's name qualified with its schema, as
+// QualifiedTableName does. Diffs and plans identify tables by it.
+func (t Table) QualifiedName() string {
+	return QualifiedTableName(t.Schema, t.Name)
+}
+
+// SplitQualifiedName splits a name returned by QualifiedTableName into its
+// schema (empty for the default schema) and table name
+func SplitQualifiedName(name string) (string, string) {
+	if schemaName, tableName, ok := strings.Cut(name, "."); ok {
+		return schemaName, tableName
+	}
+	return "", name
+}
+
+// QuoteQualifiedName quotes each part of a name returned by
+// QualifiedTableName or QualifiedIndexName with quote, a driver's
+// QuoteIdentifier
+func QuoteQualifiedName(name string, quote func(string) string) string {
+	schemaName, objectName := SplitQualifiedName(name)
+	if schemaName == "" {
+		return quote(objectName)
+	}
+	return quote(schemaName) + "." + quote(objectName)
+}
+
+// PostgresDefaultTablespace is the tablespace PostgreSQL databases store
+// objects in unless created with another default
+const PostgresDefaultTablespace = "pg_default"
+
+// TablespaceOrDefault returns the tablespace an object is stored in:
+// tablespace, or defaultTablespace (a Schema's DefaultTablespace) when it is
+// unset. An unknown default is taken to be PostgresDefaultTablespace.
+func TablespaceOrDefault(tablespace *string, defaultTablespace string) string {
+	if tablespace != nil {
+		if name := strings.TrimSpace(*tablespace); name != "" {
+			return name
+		}
+	}
+	if defaultTablespace == "" {
+		return PostgresDefaultTablespace
+	}
+	return defaultTablespace
+}
+
+// QualifiedIndexName qualifies indexName with the schema of tableName, a name
+// returned by QualifiedTableName. An index lives in its table's schema, so
+// statements naming the index alone, like DROP INDEX, need it.
+func QualifiedIndexName(tableName, indexName string) string {
+	if schemaName, _ := SplitQualifiedName(tableName); schemaName != "" {
+		return schemaName + "." + indexName
+	}
+	return indexName
+}
+
+// FindTable returns the table of schema with the qualified name name (see
+// QualifiedTableName), or nil. A table without a schema, such as one loaded
+// from a JSON file or one on the other side of the name, also matches when it
+// is the only table with the bare name.
+func FindTable(schema *Schema, name string) *Table {
+	if schema == nil {
+		return nil
+	}
+	for i := range schema.Tables {
+		if schema.Tables[i].QualifiedName() == name {
+			return &schema.Tables[i]
+		}
+	}
+	schemaName, tableName := SplitQualifiedName(name)
+	var found *Table
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if table.Name != tableName || (schemaName != "" && table.Schema != "") {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = table
+	}
+	return found
+}
+
+// MatchTable reports whether a glob pattern matches the table's name, with or
+// without its schema qualifier. An empty pattern list matches every table.
+func MatchTable(schemaName, tableName string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tableName); ok {
+			return true
+		}
+		if schemaName != "" {
+			if ok, _ := path.Match(pattern, schemaName+"."+tableName); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarkPartial records patterns as the schema's table filter and marks foreign
+// keys whose referenced table is not in the schema as External. It does
+// nothing when patterns is empty.
+func MarkPartial(schema *Schema, patterns []string) {
+	if schema == nil || len(patterns) == 0 {
+		return
+	}
+	schema.TableFilter = append([]string(nil), patterns...)
+
+	tables := make(map[string]bool, len(schema.Tables))
+	for _, table := range schema.Tables {
+		tables[table.Name] = true
+		if table.Schema != "" {
+			tables[table.Schema+"."+table.Name] = true
+		}
+	}
+	for i := range schema.Tables {
+		fks := schema.Tables[i].ForeignKeys
+		for j := range fks {
+			fks[j].External = !tables[fks[j].ReferencedTable]
+		}
+	}
+}
+
+// FilterSchemas keeps only the tables of schema in the schemaNames, where a
+// table without a schema is in DefaultSchema. It does nothing when
+// schemaNames is empty.
+func FilterSchemas(schema *Schema, schemaNames []string) {
+	if schema == nil || len(schemaNames) == 0 {
+		return
+	}
+	tables := schema.Tables[:0]
+	for _, table := range schema.Tables {
+		schemaName := table.Schema
+		if schemaName == "" {
+			schemaName = DefaultSchema
+		}
+		if slices.Contains(schemaNames, schemaName) {
+			tables = append(tables, table)
+		}
+	}
+	schema.Tables = tables
+}
+
+// bareStorageParameterValue matches values that need no quoting in a
+// WITH (...) or SET (...) list, such as numbers and keywords
+var bareStorageParameterValue = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+// FormatStorageParameters formats storage parameters as a comma-separated
+// "name = value" list sorted by name, quoting values where needed
+func FormatStorageParameters(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := params[name]
+		if !bareStorageParameterValue.MatchString(value) {
+			value = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		}
+		parts = append(parts, fmt.Sprintf("%s = %s", name, value))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,131 @@
+package database
+
+import "testing"
+
+func TestMatchTable(t *testing.T) {
+	tests := []struct {
+		schemaName string
+		tableName  string
+		patterns   []string
+		want       bool
+	}{
+		{"", "users", nil, true},
+		{"", "users", []string{"users"}, true},
+		{"", "billing_invoices", []string{"users", "billing_*"}, true},
+		{"", "orders", []string{"users", "billing_*"}, false},
+		{"auth", "users", []string{"auth.*"}, true},
+		{"public", "users", []string{"auth.*"}, false},
+	}
+	for _, tt := range tests {
+		if got := MatchTable(tt.schemaName, tt.tableName, tt.patterns); got != tt.want {
+			t.Errorf("MatchTable(%q, %q, %v) = %v; want %v", tt.schemaName, tt.tableName, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestQualifiedTableName(t *testing.T) {
+	tests := []struct {
+		schemaName, tableName, want string
+	}{
+		{"", "users", "users"},
+		{"public", "users", "users"},
+		{"billing", "invoices", "billing.invoices"},
+	}
+	for _, tt := range tests {
+		if got := QualifiedTableName(tt.schemaName, tt.tableName); got != tt.want {
+			t.Errorf("QualifiedTableName(%q, %q) = %q; want %q", tt.schemaName, tt.tableName, got, tt.want)
+		}
+	}
+	if got := QualifiedIndexName("billing.invoices", "invoices_user"); got != "billing.invoices_user" {
+		t.Errorf("Expected the index qualified with its table's schema, got %q", got)
+	}
+	if got := QualifiedIndexName("users", "users_email"); got != "users_email" {
+		t.Errorf("Expected an index of a default schema table unqualified, got %q", got)
+	}
+}
+
+func TestFindTable(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "users", Schema: "public"},
+		{Name: "users", Schema: "tenant_a"},
+		{Name: "invoices"},
+	}}
+
+	if table := FindTable(schema, "users"); table != &schema.Tables[0] {
+		t.Errorf("Expected users to find public.users, got %+v", table)
+	}
+	if table := FindTable(schema, "tenant_a.users"); table != &schema.Tables[1] {
+		t.Errorf("Expected tenant_a.users, got %+v", table)
+	}
+	if table := FindTable(schema, "billing.invoices"); table != &schema.Tables[2] {
+		t.Errorf("Expected billing.invoices to find the unqualified invoices, got %+v", table)
+	}
+	if table := FindTable(schema, "tenant_b.users"); table != nil {
+		t.Errorf("Expected no table for tenant_b.users, got %+v", table)
+	}
+}
+
+func TestFilterSchemas(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "users"},
+		{Name: "invoices", Schema: "billing"},
+		{Name: "events", Schema: "audit"},
+	}}
+
+	FilterSchemas(schema, []string{"public", "billing"})
+	if len(schema.Tables) != 2 || schema.Tables[0].Name != "users" || schema.Tables[1].Name != "invoices" {
+		t.Errorf("Expected users and billing.invoices, got %+v", schema.Tables)
+	}
+
+	FilterSchemas(schema, nil)
+	if len(schema.Tables) != 2 {
+		t.Errorf("Expected FilterSchemas without schemas to keep every table, got %+v", schema.Tables)
+	}
+}
+
+func TestMarkPartial(t *testing.T) {
+	schema := &Schema{Tables: []Table{
+		{Name: "orders", Schema: "public", ForeignKeys: []ForeignKey{
+			{Name: "orders_user_id_fkey", ReferencedTable: "users"},
+		}},
+		{Name: "order_items", Schema: "public", ForeignKeys: []ForeignKey{
+			{Name: "order_items_order_id_fkey", ReferencedTable: "orders"},
+			{Name: "order_items_sku_fkey", ReferencedTable: "public.products"},
+		}},
+	}}
+
+	MarkPartial(schema, []string{"order*"})
+
+	if len(schema.TableFilter) != 1 || schema.TableFilter[0] != "order*" {
+		t.Errorf("Expected table filter to be recorded, got %v", schema.TableFilter)
+	}
+	if !schema.Tables[0].ForeignKeys[0].External {
+		t.Error("Expected foreign key to users to be external")
+	}
+	if schema.Tables[1].ForeignKeys[0].External {
+		t.Error("Expected foreign key to orders not to be external")
+	}
+	if !schema.Tables[1].ForeignKeys[1].External {
+		t.Error("Expected foreign key to public.products to be external")
+	}
+
+	full := &Schema{Tables: []Table{{Name: "orders", ForeignKeys: []ForeignKey{{Name: "fk", ReferencedTable: "users"}}}}}
+	MarkPartial(full, nil)
+	if full.TableFilter != nil || full.Tables[0].ForeignKeys[0].External {
+		t.Error("Expected MarkPartial without patterns to leave the schema unchanged")
+	}
+}
+
+func TestFormatStorageParameters(t *testing.T) {
+	params := map[string]string{
+		"fillfactor":                     "70",
+		"autovacuum_vacuum_scale_factor": "0.01",
+		"toast.autovacuum_enabled":       "off",
+		"custom":                         "it's quoted",
+	}
+
+	want := "autovacuum_vacuum_scale_factor = 0.01, custom = 'it''s quoted', fillfactor = 70, toast.autovacuum_enabled = off"
+	if got := FormatStorageParameters(params); got != want {
+		t.Errorf("FormatStorageParameters() = %q, want %q", got, want)
+	}
+}
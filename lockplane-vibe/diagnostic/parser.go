@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	pg_query "github.com/pganalyze/pg_query_go/v6"
+
+	"github.com/lockplane/lockplane/internal/sqlsplit"
 )
 
 // ErrorRecoveryParser attempts to parse SQL with error recovery
@@ -367,29 +369,16 @@ type statement struct {
 }
 
 func splitStatements(sql string) []statement {
-	// Simple semicolon-based splitting for now
-	// (Future: more sophisticated splitting that handles strings/comments)
 	var statements []statement
-	parts := strings.Split(sql, ";")
-
 	offset := 0
-	for i, part := range parts {
-		if strings.TrimSpace(part) == "" {
-			offset += len(part) + 1
-			continue
-		}
-
+	for _, stmt := range sqlsplit.Split(sql) {
 		statements = append(statements, statement{
-			sql:       part,
-			startLine: strings.Count(sql[:offset], "\n"),
+			sql:       stmt.Text,
+			startLine: stmt.StartLine - 1,
 			startPos:  offset,
 		})
-
-		if i < len(parts)-1 {
-			offset += len(part) + 1 // +1 for semicolon
-		}
+		offset += len(stmt.Text)
 	}
-
 	return statements
 }
 
@@ -0,0 +1,217 @@
+// Package advisor finds index improvements in a live PostgreSQL database
+// while planning: foreign keys without an index on the referencing columns,
+// and indexes that are never scanned. Its findings are advice for the schema
+// files, not plan steps.
+package advisor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+)
+
+// maxIdentifierLength is PostgreSQL's NAMEDATALEN - 1; longer names are
+// truncated by the server
+const maxIdentifierLength = 63
+
+// Advise returns the advisories for source, the introspected live database,
+// given usage, its indexes with scan counts. Objects the plan to target drops
+// anyway, and foreign keys target already adds an index for, are left out.
+// The suggested SQL is generated with generator.
+func Advise(source, target *database.Schema, usage []postgres.IndexUsage, generator database.SQLGenerator) []planner.Advisory {
+	if source == nil || target == nil {
+		return nil
+	}
+
+	var advisories []planner.Advisory
+	for _, table := range source.Tables {
+		targetTable := findTable(target, table)
+		if targetTable == nil {
+			continue
+		}
+		indexes := tableUsage(usage, table)
+		advisories = append(advisories, missingForeignKeyIndexes(table, *targetTable, indexes, generator)...)
+		advisories = append(advisories, unusedIndexes(table, *targetTable, indexes, generator)...)
+	}
+	return advisories
+}
+
+// missingForeignKeyIndexes advises an index for each foreign key of table
+// whose columns don't lead any index. Without one, deleting or updating a
+// referenced row scans the whole table.
+func missingForeignKeyIndexes(table, targetTable database.Table, indexes []postgres.IndexUsage, generator database.SQLGenerator) []planner.Advisory {
+	var advisories []planner.Advisory
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Columns) == 0 || !hasForeignKey(targetTable, fk.Name) {
+			continue
+		}
+		if liveIndexCovers(indexes, fk.Columns) || targetIndexCovers(targetTable, fk.Columns) {
+			continue
+		}
+		idx := database.Index{Name: indexName(table.Name, fk.Columns), Columns: fk.Columns}
+		sql, _ := generator.AddIndex(table.Name, idx)
+		advisories = append(advisories, planner.Advisory{
+			Kind:    planner.AdvisoryMissingForeignKeyIndex,
+			Table:   table.Name,
+			Name:    fk.Name,
+			Columns: fk.Columns,
+			Message: fmt.Sprintf("Foreign key %s on %s (%s) has no index on its columns; deleting or updating rows of %s scans the whole table",
+				fk.Name, table.Name, strings.Join(fk.Columns, ", "), fk.ReferencedTable),
+			SQL: sql + ";",
+		})
+	}
+	return advisories
+}
+
+// unusedIndexes advises dropping the indexes of table that were never
+// scanned. Indexes backing a constraint or enforcing uniqueness are needed
+// even when unscanned, and so are the ones supporting a foreign key.
+func unusedIndexes(table, targetTable database.Table, indexes []postgres.IndexUsage, generator database.SQLGenerator) []planner.Advisory {
+	var advisories []planner.Advisory
+	for _, usage := range indexes {
+		if usage.Scans > 0 || usage.Constraint || usage.Unique || !hasIndex(targetTable, usage.Name) {
+			continue
+		}
+		if supportsForeignKey(table, usage) {
+			continue
+		}
+		sql, _ := generator.DropIndex(table.Name, database.Index{Name: usage.Name, Columns: usage.Columns})
+		advisories = append(advisories, planner.Advisory{
+			Kind:    planner.AdvisoryUnusedIndex,
+			Table:   table.Name,
+			Name:    usage.Name,
+			Columns: usage.Columns,
+			Message: fmt.Sprintf("Index %s on %s (%s) has not been scanned since the statistics were last reset; remove it from the schema files to drop it",
+				usage.Name, table.Name, formatSize(usage.SizeBytes)),
+			SQL: sql + ";",
+		})
+	}
+	return advisories
+}
+
+// findTable returns the table of schema with the name and schema of table
+func findTable(schema *database.Schema, table database.Table) *database.Table {
+	for i := range schema.Tables {
+		t := &schema.Tables[i]
+		if t.Name == table.Name && (t.Schema == "" || table.Schema == "" || t.Schema == table.Schema) {
+			return t
+		}
+	}
+	return nil
+}
+
+// tableUsage returns the indexes of usage on table
+func tableUsage(usage []postgres.IndexUsage, table database.Table) []postgres.IndexUsage {
+	var indexes []postgres.IndexUsage
+	for _, u := range usage {
+		if u.Table == table.Name && (table.Schema == "" || u.Schema == table.Schema) {
+			indexes = append(indexes, u)
+		}
+	}
+	return indexes
+}
+
+func hasForeignKey(table database.Table, name string) bool {
+	for _, fk := range table.ForeignKeys {
+		if fk.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasIndex(table database.Table, name string) bool {
+	for _, idx := range table.Indexes {
+		if idx.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// leadingColumns reports whether columns are the leading key columns of an
+// index, in any order
+func leadingColumns(indexColumns, columns []string) bool {
+	if len(indexColumns) < len(columns) {
+		return false
+	}
+	leading := make(map[string]bool, len(columns))
+	for _, col := range indexColumns[:len(columns)] {
+		leading[col] = true
+	}
+	for _, col := range columns {
+		if !leading[col] {
+			return false
+		}
+	}
+	return true
+}
+
+// liveIndexCovers reports whether a full index of the live database leads
+// with columns
+func liveIndexCovers(indexes []postgres.IndexUsage, columns []string) bool {
+	for _, idx := range indexes {
+		if !idx.Partial && leadingColumns(idx.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetIndexCovers reports whether the target schema has an index, or a
+// primary key, leading with columns
+func targetIndexCovers(table database.Table, columns []string) bool {
+	for _, idx := range table.Indexes {
+		if leadingColumns(idx.Columns, columns) {
+			return true
+		}
+	}
+	var primaryKey []string
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			primaryKey = append(primaryKey, col.Name)
+		}
+	}
+	return leadingColumns(primaryKey, columns)
+}
+
+// supportsForeignKey reports whether idx leads with the columns of one of
+// table's foreign keys
+func supportsForeignKey(table database.Table, idx postgres.IndexUsage) bool {
+	if idx.Partial {
+		return false
+	}
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Columns) > 0 && leadingColumns(idx.Columns, fk.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexName names the index advised for columns of table, truncated to the
+// length PostgreSQL keeps
+func indexName(table string, columns []string) string {
+	name := "idx_" + table + "_" + strings.Join(columns, "_")
+	if len(name) > maxIdentifierLength {
+		name = name[:maxIdentifierLength]
+	}
+	return name
+}
+
+// formatSize formats a size in bytes for messages
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,115 @@
+package advisor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+)
+
+func TestAdvise(t *testing.T) {
+	orders := database.Table{
+		Name:   "orders",
+		Schema: "public",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "customer_id", Type: "integer"},
+			{Name: "product_id", Type: "integer"},
+			{Name: "status", Type: "text"},
+		},
+		Indexes: []database.Index{
+			{Name: "orders_product_status", Columns: []string{"product_id", "status"}},
+			{Name: "orders_status", Columns: []string{"status"}},
+		},
+		ForeignKeys: []database.ForeignKey{
+			{Name: "orders_customer_fk", Columns: []string{"customer_id"}, ReferencedTable: "customers", ReferencedColumns: []string{"id"}},
+			{Name: "orders_product_fk", Columns: []string{"product_id"}, ReferencedTable: "products", ReferencedColumns: []string{"id"}},
+		},
+	}
+	source := &database.Schema{Tables: []database.Table{orders}}
+	target := &database.Schema{Tables: []database.Table{orders}}
+	usage := []postgres.IndexUsage{
+		{Schema: "public", Table: "orders", Name: "orders_pkey", Columns: []string{"id"}, Unique: true, Constraint: true},
+		{Schema: "public", Table: "orders", Name: "orders_product_status", Columns: []string{"product_id", "status"}},
+		{Schema: "public", Table: "orders", Name: "orders_status", Columns: []string{"status"}, SizeBytes: 16384},
+	}
+
+	advisories := Advise(source, target, usage, postgres.NewGenerator())
+	if len(advisories) != 2 {
+		t.Fatalf("Expected 2 advisories, got %+v", advisories)
+	}
+
+	missing := advisories[0]
+	if missing.Kind != planner.AdvisoryMissingForeignKeyIndex || missing.Name != "orders_customer_fk" {
+		t.Errorf("Expected the unindexed customer foreign key, got %+v", missing)
+	}
+	if missing.SQL != "CREATE INDEX idx_orders_customer_id ON orders (customer_id);" {
+		t.Errorf("Unexpected CREATE INDEX suggestion: %s", missing.SQL)
+	}
+
+	unused := advisories[1]
+	if unused.Kind != planner.AdvisoryUnusedIndex || unused.Name != "orders_status" {
+		t.Errorf("Expected the unused status index, got %+v", unused)
+	}
+	if unused.SQL != "DROP INDEX orders_status;" || !strings.Contains(unused.Message, "16.0 KiB") {
+		t.Errorf("Unexpected unused index advisory: %+v", unused)
+	}
+}
+
+func TestAdviseSkipsWhatThePlanChanges(t *testing.T) {
+	source := &database.Schema{Tables: []database.Table{{
+		Name:    "orders",
+		Columns: []database.Column{{Name: "id", Type: "integer"}, {Name: "customer_id", Type: "integer"}},
+		Indexes: []database.Index{{Name: "orders_old", Columns: []string{"id"}}},
+		ForeignKeys: []database.ForeignKey{
+			{Name: "orders_customer_fk", Columns: []string{"customer_id"}, ReferencedTable: "customers"},
+		},
+	}}}
+	usage := []postgres.IndexUsage{{Table: "orders", Name: "orders_old", Columns: []string{"id"}}}
+
+	// The target indexes the foreign key and drops the unused index
+	target := &database.Schema{Tables: []database.Table{{
+		Name:        "orders",
+		Columns:     source.Tables[0].Columns,
+		Indexes:     []database.Index{{Name: "orders_customer", Columns: []string{"customer_id"}}},
+		ForeignKeys: source.Tables[0].ForeignKeys,
+	}}}
+	if advisories := Advise(source, target, usage, postgres.NewGenerator()); len(advisories) != 0 {
+		t.Errorf("Expected no advice for what the plan fixes, got %+v", advisories)
+	}
+
+	// The target drops the table
+	if advisories := Advise(source, &database.Schema{}, usage, postgres.NewGenerator()); len(advisories) != 0 {
+		t.Errorf("Expected no advice for a dropped table, got %+v", advisories)
+	}
+}
+
+func TestLeadingColumns(t *testing.T) {
+	tests := []struct {
+		index, columns []string
+		want           bool
+	}{
+		{[]string{"a"}, []string{"a"}, true},
+		{[]string{"a", "b"}, []string{"a"}, true},
+		{[]string{"b", "a", "c"}, []string{"a", "b"}, true},
+		{[]string{"b", "a"}, []string{"a"}, false},
+		{[]string{"a"}, []string{"a", "b"}, false},
+		{[]string{"", "a"}, []string{"a"}, false},
+	}
+	for _, tt := range tests {
+		if got := leadingColumns(tt.index, tt.columns); got != tt.want {
+			t.Errorf("leadingColumns(%v, %v) = %v, want %v", tt.index, tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	if name := indexName("orders", []string{"customer_id", "region"}); name != "idx_orders_customer_id_region" {
+		t.Errorf("Unexpected index name %s", name)
+	}
+	if name := indexName(strings.Repeat("t", 70), []string{"c"}); len(name) != maxIdentifierLength {
+		t.Errorf("Expected the name truncated to %d bytes, got %d", maxIdentifierLength, len(name))
+	}
+}
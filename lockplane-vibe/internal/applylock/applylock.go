@@ -0,0 +1,181 @@
+// Package applylock serializes migrations against the same database.
+//
+// PostgreSQL targets use a session-level advisory lock keyed on the database
+// name, so any client connected to that database sees it regardless of the
+// host name or connection string it used. SQLite targets use an exclusive
+// lock on a file next to the database. Both are released by the operating
+// system or server if the process dies, so a crashed apply never leaves a
+// stale lock behind.
+package applylock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lockplane/lockplane/internal/dburl"
+)
+
+// ErrInProgress is returned when another migration holds the lock and it was
+// not released within the timeout
+var ErrInProgress = errors.New("another migration is in progress")
+
+// pollInterval is how often a held lock is retried while waiting
+var pollInterval = 500 * time.Millisecond
+
+// Lock is a held apply lock
+type Lock struct {
+	// Description names the lock for messages, e.g. the advisory lock key
+	Description string
+	release     func() error
+}
+
+// Release releases the lock. It is safe to call more than once.
+func (l *Lock) Release() error {
+	if l == nil || l.release == nil {
+		return nil
+	}
+	release := l.release
+	l.release = nil
+	return release()
+}
+
+// Acquire takes the apply lock for the database behind db, waiting up to
+// timeout for another migration to finish. A zero timeout fails immediately
+// when the lock is held. Targets that can't be locked (in-memory and remote
+// libSQL databases) get a lock that does nothing.
+func Acquire(ctx context.Context, db *sql.DB, driverType, connStr string, timeout time.Duration) (*Lock, error) {
+	switch driverType {
+	case "postgres", "postgresql":
+		return acquireAdvisoryLock(ctx, db, timeout)
+	case "sqlite", "sqlite3":
+		path := dburl.SQLitePath(connStr)
+		if path == "" || path == ":memory:" {
+			return &Lock{Description: "none (in-memory database)"}, nil
+		}
+		return acquireFileLock(ctx, LockFilePath(path), timeout)
+	default:
+		return &Lock{Description: fmt.Sprintf("none (%s databases are not locked)", driverType)}, nil
+	}
+}
+
+// AdvisoryLockKey returns the PostgreSQL advisory lock key for a database
+func AdvisoryLockKey(database string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("lockplane:apply:" + database))
+	return int64(h.Sum64())
+}
+
+// LockFilePath returns the lock file used for a SQLite database file
+func LockFilePath(dbPath string) string {
+	if abs, err := filepath.Abs(dbPath); err == nil {
+		dbPath = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dbPath); err == nil {
+		dbPath = resolved
+	}
+	return dbPath + ".lockplane-lock"
+}
+
+func acquireAdvisoryLock(ctx context.Context, db *sql.DB, timeout time.Duration) (*Lock, error) {
+	// Advisory locks belong to a session, so hold one connection until release
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock connection: %w", err)
+	}
+
+	var database string
+	if err := conn.QueryRowContext(ctx, "SELECT current_database()").Scan(&database); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read database name: %w", err)
+	}
+	key := AdvisoryLockKey(database)
+	description := fmt.Sprintf("advisory lock %d on database %q", key, database)
+
+	err = waitFor(ctx, timeout, description, func() (bool, error) {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+			return false, fmt.Errorf("failed to take advisory lock: %w", err)
+		}
+		return acquired, nil
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &Lock{
+		Description: description,
+		release: func() error {
+			defer func() { _ = conn.Close() }()
+			if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+				return fmt.Errorf("failed to release advisory lock: %w", err)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func acquireFileLock(ctx context.Context, path string, timeout time.Duration) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	description := fmt.Sprintf("file lock %s", path)
+
+	err = waitFor(ctx, timeout, description, func() (bool, error) {
+		return tryLockFile(file)
+	})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &Lock{
+		Description: description,
+		release: func() error {
+			unlockErr := unlockFile(file)
+			if err := file.Close(); err != nil && unlockErr == nil {
+				unlockErr = err
+			}
+			if unlockErr != nil {
+				return fmt.Errorf("failed to release file lock: %w", unlockErr)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// waitFor retries try until it reports the lock was taken, the timeout
+// passes, or ctx is done
+func waitFor(ctx context.Context, timeout time.Duration, description string, try func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		acquired, err := try()
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if timeout > 0 {
+				return fmt.Errorf("%w: %s is still held after waiting %s", ErrInProgress, description, timeout)
+			}
+			return fmt.Errorf("%w: %s is held", ErrInProgress, description)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(pollInterval, remaining)):
+		}
+	}
+}
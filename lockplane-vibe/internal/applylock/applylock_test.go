@@ -0,0 +1,109 @@
+package applylock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/internal/testutil"
+)
+
+func TestAdvisoryLockKey(t *testing.T) {
+	if AdvisoryLockKey("app") != AdvisoryLockKey("app") {
+		t.Error("Expected the same key for the same database")
+	}
+	if AdvisoryLockKey("app") == AdvisoryLockKey("app_test") {
+		t.Error("Expected different keys for different databases")
+	}
+}
+
+func TestFileLockSerializesApplies(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+
+	first, err := Acquire(ctx, nil, "sqlite", dbPath, 0)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	_, err = Acquire(ctx, nil, "sqlite", "sqlite://"+dbPath, 0)
+	if !errors.Is(err, ErrInProgress) {
+		t.Fatalf("Expected ErrInProgress while the lock is held, got %v", err)
+	}
+
+	// A waiting apply gets the lock once the first one releases it
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = first.Release()
+	}()
+	second, err := Acquire(ctx, nil, "sqlite", dbPath, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected to acquire the lock after release, got %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Expected a second Release to be a no-op, got %v", err)
+	}
+}
+
+func TestFileLockTimeout(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+
+	held, err := Acquire(ctx, nil, "sqlite", dbPath, 0)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer func() { _ = held.Release() }()
+
+	start := time.Now()
+	_, err = Acquire(ctx, nil, "sqlite", dbPath, 200*time.Millisecond)
+	if !errors.Is(err, ErrInProgress) {
+		t.Fatalf("Expected ErrInProgress after the timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected to wait for the timeout, returned after %s", elapsed)
+	}
+}
+
+func TestAcquireInMemoryIsNoop(t *testing.T) {
+	lock, err := Acquire(context.Background(), nil, "sqlite", ":memory:", 0)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("Failed to release lock: %v", err)
+	}
+}
+
+func TestAdvisoryLockSerializesApplies(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "postgres")
+	defer tdb.Close()
+	ctx := context.Background()
+
+	first, err := Acquire(ctx, tdb.DB, "postgres", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	// Another session can't take the lock while it is held
+	if _, err := Acquire(ctx, tdb.DB, "postgres", "", 0); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("Expected ErrInProgress while the lock is held, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+
+	second, err := Acquire(ctx, tdb.DB, "postgres", "", 0)
+	if err != nil {
+		t.Fatalf("Expected to acquire the lock after release, got %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+}
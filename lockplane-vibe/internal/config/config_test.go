@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeProjectFile creates path (and its parent directories) with content
+func writeProjectFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// projectRoot returns a temp directory with symlinks resolved, so paths
+// compare equal to the ones derived from os.Getwd
+func projectRoot(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	return root
+}
+
+func TestLoadConfigFromSubdirectory(t *testing.T) {
+	root := projectRoot(t)
+	writeProjectFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeProjectFile(t, filepath.Join(root, "lockplane.toml"), "schema_path = \"db/schema\"\n\n[environments.local]\n")
+	writeProjectFile(t, filepath.Join(root, ".env.local"), "DATABASE_URL=postgres://from-root\n")
+	// Nested package markers must not stop the search
+	writeProjectFile(t, filepath.Join(root, "apps", "api", "package.json"), "{}\n")
+	writeProjectFile(t, filepath.Join(root, "apps", "api", "go.mod"), "module api\n")
+	t.Chdir(filepath.Join(root, "apps", "api"))
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.ConfigFile() != filepath.Join(root, "lockplane.toml") {
+		t.Fatalf("Expected config from project root, got %q", cfg.ConfigFile())
+	}
+	if cfg.ConfigDir() != root {
+		t.Fatalf("Expected config dir %q, got %q", root, cfg.ConfigDir())
+	}
+
+	if got := GetSchemaPath("", cfg, nil, ""); got != filepath.Join(root, "db", "schema") {
+		t.Fatalf("Expected schema path relative to config dir, got %q", got)
+	}
+
+	env, err := ResolveEnvironment(cfg, "local")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.DatabaseURL != "postgres://from-root" {
+		t.Fatalf("Expected database URL from root .env.local, got %q", env.DatabaseURL)
+	}
+}
+
+func TestGetSchemaPathWindowsSeparators(t *testing.T) {
+	root := projectRoot(t)
+	writeProjectFile(t, filepath.Join(root, "lockplane.toml"), "schema_path = 'db\\schema'\n\n[environments.local]\nschema_path = 'db\\local'\n")
+	t.Chdir(root)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if got := GetSchemaPath("", cfg, nil, ""); got != filepath.Join(root, "db", "schema") {
+		t.Fatalf("Expected backslashes to separate directories, got %q", got)
+	}
+
+	env, err := ResolveEnvironment(cfg, "local")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.SchemaPath != filepath.Join(root, "db", "local") {
+		t.Fatalf("Expected backslashes to separate directories, got %q", env.SchemaPath)
+	}
+}
+
+func TestLoadConfigStopsAtRepositoryRoot(t *testing.T) {
+	root := projectRoot(t)
+	writeProjectFile(t, filepath.Join(root, "lockplane.toml"), "schema_path = \"outside\"\n")
+	repo := filepath.Join(root, "repo")
+	writeProjectFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	t.Chdir(repo)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.ConfigFile() != "" {
+		t.Fatalf("Expected no config outside the repository to be used, got %q", cfg.ConfigFile())
+	}
+	if cfg.ConfigDir() != repo {
+		t.Fatalf("Expected config dir to default to working directory %q, got %q", repo, cfg.ConfigDir())
+	}
+}
+
+func TestLoadConfigExplicitFile(t *testing.T) {
+	root := projectRoot(t)
+	writeProjectFile(t, filepath.Join(root, "lockplane.toml"), "schema_path = \"ignored\"\n")
+	explicit := filepath.Join(root, "deploy", "lockplane.prod.toml")
+	writeProjectFile(t, explicit, "schema_path = \"schema\"\n")
+	t.Chdir(root)
+
+	SetConfigFile(filepath.Join("deploy", "lockplane.prod.toml"))
+	t.Cleanup(func() { SetConfigFile("") })
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.ConfigFile() != explicit {
+		t.Fatalf("Expected explicit config %q, got %q", explicit, cfg.ConfigFile())
+	}
+	if got := GetSchemaPath("", cfg, nil, ""); got != filepath.Join(root, "deploy", "schema") {
+		t.Fatalf("Expected schema path relative to explicit config, got %q", got)
+	}
+}
+
+func TestLoadConfigExplicitFileMissing(t *testing.T) {
+	t.Chdir(projectRoot(t))
+
+	SetConfigFile("missing.toml")
+	t.Cleanup(func() { SetConfigFile("") })
+
+	_, err := LoadConfig()
+	if err == nil {
+		t.Fatal("Expected error for missing config file")
+	}
+	if !strings.Contains(err.Error(), "missing.toml") {
+		t.Fatalf("Expected error to name the missing file, got %v", err)
+	}
+}
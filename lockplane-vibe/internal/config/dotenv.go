@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sharedDotenvFile holds settings common to every environment
+const sharedDotenvFile = ".env"
+
+// DotenvLocalSuffix marks the per-developer .env.<name>.local files, which
+// override .env.<name> and are kept out of version control
+const DotenvLocalSuffix = ".local"
+
+// verboseOutput receives the trace of which .env file supplied each variable
+// (nil = no trace)
+var verboseOutput io.Writer
+
+// tracedEnvironments remembers the environments already traced, so resolving
+// one several times traces it once
+var tracedEnvironments = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: map[string]bool{}}
+
+// SetVerboseOutput makes ResolveEnvironment write to w which .env files it
+// read and which file supplied each variable. A nil w turns the trace off.
+func SetVerboseOutput(w io.Writer) {
+	verboseOutput = w
+}
+
+// dotenvFileNames returns the .env files of environment envName in the order
+// they are applied: .env, .env.<name>, then .env.<name>.local
+func dotenvFileNames(envName string) []string {
+	envFile := ".env." + envName
+	return []string{sharedDotenvFile, envFile, envFile + DotenvLocalSuffix}
+}
+
+// findDotenvFile returns the path of the .env file name in baseDir, falling
+// back to projectDir when baseDir has none. found is false when neither has
+// the file; path is then the one in baseDir.
+func findDotenvFile(name, baseDir, projectDir string) (path string, found bool, err error) {
+	path = name
+	if baseDir != "" {
+		path = filepath.Join(baseDir, name)
+	}
+	info, err := os.Stat(path)
+	if err == nil {
+		return path, !info.IsDir(), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", false, fmt.Errorf("failed to access %s: %w", path, err)
+	}
+	if projectDir != "" && projectDir != baseDir {
+		altPath := filepath.Join(projectDir, name)
+		if altInfo, altErr := os.Stat(altPath); altErr == nil && !altInfo.IsDir() {
+			return altPath, true, nil
+		}
+	}
+	return path, false, nil
+}
+
+// applyDotenv applies the settings of the .env file read from path to
+// resolved. A URL set in the file replaces the one resolved so far, whichever
+// variable spells it; SCHEMA_PATH only applies when lockplane.toml sets none.
+func applyDotenv(resolved *ResolvedEnvironment, values map[string]string, path string, schemaPathFromConfig bool, shadowExplicit *bool) error {
+	if url := dotenvDatabaseURL(values); url != "" {
+		resolved.DatabaseURL = url
+	}
+	// SHADOW_SQLITE_DB_PATH is a variant spelling of SQLITE_SHADOW_DB_PATH
+	if url := firstValue(values, "SHADOW_DATABASE_URL", "POSTGRES_SHADOW_URL", "SQLITE_SHADOW_DB_PATH", "SHADOW_SQLITE_DB_PATH", "LIBSQL_SHADOW_DB_PATH"); url != "" {
+		resolved.ShadowDatabaseURL = url
+		*shadowExplicit = true
+	}
+
+	if value := values["SCHEMA_PATH"]; value != "" && !schemaPathFromConfig {
+		resolved.SchemaPath = value
+	}
+
+	// Shadow schema configuration (PostgreSQL only)
+	if value := values["SHADOW_SCHEMA"]; value != "" {
+		resolved.ShadowSchema = value
+	}
+	if value := values["SHADOW_SCHEMA_PER_RUN"]; value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid SHADOW_SCHEMA_PER_RUN in %s: %w", path, err)
+		}
+		resolved.ShadowSchemaRun.Enabled = enabled
+	}
+	if value := values["SHADOW_SCHEMA_TEMPLATE"]; value != "" {
+		resolved.ShadowSchemaRun.Template = value
+	}
+	if value := values["SHADOW_SCHEMA_TTL"]; value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid SHADOW_SCHEMA_TTL in %s: %w", path, err)
+		}
+		resolved.ShadowSchemaRun.TTL = ttl
+	}
+	return nil
+}
+
+// dotenvDatabaseURL returns the database URL of a .env file: DATABASE_URL,
+// or else the URL of the first database-specific variable set
+func dotenvDatabaseURL(values map[string]string) string {
+	if url := firstValue(values, "DATABASE_URL", "POSTGRES_URL", "SQLITE_DB_PATH"); url != "" {
+		return url
+	}
+	if url := values["LIBSQL_URL"]; url != "" {
+		if authToken := values["LIBSQL_AUTH_TOKEN"]; authToken != "" {
+			return fmt.Sprintf("%s?authToken=%s", url, authToken)
+		}
+		return url
+	}
+	return ""
+}
+
+// firstValue returns the value of the first of keys set in values
+func firstValue(values map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := values[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// traceDotenv writes which .env files resolved read and which file each
+// variable came from to verboseOutput. Values are left out; they hold
+// credentials.
+func traceDotenv(resolved *ResolvedEnvironment) {
+	if verboseOutput == nil {
+		return
+	}
+	tracedEnvironments.Lock()
+	defer tracedEnvironments.Unlock()
+	if tracedEnvironments.names[resolved.Name] {
+		return
+	}
+	tracedEnvironments.names[resolved.Name] = true
+
+	if len(resolved.DotenvFiles) == 0 {
+		_, _ = fmt.Fprintf(verboseOutput, "📄 Environment %q: no .env files found (looked for %s)\n",
+			resolved.Name, strings.Join(dotenvFileNames(resolved.Name), ", "))
+		return
+	}
+	_, _ = fmt.Fprintf(verboseOutput, "📄 Environment %q reads %s\n", resolved.Name, strings.Join(resolved.DotenvFiles, ", "))
+	keys := make([]string, 0, len(resolved.DotenvSources))
+	for key := range resolved.DotenvSources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(verboseOutput, "   %s from %s\n", key, resolved.DotenvSources[key])
+	}
+}
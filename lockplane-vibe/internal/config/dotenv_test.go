@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeDotenvFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestResolveEnvironmentLayeredDotenv(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	writeDotenvFiles(t, tempDir, map[string]string{
+		".env":               "DATABASE_URL=postgres://shared\nSHADOW_SCHEMA=shared_shadow\nSCHEMA_PATH=schema\n",
+		".env.staging":       "DATABASE_URL=postgres://staging\nSHADOW_SCHEMA_TTL=2h\n",
+		".env.staging.local": "POSTGRES_URL=postgres://mine\n",
+	})
+	config := &Config{
+		configDir:    tempDir,
+		Environments: map[string]EnvironmentConfig{"staging": {DatabaseURL: "postgres://toml"}},
+	}
+
+	env, err := ResolveEnvironment(config, "staging")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+
+	// The local file wins, even though it spells the URL differently
+	if env.DatabaseURL != "postgres://mine" {
+		t.Errorf("Expected the URL of .env.staging.local, got %q", env.DatabaseURL)
+	}
+	if env.ShadowSchema != "shared_shadow" || env.ShadowSchemaRun.TTL.String() != "2h0m0s" {
+		t.Errorf("Expected settings of every file to apply, got shadow schema %q, TTL %s", env.ShadowSchema, env.ShadowSchemaRun.TTL)
+	}
+	if env.SchemaPath != filepath.Join(tempDir, "schema") {
+		t.Errorf("Expected SCHEMA_PATH from .env, got %q", env.SchemaPath)
+	}
+
+	wantFiles := []string{
+		filepath.Join(tempDir, ".env"),
+		filepath.Join(tempDir, ".env.staging"),
+		filepath.Join(tempDir, ".env.staging.local"),
+	}
+	if !slices.Equal(env.DotenvFiles, wantFiles) {
+		t.Errorf("Expected files %v, got %v", wantFiles, env.DotenvFiles)
+	}
+	wantSources := map[string]string{
+		"DATABASE_URL":      wantFiles[1],
+		"POSTGRES_URL":      wantFiles[2],
+		"SHADOW_SCHEMA":     wantFiles[0],
+		"SHADOW_SCHEMA_TTL": wantFiles[1],
+		"SCHEMA_PATH":       wantFiles[0],
+	}
+	for key, want := range wantSources {
+		if got := env.DotenvSources[key]; got != want {
+			t.Errorf("Expected %s from %s, got %s", key, want, got)
+		}
+	}
+}
+
+func TestResolveEnvironmentMissingDotenvFiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantURL    string
+		fromDotenv bool
+	}{
+		{"none", nil, "postgres://toml", false},
+		{"shared only", map[string]string{".env": "DATABASE_URL=postgres://shared\n"}, "postgres://shared", false},
+		{"local only", map[string]string{".env.staging.local": "DATABASE_URL=postgres://mine\n"}, "postgres://mine", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tempDir := t.TempDir()
+			writeDotenvFiles(t, tempDir, tt.files)
+			config := &Config{
+				configDir:    tempDir,
+				Environments: map[string]EnvironmentConfig{"staging": {DatabaseURL: "postgres://toml"}},
+			}
+
+			env, err := ResolveEnvironment(config, "staging")
+			if err != nil {
+				t.Fatalf("ResolveEnvironment returned error: %v", err)
+			}
+			if env.DatabaseURL != tt.wantURL || env.FromDotenv != tt.fromDotenv {
+				t.Errorf("Expected %q (from dotenv %v), got %q (%v)", tt.wantURL, tt.fromDotenv, env.DatabaseURL, env.FromDotenv)
+			}
+			if env.DotenvPath != filepath.Join(tempDir, ".env.staging") {
+				t.Errorf("Expected the .env.staging path, got %q", env.DotenvPath)
+			}
+		})
+	}
+}
+
+func TestResolveEnvironmentSharedDotenvDoesNotDefineEnvironment(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	writeDotenvFiles(t, tempDir, map[string]string{".env": "DATABASE_URL=postgres://shared\n"})
+	config := &Config{
+		configDir:    tempDir,
+		Environments: map[string]EnvironmentConfig{"local": {}},
+	}
+
+	if _, err := ResolveEnvironment(config, "stagign"); err == nil || !strings.Contains(err.Error(), "not defined") {
+		t.Errorf("Expected an undefined environment error, got %v", err)
+	}
+}
+
+func TestTraceDotenv(t *testing.T) {
+	var out bytes.Buffer
+	SetVerboseOutput(&out)
+	defer SetVerboseOutput(nil)
+
+	env := &ResolvedEnvironment{
+		Name:          "trace-test",
+		DotenvFiles:   []string{".env", ".env.trace-test"},
+		DotenvSources: map[string]string{"DATABASE_URL": ".env.trace-test", "SHADOW_SCHEMA": ".env"},
+	}
+	traceDotenv(env)
+	traceDotenv(env)
+
+	trace := out.String()
+	if strings.Count(trace, "reads .env, .env.trace-test") != 1 {
+		t.Errorf("Expected the environment traced once, got:\n%s", trace)
+	}
+	for _, want := range []string{"DATABASE_URL from .env.trace-test", "SHADOW_SCHEMA from .env"} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("Expected the trace to contain %q, got:\n%s", want, trace)
+		}
+	}
+}
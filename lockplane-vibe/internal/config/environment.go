@@ -3,30 +3,81 @@ package config
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 
 	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/dburl"
+	"github.com/lockplane/lockplane/internal/style"
 )
 
 // ResolvedEnvironment represents a fully-resolved environment with concrete values.
 type ResolvedEnvironment struct {
-	Name              string
-	DatabaseURL       string
-	ShadowDatabaseURL string
-	ShadowSchema      string // PostgreSQL schema name for shadow database
-	SchemaPath        string
-	DotenvPath        string
-	FromConfig        bool
-	FromDotenv        bool
-	ResolvedConfigDir string
-	Dialect           string   // Database dialect: "postgres" or "sqlite"
-	Schemas           []string // PostgreSQL schemas to manage
-	Warnings          []string
+	Name                  string
+	DatabaseURL           string
+	ShadowDatabaseURL     string
+	ShadowSchema          string // PostgreSQL schema name for shadow database
+	ShadowSchemaRun       ResolvedShadowSchemaRun
+	SchemaPath            string
+	DotenvPath            string            // The environment's .env.<name> file, whether or not it exists
+	DotenvFiles           []string          // The .env files read, in the order they were applied
+	DotenvSources         map[string]string // Variables of the .env files with the file each came from
+	FromConfig            bool
+	FromDotenv            bool
+	ResolvedConfigDir     string
+	Dialect               string            // Database dialect: "postgres" or "sqlite"
+	Schemas               []string          // PostgreSQL schemas to manage
+	AllowDestructive      bool              // Allow apply to run dangerous/data-loss steps
+	ExcludeTables         []string          // Tables not managed by lockplane (names or glob patterns)
+	Variables             map[string]string // Schema template variables, including lockplane.environment
+	StrictVariables       bool              // Fail on undefined ${name} references in schema files
+	ApplyWindow           *ApplyWindow      // Daily time range apply may run in (nil = any time)
+	PreApplyCheck         string            // SQL query whose rows abort apply
+	ColumnDropStrategy    string            // ColumnDropHard or ColumnDropSoft
+	ShadowReuse           bool              // Skip cleaning the shadow database when the schema is unchanged
+	IntrospectionCache    bool              // Reuse the last introspected schema while the database's catalog is unchanged
+	ShadowSettings        ShadowSettings    // Session settings that limit the resources shadow validation uses
+	TargetPostgresVersion int               // server_version_num generated SQL must run on (0 = not pinned)
+	ImmutableTables       map[string]string // Tables plans may create but never alter or drop, with where each was declared
+	SecretSource          string            // Where DatabaseURL came from: SecretSourceEnv, SecretSourceFile or SecretSourceExec
+	Overrides             []string          // Override variables (see OverrideVariables) that replaced resolved values
+	Warnings              []string
 }
 
+// Environment variables that override the connection settings of every
+// environment, for one-off commands against an ad-hoc database. They take
+// precedence over .env files and lockplane.toml; command-line flags still win.
+const (
+	DatabaseURLOverride       = "LOCKPLANE_DATABASE_URL"
+	ShadowDatabaseURLOverride = "LOCKPLANE_SHADOW_DATABASE_URL"
+	ShadowSchemaOverride      = "LOCKPLANE_SHADOW_SCHEMA"
+)
+
+// OverrideVariables returns the names of the override variables that are set
+func OverrideVariables() []string {
+	var set []string
+	for _, name := range []string{DatabaseURLOverride, ShadowDatabaseURLOverride, ShadowSchemaOverride} {
+		if strings.TrimSpace(os.Getenv(name)) != "" {
+			set = append(set, name)
+		}
+	}
+	return set
+}
+
+// ResolvedShadowSchemaRun holds the per-run shadow schema settings
+type ResolvedShadowSchemaRun struct {
+	Enabled  bool          // Create a unique shadow schema for each run and drop it afterwards
+	Template string        // Name template (see shadow.RunSchemaName)
+	TTL      time.Duration // Age after which abandoned per-run schemas are dropped
+}
+
+// EnvironmentVariable is the built-in schema template variable holding the
+// environment name
+const EnvironmentVariable = "lockplane.environment"
+
 // ResolveEnvironment resolves a named environment into concrete connection strings.
 func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, error) {
 	envName := strings.TrimSpace(name)
@@ -58,6 +109,8 @@ func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, erro
 		SchemaPath:        "",
 		ResolvedConfigDir: "",
 		Schemas:           []string{},
+		Variables:         map[string]string{},
+		StrictVariables:   true,
 	}
 
 	if config != nil {
@@ -71,6 +124,19 @@ func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, erro
 		if len(config.Schemas) > 0 {
 			resolved.Schemas = append([]string{}, config.Schemas...)
 		}
+		resolved.AllowDestructive = config.AllowDestructive
+		resolved.ColumnDropStrategy = config.ColumnDropStrategy
+		resolved.ShadowReuse = config.ShadowReuse
+		resolved.IntrospectionCache = config.IntrospectionCache
+		resolved.ShadowSettings = config.ShadowSettings
+		resolved.ExcludeTables = append(resolved.ExcludeTables, config.ExcludeTables...)
+		resolved.ImmutableTables = config.GlobalImmutableTables()
+		for key, value := range config.Variables {
+			resolved.Variables[key] = value
+		}
+		if config.StrictVariables != nil {
+			resolved.StrictVariables = *config.StrictVariables
+		}
 		if config.DatabaseURL != "" && envConfig.DatabaseURL == "" {
 			envConfig.DatabaseURL = config.DatabaseURL
 		}
@@ -95,15 +161,75 @@ func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, erro
 		resolved.Schemas = append([]string{}, envConfig.Schemas...)
 	}
 	resolved.ShadowSchema = envConfig.ShadowSchema
+	if envConfig.ShadowSchemaRun.Enabled != nil {
+		resolved.ShadowSchemaRun.Enabled = *envConfig.ShadowSchemaRun.Enabled
+	}
+	resolved.ShadowSchemaRun.Template = envConfig.ShadowSchemaRun.Template
+	if envConfig.ShadowSchemaRun.TTL != "" {
+		ttl, err := time.ParseDuration(envConfig.ShadowSchemaRun.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shadow_schema_run.ttl for environment %q: %w", envName, err)
+		}
+		resolved.ShadowSchemaRun.TTL = ttl
+	}
+	if envConfig.AllowDestructive {
+		resolved.AllowDestructive = true
+	}
+	if envConfig.ApplyWindow != "" {
+		window, err := ParseApplyWindow(envConfig.ApplyWindow)
+		if err != nil {
+			return nil, fmt.Errorf("environment %q: %w", envName, err)
+		}
+		resolved.ApplyWindow = window
+	}
+	resolved.PreApplyCheck = strings.TrimSpace(envConfig.PreApplyCheck)
+	if envConfig.ColumnDropStrategy != "" {
+		resolved.ColumnDropStrategy = envConfig.ColumnDropStrategy
+	}
+	switch resolved.ColumnDropStrategy {
+	case "":
+		resolved.ColumnDropStrategy = ColumnDropHard
+	case ColumnDropHard, ColumnDropSoft:
+	default:
+		return nil, fmt.Errorf("environment %q: invalid column_drop_strategy %q (use %q or %q)",
+			envName, resolved.ColumnDropStrategy, ColumnDropHard, ColumnDropSoft)
+	}
+	if envConfig.ShadowReuse != nil {
+		resolved.ShadowReuse = *envConfig.ShadowReuse
+	}
+	if envConfig.IntrospectionCache != nil {
+		resolved.IntrospectionCache = *envConfig.IntrospectionCache
+	}
+	resolved.ShadowSettings = resolved.ShadowSettings.merge(envConfig.ShadowSettings)
+	if version := strings.TrimSpace(envConfig.TargetPostgresVersion); version != "" {
+		parsed, err := ParsePostgresVersion(version)
+		if err != nil {
+			return nil, fmt.Errorf("environment %q: target_postgres_version: %w", envName, err)
+		}
+		resolved.TargetPostgresVersion = parsed
+	} else {
+		global, err := config.GlobalTargetPostgresVersion()
+		if err != nil {
+			return nil, err
+		}
+		resolved.TargetPostgresVersion = global
+	}
+	if store := resolved.ShadowSettings.TempStore; store != "" && !slices.Contains(sqliteTempStores, store) {
+		return nil, fmt.Errorf("environment %q: invalid shadow_settings.temp_store %q (use %q, %q or %q)",
+			envName, store, sqliteTempStores[0], sqliteTempStores[1], sqliteTempStores[2])
+	}
+	resolved.ExcludeTables = append(resolved.ExcludeTables, envConfig.ExcludeTables...)
+	resolved.ImmutableTables = addImmutableTables(resolved.ImmutableTables, envConfig.ImmutableTables,
+		fmt.Sprintf("%s: environments.%s.immutable_tables", config.configFileLabel(), envName))
+	for key, value := range envConfig.Variables {
+		resolved.Variables[key] = value
+	}
+	resolved.Variables[EnvironmentVariable] = envName
 	if envExists {
 		resolved.FromConfig = true
 	}
 
-	var (
-		baseDir        string
-		dotenvFileName = ".env." + envName
-	)
-	var projectDir string
+	var baseDir, projectDir string
 	if config != nil {
 		baseDir = config.ConfigDir()
 		projectDir = config.ProjectDir()
@@ -111,102 +237,73 @@ func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, erro
 		baseDir = cwd
 	}
 
-	if baseDir != "" {
-		resolved.DotenvPath = filepath.Join(baseDir, dotenvFileName)
-	} else {
-		resolved.DotenvPath = dotenvFileName
-	}
-
-	if _, err := os.Stat(resolved.DotenvPath); err != nil {
-		if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("failed to access %s: %w", resolved.DotenvPath, err)
-		}
-		if projectDir != "" && projectDir != baseDir {
-			altPath := filepath.Join(projectDir, dotenvFileName)
-			if altInfo, altErr := os.Stat(altPath); altErr == nil && !altInfo.IsDir() {
-				resolved.DotenvPath = altPath
-			}
-		}
-	}
-
-	if info, err := os.Stat(resolved.DotenvPath); err == nil && !info.IsDir() {
-		values, err := godotenv.Read(resolved.DotenvPath)
+	// Later .env files override earlier ones, and all of them override
+	// lockplane.toml, except for SCHEMA_PATH
+	schemaPathFromConfig := resolved.SchemaPath != ""
+	for _, name := range dotenvFileNames(envName) {
+		path, found, err := findDotenvFile(name, baseDir, projectDir)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read %s: %w", resolved.DotenvPath, err)
-		}
-		resolved.FromDotenv = true
-
-		// Check for generic DATABASE_URL first
-		if value := values["DATABASE_URL"]; value != "" {
-			resolved.DatabaseURL = value
+			return nil, err
 		}
-		if value := values["SHADOW_DATABASE_URL"]; value != "" {
-			resolved.ShadowDatabaseURL = value
-			shadowExplicit = true
+		if name == ".env."+envName {
+			resolved.DotenvPath = path
 		}
-
-		// Then check for database-specific variables (these take precedence if DATABASE_URL wasn't set)
-		// PostgreSQL
-		if resolved.DatabaseURL == "" {
-			if value := values["POSTGRES_URL"]; value != "" {
-				resolved.DatabaseURL = value
-			}
+		if !found {
+			continue
 		}
-		if resolved.ShadowDatabaseURL == "" {
-			if value := values["POSTGRES_SHADOW_URL"]; value != "" {
-				resolved.ShadowDatabaseURL = value
-				shadowExplicit = true
-			}
+		values, err := godotenv.Read(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
 		}
-
-		// SQLite
-		if resolved.DatabaseURL == "" {
-			if value := values["SQLITE_DB_PATH"]; value != "" {
-				resolved.DatabaseURL = value
-			}
+		if name != sharedDotenvFile {
+			resolved.FromDotenv = true
 		}
-		if resolved.ShadowDatabaseURL == "" {
-			if value := values["SQLITE_SHADOW_DB_PATH"]; value != "" {
-				resolved.ShadowDatabaseURL = value
-				shadowExplicit = true
-			}
-			// Also check the variant with different naming
-			if resolved.ShadowDatabaseURL == "" {
-				if value := values["SHADOW_SQLITE_DB_PATH"]; value != "" {
-					resolved.ShadowDatabaseURL = value
-					shadowExplicit = true
-				}
-			}
+		resolved.DotenvFiles = append(resolved.DotenvFiles, path)
+		if resolved.DotenvSources == nil {
+			resolved.DotenvSources = map[string]string{}
 		}
-
-		// libSQL
-		if resolved.DatabaseURL == "" {
-			if value := values["LIBSQL_URL"]; value != "" {
-				// Construct libSQL connection string with auth token if available
-				if authToken := values["LIBSQL_AUTH_TOKEN"]; authToken != "" {
-					resolved.DatabaseURL = fmt.Sprintf("%s?authToken=%s", value, authToken)
-				} else {
-					resolved.DatabaseURL = value
-				}
-			}
+		for key := range values {
+			resolved.DotenvSources[key] = path
 		}
-		if resolved.ShadowDatabaseURL == "" {
-			if value := values["LIBSQL_SHADOW_DB_PATH"]; value != "" {
-				resolved.ShadowDatabaseURL = value
-				shadowExplicit = true
-			}
+		if err := applyDotenv(resolved, values, path, schemaPathFromConfig, &shadowExplicit); err != nil {
+			return nil, err
 		}
+	}
+	traceDotenv(resolved)
 
-		if resolved.SchemaPath == "" {
-			if value := values["SCHEMA_PATH"]; value != "" {
-				resolved.SchemaPath = value
-			}
+	// A file or exec secret source replaces the database URL of lockplane.toml
+	// and .env files. The override variable still wins, so the secret isn't
+	// read when it is set.
+	secret := envConfig.SecretConfig
+	if config != nil {
+		secret = mergeSecretConfig(config.SecretConfig, envConfig.SecretConfig)
+	}
+	source, err := normalizeSecretSource(secret.Source)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q: %w", envName, err)
+	}
+	secret.Source = source
+	resolved.SecretSource = source
+	if source != SecretSourceEnv && strings.TrimSpace(os.Getenv(DatabaseURLOverride)) == "" {
+		value, err := readSecret(secret, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("environment %q: %w", envName, err)
 		}
+		resolved.DatabaseURL = value
+	}
 
-		// Check for shadow schema configuration (PostgreSQL only)
-		if value := values["SHADOW_SCHEMA"]; value != "" {
+	for _, name := range OverrideVariables() {
+		value := strings.TrimSpace(os.Getenv(name))
+		switch name {
+		case DatabaseURLOverride:
+			resolved.DatabaseURL = value
+		case ShadowDatabaseURLOverride:
+			resolved.ShadowDatabaseURL = value
+			shadowExplicit = true
+		case ShadowSchemaOverride:
 			resolved.ShadowSchema = value
 		}
+		resolved.Overrides = append(resolved.Overrides, name)
 	}
 
 	if resolved.DatabaseURL == "" {
@@ -236,18 +333,25 @@ func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, erro
 
 	// Warn if configured dialect conflicts with connection string
 	if resolved.Dialect != "" {
+		configDialect, err := parseDialect(resolved.Dialect)
+		if err != nil {
+			if envConfig.Dialect != "" {
+				return nil, fmt.Errorf("invalid dialect for environment %q: %w", envName, err)
+			}
+			return nil, fmt.Errorf("invalid dialect in lockplane.toml: %w", err)
+		}
+		resolved.Dialect = string(configDialect)
 		matchDialect := detectDialectFromConnectionString(resolved.DatabaseURL)
-		configDialect := database.Dialect(strings.ToLower(resolved.Dialect))
-		if configDialect != database.DialectUnknown && matchDialect != database.DialectUnknown && matchDialect != configDialect {
+		if matchDialect != database.DialectUnknown && matchDialect != configDialect {
 			warning := fmt.Sprintf("⚠️  Warning: environment %q configures dialect %q but connection string looks like %q. Update lockplane.toml or use a matching connection string.",
 				resolved.Name, configDialect, matchDialect)
-			fmt.Fprintln(os.Stderr, warning)
+			fmt.Fprintln(style.Stderr, warning)
 			resolved.Warnings = append(resolved.Warnings, warning)
 		}
 	}
 
 	if config != nil && config.Environments != nil && len(config.Environments) > 0 && !envExists {
-		if !resolved.FromDotenv {
+		if !resolved.FromDotenv && !slices.Contains(resolved.Overrides, DatabaseURLOverride) {
 			return nil, fmt.Errorf("environment %q not defined in lockplane.toml and %s not found", envName, resolved.DotenvPath)
 		}
 	}
@@ -255,25 +359,54 @@ func ResolveEnvironment(config *Config, name string) (*ResolvedEnvironment, erro
 	return resolved, nil
 }
 
-func detectDialectFromConnectionString(connStr string) database.Dialect {
-	if connStr == "" {
-		return database.DialectUnknown
+// parseDialect maps a configured dialect name to the dialect it selects.
+// libSQL/Turso databases use the SQLite dialect.
+func parseDialect(name string) (database.Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "postgres", "postgresql":
+		return database.DialectPostgres, nil
+	case "sqlite", "sqlite3", "libsql":
+		return database.DialectSQLite, nil
+	default:
+		return database.DialectUnknown, fmt.Errorf("unknown dialect %q (expected postgres, sqlite or libsql)", name)
 	}
-	lower := strings.ToLower(connStr)
+}
 
-	switch {
-	case strings.HasPrefix(lower, "postgres://"),
-		strings.HasPrefix(lower, "postgresql://"):
+func detectDialectFromConnectionString(connStr string) database.Dialect {
+	switch dburl.Detect(connStr) {
+	case dburl.KindPostgres:
 		return database.DialectPostgres
-	case strings.HasPrefix(lower, "sqlite://"),
-		strings.HasPrefix(lower, "file:"),
-		strings.HasSuffix(lower, ".db"),
-		strings.HasSuffix(lower, ".sqlite"),
-		strings.HasSuffix(lower, ".sqlite3"),
-		lower == ":memory:",
-		strings.HasPrefix(lower, "libsql://"):
+	case dburl.KindSQLite, dburl.KindLibSQL:
 		return database.DialectSQLite
 	default:
 		return database.DialectUnknown
 	}
 }
+
+// GlobalImmutableTables returns the tables lockplane.toml declares immutable
+// in every environment, with where they were declared
+func (c *Config) GlobalImmutableTables() map[string]string {
+	if c == nil {
+		return nil
+	}
+	return addImmutableTables(nil, c.ImmutableTables, c.configFileLabel()+": immutable_tables")
+}
+
+// addImmutableTables records tables declared immutable at declaredAt in
+// immutable, creating it when needed. A table declared more than once keeps
+// its first declaration.
+func addImmutableTables(immutable map[string]string, tables []string, declaredAt string) map[string]string {
+	for _, table := range tables {
+		table = strings.TrimSpace(table)
+		if table == "" {
+			continue
+		}
+		if immutable == nil {
+			immutable = map[string]string{}
+		}
+		if _, ok := immutable[table]; !ok {
+			immutable[table] = declaredAt
+		}
+	}
+	return immutable
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePostgresVersion parses a PostgreSQL release such as "15", "15.4",
+// "9.6" or "9.6.24" into its server_version_num (150000, 150004, 90600,
+// 90624)
+func ParsePostgresVersion(spec string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid PostgreSQL version %q (expected e.g. \"15\" or \"15.4\")", spec)
+		}
+		numbers[i] = n
+	}
+
+	major := numbers[0]
+	switch {
+	case major >= 10 && len(numbers) <= 2:
+		minor := 0
+		if len(numbers) == 2 {
+			minor = numbers[1]
+		}
+		return major*10000 + minor, nil
+	case major == 9 && len(numbers) >= 2 && len(numbers) <= 3:
+		patch := 0
+		if len(numbers) == 3 {
+			patch = numbers[2]
+		}
+		return major*10000 + numbers[1]*100 + patch, nil
+	}
+	return 0, fmt.Errorf("invalid PostgreSQL version %q (expected e.g. \"15\", \"15.4\" or \"9.6\")", spec)
+}
+
+// GlobalTargetPostgresVersion returns the server_version_num of the global
+// target_postgres_version, or 0 when it is not set
+func (c *Config) GlobalTargetPostgresVersion() (int, error) {
+	if c == nil || strings.TrimSpace(c.TargetPostgresVersion) == "" {
+		return 0, nil
+	}
+	version, err := ParsePostgresVersion(c.TargetPostgresVersion)
+	if err != nil {
+		return 0, fmt.Errorf("target_postgres_version: %w", err)
+	}
+	return version, nil
+}
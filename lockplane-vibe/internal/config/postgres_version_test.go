@@ -0,0 +1,66 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePostgresVersion(t *testing.T) {
+	cases := map[string]int{
+		"15":     150000,
+		"15.4":   150004,
+		" 13 ":   130000,
+		"9.6":    90600,
+		"9.6.24": 90624,
+	}
+	for spec, want := range cases {
+		got, err := ParsePostgresVersion(spec)
+		if err != nil {
+			t.Errorf("ParsePostgresVersion(%q) returned error: %v", spec, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParsePostgresVersion(%q) = %d, want %d", spec, got, want)
+		}
+	}
+
+	for _, spec := range []string{"", "fifteen", "9", "15.4.1", "8.4.x", "-1"} {
+		if _, err := ParsePostgresVersion(spec); err == nil {
+			t.Errorf("ParsePostgresVersion(%q) expected an error", spec)
+		}
+	}
+}
+
+func TestResolveEnvironmentTargetPostgresVersion(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		configDir:             t.TempDir(),
+		TargetPostgresVersion: "15",
+		Environments: map[string]EnvironmentConfig{
+			"local":  {},
+			"legacy": {TargetPostgresVersion: "13.11"},
+			"bad":    {TargetPostgresVersion: "latest"},
+		},
+	}
+
+	local, err := ResolveEnvironment(config, "local")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if local.TargetPostgresVersion != 150000 {
+		t.Errorf("Expected the global target_postgres_version, got %d", local.TargetPostgresVersion)
+	}
+
+	legacy, err := ResolveEnvironment(config, "legacy")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if legacy.TargetPostgresVersion != 130011 {
+		t.Errorf("Expected the environment to override target_postgres_version, got %d", legacy.TargetPostgresVersion)
+	}
+
+	if _, err := ResolveEnvironment(config, "bad"); err == nil || !strings.Contains(err.Error(), "target_postgres_version") {
+		t.Errorf("Expected an invalid target_postgres_version error, got %v", err)
+	}
+}
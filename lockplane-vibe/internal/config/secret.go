@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Secret sources for secret_source, which say where an environment's
+// database URL comes from
+const (
+	SecretSourceEnv  = "env"  // lockplane.toml and .env files (default)
+	SecretSourceFile = "file" // The contents of secret_file
+	SecretSourceExec = "exec" // The standard output of secret_command
+)
+
+// SecretConfig selects the secret source of the database URL. It can be set
+// globally and per environment; an environment's settings win.
+type SecretConfig struct {
+	Source  string `toml:"secret_source"`  // SecretSourceEnv, SecretSourceFile or SecretSourceExec
+	File    string `toml:"secret_file"`    // File holding the database URL, relative to the config directory
+	Command string `toml:"secret_command"` // Shell command printing the database URL, e.g. "vault kv get -field=url secret/db"
+}
+
+// secrets caches the secrets read by this process, keyed by source and file
+// or command, so a command runs once however often environments are resolved
+var secrets = struct {
+	sync.Mutex
+	values map[string]string
+}{values: map[string]string{}}
+
+// mergeSecretConfig returns base with the fields set in override replacing it
+func mergeSecretConfig(base, override SecretConfig) SecretConfig {
+	if override.Source != "" {
+		base.Source = override.Source
+	}
+	if override.File != "" {
+		base.File = override.File
+	}
+	if override.Command != "" {
+		base.Command = override.Command
+	}
+	return base
+}
+
+// normalizeSecretSource returns the secret source in lower case, with an
+// unset source as SecretSourceEnv
+func normalizeSecretSource(source string) (string, error) {
+	switch source = strings.ToLower(strings.TrimSpace(source)); source {
+	case "":
+		return SecretSourceEnv, nil
+	case SecretSourceEnv, SecretSourceFile, SecretSourceExec:
+		return source, nil
+	default:
+		return "", fmt.Errorf("invalid secret_source %q (use %q, %q or %q)", source, SecretSourceEnv, SecretSourceFile, SecretSourceExec)
+	}
+}
+
+// readSecret returns the database URL of a file or exec secret source. Files
+// and commands run in baseDir. The value is trimmed and must not be empty.
+func readSecret(secret SecretConfig, baseDir string) (string, error) {
+	var key, path string
+	switch secret.Source {
+	case SecretSourceFile:
+		if strings.TrimSpace(secret.File) == "" {
+			return "", fmt.Errorf("secret_source %q needs secret_file", SecretSourceFile)
+		}
+		path = resolveSchemaPath(secret.File, baseDir)
+		key = SecretSourceFile + ":" + path
+	case SecretSourceExec:
+		if strings.TrimSpace(secret.Command) == "" {
+			return "", fmt.Errorf("secret_source %q needs secret_command", SecretSourceExec)
+		}
+		key = SecretSourceExec + ":" + baseDir + ":" + secret.Command
+	default:
+		return "", fmt.Errorf("secret_source %q has no secret to read", secret.Source)
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+	if value, ok := secrets.values[key]; ok {
+		return value, nil
+	}
+
+	var value string
+	var err error
+	if secret.Source == SecretSourceFile {
+		value, err = readSecretFile(path)
+	} else {
+		value, err = runSecretCommand(secret.Command, baseDir)
+	}
+	if err != nil {
+		return "", err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if secret.Source == SecretSourceFile {
+			return "", fmt.Errorf("secret_file %s is empty", secret.File)
+		}
+		return "", fmt.Errorf("secret_command %q printed nothing", secret.Command)
+	}
+	secrets.values[key] = value
+	return value, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret_file: %w", err)
+	}
+	return string(data), nil
+}
+
+// runSecretCommand runs command with the system shell in dir and returns its
+// standard output. Standard error is kept for the error message only, so a
+// command's diagnostics never end up in the URL. Standard input is left
+// unset: it may carry a piped plan (apply --plan-file -) that the command
+// must not consume.
+func runSecretCommand(command, dir string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	if dir != "" {
+		cmd.Dir = filepath.Clean(dir)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("secret_command %q failed: %s", command, msg)
+		}
+		return "", fmt.Errorf("secret_command %q failed: %w", command, err)
+	}
+	return stdout.String(), nil
+}
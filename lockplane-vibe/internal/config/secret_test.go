@@ -0,0 +1,195 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestSecretConfigFromTOML(t *testing.T) {
+	t.Parallel()
+
+	var config Config
+	data := `
+secret_source = "file"
+secret_file = "db.url"
+
+[environments.production]
+secret_source = "exec"
+secret_command = "vault kv get -field=url secret/db"
+`
+	if err := toml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if config.Source != SecretSourceFile || config.File != "db.url" {
+		t.Errorf("Expected the global file secret source, got %+v", config.SecretConfig)
+	}
+
+	merged := mergeSecretConfig(config.SecretConfig, config.Environments["production"].SecretConfig)
+	want := SecretConfig{Source: SecretSourceExec, File: "db.url", Command: "vault kv get -field=url secret/db"}
+	if merged != want {
+		t.Errorf("Expected %+v, got %+v", want, merged)
+	}
+}
+
+func TestResolveEnvironmentSecretFile(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "db.url"), []byte("postgres://from-file\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	config := &Config{
+		configDir:    tempDir,
+		SecretConfig: SecretConfig{Source: "FILE", File: "db.url"},
+		Environments: map[string]EnvironmentConfig{
+			"production": {DatabaseURL: "postgres://toml"},
+		},
+	}
+
+	env, err := ResolveEnvironment(config, "production")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.DatabaseURL != "postgres://from-file" || env.SecretSource != SecretSourceFile {
+		t.Errorf("Expected the database URL from the secret file, got %q (%s)", env.DatabaseURL, env.SecretSource)
+	}
+
+	config.Environments["staging"] = EnvironmentConfig{SecretConfig: SecretConfig{Source: SecretSourceEnv}, DatabaseURL: "postgres://staging"}
+	env, err = ResolveEnvironment(config, "staging")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.DatabaseURL != "postgres://staging" {
+		t.Errorf("Expected the environment's env secret source to keep its URL, got %q", env.DatabaseURL)
+	}
+}
+
+func TestResolveEnvironmentSecretCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		configDir: tempDir,
+		Environments: map[string]EnvironmentConfig{
+			"production": {SecretConfig: SecretConfig{
+				Source:  SecretSourceExec,
+				Command: "echo run >> runs.log && echo postgres://from-command",
+			}},
+		},
+	}
+
+	for range 2 {
+		env, err := ResolveEnvironment(config, "production")
+		if err != nil {
+			t.Fatalf("ResolveEnvironment returned error: %v", err)
+		}
+		if env.DatabaseURL != "postgres://from-command" {
+			t.Errorf("Expected the database URL printed by the command, got %q", env.DatabaseURL)
+		}
+	}
+	runs, err := os.ReadFile(filepath.Join(tempDir, "runs.log"))
+	if err != nil {
+		t.Fatalf("Expected the command to run in the config directory: %v", err)
+	}
+	if strings.Count(string(runs), "run") != 1 {
+		t.Errorf("Expected the command to run once per process, ran %d times", strings.Count(string(runs), "run"))
+	}
+
+	// The override variable wins without running the command
+	t.Setenv(DatabaseURLOverride, "postgres://override")
+	config.Environments["staging"] = EnvironmentConfig{SecretConfig: SecretConfig{Source: SecretSourceExec, Command: "exit 1"}}
+	env, err := ResolveEnvironment(config, "staging")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.DatabaseURL != "postgres://override" {
+		t.Errorf("Expected the override database URL, got %q", env.DatabaseURL)
+	}
+}
+
+func TestResolveEnvironmentSecretCommandLeavesStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	// A plan piped to apply --plan-file - must still be there after the
+	// secret command runs
+	const plan = `{"steps": []}`
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe returned error: %v", err)
+	}
+	if _, err := w.WriteString(plan); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+	_ = w.Close()
+	defer func() { _ = r.Close() }()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	config := &Config{
+		configDir: t.TempDir(),
+		Environments: map[string]EnvironmentConfig{
+			"production": {SecretConfig: SecretConfig{
+				Source:  SecretSourceExec,
+				Command: "cat > /dev/null; echo postgres://from-command",
+			}},
+		},
+	}
+	env, err := ResolveEnvironment(config, "production")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.DatabaseURL != "postgres://from-command" {
+		t.Errorf("Expected the database URL printed by the command, got %q", env.DatabaseURL)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		t.Fatalf("Failed to read stdin: %v", err)
+	}
+	if string(data) != plan {
+		t.Errorf("Expected the secret command to leave the piped plan on stdin, got %q", data)
+	}
+}
+
+func TestResolveEnvironmentSecretErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		secret SecretConfig
+		want   string
+	}{
+		{"unknown source", SecretConfig{Source: "vault"}, "invalid secret_source"},
+		{"file without path", SecretConfig{Source: SecretSourceFile}, "needs secret_file"},
+		{"missing file", SecretConfig{Source: SecretSourceFile, File: "missing.url"}, "failed to read secret_file"},
+		{"exec without command", SecretConfig{Source: SecretSourceExec}, "needs secret_command"},
+		{"failing command", SecretConfig{Source: SecretSourceExec, Command: "echo denied >&2; exit 3"}, "denied"},
+		{"silent command", SecretConfig{Source: SecretSourceExec, Command: "true"}, "printed nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret.Command != "" && runtime.GOOS == "windows" {
+				t.Skip("uses a POSIX shell command")
+			}
+			config := &Config{
+				configDir:    t.TempDir(),
+				Environments: map[string]EnvironmentConfig{"production": {SecretConfig: tt.secret}},
+			}
+			_, err := ResolveEnvironment(config, "production")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
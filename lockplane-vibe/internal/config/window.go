@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ApplyWindow is a daily time range in which apply may change a database,
+// such as "02:00-04:00 UTC". A window whose end is before its start wraps
+// past midnight.
+type ApplyWindow struct {
+	Spec     string         // The window as configured
+	Start    time.Duration  // Offset from midnight
+	End      time.Duration  // Offset from midnight
+	Location *time.Location // Time zone the offsets are in (default UTC)
+}
+
+// ParseApplyWindow parses "HH:MM-HH:MM" followed by an optional IANA time
+// zone name, e.g. "02:00-04:00 UTC" or "22:00-01:00 Europe/Berlin"
+func ParseApplyWindow(spec string) (*ApplyWindow, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid apply window %q (expected \"HH:MM-HH:MM [time zone]\")", spec)
+	}
+
+	bounds := strings.Split(fields[0], "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid apply window %q (expected \"HH:MM-HH:MM [time zone]\")", spec)
+	}
+	start, err := parseClock(bounds[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid apply window %q: %w", spec, err)
+	}
+	end, err := parseClock(bounds[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid apply window %q: %w", spec, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid apply window %q: start and end are the same", spec)
+	}
+
+	location := time.UTC
+	if len(fields) == 2 {
+		location, err = time.LoadLocation(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid apply window %q: unknown time zone %q", spec, fields[1])
+		}
+	}
+
+	return &ApplyWindow{Spec: strings.Join(fields, " "), Start: start, End: end, Location: location}, nil
+}
+
+// Contains reports whether t falls inside the window
+func (w *ApplyWindow) Contains(t time.Time) bool {
+	offset := sinceMidnight(t.In(w.Location))
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpen returns when the window next opens after t
+func (w *ApplyWindow) NextOpen(t time.Time) time.Time {
+	local := t.In(w.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.Location)
+	open := midnight.Add(w.Start)
+	if !open.After(local) {
+		open = time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, w.Location).Add(w.Start)
+	}
+	return open
+}
+
+func (w *ApplyWindow) String() string {
+	return w.Spec
+}
+
+// parseClock parses a 24-hour "HH:MM" time of day
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
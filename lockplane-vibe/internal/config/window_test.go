@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseApplyWindow(t *testing.T) {
+	window, err := ParseApplyWindow("02:00-04:00 UTC")
+	if err != nil {
+		t.Fatalf("ParseApplyWindow returned error: %v", err)
+	}
+	if window.Start != 2*time.Hour || window.End != 4*time.Hour || window.Location != time.UTC {
+		t.Fatalf("unexpected window: %+v", window)
+	}
+
+	cases := []struct {
+		at   string
+		open bool
+	}{
+		{"2026-03-01T01:59:59Z", false},
+		{"2026-03-01T02:00:00Z", true},
+		{"2026-03-01T03:30:00Z", true},
+		{"2026-03-01T04:00:00Z", false},
+		{"2026-03-01T03:30:00+02:00", false}, // 01:30 UTC
+	}
+	for _, tc := range cases {
+		at, _ := time.Parse(time.RFC3339, tc.at)
+		if got := window.Contains(at); got != tc.open {
+			t.Errorf("Contains(%s) = %t, want %t", tc.at, got, tc.open)
+		}
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2026-03-01T05:00:00Z")
+	if next := window.NextOpen(at); !next.Equal(time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextOpen after the window = %s, want the next day", next)
+	}
+	at, _ = time.Parse(time.RFC3339, "2026-03-01T01:00:00Z")
+	if next := window.NextOpen(at); !next.Equal(time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextOpen before the window = %s, want the same day", next)
+	}
+}
+
+func TestParseApplyWindowWrapsMidnight(t *testing.T) {
+	window, err := ParseApplyWindow("22:00-01:00")
+	if err != nil {
+		t.Fatalf("ParseApplyWindow returned error: %v", err)
+	}
+	for at, open := range map[string]bool{
+		"2026-03-01T23:00:00Z": true,
+		"2026-03-01T00:30:00Z": true,
+		"2026-03-01T12:00:00Z": false,
+	} {
+		parsed, _ := time.Parse(time.RFC3339, at)
+		if got := window.Contains(parsed); got != open {
+			t.Errorf("Contains(%s) = %t, want %t", at, got, open)
+		}
+	}
+}
+
+func TestParseApplyWindowErrors(t *testing.T) {
+	for spec, want := range map[string]string{
+		"":                      "expected",
+		"02:00":                 "expected",
+		"25:00-04:00":           "not a HH:MM time",
+		"02:00-02:00":           "same",
+		"02:00-04:00 Mars/Base": "unknown time zone",
+	} {
+		_, err := ParseApplyWindow(spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseApplyWindow(%q) error = %v, want it to mention %q", spec, err, want)
+		}
+	}
+}
+
+func TestResolveEnvironmentApplyWindow(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{
+		DefaultEnvironment: "production",
+		configDir:          tempDir,
+		Environments: map[string]EnvironmentConfig{
+			"production": {ApplyWindow: "02:00-04:00 UTC", PreApplyCheck: " SELECT 1 WHERE false "},
+			"broken":     {ApplyWindow: "2am-4am"},
+		},
+	}
+
+	env, err := ResolveEnvironment(config, "production")
+	if err != nil {
+		t.Fatalf("ResolveEnvironment returned error: %v", err)
+	}
+	if env.ApplyWindow == nil || env.ApplyWindow.String() != "02:00-04:00 UTC" {
+		t.Fatalf("expected apply window, got %+v", env.ApplyWindow)
+	}
+	if env.PreApplyCheck != "SELECT 1 WHERE false" {
+		t.Errorf("expected trimmed pre_apply_check, got %q", env.PreApplyCheck)
+	}
+
+	if _, err := ResolveEnvironment(config, "broken"); err == nil || !strings.Contains(err.Error(), `environment "broken"`) {
+		t.Errorf("expected error naming the environment, got %v", err)
+	}
+}
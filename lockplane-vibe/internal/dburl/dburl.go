@@ -0,0 +1,263 @@
+// Package dburl builds and parses database connection strings.
+//
+// Connection strings built with the Postgres and LibSQL types round-trip
+// through ParsePostgres and ParseLibSQL, giving back the same components. Credentials are escaped,
+// so passwords and tokens may contain any character, and IPv6 hosts are
+// bracketed as required by URL syntax.
+package dburl
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Connection string kinds returned by Detect
+const (
+	KindPostgres = "postgres"
+	KindSQLite   = "sqlite"
+	KindLibSQL   = "libsql"
+)
+
+// Detect returns the kind of database a connection string points at, or ""
+// when it is not recognised as a connection string
+func Detect(connStr string) string {
+	lower := strings.ToLower(strings.TrimSpace(connStr))
+
+	switch {
+	case strings.HasPrefix(lower, "postgres://"), strings.HasPrefix(lower, "postgresql://"):
+		return KindPostgres
+	case strings.HasPrefix(lower, "libsql://"):
+		return KindLibSQL
+	case strings.HasPrefix(lower, "sqlite://"),
+		strings.HasPrefix(lower, "file:"),
+		strings.HasSuffix(lower, ".db"),
+		strings.HasSuffix(lower, ".sqlite"),
+		strings.HasSuffix(lower, ".sqlite3"),
+		lower == ":memory:":
+		return KindSQLite
+	default:
+		return ""
+	}
+}
+
+// IsLocalHost reports whether host refers to the local machine
+func IsLocalHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1" || IsSocketHost(host)
+}
+
+// IsSocketHost reports whether host is a Unix socket directory (an absolute
+// path such as /var/run/postgresql) rather than a network host
+func IsSocketHost(host string) bool {
+	return strings.HasPrefix(host, "/")
+}
+
+// SocketPath returns the PostgreSQL Unix socket file in dir for port
+// (default 5432), e.g. /var/run/postgresql/.s.PGSQL.5432
+func SocketPath(dir, port string) string {
+	if port == "" {
+		port = "5432"
+	}
+	return path.Join(dir, ".s.PGSQL."+port)
+}
+
+// Postgres holds the components of a PostgreSQL connection URL
+type Postgres struct {
+	User     string
+	Password string
+	Host     string // Hostname, IP address (IPv6 without brackets) or Unix socket directory
+	Port     string // Empty for the driver default (5432)
+	Database string
+	SSLMode  string     // Empty for DefaultSSLMode(Host)
+	Params   url.Values // Other query parameters, e.g. connect_timeout
+}
+
+// DefaultSSLMode returns the sslmode used when none is given: disable for
+// local hosts, require for everything else
+func DefaultSSLMode(host string) string {
+	if IsLocalHost(host) {
+		return "disable"
+	}
+	return "require"
+}
+
+// String formats the connection URL, always including sslmode. A Unix
+// socket directory is passed in the host (and port) query parameters, as in
+// postgresql://user@/db?host=/var/run/postgresql.
+func (p Postgres) String() string {
+	host := p.Host
+	if IsSocketHost(host) {
+		host = ""
+	} else if p.Port != "" {
+		host = net.JoinHostPort(p.Host, p.Port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	u := url.URL{Scheme: "postgresql", Host: host, Path: "/" + p.Database}
+	if p.Password != "" {
+		u.User = url.UserPassword(p.User, p.Password)
+	} else if p.User != "" {
+		u.User = url.User(p.User)
+	}
+
+	query := url.Values{}
+	for key, values := range p.Params {
+		query[key] = append([]string(nil), values...)
+	}
+	sslMode := p.SSLMode
+	if sslMode == "" {
+		sslMode = DefaultSSLMode(p.Host)
+	}
+	query.Set("sslmode", sslMode)
+	if IsSocketHost(p.Host) {
+		query.Set("host", p.Host)
+		if p.Port != "" {
+			query.Set("port", p.Port)
+		}
+	}
+	// Slashes are valid in a query; keeping them makes socket paths readable
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "%2F", "/")
+
+	return u.String()
+}
+
+// ParsePostgres parses a postgres:// or postgresql:// URL. Missing parts are
+// left empty; callers decide which ones are required.
+func ParsePostgres(connStr string) (Postgres, error) {
+	var p Postgres
+	if Detect(connStr) != KindPostgres {
+		return p, fmt.Errorf("connection string must start with postgres:// or postgresql://")
+	}
+
+	u, err := url.Parse(strings.TrimSpace(connStr))
+	if err != nil {
+		return p, fmt.Errorf("invalid connection string format: %w", err)
+	}
+
+	if u.User != nil {
+		p.User = u.User.Username()
+		p.Password, _ = u.User.Password()
+	}
+	p.Host = u.Hostname()
+	p.Port = u.Port()
+	p.Database = strings.TrimPrefix(u.Path, "/")
+
+	query := u.Query()
+	p.SSLMode = query.Get("sslmode")
+	query.Del("sslmode")
+	// Unix socket connections leave the URL host empty
+	if p.Host == "" && query.Get("host") != "" {
+		p.Host = query.Get("host")
+		p.Port = query.Get("port")
+		query.Del("host")
+		query.Del("port")
+	}
+	if len(query) > 0 {
+		p.Params = query
+	}
+
+	return p, nil
+}
+
+// SQLite builds a SQLite connection string for a file path. Relative paths
+// are prefixed with ./ so they are never mistaken for URLs; absolute and
+// dot-relative paths, with either separator on Windows, are kept as given.
+func SQLite(path string) string {
+	switch {
+	case path == "", path == ":memory:",
+		strings.HasPrefix(path, "sqlite://"), strings.HasPrefix(path, "file:"),
+		strings.HasPrefix(path, "./"), strings.HasPrefix(path, "../"), strings.HasPrefix(path, "/"),
+		filepath.IsAbs(path), strings.HasPrefix(path, "."+string(filepath.Separator)), strings.HasPrefix(path, ".."+string(filepath.Separator)):
+		return path
+	default:
+		return "./" + path
+	}
+}
+
+// SQLitePath returns the file path of a SQLite connection string, removing
+// any sqlite:// or file: prefix and query parameters
+func SQLitePath(connStr string) string {
+	for _, prefix := range []string{"sqlite://", "file:"} {
+		if strings.HasPrefix(connStr, prefix) {
+			path := strings.TrimPrefix(connStr, prefix)
+			if idx := strings.Index(path, "?"); idx >= 0 {
+				path = path[:idx]
+			}
+			return path
+		}
+	}
+	return connStr
+}
+
+// LibSQL holds the components of a libSQL/Turso connection string
+type LibSQL struct {
+	URL       string // Database URL without the auth token
+	AuthToken string
+}
+
+// String formats the URL with the auth token as the authToken query parameter
+func (l LibSQL) String() string {
+	if l.AuthToken == "" {
+		return l.URL
+	}
+
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return fmt.Sprintf("%s?authToken=%s", l.URL, url.QueryEscape(l.AuthToken))
+	}
+	query := u.Query()
+	query.Set("authToken", l.AuthToken)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ParseLibSQL splits a libSQL connection string into its URL and auth token
+func ParseLibSQL(connStr string) (LibSQL, error) {
+	u, err := url.Parse(strings.TrimSpace(connStr))
+	if err != nil {
+		return LibSQL{}, fmt.Errorf("invalid database URL: %w", err)
+	}
+	if u.Host == "" {
+		return LibSQL{}, fmt.Errorf("database URL is missing a host")
+	}
+
+	query := u.Query()
+	l := LibSQL{AuthToken: query.Get("authToken")}
+	query.Del("authToken")
+	u.RawQuery = query.Encode()
+	l.URL = u.String()
+	return l, nil
+}
+
+// redacted replaces credentials removed by Redact
+const redacted = "xxxxx"
+
+// Redact hides the password and auth token of a connection string so it can
+// be printed. SQLite paths and strings that don't parse are returned as is.
+func Redact(connStr string) string {
+	kind := Detect(connStr)
+	if kind != KindPostgres && kind != KindLibSQL {
+		return connStr
+	}
+	u, err := url.Parse(strings.TrimSpace(connStr))
+	if err != nil {
+		return connStr
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+	query := u.Query()
+	for _, key := range []string{"password", "authToken"} {
+		if query.Has(key) {
+			query.Set(key, redacted)
+		}
+	}
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "%2F", "/")
+	return u.String()
+}
@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/tracing"
+)
+
+// BatchProgress reports how far a batched update step has got
+type BatchProgress struct {
+	Step    int   // 1-based step number
+	Batches int   // Batches committed so far
+	Rows    int64 // Rows updated so far
+	Done    bool  // The last batch was committed
+}
+
+// batchExecer is satisfied by *sql.DB and *sql.Conn, which commit each
+// statement on its own
+type batchExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// execBatchedUpdate runs the batched update of a step on conn and records it
+// like execStepSQL records a step's statements
+func execBatchedUpdate(ctx context.Context, conn batchExecer, driver database.Driver, span tracing.Span, i int, step planner.PlanStep, verbose bool, result *planner.ExecutionResult, opts ApplyOptions) error {
+	report := func(progress BatchProgress) {
+		progress.Step = i + 1
+		if verbose {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    Batch %d: %d row(s) updated\n", progress.Batches, progress.Rows)
+		}
+		if opts.OnBatch != nil {
+			opts.OnBatch(progress)
+		}
+	}
+
+	start := time.Now()
+	if err := runBatchedUpdate(ctx, conn, driver, *step.Batch, step.Batch.Pause(), report); err != nil {
+		recordSpanError(span, err)
+		result.Errors = append(result.Errors, fmt.Sprintf("step %d (%s): %v", i+1, step.Description, err))
+		return fmt.Errorf("step %d failed: %w", i+1, err)
+	}
+	if opts.Profile {
+		result.Profile = append(result.Profile, planner.StatementProfile{
+			Step:        i + 1,
+			Statement:   1,
+			Description: step.Description,
+			SQL:         step.Batch.SQL(driver),
+			DurationMS:  float64(time.Since(start).Microseconds()) / 1000,
+		})
+	}
+	if verbose {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "    ✓ Executed successfully\n")
+	}
+	return nil
+}
+
+// runBatchedUpdate updates the rows of batch in ranges of its key, each range
+// holding the next batch.Size() rows that match batch.Where. Each batch
+// commits on its own, and the run sleeps for pause between batches. The last
+// batch has no upper bound, so it also updates rows inserted past the last
+// range while the update ran, and it always runs, so a table without matching
+// rows still checks the statement. report is called after every batch.
+//
+// Cancelling ctx stops the run between batches; the batches already
+// committed stay updated.
+func runBatchedUpdate(ctx context.Context, ex batchExecer, driver database.Driver, batch planner.BatchedUpdate, pause time.Duration, report func(BatchProgress)) error {
+	var progress BatchProgress
+	var last any
+	key := driver.QuoteIdentifier(batch.Key)
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("batched update of %s stopped after %d batch(es) and %d row(s): %w", batch.Table, progress.Batches, progress.Rows, err)
+		}
+
+		upper, found, err := nextBatchBound(ctx, ex, driver, batch, last)
+		if err != nil {
+			return err
+		}
+
+		var args []any
+		var conditions []string
+		if last != nil {
+			args = append(args, last)
+			conditions = append(conditions, fmt.Sprintf("%s > %s", key, driver.ParameterPlaceholder(len(args))))
+		}
+		if found {
+			args = append(args, upper)
+			conditions = append(conditions, fmt.Sprintf("%s <= %s", key, driver.ParameterPlaceholder(len(args))))
+		}
+		res, err := ex.ExecContext(ctx, batchStatement(driver, batch, conditions), args...)
+		if err != nil {
+			return fmt.Errorf("batch %d of %s failed after %d row(s): %w", progress.Batches+1, batch.Table, progress.Rows, err)
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count updated rows: %w", err)
+		}
+
+		progress.Batches++
+		progress.Rows += rows
+		progress.Done = !found
+		if report != nil {
+			report(progress)
+		}
+		if !found {
+			return nil
+		}
+		last = upper
+
+		if pause > 0 {
+			timer := time.NewTimer(pause)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// nextBatchBound returns the key of the last row of the batch after last (nil
+// for the first batch). found is false when fewer than a full batch of rows
+// remain.
+func nextBatchBound(ctx context.Context, ex batchExecer, driver database.Driver, batch planner.BatchedUpdate, last any) (any, bool, error) {
+	var args []any
+	var conditions []string
+	key := driver.QuoteIdentifier(batch.Key)
+	if last != nil {
+		args = append(args, last)
+		conditions = append(conditions, fmt.Sprintf("%s > %s", key, driver.ParameterPlaceholder(1)))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT 1 OFFSET %d",
+		key, database.QuoteQualifiedName(batch.Table, driver.QuoteIdentifier), batchWhere(batch, conditions), key, batch.Size()-1)
+
+	var bound any
+	err := ex.QueryRowContext(ctx, query, args...).Scan(&bound)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find the next batch of %s: %w", batch.Table, err)
+	}
+	// Drivers return text keys as bytes, which would be sent back as bytea
+	if b, ok := bound.([]byte); ok {
+		bound = string(b)
+	}
+	return bound, true, nil
+}
+
+// batchStatement returns the UPDATE of one batch
+func batchStatement(driver database.Driver, batch planner.BatchedUpdate, conditions []string) string {
+	return fmt.Sprintf("UPDATE %s SET %s%s", database.QuoteQualifiedName(batch.Table, driver.QuoteIdentifier), batch.Set, batchWhere(batch, conditions))
+}
+
+// batchWhere returns the WHERE clause of the key range conditions and the
+// update's own condition
+func batchWhere(batch planner.BatchedUpdate, conditions []string) string {
+	if batch.Where != "" {
+		conditions = append(conditions, "("+batch.Where+")")
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
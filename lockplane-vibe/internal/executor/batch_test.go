@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/testutil"
+)
+
+// seedBatchTable creates a SQLite table with rows ids 1..n and a NULL status
+func seedBatchTable(t *testing.T, tdb *testutil.TestDB, n int) {
+	t.Helper()
+	if _, err := tdb.DB.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, status TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 1; i <= n; i++ {
+		if _, err := tdb.DB.Exec(fmt.Sprintf("INSERT INTO items (id) VALUES (%d)", i)); err != nil {
+			t.Fatalf("Failed to insert row: %v", err)
+		}
+	}
+}
+
+func countRows(t *testing.T, tdb *testutil.TestDB, where string) int {
+	t.Helper()
+	var count int
+	if err := tdb.DB.QueryRow("SELECT COUNT(*) FROM items WHERE " + where).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	return count
+}
+
+func TestApplyPlan_BatchedUpdate(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "sqlite")
+	defer tdb.Close()
+	seedBatchTable(t, tdb, 25)
+
+	step := planner.BatchedUpdateStep(sqlite.NewDriver(), "Mark items", planner.BatchedUpdate{
+		Table: "items", Key: "id", Set: "status = 'done'", Where: "id <> 7", BatchSize: 10,
+	})
+	plan := &planner.Plan{Steps: []planner.PlanStep{step}}
+
+	var progress []BatchProgress
+	opts := ApplyOptions{OnBatch: func(p BatchProgress) { progress = append(progress, p) }}
+	result, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false, opts)
+	if err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	if result.StepsApplied != 1 {
+		t.Errorf("Expected 1 step applied, got %d", result.StepsApplied)
+	}
+	if got := countRows(t, tdb, "status = 'done'"); got != 24 {
+		t.Errorf("Expected 24 updated rows, got %d", got)
+	}
+	if got := countRows(t, tdb, "id = 7 AND status IS NULL"); got != 1 {
+		t.Error("Expected the row excluded by where to stay unchanged")
+	}
+
+	// 24 matching rows make two full batches and a last one of 4
+	if len(progress) != 3 {
+		t.Fatalf("Expected 3 batches, got %+v", progress)
+	}
+	last := progress[2]
+	if last.Step != 1 || last.Batches != 3 || last.Rows != 24 || !last.Done {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+	if progress[0].Rows != 10 || progress[0].Done {
+		t.Errorf("Unexpected first progress: %+v", progress[0])
+	}
+}
+
+func TestApplyPlan_BatchedUpdateOnEmptyTableChecksStatement(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "sqlite")
+	defer tdb.Close()
+	seedBatchTable(t, tdb, 0)
+
+	step := planner.BatchedUpdateStep(sqlite.NewDriver(), "Mark items", planner.BatchedUpdate{Table: "items", Key: "id", Set: "missing = 1"})
+	plan := &planner.Plan{Steps: []planner.PlanStep{step}}
+	if _, err := ApplyPlan(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false); err == nil {
+		t.Error("Expected the update of an unknown column to fail even without rows")
+	}
+}
+
+func TestRunBatchedUpdate_Cancelled(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "sqlite")
+	defer tdb.Close()
+	seedBatchTable(t, tdb, 30)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batch := planner.BatchedUpdate{Table: "items", Key: "id", Set: "status = 'done'", BatchSize: 10}
+	err := runBatchedUpdate(ctx, tdb.DB, tdb.Driver, batch, 0, func(p BatchProgress) {
+		if p.Batches == 1 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to stop with context.Canceled, got %v", err)
+	}
+	// The first batch was committed before the cancellation
+	if got := countRows(t, tdb, "status = 'done'"); got != 10 {
+		t.Errorf("Expected the committed batch to stay updated, got %d rows", got)
+	}
+}
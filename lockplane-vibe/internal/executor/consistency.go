@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+)
+
+// Default polling settings for WaitForSchemaConsistency
+const (
+	DefaultConsistencyTimeout = 10 * time.Second
+	defaultInitialBackoff     = 100 * time.Millisecond
+	defaultMaxBackoff         = 2 * time.Second
+)
+
+// SchemaFingerprinter returns a fingerprint that changes whenever the database schema changes
+type SchemaFingerprinter interface {
+	SchemaFingerprint(ctx context.Context) (string, error)
+}
+
+// SQLiteMasterFingerprinter fingerprints a SQLite/libSQL database by hashing sqlite_master
+type SQLiteMasterFingerprinter struct {
+	DB *sql.DB
+}
+
+// SchemaFingerprint hashes every object definition in sqlite_master
+func (f *SQLiteMasterFingerprinter) SchemaFingerprint(ctx context.Context) (string, error) {
+	rows, err := f.DB.QueryContext(ctx, `
+		SELECT type, name, tbl_name, COALESCE(sql, '')
+		FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%'
+		ORDER BY type, name
+	`)
+	if err != nil {
+		return "", fmt.Errorf("failed to read sqlite_master: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	h := sha256.New()
+	for rows.Next() {
+		var objType, name, tblName, definition string
+		if err := rows.Scan(&objType, &name, &tblName, &definition); err != nil {
+			return "", fmt.Errorf("failed to scan sqlite_master: %w", err)
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\n", objType, name, tblName, definition)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read sqlite_master: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SchemaHashFingerprinter fingerprints a database by introspecting it and
+// computing its schema hash, so the fingerprint can be compared to the target
+// hash a plan records
+type SchemaHashFingerprinter struct {
+	DB     *sql.DB
+	Driver database.Driver
+}
+
+// SchemaFingerprint returns the schema hash of the introspected database
+func (f *SchemaHashFingerprinter) SchemaFingerprint(ctx context.Context) (string, error) {
+	current, err := f.Driver.IntrospectSchema(ctx, f.DB)
+	if err != nil {
+		return "", fmt.Errorf("failed to introspect schema: %w", err)
+	}
+	return schema.ComputeSchemaHash(current)
+}
+
+// dataStatementKeywords start statements that change data but not the schema
+var dataStatementKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "SELECT": true, "WITH": true,
+}
+
+// PlanChangesSchema reports whether any statement of plan may change the
+// schema. Plans of only data changes and comments leave the schema as it was,
+// so there is nothing to wait for.
+func PlanChangesSchema(plan *planner.Plan) bool {
+	for _, step := range plan.Steps {
+		for _, stmt := range step.SQL {
+			if keyword := leadingKeyword(stmt); keyword != "" && !dataStatementKeywords[keyword] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leadingKeyword returns the first word of stmt in upper case, skipping
+// whitespace and comments, or "" when stmt is only whitespace and comments.
+// A statement starting with anything but a word returns its first character.
+func leadingKeyword(stmt string) string {
+	for i := 0; i < len(stmt); {
+		end := sqlsplit.SkipToken(stmt, i)
+		if unicode.IsSpace(rune(stmt[i])) || strings.HasPrefix(stmt[i:], "--") || strings.HasPrefix(stmt[i:], "/*") {
+			i = end
+			continue
+		}
+		j := i
+		for j < len(stmt) && unicode.IsLetter(rune(stmt[j])) {
+			j++
+		}
+		if j == i {
+			return stmt[i:end]
+		}
+		return strings.ToUpper(stmt[i:j])
+	}
+	return ""
+}
+
+// ConsistencyOptions controls how long to wait for schema changes to become visible
+type ConsistencyOptions struct {
+	Timeout        time.Duration // Total time to wait (default 10s)
+	InitialBackoff time.Duration // First delay between polls (default 100ms)
+	MaxBackoff     time.Duration // Upper bound for the exponential backoff (default 2s)
+}
+
+// ConsistencyResult describes the outcome of WaitForSchemaConsistency
+type ConsistencyResult struct {
+	Consistent  bool          // The expected schema became visible before the deadline
+	Attempts    int           // Number of fingerprint reads
+	Elapsed     time.Duration // Time spent waiting
+	Fingerprint string        // Last fingerprint read
+}
+
+// WaitForSchemaConsistency polls the schema fingerprint until it equals the
+// fingerprint expected after the migration, using exponential backoff. A
+// fingerprint that merely differs from the one before the migration could be
+// a replica that has seen only part of it.
+//
+// Turso serves reads from replicas that can briefly lag behind an acknowledged
+// write, so introspecting right after DDL may still return the old schema.
+// Reaching the deadline is not an error: the write was acknowledged, the replica
+// is just not consistent yet, and Consistent is false in the result.
+func WaitForSchemaConsistency(ctx context.Context, f SchemaFingerprinter, expected string, opts ConsistencyOptions) (*ConsistencyResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultConsistencyTimeout
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+
+	start := time.Now()
+	deadline := start.Add(opts.Timeout)
+	backoff := opts.InitialBackoff
+	result := &ConsistencyResult{}
+	var lastErr error
+
+	for {
+		fingerprint, err := f.SchemaFingerprint(ctx)
+		result.Attempts++
+		if err == nil {
+			result.Fingerprint = fingerprint
+			if fingerprint == expected {
+				result.Consistent = true
+				result.Elapsed = time.Since(start)
+				return result, nil
+			}
+		} else {
+			lastErr = err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			result.Elapsed = time.Since(start)
+			return result, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	if result.Fingerprint == "" && lastErr != nil {
+		// Never managed to read the schema at all
+		return result, fmt.Errorf("failed to read schema fingerprint: %w", lastErr)
+	}
+	return result, nil
+}
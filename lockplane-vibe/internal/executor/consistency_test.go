@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	_ "modernc.org/sqlite"
+)
+
+// laggingReplica simulates a Turso replica that keeps serving the old schema
+// for a number of reads after the write was acknowledged, then part of the
+// new schema for a number of reads.
+type laggingReplica struct {
+	staleReads   int
+	partialReads int
+	before       string
+	partial      string
+	after        string
+	reads        int
+	err          error
+}
+
+func (r *laggingReplica) SchemaFingerprint(ctx context.Context) (string, error) {
+	r.reads++
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.reads <= r.staleReads {
+		return r.before, nil
+	}
+	if r.reads <= r.staleReads+r.partialReads {
+		return r.partial, nil
+	}
+	return r.after, nil
+}
+
+func fastConsistencyOptions(timeout time.Duration) ConsistencyOptions {
+	return ConsistencyOptions{
+		Timeout:        timeout,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+	}
+}
+
+func TestWaitForSchemaConsistency_WaitsOutReplicaLag(t *testing.T) {
+	replica := &laggingReplica{staleReads: 3, before: "old", after: "new"}
+
+	result, err := WaitForSchemaConsistency(context.Background(), replica, "new", fastConsistencyOptions(time.Second))
+	if err != nil {
+		t.Fatalf("WaitForSchemaConsistency returned error: %v", err)
+	}
+	if !result.Consistent {
+		t.Fatal("expected schema to become consistent")
+	}
+	if result.Attempts != 4 {
+		t.Errorf("expected 4 reads (3 stale + 1 fresh), got %d", result.Attempts)
+	}
+	if result.Fingerprint != "new" {
+		t.Errorf("expected new fingerprint, got %q", result.Fingerprint)
+	}
+}
+
+func TestWaitForSchemaConsistency_WaitsOutPartialSchema(t *testing.T) {
+	replica := &laggingReplica{staleReads: 1, partialReads: 2, before: "old", partial: "half", after: "new"}
+
+	result, err := WaitForSchemaConsistency(context.Background(), replica, "new", fastConsistencyOptions(time.Second))
+	if err != nil {
+		t.Fatalf("WaitForSchemaConsistency returned error: %v", err)
+	}
+	if !result.Consistent || result.Attempts != 4 {
+		t.Errorf("expected a schema that only differs from the old one not to count, got %+v", result)
+	}
+}
+
+func TestWaitForSchemaConsistency_DeadlineIsNotAnError(t *testing.T) {
+	replica := &laggingReplica{staleReads: 1 << 30, before: "old", after: "new"}
+
+	result, err := WaitForSchemaConsistency(context.Background(), replica, "new", fastConsistencyOptions(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected no error when the deadline is hit, got %v", err)
+	}
+	if result.Consistent {
+		t.Fatal("expected replica to still be inconsistent")
+	}
+	if result.Attempts < 2 {
+		t.Errorf("expected several polls before giving up, got %d", result.Attempts)
+	}
+}
+
+func TestWaitForSchemaConsistency_ReadErrors(t *testing.T) {
+	replica := &laggingReplica{err: errors.New("connection reset")}
+
+	_, err := WaitForSchemaConsistency(context.Background(), replica, "new", fastConsistencyOptions(10*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected error when the schema could never be read")
+	}
+}
+
+func TestWaitForSchemaConsistency_ContextCancelled(t *testing.T) {
+	replica := &laggingReplica{staleReads: 1 << 30, before: "old", after: "new"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WaitForSchemaConsistency(ctx, replica, "new", fastConsistencyOptions(time.Second))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSchemaHashFingerprinter(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+
+	schemaPath := filepath.Join(t.TempDir(), "schema.lp.sql")
+	ddl := "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL DEFAULT 'x');\nCREATE UNIQUE INDEX users_email ON users (email);\n"
+	if err := os.WriteFile(schemaPath, []byte(ddl), 0o600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	desired, err := LoadSchemaOrIntrospect(schemaPath)
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	driver := sqlite.NewDriver()
+	current := &database.Schema{}
+	plan, err := planner.GeneratePlanWithHash(schema.DiffSchemas(current, desired), current, driver)
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	target, err := schema.ComputeSchemaHash(desired)
+	if err != nil {
+		t.Fatalf("Failed to hash schema: %v", err)
+	}
+
+	f := &SchemaHashFingerprinter{DB: db, Driver: driver}
+	if before, err := f.SchemaFingerprint(ctx); err != nil || before == target {
+		t.Fatalf("Expected the empty database not to match the target (%v)", err)
+	}
+	if _, err := ApplyPlan(ctx, db, plan, nil, current, driver, false); err != nil {
+		t.Fatalf("Failed to apply plan: %v", err)
+	}
+	if after, err := f.SchemaFingerprint(ctx); err != nil || after != target {
+		t.Errorf("Expected the applied schema to match the plan's target hash (%v)", err)
+	}
+}
+
+func TestPlanChangesSchema(t *testing.T) {
+	tests := []struct {
+		sql  []string
+		want bool
+	}{
+		{[]string{"UPDATE users SET status = 'active'", "-- nothing to do", "  /* note */ INSERT INTO audit VALUES (1)"}, false},
+		{[]string{"WITH moved AS (DELETE FROM users RETURNING *) SELECT count(*) FROM moved"}, false},
+		{[]string{"UPDATE users SET status = 'active'", "ALTER TABLE users ADD COLUMN age integer"}, true},
+		{[]string{"-- comment\nCREATE INDEX users_email ON users (email)"}, true},
+	}
+	for _, tt := range tests {
+		plan := &planner.Plan{Steps: []planner.PlanStep{{Description: "step", SQL: tt.sql}}}
+		if got := PlanChangesSchema(plan); got != tt.want {
+			t.Errorf("PlanChangesSchema(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteMasterFingerprinter(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	f := &SQLiteMasterFingerprinter{DB: db}
+	ctx := context.Background()
+
+	before, err := f.SchemaFingerprint(ctx)
+	if err != nil {
+		t.Fatalf("SchemaFingerprint returned error: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	after, err := f.SchemaFingerprint(ctx)
+	if err != nil {
+		t.Fatalf("SchemaFingerprint returned error: %v", err)
+	}
+	if before == after {
+		t.Error("expected fingerprint to change after DDL")
+	}
+}
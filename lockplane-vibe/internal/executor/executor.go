@@ -7,39 +7,39 @@ package executor
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/database/postgres"
 	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/dburl"
 	"github.com/lockplane/lockplane/internal/introspect"
 	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/lockplane/lockplane/internal/sqliteutil"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/tracing"
 )
 
-// DetectDriver detects the database driver type from a connection string.
-func DetectDriver(connString string) string {
-	connString = strings.ToLower(connString)
-
-	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
-		return "postgres"
-	}
+var (
+	// ErrSourceHashMismatch is returned by ApplyPlan when the database no longer
+	// matches the schema the plan was generated from
+	ErrSourceHashMismatch = errors.New("source schema hash mismatch")
 
-	if strings.HasPrefix(connString, "libsql://") {
-		return "libsql"
-	}
+	// ErrDryRunFailed is returned by ApplyPlan when the plan fails on the shadow
+	// database, before the target database is touched
+	ErrDryRunFailed = errors.New("dry-run validation failed")
+)
 
-	if strings.HasPrefix(connString, "sqlite://") ||
-		strings.HasPrefix(connString, "file:") ||
-		strings.HasSuffix(connString, ".db") ||
-		strings.HasSuffix(connString, ".sqlite") ||
-		strings.HasSuffix(connString, ".sqlite3") ||
-		connString == ":memory:" {
-		return "sqlite"
+// DetectDriver detects the database driver type from a connection string.
+func DetectDriver(connString string) string {
+	if kind := dburl.Detect(connString); kind != "" {
+		return kind
 	}
 
 	// Default to postgres for backward compatibility
@@ -92,6 +92,22 @@ func LoadSchemaFromConnectionString(connStr string) (*database.Schema, error) {
 // If schemas is provided and non-empty, introspects those specific schemas (PostgreSQL only).
 // If schemas is nil or empty, uses default behavior (current_schema() for PostgreSQL).
 func LoadSchemaFromConnectionStringWithSchemas(connStr string, schemas []string) (*database.Schema, error) {
+	return LoadSchemaFromConnectionStringContext(context.Background(), connStr, schemas)
+}
+
+// LoadSchemaFromConnectionStringContext is LoadSchemaFromConnectionStringWithSchemas
+// with a caller-provided context, so introspection joins the caller's trace.
+func LoadSchemaFromConnectionStringContext(ctx context.Context, connStr string, schemas []string) (*database.Schema, error) {
+	driverType, driver, db, err := openIntrospectionTarget(ctx, connStr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	return introspectConnected(ctx, db, driver, driverType, schemas)
+}
+
+// openIntrospectionTarget connects to the database connStr points at
+func openIntrospectionTarget(ctx context.Context, connStr string) (string, database.Driver, *sql.DB, error) {
 	// Detect database driver from connection string
 	driverType := DetectDriver(connStr)
 
@@ -99,13 +115,13 @@ func LoadSchemaFromConnectionStringWithSchemas(connStr string, schemas []string)
 	// Also offer to create shadow database
 	if driverType == "sqlite" || driverType == "sqlite3" {
 		if err := sqliteutil.EnsureSQLiteDatabaseWithShadow(connStr, "target", false, true); err != nil {
-			return nil, err
+			return "", nil, nil, err
 		}
 	}
 
 	driver, err := NewDriver(driverType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create database driver: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to create database driver: %w", err)
 	}
 
 	// Get the SQL driver name (use detected type, not driver.Name())
@@ -113,17 +129,20 @@ func LoadSchemaFromConnectionStringWithSchemas(connStr string, schemas []string)
 
 	db, err := sql.Open(sqlDriverName, connStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer func() { _ = db.Close() }()
 
-	ctx := context.Background()
 	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		_ = db.Close()
+		return "", nil, nil, fmt.Errorf("failed to ping database: %w", err)
 	}
+	return driverType, driver, db, nil
+}
 
+// introspectConnected introspects schemas of a connected database
+func introspectConnected(ctx context.Context, db *sql.DB, driver database.Driver, driverType string, schemas []string) (*database.Schema, error) {
 	// Use multi-schema introspection if schemas are specified
-	dbSchema, err := driver.IntrospectSchemas(ctx, db, schemas)
+	dbSchema, err := IntrospectSchemas(ctx, db, driver, schemas)
 	if err != nil {
 		return nil, fmt.Errorf("failed to introspect schema: %w", err)
 	}
@@ -132,6 +151,34 @@ func LoadSchemaFromConnectionStringWithSchemas(connStr string, schemas []string)
 	return dbSchema, nil
 }
 
+// IntrospectSchemas introspects the given schemas (or the default schema when
+// schemas is empty), recording a span when tracing is enabled.
+func IntrospectSchemas(ctx context.Context, db *sql.DB, driver database.Driver, schemas []string) (*database.Schema, error) {
+	return IntrospectTables(ctx, db, driver, schemas, nil)
+}
+
+// IntrospectTables introspects like IntrospectSchemas, limited to tables
+// matching one of the glob patterns (all tables when patterns is empty).
+func IntrospectTables(ctx context.Context, db *sql.DB, driver database.Driver, schemas []string, patterns []string) (*database.Schema, error) {
+	ctx, span := tracing.Start(ctx, spanIntrospect, tracing.String(attrDBSystem, driver.Name()))
+	defer span.End()
+	if len(schemas) > 0 {
+		span.SetAttributes(tracing.String(attrSchemas, strings.Join(schemas, ",")))
+	}
+
+	if len(patterns) > 0 {
+		span.SetAttributes(tracing.String(attrTableFilter, strings.Join(patterns, ",")))
+	}
+
+	dbSchema, err := driver.IntrospectTables(ctx, db, schemas, patterns)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(tracing.Int(attrTables, len(dbSchema.Tables)))
+	return dbSchema, nil
+}
+
 // LoadSchemaOrIntrospect loads a schema from a file/directory or introspects from a database connection string.
 func LoadSchemaOrIntrospect(pathOrConnStr string) (*database.Schema, error) {
 	return LoadSchemaOrIntrospectWithOptions(pathOrConnStr, nil)
@@ -148,128 +195,428 @@ func LoadSchemaOrIntrospectWithOptions(pathOrConnStr string, opts *schema.Schema
 	return schema.LoadSchemaWithOptions(pathOrConnStr, opts)
 }
 
+// LoadSchemaFromFS loads a schema directory, .lp.sql file or JSON schema from
+// fsys, such as an embed.FS that ships an application's schema in its binary.
+// dir is a slash-separated path in fsys ("." for the root).
+func LoadSchemaFromFS(fsys fs.FS, dir string, opts *schema.SchemaLoadOptions) (*database.Schema, error) {
+	return schema.LoadSchemaFromFS(fsys, dir, opts)
+}
+
+// ApplyOptions controls optional plan execution behavior
+type ApplyOptions struct {
+	// StatementTimeout limits how long each statement may run (0 = keep the
+	// database or role setting). Steps tagged LongRunning always run with
+	// statement_timeout disabled. Ignored by drivers without statement timeouts.
+	StatementTimeout time.Duration
+	// StartStep skips the first StartStep steps, which an interrupted run
+	// already committed. The source hash is not checked when it is set, since
+	// the database is between the plan's source and target schemas.
+	StartStep int
+	// Checkpoint is called with the number of committed steps whenever the
+	// applied steps are committed ahead of or after a non-transactional step.
+	// A failing checkpoint stops the apply.
+	Checkpoint func(stepsApplied int) error
+	// AllowDirtyShadow validates against the shadow database even when
+	// cleanup leaves objects behind, with a warning instead of an error
+	AllowDirtyShadow bool
+	// Profile records the execution time of every statement in the result,
+	// and the query plan of data-changing statements
+	Profile bool
+	// OnBatch is called after every batch a batched update step commits
+	OnBatch func(BatchProgress)
+}
+
 // ApplyPlan executes a migration plan on the target database, with optional shadow DB validation.
+//
+// When tracing is enabled, the run is recorded as a span with a child span per step.
 func ApplyPlan(ctx context.Context, db *sql.DB, plan *planner.Plan, shadowDB *sql.DB, currentSchema *database.Schema, driver database.Driver, verbose bool) (*planner.ExecutionResult, error) {
+	return ApplyPlanWithOptions(ctx, db, plan, shadowDB, currentSchema, driver, verbose, ApplyOptions{})
+}
+
+// ApplyPlanWithOptions executes a migration plan like ApplyPlan, with extra execution options
+func ApplyPlanWithOptions(ctx context.Context, db *sql.DB, plan *planner.Plan, shadowDB *sql.DB, currentSchema *database.Schema, driver database.Driver, verbose bool, opts ApplyOptions) (*planner.ExecutionResult, error) {
+	ctx, span := tracing.Start(ctx, spanApplyPlan,
+		tracing.String(attrDBSystem, driver.Name()),
+		tracing.Int(attrPlanSteps, len(plan.Steps)),
+		tracing.Bool(attrShadowDB, shadowDB != nil),
+	)
+	defer span.End()
+
+	result, err := applyPlan(ctx, db, plan, shadowDB, currentSchema, driver, verbose, opts)
+	span.SetAttributes(tracing.Int(attrStepsApplied, result.StepsApplied))
+	recordSpanError(span, err)
+	return result, err
+}
+
+func applyPlan(ctx context.Context, db *sql.DB, plan *planner.Plan, shadowDB *sql.DB, currentSchema *database.Schema, driver database.Driver, verbose bool, opts ApplyOptions) (*planner.ExecutionResult, error) {
 	result := &planner.ExecutionResult{
 		Success: false,
 		Errors:  []string{},
 	}
 
 	// Validate source hash if present in plan
-	if plan.SourceHash != "" {
-		currentHash, err := schema.ComputeSchemaHash(currentSchema)
+	if plan.SourceHash != "" && opts.StartStep == 0 {
+		// Accept legacy (version 1) hashes so older plans still verify
+		matches, err := schema.SchemaHashMatches(currentSchema, plan.SourceHash)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("failed to compute current schema hash: %v", err))
 			return result, fmt.Errorf("failed to compute current schema hash: %w", err)
 		}
 
-		if currentHash != plan.SourceHash {
+		if !matches {
+			currentHash, _ := schema.ComputeSchemaHash(currentSchema)
 			errMsg := fmt.Sprintf("source schema hash mismatch: expected %s, got %s", plan.SourceHash, currentHash)
 			result.Errors = append(result.Errors, errMsg)
-			return result, fmt.Errorf("source schema hash mismatch: plan was generated for a different database state")
+			return result, fmt.Errorf("%w: plan was generated for a different database state", ErrSourceHashMismatch)
 		}
 	}
 
+	if opts.StartStep < 0 || opts.StartStep > len(plan.Steps) {
+		return result, fmt.Errorf("cannot start at step %d of a plan with %d steps", opts.StartStep+1, len(plan.Steps))
+	}
+
 	// If shadow DB provided, run dry-run first
 	if shadowDB != nil {
-		if err := DryRunPlan(ctx, shadowDB, plan, currentSchema, driver, verbose); err != nil {
+		remaining := &planner.Plan{Steps: plan.Steps[opts.StartStep:]}
+		if err := DryRunPlanWithOptions(ctx, shadowDB, remaining, currentSchema, driver, verbose, opts); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("dry-run failed: %v", err))
-			return result, fmt.Errorf("dry-run validation failed: %w", err)
+			return result, fmt.Errorf("%w: %w", ErrDryRunFailed, err)
 		}
 	}
 
-	// Execute plan in a transaction
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("failed to begin transaction: %v", err))
-		return result, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
+	// Execute the plan in a transaction. Non-transactional steps split it: the
+	// steps before them are committed first, and a new transaction is started
+	// for the steps after.
+	var tx *sql.Tx
 	defer func() {
-		if !result.Success {
+		if tx != nil && !result.Success {
 			_ = tx.Rollback()
 		}
 	}()
 
+	result.StepsApplied = opts.StartStep
+	committed := opts.StartStep
+	checkpoint := func() error {
+		if opts.Checkpoint == nil {
+			return nil
+		}
+		if err := opts.Checkpoint(committed); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to record checkpoint: %v", err))
+			return fmt.Errorf("failed to record checkpoint after step %d: %w", committed, err)
+		}
+		return nil
+	}
+
 	// Execute each step
-	for i, step := range plan.Steps {
+	for i := opts.StartStep; i < len(plan.Steps); i++ {
+		step := plan.Steps[i]
 		if verbose {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "  [Step %d/%d] %s\n", i+1, len(plan.Steps), step.Description)
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "  [Step %d/%d] %s\n", i+1, len(plan.Steps), step.Description)
 		}
-		// Execute all SQL statements in this step
-		for j, sqlStmt := range step.SQL {
-			trimmedSQL := strings.TrimSpace(sqlStmt)
-			if trimmedSQL == "" || strings.HasPrefix(trimmedSQL, "--") {
-				continue // Skip empty or comment-only statements
-			}
 
-			if verbose {
-				// Show SQL being executed
-				sqlPreview := sqlStmt
-				if len(sqlPreview) > 200 {
-					sqlPreview = sqlPreview[:200] + "..."
+		if planner.RequiresNoTransaction(step) {
+			if tx != nil {
+				err := tx.Commit()
+				tx = nil
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("failed to commit: %v", err))
+					return result, fmt.Errorf("failed to commit transaction: %w", err)
+				}
+				committed = result.StepsApplied
+				if err := checkpoint(); err != nil {
+					return result, err
 				}
-				_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "    SQL: %s\n", sqlPreview)
 			}
-
-			_, err := tx.ExecContext(ctx, sqlStmt)
-			if err != nil {
-				errMsg := fmt.Sprintf("step %d, statement %d/%d (%s) failed: %v",
-					i+1, j+1, len(step.SQL), step.Description, err)
-				result.Errors = append(result.Errors, errMsg)
-				return result, fmt.Errorf("step %d failed: %w", i+1, err)
+			if verbose {
+				_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    ⚠️  Non-transactional step: runs outside the migration transaction\n")
 			}
+			if err := applyNonTransactionalStep(ctx, db, driver, i, step, verbose, result, opts); err != nil {
+				return result, err
+			}
+			result.StepsApplied++
+			committed = result.StepsApplied
+			if err := checkpoint(); err != nil {
+				return result, err
+			}
+			continue
+		}
 
-			if verbose {
-				_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "    ✓ Executed successfully\n")
+		if tx == nil {
+			var err error
+			if tx, err = beginApplyTransaction(ctx, db, driver, opts); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+				return result, err
 			}
 		}
+		if err := applyStep(ctx, tx, db, driver, i, step, verbose, result, opts); err != nil {
+			return result, err
+		}
 		result.StepsApplied++
 	}
 
 	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("failed to commit: %v", err))
-		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	if tx != nil {
+		err := tx.Commit()
+		tx = nil
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to commit: %v", err))
+			return result, fmt.Errorf("failed to commit transaction: %w", err)
+		}
 	}
 
 	result.Success = true
 	return result, nil
 }
 
+// beginApplyTransaction starts a transaction for plan steps, applying the
+// statement timeout
+func beginApplyTransaction(ctx context.Context, db *sql.DB, driver database.Driver, opts ApplyOptions) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if opts.StatementTimeout > 0 && driver.SupportsFeature("STATEMENT_TIMEOUT") {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", opts.StatementTimeout.Milliseconds())); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("failed to set statement_timeout: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// applyStep executes the SQL statements of a single plan step inside tx
+func applyStep(ctx context.Context, tx *sql.Tx, db *sql.DB, driver database.Driver, i int, step planner.PlanStep, verbose bool, result *planner.ExecutionResult, opts ApplyOptions) error {
+	ctx, span := startStepSpan(ctx, db, driver, i, step)
+	defer span.End()
+
+	// Long steps would otherwise be cancelled by a statement_timeout meant for quick ones
+	restoreTimeout := func() error { return nil }
+	if step.LongRunning && driver.SupportsFeature("STATEMENT_TIMEOUT") {
+		restore, err := disableStatementTimeout(ctx, tx)
+		if err != nil {
+			recordSpanError(span, err)
+			result.Errors = append(result.Errors, fmt.Sprintf("step %d (%s): %v", i+1, step.Description, err))
+			return fmt.Errorf("step %d failed: %w", i+1, err)
+		}
+		restoreTimeout = restore
+		if verbose {
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    ⏱  Long-running step: statement_timeout disabled\n")
+		}
+	}
+
+	if err := execStepSQL(ctx, tx, driver, span, i, step, verbose, result, opts.Profile); err != nil {
+		return err
+	}
+
+	if err := restoreTimeout(); err != nil {
+		recordSpanError(span, err)
+		result.Errors = append(result.Errors, fmt.Sprintf("step %d (%s): %v", i+1, step.Description, err))
+		return fmt.Errorf("step %d failed: %w", i+1, err)
+	}
+	return nil
+}
+
+// applyNonTransactionalStep executes a step that cannot run in a transaction
+// on a dedicated connection. Each statement commits on its own. The statement
+// timeout is set for the session and reset afterwards.
+func applyNonTransactionalStep(ctx context.Context, db *sql.DB, driver database.Driver, i int, step planner.PlanStep, verbose bool, result *planner.ExecutionResult, opts ApplyOptions) error {
+	ctx, span := startStepSpan(ctx, db, driver, i, step)
+	defer span.End()
+
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		result.Errors = append(result.Errors, fmt.Sprintf("step %d (%s): %v", i+1, step.Description, err))
+		return fmt.Errorf("step %d failed: %w", i+1, err)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fail(fmt.Errorf("failed to get connection: %w", err))
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Long steps run without a timeout, like in a transaction
+	if driver.SupportsFeature("STATEMENT_TIMEOUT") && (step.LongRunning || opts.StatementTimeout > 0) {
+		timeout := opts.StatementTimeout.Milliseconds()
+		if step.LongRunning {
+			timeout = 0
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET statement_timeout = %d", timeout)); err != nil {
+			return fail(fmt.Errorf("failed to set statement_timeout: %w", err))
+		}
+		defer func() { _, _ = conn.ExecContext(context.WithoutCancel(ctx), "RESET statement_timeout") }()
+	}
+
+	if step.Batch != nil {
+		return execBatchedUpdate(ctx, conn, driver, span, i, step, verbose, result, opts)
+	}
+	return execStepSQL(ctx, conn, driver, span, i, step, verbose, result, opts.Profile)
+}
+
+// stmtExecer is satisfied by *sql.Tx and *sql.Conn
+type stmtExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// execStepSQL executes the SQL statements of a step in order. With profile
+// set, each statement's run time is added to result.Profile.
+func execStepSQL(ctx context.Context, ex stmtExecer, driver database.Driver, span tracing.Span, i int, step planner.PlanStep, verbose bool, result *planner.ExecutionResult, profile bool) error {
+	for j, sqlStmt := range step.SQL {
+		trimmedSQL := strings.TrimSpace(sqlStmt)
+		if trimmedSQL == "" || strings.HasPrefix(trimmedSQL, "--") {
+			continue // Skip empty or comment-only statements
+		}
+
+		if verbose {
+			// Show SQL being executed
+			sqlPreview := sqlStmt
+			if len(sqlPreview) > 200 {
+				sqlPreview = sqlPreview[:200] + "..."
+			}
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    SQL: %s\n", sqlPreview)
+		}
+
+		var explain string
+		if profile {
+			explain = explainStatement(ctx, ex, driver, trimmedSQL)
+		}
+
+		start := time.Now()
+		_, err := ex.ExecContext(ctx, sqlStmt)
+		if err != nil {
+			recordSpanError(span, err)
+			errMsg := fmt.Sprintf("step %d, statement %d/%d (%s) failed: %v",
+				i+1, j+1, len(step.SQL), step.Description, err)
+			result.Errors = append(result.Errors, errMsg)
+			return fmt.Errorf("step %d failed: %w", i+1, err)
+		}
+		if profile {
+			result.Profile = append(result.Profile, planner.StatementProfile{
+				Step:        i + 1,
+				Statement:   j + 1,
+				Description: step.Description,
+				SQL:         trimmedSQL,
+				DurationMS:  float64(time.Since(start).Microseconds()) / 1000,
+				Explain:     explain,
+			})
+		}
+
+		if verbose {
+			_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "    ✓ Executed successfully\n")
+		}
+	}
+	return nil
+}
+
+// disableStatementTimeout turns off statement_timeout for the rest of tx and
+// returns a function that restores the previous setting. The whole plan runs
+// in one transaction, so SET LOCAL alone would also lift the timeout for every
+// later step.
+func disableStatementTimeout(ctx context.Context, tx *sql.Tx) (func() error, error) {
+	var previous string
+	if err := tx.QueryRowContext(ctx, "SELECT current_setting('statement_timeout')").Scan(&previous); err != nil {
+		return nil, fmt.Errorf("failed to read statement_timeout: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+		return nil, fmt.Errorf("failed to disable statement_timeout: %w", err)
+	}
+	return func() error {
+		if _, err := tx.ExecContext(ctx, "SELECT set_config('statement_timeout', $1, true)", previous); err != nil {
+			return fmt.Errorf("failed to restore statement_timeout: %w", err)
+		}
+		return nil
+	}, nil
+}
+
 // DryRunPlan validates a plan by executing it on shadow DB and rolling back.
 func DryRunPlan(ctx context.Context, shadowDB *sql.DB, plan *planner.Plan, currentSchema *database.Schema, driver database.Driver, verbose bool) error {
-	// First, clean up any existing tables in the shadow DB
+	return DryRunPlanWithOptions(ctx, shadowDB, plan, currentSchema, driver, verbose, ApplyOptions{})
+}
+
+// DryRunPlanWithOptions validates a plan like DryRunPlan. Only
+// opts.AllowDirtyShadow applies to the dry run. A shadow database that runs
+// out of resources fails it with ErrShadowLimitExceeded.
+func DryRunPlanWithOptions(ctx context.Context, shadowDB *sql.DB, plan *planner.Plan, currentSchema *database.Schema, driver database.Driver, verbose bool, opts ApplyOptions) error {
+	ctx, span := tracing.Start(ctx, spanDryRunPlan,
+		tracing.String(attrDBSystem, driver.Name()),
+		tracing.Int(attrPlanSteps, len(plan.Steps)),
+	)
+	defer span.End()
+
+	err := ShadowLimitError(dryRunPlan(ctx, shadowDB, plan, currentSchema, driver, verbose, opts.AllowDirtyShadow))
+	recordSpanError(span, err)
+	return err
+}
+
+func dryRunPlan(ctx context.Context, shadowDB *sql.DB, plan *planner.Plan, currentSchema *database.Schema, driver database.Driver, verbose bool, allowDirtyShadow bool) error {
+	// First, clean up any existing objects in the shadow DB
 	if err := CleanupShadowDB(ctx, shadowDB, driver, verbose); err != nil {
-		return fmt.Errorf("failed to clean shadow DB: %w", err)
+		var dirty *DirtyShadowError
+		if !allowDirtyShadow || !errors.As(err, &dirty) {
+			return fmt.Errorf("failed to clean shadow DB: %w", err)
+		}
+		_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "  [Shadow DB] ⚠️  Validating against a dirty shadow database (%d leftover object(s))\n", len(dirty.Leftovers))
 	}
 
 	// Apply the current schema to the shadow DB so it matches the target DB state
 	// This is necessary because migration plans assume the DB is already in the "before" state
 	if verbose {
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "  [Shadow DB] Preparing database to match current state...\n")
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "  [Shadow DB] Preparing database to match current state...\n")
 	}
 	if err := ApplySchemaToDB(ctx, shadowDB, currentSchema, driver, verbose); err != nil {
 		return fmt.Errorf("failed to prepare shadow DB: %w", err)
 	}
 
-	tx, err := shadowDB.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin shadow transaction: %w", err)
-	}
+	// Steps run in a transaction that is rolled back. Non-transactional steps
+	// commit the steps before them and run on their own; the shadow DB is
+	// cleaned before its next use.
+	var tx *sql.Tx
 	defer func() {
-		_ = tx.Rollback() // Always rollback shadow DB changes
+		if tx != nil {
+			_ = tx.Rollback()
+		}
 	}()
 
 	if verbose {
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "  [Shadow DB] Testing migration plan...\n")
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "  [Shadow DB] Testing migration plan...\n")
 	}
 
 	// Execute each step
 	for i, step := range plan.Steps {
 		if verbose {
-			_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "    [Step %d/%d] %s\n", i+1, len(plan.Steps), step.Description)
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "    [Step %d/%d] %s\n", i+1, len(plan.Steps), step.Description)
 		}
+
+		var ex stmtExecer = tx
+		if planner.RequiresNoTransaction(step) {
+			if tx != nil {
+				err := tx.Commit()
+				tx = nil
+				if err != nil {
+					return fmt.Errorf("failed to commit shadow transaction: %w", err)
+				}
+			}
+			ex = shadowDB
+		} else if tx == nil {
+			var err error
+			if tx, err = shadowDB.BeginTx(ctx, nil); err != nil {
+				return fmt.Errorf("failed to begin shadow transaction: %w", err)
+			}
+			ex = tx
+		}
+
+		// Shadow rows are few, so batches run without pauses
+		if step.Batch != nil {
+			if err := runBatchedUpdate(ctx, shadowDB, driver, *step.Batch, 0, nil); err != nil {
+				return fmt.Errorf("shadow DB step %d (%s) failed: %w", i+1, step.Description, err)
+			}
+			if verbose {
+				_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "      ✓ Executed successfully\n")
+			}
+			continue
+		}
+
 		for j, sqlStmt := range step.SQL {
 			trimmedSQL := strings.TrimSpace(sqlStmt)
 			if trimmedSQL == "" || strings.HasPrefix(trimmedSQL, "--") {
@@ -282,76 +629,23 @@ func DryRunPlan(ctx context.Context, shadowDB *sql.DB, plan *planner.Plan, curre
 				if len(sqlPreview) > 200 {
 					sqlPreview = sqlPreview[:200] + "..."
 				}
-				_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "      SQL: %s\n", sqlPreview)
+				_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "      SQL: %s\n", sqlPreview)
 			}
 
-			_, err := tx.ExecContext(ctx, sqlStmt)
+			_, err := ex.ExecContext(ctx, sqlStmt)
 			if err != nil {
 				return fmt.Errorf("shadow DB step %d, statement %d/%d (%s) failed: %w",
 					i+1, j+1, len(step.SQL), step.Description, err)
 			}
 
 			if verbose {
-				_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "      ✓ Executed successfully\n")
+				_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "      ✓ Executed successfully\n")
 			}
 		}
 	}
 
 	if verbose {
-		_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "  [Shadow DB] ✓ Migration test successful\n")
-	}
-
-	return nil
-}
-
-// CleanupShadowDB drops all existing tables from the shadow database.
-func CleanupShadowDB(ctx context.Context, db *sql.DB, driver database.Driver, verbose bool) error {
-	if verbose {
-		_, _ = color.New(color.FgCyan).Fprintf(os.Stderr, "  [Shadow DB] Cleaning up existing tables...\n")
-	}
-
-	// Get list of existing tables
-	tables, err := driver.GetTables(ctx, db)
-	if err != nil {
-		return fmt.Errorf("failed to get tables: %w", err)
-	}
-
-	if len(tables) == 0 {
-		if verbose {
-			_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "    ✓ Shadow database is clean (no tables)\n")
-		}
-		return nil
-	}
-
-	// Drop all tables in a transaction
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
-
-	// For each table, drop it
-	for _, tableName := range tables {
-		table := database.Table{Name: tableName}
-		dropSQL, _ := driver.DropTable(table)
-
-		if verbose {
-			_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "    Dropping table %s\n", tableName)
-		}
-
-		if _, err := tx.ExecContext(ctx, dropSQL); err != nil {
-			return fmt.Errorf("failed to drop table %s: %w", tableName, err)
-		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
-	}
-
-	if verbose {
-		_, _ = color.New(color.FgGreen).Fprintf(os.Stderr, "    ✓ Cleaned up %d table(s)\n", len(tables))
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "  [Shadow DB] ✓ Migration test successful\n")
 	}
 
 	return nil
@@ -371,7 +665,7 @@ func ApplySchemaToDB(ctx context.Context, db *sql.DB, schema *database.Schema, d
 	for _, table := range schema.Tables {
 		sql, _ := driver.CreateTable(table)
 		if verbose {
-			_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "    Creating table %s\n", table.Name)
+			_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    Creating table %s\n", table.Name)
 		}
 		if _, err := tx.ExecContext(ctx, sql); err != nil {
 			return fmt.Errorf("failed to create table %s: %w", table.Name, err)
@@ -381,7 +675,7 @@ func ApplySchemaToDB(ctx context.Context, db *sql.DB, schema *database.Schema, d
 		for _, idx := range table.Indexes {
 			sql, _ := driver.AddIndex(table.Name, idx)
 			if verbose {
-				_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "    Creating index %s\n", idx.Name)
+				_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    Creating index %s\n", idx.Name)
 			}
 			if _, err := tx.ExecContext(ctx, sql); err != nil {
 				return fmt.Errorf("failed to create index %s: %w", idx.Name, err)
@@ -399,7 +693,7 @@ func ApplySchemaToDB(ctx context.Context, db *sql.DB, schema *database.Schema, d
 				continue
 			}
 			if verbose {
-				_, _ = color.New(color.FgYellow).Fprintf(os.Stderr, "    Creating foreign key %s\n", fk.Name)
+				_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    Creating foreign key %s\n", fk.Name)
 			}
 			if _, err := tx.ExecContext(ctx, sql); err != nil {
 				return fmt.Errorf("failed to create foreign key %s: %w", fk.Name, err)
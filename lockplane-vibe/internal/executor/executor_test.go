@@ -0,0 +1,313 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/testutil"
+	"github.com/lockplane/lockplane/testkit"
+)
+
+func TestApplyPlan_LongRunningStepsIgnoreStatementTimeout(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "postgres")
+	defer tdb.Close()
+
+	opts := ApplyOptions{StatementTimeout: 100 * time.Millisecond}
+	longStep := planner.PlanStep{Description: "Long step", SQL: []string{"SELECT pg_sleep(0.3)"}, LongRunning: true}
+	quickStep := planner.PlanStep{Description: "Quick step", SQL: []string{"SELECT pg_sleep(0.3)"}}
+
+	plan := &planner.Plan{Steps: []planner.PlanStep{longStep}}
+	if _, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false, opts); err != nil {
+		t.Fatalf("Expected long-running step to outlast the statement timeout, got: %v", err)
+	}
+
+	// The timeout applies again to steps after the long one
+	plan = &planner.Plan{Steps: []planner.PlanStep{longStep, quickStep}}
+	result, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false, opts)
+	if err == nil {
+		t.Fatal("Expected the statement timeout to cancel the quick step")
+	}
+	if result.StepsApplied != 1 {
+		t.Errorf("Expected 1 step applied before the timeout, got %d", result.StepsApplied)
+	}
+}
+
+func TestApplyPlan_ValidationErrors(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "sqlite")
+	defer tdb.Close()
+	ctx := context.Background()
+
+	current := &database.Schema{Tables: []database.Table{}}
+	plan := &planner.Plan{SourceHash: "not-the-current-hash", Steps: []planner.PlanStep{
+		{Description: "Create table", SQL: []string{"CREATE TABLE t (id INTEGER)"}},
+	}}
+	if _, err := ApplyPlan(ctx, tdb.DB, plan, nil, current, tdb.Driver, false); !errors.Is(err, ErrSourceHashMismatch) {
+		t.Errorf("Expected ErrSourceHashMismatch, got: %v", err)
+	}
+
+	shadow := testutil.SetupTestDB(t, "sqlite")
+	defer shadow.Close()
+	plan = &planner.Plan{Steps: []planner.PlanStep{
+		{Description: "Broken step", SQL: []string{"CREATE TABLE"}},
+	}}
+	if _, err := ApplyPlan(ctx, tdb.DB, plan, shadow.DB, current, tdb.Driver, false); !errors.Is(err, ErrDryRunFailed) {
+		t.Errorf("Expected ErrDryRunFailed, got: %v", err)
+	}
+}
+
+// tableExists reports whether a SQLite table is present in db
+func tableExists(t *testing.T, tdb *testutil.TestDB, name string) bool {
+	t.Helper()
+	var count int
+	if err := tdb.DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return count > 0
+}
+
+func createTablesPlan(names ...string) *planner.Plan {
+	plan := &planner.Plan{}
+	for _, name := range names {
+		plan.Steps = append(plan.Steps, planner.PlanStep{
+			Description: "Create table " + name,
+			SQL:         []string{"CREATE TABLE " + name + " (id INTEGER)"},
+		})
+	}
+	return plan
+}
+
+func TestApplyPlan_FailedStepRollsBackEarlierSteps(t *testing.T) {
+	faults := &testkit.FaultInjector{FailOnExec: 3}
+	tdb := testutil.OpenFaultyDB(t, faults)
+
+	plan := createTablesPlan("a", "b", "c")
+	result, err := ApplyPlan(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false)
+	if !errors.Is(err, testkit.ErrInjectedFault) {
+		t.Fatalf("Expected the injected fault, got: %v", err)
+	}
+
+	testutil.AssertExecutionResult(t, result, testutil.ExpectedResult{
+		StepsApplied:  2,
+		ErrorContains: []string{"step 3, statement 1/1 (Create table c)", "injected fault"},
+	})
+	for _, name := range []string{"a", "b", "c"} {
+		if tableExists(t, tdb, name) {
+			t.Errorf("Expected table %s to be rolled back", name)
+		}
+	}
+}
+
+func TestApplyPlan_FailureInMultiStatementStep(t *testing.T) {
+	faults := &testkit.FaultInjector{FailOnExec: 1, Match: "INSERT"}
+	tdb := testutil.OpenFaultyDB(t, faults)
+
+	plan := &planner.Plan{Steps: []planner.PlanStep{{
+		Description: "Create and seed table",
+		SQL:         []string{"CREATE TABLE a (id INTEGER)", "INSERT INTO a VALUES (1)"},
+	}}}
+	result, err := ApplyPlan(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false)
+	if err == nil {
+		t.Fatal("Expected the step to fail")
+	}
+
+	testutil.AssertExecutionResult(t, result, testutil.ExpectedResult{
+		ErrorContains: []string{"step 1, statement 2/2"},
+	})
+	if tableExists(t, tdb, "a") {
+		t.Error("Expected the first statement of the failed step to be rolled back")
+	}
+}
+
+func TestApplyPlan_PreservesSQLState(t *testing.T) {
+	faults := &testkit.FaultInjector{FailOnExec: 1, Err: testkit.SQLStateError("55P03", "could not obtain lock")}
+	tdb := testutil.OpenFaultyDB(t, faults)
+
+	_, err := ApplyPlan(context.Background(), tdb.DB, createTablesPlan("a"), nil, &database.Schema{}, tdb.Driver, false)
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		t.Fatalf("Expected a *pq.Error, got: %v", err)
+	}
+	if pqErr.Code != "55P03" {
+		t.Errorf("Expected SQLSTATE 55P03, got %s", pqErr.Code)
+	}
+}
+
+func TestApplyPlan_ShadowDBCatchesFailure(t *testing.T) {
+	targetFaults := &testkit.FaultInjector{}
+	tdb := testutil.OpenFaultyDB(t, targetFaults)
+	shadow := testutil.OpenFaultyDB(t, &testkit.FaultInjector{FailOnExec: 1, Match: "CREATE TABLE b"})
+
+	result, err := ApplyPlan(context.Background(), tdb.DB, createTablesPlan("a", "b"), shadow.DB, &database.Schema{}, tdb.Driver, false)
+	if !errors.Is(err, ErrDryRunFailed) {
+		t.Fatalf("Expected ErrDryRunFailed, got: %v", err)
+	}
+
+	testutil.AssertExecutionResult(t, result, testutil.ExpectedResult{
+		ErrorContains: []string{"dry-run failed", "shadow DB step 2"},
+	})
+	if executed := targetFaults.Executed(); len(executed) != 0 {
+		t.Errorf("Expected nothing to run on the target database, got %v", executed)
+	}
+}
+
+func TestApplyPlan_InjectedLatencyHonorsContext(t *testing.T) {
+	faults := &testkit.FaultInjector{Latency: time.Second}
+	tdb := testutil.OpenFaultyDB(t, faults)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := ApplyPlan(ctx, tdb.DB, createTablesPlan("a"), nil, &database.Schema{}, tdb.Driver, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the slow statement to be cancelled promptly, took %s", elapsed)
+	}
+	testutil.AssertExecutionResult(t, result, testutil.ExpectedResult{})
+}
+
+func TestApplyPlan_NonTransactionalStepCommitsEarlierSteps(t *testing.T) {
+	faults := &testkit.FaultInjector{FailOnExec: 1, Match: "CREATE TABLE c"}
+	tdb := testutil.OpenFaultyDB(t, faults)
+
+	plan := createTablesPlan("a", "b")
+	// VACUUM cannot run inside a transaction
+	plan.Steps = append(plan.Steps, planner.PlanStep{Description: "Vacuum", SQL: []string{"VACUUM"}}, createTablesPlan("c").Steps[0])
+
+	var checkpoints []int
+	result, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false,
+		ApplyOptions{Checkpoint: func(stepsApplied int) error {
+			checkpoints = append(checkpoints, stepsApplied)
+			return nil
+		}})
+	if !errors.Is(err, testkit.ErrInjectedFault) {
+		t.Fatalf("Expected the injected fault, got: %v", err)
+	}
+
+	testutil.AssertExecutionResult(t, result, testutil.ExpectedResult{
+		StepsApplied:  3,
+		ErrorContains: []string{"step 4, statement 1/1 (Create table c)"},
+	})
+	if len(checkpoints) != 2 || checkpoints[0] != 2 || checkpoints[1] != 3 {
+		t.Errorf("Expected checkpoints before and after the non-transactional step [2 3], got %v", checkpoints)
+	}
+	for name, want := range map[string]bool{"a": true, "b": true, "c": false} {
+		if got := tableExists(t, tdb, name); got != want {
+			t.Errorf("Table %s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestApplyPlan_StartStepResumesInterruptedRun(t *testing.T) {
+	faults := &testkit.FaultInjector{}
+	tdb := testutil.OpenFaultyDB(t, faults)
+	if _, err := tdb.DB.Exec("CREATE TABLE a (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	plan := createTablesPlan("a", "b")
+	// The database no longer matches the source schema; resuming skips the check
+	plan.SourceHash = "not-the-current-hash"
+	result, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false,
+		ApplyOptions{StartStep: 1})
+	if err != nil {
+		t.Fatalf("Failed to resume plan: %v", err)
+	}
+
+	testutil.AssertExecutionResult(t, result, testutil.ExpectedResult{Success: true, StepsApplied: 2})
+	if executed := faults.Executed(); len(executed) != 2 || executed[1] != "CREATE TABLE b (id INTEGER)" {
+		t.Errorf("Expected only step 2 to run, got %v", executed)
+	}
+}
+
+func TestApplyPlan_FailingCheckpointStopsApply(t *testing.T) {
+	tdb := testutil.OpenFaultyDB(t, &testkit.FaultInjector{})
+
+	plan := createTablesPlan("a")
+	plan.Steps = append(plan.Steps, planner.PlanStep{Description: "Vacuum", SQL: []string{"VACUUM"}}, createTablesPlan("b").Steps[0])
+	_, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false,
+		ApplyOptions{Checkpoint: func(int) error { return errors.New("disk full") }})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Expected the checkpoint error, got: %v", err)
+	}
+	if tableExists(t, tdb, "b") {
+		t.Error("Expected no steps to run after a failed checkpoint")
+	}
+}
+
+func TestApplyPlan_ProfileRecordsStatements(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "sqlite")
+	defer tdb.Close()
+
+	plan := createTablesPlan("a")
+	plan.Steps = append(plan.Steps, planner.PlanStep{
+		Description: "Backfill a",
+		SQL:         []string{"INSERT INTO a VALUES (1)", "UPDATE a SET id = 2 WHERE id = 1"},
+	})
+	result, err := ApplyPlanWithOptions(context.Background(), tdb.DB, plan, nil, &database.Schema{}, tdb.Driver, false,
+		ApplyOptions{Profile: true})
+	if err != nil {
+		t.Fatalf("Failed to apply plan: %v", err)
+	}
+
+	if len(result.Profile) != 3 {
+		t.Fatalf("Expected 3 profiled statements, got %+v", result.Profile)
+	}
+	if p := result.Profile[0]; p.Step != 1 || p.Statement != 1 || p.Explain != "" {
+		t.Errorf("Expected step 1 without a query plan, got %+v", p)
+	}
+	update := result.Profile[2]
+	if update.Step != 2 || update.Statement != 2 || update.DurationMS < 0 {
+		t.Errorf("Unexpected profile for the update: %+v", update)
+	}
+	if !strings.Contains(update.Explain, "a") {
+		t.Errorf("Expected the update's query plan to mention table a, got %q", update.Explain)
+	}
+
+	var id int
+	if err := tdb.DB.QueryRow("SELECT id FROM a").Scan(&id); err != nil || id != 2 {
+		t.Errorf("Expected the explained statements to run once, got id %d (%v)", id, err)
+	}
+}
+
+func TestApplyPlan_WithoutProfile(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "sqlite")
+	defer tdb.Close()
+
+	result, err := ApplyPlan(context.Background(), tdb.DB, createTablesPlan("a"), nil, &database.Schema{}, tdb.Driver, false)
+	if err != nil {
+		t.Fatalf("Failed to apply plan: %v", err)
+	}
+	if result.Profile != nil {
+		t.Errorf("Expected no profile, got %+v", result.Profile)
+	}
+}
+
+func TestLoadSchemaFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema/users.lp.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL);\n")},
+	}
+
+	loaded, err := LoadSchemaFromFS(fsys, "schema", BuildSchemaLoadOptions("schema", database.DialectSQLite))
+	if err != nil {
+		t.Fatalf("LoadSchemaFromFS returned error: %v", err)
+	}
+	if loaded.Dialect != database.DialectSQLite {
+		t.Errorf("expected the requested dialect, got %q", loaded.Dialect)
+	}
+	if len(loaded.Tables) != 1 || loaded.Tables[0].Name != "users" || len(loaded.Tables[0].Columns) != 2 {
+		t.Fatalf("unexpected schema: %+v", loaded.Tables)
+	}
+	if source := loaded.Tables[0].Source; source == nil || source.File != "schema/users.lp.sql" {
+		t.Errorf("expected users to be located in schema/users.lp.sql, got %v", source)
+	}
+}
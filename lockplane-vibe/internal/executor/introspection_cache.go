@@ -0,0 +1,249 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// introspectionCacheVersion is bumped when the cache file format changes
+const introspectionCacheVersion = 1
+
+// postgresFingerprintCatalogs are the system catalogs describing what
+// introspection reads. DDL inserts, updates or deletes rows in at least one
+// of them.
+var postgresFingerprintCatalogs = []string{
+	"pg_namespace", "pg_class", "pg_attribute", "pg_attrdef", "pg_index",
+	"pg_constraint", "pg_type", "pg_enum", "pg_policy", "pg_sequence",
+	"pg_depend", "pg_description", "pg_inherits",
+}
+
+// PostgresCatalogFingerprinter fingerprints a PostgreSQL database from its
+// system catalogs. Each catalog contributes its row count and the sum of its
+// rows' xmin, the transaction that wrote them: DDL adds, rewrites or removes
+// catalog rows, which changes one or the other. VACUUM and ANALYZE update
+// pg_class in place and leave both unchanged.
+type PostgresCatalogFingerprinter struct {
+	DB *sql.DB
+}
+
+// SchemaFingerprint reads every catalog's row count and xmin sum in one query
+func (f *PostgresCatalogFingerprinter) SchemaFingerprint(ctx context.Context) (string, error) {
+	parts := []string{
+		"current_database()",
+		"(SELECT oid::text FROM pg_database WHERE datname = current_database())",
+		"current_setting('server_version_num')",
+	}
+	for _, catalog := range postgresFingerprintCatalogs {
+		parts = append(parts, fmt.Sprintf("(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_catalog.%s)", catalog))
+	}
+	var fingerprint string
+	if err := f.DB.QueryRowContext(ctx, "SELECT concat_ws('|', "+strings.Join(parts, ", ")+")").Scan(&fingerprint); err != nil {
+		return "", fmt.Errorf("failed to read catalog fingerprint: %w", err)
+	}
+	sum := sha256.Sum256([]byte(fingerprint))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewSchemaFingerprinter returns the fingerprinter for a database of
+// driverType, or nil when it has none
+func NewSchemaFingerprinter(driverType string, db *sql.DB) SchemaFingerprinter {
+	switch driverType {
+	case "postgres", "postgresql":
+		return &PostgresCatalogFingerprinter{DB: db}
+	case "sqlite", "sqlite3", "libsql":
+		return &SQLiteMasterFingerprinter{DB: db}
+	default:
+		return nil
+	}
+}
+
+// sequenceLastValueRefresher is implemented by drivers whose sequences
+// record a last value. It changes without DDL, so it is left out of the cache
+// and read again on every hit.
+type sequenceLastValueRefresher interface {
+	RefreshSequenceLastValues(ctx context.Context, db *sql.DB, sequences []database.Sequence) error
+}
+
+// IntrospectionCacheOptions configures LoadSchemaFromConnectionStringCached
+type IntrospectionCacheOptions struct {
+	// CacheDir holds the introspected schemas between runs
+	CacheDir string
+	// Version identifies the lockplane build. Schemas cached by another
+	// build are introspected again.
+	Version string
+}
+
+// IntrospectionCacheResult describes how LoadSchemaFromConnectionStringCached
+// loaded a schema
+type IntrospectionCacheResult struct {
+	// Hit is set when the cached schema was reused
+	Hit bool
+	// FallbackReason says why the database was introspected
+	FallbackReason string
+	// CacheErr is set when the cache could not be written; the schema is
+	// still valid
+	CacheErr error
+}
+
+// introspectionCache is the cache file for one database and schema list
+type introspectionCache struct {
+	Version          int              `json:"version"`
+	LockplaneVersion string           `json:"lockplane_version"`
+	Target           string           `json:"target"`
+	Fingerprint      string           `json:"fingerprint"`
+	IntrospectedAt   time.Time        `json:"introspected_at"`
+	Schema           *database.Schema `json:"schema"`
+}
+
+// LoadSchemaFromConnectionStringCached is LoadSchemaFromConnectionStringContext
+// reusing the schema introspected by an earlier run while the database's
+// schema fingerprint is unchanged. The fingerprint is read before
+// introspecting, so DDL racing the introspection at worst causes another
+// introspection next time. Databases that can't be fingerprinted are
+// introspected every time.
+func LoadSchemaFromConnectionStringCached(ctx context.Context, connStr string, schemas []string, opts IntrospectionCacheOptions) (*database.Schema, *IntrospectionCacheResult, error) {
+	driverType, driver, db, err := openIntrospectionTarget(ctx, connStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	result := &IntrospectionCacheResult{}
+	fingerprinter := NewSchemaFingerprinter(driverType, db)
+	if fingerprinter == nil {
+		result.FallbackReason = fmt.Sprintf("%s databases can't be fingerprinted", driverType)
+		loaded, err := introspectConnected(ctx, db, driver, driverType, schemas)
+		return loaded, result, err
+	}
+	fingerprint, err := fingerprinter.SchemaFingerprint(ctx)
+	if err != nil {
+		result.FallbackReason = err.Error()
+		loaded, err := introspectConnected(ctx, db, driver, driverType, schemas)
+		return loaded, result, err
+	}
+
+	target := introspectionTarget(connStr, schemas)
+	cached := readIntrospectionCache(opts.CacheDir, target, opts.Version)
+	switch {
+	case cached == nil:
+		result.FallbackReason = "no cached schema"
+	case cached.Fingerprint != fingerprint:
+		result.FallbackReason = "the database schema changed since the last run"
+	default:
+		if refresher, ok := driver.(sequenceLastValueRefresher); ok && len(cached.Schema.Sequences) > 0 {
+			if err := refresher.RefreshSequenceLastValues(ctx, db, cached.Schema.Sequences); err != nil {
+				return nil, nil, err
+			}
+		}
+		result.Hit = true
+		return cached.Schema, result, nil
+	}
+
+	loaded, err := introspectConnected(ctx, db, driver, driverType, schemas)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.CacheErr = writeIntrospectionCache(opts.CacheDir, &introspectionCache{
+		Version:          introspectionCacheVersion,
+		LockplaneVersion: opts.Version,
+		Target:           target,
+		Fingerprint:      fingerprint,
+		IntrospectedAt:   time.Now().UTC(),
+		Schema:           withoutSequenceLastValues(loaded),
+	})
+	return loaded, result, nil
+}
+
+// withoutSequenceLastValues returns s with its sequences' last values left
+// out, for caching
+func withoutSequenceLastValues(s *database.Schema) *database.Schema {
+	if len(s.Sequences) == 0 {
+		return s
+	}
+	stripped := *s
+	stripped.Sequences = make([]database.Sequence, len(s.Sequences))
+	for i, seq := range s.Sequences {
+		seq.LastValue = nil
+		stripped.Sequences[i] = seq
+	}
+	return &stripped
+}
+
+// introspectionTarget identifies a database and schema list without storing
+// the connection string, which may hold credentials
+func introspectionTarget(connStr string, schemas []string) string {
+	sum := sha256.Sum256([]byte(connStr + "\x00" + strings.Join(schemas, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// introspectionCachePath returns the cache file for a target
+func introspectionCachePath(cacheDir, target string) string {
+	return filepath.Join(cacheDir, "introspection-"+target[:16]+".json")
+}
+
+// readIntrospectionCache returns the cache for target, or nil when there is
+// none or it was written by another build or cache format
+func readIntrospectionCache(cacheDir, target, version string) *introspectionCache {
+	if cacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(introspectionCachePath(cacheDir, target))
+	if err != nil {
+		return nil
+	}
+	var cache introspectionCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil
+	}
+	if cache.Version != introspectionCacheVersion || cache.LockplaneVersion != version || cache.Target != target || cache.Schema == nil {
+		return nil
+	}
+	return &cache
+}
+
+// writeIntrospectionCache replaces the cache file atomically. It is only
+// readable by its owner, since schemas can name roles and hold policy
+// expressions.
+func writeIntrospectionCache(cacheDir string, cache *introspectionCache) error {
+	if cacheDir == "" {
+		return errors.New("no cache directory")
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("failed to encode introspection cache: %w", err)
+	}
+
+	path := introspectionCachePath(cacheDir, cache.Target)
+	tmp, err := os.CreateTemp(cacheDir, ".introspection-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write introspection cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write introspection cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write introspection cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write introspection cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/testutil"
+	_ "modernc.org/sqlite"
+)
+
+func TestLoadSchemaFromConnectionStringCached(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	opts := IntrospectionCacheOptions{CacheDir: cacheDir, Version: "test"}
+	load := func() (*IntrospectionCacheResult, []string) {
+		t.Helper()
+		loaded, result, err := LoadSchemaFromConnectionStringCached(ctx, dbPath, nil, opts)
+		if err != nil {
+			t.Fatalf("LoadSchemaFromConnectionStringCached returned error: %v", err)
+		}
+		if result.CacheErr != nil {
+			t.Fatalf("Failed to write cache: %v", result.CacheErr)
+		}
+		var columns []string
+		for _, table := range loaded.Tables {
+			for _, col := range table.Columns {
+				columns = append(columns, table.Name+"."+col.Name)
+			}
+		}
+		return result, columns
+	}
+
+	first, columns := load()
+	if first.Hit || first.FallbackReason != "no cached schema" {
+		t.Fatalf("Expected the first run to introspect, got %+v", first)
+	}
+	if !reflect.DeepEqual(columns, []string{"users.id", "users.email"}) {
+		t.Fatalf("Unexpected columns %v", columns)
+	}
+
+	second, cachedColumns := load()
+	if !second.Hit {
+		t.Fatalf("Expected the unchanged database to reuse the cache, got %+v", second)
+	}
+	if !reflect.DeepEqual(cachedColumns, columns) {
+		t.Errorf("Expected cached columns %v, got %v", columns, cachedColumns)
+	}
+
+	// The cache never stores the connection string
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one cache file, got %v (%v)", entries, err)
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read cache: %v", err)
+	}
+	if strings.Contains(string(data), dbPath) {
+		t.Error("Expected the cache not to contain the connection string")
+	}
+
+	// DDL invalidates the cache
+	if _, err := db.ExecContext(ctx, "ALTER TABLE users ADD COLUMN name TEXT"); err != nil {
+		t.Fatalf("Failed to add column: %v", err)
+	}
+	third, columns := load()
+	if third.Hit || third.FallbackReason != "the database schema changed since the last run" {
+		t.Fatalf("Expected the changed database to be introspected, got %+v", third)
+	}
+	if !reflect.DeepEqual(columns, []string{"users.id", "users.email", "users.name"}) {
+		t.Fatalf("Expected the new column, got %v", columns)
+	}
+	if fourth, _ := load(); !fourth.Hit {
+		t.Fatalf("Expected the re-introspected schema to be cached, got %+v", fourth)
+	}
+
+	// Another lockplane build introspects again
+	opts.Version = "other"
+	if fifth, _ := load(); fifth.Hit {
+		t.Fatal("Expected a cache written by another build to be ignored")
+	}
+}
+
+func TestPostgresCatalogFingerprinter(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "postgres")
+	defer tdb.Close()
+	ctx := context.Background()
+	_, _ = tdb.DB.ExecContext(ctx, "DROP TABLE IF EXISTS fingerprint_test")
+	defer func() { _, _ = tdb.DB.ExecContext(ctx, "DROP TABLE IF EXISTS fingerprint_test") }()
+
+	f := &PostgresCatalogFingerprinter{DB: tdb.DB}
+	read := func() string {
+		t.Helper()
+		fingerprint, err := f.SchemaFingerprint(ctx)
+		if err != nil {
+			t.Fatalf("SchemaFingerprint returned error: %v", err)
+		}
+		return fingerprint
+	}
+
+	before := read()
+	if again := read(); again != before {
+		t.Fatal("Expected the fingerprint to be stable without DDL")
+	}
+	if _, err := tdb.DB.ExecContext(ctx, "CREATE TABLE fingerprint_test (id integer)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	created := read()
+	if created == before {
+		t.Error("Expected the fingerprint to change after CREATE TABLE")
+	}
+	if _, err := tdb.DB.ExecContext(ctx, "ALTER TABLE fingerprint_test ALTER COLUMN id SET NOT NULL"); err != nil {
+		t.Fatalf("Failed to alter column: %v", err)
+	}
+	altered := read()
+	if altered == created {
+		t.Error("Expected the fingerprint to change after ALTER COLUMN")
+	}
+	if _, err := tdb.DB.ExecContext(ctx, "INSERT INTO fingerprint_test VALUES (1); ANALYZE fingerprint_test"); err != nil {
+		t.Fatalf("Failed to insert and analyze: %v", err)
+	}
+	if read() != altered {
+		t.Error("Expected data changes and ANALYZE to leave the fingerprint unchanged")
+	}
+	if _, err := tdb.DB.ExecContext(ctx, "DROP TABLE fingerprint_test"); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+	if read() == altered {
+		t.Error("Expected the fingerprint to change after DROP TABLE")
+	}
+}
+
+func TestLoadSchemaFromConnectionStringCached_SequenceLastValue(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "postgres")
+	defer tdb.Close()
+	ctx := context.Background()
+	_, _ = tdb.DB.ExecContext(ctx, "DROP SEQUENCE IF EXISTS cache_test_seq")
+	defer func() { _, _ = tdb.DB.ExecContext(ctx, "DROP SEQUENCE IF EXISTS cache_test_seq") }()
+	if _, err := tdb.DB.ExecContext(ctx, "CREATE SEQUENCE cache_test_seq; SELECT nextval('cache_test_seq')"); err != nil {
+		t.Fatalf("Failed to create sequence: %v", err)
+	}
+
+	opts := IntrospectionCacheOptions{CacheDir: t.TempDir(), Version: "test"}
+	lastValue := func() (*IntrospectionCacheResult, int64) {
+		t.Helper()
+		loaded, result, err := LoadSchemaFromConnectionStringCached(ctx, tdb.ConnStr, nil, opts)
+		if err != nil {
+			t.Fatalf("LoadSchemaFromConnectionStringCached returned error: %v", err)
+		}
+		for _, seq := range loaded.Sequences {
+			if seq.Name == "cache_test_seq" && seq.LastValue != nil {
+				return result, *seq.LastValue
+			}
+		}
+		t.Fatalf("Expected cache_test_seq with a last value, got %+v", loaded.Sequences)
+		return nil, 0
+	}
+
+	if _, value := lastValue(); value != 1 {
+		t.Fatalf("Expected last value 1, got %d", value)
+	}
+	// nextval is not DDL, so the cache is reused with the new last value
+	if _, err := tdb.DB.ExecContext(ctx, "SELECT nextval('cache_test_seq')"); err != nil {
+		t.Fatalf("Failed to advance sequence: %v", err)
+	}
+	if result, value := lastValue(); !result.Hit || value != 2 {
+		t.Errorf("Expected a cache hit with last value 2, got hit=%v last value %d", result.Hit, value)
+	}
+}
+
+func TestWithoutSequenceLastValues(t *testing.T) {
+	last := int64(41)
+	loaded := &database.Schema{Sequences: []database.Sequence{{Name: "s", LastValue: &last}}}
+	if stripped := withoutSequenceLastValues(loaded); stripped.Sequences[0].LastValue != nil {
+		t.Error("Expected the cached schema to leave out the last value")
+	}
+	if loaded.Sequences[0].LastValue == nil {
+		t.Error("Expected the loaded schema to keep its last value")
+	}
+}
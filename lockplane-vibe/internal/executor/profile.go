@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// explainablePattern matches the data-changing statements EXPLAIN accepts.
+// DDL such as CREATE INDEX has no query plan.
+var explainablePattern = regexp.MustCompile(`(?i)^(INSERT|UPDATE|DELETE|MERGE|WITH)\b`)
+
+// explainStatement returns the query plan of a data-changing statement without
+// running it, or "" when the statement has none or EXPLAIN fails. The
+// statement itself reports any error when it runs.
+func explainStatement(ctx context.Context, ex stmtExecer, driver database.Driver, stmt string) string {
+	if !explainablePattern.MatchString(stmt) || driver == nil {
+		return ""
+	}
+
+	prefix := "EXPLAIN "
+	switch driver.Name() {
+	case "postgres":
+		// A failed statement aborts a PostgreSQL transaction, so EXPLAIN
+		// runs in a savepoint
+		if _, ok := ex.(*sql.Tx); ok {
+			if _, err := ex.ExecContext(ctx, "SAVEPOINT lockplane_explain"); err != nil {
+				return ""
+			}
+			defer func() {
+				_, _ = ex.ExecContext(ctx, "ROLLBACK TO SAVEPOINT lockplane_explain")
+				_, _ = ex.ExecContext(ctx, "RELEASE SAVEPOINT lockplane_explain")
+			}()
+		}
+	case "sqlite":
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return ""
+	}
+
+	rows, err := ex.QueryContext(ctx, prefix+stmt)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = rows.Close() }()
+
+	// PostgreSQL returns one line of the plan per row; SQLite returns the
+	// node's detail in the last column
+	columns, err := rows.Columns()
+	if err != nil || len(columns) == 0 {
+		return ""
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]any, len(columns))
+		for k := range values {
+			values[k] = new(any)
+		}
+		if err := rows.Scan(values...); err != nil {
+			return ""
+		}
+		detail := *(values[len(values)-1].(*any))
+		if b, ok := detail.([]byte); ok {
+			detail = string(b)
+		}
+		lines = append(lines, fmt.Sprint(detail))
+	}
+	if rows.Err() != nil {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultSeedsDir is the conventional directory for seed data files
+const DefaultSeedsDir = "seeds"
+
+// SeedFile is a SQL file of reference or seed data
+type SeedFile struct {
+	Name string // File name, used for ordering and reporting
+	Path string
+	SQL  string
+}
+
+// SeedResult reports which seed files were applied
+type SeedResult struct {
+	Success      bool     `json:"success"`
+	SeedsApplied []string `json:"seeds_applied"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// LoadSeedFiles reads the .sql files directly inside dir, in lexical order of
+// their names. Subdirectories are not descended into.
+func LoadSeedFiles(dir string) ([]SeedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seeds directory: %w", err)
+	}
+
+	var files []SeedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".sql") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seed file %s: %w", path, err)
+		}
+		files = append(files, SeedFile{Name: entry.Name(), Path: path, SQL: string(data)})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// ApplySeeds runs the seed files in order inside a single transaction, so either
+// every file is applied or none is. Seeds should be idempotent (e.g. using
+// ON CONFLICT DO NOTHING) because they run on every invocation.
+func ApplySeeds(ctx context.Context, db *sql.DB, files []SeedFile) (*SeedResult, error) {
+	result := &SeedResult{SeedsApplied: []string{}}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var applied []string
+	for _, file := range files {
+		if strings.TrimSpace(file.SQL) == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, file.SQL); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", file.Name, err))
+			return result, fmt.Errorf("seed file %s failed: %w", file.Name, err)
+		}
+		applied = append(applied, file.Name)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit seeds: %w", err)
+	}
+
+	result.Success = true
+	result.SeedsApplied = append(result.SeedsApplied, applied...)
+	return result, nil
+}
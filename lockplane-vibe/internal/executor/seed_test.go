@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func writeSeedFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write seed file: %v", err)
+	}
+}
+
+func TestLoadSeedFiles_LexicalOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeSeedFile(t, dir, "010_users.sql", "SELECT 1;")
+	writeSeedFile(t, dir, "002_roles.sql", "SELECT 1;")
+	writeSeedFile(t, dir, "README.md", "not a seed")
+	if err := os.Mkdir(filepath.Join(dir, "999_nested.sql"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	files, err := LoadSeedFiles(dir)
+	if err != nil {
+		t.Fatalf("LoadSeedFiles returned error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 seed files, got %d", len(files))
+	}
+	if files[0].Name != "002_roles.sql" || files[1].Name != "010_users.sql" {
+		t.Errorf("expected lexical order, got %s, %s", files[0].Name, files[1].Name)
+	}
+}
+
+func TestApplySeeds(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE TABLE roles (name TEXT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	files := []SeedFile{
+		{Name: "001_roles.sql", SQL: "INSERT INTO roles (name) VALUES ('admin') ON CONFLICT DO NOTHING;\nINSERT INTO roles (name) VALUES ('member') ON CONFLICT DO NOTHING;"},
+		{Name: "002_empty.sql", SQL: "  \n"},
+	}
+
+	// Seeds are idempotent by convention, so running them twice is fine
+	for i := 0; i < 2; i++ {
+		result, err := ApplySeeds(context.Background(), db, files)
+		if err != nil {
+			t.Fatalf("ApplySeeds returned error: %v", err)
+		}
+		if !result.Success || len(result.SeedsApplied) != 1 || result.SeedsApplied[0] != "001_roles.sql" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM roles").Scan(&count); err != nil {
+		t.Fatalf("Failed to count roles: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 roles, got %d", count)
+	}
+}
+
+func TestApplySeeds_RollsBackOnFailure(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE TABLE roles (name TEXT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	files := []SeedFile{
+		{Name: "001_roles.sql", SQL: "INSERT INTO roles (name) VALUES ('admin');"},
+		{Name: "002_users.sql", SQL: "INSERT INTO missing (id) VALUES (1);"},
+	}
+
+	result, err := ApplySeeds(context.Background(), db, files)
+	if err == nil {
+		t.Fatal("expected error from failing seed file")
+	}
+	if result.Success || len(result.SeedsApplied) != 0 || len(result.Errors) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM roles").Scan(&count); err != nil {
+		t.Fatalf("Failed to count roles: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected earlier seed files to be rolled back, got %d role(s)", count)
+	}
+}
@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/lib/pq"
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// Reasons a shadow database object could not be dropped
+const (
+	LeftoverPermissionDenied = "permission denied"
+	LeftoverDependency       = "dependency"
+	LeftoverUnknown          = "unknown"
+)
+
+// ShadowLeftover is an object that CleanupShadowDB could not remove
+type ShadowLeftover struct {
+	// Kind is table, view, materialized view, foreign table, type or domain
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Reason is LeftoverPermissionDenied, LeftoverDependency or LeftoverUnknown
+	Reason string `json:"reason"`
+	// Detail is the error the drop failed with, if it failed
+	Detail string `json:"detail,omitempty"`
+}
+
+func (l ShadowLeftover) String() string {
+	if l.Detail == "" {
+		return fmt.Sprintf("%s %s (%s)", l.Kind, l.Name, l.Reason)
+	}
+	return fmt.Sprintf("%s %s (%s: %s)", l.Kind, l.Name, l.Reason, l.Detail)
+}
+
+// DirtyShadowError is returned by CleanupShadowDB when objects remain in the
+// shadow database after cleanup. Validating against it can fail with
+// "already exists" errors that have nothing to do with the schema.
+type DirtyShadowError struct {
+	Leftovers []ShadowLeftover
+}
+
+func (e *DirtyShadowError) Error() string {
+	names := make([]string, 0, len(e.Leftovers))
+	for _, leftover := range e.Leftovers {
+		names = append(names, leftover.Kind+" "+leftover.Name)
+	}
+	return fmt.Sprintf("shadow database is not empty after cleanup: %d object(s) could not be removed: %s",
+		len(e.Leftovers), strings.Join(names, ", "))
+}
+
+// shadowObject is a user object found in the shadow database
+type shadowObject struct {
+	kind string
+	name string
+}
+
+// CleanupShadowDB drops all tables, views and types from the shadow database,
+// then checks that it is empty. Objects are dropped one at a time so that one
+// which can't be dropped doesn't stop the cleanup of the rest; if any remain,
+// a *DirtyShadowError lists them.
+func CleanupShadowDB(ctx context.Context, db *sql.DB, driver database.Driver, verbose bool) error {
+	if verbose {
+		_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "  [Shadow DB] Cleaning up existing objects...\n")
+	}
+
+	objects, err := listShadowObjects(ctx, db, driver)
+	if err != nil {
+		return err
+	}
+
+	if len(objects) == 0 {
+		if verbose {
+			_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "    ✓ Shadow database is clean (no objects)\n")
+		}
+		return nil
+	}
+
+	// Retry failed drops while others succeed, since an object may only be
+	// droppable once the objects depending on it are gone
+	failures := make(map[shadowObject]error)
+	pending := objects
+	for len(pending) > 0 {
+		var failed []shadowObject
+		for _, object := range pending {
+			if verbose {
+				_, _ = color.New(color.FgYellow).Fprintf(style.Stderr, "    Dropping %s %s\n", object.kind, object.name)
+			}
+			if _, err := db.ExecContext(ctx, dropShadowObjectSQL(object, driver)); err != nil {
+				failures[object] = err
+				failed = append(failed, object)
+				continue
+			}
+			delete(failures, object)
+		}
+		if len(failed) == len(pending) {
+			break
+		}
+		pending = failed
+	}
+
+	// A failed drop may still have removed the object (e.g. a view already
+	// dropped by CASCADE), so check what is actually left
+	remaining, err := listShadowObjects(ctx, db, driver)
+	if err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		leftovers := make([]ShadowLeftover, 0, len(remaining))
+		for _, object := range remaining {
+			leftovers = append(leftovers, newShadowLeftover(object, failures[object]))
+		}
+		return &DirtyShadowError{Leftovers: leftovers}
+	}
+
+	if verbose {
+		_, _ = color.New(color.FgGreen).Fprintf(style.Stderr, "    ✓ Cleaned up %d object(s)\n", len(objects))
+	}
+
+	return nil
+}
+
+// listShadowObjects returns the user tables, views and types in the shadow
+// database, tables first. PostgreSQL objects are read from the catalog rather
+// than information_schema, which hides objects the current role has no
+// privileges on.
+func listShadowObjects(ctx context.Context, db *sql.DB, driver database.Driver) ([]shadowObject, error) {
+	query := `
+		SELECT type, name
+		FROM sqlite_master
+		WHERE type IN ('table', 'view')
+		AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 ELSE 1 END, name
+	`
+	if driver.SupportsSchemas() {
+		query = `
+			SELECT kind, name FROM (
+				SELECT CASE c.relkind
+						WHEN 'v' THEN 'view'
+						WHEN 'm' THEN 'materialized view'
+						WHEN 'f' THEN 'foreign table'
+						ELSE 'table'
+					END AS kind,
+					c.relname AS name,
+					CASE WHEN c.relkind IN ('r', 'p') THEN 0 ELSE 1 END AS rank
+				FROM pg_catalog.pg_class c
+				JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = current_schema()
+				AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+				AND NOT c.relispartition
+				UNION ALL
+				SELECT CASE t.typtype WHEN 'd' THEN 'domain' ELSE 'type' END,
+					t.typname,
+					2
+				FROM pg_catalog.pg_type t
+				JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+				WHERE n.nspname = current_schema()
+				AND (t.typtype IN ('e', 'd', 'r')
+					OR (t.typtype = 'c' AND EXISTS (
+						SELECT 1 FROM pg_catalog.pg_class c
+						WHERE c.oid = t.typrelid AND c.relkind = 'c')))
+			) objects
+			ORDER BY rank, kind, name
+		`
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shadow database objects: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var objects []shadowObject
+	for rows.Next() {
+		var object shadowObject
+		if err := rows.Scan(&object.kind, &object.name); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow database object: %w", err)
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list shadow database objects: %w", err)
+	}
+	return objects, nil
+}
+
+// dropShadowObjectSQL returns the statement that drops a shadow object
+func dropShadowObjectSQL(object shadowObject, driver database.Driver) string {
+	name := `"` + strings.ReplaceAll(object.name, `"`, `""`) + `"`
+	dropSQL := fmt.Sprintf("DROP %s %s", strings.ToUpper(object.kind), name)
+	if driver.SupportsFeature("CASCADE") {
+		// Shadow objects may reference each other; drop them regardless of order
+		dropSQL += " CASCADE"
+	}
+	return dropSQL
+}
+
+// newShadowLeftover describes an object that remained after cleanup, with the
+// reason its drop failed
+func newShadowLeftover(object shadowObject, dropErr error) ShadowLeftover {
+	leftover := ShadowLeftover{Kind: object.kind, Name: object.name, Reason: LeftoverUnknown}
+	if dropErr == nil {
+		leftover.Detail = "still present after cleanup"
+		return leftover
+	}
+	leftover.Detail = dropErr.Error()
+
+	var pqErr *pq.Error
+	if errors.As(dropErr, &pqErr) {
+		switch pqErr.Code {
+		case "42501": // insufficient_privilege, e.g. "must be owner of table"
+			leftover.Reason = LeftoverPermissionDenied
+		case "2BP01": // dependent_objects_still_exist, e.g. "extension postgis requires it"
+			leftover.Reason = LeftoverDependency
+		}
+		return leftover
+	}
+
+	msg := strings.ToLower(leftover.Detail)
+	switch {
+	case strings.Contains(msg, "permission denied") || strings.Contains(msg, "must be owner") || strings.Contains(msg, "not authorized"):
+		leftover.Reason = LeftoverPermissionDenied
+	case strings.Contains(msg, "depend") || strings.Contains(msg, "requires it") || strings.Contains(msg, "foreign key constraint"):
+		leftover.Reason = LeftoverDependency
+	}
+	return leftover
+}
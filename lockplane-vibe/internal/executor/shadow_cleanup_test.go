@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/testutil"
+	"github.com/lockplane/lockplane/testkit"
+)
+
+// openDirtyShadow opens a shadow database with a table that can't be dropped
+func openDirtyShadow(t *testing.T) *testutil.TestDB {
+	t.Helper()
+	faults := &testkit.FaultInjector{
+		FailOnExec: 1,
+		Match:      `DROP TABLE "locked"`,
+		Err:        testkit.SQLStateError("42501", "must be owner of table locked"),
+	}
+	shadow := testutil.OpenFaultyDB(t, faults)
+	if _, err := shadow.DB.Exec("CREATE TABLE locked (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	return shadow
+}
+
+func TestCleanupShadowDB_ReportsLeftovers(t *testing.T) {
+	shadow := openDirtyShadow(t)
+
+	err := CleanupShadowDB(context.Background(), shadow.DB, shadow.Driver, false)
+	var dirty *DirtyShadowError
+	if !errors.As(err, &dirty) {
+		t.Fatalf("Expected a *DirtyShadowError, got: %v", err)
+	}
+	if len(dirty.Leftovers) != 1 {
+		t.Fatalf("Expected 1 leftover, got %v", dirty.Leftovers)
+	}
+	leftover := dirty.Leftovers[0]
+	if leftover.Kind != "table" || leftover.Name != "locked" || leftover.Reason != LeftoverPermissionDenied {
+		t.Errorf("Expected table locked left over with permission denied, got %+v", leftover)
+	}
+}
+
+func TestCleanupShadowDB_RetriesFailedDrops(t *testing.T) {
+	faults := &testkit.FaultInjector{FailOnExec: 1, Match: `DROP TABLE "a"`, Err: errors.New("table a is referenced by a foreign key constraint")}
+	shadow := testutil.OpenFaultyDB(t, faults)
+	for _, stmt := range []string{"CREATE TABLE a (id INTEGER)", "CREATE TABLE b (id INTEGER)", "CREATE VIEW v AS SELECT id FROM a"} {
+		if _, err := shadow.DB.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up shadow database: %v", err)
+		}
+	}
+
+	if err := CleanupShadowDB(context.Background(), shadow.DB, shadow.Driver, false); err != nil {
+		t.Fatalf("Expected the cleanup to succeed after retrying, got: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if tableExists(t, shadow, name) {
+			t.Errorf("Expected table %s to be dropped", name)
+		}
+	}
+}
+
+func TestApplyPlan_RefusesDirtyShadow(t *testing.T) {
+	ctx := context.Background()
+	tdb := testutil.OpenFaultyDB(t, &testkit.FaultInjector{})
+
+	_, err := ApplyPlan(ctx, tdb.DB, createTablesPlan("a"), openDirtyShadow(t).DB, &database.Schema{}, tdb.Driver, false)
+	var dirty *DirtyShadowError
+	if !errors.Is(err, ErrDryRunFailed) || !errors.As(err, &dirty) {
+		t.Fatalf("Expected ErrDryRunFailed wrapping a *DirtyShadowError, got: %v", err)
+	}
+	if tableExists(t, tdb, "a") {
+		t.Error("Expected the target to be untouched")
+	}
+
+	opts := ApplyOptions{AllowDirtyShadow: true}
+	if _, err := ApplyPlanWithOptions(ctx, tdb.DB, createTablesPlan("a"), openDirtyShadow(t).DB, &database.Schema{}, tdb.Driver, false, opts); err != nil {
+		t.Fatalf("Expected AllowDirtyShadow to validate despite leftovers, got: %v", err)
+	}
+	if !tableExists(t, tdb, "a") {
+		t.Error("Expected the plan to be applied")
+	}
+}
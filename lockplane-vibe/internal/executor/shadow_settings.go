@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// ErrShadowLimitExceeded is returned when validation on the shadow database
+// runs out of a resource: its temp_file_limit, memory or disk space
+var ErrShadowLimitExceeded = errors.New("shadow validation exceeded a resource limit")
+
+// ShadowSettings are session settings that limit the resources validation
+// may use on the shadow database. Empty fields keep the server defaults.
+type ShadowSettings struct {
+	// PostgreSQL
+	TempFileLimit      string // e.g. "1GB"
+	WorkMem            string // e.g. "64MB"
+	MaintenanceWorkMem string // e.g. "256MB"
+	Tablespace         string // Tablespace for created objects and temporary files
+
+	// SQLite
+	CacheSize *int   // Pages, or KiB when negative
+	TempStore string // "default", "file" or "memory"
+}
+
+// shadowSetting is a session setting and the statement that applies it
+type shadowSetting struct {
+	Name  string
+	Value string
+	SQL   string
+}
+
+// shadowSettingStatements returns the settings that apply to driverName, in
+// the order they are applied. Settings of other drivers are left out.
+func shadowSettingStatements(driverName string, settings ShadowSettings) []shadowSetting {
+	var statements []shadowSetting
+	set := func(name, value string) {
+		if value != "" {
+			statements = append(statements, shadowSetting{Name: name, Value: value, SQL: fmt.Sprintf("SET %s = %s", name, quoteSettingValue(value))})
+		}
+	}
+	pragma := func(name, value string) {
+		if value != "" {
+			statements = append(statements, shadowSetting{Name: name, Value: value, SQL: fmt.Sprintf("PRAGMA %s = %s", name, value)})
+		}
+	}
+
+	switch driverName {
+	case "postgres", "postgresql":
+		set("temp_file_limit", settings.TempFileLimit)
+		set("work_mem", settings.WorkMem)
+		set("maintenance_work_mem", settings.MaintenanceWorkMem)
+		set("default_tablespace", settings.Tablespace)
+		set("temp_tablespaces", settings.Tablespace)
+	case "sqlite", "sqlite3", "libsql":
+		if settings.CacheSize != nil {
+			pragma("cache_size", strconv.Itoa(*settings.CacheSize))
+		}
+		pragma("temp_store", strings.ToUpper(settings.TempStore))
+	}
+	return statements
+}
+
+// quoteSettingValue quotes a value for SET as a string literal
+func quoteSettingValue(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// ApplyShadowSettings applies settings to the shadow database session, right
+// after connecting to it, and lists them in verbose mode
+func ApplyShadowSettings(ctx context.Context, shadowDB *sql.DB, driverName string, settings ShadowSettings, verbose bool) error {
+	for _, setting := range shadowSettingStatements(driverName, settings) {
+		if _, err := shadowDB.ExecContext(ctx, setting.SQL); err != nil {
+			return fmt.Errorf("failed to set %s = %s on the shadow database: %w", setting.Name, setting.Value, err)
+		}
+		if verbose {
+			_, _ = color.New(color.FgCyan).Fprintf(style.Stderr, "  [Shadow DB] %s = %s\n", setting.Name, setting.Value)
+		}
+	}
+	return nil
+}
+
+// ShadowLimitError marks err with ErrShadowLimitExceeded when the shadow
+// database ran out of temporary file space, memory or disk, and returns
+// other errors unchanged
+func ShadowLimitError(err error) error {
+	if err == nil || errors.Is(err, ErrShadowLimitExceeded) || !isResourceLimitError(err) {
+		return err
+	}
+	return fmt.Errorf("%w; raise shadow_settings in lockplane.toml or free space on the shadow database: %w", ErrShadowLimitExceeded, err)
+}
+
+// isResourceLimitError reports whether err is PostgreSQL's disk_full,
+// out_of_memory or configuration_limit_exceeded (raised when temp_file_limit
+// is exceeded), or SQLite's SQLITE_FULL
+func isResourceLimitError(err error) bool {
+	switch sqlState(err) {
+	case "53100", "53200", "53400":
+		return true
+	}
+	return strings.Contains(err.Error(), "database or disk is full")
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestShadowSettingStatements(t *testing.T) {
+	cacheSize := -4000
+	settings := ShadowSettings{
+		TempFileLimit:      "1GB",
+		WorkMem:            "64MB",
+		MaintenanceWorkMem: "256MB",
+		Tablespace:         "o'scratch",
+		CacheSize:          &cacheSize,
+		TempStore:          "file",
+	}
+
+	var postgresSQL []string
+	for _, setting := range shadowSettingStatements("postgres", settings) {
+		postgresSQL = append(postgresSQL, setting.SQL)
+	}
+	wantPostgres := []string{
+		"SET temp_file_limit = '1GB'",
+		"SET work_mem = '64MB'",
+		"SET maintenance_work_mem = '256MB'",
+		"SET default_tablespace = 'o''scratch'",
+		"SET temp_tablespaces = 'o''scratch'",
+	}
+	if !reflect.DeepEqual(postgresSQL, wantPostgres) {
+		t.Fatalf("postgres statements = %q, want %q", postgresSQL, wantPostgres)
+	}
+
+	var sqliteSQL []string
+	for _, setting := range shadowSettingStatements("sqlite", settings) {
+		sqliteSQL = append(sqliteSQL, setting.SQL)
+	}
+	wantSQLite := []string{"PRAGMA cache_size = -4000", "PRAGMA temp_store = FILE"}
+	if !reflect.DeepEqual(sqliteSQL, wantSQLite) {
+		t.Fatalf("sqlite statements = %q, want %q", sqliteSQL, wantSQLite)
+	}
+
+	if statements := shadowSettingStatements("postgres", ShadowSettings{}); len(statements) != 0 {
+		t.Fatalf("expected no statements without settings, got %v", statements)
+	}
+}
+
+func TestApplyShadowSettingsSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	cacheSize := 500
+	settings := ShadowSettings{CacheSize: &cacheSize, TempStore: "memory", WorkMem: "64MB"}
+	if err := ApplyShadowSettings(ctx, db, "sqlite", settings, false); err != nil {
+		t.Fatalf("ApplyShadowSettings returned error: %v", err)
+	}
+
+	var gotCache, gotTempStore int
+	if err := db.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&gotCache); err != nil {
+		t.Fatalf("failed to read cache_size: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA temp_store").Scan(&gotTempStore); err != nil {
+		t.Fatalf("failed to read temp_store: %v", err)
+	}
+	if gotCache != 500 {
+		t.Errorf("cache_size = %d, want 500", gotCache)
+	}
+	if gotTempStore != 2 { // MEMORY
+		t.Errorf("temp_store = %d, want 2 (memory)", gotTempStore)
+	}
+}
+
+func TestShadowLimitError(t *testing.T) {
+	tempFileLimit := &pq.Error{Code: "53400", Message: "temporary file size exceeds temp_file_limit (1024kB)"}
+	err := ShadowLimitError(fmt.Errorf("shadow DB step 2 failed: %w", tempFileLimit))
+	if !errors.Is(err, ErrShadowLimitExceeded) {
+		t.Fatalf("expected ErrShadowLimitExceeded for temp_file_limit, got %v", err)
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		t.Fatal("expected the driver error to stay wrapped")
+	}
+	if again := ShadowLimitError(err); again != err {
+		t.Fatalf("expected an already marked error to be returned unchanged, got %v", again)
+	}
+
+	if err := ShadowLimitError(errors.New("database or disk is full (13)")); !errors.Is(err, ErrShadowLimitExceeded) {
+		t.Fatalf("expected ErrShadowLimitExceeded for SQLITE_FULL, got %v", err)
+	}
+
+	syntax := &pq.Error{Code: "42601", Message: "syntax error"}
+	if err := ShadowLimitError(syntax); err != syntax {
+		t.Fatalf("expected other errors unchanged, got %v", err)
+	}
+	if ShadowLimitError(nil) != nil {
+		t.Fatal("expected nil for nil")
+	}
+}
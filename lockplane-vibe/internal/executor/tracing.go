@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/tracing"
+)
+
+// Span names and attribute keys used when tracing is enabled
+const (
+	spanApplyPlan  = "lockplane.apply_plan"
+	spanDryRunPlan = "lockplane.shadow_dry_run"
+	spanPlanStep   = "lockplane.plan_step"
+	spanIntrospect = "lockplane.introspect"
+
+	attrDBSystem      = "db.system"
+	attrSQLState      = "db.response.status_code"
+	attrPlanSteps     = "lockplane.plan.steps"
+	attrStepsApplied  = "lockplane.plan.steps_applied"
+	attrShadowDB      = "lockplane.shadow_db"
+	attrStepIndex     = "lockplane.step.index"
+	attrStepDesc      = "lockplane.step.description"
+	attrStepStmts     = "lockplane.step.statements"
+	attrOperationKind = "lockplane.operation"
+	attrTable         = "lockplane.table"
+	attrColumn        = "lockplane.column"
+	attrRowsEstimate  = "lockplane.rows_estimate"
+	attrSchemas       = "lockplane.schemas"
+	attrTables        = "lockplane.tables"
+	attrTableFilter   = "lockplane.table_filter"
+)
+
+// startStepSpan starts a child span describing a single plan step
+func startStepSpan(ctx context.Context, db *sql.DB, driver database.Driver, index int, step planner.PlanStep) (context.Context, tracing.Span) {
+	if !tracing.Enabled() {
+		return ctx, tracing.NoopSpan()
+	}
+
+	attrs := []tracing.Attribute{
+		tracing.Int(attrStepIndex, index+1),
+		tracing.String(attrStepDesc, step.Description),
+		tracing.Int(attrStepStmts, len(step.SQL)),
+	}
+	if op := step.Operation; op != nil {
+		attrs = append(attrs, tracing.String(attrOperationKind, op.Kind))
+		if op.Table != "" {
+			attrs = append(attrs, tracing.String(attrTable, op.Table))
+			if rows, ok := estimateRows(ctx, db, driver, op.Table); ok {
+				attrs = append(attrs, tracing.Int64(attrRowsEstimate, rows))
+			}
+		}
+		if op.Column != "" {
+			attrs = append(attrs, tracing.String(attrColumn, op.Column))
+		}
+	}
+
+	return tracing.Start(ctx, spanPlanStep, attrs...)
+}
+
+// estimateRows returns the planner's row estimate for a table. Only PostgreSQL
+// keeps one (pg_class.reltuples); it is unavailable for tables that have never
+// been analyzed or do not exist yet.
+func estimateRows(ctx context.Context, db *sql.DB, driver database.Driver, table string) (int64, bool) {
+	if db == nil || driver == nil || driver.Name() != "postgres" {
+		return 0, false
+	}
+
+	var rows sql.NullFloat64
+	// Queried outside the migration transaction so a failure cannot abort it
+	err := db.QueryRowContext(ctx, "SELECT reltuples FROM pg_class WHERE oid = to_regclass($1)", table).Scan(&rows)
+	if err != nil || !rows.Valid || rows.Float64 < 0 {
+		return 0, false
+	}
+	return int64(rows.Float64), true
+}
+
+// recordSpanError marks the span as failed, including the SQLSTATE when the
+// database driver exposes one
+func recordSpanError(span tracing.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	if state := sqlState(err); state != "" {
+		span.SetAttributes(tracing.String(attrSQLState, state))
+	}
+}
+
+// sqlState extracts the SQLSTATE code from a driver error (lib/pq and pgx both
+// implement SQLState)
+func sqlState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
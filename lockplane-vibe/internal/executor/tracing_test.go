@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/tracing"
+
+	_ "modernc.org/sqlite"
+)
+
+// recordingTracer keeps finished spans in memory
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+type fakeSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	parent, _ := ctx.Value(fakeSpanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, attrs: map[string]any{}}
+	if parent != nil {
+		span.parent = parent.name
+	}
+	span.SetAttributes(attrs...)
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, fakeSpanKey{}, span), span
+}
+
+type fakeSpanKey struct{}
+
+func (s *fakeSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+func (s *fakeSpan) RecordError(err error)            { s.err = err }
+func (s *fakeSpan) SpanContext() tracing.SpanContext { return tracing.SpanContext{} }
+func (s *fakeSpan) End()                             { s.ended = true }
+
+func (t *recordingTracer) named(name string) []*fakeSpan {
+	var out []*fakeSpan
+	for _, span := range t.spans {
+		if span.name == name {
+			out = append(out, span)
+		}
+	}
+	return out
+}
+
+func TestApplyPlan_RecordsSpans(t *testing.T) {
+	tracer := &recordingTracer{}
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	plan := &planner.Plan{Steps: []planner.PlanStep{
+		{
+			Description: "Create table users",
+			SQL:         []string{"CREATE TABLE users (id INTEGER PRIMARY KEY)"},
+			Operation:   &planner.Operation{Kind: planner.OperationCreateTable, Table: "users"},
+		},
+		{
+			Description: "Add column email to users",
+			SQL:         []string{"ALTER TABLE missing ADD COLUMN email TEXT"},
+			Operation:   &planner.Operation{Kind: planner.OperationAddColumn, Table: "users", Column: "email"},
+		},
+	}}
+
+	_, err = ApplyPlan(context.Background(), db, plan, nil, &database.Schema{}, sqlite.NewDriver(), false)
+	if err == nil {
+		t.Fatal("expected second step to fail")
+	}
+
+	planSpans := tracer.named(spanApplyPlan)
+	if len(planSpans) != 1 {
+		t.Fatalf("expected 1 plan span, got %d", len(planSpans))
+	}
+	planSpan := planSpans[0]
+	if !planSpan.ended || planSpan.err == nil {
+		t.Error("expected plan span to be ended with the failure recorded")
+	}
+	if planSpan.attrs[attrPlanSteps] != int64(2) || planSpan.attrs[attrStepsApplied] != int64(1) {
+		t.Errorf("unexpected plan span attributes: %v", planSpan.attrs)
+	}
+
+	stepSpans := tracer.named(spanPlanStep)
+	if len(stepSpans) != 2 {
+		t.Fatalf("expected 2 step spans, got %d", len(stepSpans))
+	}
+	for _, span := range stepSpans {
+		if span.parent != spanApplyPlan {
+			t.Errorf("expected step span to be a child of the plan span, got parent %q", span.parent)
+		}
+		if !span.ended {
+			t.Error("expected step span to be ended")
+		}
+	}
+
+	created, failed := stepSpans[0], stepSpans[1]
+	if created.attrs[attrOperationKind] != planner.OperationCreateTable || created.attrs[attrTable] != "users" {
+		t.Errorf("unexpected attributes on first step span: %v", created.attrs)
+	}
+	if created.err != nil {
+		t.Errorf("expected first step to succeed, got %v", created.err)
+	}
+	if failed.attrs[attrColumn] != "email" || failed.err == nil {
+		t.Errorf("expected failed step span with column attribute, got %v (err %v)", failed.attrs, failed.err)
+	}
+	if _, ok := failed.attrs[attrRowsEstimate]; ok {
+		t.Error("expected no rows estimate for SQLite")
+	}
+}
+
+type sqlStateError struct{ code string }
+
+func (e *sqlStateError) Error() string    { return "pq: relation does not exist" }
+func (e *sqlStateError) SQLState() string { return e.code }
+
+func TestRecordSpanError_SQLState(t *testing.T) {
+	span := &fakeSpan{attrs: map[string]any{}}
+	err := fmt.Errorf("step 1 failed: %w", &sqlStateError{code: "42P01"})
+
+	recordSpanError(span, err)
+
+	if span.err == nil {
+		t.Error("expected error to be recorded")
+	}
+	if span.attrs[attrSQLState] != "42P01" {
+		t.Errorf("expected SQLSTATE 42P01, got %v", span.attrs[attrSQLState])
+	}
+}
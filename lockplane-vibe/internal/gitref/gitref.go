@@ -0,0 +1,157 @@
+// Package gitref reads schema files as they were at a git revision.
+//
+// Files are read with the git command line tool and written to a temporary
+// directory, so the usual schema loaders can parse them unchanged.
+package gitref
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot holds the schema files of a path as they were at a revision
+type Snapshot struct {
+	// Ref is the revision as given; Commit is the commit it resolved to
+	Ref    string
+	Commit string
+	// Dir is the temporary directory the files were written to
+	Dir string
+	// Path is the location inside Dir that corresponds to the requested path
+	Path string
+	// Files are the repository-relative paths that existed at the revision.
+	// Empty when the path did not exist (e.g. the schema was added later).
+	Files []string
+}
+
+// Close removes the snapshot's temporary directory
+func (s *Snapshot) Close() error {
+	if s == nil || s.Dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.Dir)
+}
+
+// Export writes the schema files (.sql and .json) under schemaPath, a file or
+// directory in a git working tree, as they were at ref into a temporary
+// directory. Files added since ref are absent from the snapshot and files
+// removed since ref are present, so diffing the snapshot against schemaPath
+// gives the changes made after ref.
+func Export(ref, schemaPath string) (*Snapshot, error) {
+	absPath, err := filepath.Abs(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", schemaPath, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", schemaPath, err)
+	}
+	workDir := absPath
+	if !info.IsDir() {
+		workDir = filepath.Dir(absPath)
+	}
+
+	root, err := git(workDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", schemaPath, err)
+	}
+	root = strings.TrimSpace(root)
+
+	commit, err := git(root, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown git revision %q", ref)
+	}
+	commit = strings.TrimSpace(commit)
+
+	rel, err := repoRelative(root, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	listing, err := git(root, "ls-tree", "-r", "-z", "--name-only", commit, "--", rel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s at %s: %w", rel, ref, err)
+	}
+
+	dir, err := os.MkdirTemp("", "lockplane-diff-base-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	snapshot := &Snapshot{Ref: ref, Commit: commit, Dir: dir, Path: filepath.Join(dir, filepath.FromSlash(rel))}
+
+	for _, file := range strings.Split(listing, "\x00") {
+		if file == "" || !isSchemaFile(file) {
+			continue
+		}
+		content, err := git(root, "cat-file", "blob", commit+":"+file)
+		if err != nil {
+			_ = snapshot.Close()
+			return nil, fmt.Errorf("failed to read %s at %s: %w", file, ref, err)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			_ = snapshot.Close()
+			return nil, fmt.Errorf("failed to create directory for %s: %w", file, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			_ = snapshot.Close()
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		snapshot.Files = append(snapshot.Files, file)
+	}
+
+	// Keep the directory shape of the requested path even when it is empty,
+	// so loading it reports "no schema files" rather than "not found"
+	if info.IsDir() {
+		if err := os.MkdirAll(snapshot.Path, 0o755); err != nil {
+			_ = snapshot.Close()
+			return nil, fmt.Errorf("failed to create %s: %w", snapshot.Path, err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// repoRelative returns absPath relative to the repository root, using forward
+// slashes as git does ("." for the root itself)
+func repoRelative(root, absPath string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the git repository at %s", absPath, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func isSchemaFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".sql", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// git runs a git command in dir and returns its standard output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,116 @@
+package gitref
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setupRepo creates a git repository with one commit containing files
+func setupRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "base"},
+		{"tag", "base"},
+	} {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatalf("Failed to set up repository: %v", err)
+		}
+	}
+	return dir
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	repo := setupRepo(t, map[string]string{
+		"schema/users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\n",
+		"schema/old.lp.sql":   "CREATE TABLE old (id BIGINT PRIMARY KEY);\n",
+		"schema/app.db":       "binary",
+		"README.md":           "readme",
+	})
+
+	// Working tree changes after the base revision
+	writeFiles(t, repo, map[string]string{
+		"schema/users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);\n",
+		"schema/posts.lp.sql": "CREATE TABLE posts (id BIGINT PRIMARY KEY);\n",
+	})
+	if err := os.Remove(filepath.Join(repo, "schema", "old.lp.sql")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	snapshot, err := Export("base", filepath.Join(repo, "schema"))
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	defer func() { _ = snapshot.Close() }()
+
+	want := []string{"schema/old.lp.sql", "schema/users.lp.sql"}
+	if !reflect.DeepEqual(snapshot.Files, want) {
+		t.Errorf("Files = %v, want %v", snapshot.Files, want)
+	}
+	content, err := os.ReadFile(filepath.Join(snapshot.Path, "users.lp.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read exported file: %v", err)
+	}
+	if string(content) != "CREATE TABLE users (id BIGINT PRIMARY KEY);\n" {
+		t.Errorf("Expected the base revision's content, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(snapshot.Path, "posts.lp.sql")); !os.IsNotExist(err) {
+		t.Error("Expected a file added after the base revision to be absent")
+	}
+
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("Failed to close snapshot: %v", err)
+	}
+	if _, err := os.Stat(snapshot.Dir); !os.IsNotExist(err) {
+		t.Error("Expected Close to remove the temporary directory")
+	}
+}
+
+func TestExport_PathAddedAfterRevision(t *testing.T) {
+	repo := setupRepo(t, map[string]string{"README.md": "readme"})
+	writeFiles(t, repo, map[string]string{"schema/users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\n"})
+
+	snapshot, err := Export("base", filepath.Join(repo, "schema"))
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	defer func() { _ = snapshot.Close() }()
+
+	if len(snapshot.Files) != 0 {
+		t.Errorf("Expected no files, got %v", snapshot.Files)
+	}
+	if info, err := os.Stat(snapshot.Path); err != nil || !info.IsDir() {
+		t.Errorf("Expected an empty directory at %s", snapshot.Path)
+	}
+}
+
+func TestExport_UnknownRevision(t *testing.T) {
+	repo := setupRepo(t, map[string]string{"schema/users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\n"})
+
+	if _, err := Export("does-not-exist", filepath.Join(repo, "schema")); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+}
@@ -0,0 +1,292 @@
+// Package graph renders entity-relationship diagrams of a schema.
+//
+// Diagrams are built purely from a database.Schema, so any schema lockplane
+// can load or introspect can be drawn. Output is deterministic: tables are
+// sorted by name and foreign keys by name, so diagrams can be committed and
+// diffed.
+package graph
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// Output formats supported by Render
+const (
+	FormatDot     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// Options controls which parts of the schema are drawn
+type Options struct {
+	// Tables are glob patterns matched against table names, with or without
+	// their schema qualifier. Empty means every table.
+	Tables []string
+}
+
+// Render draws the schema in the given format
+func Render(schema *database.Schema, format string, opts Options) (string, error) {
+	g := build(schema, opts)
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatDot:
+		return g.dot(), nil
+	case FormatMermaid:
+		return g.mermaid(), nil
+	default:
+		return "", fmt.Errorf("unsupported graph format %q (use %s or %s)", format, FormatDot, FormatMermaid)
+	}
+}
+
+type node struct {
+	id    string
+	table database.Table
+}
+
+type edge struct {
+	from     *node
+	to       *node
+	fk       database.ForeignKey
+	optional bool
+}
+
+type diagram struct {
+	nodes []*node
+	edges []edge
+}
+
+// build selects the tables to draw and resolves foreign keys between them.
+// Foreign keys to tables that are filtered out or unknown are not drawn.
+func build(schema *database.Schema, opts Options) *diagram {
+	g := &diagram{}
+	if schema == nil {
+		return g
+	}
+
+	for _, table := range schema.Tables {
+		if len(opts.Tables) > 0 && !matchesAny(table, opts.Tables) {
+			continue
+		}
+		g.nodes = append(g.nodes, &node{id: qualifiedName(table), table: table})
+	}
+	sort.Slice(g.nodes, func(i, j int) bool { return g.nodes[i].id < g.nodes[j].id })
+
+	for _, n := range g.nodes {
+		fks := append([]database.ForeignKey(nil), n.table.ForeignKeys...)
+		sort.SliceStable(fks, func(i, j int) bool { return fks[i].Name < fks[j].Name })
+
+		for _, fk := range fks {
+			target := g.lookup(fk.ReferencedTable, n.table.Schema)
+			if target == nil {
+				continue
+			}
+			g.edges = append(g.edges, edge{from: n, to: target, fk: fk, optional: anyNullable(n.table, fk.Columns)})
+		}
+	}
+	return g
+}
+
+// lookup finds the node for a referenced table, which may or may not be
+// schema-qualified
+func (g *diagram) lookup(name, fromSchema string) *node {
+	if strings.Contains(name, ".") {
+		for _, n := range g.nodes {
+			if n.table.Schema+"."+n.table.Name == name {
+				return n
+			}
+		}
+	}
+	for _, n := range g.nodes {
+		if n.table.Name == name && n.table.Schema == fromSchema {
+			return n
+		}
+	}
+	for _, n := range g.nodes {
+		if n.table.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+func (g *diagram) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph schema {\n")
+	b.WriteString("  graph [rankdir=LR];\n")
+	b.WriteString("  node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, n := range g.nodes {
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "  %s [label=<\n", dotID(n.id))
+		b.WriteString("    <table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">\n")
+		fmt.Fprintf(&b, "      <tr><td bgcolor=\"lightgrey\"><b>%s</b></td></tr>\n", html.EscapeString(n.id))
+		for i, col := range n.table.Columns {
+			name := html.EscapeString(col.Name)
+			if col.IsPrimaryKey {
+				name = "<b>" + name + "</b>"
+			}
+			fmt.Fprintf(&b, "      <tr><td port=\"c%d\" align=\"left\">%s <font color=\"grey40\">%s</font></td></tr>\n",
+				i, name, html.EscapeString(col.Type))
+		}
+		b.WriteString("    </table>\n")
+		b.WriteString("  >];\n")
+	}
+
+	if len(g.edges) > 0 {
+		b.WriteString("\n")
+	}
+	for _, e := range g.edges {
+		from, to := dotID(e.from.id), dotID(e.to.id)
+		if len(e.fk.Columns) > 0 && len(e.fk.ReferencedColumns) > 0 {
+			if i := columnIndex(e.from.table, e.fk.Columns[0]); i >= 0 {
+				from = fmt.Sprintf("%s:c%d", from, i)
+			}
+			if i := columnIndex(e.to.table, e.fk.ReferencedColumns[0]); i >= 0 {
+				to = fmt.Sprintf("%s:c%d", to, i)
+			}
+		}
+		style := ""
+		if e.optional {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", from, to, dotID(onDeleteLabel(e.fk)), style)
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *diagram) mermaid() string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+
+	for _, n := range g.nodes {
+		fmt.Fprintf(&b, "    %s {\n", mermaidID(n.id))
+		fkColumns := foreignKeyColumns(n.table)
+		for _, col := range n.table.Columns {
+			var keys []string
+			if col.IsPrimaryKey {
+				keys = append(keys, "PK")
+			}
+			if fkColumns[col.Name] {
+				keys = append(keys, "FK")
+			}
+			line := fmt.Sprintf("        %s %s", mermaidType(col.Type), mermaidID(col.Name))
+			if len(keys) > 0 {
+				line += " " + strings.Join(keys, ", ")
+			}
+			if mermaidType(col.Type) != col.Type {
+				line += fmt.Sprintf(" %q", strings.ReplaceAll(col.Type, `"`, "'"))
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("    }\n")
+	}
+
+	for _, e := range g.edges {
+		parent := "||"
+		if e.optional {
+			parent = "o|"
+		}
+		label := strings.Join(e.fk.Columns, ", ") + " " + onDeleteLabel(e.fk)
+		fmt.Fprintf(&b, "    %s }o--%s %s : %q\n", mermaidID(e.from.id), parent, mermaidID(e.to.id), strings.ReplaceAll(label, `"`, "'"))
+	}
+	return b.String()
+}
+
+// onDeleteLabel describes what happens to referencing rows when the parent is deleted
+func onDeleteLabel(fk database.ForeignKey) string {
+	action := "NO ACTION"
+	if fk.OnDelete != nil && strings.TrimSpace(*fk.OnDelete) != "" {
+		action = strings.ToUpper(strings.TrimSpace(*fk.OnDelete))
+	}
+	return "ON DELETE " + action
+}
+
+func qualifiedName(table database.Table) string {
+	if table.Schema == "" || table.Schema == "public" {
+		return table.Name
+	}
+	return table.Schema + "." + table.Name
+}
+
+func matchesAny(table database.Table, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table.Name); ok {
+			return true
+		}
+		if table.Schema != "" {
+			if ok, _ := path.Match(pattern, table.Schema+"."+table.Name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyNullable(table database.Table, columns []string) bool {
+	for _, name := range columns {
+		if i := columnIndex(table, name); i >= 0 && table.Columns[i].Nullable {
+			return true
+		}
+	}
+	return false
+}
+
+func columnIndex(table database.Table, name string) int {
+	for i, col := range table.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func foreignKeyColumns(table database.Table) map[string]bool {
+	columns := make(map[string]bool)
+	for _, fk := range table.ForeignKeys {
+		for _, name := range fk.Columns {
+			columns[name] = true
+		}
+	}
+	return columns
+}
+
+// dotID quotes a Graphviz identifier
+func dotID(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+var (
+	mermaidInvalidID   = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+	mermaidInvalidType = regexp.MustCompile(`[^A-Za-z0-9_()\[\]-]`)
+)
+
+// mermaidID makes a name usable as a Mermaid entity or attribute name
+func mermaidID(s string) string {
+	id := mermaidInvalidID.ReplaceAllString(s, "_")
+	if id == "" || !isLetter(id[0]) {
+		id = "_" + id
+	}
+	return id
+}
+
+// mermaidType makes a column type usable as a Mermaid attribute type; the
+// original type is kept as the attribute comment when it had to change
+func mermaidType(s string) string {
+	t := mermaidInvalidType.ReplaceAllString(s, "_")
+	if t == "" || !isLetter(t[0]) {
+		t = "_" + t
+	}
+	return t
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
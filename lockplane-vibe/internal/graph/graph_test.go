@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func strPtr(s string) *string { return &s }
+
+func testSchema() *database.Schema {
+	return &database.Schema{
+		Tables: []database.Table{
+			{
+				Name: "posts",
+				Columns: []database.Column{
+					{Name: "id", Type: "bigint", IsPrimaryKey: true},
+					{Name: "author_id", Type: "bigint"},
+					{Name: "editor_id", Type: "bigint", Nullable: true},
+					{Name: "title", Type: "character varying(255)"},
+				},
+				ForeignKeys: []database.ForeignKey{
+					{Name: "posts_editor_id_fkey", Columns: []string{"editor_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: strPtr("SET NULL")},
+					{Name: "posts_author_id_fkey", Columns: []string{"author_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: strPtr("CASCADE")},
+				},
+			},
+			{
+				Name: "users",
+				Columns: []database.Column{
+					{Name: "id", Type: "bigint", IsPrimaryKey: true},
+					{Name: "email", Type: "text"},
+				},
+			},
+			{
+				Name:    "audit_log",
+				Columns: []database.Column{{Name: "id", Type: "bigint", IsPrimaryKey: true}},
+			},
+		},
+	}
+}
+
+func TestRenderMermaid(t *testing.T) {
+	got, err := Render(testSchema(), FormatMermaid, Options{})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+
+	want := `erDiagram
+    audit_log {
+        bigint id PK
+    }
+    posts {
+        bigint id PK
+        bigint author_id FK
+        bigint editor_id FK
+        character_varying(255) title "character varying(255)"
+    }
+    users {
+        bigint id PK
+        text email
+    }
+    posts }o--|| users : "author_id ON DELETE CASCADE"
+    posts }o--o| users : "editor_id ON DELETE SET NULL"
+`
+	if got != want {
+		t.Errorf("Unexpected mermaid output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderDot(t *testing.T) {
+	got, err := Render(testSchema(), FormatDot, Options{})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+
+	for _, want := range []string{
+		"digraph schema {",
+		`<tr><td port="c0" align="left"><b>id</b> <font color="grey40">bigint</font></td></tr>`,
+		`<tr><td port="c1" align="left">email <font color="grey40">text</font></td></tr>`,
+		`"posts":c1 -> "users":c0 [label="ON DELETE CASCADE"];`,
+		`"posts":c2 -> "users":c0 [label="ON DELETE SET NULL", style=dashed];`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected dot output to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	// Tables are sorted so output is stable
+	if strings.Index(got, `"audit_log"`) > strings.Index(got, `"posts"`) {
+		t.Errorf("Expected tables in name order, got:\n%s", got)
+	}
+
+	again, _ := Render(testSchema(), FormatDot, Options{})
+	if again != got {
+		t.Error("Expected identical output for the same schema")
+	}
+}
+
+func TestRenderFiltersTables(t *testing.T) {
+	got, err := Render(testSchema(), FormatMermaid, Options{Tables: []string{"post*"}})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if strings.Contains(got, "users {") || strings.Contains(got, "audit_log") {
+		t.Errorf("Expected only posts to be drawn, got:\n%s", got)
+	}
+	if strings.Contains(got, "}o--") {
+		t.Errorf("Expected edges to filtered-out tables to be dropped, got:\n%s", got)
+	}
+
+	got, err = Render(testSchema(), FormatMermaid, Options{Tables: []string{"posts", "users"}})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if !strings.Contains(got, "posts }o--|| users") {
+		t.Errorf("Expected edge between selected tables, got:\n%s", got)
+	}
+}
+
+func TestRenderQualifiedTables(t *testing.T) {
+	schema := &database.Schema{
+		Tables: []database.Table{
+			{Name: "users", Schema: "auth", Columns: []database.Column{{Name: "id", Type: "uuid", IsPrimaryKey: true}}},
+			{
+				Name:   "profiles",
+				Schema: "public",
+				Columns: []database.Column{
+					{Name: "user_id", Type: "uuid", IsPrimaryKey: true},
+				},
+				ForeignKeys: []database.ForeignKey{
+					{Name: "profiles_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "auth.users", ReferencedColumns: []string{"id"}},
+				},
+			},
+		},
+	}
+
+	got, err := Render(schema, FormatMermaid, Options{Tables: []string{"auth.*", "profiles"}})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if !strings.Contains(got, "auth_users {") || !strings.Contains(got, `profiles }o--|| auth_users : "user_id ON DELETE NO ACTION"`) {
+		t.Errorf("Unexpected output for schema-qualified tables:\n%s", got)
+	}
+}
+
+func TestRenderUnsupportedFormat(t *testing.T) {
+	if _, err := Render(testSchema(), "png", Options{}); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
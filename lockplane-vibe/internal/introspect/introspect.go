@@ -8,38 +8,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 
 	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/dburl"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/lockplane/lockplane/internal/sqliteutil"
 )
 
 // IsConnectionString checks if a string looks like a database connection string
 func IsConnectionString(s string) bool {
-	lower := strings.ToLower(s)
-
-	// Check for common connection string prefixes
-	if strings.HasPrefix(lower, "postgres://") ||
-		strings.HasPrefix(lower, "postgresql://") ||
-		strings.HasPrefix(lower, "libsql://") ||
-		strings.HasPrefix(lower, "sqlite://") ||
-		strings.HasPrefix(lower, "file:") {
-		return true
-	}
-
-	// Check if it looks like a SQLite file path
-	// Always treat .db files as SQLite databases to introspect, not JSON files
-	if strings.HasSuffix(lower, ".db") || strings.HasSuffix(lower, ".sqlite") || strings.HasSuffix(lower, ".sqlite3") {
-		return true
-	}
-
-	// :memory: is a SQLite in-memory database
-	if lower == ":memory:" {
-		return true
-	}
-
-	return false
+	// .db files are always treated as SQLite databases to introspect, not JSON files
+	return dburl.Detect(s) != ""
 }
 
 // LoadSchemaFromConnectionString introspects a database and returns its schema
@@ -71,6 +71,36 @@ func DetectLockModeFromSQL(sql string) LockMode {
 	return LockAccessExclusive
 }
 
+// IsLongRunningSQL returns true if the SQL scans or rewrites a whole table or
+// index, so its run time grows with the size of the data: index builds, column
+// type changes, tablespace moves, SET NOT NULL and constraint validation.
+// Constraints added NOT VALID skip the scan and are not long running.
+func IsLongRunningSQL(sql string) bool {
+	sqlUpper := strings.ToUpper(strings.Join(strings.Fields(sql), " "))
+
+	switch {
+	case strings.HasPrefix(sqlUpper, "CREATE INDEX") || strings.HasPrefix(sqlUpper, "CREATE UNIQUE INDEX"):
+		return true
+	case strings.HasPrefix(sqlUpper, "ALTER INDEX"):
+		return strings.Contains(sqlUpper, " SET TABLESPACE ")
+	case strings.HasPrefix(sqlUpper, "ALTER TABLE"):
+		switch {
+		case strings.Contains(sqlUpper, " SET TABLESPACE "),
+			strings.Contains(sqlUpper, " VALIDATE CONSTRAINT "),
+			strings.Contains(sqlUpper, " SET NOT NULL"),
+			strings.Contains(sqlUpper, " ALTER COLUMN ") && strings.Contains(sqlUpper, " TYPE "):
+			return true
+		case strings.Contains(sqlUpper, " ADD CONSTRAINT "),
+			strings.Contains(sqlUpper, " ADD PRIMARY KEY"),
+			strings.Contains(sqlUpper, " ADD UNIQUE"),
+			strings.Contains(sqlUpper, " ADD FOREIGN KEY"),
+			strings.Contains(sqlUpper, " ADD CHECK"):
+			return !strings.Contains(sqlUpper, " NOT VALID")
+		}
+	}
+	return false
+}
+
 // IsCreateIndexConcurrentlySQL returns true if the SQL creates an index concurrently
 func IsCreateIndexConcurrentlySQL(sql string) bool {
 	sqlUpper := strings.ToUpper(strings.TrimSpace(sql))
@@ -78,6 +108,24 @@ func IsCreateIndexConcurrentlySQL(sql string) bool {
 		strings.HasPrefix(sqlUpper, "CREATE UNIQUE INDEX CONCURRENTLY")
 }
 
+// IsNonTransactionalSQL returns true if the SQL cannot run inside a
+// transaction block: concurrent index builds and drops, REINDEX CONCURRENTLY
+// and VACUUM
+func IsNonTransactionalSQL(sql string) bool {
+	sqlUpper := strings.ToUpper(strings.Join(strings.Fields(sql), " "))
+
+	switch {
+	case strings.HasPrefix(sqlUpper, "CREATE INDEX CONCURRENTLY"),
+		strings.HasPrefix(sqlUpper, "CREATE UNIQUE INDEX CONCURRENTLY"),
+		strings.HasPrefix(sqlUpper, "DROP INDEX CONCURRENTLY"),
+		strings.HasPrefix(sqlUpper, "VACUUM"):
+		return true
+	case strings.HasPrefix(sqlUpper, "REINDEX"):
+		return strings.Contains(sqlUpper, " CONCURRENTLY ")
+	}
+	return false
+}
+
 // IsAddConstraintNotValidSQL returns true if the SQL adds a constraint with NOT VALID
 func IsAddConstraintNotValidSQL(sql string) bool {
 	sqlUpper := strings.ToUpper(strings.TrimSpace(sql))
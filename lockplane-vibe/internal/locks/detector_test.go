@@ -254,6 +254,63 @@ func TestIsCreateIndexConcurrently(t *testing.T) {
 	}
 }
 
+func TestIsLongRunningSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected bool
+	}{
+		{"CREATE INDEX", "CREATE INDEX idx_email ON users(email)", true},
+		{"CREATE UNIQUE INDEX CONCURRENTLY", "CREATE UNIQUE INDEX CONCURRENTLY idx_email ON users(email)", true},
+		{"ALTER COLUMN TYPE", "ALTER TABLE users ALTER COLUMN age TYPE bigint", true},
+		{"SET NOT NULL", "ALTER TABLE users ALTER COLUMN email SET NOT NULL", true},
+		{"Table tablespace move", "ALTER TABLE users SET TABLESPACE fast_ssd", true},
+		{"Index tablespace move", "ALTER INDEX idx_email SET TABLESPACE fast_ssd", true},
+		{"ADD FOREIGN KEY", "ALTER TABLE posts ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)", true},
+		{"ADD FOREIGN KEY NOT VALID", "ALTER TABLE posts ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) NOT VALID", false},
+		{"VALIDATE CONSTRAINT", "ALTER TABLE posts VALIDATE CONSTRAINT fk_user", true},
+		{"ADD COLUMN", "ALTER TABLE users ADD COLUMN email TEXT", false},
+		{"DROP NOT NULL", "ALTER TABLE users ALTER COLUMN email DROP NOT NULL", false},
+		{"SET DEFAULT", "ALTER TABLE users ALTER COLUMN status SET DEFAULT 'active'", false},
+		{"CREATE TABLE", "CREATE TABLE users (id BIGINT PRIMARY KEY)", false},
+		{"DROP INDEX", "DROP INDEX idx_email", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := locks.IsLongRunningSQL(tt.sql); got != tt.expected {
+				t.Errorf("IsLongRunningSQL(%q) = %v, want %v", tt.sql, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsNonTransactionalSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected bool
+	}{
+		{"CREATE INDEX CONCURRENTLY", "CREATE INDEX CONCURRENTLY idx_email ON users(email)", true},
+		{"CREATE UNIQUE INDEX CONCURRENTLY", "create unique index  concurrently idx_email on users(email)", true},
+		{"DROP INDEX CONCURRENTLY", "DROP INDEX CONCURRENTLY IF EXISTS idx_email", true},
+		{"REINDEX CONCURRENTLY", "REINDEX INDEX CONCURRENTLY idx_email", true},
+		{"VACUUM", "VACUUM ANALYZE users", true},
+		{"CREATE INDEX", "CREATE INDEX idx_email ON users(email)", false},
+		{"REINDEX", "REINDEX INDEX idx_email", false},
+		{"DROP INDEX", "DROP INDEX idx_email", false},
+		{"ALTER TABLE", "ALTER TABLE users ADD COLUMN email TEXT", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := locks.IsNonTransactionalSQL(tt.sql); got != tt.expected {
+				t.Errorf("IsNonTransactionalSQL(%q) = %v, want %v", tt.sql, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestIsAddConstraintNotValid(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// IsSessionSetting reports whether stmt only changes a setting of the current
+// session, like the SET and SELECT pg_catalog.set_config(...) statements at
+// the top of a pg_dump script. Session settings are not part of the schema.
+func IsSessionSetting(stmt *pg_query.Node) bool {
+	if stmt == nil {
+		return false
+	}
+	switch node := stmt.Node.(type) {
+	case *pg_query.Node_VariableSetStmt:
+		return true
+	case *pg_query.Node_SelectStmt:
+		targets := node.SelectStmt.TargetList
+		if len(targets) != 1 || node.SelectStmt.FromClause != nil {
+			return false
+		}
+		call := targets[0].GetResTarget().GetVal().GetFuncCall()
+		if call == nil {
+			return false
+		}
+		var names []string
+		for _, name := range call.Funcname {
+			names = append(names, strings.ToLower(name.GetString_().GetSval()))
+		}
+		return slices.Equal(names, []string{"set_config"}) || slices.Equal(names, []string{"pg_catalog", "set_config"})
+	}
+	return false
+}
+
+// IsOwnerChange reports whether stmt only changes the owner of an object,
+// like the ALTER ... OWNER TO statements pg_dump writes after each object.
+// Ownership is not managed.
+func IsOwnerChange(stmt *pg_query.Node) bool {
+	if stmt == nil {
+		return false
+	}
+	switch node := stmt.Node.(type) {
+	case *pg_query.Node_AlterOwnerStmt:
+		return true
+	case *pg_query.Node_AlterTableStmt:
+		// ALTER SEQUENCE and ALTER VIEW ... OWNER TO parse as ALTER TABLE
+		if len(node.AlterTableStmt.Cmds) == 0 {
+			return false
+		}
+		for _, cmd := range node.AlterTableStmt.Cmds {
+			if cmd.GetAlterTableCmd().GetSubtype() != pg_query.AlterTableType_AT_ChangeOwner {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseComment records COMMENT ON TABLE and COMMENT ON COLUMN text on the
+// table or column. Comments on other objects are ignored; IS NULL removes
+// the comment.
+func parseComment(schema *database.Schema, stmt *pg_query.CommentStmt) error {
+	names := objectNameParts(stmt.Object)
+	column := ""
+	switch stmt.Objtype {
+	case pg_query.ObjectType_OBJECT_TABLE:
+	case pg_query.ObjectType_OBJECT_COLUMN:
+		if len(names) < 2 {
+			return fmt.Errorf("COMMENT ON COLUMN missing table name")
+		}
+		column = names[len(names)-1]
+		names = names[:len(names)-1]
+	default:
+		return nil
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("COMMENT ON TABLE missing table name")
+	}
+
+	name := names[len(names)-1]
+	schemaName := ""
+	if len(names) > 1 {
+		schemaName = names[len(names)-2]
+	}
+	table := findTable(schema, schemaName, name)
+	if table == nil {
+		return unknownTableError("COMMENT ON", schema, schemaName, name)
+	}
+
+	if column == "" {
+		table.Comment = stmt.Comment
+		return nil
+	}
+	idx := findColumnIndex(table, column)
+	if idx < 0 {
+		return fmt.Errorf("COMMENT ON COLUMN %s unknown column: %s", table.Name, column)
+	}
+	table.Columns[idx].Comment = stmt.Comment
+	return nil
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// pgDumpScript is a trimmed pg_dump plain-format script
+const pgDumpScript = `--
+-- PostgreSQL database dump
+--
+
+\restrict abc123
+
+-- Dumped from database version 16.4
+-- Dumped by pg_dump version 16.4
+
+SET statement_timeout = 0;
+SET lock_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+SET check_function_bodies = false;
+SET client_min_messages = warning;
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
+
+COMMENT ON EXTENSION pgcrypto IS 'cryptographic functions';
+
+SET default_tablespace = '';
+SET default_table_access_method = heap;
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    email text NOT NULL,
+    bio text
+);
+
+ALTER TABLE public.users OWNER TO app;
+
+COMMENT ON TABLE public.users IS 'Registered users';
+COMMENT ON COLUMN public.users.email IS 'Login; must be unique';
+
+CREATE SEQUENCE public.users_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+ALTER SEQUENCE public.users_id_seq OWNER TO app;
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+
+CREATE TABLE public.posts (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    title text
+);
+
+ALTER TABLE public.posts OWNER TO app;
+
+CREATE VIEW public.user_emails AS
+ SELECT email FROM public.users;
+
+ALTER VIEW public.user_emails OWNER TO app;
+ALTER SCHEMA public OWNER TO app;
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+COPY public.users (id, email, bio) FROM stdin;
+1	a@example.com	it's; not SQL
+2	b@example.com	\N
+\.
+
+COPY public.posts (id, user_id, title) FROM stdin;
+\.
+
+SELECT pg_catalog.setval('public.users_id_seq', 2, true);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.posts
+    ADD CONSTRAINT posts_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email);
+
+ALTER TABLE ONLY public.posts
+    ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+GRANT SELECT ON TABLE public.users TO reporting;
+
+--
+-- PostgreSQL database dump complete
+--
+
+\unrestrict abc123
+`
+
+func TestParseSQLSchemaPgDump(t *testing.T) {
+	schema, err := ParseSQLSchema(pgDumpScript)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+	if len(schema.Tables) != 2 {
+		t.Fatalf("Expected users and posts, got %+v", schema.Tables)
+	}
+
+	users := schema.Tables[0]
+	if users.Name != "users" || users.Schema != "public" {
+		t.Fatalf("Expected public.users first, got %s.%s", users.Schema, users.Name)
+	}
+	if users.Owner != "" {
+		t.Errorf("Expected OWNER TO to be ignored, got owner %q", users.Owner)
+	}
+	if users.Comment != "Registered users" {
+		t.Errorf("Expected table comment, got %q", users.Comment)
+	}
+	if users.Columns[1].Comment != "Login; must be unique" || users.Columns[0].Comment != "" {
+		t.Errorf("Expected only the email column comment, got %q and %q", users.Columns[0].Comment, users.Columns[1].Comment)
+	}
+	if !users.Columns[0].IsPrimaryKey {
+		t.Errorf("Expected ALTER TABLE ONLY ... PRIMARY KEY to apply to users.id")
+	}
+	if users.Columns[0].Default == nil {
+		t.Errorf("Expected users.id to default to its sequence")
+	}
+	if len(users.Indexes) != 1 || users.Indexes[0].Name != "users_email_key" {
+		t.Errorf("Expected the unique email index, got %+v", users.Indexes)
+	}
+
+	posts := schema.Tables[1]
+	if len(posts.ForeignKeys) != 1 || posts.ForeignKeys[0].Name != "posts_user_id_fkey" {
+		t.Errorf("Expected the posts foreign key, got %+v", posts.ForeignKeys)
+	}
+	if len(schema.Sequences) != 1 {
+		t.Errorf("Expected the users_id_seq sequence, got %+v", schema.Sequences)
+	}
+}
+
+func TestParseSQLSchemaCommentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{"unknown table", "COMMENT ON TABLE missing IS 'x';"},
+		{"unknown column", "CREATE TABLE users (id integer); COMMENT ON COLUMN users.missing IS 'x';"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSQLSchema(tt.sql); err == nil {
+				t.Errorf("Expected an error for %q", tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseSQLSchemaCommentIsNull(t *testing.T) {
+	schema, err := ParseSQLSchema("CREATE TABLE users (id integer);\nCOMMENT ON TABLE users IS 'x';\nCOMMENT ON TABLE users IS NULL;")
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+	if schema.Tables[0].Comment != "" {
+		t.Errorf("Expected IS NULL to remove the comment, got %q", schema.Tables[0].Comment)
+	}
+}
+
+func TestDumpStatementKinds(t *testing.T) {
+	tests := []struct {
+		sql     string
+		session bool
+		owner   bool
+	}{
+		{"SET lock_timeout = 0", true, false},
+		{"RESET search_path", true, false},
+		{"SELECT pg_catalog.set_config('search_path', '', false)", true, false},
+		{"SELECT set_config('search_path', '', false)", true, false},
+		{"SELECT pg_catalog.setval('users_id_seq', 2, true)", false, false},
+		{"SELECT set_config('a', 'b', false) FROM users", false, false},
+		{"ALTER TABLE users OWNER TO app", false, true},
+		{"ALTER SEQUENCE users_id_seq OWNER TO app", false, true},
+		{"ALTER FUNCTION f() OWNER TO app", false, true},
+		{"ALTER TABLE users OWNER TO app, ADD COLUMN name text", false, false},
+		{"ALTER TABLE users ADD COLUMN name text", false, false},
+	}
+	for _, tt := range tests {
+		tree, err := pg_query.Parse(tt.sql)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tt.sql, err)
+		}
+		stmt := tree.Stmts[0].Stmt
+		if got := IsSessionSetting(stmt); got != tt.session {
+			t.Errorf("IsSessionSetting(%q) = %v, want %v", tt.sql, got, tt.session)
+		}
+		if got := IsOwnerChange(stmt); got != tt.owner {
+			t.Errorf("IsOwnerChange(%q) = %v, want %v", tt.sql, got, tt.owner)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// Automatic naming of unnamed indexes and constraints.
+//
+// Unnamed objects get the name PostgreSQL would give them, so that a schema
+// applied directly with psql introspects with the names lockplane expects:
+//
+//	UNIQUE (a, b)                 <table>_a_b_key
+//	FOREIGN KEY (a) REFERENCES    <table>_a_fkey
+//	CREATE INDEX ON t (a)         <table>_a_idx (also for unique indexes)
+//
+// Expression columns contribute "expr" (then "expr1", "expr2", ...). Names are
+// limited to 63 bytes: the table and column parts are shortened, the longer
+// one first, until the name fits. When the name is already used by a table,
+// index or foreign key in the same schema, a counter is appended to the
+// label (<table>_a_key1, <table>_a_key2, ...) until it is free.
+
+// maxIdentifierLength is PostgreSQL's NAMEDATALEN - 1
+const maxIdentifierLength = 63
+
+// Labels PostgreSQL appends to automatically named objects
+const (
+	uniqueConstraintLabel = "key"
+	foreignKeyLabel       = "fkey"
+	indexLabel            = "idx"
+)
+
+// chooseObjectName returns the first name built from table, columns and label
+// that isn't taken in the table's schema. table may not have been added to
+// schema yet.
+func chooseObjectName(schema *database.Schema, table *database.Table, columns []string, label string) string {
+	taken := namesInSchema(schema, table)
+	name2 := joinColumnNames(columnNameParts(columns))
+	for pass := 0; ; pass++ {
+		modLabel := label
+		if pass > 0 {
+			modLabel = fmt.Sprintf("%s%d", label, pass)
+		}
+		name := makeObjectName(table.Name, name2, modLabel)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+// makeObjectName joins name1, name2 and label with underscores, shortening
+// name1 and name2 so the result fits in maxIdentifierLength bytes (PostgreSQL's
+// makeObjectName). name2 may be empty.
+func makeObjectName(name1, name2, label string) string {
+	overhead := 0
+	if name2 != "" {
+		overhead++ // Separating underscore
+	}
+	if label != "" {
+		overhead += len(label) + 1
+	}
+	available := maxIdentifierLength - overhead
+
+	name1Len, name2Len := len(name1), len(name2)
+	for name1Len+name2Len > available {
+		if name1Len > name2Len {
+			name1Len--
+		} else {
+			name2Len--
+		}
+	}
+
+	name := clipIdentifier(name1, name1Len)
+	if name2 != "" {
+		name += "_" + clipIdentifier(name2, name2Len)
+	}
+	if label != "" {
+		name += "_" + label
+	}
+	return name
+}
+
+// joinColumnNames joins column name parts with underscores, stopping once
+// the result is longer than any name can be (PostgreSQL's
+// ChooseIndexNameAddition)
+func joinColumnNames(parts []string) string {
+	var sb strings.Builder
+	for _, part := range parts {
+		if sb.Len() > 0 {
+			sb.WriteString("_")
+		}
+		sb.WriteString(clipIdentifier(part, maxIdentifierLength))
+		if sb.Len() > maxIdentifierLength {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// columnNameParts returns the name parts of index columns: expressions
+// (empty names) become "expr", and repeats get a counter (expr1, expr2, ...)
+func columnNameParts(columns []string) []string {
+	parts := make([]string, 0, len(columns))
+	used := map[string]bool{}
+	for _, col := range columns {
+		orig := col
+		if orig == "" {
+			orig = "expr"
+		}
+		part := orig
+		for i := 1; used[part]; i++ {
+			part = fmt.Sprintf("%s%d", orig, i)
+		}
+		used[part] = true
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// clipIdentifier shortens s to at most n bytes without splitting a UTF-8
+// character
+func clipIdentifier(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// namesInSchema returns the names of the tables, indexes and foreign keys in
+// table's schema, which PostgreSQL automatic names must not reuse
+func namesInSchema(schema *database.Schema, table *database.Table) map[string]bool {
+	taken := map[string]bool{}
+	add := func(t *database.Table) {
+		taken[t.Name] = true
+		for _, idx := range t.Indexes {
+			taken[idx.Name] = true
+		}
+		for _, fk := range t.ForeignKeys {
+			taken[fk.Name] = true
+		}
+	}
+	add(table)
+	for i := range schema.Tables {
+		if schema.Tables[i].Schema == table.Schema {
+			add(&schema.Tables[i])
+		}
+	}
+	return taken
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestParseSQLSchema_AutomaticNames(t *testing.T) {
+	sql := `
+CREATE TABLE teams (id integer PRIMARY KEY);
+CREATE TABLE users (
+	id integer PRIMARY KEY,
+	email text UNIQUE,
+	team_id integer,
+	manager_team_id integer,
+	first_name text,
+	last_name text,
+	UNIQUE (first_name, last_name),
+	UNIQUE (email),
+	FOREIGN KEY (team_id) REFERENCES teams (id),
+	FOREIGN KEY (team_id, manager_team_id) REFERENCES teams (id, id)
+);
+ALTER TABLE users ADD COLUMN nickname text UNIQUE;
+ALTER TABLE users ADD FOREIGN KEY (team_id) REFERENCES teams (id);
+CREATE INDEX ON users (last_name);
+CREATE UNIQUE INDEX ON users (email);
+CREATE INDEX ON users (team_id, lower(email)) INCLUDE (first_name);
+CREATE TABLE users_last_name_idx1 (id integer);
+CREATE INDEX ON users (last_name);
+`
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema failed: %v", err)
+	}
+	users := findTable(schema, "", "users")
+
+	var indexes []string
+	for _, idx := range users.Indexes {
+		indexes = append(indexes, idx.Name)
+	}
+	wantIndexes := []string{
+		"users_email_key",
+		"users_first_name_last_name_key",
+		"users_email_key1",
+		"users_nickname_key",
+		"users_last_name_idx",
+		"users_email_idx",
+		"users_team_id_expr_first_name_idx",
+		"users_last_name_idx2",
+	}
+	if strings.Join(indexes, ",") != strings.Join(wantIndexes, ",") {
+		t.Errorf("index names = %v, want %v", indexes, wantIndexes)
+	}
+
+	var fks []string
+	for _, fk := range users.ForeignKeys {
+		fks = append(fks, fk.Name)
+	}
+	wantFKs := []string{"users_team_id_fkey", "users_team_id_manager_team_id_fkey", "users_team_id_fkey1"}
+	if strings.Join(fks, ",") != strings.Join(wantFKs, ",") {
+		t.Errorf("foreign key names = %v, want %v", fks, wantFKs)
+	}
+}
+
+func TestParseSQLSchema_AutomaticNamesPerSchema(t *testing.T) {
+	schema, err := ParseSQLSchema(`
+CREATE TABLE app.users (email text UNIQUE);
+CREATE TABLE audit.users (email text UNIQUE);
+`)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema failed: %v", err)
+	}
+	for _, table := range schema.Tables {
+		if len(table.Indexes) != 1 || table.Indexes[0].Name != "users_email_key" {
+			t.Errorf("%s.%s indexes = %+v, want users_email_key", table.Schema, table.Name, table.Indexes)
+		}
+	}
+}
+
+func TestMakeObjectName(t *testing.T) {
+	long1 := strings.Repeat("a", 40)
+	long2 := strings.Repeat("b", 40)
+	tests := []struct {
+		name1, name2, label string
+		want                string
+	}{
+		{"users", "email", "key", "users_email_key"},
+		{"users", "", "pkey", "users_pkey"},
+		{long1, long2, "key", strings.Repeat("a", 29) + "_" + strings.Repeat("b", 29) + "_key"},
+		{long1, "id", "fkey", long1 + "_id_fkey"},
+		{strings.Repeat("a", 70), "id", "fkey", strings.Repeat("a", 55) + "_id_fkey"},
+		// A multi-byte character is not split
+		{strings.Repeat("a", 54) + "é", "id", "fkey", strings.Repeat("a", 54) + "_id_fkey"},
+	}
+	for _, tt := range tests {
+		got := makeObjectName(tt.name1, tt.name2, tt.label)
+		if got != tt.want {
+			t.Errorf("makeObjectName(%q, %q, %q) = %q, want %q", tt.name1, tt.name2, tt.label, got, tt.want)
+		}
+		if len(got) > maxIdentifierLength {
+			t.Errorf("makeObjectName(%q, %q, %q) is %d bytes, longer than %d", tt.name1, tt.name2, tt.label, len(got), maxIdentifierLength)
+		}
+	}
+}
+
+func TestChooseObjectName_Collisions(t *testing.T) {
+	table := &database.Table{Name: strings.Repeat("t", 60)}
+	schema := &database.Schema{}
+
+	first := chooseObjectName(schema, table, []string{"col"}, uniqueConstraintLabel)
+	table.Indexes = append(table.Indexes, database.Index{Name: first})
+	second := chooseObjectName(schema, table, []string{"col"}, uniqueConstraintLabel)
+
+	if first == second {
+		t.Fatalf("expected distinct names, got %q twice", first)
+	}
+	if !strings.HasSuffix(second, "_key1") || len(second) > maxIdentifierLength {
+		t.Errorf("expected a _key1 name within %d bytes, got %q", maxIdentifierLength, second)
+	}
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// serialSequenceTypes maps serial pseudo-types to the data type of the
+// sequence PostgreSQL creates for them
+var serialSequenceTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+}
+
+// parseCreateSequence adds the sequence a CREATE SEQUENCE statement defines
+func parseCreateSequence(schema *database.Schema, stmt *pg_query.CreateSeqStmt) error {
+	if stmt.Sequence == nil {
+		return fmt.Errorf("CREATE SEQUENCE missing name")
+	}
+	if findSequence(schema, stmt.Sequence.Schemaname, stmt.Sequence.Relname) != nil {
+		// IF NOT EXISTS keeps the sequence defined earlier, as in PostgreSQL
+		if stmt.IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("sequence %s is already defined", qualifiedTableName(stmt.Sequence.Schemaname, stmt.Sequence.Relname))
+	}
+
+	seq := database.Sequence{Name: stmt.Sequence.Relname, Schema: stmt.Sequence.Schemaname}
+	if err := applySequenceOptions(&seq, stmt.Options, false); err != nil {
+		return err
+	}
+	schema.Sequences = append(schema.Sequences, seq)
+	return nil
+}
+
+// parseAlterSequence applies an ALTER SEQUENCE statement to a sequence
+// defined earlier, or to the sequence of a serial column. RESTART is recorded
+// as a request to restart the existing sequence.
+func parseAlterSequence(schema *database.Schema, stmt *pg_query.AlterSeqStmt) error {
+	if stmt.Sequence == nil {
+		return fmt.Errorf("ALTER SEQUENCE missing name")
+	}
+	seq := findSequence(schema, stmt.Sequence.Schemaname, stmt.Sequence.Relname)
+	if seq == nil {
+		if serialSeq, ok := serialColumnSequence(schema, stmt.Sequence.Schemaname, stmt.Sequence.Relname); ok {
+			schema.Sequences = append(schema.Sequences, serialSeq)
+			seq = &schema.Sequences[len(schema.Sequences)-1]
+		}
+	}
+	if seq == nil {
+		if stmt.MissingOk {
+			return nil
+		}
+		return fmt.Errorf("ALTER SEQUENCE references unknown sequence: %s", qualifiedTableName(stmt.Sequence.Schemaname, stmt.Sequence.Relname))
+	}
+	return applySequenceOptions(seq, stmt.Options, true)
+}
+
+// dropSequence removes a sequence from the schema; it reports whether the
+// sequence was defined
+func dropSequence(schema *database.Schema, schemaName, name string) bool {
+	for i := range schema.Sequences {
+		seq := &schema.Sequences[i]
+		if seq.Name == name && (seq.Schema == schemaName || schemaName == "" || seq.Schema == "") {
+			schema.Sequences = append(schema.Sequences[:i], schema.Sequences[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// findSequence finds a sequence by name, matching an unqualified name or
+// sequence as findTable does for tables
+func findSequence(schema *database.Schema, schemaName, name string) *database.Sequence {
+	for i := range schema.Sequences {
+		if schema.Sequences[i].Name == name && schema.Sequences[i].Schema == schemaName {
+			return &schema.Sequences[i]
+		}
+	}
+	for i := range schema.Sequences {
+		seq := &schema.Sequences[i]
+		if seq.Name == name && (schemaName == "" || seq.Schema == "") {
+			return seq
+		}
+	}
+	return nil
+}
+
+// serialColumnSequence returns the sequence PostgreSQL creates for a serial
+// column, named <table>_<column>_seq, when the schema has a serial column
+// whose sequence is name
+func serialColumnSequence(schema *database.Schema, schemaName, name string) (database.Sequence, bool) {
+	for _, table := range schema.Tables {
+		if schemaName != "" && table.Schema != "" && table.Schema != schemaName {
+			continue
+		}
+		for _, col := range table.Columns {
+			dataType, ok := serialSequenceTypes[strings.ToLower(col.Type)]
+			if !ok || table.Name+"_"+col.Name+"_seq" != name {
+				continue
+			}
+			return database.Sequence{
+				Name:     name,
+				Schema:   table.Schema,
+				DataType: dataType,
+				OwnedBy:  database.QualifiedTableName(table.Schema, table.Name) + "." + col.Name,
+			}, true
+		}
+	}
+	return database.Sequence{}, false
+}
+
+// applySequenceOptions sets the options of a CREATE or ALTER SEQUENCE on seq.
+// RESTART is only accepted by ALTER SEQUENCE.
+func applySequenceOptions(seq *database.Sequence, options []*pg_query.Node, alter bool) error {
+	for _, option := range options {
+		def := option.GetDefElem()
+		if def == nil {
+			continue
+		}
+		switch def.Defname {
+		case "as":
+			if typeName := def.Arg.GetTypeName(); typeName != nil {
+				seq.DataType, _ = formatTypeName(typeName)
+			}
+		case "increment", "minvalue", "maxvalue", "start", "cache", "restart":
+			value, err := sequenceOptionValue(def)
+			if err != nil {
+				return err
+			}
+			switch def.Defname {
+			case "increment":
+				seq.Increment = value
+			case "minvalue":
+				seq.MinValue = value // nil for NO MINVALUE
+			case "maxvalue":
+				seq.MaxValue = value
+			case "start":
+				seq.Start = value
+			case "cache":
+				seq.Cache = value
+			case "restart":
+				if !alter {
+					return fmt.Errorf("RESTART is only valid in ALTER SEQUENCE")
+				}
+				// RESTART without a value restarts at the start value
+				if value == nil {
+					start := schemaSequenceStart(*seq)
+					value = &start
+				}
+				seq.Restart = value
+			}
+		case "cycle":
+			seq.Cycle = def.Arg == nil || def.Arg.GetBoolean().GetBoolval()
+		case "owned_by":
+			seq.OwnedBy = sequenceOwner(def)
+		}
+	}
+	return nil
+}
+
+// sequenceOptionValue returns the numeric argument of a sequence option, or
+// nil when it has none (e.g. NO MAXVALUE)
+func sequenceOptionValue(def *pg_query.DefElem) (*int64, error) {
+	if def.Arg == nil {
+		return nil, nil
+	}
+	var value int64
+	switch arg := def.Arg.Node.(type) {
+	case *pg_query.Node_Integer:
+		value = int64(arg.Integer.Ival)
+	case *pg_query.Node_Float:
+		// Values outside the int4 range are parsed as Float nodes
+		parsed, err := strconv.ParseInt(arg.Float.Fval, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %s for a sequence", strings.ToUpper(def.Defname), arg.Float.Fval)
+		}
+		value = parsed
+	default:
+		return nil, fmt.Errorf("invalid %s value for a sequence", strings.ToUpper(def.Defname))
+	}
+	return &value, nil
+}
+
+// sequenceOwner returns the OWNED BY column of a sequence as "table.column",
+// or "" for OWNED BY NONE
+func sequenceOwner(def *pg_query.DefElem) string {
+	var parts []string
+	if list := def.Arg.GetList(); list != nil {
+		for _, item := range list.Items {
+			if str, ok := item.Node.(*pg_query.Node_String_); ok {
+				parts = append(parts, str.String_.Sval)
+			}
+		}
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	column := parts[len(parts)-1]
+	tableName := parts[len(parts)-2]
+	schemaName := ""
+	if len(parts) > 2 {
+		schemaName = parts[len(parts)-3]
+	}
+	return database.QualifiedTableName(schemaName, tableName) + "." + column
+}
+
+// schemaSequenceStart returns the start value of a sequence: its START, or
+// the end of its range it counts from
+func schemaSequenceStart(seq database.Sequence) int64 {
+	if seq.Start != nil {
+		return *seq.Start
+	}
+	ascending := seq.Increment == nil || *seq.Increment > 0
+	if ascending {
+		if seq.MinValue != nil {
+			return *seq.MinValue
+		}
+		return 1
+	}
+	if seq.MaxValue != nil {
+		return *seq.MaxValue
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestParseSQLSchemaSequences(t *testing.T) {
+	sql := `
+CREATE TABLE orders (id serial PRIMARY KEY, number bigint);
+CREATE SEQUENCE billing.invoice_numbers AS integer INCREMENT BY -2 MINVALUE -100 NO MAXVALUE START 5000000000 CACHE 10 CYCLE;
+CREATE SEQUENCE order_numbers OWNED BY public.orders.number;
+ALTER SEQUENCE order_numbers RESTART WITH 1000;
+ALTER SEQUENCE orders_id_seq RESTART;
+CREATE SEQUENCE scratch;
+DROP SEQUENCE scratch;
+`
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+	if len(schema.Sequences) != 3 {
+		t.Fatalf("Expected 3 sequences, got %+v", schema.Sequences)
+	}
+
+	invoices := schema.Sequences[0]
+	if invoices.Schema != "billing" || invoices.DataType != "integer" || *invoices.Increment != -2 ||
+		*invoices.MinValue != -100 || invoices.MaxValue != nil || *invoices.Start != 5000000000 ||
+		*invoices.Cache != 10 || !invoices.Cycle {
+		t.Errorf("Unexpected options of invoice_numbers: %+v", invoices)
+	}
+
+	orders := schema.Sequences[1]
+	if orders.OwnedBy != "orders.number" || orders.Restart == nil || *orders.Restart != 1000 {
+		t.Errorf("Expected order_numbers owned by orders.number and restarted at 1000, got %+v", orders)
+	}
+
+	// ALTER SEQUENCE on the sequence of a serial column round-trips its owner
+	serial := schema.Sequences[2]
+	want := database.Sequence{Name: "orders_id_seq", DataType: "integer", OwnedBy: "orders.id"}
+	if serial.Name != want.Name || serial.DataType != want.DataType || serial.OwnedBy != want.OwnedBy ||
+		serial.Restart == nil || *serial.Restart != 1 {
+		t.Errorf("Expected %+v restarted at 1, got %+v", want, serial)
+	}
+}
+
+func TestParseSQLSchemaSequenceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"unknown sequence", "ALTER SEQUENCE missing CACHE 5;", "unknown sequence: missing"},
+		{"duplicate", "CREATE SEQUENCE s; CREATE SEQUENCE s;", "already defined"},
+		{"unknown drop", "DROP SEQUENCE missing;", "unknown sequence: missing"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSQLSchema(tt.sql)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	for _, sql := range []string{"ALTER SEQUENCE IF EXISTS missing CACHE 5;", "DROP SEQUENCE IF EXISTS missing;", "CREATE SEQUENCE s; CREATE SEQUENCE IF NOT EXISTS s;"} {
+		if _, err := ParseSQLSchema(sql); err != nil {
+			t.Errorf("Expected %q to parse, got %v", sql, err)
+		}
+	}
+}
@@ -7,145 +7,326 @@ package parser
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/sqlsplit"
 	pg_query "github.com/pganalyze/pg_query_go/v6"
 )
 
 // SQL parsing utilities for extracting identifiers from SQL statements
 // These are simplified parsers that work for the SQL we generate
 
+// identifierPattern matches one identifier, bare or double-quoted as drivers
+// quote reserved words and mixed-case names
+const identifierPattern = `(?:"(?:[^"]|"")+"|[\w$]+)`
+
+// qualifiedIdentifierPattern matches an identifier with an optional schema
+const qualifiedIdentifierPattern = identifierPattern + `(?:\s*\.\s*` + identifierPattern + `)?`
+
+// qualifiedIdentifierPartPattern matches each part of a qualified identifier
+var qualifiedIdentifierPartPattern = regexp.MustCompile(identifierPattern)
+
+// unquoteName returns a possibly qualified identifier matched by
+// qualifiedIdentifierPattern as the name it stands for: quotes are removed
+// and the parts joined with a dot, as database.QualifiedTableName does
+func unquoteName(name string) string {
+	parts := qualifiedIdentifierPartPattern.FindAllString(name, -1)
+	for i, part := range parts {
+		if unquoted, ok := strings.CutPrefix(part, `"`); ok {
+			parts[i] = strings.ReplaceAll(strings.TrimSuffix(unquoted, `"`), `""`, `"`)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
 // extractTableNameFromCreate extracts table name from CREATE TABLE statement
 func ExtractTableNameFromCreate(sql string) (string, error) {
 	// Pattern: CREATE TABLE <name> ...
-	re := regexp.MustCompile(`CREATE\s+TABLE\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(` + qualifiedIdentifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 2 {
 		return "", fmt.Errorf("could not extract table name from: %s", sql)
 	}
-	return matches[1], nil
+	return unquoteName(matches[1]), nil
 }
 
 // extractTableNameFromDrop extracts table name from DROP TABLE statement
 func ExtractTableNameFromDrop(sql string) (string, error) {
 	// Pattern: DROP TABLE <name> [CASCADE]
-	re := regexp.MustCompile(`DROP\s+TABLE\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(` + qualifiedIdentifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 2 {
 		return "", fmt.Errorf("could not extract table name from: %s", sql)
 	}
-	return matches[1], nil
+	return unquoteName(matches[1]), nil
+}
+
+// IsTruncate reports whether sql is a TRUNCATE statement. Unlike ContainsSQL
+// it doesn't match identifiers such as truncated_at.
+func IsTruncate(sql string) bool {
+	return truncatePrefix.MatchString(sql)
+}
+
+var truncatePrefix = regexp.MustCompile(`(?i)^\s*TRUNCATE\b`)
+
+// ExtractTableNameFromTruncate extracts the first table name from a TRUNCATE statement
+func ExtractTableNameFromTruncate(sql string) (string, error) {
+	// Pattern: TRUNCATE [TABLE] [ONLY] <name> [, ...]
+	re := regexp.MustCompile(`(?i)^\s*TRUNCATE\s+(?:TABLE\s+)?(?:ONLY\s+)?(` + qualifiedIdentifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not extract table name from: %s", sql)
+	}
+	return unquoteName(matches[1]), nil
 }
 
 // extractTableAndColumnFromAddColumn extracts table and column name from ALTER TABLE ADD COLUMN
 func ExtractTableAndColumnFromAddColumn(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> ADD COLUMN <column> ...
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+ADD\s+COLUMN\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ADD\s+COLUMN\s+(` + identifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndColumnFromDropColumn extracts table and column name from ALTER TABLE DROP COLUMN
 func ExtractTableAndColumnFromDropColumn(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> DROP COLUMN <column>
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+DROP\s+COLUMN\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+DROP\s+COLUMN\s+(` + identifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndColumnFromAlterType extracts table and column from ALTER COLUMN TYPE
 func ExtractTableAndColumnFromAlterType(sql string) (string, string, error) {
-	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> TYPE <type>
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+ALTER\s+COLUMN\s+(\w+)\s+TYPE`)
+	// Pattern: ALTER TABLE <table> [ALTER COLUMN <column> DROP DEFAULT,] ALTER COLUMN <column> TYPE <type>
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+(?:ALTER\s+COLUMN\s+` + identifierPattern + `\s+DROP\s+DEFAULT\s*,\s*)?ALTER\s+COLUMN\s+(` + identifierPattern + `)\s+TYPE`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
+	}
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
+}
+
+// ExtractTypeFromAlterType extracts the new type from ALTER COLUMN TYPE,
+// without any USING expression
+func ExtractTypeFromAlterType(sql string) (string, error) {
+	re := regexp.MustCompile(`(?is)ALTER\s+COLUMN\s+` + identifierPattern + `\s+TYPE\s+(.+?)(?:\s+USING\s.*)?\s*;?\s*$`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not extract type from: %s", sql)
+	}
+	return matches[1], nil
+}
+
+// ExtractTableAndColumnFromAlterColumn extracts table and column from any ALTER COLUMN
+func ExtractTableAndColumnFromAlterColumn(sql string) (string, string, error) {
+	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> ...
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ALTER\s+COLUMN\s+(` + identifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndColumnFromAlterNotNull extracts table and column from ALTER COLUMN SET/DROP NOT NULL
 func ExtractTableAndColumnFromAlterNotNull(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> SET/DROP NOT NULL
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+ALTER\s+COLUMN\s+(\w+)\s+(SET|DROP)\s+NOT\s+NULL`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ALTER\s+COLUMN\s+(` + identifierPattern + `)\s+(SET|DROP)\s+NOT\s+NULL`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndColumnFromSetDefault extracts table and column from SET DEFAULT
 func ExtractTableAndColumnFromSetDefault(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> SET DEFAULT ...
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+ALTER\s+COLUMN\s+(\w+)\s+SET\s+DEFAULT`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ALTER\s+COLUMN\s+(` + identifierPattern + `)\s+SET\s+DEFAULT`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndColumnFromDropDefault extracts table and column from DROP DEFAULT
 func ExtractTableAndColumnFromDropDefault(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> DROP DEFAULT
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+ALTER\s+COLUMN\s+(\w+)\s+DROP\s+DEFAULT`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ALTER\s+COLUMN\s+(` + identifierPattern + `)\s+DROP\s+DEFAULT`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractIndexNameFromCreate extracts index name from CREATE INDEX
 func ExtractIndexNameFromCreate(sql string) (string, error) {
-	// Pattern: CREATE [UNIQUE] INDEX <name> ON ...
-	re := regexp.MustCompile(`CREATE\s+(UNIQUE\s+)?INDEX\s+(\w+)\s+ON`)
+	// Pattern: CREATE [UNIQUE] INDEX [CONCURRENTLY] [IF NOT EXISTS] <name> ON ...
+	re := regexp.MustCompile(`(?i)CREATE\s+(UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + identifierPattern + `)\s+ON`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", fmt.Errorf("could not extract index name from: %s", sql)
 	}
-	return matches[2], nil
+	return unquoteName(matches[2]), nil
+}
+
+// ExtractTableNameFromCreateIndex extracts the table name from CREATE INDEX
+func ExtractTableNameFromCreateIndex(sql string) (string, error) {
+	// Pattern: CREATE [UNIQUE] INDEX [CONCURRENTLY] [IF NOT EXISTS] <name> ON [ONLY] <table> ...
+	re := regexp.MustCompile(`(?i)CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:` + identifierPattern + `\s+)?ON\s+(?:ONLY\s+)?(` + qualifiedIdentifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not extract table name from: %s", sql)
+	}
+	return unquoteName(matches[1]), nil
 }
 
 // extractIndexNameFromDrop extracts index name from DROP INDEX
 func ExtractIndexNameFromDrop(sql string) (string, error) {
 	// Pattern: DROP INDEX <name>
-	re := regexp.MustCompile(`DROP\s+INDEX\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)DROP\s+INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?(` + qualifiedIdentifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 2 {
 		return "", fmt.Errorf("could not extract index name from: %s", sql)
 	}
-	return matches[1], nil
+	return unquoteName(matches[1]), nil
+}
+
+// ExtractTableAndConstraintsFromRenameConstraint extracts the table and the old
+// and new names from ALTER TABLE ... RENAME CONSTRAINT
+func ExtractTableAndConstraintsFromRenameConstraint(sql string) (string, string, string, error) {
+	// Pattern: ALTER TABLE <table> RENAME CONSTRAINT <old> TO <new>
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+RENAME\s+CONSTRAINT\s+(` + identifierPattern + `)\s+TO\s+(` + identifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 4 {
+		return "", "", "", fmt.Errorf("could not extract table and constraint names from: %s", sql)
+	}
+	return unquoteName(matches[1]), unquoteName(matches[2]), unquoteName(matches[3]), nil
+}
+
+// ExtractTableAndColumnsFromRenameColumn extracts the table and the old and
+// new names from ALTER TABLE ... RENAME COLUMN
+func ExtractTableAndColumnsFromRenameColumn(sql string) (string, string, string, error) {
+	// Pattern: ALTER TABLE <table> RENAME COLUMN <old> TO <new>
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+RENAME\s+COLUMN\s+(` + identifierPattern + `)\s+TO\s+(` + identifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 4 {
+		return "", "", "", fmt.Errorf("could not extract table and column names from: %s", sql)
+	}
+	return unquoteName(matches[1]), unquoteName(matches[2]), unquoteName(matches[3]), nil
+}
+
+// ExtractIndexNamesFromRename extracts the old and new names from ALTER INDEX ... RENAME TO
+func ExtractIndexNamesFromRename(sql string) (string, string, error) {
+	// Pattern: ALTER INDEX <old> RENAME TO <new>
+	re := regexp.MustCompile(`(?i)ALTER\s+INDEX\s+(` + qualifiedIdentifierPattern + `)\s+RENAME\s+TO\s+(` + identifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return "", "", fmt.Errorf("could not extract index names from: %s", sql)
+	}
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndConstraintFromAddConstraint extracts table and constraint name from ADD CONSTRAINT
 func ExtractTableAndConstraintFromAddConstraint(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> ADD CONSTRAINT <constraint> ...
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+ADD\s+CONSTRAINT\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ADD\s+CONSTRAINT\s+(` + identifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and constraint from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
 }
 
 // extractTableAndConstraintFromDropConstraint extracts table and constraint name from DROP CONSTRAINT
 func ExtractTableAndConstraintFromDropConstraint(sql string) (string, string, error) {
 	// Pattern: ALTER TABLE <table> DROP CONSTRAINT <constraint>
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)\s+DROP\s+CONSTRAINT\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+DROP\s+CONSTRAINT\s+(` + identifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 3 {
 		return "", "", fmt.Errorf("could not extract table and constraint from: %s", sql)
 	}
-	return matches[1], matches[2], nil
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
+}
+
+// ExtractObjectAndTablespaceFromSetTablespace extracts the object kind (TABLE or INDEX), name and tablespace from SET TABLESPACE
+func ExtractObjectAndTablespaceFromSetTablespace(sql string) (string, string, string, error) {
+	// Pattern: ALTER TABLE|INDEX <name> SET TABLESPACE <tablespace>
+	re := regexp.MustCompile(`(?i)ALTER\s+(TABLE|INDEX)\s+(` + qualifiedIdentifierPattern + `)\s+SET\s+TABLESPACE\s+(` + identifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 4 {
+		return "", "", "", fmt.Errorf("could not extract object and tablespace from: %s", sql)
+	}
+	return strings.ToUpper(matches[1]), unquoteName(matches[2]), unquoteName(matches[3]), nil
+}
+
+// ExtractTableAndReplicaIdentity extracts the table name and identity from REPLICA IDENTITY
+func ExtractTableAndReplicaIdentity(sql string) (string, string, error) {
+	// Pattern: ALTER TABLE <table> REPLICA IDENTITY DEFAULT|FULL|NOTHING|USING INDEX <index>
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+REPLICA\s+IDENTITY\s+(DEFAULT|FULL|NOTHING|USING\s+INDEX\s+` + identifierPattern + `)`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return "", "", fmt.Errorf("could not extract table and replica identity from: %s", sql)
+	}
+	return unquoteName(matches[1]), matches[2], nil
+}
+
+// ExtractTableAndColumnFromSetStatistics extracts table and column from SET STATISTICS
+func ExtractTableAndColumnFromSetStatistics(sql string) (string, string, error) {
+	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> SET STATISTICS <target>
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ALTER\s+COLUMN\s+(` + identifierPattern + `)\s+SET\s+STATISTICS`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
+	}
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
+}
+
+// ExtractTableAndColumnFromSetStorage extracts table and column from SET STORAGE
+func ExtractTableAndColumnFromSetStorage(sql string) (string, string, error) {
+	// Pattern: ALTER TABLE <table> ALTER COLUMN <column> SET STORAGE <storage>
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)\s+ALTER\s+COLUMN\s+(` + identifierPattern + `)\s+SET\s+STORAGE`)
+	matches := re.FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return "", "", fmt.Errorf("could not extract table and column from: %s", sql)
+	}
+	return unquoteName(matches[1]), unquoteName(matches[2]), nil
+}
+
+// ExtractTableAndStorageParameters extracts the table and parameter names from
+// ALTER TABLE ... SET (...) or RESET (...), and reports whether it is a RESET
+func ExtractTableAndStorageParameters(sql string) (string, []string, bool, error) {
+	result, err := pg_query.Parse(sql)
+	if err != nil || len(result.Stmts) != 1 {
+		return "", nil, false, fmt.Errorf("could not extract table and storage parameters from: %s", sql)
+	}
+	stmt := result.Stmts[0].Stmt.GetAlterTableStmt()
+	if stmt == nil || stmt.Relation == nil || len(stmt.Cmds) != 1 {
+		return "", nil, false, fmt.Errorf("could not extract table and storage parameters from: %s", sql)
+	}
+	cmd := stmt.Cmds[0].GetAlterTableCmd()
+	if cmd == nil || (cmd.Subtype != pg_query.AlterTableType_AT_SetRelOptions && cmd.Subtype != pg_query.AlterTableType_AT_ResetRelOptions) {
+		return "", nil, false, fmt.Errorf("could not extract table and storage parameters from: %s", sql)
+	}
+
+	var names []string
+	for _, item := range cmd.GetDef().GetList().GetItems() {
+		if def := item.GetDefElem(); def != nil {
+			names = append(names, storageParameterName(def))
+		}
+	}
+	return stmt.Relation.Relname, names, cmd.Subtype == pg_query.AlterTableType_AT_ResetRelOptions, nil
 }
 
 // ContainsSQL is a helper to check if SQL contains a substring (case-insensitive)
@@ -153,16 +334,64 @@ func ContainsSQL(sql, substr string) bool {
 	return strings.Contains(strings.ToUpper(sql), strings.ToUpper(substr))
 }
 
-// findTable locates a table by name within the schema
-func findTable(schema *database.Schema, name string) *database.Table {
+// findTable locates a table by name within the schema.
+// Names are compared exactly: pg_query already folds unquoted identifiers to
+// lower case and keeps quoted ones as written, which matches how CREATE TABLE
+// stores them. A schema-qualified reference prefers a table in that schema but
+// also matches an unqualified CREATE; an unqualified reference matches any schema.
+func findTable(schema *database.Schema, schemaName, name string) *database.Table {
 	for i := range schema.Tables {
-		if schema.Tables[i].Name == name {
+		if schema.Tables[i].Name == name && schema.Tables[i].Schema == schemaName {
 			return &schema.Tables[i]
 		}
 	}
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if table.Name != name {
+			continue
+		}
+		if schemaName == "" || table.Schema == "" {
+			return table
+		}
+	}
 	return nil
 }
 
+// unknownTableError builds an error for a table reference that couldn't be
+// resolved, listing the known tables and hinting at quoting mismatches.
+func unknownTableError(stmtKind string, schema *database.Schema, schemaName, name string) error {
+	ref := qualifiedTableName(schemaName, name)
+
+	known := make([]string, 0, len(schema.Tables))
+	var caseMatch string
+	for _, table := range schema.Tables {
+		qualified := qualifiedTableName(table.Schema, table.Name)
+		known = append(known, qualified)
+		if caseMatch == "" && strings.EqualFold(table.Name, name) {
+			caseMatch = qualified
+		}
+	}
+
+	msg := fmt.Sprintf("%s references unknown table: %s", stmtKind, ref)
+	if caseMatch != "" {
+		msg += fmt.Sprintf(" (did you mean %s? quoted identifiers are case-sensitive, unquoted ones are folded to lower case)", caseMatch)
+	}
+	if len(known) == 0 {
+		msg += "; no tables have been defined yet"
+	} else {
+		msg += fmt.Sprintf("; known tables: %s", strings.Join(known, ", "))
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// qualifiedTableName formats a table name with its schema, if any
+func qualifiedTableName(schemaName, name string) string {
+	if schemaName == "" {
+		return name
+	}
+	return schemaName + "." + name
+}
+
 // findColumnIndex finds a column index within a table by name
 func findColumnIndex(table *database.Table, columnName string) int {
 	for i := range table.Columns {
@@ -226,8 +455,9 @@ func ParseSQLSchemaWithDialect(sql string, dialect database.Dialect) (*database.
 
 // parsePostgresSQLSchema parses SQL DDL via pg_query for PostgreSQL schemas.
 func parsePostgresSQLSchema(sql string) (*database.Schema, error) {
-	// Parse the SQL
-	tree, err := pg_query.Parse(sql)
+	// Parse the SQL. COPY rows and psql meta-commands in a pg_dump script
+	// aren't SQL.
+	tree, err := pg_query.Parse(sqlsplit.BlankDumpData(sql))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse SQL: %w", err)
 	}
@@ -237,15 +467,23 @@ func parsePostgresSQLSchema(sql string) (*database.Schema, error) {
 		Dialect: database.DialectPostgres,
 	}
 
-	// Walk the parse tree
+	// Walk the parse tree. Temporary tables are not part of the schema, and
+	// neither are the session settings and ownership in a pg_dump script.
+	temps := NewTemporaryTables()
 	for _, stmt := range tree.Stmts {
-		if stmt.Stmt == nil {
+		if stmt.Stmt == nil || temps.Skip(stmt.Stmt) || IsOwnerChange(stmt.Stmt) {
 			continue
 		}
 
 		switch node := stmt.Stmt.Node.(type) {
 		case *pg_query.Node_CreateStmt:
-			table, err := parseCreateTable(node.CreateStmt)
+			// IF NOT EXISTS behaves as in PostgreSQL: a table defined
+			// earlier in the schema is kept as it is
+			if node.CreateStmt.IfNotExists && node.CreateStmt.Relation != nil &&
+				findTable(schema, node.CreateStmt.Relation.Schemaname, node.CreateStmt.Relation.Relname) != nil {
+				continue
+			}
+			table, err := parseCreateTable(schema, node.CreateStmt)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse CREATE TABLE: %w", err)
 			}
@@ -266,25 +504,54 @@ func parsePostgresSQLSchema(sql string) (*database.Schema, error) {
 				return nil, fmt.Errorf("failed to parse ALTER TABLE: %w", err)
 			}
 
-			// We can add more statement types later (ALTER TABLE, etc.)
+		case *pg_query.Node_DropStmt:
+			if err := parseDrop(schema, node.DropStmt); err != nil {
+				return nil, fmt.Errorf("failed to parse DROP: %w", err)
+			}
+
+		case *pg_query.Node_CreateSeqStmt:
+			if err := parseCreateSequence(schema, node.CreateSeqStmt); err != nil {
+				return nil, fmt.Errorf("failed to parse CREATE SEQUENCE: %w", err)
+			}
+
+		case *pg_query.Node_AlterSeqStmt:
+			if err := parseAlterSequence(schema, node.AlterSeqStmt); err != nil {
+				return nil, fmt.Errorf("failed to parse ALTER SEQUENCE: %w", err)
+			}
+
+		case *pg_query.Node_CommentStmt:
+			if err := parseComment(schema, node.CommentStmt); err != nil {
+				return nil, fmt.Errorf("failed to parse COMMENT: %w", err)
+			}
 		}
 	}
 
 	return schema, nil
 }
 
-// parseCreateTable converts a CreateStmt AST node to a Table
-func parseCreateTable(stmt *pg_query.CreateStmt) (*database.Table, error) {
+// parseCreateTable converts a CreateStmt AST node to a Table. Tables created
+// earlier in the schema are needed to expand LIKE clauses.
+func parseCreateTable(schema *database.Schema, stmt *pg_query.CreateStmt) (*database.Table, error) {
 	if stmt.Relation == nil {
 		return nil, fmt.Errorf("CREATE TABLE missing relation")
 	}
 
 	table := &database.Table{
 		Name:        stmt.Relation.Relname,
+		Schema:      stmt.Relation.Schemaname,
 		Columns:     []database.Column{},
 		Indexes:     []database.Index{},
 		ForeignKeys: []database.ForeignKey{},
 	}
+	if stmt.Tablespacename != "" {
+		tablespace := stmt.Tablespacename
+		table.Tablespace = &tablespace
+	}
+	for _, option := range stmt.Options {
+		if def := option.GetDefElem(); def != nil {
+			setStorageParameter(table, def)
+		}
+	}
 
 	// Parse columns and constraints
 	for _, elt := range stmt.TableElts {
@@ -299,18 +566,117 @@ func parseCreateTable(stmt *pg_query.CreateStmt) (*database.Table, error) {
 				return nil, err
 			}
 			table.Columns = append(table.Columns, *col)
+			addColumnUniqueIndexes(schema, table, node.ColumnDef)
 
 		case *pg_query.Node_Constraint:
-			err := parseTableConstraint(table, node.Constraint)
+			err := parseTableConstraint(schema, table, node.Constraint)
 			if err != nil {
 				return nil, err
 			}
+
+		case *pg_query.Node_TableLikeClause:
+			if err := applyTableLike(schema, table, node.TableLikeClause); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return table, nil
 }
 
+// Bits of TableLikeClause.Options (CREATE_TABLE_LIKE_* in PostgreSQL)
+const (
+	tableLikeDefaults = 1 << 3
+	tableLikeIndexes  = 1 << 6
+)
+
+// serialBaseTypes maps serial pseudo-types to the column type LIKE copies
+var serialBaseTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+}
+
+// applyTableLike copies the columns of an earlier table into a table created
+// with LIKE, the way PostgreSQL does: names, types and NOT NULL always;
+// defaults with INCLUDING DEFAULTS; the primary key and indexes with INCLUDING
+// INDEXES. Foreign keys are never copied.
+func applyTableLike(schema *database.Schema, table *database.Table, like *pg_query.TableLikeClause) error {
+	if like.Relation == nil || like.Relation.Relname == "" {
+		return fmt.Errorf("LIKE clause missing relation")
+	}
+
+	source := findTable(schema, like.Relation.Schemaname, like.Relation.Relname)
+	if source == nil {
+		stmtKind := fmt.Sprintf("CREATE TABLE %s (LIKE ...)", qualifiedTableName(table.Schema, table.Name))
+		err := unknownTableError(stmtKind, schema, like.Relation.Schemaname, like.Relation.Relname)
+		return fmt.Errorf("%w (the LIKE source must be created earlier in the schema)", err)
+	}
+
+	includeDefaults := like.Options&tableLikeDefaults != 0
+	includeIndexes := like.Options&tableLikeIndexes != 0
+
+	for _, sourceCol := range source.Columns {
+		col := database.Column{
+			Name:         sourceCol.Name,
+			Type:         sourceCol.Type,
+			Nullable:     sourceCol.Nullable,
+			IsPrimaryKey: sourceCol.IsPrimaryKey && includeIndexes,
+		}
+		if sourceCol.TypeMetadata != nil {
+			meta := *sourceCol.TypeMetadata
+			col.TypeMetadata = &meta
+		}
+
+		// A serial column's sequence belongs to the source table; the copy is a
+		// plain integer column whose default (if copied) uses that sequence
+		if baseType, ok := serialBaseTypes[strings.ToLower(sourceCol.Type)]; ok {
+			col.Type = baseType
+			col.TypeMetadata = nil
+			if includeDefaults {
+				sequence := fmt.Sprintf("%s_%s_seq", source.Name, sourceCol.Name)
+				if source.Schema != "" {
+					sequence = source.Schema + "." + sequence
+				}
+				def := fmt.Sprintf("nextval('%s'::regclass)", sequence)
+				col.Default = &def
+				col.DefaultMetadata = &database.DefaultMetadata{Raw: def, Dialect: database.DialectPostgres}
+			}
+		} else if includeDefaults && sourceCol.Default != nil {
+			def := *sourceCol.Default
+			col.Default = &def
+			if sourceCol.DefaultMetadata != nil {
+				meta := *sourceCol.DefaultMetadata
+				col.DefaultMetadata = &meta
+			}
+		}
+
+		table.Columns = append(table.Columns, col)
+	}
+
+	if includeIndexes {
+		for _, sourceIdx := range source.Indexes {
+			idx := sourceIdx
+			idx.Columns = append([]string(nil), sourceIdx.Columns...)
+			idx.Ordering = append([]database.IndexColumn(nil), sourceIdx.Ordering...)
+			idx.IncludeColumns = slices.Clone(sourceIdx.IncludeColumns)
+			idx.Name = likeIndexName(schema, table, idx)
+			table.Indexes = append(table.Indexes, idx)
+		}
+	}
+
+	return nil
+}
+
+// likeIndexName returns the name PostgreSQL gives an index copied by LIKE
+func likeIndexName(schema *database.Schema, table *database.Table, idx database.Index) string {
+	label := indexLabel
+	if idx.Unique {
+		label = uniqueConstraintLabel
+	}
+	return chooseObjectName(schema, table, append(slices.Clone(idx.Columns), idx.IncludeColumns...), label)
+}
+
 // parseColumnDef converts a ColumnDef AST node to a Column
 func parseColumnDef(colDef *pg_query.ColumnDef) (*database.Column, error) {
 	if colDef.Colname == "" {
@@ -341,9 +707,57 @@ func parseColumnDef(colDef *pg_query.ColumnDef) (*database.Column, error) {
 		}
 	}
 
+	col.Storage = parseStorage(colDef.StorageName)
+
 	return col, nil
 }
 
+// setStorageParameter records a WITH (...) or SET (...) storage parameter.
+// A parameter without a value is a boolean set to true.
+func setStorageParameter(table *database.Table, def *pg_query.DefElem) {
+	value := "true"
+	if def.Arg != nil {
+		switch arg := def.Arg.Node.(type) {
+		case *pg_query.Node_Integer:
+			value = fmt.Sprintf("%d", arg.Integer.Ival)
+		case *pg_query.Node_Float:
+			value = arg.Float.Fval
+		case *pg_query.Node_String_:
+			value = arg.String_.Sval
+		case *pg_query.Node_Boolean:
+			value = fmt.Sprintf("%t", arg.Boolean.Boolval)
+		case *pg_query.Node_TypeName:
+			value, _ = formatTypeName(arg.TypeName)
+		default:
+			value = formatExpr(def.Arg)
+		}
+	}
+	if table.StorageParameters == nil {
+		table.StorageParameters = map[string]string{}
+	}
+	table.StorageParameters[storageParameterName(def)] = value
+}
+
+// storageParameterName returns the parameter name, prefixed with its
+// namespace (e.g. "toast.autovacuum_enabled")
+func storageParameterName(def *pg_query.DefElem) string {
+	name := strings.ToLower(def.Defname)
+	if def.Defnamespace != "" {
+		name = strings.ToLower(def.Defnamespace) + "." + name
+	}
+	return name
+}
+
+// parseStorage normalizes a STORAGE specification; DEFAULT and an empty
+// name mean the type's default storage.
+func parseStorage(name string) *string {
+	if name == "" || strings.EqualFold(name, "default") {
+		return nil
+	}
+	storage := strings.ToUpper(name)
+	return &storage
+}
+
 // formatTypeName converts TypeName AST to a string representation with metadata.
 func formatTypeName(typeName *pg_query.TypeName) (string, *database.TypeMetadata) {
 	if len(typeName.Names) == 0 {
@@ -365,21 +779,32 @@ func formatTypeName(typeName *pg_query.TypeName) (string, *database.TypeMetadata
 		typeStr = parts[len(parts)-1]
 	}
 
-	// Normalize PostgreSQL internal types to standard SQL types
-	typeStr = normalizePostgreSQLType(typeStr)
-
-	// Add type modifiers (e.g., VARCHAR(255))
-	if len(typeName.Typmods) > 0 {
-		var mods []string
-		for _, mod := range typeName.Typmods {
-			if constNode, ok := mod.Node.(*pg_query.Node_AConst); ok {
-				if ival := constNode.AConst.GetIval(); ival != nil {
-					mods = append(mods, fmt.Sprintf("%d", ival.Ival))
-				}
+	var mods []int32
+	for _, mod := range typeName.Typmods {
+		if constNode, ok := mod.Node.(*pg_query.Node_AConst); ok {
+			if ival := constNode.AConst.GetIval(); ival != nil {
+				mods = append(mods, ival.Ival)
 			}
 		}
+	}
+
+	if temporal, ok := formatTemporalType(strings.ToLower(typeStr), mods); ok {
+		// Precision goes before "with time zone", as PostgreSQL prints it
+		typeStr = temporal
+		if len(mods) > 0 {
+			rawBase = temporal
+		}
+	} else {
+		// Normalize PostgreSQL internal types to standard SQL types
+		typeStr = normalizePostgreSQLType(typeStr)
+
+		// Add type modifiers (e.g., VARCHAR(255))
 		if len(mods) > 0 {
-			modStr := strings.Join(mods, ",")
+			modStrs := make([]string, len(mods))
+			for i, mod := range mods {
+				modStrs[i] = fmt.Sprintf("%d", mod)
+			}
+			modStr := strings.Join(modStrs, ",")
 			typeStr = fmt.Sprintf("%s(%s)", typeStr, modStr)
 			rawBase = fmt.Sprintf("%s(%s)", rawBase, modStr)
 		}
@@ -400,6 +825,78 @@ func formatTypeName(typeName *pg_query.TypeName) (string, *database.TypeMetadata
 	return typeStr, meta
 }
 
+// Interval field bits in an interval typmod (see PostgreSQL's datetime.h)
+const (
+	intervalMonth     = 1 << 1
+	intervalYear      = 1 << 2
+	intervalDay       = 1 << 3
+	intervalHour      = 1 << 10
+	intervalMinute    = 1 << 11
+	intervalSecond    = 1 << 12
+	intervalFullRange = 0x7FFF
+)
+
+// intervalFields names the field restrictions an interval typmod can carry
+var intervalFields = map[int32]string{
+	intervalYear:                 "year",
+	intervalMonth:                "month",
+	intervalDay:                  "day",
+	intervalHour:                 "hour",
+	intervalMinute:               "minute",
+	intervalSecond:               "second",
+	intervalYear | intervalMonth: "year to month",
+	intervalDay | intervalHour:   "day to hour",
+	intervalDay | intervalHour | intervalMinute:                  "day to minute",
+	intervalDay | intervalHour | intervalMinute | intervalSecond: "day to second",
+	intervalHour | intervalMinute:                                "hour to minute",
+	intervalHour | intervalMinute | intervalSecond:               "hour to second",
+	intervalMinute | intervalSecond:                              "minute to second",
+}
+
+// formatTemporalType renders a timestamp, time or interval type with its
+// precision the way PostgreSQL's format_type does, e.g. timestamptz(3) as
+// "timestamp(3) with time zone" and interval(3) as "interval(3)". Types
+// written without a time zone clause keep their short form ("timestamp(3)").
+func formatTemporalType(name string, mods []int32) (string, bool) {
+	precision := func(mod int32) string {
+		return fmt.Sprintf("(%d)", mod)
+	}
+
+	switch name {
+	case "timestamp", "time":
+		if len(mods) == 1 {
+			return name + precision(mods[0]), true
+		}
+		return name, len(mods) == 0
+	case "timestamptz", "timetz":
+		base := strings.TrimSuffix(name, "tz")
+		switch len(mods) {
+		case 0:
+			return base + " with time zone", true
+		case 1:
+			return base + precision(mods[0]) + " with time zone", true
+		}
+	case "interval":
+		// The parser always records the fields; a second modifier is the precision
+		if len(mods) == 0 || len(mods) > 2 {
+			return name, len(mods) == 0
+		}
+		result := name
+		if mods[0] != intervalFullRange {
+			fields, ok := intervalFields[mods[0]]
+			if !ok {
+				return "", false
+			}
+			result += " " + fields
+		}
+		if len(mods) == 2 {
+			result += precision(mods[1])
+		}
+		return result, true
+	}
+	return "", false
+}
+
 // normalizePostgreSQLType converts PostgreSQL internal type names to standard SQL types
 // This is necessary because we use pg_query (PostgreSQL parser) for all SQL parsing,
 // and it normalizes types to PostgreSQL internal names like "int4", "int8", "bool", etc.
@@ -436,6 +933,39 @@ func normalizePostgreSQLType(pgType string) string {
 		// Numeric
 		"numeric": "numeric",
 		"decimal": "decimal",
+
+		// Geometric types
+		"point":   "point",
+		"line":    "line",
+		"lseg":    "lseg",
+		"box":     "box",
+		"path":    "path",
+		"polygon": "polygon",
+		"circle":  "circle",
+
+		// Network address types
+		"inet":     "inet",
+		"cidr":     "cidr",
+		"macaddr":  "macaddr",
+		"macaddr8": "macaddr8",
+
+		// Full-text search types
+		"tsvector": "tsvector",
+		"tsquery":  "tsquery",
+
+		// Range and multirange types
+		"int4range":      "int4range",
+		"int8range":      "int8range",
+		"numrange":       "numrange",
+		"tsrange":        "tsrange",
+		"tstzrange":      "tstzrange",
+		"daterange":      "daterange",
+		"int4multirange": "int4multirange",
+		"int8multirange": "int8multirange",
+		"nummultirange":  "nummultirange",
+		"tsmultirange":   "tsmultirange",
+		"tstzmultirange": "tstzmultirange",
+		"datemultirange": "datemultirange",
 	}
 
 	if normalized, ok := typeMap[strings.ToLower(pgType)]; ok {
@@ -471,8 +1001,30 @@ func parseColumnConstraint(col *database.Column, constraint *pg_query.Constraint
 	}
 }
 
+// addColumnUniqueIndexes adds the unique indexes of a column's UNIQUE
+// constraints (e.g. "email TEXT UNIQUE") to table
+func addColumnUniqueIndexes(schema *database.Schema, table *database.Table, colDef *pg_query.ColumnDef) {
+	for _, node := range colDef.Constraints {
+		constraint := node.GetConstraint()
+		if constraint == nil || constraint.Contype != pg_query.ConstrType_CONSTR_UNIQUE {
+			continue
+		}
+		name := constraint.Conname
+		if name == "" {
+			name = chooseObjectName(schema, table, []string{colDef.Colname}, uniqueConstraintLabel)
+		}
+		table.Indexes = append(table.Indexes, database.Index{
+			Name:             name,
+			Unique:           true,
+			Columns:          []string{colDef.Colname},
+			NullsNotDistinct: constraint.NullsNotDistinct,
+			Constraint:       true,
+		})
+	}
+}
+
 // parseTableConstraint applies a table-level constraint
-func parseTableConstraint(table *database.Table, constraint *pg_query.Constraint) error {
+func parseTableConstraint(schema *database.Schema, table *database.Table, constraint *pg_query.Constraint) error {
 	switch constraint.Contype {
 	case pg_query.ConstrType_CONSTR_PRIMARY:
 		// Mark columns as primary key
@@ -489,17 +1041,43 @@ func parseTableConstraint(table *database.Table, constraint *pg_query.Constraint
 		}
 
 	case pg_query.ConstrType_CONSTR_UNIQUE:
-		// Create a unique index
+		// UNIQUE USING INDEX makes an existing index back the constraint,
+		// renaming it to the constraint name
+		if constraint.Indexname != "" {
+			for i := range table.Indexes {
+				if table.Indexes[i].Name == constraint.Indexname {
+					table.Indexes[i].Constraint = true
+					if constraint.Conname != "" {
+						table.Indexes[i].Name = constraint.Conname
+					}
+					return nil
+				}
+			}
+			return fmt.Errorf("ALTER TABLE %s ADD CONSTRAINT USING INDEX: index %s not found", table.Name, constraint.Indexname)
+		}
+
+		// Create the unique index backing the constraint
 		idx := database.Index{
-			Name:    getConstraintName(constraint, table.Name, "unique"),
-			Unique:  true,
-			Columns: []string{},
+			Name:             constraint.Conname,
+			Unique:           true,
+			Columns:          []string{},
+			NullsNotDistinct: constraint.NullsNotDistinct,
+			Constraint:       true,
 		}
 		for _, key := range constraint.Keys {
 			if keyNode, ok := key.Node.(*pg_query.Node_String_); ok {
 				idx.Columns = append(idx.Columns, keyNode.String_.Sval)
 			}
 		}
+		for _, key := range constraint.Including {
+			if keyNode, ok := key.Node.(*pg_query.Node_String_); ok {
+				idx.IncludeColumns = append(idx.IncludeColumns, keyNode.String_.Sval)
+			}
+		}
+		if idx.Name == "" {
+			// INCLUDE columns are part of the name, as in PostgreSQL
+			idx.Name = chooseObjectName(schema, table, append(slices.Clone(idx.Columns), idx.IncludeColumns...), uniqueConstraintLabel)
+		}
 		if len(idx.Columns) > 0 {
 			table.Indexes = append(table.Indexes, idx)
 		}
@@ -507,7 +1085,7 @@ func parseTableConstraint(table *database.Table, constraint *pg_query.Constraint
 	case pg_query.ConstrType_CONSTR_FOREIGN:
 		// Create foreign key
 		fk := database.ForeignKey{
-			Name:              getConstraintName(constraint, table.Name, "fk"),
+			Name:              constraint.Conname,
 			Columns:           []string{},
 			ReferencedColumns: []string{},
 		}
@@ -521,7 +1099,7 @@ func parseTableConstraint(table *database.Table, constraint *pg_query.Constraint
 
 		// Referenced table
 		if constraint.Pktable != nil && constraint.Pktable.Relname != "" {
-			fk.ReferencedTable = constraint.Pktable.Relname
+			fk.ReferencedTable = database.QualifiedTableName(constraint.Pktable.Schemaname, constraint.Pktable.Relname)
 		}
 
 		// Referenced columns
@@ -541,6 +1119,9 @@ func parseTableConstraint(table *database.Table, constraint *pg_query.Constraint
 			fk.OnUpdate = &action
 		}
 
+		if fk.Name == "" {
+			fk.Name = chooseObjectName(schema, table, fk.Columns, foreignKeyLabel)
+		}
 		if len(fk.Columns) > 0 && fk.ReferencedTable != "" {
 			table.ForeignKeys = append(table.ForeignKeys, fk)
 		}
@@ -555,9 +1136,12 @@ func parseAlterTable(schema *database.Schema, stmt *pg_query.AlterTableStmt) err
 		return fmt.Errorf("ALTER TABLE missing relation")
 	}
 
-	table := findTable(schema, stmt.Relation.Relname)
+	table := findTable(schema, stmt.Relation.Schemaname, stmt.Relation.Relname)
 	if table == nil {
-		return fmt.Errorf("ALTER TABLE references unknown table: %s", stmt.Relation.Relname)
+		if stmt.MissingOk {
+			return nil
+		}
+		return unknownTableError("ALTER TABLE", schema, stmt.Relation.Schemaname, stmt.Relation.Relname)
 	}
 
 	for _, cmdNode := range stmt.Cmds {
@@ -570,7 +1154,7 @@ func parseAlterTable(schema *database.Schema, stmt *pg_query.AlterTableStmt) err
 			continue
 		}
 
-		if err := applyAlterTableCmd(table, alterCmd.AlterTableCmd); err != nil {
+		if err := applyAlterTableCmd(schema, table, alterCmd.AlterTableCmd); err != nil {
 			return err
 		}
 	}
@@ -579,7 +1163,7 @@ func parseAlterTable(schema *database.Schema, stmt *pg_query.AlterTableStmt) err
 }
 
 // applyAlterTableCmd mutates a table based on a single ALTER TABLE command
-func applyAlterTableCmd(table *database.Table, cmd *pg_query.AlterTableCmd) error {
+func applyAlterTableCmd(schema *database.Schema, table *database.Table, cmd *pg_query.AlterTableCmd) error {
 	if cmd == nil {
 		return nil
 	}
@@ -594,11 +1178,15 @@ func applyAlterTableCmd(table *database.Table, cmd *pg_query.AlterTableCmd) erro
 		if colDef == nil {
 			return fmt.Errorf("ALTER TABLE %s ADD COLUMN missing definition", table.Name)
 		}
+		if cmd.MissingOk && findColumnIndex(table, colDef.Colname) != -1 {
+			return nil
+		}
 		col, err := parseColumnDef(colDef)
 		if err != nil {
 			return err
 		}
 		table.Columns = append(table.Columns, *col)
+		addColumnUniqueIndexes(schema, table, colDef)
 
 	case pg_query.AlterTableType_AT_DropColumn:
 		if cmd.Name == "" {
@@ -606,6 +1194,9 @@ func applyAlterTableCmd(table *database.Table, cmd *pg_query.AlterTableCmd) erro
 		}
 		idx := findColumnIndex(table, cmd.Name)
 		if idx == -1 {
+			if cmd.MissingOk {
+				return nil
+			}
 			return fmt.Errorf("ALTER TABLE %s DROP COLUMN unknown column: %s", table.Name, cmd.Name)
 		}
 		table.Columns = append(table.Columns[:idx], table.Columns[idx+1:]...)
@@ -670,12 +1261,41 @@ func applyAlterTableCmd(table *database.Table, cmd *pg_query.AlterTableCmd) erro
 		table.Columns[idx].Type = colType
 		table.Columns[idx].TypeMetadata = meta
 
+	case pg_query.AlterTableType_AT_SetStorage:
+		if cmd.Name == "" {
+			return fmt.Errorf("ALTER TABLE %s SET STORAGE missing column name", table.Name)
+		}
+		idx := findColumnIndex(table, cmd.Name)
+		if idx == -1 {
+			return fmt.Errorf("ALTER TABLE %s SET STORAGE unknown column: %s", table.Name, cmd.Name)
+		}
+		storage := ""
+		if str := cmd.GetDef().GetString_(); str != nil {
+			storage = str.Sval
+		}
+		table.Columns[idx].Storage = parseStorage(storage)
+
+	case pg_query.AlterTableType_AT_SetStatistics:
+		if cmd.Name == "" {
+			return fmt.Errorf("ALTER TABLE %s SET STATISTICS missing column name", table.Name)
+		}
+		idx := findColumnIndex(table, cmd.Name)
+		if idx == -1 {
+			return fmt.Errorf("ALTER TABLE %s SET STATISTICS unknown column: %s", table.Name, cmd.Name)
+		}
+		// -1 and DEFAULT (no value) restore default_statistics_target
+		table.Columns[idx].StatisticsTarget = nil
+		if ival := cmd.GetDef().GetInteger(); ival != nil && ival.Ival >= 0 {
+			target := int(ival.Ival)
+			table.Columns[idx].StatisticsTarget = &target
+		}
+
 	case pg_query.AlterTableType_AT_AddConstraint:
 		constraint := cmd.GetDef().GetConstraint()
 		if constraint == nil {
 			return fmt.Errorf("ALTER TABLE %s ADD CONSTRAINT missing definition", table.Name)
 		}
-		if err := parseTableConstraint(table, constraint); err != nil {
+		if err := parseTableConstraint(schema, table, constraint); err != nil {
 			return err
 		}
 
@@ -692,6 +1312,9 @@ func applyAlterTableCmd(table *database.Table, cmd *pg_query.AlterTableCmd) erro
 		if dropPrimaryKey(table) {
 			return nil
 		}
+		if cmd.MissingOk {
+			return nil
+		}
 		return fmt.Errorf("ALTER TABLE %s DROP CONSTRAINT unsupported constraint: %s", table.Name, cmd.Name)
 
 	case pg_query.AlterTableType_AT_EnableRowSecurity:
@@ -700,6 +1323,53 @@ func applyAlterTableCmd(table *database.Table, cmd *pg_query.AlterTableCmd) erro
 	case pg_query.AlterTableType_AT_DisableRowSecurity:
 		table.RLSEnabled = false
 
+	case pg_query.AlterTableType_AT_SetTableSpace:
+		if cmd.Name == "" {
+			return fmt.Errorf("ALTER TABLE %s SET TABLESPACE missing tablespace name", table.Name)
+		}
+		tablespace := cmd.Name
+		table.Tablespace = &tablespace
+
+	case pg_query.AlterTableType_AT_SetRelOptions:
+		for _, item := range cmd.GetDef().GetList().GetItems() {
+			if def := item.GetDefElem(); def != nil {
+				setStorageParameter(table, def)
+			}
+		}
+
+	case pg_query.AlterTableType_AT_ResetRelOptions:
+		for _, item := range cmd.GetDef().GetList().GetItems() {
+			if def := item.GetDefElem(); def != nil {
+				delete(table.StorageParameters, storageParameterName(def))
+			}
+		}
+		if len(table.StorageParameters) == 0 {
+			table.StorageParameters = nil
+		}
+
+	case pg_query.AlterTableType_AT_ReplicaIdentity:
+		stmt := cmd.GetDef().GetReplicaIdentityStmt()
+		if stmt == nil {
+			return fmt.Errorf("ALTER TABLE %s REPLICA IDENTITY missing identity", table.Name)
+		}
+		var identity string
+		switch stmt.IdentityType {
+		case "d":
+			identity = database.ReplicaIdentityDefault
+		case "f":
+			identity = database.ReplicaIdentityFull
+		case "n":
+			identity = database.ReplicaIdentityNothing
+		case "i":
+			identity = database.ReplicaIdentityUsingIndex(stmt.Name)
+		default:
+			return fmt.Errorf("ALTER TABLE %s unsupported REPLICA IDENTITY type: %q", table.Name, stmt.IdentityType)
+		}
+		table.ReplicaIdentity = &identity
+
+	case pg_query.AlterTableType_AT_ChangeOwner:
+		// Ownership is not managed
+
 	default:
 		return fmt.Errorf("ALTER TABLE %s unsupported command subtype: %s", table.Name, cmd.Subtype.String())
 	}
@@ -713,29 +1383,29 @@ func parseCreateIndex(schema *database.Schema, stmt *pg_query.IndexStmt) error {
 		return fmt.Errorf("CREATE INDEX missing table name")
 	}
 
-	tableName := stmt.Relation.Relname
-
-	// Find the table
-	var targetTable *database.Table
-	for i := range schema.Tables {
-		if schema.Tables[i].Name == tableName {
-			targetTable = &schema.Tables[i]
-			break
-		}
-	}
-
+	targetTable := findTable(schema, stmt.Relation.Schemaname, stmt.Relation.Relname)
 	if targetTable == nil {
-		return fmt.Errorf("CREATE INDEX references unknown table: %s", tableName)
+		return unknownTableError("CREATE INDEX", schema, stmt.Relation.Schemaname, stmt.Relation.Relname)
+	}
+	if stmt.IfNotExists && stmt.Idxname != "" && findIndex(schema, stmt.Relation.Schemaname, stmt.Idxname) != nil {
+		return nil
 	}
 
 	// Create index
 	idx := database.Index{
-		Name:    stmt.Idxname,
-		Unique:  stmt.Unique,
-		Columns: []string{},
+		Name:             stmt.Idxname,
+		Unique:           stmt.Unique,
+		Columns:          []string{},
+		NullsNotDistinct: stmt.NullsNotDistinct,
+	}
+	if stmt.TableSpace != "" {
+		tablespace := stmt.TableSpace
+		idx.Tablespace = &tablespace
 	}
 
-	// Extract column names
+	// Extract column names and their sort order
+	var keyColumns []database.IndexColumn
+	var nameColumns []string // Key and INCLUDE columns, "" for expressions
 	for _, elem := range stmt.IndexParams {
 		if elem.Node == nil {
 			continue
@@ -746,10 +1416,22 @@ func parseCreateIndex(schema *database.Schema, stmt *pg_query.IndexStmt) error {
 		}
 
 		colName := extractIndexColumnName(indexElem.IndexElem)
+		nameColumns = append(nameColumns, colName)
 		if colName != "" {
-			idx.Columns = append(idx.Columns, colName)
+			keyColumns = append(keyColumns, extractIndexColumnOrdering(colName, indexElem.IndexElem))
 		}
 	}
+	idx.SetKeyColumns(keyColumns)
+	for _, elem := range stmt.IndexIncludingParams {
+		colName := extractIndexColumnName(elem.GetIndexElem())
+		nameColumns = append(nameColumns, colName)
+		if colName != "" {
+			idx.IncludeColumns = append(idx.IncludeColumns, colName)
+		}
+	}
+	if idx.Name == "" {
+		idx.Name = chooseObjectName(schema, targetTable, nameColumns, indexLabel)
+	}
 
 	if len(idx.Columns) > 0 {
 		targetTable.Indexes = append(targetTable.Indexes, idx)
@@ -758,6 +1440,133 @@ func parseCreateIndex(schema *database.Schema, stmt *pg_query.IndexStmt) error {
 	return nil
 }
 
+// parseDrop applies DROP TABLE and DROP INDEX to the tables defined so far.
+// As in PostgreSQL, dropping something that isn't defined is an error unless
+// the statement says IF EXISTS, and a table referenced by foreign keys can
+// only be dropped with CASCADE, which drops those foreign keys. Other DROP
+// statements don't affect the schema and are ignored.
+func parseDrop(schema *database.Schema, stmt *pg_query.DropStmt) error {
+	for _, object := range stmt.Objects {
+		names := objectNameParts(object)
+		if len(names) == 0 {
+			continue
+		}
+		name := names[len(names)-1]
+		schemaName := ""
+		if len(names) > 1 {
+			schemaName = names[len(names)-2]
+		}
+
+		switch stmt.RemoveType {
+		case pg_query.ObjectType_OBJECT_TABLE:
+			table := findTable(schema, schemaName, name)
+			if table == nil {
+				if stmt.MissingOk {
+					continue
+				}
+				return unknownTableError("DROP TABLE", schema, schemaName, name)
+			}
+			if err := dropTable(schema, table, stmt.Behavior == pg_query.DropBehavior_DROP_CASCADE); err != nil {
+				return err
+			}
+
+		case pg_query.ObjectType_OBJECT_INDEX:
+			table := findIndexTable(schema, schemaName, name)
+			if table == nil {
+				if stmt.MissingOk {
+					continue
+				}
+				return fmt.Errorf("DROP INDEX references unknown index: %s", qualifiedTableName(schemaName, name))
+			}
+			removeIndexByName(table, name)
+
+		case pg_query.ObjectType_OBJECT_SEQUENCE:
+			if !dropSequence(schema, schemaName, name) && !stmt.MissingOk {
+				return fmt.Errorf("DROP SEQUENCE references unknown sequence: %s", qualifiedTableName(schemaName, name))
+			}
+		}
+	}
+	return nil
+}
+
+// objectNameParts returns the dotted name parts of a DROP object
+func objectNameParts(object *pg_query.Node) []string {
+	list := object.GetList()
+	if list == nil {
+		return nil
+	}
+	var parts []string
+	for _, item := range list.Items {
+		if str, ok := item.Node.(*pg_query.Node_String_); ok {
+			parts = append(parts, str.String_.Sval)
+		}
+	}
+	return parts
+}
+
+// dropTable removes a table from the schema. Foreign keys on other tables
+// that reference it are removed with cascade and are an error otherwise.
+func dropTable(schema *database.Schema, table *database.Table, cascade bool) error {
+	dropped := qualifiedTableName(table.Schema, table.Name)
+	for i := range schema.Tables {
+		other := &schema.Tables[i]
+		if other == table {
+			continue
+		}
+		kept := make([]database.ForeignKey, 0, len(other.ForeignKeys))
+		for _, fk := range other.ForeignKeys {
+			if fk.ReferencedTable != table.Name && fk.ReferencedTable != dropped {
+				kept = append(kept, fk)
+			} else if !cascade {
+				return fmt.Errorf("DROP TABLE %s: foreign key %s on %s depends on it (use CASCADE to drop the foreign key too)",
+					dropped, fk.Name, qualifiedTableName(other.Schema, other.Name))
+			}
+		}
+		if cascade {
+			other.ForeignKeys = kept
+		}
+	}
+
+	for i := range schema.Tables {
+		if &schema.Tables[i] == table {
+			schema.Tables = append(schema.Tables[:i], schema.Tables[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// findIndex locates an index by name among the tables of a schema
+func findIndex(schema *database.Schema, schemaName, name string) *database.Index {
+	table := findIndexTable(schema, schemaName, name)
+	if table == nil {
+		return nil
+	}
+	for i := range table.Indexes {
+		if table.Indexes[i].Name == name {
+			return &table.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// findIndexTable returns the table that has an index with the given name.
+// Index names are unique per schema, so an unqualified name matches any schema.
+func findIndexTable(schema *database.Schema, schemaName, name string) *database.Table {
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if schemaName != "" && table.Schema != "" && table.Schema != schemaName {
+			continue
+		}
+		for _, idx := range table.Indexes {
+			if idx.Name == name {
+				return table
+			}
+		}
+	}
+	return nil
+}
+
 func extractIndexColumnName(elem *pg_query.IndexElem) string {
 	if elem == nil {
 		return ""
@@ -782,6 +1591,21 @@ func extractIndexColumnName(elem *pg_query.IndexElem) string {
 	return ""
 }
 
+// extractIndexColumnOrdering reads ASC/DESC and NULLS FIRST/LAST from an index element
+func extractIndexColumnOrdering(name string, elem *pg_query.IndexElem) database.IndexColumn {
+	col := database.IndexColumn{Name: name}
+	if elem.Ordering == pg_query.SortByDir_SORTBY_DESC {
+		col.Descending = true
+	}
+	switch elem.NullsOrdering {
+	case pg_query.SortByNulls_SORTBY_NULLS_FIRST:
+		col.NullsOrder = database.NullsFirst
+	case pg_query.SortByNulls_SORTBY_NULLS_LAST:
+		col.NullsOrder = database.NullsLast
+	}
+	return col
+}
+
 func extractColumnRefName(colRef *pg_query.ColumnRef) string {
 	if colRef == nil {
 		return ""
@@ -802,15 +1626,6 @@ func extractColumnRefName(colRef *pg_query.ColumnRef) string {
 	return last
 }
 
-// getConstraintName returns the constraint name or generates one
-func getConstraintName(constraint *pg_query.Constraint, tableName, prefix string) string {
-	if constraint.Conname != "" {
-		return constraint.Conname
-	}
-	// Generate a name
-	return fmt.Sprintf("%s_%s", tableName, prefix)
-}
-
 // formatForeignKeyAction converts foreign key action code to string
 func formatForeignKeyAction(action string) string {
 	if action == "" {
@@ -851,7 +1666,8 @@ func formatExpr(node *pg_query.Node) string {
 			return fval.Fval
 		}
 		if sval := expr.AConst.GetSval(); sval != nil {
-			return fmt.Sprintf("'%s'", sval.Sval)
+			// Sval is the unescaped value; re-escape it as a standard string
+			return "'" + strings.ReplaceAll(sval.Sval, "'", "''") + "'"
 		}
 		if bsval := expr.AConst.GetBsval(); bsval != nil {
 			return bsval.Bsval
@@ -921,17 +1737,40 @@ func formatExpr(node *pg_query.Node) string {
 		}
 	}
 
-	// For anything else, return a placeholder
+	// For anything else (operators, CASE, arrays, ...), let pg_query
+	// reconstruct the expression
+	if deparsed, ok := deparseExpr(node); ok {
+		return deparsed
+	}
 	return "DEFAULT"
 }
 
+// deparseExpr reconstructs an arbitrary expression by deparsing it as the
+// target of a SELECT statement
+func deparseExpr(node *pg_query.Node) (string, bool) {
+	stmt := &pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{
+		Stmt: &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: &pg_query.SelectStmt{
+			TargetList: []*pg_query.Node{pg_query.MakeResTargetNodeWithVal(node, 0)},
+		}}},
+	}}}
+	deparsed, err := pg_query.Deparse(stmt)
+	if err != nil {
+		return "", false
+	}
+	expr, ok := strings.CutPrefix(deparsed, "SELECT ")
+	if !ok || expr == "" {
+		return "", false
+	}
+	return expr, true
+}
+
 // ExtractTableNameFromAlter extracts table name from ALTER TABLE statement
 func ExtractTableNameFromAlter(sql string) (string, error) {
 	// Pattern: ALTER TABLE <name> ...
-	re := regexp.MustCompile(`ALTER\s+TABLE\s+(\w+)`)
+	re := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(` + qualifiedIdentifierPattern + `)`)
 	matches := re.FindStringSubmatch(sql)
 	if len(matches) < 2 {
 		return "", fmt.Errorf("could not extract table name from: %s", sql)
 	}
-	return matches[1], nil
+	return unquoteName(matches[1]), nil
 }
@@ -1,9 +1,13 @@
 package parser
 
 import (
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
 )
 
 func TestParseSQLSchemaCreateTable(t *testing.T) {
@@ -373,3 +377,949 @@ CREATE TABLE audit_log (
 		}
 	}
 }
+
+func TestParseSQLSchemaWithExpressionDefaults(t *testing.T) {
+	sql := `
+CREATE TABLE products (
+    id BIGINT PRIMARY KEY,
+    price NUMERIC DEFAULT (10 * 1.1),
+    code TEXT DEFAULT lpad('7', 4, '0'),
+    discount NUMERIC DEFAULT round((100 - 15) / 3.0, 2),
+    status TEXT DEFAULT CASE WHEN 1 > 0 THEN 'active' ELSE 'inactive' END
+);
+ALTER TABLE products ALTER COLUMN id SET DEFAULT -(1 + 2);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+
+	table := schema.Tables[0]
+
+	tests := []struct {
+		columnName      string
+		expectedDefault string
+	}{
+		{"id", "- (1 + 2)"},
+		{"price", "10 * 1.1"},
+		{"code", "lpad('7', 4, '0')"},
+		{"discount", "round((100 - 15) / 3.0, 2)"},
+		{"status", "CASE WHEN 1 > 0 THEN 'active' ELSE 'inactive' END"},
+	}
+
+	for i, tt := range tests {
+		col := table.Columns[i]
+		if col.Name != tt.columnName {
+			t.Errorf("expected column %d name %s, got %s", i, tt.columnName, col.Name)
+		}
+		if col.Default == nil {
+			t.Errorf("expected column %s to have default value", tt.columnName)
+			continue
+		}
+		if *col.Default != tt.expectedDefault {
+			t.Errorf("expected column %s default %s, got %s", tt.columnName, tt.expectedDefault, *col.Default)
+		}
+	}
+}
+
+func TestParseSQLSchemaQuotedCreateUnquotedAlter(t *testing.T) {
+	sql := `
+CREATE TABLE "users" (
+    id BIGINT
+);
+ALTER TABLE users ADD COLUMN email TEXT;
+CREATE INDEX users_email_idx ON USERS (email);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+
+	table := schema.Tables[0]
+	if len(table.Columns) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(table.Columns))
+	}
+	if len(table.Indexes) != 1 {
+		t.Fatalf("expected 1 index, got %d", len(table.Indexes))
+	}
+}
+
+func TestParseSQLSchemaSchemaQualifiedAlter(t *testing.T) {
+	sql := `
+CREATE TABLE public.users (
+    id BIGINT
+);
+CREATE TABLE users_audit (
+    id BIGINT
+);
+ALTER TABLE users ADD COLUMN email TEXT;
+ALTER TABLE public.users_audit ADD COLUMN action TEXT;
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+
+	if schema.Tables[0].Schema != "public" {
+		t.Fatalf("expected users to be in schema public, got %q", schema.Tables[0].Schema)
+	}
+	if len(schema.Tables[0].Columns) != 2 {
+		t.Fatalf("expected users to have 2 columns, got %d", len(schema.Tables[0].Columns))
+	}
+	if len(schema.Tables[1].Columns) != 2 {
+		t.Fatalf("expected users_audit to have 2 columns, got %d", len(schema.Tables[1].Columns))
+	}
+}
+
+func TestParseSQLSchemaQuotedMixedCaseMismatch(t *testing.T) {
+	sql := `
+CREATE TABLE "Users" (
+    id BIGINT
+);
+CREATE TABLE teams (
+    id BIGINT
+);
+ALTER TABLE Users ADD COLUMN email TEXT;
+`
+
+	_, err := ParseSQLSchema(sql)
+	if err == nil {
+		t.Fatal("expected error for unquoted reference to quoted mixed-case table")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{"unknown table: users", "did you mean Users", "known tables: Users, teams"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error to contain %q, got: %s", want, msg)
+		}
+	}
+}
+
+func TestParseSQLSchemaUnknownTableInOtherSchema(t *testing.T) {
+	sql := `
+CREATE TABLE app.users (
+    id BIGINT
+);
+ALTER TABLE audit.users ADD COLUMN email TEXT;
+`
+
+	_, err := ParseSQLSchema(sql)
+	if err == nil {
+		t.Fatal("expected error for reference to table in a different schema")
+	}
+	if !strings.Contains(err.Error(), "unknown table: audit.users") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseSQLSchemaTablespace(t *testing.T) {
+	sql := `
+CREATE TABLE events (
+    id BIGINT,
+    created_at TIMESTAMP
+) TABLESPACE fast_ssd;
+CREATE INDEX idx_events_created_at ON events (created_at) TABLESPACE index_space;
+CREATE TABLE logs (
+    id BIGINT
+);
+ALTER TABLE logs SET TABLESPACE archive;
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	events := schema.Tables[0]
+	if events.Tablespace == nil || *events.Tablespace != "fast_ssd" {
+		t.Errorf("expected events tablespace fast_ssd, got %v", events.Tablespace)
+	}
+	if len(events.Indexes) != 1 || events.Indexes[0].Tablespace == nil || *events.Indexes[0].Tablespace != "index_space" {
+		t.Errorf("expected index tablespace index_space, got %+v", events.Indexes)
+	}
+
+	logs := schema.Tables[1]
+	if logs.Tablespace == nil || *logs.Tablespace != "archive" {
+		t.Errorf("expected logs tablespace archive after ALTER TABLE, got %v", logs.Tablespace)
+	}
+}
+
+func TestParseSQLSchemaReplicaIdentity(t *testing.T) {
+	sql := `
+CREATE TABLE orders (id BIGINT NOT NULL, external_id TEXT NOT NULL);
+CREATE UNIQUE INDEX orders_external_id_key ON orders (external_id);
+ALTER TABLE orders REPLICA IDENTITY USING INDEX orders_external_id_key;
+CREATE TABLE events (id BIGINT);
+ALTER TABLE events REPLICA IDENTITY FULL;
+CREATE TABLE logs (id BIGINT);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string]string{
+		"orders": "USING INDEX orders_external_id_key",
+		"events": "FULL",
+		"logs":   "",
+	}
+	for _, table := range schema.Tables {
+		got := ""
+		if table.ReplicaIdentity != nil {
+			got = *table.ReplicaIdentity
+		}
+		if got != expected[table.Name] {
+			t.Errorf("table %s: expected replica identity %q, got %q", table.Name, expected[table.Name], got)
+		}
+	}
+}
+
+func TestParseSQLSchemaStorageParameters(t *testing.T) {
+	sql := `
+CREATE TABLE orders (id BIGINT)
+WITH (fillfactor = 70, autovacuum_enabled = false, toast.autovacuum_enabled = off, user_catalog_table);
+ALTER TABLE orders SET (autovacuum_vacuum_scale_factor = 0.01, custom_option = 'some value');
+ALTER TABLE orders RESET (toast.autovacuum_enabled, user_catalog_table);
+CREATE TABLE events (id BIGINT) WITH (fillfactor = 90);
+ALTER TABLE events RESET (fillfactor);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string]string{
+		"fillfactor":                     "70",
+		"autovacuum_enabled":             "false",
+		"autovacuum_vacuum_scale_factor": "0.01",
+		"custom_option":                  "some value",
+	}
+	if !reflect.DeepEqual(schema.Tables[0].StorageParameters, expected) {
+		t.Errorf("Expected orders storage parameters %v, got %v", expected, schema.Tables[0].StorageParameters)
+	}
+	if schema.Tables[1].StorageParameters != nil {
+		t.Errorf("Expected events to have no storage parameters, got %v", schema.Tables[1].StorageParameters)
+	}
+}
+
+func TestExtractTableAndStorageParameters(t *testing.T) {
+	table, names, reset, err := ExtractTableAndStorageParameters("ALTER TABLE orders SET (fillfactor = 70, toast.autovacuum_enabled = off)")
+	if err != nil {
+		t.Fatalf("Failed to extract storage parameters: %v", err)
+	}
+	if table != "orders" || !reflect.DeepEqual(names, []string{"fillfactor", "toast.autovacuum_enabled"}) || reset {
+		t.Errorf("Unexpected result: %s %v %v", table, names, reset)
+	}
+
+	table, names, reset, err = ExtractTableAndStorageParameters("ALTER TABLE orders RESET (fillfactor)")
+	if err != nil {
+		t.Fatalf("Failed to extract storage parameters: %v", err)
+	}
+	if table != "orders" || !reflect.DeepEqual(names, []string{"fillfactor"}) || !reset {
+		t.Errorf("Unexpected result: %s %v %v", table, names, reset)
+	}
+
+	if _, _, _, err := ExtractTableAndStorageParameters("ALTER TABLE orders ADD COLUMN note text"); err == nil {
+		t.Error("Expected an error for a statement without storage parameters")
+	}
+}
+
+func TestParseSQLSchemaColumnStorage(t *testing.T) {
+	sql := `
+CREATE TABLE documents (
+    id BIGINT,
+    body TEXT STORAGE EXTERNAL,
+    summary TEXT,
+    payload BYTEA STORAGE main,
+    title TEXT STORAGE EXTERNAL
+);
+ALTER TABLE documents ALTER COLUMN summary SET STORAGE MAIN;
+ALTER TABLE documents ALTER COLUMN title SET STORAGE DEFAULT;
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string]string{
+		"id":      "",
+		"body":    "EXTERNAL",
+		"summary": "MAIN",
+		"payload": "MAIN",
+		"title":   "",
+	}
+	for _, col := range schema.Tables[0].Columns {
+		got := ""
+		if col.Storage != nil {
+			got = *col.Storage
+		}
+		if got != expected[col.Name] {
+			t.Errorf("column %s: expected storage %q, got %q", col.Name, expected[col.Name], got)
+		}
+	}
+}
+
+func TestParseSQLSchemaColumnStatistics(t *testing.T) {
+	sql := `
+CREATE TABLE events (
+    id BIGINT,
+    properties JSONB,
+    user_id BIGINT,
+    name TEXT,
+    kind TEXT
+);
+ALTER TABLE events ALTER COLUMN properties SET STATISTICS 1000;
+ALTER TABLE events ALTER COLUMN user_id SET STATISTICS 0;
+ALTER TABLE events ALTER COLUMN name SET STATISTICS 500;
+ALTER TABLE events ALTER COLUMN name SET STATISTICS -1;
+ALTER TABLE events ALTER COLUMN kind SET STATISTICS 200;
+ALTER TABLE events ALTER COLUMN kind SET STATISTICS DEFAULT;
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string]string{
+		"id":         "default",
+		"properties": "1000",
+		"user_id":    "0",
+		"name":       "default",
+		"kind":       "default",
+	}
+	for _, col := range schema.Tables[0].Columns {
+		got := "default"
+		if col.StatisticsTarget != nil {
+			got = strconv.Itoa(*col.StatisticsTarget)
+		}
+		if got != expected[col.Name] {
+			t.Errorf("column %s: expected statistics target %s, got %s", col.Name, expected[col.Name], got)
+		}
+	}
+
+	if _, err := ParseSQLSchema("CREATE TABLE events (id BIGINT);\nALTER TABLE events ALTER COLUMN missing SET STATISTICS 100;"); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestParseSQLSchemaTemporalPrecision(t *testing.T) {
+	tests := []struct {
+		columnType string
+		want       string
+	}{
+		{"timestamp", "timestamp"},
+		{"timestamp(3)", "timestamp(3)"},
+		{"timestamp(3) without time zone", "timestamp(3)"},
+		{"timestamptz", "timestamp with time zone"},
+		{"timestamptz(3)", "timestamp(3) with time zone"},
+		{"timestamp(0) with time zone", "timestamp(0) with time zone"},
+		{"time(3)", "time(3)"},
+		{"timetz", "time with time zone"},
+		{"timetz(2)", "time(2) with time zone"},
+		{"time(6) with time zone", "time(6) with time zone"},
+		{"interval", "interval"},
+		{"interval(3)", "interval(3)"},
+		{"interval year to month", "interval year to month"},
+		{"interval day to second(3)", "interval day to second(3)"},
+		{"interval minute", "interval minute"},
+		{"INTERVAL(6)", "interval(6)"},
+		{"INTERVAL DAY TO SECOND", "interval day to second"},
+		{"interval second(3)", "interval second(3)"},
+		{"interval hour to minute", "interval hour to minute"},
+		{"interval day to second(0)[]", "interval day to second(0)[]"},
+		{"timestamp(3)[]", "timestamp(3)[]"},
+		{"numeric(10,2)", "numeric(10,2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			schema, err := ParseSQLSchema("CREATE TABLE events (happened_at " + tt.columnType + ");")
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+			col := schema.Tables[0].Columns[0]
+			if col.Type != tt.want {
+				t.Errorf("expected type %q, got %q", tt.want, col.Type)
+			}
+			if col.LogicalType() != tt.want {
+				t.Errorf("expected logical type %q, got %q", tt.want, col.LogicalType())
+			}
+		})
+	}
+}
+
+// Types without a SQL-standard spelling should parse to the name
+// format_type introspects, and survive being generated and parsed again
+func TestParseSQLSchemaNonStandardTypes(t *testing.T) {
+	tests := []struct {
+		columnType string
+		want       string
+	}{
+		{"tsvector", "tsvector"},
+		{"TSVECTOR", "tsvector"},
+		{"pg_catalog.tsvector", "tsvector"},
+		{"tsquery", "tsquery"},
+		{"int4range", "int4range"},
+		{"pg_catalog.int4range", "int4range"},
+		{"int8range", "int8range"},
+		{"numrange", "numrange"},
+		{"tstzrange", "tstzrange"},
+		{"daterange", "daterange"},
+		{"int4multirange", "int4multirange"},
+		{"point", "point"},
+		{"POINT", "point"},
+		{"line", "line"},
+		{"lseg", "lseg"},
+		{"box", "box"},
+		{"path", "path"},
+		{"polygon", "polygon"},
+		{"circle", "circle"},
+		{"inet", "inet"},
+		{"pg_catalog.inet", "inet"},
+		{"cidr", "cidr"},
+		{"macaddr", "macaddr"},
+		{"macaddr8", "macaddr8"},
+		{"tsvector[]", "tsvector[]"},
+		{"point[]", "point[]"},
+		{"pg_catalog.int4range[]", "int4range[]"},
+	}
+
+	generator := postgres.NewGenerator()
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			schema, err := ParseSQLSchema("CREATE TABLE shapes (value " + tt.columnType + ");")
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+			col := schema.Tables[0].Columns[0]
+			if col.Type != tt.want || col.LogicalType() != tt.want {
+				t.Fatalf("expected type %q, got %q (logical %q)", tt.want, col.Type, col.LogicalType())
+			}
+
+			sql, _ := generator.CreateTable(schema.Tables[0])
+			reparsed, err := ParseSQLSchema(sql + ";")
+			if err != nil {
+				t.Fatalf("Failed to parse generated SQL %q: %v", sql, err)
+			}
+			if got := reparsed.Tables[0].Columns[0].Type; got != tt.want {
+				t.Errorf("expected type %q after a round trip through %q, got %q", tt.want, sql, got)
+			}
+		})
+	}
+}
+
+func TestParseSQLSchemaStringDefaults(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    string
+	}{
+		{`'a;b'`, `'a;b'`},
+		{`'it''s'`, `'it''s'`},
+		{`E'it\'s'`, `'it''s'`},
+		{`'C:\'`, `'C:\'`},
+		{`$$x;'y'$$`, `'x;''y'''`},
+		{`'café ☕'`, `'café ☕'`},
+		{`E'caf\u00e9'`, `'café'`},
+		{`''`, `''`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.literal, func(t *testing.T) {
+			schema, err := ParseSQLSchema("CREATE TABLE notes (body text DEFAULT " + tt.literal + ");")
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+			col := schema.Tables[0].Columns[0]
+			if col.Default == nil || *col.Default != tt.want {
+				t.Errorf("expected default %s, got %v", tt.want, col.Default)
+			}
+		})
+	}
+}
+
+func TestParseSQLSchemaNullsNotDistinct(t *testing.T) {
+	sql := `
+CREATE TABLE users (
+    id BIGINT,
+    email TEXT,
+    tenant_id BIGINT,
+    CONSTRAINT users_email_key UNIQUE NULLS NOT DISTINCT (email)
+);
+CREATE UNIQUE INDEX idx_users_tenant ON users (tenant_id) NULLS NOT DISTINCT;
+CREATE UNIQUE INDEX idx_users_id ON users (id);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string]bool{
+		"users_email_key":  true,
+		"idx_users_tenant": true,
+		"idx_users_id":     false,
+	}
+	indexes := schema.Tables[0].Indexes
+	if len(indexes) != len(expected) {
+		t.Fatalf("expected %d indexes, got %+v", len(expected), indexes)
+	}
+	for _, idx := range indexes {
+		if idx.NullsNotDistinct != expected[idx.Name] {
+			t.Errorf("index %s: expected NullsNotDistinct=%v, got %v", idx.Name, expected[idx.Name], idx.NullsNotDistinct)
+		}
+	}
+}
+
+func TestParseSQLSchemaUniqueConstraintIndexes(t *testing.T) {
+	sql := `
+CREATE TABLE users (
+    id BIGINT,
+    email TEXT UNIQUE,
+    username TEXT,
+    tenant_id BIGINT,
+    CONSTRAINT users_username_key UNIQUE (username)
+);
+CREATE UNIQUE INDEX idx_users_tenant ON users (tenant_id);
+CREATE UNIQUE INDEX idx_users_id ON users (id);
+ALTER TABLE users ADD CONSTRAINT users_id_key UNIQUE USING INDEX idx_users_id;
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string]bool{
+		"users_email_key":    true,
+		"users_username_key": true,
+		"idx_users_tenant":   false,
+		"users_id_key":       true,
+	}
+	indexes := schema.Tables[0].Indexes
+	if len(indexes) != len(expected) {
+		t.Fatalf("expected %d indexes, got %+v", len(expected), indexes)
+	}
+	for _, idx := range indexes {
+		want, ok := expected[idx.Name]
+		if !ok {
+			t.Errorf("unexpected index %s", idx.Name)
+			continue
+		}
+		if idx.Constraint != want {
+			t.Errorf("index %s: expected Constraint=%v, got %v", idx.Name, want, idx.Constraint)
+		}
+	}
+
+	if _, err := ParseSQLSchema("CREATE TABLE users (id BIGINT);\nALTER TABLE users ADD CONSTRAINT users_id_key UNIQUE USING INDEX missing;"); err == nil {
+		t.Error("expected an error for USING INDEX with an unknown index")
+	}
+}
+
+func TestParseSQLSchemaIncludeColumns(t *testing.T) {
+	sql := `
+CREATE TABLE orders (
+    id BIGINT,
+    user_id BIGINT,
+    status TEXT,
+    total NUMERIC,
+    UNIQUE (id) INCLUDE (status)
+);
+CREATE INDEX ON orders (user_id) INCLUDE (status, total);
+CREATE INDEX idx_orders_status ON orders (status);
+CREATE TABLE orders_copy (LIKE orders INCLUDING INDEXES);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	expected := map[string][]string{
+		"orders_id_status_key":            {"status"},
+		"orders_user_id_status_total_idx": {"status", "total"},
+		"idx_orders_status":               nil,
+	}
+	indexes := schema.Tables[0].Indexes
+	if len(indexes) != len(expected) {
+		t.Fatalf("expected %d indexes, got %+v", len(expected), indexes)
+	}
+	for _, idx := range indexes {
+		want, ok := expected[idx.Name]
+		if !ok {
+			t.Errorf("unexpected index %s", idx.Name)
+			continue
+		}
+		if !reflect.DeepEqual(idx.IncludeColumns, want) {
+			t.Errorf("index %s: expected INCLUDE columns %v, got %v", idx.Name, want, idx.IncludeColumns)
+		}
+		if len(idx.Columns) != 1 {
+			t.Errorf("index %s: expected one key column, got %v", idx.Name, idx.Columns)
+		}
+	}
+
+	copied := schema.Tables[1].Indexes
+	if len(copied) != 3 || copied[1].Name != "orders_copy_user_id_status_total_idx" || !reflect.DeepEqual(copied[1].IncludeColumns, []string{"status", "total"}) {
+		t.Errorf("expected LIKE to copy INCLUDE columns, got %+v", copied)
+	}
+}
+
+func TestParseSQLSchemaIndexOrdering(t *testing.T) {
+	sql := `
+CREATE TABLE events (
+    id BIGINT,
+    tenant_id BIGINT,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX idx_events_recent ON events (tenant_id ASC, created_at DESC NULLS LAST);
+CREATE INDEX idx_events_tenant ON events (tenant_id NULLS LAST);
+`
+
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	indexes := make(map[string]database.Index)
+	for _, idx := range schema.Tables[0].Indexes {
+		indexes[idx.Name] = idx
+	}
+
+	recent := indexes["idx_events_recent"].KeyColumns()
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 key columns, got %+v", recent)
+	}
+	if recent[0] != (database.IndexColumn{Name: "tenant_id"}) {
+		t.Errorf("expected default ordering for tenant_id, got %+v", recent[0])
+	}
+	if recent[1] != (database.IndexColumn{Name: "created_at", Descending: true, NullsOrder: database.NullsLast}) {
+		t.Errorf("expected created_at DESC NULLS LAST, got %+v", recent[1])
+	}
+
+	// Explicit default ordering is not recorded
+	if tenant := indexes["idx_events_tenant"]; tenant.Ordering != nil {
+		t.Errorf("expected no ordering for idx_events_tenant, got %+v", tenant.Ordering)
+	}
+}
+
+func TestParseSQLSchemaCreateTableLike(t *testing.T) {
+	base := `
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id),
+    payload JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_events_tenant ON events (tenant_id, created_at DESC);
+`
+
+	tests := []struct {
+		name            string
+		like            string
+		wantDefaults    bool
+		wantIndexes     bool
+		wantExtraColumn bool
+	}{
+		{name: "plain LIKE", like: "CREATE TABLE events_archive (LIKE events);"},
+		{name: "including defaults", like: "CREATE TABLE events_archive (LIKE events INCLUDING DEFAULTS);", wantDefaults: true},
+		{name: "including all", like: "CREATE TABLE events_archive (LIKE events INCLUDING ALL);", wantDefaults: true, wantIndexes: true},
+		{name: "including all excluding indexes", like: "CREATE TABLE events_archive (LIKE events INCLUDING ALL EXCLUDING INDEXES);", wantDefaults: true},
+		{name: "extra columns", like: "CREATE TABLE events_archive (LIKE events, archived_at TIMESTAMPTZ NOT NULL);", wantExtraColumn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSQLSchema(base + tt.like)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			archive := findTable(schema, "", "events_archive")
+			if archive == nil {
+				t.Fatal("expected events_archive table")
+			}
+
+			wantColumns := 4
+			if tt.wantExtraColumn {
+				wantColumns = 5
+			}
+			if len(archive.Columns) != wantColumns {
+				t.Fatalf("expected %d columns, got %+v", wantColumns, archive.Columns)
+			}
+
+			id := archive.Columns[0]
+			if id.Type != "bigint" || id.Nullable {
+				t.Errorf("expected id to be a NOT NULL bigint, got %+v", id)
+			}
+			if id.IsPrimaryKey != tt.wantIndexes {
+				t.Errorf("expected IsPrimaryKey=%v, got %v", tt.wantIndexes, id.IsPrimaryKey)
+			}
+
+			payload := archive.Columns[2]
+			if tt.wantDefaults {
+				if payload.Default == nil || id.Default == nil || *id.Default != "nextval('events_id_seq'::regclass)" {
+					t.Errorf("expected defaults to be copied, got id=%v payload=%v", id.Default, payload.Default)
+				}
+			} else if payload.Default != nil || id.Default != nil {
+				t.Errorf("expected no defaults, got id=%v payload=%v", id.Default, payload.Default)
+			}
+
+			if tt.wantIndexes {
+				if len(archive.Indexes) != 1 || archive.Indexes[0].Name != "events_archive_tenant_id_created_at_key" {
+					t.Fatalf("expected copied unique index, got %+v", archive.Indexes)
+				}
+				if !archive.Indexes[0].HasCustomOrdering() {
+					t.Errorf("expected index ordering to be copied, got %+v", archive.Indexes[0])
+				}
+			} else if len(archive.Indexes) != 0 {
+				t.Errorf("expected no indexes, got %+v", archive.Indexes)
+			}
+
+			if len(archive.ForeignKeys) != 0 {
+				t.Errorf("expected foreign keys not to be copied, got %+v", archive.ForeignKeys)
+			}
+		})
+	}
+}
+
+func TestParseSQLSchemaCreateTableLikeUnknownSource(t *testing.T) {
+	sql := `
+CREATE TABLE events_archive (LIKE events INCLUDING ALL);
+CREATE TABLE events (id BIGINT);
+`
+
+	_, err := ParseSQLSchema(sql)
+	if err == nil {
+		t.Fatal("expected an error for a LIKE source defined later")
+	}
+	if !strings.Contains(err.Error(), "references unknown table: events") || !strings.Contains(err.Error(), "created earlier") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseSQLSchemaIdempotentDDL(t *testing.T) {
+	base := `
+CREATE TABLE teams (id integer PRIMARY KEY);
+CREATE TABLE users (
+    id integer PRIMARY KEY,
+    team_id integer,
+    legacy text,
+    CONSTRAINT users_team_fk FOREIGN KEY (team_id) REFERENCES teams(id)
+);
+CREATE TABLE scratch (id integer);
+CREATE INDEX idx_scratch_id ON scratch(id);
+`
+	tests := []struct {
+		name       string
+		with       string
+		without    string
+		postgres   bool // SQLite has no IF [NOT] EXISTS on ALTER TABLE
+		wantTables int
+	}{
+		{
+			name:       "create table",
+			with:       "CREATE TABLE IF NOT EXISTS audit (id integer);",
+			without:    "CREATE TABLE audit (id integer);",
+			wantTables: 4,
+		},
+		{
+			name:       "create index",
+			with:       "CREATE INDEX IF NOT EXISTS idx_users_team ON users(team_id);",
+			without:    "CREATE INDEX idx_users_team ON users(team_id);",
+			wantTables: 3,
+		},
+		{
+			name:       "add column",
+			with:       "ALTER TABLE users ADD COLUMN IF NOT EXISTS name text;",
+			without:    "ALTER TABLE users ADD COLUMN name text;",
+			postgres:   true,
+			wantTables: 3,
+		},
+		{
+			name:       "drop column",
+			with:       "ALTER TABLE users DROP COLUMN IF EXISTS legacy;",
+			without:    "ALTER TABLE users DROP COLUMN legacy;",
+			postgres:   true,
+			wantTables: 3,
+		},
+		{
+			name:       "drop constraint",
+			with:       "ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_fk;",
+			without:    "ALTER TABLE users DROP CONSTRAINT users_team_fk;",
+			postgres:   true,
+			wantTables: 3,
+		},
+		{
+			name:       "drop index",
+			with:       "DROP INDEX IF EXISTS idx_scratch_id;",
+			without:    "DROP INDEX idx_scratch_id;",
+			wantTables: 3,
+		},
+		{
+			name:       "drop table",
+			with:       "DROP TABLE IF EXISTS scratch;",
+			without:    "DROP TABLE scratch;",
+			wantTables: 2,
+		},
+	}
+
+	for _, dialect := range []database.Dialect{database.DialectPostgres, database.DialectSQLite} {
+		for _, tt := range tests {
+			if tt.postgres && dialect != database.DialectPostgres {
+				continue
+			}
+			t.Run(string(dialect)+"/"+tt.name, func(t *testing.T) {
+				withClause, err := ParseSQLSchemaWithDialect(base+tt.with, dialect)
+				if err != nil {
+					t.Fatalf("Failed to parse with clause: %v", err)
+				}
+				withoutClause, err := ParseSQLSchemaWithDialect(base+tt.without, dialect)
+				if err != nil {
+					t.Fatalf("Failed to parse without clause: %v", err)
+				}
+				if !reflect.DeepEqual(withClause, withoutClause) {
+					t.Errorf("expected the same schema with and without the clause\nwith:    %+v\nwithout: %+v", withClause, withoutClause)
+				}
+				if len(withClause.Tables) != tt.wantTables {
+					t.Errorf("expected %d tables, got %d", tt.wantTables, len(withClause.Tables))
+				}
+			})
+		}
+	}
+}
+
+func TestParseSQLSchemaIdempotentDDLMissingObjects(t *testing.T) {
+	base := "CREATE TABLE users (id integer PRIMARY KEY);\n"
+
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS legacy;",
+		"DROP INDEX IF EXISTS idx_legacy;",
+		"ALTER TABLE users DROP COLUMN IF EXISTS legacy;",
+		"ALTER TABLE users DROP CONSTRAINT IF EXISTS users_legacy_fk;",
+		"ALTER TABLE IF EXISTS legacy ADD COLUMN name text;",
+		"CREATE TABLE IF NOT EXISTS users (id bigint);",
+	} {
+		schema, err := ParseSQLSchema(base + stmt)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", stmt, err)
+			continue
+		}
+		if len(schema.Tables) != 1 || schema.Tables[0].Columns[0].Type != "integer" {
+			t.Errorf("%s: expected the users table to be unchanged, got %+v", stmt, schema.Tables)
+		}
+	}
+
+	for _, stmt := range []string{
+		"DROP TABLE legacy;",
+		"DROP INDEX idx_legacy;",
+		"ALTER TABLE users DROP COLUMN legacy;",
+	} {
+		if _, err := ParseSQLSchema(base + stmt); err == nil {
+			t.Errorf("%s: expected an error for a missing object", stmt)
+		}
+	}
+}
+
+func TestParseSQLSchemaDropTableForeignKeys(t *testing.T) {
+	base := `
+CREATE TABLE teams (id integer PRIMARY KEY);
+CREATE TABLE users (
+    id integer PRIMARY KEY,
+    team_id integer,
+    CONSTRAINT users_team_fk FOREIGN KEY (team_id) REFERENCES teams(id)
+);
+`
+	_, err := ParseSQLSchema(base + "DROP TABLE teams;")
+	if err == nil || !strings.Contains(err.Error(), "users_team_fk") {
+		t.Fatalf("expected an error naming the dependent foreign key, got %v", err)
+	}
+
+	schema, err := ParseSQLSchema(base + "DROP TABLE teams CASCADE;")
+	if err != nil {
+		t.Fatalf("Failed to parse DROP TABLE CASCADE: %v", err)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "users" {
+		t.Fatalf("expected only users to remain, got %+v", schema.Tables)
+	}
+	if len(schema.Tables[0].ForeignKeys) != 0 {
+		t.Errorf("expected CASCADE to drop the foreign key, got %+v", schema.Tables[0].ForeignKeys)
+	}
+}
+
+func TestExtractSchemaQualifiedNames(t *testing.T) {
+	if name, err := ExtractTableNameFromDrop("DROP TABLE billing.invoices CASCADE"); err != nil || name != "billing.invoices" {
+		t.Errorf("Expected billing.invoices, got %q (%v)", name, err)
+	}
+	if table, column, err := ExtractTableAndColumnFromAddColumn("ALTER TABLE billing.invoices ADD COLUMN total numeric"); err != nil || table != "billing.invoices" || column != "total" {
+		t.Errorf("Expected billing.invoices.total, got %q.%q (%v)", table, column, err)
+	}
+	if name, err := ExtractIndexNameFromDrop("DROP INDEX billing.invoices_user"); err != nil || name != "billing.invoices_user" {
+		t.Errorf("Expected billing.invoices_user, got %q (%v)", name, err)
+	}
+}
+
+func TestParseSQLSchemaQualifiedForeignKeyReference(t *testing.T) {
+	schema, err := ParseSQLSchema(`
+CREATE TABLE users (id integer PRIMARY KEY);
+CREATE TABLE billing.invoices (
+  id integer PRIMARY KEY,
+  user_id integer,
+  parent_id integer,
+  CONSTRAINT invoices_user_fk FOREIGN KEY (user_id) REFERENCES public.users (id),
+  CONSTRAINT invoices_parent_fk FOREIGN KEY (parent_id) REFERENCES billing.invoices (id)
+);`)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema failed: %v", err)
+	}
+	fks := schema.Tables[1].ForeignKeys
+	if len(fks) != 2 || fks[0].ReferencedTable != "users" || fks[1].ReferencedTable != "billing.invoices" {
+		t.Errorf("Expected references to users and billing.invoices, got %+v", fks)
+	}
+}
+
+func TestExtractQuotedNames(t *testing.T) {
+	if name, err := ExtractTableNameFromDrop(`DROP TABLE IF EXISTS "Users"`); err != nil || name != "Users" {
+		t.Errorf("Expected Users, got %q (%v)", name, err)
+	}
+	if name, err := ExtractTableNameFromDrop(`DROP TABLE "My Schema"."say ""hi"""`); err != nil || name != `My Schema.say "hi"` {
+		t.Errorf(`Expected My Schema.say "hi", got %q (%v)`, name, err)
+	}
+	if table, column, err := ExtractTableAndColumnFromDropColumn(`ALTER TABLE t DROP COLUMN "order"`); err != nil || table != "t" || column != "order" {
+		t.Errorf("Expected t.order, got %q.%q (%v)", table, column, err)
+	}
+	if table, column, err := ExtractTableAndColumnFromAlterType(`ALTER TABLE "Accounts" ALTER COLUMN "Balance" DROP DEFAULT, ALTER COLUMN "Balance" TYPE bigint`); err != nil || table != "Accounts" || column != "Balance" {
+		t.Errorf("Expected Accounts.Balance, got %q.%q (%v)", table, column, err)
+	}
+	for sql, want := range map[string]string{
+		`ALTER TABLE "Accounts" ALTER COLUMN "Balance" DROP DEFAULT, ALTER COLUMN "Balance" TYPE numeric(10, 2);`: "numeric(10, 2)",
+		"ALTER TABLE t ALTER COLUMN c TYPE integer USING c::integer":                                              "integer",
+	} {
+		if got, err := ExtractTypeFromAlterType(sql); err != nil || got != want {
+			t.Errorf("Expected type %q from %s, got %q (%v)", want, sql, got, err)
+		}
+	}
+	if table, err := ExtractTableNameFromCreateIndex(`CREATE UNIQUE INDEX CONCURRENTLY "Idx" ON ONLY billing."Invoices" (id)`); err != nil || table != "billing.Invoices" {
+		t.Errorf("Expected billing.Invoices, got %q (%v)", table, err)
+	}
+}
+
+func TestExtractLowercaseNames(t *testing.T) {
+	if name, err := ExtractTableNameFromDrop("drop table if exists users"); err != nil || name != "users" {
+		t.Errorf("Expected users, got %q (%v)", name, err)
+	}
+	if table, column, err := ExtractTableAndColumnFromDropColumn("Alter Table users Drop Column email"); err != nil || table != "users" || column != "email" {
+		t.Errorf("Expected users.email, got %q.%q (%v)", table, column, err)
+	}
+	if name, err := ExtractTableNameFromTruncate("truncate table only billing.invoices"); err != nil || name != "billing.invoices" {
+		t.Errorf("Expected billing.invoices, got %q (%v)", name, err)
+	}
+	if IsTruncate("ALTER TABLE users ADD COLUMN truncated_at timestamp") {
+		t.Error("Expected a truncated_at column not to be a TRUNCATE statement")
+	}
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// tempSchema is the schema PostgreSQL creates temporary tables in
+const tempSchema = "pg_temp"
+
+// IsTemporaryRelation reports whether a CREATE TABLE relation is temporary:
+// created with TEMP or TEMPORARY, or in pg_temp. UNLOGGED tables are not
+// temporary.
+func IsTemporaryRelation(rel *pg_query.RangeVar) bool {
+	return rel != nil && (rel.Relpersistence == "t" || strings.EqualFold(rel.Schemaname, tempSchema))
+}
+
+// TemporaryTables tracks the temporary tables created by schema statements,
+// and the indexes on them. Temporary tables only last for a session, so they
+// and the statements on them are left out of the schema model.
+type TemporaryTables struct {
+	tables  map[string]bool
+	indexes map[string]bool
+}
+
+// NewTemporaryTables returns a tracker with no temporary tables
+func NewTemporaryTables() *TemporaryTables {
+	return &TemporaryTables{tables: map[string]bool{}, indexes: map[string]bool{}}
+}
+
+// Skip records the temporary tables and indexes stmt creates or drops, and
+// reports whether stmt only creates, changes or drops temporary objects
+func (t *TemporaryTables) Skip(stmt *pg_query.Node) bool {
+	if stmt == nil {
+		return false
+	}
+	switch node := stmt.Node.(type) {
+	case *pg_query.Node_CreateStmt:
+		if IsTemporaryRelation(node.CreateStmt.Relation) {
+			t.tables[node.CreateStmt.Relation.Relname] = true
+			return true
+		}
+	case *pg_query.Node_IndexStmt:
+		if t.references(node.IndexStmt.Relation) {
+			if node.IndexStmt.Idxname != "" {
+				t.indexes[node.IndexStmt.Idxname] = true
+			}
+			return true
+		}
+	case *pg_query.Node_AlterTableStmt:
+		return t.references(node.AlterTableStmt.Relation)
+	case *pg_query.Node_DropStmt:
+		return t.drop(node.DropStmt)
+	}
+	return false
+}
+
+// references reports whether rel names a temporary table. Temporary tables
+// come first in the search path, so an unqualified name refers to one even
+// when a permanent table has the same name.
+func (t *TemporaryTables) references(rel *pg_query.RangeVar) bool {
+	if rel == nil {
+		return false
+	}
+	if strings.EqualFold(rel.Schemaname, tempSchema) {
+		return true
+	}
+	return rel.Schemaname == "" && t.tables[rel.Relname]
+}
+
+// drop forgets the temporary tables or indexes a DROP statement removes. It
+// reports false, leaving them tracked, unless every object it drops is
+// temporary.
+func (t *TemporaryTables) drop(stmt *pg_query.DropStmt) bool {
+	var known map[string]bool
+	switch stmt.RemoveType {
+	case pg_query.ObjectType_OBJECT_TABLE:
+		known = t.tables
+	case pg_query.ObjectType_OBJECT_INDEX:
+		known = t.indexes
+	default:
+		return false
+	}
+
+	var names []string
+	for _, object := range stmt.Objects {
+		parts := objectNameParts(object)
+		switch {
+		case len(parts) == 2 && strings.EqualFold(parts[0], tempSchema):
+		case len(parts) == 1 && known[parts[0]]:
+		default:
+			return false
+		}
+		names = append(names, parts[len(parts)-1])
+	}
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		delete(known, name)
+	}
+	return true
+}
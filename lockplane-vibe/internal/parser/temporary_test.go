@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestParseSQLSchemaTemporaryTables(t *testing.T) {
+	sql := `
+CREATE TABLE users (id integer PRIMARY KEY, email text);
+CREATE UNLOGGED TABLE events (id integer);
+CREATE TEMP TABLE staging (id integer, email text);
+CREATE INDEX idx_staging_email ON staging (email);
+ALTER TABLE staging ADD COLUMN imported_at timestamp;
+INSERT INTO staging (id, email) VALUES (1, 'a@example.com');
+DROP INDEX idx_staging_email;
+DROP TABLE staging;
+CREATE TEMPORARY TABLE users (id integer);
+ALTER TABLE users ADD COLUMN scratch text;
+CREATE TABLE pg_temp.helper (id integer);
+ALTER TABLE public.users ADD COLUMN name text;
+`
+	schema, err := ParseSQLSchema(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLSchema returned error: %v", err)
+	}
+	if len(schema.Tables) != 2 || schema.Tables[0].Name != "users" || schema.Tables[1].Name != "events" {
+		t.Fatalf("Expected only users and events, got %+v", schema.Tables)
+	}
+
+	// The unqualified ALTER TABLE changes the temporary users, which shadows
+	// the permanent one; the qualified one changes the permanent table
+	var columns []string
+	for _, col := range schema.Tables[0].Columns {
+		columns = append(columns, col.Name)
+	}
+	if len(columns) != 3 || columns[2] != "name" {
+		t.Errorf("Expected users to have id, email and name, got %v", columns)
+	}
+}
+
+func TestParseSQLSchemaTemporaryTablesSQLite(t *testing.T) {
+	sql := `
+CREATE TABLE users (id INTEGER PRIMARY KEY);
+CREATE TEMP TABLE staging (id INTEGER);
+CREATE INDEX idx_staging_id ON staging (id);
+`
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect returned error: %v", err)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "users" {
+		t.Fatalf("Expected only users, got %+v", schema.Tables)
+	}
+}
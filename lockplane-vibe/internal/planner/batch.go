@@ -0,0 +1,133 @@
+package planner
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// DefaultBatchSize is the number of rows a batched update changes per batch
+// when the plan doesn't say
+const DefaultBatchSize = 1000
+
+// BatchedUpdate is an UPDATE the executor runs in batches of rows, in order
+// of an ordering key, committing each batch on its own. A single UPDATE of a
+// large table holds its row locks until it finishes; batches release them as
+// they go, so concurrent writes only wait for one batch.
+type BatchedUpdate struct {
+	Table string `json:"table"` // Unquoted, possibly schema-qualified
+	// Key orders the batches: a NOT NULL column with unique values, such as a
+	// single-column primary key. Each batch updates a range of its values.
+	Key   string `json:"key"`
+	Set   string `json:"set"`             // Assignments of the SET clause, e.g. "status = 'active'"
+	Where string `json:"where,omitempty"` // Condition the updated rows must also meet
+	// BatchSize is the number of rows per batch (0 = DefaultBatchSize)
+	BatchSize int `json:"batch_size,omitempty"`
+	// PauseMS is how long the executor sleeps between batches, so replicas
+	// and autovacuum keep up
+	PauseMS int `json:"pause_ms,omitempty"`
+}
+
+// Size returns the number of rows per batch
+func (b BatchedUpdate) Size() int {
+	if b.BatchSize > 0 {
+		return b.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+// Pause returns the time to sleep between batches
+func (b BatchedUpdate) Pause() time.Duration {
+	return time.Duration(b.PauseMS) * time.Millisecond
+}
+
+// SQL returns the update as a single statement for driver, as reviewers see it
+func (b BatchedUpdate) SQL(driver database.Driver) string {
+	sql := fmt.Sprintf("UPDATE %s SET %s", quoteName(driver, b.Table), b.Set)
+	if b.Where != "" {
+		sql += " WHERE " + b.Where
+	}
+	return sql
+}
+
+// BatchedUpdateStep returns a plan step that runs update in batches. The step
+// runs outside the plan's transaction, since each batch commits on its own.
+// Its SQL is the unbatched statement, for review; the executor runs the
+// batches instead.
+func BatchedUpdateStep(driver database.Driver, description string, update BatchedUpdate) PlanStep {
+	return PlanStep{
+		Description:      fmt.Sprintf("%s in batches of %d rows ordered by %s", description, update.Size(), update.Key),
+		SQL:              []string{update.SQL(driver)},
+		NonTransactional: true,
+		Batch:            &update,
+		Operation: &Operation{
+			Kind:  OperationBatchedUpdate,
+			Table: update.Table,
+			Details: map[string]string{
+				"key":        update.Key,
+				"batch_size": strconv.Itoa(update.Size()),
+			},
+		},
+	}
+}
+
+// OrderingKey returns the column batched updates of table are ordered by:
+// its single-column primary key, or else a NOT NULL column with a unique
+// index of its own. It returns "" when the table has neither.
+func OrderingKey(table *database.Table) string {
+	if table == nil {
+		return ""
+	}
+	var primaryKey []string
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			primaryKey = append(primaryKey, col.Name)
+		}
+	}
+	if len(primaryKey) == 1 {
+		return primaryKey[0]
+	}
+	for _, idx := range table.Indexes {
+		if len(idx.Columns) == 1 && IsOrderingKey(table, idx.Columns[0]) {
+			return idx.Columns[0]
+		}
+	}
+	return ""
+}
+
+// IsOrderingKey reports whether batched updates of table can be ordered by
+// column: it is the table's only primary key column, or it is NOT NULL and
+// has a unique index of its own. Batches are ranges of key values, so rows
+// with a NULL or duplicate key would be skipped or split across batches.
+func IsOrderingKey(table *database.Table, column string) bool {
+	if table == nil {
+		return false
+	}
+	var col *database.Column
+	primaryKeyColumns := 0
+	for i := range table.Columns {
+		if table.Columns[i].IsPrimaryKey {
+			primaryKeyColumns++
+		}
+		if table.Columns[i].Name == column {
+			col = &table.Columns[i]
+		}
+	}
+	if col == nil {
+		return false
+	}
+	if col.IsPrimaryKey && primaryKeyColumns == 1 {
+		return true
+	}
+	if col.Nullable {
+		return false
+	}
+	for _, idx := range table.Indexes {
+		if idx.Unique && len(idx.Columns) == 1 && idx.Columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,31 @@
 package planner
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/xeipuuv/gojsonschema"
 )
 
+// Limits for plans fetched over HTTP(S)
+const (
+	maxRemotePlanSize = 32 << 20 // 32 MiB
+	remotePlanTimeout = 30 * time.Second
+	planSourceStdin   = "-"
+	maxPlanRedirects  = 10
+)
+
+// planHTTPClient fetches remote plans; tests replace it to trust their TLS server
+var planHTTPClient = http.DefaultClient
+
 // LoadJSONPlan loads and validates a JSON plan file, returning a Plan
 func LoadJSONPlan(path string) (*Plan, error) {
 	// Read the JSON file
@@ -16,11 +34,134 @@ func LoadJSONPlan(path string) (*Plan, error) {
 		return nil, fmt.Errorf("failed to read JSON file: %w", err)
 	}
 
+	return ParseJSONPlan(data)
+}
+
+// fetchPlan downloads plan JSON over HTTPS, or plain HTTP when allowHTTP is
+// set, up to maxRemotePlanSize. Redirects are held to the same rule, so an
+// https:// URL can't be downgraded.
+func fetchPlan(ctx context.Context, url string, allowHTTP bool) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, remotePlanTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan URL %q: %w", url, err)
+	}
+	if err := checkPlanURLScheme(req, allowHTTP); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := *planHTTPClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxPlanRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxPlanRedirects)
+		}
+		return checkPlanURLScheme(req, allowHTTP)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch plan: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch plan from %s: server returned %s", url, resp.Status)
+	}
+
+	// Read one byte past the limit to detect oversized responses
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemotePlanSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan from %s: %w", url, err)
+	}
+	if len(data) > maxRemotePlanSize {
+		return nil, fmt.Errorf("plan at %s exceeds the %d MiB limit", url, maxRemotePlanSize>>20)
+	}
+	return data, nil
+}
+
+// ErrInsecurePlanURL is returned for plan URLs that aren't https:// unless
+// PlanLoadOptions.AllowInsecureHTTP is set
+var ErrInsecurePlanURL = errors.New("plan URLs must use https://")
+
+func checkPlanURLScheme(req *http.Request, allowHTTP bool) error {
+	switch {
+	case req.URL.Scheme == "https":
+		return nil
+	case req.URL.Scheme == "http" && allowHTTP:
+		return nil
+	default:
+		return fmt.Errorf("%w, refusing to fetch %s", ErrInsecurePlanURL, req.URL.Redacted())
+	}
+}
+
+// LoadJSONPlanFromSource loads a plan from a file path, from stdin when source
+// is "-", or over HTTPS when source is an https:// URL. http:// URLs are
+// refused unless PlanLoadOptions.AllowInsecureHTTP is set.
+func LoadJSONPlanFromSource(ctx context.Context, source string, stdin io.Reader) (*Plan, error) {
+	return LoadJSONPlanFromSourceWithOptions(ctx, source, stdin, PlanLoadOptions{})
+}
+
+// LoadJSONPlanFromSourceWithOptions is LoadJSONPlanFromSource with control
+// over validation, e.g. to accept plans without steps
+func LoadJSONPlanFromSourceWithOptions(ctx context.Context, source string, stdin io.Reader, opts PlanLoadOptions) (*Plan, error) {
+	var data []byte
+	var err error
+	switch {
+	case source == planSourceStdin:
+		data, err = io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plan: %w", err)
+		}
+	case IsRemotePlanSource(source):
+		data, err = fetchPlan(ctx, source, opts.AllowInsecureHTTP)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		data, err = os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON file: %w", err)
+		}
+	}
+	return ParseJSONPlanWithOptions(data, opts)
+}
+
+// IsRemotePlanSource reports whether source is an HTTP(S) URL
+func IsRemotePlanSource(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// ParseJSONPlan parses and validates plan JSON, rejecting plans written in a
+// newer format than this version of lockplane understands, plans with unknown
+// or mistyped fields, and plans without steps
+func ParseJSONPlan(data []byte) (*Plan, error) {
+	return ParseJSONPlanWithOptions(data, PlanLoadOptions{})
+}
+
+// ParseJSONPlanWithOptions is ParseJSONPlan with control over validation
+func ParseJSONPlanWithOptions(data []byte, opts PlanLoadOptions) (*Plan, error) {
+	// Check the structure first, so errors name the JSON path of the problem
+	if err := validatePlanJSON(data); err != nil {
+		return nil, err
+	}
+
 	// Parse into Plan
 	var plan Plan
-	if err := json.Unmarshal(data, &plan); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&plan); err != nil {
 		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
 	}
+	if err := checkPlanFormatVersion(&plan); err != nil {
+		return nil, err
+	}
+	if err := checkPlanSteps(&plan, opts); err != nil {
+		return nil, err
+	}
 
 	// Validate against JSON Schema
 	schemaLoader := gojsonschema.NewReferenceLoader("file://schema-json/plan.json")
@@ -42,3 +183,13 @@ func LoadJSONPlan(path string) (*Plan, error) {
 
 	return &plan, nil
 }
+
+// checkPlanFormatVersion rejects plans from a newer lockplane release, whose
+// source hash or steps this version may not interpret correctly
+func checkPlanFormatVersion(plan *Plan) error {
+	if plan.FormatVersion > PlanFormatVersion {
+		return fmt.Errorf("plan format version %d is newer than the supported version %d; upgrade lockplane to apply this plan",
+			plan.FormatVersion, PlanFormatVersion)
+	}
+	return nil
+}
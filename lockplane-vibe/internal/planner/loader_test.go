@@ -1,7 +1,11 @@
 package planner
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -483,8 +487,138 @@ func TestLoadJSONPlan_MissingRequiredFields(t *testing.T) {
 	}
 
 	_, err := LoadJSONPlan(planPath)
-	// Should fail validation if schema exists, or succeed with empty steps if not
+	if err == nil {
+		t.Fatal("Expected error for plan without steps, got nil")
+	}
+	if !strings.Contains(err.Error(), "steps: required field is missing") {
+		t.Errorf("Expected missing steps error, got: %v", err)
+	}
+}
+
+const remotePlanJSON = `{
+	"format_version": 2,
+	"source_hash": "abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890",
+	"steps": [{"description": "Create table", "sql": ["CREATE TABLE test (id INT)"]}]
+}`
+
+// TestLoadJSONPlanFromSource_Stdin tests reading a plan from stdin ("-")
+func TestLoadJSONPlanFromSource_Stdin(t *testing.T) {
+	plan, err := LoadJSONPlanFromSource(context.Background(), "-", strings.NewReader(remotePlanJSON))
+	if err != nil {
+		t.Fatalf("LoadJSONPlanFromSource failed: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Description != "Create table" {
+		t.Errorf("unexpected plan steps: %+v", plan.Steps)
+	}
+}
+
+// TestLoadJSONPlanFromSource_URL tests fetching a plan over HTTPS
+func TestLoadJSONPlanFromSource_URL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/migration.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(remotePlanJSON))
+	}))
+	defer server.Close()
+	useTestPlanClient(t, server.Client())
+
+	plan, err := LoadJSONPlanFromSource(context.Background(), server.URL+"/migration.json", nil)
+	if err != nil {
+		t.Fatalf("LoadJSONPlanFromSource failed: %v", err)
+	}
+	if plan.SourceHash != "abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890" {
+		t.Errorf("SourceHash not preserved: %s", plan.SourceHash)
+	}
+
+	_, err = LoadJSONPlanFromSource(context.Background(), server.URL+"/missing.json", nil)
+	if err == nil {
+		t.Fatal("Expected error for missing remote plan, got nil")
+	}
+	if !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected HTTP status in error, got: %v", err)
+	}
+}
+
+// useTestPlanClient fetches remote plans with client for the rest of the test
+func useTestPlanClient(t *testing.T, client *http.Client) {
+	t.Helper()
+	previous := planHTTPClient
+	planHTTPClient = client
+	t.Cleanup(func() { planHTTPClient = previous })
+}
+
+// TestLoadJSONPlanFromSource_InsecureURL tests that plain HTTP plan URLs need
+// PlanLoadOptions.AllowInsecureHTTP
+func TestLoadJSONPlanFromSource_InsecureURL(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(remotePlanJSON))
+	}))
+	defer server.Close()
+
+	_, err := LoadJSONPlanFromSource(context.Background(), server.URL+"/migration.json", nil)
+	if !errors.Is(err, ErrInsecurePlanURL) {
+		t.Fatalf("Expected ErrInsecurePlanURL for an http:// plan URL, got: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to be sent, got %d", requests)
+	}
+
+	plan, err := LoadJSONPlanFromSourceWithOptions(context.Background(), server.URL+"/migration.json", nil, PlanLoadOptions{AllowInsecureHTTP: true})
 	if err != nil {
-		t.Logf("Got validation error (expected if schema exists): %v", err)
+		t.Fatalf("Expected AllowInsecureHTTP to fetch the plan, got: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Errorf("unexpected plan steps: %+v", plan.Steps)
+	}
+}
+
+// TestLoadJSONPlanFromSource_RedirectToHTTP tests that an https:// plan URL
+// can't be redirected to plain HTTP
+func TestLoadJSONPlanFromSource_RedirectToHTTP(t *testing.T) {
+	insecure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(remotePlanJSON))
+	}))
+	defer insecure.Close()
+	server := httptest.NewTLSServer(http.RedirectHandler(insecure.URL+"/migration.json", http.StatusFound))
+	defer server.Close()
+	useTestPlanClient(t, server.Client())
+
+	_, err := LoadJSONPlanFromSource(context.Background(), server.URL+"/migration.json", nil)
+	if !errors.Is(err, ErrInsecurePlanURL) {
+		t.Fatalf("Expected ErrInsecurePlanURL for a redirect to http://, got: %v", err)
+	}
+}
+
+// TestParseJSONPlan_NewerFormatVersion tests that plans from a newer release are rejected
+func TestParseJSONPlan_NewerFormatVersion(t *testing.T) {
+	data := strings.Replace(remotePlanJSON, `"format_version": 2`, `"format_version": 99`, 1)
+
+	_, err := ParseJSONPlan([]byte(data))
+	if err == nil {
+		t.Fatal("Expected error for newer plan format version, got nil")
+	}
+	if !strings.Contains(err.Error(), "plan format version 99") {
+		t.Errorf("Expected format version error, got: %v", err)
+	}
+}
+
+// TestIsRemotePlanSource tests URL detection for plan sources
+func TestIsRemotePlanSource(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/plan.json": true,
+		"HTTP://example.com/plan.json":  true,
+		"plan.json":                     false,
+		"-":                             false,
+		"./https/plan.json":             false,
+	}
+	for source, expected := range tests {
+		if got := IsRemotePlanSource(source); got != expected {
+			t.Errorf("IsRemotePlanSource(%q) = %v, expected %v", source, got, expected)
+		}
 	}
 }
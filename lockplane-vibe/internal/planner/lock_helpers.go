@@ -40,6 +40,32 @@ func IsValidateConstraint(step PlanStep) bool {
 	return locks.IsValidateConstraintSQL(step.SQL[0])
 }
 
+// IsLongRunning returns true if any statement in the step is expected to run
+// for a time proportional to the table size (see locks.IsLongRunningSQL)
+func IsLongRunning(step PlanStep) bool {
+	for _, sql := range step.SQL {
+		if locks.IsLongRunningSQL(sql) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresNoTransaction returns true if the step is marked NonTransactional,
+// runs a batched update, or any of its statements cannot run inside a
+// transaction block
+func RequiresNoTransaction(step PlanStep) bool {
+	if step.NonTransactional || step.Batch != nil {
+		return true
+	}
+	for _, sql := range step.SQL {
+		if locks.IsNonTransactionalSQL(sql) {
+			return true
+		}
+	}
+	return false
+}
+
 // MeasureLockDuration measures how long a plan step holds locks on the shadow DB
 func MeasureLockDuration(ctx context.Context, db *sql.DB, step PlanStep) (*locks.LockMeasurement, error) {
 	return locks.MeasureLockDurationSQL(ctx, db, step.SQL)
@@ -0,0 +1,190 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/parser"
+)
+
+// MergePlans combines several plans that were generated one after another into
+// a single plan. Steps, tombstones and advisories are concatenated in order
+// and create-then-drop pairs that cancel each other out are removed. Repeated
+// steps are kept: a later plan may set something back, as in SET DEFAULT 1,
+// 2, 1.
+//
+// Plans must chain: when a plan records a target hash, the next plan's source
+// hash must match it. Two plans generated against the same source schema cannot
+// be chained and are rejected.
+func MergePlans(plans []*Plan) (*Plan, error) {
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("no plans to merge")
+	}
+
+	for i, plan := range plans {
+		if plan == nil {
+			return nil, fmt.Errorf("plan %d is nil", i+1)
+		}
+		if i == 0 {
+			continue
+		}
+		if err := checkPlansChain(plans[i-1], plan, i); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := &Plan{
+		FormatVersion: PlanFormatVersion,
+		SourceHash:    plans[0].SourceHash,
+		TargetHash:    plans[len(plans)-1].TargetHash,
+		Steps:         []PlanStep{},
+	}
+
+	var steps []PlanStep
+	for _, plan := range plans {
+		steps = append(steps, plan.Steps...)
+		merged.Tombstones = append(merged.Tombstones, plan.Tombstones...)
+		merged.Advisories = append(merged.Advisories, plan.Advisories...)
+	}
+
+	merged.Steps = removeCancellingSteps(steps)
+
+	return merged, nil
+}
+
+// checkPlansChain verifies that next can be applied directly after prev.
+// idx is the zero-based position of next in the merge list.
+func checkPlansChain(prev, next *Plan, idx int) error {
+	if next.SourceHash == "" {
+		return nil
+	}
+
+	if prev.TargetHash != "" {
+		if prev.TargetHash != next.SourceHash {
+			return fmt.Errorf("plan %d does not chain from plan %d: expected source hash %s, got %s",
+				idx+1, idx, prev.TargetHash, next.SourceHash)
+		}
+		return nil
+	}
+
+	// Without a target hash we can only catch plans that start from the same
+	// schema, which means they were generated as alternatives, not a sequence.
+	if len(prev.Steps) > 0 && prev.SourceHash == next.SourceHash {
+		return fmt.Errorf("plans %d and %d were both generated against source hash %s and cannot be chained",
+			idx, idx+1, next.SourceHash)
+	}
+
+	return nil
+}
+
+// removeCancellingSteps removes pairs of steps that create an object and later
+// drop it again, along with any steps in between that only touch that object.
+// A pair is left alone if another step in between depends on the object.
+func removeCancellingSteps(steps []PlanStep) []PlanStep {
+	removed := make([]bool, len(steps))
+
+	for i, step := range steps {
+		if removed[i] || len(step.SQL) == 0 {
+			continue
+		}
+		stmt := step.SQL[0]
+
+		switch {
+		case parser.ContainsSQL(stmt, "CREATE TABLE"):
+			table, err := parser.ExtractTableNameFromCreate(stmt)
+			if err != nil {
+				continue
+			}
+			cancelPair(steps, removed, i, func(s string) bool {
+				name, err := parser.ExtractTableNameFromDrop(s)
+				return err == nil && parser.ContainsSQL(s, "DROP TABLE") && name == table
+			}, func(s string) bool {
+				return stepTargetsTable(s, table)
+			}, table)
+
+		case parser.ContainsSQL(stmt, "ADD COLUMN"):
+			table, column, err := parser.ExtractTableAndColumnFromAddColumn(stmt)
+			if err != nil {
+				continue
+			}
+			cancelPair(steps, removed, i, func(s string) bool {
+				t, c, err := parser.ExtractTableAndColumnFromDropColumn(s)
+				return err == nil && t == table && c == column
+			}, func(s string) bool {
+				t, c, err := parser.ExtractTableAndColumnFromAlterColumn(s)
+				return err == nil && t == table && c == column
+			}, column)
+
+		case parser.ContainsSQL(stmt, "CREATE INDEX") || parser.ContainsSQL(stmt, "CREATE UNIQUE INDEX"):
+			index, err := parser.ExtractIndexNameFromCreate(stmt)
+			if err != nil {
+				continue
+			}
+			cancelPair(steps, removed, i, func(s string) bool {
+				name, err := parser.ExtractIndexNameFromDrop(s)
+				return err == nil && name == index
+			}, func(string) bool { return false }, index)
+		}
+	}
+
+	result := make([]PlanStep, 0, len(steps))
+	for i, step := range steps {
+		if !removed[i] {
+			result = append(result, step)
+		}
+	}
+	return result
+}
+
+// cancelPair looks for the first step after start matching isDrop. If every
+// step in between that mentions name also satisfies isOwned, the create, the
+// drop and the owned steps are all marked as removed.
+func cancelPair(steps []PlanStep, removed []bool, start int, isDrop, isOwned func(string) bool, name string) {
+	// A qualified name may be written with quoted parts, so only its last
+	// part is looked for. Unquoted identifiers are case-insensitive and may
+	// contain $, so the match ignores case and only stops at characters that
+	// can't be part of an identifier.
+	_, bare := database.SplitQualifiedName(name)
+	mentions := regexp.MustCompile(`(?i)(?:^|[^\w$])` + regexp.QuoteMeta(bare) + `(?:$|[^\w$])`)
+
+	var owned []int
+	for j := start + 1; j < len(steps); j++ {
+		if removed[j] || len(steps[j].SQL) == 0 {
+			continue
+		}
+		stmt := steps[j].SQL[0]
+		if isDrop(stmt) {
+			removed[start] = true
+			removed[j] = true
+			for _, k := range owned {
+				removed[k] = true
+			}
+			return
+		}
+
+		if !mentions.MatchString(strings.Join(steps[j].SQL, "\n")) {
+			continue
+		}
+		if len(steps[j].SQL) == 1 && isOwned(stmt) {
+			owned = append(owned, j)
+			continue
+		}
+		// Something else depends on the object, so the pair can't be dropped
+		return
+	}
+}
+
+// stepTargetsTable reports whether a statement only modifies the given table
+func stepTargetsTable(stmt, table string) bool {
+	if parser.ContainsSQL(stmt, "ALTER TABLE") {
+		name, err := parser.ExtractTableNameFromAlter(stmt)
+		return err == nil && name == table
+	}
+	if parser.ContainsSQL(stmt, "CREATE INDEX") || parser.ContainsSQL(stmt, "CREATE UNIQUE INDEX") {
+		name, err := parser.ExtractTableNameFromCreateIndex(stmt)
+		return err == nil && name == table
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package planner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergePlans_ConcatenatesInOrder(t *testing.T) {
+	first := &Plan{
+		SourceHash: "aaa",
+		TargetHash: "bbb",
+		Steps: []PlanStep{
+			{Description: "Create table users", SQL: []string{"CREATE TABLE users (id integer NOT NULL)"}},
+		},
+	}
+	second := &Plan{
+		SourceHash: "bbb",
+		TargetHash: "ccc",
+		Steps: []PlanStep{
+			{Description: "Add column email to table users", SQL: []string{"ALTER TABLE users ADD COLUMN email text"}},
+		},
+	}
+
+	merged, err := MergePlans([]*Plan{first, second})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+
+	if merged.SourceHash != "aaa" {
+		t.Errorf("Expected source hash aaa, got %s", merged.SourceHash)
+	}
+	if merged.TargetHash != "ccc" {
+		t.Errorf("Expected target hash ccc, got %s", merged.TargetHash)
+	}
+	if len(merged.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(merged.Steps))
+	}
+	if !strings.Contains(merged.Steps[0].SQL[0], "CREATE TABLE users") {
+		t.Errorf("Expected CREATE TABLE first, got %v", merged.Steps[0].SQL)
+	}
+}
+
+func TestMergePlans_KeepsTombstonesAndAdvisories(t *testing.T) {
+	first := &Plan{
+		SourceHash: "aaa",
+		TargetHash: "bbb",
+		Steps:      []PlanStep{{Description: "Soft-drop column legacy", SQL: []string{`ALTER TABLE users RENAME COLUMN legacy TO _lockplane_legacy`}}},
+		Tombstones: []Tombstone{{Table: "users", Column: "legacy", Tombstone: "_lockplane_legacy"}},
+		Advisories: []Advisory{{Kind: AdvisoryUnusedIndex, Table: "users", Name: "users_legacy_idx"}},
+	}
+	second := &Plan{
+		SourceHash: "bbb",
+		TargetHash: "ccc",
+		Steps:      []PlanStep{{Description: "Soft-drop column old", SQL: []string{`ALTER TABLE posts RENAME COLUMN old TO _lockplane_old`}}},
+		Tombstones: []Tombstone{{Table: "posts", Column: "old", Tombstone: "_lockplane_old"}},
+		Advisories: []Advisory{{Kind: AdvisoryMissingForeignKeyIndex, Table: "posts", Name: "posts_user_fk"}},
+	}
+
+	merged, err := MergePlans([]*Plan{first, second})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+
+	wantTombstones := append(append([]Tombstone{}, first.Tombstones...), second.Tombstones...)
+	if !reflect.DeepEqual(merged.Tombstones, wantTombstones) {
+		t.Errorf("Expected tombstones %v, got %v", wantTombstones, merged.Tombstones)
+	}
+	wantAdvisories := append(append([]Advisory{}, first.Advisories...), second.Advisories...)
+	if !reflect.DeepEqual(merged.Advisories, wantAdvisories) {
+		t.Errorf("Expected advisories %v, got %v", wantAdvisories, merged.Advisories)
+	}
+}
+
+func TestMergePlans_RejectsBrokenChain(t *testing.T) {
+	first := &Plan{SourceHash: "aaa", TargetHash: "bbb", Steps: []PlanStep{{Description: "x", SQL: []string{"CREATE TABLE a (id integer)"}}}}
+	second := &Plan{SourceHash: "zzz", Steps: []PlanStep{{Description: "y", SQL: []string{"CREATE TABLE b (id integer)"}}}}
+
+	if _, err := MergePlans([]*Plan{first, second}); err == nil {
+		t.Fatal("Expected error for plans that don't chain")
+	}
+}
+
+func TestMergePlans_RejectsSameSourceHash(t *testing.T) {
+	first := &Plan{SourceHash: "aaa", Steps: []PlanStep{{Description: "x", SQL: []string{"CREATE TABLE a (id integer)"}}}}
+	second := &Plan{SourceHash: "aaa", Steps: []PlanStep{{Description: "y", SQL: []string{"CREATE TABLE b (id integer)"}}}}
+
+	_, err := MergePlans([]*Plan{first, second})
+	if err == nil {
+		t.Fatal("Expected error for plans generated against the same source")
+	}
+	if !strings.Contains(err.Error(), "cannot be chained") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestMergePlans_DropsCreateThenDropTable(t *testing.T) {
+	first := &Plan{Steps: []PlanStep{
+		{Description: "Create table users", SQL: []string{"CREATE TABLE users (id integer)"}},
+		{Description: "Create table temp", SQL: []string{"CREATE TABLE temp (id integer)"}},
+		{Description: "Create index idx_temp_id on table temp", SQL: []string{"CREATE INDEX idx_temp_id ON temp (id)"}},
+	}}
+	second := &Plan{Steps: []PlanStep{
+		{Description: "Add column name to table temp", SQL: []string{"ALTER TABLE temp ADD COLUMN name text"}},
+		{Description: "Drop table temp", SQL: []string{"DROP TABLE temp CASCADE"}},
+	}}
+
+	merged, err := MergePlans([]*Plan{first, second})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+
+	if len(merged.Steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d: %+v", len(merged.Steps), merged.Steps)
+	}
+	if merged.Steps[0].Description != "Create table users" {
+		t.Errorf("Expected users table to remain, got %s", merged.Steps[0].Description)
+	}
+}
+
+func TestMergePlans_KeepsPairWhenReferenced(t *testing.T) {
+	first := &Plan{Steps: []PlanStep{
+		{Description: "Create table teams", SQL: []string{"CREATE TABLE teams (id integer PRIMARY KEY)"}},
+		{Description: "Add foreign key", SQL: []string{"ALTER TABLE users ADD CONSTRAINT fk_team FOREIGN KEY (team_id) REFERENCES teams (id)"}},
+	}}
+	second := &Plan{Steps: []PlanStep{
+		{Description: "Drop table teams", SQL: []string{"DROP TABLE teams CASCADE"}},
+	}}
+
+	merged, err := MergePlans([]*Plan{first, second})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+
+	if len(merged.Steps) != 3 {
+		t.Errorf("Expected all 3 steps to remain, got %d", len(merged.Steps))
+	}
+}
+
+func TestMergePlans_DropsAddThenDropColumn(t *testing.T) {
+	first := &Plan{Steps: []PlanStep{
+		{Description: "Add column nickname", SQL: []string{"ALTER TABLE users ADD COLUMN nickname text"}},
+		{Description: "Set NOT NULL", SQL: []string{"ALTER TABLE users ALTER COLUMN nickname SET NOT NULL"}},
+	}}
+	second := &Plan{Steps: []PlanStep{
+		{Description: "Drop column nickname", SQL: []string{"ALTER TABLE users DROP COLUMN nickname"}},
+	}}
+
+	merged, err := MergePlans([]*Plan{first, second})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+
+	if len(merged.Steps) != 0 {
+		t.Errorf("Expected no steps, got %+v", merged.Steps)
+	}
+}
+
+func TestMergePlans_CaseInsensitiveSQL(t *testing.T) {
+	merged, err := MergePlans([]*Plan{
+		{Steps: []PlanStep{
+			{Description: "Create index idx_users_email", SQL: []string{"create index idx_users_email on users (email)"}},
+			{Description: "Create table temp", SQL: []string{"Create Table temp (id integer)"}},
+			{Description: "Add column nickname", SQL: []string{"alter table users add column nickname text"}},
+		}},
+		{Steps: []PlanStep{
+			{Description: "Drop index idx_users_email", SQL: []string{"drop index idx_users_email"}},
+			{Description: "Drop table temp", SQL: []string{"DROP TABLE temp"}},
+			{Description: "Drop column nickname", SQL: []string{"ALTER TABLE users Drop Column nickname"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+	if len(merged.Steps) != 0 {
+		t.Errorf("Expected every pair to cancel, got %+v", merged.Steps)
+	}
+}
+
+func TestMergePlans_KeepsPairReferencedInOtherCase(t *testing.T) {
+	for _, reference := range []string{
+		"CREATE VIEW active AS SELECT * FROM TEMP",
+		"CREATE VIEW active AS SELECT * FROM public.Temp",
+	} {
+		merged, err := MergePlans([]*Plan{
+			{Steps: []PlanStep{
+				{Description: "Create table temp", SQL: []string{"CREATE TABLE temp (id integer)"}},
+				{Description: "Create view active", SQL: []string{reference}},
+			}},
+			{Steps: []PlanStep{{Description: "Drop table temp", SQL: []string{"drop table temp cascade"}}}},
+		})
+		if err != nil {
+			t.Fatalf("MergePlans returned error: %v", err)
+		}
+		if len(merged.Steps) != 3 {
+			t.Errorf("Expected the pair to stay when %q references it, got %+v", reference, merged.Steps)
+		}
+	}
+
+	// A name that only contains the created one doesn't reference it
+	merged, err := MergePlans([]*Plan{
+		{Steps: []PlanStep{
+			{Description: "Create index idx", SQL: []string{"CREATE INDEX idx ON users (id)"}},
+			{Description: "Create index idx$2", SQL: []string{"CREATE INDEX idx$2 ON users (email)"}},
+		}},
+		{Steps: []PlanStep{{Description: "Drop index idx", SQL: []string{"DROP INDEX idx"}}}},
+	})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+	if len(merged.Steps) != 1 || merged.Steps[0].Description != "Create index idx$2" {
+		t.Errorf("Expected only idx$2 to remain, got %+v", merged.Steps)
+	}
+}
+
+func TestMergePlans_KeepsRepeatedSteps(t *testing.T) {
+	setDefault := func(value string) PlanStep {
+		return PlanStep{
+			Description: "Set default of users.status to " + value,
+			SQL:         []string{"ALTER TABLE users ALTER COLUMN status SET DEFAULT " + value},
+		}
+	}
+	merged, err := MergePlans([]*Plan{
+		{Steps: []PlanStep{setDefault("1")}},
+		{Steps: []PlanStep{setDefault("2")}},
+		{Steps: []PlanStep{setDefault("1")}},
+	})
+	if err != nil {
+		t.Fatalf("MergePlans returned error: %v", err)
+	}
+
+	var got []string
+	for _, step := range merged.Steps {
+		got = append(got, step.SQL[0])
+	}
+	want := []string{setDefault("1").SQL[0], setDefault("2").SQL[0], setDefault("1").SQL[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected every step in order, got %v", got)
+	}
+}
+
+func TestMergePlans_Empty(t *testing.T) {
+	if _, err := MergePlans(nil); err == nil {
+		t.Error("Expected error when merging no plans")
+	}
+}
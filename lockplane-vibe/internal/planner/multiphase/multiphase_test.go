@@ -1,8 +1,12 @@
 package multiphase
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
 )
 
 func TestGenerateExpandContractPlan(t *testing.T) {
@@ -449,3 +453,146 @@ func TestGenerateDropTablePlan_SafetyNotes(t *testing.T) {
 		t.Error("Expected foreign key warning in safety notes")
 	}
 }
+
+func TestGeneratePKToUUIDPlan(t *testing.T) {
+	refs := []ReferencingColumn{
+		{Table: "orders", Column: "user_id", ForeignKey: "orders_user_id_fkey", OnDelete: "CASCADE", NotNull: true},
+	}
+	plan, err := GeneratePKToUUIDPlan("users", "id", refs, 500, "abc123")
+	if err != nil {
+		t.Fatalf("Failed to generate pk to uuid plan: %v", err)
+	}
+
+	if plan.Pattern != "pk_to_uuid" {
+		t.Errorf("Expected pattern 'pk_to_uuid', got '%s'", plan.Pattern)
+	}
+	if plan.TotalPhases != 6 || len(plan.Phases) != 6 {
+		t.Fatalf("Expected 6 phases, got %d (%d in array)", plan.TotalPhases, len(plan.Phases))
+	}
+
+	expectedPhases := []string{"expand", "dual_write", "backfill", "constrain", "migrate_reads", "contract"}
+	for i, expectedName := range expectedPhases {
+		if plan.Phases[i].Name != expectedName {
+			t.Errorf("Phase %d: expected name '%s', got '%s'", i+1, expectedName, plan.Phases[i].Name)
+		}
+		if plan.Phases[i].DependsOnPhase != i {
+			t.Errorf("Phase %d: expected DependsOnPhase %d, got %d", i+1, i, plan.Phases[i].DependsOnPhase)
+		}
+	}
+
+	// The volatile default must not be part of ADD COLUMN, which would rewrite the table
+	expand := plan.Phases[0].Plan.Steps
+	if expand[0].SQL[0] != "ALTER TABLE users ADD COLUMN id_uuid UUID" {
+		t.Errorf("Expected the UUID column to be added without a default, got %q", expand[0].SQL[0])
+	}
+	if !strings.Contains(expand[1].SQL[0], "SET DEFAULT gen_random_uuid()") {
+		t.Errorf("Expected the default to be set separately, got %q", expand[1].SQL[0])
+	}
+
+	for _, step := range plan.Phases[2].Plan.Steps {
+		if !step.NonTransactional || !step.LongRunning {
+			t.Errorf("Expected backfill step %q to be non-transactional and long running", step.Description)
+		}
+		if step.LockMode != "ROW EXCLUSIVE" || step.BlocksWrites {
+			t.Errorf("Expected backfill step %q to take row locks only, got %s", step.Description, step.LockMode)
+		}
+		if !strings.Contains(step.SQL[0], "LIMIT 500") || !strings.Contains(step.SQL[0], "COMMIT;") {
+			t.Errorf("Expected backfill to commit batches of 500 rows, got:\n%s", step.SQL[0])
+		}
+	}
+	if !strings.Contains(plan.Phases[2].Plan.Steps[1].SQL[0], "UPDATE orders AS r SET user_id_uuid = p.id_uuid FROM users AS p") {
+		t.Errorf("Expected the referencing column to be backfilled via a join, got:\n%s", plan.Phases[2].Plan.Steps[1].SQL[0])
+	}
+
+	constrainSQL := phaseSQL(plan.Phases[3])
+	for _, want := range []string{
+		"ALTER TABLE users ADD CONSTRAINT users_id_uuid_not_null CHECK (id_uuid IS NOT NULL) NOT VALID",
+		"CREATE UNIQUE INDEX CONCURRENTLY users_id_uuid_key ON users (id_uuid)",
+		"ALTER TABLE orders ALTER COLUMN user_id_uuid SET NOT NULL",
+		"ALTER TABLE orders ADD CONSTRAINT orders_user_id_uuid_fkey FOREIGN KEY (user_id_uuid) REFERENCES users (id_uuid) ON DELETE CASCADE NOT VALID",
+		"ALTER TABLE orders VALIDATE CONSTRAINT orders_user_id_uuid_fkey",
+	} {
+		if !strings.Contains(constrainSQL, want) {
+			t.Errorf("Expected constrain phase to contain %q, got:\n%s", want, constrainSQL)
+		}
+	}
+
+	contractSQL := phaseSQL(plan.Phases[5])
+	for _, want := range []string{
+		"ALTER TABLE orders DROP CONSTRAINT orders_user_id_fkey",
+		"ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY USING INDEX users_id_uuid_key",
+		"ALTER TABLE users DROP COLUMN id",
+		"ALTER TABLE users RENAME COLUMN id_uuid TO id",
+		"ALTER TABLE orders RENAME CONSTRAINT orders_user_id_uuid_fkey TO orders_user_id_fkey",
+	} {
+		if !strings.Contains(contractSQL, want) {
+			t.Errorf("Expected contract phase to contain %q, got:\n%s", want, contractSQL)
+		}
+	}
+	if strings.Index(contractSQL, "DROP CONSTRAINT orders_user_id_fkey") > strings.Index(contractSQL, "DROP CONSTRAINT users_pkey") {
+		t.Error("Expected the old foreign key to be dropped before the primary key it depends on")
+	}
+}
+
+func TestGeneratePKToUUIDPlan_Validation(t *testing.T) {
+	if _, err := GeneratePKToUUIDPlan("", "id", nil, 0, "abc123"); err == nil {
+		t.Error("Expected error for empty table name")
+	}
+	if _, err := GeneratePKToUUIDPlan("users", "id", []ReferencingColumn{{Table: "orders"}}, 0, "abc123"); err == nil {
+		t.Error("Expected error for referencing column without a column name")
+	}
+
+	plan, err := GeneratePKToUUIDPlan("users", "id", nil, 0, "abc123")
+	if err != nil {
+		t.Fatalf("Failed to generate pk to uuid plan: %v", err)
+	}
+	if !strings.Contains(plan.Phases[2].Plan.Steps[0].SQL[0], "LIMIT 10000") {
+		t.Error("Expected the default batch size")
+	}
+	if plan.Phases[1].RequiresCodeDeploy {
+		t.Error("Expected no dual-write deploy without referencing foreign keys")
+	}
+}
+
+func TestPrimaryKeyReferences(t *testing.T) {
+	onDelete := "SET NULL"
+	s := &database.Schema{Tables: []database.Table{
+		{Name: "users", Columns: []database.Column{{Name: "id", Type: "bigint", IsPrimaryKey: true}}},
+		{
+			Name:    "posts",
+			Columns: []database.Column{{Name: "id", IsPrimaryKey: true}, {Name: "author_id", Nullable: true}},
+			ForeignKeys: []database.ForeignKey{
+				{Name: "posts_author_fk", Columns: []string{"author_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: &onDelete},
+			},
+		},
+		{Name: "pairs", Columns: []database.Column{{Name: "a", IsPrimaryKey: true}, {Name: "b", IsPrimaryKey: true}}},
+	}}
+
+	column, refs, err := PrimaryKeyReferences(s, "users")
+	if err != nil {
+		t.Fatalf("Failed to find primary key references: %v", err)
+	}
+	if column != "id" {
+		t.Errorf("Expected primary key column id, got %s", column)
+	}
+	want := []ReferencingColumn{{Table: "posts", Column: "author_id", ForeignKey: "posts_author_fk", OnDelete: "SET NULL"}}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("Expected references %+v, got %+v", want, refs)
+	}
+
+	if _, _, err := PrimaryKeyReferences(s, "pairs"); err == nil || !strings.Contains(err.Error(), "composite") {
+		t.Errorf("Expected composite primary key error, got %v", err)
+	}
+	if _, _, err := PrimaryKeyReferences(s, "missing"); err == nil {
+		t.Error("Expected error for unknown table")
+	}
+}
+
+// phaseSQL joins the SQL of all steps in a phase
+func phaseSQL(phase planner.Phase) string {
+	var sql []string
+	for _, step := range phase.Plan.Steps {
+		sql = append(sql, step.SQL...)
+	}
+	return strings.Join(sql, "\n")
+}
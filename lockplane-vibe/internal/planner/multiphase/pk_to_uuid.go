@@ -0,0 +1,527 @@
+package multiphase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/locks"
+	"github.com/lockplane/lockplane/internal/planner"
+)
+
+// DefaultBackfillBatchSize is the number of rows GeneratePKToUUIDPlan
+// backfills per transaction when no batch size is given
+const DefaultBackfillBatchSize = 10000
+
+// ReferencingColumn is a foreign key column that references the primary key
+// converted by GeneratePKToUUIDPlan
+type ReferencingColumn struct {
+	Table      string `json:"table"`
+	Column     string `json:"column"`
+	ForeignKey string `json:"foreign_key"`         // Name of the foreign key constraint
+	OnDelete   string `json:"on_delete,omitempty"` // ON DELETE action, empty for NO ACTION
+	NotNull    bool   `json:"not_null,omitempty"`
+}
+
+// PrimaryKeyReferences returns the primary key column of a table and the
+// foreign key columns referencing it. Composite primary and foreign keys are
+// not supported.
+func PrimaryKeyReferences(s *database.Schema, table string) (string, []ReferencingColumn, error) {
+	target := database.FindTable(s, table)
+	if target == nil {
+		return "", nil, fmt.Errorf("table %s not found in schema", table)
+	}
+
+	var pkColumns []string
+	for _, col := range target.Columns {
+		if col.IsPrimaryKey {
+			pkColumns = append(pkColumns, col.Name)
+		}
+	}
+	switch len(pkColumns) {
+	case 0:
+		return "", nil, fmt.Errorf("table %s has no primary key", table)
+	case 1:
+	default:
+		return "", nil, fmt.Errorf("table %s has a composite primary key (%s), which is not supported",
+			table, strings.Join(pkColumns, ", "))
+	}
+	pkColumn := pkColumns[0]
+
+	var refs []ReferencingColumn
+	for _, t := range s.Tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.ReferencedTable != table {
+				continue
+			}
+			if len(fk.Columns) != 1 || len(fk.ReferencedColumns) != 1 {
+				return "", nil, fmt.Errorf("foreign key %s on %s is a composite key, which is not supported", fk.Name, t.Name)
+			}
+			if fk.ReferencedColumns[0] != pkColumn {
+				continue
+			}
+			ref := ReferencingColumn{Table: t.Name, Column: fk.Columns[0], ForeignKey: fk.Name}
+			if fk.OnDelete != nil && !strings.EqualFold(*fk.OnDelete, "NO ACTION") {
+				ref.OnDelete = strings.ToUpper(*fk.OnDelete)
+			}
+			for _, col := range t.Columns {
+				if col.Name == ref.Column {
+					ref.NotNull = !col.Nullable
+				}
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return pkColumn, refs, nil
+}
+
+// GeneratePKToUUIDPlan creates a multi-phase plan that replaces a serial or
+// identity integer primary key with a UUID, along with the foreign keys
+// referencing it:
+// - Phase 1: Add UUID columns (the primary key one defaults to gen_random_uuid())
+// - Phase 2: Write the referencing UUID columns alongside the integer ones
+// - Phase 3: Backfill the UUID columns in batches
+// - Phase 4: Add the unique index and the foreign keys on the UUID columns
+// - Phase 5: Migrate reads to the UUID columns
+// - Phase 6: Swap the primary key and drop the integer columns
+func GeneratePKToUUIDPlan(
+	table string,
+	column string,
+	refs []ReferencingColumn,
+	batchSize int,
+	sourceHash string,
+) (*planner.MultiPhasePlan, error) {
+	if table == "" || column == "" {
+		return nil, fmt.Errorf("table and column are required")
+	}
+	for _, ref := range refs {
+		if ref.Table == "" || ref.Column == "" {
+			return nil, fmt.Errorf("referencing columns require a table and a column")
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBackfillBatchSize
+	}
+
+	uuidColumn := column + "_uuid"
+	uniqueIndex := fmt.Sprintf("%s_%s_key", table, uuidColumn)
+	pkeyName := table + "_pkey"
+
+	// Phase 1: Add UUID columns
+	var expandRollback []string
+	expandSteps := []planner.PlanStep{
+		{
+			Description: fmt.Sprintf("Add %s.%s column (type: UUID)", table, uuidColumn),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s UUID", table, uuidColumn)},
+			Operation:   &planner.Operation{Kind: planner.OperationAddColumn, Table: table, Column: uuidColumn},
+		},
+		{
+			// Setting the default separately only affects new rows; a volatile
+			// default in ADD COLUMN would rewrite the table
+			Description: fmt.Sprintf("Generate %s.%s for new rows", table, uuidColumn),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT gen_random_uuid()", table, uuidColumn)},
+			Operation: &planner.Operation{Kind: planner.OperationAlterColumn, Table: table, Column: uuidColumn,
+				Details: map[string]string{"default": "gen_random_uuid()"}},
+		},
+	}
+	for _, ref := range refs {
+		refColumn := ref.Column + "_uuid"
+		expandSteps = append(expandSteps, planner.PlanStep{
+			Description: fmt.Sprintf("Add %s.%s column (type: UUID)", ref.Table, refColumn),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s UUID", ref.Table, refColumn)},
+			Operation:   &planner.Operation{Kind: planner.OperationAddColumn, Table: ref.Table, Column: refColumn},
+		})
+		expandRollback = append(expandRollback, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", ref.Table, refColumn))
+	}
+	expandRollback = append(expandRollback, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, uuidColumn))
+
+	phase1 := planner.Phase{
+		PhaseNumber:        1,
+		Name:               "expand",
+		Description:        fmt.Sprintf("Add UUID columns to %s and the tables referencing it", table),
+		RequiresCodeDeploy: false,
+		DependsOnPhase:     0,
+		CodeChangesRequired: []string{
+			"No code changes yet - new columns are not used",
+		},
+		Plan: newPhasePlan(sourceHash, expandSteps),
+		Verification: []string{
+			fmt.Sprintf("Verify column exists: SELECT %s FROM %s LIMIT 1", uuidColumn, table),
+			fmt.Sprintf("Verify new rows get a UUID: SELECT COUNT(*) FROM %s WHERE %s IS NULL (should not grow)", table, uuidColumn),
+		},
+		Rollback: &planner.PhaseRollback{
+			Description: "Drop the UUID columns",
+			SQL:         expandRollback,
+			Note:        "Safe to rollback - the UUID columns are not used yet",
+		},
+		EstimatedDuration: "< 1 second",
+		LockImpact:        "AccessExclusive lock (brief, no table rewrite)",
+	}
+
+	// Phase 2: Dual-write referencing columns
+	dualWriteChanges := []string{}
+	for _, ref := range refs {
+		dualWriteChanges = append(dualWriteChanges,
+			fmt.Sprintf("Set %s.%s to the %s of the referenced %s row whenever %s.%s is written",
+				ref.Table, ref.Column+"_uuid", uuidColumn, table, ref.Table, ref.Column))
+	}
+	dualWriteChanges = append(dualWriteChanges,
+		fmt.Sprintf("Keep reading and writing %s.%s and the integer foreign key columns", table, column),
+		fmt.Sprintf("%s.%s is generated by its default - no code change needed for it", table, uuidColumn),
+	)
+
+	phase2 := planner.Phase{
+		PhaseNumber:         2,
+		Name:                "dual_write",
+		Description:         "Update application to write the UUID foreign key columns alongside the integer ones",
+		RequiresCodeDeploy:  len(refs) > 0,
+		DependsOnPhase:      1,
+		CodeChangesRequired: dualWriteChanges,
+		Plan:                newPhasePlan(sourceHash, nil), // Code only
+		Verification: []string{
+			"Verify new rows have both the integer and the UUID foreign key columns populated",
+		},
+		Rollback: &planner.PhaseRollback{
+			Description:  "Stop writing the UUID foreign key columns",
+			SQL:          []string{},
+			Note:         "Code deployment only - remove dual-write logic",
+			RequiresCode: true,
+		},
+		EstimatedDuration: "Instant (code deployment only)",
+		LockImpact:        "None",
+	}
+
+	// Phase 3: Backfill in batches. Each batch commits on its own, so the
+	// steps run outside a transaction.
+	backfillSteps := []planner.PlanStep{{
+		Description: fmt.Sprintf("Backfill %s.%s in batches of %d", table, uuidColumn, batchSize),
+		SQL: []string{batchedUpdateSQL(fmt.Sprintf(
+			"UPDATE %s SET %s = gen_random_uuid()\n"+
+				"    WHERE ctid = ANY (ARRAY(SELECT ctid FROM %s WHERE %s IS NULL LIMIT %d));",
+			table, uuidColumn, table, uuidColumn, batchSize))},
+		LongRunning:      true,
+		NonTransactional: true,
+	}}
+	rowLocks(&backfillSteps[0])
+	verification := []string{
+		fmt.Sprintf("Verify all rows have %s: SELECT COUNT(*) FROM %s WHERE %s IS NULL", uuidColumn, table, uuidColumn),
+	}
+	for _, ref := range refs {
+		refColumn := ref.Column + "_uuid"
+		backfillSteps = append(backfillSteps, planner.PlanStep{
+			Description: fmt.Sprintf("Backfill %s.%s from %s.%s in batches of %d", ref.Table, refColumn, table, uuidColumn, batchSize),
+			SQL: []string{batchedUpdateSQL(fmt.Sprintf(
+				"UPDATE %s AS r SET %s = p.%s FROM %s AS p\n"+
+					"    WHERE r.ctid = ANY (ARRAY(SELECT ctid FROM %s WHERE %s IS NULL AND %s IS NOT NULL LIMIT %d))\n"+
+					"    AND p.%s = r.%s;",
+				ref.Table, refColumn, uuidColumn, table,
+				ref.Table, refColumn, ref.Column, batchSize,
+				column, ref.Column))},
+			LongRunning:      true,
+			NonTransactional: true,
+		})
+		rowLocks(&backfillSteps[len(backfillSteps)-1])
+		verification = append(verification, fmt.Sprintf(
+			"Verify all references are backfilled: SELECT COUNT(*) FROM %s WHERE %s IS NULL AND %s IS NOT NULL",
+			ref.Table, refColumn, ref.Column))
+	}
+
+	phase3 := planner.Phase{
+		PhaseNumber:        3,
+		Name:               "backfill",
+		Description:        fmt.Sprintf("Backfill the UUID columns in batches of %d rows", batchSize),
+		RequiresCodeDeploy: false,
+		DependsOnPhase:     2,
+		CodeChangesRequired: []string{
+			"No code changes required for this phase",
+		},
+		Plan:         newPhasePlan(sourceHash, backfillSteps),
+		Verification: verification,
+		Rollback: &planner.PhaseRollback{
+			Description: "No rollback needed",
+			SQL:         []string{},
+			Note:        "Backfilled values are not used until phase 5; rolling back phase 1 drops them",
+		},
+		EstimatedDuration: "Depends on table size",
+		LockImpact:        "Row-level locks, one batch at a time",
+	}
+
+	// Phase 4: Constrain the UUID columns and add the new foreign keys
+	constrainSteps := notNullSteps(table, uuidColumn)
+	constrainSteps = append(constrainSteps, planner.PlanStep{
+		Description: fmt.Sprintf("Create unique index %s concurrently", uniqueIndex),
+		SQL:         []string{fmt.Sprintf("CREATE UNIQUE INDEX CONCURRENTLY %s ON %s (%s)", uniqueIndex, table, uuidColumn)},
+		Operation: &planner.Operation{Kind: planner.OperationAddIndex, Table: table,
+			Details: map[string]string{"name": uniqueIndex}},
+		LongRunning:      true,
+		NonTransactional: true,
+	})
+	constrainRollback := []string{
+		fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", uniqueIndex),
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, uuidColumn),
+	}
+	for _, ref := range refs {
+		refColumn := ref.Column + "_uuid"
+		newFK := uuidForeignKeyName(ref)
+		if ref.NotNull {
+			constrainSteps = append(constrainSteps, notNullSteps(ref.Table, refColumn)...)
+			constrainRollback = append(constrainRollback,
+				fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", ref.Table, refColumn))
+		}
+		onDelete := ""
+		if ref.OnDelete != "" {
+			onDelete = " ON DELETE " + ref.OnDelete
+		}
+		fkOp := &planner.Operation{Kind: planner.OperationAddForeignKey, Table: ref.Table, Column: refColumn,
+			Details: map[string]string{"name": newFK}}
+		constrainSteps = append(constrainSteps,
+			planner.PlanStep{
+				Description: fmt.Sprintf("Add foreign key %s on %s.%s (NOT VALID)", newFK, ref.Table, refColumn),
+				SQL: []string{fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)%s NOT VALID",
+					ref.Table, newFK, refColumn, table, uuidColumn, onDelete)},
+				Operation: fkOp,
+			},
+			planner.PlanStep{
+				Description: fmt.Sprintf("Validate foreign key %s", newFK),
+				SQL:         []string{fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", ref.Table, newFK)},
+				Operation:   fkOp,
+				LongRunning: true,
+			},
+		)
+		constrainRollback = append([]string{
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", ref.Table, newFK),
+		}, constrainRollback...)
+	}
+
+	phase4 := planner.Phase{
+		PhaseNumber:        4,
+		Name:               "constrain",
+		Description:        fmt.Sprintf("Make %s.%s NOT NULL and unique, and add foreign keys on the UUID columns", table, uuidColumn),
+		RequiresCodeDeploy: false,
+		DependsOnPhase:     3,
+		CodeChangesRequired: []string{
+			"No code changes required for this phase",
+		},
+		Plan: newPhasePlan(sourceHash, constrainSteps),
+		Verification: []string{
+			fmt.Sprintf("Verify the unique index is valid: SELECT indisvalid FROM pg_index WHERE indexrelid = '%s'::regclass", uniqueIndex),
+			"Verify the new foreign keys are validated: SELECT conname, convalidated FROM pg_constraint WHERE contype = 'f'",
+		},
+		Rollback: &planner.PhaseRollback{
+			Description: "Drop the new foreign keys, unique index and NOT NULL constraints",
+			SQL:         constrainRollback,
+			Note:        "Safe to rollback - the integer keys are still in place",
+		},
+		EstimatedDuration: "Depends on table size",
+		LockImpact:        "ShareUpdateExclusive lock during validation and index build (reads and writes continue)",
+	}
+
+	// Phase 5: Migrate reads
+	phase5 := planner.Phase{
+		PhaseNumber:        5,
+		Name:               "migrate_reads",
+		Description:        fmt.Sprintf("Update application to identify %s rows by %s", table, uuidColumn),
+		RequiresCodeDeploy: true,
+		DependsOnPhase:     4,
+		CodeChangesRequired: []string{
+			fmt.Sprintf("Look up %s rows by %s and join on the UUID foreign key columns", table, uuidColumn),
+			"Expose UUIDs instead of integer IDs in APIs and URLs",
+			"Continue writing both the integer and the UUID columns",
+		},
+		Plan: newPhasePlan(sourceHash, nil), // Code only
+		Verification: []string{
+			fmt.Sprintf("Verify no queries filter or join on %s.%s", table, column),
+			"Monitor application errors",
+		},
+		Rollback: &planner.PhaseRollback{
+			Description:  "Switch reads back to the integer keys",
+			SQL:          []string{},
+			Note:         "Code deployment only",
+			RequiresCode: true,
+		},
+		EstimatedDuration: "Instant (code deployment only)",
+		LockImpact:        "None",
+	}
+
+	// Phase 6: Contract
+	var contractSteps []planner.PlanStep
+	for _, ref := range refs {
+		contractSteps = append(contractSteps, planner.PlanStep{
+			Description: fmt.Sprintf("Drop foreign key %s", ref.ForeignKey),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", ref.Table, ref.ForeignKey)},
+			Operation: &planner.Operation{Kind: planner.OperationDropForeignKey, Table: ref.Table,
+				Details: map[string]string{"name": ref.ForeignKey}},
+		})
+	}
+	contractSteps = append(contractSteps, planner.PlanStep{
+		// Both statements in one step, so the table always has a primary key
+		Description: fmt.Sprintf("Make %s the primary key of %s", uuidColumn, table),
+		SQL: []string{
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, pkeyName),
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s PRIMARY KEY USING INDEX %s", table, pkeyName, uniqueIndex),
+		},
+	})
+	for _, ref := range refs {
+		contractSteps = append(contractSteps, planner.PlanStep{
+			Description: fmt.Sprintf("Drop old %s.%s column", ref.Table, ref.Column),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", ref.Table, ref.Column)},
+			Operation:   &planner.Operation{Kind: planner.OperationDropColumn, Table: ref.Table, Column: ref.Column},
+		})
+	}
+	contractSteps = append(contractSteps, planner.PlanStep{
+		Description: fmt.Sprintf("Drop old %s.%s column", table, column),
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)},
+		Operation:   &planner.Operation{Kind: planner.OperationDropColumn, Table: table, Column: column},
+	})
+	contractSteps = append(contractSteps, planner.PlanStep{
+		Description: fmt.Sprintf("Rename %s.%s to %s", table, uuidColumn, column),
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, uuidColumn, column)},
+	})
+	for _, ref := range refs {
+		contractSteps = append(contractSteps, planner.PlanStep{
+			Description: fmt.Sprintf("Rename %s.%s to %s", ref.Table, ref.Column+"_uuid", ref.Column),
+			SQL: []string{
+				fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", ref.Table, ref.Column+"_uuid", ref.Column),
+				fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", ref.Table, uuidForeignKeyName(ref), ref.ForeignKey),
+			},
+			Operation: &planner.Operation{Kind: planner.OperationRenameForeignKey, Table: ref.Table,
+				Details: map[string]string{"name": ref.ForeignKey, "old_name": uuidForeignKeyName(ref)}},
+		})
+	}
+
+	phase6 := planner.Phase{
+		PhaseNumber:        6,
+		Name:               "contract",
+		Description:        fmt.Sprintf("Swap the primary key of %s to UUID and drop the integer key columns", table),
+		RequiresCodeDeploy: true,
+		DependsOnPhase:     5,
+		CodeChangesRequired: []string{
+			fmt.Sprintf("Stop reading and writing %s.%s and the integer foreign key columns", table, column),
+			fmt.Sprintf("After this phase, refer to %s as %s (and each *_uuid column by its original name)", uuidColumn, column),
+		},
+		Plan: newPhasePlan(sourceHash, contractSteps),
+		Verification: []string{
+			fmt.Sprintf("Verify the primary key: SELECT pg_get_constraintdef(oid) FROM pg_constraint WHERE conname = '%s'", pkeyName),
+			fmt.Sprintf("Verify %s.%s is a UUID column", table, column),
+			"Confirm application works with final schema",
+		},
+		Rollback: &planner.PhaseRollback{
+			Description:  "Cannot easily rollback - the integer keys are dropped",
+			SQL:          []string{},
+			Warning:      fmt.Sprintf("Complex rollback: would need to re-add %s.%s, regenerate the integer keys and rebuild the foreign keys", table, column),
+			RequiresCode: true,
+		},
+		EstimatedDuration: "< 1 second",
+		LockImpact:        "AccessExclusive lock during constraint swap, DROP and RENAME",
+	}
+
+	safetyNotes := []string{
+		"Converting a primary key requires coordination between database and application",
+		"Each phase is backward compatible with previous phase",
+		fmt.Sprintf("Phase 1: Add %s and the UUID foreign key columns", uuidColumn),
+		"Phase 2: Application writes the UUID foreign key columns (dual-write)",
+		fmt.Sprintf("Phase 3: Backfill in batches of %d rows, committing each batch", batchSize),
+		"Phase 4: NOT NULL via a validated CHECK, unique index built concurrently, foreign keys added NOT VALID then validated",
+		fmt.Sprintf("Phase 5: Application reads by %s", uuidColumn),
+		fmt.Sprintf("Phase 6: Swap the primary key, drop %s.%s, rename %s to %s", table, column, uuidColumn, column),
+		fmt.Sprintf("Assumes the primary key constraint is named %s and PostgreSQL 13+ for gen_random_uuid()", pkeyName),
+		"Dropping a serial or identity column also drops its sequence",
+	}
+	if len(refs) == 0 {
+		safetyNotes = append(safetyNotes, fmt.Sprintf("No foreign keys reference %s.%s", table, column))
+	}
+
+	return &planner.MultiPhasePlan{
+		MultiPhase:  true,
+		Operation:   "convert_primary_key",
+		Description: fmt.Sprintf("Convert %s.%s primary key to UUID", table, column),
+		Pattern:     "pk_to_uuid",
+		TotalPhases: 6,
+		Phases: []planner.Phase{
+			phase1,
+			phase2,
+			phase3,
+			phase4,
+			phase5,
+			phase6,
+		},
+		SafetyNotes: safetyNotes,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+// newPhasePlan returns a phase plan with lock analysis for the steps that
+// don't have it yet
+func newPhasePlan(sourceHash string, steps []planner.PlanStep) *planner.Plan {
+	if steps == nil {
+		steps = []planner.PlanStep{}
+	}
+	for i := range steps {
+		if steps[i].LockMode == "" {
+			planner.PopulateLockAnalysis(&steps[i])
+		}
+	}
+	return &planner.Plan{SourceHash: sourceHash, Steps: steps}
+}
+
+// rowLocks sets the lock metadata of a batched backfill, which the lock
+// detector can't see through: each batch takes row locks only
+func rowLocks(step *planner.PlanStep) {
+	step.LockMode = locks.LockRowExclusive.String()
+	step.BlocksReads = locks.LockRowExclusive.BlocksReads()
+	step.BlocksWrites = locks.LockRowExclusive.BlocksWrites()
+	step.LockImpact = "Row locks on one batch at a time (concurrent reads and writes of other rows allowed)"
+}
+
+// notNullSteps sets NOT NULL on a column without scanning the table under an
+// AccessExclusive lock: a CHECK constraint is validated first, which lets
+// SET NOT NULL skip the scan (PostgreSQL 12+)
+func notNullSteps(table, column string) []planner.PlanStep {
+	check := fmt.Sprintf("%s_%s_not_null", table, column)
+	op := &planner.Operation{Kind: planner.OperationAlterColumn, Table: table, Column: column,
+		Details: map[string]string{"nullable": "false"}}
+	return []planner.PlanStep{
+		{
+			Description: fmt.Sprintf("Add NOT NULL check on %s.%s (NOT VALID)", table, column),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", table, check, column)},
+			Operation:   op,
+		},
+		{
+			Description: fmt.Sprintf("Validate NOT NULL check on %s.%s", table, column),
+			SQL:         []string{fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, check)},
+			Operation:   op,
+			LongRunning: true,
+		},
+		{
+			Description: fmt.Sprintf("Set %s.%s NOT NULL", table, column),
+			SQL: []string{
+				fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column),
+				fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, check),
+			},
+			Operation: op,
+		},
+	}
+}
+
+// batchedUpdateSQL wraps an UPDATE in a loop that commits after each batch
+// until no rows are left to update
+func batchedUpdateSQL(update string) string {
+	return "DO $$\n" +
+		"DECLARE\n" +
+		"  updated integer;\n" +
+		"BEGIN\n" +
+		"  LOOP\n" +
+		"    " + update + "\n" +
+		"    GET DIAGNOSTICS updated = ROW_COUNT;\n" +
+		"    EXIT WHEN updated = 0;\n" +
+		"    COMMIT;\n" +
+		"  END LOOP;\n" +
+		"END $$"
+}
+
+// uuidForeignKeyName returns the name of the foreign key added on the UUID
+// column of a referencing table
+func uuidForeignKeyName(ref ReferencingColumn) string {
+	return fmt.Sprintf("%s_%s_uuid_fkey", ref.Table, ref.Column)
+}
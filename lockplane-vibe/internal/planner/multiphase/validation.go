@@ -148,6 +148,7 @@ func GenerateValidationPhasePlan(
 	if constraintType == "unique" {
 		phase2.EstimatedDuration = "Depends on table size (concurrent index build)"
 		phase2.LockImpact = "None (CONCURRENTLY avoids locks)"
+		phase2.Plan.Steps[0].NonTransactional = true
 	}
 
 	phases = append(phases, phase2)
@@ -0,0 +1,140 @@
+package planner
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// addColumnSteps plans adding col to an existing table. A NOT NULL column is
+// added as nullable (with its DEFAULT), optionally backfilled, and then made
+// NOT NULL in a separate step, so existing rows never violate the constraint
+// while the column is added. Primary key columns are NOT NULL through their
+// constraint, and SQLite can't alter nullability, so both keep the inline
+// definition. Adding a NOT NULL column without a DEFAULT or a backfill fails
+// on any non-empty table and is rejected here. With opts.BackfillBatchSize
+// set, the backfill is a batched update of its own, ordered by the
+// OrderingKey of table, the column's table in the source schema.
+func addColumnSteps(driver database.Driver, table *database.Table, tableName string, col database.Column, opts PlanOptions) ([]PlanStep, error) {
+	expr, hasBackfill := opts.Backfill[BackfillKey(tableName, col.Name)]
+	hasDefault := col.Default != nil && *col.Default != ""
+	deferNotNull := !col.Nullable && !col.IsPrimaryKey && driver.SupportsFeature("ALTER_COLUMN_NULLABLE")
+
+	if !col.Nullable && !col.IsPrimaryKey && !hasDefault {
+		if !driver.SupportsFeature("ALTER_COLUMN_NULLABLE") {
+			return nil, fmt.Errorf("cannot add NOT NULL column %s.%s without a DEFAULT: %s can't make a column NOT NULL after adding it; add a DEFAULT",
+				tableName, col.Name, driver.Name())
+		}
+		if !hasBackfill {
+			return nil, fmt.Errorf("cannot add NOT NULL column %s.%s without a DEFAULT: existing rows would violate the constraint; add a DEFAULT or a backfill (--backfill %s=<expression>)",
+				tableName, col.Name, BackfillKey(tableName, col.Name))
+		}
+	}
+
+	var batchKey string
+	if hasBackfill && opts.BackfillBatchSize > 0 {
+		if batchKey = OrderingKey(table); batchKey == "" {
+			return nil, fmt.Errorf("cannot backfill %s.%s in batches: %s has no single-column primary key or NOT NULL unique column to order the batches by",
+				tableName, col.Name, tableName)
+		}
+	}
+
+	added := col
+	if deferNotNull {
+		added.Nullable = true
+	}
+	sql, desc := driver.AddColumn(tableName, added)
+	step := PlanStep{
+		Description: desc,
+		SQL:         []string{sql},
+		Operation:   &Operation{Kind: OperationAddColumn, Table: tableName, Column: col.Name},
+		Source:      col.Source,
+	}
+	if deferNotNull {
+		step.Description += " as nullable"
+		step.Operation.Details = map[string]string{"not_null": "deferred"}
+	}
+	if hasBackfill {
+		if step.Operation.Details == nil {
+			step.Operation.Details = map[string]string{}
+		}
+		step.Operation.Details["backfill"] = expr
+		if batchKey == "" {
+			step.SQL = append(step.SQL, fmt.Sprintf("UPDATE %s SET %s = %s", quoteName(driver, tableName), driver.QuoteIdentifier(col.Name), expr))
+			step.Description += fmt.Sprintf(" and backfill it with %s", expr)
+		}
+	}
+	steps := []PlanStep{step}
+	if batchKey != "" {
+		backfill := BatchedUpdateStep(driver, fmt.Sprintf("Backfill %s.%s with %s", tableName, col.Name, expr), BatchedUpdate{
+			Table:     tableName,
+			Key:       batchKey,
+			Set:       fmt.Sprintf("%s = %s", driver.QuoteIdentifier(col.Name), expr),
+			BatchSize: opts.BackfillBatchSize,
+			PauseMS:   int(opts.BackfillPause.Milliseconds()),
+		})
+		backfill.Operation.Column = col.Name
+		backfill.Operation.Details["backfill"] = expr
+		backfill.Source = col.Source
+		steps = append(steps, backfill)
+	}
+	if !deferNotNull {
+		return steps, nil
+	}
+
+	setNotNull := PlanStep{
+		Description: fmt.Sprintf("Set NOT NULL on %s.%s now that existing rows have a value", tableName, col.Name),
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", quoteName(driver, tableName), driver.QuoteIdentifier(col.Name))},
+		Operation: &Operation{
+			Kind:    OperationAlterColumn,
+			Table:   tableName,
+			Column:  col.Name,
+			Details: map[string]string{"changes": "nullable"},
+		},
+		Source: col.Source,
+	}
+	return append(steps, setNotNull), nil
+}
+
+// BackfillKey returns the PlanOptions.Backfill key of a column
+func BackfillKey(tableName, columnName string) string {
+	return tableName + "." + columnName
+}
+
+// ParseBackfills parses table.column=expression pairs into PlanOptions.Backfill.
+// The expression is everything after the first "=", so it may contain "=" itself.
+func ParseBackfills(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	backfill := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, expr, ok := strings.Cut(pair, "=")
+		key, expr = strings.TrimSpace(key), strings.TrimSpace(expr)
+		table, column, dotted := strings.Cut(key, ".")
+		if !ok || !dotted || table == "" || column == "" || expr == "" {
+			return nil, fmt.Errorf("invalid backfill %q: expected table.column=expression", pair)
+		}
+		backfill[key] = expr
+	}
+	return backfill, nil
+}
+
+// checkBackfills returns an error naming backfills that don't match a column
+// added to an existing table, which are most likely typos
+func checkBackfills(backfill map[string]string, added []string) error {
+	var unknown []string
+	for key := range backfill {
+		if !slices.Contains(added, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("backfill for %s doesn't match a column added to an existing table", strings.Join(unknown, ", "))
+}
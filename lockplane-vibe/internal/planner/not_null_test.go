@@ -0,0 +1,216 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func addedColumnDiff(col database.Column) *schema.SchemaDiff {
+	return &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:    "users",
+		AddedColumns: []database.Column{col},
+	}}}
+}
+
+func TestGeneratePlan_AddNotNullColumnWithDefault(t *testing.T) {
+	defaultVal := "'active'"
+	diff := addedColumnDiff(database.Column{Name: "status", Type: "text", Default: &defaultVal})
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected add and SET NOT NULL steps, got %+v", plan.Steps)
+	}
+	if got := plan.Steps[0].SQL[0]; got != "ALTER TABLE users ADD COLUMN status text DEFAULT 'active'" {
+		t.Errorf("Expected the column to be added as nullable, got %q", got)
+	}
+	if plan.Steps[0].Operation.Details["not_null"] != "deferred" {
+		t.Errorf("Expected the add step to record the deferred NOT NULL, got %v", plan.Steps[0].Operation.Details)
+	}
+	if got := plan.Steps[1].SQL[0]; got != "ALTER TABLE users ALTER COLUMN status SET NOT NULL" {
+		t.Errorf("Expected SET NOT NULL as a separate step, got %q", got)
+	}
+
+	// SQLite can't alter nullability; it fills existing rows with the DEFAULT
+	plan, err = GeneratePlan(diff, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || !strings.Contains(plan.Steps[0].SQL[0], "NOT NULL DEFAULT 'active'") {
+		t.Errorf("Expected an inline NOT NULL column for SQLite, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_AddNotNullColumnWithoutDefault(t *testing.T) {
+	diff := addedColumnDiff(database.Column{Name: "email", Type: "text"})
+
+	for _, driver := range []database.Driver{postgres.NewDriver(), sqlite.NewDriver()} {
+		_, err := GeneratePlan(diff, driver)
+		if err == nil || !strings.Contains(err.Error(), "cannot add NOT NULL column users.email without a DEFAULT") {
+			t.Errorf("%s: expected a plan-time error, got %v", driver.Name(), err)
+		}
+	}
+
+	// New tables keep the inline NOT NULL
+	plan, err := GeneratePlan(&schema.SchemaDiff{AddedTables: []database.Table{{
+		Name:    "users",
+		Columns: []database.Column{{Name: "email", Type: "text"}},
+	}}}, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || !strings.Contains(plan.Steps[0].SQL[0], "email text NOT NULL") {
+		t.Errorf("Expected NOT NULL inline in CREATE TABLE, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_AddNotNullColumnWithBackfill(t *testing.T) {
+	diff := addedColumnDiff(database.Column{Name: "email", Type: "text"})
+	opts := PlanOptions{Backfill: map[string]string{"users.email": "name || '@example.com'"}}
+
+	plan, err := GeneratePlanWithOptions(diff, nil, postgres.NewDriver(), opts)
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected add and SET NOT NULL steps, got %+v", plan.Steps)
+	}
+	want := []string{"ALTER TABLE users ADD COLUMN email text", "UPDATE users SET email = name || '@example.com'"}
+	if strings.Join(plan.Steps[0].SQL, "; ") != strings.Join(want, "; ") {
+		t.Errorf("Expected the column to be added and backfilled in one step, got %v", plan.Steps[0].SQL)
+	}
+	if plan.Steps[0].Operation.Details["backfill"] != "name || '@example.com'" {
+		t.Errorf("Expected the backfill in the operation details, got %v", plan.Steps[0].Operation.Details)
+	}
+	if plan.Steps[1].SQL[0] != "ALTER TABLE users ALTER COLUMN email SET NOT NULL" {
+		t.Errorf("Expected SET NOT NULL after the backfill, got %v", plan.Steps[1].SQL)
+	}
+
+	// Rolling back drops the column again
+	rollback, err := GenerateRollback(plan, &database.Schema{}, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	if last := rollback.Steps[len(rollback.Steps)-1].SQL[0]; last != "ALTER TABLE users DROP COLUMN email" {
+		t.Errorf("Expected the rollback to drop the column, got %q", last)
+	}
+
+	if _, err := GeneratePlanWithOptions(diff, nil, sqlite.NewDriver(), opts); err == nil {
+		t.Error("Expected SQLite to reject a backfilled NOT NULL column without a DEFAULT")
+	}
+}
+
+func TestGeneratePlan_BatchedBackfill(t *testing.T) {
+	diff := addedColumnDiff(database.Column{Name: "email", Type: "text"})
+	source := &database.Schema{Tables: []database.Table{{
+		Name:    "users",
+		Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}, {Name: "name", Type: "text"}},
+	}}}
+	opts := PlanOptions{
+		Backfill:          map[string]string{"users.email": "name || '@example.com'"},
+		BackfillBatchSize: 500,
+		BackfillPause:     250 * time.Millisecond,
+	}
+
+	plan, err := GeneratePlanWithOptions(diff, source, postgres.NewDriver(), opts)
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("Expected add, backfill and SET NOT NULL steps, got %+v", plan.Steps)
+	}
+	if len(plan.Steps[0].SQL) != 1 {
+		t.Errorf("Expected the add step to leave the backfill to its own step, got %v", plan.Steps[0].SQL)
+	}
+
+	backfill := plan.Steps[1]
+	want := BatchedUpdate{Table: "users", Key: "id", Set: "email = name || '@example.com'", BatchSize: 500, PauseMS: 250}
+	if backfill.Batch == nil || *backfill.Batch != want {
+		t.Fatalf("Expected batched update %+v, got %+v", want, backfill.Batch)
+	}
+	if !RequiresNoTransaction(backfill) || backfill.LongRunning {
+		t.Error("Expected the batched backfill to run outside the transaction, with the statement timeout")
+	}
+	if backfill.SQL[0] != "UPDATE users SET email = name || '@example.com'" {
+		t.Errorf("Expected the unbatched update for review, got %v", backfill.SQL)
+	}
+	if backfill.Operation.Kind != OperationBatchedUpdate || backfill.Operation.Column != "email" {
+		t.Errorf("Unexpected operation: %+v", backfill.Operation)
+	}
+
+	// Rolling back drops the column, which undoes the backfill
+	rollback, err := GenerateRollback(plan, source, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	if last := rollback.Steps[len(rollback.Steps)-1].SQL[0]; last != "ALTER TABLE users DROP COLUMN email" {
+		t.Errorf("Expected the rollback to drop the column, got %q", last)
+	}
+
+	// Without an ordering key the backfill can't be batched
+	source.Tables[0].Columns[0].IsPrimaryKey = false
+	if _, err := GeneratePlanWithOptions(diff, source, postgres.NewDriver(), opts); err == nil || !strings.Contains(err.Error(), "in batches") {
+		t.Errorf("Expected an error about the missing ordering key, got %v", err)
+	}
+}
+
+func TestOrderingKey(t *testing.T) {
+	table := &database.Table{
+		Name: "events",
+		Columns: []database.Column{
+			{Name: "tenant_id", Type: "integer", IsPrimaryKey: true},
+			{Name: "seq", Type: "integer", IsPrimaryKey: true},
+			{Name: "external_id", Type: "text", Nullable: true},
+			{Name: "uid", Type: "uuid"},
+		},
+		Indexes: []database.Index{
+			{Name: "events_external_id_key", Columns: []string{"external_id"}, Unique: true},
+			{Name: "events_uid_idx", Columns: []string{"uid"}},
+		},
+	}
+	if key := OrderingKey(table); key != "" {
+		t.Errorf("Expected no ordering key for a composite key and a nullable unique column, got %q", key)
+	}
+	if IsOrderingKey(table, "tenant_id") || IsOrderingKey(table, "external_id") || IsOrderingKey(table, "uid") {
+		t.Error("Expected part of a composite key, a nullable column and a non-unique column to be rejected")
+	}
+
+	table.Indexes[1].Unique = true
+	if key := OrderingKey(table); key != "uid" {
+		t.Errorf("Expected the NOT NULL unique column, got %q", key)
+	}
+}
+
+func TestGeneratePlan_UnknownBackfill(t *testing.T) {
+	diff := addedColumnDiff(database.Column{Name: "bio", Type: "text", Nullable: true})
+	opts := PlanOptions{Backfill: map[string]string{"users.emial": "''"}}
+
+	_, err := GeneratePlanWithOptions(diff, nil, postgres.NewDriver(), opts)
+	if err == nil || !strings.Contains(err.Error(), "backfill for users.emial") {
+		t.Errorf("Expected an error naming the unknown backfill, got %v", err)
+	}
+}
+
+func TestParseBackfills(t *testing.T) {
+	backfill, err := ParseBackfills([]string{"users.email=lower(name)", "users.active = x = 1"})
+	if err != nil {
+		t.Fatalf("ParseBackfills failed: %v", err)
+	}
+	if backfill["users.email"] != "lower(name)" || backfill["users.active"] != "x = 1" {
+		t.Errorf("Unexpected backfills: %v", backfill)
+	}
+
+	for _, pair := range []string{"users.email", "email=lower(name)", "users.email=", ".email=1"} {
+		if _, err := ParseBackfills([]string{pair}); err == nil {
+			t.Errorf("Expected %q to be rejected", pair)
+		}
+	}
+}
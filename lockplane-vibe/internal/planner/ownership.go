@@ -0,0 +1,62 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// dropTablePattern matches a DROP TABLE statement and captures the table name
+var dropTablePattern = regexp.MustCompile(`(?i)^\s*DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?("?[\w$.]+"?)`)
+
+// RecreatedTable returns the name of the existing table a step drops and
+// creates again (e.g. a SQLite table rebuild), or "" when it doesn't
+func RecreatedTable(step PlanStep) string {
+	if step.Operation == nil || step.Operation.Kind == OperationDropTable || step.Operation.Table == "" {
+		return ""
+	}
+	for _, sql := range step.SQL {
+		match := dropTablePattern.FindStringSubmatch(sql)
+		if match != nil && strings.Trim(match[1], `"`) == step.Operation.Table {
+			return step.Operation.Table
+		}
+	}
+	return ""
+}
+
+// RecreateWarning describes what a table loses when it is recreated by
+// currentUser: the new table is owned by the connecting role and has default
+// privileges. It returns "" when the table's owner and privileges are the
+// ones a new table gets anyway.
+func RecreateWarning(table database.Table, currentUser string) string {
+	var lost []string
+	if table.Owner != "" && currentUser != "" && table.Owner != currentUser {
+		lost = append(lost, fmt.Sprintf("its owner %s (the new table is owned by %s)", table.Owner, currentUser))
+	}
+	if len(table.ACL) > 0 {
+		lost = append(lost, fmt.Sprintf("its grants %s", strings.Join(table.ACL, ", ")))
+	}
+	if len(lost) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Recreating table %s loses %s; restore them after applying", table.Name, strings.Join(lost, " and "))
+}
+
+// recreateWarnings returns the warnings of a step that recreates a table of
+// sourceSchema
+func recreateWarnings(step PlanStep, sourceSchema *database.Schema) []string {
+	name := RecreatedTable(step)
+	if name == "" {
+		return nil
+	}
+	table := findTable(sourceSchema, name)
+	if table == nil {
+		return nil
+	}
+	if warning := RecreateWarning(*table, sourceSchema.CurrentUser); warning != "" {
+		return []string{warning}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func ownedPostsSchema(owner string, acl []string) *database.Schema {
+	return &database.Schema{
+		CurrentUser: "deploy",
+		Tables: []database.Table{
+			{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}}},
+			{
+				Name:    "posts",
+				Owner:   owner,
+				ACL:     acl,
+				Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}, {Name: "user_id", Type: "integer"}},
+			},
+		},
+	}
+}
+
+var postsUserFK = database.ForeignKey{
+	Name:              "posts_user_id_fkey",
+	Columns:           []string{"user_id"},
+	ReferencedTable:   "users",
+	ReferencedColumns: []string{"id"},
+}
+
+func TestGeneratePlan_RecreatedTableWarnings(t *testing.T) {
+	before := ownedPostsSchema("app_owner", []string{"app_owner=arwdDxt/app_owner", "reporting=r/app_owner"})
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:        "posts",
+		AddedForeignKeys: []database.ForeignKey{postsUserFK},
+	}}}
+
+	plan, err := GeneratePlanWithHash(diff, before, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d", len(plan.Steps))
+	}
+	if RecreatedTable(plan.Steps[0]) != "posts" {
+		t.Fatalf("Expected the SQLite foreign key step to recreate posts: %v", plan.Steps[0].SQL)
+	}
+	warnings := plan.Steps[0].Warnings
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", warnings)
+	}
+	for _, want := range []string{"its owner app_owner (the new table is owned by deploy)", "reporting=r/app_owner"} {
+		if !strings.Contains(warnings[0], want) {
+			t.Errorf("Expected warning to mention %q, got %q", want, warnings[0])
+		}
+	}
+
+	// PostgreSQL adds the foreign key in place
+	plan, err = GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	for _, step := range plan.Steps {
+		if len(step.Warnings) > 0 {
+			t.Errorf("Expected no warnings for %q, got %v", step.Description, step.Warnings)
+		}
+	}
+}
+
+func TestGeneratePlan_RecreatedTableWithoutOwnershipChange(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:        "posts",
+		AddedForeignKeys: []database.ForeignKey{postsUserFK},
+	}}}
+
+	for _, before := range []*database.Schema{ownedPostsSchema("deploy", nil), ownedPostsSchema("", nil)} {
+		plan, err := GeneratePlanWithHash(diff, before, sqlite.NewDriver())
+		if err != nil {
+			t.Fatalf("Failed to generate plan: %v", err)
+		}
+		if len(plan.Steps) != 1 || plan.Steps[0].Warnings != nil {
+			t.Errorf("Expected one step without warnings for owner %q, got %+v", before.Tables[1].Owner, plan.Steps)
+		}
+	}
+}
+
+func TestRecreateWarning(t *testing.T) {
+	table := database.Table{Name: "posts", Owner: "app_owner"}
+	if got := RecreateWarning(table, ""); got != "" {
+		t.Errorf("Expected no warning without a known current user, got %q", got)
+	}
+	if got := RecreateWarning(table, "deploy"); got != "Recreating table posts loses its owner app_owner (the new table is owned by deploy); restore them after applying" {
+		t.Errorf("Unexpected owner warning: %q", got)
+	}
+
+	table.Owner = "deploy"
+	table.ACL = []string{"=r/deploy"}
+	if got := RecreateWarning(table, "deploy"); got != "Recreating table posts loses its grants =r/deploy; restore them after applying" {
+		t.Errorf("Unexpected grants warning: %q", got)
+	}
+}
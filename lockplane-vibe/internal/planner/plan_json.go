@@ -0,0 +1,335 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/strutil"
+)
+
+// ErrEmptyPlan is returned when a plan has no steps and empty plans were not
+// explicitly allowed. A typo in the "steps" key would otherwise produce a plan
+// that applies zero steps successfully.
+var ErrEmptyPlan = errors.New("plan has no steps")
+
+// PlanSchemaID is the $id of the JSON Schema published for plan files
+const PlanSchemaID = "https://lockplane.dev/plan.json"
+
+// PlanLoadOptions controls how plan JSON is validated when loaded
+type PlanLoadOptions struct {
+	// AllowEmpty accepts plans without steps instead of returning ErrEmptyPlan
+	AllowEmpty bool
+	// AllowInsecureHTTP fetches remote plans over plain http:// URLs. Without
+	// it only https:// URLs are fetched, since a plan read over an
+	// unauthenticated connection could be altered before it is applied.
+	AllowInsecureHTTP bool
+}
+
+// jsonField is a struct field as it appears in plan JSON
+type jsonField struct {
+	name     string
+	required bool // The field has no omitempty, so plan always writes it
+	typ      reflect.Type
+}
+
+var planType = reflect.TypeOf(Plan{})
+
+// jsonFields returns the JSON fields of a struct type in declaration order
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{
+			name:     name,
+			required: !strings.Contains(","+opts+",", ",omitempty,"),
+			typ:      f.Type,
+		})
+	}
+	return fields
+}
+
+// validatePlanJSON checks the structure of plan JSON against the Plan type
+// and returns an error naming the JSON path of every problem: unknown or
+// missing fields, and values of the wrong type
+func validatePlanJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("failed to parse plan JSON: unexpected data after the plan object")
+	}
+
+	var problems []string
+	checkJSONValue("", doc, planType, &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plan JSON:\n- %s", strings.Join(problems, "\n- "))
+	}
+	return nil
+}
+
+// checkJSONValue checks that v, decoded from JSON at path, fits type t
+func checkJSONValue(path string, v any, t reflect.Type, problems *[]string) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	report := func(format string, args ...any) {
+		where := path
+		if where == "" {
+			where = "plan"
+		}
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+	if v == nil {
+		report("expected %s, got null", describeJSONType(t))
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			report("expected %s, got %s", describeJSONType(t), describeJSONValue(v))
+			return
+		}
+		fields := jsonFields(t)
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f.name
+		}
+		for _, f := range fields {
+			value, present := obj[f.name]
+			switch {
+			case !present && f.required:
+				*problems = append(*problems, joinJSONPath(path, f.name)+": required field is missing")
+			case !present:
+			case value == nil && isNillable(f.typ):
+				// Go writes nil slices, maps and pointers as null
+			default:
+				checkJSONValue(joinJSONPath(path, f.name), value, f.typ, problems)
+			}
+		}
+		unknown := make([]string, 0)
+		for key := range obj {
+			if !slices.Contains(names, key) {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			msg := joinJSONPath(path, key) + ": unknown field"
+			if closest, _ := strutil.FindClosestCommand(key, names, 2); closest != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", closest)
+			}
+			*problems = append(*problems, msg)
+		}
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			report("expected %s, got %s", describeJSONType(t), describeJSONValue(v))
+			return
+		}
+		for i, item := range arr {
+			checkJSONValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), problems)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			report("expected %s, got %s", describeJSONType(t), describeJSONValue(v))
+			return
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			checkJSONValue(joinJSONPath(path, key), obj[key], t.Elem(), problems)
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			report("expected %s, got %s", describeJSONType(t), describeJSONValue(v))
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			report("expected %s, got %s", describeJSONType(t), describeJSONValue(v))
+		}
+	case reflect.Int, reflect.Int64:
+		n, ok := v.(json.Number)
+		if !ok {
+			report("expected %s, got %s", describeJSONType(t), describeJSONValue(v))
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			report("expected %s, got %s", describeJSONType(t), n)
+		}
+	}
+}
+
+// checkPlanSteps rejects plans without steps (unless allowed), steps
+// without SQL to run and incomplete batched updates
+func checkPlanSteps(plan *Plan, opts PlanLoadOptions) error {
+	if len(plan.Steps) == 0 && !opts.AllowEmpty {
+		return ErrEmptyPlan
+	}
+	var problems []string
+	for i, step := range plan.Steps {
+		if len(step.SQL) == 0 {
+			problems = append(problems, fmt.Sprintf("steps[%d].sql: step has no SQL statements", i))
+		}
+		for j, stmt := range step.SQL {
+			if strings.TrimSpace(stmt) == "" {
+				problems = append(problems, fmt.Sprintf("steps[%d].sql[%d]: statement is empty", i, j))
+			}
+		}
+		if step.Batch != nil {
+			problems = append(problems, checkBatchedUpdate(fmt.Sprintf("steps[%d].batch", i), *step.Batch)...)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plan JSON:\n- %s", strings.Join(problems, "\n- "))
+	}
+	return nil
+}
+
+// checkBatchedUpdate returns the problems of a step's batched update, at path
+func checkBatchedUpdate(path string, batch BatchedUpdate) []string {
+	var problems []string
+	for _, field := range []struct{ name, value string }{{"table", batch.Table}, {"key", batch.Key}, {"set", batch.Set}} {
+		if strings.TrimSpace(field.value) == "" {
+			problems = append(problems, fmt.Sprintf("%s.%s: must not be empty", path, field.name))
+		}
+	}
+	if batch.BatchSize < 0 {
+		problems = append(problems, fmt.Sprintf("%s.batch_size: must not be negative", path))
+	}
+	if batch.PauseMS < 0 {
+		problems = append(problems, fmt.Sprintf("%s.pause_ms: must not be negative", path))
+	}
+	return problems
+}
+
+// PlanJSONSchema returns a JSON Schema (draft-07) document for plan files,
+// generated from the Plan type so that it always matches what the loader
+// accepts
+func PlanJSONSchema() map[string]any {
+	schema := jsonSchemaFor(planType)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = PlanSchemaID
+	schema["title"] = "Lockplane Migration Plan"
+	schema["description"] = "A migration plan generated by lockplane plan. Each step is one logical operation made of one or more SQL statements."
+	return schema
+}
+
+// jsonSchemaFor returns the JSON Schema of values of type t
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		schema := jsonSchemaFor(t.Elem())
+		schema["type"] = []any{schema["type"], "null"}
+		return schema
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for _, f := range jsonFields(t) {
+			properties[f.name] = jsonSchemaFor(f.typ)
+			if f.required {
+				required = append(required, f.name)
+			}
+		}
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice:
+		return map[string]any{"type": []string{"array", "null"}, "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// describeJSONType names the JSON type that values of t are written as
+func describeJSONType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return "an array of strings"
+		}
+		return "an array"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	default:
+		return "a string"
+	}
+}
+
+// describeJSONValue names the JSON type of a decoded value
+func describeJSONValue(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+func isNillable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func joinJSONPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
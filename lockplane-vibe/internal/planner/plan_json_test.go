@@ -0,0 +1,222 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestParseJSONPlan_StructureErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "misspelled steps",
+			data: `{"stpes": [{"description": "Create table", "sql": ["CREATE TABLE t (id INT)"]}]}`,
+			want: []string{
+				"steps: required field is missing",
+				`stpes: unknown field (did you mean "steps"?)`,
+			},
+		},
+		{
+			name: "sql as a string",
+			data: `{"steps": [{"description": "Create table", "sql": "CREATE TABLE t (id INT)"}]}`,
+			want: []string{"steps[0].sql: expected an array of strings, got a string"},
+		},
+		{
+			name: "sql statement not a string",
+			data: `{"steps": [{"description": "Create table", "sql": ["CREATE TABLE t (id INT)", 42]}]}`,
+			want: []string{"steps[0].sql[1]: expected a string, got a number"},
+		},
+		{
+			name: "missing step fields",
+			data: `{"steps": [{"sql": ["SELECT 1"]}, {"description": "Nothing"}]}`,
+			want: []string{
+				"steps[0].description: required field is missing",
+				"steps[1].sql: required field is missing",
+			},
+		},
+		{
+			name: "null sql",
+			data: `{"steps": [{"description": "Create table", "sql": null}]}`,
+			want: []string{"steps[0].sql: step has no SQL statements"},
+		},
+		{
+			name: "null statement",
+			data: `{"steps": [{"description": "Create table", "sql": [null]}]}`,
+			want: []string{"steps[0].sql[0]: expected a string, got null"},
+		},
+		{
+			name: "incomplete batched update",
+			data: `{"steps": [{"description": "Backfill", "sql": ["UPDATE users SET x = 1"], "batch": {"table": "users", "key": " ", "set": "x = 1", "batch_size": -5}}]}`,
+			want: []string{
+				"steps[0].batch.key: must not be empty",
+				"steps[0].batch.batch_size: must not be negative",
+			},
+		},
+		{
+			name: "unknown nested field",
+			data: `{"steps": [{"description": "Add index", "sql": ["SELECT 1"], "operation": {"kind": "add_index", "tabel": "users"}}]}`,
+			want: []string{`steps[0].operation.tabel: unknown field (did you mean "table"?)`},
+		},
+		{
+			name: "wrong scalar type",
+			data: `{"format_version": "2", "steps": [{"description": "Create table", "sql": ["SELECT 1"], "long_running": 1}]}`,
+			want: []string{
+				"format_version: expected an integer, got a string",
+				"steps[0].long_running: expected a boolean, got a number",
+			},
+		},
+		{
+			name: "steps not an array",
+			data: `{"steps": {"description": "Create table"}}`,
+			want: []string{"steps: expected an array, got an object"},
+		},
+		{
+			name: "plan not an object",
+			data: `[]`,
+			want: []string{"plan: expected an object, got an array"},
+		},
+		{
+			name: "empty statement",
+			data: `{"steps": [{"description": "Create table", "sql": ["  "]}, {"description": "Nothing", "sql": []}]}`,
+			want: []string{
+				"steps[0].sql[0]: statement is empty",
+				"steps[1].sql: step has no SQL statements",
+			},
+		},
+		{
+			name: "trailing data",
+			data: `{"steps": []} {"steps": []}`,
+			want: []string{"unexpected data after the plan object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJSONPlan([]byte(tt.data))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJSONPlan_EmptySteps(t *testing.T) {
+	for _, data := range []string{`{"steps": []}`, `{"steps": null}`} {
+		_, err := ParseJSONPlan([]byte(data))
+		if !errors.Is(err, ErrEmptyPlan) {
+			t.Errorf("%s: expected ErrEmptyPlan, got %v", data, err)
+		}
+
+		plan, err := ParseJSONPlanWithOptions([]byte(data), PlanLoadOptions{AllowEmpty: true})
+		if err != nil {
+			t.Fatalf("%s: expected empty plan to be allowed, got %v", data, err)
+		}
+		if len(plan.Steps) != 0 {
+			t.Errorf("%s: expected no steps, got %d", data, len(plan.Steps))
+		}
+	}
+}
+
+func TestParseJSONPlan_AcceptsSchemaReference(t *testing.T) {
+	data := `{
+		"$schema": "./plan.schema.json",
+		"steps": [{"description": "Create table", "sql": ["CREATE TABLE t (id INT)"], "operation": null}]
+	}`
+	plan, err := ParseJSONPlan([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseJSONPlan failed: %v", err)
+	}
+	if plan.Schema != "./plan.schema.json" || len(plan.Steps) != 1 {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
+
+// TestPlanJSONSchema checks that the generated schema accepts what plan
+// writes and rejects the mistakes the loader rejects
+func TestPlanJSONSchema(t *testing.T) {
+	schemaBytes, err := json.Marshal(PlanJSONSchema())
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+	schemaLoader := gojsonschema.NewBytesLoader(schemaBytes)
+
+	plan := &Plan{
+		Summary:       &ImpactSummary{TablesCreated: 1, HighestSafety: "Safe", EstimatedRows: 10},
+		FormatVersion: PlanFormatVersion,
+		SourceHash:    "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		TargetHash:    "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+		Steps: []PlanStep{{
+			Description:      "Create index",
+			SQL:              []string{"CREATE INDEX CONCURRENTLY idx_users_email ON users (email)"},
+			Source:           &database.SourceLocation{File: "schema/users.lp.sql", Line: 3, Column: 1},
+			LockMode:         "SHARE UPDATE EXCLUSIVE",
+			LockImpact:       "Blocks schema changes",
+			BlocksWrites:     true,
+			LongRunning:      true,
+			NonTransactional: true,
+			Operation:        &Operation{Kind: OperationAddIndex, Table: "users", Details: map[string]string{"index": "idx_users_email"}},
+			Review:           &StepReview{Decision: "approved", Safety: "Safe", Warnings: []string{"none"}},
+		}},
+	}
+	planBytes, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Failed to marshal plan: %v", err)
+	}
+
+	result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewBytesLoader(planBytes))
+	if err != nil {
+		t.Fatalf("Failed to validate plan: %v", err)
+	}
+	if !result.Valid() {
+		t.Errorf("Generated schema rejected a plan lockplane writes: %v", result.Errors())
+	}
+	if _, err := ParseJSONPlan(planBytes); err != nil {
+		t.Errorf("ParseJSONPlan rejected a plan lockplane writes: %v", err)
+	}
+
+	for _, invalid := range []string{
+		`{"stpes": []}`,
+		`{"steps": [{"description": "Create table", "sql": "SELECT 1"}]}`,
+		`{"steps": [{"description": "Create table", "sql": ["SELECT 1"], "lock_mod": "SHARE"}]}`,
+	} {
+		result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewStringLoader(invalid))
+		if err != nil {
+			t.Fatalf("Failed to validate plan: %v", err)
+		}
+		if result.Valid() {
+			t.Errorf("Generated schema accepted invalid plan %s", invalid)
+		}
+	}
+}
+
+// schema-json/plan.json is published for editors and plans that reference it
+// by URL, so it must stay identical to what lockplane plan schema prints
+func TestPlanJSONSchema_MatchesPublishedFile(t *testing.T) {
+	published, err := os.ReadFile(filepath.Join("..", "..", "schema-json", "plan.json"))
+	if err != nil {
+		t.Fatalf("Failed to read published schema: %v", err)
+	}
+	generated, err := json.MarshalIndent(PlanJSONSchema(), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(published), generated) {
+		t.Error("schema-json/plan.json is out of date; regenerate it with: go run . plan schema > schema-json/plan.json")
+	}
+}
@@ -2,6 +2,10 @@ package planner
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lockplane/lockplane/database"
 	sqlitedb "github.com/lockplane/lockplane/database/sqlite"
@@ -15,8 +19,14 @@ func GeneratePlan(diff *schema.SchemaDiff, driver database.Driver) (*Plan, error
 
 // GeneratePlanWithHash creates a migration plan with a source schema hash using the provided driver
 func GeneratePlanWithHash(diff *schema.SchemaDiff, sourceSchema *database.Schema, driver database.Driver) (*Plan, error) {
+	return GeneratePlanWithOptions(diff, sourceSchema, driver, PlanOptions{})
+}
+
+// GeneratePlanWithOptions creates a migration plan like GeneratePlanWithHash, with extra generation options
+func GeneratePlanWithOptions(diff *schema.SchemaDiff, sourceSchema *database.Schema, driver database.Driver, opts PlanOptions) (*Plan, error) {
 	plan := &Plan{
-		Steps: []PlanStep{},
+		FormatVersion: PlanFormatVersion,
+		Steps:         []PlanStep{},
 	}
 
 	// Compute source schema hash if provided
@@ -28,7 +38,14 @@ func GeneratePlanWithHash(diff *schema.SchemaDiff, sourceSchema *database.Schema
 		plan.SourceHash = hash
 	}
 
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
 	// Order of operations for safe migrations:
+	// 0. Create the schemas new tables and sequences are added to, then
+	//    create and alter sequences (before the columns that use them)
 	// 1. Add new tables
 	// 2. Add new columns to existing tables
 	// 3. Modify columns (type changes, nullability, defaults)
@@ -37,83 +54,211 @@ func GeneratePlanWithHash(diff *schema.SchemaDiff, sourceSchema *database.Schema
 	// 6. Remove indexes (from removed tables or columns)
 	// 7. Remove foreign keys (before referenced tables/columns are dropped)
 	// 8. Remove columns
-	// 9. Remove tables
+	// 9. Remove tables, then the sequences they don't take along
+
+	// Step 0: Create missing schemas
+	if driver.SupportsSchemas() {
+		for _, schemaName := range missingSchemas(diff, sourceSchema) {
+			plan.Steps = append(plan.Steps, createSchemaStep(driver, schemaName))
+		}
+	}
+
+	// Create and alter sequences; owners are set once the tables exist
+	sequences := driver.SupportsFeature("SEQUENCES")
+	if sequences {
+		for _, seq := range diff.AddedSequences {
+			plan.Steps = append(plan.Steps, createSequenceStep(driver, seq))
+		}
+		for _, seqDiff := range diff.ModifiedSequences {
+			if step, ok := alterSequenceStep(driver, seqDiff); ok {
+				plan.Steps = append(plan.Steps, step)
+			}
+		}
+	}
 
 	// Step 1: Add new tables
 	for _, table := range diff.AddedTables {
+		tableName := table.QualifiedName()
 		sql, desc := driver.CreateTable(table)
 		plan.Steps = append(plan.Steps, PlanStep{
 			Description: desc,
 			SQL:         []string{sql},
+			Operation:   &Operation{Kind: OperationCreateTable, Table: tableName},
+			Source:      table.Source,
 		})
 
+		// Column storage is set separately; CREATE TABLE only accepts it from PostgreSQL 16
+		if driver.SupportsFeature("COLUMN_STORAGE") {
+			for _, col := range table.Columns {
+				if schema.ColumnStorage(col) != "" {
+					plan.Steps = append(plan.Steps, columnStorageStep(driver, tableName, col))
+				}
+			}
+		}
+		if driver.SupportsFeature("COLUMN_STATISTICS") {
+			for _, col := range table.Columns {
+				if col.StatisticsTarget != nil {
+					plan.Steps = append(plan.Steps, columnStatisticsStep(driver, tableName, col))
+				}
+			}
+		}
+
 		// Add foreign keys for new tables (after table is created)
 		// For SQLite, foreign keys are included in CREATE TABLE, so skip this step
 		if driver.SupportsFeature("ALTER_ADD_FOREIGN_KEY") {
 			for _, fk := range table.ForeignKeys {
-				sql, desc := driver.AddForeignKey(table.Name, fk)
+				sql, desc := addForeignKeySQL(driver, tableName, fk, opts.Idempotent)
 				plan.Steps = append(plan.Steps, PlanStep{
 					Description: desc,
 					SQL:         []string{sql},
+					Operation:   foreignKeyOperation(OperationAddForeignKey, tableName, fk),
+					Source:      fk.Source,
 				})
 			}
 		}
 
 		// Add indexes defined on newly created tables
 		for _, idx := range table.Indexes {
-			sql, desc := driver.AddIndex(table.Name, idx)
+			sql, desc := addIndexSQL(driver, tableName, idx, opts.Idempotent)
 			plan.Steps = append(plan.Steps, PlanStep{
 				Description: desc,
 				SQL:         []string{sql},
+				Operation:   indexOperation(OperationAddIndex, tableName, idx),
+				Source:      idx.Source,
 			})
 		}
+
+		// Set the replica identity once its index exists
+		if schema.NormalizeReplicaIdentity(table.ReplicaIdentity) != "" && driver.SupportsFeature("REPLICA_IDENTITY") {
+			step := replicaIdentityStep(driver, tableName, table.ReplicaIdentity)
+			step.Source = table.Source
+			plan.Steps = append(plan.Steps, step)
+		}
 	}
 
 	// Step 2-4: Process table modifications
+	var addedColumns []string
 	for _, tableDiff := range diff.ModifiedTables {
 		// Add new columns
 		for _, col := range tableDiff.AddedColumns {
-			sql, desc := driver.AddColumn(tableDiff.TableName, col)
-			plan.Steps = append(plan.Steps, PlanStep{
-				Description: desc,
-				SQL:         []string{sql},
-			})
+			steps, err := addColumnSteps(driver, findTable(sourceSchema, tableDiff.TableName), tableDiff.TableName, col, opts)
+			if err != nil {
+				return nil, err
+			}
+			plan.Steps = append(plan.Steps, steps...)
+			addedColumns = append(addedColumns, BackfillKey(tableDiff.TableName, col.Name))
+			if schema.ColumnStorage(col) != "" && driver.SupportsFeature("COLUMN_STORAGE") {
+				plan.Steps = append(plan.Steps, columnStorageStep(driver, tableDiff.TableName, col))
+			}
+			if col.StatisticsTarget != nil && driver.SupportsFeature("COLUMN_STATISTICS") {
+				plan.Steps = append(plan.Steps, columnStatisticsStep(driver, tableDiff.TableName, col))
+			}
 		}
 
 		// Modify existing columns
 		for _, colDiff := range tableDiff.ModifiedColumns {
+			// Storage and statistics are planned on their own below, not by the driver
+			changes := make([]string, 0, len(colDiff.Changes))
+			for _, change := range colDiff.Changes {
+				if change != "storage" && change != "statistics" {
+					changes = append(changes, change)
+				}
+			}
+
 			// Convert main.ColumnDiff to database.ColumnDiff
 			dbColDiff := database.ColumnDiff{
 				ColumnName: colDiff.ColumnName,
 				Old:        colDiff.Old,
 				New:        colDiff.New,
-				Changes:    colDiff.Changes,
+				Changes:    changes,
+			}
+			var steps []database.PlanStep
+			if len(changes) > 0 {
+				steps = driver.ModifyColumn(tableDiff.TableName, dbColDiff)
 			}
-			steps := driver.ModifyColumn(tableDiff.TableName, dbColDiff)
 			// Convert []database.PlanStep to []PlanStep
 			for _, step := range steps {
 				plan.Steps = append(plan.Steps, PlanStep{
 					Description: step.Description,
 					SQL:         step.SQL,
+					Operation:   alterColumnOperation(tableDiff.TableName, colDiff),
+					Source:      colDiff.New.Source,
 				})
 			}
+			if slices.Contains(colDiff.Changes, "storage") && driver.SupportsFeature("COLUMN_STORAGE") {
+				plan.Steps = append(plan.Steps, columnStorageStep(driver, tableDiff.TableName, colDiff.New))
+			}
+			if slices.Contains(colDiff.Changes, "statistics") && driver.SupportsFeature("COLUMN_STATISTICS") {
+				plan.Steps = append(plan.Steps, columnStatisticsStep(driver, tableDiff.TableName, colDiff.New))
+			}
+		}
+
+		// Reorder columns when enforced. SQLite rebuilds the table; a foreign key
+		// change below rebuilds it in the desired order anyway.
+		if tableDiff.ColumnOrderChanged && driver.Name() == "sqlite" &&
+			len(tableDiff.AddedForeignKeys) == 0 && len(tableDiff.RemovedForeignKeys) == 0 {
+			if sqliteGen, ok := driver.(*sqlitedb.Driver); ok {
+				if table := rebuildTable(sourceSchema, &tableDiff); table != nil {
+					step := sqliteGen.ReorderColumns(*table)
+					plan.Steps = append(plan.Steps, PlanStep{
+						Description: step.Description,
+						SQL:         step.SQL,
+						Operation:   &Operation{Kind: OperationReorderColumns, Table: tableDiff.TableName},
+						Source:      tableDiff.Source,
+					})
+				}
+			}
 		}
 
 		// Add new foreign keys
+		// Rename indexes and foreign keys that only differ in name, which keeps
+		// them enforced instead of dropping and re-adding them
+		for _, idxDiff := range tableDiff.RenamedIndexes {
+			if driver.SupportsFeature("RENAME_CONSTRAINT") {
+				plan.Steps = append(plan.Steps, PlanStep{
+					Description: fmt.Sprintf("Rename index %s on table %s to %s", idxDiff.Old.Name, tableDiff.TableName, idxDiff.New.Name),
+					SQL:         []string{fmt.Sprintf("ALTER INDEX %s RENAME TO %s", quoteName(driver, database.QualifiedIndexName(tableDiff.TableName, idxDiff.Old.Name)), driver.QuoteIdentifier(idxDiff.New.Name))},
+					Operation:   renameOperation(OperationRenameIndex, tableDiff.TableName, idxDiff.Old.Name, idxDiff.New.Name),
+					Source:      idxDiff.New.Source,
+				})
+				continue
+			}
+			// SQLite can't rename indexes; recreating one is cheap there
+			dropSQL, dropDesc := driver.DropIndex(tableDiff.TableName, idxDiff.Old)
+			addSQL, addDesc := driver.AddIndex(tableDiff.TableName, idxDiff.New)
+			plan.Steps = append(plan.Steps,
+				PlanStep{
+					Description: fmt.Sprintf("%s (recreate: renamed to %s)", dropDesc, idxDiff.New.Name),
+					SQL:         []string{dropSQL},
+					Operation:   indexOperation(OperationDropIndex, tableDiff.TableName, idxDiff.Old),
+					Source:      idxDiff.New.Source,
+				},
+				PlanStep{
+					Description: addDesc,
+					SQL:         []string{addSQL},
+					Operation:   indexOperation(OperationAddIndex, tableDiff.TableName, idxDiff.New),
+					Source:      idxDiff.New.Source,
+				},
+			)
+		}
+		// SQLite doesn't keep foreign key names, so there is nothing to rename there
+		if driver.SupportsFeature("RENAME_CONSTRAINT") {
+			for _, rename := range tableDiff.RenamedForeignKeys {
+				plan.Steps = append(plan.Steps, PlanStep{
+					Description: fmt.Sprintf("Rename foreign key %s on table %s to %s", rename.OldName, tableDiff.TableName, rename.NewName),
+					SQL:         []string{fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", quoteName(driver, tableDiff.TableName), driver.QuoteIdentifier(rename.OldName), driver.QuoteIdentifier(rename.NewName))},
+					Operation:   renameOperation(OperationRenameForeignKey, tableDiff.TableName, rename.OldName, rename.NewName),
+					Source:      tableDiff.Source,
+				})
+			}
+		}
+
 		for _, fk := range tableDiff.AddedForeignKeys {
 			// For SQLite, adding foreign keys requires table recreation
 			if driver.Name() == "sqlite" && !driver.SupportsFeature("ALTER_ADD_FOREIGN_KEY") {
 				if sqliteGen, ok := driver.(*sqlitedb.Driver); ok {
-					// Find the source table to get its current definition
-					var sourceTable *database.Table
-					if sourceSchema != nil {
-						for i := range sourceSchema.Tables {
-							if sourceSchema.Tables[i].Name == tableDiff.TableName {
-								sourceTable = &sourceSchema.Tables[i]
-								break
-							}
-						}
-					}
+					// Rebuild from the table as it is at this point in the plan
+					sourceTable := rebuildTable(sourceSchema, &tableDiff)
 
 					if sourceTable != nil {
 						// Use table recreation for SQLite (returns single atomic step)
@@ -121,6 +266,8 @@ func GeneratePlanWithHash(diff *schema.SchemaDiff, sourceSchema *database.Schema
 						plan.Steps = append(plan.Steps, PlanStep{
 							Description: step.Description,
 							SQL:         step.SQL,
+							Operation:   foreignKeyOperation(OperationAddForeignKey, tableDiff.TableName, fk),
+							Source:      fk.Source,
 						})
 					} else {
 						// Fallback if we can't find the source table
@@ -128,6 +275,8 @@ func GeneratePlanWithHash(diff *schema.SchemaDiff, sourceSchema *database.Schema
 						plan.Steps = append(plan.Steps, PlanStep{
 							Description: desc,
 							SQL:         []string{sql},
+							Operation:   foreignKeyOperation(OperationAddForeignKey, tableDiff.TableName, fk),
+							Source:      fk.Source,
 						})
 					}
 				} else {
@@ -136,119 +285,610 @@ func GeneratePlanWithHash(diff *schema.SchemaDiff, sourceSchema *database.Schema
 					plan.Steps = append(plan.Steps, PlanStep{
 						Description: desc,
 						SQL:         []string{sql},
+						Operation:   foreignKeyOperation(OperationAddForeignKey, tableDiff.TableName, fk),
+						Source:      fk.Source,
 					})
 				}
 			} else {
 				// PostgreSQL and other databases can add foreign keys directly
-				sql, desc := driver.AddForeignKey(tableDiff.TableName, fk)
+				sql, desc := addForeignKeySQL(driver, tableDiff.TableName, fk, opts.Idempotent)
 				plan.Steps = append(plan.Steps, PlanStep{
 					Description: desc,
 					SQL:         []string{sql},
+					Operation:   foreignKeyOperation(OperationAddForeignKey, tableDiff.TableName, fk),
+					Source:      fk.Source,
 				})
 			}
 		}
 
 		// Add new indexes
 		for _, idx := range tableDiff.AddedIndexes {
-			sql, desc := driver.AddIndex(tableDiff.TableName, idx)
+			sql, desc := addIndexSQL(driver, tableDiff.TableName, idx, opts.Idempotent)
 			plan.Steps = append(plan.Steps, PlanStep{
 				Description: desc,
 				SQL:         []string{sql},
+				Operation:   indexOperation(OperationAddIndex, tableDiff.TableName, idx),
+				Source:      idx.Source,
 			})
 		}
 
+		// Recreate indexes whose definition cannot be altered in place
+		for _, idxDiff := range tableDiff.RecreatedIndexes {
+			dropSQL, dropDesc := driver.DropIndex(tableDiff.TableName, idxDiff.Old)
+			addSQL, addDesc := driver.AddIndex(tableDiff.TableName, idxDiff.New)
+			plan.Steps = append(plan.Steps,
+				PlanStep{
+					Description: fmt.Sprintf("%s (recreate: %s changed)", dropDesc, strings.Join(idxDiff.Changes, ", ")),
+					SQL:         []string{dropSQL},
+					Operation:   indexOperation(OperationDropIndex, tableDiff.TableName, idxDiff.Old),
+					Source:      idxDiff.New.Source,
+				},
+				PlanStep{
+					Description: addDesc,
+					SQL:         []string{addSQL},
+					Operation:   indexOperation(OperationAddIndex, tableDiff.TableName, idxDiff.New),
+					Source:      idxDiff.New.Source,
+				},
+			)
+		}
+
+		// Move indexes to a different tablespace
+		if driver.SupportsFeature("TABLESPACE") {
+			for _, idx := range tableDiff.MovedIndexes {
+				tablespace := database.TablespaceOrDefault(idx.Tablespace, diff.DefaultTablespace)
+				plan.Steps = append(plan.Steps, PlanStep{
+					Description: fmt.Sprintf("Move index %s on table %s to tablespace %s", idx.Name, tableDiff.TableName, tablespace),
+					SQL:         []string{fmt.Sprintf("ALTER INDEX %s SET TABLESPACE %s", quoteName(driver, database.QualifiedIndexName(tableDiff.TableName, idx.Name)), driver.QuoteIdentifier(tablespace))},
+					Operation:   tablespaceOperation(tableDiff.TableName, idx.Name, tablespace),
+					Source:      idx.Source,
+				})
+			}
+		}
+
+		// Change the replica identity after new indexes exist and before old ones are dropped
+		if tableDiff.ReplicaIdentityChanged && driver.SupportsFeature("REPLICA_IDENTITY") {
+			step := replicaIdentityStep(driver, tableDiff.TableName, tableDiff.ReplicaIdentity)
+			step.Source = tableDiff.Source
+			plan.Steps = append(plan.Steps, step)
+		}
+
 		// Remove old indexes
 		for _, idx := range tableDiff.RemovedIndexes {
 			sql, desc := driver.DropIndex(tableDiff.TableName, idx)
 			plan.Steps = append(plan.Steps, PlanStep{
 				Description: desc,
 				SQL:         []string{sql},
+				Operation:   indexOperation(OperationDropIndex, tableDiff.TableName, idx),
+				Source:      tableDiff.Source,
 			})
 		}
 
 		// Remove old foreign keys
 		for _, fk := range tableDiff.RemovedForeignKeys {
-			// For SQLite, dropping foreign keys requires table recreation
-			if driver.Name() == "sqlite" && !driver.SupportsFeature("ALTER_ADD_FOREIGN_KEY") {
-				if sqliteGen, ok := driver.(*sqlitedb.Driver); ok {
-					// Find the source table to get its current definition
-					var sourceTable *database.Table
-					if sourceSchema != nil {
-						for i := range sourceSchema.Tables {
-							if sourceSchema.Tables[i].Name == tableDiff.TableName {
-								sourceTable = &sourceSchema.Tables[i]
-								break
-							}
-						}
-					}
-
-					if sourceTable != nil {
-						// Use table recreation for SQLite (returns single atomic step)
-						step := sqliteGen.RecreateTableWithoutForeignKey(*sourceTable, fk.Name)
-						plan.Steps = append(plan.Steps, PlanStep{
-							Description: step.Description,
-							SQL:         step.SQL,
-						})
-					} else {
-						// Fallback if we can't find the source table
-						sql, desc := driver.DropForeignKey(tableDiff.TableName, fk)
-						plan.Steps = append(plan.Steps, PlanStep{
-							Description: desc,
-							SQL:         []string{sql},
-						})
-					}
-				} else {
-					// Should not happen, but fallback just in case
-					sql, desc := driver.DropForeignKey(tableDiff.TableName, fk)
-					plan.Steps = append(plan.Steps, PlanStep{
-						Description: desc,
-						SQL:         []string{sql},
-					})
-				}
-			} else {
-				// PostgreSQL and other databases can drop foreign keys directly
-				sql, desc := driver.DropForeignKey(tableDiff.TableName, fk)
-				plan.Steps = append(plan.Steps, PlanStep{
-					Description: desc,
-					SQL:         []string{sql},
-				})
-			}
+			step := dropForeignKeyStep(driver, rebuildTable(sourceSchema, &tableDiff), tableDiff.TableName, fk)
+			step.Source = tableDiff.Source
+			plan.Steps = append(plan.Steps, step)
 		}
 
 		// Handle RLS changes
 		if tableDiff.RLSChanged {
 			var sql, desc string
 			if tableDiff.RLSEnabled {
-				sql = fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", tableDiff.TableName)
+				sql = fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", quoteName(driver, tableDiff.TableName))
 				desc = fmt.Sprintf("Enable row level security on table %s", tableDiff.TableName)
 			} else {
-				sql = fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", tableDiff.TableName)
+				sql = fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", quoteName(driver, tableDiff.TableName))
 				desc = fmt.Sprintf("Disable row level security on table %s", tableDiff.TableName)
 			}
 			plan.Steps = append(plan.Steps, PlanStep{
 				Description: desc,
 				SQL:         []string{sql},
+				Operation:   rlsOperation(tableDiff.TableName, tableDiff.RLSEnabled),
+				Source:      tableDiff.Source,
+			})
+		}
+
+		// Move the table to a different tablespace
+		if tableDiff.TablespaceChanged && driver.SupportsFeature("TABLESPACE") {
+			tablespace := database.TablespaceOrDefault(tableDiff.Tablespace, diff.DefaultTablespace)
+			plan.Steps = append(plan.Steps, PlanStep{
+				Description: fmt.Sprintf("Move table %s to tablespace %s", tableDiff.TableName, tablespace),
+				SQL:         []string{fmt.Sprintf("ALTER TABLE %s SET TABLESPACE %s", quoteName(driver, tableDiff.TableName), driver.QuoteIdentifier(tablespace))},
+				Operation:   tablespaceOperation(tableDiff.TableName, "", tablespace),
+				Source:      tableDiff.Source,
+			})
+		}
+
+		// Set and reset storage parameters
+		if len(tableDiff.SetStorageParameters) > 0 && driver.SupportsFeature("STORAGE_PARAMETERS") {
+			plan.Steps = append(plan.Steps, PlanStep{
+				Description: fmt.Sprintf("Set storage parameters of table %s", tableDiff.TableName),
+				SQL:         []string{fmt.Sprintf("ALTER TABLE %s SET (%s)", quoteName(driver, tableDiff.TableName), database.FormatStorageParameters(tableDiff.SetStorageParameters))},
+				Operation:   storageParametersOperation(tableDiff.TableName, tableDiff.SetStorageParameters, nil),
+				Source:      tableDiff.Source,
+			})
+		}
+		if len(tableDiff.ResetStorageParameters) > 0 && driver.SupportsFeature("STORAGE_PARAMETERS") {
+			plan.Steps = append(plan.Steps, PlanStep{
+				Description: fmt.Sprintf("Reset storage parameters %s of table %s", strings.Join(tableDiff.ResetStorageParameters, ", "), tableDiff.TableName),
+				SQL:         []string{fmt.Sprintf("ALTER TABLE %s RESET (%s)", quoteName(driver, tableDiff.TableName), strings.Join(tableDiff.ResetStorageParameters, ", "))},
+				Operation:   storageParametersOperation(tableDiff.TableName, nil, tableDiff.ResetStorageParameters),
+				Source:      tableDiff.Source,
 			})
 		}
 
 		// Remove old columns
 		for _, col := range tableDiff.RemovedColumns {
+			if opts.SoftDropColumns {
+				step, tombstone := softDropColumnStep(driver, tableDiff.TableName, col, now)
+				step.Source = tableDiff.Source
+				plan.Steps = append(plan.Steps, step)
+				plan.Tombstones = append(plan.Tombstones, tombstone)
+				continue
+			}
 			sql, desc := driver.DropColumn(tableDiff.TableName, col)
 			plan.Steps = append(plan.Steps, PlanStep{
 				Description: desc,
 				SQL:         []string{sql},
+				Operation:   &Operation{Kind: OperationDropColumn, Table: tableDiff.TableName, Column: col.Name},
+				Source:      tableDiff.Source,
 			})
 		}
 	}
+	if err := checkBackfills(opts.Backfill, addedColumns); err != nil {
+		return nil, err
+	}
+
+	// Set sequence owners after the owning columns are added
+	if sequences {
+		for _, seq := range diff.AddedSequences {
+			if schema.NormalizeSequenceOwner(seq.OwnedBy) != "" {
+				plan.Steps = append(plan.Steps, sequenceOwnerStep(driver, seq.QualifiedName(), seq.OwnedBy))
+			}
+		}
+		for _, seqDiff := range diff.ModifiedSequences {
+			if slices.Contains(seqDiff.Changes, "owned_by") {
+				plan.Steps = append(plan.Steps, sequenceOwnerStep(driver, seqDiff.SequenceName, seqDiff.New.OwnedBy))
+			}
+		}
+	}
 
 	// Step 7: Remove old tables
-	for _, table := range diff.RemovedTables {
+	// Foreign keys that reference a dropped table are removed explicitly first so the
+	// DROP TABLE never relies on CASCADE, unless the caller opted into CASCADE.
+	cascade := opts.Cascade && driver.SupportsFeature("CASCADE")
+	removedTables := orderTablesForDrop(diff.RemovedTables)
+	if !cascade {
+		plan.Steps = append(plan.Steps, dependentForeignKeySteps(diff, removedTables, sourceSchema, driver)...)
+	}
+	for _, table := range removedTables {
 		sql, desc := driver.DropTable(table)
+		if cascade {
+			sql += " CASCADE"
+			desc += " (CASCADE)"
+		}
 		plan.Steps = append(plan.Steps, PlanStep{
 			Description: desc,
 			SQL:         []string{sql},
+			Operation:   &Operation{Kind: OperationDropTable, Table: table.QualifiedName()},
 		})
 	}
 
+	// Remove sequences after the tables whose defaults may use them
+	if sequences {
+		for _, seq := range diff.RemovedSequences {
+			if !droppedWithOwner(diff, seq) {
+				plan.Steps = append(plan.Steps, dropSequenceStep(driver, seq))
+			}
+		}
+	}
+
+	// Tag steps that may outlast a statement_timeout so the executor can lift it
+	for i := range plan.Steps {
+		plan.Steps[i].LongRunning = IsLongRunning(plan.Steps[i])
+	}
+
+	// Warn about steps that recreate a table, which resets its owner and grants
+	for i := range plan.Steps {
+		plan.Steps[i].Warnings = append(plan.Steps[i].Warnings, recreateWarnings(plan.Steps[i], sourceSchema)...)
+	}
+
 	return plan, nil
 }
+
+// dropForeignKeyStep generates the step that drops a foreign key from a table.
+// sourceTable is the table's definition at this point in the plan, if known.
+func dropForeignKeyStep(driver database.Driver, sourceTable *database.Table, tableName string, fk database.ForeignKey) PlanStep {
+	// For SQLite, dropping foreign keys requires table recreation
+	if driver.Name() == "sqlite" && !driver.SupportsFeature("ALTER_ADD_FOREIGN_KEY") {
+		if sqliteGen, ok := driver.(*sqlitedb.Driver); ok {
+			if sourceTable != nil {
+				// Use table recreation for SQLite (returns single atomic step)
+				step := sqliteGen.RecreateTableWithoutForeignKey(*sourceTable, fk.Name)
+				return PlanStep{
+					Description: step.Description,
+					SQL:         step.SQL,
+					Operation:   foreignKeyOperation(OperationDropForeignKey, tableName, fk),
+				}
+			}
+		}
+		// Fall back to the driver's statement if we can't recreate the table
+	}
+
+	// PostgreSQL and other databases can drop foreign keys directly
+	sql, desc := driver.DropForeignKey(tableName, fk)
+	return PlanStep{
+		Description: desc,
+		SQL:         []string{sql},
+		Operation:   foreignKeyOperation(OperationDropForeignKey, tableName, fk),
+	}
+}
+
+// ColumnOrderWarnings describes the enforced column order changes in diff that
+// the plan does not apply. Only SQLite rebuilds tables to reorder columns.
+func ColumnOrderWarnings(diff *schema.SchemaDiff, driver database.Driver) []string {
+	if driver.Name() == "sqlite" {
+		return nil
+	}
+	var warnings []string
+	for _, tableDiff := range diff.ModifiedTables {
+		if tableDiff.ColumnOrderChanged {
+			warnings = append(warnings, fmt.Sprintf("Column order of table %s differs from the schema (%s); %s can't reorder columns without recreating the table",
+				tableDiff.TableName, strings.Join(tableDiff.ColumnOrder, ", "), driver.Name()))
+		}
+	}
+	return warnings
+}
+
+// findTable returns the table of a schema with the given qualified name, or nil
+func findTable(s *database.Schema, name string) *database.Table {
+	return database.FindTable(s, name)
+}
+
+// rebuildTable returns the definition a table rebuild starts from: the source
+// table plus the columns the plan added earlier, in the desired column order.
+// Columns the plan drops later keep their place after the desired ones.
+func rebuildTable(sourceSchema *database.Schema, tableDiff *schema.TableDiff) *database.Table {
+	source := findTable(sourceSchema, tableDiff.TableName)
+	if source == nil {
+		return nil
+	}
+
+	available := make(map[string]database.Column, len(source.Columns)+len(tableDiff.AddedColumns))
+	for _, col := range source.Columns {
+		available[col.Name] = col
+	}
+	for _, col := range tableDiff.AddedColumns {
+		available[col.Name] = col
+	}
+
+	columns := make([]database.Column, 0, len(available))
+	placed := make(map[string]bool, len(available))
+	for _, name := range tableDiff.ColumnOrder {
+		if col, ok := available[name]; ok && !placed[name] {
+			columns = append(columns, col)
+			placed[name] = true
+		}
+	}
+	for _, col := range source.Columns {
+		if !placed[col.Name] {
+			columns = append(columns, col)
+			placed[col.Name] = true
+		}
+	}
+
+	table := *source
+	table.Columns = columns
+	return &table
+}
+
+// orderTablesForDrop orders removed tables so that a table is dropped before
+// any other removed table it references. Tables in a reference cycle keep
+// their original order; the foreign keys between them are dropped explicitly.
+func orderTablesForDrop(tables []database.Table) []database.Table {
+	pending := append([]database.Table(nil), tables...)
+	ordered := make([]database.Table, 0, len(tables))
+
+	for len(pending) > 0 {
+		next := 0
+		for i, table := range pending {
+			if !referencedByOthers(table.QualifiedName(), pending) {
+				next = i
+				break
+			}
+		}
+		ordered = append(ordered, pending[next])
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+	return ordered
+}
+
+// referencedByOthers reports whether any other table has a foreign key to tableName
+func referencedByOthers(tableName string, tables []database.Table) bool {
+	for _, table := range tables {
+		if table.QualifiedName() == tableName {
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			if fk.ReferencedTable == tableName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dependentForeignKeySteps drops the foreign keys that still reference a table
+// being removed. Foreign keys the diff already removes, and foreign keys on
+// removed tables that are dropped before the table they reference, are skipped.
+func dependentForeignKeySteps(diff *schema.SchemaDiff, removedTables []database.Table, sourceSchema *database.Schema, driver database.Driver) []PlanStep {
+	dropOrder := make(map[string]int, len(removedTables))
+	for i, table := range removedTables {
+		dropOrder[table.QualifiedName()] = i
+	}
+
+	alreadyDropped := make(map[string]bool)
+	for _, tableDiff := range diff.ModifiedTables {
+		for _, fk := range tableDiff.RemovedForeignKeys {
+			alreadyDropped[tableDiff.TableName+"."+fk.Name] = true
+		}
+	}
+
+	var steps []PlanStep
+	for _, table := range removedTables {
+		for _, dep := range schema.FindTableDependents(sourceSchema, table.QualifiedName()) {
+			if alreadyDropped[dep.Table+"."+dep.ForeignKey.Name] {
+				continue
+			}
+			if i, removed := dropOrder[dep.Table]; removed && i < dropOrder[table.QualifiedName()] {
+				continue
+			}
+			alreadyDropped[dep.Table+"."+dep.ForeignKey.Name] = true
+			steps = append(steps, dropForeignKeyStep(driver, findTable(sourceSchema, dep.Table), dep.Table, dep.ForeignKey))
+		}
+	}
+	return steps
+}
+
+// alterColumnOperation describes a column modification
+func alterColumnOperation(tableName string, colDiff schema.ColumnDiff) *Operation {
+	op := &Operation{
+		Kind:    OperationAlterColumn,
+		Table:   tableName,
+		Column:  colDiff.ColumnName,
+		Details: map[string]string{"changes": strings.Join(colDiff.Changes, ",")},
+	}
+	for _, change := range colDiff.Changes {
+		if change == "type" {
+			op.Details["old_type"] = colDiff.Old.Type
+			op.Details["new_type"] = colDiff.New.Type
+		}
+	}
+	return op
+}
+
+// idempotentGenerator is implemented by drivers that can guard foreign key
+// and index additions so a partially applied plan can be re-run
+type idempotentGenerator interface {
+	AddForeignKeyIfNotExists(tableName string, fk database.ForeignKey) (string, string)
+	AddIndexIfNotExists(tableName string, idx database.Index) (string, string)
+}
+
+// addForeignKeySQL returns the SQL adding fk, guarded when idempotent is set and the driver supports it
+func addForeignKeySQL(driver database.Driver, tableName string, fk database.ForeignKey, idempotent bool) (string, string) {
+	if gen, ok := driver.(idempotentGenerator); ok && idempotent {
+		return gen.AddForeignKeyIfNotExists(tableName, fk)
+	}
+	return driver.AddForeignKey(tableName, fk)
+}
+
+// addIndexSQL returns the SQL creating idx, guarded when idempotent is set and the driver supports it
+func addIndexSQL(driver database.Driver, tableName string, idx database.Index, idempotent bool) (string, string) {
+	if gen, ok := driver.(idempotentGenerator); ok && idempotent {
+		return gen.AddIndexIfNotExists(tableName, idx)
+	}
+	return driver.AddIndex(tableName, idx)
+}
+
+// foreignKeyOperation describes adding or dropping a foreign key
+func foreignKeyOperation(kind, tableName string, fk database.ForeignKey) *Operation {
+	return &Operation{
+		Kind:  kind,
+		Table: tableName,
+		Details: map[string]string{
+			"name":             fk.Name,
+			"referenced_table": fk.ReferencedTable,
+		},
+	}
+}
+
+// indexOperation describes adding or dropping an index
+func indexOperation(kind, tableName string, idx database.Index) *Operation {
+	return &Operation{
+		Kind:    kind,
+		Table:   tableName,
+		Details: map[string]string{"name": idx.Name},
+	}
+}
+
+// renameOperation describes renaming an index or foreign key
+func renameOperation(kind, tableName, oldName, newName string) *Operation {
+	return &Operation{
+		Kind:    kind,
+		Table:   tableName,
+		Details: map[string]string{"old_name": oldName, "name": newName},
+	}
+}
+
+// rlsOperation describes enabling or disabling row level security
+func rlsOperation(tableName string, enabled bool) *Operation {
+	if enabled {
+		return &Operation{Kind: OperationEnableRLS, Table: tableName}
+	}
+	return &Operation{Kind: OperationDisableRLS, Table: tableName}
+}
+
+// tablespaceOperation describes moving a table, or one of its indexes, to a tablespace
+func tablespaceOperation(tableName, indexName, tablespace string) *Operation {
+	op := &Operation{
+		Kind:    OperationSetTablespace,
+		Table:   tableName,
+		Details: map[string]string{"tablespace": tablespace},
+	}
+	if indexName != "" {
+		op.Details["index"] = indexName
+	}
+	return op
+}
+
+// storageParametersOperation describes setting or resetting a table's storage parameters
+func storageParametersOperation(tableName string, set map[string]string, reset []string) *Operation {
+	op := &Operation{
+		Kind:    OperationSetStorageParameters,
+		Table:   tableName,
+		Details: map[string]string{},
+	}
+	for name, value := range set {
+		op.Details[name] = value
+	}
+	if len(reset) > 0 {
+		op.Details["reset"] = strings.Join(reset, ",")
+	}
+	return op
+}
+
+// missingSchemas returns the schemas of the added sequences and tables that
+// no table or sequence of the source has, in order of first use. The default
+// schema always exists.
+func missingSchemas(diff *schema.SchemaDiff, sourceSchema *database.Schema) []string {
+	existing := map[string]bool{"": true, database.DefaultSchema: true}
+	if sourceSchema != nil {
+		for _, table := range sourceSchema.Tables {
+			existing[table.Schema] = true
+		}
+		for _, seq := range sourceSchema.Sequences {
+			existing[seq.Schema] = true
+		}
+	}
+	for _, table := range diff.RemovedTables {
+		existing[table.Schema] = true
+	}
+	for _, tableDiff := range diff.ModifiedTables {
+		schemaName, _ := database.SplitQualifiedName(tableDiff.TableName)
+		existing[schemaName] = true
+	}
+
+	var missing []string
+	for _, seq := range diff.AddedSequences {
+		if !existing[seq.Schema] {
+			existing[seq.Schema] = true
+			missing = append(missing, seq.Schema)
+		}
+	}
+	for _, table := range diff.AddedTables {
+		if !existing[table.Schema] {
+			existing[table.Schema] = true
+			missing = append(missing, table.Schema)
+		}
+	}
+	return missing
+}
+
+// createSchemaStep creates a schema unless it exists. The source may have the
+// schema without any tables in it, so the step is idempotent.
+func createSchemaStep(driver database.Driver, schemaName string) PlanStep {
+	return PlanStep{
+		Description: fmt.Sprintf("Create schema %s", schemaName),
+		SQL:         []string{fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", driver.QuoteIdentifier(schemaName))},
+		Operation: &Operation{
+			Kind:    OperationCreateSchema,
+			Details: map[string]string{"schema": schemaName},
+		},
+	}
+}
+
+// replicaIdentityStep sets a table's REPLICA IDENTITY
+func replicaIdentityStep(driver database.Driver, tableName string, replicaIdentity *string) PlanStep {
+	identity := replicaIdentityOrDefault(replicaIdentity)
+	return PlanStep{
+		Description: fmt.Sprintf("Set replica identity of table %s to %s", tableName, identity),
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY %s", quoteName(driver, tableName), replicaIdentitySQL(driver, identity))},
+		Operation: &Operation{
+			Kind:    OperationSetReplicaIdentity,
+			Table:   tableName,
+			Details: map[string]string{"replica_identity": identity},
+		},
+	}
+}
+
+// columnStorageStep sets a column's storage mode. DEFAULT restores the
+// type's default storage and requires PostgreSQL 16.
+func columnStorageStep(driver database.Driver, tableName string, col database.Column) PlanStep {
+	storage := schema.ColumnStorage(col)
+	if storage == "" {
+		storage = "DEFAULT"
+	}
+	return PlanStep{
+		Description: fmt.Sprintf("Set storage of %s.%s to %s", tableName, col.Name, storage),
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STORAGE %s", quoteName(driver, tableName), driver.QuoteIdentifier(col.Name), storage)},
+		Operation: &Operation{
+			Kind:    OperationAlterColumn,
+			Table:   tableName,
+			Column:  col.Name,
+			Details: map[string]string{"changes": "storage", "storage": storage},
+		},
+		Source: col.Source,
+	}
+}
+
+// columnStatisticsStep sets a column's statistics target. -1 restores
+// default_statistics_target.
+func columnStatisticsStep(driver database.Driver, tableName string, col database.Column) PlanStep {
+	target := columnStatisticsTarget(col)
+	desc := fmt.Sprintf("Set statistics target of %s.%s to %d", tableName, col.Name, target)
+	if col.StatisticsTarget == nil {
+		desc = fmt.Sprintf("Reset statistics target of %s.%s to the default", tableName, col.Name)
+	}
+	return PlanStep{
+		Description: desc,
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STATISTICS %d", quoteName(driver, tableName), driver.QuoteIdentifier(col.Name), target)},
+		Operation: &Operation{
+			Kind:    OperationAlterColumn,
+			Table:   tableName,
+			Column:  col.Name,
+			Details: map[string]string{"changes": "statistics", "statistics_target": strconv.Itoa(target)},
+		},
+		Source: col.Source,
+	}
+}
+
+// quoteName quotes a table, index or sequence name for driver, each part on
+// its own when it is schema-qualified
+func quoteName(driver database.Driver, name string) string {
+	return database.QuoteQualifiedName(name, driver.QuoteIdentifier)
+}
+
+// replicaIdentitySQL returns a replica identity as written after REPLICA
+// IDENTITY, with the index of USING INDEX quoted
+func replicaIdentitySQL(driver database.Driver, identity string) string {
+	if index, ok := strings.CutPrefix(identity, "USING INDEX "); ok {
+		return "USING INDEX " + driver.QuoteIdentifier(index)
+	}
+	return identity
+}
+
+// columnStatisticsTarget returns the column's statistics target, or -1 for the default
+func columnStatisticsTarget(col database.Column) int {
+	if col.StatisticsTarget == nil {
+		return -1
+	}
+	return *col.StatisticsTarget
+}
+
+// replicaIdentityOrDefault returns the REPLICA IDENTITY clause, using DEFAULT when none is set
+func replicaIdentityOrDefault(replicaIdentity *string) string {
+	if identity := schema.NormalizeReplicaIdentity(replicaIdentity); identity != "" {
+		return identity
+	}
+	return database.ReplicaIdentityDefault
+}
@@ -1,13 +1,18 @@
 package planner
 
 import (
+	"database/sql"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/database/postgres"
 	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/parser"
 	"github.com/lockplane/lockplane/internal/schema"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	_ "modernc.org/sqlite"
 )
 
 func TestGeneratePlan_AddTable(t *testing.T) {
@@ -103,8 +108,114 @@ func TestGeneratePlan_DropTable(t *testing.T) {
 	}
 
 	step := plan.Steps[0]
-	if len(step.SQL) == 0 || step.SQL[0] != "DROP TABLE old_table CASCADE" {
-		t.Errorf("Expected 'DROP TABLE old_table CASCADE', got: %v", step.SQL)
+	if len(step.SQL) == 0 || step.SQL[0] != "DROP TABLE old_table" {
+		t.Errorf("Expected 'DROP TABLE old_table', got: %v", step.SQL)
+	}
+}
+
+func dropTableWithDependentsFixture() (*schema.SchemaDiff, *database.Schema) {
+	users := database.Table{
+		Name:    "users",
+		Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}},
+	}
+	posts := database.Table{
+		Name: "posts",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "author_id", Type: "integer"},
+		},
+		ForeignKeys: []database.ForeignKey{
+			{Name: "fk_posts_author", Columns: []string{"author_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+		},
+	}
+	before := &database.Schema{Tables: []database.Table{users, posts}}
+	diff := &schema.SchemaDiff{RemovedTables: []database.Table{users}}
+	return diff, before
+}
+
+func TestGeneratePlan_DropTableDropsDependentForeignKeysFirst(t *testing.T) {
+	diff, before := dropTableWithDependentsFixture()
+
+	plan, err := GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d: %+v", len(plan.Steps), plan.Steps)
+	}
+	if plan.Steps[0].SQL[0] != "ALTER TABLE posts DROP CONSTRAINT fk_posts_author" {
+		t.Errorf("Expected dependent foreign key to be dropped first, got: %v", plan.Steps[0].SQL)
+	}
+	if plan.Steps[0].Operation == nil || plan.Steps[0].Operation.Kind != OperationDropForeignKey {
+		t.Errorf("Expected drop_foreign_key operation, got: %+v", plan.Steps[0].Operation)
+	}
+	if plan.Steps[1].SQL[0] != "DROP TABLE users" {
+		t.Errorf("Expected 'DROP TABLE users' without CASCADE, got: %v", plan.Steps[1].SQL)
+	}
+}
+
+func TestGeneratePlan_DropTableSkipsForeignKeysAlreadyRemoved(t *testing.T) {
+	diff, before := dropTableWithDependentsFixture()
+	diff.ModifiedTables = []schema.TableDiff{
+		{TableName: "posts", RemovedForeignKeys: before.Tables[1].ForeignKeys},
+	}
+
+	plan, err := GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	dropCount := 0
+	for _, step := range plan.Steps {
+		if strings.Contains(step.SQL[0], "DROP CONSTRAINT fk_posts_author") {
+			dropCount++
+		}
+	}
+	if dropCount != 1 {
+		t.Errorf("Expected foreign key to be dropped exactly once, got %d", dropCount)
+	}
+}
+
+func TestGeneratePlan_DropTableCascade(t *testing.T) {
+	diff, before := dropTableWithDependentsFixture()
+
+	plan, err := GeneratePlanWithOptions(diff, before, postgres.NewDriver(), PlanOptions{Cascade: true})
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	if len(plan.Steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d: %+v", len(plan.Steps), plan.Steps)
+	}
+	if plan.Steps[0].SQL[0] != "DROP TABLE users CASCADE" {
+		t.Errorf("Expected 'DROP TABLE users CASCADE', got: %v", plan.Steps[0].SQL)
+	}
+
+	// SQLite has no CASCADE, so the dependent foreign key is still dropped explicitly
+	plan, err = GeneratePlanWithOptions(diff, before, sqlite.NewDriver(), PlanOptions{Cascade: true})
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 2 || strings.Contains(plan.Steps[1].SQL[0], "CASCADE") {
+		t.Errorf("Expected explicit foreign key drop and plain DROP TABLE on SQLite, got: %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_DropRelatedTablesInDependencyOrder(t *testing.T) {
+	diff, before := dropTableWithDependentsFixture()
+	diff.RemovedTables = before.Tables // users listed before posts
+
+	plan, err := GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d: %+v", len(plan.Steps), plan.Steps)
+	}
+	if plan.Steps[0].SQL[0] != "DROP TABLE posts" || plan.Steps[1].SQL[0] != "DROP TABLE users" {
+		t.Errorf("Expected posts to be dropped before users, got: %v, %v", plan.Steps[0].SQL, plan.Steps[1].SQL)
 	}
 }
 
@@ -140,6 +251,46 @@ func TestGeneratePlan_AddColumn(t *testing.T) {
 	}
 }
 
+func TestGeneratePlan_StepSourceLocations(t *testing.T) {
+	tableLoc := &database.SourceLocation{File: "schema/users.lp.sql", Line: 1, Column: 14}
+	columnLoc := &database.SourceLocation{File: "schema/users.lp.sql", Line: 4, Column: 3}
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{Name: "posts", Columns: []database.Column{{Name: "id", Type: "bigint"}}, Source: tableLoc},
+		},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:      "users",
+				AddedColumns:   []database.Column{{Name: "age", Type: "integer", Nullable: true, Source: columnLoc}},
+				RemovedColumns: []database.Column{{Name: "legacy", Type: "text", Nullable: true}},
+				Source:         tableLoc,
+			},
+		},
+		RemovedTables: []database.Table{{Name: "old_posts", Columns: []database.Column{{Name: "id", Type: "bigint"}}}},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	want := map[string]*database.SourceLocation{
+		OperationCreateTable: tableLoc,
+		OperationAddColumn:   columnLoc,
+		OperationDropColumn:  tableLoc,
+		OperationDropTable:   nil,
+	}
+	for _, step := range plan.Steps {
+		loc, ok := want[step.Operation.Kind]
+		if !ok {
+			continue
+		}
+		if step.Source != loc {
+			t.Errorf("%s step: expected source %v, got %v", step.Operation.Kind, loc, step.Source)
+		}
+	}
+}
+
 func TestGeneratePlan_DropColumn(t *testing.T) {
 	diff := &schema.SchemaDiff{
 		ModifiedTables: []schema.TableDiff{
@@ -201,6 +352,42 @@ func TestGeneratePlan_ModifyColumn_Type(t *testing.T) {
 	}
 }
 
+func TestGeneratePlan_TagsLongRunningSteps(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:    "users",
+				AddedColumns: []database.Column{{Name: "nickname", Type: "text", Nullable: true}},
+				ModifiedColumns: []schema.ColumnDiff{
+					{
+						ColumnName: "age",
+						Old:        database.Column{Name: "age", Type: "integer", Nullable: true},
+						New:        database.Column{Name: "age", Type: "bigint", Nullable: true},
+						Changes:    []string{"type"},
+					},
+				},
+				AddedIndexes: []database.Index{{Name: "idx_users_nickname", Columns: []string{"nickname"}}},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	want := map[string]bool{
+		OperationAddColumn:   false,
+		OperationAlterColumn: true,
+		OperationAddIndex:    true,
+	}
+	for _, step := range plan.Steps {
+		if step.LongRunning != want[step.Operation.Kind] {
+			t.Errorf("%s step: expected LongRunning=%v, got %v", step.Operation.Kind, want[step.Operation.Kind], step.LongRunning)
+		}
+	}
+}
+
 func TestGeneratePlan_RLSEnable(t *testing.T) {
 	diff := &schema.SchemaDiff{
 		ModifiedTables: []schema.TableDiff{
@@ -265,6 +452,247 @@ func TestGeneratePlan_RLSDisable(t *testing.T) {
 	}
 }
 
+func TestGeneratePlan_ReplicaIdentity(t *testing.T) {
+	identity := database.ReplicaIdentityUsingIndex("orders_external_id_key")
+	oldKey := database.Index{Name: "orders_id_key", Columns: []string{"id"}, Unique: true}
+	newKey := database.Index{Name: "orders_external_id_key", Columns: []string{"external_id"}, Unique: true}
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:              "orders",
+				AddedIndexes:           []database.Index{newKey},
+				RemovedIndexes:         []database.Index{oldKey},
+				ReplicaIdentityChanged: true,
+				ReplicaIdentity:        &identity,
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	// The identity moves to the new index before the old one is dropped
+	expected := []string{
+		"CREATE UNIQUE INDEX orders_external_id_key ON orders (external_id)",
+		"ALTER TABLE orders REPLICA IDENTITY USING INDEX orders_external_id_key",
+		"DROP INDEX orders_id_key",
+	}
+	if len(plan.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), plan.Steps)
+	}
+	for i, sql := range expected {
+		if plan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, plan.Steps[i].SQL[0])
+		}
+	}
+	if op := plan.Steps[1].Operation; op == nil || op.Kind != OperationSetReplicaIdentity {
+		t.Errorf("Expected set_replica_identity operation, got %+v", op)
+	}
+
+	// SQLite has no replica identity
+	plan, err = GeneratePlan(&schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{{TableName: "orders", ReplicaIdentityChanged: true, ReplicaIdentity: &identity}},
+	}, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("Expected no SQLite steps, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_StorageParameters(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{
+				Name:              "events",
+				Columns:           []database.Column{{Name: "id", Type: "bigint"}},
+				StorageParameters: map[string]string{"fillfactor": "90"},
+			},
+		},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:              "orders",
+				SetStorageParameters:   map[string]string{"fillfactor": "70", "autovacuum_vacuum_scale_factor": "0.01"},
+				ResetStorageParameters: []string{"autovacuum_enabled"},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	expected := []string{
+		"CREATE TABLE events (\n  id bigint NOT NULL\n) WITH (fillfactor = 90)",
+		"ALTER TABLE orders SET (autovacuum_vacuum_scale_factor = 0.01, fillfactor = 70)",
+		"ALTER TABLE orders RESET (autovacuum_enabled)",
+	}
+	if len(plan.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), plan.Steps)
+	}
+	for i, sql := range expected {
+		if plan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, plan.Steps[i].SQL[0])
+		}
+	}
+	if op := plan.Steps[1].Operation; op == nil || op.Kind != OperationSetStorageParameters || op.Details["fillfactor"] != "70" {
+		t.Errorf("Expected set_storage_parameters operation, got %+v", op)
+	}
+
+	// SQLite has no storage parameters
+	plan, err = GeneratePlan(&schema.SchemaDiff{ModifiedTables: diff.ModifiedTables}, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("Expected no SQLite steps, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_ColumnStorage(t *testing.T) {
+	external := "EXTERNAL"
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{
+				Name: "documents",
+				Columns: []database.Column{
+					{Name: "id", Type: "bigint"},
+					{Name: "body", Type: "text", Nullable: true, Storage: &external},
+				},
+			},
+		},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:    "users",
+				AddedColumns: []database.Column{{Name: "bio", Type: "text", Nullable: true, Storage: &external}},
+				ModifiedColumns: []schema.ColumnDiff{
+					{
+						ColumnName: "avatar",
+						Old:        database.Column{Name: "avatar", Type: "bytea", Nullable: true, Storage: &external},
+						New:        database.Column{Name: "avatar", Type: "bytea", Nullable: false},
+						Changes:    []string{"nullable", "storage"},
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	expected := []string{
+		"CREATE TABLE documents (\n  id bigint NOT NULL,\n  body text\n)",
+		"ALTER TABLE documents ALTER COLUMN body SET STORAGE EXTERNAL",
+		"ALTER TABLE users ADD COLUMN bio text",
+		"ALTER TABLE users ALTER COLUMN bio SET STORAGE EXTERNAL",
+		"ALTER TABLE users ALTER COLUMN avatar SET NOT NULL",
+		"ALTER TABLE users ALTER COLUMN avatar SET STORAGE DEFAULT",
+	}
+	if len(plan.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), plan.Steps)
+	}
+	for i, sql := range expected {
+		if plan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, plan.Steps[i].SQL[0])
+		}
+	}
+
+	// SQLite has no storage modes
+	plan, err = GeneratePlan(&schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{{
+			TableName: "users",
+			ModifiedColumns: []schema.ColumnDiff{{
+				ColumnName: "avatar",
+				Old:        database.Column{Name: "avatar", Type: "blob", Storage: &external},
+				New:        database.Column{Name: "avatar", Type: "blob"},
+				Changes:    []string{"storage"},
+			}},
+		}},
+	}, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("Expected no SQLite steps, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_ColumnStatistics(t *testing.T) {
+	target := func(n int) *int { return &n }
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{
+				Name: "events",
+				Columns: []database.Column{
+					{Name: "id", Type: "bigint"},
+					{Name: "properties", Type: "jsonb", Nullable: true, StatisticsTarget: target(1000)},
+				},
+			},
+		},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:    "users",
+				AddedColumns: []database.Column{{Name: "country", Type: "text", Nullable: true, StatisticsTarget: target(500)}},
+				ModifiedColumns: []schema.ColumnDiff{
+					{
+						ColumnName: "email",
+						Old:        database.Column{Name: "email", Type: "text", Nullable: true, StatisticsTarget: target(200)},
+						New:        database.Column{Name: "email", Type: "text", Nullable: false},
+						Changes:    []string{"nullable", "statistics"},
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	expected := []string{
+		"CREATE TABLE events (\n  id bigint NOT NULL,\n  properties jsonb\n)",
+		"ALTER TABLE events ALTER COLUMN properties SET STATISTICS 1000",
+		"ALTER TABLE users ADD COLUMN country text",
+		"ALTER TABLE users ALTER COLUMN country SET STATISTICS 500",
+		"ALTER TABLE users ALTER COLUMN email SET NOT NULL",
+		"ALTER TABLE users ALTER COLUMN email SET STATISTICS -1",
+	}
+	if len(plan.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), plan.Steps)
+	}
+	for i, sql := range expected {
+		if plan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, plan.Steps[i].SQL[0])
+		}
+	}
+
+	// SQLite has no statistics targets
+	plan, err = GeneratePlan(&schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{{
+			TableName: "users",
+			ModifiedColumns: []schema.ColumnDiff{{
+				ColumnName: "email",
+				Old:        database.Column{Name: "email", Type: "text", StatisticsTarget: target(200)},
+				New:        database.Column{Name: "email", Type: "text"},
+				Changes:    []string{"statistics"},
+			}},
+		}},
+	}, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("Expected no SQLite steps, got %+v", plan.Steps)
+	}
+}
+
 func TestGeneratePlan_ModifyColumn_Nullable(t *testing.T) {
 	// Test setting NOT NULL
 	diff := &schema.SchemaDiff{
@@ -583,3 +1011,590 @@ func TestGeneratePlan_PostgreSQLvsSQLite(t *testing.T) {
 		}
 	})
 }
+
+func TestGeneratePlan_PopulatesOperation(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{
+				Name:    "posts",
+				Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}},
+				Indexes: []database.Index{{Name: "idx_posts_id", Columns: []string{"id"}}},
+			},
+		},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:    "users",
+				AddedColumns: []database.Column{{Name: "bio", Type: "text", Nullable: true}},
+				ModifiedColumns: []schema.ColumnDiff{
+					{
+						ColumnName: "age",
+						Old:        database.Column{Name: "age", Type: "integer", Nullable: true},
+						New:        database.Column{Name: "age", Type: "bigint", Nullable: true},
+						Changes:    []string{"type"},
+					},
+				},
+				RemovedColumns: []database.Column{{Name: "legacy", Type: "text"}},
+			},
+		},
+		RemovedTables: []database.Table{{Name: "old_logs"}},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	expected := []Operation{
+		{Kind: OperationCreateTable, Table: "posts"},
+		{Kind: OperationAddIndex, Table: "posts"},
+		{Kind: OperationAddColumn, Table: "users", Column: "bio"},
+		{Kind: OperationAlterColumn, Table: "users", Column: "age"},
+		{Kind: OperationDropColumn, Table: "users", Column: "legacy"},
+		{Kind: OperationDropTable, Table: "old_logs"},
+	}
+
+	if len(plan.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %d", len(expected), len(plan.Steps))
+	}
+
+	for i, want := range expected {
+		op := plan.Steps[i].Operation
+		if op == nil {
+			t.Fatalf("Step %d (%s) has no operation", i, plan.Steps[i].Description)
+		}
+		if op.Kind != want.Kind || op.Table != want.Table || op.Column != want.Column {
+			t.Errorf("Step %d: expected %+v, got %+v", i, want, *op)
+		}
+	}
+
+	if name := plan.Steps[1].Operation.Details["name"]; name != "idx_posts_id" {
+		t.Errorf("Expected index name in details, got %q", name)
+	}
+	alter := plan.Steps[3].Operation.Details
+	if alter["old_type"] != "integer" || alter["new_type"] != "bigint" {
+		t.Errorf("Expected old/new type in details, got %v", alter)
+	}
+}
+
+func TestGeneratePlan_DropUniqueConstraint(t *testing.T) {
+	before, err := parser.ParseSQLSchema("CREATE TABLE users (id integer, email text, CONSTRAINT users_email_key UNIQUE (email));")
+	if err != nil {
+		t.Fatalf("Failed to parse before schema: %v", err)
+	}
+	after, err := parser.ParseSQLSchema("CREATE TABLE users (id integer, email text);")
+	if err != nil {
+		t.Fatalf("Failed to parse after schema: %v", err)
+	}
+
+	driver := postgres.NewDriver()
+	plan, err := GeneratePlanWithHash(schema.DiffSchemas(before, after), before, driver)
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	// The constraint's index goes with it, so it is dropped once
+	if len(plan.Steps) != 1 {
+		t.Fatalf("Expected a single drop step, got %+v", plan.Steps)
+	}
+	if want := []string{"ALTER TABLE users DROP CONSTRAINT users_email_key"}; !reflect.DeepEqual(plan.Steps[0].SQL, want) {
+		t.Errorf("Expected %q, got %q", want, plan.Steps[0].SQL)
+	}
+
+	rollback, err := GenerateRollback(plan, before, driver)
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	if len(rollback.Steps) != 1 || rollback.Steps[0].SQL[0] != "ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email)" {
+		t.Errorf("Expected rollback to add the constraint back, got %+v", rollback.Steps)
+	}
+}
+
+func TestGeneratePlan_RecreateIndexForNullsNotDistinct(t *testing.T) {
+	oldIdx := database.Index{Name: "users_email_key", Columns: []string{"email"}, Unique: true}
+	newIdx := oldIdx
+	newIdx.NullsNotDistinct = true
+
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "users",
+				RecreatedIndexes: []schema.IndexDiff{
+					{IndexName: "users_email_key", Old: oldIdx, New: newIdx, Changes: []string{"nulls_not_distinct"}},
+				},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected drop and create steps, got %+v", plan.Steps)
+	}
+	if plan.Steps[0].SQL[0] != "DROP INDEX users_email_key" {
+		t.Errorf("Expected DROP INDEX first, got %s", plan.Steps[0].SQL[0])
+	}
+	if plan.Steps[1].SQL[0] != "CREATE UNIQUE INDEX users_email_key ON users (email) NULLS NOT DISTINCT" {
+		t.Errorf("Expected CREATE UNIQUE INDEX ... NULLS NOT DISTINCT, got %s", plan.Steps[1].SQL[0])
+	}
+	if !strings.Contains(plan.Steps[0].Description, "nulls_not_distinct changed") {
+		t.Errorf("Expected description to explain the recreate, got %q", plan.Steps[0].Description)
+	}
+}
+
+func TestGeneratePlan_SetTablespace(t *testing.T) {
+	archive := "archive"
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:         "events",
+				TablespaceChanged: true,
+				Tablespace:        &archive,
+				MovedIndexes:      []database.Index{{Name: "idx_events_created_at", Columns: []string{"created_at"}}},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	var sql []string
+	for _, step := range plan.Steps {
+		sql = append(sql, step.SQL...)
+		if step.Operation == nil || step.Operation.Kind != OperationSetTablespace {
+			t.Errorf("Expected set_tablespace operation, got %+v", step.Operation)
+		}
+	}
+	expected := []string{
+		"ALTER INDEX idx_events_created_at SET TABLESPACE pg_default",
+		"ALTER TABLE events SET TABLESPACE archive",
+	}
+	if strings.Join(sql, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("Expected %v, got %v", expected, sql)
+	}
+
+	// SQLite has no tablespaces
+	plan, err = GeneratePlan(diff, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("Expected no steps for SQLite, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_SetTablespaceToDatabaseDefault(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		DefaultTablespace: "fast_ssd",
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:         "events",
+				TablespaceChanged: true,
+				MovedIndexes:      []database.Index{{Name: "idx_events_created_at", Columns: []string{"created_at"}}},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	var sql []string
+	for _, step := range plan.Steps {
+		sql = append(sql, step.SQL...)
+	}
+	expected := []string{
+		"ALTER INDEX idx_events_created_at SET TABLESPACE fast_ssd",
+		"ALTER TABLE events SET TABLESPACE fast_ssd",
+	}
+	if strings.Join(sql, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("Expected objects to move to the database default, got %v", sql)
+	}
+}
+
+func TestGeneratePlan_EnforcedColumnOrder(t *testing.T) {
+	before := &database.Schema{Tables: []database.Table{{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+			{Name: "email", Type: "TEXT"},
+			{Name: "legacy", Type: "TEXT"},
+		},
+		Indexes: []database.Index{{Name: "idx_users_email", Columns: []string{"email"}}},
+	}}}
+	after := &database.Schema{Tables: []database.Table{{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+			{Name: "name", Type: "TEXT", Nullable: true},
+			{Name: "email", Type: "TEXT"},
+		},
+		Indexes: []database.Index{{Name: "idx_users_email", Columns: []string{"email"}}},
+	}}}
+	diff := schema.DiffSchemasWithOptions(before, after, schema.DiffOptions{EnforceColumnOrder: true})
+
+	// SQLite rebuilds the table with the desired order, keeping the column
+	// that is dropped later and recreating the index
+	plan, err := GeneratePlanWithHash(diff, before, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	var reorder *PlanStep
+	for i := range plan.Steps {
+		if plan.Steps[i].Operation != nil && plan.Steps[i].Operation.Kind == OperationReorderColumns {
+			reorder = &plan.Steps[i]
+		}
+	}
+	if reorder == nil {
+		t.Fatalf("Expected a reorder step, got %v", plan.Steps)
+	}
+	if !strings.Contains(reorder.SQL[1], "INSERT INTO users_new (id, name, email, legacy)") {
+		t.Errorf("Expected rows copied in the desired order, got %s", reorder.SQL[1])
+	}
+	if last := reorder.SQL[len(reorder.SQL)-1]; !strings.Contains(last, "CREATE INDEX idx_users_email") {
+		t.Errorf("Expected index to be recreated, got %s", last)
+	}
+
+	// PostgreSQL can't reorder columns, so the change is only reported
+	plan, err = GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	for _, step := range plan.Steps {
+		if step.Operation != nil && step.Operation.Kind == OperationReorderColumns {
+			t.Errorf("Expected no reorder step for PostgreSQL, got %q", step.Description)
+		}
+	}
+	warnings := ColumnOrderWarnings(diff, postgres.NewDriver())
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "users") {
+		t.Errorf("Expected a column order warning for users, got %v", warnings)
+	}
+	if warnings := ColumnOrderWarnings(diff, sqlite.NewDriver()); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for SQLite, got %v", warnings)
+	}
+}
+
+func TestGeneratePlan_SQLiteForeignKeyRebuildKeepsAddedColumns(t *testing.T) {
+	before := &database.Schema{Tables: []database.Table{
+		{Name: "teams", Columns: []database.Column{{Name: "id", Type: "INTEGER", IsPrimaryKey: true}}},
+		{Name: "users", Columns: []database.Column{
+			{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+			{Name: "email", Type: "TEXT"},
+		}},
+	}}
+	after := &database.Schema{Tables: []database.Table{
+		{Name: "teams", Columns: []database.Column{{Name: "id", Type: "INTEGER", IsPrimaryKey: true}}},
+		{Name: "users",
+			Columns: []database.Column{
+				{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+				{Name: "team_id", Type: "INTEGER", Nullable: true},
+				{Name: "email", Type: "TEXT"},
+			},
+			ForeignKeys: []database.ForeignKey{{Name: "fk_users_team", Columns: []string{"team_id"}, ReferencedTable: "teams", ReferencedColumns: []string{"id"}}},
+		},
+	}}
+
+	plan, err := GeneratePlanWithHash(schema.DiffSchemas(before, after), before, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	var rebuild *PlanStep
+	for i := range plan.Steps {
+		if plan.Steps[i].Operation != nil && plan.Steps[i].Operation.Kind == OperationAddForeignKey {
+			rebuild = &plan.Steps[i]
+		}
+	}
+	if rebuild == nil {
+		t.Fatalf("Expected a foreign key rebuild step, got %v", plan.Steps)
+	}
+	if !strings.Contains(rebuild.SQL[1], "(id, team_id, email)") {
+		t.Errorf("Expected rebuild to keep the added column in the desired order, got %s", rebuild.SQL[1])
+	}
+}
+
+func TestGeneratePlan_Idempotent(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "posts",
+				AddedForeignKeys: []database.ForeignKey{
+					{Name: "fk_posts_user", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				},
+				AddedIndexes: []database.Index{{Name: "idx_posts_user", Columns: []string{"user_id"}}},
+			},
+		},
+	}
+
+	plan, err := GeneratePlanWithOptions(diff, nil, postgres.NewDriver(), PlanOptions{Idempotent: true})
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %+v", plan.Steps)
+	}
+	for _, step := range plan.Steps {
+		if !strings.HasPrefix(step.SQL[0], "DO $lockplane$") {
+			t.Errorf("Expected a guarded step, got %q", step.SQL[0])
+		}
+	}
+	if op := plan.Steps[0].Operation; op == nil || op.Kind != OperationAddForeignKey {
+		t.Errorf("Expected add_foreign_key operation, got %+v", op)
+	}
+
+	// Guarded steps still roll back to plain drops
+	rollback, err := GenerateRollback(plan, &database.Schema{}, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	var rollbackSQL []string
+	for _, step := range rollback.Steps {
+		rollbackSQL = append(rollbackSQL, step.SQL...)
+	}
+	want := []string{"DROP INDEX idx_posts_user", "ALTER TABLE posts DROP CONSTRAINT fk_posts_user"}
+	if !reflect.DeepEqual(rollbackSQL, want) {
+		t.Errorf("Expected rollback %v, got %v", want, rollbackSQL)
+	}
+
+	// Drivers without guarded DDL ignore the option
+	plan, err = GeneratePlanWithOptions(&schema.SchemaDiff{ModifiedTables: []schema.TableDiff{
+		{TableName: "posts", AddedIndexes: diff.ModifiedTables[0].AddedIndexes},
+	}}, nil, sqlite.NewDriver(), PlanOptions{Idempotent: true})
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || strings.HasPrefix(plan.Steps[0].SQL[0], "DO") {
+		t.Errorf("Expected a plain SQLite index step, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_Renames(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "orders",
+				RenamedIndexes: []schema.IndexDiff{{
+					IndexName: "uq_orders_code",
+					Old:       database.Index{Name: "orders_code_key", Columns: []string{"code"}, Unique: true},
+					New:       database.Index{Name: "uq_orders_code", Columns: []string{"code"}, Unique: true},
+					Changes:   []string{"name"},
+				}},
+				RenamedForeignKeys: []schema.Rename{{OldName: "orders_user_id_fkey", NewName: "fk_orders_user"}},
+			},
+		},
+	}
+
+	plan, err := GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	expected := []string{
+		"ALTER INDEX orders_code_key RENAME TO uq_orders_code",
+		"ALTER TABLE orders RENAME CONSTRAINT orders_user_id_fkey TO fk_orders_user",
+	}
+	if len(plan.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), plan.Steps)
+	}
+	for i, sql := range expected {
+		if plan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, plan.Steps[i].SQL[0])
+		}
+	}
+	if op := plan.Steps[1].Operation; op == nil || op.Kind != OperationRenameForeignKey || op.Details["old_name"] != "orders_user_id_fkey" {
+		t.Errorf("Expected rename_foreign_key operation, got %+v", op)
+	}
+
+	rollback, err := GenerateRollback(plan, &database.Schema{}, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	var rollbackSQL []string
+	for _, step := range rollback.Steps {
+		rollbackSQL = append(rollbackSQL, step.SQL...)
+	}
+	want := []string{
+		"ALTER TABLE orders RENAME CONSTRAINT fk_orders_user TO orders_user_id_fkey",
+		"ALTER INDEX uq_orders_code RENAME TO orders_code_key",
+	}
+	if !reflect.DeepEqual(rollbackSQL, want) {
+		t.Errorf("Expected rollback %v, got %v", want, rollbackSQL)
+	}
+
+	// SQLite recreates renamed indexes and has no foreign key names to rename
+	plan, err = GeneratePlan(diff, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 2 || !strings.HasPrefix(plan.Steps[0].SQL[0], "DROP INDEX orders_code_key") ||
+		!strings.Contains(plan.Steps[1].SQL[0], "INDEX uq_orders_code") {
+		t.Errorf("Expected SQLite to recreate the index, got %+v", plan.Steps)
+	}
+}
+
+func TestGeneratePlan_MultipleSchemas(t *testing.T) {
+	source := &database.Schema{Tables: []database.Table{
+		{Name: "users", Schema: "public", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "users", Schema: "tenant_a", Columns: []database.Column{{Name: "id", Type: "integer"}},
+			Indexes: []database.Index{{Name: "users_old", Columns: []string{"id"}}}},
+	}}
+	target := &database.Schema{Tables: []database.Table{
+		{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "users", Schema: "tenant_a", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "invoices", Schema: "billing", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+	}}
+
+	diff := schema.DiffSchemas(source, target)
+	plan, err := GeneratePlanWithHash(diff, source, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	var sql []string
+	for _, step := range plan.Steps {
+		sql = append(sql, step.SQL...)
+	}
+	want := []string{
+		"CREATE SCHEMA IF NOT EXISTS billing",
+		"CREATE TABLE billing.invoices (\n  id integer NOT NULL\n)",
+		"DROP INDEX tenant_a.users_old",
+	}
+	if !reflect.DeepEqual(sql, want) {
+		t.Errorf("Unexpected plan SQL:\n got: %q\nwant: %q", sql, want)
+	}
+	if op := plan.Steps[0].Operation; op == nil || op.Kind != OperationCreateSchema || op.Details["schema"] != "billing" {
+		t.Errorf("Expected a create_schema operation, got %+v", op)
+	}
+
+	// SQLite has no schemas to create
+	plan, err = GeneratePlanWithHash(diff, source, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	for _, step := range plan.Steps {
+		if step.Operation != nil && step.Operation.Kind == OperationCreateSchema {
+			t.Errorf("Expected no CREATE SCHEMA step for SQLite, got %v", step.SQL)
+		}
+	}
+}
+
+// TestGeneratePlan_QuotesIdentifiers plans a reserved word and a mixed-case
+// name through every kind of step and its rollback. PostgreSQL statements
+// must parse and SQLite statements must run.
+func TestGeneratePlan_QuotesIdentifiers(t *testing.T) {
+	text, zero := "'none'", "0"
+	backfill := map[string]string{BackfillKey("UserAccounts", "Group"): "0"}
+	before := &database.Schema{Tables: []database.Table{{
+		Name: "UserAccounts",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "order", Type: "text", Nullable: true},
+			{Name: "Email", Type: "text", Nullable: true},
+		},
+		Indexes: []database.Index{{Name: "ByEmail", Columns: []string{"Email"}}},
+	}}}
+	// desired adds a NOT NULL column, backfilled on PostgreSQL, and on
+	// PostgreSQL also a DEFAULT and a foreign key, which SQLite would need a
+	// table rebuild for
+	desired := func(sqlite bool) *database.Schema {
+		accounts := database.Table{
+			Name: "UserAccounts",
+			Columns: []database.Column{
+				{Name: "id", Type: "integer", IsPrimaryKey: true},
+				{Name: "order", Type: "text", Nullable: true, Default: &text},
+				{Name: "Group", Type: "integer"},
+			},
+			Indexes:     []database.Index{{Name: "ByOrder", Columns: []string{"order"}, Unique: true}},
+			ForeignKeys: []database.ForeignKey{{Name: "AccountOrder", Columns: []string{"id"}, ReferencedTable: "order", ReferencedColumns: []string{"id"}}},
+		}
+		if sqlite {
+			accounts.Columns[1].Default = nil
+			accounts.Columns[2].Default = &zero
+			accounts.ForeignKeys = nil
+		}
+		return &database.Schema{Tables: []database.Table{accounts, {
+			Name: "order",
+			Columns: []database.Column{
+				{Name: "id", Type: "integer", IsPrimaryKey: true},
+				{Name: "UserId", Type: "integer", Nullable: true},
+			},
+			ForeignKeys: []database.ForeignKey{{Name: "OrderUser", Columns: []string{"UserId"}, ReferencedTable: "UserAccounts", ReferencedColumns: []string{"id"}}},
+		}}}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		driver database.Driver
+		opts   PlanOptions
+		check  func(t *testing.T, before *database.Schema, statements []string)
+	}{
+		{"postgres", postgres.NewDriver(), PlanOptions{SoftDropColumns: true, Backfill: backfill}, parsePostgresStatements},
+		{"postgres batched", postgres.NewDriver(), PlanOptions{Backfill: backfill, BackfillBatchSize: 100}, parsePostgresStatements},
+		{"sqlite", sqlite.NewDriver(), PlanOptions{}, execSQLiteStatements},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			after := desired(tt.driver.Name() == "sqlite")
+			plan, err := GeneratePlanWithOptions(schema.DiffSchemas(before, after), before, tt.driver, tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to generate plan: %v", err)
+			}
+			rollback, err := GenerateRollback(plan, before, tt.driver)
+			if err != nil {
+				t.Fatalf("Failed to generate rollback: %v", err)
+			}
+
+			var statements []string
+			for _, p := range []*Plan{plan, rollback} {
+				for _, step := range p.Steps {
+					statements = append(statements, step.SQL...)
+				}
+			}
+			tt.check(t, before, statements)
+		})
+	}
+}
+
+// parsePostgresStatements checks that statements parse and quote the
+// mixed-case name, which PostgreSQL would otherwise fold to lower case
+func parsePostgresStatements(t *testing.T, _ *database.Schema, statements []string) {
+	t.Helper()
+	for _, stmt := range statements {
+		if strings.Count(stmt, "UserAccounts") != strings.Count(stmt, `"UserAccounts"`) {
+			t.Errorf("Expected UserAccounts to be quoted in %q", stmt)
+		}
+		if _, err := pg_query.Parse(stmt); err != nil {
+			t.Errorf("Failed to parse %q: %v", stmt, err)
+		}
+	}
+}
+
+// execSQLiteStatements runs statements against an in-memory database holding
+// the before schema
+func execSQLiteStatements(t *testing.T, before *database.Schema, statements []string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	setup, err := GeneratePlanWithHash(schema.DiffSchemas(&database.Schema{}, before), &database.Schema{}, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate setup plan: %v", err)
+	}
+	var all []string
+	for _, step := range setup.Steps {
+		all = append(all, step.SQL...)
+	}
+	for _, stmt := range append(all, statements...) {
+		if strings.HasPrefix(strings.TrimSpace(stmt), "--") {
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			t.Errorf("Failed to run %q: %v", stmt, err)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package planner
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/internal/parser"
+	"github.com/lockplane/lockplane/internal/schema"
 )
 
 // GenerateRollback creates a rollback plan from a forward migration plan
@@ -36,43 +39,92 @@ func generateReverseOperation(step PlanStep, beforeSchema *database.Schema, driv
 	// For steps with multiple SQL statements, we check the first statement to determine the operation type
 	sqlStmt := step.SQL[0]
 
+	// A column reorder rebuilds the table, so its first statement is a CREATE
+	// TABLE. Later rollback steps find columns by name, so the original order
+	// is not restored.
+	if step.Operation != nil && step.Operation.Kind == OperationReorderColumns {
+		return nil, nil
+	}
+
+	// A schema is created only if missing, so it may have existed before the
+	// plan; rolling back its tables is enough and the schema is kept.
+	if step.Operation != nil && step.Operation.Kind == OperationCreateSchema {
+		return nil, nil
+	}
+
+	if step.Operation != nil {
+		switch step.Operation.Kind {
+		case OperationCreateSequence:
+			return generateReverseCreateSequence(step, driver)
+		case OperationAlterSequence:
+			return generateReverseAlterSequence(step, beforeSchema, driver)
+		case OperationDropSequence:
+			return generateReverseDropSequence(step, beforeSchema, driver)
+		case OperationBatchedUpdate:
+			// The rows' previous values aren't kept; a backfill is undone
+			// when rolling back drops the column it filled
+			return nil, nil
+		}
+	}
+
 	if parser.ContainsSQL(sqlStmt, "CREATE TABLE") {
-		return generateReverseCreateTable(step)
+		return generateReverseCreateTable(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DROP TABLE") {
 		return generateReverseDropTable(step, beforeSchema, driver)
 	} else if parser.ContainsSQL(sqlStmt, "ADD COLUMN") {
-		return generateReverseAddColumn(step)
+		return generateReverseAddColumn(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DROP COLUMN") {
 		return generateReverseDropColumn(step, beforeSchema, driver)
+	} else if parser.ContainsSQL(sqlStmt, "SET STORAGE") {
+		return generateReverseSetStorage(step, beforeSchema, driver)
+	} else if parser.ContainsSQL(sqlStmt, "SET STATISTICS") {
+		return generateReverseSetStatistics(step, beforeSchema, driver)
 	} else if parser.ContainsSQL(sqlStmt, "ALTER COLUMN") && parser.ContainsSQL(sqlStmt, "TYPE") {
-		return generateReverseAlterColumnType(step, beforeSchema)
+		return generateReverseAlterColumnType(step, beforeSchema, driver)
 	} else if parser.ContainsSQL(sqlStmt, "SET NOT NULL") {
-		return generateReverseSetNotNull(step)
+		return generateReverseSetNotNull(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DROP NOT NULL") {
-		return generateReverseDropNotNull(step)
+		return generateReverseDropNotNull(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "SET DEFAULT") {
-		return generateReverseSetDefault(step, beforeSchema)
+		return generateReverseSetDefault(step, beforeSchema, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DROP DEFAULT") {
-		return generateReverseDropDefault(step, beforeSchema)
+		return generateReverseDropDefault(step, beforeSchema, driver)
 	} else if parser.ContainsSQL(sqlStmt, "CREATE INDEX") || parser.ContainsSQL(sqlStmt, "CREATE UNIQUE INDEX") {
-		return generateReverseCreateIndex(step)
+		return generateReverseCreateIndex(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DROP INDEX") {
 		return generateReverseDropIndex(step, beforeSchema, driver)
+	} else if parser.ContainsSQL(sqlStmt, "RENAME CONSTRAINT") {
+		return generateReverseRenameConstraint(step, driver)
+	} else if parser.ContainsSQL(sqlStmt, "RENAME COLUMN") {
+		return generateReverseRenameColumn(step, driver)
+	} else if parser.ContainsSQL(sqlStmt, "ALTER INDEX") && parser.ContainsSQL(sqlStmt, "RENAME TO") {
+		return generateReverseRenameIndex(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "ADD CONSTRAINT") && parser.ContainsSQL(sqlStmt, "FOREIGN KEY") {
-		return generateReverseAddForeignKey(step)
+		return generateReverseAddForeignKey(step, driver)
+	} else if parser.ContainsSQL(sqlStmt, "ADD CONSTRAINT") && parser.ContainsSQL(sqlStmt, "UNIQUE") {
+		return generateReverseAddUniqueConstraint(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DROP CONSTRAINT") {
+		if step.Operation != nil && step.Operation.Kind == OperationDropIndex {
+			return generateReverseDropUniqueConstraint(step, beforeSchema, driver)
+		}
 		return generateReverseDropForeignKey(step, beforeSchema, driver)
 	} else if parser.ContainsSQL(sqlStmt, "ENABLE ROW LEVEL SECURITY") {
-		return generateReverseEnableRLS(step)
+		return generateReverseEnableRLS(step, driver)
 	} else if parser.ContainsSQL(sqlStmt, "DISABLE ROW LEVEL SECURITY") {
-		return generateReverseDisableRLS(step)
+		return generateReverseDisableRLS(step, driver)
+	} else if parser.ContainsSQL(sqlStmt, "SET (") || parser.ContainsSQL(sqlStmt, "RESET (") {
+		return generateReverseSetStorageParameters(step, beforeSchema, driver)
+	} else if parser.ContainsSQL(sqlStmt, "SET TABLESPACE") {
+		return generateReverseSetTablespace(step, beforeSchema, driver)
+	} else if parser.ContainsSQL(sqlStmt, "REPLICA IDENTITY") {
+		return generateReverseSetReplicaIdentity(step, beforeSchema, driver)
 	}
 
 	return nil, fmt.Errorf("unsupported operation for rollback: %v", step.SQL)
 }
 
 // generateReverseCreateTable creates a DROP TABLE statement
-func generateReverseCreateTable(step PlanStep) ([]PlanStep, error) {
+func generateReverseCreateTable(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	// Extract table name from "CREATE TABLE tablename ..."
 	// Simplified: assumes format "CREATE TABLE <name> ..."
 	// For multi-statement steps, we look at the first statement
@@ -82,7 +134,10 @@ func generateReverseCreateTable(step PlanStep) ([]PlanStep, error) {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("DROP TABLE %s CASCADE", tableName)
+	sql := "DROP TABLE " + quoteName(driver, tableName)
+	if driver.SupportsFeature("CASCADE") {
+		sql += " CASCADE"
+	}
 	desc := fmt.Sprintf("Rollback: Drop table %s", tableName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
@@ -98,14 +153,7 @@ func generateReverseDropTable(step PlanStep, beforeSchema *database.Schema, driv
 	}
 
 	// Find the table in the before schema
-	var table *database.Table
-	for i := range beforeSchema.Tables {
-		if beforeSchema.Tables[i].Name == tableName {
-			table = &beforeSchema.Tables[i]
-			break
-		}
-	}
-
+	table := findTable(beforeSchema, tableName)
 	if table == nil {
 		return nil, fmt.Errorf("table %s not found in before schema", tableName)
 	}
@@ -116,14 +164,14 @@ func generateReverseDropTable(step PlanStep, beforeSchema *database.Schema, driv
 }
 
 // generateReverseAddColumn creates a DROP COLUMN statement
-func generateReverseAddColumn(step PlanStep) ([]PlanStep, error) {
+func generateReverseAddColumn(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	tableName, columnName, err := parser.ExtractTableAndColumnFromAddColumn(sqlStmt)
 	if err != nil {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, columnName)
+	sql := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteName(driver, tableName), driver.QuoteIdentifier(columnName))
 	desc := fmt.Sprintf("Rollback: Drop column %s from table %s", columnName, tableName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
@@ -143,14 +191,14 @@ func generateReverseDropColumn(step PlanStep, beforeSchema *database.Schema, dri
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", tableName, driver.FormatColumnDefinition(*column))
+	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteName(driver, tableName), driver.FormatColumnDefinition(*column))
 	desc := fmt.Sprintf("Rollback: Add column %s to table %s", columnName, tableName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
 
 // generateReverseAlterColumnType changes the column type back
-func generateReverseAlterColumnType(step PlanStep, beforeSchema *database.Schema) ([]PlanStep, error) {
+func generateReverseAlterColumnType(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	tableName, columnName, err := parser.ExtractTableAndColumnFromAlterType(sqlStmt)
 	if err != nil {
@@ -163,42 +211,53 @@ func generateReverseAlterColumnType(step PlanStep, beforeSchema *database.Schema
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", tableName, columnName, column.Type)
+	columnType := driver.RenderType(*column)
+	table, col := quoteName(driver, tableName), driver.QuoteIdentifier(columnName)
+	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, col, columnType)
+	// A forward step that reset the default couldn't cast it, so neither can
+	// the way back
+	if parser.ContainsSQL(sqlStmt, "DROP DEFAULT") {
+		sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT, ALTER COLUMN %s TYPE %s",
+			table, col, col, columnType)
+		if column.Default != nil {
+			sql += fmt.Sprintf(", ALTER COLUMN %s SET DEFAULT %s", col, *column.Default)
+		}
+	}
 	desc := fmt.Sprintf("Rollback: Change type of %s.%s back to %s", tableName, columnName, column.Type)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
 
 // generateReverseSetNotNull drops NOT NULL
-func generateReverseSetNotNull(step PlanStep) ([]PlanStep, error) {
+func generateReverseSetNotNull(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	tableName, columnName, err := parser.ExtractTableAndColumnFromAlterNotNull(sqlStmt)
 	if err != nil {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", tableName, columnName)
+	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", quoteName(driver, tableName), driver.QuoteIdentifier(columnName))
 	desc := fmt.Sprintf("Rollback: Allow nulls in %s.%s", tableName, columnName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
 
 // generateReverseDropNotNull sets NOT NULL
-func generateReverseDropNotNull(step PlanStep) ([]PlanStep, error) {
+func generateReverseDropNotNull(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	tableName, columnName, err := parser.ExtractTableAndColumnFromAlterNotNull(sqlStmt)
 	if err != nil {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", tableName, columnName)
+	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", quoteName(driver, tableName), driver.QuoteIdentifier(columnName))
 	desc := fmt.Sprintf("Rollback: Require non-null in %s.%s", tableName, columnName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
 
 // generateReverseSetDefault drops the default
-func generateReverseSetDefault(step PlanStep, beforeSchema *database.Schema) ([]PlanStep, error) {
+func generateReverseSetDefault(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	tableName, columnName, err := parser.ExtractTableAndColumnFromSetDefault(sqlStmt)
 	if err != nil {
@@ -213,9 +272,9 @@ func generateReverseSetDefault(step PlanStep, beforeSchema *database.Schema) ([]
 
 	var sql string
 	if column.Default == nil {
-		sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", tableName, columnName)
+		sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", quoteName(driver, tableName), driver.QuoteIdentifier(columnName))
 	} else {
-		sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", tableName, columnName, *column.Default)
+		sql = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", quoteName(driver, tableName), driver.QuoteIdentifier(columnName), *column.Default)
 	}
 
 	desc := fmt.Sprintf("Rollback: Restore default for %s.%s", tableName, columnName)
@@ -223,7 +282,7 @@ func generateReverseSetDefault(step PlanStep, beforeSchema *database.Schema) ([]
 }
 
 // generateReverseDropDefault restores the default
-func generateReverseDropDefault(step PlanStep, beforeSchema *database.Schema) ([]PlanStep, error) {
+func generateReverseDropDefault(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	tableName, columnName, err := parser.ExtractTableAndColumnFromDropDefault(sqlStmt)
 	if err != nil {
@@ -240,21 +299,21 @@ func generateReverseDropDefault(step PlanStep, beforeSchema *database.Schema) ([
 		return nil, fmt.Errorf("column %s.%s had no default value", tableName, columnName)
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", tableName, columnName, *column.Default)
+	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", quoteName(driver, tableName), driver.QuoteIdentifier(columnName), *column.Default)
 	desc := fmt.Sprintf("Rollback: Restore default for %s.%s", tableName, columnName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
 
 // generateReverseCreateIndex drops the index
-func generateReverseCreateIndex(step PlanStep) ([]PlanStep, error) {
+func generateReverseCreateIndex(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	sqlStmt := step.SQL[0]
 	indexName, err := parser.ExtractIndexNameFromCreate(sqlStmt)
 	if err != nil {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("DROP INDEX %s", indexName)
+	sql := fmt.Sprintf("DROP INDEX %s", quoteName(driver, indexName))
 	desc := fmt.Sprintf("Rollback: Drop index %s", indexName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
@@ -280,25 +339,29 @@ func generateReverseDropIndex(step PlanStep, beforeSchema *database.Schema, driv
 
 // Helper function to find a column in a schema
 func findColumn(schema *database.Schema, tableName, columnName string) (*database.Column, error) {
-	for _, table := range schema.Tables {
-		if table.Name == tableName {
-			for i := range table.Columns {
-				if table.Columns[i].Name == columnName {
-					return &table.Columns[i], nil
-				}
-			}
-			return nil, fmt.Errorf("column %s not found in table %s", columnName, tableName)
+	table := findTable(schema, tableName)
+	if table == nil {
+		return nil, fmt.Errorf("table %s not found", tableName)
+	}
+	for i := range table.Columns {
+		if table.Columns[i].Name == columnName {
+			return &table.Columns[i], nil
 		}
 	}
-	return nil, fmt.Errorf("table %s not found", tableName)
+	return nil, fmt.Errorf("column %s not found in table %s", columnName, tableName)
 }
 
-// Helper function to find an index in a schema
+// Helper function to find an index in a schema. A schema-qualified index name
+// only matches the tables of that schema.
 func findIndex(schema *database.Schema, indexName string) (string, *database.Index, error) {
+	indexSchema, name := database.SplitQualifiedName(indexName)
 	for _, table := range schema.Tables {
+		if indexSchema != "" && table.Schema != "" && table.Schema != indexSchema {
+			continue
+		}
 		for i := range table.Indexes {
-			if table.Indexes[i].Name == indexName {
-				return table.Name, &table.Indexes[i], nil
+			if table.Indexes[i].Name == name {
+				return table.QualifiedName(), &table.Indexes[i], nil
 			}
 		}
 	}
@@ -310,7 +373,7 @@ func findForeignKey(schema *database.Schema, fkName string) (string, *database.F
 	for _, table := range schema.Tables {
 		for i := range table.ForeignKeys {
 			if table.ForeignKeys[i].Name == fkName {
-				return table.Name, &table.ForeignKeys[i], nil
+				return table.QualifiedName(), &table.ForeignKeys[i], nil
 			}
 		}
 	}
@@ -318,7 +381,7 @@ func findForeignKey(schema *database.Schema, fkName string) (string, *database.F
 }
 
 // generateReverseAddForeignKey creates a DROP CONSTRAINT statement
-func generateReverseAddForeignKey(step PlanStep) ([]PlanStep, error) {
+func generateReverseAddForeignKey(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	// Extract table name and constraint name from "ALTER TABLE tablename ADD CONSTRAINT constraintname ..."
 	// For multi-statement steps (SQLite), look at the first CREATE TABLE statement
 	sqlStmt := step.SQL[0]
@@ -327,7 +390,7 @@ func generateReverseAddForeignKey(step PlanStep) ([]PlanStep, error) {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", tableName, constraintName)
+	sql := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", quoteName(driver, tableName), driver.QuoteIdentifier(constraintName))
 	desc := fmt.Sprintf("Rollback: Drop foreign key %s from table %s", constraintName, tableName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
@@ -358,8 +421,91 @@ func generateReverseDropForeignKey(step PlanStep, beforeSchema *database.Schema,
 	return []PlanStep{{Description: fmt.Sprintf("Rollback: %s", desc), SQL: []string{sql}}}, nil
 }
 
+// generateReverseAddUniqueConstraint creates a DROP CONSTRAINT statement,
+// which also drops the constraint's index
+func generateReverseAddUniqueConstraint(step PlanStep, driver database.Driver) ([]PlanStep, error) {
+	tableName, constraintName, err := parser.ExtractTableAndConstraintFromAddConstraint(step.SQL[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", quoteName(driver, tableName), driver.QuoteIdentifier(constraintName))
+	desc := fmt.Sprintf("Rollback: Drop unique constraint %s from table %s", constraintName, tableName)
+
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
+// generateReverseDropUniqueConstraint recreates a dropped UNIQUE constraint
+// from its index in the before schema
+func generateReverseDropUniqueConstraint(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	tableName, constraintName, err := parser.ExtractTableAndConstraintFromDropConstraint(step.SQL[0])
+	if err != nil {
+		return nil, err
+	}
+
+	foundTableName, index, err := findIndex(beforeSchema, constraintName)
+	if err != nil {
+		return nil, err
+	}
+	if foundTableName != tableName {
+		return nil, fmt.Errorf("unique constraint %s found in table %s, expected %s", constraintName, foundTableName, tableName)
+	}
+
+	sql, desc := driver.AddIndex(tableName, *index)
+	return []PlanStep{{Description: fmt.Sprintf("Rollback: %s", desc), SQL: []string{sql}}}, nil
+}
+
+// generateReverseRenameConstraint renames a constraint back to its old name
+func generateReverseRenameConstraint(step PlanStep, driver database.Driver) ([]PlanStep, error) {
+	tableName, oldName, newName, err := parser.ExtractTableAndConstraintsFromRenameConstraint(step.SQL[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", quoteName(driver, tableName), driver.QuoteIdentifier(newName), driver.QuoteIdentifier(oldName))
+	desc := fmt.Sprintf("Rollback: Rename constraint %s on table %s back to %s", newName, tableName, oldName)
+
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
+// generateReverseRenameColumn renames a column back to its old name. A soft
+// drop that also dropped the tombstone's NOT NULL sets it again first, which
+// fails if rows were written without the column in the meantime.
+func generateReverseRenameColumn(step PlanStep, driver database.Driver) ([]PlanStep, error) {
+	tableName, oldName, newName, err := parser.ExtractTableAndColumnsFromRenameColumn(step.SQL[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var sqls []string
+	for _, stmt := range step.SQL[1:] {
+		if parser.ContainsSQL(stmt, "DROP NOT NULL") {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", quoteName(driver, tableName), driver.QuoteIdentifier(newName)))
+		}
+	}
+	sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteName(driver, tableName), driver.QuoteIdentifier(newName), driver.QuoteIdentifier(oldName)))
+	desc := fmt.Sprintf("Rollback: Rename column %s on table %s back to %s", newName, tableName, oldName)
+
+	return []PlanStep{{Description: desc, SQL: sqls}}, nil
+}
+
+// generateReverseRenameIndex renames an index back to its old name
+func generateReverseRenameIndex(step PlanStep, driver database.Driver) ([]PlanStep, error) {
+	oldName, newName, err := parser.ExtractIndexNamesFromRename(step.SQL[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// The new name is qualified like the old one
+	schemaName, oldIndex := database.SplitQualifiedName(oldName)
+	sql := fmt.Sprintf("ALTER INDEX %s RENAME TO %s", quoteName(driver, database.QualifiedTableName(schemaName, newName)), driver.QuoteIdentifier(oldIndex))
+	desc := fmt.Sprintf("Rollback: Rename index %s back to %s", newName, oldName)
+
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
 // generateReverseEnableRLS creates a DISABLE ROW LEVEL SECURITY statement
-func generateReverseEnableRLS(step PlanStep) ([]PlanStep, error) {
+func generateReverseEnableRLS(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	// Extract table name from "ALTER TABLE tablename ENABLE ROW LEVEL SECURITY"
 	sqlStmt := step.SQL[0]
 	tableName, err := parser.ExtractTableNameFromAlter(sqlStmt)
@@ -367,14 +513,14 @@ func generateReverseEnableRLS(step PlanStep) ([]PlanStep, error) {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", tableName)
+	sql := fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", quoteName(driver, tableName))
 	desc := fmt.Sprintf("Rollback: Disable row level security on table %s", tableName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
 
 // generateReverseDisableRLS creates an ENABLE ROW LEVEL SECURITY statement
-func generateReverseDisableRLS(step PlanStep) ([]PlanStep, error) {
+func generateReverseDisableRLS(step PlanStep, driver database.Driver) ([]PlanStep, error) {
 	// Extract table name from "ALTER TABLE tablename DISABLE ROW LEVEL SECURITY"
 	sqlStmt := step.SQL[0]
 	tableName, err := parser.ExtractTableNameFromAlter(sqlStmt)
@@ -382,8 +528,204 @@ func generateReverseDisableRLS(step PlanStep) ([]PlanStep, error) {
 		return nil, err
 	}
 
-	sql := fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", tableName)
+	sql := fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", quoteName(driver, tableName))
 	desc := fmt.Sprintf("Rollback: Enable row level security on table %s", tableName)
 
 	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
 }
+
+// generateReverseSetTablespace moves a table or index back to its original tablespace
+func generateReverseSetTablespace(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	// Extract object from "ALTER TABLE|INDEX name SET TABLESPACE tablespace"
+	sqlStmt := step.SQL[0]
+	kind, name, _, err := parser.ExtractObjectAndTablespaceFromSetTablespace(sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the original placement in the before schema
+	var original *string
+	found := false
+	if kind == "TABLE" {
+		if table := findTable(beforeSchema, name); table != nil {
+			original, found = table.Tablespace, true
+		}
+	} else if _, idx, err := findIndex(beforeSchema, name); err == nil {
+		original, found = idx.Tablespace, true
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%s %s not found in before schema", strings.ToLower(kind), name)
+	}
+
+	tablespace := database.TablespaceOrDefault(original, beforeSchema.DefaultTablespace)
+	sql := fmt.Sprintf("ALTER %s %s SET TABLESPACE %s", kind, quoteName(driver, name), driver.QuoteIdentifier(tablespace))
+	desc := fmt.Sprintf("Rollback: Move %s %s to tablespace %s", strings.ToLower(kind), name, tablespace)
+
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
+// generateReverseSetReplicaIdentity restores a table's original replica identity
+func generateReverseSetReplicaIdentity(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	// Extract table name from "ALTER TABLE tablename REPLICA IDENTITY ..."
+	sqlStmt := step.SQL[0]
+	tableName, err := parser.ExtractTableNameFromAlter(sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	table := findTable(beforeSchema, tableName)
+
+	// A table created by this plan is dropped by the rollback instead
+	if table == nil {
+		return nil, nil
+	}
+
+	identity := replicaIdentityOrDefault(table.ReplicaIdentity)
+	sql := fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY %s", quoteName(driver, tableName), replicaIdentitySQL(driver, identity))
+	desc := fmt.Sprintf("Rollback: Set replica identity of table %s to %s", tableName, identity)
+
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
+// generateReverseSetStorage restores a column's previous storage mode. Columns
+// that did not exist before are dropped by their own rollback step.
+func generateReverseSetStorage(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	sqlStmt := step.SQL[0]
+	tableName, columnName, err := parser.ExtractTableAndColumnFromSetStorage(sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	if table := findTable(beforeSchema, tableName); table != nil {
+		for _, col := range table.Columns {
+			if col.Name != columnName {
+				continue
+			}
+			storage := schema.ColumnStorage(col)
+			if storage == "" {
+				storage = "DEFAULT"
+			}
+			sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STORAGE %s", quoteName(driver, tableName), driver.QuoteIdentifier(columnName), storage)
+			desc := fmt.Sprintf("Rollback: Set storage of %s.%s to %s", tableName, columnName, storage)
+			return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// generateReverseSetStorageParameters restores the storage parameters a SET
+// or RESET step changed to their values in the before schema
+func generateReverseSetStorageParameters(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	sqlStmt := step.SQL[0]
+	tableName, names, _, err := parser.ExtractTableAndStorageParameters(sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	var original map[string]string
+	found := false
+	if table := findTable(beforeSchema, tableName); table != nil {
+		original, found = table.StorageParameters, true
+	}
+	if !found {
+		return nil, fmt.Errorf("table %s not found in before schema", tableName)
+	}
+
+	restore := map[string]string{}
+	var reset []string
+	for _, name := range names {
+		if value, ok := original[name]; ok {
+			restore[name] = value
+		} else {
+			reset = append(reset, name)
+		}
+	}
+
+	var sql []string
+	if len(restore) > 0 {
+		sql = append(sql, fmt.Sprintf("ALTER TABLE %s SET (%s)", quoteName(driver, tableName), database.FormatStorageParameters(restore)))
+	}
+	if len(reset) > 0 {
+		sql = append(sql, fmt.Sprintf("ALTER TABLE %s RESET (%s)", quoteName(driver, tableName), strings.Join(reset, ", ")))
+	}
+	desc := fmt.Sprintf("Rollback: Restore storage parameters of table %s", tableName)
+
+	return []PlanStep{{Description: desc, SQL: sql}}, nil
+}
+
+// generateReverseSetStatistics restores a column's previous statistics target.
+// Columns that did not exist before are dropped by their own rollback step.
+func generateReverseSetStatistics(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	sqlStmt := step.SQL[0]
+	tableName, columnName, err := parser.ExtractTableAndColumnFromSetStatistics(sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	if table := findTable(beforeSchema, tableName); table != nil {
+		for _, col := range table.Columns {
+			if col.Name != columnName {
+				continue
+			}
+			target := columnStatisticsTarget(col)
+			sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STATISTICS %d", quoteName(driver, tableName), driver.QuoteIdentifier(columnName), target)
+			desc := fmt.Sprintf("Rollback: Set statistics target of %s.%s to %d", tableName, columnName, target)
+			return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// generateReverseCreateSequence drops a sequence the plan created
+func generateReverseCreateSequence(step PlanStep, driver database.Driver) ([]PlanStep, error) {
+	name := step.Operation.Details["name"]
+	sql := fmt.Sprintf("DROP SEQUENCE %s", quoteName(driver, name))
+	desc := fmt.Sprintf("Rollback: Drop sequence %s", name)
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
+// generateReverseAlterSequence restores the options the step changed. A
+// restarted sequence is restarted at the value it would have returned next.
+func generateReverseAlterSequence(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	name := step.Operation.Details["name"]
+	seq := database.FindSequence(beforeSchema, name)
+
+	// A sequence created by this plan is dropped by the rollback instead
+	if seq == nil {
+		return nil, nil
+	}
+
+	changes := strings.Split(step.Operation.Details["changes"], ",")
+	if slices.Contains(changes, "owned_by") {
+		ownerStep := sequenceOwnerStep(driver, name, seq.OwnedBy)
+		return []PlanStep{{Description: "Rollback: " + ownerStep.Description, SQL: ownerStep.SQL}}, nil
+	}
+
+	previous := *seq
+	next := schema.NextSequenceValue(previous)
+	previous.Restart = &next
+	sql := fmt.Sprintf("ALTER SEQUENCE %s %s", quoteName(driver, name), strings.Join(sequenceClauses(previous, changes), " "))
+	desc := fmt.Sprintf("Rollback: Restore %s of sequence %s", strings.Join(changes, ", "), name)
+	return []PlanStep{{Description: desc, SQL: []string{sql}}}, nil
+}
+
+// generateReverseDropSequence recreates a dropped sequence with its options,
+// owner and position. Values handed out after the plan ran are not known.
+func generateReverseDropSequence(step PlanStep, beforeSchema *database.Schema, driver database.Driver) ([]PlanStep, error) {
+	name := step.Operation.Details["name"]
+	seq := database.FindSequence(beforeSchema, name)
+	if seq == nil {
+		return nil, fmt.Errorf("sequence %s not found in before schema", name)
+	}
+
+	createStep := createSequenceStep(driver, *seq)
+	sqls := createStep.SQL
+	if seq.LastValue != nil {
+		sqls = append(sqls, fmt.Sprintf("ALTER SEQUENCE %s RESTART WITH %d", quoteName(driver, name), schema.NextSequenceValue(*seq)))
+	}
+	if owner := schema.NormalizeSequenceOwner(seq.OwnedBy); owner != "" {
+		sqls = append(sqls, sequenceOwnerStep(driver, name, owner).SQL...)
+	}
+	return []PlanStep{{Description: "Rollback: " + createStep.Description, SQL: sqls}}, nil
+}
@@ -1,6 +1,7 @@
 package planner
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -187,6 +188,43 @@ func TestGenerateRollback_AlterColumnType(t *testing.T) {
 	}
 }
 
+func TestGenerateRollback_AlterColumnTypeResetsDefault(t *testing.T) {
+	oldDefault := "'0'::text"
+	beforeSchema := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name: "users",
+				Columns: []database.Column{
+					{Name: "score", Type: "text", Default: &oldDefault},
+				},
+			},
+		},
+	}
+
+	forwardPlan := &Plan{
+		Steps: []PlanStep{
+			{
+				Description: "Change type of users.score from text to integer",
+				SQL:         []string{"ALTER TABLE users ALTER COLUMN score DROP DEFAULT, ALTER COLUMN score TYPE integer, ALTER COLUMN score SET DEFAULT 0"},
+			},
+		},
+	}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+
+	if len(rollbackPlan.Steps) != 1 {
+		t.Fatalf("Expected 1 rollback step, got %d", len(rollbackPlan.Steps))
+	}
+
+	want := "ALTER TABLE users ALTER COLUMN score DROP DEFAULT, ALTER COLUMN score TYPE text, ALTER COLUMN score SET DEFAULT '0'::text"
+	if step := rollbackPlan.Steps[0]; len(step.SQL) != 1 || step.SQL[0] != want {
+		t.Errorf("Expected the rollback to reset the default, got: %v", step.SQL)
+	}
+}
+
 func TestGenerateRollback_EnableRLS(t *testing.T) {
 	beforeSchema := &database.Schema{}
 	forwardPlan := &Plan{
@@ -243,6 +281,150 @@ func TestGenerateRollback_DisableRLS(t *testing.T) {
 	}
 }
 
+func TestGenerateRollback_SetReplicaIdentity(t *testing.T) {
+	full := database.ReplicaIdentityFull
+	beforeSchema := &database.Schema{
+		Tables: []database.Table{
+			{Name: "events", ReplicaIdentity: &full},
+			{Name: "orders"},
+		},
+	}
+	forwardPlan := &Plan{
+		Steps: []PlanStep{
+			{SQL: []string{"ALTER TABLE events REPLICA IDENTITY NOTHING"}},
+			{SQL: []string{"ALTER TABLE orders REPLICA IDENTITY FULL"}},
+		},
+	}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+
+	expected := []string{
+		"ALTER TABLE orders REPLICA IDENTITY DEFAULT",
+		"ALTER TABLE events REPLICA IDENTITY FULL",
+	}
+	if len(rollbackPlan.Steps) != len(expected) {
+		t.Fatalf("Expected %d rollback steps, got %+v", len(expected), rollbackPlan.Steps)
+	}
+	for i, sql := range expected {
+		if rollbackPlan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, rollbackPlan.Steps[i].SQL[0])
+		}
+	}
+}
+
+func TestGenerateRollback_SetStorageParameters(t *testing.T) {
+	beforeSchema := &database.Schema{
+		Tables: []database.Table{
+			{Name: "orders", StorageParameters: map[string]string{"fillfactor": "100", "autovacuum_enabled": "false"}},
+		},
+	}
+	forwardPlan := &Plan{
+		Steps: []PlanStep{
+			{SQL: []string{"ALTER TABLE orders SET (autovacuum_vacuum_scale_factor = 0.01, fillfactor = 70)"}},
+			{SQL: []string{"ALTER TABLE orders RESET (autovacuum_enabled)"}},
+		},
+	}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+
+	expected := [][]string{
+		{"ALTER TABLE orders SET (autovacuum_enabled = false)"},
+		{"ALTER TABLE orders SET (fillfactor = 100)", "ALTER TABLE orders RESET (autovacuum_vacuum_scale_factor)"},
+	}
+	if len(rollbackPlan.Steps) != len(expected) {
+		t.Fatalf("Expected %d rollback steps, got %+v", len(expected), rollbackPlan.Steps)
+	}
+	for i, sql := range expected {
+		if !reflect.DeepEqual(rollbackPlan.Steps[i].SQL, sql) {
+			t.Errorf("step %d: expected %q, got %q", i, sql, rollbackPlan.Steps[i].SQL)
+		}
+	}
+}
+
+func TestGenerateRollback_SetStorage(t *testing.T) {
+	main := "MAIN"
+	beforeSchema := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name: "documents",
+				Columns: []database.Column{
+					{Name: "body", Type: "text", Storage: &main},
+					{Name: "title", Type: "text"},
+				},
+			},
+		},
+	}
+	forwardPlan := &Plan{
+		Steps: []PlanStep{
+			{SQL: []string{"ALTER TABLE documents ALTER COLUMN body SET STORAGE EXTERNAL"}},
+			{SQL: []string{"ALTER TABLE documents ALTER COLUMN title SET STORAGE EXTERNAL"}},
+		},
+	}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+
+	expected := []string{
+		"ALTER TABLE documents ALTER COLUMN title SET STORAGE DEFAULT",
+		"ALTER TABLE documents ALTER COLUMN body SET STORAGE MAIN",
+	}
+	if len(rollbackPlan.Steps) != len(expected) {
+		t.Fatalf("Expected %d rollback steps, got %+v", len(expected), rollbackPlan.Steps)
+	}
+	for i, sql := range expected {
+		if rollbackPlan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, rollbackPlan.Steps[i].SQL[0])
+		}
+	}
+}
+
+func TestGenerateRollback_SetStatistics(t *testing.T) {
+	target := 250
+	beforeSchema := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name: "events",
+				Columns: []database.Column{
+					{Name: "properties", Type: "jsonb", StatisticsTarget: &target},
+					{Name: "name", Type: "text"},
+				},
+			},
+		},
+	}
+	forwardPlan := &Plan{
+		Steps: []PlanStep{
+			{SQL: []string{"ALTER TABLE events ALTER COLUMN properties SET STATISTICS 1000"}},
+			{SQL: []string{"ALTER TABLE events ALTER COLUMN name SET STATISTICS 1000"}},
+		},
+	}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+
+	expected := []string{
+		"ALTER TABLE events ALTER COLUMN name SET STATISTICS -1",
+		"ALTER TABLE events ALTER COLUMN properties SET STATISTICS 250",
+	}
+	if len(rollbackPlan.Steps) != len(expected) {
+		t.Fatalf("Expected %d rollback steps, got %+v", len(expected), rollbackPlan.Steps)
+	}
+	for i, sql := range expected {
+		if rollbackPlan.Steps[i].SQL[0] != sql {
+			t.Errorf("step %d: expected %q, got %q", i, sql, rollbackPlan.Steps[i].SQL[0])
+		}
+	}
+}
+
 func TestGenerateRollback_SetNotNull(t *testing.T) {
 	beforeSchema := &database.Schema{
 		Tables: []database.Table{
@@ -552,3 +734,90 @@ func TestGenerateRollback_DropForeignKey(t *testing.T) {
 		t.Errorf("Expected ON DELETE CASCADE in foreign key, got: %v", step.SQL)
 	}
 }
+
+func TestGenerateRollback_SetTablespace(t *testing.T) {
+	fast := "fast_ssd"
+	beforeSchema := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:       "events",
+				Tablespace: &fast,
+				Indexes:    []database.Index{{Name: "idx_events_created_at", Columns: []string{"created_at"}}},
+			},
+		},
+	}
+	forwardPlan := &Plan{
+		Steps: []PlanStep{
+			{
+				Description: "Move table events to tablespace archive",
+				SQL:         []string{"ALTER TABLE events SET TABLESPACE archive"},
+			},
+			{
+				Description: "Move index idx_events_created_at on table events to tablespace archive",
+				SQL:         []string{"ALTER INDEX idx_events_created_at SET TABLESPACE archive"},
+			},
+		},
+	}
+
+	driver := postgres.NewDriver()
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, driver)
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+
+	if len(rollbackPlan.Steps) != 2 {
+		t.Fatalf("Expected 2 rollback steps, got %d", len(rollbackPlan.Steps))
+	}
+	if got := rollbackPlan.Steps[0].SQL[0]; got != "ALTER INDEX idx_events_created_at SET TABLESPACE pg_default" {
+		t.Errorf("Expected index to move back to pg_default, got %q", got)
+	}
+	if got := rollbackPlan.Steps[1].SQL[0]; got != "ALTER TABLE events SET TABLESPACE fast_ssd" {
+		t.Errorf("Expected table to move back to fast_ssd, got %q", got)
+	}
+}
+
+func TestGenerateRollback_SetTablespaceToDatabaseDefault(t *testing.T) {
+	beforeSchema := &database.Schema{
+		DefaultTablespace: "fast_ssd",
+		Tables:            []database.Table{{Name: "events"}},
+	}
+	forwardPlan := &Plan{Steps: []PlanStep{{
+		Description: "Move table events to tablespace archive",
+		SQL:         []string{"ALTER TABLE events SET TABLESPACE archive"},
+	}}}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	if len(rollbackPlan.Steps) != 1 || rollbackPlan.Steps[0].SQL[0] != "ALTER TABLE events SET TABLESPACE fast_ssd" {
+		t.Errorf("Expected table to move back to the database default, got %+v", rollbackPlan.Steps)
+	}
+}
+
+func TestGenerateRollback_SchemaQualified(t *testing.T) {
+	beforeSchema := &database.Schema{Tables: []database.Table{
+		{Name: "users", Schema: "public", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "users", Schema: "tenant_a", Columns: []database.Column{{Name: "id", Type: "integer"}, {Name: "note", Type: "text", Nullable: true}},
+			Indexes: []database.Index{{Name: "users_note", Columns: []string{"note"}}}},
+	}}
+	forwardPlan := &Plan{Steps: []PlanStep{
+		{Description: "Create schema billing", SQL: []string{"CREATE SCHEMA IF NOT EXISTS billing"}, Operation: &Operation{Kind: OperationCreateSchema}},
+		{Description: "Drop index users_note", SQL: []string{"DROP INDEX tenant_a.users_note"}},
+		{Description: "Drop column note", SQL: []string{"ALTER TABLE tenant_a.users DROP COLUMN note"}},
+	}}
+
+	rollbackPlan, err := GenerateRollback(forwardPlan, beforeSchema, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	if len(rollbackPlan.Steps) != 2 {
+		t.Fatalf("Expected 2 rollback steps, got %+v", rollbackPlan.Steps)
+	}
+	if sql := rollbackPlan.Steps[0].SQL[0]; sql != "ALTER TABLE tenant_a.users ADD COLUMN note text" {
+		t.Errorf("Expected the column restored on tenant_a.users, got %q", sql)
+	}
+	if sql := rollbackPlan.Steps[1].SQL[0]; sql != "CREATE INDEX users_note ON tenant_a.users (note)" {
+		t.Errorf("Expected the index recreated on tenant_a.users, got %q", sql)
+	}
+}
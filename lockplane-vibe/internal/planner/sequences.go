@@ -0,0 +1,217 @@
+package planner
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// createSequenceStep creates a sequence with the options it sets. Its owner
+// is set by a later step, once the owning column exists.
+func createSequenceStep(driver database.Driver, seq database.Sequence) PlanStep {
+	name := seq.QualifiedName()
+	return PlanStep{
+		Description: fmt.Sprintf("Create sequence %s", name),
+		SQL:         []string{CreateSequenceSQL(driver, seq)},
+		Operation: &Operation{
+			Kind:    OperationCreateSequence,
+			Details: map[string]string{"name": name},
+		},
+	}
+}
+
+// CreateSequenceSQL returns the CREATE SEQUENCE statement of seq, with the
+// options that differ from their defaults. The owner is left out; see
+// SequenceOwnerSQL.
+func CreateSequenceSQL(driver database.Driver, seq database.Sequence) string {
+	sql := "CREATE SEQUENCE " + quoteName(driver, seq.QualifiedName())
+	if clauses := sequenceClauses(seq, createSequenceOptions(seq)); len(clauses) > 0 {
+		sql += " " + strings.Join(clauses, " ")
+	}
+	return sql
+}
+
+// SequenceOwnerSQL returns the ALTER SEQUENCE statement that makes seq owned
+// by its column, or "" when it has no owner
+func SequenceOwnerSQL(driver database.Driver, seq database.Sequence) string {
+	if schema.NormalizeSequenceOwner(seq.OwnedBy) == "" {
+		return ""
+	}
+	return sequenceOwnerStep(driver, seq.QualifiedName(), seq.OwnedBy).SQL[0]
+}
+
+// createSequenceOptions returns the options of seq that differ from the
+// defaults of its type and direction, as SequenceDiff.Changes names them
+func createSequenceOptions(seq database.Sequence) []string {
+	settings := schema.EffectiveSequenceSettings(seq)
+	defaults := schema.EffectiveSequenceSettings(database.Sequence{DataType: seq.DataType, Increment: seq.Increment})
+	var options []string
+	if settings.DataType != "bigint" {
+		options = append(options, "data_type")
+	}
+	if settings.Increment != 1 {
+		options = append(options, "increment")
+	}
+	if settings.MinValue != defaults.MinValue {
+		options = append(options, "min_value")
+	}
+	if settings.MaxValue != defaults.MaxValue {
+		options = append(options, "max_value")
+	}
+	// The start defaults to the bound the sequence counts from
+	bounded := database.Sequence{DataType: seq.DataType, Increment: seq.Increment, MinValue: seq.MinValue, MaxValue: seq.MaxValue}
+	if settings.Start != schema.EffectiveSequenceSettings(bounded).Start {
+		options = append(options, "start")
+	}
+	if settings.Cache != defaults.Cache {
+		options = append(options, "cache")
+	}
+	if settings.Cycle {
+		options = append(options, "cycle")
+	}
+	return options
+}
+
+// alterSequenceStep changes the options of an existing sequence in place,
+// keeping its current value unless the sequence is restarted. It returns
+// false when only the owner changed (see sequenceOwnerStep).
+func alterSequenceStep(driver database.Driver, seqDiff schema.SequenceDiff) (PlanStep, bool) {
+	var changes []string
+	for _, change := range seqDiff.Changes {
+		if change != "owned_by" {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return PlanStep{}, false
+	}
+
+	details := map[string]string{"name": seqDiff.SequenceName, "changes": strings.Join(changes, ",")}
+	desc := fmt.Sprintf("Alter sequence %s (%s)", seqDiff.SequenceName, strings.Join(changes, ", "))
+	if slices.Contains(changes, "restart") {
+		restart := *seqDiff.New.Restart
+		details["restart"] = strconv.FormatInt(restart, 10)
+		if seqDiff.Old.LastValue != nil {
+			details["last_value"] = strconv.FormatInt(*seqDiff.Old.LastValue, 10)
+		}
+		if schema.RestartMovesBackward(seqDiff.Old, restart) {
+			details["restart_direction"] = "backward"
+		} else {
+			details["restart_direction"] = "forward"
+		}
+	}
+	return PlanStep{
+		Description: desc,
+		SQL:         []string{fmt.Sprintf("ALTER SEQUENCE %s %s", quoteName(driver, seqDiff.SequenceName), strings.Join(sequenceClauses(seqDiff.New, changes), " "))},
+		Operation: &Operation{
+			Kind:    OperationAlterSequence,
+			Details: details,
+		},
+	}, true
+}
+
+// sequenceClauses returns the CREATE or ALTER SEQUENCE clauses that set the
+// options of seq named by options. An unset bound is reset to the default
+// with NO MINVALUE or NO MAXVALUE.
+func sequenceClauses(seq database.Sequence, options []string) []string {
+	settings := schema.EffectiveSequenceSettings(seq)
+	var clauses []string
+	for _, option := range options {
+		switch option {
+		case "data_type":
+			clauses = append(clauses, "AS "+settings.DataType)
+		case "increment":
+			clauses = append(clauses, fmt.Sprintf("INCREMENT BY %d", settings.Increment))
+		case "min_value":
+			if seq.MinValue == nil {
+				clauses = append(clauses, "NO MINVALUE")
+			} else {
+				clauses = append(clauses, fmt.Sprintf("MINVALUE %d", *seq.MinValue))
+			}
+		case "max_value":
+			if seq.MaxValue == nil {
+				clauses = append(clauses, "NO MAXVALUE")
+			} else {
+				clauses = append(clauses, fmt.Sprintf("MAXVALUE %d", *seq.MaxValue))
+			}
+		case "start":
+			clauses = append(clauses, fmt.Sprintf("START WITH %d", settings.Start))
+		case "cache":
+			clauses = append(clauses, fmt.Sprintf("CACHE %d", settings.Cache))
+		case "cycle":
+			if settings.Cycle {
+				clauses = append(clauses, "CYCLE")
+			} else {
+				clauses = append(clauses, "NO CYCLE")
+			}
+		case "restart":
+			clauses = append(clauses, fmt.Sprintf("RESTART WITH %d", *seq.Restart))
+		}
+	}
+	return clauses
+}
+
+// sequenceOwnerStep makes a sequence belong to a column, or to none when
+// ownedBy is empty
+func sequenceOwnerStep(driver database.Driver, name, ownedBy string) PlanStep {
+	owner := schema.NormalizeSequenceOwner(ownedBy)
+	desc := fmt.Sprintf("Make sequence %s owned by %s", name, owner)
+	ownerSQL := "NONE"
+	if tableName, columnName, ok := database.SplitSequenceOwner(owner); ok {
+		ownerSQL = quoteName(driver, tableName) + "." + driver.QuoteIdentifier(columnName)
+	} else {
+		owner = "NONE"
+		desc = fmt.Sprintf("Remove the owner of sequence %s", name)
+	}
+	return PlanStep{
+		Description: desc,
+		SQL:         []string{fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s", quoteName(driver, name), ownerSQL)},
+		Operation: &Operation{
+			Kind:    OperationAlterSequence,
+			Details: map[string]string{"name": name, "changes": "owned_by", "owned_by": owner},
+		},
+	}
+}
+
+// dropSequenceStep drops a sequence
+func dropSequenceStep(driver database.Driver, seq database.Sequence) PlanStep {
+	name := seq.QualifiedName()
+	return PlanStep{
+		Description: fmt.Sprintf("Drop sequence %s", name),
+		SQL:         []string{fmt.Sprintf("DROP SEQUENCE %s", quoteName(driver, name))},
+		Operation: &Operation{
+			Kind:    OperationDropSequence,
+			Details: map[string]string{"name": name},
+		},
+	}
+}
+
+// droppedWithOwner reports whether the plan removes the column that owns seq.
+// Dropping the column drops the sequence along with it; a soft-dropped column
+// keeps its sequence until lockplane cleanup-tombstones drops the column.
+func droppedWithOwner(diff *schema.SchemaDiff, seq database.Sequence) bool {
+	tableName, columnName, ok := database.SplitSequenceOwner(schema.NormalizeSequenceOwner(seq.OwnedBy))
+	if !ok {
+		return false
+	}
+	for _, table := range diff.RemovedTables {
+		if table.QualifiedName() == tableName {
+			return true
+		}
+	}
+	for _, tableDiff := range diff.ModifiedTables {
+		if tableDiff.TableName != tableName {
+			continue
+		}
+		for _, col := range tableDiff.RemovedColumns {
+			if col.Name == columnName {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package planner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// sequencePlanSchemas returns a database with sequences and a desired schema
+// that changes them and adds a column owning one of them
+func sequencePlanSchemas() (before, after *database.Schema) {
+	before = &database.Schema{
+		Tables: []database.Table{
+			{Name: "orders", Columns: []database.Column{{Name: "id", Type: "bigint"}}},
+			{Name: "legacy", Columns: []database.Column{{Name: "id", Type: "bigint"}}},
+		},
+		Sequences: []database.Sequence{
+			{Name: "order_numbers", LastValue: int64Ptr(500)},
+			{Name: "legacy_ids", OwnedBy: "legacy.id"},
+			{Name: "unused"},
+		},
+	}
+	after = &database.Schema{
+		Tables: []database.Table{
+			{Name: "orders", Columns: []database.Column{{Name: "id", Type: "bigint"}, {Name: "number", Type: "bigint", Nullable: true}}},
+		},
+		Sequences: []database.Sequence{
+			{Name: "order_numbers", Cache: int64Ptr(20), MaxValue: int64Ptr(1000000), Restart: int64Ptr(100), OwnedBy: "orders.number"},
+			{Name: "invoice_numbers", Schema: "billing", Start: int64Ptr(1000)},
+		},
+	}
+	return before, after
+}
+
+func TestGeneratePlan_Sequences(t *testing.T) {
+	before, after := sequencePlanSchemas()
+	diff := schema.DiffSchemas(before, after)
+
+	plan, err := GeneratePlanWithHash(diff, before, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("GeneratePlan returned error: %v", err)
+	}
+
+	var got []string
+	for _, step := range plan.Steps {
+		got = append(got, step.SQL...)
+	}
+	want := []string{
+		"CREATE SCHEMA IF NOT EXISTS billing",
+		"CREATE SEQUENCE billing.invoice_numbers START WITH 1000",
+		"ALTER SEQUENCE order_numbers MAXVALUE 1000000 CACHE 20 RESTART WITH 100",
+		"ALTER TABLE orders ADD COLUMN number bigint",
+		"ALTER SEQUENCE order_numbers OWNED BY orders.number",
+		"DROP TABLE legacy",
+		"DROP SEQUENCE unused",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected plan SQL:\ngot  %q\nwant %q", got, want)
+	}
+
+	// legacy_ids is dropped along with the legacy table that owns it
+	alter := plan.Steps[2].Operation
+	if alter.Kind != OperationAlterSequence || alter.Details["restart_direction"] != "backward" || alter.Details["last_value"] != "500" {
+		t.Errorf("Expected a backward restart operation, got %+v", alter)
+	}
+}
+
+func TestGeneratePlan_SequencesUnsupported(t *testing.T) {
+	before, after := sequencePlanSchemas()
+	before.Sequences, after.Sequences[1].Schema = before.Sequences[:1], ""
+	diff := schema.DiffSchemas(before, after)
+
+	plan, err := GeneratePlanWithHash(diff, before, sqlite.NewDriver())
+	if err != nil {
+		t.Fatalf("GeneratePlan returned error: %v", err)
+	}
+	for _, step := range plan.Steps {
+		if step.Operation == nil {
+			continue
+		}
+		switch step.Operation.Kind {
+		case OperationCreateSequence, OperationAlterSequence, OperationDropSequence:
+			t.Errorf("Expected no sequence steps on SQLite, got %+v", step)
+		}
+	}
+}
+
+func TestCreateSequenceSQL(t *testing.T) {
+	tests := []struct {
+		seq  database.Sequence
+		want string
+	}{
+		{database.Sequence{Name: "s"}, "CREATE SEQUENCE s"},
+		// Introspected options equal to the defaults are left out
+		{database.Sequence{Name: "s", Schema: "public", DataType: "bigint", Increment: int64Ptr(1), MinValue: int64Ptr(1),
+			MaxValue: int64Ptr(9223372036854775807), Start: int64Ptr(1), Cache: int64Ptr(1)}, "CREATE SEQUENCE s"},
+		{database.Sequence{Name: "s", DataType: "integer", Increment: int64Ptr(-1), MinValue: int64Ptr(-50), Cycle: true},
+			"CREATE SEQUENCE s AS integer INCREMENT BY -1 MINVALUE -50 CYCLE"},
+		{database.Sequence{Name: "s", MinValue: int64Ptr(10)}, "CREATE SEQUENCE s MINVALUE 10"},
+		{database.Sequence{Name: "s", MinValue: int64Ptr(10), Start: int64Ptr(20)}, "CREATE SEQUENCE s MINVALUE 10 START WITH 20"},
+	}
+	for _, tt := range tests {
+		if got := CreateSequenceSQL(postgres.NewDriver(), tt.seq); got != tt.want {
+			t.Errorf("CreateSequenceSQL(%+v) = %q, want %q", tt.seq, got, tt.want)
+		}
+	}
+
+	if got := SequenceOwnerSQL(postgres.NewDriver(), database.Sequence{Name: "s", OwnedBy: "public.users.id"}); got != "ALTER SEQUENCE s OWNED BY users.id" {
+		t.Errorf("Unexpected owner SQL %q", got)
+	}
+}
+
+func TestGenerateRollback_Sequences(t *testing.T) {
+	before, after := sequencePlanSchemas()
+	before.Sequences[0].Cache = int64Ptr(5)
+	before.Sequences[2].Increment = int64Ptr(10)
+	before.Sequences[2].LastValue = int64Ptr(70)
+	driver := postgres.NewDriver()
+	plan, err := GeneratePlanWithHash(schema.DiffSchemas(before, after), before, driver)
+	if err != nil {
+		t.Fatalf("GeneratePlan returned error: %v", err)
+	}
+
+	rollback, err := GenerateRollback(plan, before, driver)
+	if err != nil {
+		t.Fatalf("GenerateRollback returned error: %v", err)
+	}
+
+	var got []string
+	for _, step := range rollback.Steps {
+		got = append(got, step.SQL...)
+	}
+	want := []string{
+		"CREATE SEQUENCE unused INCREMENT BY 10",
+		"ALTER SEQUENCE unused RESTART WITH 80",
+		"ALTER SEQUENCE order_numbers OWNED BY NONE",
+		"ALTER TABLE orders DROP COLUMN number",
+		"ALTER SEQUENCE order_numbers NO MAXVALUE CACHE 5 RESTART WITH 501",
+		"DROP SEQUENCE billing.invoice_numbers",
+	}
+	// Leave out recreating the dropped table, whose SQL comes from the driver
+	var filtered []string
+	for _, sql := range got {
+		if !strings.HasPrefix(sql, "CREATE TABLE") {
+			filtered = append(filtered, sql)
+		}
+	}
+	if !reflect.DeepEqual(filtered, want) {
+		t.Errorf("Unexpected rollback SQL:\ngot  %q\nwant %q", filtered, want)
+	}
+}
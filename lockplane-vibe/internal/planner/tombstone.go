@@ -0,0 +1,103 @@
+package planner
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// softDropColumnStep renames a removed column to its tombstone instead of
+// dropping it. PostgreSQL also drops a NOT NULL constraint without a DEFAULT,
+// since writes that no longer set the column would fail; SQLite can't, so the
+// step warns instead.
+func softDropColumnStep(driver database.Driver, tableName string, col database.Column, now time.Time) (PlanStep, Tombstone) {
+	tombstone := Tombstone{Table: tableName, Column: col.Name, Tombstone: schema.TombstoneColumnName(col.Name, now)}
+	step := PlanStep{
+		Description: fmt.Sprintf("Soft-drop column %s from table %s (rename to %s)", col.Name, tableName, tombstone.Tombstone),
+		SQL:         []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteName(driver, tableName), driver.QuoteIdentifier(col.Name), driver.QuoteIdentifier(tombstone.Tombstone))},
+		Operation: &Operation{
+			Kind:    OperationSoftDropColumn,
+			Table:   tableName,
+			Column:  col.Name,
+			Details: map[string]string{"tombstone": tombstone.Tombstone},
+		},
+	}
+
+	if !tombstoneDropsNotNull(col) {
+		return step, tombstone
+	}
+	if driver.SupportsFeature("ALTER_COLUMN_NULLABLE") {
+		step.SQL = append(step.SQL, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", quoteName(driver, tableName), driver.QuoteIdentifier(tombstone.Tombstone)))
+	} else {
+		step.Warnings = append(step.Warnings, fmt.Sprintf(
+			"%s.%s stays NOT NULL without a DEFAULT, so inserts that omit it fail until cleanup-tombstones drops it",
+			tableName, tombstone.Tombstone))
+	}
+	return step, tombstone
+}
+
+// tombstoneDropsNotNull reports whether soft-dropping col drops its NOT NULL
+// constraint, on drivers that can
+func tombstoneDropsNotNull(col database.Column) bool {
+	return !col.Nullable && !col.IsPrimaryKey && (col.Default == nil || *col.Default == "")
+}
+
+// WithTombstones returns a copy of after with the tombstone columns a plan
+// leaves behind, as they will be introspected once the plan is applied. Each
+// tombstone keeps its place after the column that preceded it in before.
+func WithTombstones(after, before *database.Schema, tombstones []Tombstone, driver database.Driver) *database.Schema {
+	if len(tombstones) == 0 {
+		return after
+	}
+	result := *after
+	result.Tables = slices.Clone(after.Tables)
+	for _, tombstone := range tombstones {
+		beforeTable := findTable(before, tombstone.Table)
+		idx := slices.IndexFunc(result.Tables, func(t database.Table) bool { return t.QualifiedName() == tombstone.Table })
+		if beforeTable == nil || idx < 0 {
+			continue
+		}
+		pos := slices.IndexFunc(beforeTable.Columns, func(c database.Column) bool { return c.Name == tombstone.Column })
+		if pos < 0 {
+			continue
+		}
+		col := beforeTable.Columns[pos]
+		col.Name = tombstone.Tombstone
+		if tombstoneDropsNotNull(col) && driver.SupportsFeature("ALTER_COLUMN_NULLABLE") {
+			col.Nullable = true
+		}
+
+		table := &result.Tables[idx]
+		insertAt := 0
+		for i := pos - 1; i >= 0; i-- {
+			name := beforeTable.Columns[i].Name
+			if at := slices.IndexFunc(table.Columns, func(c database.Column) bool { return c.Name == name }); at >= 0 {
+				insertAt = at + 1
+				break
+			}
+		}
+		table.Columns = slices.Insert(slices.Clone(table.Columns), insertAt, col)
+	}
+	return &result
+}
+
+// GenerateTombstoneCleanupPlan returns a plan that drops the tombstone columns
+// of current soft-dropped before cutoff
+func GenerateTombstoneCleanupPlan(current *database.Schema, cutoff time.Time, driver database.Driver) (*Plan, error) {
+	diff := &schema.SchemaDiff{}
+	for _, table := range current.Tables {
+		tableDiff := schema.TableDiff{TableName: table.QualifiedName()}
+		for _, col := range table.Columns {
+			if _, date, ok := schema.ParseTombstoneColumnName(col.Name); ok && date.Before(cutoff) {
+				tableDiff.RemovedColumns = append(tableDiff.RemovedColumns, col)
+			}
+		}
+		if len(tableDiff.RemovedColumns) > 0 {
+			diff.ModifiedTables = append(diff.ModifiedTables, tableDiff)
+		}
+	}
+	return GeneratePlanWithHash(diff, current, driver)
+}
@@ -0,0 +1,140 @@
+package planner
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+var softDropDate = time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+
+func TestGeneratePlan_SoftDropColumn(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:      "users",
+		RemovedColumns: []database.Column{{Name: "email", Type: "text"}, {Name: "bio", Type: "text", Nullable: true}},
+	}}}
+	opts := PlanOptions{SoftDropColumns: true, Now: softDropDate}
+
+	plan, err := GeneratePlanWithOptions(diff, nil, postgres.NewDriver(), opts)
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected a step per column, got %+v", plan.Steps)
+	}
+	want := []string{
+		"ALTER TABLE users RENAME COLUMN email TO deleted_email_20260314",
+		"ALTER TABLE users ALTER COLUMN deleted_email_20260314 DROP NOT NULL",
+	}
+	if !slices.Equal(plan.Steps[0].SQL, want) {
+		t.Errorf("Expected the NOT NULL column to be renamed and made nullable, got %v", plan.Steps[0].SQL)
+	}
+	if got := plan.Steps[1].SQL; len(got) != 1 || got[0] != "ALTER TABLE users RENAME COLUMN bio TO deleted_bio_20260314" {
+		t.Errorf("Expected the nullable column to be renamed only, got %v", got)
+	}
+	if op := plan.Steps[0].Operation; op.Kind != OperationSoftDropColumn || op.Details["tombstone"] != "deleted_email_20260314" {
+		t.Errorf("Unexpected operation %+v", op)
+	}
+	wantTombstones := []Tombstone{
+		{Table: "users", Column: "email", Tombstone: "deleted_email_20260314"},
+		{Table: "users", Column: "bio", Tombstone: "deleted_bio_20260314"},
+	}
+	if !slices.Equal(plan.Tombstones, wantTombstones) {
+		t.Errorf("Expected tombstones %+v, got %+v", wantTombstones, plan.Tombstones)
+	}
+
+	// Rolling back renames the columns back, restoring NOT NULL
+	rollback, err := GenerateRollback(plan, &database.Schema{}, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate rollback: %v", err)
+	}
+	want = []string{
+		"ALTER TABLE users ALTER COLUMN deleted_email_20260314 SET NOT NULL",
+		"ALTER TABLE users RENAME COLUMN deleted_email_20260314 TO email",
+	}
+	if !slices.Equal(rollback.Steps[len(rollback.Steps)-1].SQL, want) {
+		t.Errorf("Expected the rollback to restore email, got %v", rollback.Steps[len(rollback.Steps)-1].SQL)
+	}
+
+	// SQLite can't drop NOT NULL, so it warns instead
+	plan, err = GeneratePlanWithOptions(diff, nil, sqlite.NewDriver(), opts)
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps[0].SQL) != 1 || len(plan.Steps[0].Warnings) != 1 || !strings.Contains(plan.Steps[0].Warnings[0], "stays NOT NULL") {
+		t.Errorf("Expected a rename with a NOT NULL warning, got %+v", plan.Steps[0])
+	}
+}
+
+func TestGenerateTombstoneCleanupPlan(t *testing.T) {
+	current := &database.Schema{Tables: []database.Table{{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "deleted_email_20260101", Type: "text", Nullable: true},
+			{Name: "deleted_bio_20260314", Type: "text", Nullable: true},
+			{Name: "deleted_at", Type: "timestamp", Nullable: true},
+		},
+	}}}
+
+	plan, err := GenerateTombstoneCleanupPlan(current, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].SQL[0] != "ALTER TABLE users DROP COLUMN deleted_email_20260101" {
+		t.Fatalf("Expected only the old tombstone to be dropped, got %+v", plan.Steps)
+	}
+	if plan.SourceHash == "" {
+		t.Error("Expected the cleanup plan to carry a source hash")
+	}
+}
+
+func TestGenerateTombstoneCleanupPlan_NonDefaultSchema(t *testing.T) {
+	current := &database.Schema{Tables: []database.Table{{
+		Schema: "billing",
+		Name:   "invoices",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "deleted_memo_20260101", Type: "text", Nullable: true},
+		},
+	}}}
+
+	plan, err := GenerateTombstoneCleanupPlan(current, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].SQL[0] != "ALTER TABLE billing.invoices DROP COLUMN deleted_memo_20260101" {
+		t.Fatalf("Expected the tombstone to be dropped from billing.invoices, got %+v", plan.Steps)
+	}
+}
+
+func TestWithTombstones(t *testing.T) {
+	before := &database.Schema{Tables: []database.Table{{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "email", Type: "text"},
+			{Name: "name", Type: "text"},
+		},
+	}}}
+	after := &database.Schema{Tables: []database.Table{{
+		Name:    "users",
+		Columns: []database.Column{before.Tables[0].Columns[0], before.Tables[0].Columns[2]},
+	}}}
+	tombstones := []Tombstone{{Table: "users", Column: "email", Tombstone: "deleted_email_20260314"}}
+
+	result := WithTombstones(after, before, tombstones, postgres.NewDriver())
+	columns := result.Tables[0].Columns
+	if len(columns) != 3 || columns[1].Name != "deleted_email_20260314" || !columns[1].Nullable {
+		t.Errorf("Expected a nullable tombstone in place of email, got %+v", columns)
+	}
+	if len(after.Tables[0].Columns) != 2 {
+		t.Error("Expected after to be left unchanged")
+	}
+}
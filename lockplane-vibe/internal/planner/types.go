@@ -1,9 +1,125 @@
 package planner
 
+import (
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// PlanFormatVersion is the current plan format. Version 2 plans carry source
+// hashes computed with the canonical schema serialization (schema.SchemaHashVersion 2).
+const PlanFormatVersion = 2
+
 // Plan represents a migration plan with a series of steps
 type Plan struct {
-	SourceHash string     `json:"source_hash"`
+	// Summary is an overview of the plan's impact for reviewers (optional;
+	// written by plan, ignored when applying)
+	Summary *ImpactSummary `json:"summary,omitempty"`
+	// Schema is the JSON Schema reference editors use to validate a
+	// hand-edited plan file (optional; ignored when applying)
+	Schema        string `json:"$schema,omitempty"`
+	FormatVersion int    `json:"format_version,omitempty"` // Missing (0) means a version 1 plan
+	// SourceHash is the hash of the schema the plan applies to; plans without
+	// one are applied without checking the database
+	SourceHash string     `json:"source_hash,omitempty"`
+	TargetHash string     `json:"target_hash,omitempty"` // Hash of the schema after the plan is applied
 	Steps      []PlanStep `json:"steps"`
+	// Tombstones lists the columns the plan soft-drops by renaming them, for
+	// lockplane cleanup-tombstones to drop later
+	Tombstones []Tombstone `json:"tombstones,omitempty"`
+	// Advisories are findings about the source database that aren't part of
+	// the plan (optional; written by plan --advise, ignored when applying)
+	Advisories []Advisory `json:"advisories,omitempty"`
+}
+
+// Kinds of Advisory
+const (
+	AdvisoryMissingForeignKeyIndex = "missing_foreign_key_index"
+	AdvisoryUnusedIndex            = "unused_index"
+)
+
+// Advisory is a finding about the source database with SQL the user can copy
+// into their schema files to act on it
+type Advisory struct {
+	Kind    string   `json:"kind"` // AdvisoryMissingForeignKeyIndex or AdvisoryUnusedIndex
+	Table   string   `json:"table"`
+	Name    string   `json:"name"` // Foreign key or index the finding is about
+	Columns []string `json:"columns,omitempty"`
+	Message string   `json:"message"`
+	SQL     string   `json:"sql"`
+}
+
+// Tombstone records a soft-dropped column and the name it was renamed to
+type Tombstone struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`    // Original column name
+	Tombstone string `json:"tombstone"` // Name the column was renamed to
+}
+
+// ImpactSummary counts what a plan changes and records the riskiest step. It
+// is aggregated from the schema diff and the step safety classifications.
+type ImpactSummary struct {
+	TablesCreated      int `json:"tables_created"`
+	TablesDropped      int `json:"tables_dropped"`
+	ColumnsAdded       int `json:"columns_added"`
+	ColumnsDropped     int `json:"columns_dropped"`
+	ColumnsModified    int `json:"columns_modified"`
+	IndexesAdded       int `json:"indexes_added"`
+	IndexesDropped     int `json:"indexes_dropped"`
+	ForeignKeysAdded   int `json:"foreign_keys_added"`
+	ForeignKeysDropped int `json:"foreign_keys_dropped"`
+	// HighestSafety is the riskiest safety level among the steps (e.g.,
+	// "Dangerous"), or empty when no step was classified
+	HighestSafety string `json:"highest_safety,omitempty"`
+	// EstimatedRows is the total number of rows affected, when row estimates are available
+	EstimatedRows int64 `json:"estimated_rows,omitempty"`
+	// Safety counts the safety report's operations, when the plan was
+	// generated with --check-schema
+	Safety *SafetySummary `json:"safety,omitempty"`
+}
+
+// SafetySummary aggregates a migration safety report: how many operations
+// fall in each safety level, and whether the migration passed
+type SafetySummary struct {
+	Operations   int  `json:"operations"`
+	Safe         int  `json:"safe"`
+	Review       int  `json:"review"`
+	Lossy        int  `json:"lossy"`
+	Dangerous    int  `json:"dangerous"`
+	MultiPhase   int  `json:"multi_phase"`
+	Blocked      int  `json:"blocked"`      // Operations that failed validation
+	Irreversible int  `json:"irreversible"` // Operations that cannot be rolled back
+	Valid        bool `json:"valid"`        // No operation was blocked
+	// LockLevels counts operations by the PostgreSQL lock they take, e.g.
+	// "ACCESS EXCLUSIVE"; operations with an unknown lock aren't counted
+	LockLevels map[string]int `json:"lock_levels,omitempty"`
+	Rewrites   int            `json:"rewrites,omitempty"` // Operations that rewrite a table or index
+}
+
+// PlanOptions controls optional plan generation behavior
+type PlanOptions struct {
+	// Cascade drops removed tables with CASCADE instead of explicitly dropping
+	// the foreign keys that reference them first. Ignored by drivers without CASCADE support.
+	Cascade bool
+	// Idempotent guards foreign key and index additions so re-running a
+	// partially applied plan skips objects that already exist with the same
+	// definition (and fails on a same-named object with a different one).
+	// Ignored by drivers without guarded DDL support.
+	Idempotent bool
+	// Backfill maps "table.column" of columns added to existing tables to a SQL
+	// expression that fills their existing rows. It lets a NOT NULL column
+	// without a DEFAULT be added as nullable, backfilled and then made NOT NULL.
+	Backfill map[string]string
+	// SoftDropColumns renames removed columns to a dated tombstone (see
+	// schema.TombstoneColumnName) instead of dropping them
+	SoftDropColumns bool
+	// BackfillBatchSize runs backfills as batched updates of this many rows
+	// (see BatchedUpdate) instead of a single UPDATE (0 = single UPDATE)
+	BackfillBatchSize int
+	// BackfillPause is how long batched backfills sleep between batches
+	BackfillPause time.Duration
+	// Now dates soft-drop tombstones; the zero value means the current time
+	Now time.Time
 }
 
 // PlanStep represents a single logical migration operation
@@ -11,15 +127,78 @@ type Plan struct {
 type PlanStep struct {
 	Description string   `json:"description"`
 	SQL         []string `json:"sql"` // Array of SQL statements to execute in order
-	// Source location metadata (optional, for error reporting)
-	SourceFile string `json:"source_file,omitempty"` // Original file where this step was defined
-	SourceLine int    `json:"source_line,omitempty"` // Line number in the source file
+	// Source is the schema file location of the object this step creates or
+	// changes (optional; only set when the desired schema was loaded from SQL files)
+	Source *database.SourceLocation `json:"source,omitempty"`
 	// Lock analysis metadata (optional, for impact reporting)
 	LockMode     string `json:"lock_mode,omitempty"`     // PostgreSQL lock mode (e.g., "ACCESS EXCLUSIVE")
 	LockImpact   string `json:"lock_impact,omitempty"`   // Human-readable impact description
 	BlocksReads  bool   `json:"blocks_reads,omitempty"`  // Whether this blocks SELECT queries
 	BlocksWrites bool   `json:"blocks_writes,omitempty"` // Whether this blocks INSERT/UPDATE/DELETE
 	Rewritable   bool   `json:"rewritable,omitempty"`    // Whether this can be rewritten to be lock-safe
+	// LongRunning marks steps that scan or rewrite a whole table or index. The
+	// executor runs them with statement_timeout disabled.
+	LongRunning bool `json:"long_running,omitempty"`
+	// NonTransactional marks steps that cannot run inside a transaction, such
+	// as CREATE INDEX CONCURRENTLY. The executor commits the steps before them
+	// and runs them on their own.
+	NonTransactional bool `json:"non_transactional,omitempty"`
+	// Batch makes the executor run an UPDATE in batches instead of SQL, which
+	// then only shows the update for review (see BatchedUpdateStep)
+	Batch *BatchedUpdate `json:"batch,omitempty"`
+	// Structured description of the change (optional, for programmatic consumers)
+	Operation *Operation `json:"operation,omitempty"`
+	// Warnings are side effects of the step that reviewers should know about,
+	// such as a recreated table losing its owner and grants
+	Warnings []string `json:"warnings,omitempty"`
+	// Reason says which schema difference the step carries out (optional,
+	// written by plan --explain)
+	Reason string `json:"reason,omitempty"`
+	// Review metadata (optional, written by plan --review)
+	Review *StepReview `json:"review,omitempty"`
+}
+
+// Operation kinds used in PlanStep.Operation
+const (
+	OperationCreateSchema   = "create_schema"
+	OperationCreateTable    = "create_table"
+	OperationDropTable      = "drop_table"
+	OperationAddColumn      = "add_column"
+	OperationDropColumn     = "drop_column"
+	OperationAlterColumn    = "alter_column"
+	OperationAddForeignKey  = "add_foreign_key"
+	OperationDropForeignKey = "drop_foreign_key"
+	OperationAddIndex       = "add_index"
+	OperationDropIndex      = "drop_index"
+	OperationEnableRLS      = "enable_rls"
+	OperationDisableRLS     = "disable_rls"
+	OperationSetTablespace  = "set_tablespace"
+
+	OperationSetReplicaIdentity   = "set_replica_identity"
+	OperationSetStorageParameters = "set_storage_parameters"
+	OperationReorderColumns       = "reorder_columns"
+	OperationRenameIndex          = "rename_index"
+	OperationRenameForeignKey     = "rename_foreign_key"
+	OperationSoftDropColumn       = "soft_drop_column"
+	OperationCreateSequence       = "create_sequence"
+	OperationAlterSequence        = "alter_sequence"
+	OperationDropSequence         = "drop_sequence"
+	OperationBatchedUpdate        = "batched_update"
+)
+
+// Operation is a machine-readable description of what a plan step changes
+type Operation struct {
+	Kind    string            `json:"kind"`              // One of the Operation* constants
+	Table   string            `json:"table,omitempty"`   // Table the step modifies
+	Column  string            `json:"column,omitempty"`  // Column the step modifies, if any
+	Details map[string]string `json:"details,omitempty"` // Kind-specific details (e.g., index name, old/new type)
+}
+
+// StepReview records a reviewer's decision about a plan step
+type StepReview struct {
+	Decision string   `json:"decision"`           // "approved", "flagged", or "pending"
+	Safety   string   `json:"safety,omitempty"`   // Safety level shown during review (e.g., "Dangerous")
+	Warnings []string `json:"warnings,omitempty"` // Safety warnings shown during review
 }
 
 // ExecutionResult tracks the outcome of executing a plan
@@ -27,6 +206,31 @@ type ExecutionResult struct {
 	Success      bool     `json:"success"`
 	StepsApplied int      `json:"steps_applied"`
 	Errors       []string `json:"errors,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"`
+
+	// Profile holds the run time of each executed statement when profiling
+	Profile []StatementProfile `json:"profile,omitempty"`
+
+	// SkippedSteps lists the plan steps the operator skipped during an
+	// interactive apply; they were not run and remain to be applied
+	SkippedSteps []SkippedStep `json:"skipped_steps,omitempty"`
+}
+
+// SkippedStep records a plan step that was left out of an apply
+type SkippedStep struct {
+	Step        int      `json:"step"`        // 1-based step number in the plan
+	Description string   `json:"description"` // Description of the step
+	SQL         []string `json:"sql"`         // The statements that were not run
+}
+
+// StatementProfile records how long a plan statement took to execute
+type StatementProfile struct {
+	Step        int     `json:"step"`              // 1-based step number
+	Statement   int     `json:"statement"`         // 1-based statement number within the step
+	Description string  `json:"description"`       // Description of the step
+	SQL         string  `json:"sql"`               // The statement
+	DurationMS  float64 `json:"duration_ms"`       // Execution time in milliseconds
+	Explain     string  `json:"explain,omitempty"` // Query plan of data-changing statements
 }
 
 // MultiPhasePlan represents a migration requiring multiple coordinated phases
@@ -0,0 +1,242 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+// DecisionSkipped marks a step the operator chose not to apply
+const DecisionSkipped = "skipped"
+
+// approvalPhase is the screen the apply confirmation shows
+type approvalPhase int
+
+const (
+	phaseOverview approvalPhase = iota // Approve the whole plan or step through it
+	phaseStepping                      // Apply or skip the step under the cursor
+	phaseConfirm                       // Confirm the approved steps
+)
+
+// ApprovalModel holds the state for confirming an apply. The operator
+// approves the whole plan, or steps through it applying or skipping each
+// step, and can abort at any point.
+type ApprovalModel struct {
+	Model
+	target    string
+	phase     approvalPhase
+	confirmed bool
+}
+
+// NewApproval creates an apply confirmation for a plan about to be applied to
+// target. The diff is optional, as in New.
+func NewApproval(plan *planner.Plan, diff *schema.SchemaDiff, target string) ApprovalModel {
+	m := ApprovalModel{Model: New(plan, diff), target: target}
+	for i := range m.items {
+		m.items[i].decision = DecisionPending
+	}
+	return m
+}
+
+// Update implements tea.Model
+func (m ApprovalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.scrollToCursor()
+		return m, nil
+
+	case tea.KeyMsg:
+		key := msg.String()
+		if key == "ctrl+c" || key == "esc" || key == "q" {
+			m.cancelled = true
+			return m, tea.Quit
+		}
+		switch m.phase {
+		case phaseOverview:
+			switch key {
+			case "a":
+				for i := range m.items {
+					m.items[i].decision = DecisionApproved
+				}
+				m.confirmed = true
+				return m, tea.Quit
+			case "s", "enter":
+				m.phase = phaseStepping
+				m.cursor = 0
+			case "up", "k":
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			case "down", "j":
+				if m.cursor < len(m.items)-1 {
+					m.cursor++
+				}
+			}
+		case phaseStepping:
+			switch key {
+			case "y", "a":
+				m.decide(DecisionApproved)
+			case "n", "s":
+				m.decide(DecisionSkipped)
+			case "up", "k", "b":
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			}
+		case phaseConfirm:
+			switch key {
+			case "y", "enter":
+				m.confirmed = true
+				return m, tea.Quit
+			case "up", "k", "b":
+				m.phase = phaseStepping
+			}
+		}
+		m.scrollToCursor()
+	}
+	return m, nil
+}
+
+// decide records a decision for the current step and moves to the next one,
+// or to the confirmation once every step is decided
+func (m *ApprovalModel) decide(decision string) {
+	if len(m.items) == 0 {
+		return
+	}
+	m.items[m.cursor].decision = decision
+	if m.cursor < len(m.items)-1 {
+		m.cursor++
+		return
+	}
+	m.phase = phaseConfirm
+}
+
+// View implements tea.Model
+func (m ApprovalModel) View() string {
+	if m.confirmed || m.cancelled {
+		return ""
+	}
+	return style.Text(m.view())
+}
+
+func (m ApprovalModel) view() string {
+	var b strings.Builder
+	approved, skipped := m.decisionCounts()
+
+	switch m.phase {
+	case phaseConfirm:
+		b.WriteString(headerStyle.Render(fmt.Sprintf("🚀 Apply %d of %d steps to %s?", approved, len(m.items), m.target)))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderList(m.listWidth()))
+		b.WriteString("\n\n")
+		if approved == 0 {
+			b.WriteString(warningStyle.Render("All steps are skipped; nothing will be applied."))
+			b.WriteString("\n")
+		} else if skipped > 0 {
+			b.WriteString(warningStyle.Render(fmt.Sprintf("%d skipped step(s) stay pending and show up in the next plan.", skipped)))
+			b.WriteString("\n")
+		}
+		b.WriteString(statusBarStyle.Render("y/Enter: apply  b: back  Esc/q: abort"))
+		return b.String()
+
+	case phaseStepping:
+		b.WriteString(headerStyle.Render(fmt.Sprintf("🚀 Step %d of %d (%d to apply, %d skipped) on %s", m.cursor+1, len(m.items), approved, skipped, m.target)))
+	default:
+		b.WriteString(headerStyle.Render(fmt.Sprintf("🚀 Apply %d steps to %s?", len(m.items), m.target)))
+	}
+	b.WriteString("\n")
+	if len(m.items) == 0 {
+		b.WriteString(labelStyle.Render("No steps to apply."))
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	listWidth := m.listWidth()
+	detailWidth := 60
+	if m.width > 0 {
+		detailWidth = m.width - listWidth - 4
+	}
+	list := panelStyle.Width(listWidth - 4).Render(m.renderList(listWidth - 4))
+	detail := panelStyle.Width(detailWidth - 4).Render(m.renderDetail(m.items[m.cursor]))
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, list, detail))
+	b.WriteString("\n")
+
+	if m.phase == phaseStepping {
+		b.WriteString(statusBarStyle.Render("y: apply  n: skip  b: back  Esc/q: abort"))
+	} else {
+		b.WriteString(statusBarStyle.Render("↑/↓: browse  a: apply all  s/Enter: step through  Esc/q: abort"))
+	}
+	return b.String()
+}
+
+func (m ApprovalModel) listWidth() int {
+	if m.width > 0 {
+		return m.width * 2 / 5
+	}
+	return 40
+}
+
+func (m ApprovalModel) decisionCounts() (approved, skipped int) {
+	for _, item := range m.items {
+		switch item.decision {
+		case DecisionApproved:
+			approved++
+		case DecisionSkipped:
+			skipped++
+		}
+	}
+	return approved, skipped
+}
+
+// ApprovedPlan returns a copy of the plan with only the approved steps, and
+// the steps that were skipped
+func (m ApprovalModel) ApprovedPlan() (*planner.Plan, []planner.SkippedStep) {
+	approved := *m.plan
+	approved.Steps = nil
+	var skipped []planner.SkippedStep
+	for i, item := range m.items {
+		if item.decision == DecisionApproved {
+			approved.Steps = append(approved.Steps, item.step)
+			continue
+		}
+		skipped = append(skipped, planner.SkippedStep{
+			Step:        i + 1,
+			Description: item.step.Description,
+			SQL:         item.step.SQL,
+		})
+	}
+	return &approved, skipped
+}
+
+// RunApproval starts the apply confirmation on stderr and returns the plan of
+// approved steps and the skipped ones. Returns ErrCancelled if the operator
+// aborts.
+func RunApproval(plan *planner.Plan, diff *schema.SchemaDiff, target string) (*planner.Plan, []planner.SkippedStep, error) {
+	if len(plan.Steps) == 0 {
+		return plan, nil, nil
+	}
+	m := NewApproval(plan, diff, target)
+	p := tea.NewProgram(m, tea.WithOutput(os.Stderr), tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fm, ok := final.(ApprovalModel)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected model type")
+	}
+	if !fm.confirmed {
+		return nil, nil, ErrCancelled
+	}
+	approved, skipped := fm.ApprovedPlan()
+	return approved, skipped, nil
+}
@@ -0,0 +1,85 @@
+package review
+
+import (
+	"strings"
+	"testing"
+)
+
+func sendApprovalKey(m ApprovalModel, key string) ApprovalModel {
+	updated, _ := m.Update(keyMsg(key))
+	return updated.(ApprovalModel)
+}
+
+func TestApproval_ApplyAll(t *testing.T) {
+	m := NewApproval(testPlan(), nil, "production")
+	m = sendApprovalKey(m, "a")
+
+	if !m.confirmed {
+		t.Fatal("expected apply all to confirm the plan")
+	}
+	approved, skipped := m.ApprovedPlan()
+	if len(approved.Steps) != 3 || len(skipped) != 0 {
+		t.Errorf("expected all 3 steps approved, got %d approved and %d skipped", len(approved.Steps), len(skipped))
+	}
+	if approved.SourceHash != "abc" {
+		t.Errorf("expected source hash to be preserved, got %q", approved.SourceHash)
+	}
+}
+
+func TestApproval_StepThrough(t *testing.T) {
+	m := NewApproval(testPlan(), nil, "production")
+
+	m = sendApprovalKey(m, "s") // step through
+	m = sendApprovalKey(m, "y") // apply step 1
+	m = sendApprovalKey(m, "n") // skip step 2
+	m = sendApprovalKey(m, "b") // back to step 2
+	m = sendApprovalKey(m, "y") // apply step 2 after all
+	m = sendApprovalKey(m, "n") // skip step 3
+
+	if m.phase != phaseConfirm {
+		t.Fatal("expected the confirmation once every step is decided")
+	}
+	if !strings.Contains(m.View(), "Apply 2 of 3 steps to production?") {
+		t.Errorf("expected the confirmation to count the approved steps, got:\n%s", m.View())
+	}
+	m = sendApprovalKey(m, "enter")
+	if !m.confirmed {
+		t.Fatal("expected enter to confirm")
+	}
+
+	approved, skipped := m.ApprovedPlan()
+	if len(approved.Steps) != 2 || approved.Steps[1].Description != "Drop column legacy from table users" {
+		t.Errorf("expected steps 1 and 2 to be applied, got %+v", approved.Steps)
+	}
+	if len(skipped) != 1 || skipped[0].Step != 3 || skipped[0].SQL[0] != "DROP TABLE old_logs CASCADE" {
+		t.Errorf("expected step 3 to be recorded as skipped, got %+v", skipped)
+	}
+}
+
+func TestApproval_Abort(t *testing.T) {
+	for _, key := range []string{"esc", "q"} {
+		m := NewApproval(testPlan(), nil, "production")
+		m = sendApprovalKey(m, "s")
+		m = sendApprovalKey(m, "y")
+		m = sendApprovalKey(m, key)
+
+		if !m.cancelled || m.confirmed {
+			t.Errorf("expected %s to abort the apply", key)
+		}
+	}
+}
+
+func TestApproval_ViewShowsSafetyDetails(t *testing.T) {
+	m := NewApproval(testPlan(), nil, "production")
+	m = sendApprovalKey(m, "s")
+	m = sendApprovalKey(m, "y")
+	m = sendApprovalKey(m, "y")
+
+	view := m.View()
+	if !strings.Contains(view, "Step 3 of 3") {
+		t.Error("expected view to show the current step number")
+	}
+	if !strings.Contains(view, "DROP TABLE old_logs CASCADE") {
+		t.Error("expected view to show step SQL")
+	}
+}
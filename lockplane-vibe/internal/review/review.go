@@ -0,0 +1,339 @@
+// Package review provides an interactive terminal UI for reviewing migration plans.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+// Review decisions recorded on each step
+const (
+	DecisionPending  = "pending"
+	DecisionApproved = "approved"
+	DecisionFlagged  = "flagged"
+)
+
+// ErrCancelled is returned when the reviewer quits without saving
+var ErrCancelled = errors.New("review cancelled")
+
+// reviewItem is a plan step with its safety analysis and review decision
+type reviewItem struct {
+	step     planner.PlanStep
+	safety   *validation.ValidationResult
+	decision string
+}
+
+// Model holds the state for the plan review UI
+type Model struct {
+	plan   *planner.Plan
+	items  []reviewItem
+	cursor int
+	offset int
+
+	finished  bool
+	cancelled bool
+
+	width  int
+	height int
+}
+
+// New creates a review model for a plan. The diff is optional and only used
+// to give validators full column and table definitions.
+func New(plan *planner.Plan, diff *schema.SchemaDiff) Model {
+	items := make([]reviewItem, len(plan.Steps))
+	for i, step := range plan.Steps {
+		decision := DecisionPending
+		if step.Review != nil && step.Review.Decision != "" {
+			decision = step.Review.Decision
+		}
+		items[i] = reviewItem{
+			step:     step,
+			safety:   validation.AnalyzePlanStep(step, diff),
+			decision: decision,
+		}
+	}
+	return Model{plan: plan, items: items}
+}
+
+// Init implements tea.Model
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.scrollToCursor()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.cancelled = true
+			return m, tea.Quit
+		case "q", "enter":
+			m.finished = true
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+		case "a":
+			m.setDecision(DecisionApproved)
+		case "f":
+			m.setDecision(DecisionFlagged)
+		case "u":
+			m.setDecision(DecisionPending)
+		case "A":
+			for i := range m.items {
+				if m.items[i].decision == DecisionPending {
+					m.items[i].decision = DecisionApproved
+				}
+			}
+		}
+		m.scrollToCursor()
+	}
+	return m, nil
+}
+
+// setDecision records a decision for the current step and moves to the next one
+func (m *Model) setDecision(decision string) {
+	if len(m.items) == 0 {
+		return
+	}
+	m.items[m.cursor].decision = decision
+	if decision != DecisionPending && m.cursor < len(m.items)-1 {
+		m.cursor++
+	}
+}
+
+// visibleRows returns how many steps fit in the list panel
+func (m Model) visibleRows() int {
+	if m.height == 0 {
+		return len(m.items)
+	}
+	// Header, panel borders and status bar take up 6 lines
+	rows := m.height - 6
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+func (m *Model) scrollToCursor() {
+	rows := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+}
+
+// View implements tea.Model
+func (m Model) View() string {
+	return style.Text(m.view())
+}
+
+func (m Model) view() string {
+	if m.finished || m.cancelled {
+		return ""
+	}
+
+	var b strings.Builder
+	approved, flagged := m.counts()
+	b.WriteString(headerStyle.Render(fmt.Sprintf("📋 Plan review: %d steps (%d approved, %d flagged)", len(m.items), approved, flagged)))
+	b.WriteString("\n")
+
+	if len(m.items) == 0 {
+		b.WriteString(labelStyle.Render("No steps to review."))
+		b.WriteString("\n")
+	} else {
+		listWidth := 40
+		detailWidth := 60
+		if m.width > 0 {
+			listWidth = m.width * 2 / 5
+			detailWidth = m.width - listWidth - 4
+		}
+		list := panelStyle.Width(listWidth - 4).Render(m.renderList(listWidth - 4))
+		detail := panelStyle.Width(detailWidth - 4).Render(m.renderDetail(m.items[m.cursor]))
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, list, detail))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(statusBarStyle.Render("↑/↓: navigate  a: approve  f: flag  u: reset  A: approve all  Enter/q: save  Esc: discard"))
+	return b.String()
+}
+
+func (m Model) renderList(width int) string {
+	var lines []string
+	end := m.offset + m.visibleRows()
+	if end > len(m.items) {
+		end = len(m.items)
+	}
+	for i := m.offset; i < end; i++ {
+		item := m.items[i]
+
+		marker := iconPending
+		markerStyle := labelStyle
+		switch item.decision {
+		case DecisionApproved:
+			marker, markerStyle = iconApproved, approvedStyle
+		case DecisionFlagged:
+			marker, markerStyle = style.Icon(iconFlagged, iconFlaggedPlain), flaggedStyle
+		case DecisionSkipped:
+			marker, markerStyle = iconSkipped, warningStyle
+		}
+
+		icon := validation.SafetyLevelSafe.Icon()
+		if item.safety != nil && item.safety.Safety != nil {
+			icon = item.safety.Safety.Level.Icon()
+		}
+
+		text := truncate(fmt.Sprintf("%d. %s", i+1, item.step.Description), width-8)
+		if i == m.cursor {
+			lines = append(lines, fmt.Sprintf("%s %s %s", markerStyle.Render(marker), icon, selectedStyle.Render(iconArrow+" "+text)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %s %s", markerStyle.Render(marker), icon, unselectedStyle.Render("  "+text)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) renderDetail(item reviewItem) string {
+	var b strings.Builder
+	b.WriteString(sectionHeaderStyle.Render(item.step.Description))
+	b.WriteString("\n\n")
+
+	if item.safety != nil && item.safety.Safety != nil {
+		safety := item.safety.Safety
+		fmt.Fprintf(&b, "%s %s\n", safety.Level.Icon(), safety.Level.String())
+		fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Breaking change:"), yesNo(safety.BreakingChange))
+		fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Data loss:"), yesNo(safety.DataLoss))
+		if safety.RollbackDescription != "" {
+			fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Rollback:"), safety.RollbackDescription)
+		}
+		if len(safety.SaferAlternatives) > 0 {
+			b.WriteString(labelStyle.Render("Safer alternatives:"))
+			b.WriteString("\n")
+			for _, alt := range safety.SaferAlternatives {
+				fmt.Fprintf(&b, "  • %s\n", alt)
+			}
+		}
+	} else {
+		fmt.Fprintf(&b, "%s %s\n", validation.SafetyLevelSafe.Icon(), "No safety concerns detected")
+	}
+
+	if item.safety != nil {
+		for _, msg := range item.safety.Errors {
+			b.WriteString(flaggedStyle.Render("❌ " + msg))
+			b.WriteString("\n")
+		}
+		for _, warning := range item.safety.Warnings {
+			b.WriteString(warningStyle.Render("⚠️  " + warning))
+			b.WriteString("\n")
+		}
+	}
+
+	if item.step.Reason != "" {
+		fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Why:"), item.step.Reason)
+	}
+	if item.step.LockMode != "" {
+		fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Lock:"), item.step.LockMode)
+	}
+	if item.step.Source != nil {
+		fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Source:"), item.step.Source)
+	}
+
+	b.WriteString("\n")
+	for _, stmt := range item.step.SQL {
+		b.WriteString(sqlStyle.Render(stmt))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func (m Model) counts() (approved, flagged int) {
+	for _, item := range m.items {
+		switch item.decision {
+		case DecisionApproved:
+			approved++
+		case DecisionFlagged:
+			flagged++
+		}
+	}
+	return approved, flagged
+}
+
+// AnnotatedPlan returns a copy of the plan with review decisions embedded in each step
+func (m Model) AnnotatedPlan() *planner.Plan {
+	annotated := *m.plan
+	annotated.Steps = make([]planner.PlanStep, len(m.items))
+	for i, item := range m.items {
+		step := item.step
+		step.Review = &planner.StepReview{Decision: item.decision}
+		if item.safety != nil {
+			if item.safety.Safety != nil {
+				step.Review.Safety = item.safety.Safety.Level.String()
+			}
+			step.Review.Warnings = item.safety.Warnings
+		}
+		annotated.Steps[i] = step
+	}
+	return &annotated
+}
+
+// Run starts the review UI on stderr and returns the annotated plan.
+// Returns ErrCancelled if the reviewer discards the review.
+func Run(plan *planner.Plan, diff *schema.SchemaDiff) (*planner.Plan, error) {
+	m := New(plan, diff)
+	p := tea.NewProgram(m, tea.WithOutput(os.Stderr), tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	fm, ok := final.(Model)
+	if !ok {
+		return nil, fmt.Errorf("unexpected model type")
+	}
+	if fm.cancelled {
+		return nil, ErrCancelled
+	}
+	return fm.AnnotatedPlan(), nil
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+func truncate(s string, width int) string {
+	if width <= 3 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-3]) + "..."
+}
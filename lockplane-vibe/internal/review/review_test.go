@@ -0,0 +1,123 @@
+package review
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/validation"
+)
+
+func testPlan() *planner.Plan {
+	return &planner.Plan{
+		SourceHash: "abc",
+		Steps: []planner.PlanStep{
+			{Description: "Create table posts", SQL: []string{"CREATE TABLE posts (id integer)"}},
+			{Description: "Drop column legacy from table users", SQL: []string{"ALTER TABLE users DROP COLUMN legacy"}},
+			{Description: "Drop table old_logs", SQL: []string{"DROP TABLE old_logs CASCADE"}},
+		},
+	}
+}
+
+func keyMsg(key string) tea.KeyMsg {
+	switch key {
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	default:
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+}
+
+func sendKey(m Model, key string) Model {
+	updated, _ := m.Update(keyMsg(key))
+	return updated.(Model)
+}
+
+func TestReview_ApproveAndFlag(t *testing.T) {
+	m := New(testPlan(), nil)
+
+	m = sendKey(m, "a") // approve step 1, move to step 2
+	m = sendKey(m, "f") // flag step 2, move to step 3
+	m = sendKey(m, "enter")
+
+	if !m.finished {
+		t.Fatal("expected review to be finished after enter")
+	}
+
+	annotated := m.AnnotatedPlan()
+	want := []string{DecisionApproved, DecisionFlagged, DecisionPending}
+	for i, step := range annotated.Steps {
+		if step.Review == nil {
+			t.Fatalf("step %d missing review", i)
+		}
+		if step.Review.Decision != want[i] {
+			t.Errorf("step %d: expected decision %s, got %s", i, want[i], step.Review.Decision)
+		}
+	}
+
+	if annotated.SourceHash != "abc" {
+		t.Errorf("expected source hash to be preserved, got %q", annotated.SourceHash)
+	}
+}
+
+func TestReview_ApproveAllAndReset(t *testing.T) {
+	m := New(testPlan(), nil)
+
+	m = sendKey(m, "down")
+	m = sendKey(m, "f")
+	m = sendKey(m, "A")
+	m = sendKey(m, "up")
+	m = sendKey(m, "u")
+
+	annotated := m.AnnotatedPlan()
+	want := []string{DecisionApproved, DecisionPending, DecisionApproved}
+	for i, step := range annotated.Steps {
+		if step.Review.Decision != want[i] {
+			t.Errorf("step %d: expected decision %s, got %s", i, want[i], step.Review.Decision)
+		}
+	}
+}
+
+func TestReview_Cancel(t *testing.T) {
+	m := New(testPlan(), nil)
+	m = sendKey(m, "esc")
+
+	if !m.cancelled {
+		t.Error("expected review to be cancelled after esc")
+	}
+}
+
+func TestReview_ViewShowsSafetyDetails(t *testing.T) {
+	m := New(testPlan(), nil)
+	m = sendKey(m, "down")
+	m = sendKey(m, "down")
+
+	view := m.View()
+	if !strings.Contains(view, "Drop table old_logs") {
+		t.Error("expected view to show the selected step")
+	}
+	if !strings.Contains(view, validation.SafetyLevelDangerous.String()) {
+		t.Error("expected view to show dangerous safety level for DROP TABLE")
+	}
+	if !strings.Contains(view, "DROP TABLE old_logs CASCADE") {
+		t.Error("expected view to show step SQL")
+	}
+}
+
+func TestReview_AnnotatedPlanIncludesSafety(t *testing.T) {
+	annotated := New(testPlan(), nil).AnnotatedPlan()
+
+	if annotated.Steps[0].Review.Safety != "" {
+		t.Errorf("expected no safety level for CREATE TABLE, got %q", annotated.Steps[0].Review.Safety)
+	}
+	if annotated.Steps[2].Review.Safety != validation.SafetyLevelDangerous.String() {
+		t.Errorf("expected Dangerous for DROP TABLE, got %q", annotated.Steps[2].Review.Safety)
+	}
+}
@@ -0,0 +1,70 @@
+package review
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Color palette (matches the init wizard)
+var (
+	colorPrimary   = lipgloss.Color("#374151") // Slate
+	colorSuccess   = lipgloss.Color("#2F855A") // Muted green
+	colorError     = lipgloss.Color("#C53030") // Muted red
+	colorWarning   = lipgloss.Color("#B7791F") // Muted amber
+	colorInfo      = lipgloss.Color("#2563EB") // Mid blue
+	colorSubtle    = lipgloss.Color("#4B5563") // Gray
+	colorHighlight = lipgloss.Color("#1D4ED8") // Accent blue
+)
+
+// Style definitions
+var (
+	headerStyle = lipgloss.NewStyle().
+			Foreground(colorPrimary).
+			Bold(true)
+
+	sectionHeaderStyle = lipgloss.NewStyle().
+				Foreground(colorInfo).
+				Bold(true)
+
+	labelStyle = lipgloss.NewStyle().
+			Foreground(colorSubtle)
+
+	selectedStyle = lipgloss.NewStyle().
+			Foreground(colorHighlight).
+			Bold(true)
+
+	unselectedStyle = lipgloss.NewStyle().
+			Foreground(colorPrimary)
+
+	approvedStyle = lipgloss.NewStyle().
+			Foreground(colorSuccess).
+			Bold(true)
+
+	flaggedStyle = lipgloss.NewStyle().
+			Foreground(colorError).
+			Bold(true)
+
+	warningStyle = lipgloss.NewStyle().
+			Foreground(colorWarning)
+
+	sqlStyle = lipgloss.NewStyle().
+			Foreground(colorInfo)
+
+	panelStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(colorSubtle).
+			Padding(0, 1)
+
+	statusBarStyle = lipgloss.NewStyle().
+			Foreground(colorSubtle).
+			Italic(true)
+)
+
+// Icons
+const (
+	iconApproved     = "✓"
+	iconFlagged      = "⚑"
+	iconFlaggedPlain = "!" // iconFlagged with emoji disabled
+	iconSkipped      = "-"
+	iconPending      = "·"
+	iconArrow        = "▶"
+)
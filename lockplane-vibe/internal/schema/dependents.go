@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// TableDependent is an object in another table that depends on a table
+type TableDependent struct {
+	Table      string              // Table that owns the dependent object
+	ForeignKey database.ForeignKey // Foreign key referencing the table
+}
+
+// String describes the dependent object for reports
+func (d TableDependent) String() string {
+	return fmt.Sprintf("foreign key %s on %s", d.ForeignKey.Name, d.Table)
+}
+
+// FindTableDependents returns the foreign keys in other tables that reference tableName.
+// Self-referencing foreign keys are ignored since they are dropped with the table.
+func FindTableDependents(s *database.Schema, tableName string) []TableDependent {
+	if s == nil {
+		return nil
+	}
+
+	var dependents []TableDependent
+	for _, table := range s.Tables {
+		if table.QualifiedName() == tableName {
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			if fk.ReferencedTable == tableName {
+				dependents = append(dependents, TableDependent{Table: table.QualifiedName(), ForeignKey: fk})
+			}
+		}
+	}
+	return dependents
+}
@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestFindTableDependents(t *testing.T) {
+	s := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name: "users",
+				ForeignKeys: []database.ForeignKey{
+					{Name: "fk_users_manager", Columns: []string{"manager_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				},
+			},
+			{
+				Name: "posts",
+				ForeignKeys: []database.ForeignKey{
+					{Name: "fk_posts_author", Columns: []string{"author_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				},
+			},
+			{
+				Name: "comments",
+				ForeignKeys: []database.ForeignKey{
+					{Name: "fk_comments_post", Columns: []string{"post_id"}, ReferencedTable: "posts", ReferencedColumns: []string{"id"}},
+				},
+			},
+		},
+	}
+
+	dependents := FindTableDependents(s, "users")
+	if len(dependents) != 1 {
+		t.Fatalf("expected 1 dependent of users, got %d: %v", len(dependents), dependents)
+	}
+	if dependents[0].Table != "posts" || dependents[0].ForeignKey.Name != "fk_posts_author" {
+		t.Errorf("unexpected dependent: %+v", dependents[0])
+	}
+	if got := dependents[0].String(); got != "foreign key fk_posts_author on posts" {
+		t.Errorf("unexpected description: %q", got)
+	}
+
+	if len(FindTableDependents(s, "comments")) != 0 {
+		t.Error("expected no dependents for comments")
+	}
+	if FindTableDependents(nil, "users") != nil {
+		t.Error("expected nil dependents for nil schema")
+	}
+}
@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+)
+
+// DialectIncompatibility is syntax in a schema file that the dialect the file
+// is loaded with does not support
+type DialectIncompatibility struct {
+	Location SourceLocation   `json:"location"`
+	Feature  string           `json:"feature"`
+	Dialect  database.Dialect `json:"dialect"`
+}
+
+func (i DialectIncompatibility) String() string {
+	return fmt.Sprintf("%s: %s is not supported by %s", i.Location, i.Feature, i.Dialect)
+}
+
+type dialectRule struct {
+	feature string
+	pattern *regexp.Regexp
+}
+
+// postgresOnlySyntax lists PostgreSQL syntax that SQLite rejects or silently
+// treats differently
+var postgresOnlySyntax = []dialectRule{
+	{"PostgreSQL :: cast", regexp.MustCompile(`::`)},
+	{"CREATE EXTENSION", regexp.MustCompile(`(?i)\bCREATE\s+EXTENSION\b`)},
+	{"CREATE TYPE", regexp.MustCompile(`(?i)\bCREATE\s+TYPE\b`)},
+	{"CREATE SCHEMA", regexp.MustCompile(`(?i)\bCREATE\s+SCHEMA\b`)},
+	{"CREATE SEQUENCE", regexp.MustCompile(`(?i)\bCREATE\s+SEQUENCE\b`)},
+	{"CREATE FUNCTION", regexp.MustCompile(`(?i)\bCREATE\s+(OR\s+REPLACE\s+)?FUNCTION\b`)},
+	{"CREATE POLICY", regexp.MustCompile(`(?i)\bCREATE\s+POLICY\b`)},
+	{"COMMENT ON", regexp.MustCompile(`(?i)\bCOMMENT\s+ON\b`)},
+	{"identity column", regexp.MustCompile(`(?i)\bGENERATED\s+(ALWAYS|BY\s+DEFAULT)\s+AS\s+IDENTITY\b`)},
+	{"SERIAL column type", regexp.MustCompile(`(?i)\b(SMALL|BIG)?SERIAL\b`)},
+	{"ALTER TABLE ... ADD CONSTRAINT", regexp.MustCompile(`(?i)\bADD\s+CONSTRAINT\b`)},
+	{"row level security", regexp.MustCompile(`(?i)\b(ENABLE|DISABLE|FORCE)\s+ROW\s+LEVEL\s+SECURITY\b`)},
+	{"CREATE INDEX CONCURRENTLY", regexp.MustCompile(`(?i)\bINDEX\s+CONCURRENTLY\b`)},
+	{"index access method", regexp.MustCompile(`(?i)\bUSING\s+(BTREE|HASH|GIN|GIST|SPGIST|BRIN)\b`)},
+	{"index INCLUDE columns", regexp.MustCompile(`(?i)\bINCLUDE\s*\(`)},
+	{"UNIQUE NULLS NOT DISTINCT", regexp.MustCompile(`(?i)\bNULLS\s+NOT\s+DISTINCT\b`)},
+}
+
+// sqliteOnlySyntax lists SQLite syntax that PostgreSQL rejects
+var sqliteOnlySyntax = []dialectRule{
+	{"AUTOINCREMENT", regexp.MustCompile(`(?i)\bAUTOINCREMENT\b`)},
+	{"WITHOUT ROWID", regexp.MustCompile(`(?i)\bWITHOUT\s+ROWID\b`)},
+	{"STRICT table", regexp.MustCompile(`(?i)\)\s*STRICT\b`)},
+	{"PRAGMA", regexp.MustCompile(`(?i)\bPRAGMA\b`)},
+	{"backtick-quoted identifier", regexp.MustCompile("`")},
+}
+
+// FindDialectIncompatibilities reports syntax in files that dialect does not
+// support, such as PostgreSQL casts in a schema loaded for SQLite. Comments
+// and quoted strings are ignored.
+func FindDialectIncompatibilities(files []SourceFile, dialect database.Dialect) []DialectIncompatibility {
+	var rules []dialectRule
+	switch dialect {
+	case database.DialectSQLite:
+		rules = postgresOnlySyntax
+	case database.DialectPostgres:
+		rules = sqliteOnlySyntax
+	default:
+		return nil
+	}
+
+	var found []DialectIncompatibility
+	for _, file := range files {
+		code := maskCommentsAndStrings(file.Content)
+		for _, rule := range rules {
+			match := rule.pattern.FindStringIndex(code)
+			if match == nil {
+				continue
+			}
+			var location SourceLocation
+			if file.Expansion != nil {
+				location = file.Expansion.Location(match[0])
+			} else {
+				location = lineColumn(file.Path, file.Content, match[0])
+			}
+			found = append(found, DialectIncompatibility{Location: location, Feature: rule.feature, Dialect: dialect})
+		}
+	}
+	return found
+}
+
+// FindDialectIncompatibilitiesInPath runs FindDialectIncompatibilities on a
+// .lp.sql file or the .lp.sql files of a schema directory. JSON schemas are
+// not checked.
+func FindDialectIncompatibilitiesInPath(path string, opts *SchemaLoadOptions) ([]DialectIncompatibility, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	var sources []SourceFile
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		sources, err = dirFS(path).dirSources(".", opts)
+		if err != nil {
+			return nil, err
+		}
+	} else if strings.HasSuffix(strings.ToLower(path), ".lp.sql") {
+		fsys, name := fileFS(path)
+		sources, err = fsys.readSources([]string{name}, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return FindDialectIncompatibilities(sources, opts.Dialect), nil
+}
+
+// maskCommentsAndStrings blanks out comments, quoted strings and identifiers
+// and dollar-quoted bodies, keeping byte offsets and line breaks, so keywords
+// inside them aren't matched
+func maskCommentsAndStrings(content string) string {
+	masked := []byte(content)
+	for i := 0; i < len(content); {
+		end := sqlsplit.SkipToken(content, i)
+		if end-i > 1 && strings.ContainsRune("-/'\"$", rune(content[i])) {
+			for j := i; j < end; j++ {
+				if masked[j] != '\n' {
+					masked[j] = ' '
+				}
+			}
+		}
+		i = end
+	}
+	return string(masked)
+}
@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestFindDialectIncompatibilitiesForSQLite(t *testing.T) {
+	files := []SourceFile{
+		{
+			Path: "users.lp.sql",
+			Content: `-- comments can mention CREATE EXTENSION freely
+CREATE TABLE users (
+  id BIGINT PRIMARY KEY,
+  name TEXT DEFAULT 'a::b',
+  created_at TEXT DEFAULT now()::text
+);
+`,
+		},
+		{
+			Path: "types.lp.sql",
+			Content: `CREATE TYPE mood AS ENUM ('happy');
+`,
+		},
+		{
+			Path: "emails.lp.sql",
+			Content: `CREATE UNIQUE INDEX emails_address_key ON emails (address) NULLS NOT DISTINCT;
+`,
+		},
+	}
+
+	found := FindDialectIncompatibilities(files, database.DialectSQLite)
+	if len(found) != 3 {
+		t.Fatalf("expected 3 incompatibilities, got %d: %v", len(found), found)
+	}
+	if found[2].Feature != "UNIQUE NULLS NOT DISTINCT" || found[2].Location != (SourceLocation{File: "emails.lp.sql", Line: 1, Column: 60}) {
+		t.Errorf("unexpected NULLS NOT DISTINCT finding: %+v", found[2])
+	}
+	if found[0].Feature != "PostgreSQL :: cast" || found[0].Location != (SourceLocation{File: "users.lp.sql", Line: 5, Column: 32}) {
+		t.Errorf("unexpected cast finding: %+v", found[0])
+	}
+	if found[1].Feature != "CREATE TYPE" || found[1].Location.File != "types.lp.sql" || found[1].Location.Line != 1 {
+		t.Errorf("unexpected type finding: %+v", found[1])
+	}
+	if got := found[1].String(); got != "types.lp.sql:1:1: CREATE TYPE is not supported by sqlite" {
+		t.Errorf("unexpected message: %q", got)
+	}
+}
+
+func TestFindDialectIncompatibilitiesForPostgres(t *testing.T) {
+	files := []SourceFile{{
+		Path:    "items.lp.sql",
+		Content: "CREATE TABLE items (\n  id INTEGER PRIMARY KEY AUTOINCREMENT,\n  \"strict\" TEXT\n) STRICT;\n",
+	}}
+
+	found := FindDialectIncompatibilities(files, database.DialectPostgres)
+	if len(found) != 2 {
+		t.Fatalf("expected 2 incompatibilities, got %d: %v", len(found), found)
+	}
+	if found[0].Feature != "AUTOINCREMENT" || found[0].Location.Line != 2 {
+		t.Errorf("unexpected AUTOINCREMENT finding: %+v", found[0])
+	}
+	if found[1].Feature != "STRICT table" || found[1].Location.Line != 4 {
+		t.Errorf("unexpected STRICT finding: %+v", found[1])
+	}
+
+	if found := FindDialectIncompatibilities(files, database.DialectUnknown); found != nil {
+		t.Errorf("expected no findings without a dialect, got %v", found)
+	}
+}
+
+func TestFindDialectIncompatibilitiesInPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.lp.sql"), []byte("CREATE TABLE users (id ${id_type} PRIMARY KEY);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &SchemaLoadOptions{Dialect: database.DialectSQLite, Variables: map[string]string{"id_type": "BIGSERIAL"}}
+	found, err := FindDialectIncompatibilitiesInPath(dir, opts)
+	if err != nil {
+		t.Fatalf("FindDialectIncompatibilitiesInPath returned error: %v", err)
+	}
+	if len(found) != 1 || found[0].Feature != "SERIAL column type" {
+		t.Fatalf("expected the expanded SERIAL type to be reported, got %v", found)
+	}
+	if found[0].Location.Line != 1 || found[0].Location.Column != 24 {
+		t.Errorf("expected the location of the variable reference, got %s", found[0].Location)
+	}
+}
@@ -1,26 +1,94 @@
 package schema
 
-import "github.com/lockplane/lockplane/database"
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+)
 
 // SchemaDiff represents all differences between two schemas
 type SchemaDiff struct {
 	AddedTables    []database.Table `json:"added_tables,omitempty"`
 	RemovedTables  []database.Table `json:"removed_tables,omitempty"`
 	ModifiedTables []TableDiff      `json:"modified_tables,omitempty"`
+	// Sequences are diffed by qualified name (PostgreSQL only)
+	AddedSequences    []database.Sequence `json:"added_sequences,omitempty"`
+	RemovedSequences  []database.Sequence `json:"removed_sequences,omitempty"`
+	ModifiedSequences []SequenceDiff      `json:"modified_sequences,omitempty"`
+	// DefaultTablespace is the current database's default tablespace (see
+	// database.Schema), where a table or index without a tablespace is moved
+	DefaultTablespace string `json:"default_tablespace,omitempty"`
 }
 
 // TableDiff represents changes to a single table
 type TableDiff struct {
-	TableName          string                `json:"table_name"`
-	AddedColumns       []database.Column     `json:"added_columns,omitempty"`
-	RemovedColumns     []database.Column     `json:"removed_columns,omitempty"`
-	ModifiedColumns    []ColumnDiff          `json:"modified_columns,omitempty"`
-	AddedIndexes       []database.Index      `json:"added_indexes,omitempty"`
-	RemovedIndexes     []database.Index      `json:"removed_indexes,omitempty"`
-	AddedForeignKeys   []database.ForeignKey `json:"added_foreign_keys,omitempty"`
-	RemovedForeignKeys []database.ForeignKey `json:"removed_foreign_keys,omitempty"`
-	RLSChanged         bool                  `json:"rls_changed,omitempty"`
-	RLSEnabled         bool                  `json:"rls_enabled,omitempty"` // New value when RLSChanged is true
+	TableName              string                `json:"table_name"`
+	AddedColumns           []database.Column     `json:"added_columns,omitempty"`
+	RemovedColumns         []database.Column     `json:"removed_columns,omitempty"`
+	ModifiedColumns        []ColumnDiff          `json:"modified_columns,omitempty"`
+	AddedIndexes           []database.Index      `json:"added_indexes,omitempty"`
+	RemovedIndexes         []database.Index      `json:"removed_indexes,omitempty"`
+	AddedForeignKeys       []database.ForeignKey `json:"added_foreign_keys,omitempty"`
+	RemovedForeignKeys     []database.ForeignKey `json:"removed_foreign_keys,omitempty"`
+	RLSChanged             bool                  `json:"rls_changed,omitempty"`
+	RLSEnabled             bool                  `json:"rls_enabled,omitempty"` // New value when RLSChanged is true
+	TablespaceChanged      bool                  `json:"tablespace_changed,omitempty"`
+	Tablespace             *string               `json:"tablespace,omitempty"` // New value when TablespaceChanged is true
+	ReplicaIdentityChanged bool                  `json:"replica_identity_changed,omitempty"`
+	ReplicaIdentity        *string               `json:"replica_identity,omitempty"`  // New value when ReplicaIdentityChanged is true
+	MovedIndexes           []database.Index      `json:"moved_indexes,omitempty"`     // Existing indexes whose tablespace changed
+	RecreatedIndexes       []IndexDiff           `json:"recreated_indexes,omitempty"` // Existing indexes that must be dropped and recreated
+	// RenamedIndexes are existing indexes whose definition matches a desired
+	// index with a different name; Changes is always ["name"]
+	RenamedIndexes []IndexDiff `json:"renamed_indexes,omitempty"`
+	// RenamedForeignKeys are existing foreign keys whose definition matches a
+	// desired foreign key with a different name
+	RenamedForeignKeys []Rename `json:"renamed_foreign_keys,omitempty"`
+	// SetStorageParameters are storage parameters added or changed, with their new values
+	SetStorageParameters map[string]string `json:"set_storage_parameters,omitempty"`
+	// ResetStorageParameters are storage parameters removed from the table, sorted by name
+	ResetStorageParameters []string `json:"reset_storage_parameters,omitempty"`
+	// ColumnOrderChanged is set when DiffOptions.EnforceColumnOrder is on and
+	// the columns would not end up in the desired order
+	ColumnOrderChanged bool `json:"column_order_changed,omitempty"`
+	// ColumnOrder is the desired column order, used by table rebuilds
+	ColumnOrder []string `json:"-"`
+	// Source is where the desired table is defined when loaded from SQL files
+	Source *database.SourceLocation `json:"-"`
+}
+
+// DiffOptions controls optional schema comparison behavior
+type DiffOptions struct {
+	// EnforceColumnOrder reports tables whose columns are in a different order
+	// than desired. Columns are otherwise matched by name only.
+	EnforceColumnOrder bool
+	// IgnoreConstraintNames treats indexes and foreign keys with the same
+	// definition as equal whatever their names. By default a name-only
+	// difference is reported as a rename.
+	IgnoreConstraintNames bool
+	// IgnoreTombstones leaves out current columns named like soft-drop
+	// tombstones (see TombstoneColumnName) that the desired schema doesn't
+	// have. They are dropped by lockplane cleanup-tombstones instead.
+	IgnoreTombstones bool
+}
+
+// Rename represents a constraint that only differs in name
+type Rename struct {
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+}
+
+// IndexDiff represents an index whose definition changed in a way that
+// requires dropping and recreating it
+type IndexDiff struct {
+	IndexName string         `json:"index_name"`
+	Old       database.Index `json:"old"`
+	New       database.Index `json:"new"`
+	Changes   []string       `json:"changes"` // e.g., "nulls_not_distinct"
 }
 
 // ColumnDiff represents changes to a single column
@@ -31,30 +99,28 @@ type ColumnDiff struct {
 	Changes    []string        `json:"changes"` // e.g. ["type", "nullable", "default"]
 }
 
-// DiffSchemas compares two schemas and returns their differences
+// DiffSchemas compares two schemas and returns their differences. Tables are
+// matched by schema and name, and columns, indexes and foreign keys by name,
+// so declaration order never produces a difference.
 func DiffSchemas(current, desired *database.Schema) *SchemaDiff {
-	diff := &SchemaDiff{}
+	return DiffSchemasWithOptions(current, desired, DiffOptions{})
+}
 
-	// Build maps for quick lookup
-	currentTables := make(map[string]*database.Table)
-	for i := range current.Tables {
-		currentTables[current.Tables[i].Name] = &current.Tables[i]
-	}
+// DiffSchemasWithOptions compares two schemas like DiffSchemas, with extra comparison options
+func DiffSchemasWithOptions(current, desired *database.Schema, opts DiffOptions) *SchemaDiff {
+	diff := &SchemaDiff{DefaultTablespace: current.DefaultTablespace}
+	matches := matchTables(current, desired)
 
-	desiredTables := make(map[string]*database.Table)
+	// Find added and modified tables, in declaration order
 	for i := range desired.Tables {
-		desiredTables[desired.Tables[i].Name] = &desired.Tables[i]
-	}
-
-	// Find added and modified tables
-	for name, desiredTable := range desiredTables {
-		currentTable, exists := currentTables[name]
+		desiredTable := &desired.Tables[i]
+		currentTable, exists := matches[desiredTable]
 		if !exists {
 			// Table added
 			diff.AddedTables = append(diff.AddedTables, *desiredTable)
 		} else {
 			// Table exists, check for modifications
-			tableDiff := diffTables(currentTable, desiredTable)
+			tableDiff := diffTables(currentTable, desiredTable, current.DefaultTablespace, opts)
 			if !tableDiff.IsEmpty() {
 				diff.ModifiedTables = append(diff.ModifiedTables, *tableDiff)
 			}
@@ -62,19 +128,70 @@ func DiffSchemas(current, desired *database.Schema) *SchemaDiff {
 	}
 
 	// Find removed tables
-	for name, currentTable := range currentTables {
-		if _, exists := desiredTables[name]; !exists {
-			diff.RemovedTables = append(diff.RemovedTables, *currentTable)
+	matched := make(map[*database.Table]bool, len(matches))
+	for _, currentTable := range matches {
+		matched[currentTable] = true
+	}
+	for i := range current.Tables {
+		if !matched[&current.Tables[i]] {
+			diff.RemovedTables = append(diff.RemovedTables, current.Tables[i])
 		}
 	}
 
+	diffSequences(current, desired, diff)
+
 	return diff
 }
 
+// matchTables pairs each desired table with the current table of the same
+// qualified name (see database.QualifiedTableName). A table without a schema,
+// such as one from schema files that don't qualify their tables, is paired
+// with the only unpaired table of the same name, so a database introspected
+// with a search_path other than public still matches.
+func matchTables(current, desired *database.Schema) map[*database.Table]*database.Table {
+	currentTables := make(map[string]*database.Table, len(current.Tables))
+	for i := range current.Tables {
+		currentTables[current.Tables[i].QualifiedName()] = &current.Tables[i]
+	}
+
+	matches := make(map[*database.Table]*database.Table, len(desired.Tables))
+	paired := make(map[*database.Table]bool, len(desired.Tables))
+	for i := range desired.Tables {
+		if currentTable, ok := currentTables[desired.Tables[i].QualifiedName()]; ok {
+			matches[&desired.Tables[i]] = currentTable
+			paired[currentTable] = true
+		}
+	}
+
+	for i := range desired.Tables {
+		desiredTable := &desired.Tables[i]
+		if _, ok := matches[desiredTable]; ok {
+			continue
+		}
+		var candidate *database.Table
+		candidates := 0
+		for j := range current.Tables {
+			currentTable := &current.Tables[j]
+			if paired[currentTable] || currentTable.Name != desiredTable.Name || (currentTable.Schema != "" && desiredTable.Schema != "") {
+				continue
+			}
+			candidate = currentTable
+			candidates++
+		}
+		if candidates == 1 {
+			matches[desiredTable] = candidate
+			paired[candidate] = true
+		}
+	}
+	return matches
+}
+
 // diffTables compares two tables and returns their differences
-func diffTables(current, desired *database.Table) *TableDiff {
+func diffTables(current, desired *database.Table, defaultTablespace string, opts DiffOptions) *TableDiff {
 	diff := &TableDiff{
-		TableName: current.Name,
+		TableName:   current.QualifiedName(),
+		ColumnOrder: columnNames(desired.Columns),
+		Source:      desired.Source,
 	}
 
 	// Build maps for columns
@@ -89,8 +206,9 @@ func diffTables(current, desired *database.Table) *TableDiff {
 	}
 
 	// Find added and modified columns
-	for name, desiredCol := range desiredCols {
-		currentCol, exists := currentCols[name]
+	for i := range desired.Columns {
+		desiredCol := &desired.Columns[i]
+		currentCol, exists := currentCols[desiredCol.Name]
 		if !exists {
 			// Column added
 			diff.AddedColumns = append(diff.AddedColumns, *desiredCol)
@@ -104,12 +222,19 @@ func diffTables(current, desired *database.Table) *TableDiff {
 	}
 
 	// Find removed columns
-	for name, currentCol := range currentCols {
-		if _, exists := desiredCols[name]; !exists {
-			diff.RemovedColumns = append(diff.RemovedColumns, *currentCol)
+	for i := range current.Columns {
+		if _, exists := desiredCols[current.Columns[i].Name]; !exists {
+			if _, _, tombstone := ParseTombstoneColumnName(current.Columns[i].Name); tombstone && opts.IgnoreTombstones {
+				continue
+			}
+			diff.RemovedColumns = append(diff.RemovedColumns, current.Columns[i])
 		}
 	}
 
+	if opts.EnforceColumnOrder {
+		diff.ColumnOrderChanged = !slices.Equal(columnOrderAfterAlter(current, desired, currentCols, desiredCols), diff.ColumnOrder)
+	}
+
 	// Build maps for indexes
 	currentIdxs := make(map[string]*database.Index)
 	for i := range current.Indexes {
@@ -121,17 +246,29 @@ func diffTables(current, desired *database.Table) *TableDiff {
 		desiredIdxs[desired.Indexes[i].Name] = &desired.Indexes[i]
 	}
 
-	// Find added indexes
-	for name, desiredIdx := range desiredIdxs {
-		if _, exists := currentIdxs[name]; !exists {
+	// Find added and moved indexes
+	for i := range desired.Indexes {
+		desiredIdx := &desired.Indexes[i]
+		name := desiredIdx.Name
+		currentIdx, exists := currentIdxs[name]
+		if !exists {
 			diff.AddedIndexes = append(diff.AddedIndexes, *desiredIdx)
+		} else if changes := diffIndex(currentIdx, desiredIdx); len(changes) > 0 {
+			diff.RecreatedIndexes = append(diff.RecreatedIndexes, IndexDiff{
+				IndexName: name,
+				Old:       *currentIdx,
+				New:       *desiredIdx,
+				Changes:   changes,
+			})
+		} else if !equalTablespaces(currentIdx.Tablespace, desiredIdx.Tablespace, defaultTablespace) {
+			diff.MovedIndexes = append(diff.MovedIndexes, *desiredIdx)
 		}
 	}
 
 	// Find removed indexes
-	for name, currentIdx := range currentIdxs {
-		if _, exists := desiredIdxs[name]; !exists {
-			diff.RemovedIndexes = append(diff.RemovedIndexes, *currentIdx)
+	for i := range current.Indexes {
+		if _, exists := desiredIdxs[current.Indexes[i].Name]; !exists {
+			diff.RemovedIndexes = append(diff.RemovedIndexes, current.Indexes[i])
 		}
 	}
 
@@ -147,16 +284,38 @@ func diffTables(current, desired *database.Table) *TableDiff {
 	}
 
 	// Find added foreign keys
-	for name, desiredFK := range desiredFKs {
-		if _, exists := currentFKs[name]; !exists {
-			diff.AddedForeignKeys = append(diff.AddedForeignKeys, *desiredFK)
+	for i := range desired.ForeignKeys {
+		if _, exists := currentFKs[desired.ForeignKeys[i].Name]; !exists {
+			diff.AddedForeignKeys = append(diff.AddedForeignKeys, desired.ForeignKeys[i])
 		}
 	}
 
 	// Find removed foreign keys
-	for name, currentFK := range currentFKs {
-		if _, exists := desiredFKs[name]; !exists {
-			diff.RemovedForeignKeys = append(diff.RemovedForeignKeys, *currentFK)
+	for i := range current.ForeignKeys {
+		if _, exists := desiredFKs[current.ForeignKeys[i].Name]; !exists {
+			diff.RemovedForeignKeys = append(diff.RemovedForeignKeys, current.ForeignKeys[i])
+		}
+	}
+
+	// Match removed and added objects that only differ in name, so they are
+	// renamed instead of dropped and re-created
+	var indexPairs [][2]database.Index
+	diff.RemovedIndexes, diff.AddedIndexes, indexPairs = matchRenames(diff.RemovedIndexes, diff.AddedIndexes, func(a, b database.Index) bool {
+		return equivalentIndexes(a, b, defaultTablespace)
+	})
+	var fkPairs [][2]database.ForeignKey
+	diff.RemovedForeignKeys, diff.AddedForeignKeys, fkPairs = matchRenames(diff.RemovedForeignKeys, diff.AddedForeignKeys, equivalentForeignKeys)
+	if !opts.IgnoreConstraintNames {
+		for _, pair := range indexPairs {
+			diff.RenamedIndexes = append(diff.RenamedIndexes, IndexDiff{
+				IndexName: pair[1].Name,
+				Old:       pair[0],
+				New:       pair[1],
+				Changes:   []string{"name"},
+			})
+		}
+		for _, pair := range fkPairs {
+			diff.RenamedForeignKeys = append(diff.RenamedForeignKeys, Rename{OldName: pair[0].Name, NewName: pair[1].Name})
 		}
 	}
 
@@ -166,14 +325,187 @@ func diffTables(current, desired *database.Table) *TableDiff {
 		diff.RLSEnabled = desired.RLSEnabled
 	}
 
+	// Check for tablespace changes
+	if !equalTablespaces(current.Tablespace, desired.Tablespace, defaultTablespace) {
+		diff.TablespaceChanged = true
+		diff.Tablespace = desired.Tablespace
+	}
+
+	// Check for replica identity changes. Dropping the index a table uses as its
+	// replica identity resets it, so a recreated identity index is set again.
+	identity := NormalizeReplicaIdentity(desired.ReplicaIdentity)
+	if NormalizeReplicaIdentity(current.ReplicaIdentity) != identity || recreatesReplicaIdentityIndex(diff, identity) {
+		diff.ReplicaIdentityChanged = true
+		diff.ReplicaIdentity = desired.ReplicaIdentity
+	}
+
+	diff.SetStorageParameters, diff.ResetStorageParameters = diffStorageParameters(current.StorageParameters, desired.StorageParameters)
+
 	return diff
 }
 
+// matchRenames pairs each removed object with the first unpaired added object
+// of the same definition. It returns the removed and added objects left
+// unpaired, and the (removed, added) pairs.
+func matchRenames[T any](removed, added []T, equivalent func(a, b T) bool) ([]T, []T, [][2]T) {
+	var pairs [][2]T
+	paired := make([]bool, len(added))
+	var unmatched []T
+	for _, old := range removed {
+		match := -1
+		for i, candidate := range added {
+			if !paired[i] && equivalent(old, candidate) {
+				match = i
+				break
+			}
+		}
+		if match < 0 {
+			unmatched = append(unmatched, old)
+			continue
+		}
+		paired[match] = true
+		pairs = append(pairs, [2]T{old, added[match]})
+	}
+	if len(pairs) == 0 {
+		return removed, added, nil
+	}
+
+	var remaining []T
+	for i, candidate := range added {
+		if !paired[i] {
+			remaining = append(remaining, candidate)
+		}
+	}
+	return unmatched, remaining, pairs
+}
+
+// equivalentIndexes reports whether two indexes have the same definition.
+// Indexes without named key columns (expression indexes) never match, since
+// their definitions can't be compared.
+func equivalentIndexes(a, b database.Index, defaultTablespace string) bool {
+	return len(a.KeyColumns()) > 0 &&
+		a.Unique == b.Unique &&
+		a.NullsNotDistinct == b.NullsNotDistinct &&
+		slices.Equal(a.Columns, b.Columns) &&
+		slices.Equal(a.KeyColumns(), b.KeyColumns()) &&
+		slices.Equal(a.IncludeColumns, b.IncludeColumns) &&
+		equalTablespaces(a.Tablespace, b.Tablespace, defaultTablespace)
+}
+
+// equivalentForeignKeys reports whether two foreign keys have the same
+// columns, referenced table and columns, and referential actions
+func equivalentForeignKeys(a, b database.ForeignKey) bool {
+	return slices.Equal(a.Columns, b.Columns) &&
+		sameReferencedTable(a.ReferencedTable, b.ReferencedTable) &&
+		slices.Equal(a.ReferencedColumns, b.ReferencedColumns) &&
+		normalizeForeignKeyAction(a.OnDelete) == normalizeForeignKeyAction(b.OnDelete) &&
+		normalizeForeignKeyAction(a.OnUpdate) == normalizeForeignKeyAction(b.OnUpdate)
+}
+
+// sameReferencedTable reports whether two foreign keys reference the same
+// table. An unqualified reference, as written in a schema file, matches a
+// qualified one with the same bare name, as matchTables matches tables.
+func sameReferencedTable(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	schemaA, tableA := database.SplitQualifiedName(a)
+	schemaB, tableB := database.SplitQualifiedName(b)
+	return (schemaA == "" || schemaB == "") && strings.EqualFold(tableA, tableB)
+}
+
+// normalizeForeignKeyAction returns the uppercase referential action, with
+// an unset action as NO ACTION
+func normalizeForeignKeyAction(action *string) string {
+	if action == nil || strings.TrimSpace(*action) == "" {
+		return "NO ACTION"
+	}
+	return strings.ToUpper(strings.TrimSpace(*action))
+}
+
+// diffStorageParameters returns the parameters to set and those to reset.
+// Names and values are compared case-insensitively; values are otherwise opaque.
+func diffStorageParameters(current, desired map[string]string) (map[string]string, []string) {
+	currentParams := normalizeStorageParameters(current)
+	desiredParams := normalizeStorageParameters(desired)
+
+	var set map[string]string
+	for name, value := range desiredParams {
+		if currentValue, ok := currentParams[name]; ok && strings.EqualFold(currentValue, value) {
+			continue
+		}
+		if set == nil {
+			set = map[string]string{}
+		}
+		set[name] = value
+	}
+
+	var reset []string
+	for name := range currentParams {
+		if _, ok := desiredParams[name]; !ok {
+			reset = append(reset, name)
+		}
+	}
+	slices.Sort(reset)
+
+	return set, reset
+}
+
+// normalizeStorageParameters lowercases parameter names
+func normalizeStorageParameters(params map[string]string) map[string]string {
+	normalized := make(map[string]string, len(params))
+	for name, value := range params {
+		normalized[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return normalized
+}
+
+// columnOrderAfterAlter returns the column order a table ends up with when the
+// diff is applied with ALTER TABLE: kept columns stay where they are and added
+// columns are appended in declaration order.
+func columnOrderAfterAlter(current, desired *database.Table, currentCols, desiredCols map[string]*database.Column) []string {
+	order := make([]string, 0, len(desired.Columns))
+	for _, col := range current.Columns {
+		if _, kept := desiredCols[col.Name]; kept {
+			order = append(order, col.Name)
+		}
+	}
+	for _, col := range desired.Columns {
+		if _, exists := currentCols[col.Name]; !exists {
+			order = append(order, col.Name)
+		}
+	}
+	return order
+}
+
+func columnNames(columns []database.Column) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// recreatesReplicaIdentityIndex reports whether the diff drops and recreates
+// the index named by a USING INDEX replica identity
+func recreatesReplicaIdentityIndex(diff *TableDiff, identity string) bool {
+	indexName, ok := strings.CutPrefix(identity, "USING INDEX ")
+	if !ok {
+		return false
+	}
+	for _, idxDiff := range diff.RecreatedIndexes {
+		if idxDiff.IndexName == indexName {
+			return true
+		}
+	}
+	return false
+}
+
 // diffColumns compares two columns and returns their differences
 func diffColumns(current, desired *database.Column) *ColumnDiff {
 	var changes []string
 
-	if current.LogicalType() != desired.LogicalType() {
+	if !equalColumnTypes(current.LogicalType(), desired.LogicalType()) {
 		changes = append(changes, "type")
 	}
 	if current.Nullable != desired.Nullable {
@@ -185,6 +517,12 @@ func diffColumns(current, desired *database.Column) *ColumnDiff {
 	if current.IsPrimaryKey != desired.IsPrimaryKey {
 		changes = append(changes, "is_primary_key")
 	}
+	if ColumnStorage(*current) != ColumnStorage(*desired) {
+		changes = append(changes, "storage")
+	}
+	if !equalStatisticsTargets(current.StatisticsTarget, desired.StatisticsTarget) {
+		changes = append(changes, "statistics")
+	}
 
 	if len(changes) == 0 {
 		return nil
@@ -206,9 +544,249 @@ func equalDefaults(a, b *string) bool {
 	if a == nil || b == nil {
 		return false
 	}
+	return normalizeDefaultValue(*a) == normalizeDefaultValue(*b)
+}
+
+// equalColumnTypes compares two column types, treating the different
+// spellings of the same timestamp, time or interval type as equal
+func equalColumnTypes(a, b string) bool {
+	return normalizeColumnType(a) == normalizeColumnType(b)
+}
+
+// temporalTypePattern matches timestamp and time types with an optional
+// precision and time zone clause. The precision may also follow the clause,
+// as in "timestamp with time zone(3)".
+var temporalTypePattern = regexp.MustCompile(`^(timestamp|time)(tz)?\s*(?:\(\s*(\d+)\s*\))?\s*(with time zone|without time zone)?\s*(?:\(\s*(\d+)\s*\))?$`)
+
+// intervalTypePattern matches interval types with optional field qualifiers
+// and precision, e.g. "interval day to second (3)" or "interval(6)"
+var intervalTypePattern = regexp.MustCompile(`^interval\s*(?:\(\s*(\d+)\s*\))?\s*((?:year|month|day|hour|minute|second)(?: to (?:month|hour|minute|second))?)?\s*(?:\(\s*(\d+)\s*\))?$`)
+
+// normalizeColumnType returns a canonical form of a column type. Timestamp,
+// time and interval types are spelled the way PostgreSQL prints them, so
+// "timestamptz(3)" and "timestamp(3) with time zone" compare equal, as do
+// "timestamp" and "timestamp without time zone", and "INTERVAL DAY TO
+// SECOND (3)" and "interval day to second(3)". Built-in types may be
+// qualified with pg_catalog, as in "pg_catalog.tsvector".
+func normalizeColumnType(columnType string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(columnType), " "))
+	normalized = strings.TrimPrefix(normalized, "pg_catalog.")
+	array := ""
+	for strings.HasSuffix(normalized, "[]") {
+		normalized = strings.TrimSpace(strings.TrimSuffix(normalized, "[]"))
+		array += "[]"
+	}
+
+	if match := intervalTypePattern.FindStringSubmatch(normalized); match != nil {
+		base := "interval"
+		if match[2] != "" {
+			base += " " + match[2]
+		}
+		if precision := match[1] + match[3]; precision != "" {
+			base += "(" + precision + ")"
+		}
+		return base + array
+	}
+
+	match := temporalTypePattern.FindStringSubmatch(normalized)
+	if match == nil {
+		return normalized + array
+	}
+	base := match[1]
+	if precision := match[3] + match[5]; precision != "" {
+		base += "(" + precision + ")"
+	}
+	zone := "without time zone"
+	if match[2] == "tz" || match[4] == "with time zone" {
+		zone = "with time zone"
+	}
+	return base + " " + zone + array
+}
+
+// normalizeDefaultValue returns a canonical form of a default expression so
+// that equivalent spellings (e.g. NOW() and now(), 'x'::text and 'x') compare equal
+func normalizeDefaultValue(value string) string {
+	value = strings.TrimSpace(value)
+
+	// Strip trailing casts of the whole expression, like 'x'::text. A cast
+	// inside parentheses, a literal or an operand, like
+	// nextval('s'::regclass) or a::int + b, is part of the expression.
+	for {
+		idx := trailingCast(value)
+		if idx <= 0 {
+			break
+		}
+		value = strings.TrimSpace(value[:idx])
+	}
+
+	// Keywords and function names are case-insensitive; string literals and
+	// quoted identifiers are not
+	var normalized strings.Builder
+	for i := 0; i < len(value); {
+		end := sqlsplit.SkipToken(value, i)
+		if isQuotedToken(value, i, end) {
+			normalized.WriteString(value[i:end])
+		} else {
+			normalized.WriteString(strings.ToLower(value[i:end]))
+		}
+		i = end
+	}
+	return normalized.String()
+}
+
+// castTypePattern matches the type name ending a cast: words, optionally
+// schema-qualified or quoted, with optional precision and array brackets,
+// e.g. "character varying(20)" or public."Mood"[]
+var castTypePattern = regexp.MustCompile(`^\s*(?:"(?:[^"]|"")+"|[\w$]+)(?:\s*\.\s*(?:"(?:[^"]|"")+"|[\w$]+)|\s+[\w$]+)*\s*(?:\(\s*\d+\s*(?:,\s*\d+\s*)?\))?(?:\s+with(?:out)?\s+time\s+zone)?(?:\s*\[\s*\d*\s*\])*\s*$`)
+
+// trailingCast returns the offset of the :: casting the whole of value, the
+// last one outside parentheses, quoted strings and identifiers, when only a
+// type name follows it; otherwise -1
+func trailingCast(value string) int {
+	last, depth := -1, 0
+	for i := 0; i < len(value); {
+		end := sqlsplit.SkipToken(value, i)
+		if !isQuotedToken(value, i, end) {
+			switch {
+			case value[i] == '(':
+				depth++
+			case value[i] == ')':
+				depth--
+			case depth == 0 && strings.HasPrefix(value[i:], "::"):
+				last = i
+				end = i + 2
+			}
+		}
+		i = end
+	}
+	if last < 0 || !castTypePattern.MatchString(value[last+2:]) {
+		return -1
+	}
+	return last
+}
+
+// isQuotedToken reports whether value[start:end], a token from
+// sqlsplit.SkipToken, is a quoted string, quoted identifier or dollar-quoted
+// string
+func isQuotedToken(value string, start, end int) bool {
+	return end-start > 1 && strings.IndexByte(`'"$`, value[start]) >= 0
+}
+
+// diffIndex lists the index properties that cannot be altered in place.
+// The new index is created with its tablespace, so a tablespace move is
+// folded into the recreate.
+func diffIndex(current, desired *database.Index) []string {
+	var changes []string
+	if current.Unique && desired.Unique && current.NullsNotDistinct != desired.NullsNotDistinct {
+		changes = append(changes, "nulls_not_distinct")
+	}
+	if !slices.Equal(current.IncludeColumns, desired.IncludeColumns) {
+		changes = append(changes, "include_columns")
+	}
+
+	// Column lists are only compared when both sides know them; indexes made
+	// up entirely of expressions have no named key columns.
+	currentCols, desiredCols := current.KeyColumns(), desired.KeyColumns()
+	if len(currentCols) == 0 || len(desiredCols) == 0 {
+		return changes
+	}
+	if !slices.Equal(current.Columns, desired.Columns) {
+		return append(changes, "columns")
+	}
+	if !slices.Equal(currentCols, desiredCols) {
+		changes = append(changes, "ordering")
+	}
+	return changes
+}
+
+// NormalizeStorage returns the uppercase storage mode, or "" for the type's
+// default storage
+func NormalizeStorage(storage *string) string {
+	if storage == nil {
+		return ""
+	}
+	normalized := strings.ToUpper(strings.TrimSpace(*storage))
+	if normalized == "DEFAULT" {
+		return ""
+	}
+	return normalized
+}
+
+// typeDefaultStorage is the default storage mode of common PostgreSQL types.
+// Introspection only records a storage mode that differs from the type's
+// default, so a schema file spelling out the default must compare equal.
+var typeDefaultStorage = map[string]string{
+	"text":              "EXTENDED",
+	"varchar":           "EXTENDED",
+	"character varying": "EXTENDED",
+	"char":              "EXTENDED",
+	"character":         "EXTENDED",
+	"bytea":             "EXTENDED",
+	"json":              "EXTENDED",
+	"jsonb":             "EXTENDED",
+	"xml":               "EXTENDED",
+	"numeric":           "MAIN",
+	"decimal":           "MAIN",
+	"smallint":          "PLAIN",
+	"integer":           "PLAIN",
+	"bigint":            "PLAIN",
+	"boolean":           "PLAIN",
+	"uuid":              "PLAIN",
+}
+
+// ColumnStorage returns the column's storage mode, or "" when it uses the
+// default storage of its type
+func ColumnStorage(col database.Column) string {
+	storage := NormalizeStorage(col.Storage)
+	if storage != "" && typeDefaultStorage[baseType(col.LogicalType())] == storage {
+		return ""
+	}
+	return storage
+}
+
+// equalStatisticsTargets compares two column statistics targets, nil meaning
+// the default target
+func equalStatisticsTargets(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
 	return *a == *b
 }
 
+// equalTablespaces compares two tablespace placements in a database whose
+// default tablespace is defaultTablespace
+func equalTablespaces(a, b *string, defaultTablespace string) bool {
+	return normalizeTablespace(a, defaultTablespace) == normalizeTablespace(b, defaultTablespace)
+}
+
+// normalizeTablespace returns the tablespace name, or "" for the database
+// default. Naming the default explicitly is the same as leaving it out, so
+// explicit and implicit placements don't churn.
+func normalizeTablespace(tablespace *string, defaultTablespace string) string {
+	name := database.TablespaceOrDefault(tablespace, defaultTablespace)
+	if name == database.TablespaceOrDefault(nil, defaultTablespace) {
+		return ""
+	}
+	return name
+}
+
+// NormalizeReplicaIdentity returns the REPLICA IDENTITY clause with keywords
+// upper-cased, or "" for the default identity
+func NormalizeReplicaIdentity(identity *string) string {
+	if identity == nil {
+		return ""
+	}
+	fields := strings.Fields(*identity)
+	if len(fields) == 3 && strings.EqualFold(fields[0], "USING") && strings.EqualFold(fields[1], "INDEX") {
+		return "USING INDEX " + fields[2]
+	}
+	normalized := strings.ToUpper(strings.Join(fields, " "))
+	if normalized == database.ReplicaIdentityDefault {
+		return ""
+	}
+	return normalized
+}
+
 // IsEmpty returns true if there are no differences
 func (d *TableDiff) IsEmpty() bool {
 	return len(d.AddedColumns) == 0 &&
@@ -218,12 +796,41 @@ func (d *TableDiff) IsEmpty() bool {
 		len(d.RemovedIndexes) == 0 &&
 		len(d.AddedForeignKeys) == 0 &&
 		len(d.RemovedForeignKeys) == 0 &&
-		!d.RLSChanged
+		len(d.MovedIndexes) == 0 &&
+		len(d.RecreatedIndexes) == 0 &&
+		len(d.RenamedIndexes) == 0 &&
+		len(d.RenamedForeignKeys) == 0 &&
+		!d.RLSChanged &&
+		!d.TablespaceChanged &&
+		!d.ReplicaIdentityChanged &&
+		len(d.SetStorageParameters) == 0 &&
+		len(d.ResetStorageParameters) == 0 &&
+		!d.ColumnOrderChanged
 }
 
 // IsEmpty returns true if there are no differences
 func (d *SchemaDiff) IsEmpty() bool {
 	return len(d.AddedTables) == 0 &&
 		len(d.RemovedTables) == 0 &&
-		len(d.ModifiedTables) == 0
+		len(d.ModifiedTables) == 0 &&
+		len(d.AddedSequences) == 0 &&
+		len(d.RemovedSequences) == 0 &&
+		len(d.ModifiedSequences) == 0
+}
+
+// PartialSchemaWarning describes the risk of diffing schemas captured with
+// different table filters (see database.Schema.TableFilter), or returns "" when
+// both cover the same tables
+func PartialSchemaWarning(current, desired *database.Schema) string {
+	if current == nil || desired == nil || slices.Equal(current.TableFilter, desired.TableFilter) {
+		return ""
+	}
+	switch {
+	case len(desired.TableFilter) == 0:
+		return fmt.Sprintf("The 'from' schema only covers tables matching %s; other tables in the 'to' schema will be planned as new", strings.Join(current.TableFilter, ", "))
+	case len(current.TableFilter) == 0:
+		return fmt.Sprintf("The 'to' schema only covers tables matching %s; other tables in the 'from' schema will be planned as dropped", strings.Join(desired.TableFilter, ", "))
+	default:
+		return fmt.Sprintf("The schemas were introspected with different table filters (%s vs %s); tables outside either filter will be planned as added or dropped", strings.Join(current.TableFilter, ", "), strings.Join(desired.TableFilter, ", "))
+	}
 }
@@ -1,6 +1,9 @@
 package schema
 
 import (
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/lockplane/lockplane/database"
@@ -105,6 +108,88 @@ func TestTableDiff_IsEmpty(t *testing.T) {
 	}
 }
 
+func TestDiffSchemas_TemporalPrecision(t *testing.T) {
+	desired, err := LoadSQLSchemaFromBytes([]byte(`CREATE TABLE events (
+		plain timestamp,
+		ts3 timestamp(3),
+		tstz timestamptz,
+		tstz3 timestamptz(3),
+		t2 time(2),
+		ttz2 timetz(2),
+		iv3 interval(3),
+		ivds interval day to second(3),
+		arr timestamp(3)[]
+	);`), nil)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	// Types as PostgreSQL's format_type reports them
+	introspected := map[string]string{
+		"plain": "timestamp without time zone",
+		"ts3":   "timestamp(3) without time zone",
+		"tstz":  "timestamp with time zone",
+		"tstz3": "timestamp(3) with time zone",
+		"t2":    "time(2) without time zone",
+		"ttz2":  "time(2) with time zone",
+		"iv3":   "interval(3)",
+		"ivds":  "interval day to second(3)",
+		"arr":   "timestamp(3) without time zone[]",
+	}
+	current := &database.Schema{Dialect: database.DialectPostgres, Tables: []database.Table{{Name: "events"}}}
+	for _, col := range desired.Tables[0].Columns {
+		current.Tables[0].Columns = append(current.Tables[0].Columns, database.Column{
+			Name:     col.Name,
+			Type:     introspected[col.Name],
+			Nullable: true,
+		})
+	}
+
+	diff := DiffSchemas(current, desired)
+	if !diff.IsEmpty() {
+		t.Fatalf("Expected no differences, got %+v", diff.ModifiedTables)
+	}
+
+	// A different precision is still a type change
+	current.Tables[0].Columns[3].Type = "timestamp(6) with time zone"
+	current.Tables[0].Columns[7].Type = "interval day to second(6)"
+	diff = DiffSchemas(current, desired)
+	if len(diff.ModifiedTables) != 1 || len(diff.ModifiedTables[0].ModifiedColumns) != 2 {
+		t.Fatalf("Expected precision changes on 2 columns, got %+v", diff.ModifiedTables)
+	}
+	for _, col := range diff.ModifiedTables[0].ModifiedColumns {
+		if !slices.Equal(col.Changes, []string{"type"}) {
+			t.Errorf("column %s: expected a type change, got %v", col.ColumnName, col.Changes)
+		}
+	}
+}
+
+func TestNormalizeColumnType(t *testing.T) {
+	tests := map[string]string{
+		"timestamp":                     "timestamp without time zone",
+		"TIMESTAMP(3)":                  "timestamp(3) without time zone",
+		"timestamptz(3)":                "timestamp(3) with time zone",
+		"timestamp with time zone(3)":   "timestamp(3) with time zone",
+		"timestamp (3)  with time zone": "timestamp(3) with time zone",
+		"timetz":                        "time with time zone",
+		"time(2)[]":                     "time(2) without time zone[]",
+		"interval day to second(3)":     "interval day to second(3)",
+		"INTERVAL DAY TO SECOND (3)":    "interval day to second(3)",
+		"interval (6)":                  "interval(6)",
+		"interval  year to month":       "interval year to month",
+		"interval second(3)[]":          "interval second(3)[]",
+		"varchar(255)":                  "varchar(255)",
+		"pg_catalog.tsvector":           "tsvector",
+		"PG_CATALOG.INT4RANGE[]":        "int4range[]",
+		"public.citext":                 "public.citext",
+	}
+	for input, want := range tests {
+		if got := normalizeColumnType(input); got != want {
+			t.Errorf("normalizeColumnType(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
 func TestDiffSchemas_DetectsRLSChange(t *testing.T) {
 	before := &database.Schema{
 		Tables: []database.Table{
@@ -141,6 +226,151 @@ func TestDiffSchemas_DetectsRLSChange(t *testing.T) {
 	}
 }
 
+func TestDiffSchemas_ReplicaIdentity(t *testing.T) {
+	full := database.ReplicaIdentityFull
+	defaultIdentity := "default"
+	usingIndex := database.ReplicaIdentityUsingIndex("orders_external_id_key")
+	key := database.Index{Name: "orders_external_id_key", Columns: []string{"external_id"}, Unique: true}
+	keyNullsNotDistinct := key
+	keyNullsNotDistinct.NullsNotDistinct = true
+
+	table := func(identity *string, idx database.Index) *database.Schema {
+		return &database.Schema{Tables: []database.Table{{Name: "orders", ReplicaIdentity: identity, Indexes: []database.Index{idx}}}}
+	}
+
+	tests := []struct {
+		name    string
+		before  *database.Schema
+		after   *database.Schema
+		changed bool
+	}{
+		{name: "default to full", before: table(nil, key), after: table(&full, key), changed: true},
+		{name: "explicit default matches unset", before: table(nil, key), after: table(&defaultIdentity, key)},
+		{name: "unchanged index identity", before: table(&usingIndex, key), after: table(&usingIndex, key)},
+		{name: "recreated identity index", before: table(&usingIndex, key), after: table(&usingIndex, keyNullsNotDistinct), changed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffSchemas(tt.before, tt.after)
+			changed := len(diff.ModifiedTables) == 1 && diff.ModifiedTables[0].ReplicaIdentityChanged
+			if changed != tt.changed {
+				t.Errorf("Expected ReplicaIdentityChanged=%v, got %#v", tt.changed, diff)
+			}
+			if !tt.changed && !diff.IsEmpty() {
+				t.Errorf("Expected no diff, got %#v", diff)
+			}
+		})
+	}
+}
+
+func TestDiffSchemas_StorageParameters(t *testing.T) {
+	table := func(params map[string]string) *database.Schema {
+		return &database.Schema{Tables: []database.Table{{Name: "orders", StorageParameters: params}}}
+	}
+
+	diff := DiffSchemas(
+		table(map[string]string{"fillfactor": "100", "autovacuum_enabled": "false", "toast.autovacuum_enabled": "off"}),
+		table(map[string]string{"fillfactor": "70", "autovacuum_enabled": "FALSE", "autovacuum_vacuum_scale_factor": "0.01"}),
+	)
+	if len(diff.ModifiedTables) != 1 {
+		t.Fatalf("Expected 1 modified table, got %#v", diff)
+	}
+	tableDiff := diff.ModifiedTables[0]
+	expectedSet := map[string]string{"fillfactor": "70", "autovacuum_vacuum_scale_factor": "0.01"}
+	if !reflect.DeepEqual(tableDiff.SetStorageParameters, expectedSet) {
+		t.Errorf("Expected set %v, got %v", expectedSet, tableDiff.SetStorageParameters)
+	}
+	if !reflect.DeepEqual(tableDiff.ResetStorageParameters, []string{"toast.autovacuum_enabled"}) {
+		t.Errorf("Expected reset [toast.autovacuum_enabled], got %v", tableDiff.ResetStorageParameters)
+	}
+
+	if diff := DiffSchemas(table(map[string]string{"fillfactor": "70"}), table(map[string]string{"FillFactor": "70"})); !diff.IsEmpty() {
+		t.Errorf("Expected no diff, got %#v", diff)
+	}
+	if diff := DiffSchemas(table(nil), table(map[string]string{})); !diff.IsEmpty() {
+		t.Errorf("Expected no diff, got %#v", diff)
+	}
+}
+
+func TestDiffSchemas_ColumnStorage(t *testing.T) {
+	external := "EXTERNAL"
+	lowerExternal := "external"
+	extended := "EXTENDED"
+
+	table := func(colType string, storage *string) *database.Schema {
+		return &database.Schema{Tables: []database.Table{{
+			Name:    "documents",
+			Columns: []database.Column{{Name: "body", Type: colType, Nullable: true, Storage: storage}},
+		}}}
+	}
+
+	tests := []struct {
+		name    string
+		before  *database.Schema
+		after   *database.Schema
+		changed bool
+	}{
+		{name: "default to external", before: table("text", nil), after: table("text", &external), changed: true},
+		{name: "external to default", before: table("text", &external), after: table("text", nil), changed: true},
+		{name: "case-insensitive", before: table("text", &external), after: table("text", &lowerExternal)},
+		{name: "type default spelled out", before: table("text", nil), after: table("text", &extended)},
+		{name: "non-default for type", before: table("bigint", nil), after: table("bigint", &extended), changed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffSchemas(tt.before, tt.after)
+			changed := len(diff.ModifiedTables) == 1 && len(diff.ModifiedTables[0].ModifiedColumns) == 1 &&
+				diff.ModifiedTables[0].ModifiedColumns[0].Changes[0] == "storage"
+			if changed != tt.changed {
+				t.Errorf("Expected storage changed=%v, got %#v", tt.changed, diff)
+			}
+			if !tt.changed && !diff.IsEmpty() {
+				t.Errorf("Expected no diff, got %#v", diff)
+			}
+		})
+	}
+}
+
+func TestDiffSchemas_ColumnStatistics(t *testing.T) {
+	target := func(n int) *int { return &n }
+
+	table := func(statistics *int) *database.Schema {
+		return &database.Schema{Tables: []database.Table{{
+			Name:    "events",
+			Columns: []database.Column{{Name: "properties", Type: "jsonb", Nullable: true, StatisticsTarget: statistics}},
+		}}}
+	}
+
+	tests := []struct {
+		name    string
+		before  *database.Schema
+		after   *database.Schema
+		changed bool
+	}{
+		{name: "default to 1000", before: table(nil), after: table(target(1000)), changed: true},
+		{name: "1000 to default", before: table(target(1000)), after: table(nil), changed: true},
+		{name: "1000 to 500", before: table(target(1000)), after: table(target(500)), changed: true},
+		{name: "zero is not the default", before: table(nil), after: table(target(0)), changed: true},
+		{name: "unchanged", before: table(target(1000)), after: table(target(1000))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffSchemas(tt.before, tt.after)
+			changed := len(diff.ModifiedTables) == 1 && len(diff.ModifiedTables[0].ModifiedColumns) == 1 &&
+				diff.ModifiedTables[0].ModifiedColumns[0].Changes[0] == "statistics"
+			if changed != tt.changed {
+				t.Errorf("Expected statistics changed=%v, got %#v", tt.changed, diff)
+			}
+			if !tt.changed && !diff.IsEmpty() {
+				t.Errorf("Expected no diff, got %#v", diff)
+			}
+		})
+	}
+}
+
 func TestEqualDefaults(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -190,6 +420,96 @@ func TestEqualDefaults(t *testing.T) {
 			b:        stringPtr(""),
 			expected: true,
 		},
+		{
+			name:     "semicolon in literal matches introspected cast",
+			a:        stringPtr("'a;b'"),
+			b:        stringPtr("'a;b'::text"),
+			expected: true,
+		},
+		{
+			name:     "doubled quote matches introspected cast",
+			a:        stringPtr("'it''s'"),
+			b:        stringPtr("'it''s'::text"),
+			expected: true,
+		},
+		{
+			name:     "cast marker inside literal is kept",
+			a:        stringPtr("'a::b'::text"),
+			b:        stringPtr("'a::b'"),
+			expected: true,
+		},
+		{
+			name:     "cast after escaped quote is stripped",
+			a:        stringPtr("'it''s::'::character varying"),
+			b:        stringPtr("'it''s::'"),
+			expected: true,
+		},
+		{
+			name:     "literal case is significant",
+			a:        stringPtr("'It''s'"),
+			b:        stringPtr("'it''s'"),
+			expected: false,
+		},
+		{
+			name:     "function case is ignored around literals",
+			a:        stringPtr("LOWER('ABC')"),
+			b:        stringPtr("lower('ABC')"),
+			expected: true,
+		},
+		{
+			name:     "unicode literal",
+			a:        stringPtr("'café ☕'::text"),
+			b:        stringPtr("'café ☕'"),
+			expected: true,
+		},
+		{
+			name:     "unicode literal case is significant",
+			a:        stringPtr("'CAFÉ'"),
+			b:        stringPtr("'café'"),
+			expected: false,
+		},
+		{
+			name:     "cast inside a function call is kept",
+			a:        stringPtr("nextval('s'::regclass)"),
+			b:        stringPtr("nextval('t'::regclass)"),
+			expected: false,
+		},
+		{
+			name:     "same sequence default",
+			a:        stringPtr("nextval('s'::regclass)"),
+			b:        stringPtr("NEXTVAL('s'::regclass)"),
+			expected: true,
+		},
+		{
+			name:     "cast inside parentheses is kept",
+			a:        stringPtr("(a::int + b)"),
+			b:        stringPtr("(a::int + c)"),
+			expected: false,
+		},
+		{
+			name:     "cast of an operand is kept",
+			a:        stringPtr("a::int + b"),
+			b:        stringPtr("a::int + c"),
+			expected: false,
+		},
+		{
+			name:     "cast of a parenthesized expression is stripped",
+			a:        stringPtr("(a::int + b)::bigint"),
+			b:        stringPtr("(a::int + b)"),
+			expected: true,
+		},
+		{
+			name:     "cast to a multi-word type with precision is stripped",
+			a:        stringPtr("'2020-01-01'::timestamp(3) with time zone"),
+			b:        stringPtr("'2020-01-01'"),
+			expected: true,
+		},
+		{
+			name:     "cast to a quoted array type is stripped",
+			a:        stringPtr(`'{}'::public."Mood"[]`),
+			b:        stringPtr("'{}'"),
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -214,3 +534,405 @@ func formatPtr(s *string) string {
 	}
 	return "\"" + *s + "\""
 }
+
+func TestDiffSchemas_DetectsTablespaceChanges(t *testing.T) {
+	fast := "fast_ssd"
+	archive := "archive"
+	before := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:    "events",
+				Indexes: []database.Index{{Name: "idx_events_created_at", Columns: []string{"created_at"}}},
+			},
+		},
+	}
+	after := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:       "events",
+				Tablespace: &fast,
+				Indexes:    []database.Index{{Name: "idx_events_created_at", Columns: []string{"created_at"}, Tablespace: &archive}},
+			},
+		},
+	}
+
+	diff := DiffSchemas(before, after)
+	if len(diff.ModifiedTables) != 1 {
+		t.Fatalf("Expected exactly one modified table, got %d", len(diff.ModifiedTables))
+	}
+	tableDiff := diff.ModifiedTables[0]
+	if !tableDiff.TablespaceChanged || tableDiff.Tablespace == nil || *tableDiff.Tablespace != "fast_ssd" {
+		t.Errorf("Expected table tablespace change to fast_ssd, got %#v", tableDiff)
+	}
+	if len(tableDiff.MovedIndexes) != 1 || *tableDiff.MovedIndexes[0].Tablespace != "archive" {
+		t.Errorf("Expected index moved to archive, got %#v", tableDiff.MovedIndexes)
+	}
+	if len(tableDiff.AddedIndexes) != 0 || len(tableDiff.RemovedIndexes) != 0 {
+		t.Errorf("Expected moved index not to be re-created, got %#v", tableDiff)
+	}
+}
+
+func TestDiffSchemas_NullsNotDistinctRecreatesIndex(t *testing.T) {
+	before := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:    "users",
+				Indexes: []database.Index{{Name: "users_email_key", Columns: []string{"email"}, Unique: true}},
+			},
+		},
+	}
+	after := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:    "users",
+				Indexes: []database.Index{{Name: "users_email_key", Columns: []string{"email"}, Unique: true, NullsNotDistinct: true}},
+			},
+		},
+	}
+
+	diff := DiffSchemas(before, after)
+	if len(diff.ModifiedTables) != 1 {
+		t.Fatalf("Expected exactly one modified table, got %d", len(diff.ModifiedTables))
+	}
+	recreated := diff.ModifiedTables[0].RecreatedIndexes
+	if len(recreated) != 1 {
+		t.Fatalf("Expected one recreated index, got %#v", diff.ModifiedTables[0])
+	}
+	if recreated[0].Old.NullsNotDistinct || !recreated[0].New.NullsNotDistinct {
+		t.Errorf("Expected old/new NULLS NOT DISTINCT to be false/true, got %#v", recreated[0])
+	}
+	if len(recreated[0].Changes) != 1 || recreated[0].Changes[0] != "nulls_not_distinct" {
+		t.Errorf("Expected nulls_not_distinct change, got %v", recreated[0].Changes)
+	}
+
+	// Round trip: the same definition on both sides is not a change
+	if diff := DiffSchemas(after, after); !diff.IsEmpty() {
+		t.Errorf("Expected no diff for identical NULLS NOT DISTINCT index, got %#v", diff)
+	}
+}
+
+func TestDiffSchemas_IncludeColumnsRecreateIndex(t *testing.T) {
+	schemaWith := func(include ...string) *database.Schema {
+		return &database.Schema{
+			Tables: []database.Table{
+				{
+					Name:    "orders",
+					Indexes: []database.Index{{Name: "orders_user_id_idx", Columns: []string{"user_id"}, IncludeColumns: include}},
+				},
+			},
+		}
+	}
+
+	diff := DiffSchemas(schemaWith(), schemaWith("status", "total"))
+	if len(diff.ModifiedTables) != 1 {
+		t.Fatalf("Expected exactly one modified table, got %d", len(diff.ModifiedTables))
+	}
+	recreated := diff.ModifiedTables[0].RecreatedIndexes
+	if len(recreated) != 1 {
+		t.Fatalf("Expected one recreated index, got %#v", diff.ModifiedTables[0])
+	}
+	if len(recreated[0].Changes) != 1 || recreated[0].Changes[0] != "include_columns" {
+		t.Errorf("Expected include_columns change, got %v", recreated[0].Changes)
+	}
+
+	// Reordering the included columns changes the definition too
+	if diff := DiffSchemas(schemaWith("status", "total"), schemaWith("total", "status")); diff.IsEmpty() {
+		t.Error("Expected a diff when INCLUDE columns are reordered")
+	}
+
+	// Round trip: the same definition on both sides is not a change
+	if diff := DiffSchemas(schemaWith("status"), schemaWith("status")); !diff.IsEmpty() {
+		t.Errorf("Expected no diff for identical covering index, got %#v", diff)
+	}
+}
+
+func TestDiffSchemas_IndexOrderingRecreatesIndex(t *testing.T) {
+	ascending := database.Index{Name: "idx_events_created_at", Columns: []string{"created_at"}}
+	descending := ascending
+	descending.SetKeyColumns([]database.IndexColumn{{Name: "created_at", Descending: true}})
+	reordered := database.Index{Name: "idx_events_created_at", Columns: []string{"tenant_id", "created_at"}}
+
+	schemaWith := func(idx database.Index) *database.Schema {
+		return &database.Schema{Tables: []database.Table{{Name: "events", Indexes: []database.Index{idx}}}}
+	}
+
+	tests := []struct {
+		name    string
+		before  database.Index
+		after   database.Index
+		changes []string
+	}{
+		{name: "ascending to descending", before: ascending, after: descending, changes: []string{"ordering"}},
+		{name: "columns changed", before: ascending, after: reordered, changes: []string{"columns"}},
+		{name: "unchanged", before: descending, after: descending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffSchemas(schemaWith(tt.before), schemaWith(tt.after))
+			if len(tt.changes) == 0 {
+				if !diff.IsEmpty() {
+					t.Errorf("Expected no diff, got %#v", diff)
+				}
+				return
+			}
+			if len(diff.ModifiedTables) != 1 || len(diff.ModifiedTables[0].RecreatedIndexes) != 1 {
+				t.Fatalf("Expected one recreated index, got %#v", diff)
+			}
+			if got := diff.ModifiedTables[0].RecreatedIndexes[0].Changes; !slices.Equal(got, tt.changes) {
+				t.Errorf("Expected changes %v, got %v", tt.changes, got)
+			}
+		})
+	}
+}
+
+func TestDiffSchemas_DefaultTablespaceDoesNotChurn(t *testing.T) {
+	pgDefault := "pg_default"
+	empty := ""
+	before := &database.Schema{Tables: []database.Table{{Name: "events"}}}
+	after := &database.Schema{Tables: []database.Table{{Name: "events", Tablespace: &pgDefault}}}
+
+	if diff := DiffSchemas(before, after); !diff.IsEmpty() {
+		t.Errorf("Expected pg_default to match the default tablespace, got %#v", diff)
+	}
+
+	after.Tables[0].Tablespace = &empty
+	if diff := DiffSchemas(before, after); !diff.IsEmpty() {
+		t.Errorf("Expected empty tablespace to match the default tablespace, got %#v", diff)
+	}
+}
+
+func TestDiffSchemas_DatabaseDefaultTablespace(t *testing.T) {
+	fast := "fast_ssd"
+	pgDefault := "pg_default"
+	archive := "archive"
+	// The database stores objects without a tablespace on fast_ssd
+	before := &database.Schema{
+		DefaultTablespace: fast,
+		Tables:            []database.Table{{Name: "events"}, {Name: "logs", Tablespace: &archive}},
+	}
+
+	after := &database.Schema{Tables: []database.Table{{Name: "events", Tablespace: &fast}, {Name: "logs", Tablespace: &archive}}}
+	if diff := DiffSchemas(before, after); !diff.IsEmpty() {
+		t.Errorf("Expected the database default to match an unset tablespace, got %#v", diff)
+	}
+
+	after.Tables[0].Tablespace = &pgDefault
+	diff := DiffSchemas(before, after)
+	if len(diff.ModifiedTables) != 1 || !diff.ModifiedTables[0].TablespaceChanged {
+		t.Fatalf("Expected pg_default to differ from the database default, got %#v", diff)
+	}
+
+	after.Tables[0].Tablespace = nil
+	after.Tables[1].Tablespace = nil
+	diff = DiffSchemas(before, after)
+	if len(diff.ModifiedTables) != 1 || diff.ModifiedTables[0].TableName != "logs" || diff.ModifiedTables[0].Tablespace != nil {
+		t.Fatalf("Expected logs to move back to the default tablespace, got %#v", diff)
+	}
+	if diff.DefaultTablespace != fast {
+		t.Errorf("Expected the diff to carry the database default, got %q", diff.DefaultTablespace)
+	}
+
+	hashBefore, err := ComputeSchemaHash(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit := &database.Schema{
+		DefaultTablespace: fast,
+		Tables:            []database.Table{{Name: "events", Tablespace: &fast}, {Name: "logs", Tablespace: &archive}},
+	}
+	if hashExplicit, err := ComputeSchemaHash(explicit); err != nil || hashExplicit != hashBefore {
+		t.Errorf("Expected naming the database default to hash the same, got %s and %s (%v)", hashBefore, hashExplicit, err)
+	}
+}
+
+func TestDiffSchemas_ColumnOrder(t *testing.T) {
+	id := database.Column{Name: "id", Type: "integer", IsPrimaryKey: true}
+	email := database.Column{Name: "email", Type: "text"}
+	name := database.Column{Name: "name", Type: "text"}
+	age := database.Column{Name: "age", Type: "integer"}
+
+	current := &database.Schema{Tables: []database.Table{{Name: "users", Columns: []database.Column{id, email, name}}}}
+	reordered := &database.Schema{Tables: []database.Table{{Name: "users", Columns: []database.Column{id, name, email}}}}
+	appended := &database.Schema{Tables: []database.Table{{Name: "users", Columns: []database.Column{id, email, name, age}}}}
+	inserted := &database.Schema{Tables: []database.Table{{Name: "users", Columns: []database.Column{id, age, email, name}}}}
+
+	// Columns are matched by name unless order is enforced
+	if diff := DiffSchemas(current, reordered); !diff.IsEmpty() {
+		t.Errorf("Expected reordered columns to produce no diff, got %#v", diff)
+	}
+
+	enforce := DiffOptions{EnforceColumnOrder: true}
+	tests := []struct {
+		name    string
+		desired *database.Schema
+		want    bool
+	}{
+		{"reordered", reordered, true},
+		{"appended", appended, false},
+		{"inserted", inserted, true},
+	}
+	for _, tt := range tests {
+		diff := DiffSchemasWithOptions(current, tt.desired, enforce)
+		changed := len(diff.ModifiedTables) == 1 && diff.ModifiedTables[0].ColumnOrderChanged
+		if changed != tt.want {
+			t.Errorf("%s: ColumnOrderChanged = %v, want %v", tt.name, changed, tt.want)
+		}
+	}
+}
+
+func TestDiffSchemas_DeterministicOrder(t *testing.T) {
+	current := &database.Schema{Tables: []database.Table{{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer"}}}}}
+	desired := &database.Schema{Tables: []database.Table{
+		{Name: "users", Columns: []database.Column{
+			{Name: "id", Type: "integer"},
+			{Name: "d", Type: "text"},
+			{Name: "b", Type: "text"},
+			{Name: "c", Type: "text"},
+			{Name: "a", Type: "text"},
+		}},
+		{Name: "zebras"},
+		{Name: "apples"},
+	}}
+
+	for i := 0; i < 10; i++ {
+		diff := DiffSchemas(current, desired)
+		var added []string
+		for _, col := range diff.ModifiedTables[0].AddedColumns {
+			added = append(added, col.Name)
+		}
+		if strings.Join(added, ",") != "d,b,c,a" {
+			t.Fatalf("Expected added columns in declaration order, got %v", added)
+		}
+		if diff.AddedTables[0].Name != "zebras" || diff.AddedTables[1].Name != "apples" {
+			t.Fatalf("Expected added tables in declaration order, got %s, %s", diff.AddedTables[0].Name, diff.AddedTables[1].Name)
+		}
+	}
+}
+
+func TestPartialSchemaWarning(t *testing.T) {
+	full := &database.Schema{}
+	partial := &database.Schema{TableFilter: []string{"users", "billing_*"}}
+	other := &database.Schema{TableFilter: []string{"orders"}}
+
+	if got := PartialSchemaWarning(full, full); got != "" {
+		t.Errorf("expected no warning for two full schemas, got %q", got)
+	}
+	if got := PartialSchemaWarning(partial, &database.Schema{TableFilter: []string{"users", "billing_*"}}); got != "" {
+		t.Errorf("expected no warning for matching filters, got %q", got)
+	}
+	if got := PartialSchemaWarning(partial, full); !strings.Contains(got, "'from' schema only covers tables matching users, billing_*") {
+		t.Errorf("unexpected warning for partial 'from' schema: %q", got)
+	}
+	if got := PartialSchemaWarning(full, partial); !strings.Contains(got, "planned as dropped") {
+		t.Errorf("unexpected warning for partial 'to' schema: %q", got)
+	}
+	if got := PartialSchemaWarning(partial, other); !strings.Contains(got, "different table filters") {
+		t.Errorf("unexpected warning for different filters: %q", got)
+	}
+}
+
+func TestDiffSchemas_ConstraintRenames(t *testing.T) {
+	cascade := "CASCADE"
+	current := &database.Schema{Tables: []database.Table{{
+		Name:    "orders",
+		Columns: []database.Column{{Name: "id", Type: "bigint"}, {Name: "user_id", Type: "bigint"}, {Name: "code", Type: "text"}},
+		Indexes: []database.Index{
+			{Name: "orders_code_key", Columns: []string{"code"}, Unique: true},
+			{Name: "orders_user_idx", Columns: []string{"user_id"}},
+		},
+		ForeignKeys: []database.ForeignKey{
+			{Name: "orders_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: &cascade},
+		},
+	}}}
+	desired := &database.Schema{Tables: []database.Table{{
+		Name:    "orders",
+		Columns: current.Tables[0].Columns,
+		Indexes: []database.Index{
+			{Name: "uq_orders_code", Columns: []string{"code"}, Unique: true},
+			// Same columns but not unique: a real change, not a rename
+			{Name: "idx_orders_user", Columns: []string{"user_id"}, Unique: true},
+		},
+		ForeignKeys: []database.ForeignKey{
+			{Name: "fk_orders_user", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: &cascade},
+		},
+	}}}
+
+	diff := DiffSchemas(current, desired)
+	if len(diff.ModifiedTables) != 1 {
+		t.Fatalf("Expected 1 modified table, got %d", len(diff.ModifiedTables))
+	}
+	tableDiff := diff.ModifiedTables[0]
+
+	if len(tableDiff.RenamedIndexes) != 1 || tableDiff.RenamedIndexes[0].Old.Name != "orders_code_key" || tableDiff.RenamedIndexes[0].New.Name != "uq_orders_code" {
+		t.Errorf("Expected orders_code_key to be renamed to uq_orders_code, got %+v", tableDiff.RenamedIndexes)
+	}
+	want := []Rename{{OldName: "orders_user_id_fkey", NewName: "fk_orders_user"}}
+	if !reflect.DeepEqual(tableDiff.RenamedForeignKeys, want) {
+		t.Errorf("Expected foreign key renames %+v, got %+v", want, tableDiff.RenamedForeignKeys)
+	}
+	if len(tableDiff.AddedForeignKeys) != 0 || len(tableDiff.RemovedForeignKeys) != 0 {
+		t.Errorf("Expected no foreign keys to be dropped or added, got +%v -%v", tableDiff.AddedForeignKeys, tableDiff.RemovedForeignKeys)
+	}
+	if len(tableDiff.AddedIndexes) != 1 || tableDiff.AddedIndexes[0].Name != "idx_orders_user" {
+		t.Errorf("Expected idx_orders_user to be added, got %+v", tableDiff.AddedIndexes)
+	}
+	if len(tableDiff.RemovedIndexes) != 1 || tableDiff.RemovedIndexes[0].Name != "orders_user_idx" {
+		t.Errorf("Expected orders_user_idx to be removed, got %+v", tableDiff.RemovedIndexes)
+	}
+
+	// A different referential action is not a rename
+	noAction := "NO ACTION"
+	desired.Tables[0].ForeignKeys[0].OnDelete = &noAction
+	tableDiff = DiffSchemas(current, desired).ModifiedTables[0]
+	if len(tableDiff.RenamedForeignKeys) != 0 || len(tableDiff.AddedForeignKeys) != 1 {
+		t.Errorf("Expected the foreign key to be re-created, got renames %+v", tableDiff.RenamedForeignKeys)
+	}
+	desired.Tables[0].ForeignKeys[0].OnDelete = &cascade
+
+	// Ignoring names leaves only the real index change
+	tableDiff = DiffSchemasWithOptions(current, desired, DiffOptions{IgnoreConstraintNames: true}).ModifiedTables[0]
+	if len(tableDiff.RenamedIndexes) != 0 || len(tableDiff.RenamedForeignKeys) != 0 {
+		t.Errorf("Expected no renames when ignoring names, got %+v %+v", tableDiff.RenamedIndexes, tableDiff.RenamedForeignKeys)
+	}
+	if len(tableDiff.AddedIndexes) != 1 || len(tableDiff.RemovedIndexes) != 1 || len(tableDiff.AddedForeignKeys) != 0 {
+		t.Errorf("Expected only the non-unique index to change, got %+v", tableDiff)
+	}
+
+	desired.Tables[0].Indexes[1].Unique = false
+	if diff := DiffSchemasWithOptions(current, desired, DiffOptions{IgnoreConstraintNames: true}); !diff.IsEmpty() {
+		t.Errorf("Expected no differences when only names differ, got %+v", diff)
+	}
+}
+
+func TestDiffSchemas_SameTableNameInTwoSchemas(t *testing.T) {
+	current := &database.Schema{Tables: []database.Table{
+		{Name: "users", Schema: "public", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "users", Schema: "tenant_a", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+	}}
+	desired := &database.Schema{Tables: []database.Table{
+		{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "users", Schema: "tenant_a", Columns: []database.Column{
+			{Name: "id", Type: "integer"},
+			{Name: "email", Type: "text", Nullable: true},
+		}},
+	}}
+
+	diff := DiffSchemas(current, desired)
+	if len(diff.AddedTables) != 0 || len(diff.RemovedTables) != 0 {
+		t.Fatalf("Expected both tables matched, got added %+v removed %+v", diff.AddedTables, diff.RemovedTables)
+	}
+	if len(diff.ModifiedTables) != 1 || diff.ModifiedTables[0].TableName != "tenant_a.users" {
+		t.Fatalf("Expected only tenant_a.users modified, got %+v", diff.ModifiedTables)
+	}
+
+	// A table moved to another schema is dropped and created
+	desired.Tables[1].Schema = "tenant_b"
+	diff = DiffSchemas(current, desired)
+	if len(diff.AddedTables) != 1 || diff.AddedTables[0].QualifiedName() != "tenant_b.users" {
+		t.Errorf("Expected tenant_b.users added, got %+v", diff.AddedTables)
+	}
+	if len(diff.RemovedTables) != 1 || diff.RemovedTables[0].QualifiedName() != "tenant_a.users" {
+		t.Errorf("Expected tenant_a.users removed, got %+v", diff.RemovedTables)
+	}
+}
@@ -0,0 +1,339 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/parser"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// Kinds of schema objects checked for duplicate definitions
+const (
+	ObjectKindTable      = "table"
+	ObjectKindIndex      = "index"
+	ObjectKindConstraint = "constraint"
+)
+
+// SourceFile is the content of one schema file
+type SourceFile struct {
+	Path    string
+	Content string
+	// Expansion maps Content back to the file as written when it has been
+	// through template expansion; nil when Content is the file as written
+	Expansion *ExpandedSource
+}
+
+// SourceLocation points at a position in a schema file (1-based)
+type SourceLocation = database.SourceLocation
+
+// DuplicateDefinitionError reports a schema object defined more than once
+type DuplicateDefinitionError struct {
+	Kind      string         `json:"kind"`
+	Name      string         `json:"name"`
+	First     SourceLocation `json:"first"`
+	Duplicate SourceLocation `json:"duplicate"`
+	// Identical is set when both definitions are the same apart from
+	// formatting and comments, as after a merge that kept both copies
+	Identical bool `json:"identical"`
+}
+
+func (e *DuplicateDefinitionError) Error() string {
+	if e.Identical {
+		return fmt.Sprintf("%s %q is defined identically more than once: %s and %s", e.Kind, e.Name, e.First, e.Duplicate)
+	}
+	return fmt.Sprintf("%s %q is defined more than once: %s and %s", e.Kind, e.Name, e.First, e.Duplicate)
+}
+
+// FindDuplicateDefinitions reports tables, indexes and named constraints that
+// are defined more than once across the given files. Each later definition is
+// reported against the first one, and marked Identical when the two only
+// differ in formatting. Files that do not parse as PostgreSQL are skipped; the
+// schema parser reports their errors.
+func FindDuplicateDefinitions(files []SourceFile) []*DuplicateDefinitionError {
+	seen := make(map[string]definition)
+	var duplicates []*DuplicateDefinitionError
+
+	record := func(key, name string, def definition) {
+		mapKey := def.kind + "\x00" + key
+		first, ok := seen[mapKey]
+		if !ok {
+			seen[mapKey] = def
+			return
+		}
+		firstSQL, dupSQL := first.canonical(), def.canonical()
+		duplicates = append(duplicates, &DuplicateDefinitionError{
+			Kind:      def.kind,
+			Name:      name,
+			First:     first.location,
+			Duplicate: def.location,
+			Identical: firstSQL != "" && firstSQL == dupSQL,
+		})
+	}
+
+	for _, file := range files {
+		walkDefinitions(file, func(def definition) {
+			switch def.kind {
+			case ObjectKindTable:
+				record(def.table, def.table, def)
+			case ObjectKindIndex:
+				// Index names share a namespace across the whole schema
+				index := qualifiedName(def.schema, def.name)
+				record(index, index, def)
+			case ObjectKindConstraint:
+				if def.name == "" {
+					return
+				}
+				record(def.table+"."+def.name, def.name, def)
+			}
+		})
+	}
+
+	return duplicates
+}
+
+// ConflictingDuplicates returns the duplicates whose definitions differ
+func ConflictingDuplicates(duplicates []*DuplicateDefinitionError) []*DuplicateDefinitionError {
+	var conflicting []*DuplicateDefinitionError
+	for _, d := range duplicates {
+		if !d.Identical {
+			conflicting = append(conflicting, d)
+		}
+	}
+	return conflicting
+}
+
+// objectKindColumn marks column definitions found by walkDefinitions
+const objectKindColumn = "column"
+
+// definition is a schema object found in a schema file
+type definition struct {
+	kind     string
+	schema   string
+	table    string // schema-qualified table the object belongs to
+	name     string // object name; empty for tables and unnamed constraints
+	foreign  bool   // constraint is a foreign key
+	location SourceLocation
+	// canonical returns the definition as deparsed SQL, which ignores
+	// formatting and comments; nil for columns
+	canonical func() string
+}
+
+// walkDefinitions calls visit for every table, column, named index and
+// constraint defined in file, in file order. Temporary tables are not
+// definitions, and files that do not parse as PostgreSQL are skipped.
+func walkDefinitions(file SourceFile, visit func(definition)) {
+	tree, err := pg_query.Parse(file.Content)
+	if err != nil {
+		return
+	}
+
+	locate := func(offset int32) SourceLocation {
+		start := skipToToken(file.Content, int(offset))
+		if file.Expansion != nil {
+			return file.Expansion.Location(start)
+		}
+		return lineColumn(file.Path, file.Content, start)
+	}
+	// Constraints are compared as ALTER TABLE ... ADD CONSTRAINT, prefixed
+	// with the column of a column constraint
+	constraint := func(relation *pg_query.RangeVar, columnName string, c *pg_query.Constraint) {
+		visit(definition{
+			kind:     ObjectKindConstraint,
+			schema:   relation.Schemaname,
+			table:    qualifiedName(relation.Schemaname, relation.Relname),
+			name:     c.Conname,
+			foreign:  c.Contype == pg_query.ConstrType_CONSTR_FOREIGN,
+			location: locate(c.Location),
+			canonical: func() string {
+				sql := deparseStatement(&pg_query.Node{Node: &pg_query.Node_AlterTableStmt{AlterTableStmt: &pg_query.AlterTableStmt{
+					Relation: relation,
+					Objtype:  pg_query.ObjectType_OBJECT_TABLE,
+					Cmds: []*pg_query.Node{{Node: &pg_query.Node_AlterTableCmd{AlterTableCmd: &pg_query.AlterTableCmd{
+						Subtype: pg_query.AlterTableType_AT_AddConstraint,
+						Def:     &pg_query.Node{Node: &pg_query.Node_Constraint{Constraint: c}},
+					}}}},
+				}}})
+				if sql == "" || columnName == "" {
+					return sql
+				}
+				return columnName + ": " + sql
+			},
+		})
+	}
+	column := func(relation *pg_query.RangeVar, col *pg_query.ColumnDef) {
+		visit(definition{
+			kind:     objectKindColumn,
+			schema:   relation.Schemaname,
+			table:    qualifiedName(relation.Schemaname, relation.Relname),
+			name:     col.Colname,
+			location: locate(col.Location),
+		})
+		for _, cn := range col.Constraints {
+			if c := cn.GetConstraint(); c != nil {
+				constraint(relation, col.Colname, c)
+			}
+		}
+	}
+	statement := func(stmt *pg_query.Node) func() string {
+		return func() string { return deparseStatement(stmt) }
+	}
+
+	temps := parser.NewTemporaryTables()
+	for _, raw := range tree.Stmts {
+		if raw.Stmt == nil || temps.Skip(raw.Stmt) {
+			continue
+		}
+
+		switch node := raw.Stmt.Node.(type) {
+		case *pg_query.Node_CreateStmt:
+			stmt := node.CreateStmt
+			if stmt.Relation == nil {
+				continue
+			}
+			schemaName := stmt.Relation.Schemaname
+			table := qualifiedName(schemaName, stmt.Relation.Relname)
+			visit(definition{kind: ObjectKindTable, schema: schemaName, table: table, location: locate(stmt.Relation.Location), canonical: statement(raw.Stmt)})
+
+			for _, elt := range stmt.TableElts {
+				if c := elt.GetConstraint(); c != nil {
+					constraint(stmt.Relation, "", c)
+				}
+				if col := elt.GetColumnDef(); col != nil {
+					column(stmt.Relation, col)
+				}
+			}
+
+		case *pg_query.Node_IndexStmt:
+			stmt := node.IndexStmt
+			if stmt.Idxname == "" || stmt.Relation == nil {
+				continue
+			}
+			schemaName := stmt.Relation.Schemaname
+			visit(definition{
+				kind:      ObjectKindIndex,
+				schema:    schemaName,
+				table:     qualifiedName(schemaName, stmt.Relation.Relname),
+				name:      stmt.Idxname,
+				location:  locate(raw.StmtLocation),
+				canonical: statement(raw.Stmt),
+			})
+
+		case *pg_query.Node_AlterTableStmt:
+			stmt := node.AlterTableStmt
+			if stmt.Relation == nil {
+				continue
+			}
+			for _, cmdNode := range stmt.Cmds {
+				cmd := cmdNode.GetAlterTableCmd()
+				if cmd == nil {
+					continue
+				}
+				switch cmd.Subtype {
+				case pg_query.AlterTableType_AT_AddConstraint:
+					if c := cmd.GetDef().GetConstraint(); c != nil {
+						constraint(stmt.Relation, "", c)
+					}
+				case pg_query.AlterTableType_AT_AddColumn:
+					if col := cmd.GetDef().GetColumnDef(); col != nil {
+						column(stmt.Relation, col)
+					}
+				}
+			}
+		}
+	}
+}
+
+// dropRepeatedDefinitions removes the later copies of tables, indexes and
+// foreign keys that identical duplicate definitions added to schema
+func dropRepeatedDefinitions(schema *database.Schema) {
+	seenTables := make(map[string]bool)
+	tables := schema.Tables[:0]
+	for _, table := range schema.Tables {
+		key := qualifiedName(table.Schema, table.Name)
+		if seenTables[key] {
+			continue
+		}
+		seenTables[key] = true
+
+		seenIndexes := make(map[string]bool)
+		indexes := table.Indexes[:0]
+		for _, idx := range table.Indexes {
+			if !seenIndexes[idx.Name] {
+				seenIndexes[idx.Name] = true
+				indexes = append(indexes, idx)
+			}
+		}
+		table.Indexes = indexes
+
+		seenForeignKeys := make(map[string]bool)
+		foreignKeys := table.ForeignKeys[:0]
+		for _, fk := range table.ForeignKeys {
+			if fk.Name == "" || !seenForeignKeys[fk.Name] {
+				seenForeignKeys[fk.Name] = true
+				foreignKeys = append(foreignKeys, fk)
+			}
+		}
+		table.ForeignKeys = foreignKeys
+
+		tables = append(tables, table)
+	}
+	schema.Tables = tables
+}
+
+// duplicateDefinitionsError combines the duplicates into a single error
+func duplicateDefinitionsError(duplicates []*DuplicateDefinitionError) error {
+	errs := make([]error, 0, len(duplicates))
+	for _, d := range duplicates {
+		errs = append(errs, d)
+	}
+	return errors.Join(errs...)
+}
+
+// deparseStatement returns stmt as SQL, or "" when it can't be deparsed
+func deparseStatement(stmt *pg_query.Node) string {
+	sql, err := pg_query.Deparse(&pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: stmt}}})
+	if err != nil {
+		return ""
+	}
+	return sql
+}
+
+func qualifiedName(schemaName, name string) string {
+	if schemaName == "" {
+		return name
+	}
+	return schemaName + "." + name
+}
+
+// skipToToken advances offset past whitespace and comments that precede a statement
+func skipToToken(content string, offset int) int {
+	if offset < 0 || offset > len(content) {
+		offset = 0
+	}
+	for offset < len(content) {
+		rest := content[offset:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n':
+			offset++
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			offset += end + 1
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				offset = len(content)
+			} else {
+				offset += end + 4
+			}
+		default:
+			return offset
+		}
+	}
+	return offset
+}
@@ -0,0 +1,196 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicateDefinitions(t *testing.T) {
+	files := []SourceFile{
+		{
+			Path: "001_users.lp.sql",
+			Content: `CREATE TABLE users (
+  id BIGINT PRIMARY KEY,
+  email TEXT CONSTRAINT users_email_key UNIQUE
+);
+
+CREATE INDEX users_email_idx ON users (email);
+`,
+		},
+		{
+			Path: "002_copy.lp.sql",
+			Content: `-- copied from 001
+CREATE TABLE users (
+  id BIGINT PRIMARY KEY
+);
+CREATE INDEX users_email_idx ON users (email);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+`,
+		},
+		{
+			Path:    "003_broken.lp.sql",
+			Content: "CREATE TABLE users (",
+		},
+	}
+
+	duplicates := FindDuplicateDefinitions(files)
+	if len(duplicates) != 3 {
+		t.Fatalf("expected 3 duplicates, got %d: %v", len(duplicates), duplicates)
+	}
+
+	table := duplicates[0]
+	if table.Kind != ObjectKindTable || table.Name != "users" {
+		t.Errorf("expected duplicate table users, got %s %q", table.Kind, table.Name)
+	}
+	if table.First != (SourceLocation{File: "001_users.lp.sql", Line: 1, Column: 14}) {
+		t.Errorf("unexpected first location: %s", table.First)
+	}
+	if table.Duplicate != (SourceLocation{File: "002_copy.lp.sql", Line: 2, Column: 14}) {
+		t.Errorf("unexpected duplicate location: %s", table.Duplicate)
+	}
+	if table.Identical {
+		t.Error("expected the table copies to conflict")
+	}
+
+	index := duplicates[1]
+	if index.Kind != ObjectKindIndex || index.Name != "users_email_idx" || index.Duplicate.Line != 5 || index.Duplicate.Column != 1 {
+		t.Errorf("unexpected index duplicate: %+v", index)
+	}
+	if !index.Identical {
+		t.Error("expected the index copies to be identical")
+	}
+
+	constraint := duplicates[2]
+	if constraint.Kind != ObjectKindConstraint || constraint.Name != "users_email_key" || constraint.First.Line != 3 || constraint.Duplicate.Line != 6 {
+		t.Errorf("unexpected constraint duplicate: %+v", constraint)
+	}
+}
+
+func TestFindDuplicateDefinitions_DistinctObjects(t *testing.T) {
+	files := []SourceFile{
+		{Path: "a.lp.sql", Content: "CREATE TABLE users (id BIGINT PRIMARY KEY);\nCREATE TABLE audit.users (id BIGINT PRIMARY KEY);"},
+		{Path: "b.lp.sql", Content: "CREATE TABLE posts (id BIGINT, CONSTRAINT pk PRIMARY KEY (id));\nCREATE TABLE comments (id BIGINT, CONSTRAINT pk PRIMARY KEY (id));"},
+	}
+
+	if duplicates := FindDuplicateDefinitions(files); len(duplicates) != 0 {
+		t.Errorf("expected no duplicates, got %v", duplicates)
+	}
+}
+
+func TestLoadSchema_DirectoryWithDuplicateTable(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"001_users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\n",
+		"002_users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write schema file: %v", err)
+		}
+	}
+
+	_, err := LoadSchema(dir)
+	if err == nil {
+		t.Fatal("expected error for duplicate table definition")
+	}
+
+	var dup *DuplicateDefinitionError
+	if !errors.As(err, &dup) {
+		t.Fatalf("expected DuplicateDefinitionError, got %v", err)
+	}
+	if dup.First.File != filepath.Join(dir, "001_users.lp.sql") || dup.Duplicate.File != filepath.Join(dir, "002_users.lp.sql") {
+		t.Errorf("expected both file locations, got %s and %s", dup.First, dup.Duplicate)
+	}
+	if !strings.Contains(err.Error(), "001_users.lp.sql:1:14") || !strings.Contains(err.Error(), "002_users.lp.sql:1:14") {
+		t.Errorf("expected error message to include both locations, got %v", err)
+	}
+}
+
+func TestFindDuplicateDefinitions_Identical(t *testing.T) {
+	files := []SourceFile{
+		{Path: "a.lp.sql", Content: `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT NOT NULL);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+CREATE TABLE posts (id BIGINT, author BIGINT CONSTRAINT posts_author_fkey REFERENCES users (id));`},
+		{Path: "b.lp.sql", Content: `-- merged twice
+create table users (
+  id bigint primary key,
+  email text not null -- login
+);
+alter table users add constraint users_email_key unique ( email );
+CREATE TABLE comments (id BIGINT, posted_by BIGINT CONSTRAINT posts_author_fkey REFERENCES users (id));`},
+	}
+
+	duplicates := FindDuplicateDefinitions(files)
+	if len(duplicates) != 2 {
+		t.Fatalf("expected 2 duplicates, got %d: %v", len(duplicates), duplicates)
+	}
+	for _, dup := range duplicates {
+		if !dup.Identical {
+			t.Errorf("expected %s %q to be an identical duplicate", dup.Kind, dup.Name)
+		}
+	}
+	if !strings.Contains(duplicates[0].Error(), "defined identically") {
+		t.Errorf("expected the error to say the copies are identical, got %v", duplicates[0])
+	}
+
+	// The same constraint name on different columns is a conflict
+	files[1].Content = "CREATE TABLE posts (id BIGINT, editor BIGINT CONSTRAINT posts_author_fkey REFERENCES users (id));"
+	duplicates = FindDuplicateDefinitions(files)
+	if len(duplicates) != 2 || duplicates[1].Identical {
+		t.Errorf("expected a conflicting constraint duplicate, got %v", duplicates)
+	}
+}
+
+func TestLoadSchema_DirectoryWithIdenticalDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	users := "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);\nCREATE INDEX users_email_idx ON users (email);\n"
+	posts := "CREATE TABLE posts (id BIGINT PRIMARY KEY, user_id BIGINT);\nALTER TABLE posts ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);\n"
+	for name, content := range map[string]string{
+		"001_users.lp.sql":  users,
+		"002_posts.lp.sql":  posts,
+		"003_merged.lp.sql": strings.ToLower(users) + "\n" + posts,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write schema file: %v", err)
+		}
+	}
+
+	if _, err := LoadSchema(dir); err == nil || !strings.Contains(err.Error(), "defined identically") {
+		t.Fatalf("expected identical duplicates to be rejected by default, got %v", err)
+	}
+
+	schema, err := LoadSchemaWithOptions(dir, &SchemaLoadOptions{AllowIdenticalDuplicates: true})
+	if err != nil {
+		t.Fatalf("Failed to load schema with identical duplicates: %v", err)
+	}
+	if len(schema.Tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(schema.Tables))
+	}
+	for _, table := range schema.Tables {
+		if table.Source == nil || strings.HasSuffix(table.Source.File, "003_merged.lp.sql") {
+			t.Errorf("expected table %s to point at its first definition, got %v", table.Name, table.Source)
+		}
+		switch table.Name {
+		case "users":
+			if len(table.Indexes) != 1 {
+				t.Errorf("expected 1 index on users, got %v", table.Indexes)
+			}
+		case "posts":
+			if len(table.ForeignKeys) != 1 {
+				t.Errorf("expected 1 foreign key on posts, got %v", table.ForeignKeys)
+			}
+		}
+	}
+
+	// Conflicting copies are an error even when identical ones are allowed
+	conflict := "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT NOT NULL);\n"
+	if err := os.WriteFile(filepath.Join(dir, "004_conflict.lp.sql"), []byte(conflict), 0o644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+	_, err = LoadSchemaWithOptions(dir, &SchemaLoadOptions{AllowIdenticalDuplicates: true})
+	if err == nil || !strings.Contains(err.Error(), "conflicting definitions") || !strings.Contains(err.Error(), "004_conflict.lp.sql:1:14") {
+		t.Errorf("expected a conflicting definition error, got %v", err)
+	}
+}
@@ -11,11 +11,29 @@ import (
 	"github.com/lockplane/lockplane/database"
 )
 
+// SchemaHashVersion identifies the canonical serialization used by
+// ComputeSchemaHash. Bump it whenever the serialization changes.
+const SchemaHashVersion = 2
+
 // ComputeSchemaHash generates a deterministic hash of a schema.
 // The hash represents the complete state of the schema including all tables,
 // columns, indexes, and foreign keys. Any change to the schema will produce
-// a different hash.
+// a different hash, while semantically equal schemas hash the same regardless
+// of table or column order, type spelling, or default formatting.
 func ComputeSchemaHash(schema *database.Schema) (string, error) {
+	canonical, err := canonicalizeSchema(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize schema: %w", err)
+	}
+
+	hash := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// ComputeLegacySchemaHash generates the version 1 schema hash.
+// Plans generated before SchemaHashVersion 2 carry this hash, so it is still
+// accepted during verification.
+func ComputeLegacySchemaHash(schema *database.Schema) (string, error) {
 	// Create a normalized representation for hashing
 	normalized, err := normalizeSchema(schema)
 	if err != nil {
@@ -27,7 +45,244 @@ func ComputeSchemaHash(schema *database.Schema) (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
-// normalizeSchema creates a canonical string representation of the schema
+// SchemaHashMatches reports whether expected matches either the current or the
+// legacy hash of schema.
+func SchemaHashMatches(schema *database.Schema, expected string) (bool, error) {
+	current, err := ComputeSchemaHash(schema)
+	if err != nil {
+		return false, err
+	}
+	if current == expected {
+		return true, nil
+	}
+
+	legacy, err := ComputeLegacySchemaHash(schema)
+	if err != nil {
+		return false, err
+	}
+	return legacy == expected, nil
+}
+
+type canonicalSchema struct {
+	Version int              `json:"version"`
+	Tables  []canonicalTable `json:"tables"`
+	// Omitted when there are none so hashes of existing schemas are unchanged
+	Sequences []canonicalSequence `json:"sequences,omitempty"`
+}
+
+// canonicalSequence holds a sequence's effective options. Its current value
+// and any requested restart aren't part of the schema.
+type canonicalSequence struct {
+	Schema    string `json:"schema,omitempty"`
+	Name      string `json:"name"`
+	DataType  string `json:"data_type"`
+	Increment int64  `json:"increment"`
+	MinValue  int64  `json:"min_value"`
+	MaxValue  int64  `json:"max_value"`
+	Start     int64  `json:"start"`
+	Cache     int64  `json:"cache"`
+	Cycle     bool   `json:"cycle"`
+	OwnedBy   string `json:"owned_by,omitempty"`
+}
+
+type canonicalTable struct {
+	Schema      string                `json:"schema,omitempty"`
+	Name        string                `json:"name"`
+	Columns     []canonicalColumn     `json:"columns"`
+	Indexes     []canonicalIndex      `json:"indexes,omitempty"`
+	ForeignKeys []canonicalForeignKey `json:"foreign_keys,omitempty"`
+	Tablespace  string                `json:"tablespace,omitempty"`
+	// Omitted for the default identity so hashes of existing schemas are unchanged
+	ReplicaIdentity string `json:"replica_identity,omitempty"`
+	// Omitted when no parameters are set so hashes of existing schemas are unchanged
+	StorageParameters map[string]string `json:"storage_parameters,omitempty"`
+}
+
+type canonicalColumn struct {
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Nullable     bool    `json:"nullable"`
+	IsPrimaryKey bool    `json:"is_primary_key"`
+	Default      *string `json:"default,omitempty"`
+	// Omitted for the type's default storage so hashes of existing schemas are unchanged
+	Storage string `json:"storage,omitempty"`
+	// Omitted for the default statistics target, for the same reason
+	StatisticsTarget *int `json:"statistics_target,omitempty"`
+}
+
+type canonicalIndex struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	Unique     bool     `json:"unique"`
+	Tablespace string   `json:"tablespace,omitempty"`
+	// Omitted when false so hashes of existing schemas are unchanged
+	NullsNotDistinct bool `json:"nulls_not_distinct,omitempty"`
+	// Per-column ordering, e.g. "created_at DESC"; omitted for default order
+	Ordering []string `json:"ordering,omitempty"`
+	// Non-key INCLUDE columns; omitted when there are none
+	IncludeColumns []string `json:"include_columns,omitempty"`
+}
+
+type canonicalForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// canonicalizeSchema serializes a schema for hashing. Tables are sorted by
+// (schema, name); columns, indexes and foreign keys by name. Column types use
+// the logical type, defaults go through the same normalization as the diff
+// comparator, and dialect/raw metadata is left out.
+func canonicalizeSchema(schema *database.Schema) (string, error) {
+	result := canonicalSchema{Version: SchemaHashVersion, Tables: []canonicalTable{}}
+
+	if schema != nil {
+		for _, table := range schema.Tables {
+			result.Tables = append(result.Tables, canonicalizeTable(table, schema.DefaultTablespace))
+		}
+		// Sequences of serial columns are part of their column, and schema
+		// files don't declare them
+		for _, seq := range schema.Sequences {
+			if !OwnedBySerialColumn(schema, seq) {
+				result.Sequences = append(result.Sequences, canonicalizeSequence(seq))
+			}
+		}
+	}
+
+	sort.Slice(result.Tables, func(i, j int) bool {
+		if result.Tables[i].Schema != result.Tables[j].Schema {
+			return result.Tables[i].Schema < result.Tables[j].Schema
+		}
+		return result.Tables[i].Name < result.Tables[j].Name
+	})
+
+	sort.Slice(result.Sequences, func(i, j int) bool {
+		if result.Sequences[i].Schema != result.Sequences[j].Schema {
+			return result.Sequences[i].Schema < result.Sequences[j].Schema
+		}
+		return result.Sequences[i].Name < result.Sequences[j].Name
+	})
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal canonical schema: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
+
+func canonicalizeSequence(seq database.Sequence) canonicalSequence {
+	settings := EffectiveSequenceSettings(seq)
+	result := canonicalSequence{
+		Name:      seq.Name,
+		DataType:  settings.DataType,
+		Increment: settings.Increment,
+		MinValue:  settings.MinValue,
+		MaxValue:  settings.MaxValue,
+		Start:     settings.Start,
+		Cache:     settings.Cache,
+		Cycle:     settings.Cycle,
+		OwnedBy:   NormalizeSequenceOwner(seq.OwnedBy),
+	}
+	if seq.Schema != "public" {
+		result.Schema = seq.Schema
+	}
+	return result
+}
+
+func canonicalizeTable(table database.Table, defaultTablespace string) canonicalTable {
+	result := canonicalTable{
+		Name:            table.Name,
+		Columns:         make([]canonicalColumn, 0, len(table.Columns)),
+		Tablespace:      normalizeTablespace(table.Tablespace, defaultTablespace),
+		ReplicaIdentity: NormalizeReplicaIdentity(table.ReplicaIdentity),
+	}
+	if len(table.StorageParameters) > 0 {
+		// Values compare case-insensitively in the diff
+		result.StorageParameters = normalizeStorageParameters(table.StorageParameters)
+		for name, value := range result.StorageParameters {
+			result.StorageParameters[name] = strings.ToLower(value)
+		}
+	}
+
+	// "public" is the default schema, so treat it the same as an unqualified table
+	if table.Schema != "public" {
+		result.Schema = table.Schema
+	}
+
+	for _, col := range table.Columns {
+		canonical := canonicalColumn{
+			Name:             col.Name,
+			Type:             col.LogicalType(),
+			Nullable:         col.Nullable,
+			IsPrimaryKey:     col.IsPrimaryKey,
+			Storage:          ColumnStorage(col),
+			StatisticsTarget: col.StatisticsTarget,
+		}
+		if col.Default != nil {
+			normalized := normalizeDefaultValue(*col.Default)
+			canonical.Default = &normalized
+		}
+		result.Columns = append(result.Columns, canonical)
+	}
+	sort.Slice(result.Columns, func(i, j int) bool {
+		return result.Columns[i].Name < result.Columns[j].Name
+	})
+
+	for _, idx := range table.Indexes {
+		canonical := canonicalIndex{
+			Name:             idx.Name,
+			Columns:          idx.Columns,
+			Unique:           idx.Unique,
+			Tablespace:       normalizeTablespace(idx.Tablespace, defaultTablespace),
+			NullsNotDistinct: idx.NullsNotDistinct,
+			IncludeColumns:   idx.IncludeColumns,
+		}
+		if idx.HasCustomOrdering() {
+			for _, col := range idx.KeyColumns() {
+				canonical.Ordering = append(canonical.Ordering, col.SQL())
+			}
+		}
+		result.Indexes = append(result.Indexes, canonical)
+	}
+	sort.Slice(result.Indexes, func(i, j int) bool {
+		return result.Indexes[i].Name < result.Indexes[j].Name
+	})
+
+	for _, fk := range table.ForeignKeys {
+		result.ForeignKeys = append(result.ForeignKeys, canonicalForeignKey{
+			Name:              fk.Name,
+			Columns:           fk.Columns,
+			ReferencedTable:   fk.ReferencedTable,
+			ReferencedColumns: fk.ReferencedColumns,
+			OnDelete:          canonicalFKAction(fk.OnDelete),
+			OnUpdate:          canonicalFKAction(fk.OnUpdate),
+		})
+	}
+	sort.Slice(result.ForeignKeys, func(i, j int) bool {
+		return result.ForeignKeys[i].Name < result.ForeignKeys[j].Name
+	})
+
+	return result
+}
+
+// canonicalFKAction upper-cases a referential action. NO ACTION is the
+// default, so it is treated the same as an unset action.
+func canonicalFKAction(action *string) string {
+	if action == nil {
+		return ""
+	}
+	normalized := strings.ToUpper(strings.TrimSpace(*action))
+	if normalized == "NO ACTION" {
+		return ""
+	}
+	return normalized
+}
+
+// normalizeSchema creates the version 1 string representation of the schema
 func normalizeSchema(schema *database.Schema) (string, error) {
 	// Handle nil schema - treat it the same as empty schema
 	if schema == nil {
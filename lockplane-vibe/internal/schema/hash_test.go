@@ -304,6 +304,28 @@ func TestComputeSchemaHash_AllFieldsAffectHash(t *testing.T) {
 				s.Tables[0].Indexes[0].Unique = false
 			},
 		},
+		{
+			name: "index nulls not distinct change",
+			modify: func(s *database.Schema) {
+				s.Tables[0].Indexes[0].NullsNotDistinct = true
+			},
+		},
+		{
+			name: "index ordering change",
+			modify: func(s *database.Schema) {
+				idx := &s.Tables[0].Indexes[0]
+				cols := idx.KeyColumns()
+				cols[0].Descending = true
+				idx.SetKeyColumns(cols)
+			},
+		},
+		{
+			name: "replica identity change",
+			modify: func(s *database.Schema) {
+				identity := database.ReplicaIdentityFull
+				s.Tables[0].ReplicaIdentity = &identity
+			},
+		},
 		{
 			name: "foreign key name change",
 			modify: func(s *database.Schema) {
@@ -432,3 +454,155 @@ func TestComputeSchemaHash_AllFieldsAffectHash(t *testing.T) {
 		})
 	}
 }
+
+// TestComputeSchemaHash_OrderIndependent verifies that table and column order
+// don't affect the hash
+func TestComputeSchemaHash_OrderIndependent(t *testing.T) {
+	schema1 := &database.Schema{
+		Tables: []database.Table{
+			{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer"}, {Name: "email", Type: "text"}}},
+			{Name: "posts", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		},
+	}
+	schema2 := &database.Schema{
+		Tables: []database.Table{
+			{Name: "posts", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+			{Name: "users", Columns: []database.Column{{Name: "email", Type: "text"}, {Name: "id", Type: "integer"}}},
+		},
+	}
+
+	hash1, err := ComputeSchemaHash(schema1)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+	hash2, err := ComputeSchemaHash(schema2)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+
+	if hash1 != hash2 {
+		t.Errorf("expected same hash regardless of order\nFirst:  %s\nSecond: %s", hash1, hash2)
+	}
+}
+
+// TestComputeSchemaHash_NormalizesEquivalentValues verifies that equivalent
+// defaults, referential actions and the default schema hash the same
+func TestComputeSchemaHash_NormalizesEquivalentValues(t *testing.T) {
+	now := "NOW()"
+	nowLower := "now()"
+	noAction := "NO ACTION"
+
+	schema1 := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:    "users",
+				Schema:  "public",
+				Columns: []database.Column{{Name: "created_at", Type: "timestamp", Default: &now}},
+				ForeignKeys: []database.ForeignKey{
+					{Name: "fk_org", Columns: []string{"org_id"}, ReferencedTable: "orgs", ReferencedColumns: []string{"id"}, OnDelete: &noAction},
+				},
+			},
+		},
+	}
+	schema2 := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:    "users",
+				Columns: []database.Column{{Name: "created_at", Type: "TIMESTAMP", Default: &nowLower}},
+				ForeignKeys: []database.ForeignKey{
+					{Name: "fk_org", Columns: []string{"org_id"}, ReferencedTable: "orgs", ReferencedColumns: []string{"id"}},
+				},
+			},
+		},
+	}
+
+	hash1, err := ComputeSchemaHash(schema1)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+	hash2, err := ComputeSchemaHash(schema2)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+
+	if hash1 != hash2 {
+		t.Errorf("expected same hash for equivalent schemas\nFirst:  %s\nSecond: %s", hash1, hash2)
+	}
+}
+
+// TestComputeSchemaHash_IgnoresStats verifies that the approximate table
+// stats recorded by introspect --with-stats never change the hash or the diff
+func TestComputeSchemaHash_IgnoresStats(t *testing.T) {
+	rows := int64(1200)
+	withoutStats := &database.Schema{
+		Tables: []database.Table{
+			{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}}},
+		},
+	}
+	withStats := &database.Schema{
+		Tables: []database.Table{
+			{
+				Name:    "users",
+				Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}},
+				Stats:   &database.TableStats{ApproximateRows: &rows, TotalBytes: 81920, IndexBytes: 16384},
+			},
+		},
+	}
+
+	hash1, err := ComputeSchemaHash(withoutStats)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+	hash2, err := ComputeSchemaHash(withStats)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+	if hash1 != hash2 {
+		t.Errorf("expected stats to leave the hash unchanged\nWithout: %s\nWith:    %s", hash1, hash2)
+	}
+
+	if diff := DiffSchemas(withoutStats, withStats); !diff.IsEmpty() {
+		t.Errorf("expected no diff for stats, got %+v", diff)
+	}
+}
+
+// TestSchemaHashMatches_AcceptsLegacyHash verifies that plans carrying a
+// version 1 hash still verify during the transition period
+func TestSchemaHashMatches_AcceptsLegacyHash(t *testing.T) {
+	schema := &database.Schema{
+		Tables: []database.Table{
+			{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}}},
+		},
+	}
+
+	legacy, err := ComputeLegacySchemaHash(schema)
+	if err != nil {
+		t.Fatalf("failed to compute legacy hash: %v", err)
+	}
+	current, err := ComputeSchemaHash(schema)
+	if err != nil {
+		t.Fatalf("failed to compute hash: %v", err)
+	}
+
+	if legacy == current {
+		t.Fatal("expected legacy and current hashes to differ")
+	}
+
+	for _, hash := range []string{legacy, current} {
+		matches, err := SchemaHashMatches(schema, hash)
+		if err != nil {
+			t.Fatalf("SchemaHashMatches returned error: %v", err)
+		}
+		if !matches {
+			t.Errorf("expected hash %s to match", hash)
+		}
+	}
+
+	matches, err := SchemaHashMatches(schema, "0000000000000000000000000000000000000000000000000000000000000000")
+	if err != nil {
+		t.Fatalf("SchemaHashMatches returned error: %v", err)
+	}
+	if matches {
+		t.Error("expected unrelated hash not to match")
+	}
+}
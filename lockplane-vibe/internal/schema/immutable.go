@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+)
+
+// ImmutableDirective is the comment that declares the table created by the
+// statement it precedes immutable: plans may create the table, but never
+// alter or drop it
+const ImmutableDirective = "-- lockplane:immutable"
+
+var (
+	immutableDirectivePattern = regexp.MustCompile(`(?m)^[ \t]*--[ \t]*lockplane:immutable[ \t]*$`)
+	createTablePattern        = regexp.MustCompile(`(?i)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|[\w$]+)(?:\s*\.\s*(?:"[^"]+"|[\w$]+))?)`)
+)
+
+// FindImmutableTables returns the tables declared immutable with
+// ImmutableDirective in the .lp.sql files at path, a schema directory or
+// file, with where each directive is. Other inputs, such as JSON schemas or
+// directories without .lp.sql files, declare none. A directive that doesn't
+// precede a CREATE TABLE statement is an error, so a misplaced one isn't
+// silently ignored.
+func FindImmutableTables(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil
+	}
+
+	fsys, files := dirFS(path), []string(nil)
+	if info.IsDir() {
+		if files, err = fsys.dirFiles("."); err != nil {
+			return nil, nil
+		}
+	} else if strings.HasSuffix(strings.ToLower(path), ".lp.sql") {
+		var name string
+		fsys, name = fileFS(path)
+		files = []string{name}
+	}
+
+	tables := map[string]string{}
+	for _, file := range files {
+		data, err := fsys.readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file: %w", err)
+		}
+		if err := findImmutableDirectives(fsys.display(file), sqlsplit.NormalizeLineEndings(string(data)), tables); err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// findImmutableDirectives adds the tables declared immutable in content to
+// tables, keeping the first declaration of each
+func findImmutableDirectives(path, content string, tables map[string]string) error {
+	for _, match := range immutableDirectivePattern.FindAllStringIndex(content, -1) {
+		directive := lineColumn(path, content, match[0]+strings.Index(content[match[0]:match[1]], "--")).String()
+		stmt := content[skipToToken(content, match[1]):]
+		create := createTablePattern.FindStringSubmatch(stmt)
+		if create == nil {
+			return fmt.Errorf("%s: %s must directly precede a CREATE TABLE statement", directive, ImmutableDirective)
+		}
+		name := immutableTableName(create[1])
+		if _, ok := tables[name]; !ok {
+			tables[name] = directive
+		}
+	}
+	return nil
+}
+
+// immutableTableName normalizes a possibly qualified, possibly quoted table
+// name the way PostgreSQL does: unquoted identifiers are folded to lowercase
+func immutableTableName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if unquoted, ok := strings.CutPrefix(part, `"`); ok {
+			parts[i] = strings.TrimSuffix(unquoted, `"`)
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// IsImmutableTable returns where table was declared immutable, if it was.
+// A declaration without a schema covers the table in any schema.
+func IsImmutableTable(immutable map[string]string, table string) (string, bool) {
+	if declaredAt, ok := immutable[table]; ok {
+		return declaredAt, true
+	}
+	if _, name, qualified := strings.Cut(table, "."); qualified {
+		declaredAt, ok := immutable[name]
+		return declaredAt, ok
+	}
+	return "", false
+}
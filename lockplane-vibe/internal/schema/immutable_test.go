@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindImmutableTables(t *testing.T) {
+	dir := t.TempDir()
+	content := "CREATE TABLE users (id integer);\r\n\r\n  -- lockplane:immutable\r\n-- Append-only record of changes\r\nCREATE TABLE IF NOT EXISTS Audit.\"EventLog\" (id integer);\r\n" +
+		"-- lockplane:immutable\nCREATE UNLOGGED TABLE Ledger (id integer);\n"
+	if err := os.WriteFile(filepath.Join(dir, "tables.lp.sql"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.sql"), []byte("-- lockplane:immutable\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := FindImmutableTables(dir)
+	if err != nil {
+		t.Fatalf("FindImmutableTables returned error: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("Expected 2 immutable tables, got %v", tables)
+	}
+	if at := tables[`audit.EventLog`]; !strings.HasSuffix(at, "tables.lp.sql:3:3") {
+		t.Errorf("Expected the directive location of audit.EventLog, got %q", at)
+	}
+	if at := tables["ledger"]; !strings.HasSuffix(at, "tables.lp.sql:6:1") {
+		t.Errorf("Expected the directive location of ledger, got %q", at)
+	}
+
+	if tables, err := FindImmutableTables(filepath.Join(dir, "missing")); err != nil || tables != nil {
+		t.Errorf("Expected no immutable tables for a missing path, got %v, %v", tables, err)
+	}
+}
+
+func TestFindImmutableTablesMisplacedDirective(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "schema.lp.sql")
+	if err := os.WriteFile(file, []byte("-- lockplane:immutable\nCREATE INDEX idx_users_email ON users (email);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := FindImmutableTables(file)
+	if err == nil || !strings.Contains(err.Error(), "schema.lp.sql:1:1") || !strings.Contains(err.Error(), "CREATE TABLE") {
+		t.Fatalf("Expected an error naming the misplaced directive, got %v", err)
+	}
+}
+
+func TestIsImmutableTable(t *testing.T) {
+	immutable := map[string]string{"audit_log": "a.lp.sql:1:1", "billing.ledger": "b.lp.sql:1:1"}
+
+	for table, want := range map[string]string{
+		"audit_log":         "a.lp.sql:1:1",
+		"archive.audit_log": "a.lp.sql:1:1",
+		"billing.ledger":    "b.lp.sql:1:1",
+		"ledger":            "",
+		"public.ledger":     "",
+	} {
+		declaredAt, ok := IsImmutableTable(immutable, table)
+		if declaredAt != want || ok != (want != "") {
+			t.Errorf("IsImmutableTable(%q) = %q, %v; want %q", table, declaredAt, ok, want)
+		}
+	}
+}
@@ -0,0 +1,511 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// schemaCacheVersion is bumped when the cache file format changes
+const schemaCacheVersion = 1
+
+// IncrementalOptions configures LoadSchemaIncremental
+type IncrementalOptions struct {
+	// CacheDir holds the per-file hashes and parsed tables between runs
+	CacheDir string
+	// Version identifies the lockplane build. Caches written by another
+	// build are ignored, since its parser may read files differently.
+	Version string
+}
+
+// IncrementalResult describes how LoadSchemaIncremental built a schema
+type IncrementalResult struct {
+	// Incremental is false when every file was parsed
+	Incremental bool
+	// FallbackReason explains why every file was parsed
+	FallbackReason string
+	// ChangedFiles lists files added, modified or removed since the cached run
+	ChangedFiles []string
+	// ParsedFiles is the number of files parsed: the changed files plus the
+	// files that share tables or indexes with them
+	ParsedFiles int
+	TotalFiles  int
+	// CacheErr is set when the cache could not be written; the schema is
+	// still complete
+	CacheErr error
+}
+
+// schemaCache is the cache file for one schema directory
+type schemaCache struct {
+	Version          int                         `json:"version"`
+	LockplaneVersion string                      `json:"lockplane_version"`
+	Dir              string                      `json:"dir"`
+	Dialect          database.Dialect            `json:"dialect"`
+	Files            map[string]cachedSchemaFile `json:"files"`
+	Components       []cachedComponent           `json:"components"`
+}
+
+// cachedSchemaFile records a file's content hash and the table and index
+// names it creates or changes
+type cachedSchemaFile struct {
+	Hash  string   `json:"hash"`
+	Names []string `json:"names"`
+}
+
+// cachedComponent holds the tables parsed from a group of files that share
+// table or index names, keyed by the files' paths and hashes
+type cachedComponent struct {
+	Key    string        `json:"key"`
+	Tables []cachedTable `json:"tables"`
+}
+
+// cachedTable is a table with the source locations that database.Table does
+// not serialize
+type cachedTable struct {
+	Table       database.Table    `json:"table"`
+	Source      *SourceLocation   `json:"source,omitempty"`
+	Columns     []*SourceLocation `json:"columns,omitempty"`
+	Indexes     []*SourceLocation `json:"indexes,omitempty"`
+	ForeignKeys []*SourceLocation `json:"foreign_keys,omitempty"`
+}
+
+// LoadSchemaIncremental loads a schema directory like LoadSchemaWithOptions,
+// reusing the tables parsed on the previous run for files that have not
+// changed. A changed file is parsed together with every file that creates or
+// alters the same tables or indexes, so the result matches a full parse.
+//
+// Every file is parsed when there is no usable cache, the dialect is not
+// PostgreSQL, or a file has statements whose effect can't be tracked per file
+// (DROP TABLE, DROP INDEX, CREATE INDEX IF NOT EXISTS). The cache is updated
+// after each load.
+func LoadSchemaIncremental(path string, opts *SchemaLoadOptions, inc IncrementalOptions) (*database.Schema, *IncrementalResult, error) {
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		loaded, err := LoadSchemaWithOptions(path, opts)
+		return loaded, &IncrementalResult{FallbackReason: "not a schema directory", TotalFiles: 1, ParsedFiles: 1}, err
+	}
+
+	fsys := dirFS(path)
+	sources, err := fsys.dirSources(".", opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &IncrementalResult{TotalFiles: len(sources)}
+	full := func(reason string, files map[string]cachedSchemaFile) (*database.Schema, *IncrementalResult, error) {
+		result.Incremental = false
+		result.FallbackReason = reason
+		result.ParsedFiles = len(sources)
+		loaded, err := fsys.loadDir(".", opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if files != nil {
+			if cache := buildSchemaCache(path, inc, sources, files, loaded); cache != nil {
+				result.CacheErr = writeSchemaCache(inc.CacheDir, cache)
+			}
+		}
+		return loaded, result, nil
+	}
+
+	dialect := database.DialectPostgres
+	if opts != nil && opts.Dialect != database.DialectUnknown {
+		dialect = opts.Dialect
+	}
+	if dialect != database.DialectPostgres {
+		return full(fmt.Sprintf("incremental parsing supports PostgreSQL schemas only, not %s", dialect), nil)
+	}
+
+	cached := readSchemaCache(inc.CacheDir, path, inc.Version)
+	files, err := scanSchemaFiles(sources, cached)
+	if err != nil {
+		return full(err.Error(), nil)
+	}
+	if cached == nil {
+		return full("no cached parse of "+path, files)
+	}
+	result.ChangedFiles = changedSchemaFiles(sources, files, cached)
+
+	cachedTables := make(map[string][]cachedTable, len(cached.Components))
+	for _, component := range cached.Components {
+		cachedTables[component.Key] = component.Tables
+	}
+
+	var tables []database.Table
+	for _, component := range groupSchemaFiles(sources, files) {
+		key := componentKey(sources, files, component)
+		if entries, ok := cachedTables[key]; ok {
+			for _, entry := range entries {
+				tables = append(tables, entry.restore())
+			}
+			continue
+		}
+
+		componentSources := make([]SourceFile, 0, len(component))
+		for _, i := range component {
+			componentSources = append(componentSources, sources[i])
+		}
+		if duplicates := FindDuplicateDefinitions(componentSources); len(duplicates) > 0 {
+			return full("duplicate definitions", files)
+		}
+		parsed, err := LoadSQLSchemaFromBytes([]byte(concatSchemaSources(componentSources)), opts)
+		if err != nil {
+			// Let the full parse report the error exactly as it always does
+			return full(err.Error(), files)
+		}
+		annotateSources(parsed, componentSources)
+		tables = append(tables, parsed.Tables...)
+		result.ParsedFiles += len(component)
+	}
+
+	order, ok := tableFileOrder(sources, tables)
+	if !ok {
+		return full("a table has no source file", files)
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return order[i] < order[j] })
+
+	loaded := &database.Schema{Tables: tables, Dialect: dialect}
+	if tables == nil {
+		loaded.Tables = []database.Table{}
+	}
+	result.Incremental = true
+	if cache := buildSchemaCache(path, inc, sources, files, loaded); cache != nil {
+		result.CacheErr = writeSchemaCache(inc.CacheDir, cache)
+	}
+	return loaded, result, nil
+}
+
+// concatSchemaSources joins schema files into the script a schema directory
+// is parsed as
+func concatSchemaSources(sources []SourceFile) string {
+	var builder strings.Builder
+	for _, source := range sources {
+		builder.WriteString(fmt.Sprintf("-- File: %s\n", source.Path))
+		builder.WriteString(source.Content)
+		if len(source.Content) == 0 || source.Content[len(source.Content)-1] != '\n' {
+			builder.WriteByte('\n')
+		}
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// scanSchemaFiles hashes each file and lists the names it touches, reusing
+// the cached names of files whose hash is unchanged
+func scanSchemaFiles(sources []SourceFile, cached *schemaCache) (map[string]cachedSchemaFile, error) {
+	files := make(map[string]cachedSchemaFile, len(sources))
+	for _, source := range sources {
+		hash := contentHash(source.Content)
+		if cached != nil {
+			if entry, ok := cached.Files[source.Path]; ok && entry.Hash == hash {
+				files[source.Path] = entry
+				continue
+			}
+		}
+		names, err := schemaFileNames(source)
+		if err != nil {
+			return nil, err
+		}
+		files[source.Path] = cachedSchemaFile{Hash: hash, Names: names}
+	}
+	return files, nil
+}
+
+// schemaFileNames lists the tables ("table:<name>") and indexes
+// ("index:<name>") a file creates or changes, by unqualified name. Files
+// sharing a name are parsed together. It fails for statements that can
+// affect tables the file doesn't name.
+func schemaFileNames(file SourceFile) ([]string, error) {
+	tree, err := pg_query.Parse(file.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%s does not parse as PostgreSQL", file.Path)
+	}
+
+	names := make(map[string]bool)
+	table := func(rv *pg_query.RangeVar) {
+		if rv != nil {
+			names["table:"+rv.Relname] = true
+		}
+	}
+	for _, raw := range tree.Stmts {
+		if raw.Stmt == nil {
+			continue
+		}
+		switch node := raw.Stmt.Node.(type) {
+		case *pg_query.Node_CreateStmt:
+			table(node.CreateStmt.Relation)
+			for _, elt := range node.CreateStmt.TableElts {
+				if like := elt.GetTableLikeClause(); like != nil {
+					table(like.Relation)
+				}
+			}
+		case *pg_query.Node_IndexStmt:
+			if node.IndexStmt.IfNotExists {
+				return nil, fmt.Errorf("%s has CREATE INDEX IF NOT EXISTS", file.Path)
+			}
+			table(node.IndexStmt.Relation)
+			if node.IndexStmt.Idxname != "" {
+				names["index:"+node.IndexStmt.Idxname] = true
+			}
+		case *pg_query.Node_AlterTableStmt:
+			// ALTER INDEX is parsed as ALTER TABLE on the index name
+			if rv := node.AlterTableStmt.Relation; rv != nil {
+				names["table:"+rv.Relname] = true
+				names["index:"+rv.Relname] = true
+			}
+		case *pg_query.Node_DropStmt:
+			switch node.DropStmt.RemoveType {
+			case pg_query.ObjectType_OBJECT_TABLE, pg_query.ObjectType_OBJECT_INDEX:
+				return nil, fmt.Errorf("%s has a DROP statement", file.Path)
+			}
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// groupSchemaFiles splits files into groups that share table or index names.
+// Groups are ordered by their first file and list files in directory order.
+func groupSchemaFiles(sources []SourceFile, files map[string]cachedSchemaFile) [][]int {
+	parent := make([]int, len(sources))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	owner := make(map[string]int)
+	for i, source := range sources {
+		for _, name := range files[source.Path].Names {
+			if j, ok := owner[name]; ok {
+				a, b := find(i), find(j)
+				if a < b {
+					a, b = b, a
+				}
+				parent[a] = b
+				continue
+			}
+			owner[name] = i
+		}
+	}
+
+	var groups [][]int
+	index := make(map[int]int)
+	for i := range sources {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// componentKey identifies a group of files by their paths and hashes
+func componentKey(sources []SourceFile, files map[string]cachedSchemaFile, component []int) string {
+	parts := make([]string, 0, len(component))
+	for _, i := range component {
+		parts = append(parts, sources[i].Path+"@"+files[sources[i].Path].Hash)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// changedSchemaFiles lists files whose hash differs from the cache, and
+// cached files that no longer exist
+func changedSchemaFiles(sources []SourceFile, files map[string]cachedSchemaFile, cached *schemaCache) []string {
+	var changed []string
+	present := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		present[source.Path] = true
+		if entry, ok := cached.Files[source.Path]; !ok || entry.Hash != files[source.Path].Hash {
+			changed = append(changed, source.Path)
+		}
+	}
+	for path := range cached.Files {
+		if !present[path] {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// tableFileOrder returns, for each table, the position of the file that
+// creates it
+func tableFileOrder(sources []SourceFile, tables []database.Table) ([]int, bool) {
+	position := make(map[string]int, len(sources))
+	for i, source := range sources {
+		position[source.Path] = i
+	}
+	order := make([]int, len(tables))
+	for i, table := range tables {
+		if table.Source == nil {
+			return nil, false
+		}
+		p, ok := position[table.Source.File]
+		if !ok {
+			return nil, false
+		}
+		order[i] = p
+	}
+	return order, true
+}
+
+// buildSchemaCache splits the loaded tables into the groups of files that
+// define them. It returns nil when a table can't be traced to its file.
+func buildSchemaCache(dir string, inc IncrementalOptions, sources []SourceFile, files map[string]cachedSchemaFile, loaded *database.Schema) *schemaCache {
+	order, ok := tableFileOrder(sources, loaded.Tables)
+	if !ok {
+		return nil
+	}
+
+	groups := groupSchemaFiles(sources, files)
+	groupOf := make(map[int]int, len(sources))
+	for g, group := range groups {
+		for _, i := range group {
+			groupOf[i] = g
+		}
+	}
+
+	cache := &schemaCache{
+		Version:          schemaCacheVersion,
+		LockplaneVersion: inc.Version,
+		Dir:              dir,
+		Dialect:          loaded.Dialect,
+		Files:            files,
+		Components:       make([]cachedComponent, len(groups)),
+	}
+	for g, group := range groups {
+		cache.Components[g].Key = componentKey(sources, files, group)
+		cache.Components[g].Tables = []cachedTable{}
+	}
+	for i, table := range loaded.Tables {
+		g := groupOf[order[i]]
+		cache.Components[g].Tables = append(cache.Components[g].Tables, newCachedTable(table))
+	}
+	return cache
+}
+
+func newCachedTable(table database.Table) cachedTable {
+	entry := cachedTable{Table: table, Source: table.Source}
+	for _, col := range table.Columns {
+		entry.Columns = append(entry.Columns, col.Source)
+	}
+	for _, idx := range table.Indexes {
+		entry.Indexes = append(entry.Indexes, idx.Source)
+	}
+	for _, fk := range table.ForeignKeys {
+		entry.ForeignKeys = append(entry.ForeignKeys, fk.Source)
+	}
+	return entry
+}
+
+// restore returns the cached table with its source locations
+func (c cachedTable) restore() database.Table {
+	table := c.Table
+	table.Source = c.Source
+	for i := range table.Columns {
+		if i < len(c.Columns) {
+			table.Columns[i].Source = c.Columns[i]
+		}
+	}
+	for i := range table.Indexes {
+		if i < len(c.Indexes) {
+			table.Indexes[i].Source = c.Indexes[i]
+		}
+	}
+	for i := range table.ForeignKeys {
+		if i < len(c.ForeignKeys) {
+			table.ForeignKeys[i].Source = c.ForeignKeys[i]
+		}
+	}
+	return table
+}
+
+// schemaCachePath returns the cache file for a schema directory
+func schemaCachePath(cacheDir, dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	return filepath.Join(cacheDir, "schema-"+contentHash(abs)[:16]+".json")
+}
+
+// readSchemaCache returns the cache for dir, or nil when there is none or it
+// was written by another lockplane build or for another spelling of dir.
+// Spellings that only differ in separators or a trailing slash share a
+// cache, since source locations are reported by cleaned paths.
+func readSchemaCache(cacheDir, dir, version string) *schemaCache {
+	data, err := os.ReadFile(schemaCachePath(cacheDir, dir))
+	if err != nil {
+		return nil
+	}
+	var cache schemaCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil
+	}
+	if cache.Version != schemaCacheVersion || cache.LockplaneVersion != version || filepath.Clean(cache.Dir) != filepath.Clean(dir) {
+		return nil
+	}
+	return &cache
+}
+
+// writeSchemaCache replaces the cache file atomically
+func writeSchemaCache(cacheDir string, cache *schemaCache) error {
+	if cacheDir == "" {
+		return errors.New("no cache directory")
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema cache: %w", err)
+	}
+
+	path := schemaCachePath(cacheDir, cache.Dir)
+	tmp, err := os.CreateTemp(cacheDir, ".schema-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	return nil
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
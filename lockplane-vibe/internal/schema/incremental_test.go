@@ -0,0 +1,220 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// schemaSnapshot captures what a plan depends on in a loaded schema: the
+// tables as serialized plus their source locations
+func schemaSnapshot(t *testing.T, s *database.Schema) string {
+	t.Helper()
+	entries := make([]cachedTable, 0, len(s.Tables))
+	for _, table := range s.Tables {
+		entries = append(entries, newCachedTable(table))
+	}
+	data, err := json.Marshal(struct {
+		Dialect database.Dialect `json:"dialect"`
+		Tables  []cachedTable    `json:"tables"`
+	}{s.Dialect, entries})
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+	return string(data)
+}
+
+func writeSchemaFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// loadIncremental loads dir incrementally and checks the result against a full parse
+func loadIncremental(t *testing.T, dir string, inc IncrementalOptions) *IncrementalResult {
+	t.Helper()
+	got, result, err := LoadSchemaIncremental(dir, nil, inc)
+	if err != nil {
+		t.Fatalf("LoadSchemaIncremental returned error: %v", err)
+	}
+	if result.CacheErr != nil {
+		t.Fatalf("Failed to write cache: %v", result.CacheErr)
+	}
+	want, err := LoadSchemaWithOptions(dir, nil)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions returned error: %v", err)
+	}
+	if schemaSnapshot(t, got) != schemaSnapshot(t, want) {
+		t.Fatalf("Incremental schema differs from full parse:\n got: %s\nwant: %s", schemaSnapshot(t, got), schemaSnapshot(t, want))
+	}
+	return result
+}
+
+func TestLoadSchemaIncremental(t *testing.T) {
+	dir := t.TempDir()
+	inc := IncrementalOptions{CacheDir: t.TempDir(), Version: "test"}
+	writeSchemaFiles(t, dir, map[string]string{
+		"001_users.lp.sql": "CREATE TABLE users (id bigint PRIMARY KEY, email text NOT NULL);\n",
+		"002_posts.lp.sql": "CREATE TABLE posts (id bigint PRIMARY KEY, user_id bigint REFERENCES users(id));\n" +
+			"CREATE INDEX posts_user_id_idx ON posts (user_id);\n",
+		"003_tags.lp.sql": "CREATE TABLE tags (id bigint PRIMARY KEY, name text);\n",
+		// Changes a table defined in another file
+		"004_users_extra.lp.sql": "ALTER TABLE users ADD COLUMN name text;\n",
+	})
+
+	result := loadIncremental(t, dir, inc)
+	if result.Incremental {
+		t.Fatal("Expected a full parse without a cache")
+	}
+	if !strings.Contains(result.FallbackReason, "no cached parse") {
+		t.Errorf("Expected missing cache as the fallback reason, got %q", result.FallbackReason)
+	}
+
+	result = loadIncremental(t, dir, inc)
+	if !result.Incremental || result.ParsedFiles != 0 || len(result.ChangedFiles) != 0 {
+		t.Fatalf("Expected every file from the cache, got %+v", result)
+	}
+
+	// Only the changed file is parsed
+	writeSchemaFiles(t, dir, map[string]string{
+		"003_tags.lp.sql": "CREATE TABLE tags (id bigint PRIMARY KEY, name text NOT NULL, color text);\n",
+	})
+	result = loadIncremental(t, dir, inc)
+	if !result.Incremental || result.ParsedFiles != 1 {
+		t.Fatalf("Expected only 003_tags.lp.sql to be parsed, got %+v", result)
+	}
+	if !reflect.DeepEqual(result.ChangedFiles, []string{filepath.Join(dir, "003_tags.lp.sql")}) {
+		t.Errorf("Unexpected changed files: %v", result.ChangedFiles)
+	}
+
+	// A file altering another file's table is parsed with it
+	writeSchemaFiles(t, dir, map[string]string{
+		"001_users.lp.sql": "CREATE TABLE users (id bigint PRIMARY KEY, email text);\n",
+	})
+	result = loadIncremental(t, dir, inc)
+	if !result.Incremental || result.ParsedFiles != 2 {
+		t.Fatalf("Expected users and users_extra files to be parsed, got %+v", result)
+	}
+
+	// Added and removed files
+	if err := os.Remove(filepath.Join(dir, "003_tags.lp.sql")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	writeSchemaFiles(t, dir, map[string]string{
+		"000_accounts.lp.sql": "CREATE TABLE accounts (id bigint PRIMARY KEY);\n",
+	})
+	result = loadIncremental(t, dir, inc)
+	if !result.Incremental || result.ParsedFiles != 1 || len(result.ChangedFiles) != 2 {
+		t.Fatalf("Expected the added file parsed and the removed one dropped, got %+v", result)
+	}
+}
+
+func TestLoadSchemaIncrementalFallsBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		opts   *SchemaLoadOptions
+		reason string
+	}{
+		{
+			name: "drop statement",
+			files: map[string]string{
+				"001_a.lp.sql": "CREATE TABLE a (id bigint);\nCREATE TABLE old (id bigint);\n",
+				"002_b.lp.sql": "DROP TABLE old;\n",
+			},
+			reason: "DROP statement",
+		},
+		{
+			name: "index if not exists",
+			files: map[string]string{
+				"001_a.lp.sql": "CREATE TABLE a (id bigint);\nCREATE INDEX IF NOT EXISTS a_id_idx ON a (id);\n",
+			},
+			reason: "IF NOT EXISTS",
+		},
+		{
+			name: "sqlite",
+			files: map[string]string{
+				"001_a.lp.sql": "CREATE TABLE a (id INTEGER PRIMARY KEY);\n",
+			},
+			opts:   &SchemaLoadOptions{Dialect: database.DialectSQLite},
+			reason: "PostgreSQL schemas only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			inc := IncrementalOptions{CacheDir: t.TempDir(), Version: "test"}
+			writeSchemaFiles(t, dir, tt.files)
+
+			for run := 0; run < 2; run++ {
+				_, result, err := LoadSchemaIncremental(dir, tt.opts, inc)
+				if err != nil {
+					t.Fatalf("LoadSchemaIncremental returned error: %v", err)
+				}
+				if result.Incremental {
+					t.Fatalf("Expected a full parse on run %d", run+1)
+				}
+				if !strings.Contains(result.FallbackReason, tt.reason) {
+					t.Errorf("Expected fallback reason containing %q, got %q", tt.reason, result.FallbackReason)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSchemaIncrementalIgnoresOtherVersions(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	writeSchemaFiles(t, dir, map[string]string{
+		"001_a.lp.sql": "CREATE TABLE a (id bigint);\n",
+	})
+
+	loadIncremental(t, dir, IncrementalOptions{CacheDir: cacheDir, Version: "1.0.0"})
+	result := loadIncremental(t, dir, IncrementalOptions{CacheDir: cacheDir, Version: "1.1.0"})
+	if result.Incremental {
+		t.Fatal("Expected a cache from another version to be ignored")
+	}
+}
+
+func TestLoadSchemaIncrementalSharesCacheAcrossSpellings(t *testing.T) {
+	dir := t.TempDir()
+	inc := IncrementalOptions{CacheDir: t.TempDir(), Version: "test"}
+	writeSchemaFiles(t, dir, map[string]string{
+		"001_a.lp.sql": "CREATE TABLE a (id bigint);\r\nCREATE TABLE b (id bigint);\r\n",
+	})
+
+	loadIncremental(t, dir, inc)
+	result := loadIncremental(t, dir+string(filepath.Separator), inc)
+	if !result.Incremental {
+		t.Fatalf("Expected the cache of %s to be used with a trailing separator, got %+v", dir, result)
+	}
+}
+
+func TestLoadSchemaIncrementalReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	inc := IncrementalOptions{CacheDir: t.TempDir(), Version: "test"}
+	writeSchemaFiles(t, dir, map[string]string{
+		"001_a.lp.sql": "CREATE TABLE a (id bigint);\n",
+		"002_b.lp.sql": "CREATE TABLE b (id bigint);\n",
+	})
+	loadIncremental(t, dir, inc)
+
+	// A duplicate across files must fail just like a full parse
+	writeSchemaFiles(t, dir, map[string]string{
+		"002_b.lp.sql": "CREATE TABLE a (id bigint);\n",
+	})
+	_, _, err := LoadSchemaIncremental(dir, nil, inc)
+	_, wantErr := LoadSchemaWithOptions(dir, nil)
+	if err == nil || wantErr == nil || err.Error() != wantErr.Error() {
+		t.Fatalf("Expected error %v, got %v", wantErr, err)
+	}
+}
@@ -3,8 +3,11 @@ package schema
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -17,9 +20,19 @@ import (
 // SchemaLoadOptions controls how schema files are parsed.
 type SchemaLoadOptions struct {
 	Dialect database.Dialect
+	// Variables are substituted for ${name} references in SQL schema files.
+	// Files are used as written when Variables is nil.
+	Variables map[string]string
+	// AllowUndefinedVariables leaves references to undefined variables as
+	// written instead of failing
+	AllowUndefinedVariables bool
+	// AllowIdenticalDuplicates accepts tables, indexes and constraints that
+	// are defined more than once in a schema directory when every copy is
+	// the same; the first copy is kept. Conflicting copies are always an error.
+	AllowIdenticalDuplicates bool
 }
 
-// LoadSchema loads a schema from either JSON (.json) or SQL DDL (.lp.sql) file
+// LoadSchema loads a schema from either JSON (.json) or SQL DDL (.sql) file
 func LoadSchema(path string) (*database.Schema, error) {
 	return LoadSchemaWithOptions(path, nil)
 }
@@ -27,18 +40,19 @@ func LoadSchema(path string) (*database.Schema, error) {
 // LoadSchemaWithOptions loads a schema with optional parsing options.
 func LoadSchemaWithOptions(path string, opts *SchemaLoadOptions) (*database.Schema, error) {
 	if info, err := os.Stat(path); err == nil && info.IsDir() {
-		return loadSchemaFromDir(path, opts)
-	}
-
-	ext := strings.ToLower(filepath.Ext(path))
-
-	// Check for .lp.sql extension
-	if ext == ".sql" && strings.HasSuffix(strings.ToLower(path), ".lp.sql") {
-		return LoadSQLSchemaWithOptions(path, opts)
+		return dirFS(path).load(".", opts)
 	}
+	fsys, name := fileFS(path)
+	return fsys.load(name, opts)
+}
 
-	// Otherwise assume JSON
-	return LoadJSONSchema(path)
+// LoadSchemaFromFS loads a schema from a file system such as an embed.FS, so
+// applications that ship their schema in the binary can plan against it
+// without extracting it. name is a schema directory, .lp.sql file or JSON
+// schema in fsys, as a slash-separated fs.FS path ("." for the root); source
+// locations use the same paths.
+func LoadSchemaFromFS(fsys fs.FS, name string, opts *SchemaLoadOptions) (*database.Schema, error) {
+	return schemaFS{fsys: fsys}.load(name, opts)
 }
 
 // LoadSQLSchema loads a schema from a SQL DDL file
@@ -48,13 +62,8 @@ func LoadSQLSchema(path string) (*database.Schema, error) {
 
 // LoadSQLSchemaWithOptions loads a SQL schema with optional parsing options.
 func LoadSQLSchemaWithOptions(path string, opts *SchemaLoadOptions) (*database.Schema, error) {
-	// Read the SQL file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SQL file: %w", err)
-	}
-
-	return LoadSQLSchemaFromBytes(data, opts)
+	fsys, name := fileFS(path)
+	return fsys.loadSQLFile(name, opts)
 }
 
 // LoadSQLSchemaFromBytes loads a SQL schema from a byte slice
@@ -79,10 +88,150 @@ func LoadSQLSchemaFromBytes(data []byte, opts *SchemaLoadOptions) (*database.Sch
 	return schema, nil
 }
 
-func loadSchemaFromDir(dir string, opts *SchemaLoadOptions) (*database.Schema, error) {
-	entries, err := os.ReadDir(dir)
+// schemaFS reads schema files from a file system. Files on disk are read
+// through os.DirFS rooted at root, and reported by their path on disk.
+type schemaFS struct {
+	fsys fs.FS
+	root string // Directory fsys is rooted at on disk ("" for other file systems)
+}
+
+// dirFS returns the schema file system of a directory on disk
+func dirFS(dir string) schemaFS {
+	return schemaFS{fsys: os.DirFS(dir), root: dir}
+}
+
+// fileFS returns the schema file system of the directory containing path on
+// disk, and the name of path in it
+func fileFS(path string) (schemaFS, string) {
+	return dirFS(filepath.Dir(path)), filepath.Base(path)
+}
+
+// display returns the path a file is reported by in errors and source locations
+func (s schemaFS) display(name string) string {
+	if s.root == "" {
+		return name
+	}
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+// readFile reads a file, reporting it by its display path when it can't be read
+func (s schemaFS) readFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(s.fsys, name)
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		pathErr.Path = s.display(name)
+	}
+	return data, err
+}
+
+// load loads a schema directory, a SQL file or a JSON schema. A single SQL
+// file may be any .sql file, such as a pg_dump script; schema directories
+// only read their .lp.sql files.
+func (s schemaFS) load(name string, opts *SchemaLoadOptions) (*database.Schema, error) {
+	if info, err := fs.Stat(s.fsys, name); err == nil && info.IsDir() {
+		return s.loadDir(name, opts)
+	}
+
+	if strings.HasSuffix(strings.ToLower(name), ".sql") {
+		return s.loadSQLFile(name, opts)
+	}
+
+	// Otherwise assume JSON
+	data, err := s.readFile(name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read schema directory %s: %w", dir, err)
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
+	}
+	return parseJSONSchema(data)
+}
+
+// loadSQLFile loads a single SQL file
+func (s schemaFS) loadSQLFile(name string, opts *SchemaLoadOptions) (*database.Schema, error) {
+	data, err := s.readFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SQL file: %w", err)
+	}
+
+	path := s.display(name)
+	expanded, err := ExpandSchemaFile(path, string(data), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand variables in %s: %w", path, err)
+	}
+
+	schema, err := LoadSQLSchemaFromBytes([]byte(expanded.Text), opts)
+	if err != nil {
+		return nil, err
+	}
+	annotateSources(schema, []SourceFile{{Path: path, Content: expanded.Text, Expansion: expanded}})
+	return schema, nil
+}
+
+func (s schemaFS) loadDir(dir string, opts *SchemaLoadOptions) (*database.Schema, error) {
+	sources, err := s.dirSources(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return loadSources(sources, "schema directory "+s.display(dir), opts)
+}
+
+// LoadSQLFiles loads SQL files on disk as one schema, as if they were the
+// files of a schema directory read in the order given. Any .sql file can be
+// listed, and the files may be in different directories.
+func LoadSQLFiles(paths []string, opts *SchemaLoadOptions) (*database.Schema, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no SQL files given")
+	}
+	sources := make([]SourceFile, 0, len(paths))
+	var errs []error
+	for _, path := range paths {
+		fsys, name := fileFS(path)
+		data, err := fsys.readFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file %s: %w", path, err)
+		}
+		expanded, err := ExpandSchemaFile(fsys.display(name), string(data), opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sources = append(sources, SourceFile{Path: fsys.display(name), Content: expanded.Text, Expansion: expanded})
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to expand schema variables: %w", errors.Join(errs...))
+	}
+	return loadSources(sources, "schema files", opts)
+}
+
+// loadSources parses expanded schema files as one schema. where names the
+// files in errors.
+func loadSources(sources []SourceFile, where string, opts *SchemaLoadOptions) (*database.Schema, error) {
+	// Concatenating the files would otherwise keep both copies of a table (or
+	// fail later with "already exists"), so report duplicates with both locations
+	duplicates := FindDuplicateDefinitions(sources)
+	if opts != nil && opts.AllowIdenticalDuplicates {
+		if conflicting := ConflictingDuplicates(duplicates); len(conflicting) > 0 {
+			return nil, fmt.Errorf("conflicting definitions in %s: %w", where, duplicateDefinitionsError(conflicting))
+		}
+	} else if len(duplicates) > 0 {
+		return nil, fmt.Errorf("duplicate definitions in %s: %w", where, duplicateDefinitionsError(duplicates))
+	}
+
+	schema, err := LoadSQLSchemaFromBytes([]byte(concatSchemaSources(sources)), opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) > 0 {
+		dropRepeatedDefinitions(schema)
+	}
+	annotateSources(schema, sources)
+	return schema, nil
+}
+
+// dirFiles lists the top-level .lp.sql files in dir, sorted by name.
+// Subdirectories, symlinks and database files are skipped.
+func (s schemaFS) dirFiles(dir string) ([]string, error) {
+	entries, err := fs.ReadDir(s.fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema directory %s: %w", s.display(dir), err)
 	}
 
 	var sqlFiles []string
@@ -91,7 +240,7 @@ func loadSchemaFromDir(dir string, opts *SchemaLoadOptions) (*database.Schema, e
 			continue
 		}
 
-		if entry.Type()&os.ModeSymlink != 0 {
+		if entry.Type()&fs.ModeSymlink != 0 {
 			continue
 		}
 
@@ -107,32 +256,59 @@ func loadSchemaFromDir(dir string, opts *SchemaLoadOptions) (*database.Schema, e
 
 		// Only include .lp.sql files
 		if strings.HasSuffix(lowerName, ".lp.sql") {
-			sqlFiles = append(sqlFiles, filepath.Join(dir, name))
+			sqlFiles = append(sqlFiles, path.Join(dir, name))
 		}
 	}
 
 	if len(sqlFiles) == 0 {
-		return nil, fmt.Errorf("no .lp.sql files found in directory %s", dir)
+		return nil, fmt.Errorf("no .lp.sql files found in directory %s", s.display(dir))
 	}
 
 	sort.Strings(sqlFiles)
+	return sqlFiles, nil
+}
 
-	var builder strings.Builder
+// readSources reads and expands the given schema files. Undefined
+// variables in every file are reported together.
+func (s schemaFS) readSources(sqlFiles []string, opts *SchemaLoadOptions) ([]SourceFile, error) {
+	sources := make([]SourceFile, 0, len(sqlFiles))
+	var errs []error
 	for _, file := range sqlFiles {
-		data, readErr := os.ReadFile(file)
-		if readErr != nil {
-			return nil, fmt.Errorf("failed to read SQL file %s: %w", file, readErr)
+		data, err := s.readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file %s: %w", s.display(file), err)
 		}
-
-		builder.WriteString(fmt.Sprintf("-- File: %s\n", file))
-		builder.Write(data)
-		if len(data) == 0 || data[len(data)-1] != '\n' {
-			builder.WriteByte('\n')
+		path := s.display(file)
+		expanded, err := ExpandSchemaFile(path, string(data), opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
-		builder.WriteByte('\n')
+		sources = append(sources, SourceFile{Path: path, Content: expanded.Text, Expansion: expanded})
 	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to expand schema variables: %w", errors.Join(errs...))
+	}
+	return sources, nil
+}
 
-	return LoadSQLSchemaFromBytes([]byte(builder.String()), opts)
+// dirSources reads and expands the .lp.sql files of a schema directory
+func (s schemaFS) dirSources(dir string, opts *SchemaLoadOptions) ([]SourceFile, error) {
+	sqlFiles, err := s.dirFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return s.readSources(sqlFiles, opts)
+}
+
+// FindDuplicateDefinitionsInDir reports objects defined more than once across
+// the .lp.sql files of a schema directory
+func FindDuplicateDefinitionsInDir(dir string, opts *SchemaLoadOptions) ([]*DuplicateDefinitionError, error) {
+	sources, err := dirFS(dir).dirSources(".", opts)
+	if err != nil {
+		return nil, err
+	}
+	return FindDuplicateDefinitions(sources), nil
 }
 
 // DriverNameToDialect converts a driver name to a dialect
@@ -154,7 +330,11 @@ func LoadJSONSchema(path string) (*database.Schema, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read JSON file: %w", err)
 	}
+	return parseJSONSchema(data)
+}
 
+// parseJSONSchema validates and decodes a JSON schema document
+func parseJSONSchema(data []byte) (*database.Schema, error) {
 	// Step 1: Validate against JSON Schema FIRST (catches extra fields via additionalProperties: false)
 	schemaLoader := gojsonschema.NewReferenceLoader("file://schema-json/schema.json")
 	documentLoader := gojsonschema.NewStringLoader(string(data))
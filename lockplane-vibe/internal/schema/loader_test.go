@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/lockplane/lockplane/database"
 	"github.com/xeipuuv/gojsonschema"
@@ -426,3 +427,121 @@ func TestDialectPrecedence(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadSchemaFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/schema/users.lp.sql":    {Data: []byte("CREATE TABLE users (\n  id ${id_type} PRIMARY KEY\n);\n")},
+		"db/schema/posts.lp.sql":    {Data: []byte("CREATE TABLE posts (\n  id BIGINT PRIMARY KEY,\n  user_id BIGINT REFERENCES users (id)\n);\n")},
+		"db/schema/notes.md":        {Data: []byte("not a schema file")},
+		"db/schema/old/old.lp.sql":  {Data: []byte("CREATE TABLE old (id BIGINT);\n")},
+		"db/schema/dev.sqlite":      {Data: []byte("binary")},
+		"db/snapshot.json":          {Data: []byte(`{"tables": [{"name": "users", "columns": [{"name": "id", "type": "bigint", "nullable": false, "is_primary_key": true}]}]}`)},
+		"db/empty/README.md":        {Data: []byte("nothing here")},
+		"db/single/accounts.lp.sql": {Data: []byte("CREATE TABLE accounts (id BIGINT);\n")},
+	}
+	opts := &SchemaLoadOptions{Variables: map[string]string{"id_type": "BIGINT"}}
+
+	loaded, err := LoadSchemaFromFS(fsys, "db/schema", opts)
+	if err != nil {
+		t.Fatalf("LoadSchemaFromFS returned error: %v", err)
+	}
+	if len(loaded.Tables) != 2 || loaded.Tables[0].Name != "posts" || loaded.Tables[1].Name != "users" {
+		t.Fatalf("expected posts and users tables in file order, got %+v", loaded.Tables)
+	}
+	if source := loaded.Tables[1].Source; source == nil || *source != (database.SourceLocation{File: "db/schema/users.lp.sql", Line: 1, Column: 14}) {
+		t.Errorf("expected users to be located by its fs path, got %v", source)
+	}
+
+	loaded, err = LoadSchemaFromFS(fsys, "db/single/accounts.lp.sql", nil)
+	if err != nil {
+		t.Fatalf("LoadSchemaFromFS returned error for a single file: %v", err)
+	}
+	if len(loaded.Tables) != 1 || loaded.Tables[0].Source.File != "db/single/accounts.lp.sql" {
+		t.Errorf("unexpected single-file schema: %+v", loaded.Tables)
+	}
+
+	loaded, err = LoadSchemaFromFS(fsys, "db/snapshot.json", nil)
+	if err != nil {
+		t.Fatalf("LoadSchemaFromFS returned error for JSON: %v", err)
+	}
+	if len(loaded.Tables) != 1 || loaded.Tables[0].Name != "users" {
+		t.Errorf("unexpected JSON schema: %+v", loaded.Tables)
+	}
+
+	if _, err := LoadSchemaFromFS(fsys, "db/empty", nil); err == nil || !strings.Contains(err.Error(), "no .lp.sql files found in directory db/empty") {
+		t.Errorf("expected an error naming the empty directory, got %v", err)
+	}
+	if _, err := LoadSchemaFromFS(fsys, "db/missing.lp.sql", nil); err == nil || !strings.Contains(err.Error(), "db/missing.lp.sql") {
+		t.Errorf("expected an error naming the missing file, got %v", err)
+	}
+}
+
+func TestLoadSchemaWithOptionsReportsDiskPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.lp.sql"), []byte("CREATE TABLE users (id BIGINT PRIMARY KEY);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSchemaWithOptions(dir, nil)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions returned error: %v", err)
+	}
+	if want := filepath.Join(dir, "users.lp.sql"); loaded.Tables[0].Source == nil || loaded.Tables[0].Source.File != want {
+		t.Errorf("expected source file %s, got %v", want, loaded.Tables[0].Source)
+	}
+
+	missing := filepath.Join(dir, "missing.lp.sql")
+	if _, err := LoadSchemaWithOptions(missing, nil); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("expected an error naming %s, got %v", missing, err)
+	}
+}
+
+func TestLoadSchemaWithOptionsPlainSQLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	dump := "SET lock_timeout = 0;\n" +
+		"CREATE TABLE public.users (id integer NOT NULL);\n" +
+		"ALTER TABLE public.users OWNER TO app;\n" +
+		"COPY public.users (id) FROM stdin;\n1\n\\.\n"
+	if err := os.WriteFile(path, []byte(dump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSchemaWithOptions(path, nil)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions returned error: %v", err)
+	}
+	if len(loaded.Tables) != 1 || loaded.Tables[0].Name != "users" {
+		t.Errorf("expected the users table, got %+v", loaded.Tables)
+	}
+}
+
+func TestLoadSQLFiles(t *testing.T) {
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.sql")
+	posts := filepath.Join(dir, "nested", "posts.lp.sql")
+	if err := os.MkdirAll(filepath.Dir(posts), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(users, []byte("CREATE TABLE users (id integer PRIMARY KEY);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(posts, []byte("CREATE TABLE posts (id integer, user_id integer REFERENCES users (id));\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSQLFiles([]string{users, posts}, nil)
+	if err != nil {
+		t.Fatalf("LoadSQLFiles returned error: %v", err)
+	}
+	if len(loaded.Tables) != 2 {
+		t.Fatalf("expected users and posts, got %+v", loaded.Tables)
+	}
+	if source := loaded.Tables[1].Source; source == nil || source.File != posts {
+		t.Errorf("expected posts to be located in %s, got %+v", posts, source)
+	}
+
+	// The files are one schema, so a table defined in both is a duplicate
+	if _, err := LoadSQLFiles([]string{users, users}, nil); err == nil || !strings.Contains(err.Error(), "duplicate definitions in schema files") {
+		t.Errorf("expected a duplicate definition error, got %v", err)
+	}
+}
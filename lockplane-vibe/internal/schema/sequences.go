@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"math"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// SequenceDiff represents changes to the options or owner of an existing
+// sequence. They are applied with ALTER SEQUENCE, which keeps the sequence's
+// current value unless it is restarted.
+type SequenceDiff struct {
+	SequenceName string            `json:"sequence_name"`
+	Old          database.Sequence `json:"old"`
+	New          database.Sequence `json:"new"`
+	Changes      []string          `json:"changes"` // e.g. ["cache", "restart", "owned_by"]
+}
+
+// SequenceSettings are the effective options of a sequence, with the
+// PostgreSQL defaults filled in for the options left unset
+type SequenceSettings struct {
+	DataType  string
+	Increment int64
+	MinValue  int64
+	MaxValue  int64
+	Start     int64
+	Cache     int64
+	Cycle     bool
+}
+
+// sequenceTypeBounds maps sequence data types to the range of their values
+var sequenceTypeBounds = map[string][2]int64{
+	"smallint": {math.MinInt16, math.MaxInt16},
+	"integer":  {math.MinInt32, math.MaxInt32},
+	"bigint":   {math.MinInt64, math.MaxInt64},
+}
+
+// SequenceDataType returns the normalized data type of a sequence: smallint,
+// integer or bigint (the default)
+func SequenceDataType(seq database.Sequence) string {
+	switch strings.ToLower(strings.TrimSpace(seq.DataType)) {
+	case "smallint", "int2":
+		return "smallint"
+	case "integer", "int", "int4":
+		return "integer"
+	default:
+		return "bigint"
+	}
+}
+
+// EffectiveSequenceSettings returns the options of seq as PostgreSQL applies
+// them: an ascending sequence defaults to MINVALUE 1 and the type's maximum,
+// a descending one to the type's minimum and MAXVALUE -1, and both start at
+// the end they count from
+func EffectiveSequenceSettings(seq database.Sequence) SequenceSettings {
+	settings := SequenceSettings{DataType: SequenceDataType(seq), Increment: 1, Cache: 1, Cycle: seq.Cycle}
+	if seq.Increment != nil && *seq.Increment != 0 {
+		settings.Increment = *seq.Increment
+	}
+	if seq.Cache != nil {
+		settings.Cache = *seq.Cache
+	}
+
+	bounds := sequenceTypeBounds[settings.DataType]
+	if settings.Increment > 0 {
+		settings.MinValue, settings.MaxValue = 1, bounds[1]
+	} else {
+		settings.MinValue, settings.MaxValue = bounds[0], -1
+	}
+	if seq.MinValue != nil {
+		settings.MinValue = *seq.MinValue
+	}
+	if seq.MaxValue != nil {
+		settings.MaxValue = *seq.MaxValue
+	}
+
+	if settings.Increment > 0 {
+		settings.Start = settings.MinValue
+	} else {
+		settings.Start = settings.MaxValue
+	}
+	if seq.Start != nil {
+		settings.Start = *seq.Start
+	}
+	return settings
+}
+
+// NextSequenceValue returns the value the sequence returns next, as of its
+// introspection: the start value when it was never used
+func NextSequenceValue(seq database.Sequence) int64 {
+	settings := EffectiveSequenceSettings(seq)
+	if seq.LastValue == nil {
+		return settings.Start
+	}
+	return *seq.LastValue + settings.Increment
+}
+
+// RestartMovesBackward reports whether restarting seq at restart hands out
+// values it already returned, which can produce duplicate keys. A sequence
+// that was never used can't move backward.
+func RestartMovesBackward(seq database.Sequence, restart int64) bool {
+	if seq.LastValue == nil {
+		return false
+	}
+	if EffectiveSequenceSettings(seq).Increment > 0 {
+		return restart <= *seq.LastValue
+	}
+	return restart >= *seq.LastValue
+}
+
+// NormalizeSequenceOwner qualifies the table of a Sequence.OwnedBy value as
+// QualifiedTableName does, so "public.users.id" and "users.id" compare equal
+func NormalizeSequenceOwner(ownedBy string) string {
+	tableName, columnName, ok := database.SplitSequenceOwner(strings.TrimSpace(ownedBy))
+	if !ok {
+		return ""
+	}
+	return database.QualifiedTableName(database.SplitQualifiedName(tableName)) + "." + columnName
+}
+
+// OwnedBySerialColumn reports whether seq belongs to a serial column of s.
+// Such a sequence is created and dropped with its column, so schema files
+// don't declare it.
+func OwnedBySerialColumn(s *database.Schema, seq database.Sequence) bool {
+	tableName, columnName, ok := database.SplitSequenceOwner(seq.OwnedBy)
+	if !ok {
+		return false
+	}
+	table := database.FindTable(s, tableName)
+	if table == nil {
+		return false
+	}
+	for _, col := range table.Columns {
+		if col.Name == columnName {
+			switch strings.ToLower(col.Type) {
+			case "serial", "bigserial", "smallserial", "serial4", "serial8", "serial2":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// diffSequences adds the sequences added to, removed from and changed in
+// desired to diff. Sequences of serial columns are managed with their column:
+// they are never removed, and keep their owner when desired declares them
+// without one.
+func diffSequences(current, desired *database.Schema, diff *SchemaDiff) {
+	for _, desiredSeq := range desired.Sequences {
+		currentSeq := database.FindSequence(current, desiredSeq.QualifiedName())
+		if currentSeq == nil {
+			diff.AddedSequences = append(diff.AddedSequences, desiredSeq)
+			continue
+		}
+		if changes := sequenceChanges(current, *currentSeq, desiredSeq); len(changes) > 0 {
+			diff.ModifiedSequences = append(diff.ModifiedSequences, SequenceDiff{
+				SequenceName: currentSeq.QualifiedName(),
+				Old:          *currentSeq,
+				New:          desiredSeq,
+				Changes:      changes,
+			})
+		}
+	}
+
+	for _, currentSeq := range current.Sequences {
+		if database.FindSequence(desired, currentSeq.QualifiedName()) == nil && !OwnedBySerialColumn(current, currentSeq) {
+			diff.RemovedSequences = append(diff.RemovedSequences, currentSeq)
+		}
+	}
+}
+
+// sequenceChanges returns the options of an existing sequence that differ in
+// desired, in ALTER SEQUENCE clause order
+func sequenceChanges(currentSchema *database.Schema, current, desired database.Sequence) []string {
+	from, to := EffectiveSequenceSettings(current), EffectiveSequenceSettings(desired)
+	var changes []string
+	if from.DataType != to.DataType {
+		changes = append(changes, "data_type")
+	}
+	if from.Increment != to.Increment {
+		changes = append(changes, "increment")
+	}
+	if from.MinValue != to.MinValue {
+		changes = append(changes, "min_value")
+	}
+	if from.MaxValue != to.MaxValue {
+		changes = append(changes, "max_value")
+	}
+	if from.Start != to.Start {
+		changes = append(changes, "start")
+	}
+	if from.Cache != to.Cache {
+		changes = append(changes, "cache")
+	}
+	if from.Cycle != to.Cycle {
+		changes = append(changes, "cycle")
+	}
+	if desired.Restart != nil && *desired.Restart != NextSequenceValue(current) {
+		changes = append(changes, "restart")
+	}
+
+	owner := NormalizeSequenceOwner(desired.OwnedBy)
+	if owner != NormalizeSequenceOwner(current.OwnedBy) && (owner != "" || !OwnedBySerialColumn(currentSchema, current)) {
+		changes = append(changes, "owned_by")
+	}
+	return changes
+}
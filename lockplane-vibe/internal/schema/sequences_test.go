@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestDiffSchemas_Sequences(t *testing.T) {
+	current := &database.Schema{
+		Tables: []database.Table{{
+			Name:   "orders",
+			Schema: "public",
+			Columns: []database.Column{
+				{Name: "id", Type: "serial", IsPrimaryKey: true},
+				{Name: "number", Type: "bigint"},
+			},
+		}},
+		Sequences: []database.Sequence{
+			// As introspected: every option is set
+			{Name: "order_numbers", Schema: "public", DataType: "bigint", Increment: int64Ptr(1), MinValue: int64Ptr(1),
+				MaxValue: int64Ptr(9223372036854775807), Start: int64Ptr(1), Cache: int64Ptr(1), LastValue: int64Ptr(500)},
+			{Name: "orders_id_seq", Schema: "public", DataType: "integer", Increment: int64Ptr(1), MinValue: int64Ptr(1),
+				MaxValue: int64Ptr(2147483647), Start: int64Ptr(1), Cache: int64Ptr(1), OwnedBy: "orders.id"},
+			{Name: "legacy", Schema: "public"},
+		},
+	}
+	desired := &database.Schema{
+		Tables: []database.Table{{
+			Name: "orders",
+			Columns: []database.Column{
+				{Name: "id", Type: "serial", IsPrimaryKey: true},
+				{Name: "number", Type: "bigint"},
+			},
+		}},
+		Sequences: []database.Sequence{
+			{Name: "order_numbers", Cache: int64Ptr(20), Restart: int64Ptr(1000), OwnedBy: "public.orders.number"},
+			{Name: "invoice_numbers", Start: int64Ptr(100)},
+		},
+	}
+
+	diff := DiffSchemas(current, desired)
+
+	if len(diff.AddedSequences) != 1 || diff.AddedSequences[0].Name != "invoice_numbers" {
+		t.Errorf("Expected invoice_numbers to be added, got %+v", diff.AddedSequences)
+	}
+	// The sequence of the serial column isn't declared, but belongs to its column
+	if len(diff.RemovedSequences) != 1 || diff.RemovedSequences[0].Name != "legacy" {
+		t.Errorf("Expected only legacy to be removed, got %+v", diff.RemovedSequences)
+	}
+	if len(diff.ModifiedSequences) != 1 {
+		t.Fatalf("Expected 1 modified sequence, got %+v", diff.ModifiedSequences)
+	}
+	want := []string{"cache", "restart", "owned_by"}
+	if got := diff.ModifiedSequences[0].Changes; !slices.Equal(got, want) {
+		t.Errorf("Expected changes %v, got %v", want, got)
+	}
+}
+
+func TestDiffSchemas_SequenceDefaultsMatchIntrospection(t *testing.T) {
+	current := &database.Schema{Sequences: []database.Sequence{
+		{Name: "countdown", DataType: "integer", Increment: int64Ptr(-1), MinValue: int64Ptr(-2147483648),
+			MaxValue: int64Ptr(-1), Start: int64Ptr(-1), Cache: int64Ptr(1)},
+	}}
+	desired := &database.Schema{Sequences: []database.Sequence{
+		{Name: "countdown", DataType: "int4", Increment: int64Ptr(-1)},
+	}}
+
+	if diff := DiffSchemas(current, desired); !diff.IsEmpty() {
+		t.Errorf("Expected no changes for default options, got %+v", diff.ModifiedSequences)
+	}
+}
+
+func TestDiffSchemas_SequenceRestartAlreadyApplied(t *testing.T) {
+	current := &database.Schema{Sequences: []database.Sequence{{Name: "tickets", LastValue: int64Ptr(999)}}}
+	desired := &database.Schema{Sequences: []database.Sequence{{Name: "tickets", Restart: int64Ptr(1000)}}}
+
+	if diff := DiffSchemas(current, desired); !diff.IsEmpty() {
+		t.Errorf("Expected no restart when the sequence returns 1000 next, got %+v", diff.ModifiedSequences)
+	}
+}
+
+func TestDiffSchemas_SerialSequenceKeepsOwner(t *testing.T) {
+	current := &database.Schema{
+		Tables:    []database.Table{{Name: "users", Columns: []database.Column{{Name: "id", Type: "bigserial"}}}},
+		Sequences: []database.Sequence{{Name: "users_id_seq", OwnedBy: "users.id", LastValue: int64Ptr(10)}},
+	}
+	desired := &database.Schema{
+		Tables:    current.Tables,
+		Sequences: []database.Sequence{{Name: "users_id_seq", Cache: int64Ptr(5)}},
+	}
+
+	diff := DiffSchemas(current, desired)
+	if len(diff.ModifiedSequences) != 1 || !slices.Equal(diff.ModifiedSequences[0].Changes, []string{"cache"}) {
+		t.Errorf("Expected only the cache to change, got %+v", diff.ModifiedSequences)
+	}
+}
+
+func TestRestartMovesBackward(t *testing.T) {
+	tests := []struct {
+		name    string
+		seq     database.Sequence
+		restart int64
+		want    bool
+	}{
+		{"never used", database.Sequence{}, 1, false},
+		{"ascending past last value", database.Sequence{LastValue: int64Ptr(100)}, 101, false},
+		{"ascending at last value", database.Sequence{LastValue: int64Ptr(100)}, 100, true},
+		{"ascending below last value", database.Sequence{LastValue: int64Ptr(100)}, 1, true},
+		{"descending past last value", database.Sequence{Increment: int64Ptr(-1), LastValue: int64Ptr(-100)}, -101, false},
+		{"descending above last value", database.Sequence{Increment: int64Ptr(-1), LastValue: int64Ptr(-100)}, -1, true},
+	}
+	for _, tt := range tests {
+		if got := RestartMovesBackward(tt.seq, tt.restart); got != tt.want {
+			t.Errorf("%s: RestartMovesBackward = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComputeSchemaHash_Sequences(t *testing.T) {
+	tables := []database.Table{{Name: "users", Columns: []database.Column{{Name: "id", Type: "serial"}}}}
+	base, err := ComputeSchemaHash(&database.Schema{Tables: tables})
+	if err != nil {
+		t.Fatalf("ComputeSchemaHash returned error: %v", err)
+	}
+
+	// Sequences of serial columns, current values and restarts aren't hashed
+	withSerial, _ := ComputeSchemaHash(&database.Schema{Tables: tables, Sequences: []database.Sequence{
+		{Name: "users_id_seq", DataType: "integer", OwnedBy: "users.id", LastValue: int64Ptr(42)},
+	}})
+	if withSerial != base {
+		t.Error("Expected the sequence of a serial column not to change the hash")
+	}
+	used, _ := ComputeSchemaHash(&database.Schema{Tables: tables, Sequences: []database.Sequence{{Name: "s", LastValue: int64Ptr(42)}}})
+	restarted, _ := ComputeSchemaHash(&database.Schema{Tables: tables, Sequences: []database.Sequence{{Name: "s", Restart: int64Ptr(1)}}})
+	if used != restarted {
+		t.Error("Expected the current value and restart not to change the hash")
+	}
+	if used == base {
+		t.Error("Expected a standalone sequence to change the hash")
+	}
+	cached, _ := ComputeSchemaHash(&database.Schema{Tables: tables, Sequences: []database.Sequence{{Name: "s", Cache: int64Ptr(10)}}})
+	if cached == used {
+		t.Error("Expected a changed option to change the hash")
+	}
+}
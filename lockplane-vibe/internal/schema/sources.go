@@ -0,0 +1,73 @@
+package schema
+
+import "github.com/lockplane/lockplane/database"
+
+// annotateSources records on each table, column, index and foreign key of
+// schema where it is defined in files. Objects are matched by name and the
+// first definition wins. Columns, indexes and foreign keys without a matching
+// definition (e.g. copied by CREATE TABLE ... LIKE, or unnamed inline
+// constraints) point at their table.
+func annotateSources(schema *database.Schema, files []SourceFile) {
+	if schema == nil || len(files) == 0 {
+		return
+	}
+
+	locations := make(map[string]SourceLocation)
+	record := func(kind, key string, loc SourceLocation) {
+		mapKey := kind + "\x00" + key
+		if _, ok := locations[mapKey]; !ok {
+			locations[mapKey] = loc
+		}
+	}
+	for _, file := range files {
+		walkDefinitions(file, func(def definition) {
+			switch def.kind {
+			case ObjectKindTable:
+				record(ObjectKindTable, def.table, def.location)
+			case objectKindColumn, ObjectKindIndex:
+				record(def.kind, def.table+"."+def.name, def.location)
+			case ObjectKindConstraint:
+				if def.name == "" {
+					return
+				}
+				// Unique and primary key constraints are loaded as indexes
+				kind := ObjectKindIndex
+				if def.foreign {
+					kind = ObjectKindConstraint
+				}
+				record(kind, def.table+"."+def.name, def.location)
+			}
+		})
+	}
+
+	lookup := func(kind, key string) *SourceLocation {
+		if loc, ok := locations[kind+"\x00"+key]; ok {
+			return &loc
+		}
+		return nil
+	}
+
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		name := qualifiedName(table.Schema, table.Name)
+		table.Source = lookup(ObjectKindTable, name)
+		for j := range table.Columns {
+			col := &table.Columns[j]
+			if col.Source = lookup(objectKindColumn, name+"."+col.Name); col.Source == nil {
+				col.Source = table.Source
+			}
+		}
+		for j := range table.Indexes {
+			idx := &table.Indexes[j]
+			if idx.Source = lookup(ObjectKindIndex, name+"."+idx.Name); idx.Source == nil {
+				idx.Source = table.Source
+			}
+		}
+		for j := range table.ForeignKeys {
+			fk := &table.ForeignKeys[j]
+			if fk.Source = lookup(ObjectKindConstraint, name+"."+fk.Name); fk.Source == nil {
+				fk.Source = table.Source
+			}
+		}
+	}
+}
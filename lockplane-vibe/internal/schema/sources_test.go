@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestLoadSchemaRecordsSourceLocations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_users.lp.sql": `CREATE TABLE users (
+  id BIGINT PRIMARY KEY,
+  email TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX users_email_idx ON users (email);
+`,
+		"002_posts.lp.sql": `-- Posts
+CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  author_id BIGINT
+);
+ALTER TABLE posts ADD COLUMN ${title_column} TEXT;
+ALTER TABLE posts ADD CONSTRAINT posts_author_fkey FOREIGN KEY (author_id) REFERENCES users (id);
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	loaded, err := LoadSchemaWithOptions(dir, &SchemaLoadOptions{Variables: map[string]string{"title_column": "title"}})
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+
+	usersFile := filepath.Join(dir, "001_users.lp.sql")
+	postsFile := filepath.Join(dir, "002_posts.lp.sql")
+	users := findTestTable(t, loaded, "users")
+	posts := findTestTable(t, loaded, "posts")
+
+	assertSource(t, "users", users.Source, usersFile, 1)
+	assertSource(t, "users.email", findTestColumn(t, users, "email").Source, usersFile, 3)
+	for _, idx := range users.Indexes {
+		if idx.Name == "users_email_idx" {
+			assertSource(t, "users_email_idx", idx.Source, usersFile, 6)
+		}
+	}
+
+	assertSource(t, "posts", posts.Source, postsFile, 2)
+	// Columns added through a template variable point at what the user wrote
+	title := findTestColumn(t, posts, "title")
+	assertSource(t, "posts.title", title.Source, postsFile, 6)
+	if title.Source.Column != 30 {
+		t.Errorf("Expected posts.title at column 30, got %d", title.Source.Column)
+	}
+	if len(posts.ForeignKeys) != 1 {
+		t.Fatalf("Expected 1 foreign key on posts, got %d", len(posts.ForeignKeys))
+	}
+	assertSource(t, "posts_author_fkey", posts.ForeignKeys[0].Source, postsFile, 7)
+}
+
+func TestLoadSchemaSourceLocationsCRLF(t *testing.T) {
+	dir := t.TempDir()
+	content := "-- Saved on Windows\r\nCREATE TABLE users (\r\n  id BIGINT PRIMARY KEY\r\n);\r\nALTER TABLE users ADD COLUMN ${email_column} TEXT;\r\n"
+	file := filepath.Join(dir, "users.lp.sql")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	loaded, err := LoadSchemaWithOptions(dir, &SchemaLoadOptions{Variables: map[string]string{"email_column": "email"}})
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	users := findTestTable(t, loaded, "users")
+	assertSource(t, "users", users.Source, file, 2)
+	email := findTestColumn(t, users, "email")
+	assertSource(t, "users.email", email.Source, file, 5)
+	if email.Source.Column != 30 {
+		t.Errorf("Expected users.email at column 30, got %d", email.Source.Column)
+	}
+}
+
+func TestSourceLocationsAreNotSerialized(t *testing.T) {
+	loc := &database.SourceLocation{File: "schema.lp.sql", Line: 1}
+	s := &database.Schema{Tables: []database.Table{{Name: "users", Source: loc, Columns: []database.Column{{Name: "id", Type: "bigint", Source: loc}}}}}
+	withoutSource := &database.Schema{Tables: []database.Table{{Name: "users", Columns: []database.Column{{Name: "id", Type: "bigint"}}}}}
+
+	hash, err := ComputeSchemaHash(s)
+	if err != nil {
+		t.Fatalf("Failed to hash schema: %v", err)
+	}
+	want, err := ComputeSchemaHash(withoutSource)
+	if err != nil {
+		t.Fatalf("Failed to hash schema: %v", err)
+	}
+	if hash != want {
+		t.Errorf("Expected source locations not to change the schema hash")
+	}
+}
+
+func findTestTable(t *testing.T, s *database.Schema, name string) *database.Table {
+	t.Helper()
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	t.Fatalf("Table %s not found", name)
+	return nil
+}
+
+func findTestColumn(t *testing.T, table *database.Table, name string) *database.Column {
+	t.Helper()
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	t.Fatalf("Column %s.%s not found", table.Name, name)
+	return nil
+}
+
+func assertSource(t *testing.T, object string, loc *database.SourceLocation, file string, line int) {
+	t.Helper()
+	if loc == nil {
+		t.Errorf("Expected a source location for %s", object)
+		return
+	}
+	if loc.File != file || loc.Line != line {
+		t.Errorf("Expected %s at %s:%d, got %s", object, file, line, loc)
+	}
+}
@@ -0,0 +1,197 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+)
+
+// templateReference matches ${name} and the escaped form $${name}
+var templateReference = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// templateVariableName is the syntax of a variable name inside ${...}
+var templateVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// UndefinedVariableError reports a ${name} reference with no value
+type UndefinedVariableError struct {
+	Name     string         `json:"name"`
+	Location SourceLocation `json:"location"`
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("%s: undefined variable ${%s}", e.Location, e.Name)
+}
+
+// ExpandedSource is a schema file after template expansion. It maps positions
+// in the expanded text back to the original file so errors can point at what
+// the user wrote.
+type ExpandedSource struct {
+	File     string
+	Original string
+	Text     string
+	segments []templateSegment
+}
+
+// templateSegment is a run of expanded text. Copied text maps one-to-one onto
+// the original; a substituted value maps onto the start of its reference.
+type templateSegment struct {
+	expandedStart int
+	originalStart int
+	length        int
+	substituted   bool
+}
+
+// ExpandTemplate replaces ${name} references in content with values from vars.
+// $${name} is written out as a literal ${name}. When strict is set, references
+// to undefined variables are errors; otherwise they are left as written.
+func ExpandTemplate(file, content string, vars map[string]string, strict bool) (*ExpandedSource, error) {
+	src := &ExpandedSource{File: file, Original: content}
+	var builder strings.Builder
+	var errs []error
+
+	copyText := func(originalStart, originalEnd int) {
+		if originalEnd <= originalStart {
+			return
+		}
+		src.segments = append(src.segments, templateSegment{
+			expandedStart: builder.Len(),
+			originalStart: originalStart,
+			length:        originalEnd - originalStart,
+		})
+		builder.WriteString(content[originalStart:originalEnd])
+	}
+
+	last := 0
+	for _, match := range templateReference.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[0], match[1]
+		name := strings.TrimSpace(content[match[2]:match[3]])
+
+		if strings.HasPrefix(content[start:], "$${") {
+			// Escaped: drop the first $ and keep the rest as written
+			copyText(last, start)
+			copyText(start+1, end)
+			last = end
+			continue
+		}
+
+		if !templateVariableName.MatchString(name) {
+			continue
+		}
+
+		value, ok := vars[name]
+		if !ok {
+			if strict {
+				errs = append(errs, &UndefinedVariableError{Name: name, Location: lineColumn(file, content, start)})
+			}
+			continue
+		}
+
+		copyText(last, start)
+		src.segments = append(src.segments, templateSegment{
+			expandedStart: builder.Len(),
+			originalStart: start,
+			length:        len(value),
+			substituted:   true,
+		})
+		builder.WriteString(value)
+		last = end
+	}
+	copyText(last, len(content))
+
+	src.Text = builder.String()
+	if len(errs) > 0 {
+		return src, errors.Join(errs...)
+	}
+	return src, nil
+}
+
+// OriginalOffset maps a byte offset in the expanded text to the original file
+func (s *ExpandedSource) OriginalOffset(offset int) int {
+	idx := sort.Search(len(s.segments), func(i int) bool {
+		return s.segments[i].expandedStart > offset
+	}) - 1
+	if idx < 0 {
+		return offset
+	}
+
+	seg := s.segments[idx]
+	if seg.substituted {
+		return seg.originalStart
+	}
+	delta := offset - seg.expandedStart
+	if delta > seg.length {
+		delta = seg.length
+	}
+	return seg.originalStart + delta
+}
+
+// Location returns the original file location of an offset in the expanded text
+func (s *ExpandedSource) Location(offset int) SourceLocation {
+	return lineColumn(s.File, s.Original, s.OriginalOffset(offset))
+}
+
+// OriginalPosition maps a 1-based line and column in the expanded text to the
+// original file
+func (s *ExpandedSource) OriginalPosition(line, column int) (int, int) {
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := strings.IndexByte(s.Text[offset:], '\n')
+		if next < 0 {
+			offset = len(s.Text)
+			break
+		}
+		offset += next + 1
+	}
+	offset += column - 1
+	if offset > len(s.Text) {
+		offset = len(s.Text)
+	}
+
+	loc := s.Location(offset)
+	return loc.Line, loc.Column
+}
+
+// UndefinedVariables returns every undefined variable error wrapped in err
+func UndefinedVariables(err error) []*UndefinedVariableError {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *UndefinedVariableError:
+		return []*UndefinedVariableError{e}
+	case interface{ Unwrap() []error }:
+		var undefined []*UndefinedVariableError
+		for _, inner := range e.Unwrap() {
+			undefined = append(undefined, UndefinedVariables(inner)...)
+		}
+		return undefined
+	default:
+		return UndefinedVariables(errors.Unwrap(err))
+	}
+}
+
+// ExpandSchemaFile expands the template references in a schema file when the
+// load options carry variables; otherwise the content is used as written.
+// CRLF line endings are normalized first, so source locations don't count
+// carriage returns.
+func ExpandSchemaFile(file, content string, opts *SchemaLoadOptions) (*ExpandedSource, error) {
+	content = sqlsplit.NormalizeLineEndings(content)
+	if opts == nil || opts.Variables == nil {
+		return &ExpandedSource{File: file, Original: content, Text: content}, nil
+	}
+	return ExpandTemplate(file, content, opts.Variables, !opts.AllowUndefinedVariables)
+}
+
+// lineColumn converts a byte offset into a 1-based line and column
+func lineColumn(file, content string, offset int) SourceLocation {
+	if offset < 0 || offset > len(content) {
+		offset = 0
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndexByte(before, '\n')
+	return SourceLocation{File: file, Line: line, Column: column}
+}
@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]string{
+		"app_role":              "app_user",
+		"lockplane.environment": "staging",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no references", "CREATE TABLE users (id int);", "CREATE TABLE users (id int);"},
+		{"variable", "GRANT SELECT ON users TO ${app_role};", "GRANT SELECT ON users TO app_user;"},
+		{"environment", "COMMENT ON TABLE users IS '${lockplane.environment}';", "COMMENT ON TABLE users IS 'staging';"},
+		{"whitespace inside braces", "GRANT ALL ON users TO ${ app_role };", "GRANT ALL ON users TO app_user;"},
+		{"escaped reference", "SELECT '$${app_role}';", "SELECT '${app_role}';"},
+		{"not a variable name", "SELECT '${1abc}';", "SELECT '${1abc}';"},
+		{"dollar quoting is untouched", "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1 $$ LANGUAGE sql;", "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1 $$ LANGUAGE sql;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := ExpandTemplate("schema.lp.sql", tt.content, vars, true)
+			if err != nil {
+				t.Fatalf("Failed to expand: %v", err)
+			}
+			if expanded.Text != tt.want {
+				t.Errorf("Expanded text = %q, want %q", expanded.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTemplateUndefinedVariables(t *testing.T) {
+	content := "CREATE TABLE users (id int);\nGRANT SELECT ON users TO ${reader};\nGRANT ALL ON users TO ${writer};\n"
+
+	expanded, err := ExpandTemplate("roles.lp.sql", content, map[string]string{}, true)
+	if err == nil {
+		t.Fatal("Expected an error for undefined variables")
+	}
+
+	undefined := UndefinedVariables(err)
+	if len(undefined) != 2 {
+		t.Fatalf("Expected 2 undefined variables, got %d: %v", len(undefined), err)
+	}
+	if undefined[0].Name != "reader" || undefined[0].Location.String() != "roles.lp.sql:2:26" {
+		t.Errorf("Unexpected first error: %v", undefined[0])
+	}
+	if undefined[1].Name != "writer" || undefined[1].Location.String() != "roles.lp.sql:3:23" {
+		t.Errorf("Unexpected second error: %v", undefined[1])
+	}
+	if expanded.Text != content {
+		t.Errorf("Expected undefined references to be left in place, got %q", expanded.Text)
+	}
+
+	// Non-strict expansion leaves the references without failing
+	if _, err := ExpandTemplate("roles.lp.sql", content, map[string]string{}, false); err != nil {
+		t.Errorf("Expected no error in non-strict mode, got %v", err)
+	}
+}
+
+func TestExpandedSourcePositions(t *testing.T) {
+	content := "-- ${short}\nGRANT ALL ON users TO ${role};\nCREATE TABLE posts (id int);\n"
+	vars := map[string]string{"short": "a\nmuch\nlonger value", "role": "r"}
+
+	expanded, err := ExpandTemplate("schema.lp.sql", content, vars, true)
+	if err != nil {
+		t.Fatalf("Failed to expand: %v", err)
+	}
+
+	tests := []struct {
+		name                 string
+		needle               string
+		wantLine, wantColumn int
+	}{
+		{"text after a multi-line value", "GRANT", 2, 1},
+		{"inside a substituted value", "longer", 1, 4},
+		{"substituted value", "r;", 2, 23},
+		{"text after a shorter value", "CREATE TABLE posts", 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(expanded.Text, tt.needle)
+			before := expanded.Text[:offset]
+			line := strings.Count(before, "\n") + 1
+			column := offset - strings.LastIndexByte(before, '\n')
+
+			gotLine, gotColumn := expanded.OriginalPosition(line, column)
+			if gotLine != tt.wantLine || gotColumn != tt.wantColumn {
+				t.Errorf("OriginalPosition(%d, %d) = %d:%d, want %d:%d", line, column, gotLine, gotColumn, tt.wantLine, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestLoadSchemaExpandsVariables(t *testing.T) {
+	dir := t.TempDir()
+	content := "CREATE TABLE ${prefix}_users (\n    id integer PRIMARY KEY\n);\n"
+	if err := os.WriteFile(filepath.Join(dir, "users.lp.sql"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+
+	schema, err := LoadSchemaWithOptions(dir, &SchemaLoadOptions{Variables: map[string]string{"prefix": "app"}})
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "app_users" {
+		t.Fatalf("Expected table app_users, got %+v", schema.Tables)
+	}
+
+	_, err = LoadSchemaWithOptions(dir, &SchemaLoadOptions{Variables: map[string]string{}})
+	var undefined *UndefinedVariableError
+	if !errors.As(err, &undefined) {
+		t.Fatalf("Expected an undefined variable error, got %v", err)
+	}
+	if undefined.Location.Line != 1 || undefined.Location.Column != 14 {
+		t.Errorf("Expected error at 1:14, got %s", undefined.Location)
+	}
+}
+
+func TestFindDuplicateDefinitionsMapsExpandedPositions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"001.lp.sql": "/* owner: ${owner} */\nCREATE TABLE users (id int);\n",
+		"002.lp.sql": "/* owner: ${owner} */\n\nCREATE TABLE users (id int);\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write schema file: %v", err)
+		}
+	}
+
+	opts := &SchemaLoadOptions{Variables: map[string]string{"owner": "a\nmulti-line\nvalue"}}
+	duplicates, err := FindDuplicateDefinitionsInDir(dir, opts)
+	if err != nil {
+		t.Fatalf("Failed to find duplicates: %v", err)
+	}
+	if len(duplicates) != 1 {
+		t.Fatalf("Expected 1 duplicate, got %d", len(duplicates))
+	}
+	if duplicates[0].First.Line != 2 || duplicates[0].Duplicate.Line != 3 {
+		t.Errorf("Expected definitions at lines 2 and 3, got %s and %s", duplicates[0].First, duplicates[0].Duplicate)
+	}
+}
@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"regexp"
+	"time"
+)
+
+// Soft-dropped columns are renamed to a tombstone "deleted_<name>_<YYYYMMDD>",
+// recording the date, and dropped later by lockplane cleanup-tombstones
+const (
+	tombstonePrefix     = "deleted_"
+	tombstoneDateLayout = "20060102"
+	// maxIdentifierLength is PostgreSQL's limit; longer names are truncated
+	maxIdentifierLength = 63
+)
+
+var tombstonePattern = regexp.MustCompile(`^deleted_(.+)_(\d{8})$`)
+
+// TombstoneColumnName returns the name a column soft-dropped on date is
+// renamed to. Long column names are shortened to fit PostgreSQL's identifier
+// limit.
+func TombstoneColumnName(column string, date time.Time) string {
+	suffix := "_" + date.UTC().Format(tombstoneDateLayout)
+	if room := maxIdentifierLength - len(tombstonePrefix) - len(suffix); len(column) > room {
+		column = column[:room]
+	}
+	return tombstonePrefix + column + suffix
+}
+
+// ParseTombstoneColumnName returns the original name and soft-drop date of a
+// tombstone column, and false when name isn't one
+func ParseTombstoneColumnName(name string) (string, time.Time, bool) {
+	match := tombstonePattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", time.Time{}, false
+	}
+	date, err := time.Parse(tombstoneDateLayout, match[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return match[1], date, true
+}
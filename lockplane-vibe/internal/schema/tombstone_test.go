@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+func TestTombstoneColumnName(t *testing.T) {
+	date := time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC)
+	name := TombstoneColumnName("email", date)
+	if name != "deleted_email_20260314" {
+		t.Fatalf("Unexpected tombstone name %q", name)
+	}
+	column, parsed, ok := ParseTombstoneColumnName(name)
+	if !ok || column != "email" || !parsed.Equal(time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected email soft-dropped on 2026-03-14, got %q %v %v", column, parsed, ok)
+	}
+
+	long := TombstoneColumnName(strings.Repeat("x", 70), date)
+	if len(long) != 63 || !strings.HasSuffix(long, "_20260314") {
+		t.Errorf("Expected a truncated 63 character tombstone, got %q", long)
+	}
+
+	for _, name := range []string{"email", "deleted_email", "deleted_email_2026031", "deleted__20260314", "deleted_email_20261399"} {
+		if _, _, ok := ParseTombstoneColumnName(name); ok {
+			t.Errorf("Expected %q not to be a tombstone", name)
+		}
+	}
+}
+
+func TestDiffSchemas_IgnoreTombstones(t *testing.T) {
+	current := &database.Schema{Tables: []database.Table{{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer"},
+			{Name: "deleted_email_20260314", Type: "text"},
+			{Name: "legacy", Type: "text"},
+		},
+	}}}
+	desired := &database.Schema{Tables: []database.Table{{
+		Name:    "users",
+		Columns: []database.Column{{Name: "id", Type: "integer"}},
+	}}}
+
+	tableDiff := DiffSchemas(current, desired).ModifiedTables[0]
+	if len(tableDiff.RemovedColumns) != 2 {
+		t.Errorf("Expected tombstones to be removed by default, got %+v", tableDiff.RemovedColumns)
+	}
+
+	tableDiff = DiffSchemasWithOptions(current, desired, DiffOptions{IgnoreTombstones: true}).ModifiedTables[0]
+	if len(tableDiff.RemovedColumns) != 1 || tableDiff.RemovedColumns[0].Name != "legacy" {
+		t.Errorf("Expected only legacy to be removed, got %+v", tableDiff.RemovedColumns)
+	}
+}
@@ -0,0 +1,292 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+)
+
+// TypeMap maps column types for each target dialect. Keys of the inner map are
+// lowercase source types (e.g. "uuid" or "varchar(255)"); values are the type
+// to use in the target dialect.
+type TypeMap map[database.Dialect]map[string]string
+
+// defaultTypeMap translates PostgreSQL types that SQLite has no equivalent for.
+// serial maps to INTEGER: an INTEGER PRIMARY KEY column is already assigned
+// automatically by SQLite, and AUTOINCREMENT cannot be introspected.
+var defaultTypeMap = TypeMap{
+	database.DialectSQLite: {
+		"uuid":                        "TEXT",
+		"json":                        "TEXT",
+		"jsonb":                       "TEXT",
+		"timestamptz":                 "TEXT", // ISO 8601 string
+		"timestamp with time zone":    "TEXT",
+		"timestamp":                   "TEXT",
+		"timestamp without time zone": "TEXT",
+		"smallserial":                 "INTEGER",
+		"serial":                      "INTEGER",
+		"bigserial":                   "INTEGER",
+	},
+}
+
+// DefaultTypeMap returns a copy of the built-in cross-dialect type mappings
+func DefaultTypeMap() TypeMap {
+	return defaultTypeMap.Merge(nil)
+}
+
+// Merge returns a new TypeMap with overrides applied on top of m
+func (m TypeMap) Merge(overrides TypeMap) TypeMap {
+	merged := make(TypeMap, len(m)+len(overrides))
+	for _, source := range []TypeMap{m, overrides} {
+		for dialect, types := range source {
+			if merged[dialect] == nil {
+				merged[dialect] = make(map[string]string, len(types))
+			}
+			for from, to := range types {
+				merged[dialect][strings.ToLower(strings.TrimSpace(from))] = to
+			}
+		}
+	}
+	return merged
+}
+
+// Lookup returns the target type for a column type in the given dialect. An
+// exact match (including modifiers such as "(255)") wins over the base type.
+func (m TypeMap) Lookup(dialect database.Dialect, columnType string) (string, bool) {
+	types := m[dialect]
+	if len(types) == 0 {
+		return "", false
+	}
+
+	key := strings.ToLower(strings.TrimSpace(columnType))
+	if mapped, ok := types[key]; ok {
+		return mapped, true
+	}
+	if base := baseType(key); base != key {
+		if mapped, ok := types[base]; ok {
+			return mapped, true
+		}
+	}
+	return "", false
+}
+
+// TranslateSchema returns a copy of schema with column types mapped into the
+// target dialect. Index NULLS ordering, column storage modes and statistics
+// targets, and table storage parameters are dropped for SQLite. The schema is
+// returned unchanged when either dialect is unknown or they already match.
+func TranslateSchema(schema *database.Schema, target database.Dialect, types TypeMap) *database.Schema {
+	if schema == nil || target == database.DialectUnknown || schema.Dialect == database.DialectUnknown || schema.Dialect == target {
+		return schema
+	}
+
+	translated := *schema
+	translated.Dialect = target
+	translated.Tables = make([]database.Table, len(schema.Tables))
+	for i, table := range schema.Tables {
+		table.Columns = append([]database.Column(nil), table.Columns...)
+		for j := range table.Columns {
+			col := &table.Columns[j]
+			if target == database.DialectSQLite {
+				col.Storage = nil
+				col.StatisticsTarget = nil
+			}
+			mapped, ok := types.Lookup(target, col.LogicalType())
+			if !ok {
+				continue
+			}
+			col.Type = mapped
+			col.TypeMetadata = &database.TypeMetadata{
+				Logical: strings.ToLower(mapped),
+				Raw:     mapped,
+				Dialect: target,
+			}
+		}
+		if target == database.DialectSQLite {
+			table.Indexes = withoutNullsOrdering(table.Indexes)
+			table.StorageParameters = nil
+		}
+		translated.Tables[i] = table
+	}
+	return &translated
+}
+
+// TypeEquivalents lists, for each target dialect, the introspected types that a
+// column type written for another dialect may match without being reported as
+// a change. Keys of the inner map are lowercase source types without modifiers
+// (e.g. "boolean"); values are the lowercase target types considered equal.
+type TypeEquivalents map[database.Dialect]map[string][]string
+
+// defaultTypeEquivalents covers types that the other dialect stores under a
+// different name, in both directions. SQLite only knows a handful of storage
+// classes, so a SQLite schema matches several PostgreSQL types.
+var defaultTypeEquivalents = TypeEquivalents{
+	database.DialectSQLite: {
+		"boolean":           {"integer"},
+		"bool":              {"integer"},
+		"smallint":          {"integer"},
+		"int2":              {"integer"},
+		"int":               {"integer"},
+		"int4":              {"integer"},
+		"bigint":            {"integer"},
+		"int8":              {"integer"},
+		"real":              {"real"},
+		"float4":            {"real"},
+		"double precision":  {"real"},
+		"float8":            {"real"},
+		"numeric":           {"numeric", "real"},
+		"decimal":           {"numeric", "real"},
+		"varchar":           {"text"},
+		"character varying": {"text"},
+		"char":              {"text"},
+		"character":         {"text"},
+		"citext":            {"text"},
+		"date":              {"text"},
+		"time":              {"text"},
+		"bytea":             {"blob"},
+	},
+	database.DialectPostgres: {
+		"integer": {"integer", "int4", "smallint", "int2", "bigint", "int8", "boolean", "bool"},
+		"int":     {"integer", "int4", "smallint", "int2", "bigint", "int8", "boolean", "bool"},
+		"text": {
+			"text", "varchar", "character varying", "char", "character", "citext", "uuid", "json", "jsonb",
+			"date", "time", "timestamp", "timestamptz", "timestamp with time zone", "timestamp without time zone",
+		},
+		"real":    {"real", "float4", "double precision", "float8"},
+		"numeric": {"numeric", "decimal"},
+		"blob":    {"bytea"},
+	},
+}
+
+// DefaultTypeEquivalents returns a copy of the built-in cross-dialect type
+// equivalences
+func DefaultTypeEquivalents() TypeEquivalents {
+	return defaultTypeEquivalents.Merge(nil)
+}
+
+// Merge returns new TypeEquivalents with overrides applied on top of e. An
+// override replaces the whole list for its source type.
+func (e TypeEquivalents) Merge(overrides TypeEquivalents) TypeEquivalents {
+	merged := make(TypeEquivalents, len(e)+len(overrides))
+	for _, source := range []TypeEquivalents{e, overrides} {
+		for dialect, types := range source {
+			if merged[dialect] == nil {
+				merged[dialect] = make(map[string][]string, len(types))
+			}
+			for from, to := range types {
+				normalized := make([]string, len(to))
+				for i, t := range to {
+					normalized[i] = baseType(t)
+				}
+				merged[dialect][baseType(from)] = normalized
+			}
+		}
+	}
+	return merged
+}
+
+// Equivalent reports whether sourceType, written for another dialect, matches
+// targetType introspected from a database of the target dialect. Modifiers such
+// as "(255)" are ignored on both sides.
+func (e TypeEquivalents) Equivalent(target database.Dialect, sourceType, targetType string) bool {
+	for _, candidate := range e[target][baseType(sourceType)] {
+		if candidate == baseType(targetType) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseType lowercases a column type and strips its modifiers, wherever they
+// appear: "varchar(255)" becomes "varchar" and "timestamp(3) with time zone"
+// becomes "timestamp with time zone"
+func baseType(columnType string) string {
+	key := strings.ToLower(columnType)
+	for {
+		open := strings.Index(key, "(")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(key[open:], ")")
+		if end < 0 {
+			key = key[:open]
+			break
+		}
+		key = key[:open] + key[open+end+1:]
+	}
+	return strings.Join(strings.Fields(key), " ")
+}
+
+// withoutNullsOrdering drops NULLS FIRST/LAST from index columns, which
+// SQLite indexes cannot express, keeping ASC/DESC
+func withoutNullsOrdering(indexes []database.Index) []database.Index {
+	result := make([]database.Index, len(indexes))
+	for i, idx := range indexes {
+		cols := idx.KeyColumns()
+		for j := range cols {
+			cols[j].NullsOrder = ""
+		}
+		idx.SetKeyColumns(cols)
+		result[i] = idx
+	}
+	return result
+}
+
+// AlignDialects translates desired into the dialect of current, so that
+// schemas written for one database can be diffed against another without
+// reporting type changes for equivalent types. It uses the default type
+// equivalences; see AlignDialectsWithEquivalents.
+func AlignDialects(current, desired *database.Schema, types TypeMap) *database.Schema {
+	return AlignDialectsWithEquivalents(current, desired, types, DefaultTypeEquivalents())
+}
+
+// AlignDialectsWithEquivalents translates desired into the dialect of current
+// like AlignDialects, then gives each column whose original type is equivalent
+// to the type current has for it that same type, so the diff treats them as
+// equal.
+func AlignDialectsWithEquivalents(current, desired *database.Schema, types TypeMap, equivalents TypeEquivalents) *database.Schema {
+	if current == nil {
+		return desired
+	}
+	aligned := TranslateSchema(desired, current.Dialect, types)
+	if aligned == desired || len(equivalents[current.Dialect]) == 0 {
+		return aligned
+	}
+
+	currentTables := make(map[string]*database.Table, len(current.Tables))
+	for i := range current.Tables {
+		currentTables[current.Tables[i].Name] = &current.Tables[i]
+	}
+	for i := range aligned.Tables {
+		table := &aligned.Tables[i]
+		currentTable, ok := currentTables[table.Name]
+		if !ok {
+			continue
+		}
+		for j := range table.Columns {
+			col := &table.Columns[j]
+			currentCol := findColumn(currentTable, col.Name)
+			if currentCol == nil || col.LogicalType() == currentCol.LogicalType() {
+				continue
+			}
+			original := desired.Tables[i].Columns[j]
+			if equivalents.Equivalent(current.Dialect, original.LogicalType(), currentCol.LogicalType()) {
+				col.Type = currentCol.Type
+				col.TypeMetadata = nil
+				if currentCol.TypeMetadata != nil {
+					meta := *currentCol.TypeMetadata
+					col.TypeMetadata = &meta
+				}
+			}
+		}
+	}
+	return aligned
+}
+
+func findColumn(table *database.Table, name string) *database.Column {
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
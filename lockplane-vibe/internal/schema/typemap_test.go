@@ -0,0 +1,191 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/parser"
+)
+
+func TestTypeMapLookup(t *testing.T) {
+	types := DefaultTypeMap().Merge(TypeMap{
+		database.DialectSQLite: {"UUID": "BLOB", "varchar(36)": "CHAR(36)"},
+	})
+
+	tests := []struct {
+		columnType string
+		want       string
+		wantOK     bool
+	}{
+		{"uuid", "BLOB", true},                     // override wins over the default
+		{"jsonb", "TEXT", true},                    // default kept
+		{"timestamp with time zone", "TEXT", true}, // normalized timestamptz
+		{"timestamp(3) with time zone", "TEXT", true},
+		{"timestamp(3) without time zone", "TEXT", true},
+		{"varchar(36)", "CHAR(36)", true}, // exact match with modifiers
+		{"varchar(255)", "", false},       // base type not mapped
+		{"numeric(10,2)", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := types.Lookup(database.DialectSQLite, tt.columnType)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.columnType, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if _, ok := types.Lookup(database.DialectPostgres, "uuid"); ok {
+		t.Error("expected no mapping into PostgreSQL by default")
+	}
+	if _, ok := DefaultTypeMap().Lookup(database.DialectSQLite, "uuid"); !ok {
+		t.Error("expected Merge not to modify the default type map")
+	}
+}
+
+func TestTranslateSchema_SameDialectUnchanged(t *testing.T) {
+	schema := &database.Schema{Dialect: database.DialectSQLite, Tables: []database.Table{
+		{Name: "users", Columns: []database.Column{{Name: "id", Type: "uuid"}}},
+	}}
+
+	if got := TranslateSchema(schema, database.DialectSQLite, DefaultTypeMap()); got != schema {
+		t.Error("expected schema with the same dialect to be returned unchanged")
+	}
+	if got := TranslateSchema(schema, database.DialectUnknown, DefaultTypeMap()); got != schema {
+		t.Error("expected schema to be returned unchanged for an unknown target dialect")
+	}
+}
+
+func TestAlignDialects_NoDriftForMappedTypes(t *testing.T) {
+	desired, err := parser.ParseSQLSchemaWithDialect(`
+CREATE TABLE events (
+  id serial PRIMARY KEY,
+  external_id uuid NOT NULL,
+  payload jsonb,
+  created_at timestamptz NOT NULL,
+  name text NOT NULL
+);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse PostgreSQL schema: %v", err)
+	}
+
+	current, err := parser.ParseSQLSchemaWithDialect(`
+CREATE TABLE events (
+  id INTEGER PRIMARY KEY NOT NULL,
+  external_id TEXT NOT NULL,
+  payload TEXT,
+  created_at TEXT NOT NULL,
+  name TEXT NOT NULL
+);`, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse SQLite schema: %v", err)
+	}
+
+	if diff := DiffSchemas(current, desired); diff.IsEmpty() {
+		t.Fatal("expected naive cross-dialect diff to report type changes")
+	}
+
+	aligned := AlignDialects(current, desired, DefaultTypeMap())
+	if diff := DiffSchemas(current, aligned); !diff.IsEmpty() {
+		t.Errorf("expected no differences after type mapping, got %+v", diff.ModifiedTables)
+	}
+	if aligned.Dialect != database.DialectSQLite {
+		t.Errorf("expected aligned schema to use the SQLite dialect, got %q", aligned.Dialect)
+	}
+	if desired.Tables[0].Columns[1].Type != "uuid" {
+		t.Errorf("expected desired schema not to be modified, got %q", desired.Tables[0].Columns[1].Type)
+	}
+}
+
+func TestTypeEquivalents(t *testing.T) {
+	equivalents := DefaultTypeEquivalents().Merge(TypeEquivalents{
+		database.DialectSQLite: {"BOOLEAN": {"BOOLEAN"}},
+	})
+
+	tests := []struct {
+		target     database.Dialect
+		sourceType string
+		targetType string
+		want       bool
+	}{
+		{database.DialectSQLite, "boolean", "INTEGER", false}, // override replaces the default list
+		{database.DialectSQLite, "boolean", "boolean", true},
+		{database.DialectSQLite, "varchar(255)", "TEXT", true}, // modifiers ignored
+		{database.DialectSQLite, "bigint", "INTEGER", true},
+		{database.DialectSQLite, "bigint", "TEXT", false},
+		{database.DialectPostgres, "INTEGER", "boolean", true},
+		{database.DialectPostgres, "TEXT", "character varying(255)", true},
+		{database.DialectPostgres, "TEXT", "integer", false},
+	}
+	for _, tt := range tests {
+		if got := equivalents.Equivalent(tt.target, tt.sourceType, tt.targetType); got != tt.want {
+			t.Errorf("Equivalent(%s, %q, %q) = %v; want %v", tt.target, tt.sourceType, tt.targetType, got, tt.want)
+		}
+	}
+
+	if !DefaultTypeEquivalents().Equivalent(database.DialectSQLite, "boolean", "INTEGER") {
+		t.Error("expected Merge not to modify the default type equivalences")
+	}
+}
+
+func TestAlignDialects_NoDriftForEquivalentTypes(t *testing.T) {
+	desired, err := parser.ParseSQLSchemaWithDialect(`
+CREATE TABLE accounts (
+  id bigint PRIMARY KEY,
+  email varchar(255) NOT NULL,
+  active boolean NOT NULL,
+  balance numeric(10,2)
+);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse PostgreSQL schema: %v", err)
+	}
+
+	current, err := parser.ParseSQLSchemaWithDialect(`
+CREATE TABLE accounts (
+  id INTEGER PRIMARY KEY NOT NULL,
+  email TEXT NOT NULL,
+  active INTEGER NOT NULL,
+  balance REAL
+);`, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse SQLite schema: %v", err)
+	}
+
+	aligned := AlignDialects(current, desired, DefaultTypeMap())
+	if diff := DiffSchemas(current, aligned); !diff.IsEmpty() {
+		t.Errorf("expected no differences for equivalent types, got %+v", diff.ModifiedTables)
+	}
+
+	aligned = AlignDialectsWithEquivalents(current, desired, DefaultTypeMap(), TypeEquivalents{})
+	if diff := DiffSchemas(current, aligned); diff.IsEmpty() {
+		t.Error("expected type changes without type equivalences")
+	}
+	if desired.Tables[0].Columns[2].Type != "boolean" {
+		t.Errorf("expected desired schema not to be modified, got %q", desired.Tables[0].Columns[2].Type)
+	}
+}
+
+func TestAlignDialects_SQLiteSchemaAgainstPostgres(t *testing.T) {
+	desired, err := parser.ParseSQLSchemaWithDialect(`
+CREATE TABLE notes (
+  id INTEGER PRIMARY KEY NOT NULL,
+  body TEXT NOT NULL,
+  archived INTEGER NOT NULL
+);`, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("Failed to parse SQLite schema: %v", err)
+	}
+
+	current, err := parser.ParseSQLSchemaWithDialect(`
+CREATE TABLE notes (
+  id bigint PRIMARY KEY NOT NULL,
+  body varchar(1000) NOT NULL,
+  archived integer NOT NULL
+);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse PostgreSQL schema: %v", err)
+	}
+
+	aligned := AlignDialects(current, desired, DefaultTypeMap())
+	if diff := DiffSchemas(current, aligned); !diff.IsEmpty() {
+		t.Errorf("expected no differences for equivalent types, got %+v", diff.ModifiedTables)
+	}
+}
@@ -0,0 +1,102 @@
+package shadow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReuseState records the schema a validation run left in a shadow database,
+// so the next run can skip cleaning and re-applying it when nothing changed.
+// States live in the cache directory, one file per shadow target.
+type ReuseState struct {
+	Target           string    `json:"target"`            // ReuseTarget of the shadow database
+	LockplaneVersion string    `json:"lockplane_version"` // Build that applied the schema
+	SchemaHash       string    `json:"schema_hash"`       // Hash of the schema files' schema
+	ShadowHash       string    `json:"shadow_hash"`       // Hash of the shadow database as introspected afterwards
+	StepsApplied     int       `json:"steps_applied"`
+	ValidatedAt      time.Time `json:"validated_at"`
+}
+
+// ReuseTarget identifies a shadow database and schema without storing the
+// connection string, which may hold credentials
+func ReuseTarget(shadowURL, shadowSchema string) string {
+	hash := sha256.Sum256([]byte(shadowURL + "\x00" + shadowSchema))
+	return hex.EncodeToString(hash[:])
+}
+
+// reuseStatePath returns the state file for a shadow target
+func reuseStatePath(cacheDir, target string) string {
+	return filepath.Join(cacheDir, "shadow-"+target[:16]+".json")
+}
+
+// LoadReuseState returns the state recorded for target, or nil when there is
+// none
+func LoadReuseState(cacheDir, target string) (*ReuseState, error) {
+	data, err := os.ReadFile(reuseStatePath(cacheDir, target))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state ReuseState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode shadow state: %w", err)
+	}
+	if state.Target != target {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// SaveReuseState records state for its target, replacing any earlier state
+func SaveReuseState(cacheDir string, state *ReuseState) error {
+	if state == nil {
+		return errors.New("nil shadow state")
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := reuseStatePath(cacheDir, state.Target)
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
+
+// ClearReuseState forgets the state of target, so the next run cleans the
+// shadow database
+func ClearReuseState(cacheDir, target string) error {
+	if err := os.Remove(reuseStatePath(cacheDir, target)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Reusable reports whether a shadow database holding state can stand in for
+// applying a schema hashed schemaHash with this build, given the hash of the
+// shadow database as introspected now. It returns the reason when it can't.
+func (s *ReuseState) Reusable(version, schemaHash, shadowHash string) (bool, string) {
+	switch {
+	case s == nil:
+		return false, "no earlier run recorded"
+	case s.LockplaneVersion != version:
+		return false, "recorded by another lockplane build"
+	case s.SchemaHash != schemaHash:
+		return false, "schema changed since the last run"
+	case s.ShadowHash != shadowHash:
+		return false, "shadow database changed since the last run"
+	}
+	return true, ""
+}
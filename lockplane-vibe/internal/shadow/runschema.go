@@ -0,0 +1,213 @@
+package shadow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/style"
+)
+
+const (
+	// DefaultRunSchemaTemplate names per-run shadow schemas after the configured
+	// shadow schema, the OS user, and the process ID
+	DefaultRunSchemaTemplate = "{schema}_{user}_{pid}"
+
+	// DefaultRunSchemaTTL is how old a per-run shadow schema must be before a
+	// later run treats it as abandoned and drops it
+	DefaultRunSchemaTTL = 24 * time.Hour
+
+	// runSchemaComment marks schemas created by CreateRunSchema so that stale
+	// ones can be found regardless of the template that named them
+	runSchemaComment = "lockplane shadow run created_at="
+
+	// maxIdentifierLength is PostgreSQL's NAMEDATALEN - 1
+	maxIdentifierLength = 63
+)
+
+// RunSchemaName expands a per-run schema template. Supported placeholders are
+// {schema} (the configured shadow schema), {user}, {pid}, and {time} (Unix
+// seconds). The result is lowercased, restricted to [a-z0-9_], and truncated
+// to PostgreSQL's identifier limit.
+func RunSchemaName(template, base string) string {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultRunSchemaTemplate
+	}
+	if strings.TrimSpace(base) == "" {
+		base = "lockplane_shadow"
+	}
+	name := strings.NewReplacer(
+		"{schema}", base,
+		"{user}", currentUser(),
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{time}", strconv.FormatInt(time.Now().Unix(), 10),
+	).Replace(template)
+	return sanitizeIdentifier(name)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+func sanitizeIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	sanitized := b.String()
+	if sanitized == "" || (sanitized[0] >= '0' && sanitized[0] <= '9') {
+		sanitized = "s_" + sanitized
+	}
+	if len(sanitized) > maxIdentifierLength {
+		sanitized = sanitized[:maxIdentifierLength]
+	}
+	return sanitized
+}
+
+// RunSchema is a shadow schema owned by a single lockplane run. Drop removes it;
+// it is safe to call more than once and from the interrupt handler.
+type RunSchema struct {
+	Name string
+
+	db     *sql.DB
+	driver database.Driver
+	once   sync.Once
+	err    error
+	stop   chan struct{}
+}
+
+// CreateRunSchema creates a per-run shadow schema and marks it with its creation
+// time. Call Drop when the run finishes; until then an interrupt (SIGINT or
+// SIGTERM) drops the schema before the process exits.
+func CreateRunSchema(ctx context.Context, db *sql.DB, driver database.Driver, name string) (*RunSchema, error) {
+	if !driver.SupportsSchemas() {
+		return nil, fmt.Errorf("per-run shadow schemas require a database with schema support")
+	}
+	if err := driver.CreateSchema(ctx, db, name); err != nil {
+		return nil, err
+	}
+	comment := runSchemaComment + time.Now().UTC().Format(time.RFC3339)
+	query := fmt.Sprintf("COMMENT ON SCHEMA %s IS '%s'", quoteIdentifier(name), comment)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		_ = driver.DropSchema(ctx, db, name, true)
+		return nil, fmt.Errorf("failed to mark shadow schema %q: %w", name, err)
+	}
+
+	rs := &RunSchema{Name: name, db: db, driver: driver, stop: make(chan struct{})}
+	rs.dropOnInterrupt()
+	return rs, nil
+}
+
+// Drop removes the schema and everything in it
+func (rs *RunSchema) Drop(ctx context.Context) error {
+	if rs == nil {
+		return nil
+	}
+	rs.once.Do(func() {
+		close(rs.stop)
+		rs.err = rs.driver.DropSchema(ctx, rs.db, rs.Name, true)
+	})
+	return rs.err
+}
+
+func (rs *RunSchema) dropOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-signals:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			fmt.Fprintf(style.Stderr, "\n⚠️  Interrupted, dropping shadow schema %q\n", rs.Name)
+			if err := rs.Drop(ctx); err != nil {
+				fmt.Fprintf(style.Stderr, "⚠️  %v\n", err)
+			}
+			os.Exit(130)
+		case <-rs.stop:
+		}
+	}()
+}
+
+// DropStaleRunSchemas drops per-run shadow schemas created more than ttl ago,
+// left behind by runs that crashed or were killed. It returns the names of the
+// dropped schemas.
+func DropStaleRunSchemas(ctx context.Context, db *sql.DB, driver database.Driver, ttl time.Duration) ([]string, error) {
+	if !driver.SupportsSchemas() {
+		return nil, nil
+	}
+	if ttl <= 0 {
+		ttl = DefaultRunSchemaTTL
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT nspname, obj_description(oid, 'pg_namespace')
+		FROM pg_namespace
+		WHERE obj_description(oid, 'pg_namespace') LIKE $1
+		ORDER BY nspname
+	`, runSchemaComment+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shadow schemas: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	cutoff := time.Now().Add(-ttl)
+	var stale []string
+	for rows.Next() {
+		var name, comment string
+		if err := rows.Scan(&name, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow schema: %w", err)
+		}
+		if isStale(comment, cutoff) {
+			stale = append(stale, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shadow schemas: %w", err)
+	}
+
+	var dropped []string
+	for _, name := range stale {
+		if err := driver.DropSchema(ctx, db, name, true); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
+
+// isStale reports whether a schema comment written by CreateRunSchema records a
+// creation time before cutoff. Unparseable comments are never stale.
+func isStale(comment string, cutoff time.Time) bool {
+	createdAt, err := time.Parse(time.RFC3339, strings.TrimPrefix(comment, runSchemaComment))
+	if err != nil {
+		return false
+	}
+	return createdAt.Before(cutoff)
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
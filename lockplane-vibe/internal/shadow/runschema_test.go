@@ -0,0 +1,118 @@
+package shadow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lockplane/lockplane/internal/testutil"
+)
+
+func TestRunSchemaName(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	name := RunSchemaName("", "lockplane_shadow")
+	if !strings.HasPrefix(name, "lockplane_shadow_") || !strings.HasSuffix(name, "_"+pid) {
+		t.Errorf("Expected default template to produce lockplane_shadow_<user>_%s, got %q", pid, name)
+	}
+
+	if got := RunSchemaName("ci_{pid}", "ignored"); got != "ci_"+pid {
+		t.Errorf("Expected custom template to be expanded, got %q", got)
+	}
+
+	if got := RunSchemaName("Shadow-{schema}.x", "My Schema"); got != "shadow_my_schema_x" {
+		t.Errorf("Expected name to be sanitized, got %q", got)
+	}
+
+	if got := RunSchemaName("{pid}", ""); got != "s_"+pid {
+		t.Errorf("Expected leading digit to be prefixed, got %q", got)
+	}
+
+	long := RunSchemaName(strings.Repeat("a", 100), "")
+	if len(long) != maxIdentifierLength {
+		t.Errorf("Expected name truncated to %d bytes, got %d", maxIdentifierLength, len(long))
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	cutoff := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		comment string
+		want    bool
+	}{
+		{runSchemaComment + "2025-01-01T00:00:00Z", true},
+		{runSchemaComment + "2025-01-03T00:00:00Z", false},
+		{runSchemaComment + "not a time", false},
+		{"created by someone else", false},
+	}
+	for _, tt := range tests {
+		if got := isStale(tt.comment, cutoff); got != tt.want {
+			t.Errorf("isStale(%q) = %v, want %v", tt.comment, got, tt.want)
+		}
+	}
+}
+
+func TestRunSchemaLifecycle(t *testing.T) {
+	tdb := testutil.SetupTestDB(t, "postgres")
+	defer tdb.Close()
+	ctx := context.Background()
+
+	name := RunSchemaName("lockplane_test_run_{pid}", "")
+	runSchema, err := CreateRunSchema(ctx, tdb.DB, tdb.Driver, name)
+	if err != nil {
+		t.Fatalf("Failed to create run schema: %v", err)
+	}
+	if !schemaExists(t, tdb, name) {
+		t.Fatalf("Expected schema %q to exist", name)
+	}
+
+	// A fresh schema is not stale
+	dropped, err := DropStaleRunSchemas(ctx, tdb.DB, tdb.Driver, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to drop stale schemas: %v", err)
+	}
+	for _, d := range dropped {
+		if d == name {
+			t.Fatalf("Expected fresh schema %q to be kept", name)
+		}
+	}
+
+	// Backdate it past the TTL
+	old := runSchemaComment + time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339)
+	if _, err := tdb.DB.ExecContext(ctx, fmt.Sprintf("COMMENT ON SCHEMA %s IS '%s'", quoteIdentifier(name), old)); err != nil {
+		t.Fatalf("Failed to backdate schema: %v", err)
+	}
+	dropped, err = DropStaleRunSchemas(ctx, tdb.DB, tdb.Driver, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to drop stale schemas: %v", err)
+	}
+	found := false
+	for _, d := range dropped {
+		found = found || d == name
+	}
+	if !found || schemaExists(t, tdb, name) {
+		t.Fatalf("Expected stale schema %q to be dropped, dropped %v", name, dropped)
+	}
+
+	// Dropping again is a no-op
+	if err := runSchema.Drop(ctx); err != nil {
+		t.Fatalf("Failed to drop run schema: %v", err)
+	}
+	if err := runSchema.Drop(ctx); err != nil {
+		t.Fatalf("Expected second drop to succeed: %v", err)
+	}
+}
+
+func schemaExists(t *testing.T, tdb *testutil.TestDB, name string) bool {
+	t.Helper()
+	var exists bool
+	if err := tdb.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", name).Scan(&exists); err != nil {
+		t.Fatalf("Failed to query schemas: %v", err)
+	}
+	return exists
+}
@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/lockplane/lockplane/internal/dburl"
+	"github.com/lockplane/lockplane/internal/style"
 	_ "modernc.org/sqlite"
 )
 
@@ -45,28 +47,7 @@ func IsSQLiteFilePath(s string) bool {
 
 // ExtractSQLiteFilePath extracts the actual file path from a SQLite connection string
 func ExtractSQLiteFilePath(connStr string) string {
-	// Handle sqlite:// prefix
-	if strings.HasPrefix(connStr, "sqlite://") {
-		path := strings.TrimPrefix(connStr, "sqlite://")
-		// Remove query parameters
-		if idx := strings.Index(path, "?"); idx >= 0 {
-			path = path[:idx]
-		}
-		return path
-	}
-
-	// Handle file: prefix
-	if strings.HasPrefix(connStr, "file:") {
-		path := strings.TrimPrefix(connStr, "file:")
-		// Remove query parameters
-		if idx := strings.Index(path, "?"); idx >= 0 {
-			path = path[:idx]
-		}
-		return path
-	}
-
-	// Otherwise, it's already a file path
-	return connStr
+	return dburl.SQLitePath(connStr)
 }
 
 // CheckSQLiteDatabase checks if a SQLite database file exists and is valid
@@ -161,30 +142,30 @@ func EnsureSQLiteDatabaseWithShadow(connStr string, dbName string, autoCreate bo
 
 	if !exists {
 		if autoCreate {
-			fmt.Fprintf(os.Stderr, "📁 Creating %s database: %s\n", dbName, filePath)
+			fmt.Fprintf(style.Stderr, "📁 Creating %s database: %s\n", dbName, filePath)
 			if err := CreateSQLiteDatabase(connStr); err != nil {
 				return fmt.Errorf("failed to create %s database: %w", dbName, err)
 			}
-			fmt.Fprintf(os.Stderr, "✓ Created %s database\n", dbName)
+			fmt.Fprintf(style.Stderr, "✓ Created %s database\n", dbName)
 
 			// Also create shadow database if requested
 			if createShadow && dbName == "target" {
 				shadowPath := GenerateShadowDBPath(filePath)
 				shadowExists, _, _ := CheckSQLiteDatabase(shadowPath)
 				if !shadowExists {
-					fmt.Fprintf(os.Stderr, "📁 Creating shadow database: %s\n", shadowPath)
+					fmt.Fprintf(style.Stderr, "📁 Creating shadow database: %s\n", shadowPath)
 					if err := CreateSQLiteDatabase(shadowPath); err != nil {
-						fmt.Fprintf(os.Stderr, "⚠️  Warning: failed to create shadow database: %v\n", err)
+						fmt.Fprintf(style.Stderr, "⚠️  Warning: failed to create shadow database: %v\n", err)
 					} else {
-						fmt.Fprintf(os.Stderr, "✓ Created shadow database\n")
+						fmt.Fprintf(style.Stderr, "✓ Created shadow database\n")
 					}
 				}
 			}
 			return nil
 		}
 
-		fmt.Fprintf(os.Stderr, "\n⚠️  %s database file does not exist: %s\n", dbName, filePath)
-		fmt.Fprintf(os.Stderr, "Would you like to create it? [Y/n]: ")
+		fmt.Fprintf(style.Stderr, "\n⚠️  %s database file does not exist: %s\n", dbName, filePath)
+		fmt.Fprintf(style.Stderr, "Would you like to create it? [Y/n]: ")
 
 		var response string
 		_, _ = fmt.Scanln(&response)
@@ -194,15 +175,15 @@ func EnsureSQLiteDatabaseWithShadow(connStr string, dbName string, autoCreate bo
 			if err := CreateSQLiteDatabase(connStr); err != nil {
 				return fmt.Errorf("failed to create %s database: %w", dbName, err)
 			}
-			fmt.Fprintf(os.Stderr, "✓ Created %s database: %s\n", dbName, filePath)
+			fmt.Fprintf(style.Stderr, "✓ Created %s database: %s\n", dbName, filePath)
 
 			// Also create shadow database if requested
 			if createShadow && dbName == "target" {
 				shadowPath := GenerateShadowDBPath(filePath)
 				shadowExists, _, _ := CheckSQLiteDatabase(shadowPath)
 				if !shadowExists {
-					fmt.Fprintf(os.Stderr, "\n⚠️  Shadow database file does not exist: %s\n", shadowPath)
-					fmt.Fprintf(os.Stderr, "Would you like to create it? [Y/n]: ")
+					fmt.Fprintf(style.Stderr, "\n⚠️  Shadow database file does not exist: %s\n", shadowPath)
+					fmt.Fprintf(style.Stderr, "Would you like to create it? [Y/n]: ")
 
 					var shadowResponse string
 					_, _ = fmt.Scanln(&shadowResponse)
@@ -210,9 +191,9 @@ func EnsureSQLiteDatabaseWithShadow(connStr string, dbName string, autoCreate bo
 
 					if shadowResponse == "" || shadowResponse == "y" || shadowResponse == "yes" {
 						if err := CreateSQLiteDatabase(shadowPath); err != nil {
-							fmt.Fprintf(os.Stderr, "⚠️  Warning: failed to create shadow database: %v\n", err)
+							fmt.Fprintf(style.Stderr, "⚠️  Warning: failed to create shadow database: %v\n", err)
 						} else {
-							fmt.Fprintf(os.Stderr, "✓ Created shadow database: %s\n", shadowPath)
+							fmt.Fprintf(style.Stderr, "✓ Created shadow database: %s\n", shadowPath)
 						}
 					}
 				}
@@ -225,12 +206,12 @@ func EnsureSQLiteDatabaseWithShadow(connStr string, dbName string, autoCreate bo
 	}
 
 	if isEmpty {
-		fmt.Fprintf(os.Stderr, "⚠️  Warning: %s database file exists but is empty: %s\n", dbName, filePath)
-		fmt.Fprintf(os.Stderr, "Initializing empty database...\n")
+		fmt.Fprintf(style.Stderr, "⚠️  Warning: %s database file exists but is empty: %s\n", dbName, filePath)
+		fmt.Fprintf(style.Stderr, "Initializing empty database...\n")
 		if err := CreateSQLiteDatabase(connStr); err != nil {
 			return fmt.Errorf("failed to initialize %s database: %w", dbName, err)
 		}
-		fmt.Fprintf(os.Stderr, "✓ Initialized %s database\n", dbName)
+		fmt.Fprintf(style.Stderr, "✓ Initialized %s database\n", dbName)
 	}
 
 	return nil
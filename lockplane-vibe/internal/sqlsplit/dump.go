@@ -0,0 +1,76 @@
+package sqlsplit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// copyFromStdinPattern matches a COPY statement whose rows follow it inline,
+// as pg_dump writes table data
+var copyFromStdinPattern = regexp.MustCompile(`(?im)^\s*COPY\s[^;]*\bFROM\s+stdin\b`)
+
+// BlankDumpData blanks the parts of a pg_dump plain-format script that are
+// not SQL: the rows of COPY ... FROM stdin blocks, up to and including their
+// \. terminator, and psql meta-commands such as \connect or \restrict at the
+// start of a line. Blanked text is replaced with spaces and newlines are
+// kept, so line and column positions in the result match sqlText.
+func BlankDumpData(sqlText string) string {
+	out := []byte(sqlText)
+	blank := func(start, end int) {
+		for j := start; j < end; j++ {
+			if out[j] != '\n' {
+				out[j] = ' '
+			}
+		}
+	}
+
+	stmtStart := 0
+	for i := 0; i < len(sqlText); {
+		if sqlText[i] == '\\' && (i == 0 || sqlText[i-1] == '\n') {
+			end := lineEnd(sqlText, i)
+			blank(i, end)
+			i = end
+			continue
+		}
+
+		end := SkipToken(sqlText, i)
+		if sqlText[i] == ';' {
+			if copyFromStdinPattern.MatchString(sqlText[stmtStart:end]) {
+				// The rows start on the line after the statement
+				rowsEnd := copyDataEnd(sqlText, end)
+				blank(lineEnd(sqlText, end), rowsEnd)
+				end = rowsEnd
+			}
+			stmtStart = end
+		}
+		i = end
+	}
+	return string(out)
+}
+
+// copyDataEnd returns the index just past the \. line that ends the COPY
+// rows following the statement ending at i, or the end of sqlText when the
+// rows are unterminated
+func copyDataEnd(sqlText string, i int) int {
+	start := lineEnd(sqlText, i)
+	for start < len(sqlText) {
+		end := lineEnd(sqlText, start+1)
+		if strings.TrimRight(sqlText[start+1:end], "\r") == `\.` {
+			return end
+		}
+		start = end
+	}
+	return len(sqlText)
+}
+
+// lineEnd returns the index of the newline ending the line that contains
+// sqlText[i], or len(sqlText) on the last line
+func lineEnd(sqlText string, i int) int {
+	if i >= len(sqlText) {
+		return len(sqlText)
+	}
+	if nl := strings.IndexByte(sqlText[i:], '\n'); nl >= 0 {
+		return i + nl
+	}
+	return len(sqlText)
+}
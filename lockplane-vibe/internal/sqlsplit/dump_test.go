@@ -0,0 +1,38 @@
+package sqlsplit
+
+import "testing"
+
+func TestBlankDumpData(t *testing.T) {
+	sqlText := "\\restrict key\n" +
+		"CREATE TABLE t (a text DEFAULT '\\.');\n" +
+		"COPY public.t (a) FROM stdin;\n" +
+		"x; y\n" +
+		"'z\n" +
+		"\\.\n" +
+		"CREATE FUNCTION f() RETURNS text AS $$\n\\d\n$$ LANGUAGE sql;\n" +
+		"copy t from STDIN;\n" +
+		"\\.\n" +
+		"COPY t TO stdout;\n"
+
+	want := "             \n" +
+		"CREATE TABLE t (a text DEFAULT '\\.');\n" +
+		"COPY public.t (a) FROM stdin;\n" +
+		"    \n" +
+		"  \n" +
+		"  \n" +
+		"CREATE FUNCTION f() RETURNS text AS $$\n\\d\n$$ LANGUAGE sql;\n" +
+		"copy t from STDIN;\n" +
+		"  \n" +
+		"COPY t TO stdout;\n"
+
+	if got := BlankDumpData(sqlText); got != want {
+		t.Errorf("BlankDumpData() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestBlankDumpDataUnterminatedCopy(t *testing.T) {
+	got := BlankDumpData("COPY t FROM stdin;\n1\n2")
+	if want := "COPY t FROM stdin;\n \n "; got != want {
+		t.Errorf("BlankDumpData() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Package sqlsplit splits SQL scripts into statements using PostgreSQL's
+// lexical rules for string literals, quoted identifiers and comments.
+package sqlsplit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Statement is one statement of a SQL script
+type Statement struct {
+	Text      string
+	StartLine int // Line of the first non-whitespace character, from 1
+}
+
+// Split splits SQL text into individual statements on semicolons,
+// tracking the line each statement starts on.
+//
+// Semicolons are ignored inside string literals ('...', with E'...' backslash
+// escapes), quoted identifiers ("..."), dollar-quoted strings ($$...$$ and
+// $tag$...$tag$), line comments and (nested) block comments. Statements are
+// byte-for-byte slices of sqlText, so concatenating them reproduces the input
+// apart from trailing whitespace.
+func Split(sqlText string) []Statement {
+	var statements []Statement
+	currentLine := 1
+	stmtStart := 0
+	stmtStartLine := 1
+	seenNonWhitespace := false
+
+	flush := func(end int) {
+		stmt := sqlText[stmtStart:end]
+		if strings.TrimSpace(stmt) != "" {
+			statements = append(statements, Statement{
+				Text:      stmt,
+				StartLine: stmtStartLine,
+			})
+		}
+		stmtStart = end
+		seenNonWhitespace = false
+	}
+
+	for i := 0; i < len(sqlText); {
+		// Track first non-whitespace character for accurate line numbers
+		if !seenNonWhitespace && !isSpaceAt(sqlText, i) {
+			stmtStartLine = currentLine
+			seenNonWhitespace = true
+		}
+
+		end := SkipToken(sqlText, i)
+		currentLine += strings.Count(sqlText[i:end], "\n")
+
+		// Statement terminator outside strings/comments
+		if sqlText[i] == ';' {
+			flush(end)
+		}
+		i = end
+	}
+
+	// Add any remaining statement
+	flush(len(sqlText))
+
+	return statements
+}
+
+// NormalizeLineEndings converts Windows (CRLF) line endings to LF. Line and
+// column positions computed from the result match what editors show, without
+// counting the carriage returns of files saved on Windows.
+func NormalizeLineEndings(sqlText string) string {
+	return strings.ReplaceAll(sqlText, "\r\n", "\n")
+}
+
+// SkipToken returns the index just past the token starting at sqlText[i].
+// Quoted strings, quoted identifiers and comments are consumed whole (up to the
+// end of input when unterminated); anything else advances by one character.
+func SkipToken(sqlText string, i int) int {
+	rest := sqlText[i:]
+	switch {
+	case strings.HasPrefix(rest, "--"):
+		if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+			return i + nl // The newline itself is ordinary whitespace
+		}
+		return len(sqlText)
+
+	case strings.HasPrefix(rest, "/*"):
+		// PostgreSQL block comments nest
+		depth := 0
+		for j := i; j < len(sqlText)-1; j++ {
+			switch sqlText[j : j+2] {
+			case "/*":
+				depth++
+				j++
+			case "*/":
+				depth--
+				j++
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(sqlText)
+
+	case rest[0] == '\'':
+		return skipQuoted(sqlText, i, '\'', isEscapeStringPrefix(sqlText, i))
+
+	case rest[0] == '"':
+		return skipQuoted(sqlText, i, '"', false)
+
+	case rest[0] == '$':
+		if tag := dollarQuoteTag(sqlText, i); tag != "" {
+			body := i + len(tag)
+			if closing := strings.Index(sqlText[body:], tag); closing >= 0 {
+				return body + closing + len(tag)
+			}
+			return len(sqlText)
+		}
+	}
+	_, size := utf8.DecodeRuneInString(rest)
+	return i + size
+}
+
+// skipQuoted returns the index just past the quoted token opening at
+// sqlText[i]. A doubled quote is an escaped quote; when backslashEscapes is
+// set (E'...' strings), a backslash escapes the following byte.
+func skipQuoted(sqlText string, i int, quote byte, backslashEscapes bool) int {
+	for j := i + 1; j < len(sqlText); j++ {
+		switch sqlText[j] {
+		case '\\':
+			if backslashEscapes {
+				j++
+			}
+		case quote:
+			if j+1 < len(sqlText) && sqlText[j+1] == quote {
+				j++ // Doubled quote
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sqlText)
+}
+
+// isEscapeStringPrefix reports whether the quote at sqlText[i] opens an
+// escape string constant (E'...'), where backslashes escape characters even
+// with standard_conforming_strings on
+func isEscapeStringPrefix(sqlText string, i int) bool {
+	if i == 0 || (sqlText[i-1] != 'E' && sqlText[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentByte(sqlText[i-2])
+}
+
+// dollarQuoteTag returns the opening delimiter ($$ or $tag$) of a dollar-quoted
+// string starting at sqlText[i], or "" if there is none. A $ inside an
+// identifier or followed by digits (a positional parameter like $1) does not
+// start a dollar quote.
+func dollarQuoteTag(sqlText string, i int) string {
+	if i > 0 && isIdentByte(sqlText[i-1]) {
+		return ""
+	}
+	for j := i + 1; j < len(sqlText); j++ {
+		c := sqlText[j]
+		if c == '$' {
+			return sqlText[i : j+1]
+		}
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= utf8.RuneSelf
+		if !isLetter && (j == i+1 || c < '0' || c > '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+// isIdentByte reports whether c can appear inside an unquoted identifier
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= utf8.RuneSelf
+}
+
+// isSpaceAt reports whether sqlText[i] starts a whitespace character
+func isSpaceAt(sqlText string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(sqlText[i:])
+	return unicode.IsSpace(r)
+}
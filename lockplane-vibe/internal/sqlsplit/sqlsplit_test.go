@@ -0,0 +1,62 @@
+package sqlsplit
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	sqlText := "CREATE TABLE notes (\n  sep text DEFAULT 'a;b',\n  quote text DEFAULT 'it''s;'\n);\n\n" +
+		"CREATE TABLE paths (p text DEFAULT 'C:\\', e text DEFAULT E'\\';');\n" +
+		"CREATE FUNCTION f() RETURNS text AS $body$ SELECT 'x;y'; $body$ LANGUAGE sql;\n" +
+		"-- done;\n"
+
+	statements := Split(sqlText)
+	wantLines := []int{1, 6, 7, 8}
+	if len(statements) != len(wantLines) {
+		t.Fatalf("expected %d statements, got %d: %q", len(wantLines), len(statements), statements)
+	}
+	for i, stmt := range statements {
+		if stmt.StartLine != wantLines[i] {
+			t.Errorf("statement %d: expected start line %d, got %d (%q)", i, wantLines[i], stmt.StartLine, stmt.Text)
+		}
+	}
+}
+
+func TestSkipToken(t *testing.T) {
+	tests := []struct {
+		sqlText string
+		want    int
+	}{
+		{`'it''s' x`, 7},
+		{`'C:\' x`, 5},
+		{`E'it\'s' x`, 1}, // The E is its own token
+		{`"a""b" x`, 6},
+		{`$$a$b$$ x`, 7},
+		{`$tag$ $$ $tag$ x`, 14},
+		{`$1 x`, 1},
+		{"-- c\nx", 4},
+		{`/* a /* b */ c */ x`, 17},
+		{`'unterminated`, 13},
+		{`é x`, 2},
+	}
+
+	for _, tt := range tests {
+		if got := SkipToken(tt.sqlText, 0); got != tt.want {
+			t.Errorf("SkipToken(%q, 0) = %d, expected %d", tt.sqlText, got, tt.want)
+		}
+	}
+	if got := SkipToken(`E'it\'s' x`, 1); got != 8 {
+		t.Errorf("expected the E string to end at 8, got %d", got)
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	crlf := "CREATE TABLE a (\r\n  id int\r\n);\r\n\r\nCREATE TABLE b (note text DEFAULT 'x\ry');\r\n"
+	got := NormalizeLineEndings(crlf)
+	if want := "CREATE TABLE a (\n  id int\n);\n\nCREATE TABLE b (note text DEFAULT 'x\ry');\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	statements := Split(got)
+	if len(statements) != 2 || statements[1].StartLine != 5 {
+		t.Errorf("expected the second statement on line 5, got %+v", statements)
+	}
+}
@@ -14,11 +14,14 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
 
 	pg_query "github.com/pganalyze/pg_query_go/v6"
 
 	"github.com/lockplane/lockplane/database"
 	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/sqlsplit"
+	"github.com/lockplane/lockplane/internal/style"
 )
 
 // ValidationIssue represents a validation error or warning
@@ -43,63 +46,63 @@ func RunValidateSQL(args []string) {
 
 	// Custom usage function
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: lockplane validate sql [options] <file-or-directory>\n\n")
-		fmt.Fprintf(os.Stderr, "Validate a SQL schema file or directory of .lp.sql files.\n\n")
-		fmt.Fprintf(os.Stderr, "ABOUT .lp.sql FILES:\n\n")
-		fmt.Fprintf(os.Stderr, "  .lp.sql files are declarative schema definition files that describe your database\n")
-		fmt.Fprintf(os.Stderr, "  structure using standard PostgreSQL DDL syntax. They serve as the source of truth\n")
-		fmt.Fprintf(os.Stderr, "  for your schema and are used to generate migration plans.\n\n")
-		fmt.Fprintf(os.Stderr, "  VALID SQL STATEMENTS:\n")
-		fmt.Fprintf(os.Stderr, "    - CREATE TABLE - Define tables with columns, constraints, and defaults\n")
-		fmt.Fprintf(os.Stderr, "    - CREATE INDEX - Create indexes (prefer CONCURRENTLY for production)\n")
-		fmt.Fprintf(os.Stderr, "    - CREATE UNIQUE INDEX - Create unique indexes\n")
-		fmt.Fprintf(os.Stderr, "    - ALTER TABLE - Add/modify columns, constraints, and foreign keys\n")
-		fmt.Fprintf(os.Stderr, "    - Comments (-- and /* */) - Documentation and notes\n\n")
-		fmt.Fprintf(os.Stderr, "  INVALID SQL STATEMENTS (rejected by validation):\n\n")
-		fmt.Fprintf(os.Stderr, "    Data Loss Operations (ERROR):\n")
-		fmt.Fprintf(os.Stderr, "      - DROP TABLE - Permanently deletes all data\n")
-		fmt.Fprintf(os.Stderr, "      - DROP COLUMN - Irreversible data loss\n")
-		fmt.Fprintf(os.Stderr, "      - TRUNCATE TABLE - Deletes all rows\n")
-		fmt.Fprintf(os.Stderr, "      - DELETE without WHERE - Unintentional data deletion\n\n")
-		fmt.Fprintf(os.Stderr, "    Non-Declarative Patterns (ERROR):\n")
-		fmt.Fprintf(os.Stderr, "      - IF NOT EXISTS clauses - Makes schema non-deterministic\n")
-		fmt.Fprintf(os.Stderr, "      - Transaction control (BEGIN, COMMIT, ROLLBACK) - Lockplane manages transactions\n")
-		fmt.Fprintf(os.Stderr, "      - CREATE OR REPLACE - Use plain CREATE statements\n\n")
-		fmt.Fprintf(os.Stderr, "  MULTI-FILE SUPPORT:\n\n")
-		fmt.Fprintf(os.Stderr, "    You can organize schema across multiple .lp.sql files in a directory.\n")
-		fmt.Fprintf(os.Stderr, "    Files are combined in lexicographic order, so use prefixes for explicit\n")
-		fmt.Fprintf(os.Stderr, "    ordering (e.g., 001_tables.lp.sql, 010_indexes.lp.sql).\n\n")
-		fmt.Fprintf(os.Stderr, "    Only top-level files are considered - subdirectories and symlinks are\n")
-		fmt.Fprintf(os.Stderr, "    skipped to avoid accidental recursion.\n\n")
-		fmt.Fprintf(os.Stderr, "  VALIDATION CHECKS:\n\n")
-		fmt.Fprintf(os.Stderr, "    1. SQL Syntax - Statement-by-statement parsing using PostgreSQL parser\n")
-		fmt.Fprintf(os.Stderr, "       - Detects multiple syntax errors in a single pass\n")
-		fmt.Fprintf(os.Stderr, "       - Reports exact line numbers for each error\n\n")
-		fmt.Fprintf(os.Stderr, "    2. database.Schema Structure - Referential integrity and consistency\n")
-		fmt.Fprintf(os.Stderr, "       - Duplicate column names\n")
-		fmt.Fprintf(os.Stderr, "       - Missing data types\n")
-		fmt.Fprintf(os.Stderr, "       - Missing primary keys (warning)\n")
-		fmt.Fprintf(os.Stderr, "       - Invalid foreign key references\n")
-		fmt.Fprintf(os.Stderr, "       - Duplicate index names\n")
-		fmt.Fprintf(os.Stderr, "       - Invalid index column references\n\n")
-		fmt.Fprintf(os.Stderr, "    3. Dangerous Patterns - Data loss risks flagged as errors\n")
-		fmt.Fprintf(os.Stderr, "    4. Non-Declarative Patterns - Imperative SQL not allowed\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
+		fmt.Fprintf(style.Stderr, "Usage: lockplane validate sql [options] <file-or-directory>\n\n")
+		fmt.Fprintf(style.Stderr, "Validate a SQL schema file or directory of .lp.sql files.\n\n")
+		fmt.Fprintf(style.Stderr, "ABOUT .lp.sql FILES:\n\n")
+		fmt.Fprintf(style.Stderr, "  .lp.sql files are declarative schema definition files that describe your database\n")
+		fmt.Fprintf(style.Stderr, "  structure using standard PostgreSQL DDL syntax. They serve as the source of truth\n")
+		fmt.Fprintf(style.Stderr, "  for your schema and are used to generate migration plans.\n\n")
+		fmt.Fprintf(style.Stderr, "  VALID SQL STATEMENTS:\n")
+		fmt.Fprintf(style.Stderr, "    - CREATE TABLE - Define tables with columns, constraints, and defaults\n")
+		fmt.Fprintf(style.Stderr, "    - CREATE INDEX - Create indexes (prefer CONCURRENTLY for production)\n")
+		fmt.Fprintf(style.Stderr, "    - CREATE UNIQUE INDEX - Create unique indexes\n")
+		fmt.Fprintf(style.Stderr, "    - ALTER TABLE - Add/modify columns, constraints, and foreign keys\n")
+		fmt.Fprintf(style.Stderr, "    - Comments (-- and /* */) - Documentation and notes\n\n")
+		fmt.Fprintf(style.Stderr, "  INVALID SQL STATEMENTS (rejected by validation):\n\n")
+		fmt.Fprintf(style.Stderr, "    Data Loss Operations (ERROR):\n")
+		fmt.Fprintf(style.Stderr, "      - DROP TABLE - Permanently deletes all data\n")
+		fmt.Fprintf(style.Stderr, "      - DROP COLUMN - Irreversible data loss\n")
+		fmt.Fprintf(style.Stderr, "      - TRUNCATE TABLE - Deletes all rows\n")
+		fmt.Fprintf(style.Stderr, "      - DELETE without WHERE - Unintentional data deletion\n\n")
+		fmt.Fprintf(style.Stderr, "    Non-Declarative Patterns (ERROR):\n")
+		fmt.Fprintf(style.Stderr, "      - IF NOT EXISTS clauses - Makes schema non-deterministic\n")
+		fmt.Fprintf(style.Stderr, "      - Transaction control (BEGIN, COMMIT, ROLLBACK) - Lockplane manages transactions\n")
+		fmt.Fprintf(style.Stderr, "      - CREATE OR REPLACE - Use plain CREATE statements\n\n")
+		fmt.Fprintf(style.Stderr, "  MULTI-FILE SUPPORT:\n\n")
+		fmt.Fprintf(style.Stderr, "    You can organize schema across multiple .lp.sql files in a directory.\n")
+		fmt.Fprintf(style.Stderr, "    Files are combined in lexicographic order, so use prefixes for explicit\n")
+		fmt.Fprintf(style.Stderr, "    ordering (e.g., 001_tables.lp.sql, 010_indexes.lp.sql).\n\n")
+		fmt.Fprintf(style.Stderr, "    Only top-level files are considered - subdirectories and symlinks are\n")
+		fmt.Fprintf(style.Stderr, "    skipped to avoid accidental recursion.\n\n")
+		fmt.Fprintf(style.Stderr, "  VALIDATION CHECKS:\n\n")
+		fmt.Fprintf(style.Stderr, "    1. SQL Syntax - Statement-by-statement parsing using PostgreSQL parser\n")
+		fmt.Fprintf(style.Stderr, "       - Detects multiple syntax errors in a single pass\n")
+		fmt.Fprintf(style.Stderr, "       - Reports exact line numbers for each error\n\n")
+		fmt.Fprintf(style.Stderr, "    2. database.Schema Structure - Referential integrity and consistency\n")
+		fmt.Fprintf(style.Stderr, "       - Duplicate column names\n")
+		fmt.Fprintf(style.Stderr, "       - Missing data types\n")
+		fmt.Fprintf(style.Stderr, "       - Missing primary keys (warning)\n")
+		fmt.Fprintf(style.Stderr, "       - Invalid foreign key references\n")
+		fmt.Fprintf(style.Stderr, "       - Duplicate index names\n")
+		fmt.Fprintf(style.Stderr, "       - Invalid index column references\n\n")
+		fmt.Fprintf(style.Stderr, "    3. Dangerous Patterns - Data loss risks flagged as errors\n")
+		fmt.Fprintf(style.Stderr, "    4. Non-Declarative Patterns - Imperative SQL not allowed\n\n")
+		fmt.Fprintf(style.Stderr, "Options:\n")
 		fs.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  # Validate SQL schema file (text output)\n")
-		fmt.Fprintf(os.Stderr, "  lockplane validate sql schema.lp.sql\n\n")
-		fmt.Fprintf(os.Stderr, "  # Validate with JSON output (for IDE integration)\n")
-		fmt.Fprintf(os.Stderr, "  lockplane validate sql --output-format json schema.lp.sql\n\n")
-		fmt.Fprintf(os.Stderr, "  # Validate directory of SQL files\n")
-		fmt.Fprintf(os.Stderr, "  lockplane validate sql schema/\n\n")
-		fmt.Fprintf(os.Stderr, "  # Example valid .lp.sql file:\n")
-		fmt.Fprintf(os.Stderr, "  #   CREATE TABLE users (\n")
-		fmt.Fprintf(os.Stderr, "  #     id BIGINT PRIMARY KEY,\n")
-		fmt.Fprintf(os.Stderr, "  #     email TEXT NOT NULL,\n")
-		fmt.Fprintf(os.Stderr, "  #     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()\n")
-		fmt.Fprintf(os.Stderr, "  #   );\n")
-		fmt.Fprintf(os.Stderr, "  #   CREATE UNIQUE INDEX users_email_idx ON users(email);\n\n")
+		fmt.Fprintf(style.Stderr, "\nExamples:\n")
+		fmt.Fprintf(style.Stderr, "  # Validate SQL schema file (text output)\n")
+		fmt.Fprintf(style.Stderr, "  lockplane validate sql schema.lp.sql\n\n")
+		fmt.Fprintf(style.Stderr, "  # Validate with JSON output (for IDE integration)\n")
+		fmt.Fprintf(style.Stderr, "  lockplane validate sql --output-format json schema.lp.sql\n\n")
+		fmt.Fprintf(style.Stderr, "  # Validate directory of SQL files\n")
+		fmt.Fprintf(style.Stderr, "  lockplane validate sql schema/\n\n")
+		fmt.Fprintf(style.Stderr, "  # Example valid .lp.sql file:\n")
+		fmt.Fprintf(style.Stderr, "  #   CREATE TABLE users (\n")
+		fmt.Fprintf(style.Stderr, "  #     id BIGINT PRIMARY KEY,\n")
+		fmt.Fprintf(style.Stderr, "  #     email TEXT NOT NULL,\n")
+		fmt.Fprintf(style.Stderr, "  #     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()\n")
+		fmt.Fprintf(style.Stderr, "  #   );\n")
+		fmt.Fprintf(style.Stderr, "  #   CREATE UNIQUE INDEX users_email_idx ON users(email);\n\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -191,15 +194,15 @@ func RunValidateSQL(args []string) {
 	} else {
 		// Text output
 		if len(issues) == 0 {
-			fmt.Fprintf(os.Stderr, "✓ SQL schema is valid: %s\n", path)
+			fmt.Fprintf(style.Stderr, "✓ SQL schema is valid: %s\n", path)
 		} else {
-			fmt.Fprintf(os.Stderr, "✗ SQL schema validation failed: %s\n\n", path)
+			fmt.Fprintf(style.Stderr, "✗ SQL schema validation failed: %s\n\n", path)
 			for _, issue := range issues {
 				severity := "ERROR"
 				if issue.Severity == "warning" {
 					severity = "WARNING"
 				}
-				fmt.Fprintf(os.Stderr, "%s:%d:%d: %s: %s\n",
+				fmt.Fprintf(style.Stderr, "%s:%d:%d: %s: %s\n",
 					issue.File, issue.Line, issue.Column, severity, issue.Message)
 			}
 			os.Exit(1)
@@ -221,11 +224,14 @@ func validateSQLFile(filePath string) []ValidationIssue {
 		}}
 	}
 
+	// Positions are computed on LF line endings
+	content := sqlsplit.NormalizeLineEndings(string(sqlContent))
+
 	// First, do statement-by-statement syntax validation
-	syntaxIssues := validateSQLSyntax(filePath, string(sqlContent))
+	syntaxIssues := validateSQLSyntax(filePath, content)
 
 	// Check for dangerous patterns (data loss operations, etc.)
-	dangerousIssues := validateDangerousPatterns(filePath, string(sqlContent))
+	dangerousIssues := validateDangerousPatterns(filePath, content)
 
 	// Combine all issues
 	return append(syntaxIssues, dangerousIssues...)
@@ -446,99 +452,16 @@ type sqlStatement struct {
 }
 
 // splitSQLStatements splits SQL into individual statements by semicolons
-// while preserving line numbers for error reporting
+// while preserving line numbers for error reporting. Each statement starts at
+// its first non-whitespace character, so its line 1 is startLine.
 func splitSQLStatements(sql string) []sqlStatement {
 	var statements []sqlStatement
-	var currentStmt strings.Builder
-	currentLine := 1
-	stmtStartLine := 1
-	hasSeenNonWhitespace := false
-
-	inSingleQuote := false
-	inDoubleQuote := false
-	inLineComment := false
-	inBlockComment := false
-
-	runes := []rune(sql)
-	for i := 0; i < len(runes); i++ {
-		ch := runes[i]
-
-		// Handle newlines
-		if ch == '\n' {
-			currentLine++
-			if inLineComment {
-				inLineComment = false
-			}
-		}
-
-		// Handle comments
-		if !inSingleQuote && !inDoubleQuote {
-			// Line comment start
-			if !inBlockComment && i+1 < len(runes) && ch == '-' && runes[i+1] == '-' {
-				inLineComment = true
-			}
-			// Block comment start
-			if !inLineComment && i+1 < len(runes) && ch == '/' && runes[i+1] == '*' {
-				inBlockComment = true
-			}
-			// Block comment end
-			if inBlockComment && i+1 < len(runes) && ch == '*' && runes[i+1] == '/' {
-				inBlockComment = false
-				currentStmt.WriteRune(ch)
-				i++
-				if i < len(runes) {
-					currentStmt.WriteRune(runes[i])
-				}
-				continue
-			}
-		}
-
-		// Handle string literals
-		if !inLineComment && !inBlockComment {
-			if ch == '\'' && (i == 0 || runes[i-1] != '\\') {
-				inSingleQuote = !inSingleQuote
-			}
-			if ch == '"' && (i == 0 || runes[i-1] != '\\') {
-				inDoubleQuote = !inDoubleQuote
-			}
-		}
-
-		// Check for statement terminator (semicolon)
-		if ch == ';' && !inSingleQuote && !inDoubleQuote && !inLineComment && !inBlockComment {
-			currentStmt.WriteRune(ch)
-			statements = append(statements, sqlStatement{
-				sql:       currentStmt.String(),
-				startLine: stmtStartLine,
-			})
-			currentStmt.Reset()
-			hasSeenNonWhitespace = false
-			// Don't set stmtStartLine here - wait until we see actual content
-			continue
-		}
-
-		// Track the first non-whitespace, non-comment character for line number
-		if !hasSeenNonWhitespace && !inLineComment && !inBlockComment {
-			if ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r' {
-				stmtStartLine = currentLine
-				hasSeenNonWhitespace = true
-			}
-		}
-
-		// Only add to statement after we've seen the first non-whitespace character
-		// This ensures stmt.sql doesn't have leading blank lines that throw off line counting
-		if hasSeenNonWhitespace {
-			currentStmt.WriteRune(ch)
-		}
-	}
-
-	// Add final statement if there's content
-	if currentStmt.Len() > 0 {
+	for _, stmt := range sqlsplit.Split(sql) {
 		statements = append(statements, sqlStatement{
-			sql:       currentStmt.String(),
-			startLine: stmtStartLine,
+			sql:       strings.TrimLeftFunc(stmt.Text, unicode.IsSpace),
+			startLine: stmt.StartLine,
 		})
 	}
-
 	return statements
 }
 
@@ -1,6 +1,8 @@
 package sqlvalidation
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -48,6 +50,22 @@ CREATE INVALID syntax here (
 			expectedLine: 7, // CREATE INVALID (line 7 in the SQL string)
 			expectedMsg:  "syntax error at or near \"INVALID\"",
 		},
+		{
+			name: "semicolons and quotes inside string defaults",
+			sql: `CREATE TABLE notes (
+  sep TEXT DEFAULT 'a;b',
+  quote TEXT DEFAULT 'it''s; fine',
+  path TEXT DEFAULT 'C:\',
+  escaped TEXT DEFAULT E'\'; ok',
+  body TEXT DEFAULT $$x;y$$,
+  label TEXT DEFAULT 'café; ☕'
+);
+CREATE ha TABLE todos (
+  id TEXT PRIMARY KEY
+);`,
+			expectedLine: 9,
+			expectedMsg:  "syntax error at or near \"ha\"",
+		},
 	}
 
 	for _, tt := range tests {
@@ -207,3 +225,37 @@ ALTER TABLE genomes ENABLE ROW LEVEL SECURITY;`
 		t.Errorf("expected error message to mention 'CRETE', got: %s", issues[0].Message)
 	}
 }
+
+// TestValidateSQLFile_CRLF checks that files saved with Windows line endings
+// get the same positions as their LF versions
+func TestValidateSQLFile_CRLF(t *testing.T) {
+	fixtures := map[string]string{
+		"syntax error": "CREATE TABLE users (\n  id integer PRIMARY KEY,\n  email text NOT NULL\n  name text\n);\n",
+		"typo":         "-- users\n\nCRETE TABLE users (\n  id integer\n);\n",
+		"drop table":   "CREATE TABLE users (\n  id integer PRIMARY KEY\n);\n\nDROP TABLE users;\n",
+	}
+
+	dir := t.TempDir()
+	for name, lf := range fixtures {
+		lfPath := filepath.Join(dir, "lf.lp.sql")
+		crlfPath := filepath.Join(dir, "crlf.lp.sql")
+		if err := os.WriteFile(lfPath, []byte(lf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(crlfPath, []byte(strings.ReplaceAll(lf, "\n", "\r\n")), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		want := validateSQLFile(lfPath)
+		got := validateSQLFile(crlfPath)
+		if len(want) == 0 || len(got) != len(want) {
+			t.Fatalf("%s: expected the same issues, got %+v and %+v", name, want, got)
+		}
+		for i := range want {
+			if got[i].Line != want[i].Line || got[i].Column != want[i].Column || got[i].Message != want[i].Message {
+				t.Errorf("%s: expected %d:%d %q, got %d:%d %q", name,
+					want[i].Line, want[i].Column, want[i].Message, got[i].Line, got[i].Column, got[i].Message)
+			}
+		}
+	}
+}
@@ -6,16 +6,47 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/lockplane/lockplane/database"
 )
 
 // StateFile is the filename for state tracking
 const StateFile = ".lockplane-state.json"
 
-// State tracks multi-phase migration progress
+// State tracks multi-phase migration progress and interrupted applies
 // Stored in .lockplane-state.json in the project root (git-ignored)
 type State struct {
 	Version         string           `json:"version"` // State file format version
 	ActiveMigration *ActiveMigration `json:"active_migration,omitempty"`
+	// ApplyCheckpoints records unfinished applies of plans with
+	// non-transactional steps, keyed by target database
+	ApplyCheckpoints map[string]*ApplyCheckpoint `json:"apply_checkpoints,omitempty"`
+	// WindowOverrides records every apply that ran outside its
+	// environment's apply window with --override-window
+	WindowOverrides []WindowOverride `json:"window_overrides,omitempty"`
+}
+
+// WindowOverride records why an apply ran outside the apply window
+type WindowOverride struct {
+	Target    string    `json:"target"`         // Target environment
+	Window    string    `json:"window"`         // The apply window that was closed
+	Reason    string    `json:"reason"`         // Reason given with --override-window
+	User      string    `json:"user,omitempty"` // OS user who ran the apply
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// ApplyCheckpoint records how far an apply got. Plans with non-transactional
+// steps (e.g. CREATE INDEX CONCURRENTLY) are committed in parts, so a run
+// that is killed leaves the database between the plan's source and target.
+type ApplyCheckpoint struct {
+	PlanHash     string           `json:"plan_hash"`     // Identifies the plan being applied
+	Target       string           `json:"target"`        // Target environment, for messages
+	TotalSteps   int              `json:"total_steps"`   // Number of steps in the plan
+	StepsApplied int              `json:"steps_applied"` // Steps committed to the database
+	SchemaHash   string           `json:"schema_hash"`   // Database schema hash after StepsApplied steps
+	SourceSchema *database.Schema `json:"source_schema"` // Database schema before the apply, for cleanup plans
+	StartedAt    time.Time        `json:"started_at"`
+	LastUpdated  time.Time        `json:"last_updated"`
 }
 
 // ActiveMigration tracks the currently running multi-phase migration
@@ -190,3 +221,35 @@ func (s *State) GetNextPhase() int {
 
 	return s.ActiveMigration.CurrentPhase + 1
 }
+
+// ApplyCheckpoint returns the checkpoint of an unfinished apply on the target
+// database, or nil
+func (s *State) ApplyCheckpoint(target string) *ApplyCheckpoint {
+	return s.ApplyCheckpoints[target]
+}
+
+// SaveApplyCheckpoint records the progress of an apply on the target database
+func (s *State) SaveApplyCheckpoint(target string, checkpoint *ApplyCheckpoint) error {
+	if s.ApplyCheckpoints == nil {
+		s.ApplyCheckpoints = map[string]*ApplyCheckpoint{}
+	}
+	checkpoint.LastUpdated = time.Now()
+	s.ApplyCheckpoints[target] = checkpoint
+	return s.Save()
+}
+
+// ClearApplyCheckpoint removes the checkpoint of the target database (use
+// after the apply completes or is aborted)
+func (s *State) ClearApplyCheckpoint(target string) error {
+	if _, ok := s.ApplyCheckpoints[target]; !ok {
+		return nil
+	}
+	delete(s.ApplyCheckpoints, target)
+	return s.Save()
+}
+
+// RecordWindowOverride appends an apply window override to the history
+func (s *State) RecordWindowOverride(override WindowOverride) error {
+	s.WindowOverrides = append(s.WindowOverrides, override)
+	return s.Save()
+}
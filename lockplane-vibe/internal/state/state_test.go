@@ -3,6 +3,8 @@ package state
 import (
 	"os"
 	"testing"
+
+	"github.com/lockplane/lockplane/database"
 )
 
 func TestStateLoadAndSave(t *testing.T) {
@@ -219,3 +221,86 @@ func TestGetNextPhase(t *testing.T) {
 		t.Error("Expected active migration to be cleared")
 	}
 }
+
+func TestApplyCheckpoint(t *testing.T) {
+	defer func() { _ = os.Remove(StateFile) }()
+
+	state, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	if state.ApplyCheckpoint("target-a") != nil {
+		t.Fatal("Expected no checkpoint")
+	}
+
+	err = state.SaveApplyCheckpoint("target-a", &ApplyCheckpoint{
+		PlanHash:     "plan",
+		TotalSteps:   3,
+		StepsApplied: 2,
+		SchemaHash:   "schema",
+		SourceSchema: &database.Schema{Tables: []database.Table{{Name: "users"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	// Reload from disk
+	state, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to reload state: %v", err)
+	}
+
+	checkpoint := state.ApplyCheckpoint("target-a")
+	if checkpoint == nil {
+		t.Fatal("Expected checkpoint after reload")
+	}
+	if checkpoint.StepsApplied != 2 || checkpoint.PlanHash != "plan" || checkpoint.SchemaHash != "schema" {
+		t.Errorf("Unexpected checkpoint: %+v", checkpoint)
+	}
+	if checkpoint.SourceSchema == nil || len(checkpoint.SourceSchema.Tables) != 1 {
+		t.Errorf("Expected source schema to survive reload, got %+v", checkpoint.SourceSchema)
+	}
+	if checkpoint.LastUpdated.IsZero() {
+		t.Error("Expected LastUpdated to be set")
+	}
+	if state.ApplyCheckpoint("target-b") != nil {
+		t.Error("Expected checkpoints to be per target")
+	}
+
+	if err := state.ClearApplyCheckpoint("target-a"); err != nil {
+		t.Fatalf("Failed to clear checkpoint: %v", err)
+	}
+	state, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to reload state: %v", err)
+	}
+	if state.ApplyCheckpoint("target-a") != nil {
+		t.Error("Expected checkpoint to be cleared")
+	}
+}
+
+func TestRecordWindowOverride(t *testing.T) {
+	defer func() { _ = os.Remove(StateFile) }()
+
+	state, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	for _, reason := range []string{"hotfix for incident 42", "customer outage"} {
+		if err := state.RecordWindowOverride(WindowOverride{Target: "production", Window: "02:00-04:00 UTC", Reason: reason}); err != nil {
+			t.Fatalf("Failed to record override: %v", err)
+		}
+	}
+
+	state, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to reload state: %v", err)
+	}
+	if len(state.WindowOverrides) != 2 {
+		t.Fatalf("Expected 2 overrides after reload, got %+v", state.WindowOverrides)
+	}
+	if state.WindowOverrides[0].Reason != "hotfix for incident 42" || state.WindowOverrides[1].Target != "production" {
+		t.Errorf("Unexpected overrides: %+v", state.WindowOverrides)
+	}
+}
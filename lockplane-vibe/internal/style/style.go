@@ -0,0 +1,155 @@
+// Package style decides how lockplane's human-readable output looks: whether
+// ANSI colors are used and whether emoji are kept. Every renderer (the fatih
+// color output of the commands and the lipgloss screens of the init wizard
+// and plan review) follows the decision made once by Configure.
+package style
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/termenv"
+)
+
+// EnvNoColor is the NO_COLOR environment variable (https://no-color.org):
+// when set to any non-empty value, output has no ANSI colors
+const EnvNoColor = "NO_COLOR"
+
+var (
+	colorEnabled = true
+	emojiEnabled = true
+
+	// Stdout and Stderr are the streams human-readable output is written to.
+	// With emoji disabled they strip emoji from everything written.
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
+// Configure sets the output style for the rest of the process. Colors are
+// used unless noColor is set, NO_COLOR is set, TERM is "dumb" or stdout is
+// not a terminal; emoji are kept unless noEmoji is set.
+func Configure(noColor, noEmoji bool) {
+	colorEnabled = useColor(noColor, os.Getenv, stdoutIsTerminal())
+	emojiEnabled = !noEmoji
+
+	color.NoColor = !colorEnabled
+	if !colorEnabled {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	Stdout, Stderr = io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if !emojiEnabled {
+		Stdout, Stderr = plainWriter{os.Stdout}, plainWriter{os.Stderr}
+	}
+}
+
+// ColorEnabled reports whether output uses ANSI colors
+func ColorEnabled() bool {
+	return colorEnabled
+}
+
+// EmojiEnabled reports whether output keeps its emoji
+func EmojiEnabled() bool {
+	return emojiEnabled
+}
+
+// Text returns s as it should be shown: unchanged, or without its emoji when
+// emoji are disabled. Renderers that build whole screens, like the wizard,
+// pass their output through it.
+func Text(s string) string {
+	if emojiEnabled {
+		return s
+	}
+	return StripEmoji(s)
+}
+
+// Icon returns icon, or fallback when emoji are disabled. It is for markers
+// whose meaning would be lost if the emoji were just stripped.
+func Icon(icon, fallback string) string {
+	if emojiEnabled {
+		return icon
+	}
+	return fallback
+}
+
+// useColor reports whether colors are used, given the --no-color flag, the
+// environment and whether stdout is a terminal
+func useColor(noColor bool, getenv func(string) string, terminal bool) bool {
+	if noColor || getenv(EnvNoColor) != "" || getenv("TERM") == "dumb" {
+		return false
+	}
+	return terminal
+}
+
+func stdoutIsTerminal() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// StripEmoji removes the emoji of s, with the spaces following each one, so
+// "✅ Plan applied" becomes "Plan applied". Plain symbols such as ✓, ✗, •
+// and arrows are kept.
+func StripEmoji(s string) string {
+	if !hasEmoji(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	skipSpaces := false
+	for i, r := range s {
+		next, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):])
+		switch {
+		case isEmoji(r) || (next == '\uFE0F' && r > utf8.RuneSelf):
+			skipSpaces = true
+		case r == '\uFE0F' || r == '\u200D':
+			// Variation selectors and joiners belong to the emoji before them
+		case r == ' ' && skipSpaces:
+		default:
+			skipSpaces = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func hasEmoji(s string) bool {
+	for _, r := range s {
+		if isEmoji(r) || r == '\uFE0F' {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmoji reports whether r is shown as an emoji by default. Check and ballot
+// marks (✓ ✗) are text symbols and are not emoji.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Pictographs, emoticons, transport, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return r < 0x2713 || r > 0x2718
+	case r >= 0x231A && r <= 0x231B, r >= 0x23E9 && r <= 0x23FA: // Watches, hourglasses, media controls
+		return true
+	case r == 0x2139, r == 0x2B50, r == 0x2B55, r == 0x2B1B, r == 0x2B1C: // ℹ ⭐ ⭕ ⬛ ⬜
+		return true
+	}
+	return false
+}
+
+// plainWriter strips emoji from everything written to w
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, StripEmoji(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
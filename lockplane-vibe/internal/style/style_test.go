@@ -0,0 +1,82 @@
+package style
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"✅ Plan applied", "Plan applied"},
+		{"⚠️  Skipping --advise: no database\n", "Skipping --advise: no database\n"},
+		{"   🔍 Checking 3 tables", "   Checking 3 tables"},
+		{"ℹ️ Nothing to do", "Nothing to do"},
+		{"⏳ Waiting for lock", "Waiting for lock"},
+		{"✓ users → accounts • done", "✓ users → accounts • done"},
+		{"✗ failed ▶ next", "✗ failed ▶ next"},
+		{"👩‍💻 dev", "dev"},
+		{"plain text", "plain text"},
+	}
+	for _, tt := range tests {
+		if got := StripEmoji(tt.in); got != tt.want {
+			t.Errorf("StripEmoji(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	tests := []struct {
+		name     string
+		noColor  bool
+		vars     map[string]string
+		terminal bool
+		want     bool
+	}{
+		{"terminal", false, nil, true, true},
+		{"not a terminal", false, nil, false, false},
+		{"--no-color", true, nil, true, false},
+		{"NO_COLOR", false, map[string]string{EnvNoColor: "1"}, true, false},
+		{"empty NO_COLOR", false, map[string]string{EnvNoColor: ""}, true, true},
+		{"dumb terminal", false, map[string]string{"TERM": "dumb"}, true, false},
+	}
+	for _, tt := range tests {
+		if got := useColor(tt.noColor, env(tt.vars), tt.terminal); got != tt.want {
+			t.Errorf("%s: useColor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { Configure(false, false) })
+
+	Configure(true, true)
+	if ColorEnabled() || EmojiEnabled() {
+		t.Fatalf("Expected colors and emoji disabled, got color=%v emoji=%v", ColorEnabled(), EmojiEnabled())
+	}
+	if got := Text("🚀 Applying"); got != "Applying" {
+		t.Errorf("Expected the emoji stripped, got %q", got)
+	}
+	if got := Icon("⚑", "!"); got != "!" {
+		t.Errorf("Expected the fallback icon, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	w := plainWriter{&buf}
+	n, err := w.Write([]byte("❌ Error: boom\n"))
+	if err != nil || n != len("❌ Error: boom\n") {
+		t.Errorf("Expected the full length written, got %d, %v", n, err)
+	}
+	if buf.String() != "Error: boom\n" {
+		t.Errorf("Expected the emoji stripped from the stream, got %q", buf.String())
+	}
+
+	Configure(false, false)
+	if !EmojiEnabled() || Text("🚀 Applying") != "🚀 Applying" {
+		t.Error("Expected emoji kept by default")
+	}
+}
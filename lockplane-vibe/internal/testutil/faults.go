@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lockplane/lockplane/database/sqlite"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/testkit"
+)
+
+// faultyDBCount makes each faulty database's name unique
+var faultyDBCount atomic.Int64
+
+// OpenFaultyDB opens an in-memory SQLite database whose statements go through
+// faults (see testkit.WrapDriver). All connections share the same database,
+// so tables created in a rolled-back transaction can be checked for
+// afterwards. The database is closed when the test ends.
+func OpenFaultyDB(t testing.TB, faults *testkit.FaultInjector) *TestDB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:lockplane_faults_%d?mode=memory&cache=shared", faultyDBCount.Add(1))
+	base, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	drv := base.Driver()
+	_ = base.Close()
+
+	db := sql.OpenDB(testkit.WrapDriver(drv, dsn, faults))
+	// Keep one connection open so the shared in-memory database survives
+	db.SetMaxIdleConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return &TestDB{
+		DB:     db,
+		Driver: sqlite.NewDriver(),
+		Type:   "sqlite",
+		ctx:    context.Background(),
+	}
+}
+
+// ExpectedResult describes the ExecutionResult a test expects
+type ExpectedResult struct {
+	Success      bool
+	StepsApplied int
+	// ErrorContains lists substrings that must each appear in some entry of
+	// ExecutionResult.Errors
+	ErrorContains []string
+}
+
+// AssertExecutionResult fails the test unless result matches want
+func AssertExecutionResult(t testing.TB, result *planner.ExecutionResult, want ExpectedResult) {
+	t.Helper()
+
+	if result == nil {
+		t.Fatal("Expected an execution result, got nil")
+	}
+	if result.Success != want.Success {
+		t.Errorf("Expected Success=%v, got %v (errors: %v)", want.Success, result.Success, result.Errors)
+	}
+	if result.StepsApplied != want.StepsApplied {
+		t.Errorf("Expected %d steps applied, got %d", want.StepsApplied, result.StepsApplied)
+	}
+	for _, substr := range want.ErrorContains {
+		found := false
+		for _, msg := range result.Errors {
+			if strings.Contains(msg, substr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected an error containing %q, got %v", substr, result.Errors)
+		}
+	}
+	if want.Success && len(result.Errors) > 0 {
+		t.Errorf("Expected no errors, got %v", result.Errors)
+	}
+}
@@ -0,0 +1,190 @@
+// Package textdiff renders line-based unified diffs.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// opKind is the kind of an edit operation
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// edit is one line of the edit script. aLine and bLine are the 0-based
+// line numbers in a and b the edit was made at.
+type edit struct {
+	kind  opKind
+	line  string
+	aLine int
+	bLine int
+}
+
+// Unified returns the unified diff of a and b with the given number of
+// context lines, or "" when they are equal. aName and bName label the
+// --- and +++ headers.
+func Unified(aName, bName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for _, hunk := range hunks(edits, context) {
+		writeHunk(&sb, hunk)
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script turning a into b, using
+// Myers' O(ND) algorithm
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+2)
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Move down: insert from b
+			} else {
+				x = v[offset+k-1] + 1 // Move right: delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved V arrays from the end to recover the edit script
+func backtrack(a, b []string, trace [][]int, offset, d int) []edit {
+	x, y := len(a), len(b)
+	var edits []edit
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{kind: opEqual, line: a[x], aLine: x, bLine: y})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{kind: opInsert, line: b[y], aLine: x, bLine: y})
+		} else {
+			x--
+			edits = append(edits, edit{kind: opDelete, line: a[x], aLine: x, bLine: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{kind: opEqual, line: a[x], aLine: x, bLine: y})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// hunks groups the changes of an edit script with up to context unchanged
+// lines around them. Changes separated by at most 2*context unchanged lines
+// share a hunk.
+func hunks(edits []edit, context int) [][]edit {
+	var result [][]edit
+	start, end := -1, -1
+	for i, e := range edits {
+		if e.kind == opEqual {
+			continue
+		}
+		lo, hi := max(i-context, 0), min(i+context, len(edits)-1)
+		if start >= 0 && lo <= end+1 {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			result = append(result, edits[start:end+1])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		result = append(result, edits[start:end+1])
+	}
+	return result
+}
+
+// writeHunk writes a hunk with its @@ header
+func writeHunk(sb *strings.Builder, hunk []edit) {
+	aStart, bStart := hunk[0].aLine, hunk[0].bLine
+	var aCount, bCount int
+	for _, e := range hunk {
+		if e.kind != opInsert {
+			aCount++
+		}
+		if e.kind != opDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, e := range hunk {
+		switch e.kind {
+		case opEqual:
+			sb.WriteString(" ")
+		case opDelete:
+			sb.WriteString("-")
+		case opInsert:
+			sb.WriteString("+")
+		}
+		sb.WriteString(e.line)
+		sb.WriteString("\n")
+	}
+}
+
+// hunkRange formats the 1-based start line and line count of a hunk side.
+// An empty side is reported at the line before it, as diff -u does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,65 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified_Equal(t *testing.T) {
+	if got := Unified("a", "b", "x\ny\n", "x\ny\n", DefaultContext); got != "" {
+		t.Errorf("Expected no diff for equal text, got:\n%s", got)
+	}
+}
+
+func TestUnified_Change(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\n"
+	b := "one\ntwo\n3\nfour\nfive\n"
+	want := `--- before
++++ after
+@@ -1,4 +1,5 @@
+ one
+ two
+-three
++3
+ four
++five
+`
+	if got := Unified("before", "after", a, b, DefaultContext); got != want {
+		t.Errorf("Unexpected diff.\nWant:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, strings.Repeat("x", i+1))
+	}
+	a := strings.Join(lines, "\n") + "\n"
+	changed := append([]string(nil), lines...)
+	changed[1] = "changed"
+	changed[18] = "changed too"
+	b := strings.Join(changed, "\n") + "\n"
+
+	got := Unified("a", "b", a, b, 1)
+	if strings.Count(got, "@@ -") != 2 {
+		t.Fatalf("Expected 2 hunks, got:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -1,3 +1,3 @@\n x\n-xx\n+changed\n xxx\n") {
+		t.Errorf("Unexpected first hunk:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -18,3 +18,3 @@\n") {
+		t.Errorf("Unexpected second hunk header:\n%s", got)
+	}
+}
+
+func TestUnified_EmptySide(t *testing.T) {
+	want := `--- a
++++ b
+@@ -0,0 +1,2 @@
++CREATE TABLE users ();
++
+`
+	if got := Unified("a", "b", "", "CREATE TABLE users ();\n\n", DefaultContext); got != want {
+		t.Errorf("Unexpected diff.\nWant:\n%s\nGot:\n%s", want, got)
+	}
+}
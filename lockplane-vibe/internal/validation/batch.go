@@ -0,0 +1,89 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+)
+
+// BatchedUpdateValidator validates that the table of a batched update has the
+// ordering key the update names
+type BatchedUpdateValidator struct {
+	Step   int // 1-based step number
+	Update planner.BatchedUpdate
+	Table  *database.Table // The table in the database the plan applies to (nil = missing)
+}
+
+func (v *BatchedUpdateValidator) Validate() ValidationResult {
+	if planner.IsOrderingKey(v.Table, v.Update.Key) {
+		return ValidationResult{
+			Valid:      true,
+			Reversible: false,
+			Errors:     []string{},
+			Warnings:   []string{},
+			Reasons:    []string{fmt.Sprintf("Batches of %s are ranges of its unique, NOT NULL column %s", v.Update.Table, v.Update.Key)},
+		}
+	}
+
+	var problem string
+	var alternatives []string
+	switch {
+	case v.Table == nil:
+		problem = fmt.Sprintf("table %s does not exist", v.Update.Table)
+	case !hasColumn(v.Table, v.Update.Key):
+		problem = fmt.Sprintf("%s has no column %s", v.Update.Table, v.Update.Key)
+	default:
+		problem = fmt.Sprintf("%s is not the single-column primary key of %s, or a NOT NULL column with a unique index of its own", v.Update.Key, v.Update.Table)
+	}
+	if key := planner.OrderingKey(v.Table); key != "" {
+		alternatives = append(alternatives, fmt.Sprintf("Order the batches by %s instead", key))
+	}
+	alternatives = append(alternatives, "Add a unique index on a NOT NULL column in an earlier migration and order the batches by it")
+
+	return ValidationResult{
+		Valid:      false,
+		Reversible: false,
+		Errors: []string{
+			fmt.Sprintf("Step %d updates %s in batches ordered by %s, but %s", v.Step, v.Update.Table, v.Update.Key, problem),
+		},
+		Warnings: []string{},
+		Reasons: []string{
+			"Batches are ranges of the ordering key, so rows with a NULL or duplicate key would be skipped or updated twice",
+		},
+		Safety: &SafetyClassification{
+			Level:             SafetyLevelDangerous,
+			SaferAlternatives: alternatives,
+		},
+	}
+}
+
+// ValidateBatchedUpdates checks that every batched update step of plan can
+// be ordered by its key in sourceSchema, the database the plan applies to.
+// Only failed checks are returned.
+func ValidateBatchedUpdates(plan *planner.Plan, sourceSchema *database.Schema) []ValidationResult {
+	var results []ValidationResult
+	for i, step := range plan.Steps {
+		if step.Batch == nil {
+			continue
+		}
+		validator := &BatchedUpdateValidator{
+			Step:   i + 1,
+			Update: *step.Batch,
+			Table:  database.FindTable(sourceSchema, step.Batch.Table),
+		}
+		if result := validator.Validate(); !result.Valid {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func hasColumn(table *database.Table, name string) bool {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
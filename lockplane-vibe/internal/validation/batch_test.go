@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+)
+
+func TestValidateBatchedUpdates(t *testing.T) {
+	source := &database.Schema{Tables: []database.Table{{
+		Name: "users",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", IsPrimaryKey: true},
+			{Name: "email", Type: "text", Nullable: true},
+		},
+	}}}
+	plan := &planner.Plan{Steps: []planner.PlanStep{
+		{Description: "Create index", SQL: []string{"CREATE INDEX users_email_idx ON users (email)"}},
+		planner.BatchedUpdateStep(postgres.NewDriver(), "Lowercase emails", planner.BatchedUpdate{Table: "users", Key: "id", Set: "email = lower(email)"}),
+		planner.BatchedUpdateStep(postgres.NewDriver(), "Lowercase emails", planner.BatchedUpdate{Table: "users", Key: "email", Set: "email = lower(email)"}),
+		planner.BatchedUpdateStep(postgres.NewDriver(), "Fill orders", planner.BatchedUpdate{Table: "orders", Key: "id", Set: "total = 0"}),
+	}}
+
+	results := ValidateBatchedUpdates(plan, source)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 failed checks, got %+v", results)
+	}
+	if !strings.Contains(results[0].Errors[0], "Step 3 updates users in batches ordered by email") {
+		t.Errorf("Unexpected error: %s", results[0].Errors[0])
+	}
+	if alternatives := results[0].Safety.SaferAlternatives; alternatives[0] != "Order the batches by id instead" {
+		t.Errorf("Expected the primary key to be suggested, got %v", alternatives)
+	}
+	if !strings.Contains(results[1].Errors[0], "table orders does not exist") {
+		t.Errorf("Unexpected error: %s", results[1].Errors[0])
+	}
+}
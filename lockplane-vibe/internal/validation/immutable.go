@@ -0,0 +1,204 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// CodeImmutableTable is the diagnostic code of a change to an immutable table
+const CodeImmutableTable = "immutable_table"
+
+// ImmutableTableValidator rejects changes to a table declared immutable,
+// unless the change was allowed with a reason
+type ImmutableTableValidator struct {
+	TableName      string
+	Changes        []string // What the plan does to the table, e.g. "drop column email"
+	DeclaredAt     string   // Where the table was declared immutable
+	OverrideReason string   // Reason the change is allowed anyway, if any
+}
+
+func (v *ImmutableTableValidator) Validate() ValidationResult {
+	message := fmt.Sprintf("Table '%s' is immutable (declared at %s) but the plan would %s",
+		v.TableName, v.DeclaredAt, strings.Join(v.Changes, ", "))
+	result := ValidationResult{
+		Valid:      false,
+		Reversible: true, // The changes' own validators report whether they can be rolled back
+		Errors:     []string{message},
+		Warnings:   []string{},
+		Reasons: []string{
+			"Immutable tables may only be created; altering, dropping or re-indexing them needs --allow-immutable-change with a reason",
+		},
+		Safety: &SafetyClassification{
+			Level: SafetyLevelDangerous,
+			SaferAlternatives: []string{
+				fmt.Sprintf("Create a new table instead of changing '%s'", v.TableName),
+				fmt.Sprintf("Remove the declaration at %s if the table is no longer immutable", v.DeclaredAt),
+			},
+		},
+		Code: CodeImmutableTable,
+	}
+	if v.OverrideReason != "" {
+		result.Valid = true
+		result.Errors = []string{}
+		result.Warnings = []string{fmt.Sprintf("%s; allowed by --allow-immutable-change: %s", message, v.OverrideReason)}
+		result.Safety.Level = SafetyLevelReview
+		result.Safety.SaferAlternatives = nil
+	}
+	return result
+}
+
+// validateImmutableTables reports the removed and modified tables of diff
+// that are declared immutable
+func validateImmutableTables(diff *schema.SchemaDiff, sourceSchema *database.Schema, opts ValidationOptions) []ValidationResult {
+	if len(opts.ImmutableTables) == 0 {
+		return nil
+	}
+
+	var results []ValidationResult
+	check := func(tableName string, changes []string, source *database.SourceLocation) {
+		declaredAt, ok := schema.IsImmutableTable(opts.ImmutableTables, tableName)
+		if !ok || len(changes) == 0 {
+			return
+		}
+		validator := &ImmutableTableValidator{
+			TableName:      tableName,
+			Changes:        changes,
+			DeclaredAt:     declaredAt,
+			OverrideReason: strings.TrimSpace(opts.AllowImmutableChange),
+		}
+		results = append(results, locate(validator.Validate(), source))
+	}
+
+	for _, table := range diff.RemovedTables {
+		check(table.Name, []string{"drop the table"}, findTableSource(sourceSchema, table.Name))
+	}
+	for _, tableDiff := range diff.ModifiedTables {
+		check(tableDiff.TableName, tableDiffChanges(tableDiff), tableDiff.Source)
+	}
+	return results
+}
+
+// tableDiffChanges describes the changes a table diff makes
+func tableDiffChanges(td schema.TableDiff) []string {
+	var changes []string
+	for _, col := range td.AddedColumns {
+		changes = append(changes, "add column "+col.Name)
+	}
+	for _, col := range td.RemovedColumns {
+		changes = append(changes, "drop column "+col.Name)
+	}
+	for _, col := range td.ModifiedColumns {
+		changes = append(changes, fmt.Sprintf("change %s of column %s", strings.Join(col.Changes, ", "), col.ColumnName))
+	}
+	for _, idx := range td.AddedIndexes {
+		changes = append(changes, "add index "+idx.Name)
+	}
+	for _, idx := range td.RemovedIndexes {
+		changes = append(changes, "drop index "+idx.Name)
+	}
+	for _, idx := range td.RecreatedIndexes {
+		changes = append(changes, "recreate index "+idx.IndexName)
+	}
+	for _, idx := range td.RenamedIndexes {
+		changes = append(changes, "rename index "+idx.IndexName)
+	}
+	for _, idx := range td.MovedIndexes {
+		changes = append(changes, "move index "+idx.Name)
+	}
+	for _, fk := range td.AddedForeignKeys {
+		changes = append(changes, "add foreign key "+fk.Name)
+	}
+	for _, fk := range td.RemovedForeignKeys {
+		changes = append(changes, "drop foreign key "+fk.Name)
+	}
+	if len(td.RenamedForeignKeys) > 0 {
+		changes = append(changes, "rename foreign keys")
+	}
+	if td.RLSChanged {
+		changes = append(changes, "change row level security")
+	}
+	if td.TablespaceChanged {
+		changes = append(changes, "move it to another tablespace")
+	}
+	if td.ReplicaIdentityChanged {
+		changes = append(changes, "change its replica identity")
+	}
+	if len(td.SetStorageParameters) > 0 || len(td.ResetStorageParameters) > 0 {
+		changes = append(changes, "change its storage parameters")
+	}
+	if td.ColumnOrderChanged {
+		changes = append(changes, "reorder its columns")
+	}
+	return changes
+}
+
+// ImmutableStep is a plan step that changes a table declared immutable
+type ImmutableStep struct {
+	Index      int // Zero-based position in the plan
+	Step       planner.PlanStep
+	Table      string
+	DeclaredAt string
+}
+
+// FindImmutableSteps returns the plan steps that alter or drop a table in
+// immutable, which maps table names to where they were declared immutable.
+// Creating a table, along with its indexes and foreign keys in the same plan,
+// is allowed.
+func FindImmutableSteps(plan *planner.Plan, immutable map[string]string) []ImmutableStep {
+	if len(immutable) == 0 {
+		return nil
+	}
+
+	created := map[string]bool{}
+	for _, step := range plan.Steps {
+		if step.Operation != nil && step.Operation.Kind == planner.OperationCreateTable {
+			created[step.Operation.Table] = true
+		}
+	}
+
+	var steps []ImmutableStep
+	for i, step := range plan.Steps {
+		table := stepTable(step)
+		if table == "" || created[table] {
+			continue
+		}
+		if declaredAt, ok := schema.IsImmutableTable(immutable, table); ok {
+			steps = append(steps, ImmutableStep{Index: i, Step: step, Table: table, DeclaredAt: declaredAt})
+		}
+	}
+	return steps
+}
+
+// stepTablePattern matches the table of a DROP TABLE or ALTER TABLE statement
+var stepTablePattern = regexp.MustCompile(`(?i)^\s*(?:DROP|ALTER)\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?((?:"[^"]+"|[\w$]+)(?:\s*\.\s*(?:"[^"]+"|[\w$]+))?)`)
+
+// stepTable returns the table a plan step changes. Steps of plans written
+// before steps recorded their operation are matched by their SQL.
+func stepTable(step planner.PlanStep) string {
+	if step.Operation != nil {
+		return step.Operation.Table
+	}
+	if len(step.SQL) == 0 {
+		return ""
+	}
+	if match := stepTablePattern.FindStringSubmatch(step.SQL[0]); match != nil {
+		return strings.ReplaceAll(strings.Join(strings.Fields(match[1]), ""), `"`, "")
+	}
+	return ""
+}
+
+// HasImmutableViolations reports whether results include a change to an
+// immutable table that wasn't allowed
+func HasImmutableViolations(results []ValidationResult) bool {
+	for _, result := range results {
+		if result.Code == CodeImmutableTable && !result.Valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestValidateImmutableTables(t *testing.T) {
+	before := &database.Schema{Tables: []database.Table{
+		{Name: "audit_log", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		{Name: "ledger", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+	}}
+	diff := &schema.SchemaDiff{
+		AddedTables:   []database.Table{{Name: "events", Columns: []database.Column{{Name: "id", Type: "integer"}}}},
+		RemovedTables: []database.Table{before.Tables[1]},
+		ModifiedTables: []schema.TableDiff{{
+			TableName:    "audit_log",
+			AddedColumns: []database.Column{{Name: "note", Type: "text", Nullable: true}},
+		}},
+	}
+	immutable := map[string]string{
+		"audit_log": "schema/audit.lp.sql:3:1",
+		"ledger":    "lockplane.toml: immutable_tables",
+		"events":    "schema/events.lp.sql:1:1",
+	}
+
+	results := ValidateSchemaDiffWithOptions(diff, before, nil, ValidationOptions{ImmutableTables: immutable})
+	if AllValid(results) || !HasImmutableViolations(results) {
+		t.Fatalf("Expected changes to immutable tables to fail validation, got %+v", results)
+	}
+	var errors []string
+	for _, result := range results {
+		if result.Code == CodeImmutableTable {
+			errors = append(errors, result.Errors...)
+		}
+	}
+	if len(errors) != 2 {
+		t.Fatalf("Expected the dropped and altered tables to be reported, got %v", errors)
+	}
+	if !strings.Contains(errors[0], "'ledger'") || !strings.Contains(errors[0], "lockplane.toml: immutable_tables") || !strings.Contains(errors[0], "drop the table") {
+		t.Errorf("Expected the drop of ledger with its declaration, got %q", errors[0])
+	}
+	if !strings.Contains(errors[1], "schema/audit.lp.sql:3:1") || !strings.Contains(errors[1], "add column note") {
+		t.Errorf("Expected the added column of audit_log with its declaration, got %q", errors[1])
+	}
+
+	results = ValidateSchemaDiffWithOptions(diff, before, nil, ValidationOptions{ImmutableTables: immutable, AllowImmutableChange: "approved in ticket 42"})
+	if HasImmutableViolations(results) {
+		t.Fatalf("Expected --allow-immutable-change to allow the changes, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Code == CodeImmutableTable && (len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "approved in ticket 42")) {
+			t.Errorf("Expected the override reason in the warning, got %v", result.Warnings)
+		}
+	}
+}
+
+func TestFindImmutableSteps(t *testing.T) {
+	plan := &planner.Plan{Steps: []planner.PlanStep{
+		{Description: "Create table events", SQL: []string{"CREATE TABLE events (id integer)"}, Operation: &planner.Operation{Kind: planner.OperationCreateTable, Table: "events"}},
+		{Description: "Create index idx_events_id", SQL: []string{"CREATE INDEX idx_events_id ON events (id)"}, Operation: &planner.Operation{Table: "events"}},
+		{Description: "Add column note to table audit_log", SQL: []string{"ALTER TABLE audit_log ADD COLUMN note text"}},
+		{Description: "Drop table public.ledger", SQL: []string{"DROP TABLE public.ledger"}},
+		{Description: "Drop table users", SQL: []string{"DROP TABLE users"}},
+	}}
+	immutable := map[string]string{"events": "a", "audit_log": "b", "ledger": "c"}
+
+	steps := FindImmutableSteps(plan, immutable)
+	if len(steps) != 2 {
+		t.Fatalf("Expected 2 steps on immutable tables, got %+v", steps)
+	}
+	if steps[0].Index != 2 || steps[0].Table != "audit_log" || steps[0].DeclaredAt != "b" {
+		t.Errorf("Expected the altered audit_log, got %+v", steps[0])
+	}
+	if steps[1].Index != 3 || steps[1].DeclaredAt != "c" {
+		t.Errorf("Expected the dropped ledger, got %+v", steps[1])
+	}
+}
@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// riskRank orders safety levels from least to most risky. Dangerous outranks
+// Multi-Phase: it means permanent data loss rather than a deployment process.
+var riskRank = map[SafetyLevel]int{
+	SafetyLevelSafe:       0,
+	SafetyLevelReview:     1,
+	SafetyLevelLossy:      2,
+	SafetyLevelMultiPhase: 3,
+	SafetyLevelDangerous:  4,
+}
+
+// SummarizeImpact aggregates the diff and the safety classification of each
+// plan step into an impact summary. The diff is optional; without it only the
+// safety level and row estimate are filled in.
+func SummarizeImpact(plan *planner.Plan, diff *schema.SchemaDiff) *planner.ImpactSummary {
+	summary := &planner.ImpactSummary{}
+
+	if diff != nil {
+		summary.TablesCreated = len(diff.AddedTables)
+		summary.TablesDropped = len(diff.RemovedTables)
+		for _, table := range diff.AddedTables {
+			summary.IndexesAdded += len(table.Indexes)
+			summary.ForeignKeysAdded += len(table.ForeignKeys)
+		}
+		for _, tableDiff := range diff.ModifiedTables {
+			summary.ColumnsAdded += len(tableDiff.AddedColumns)
+			summary.ColumnsDropped += len(tableDiff.RemovedColumns)
+			summary.ColumnsModified += len(tableDiff.ModifiedColumns)
+			summary.IndexesAdded += len(tableDiff.AddedIndexes)
+			summary.IndexesDropped += len(tableDiff.RemovedIndexes)
+			summary.ForeignKeysAdded += len(tableDiff.AddedForeignKeys)
+			summary.ForeignKeysDropped += len(tableDiff.RemovedForeignKeys)
+		}
+	}
+
+	if plan == nil {
+		return summary
+	}
+
+	highest := SafetyLevel(-1)
+	for _, step := range plan.Steps {
+		result := AnalyzePlanStep(step, diff)
+		if result == nil || result.Safety == nil {
+			continue
+		}
+		if highest < 0 || riskRank[result.Safety.Level] > riskRank[highest] {
+			highest = result.Safety.Level
+		}
+		summary.EstimatedRows += result.Safety.AffectedRows
+	}
+	if highest >= 0 {
+		summary.HighestSafety = highest.String()
+	}
+
+	return summary
+}
+
+// SummarizeSafety counts validation results by safety level for the summary
+// of a safety report
+func SummarizeSafety(results []ValidationResult) *planner.SafetySummary {
+	summary := &planner.SafetySummary{Operations: len(results), Valid: true}
+	for _, result := range results {
+		if !result.Valid {
+			summary.Blocked++
+			summary.Valid = false
+		}
+		if !result.Reversible {
+			summary.Irreversible++
+		}
+		if result.Safety == nil {
+			continue
+		}
+		if result.Safety.LockLevel != "" {
+			if summary.LockLevels == nil {
+				summary.LockLevels = map[string]int{}
+			}
+			summary.LockLevels[result.Safety.LockLevel]++
+		}
+		if result.Safety.Rewrite {
+			summary.Rewrites++
+		}
+		switch result.Safety.Level {
+		case SafetyLevelSafe:
+			summary.Safe++
+		case SafetyLevelReview:
+			summary.Review++
+		case SafetyLevelLossy:
+			summary.Lossy++
+		case SafetyLevelDangerous:
+			summary.Dangerous++
+		case SafetyLevelMultiPhase:
+			summary.MultiPhase++
+		}
+	}
+	return summary
+}
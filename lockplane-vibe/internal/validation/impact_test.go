@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestSummarizeImpact(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{
+				Name:        "posts",
+				Columns:     []database.Column{{Name: "id", Type: "bigint"}, {Name: "user_id", Type: "bigint"}},
+				Indexes:     []database.Index{{Name: "idx_posts_user_id", Columns: []string{"user_id"}}},
+				ForeignKeys: []database.ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			},
+		},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:      "users",
+				AddedColumns:   []database.Column{{Name: "bio", Type: "text", Nullable: true}},
+				RemovedColumns: []database.Column{{Name: "legacy", Type: "text", Nullable: true}},
+				RemovedIndexes: []database.Index{{Name: "idx_users_legacy", Columns: []string{"legacy"}}},
+			},
+		},
+	}
+	plan := &planner.Plan{
+		Steps: []planner.PlanStep{
+			{SQL: []string{"CREATE TABLE posts (id bigint NOT NULL, user_id bigint NOT NULL)"}},
+			{SQL: []string{"ALTER TABLE users ADD COLUMN bio text"}},
+			{SQL: []string{"DROP INDEX idx_users_legacy"}},
+			{SQL: []string{"ALTER TABLE users DROP COLUMN legacy"}},
+		},
+	}
+
+	summary := SummarizeImpact(plan, diff)
+
+	expected := planner.ImpactSummary{
+		TablesCreated:    1,
+		ColumnsAdded:     1,
+		ColumnsDropped:   1,
+		IndexesAdded:     1,
+		IndexesDropped:   1,
+		ForeignKeysAdded: 1,
+		HighestSafety:    SafetyLevelDangerous.String(),
+	}
+	if *summary != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *summary)
+	}
+}
+
+func TestSummarizeImpact_SafePlan(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{TableName: "users", AddedColumns: []database.Column{{Name: "bio", Type: "text", Nullable: true}}},
+		},
+	}
+	plan := &planner.Plan{
+		Steps: []planner.PlanStep{{SQL: []string{"ALTER TABLE users ADD COLUMN bio text"}}},
+	}
+
+	summary := SummarizeImpact(plan, diff)
+	if summary.ColumnsAdded != 1 || summary.HighestSafety != SafetyLevelSafe.String() || summary.EstimatedRows != 0 {
+		t.Errorf("Unexpected summary: %+v", *summary)
+	}
+}
+
+func TestDropTableValidator_AffectedRows(t *testing.T) {
+	result := (&DropTableValidator{Table: database.Table{Name: "users"}, RowCount: 1200}).Validate()
+	if result.Safety.AffectedRows != 1200 {
+		t.Errorf("Expected 1200 affected rows, got %d", result.Safety.AffectedRows)
+	}
+}
+
+func TestSummarizeSafety(t *testing.T) {
+	results := []ValidationResult{
+		{Valid: true, Reversible: true, Safety: &SafetyClassification{Level: SafetyLevelSafe}},
+		{Valid: true, Reversible: true, Safety: &SafetyClassification{Level: SafetyLevelSafe}},
+		{Valid: true, Reversible: false, Safety: &SafetyClassification{Level: SafetyLevelLossy}},
+		{Valid: false, Reversible: false, Safety: &SafetyClassification{Level: SafetyLevelDangerous}},
+		{Valid: true, Reversible: true},
+	}
+
+	summary := SummarizeSafety(results)
+
+	expected := planner.SafetySummary{
+		Operations:   5,
+		Safe:         2,
+		Lossy:        1,
+		Dangerous:    1,
+		Blocked:      1,
+		Irreversible: 2,
+		Valid:        false,
+	}
+	if !reflect.DeepEqual(*summary, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, *summary)
+	}
+
+	if empty := SummarizeSafety(nil); !empty.Valid || empty.Operations != 0 {
+		t.Errorf("Expected an empty report to pass, got %+v", *empty)
+	}
+}
@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/locks"
+)
+
+// volatileDefaultPattern matches DEFAULT expressions PostgreSQL evaluates once
+// per row, so adding a column with them rewrites the table on any version
+var volatileDefaultPattern = regexp.MustCompile(`(?i)\b(nextval|random|clock_timestamp|statement_timestamp|timeofday|gen_random_uuid|uuid_generate_v1|uuid_generate_v1mc|uuid_generate_v4|txid_current)\s*\(`)
+
+// serialTypes are column types whose implicit DEFAULT is nextval()
+var serialTypes = map[string]bool{"smallserial": true, "serial": true, "bigserial": true, "serial2": true, "serial4": true, "serial8": true}
+
+// withLock records on result the PostgreSQL lock its operation takes and
+// whether it rewrites the table or index. Results without a safety
+// classification are left alone.
+func withLock(result ValidationResult, mode locks.LockMode, rewrite bool) ValidationResult {
+	if result.Safety != nil {
+		result.Safety.LockLevel = mode.String()
+		result.Safety.Rewrite = rewrite
+	}
+	return result
+}
+
+// addColumnLock returns the lock adding col takes and whether it rewrites
+// the table. Since PostgreSQL 11 only a volatile DEFAULT does; before, any
+// DEFAULT did.
+func addColumnLock(col database.Column, serverVersion int) (locks.LockMode, bool) {
+	if serialTypes[strings.ToLower(strings.TrimSpace(col.Type))] {
+		return locks.LockAccessExclusive, true
+	}
+	if col.Default == nil || strings.TrimSpace(*col.Default) == "" {
+		return locks.LockAccessExclusive, false
+	}
+	if volatileDefaultPattern.MatchString(*col.Default) {
+		return locks.LockAccessExclusive, true
+	}
+	fastDefault := serverVersion == 0 || serverVersion >= postgres.FastDefaultMinVersion
+	return locks.LockAccessExclusive, !fastDefault
+}
+
+// addForeignKeyLock returns the lock adding a foreign key takes on its table
+// and the referenced table. It scans the table to validate the key, but
+// doesn't rewrite it.
+func addForeignKeyLock(serverVersion int) locks.LockMode {
+	if serverVersion != 0 && serverVersion < postgres.ForeignKeyShareRowExclusiveMinVersion {
+		return locks.LockAccessExclusive
+	}
+	return locks.LockShareRowExclusive
+}
+
+// typeModifierPattern splits a type into its name and modifiers, e.g.
+// "numeric(10,2)" into "numeric" and "10,2"
+var typeModifierPattern = regexp.MustCompile(`^([a-z ]+?)\s*(?:\(([\d\s,]*)\))?$`)
+
+// alterTypeRewrites reports whether changing a column from oldType to
+// newType rewrites the table. Binary-coercible changes that only relax a
+// length or precision limit don't: varchar(n) to a longer varchar or text,
+// and numeric(p,s) to a larger precision with the same scale.
+func alterTypeRewrites(oldType, newType string) bool {
+	oldName, oldMods, ok := splitTypeModifiers(oldType)
+	if !ok {
+		return true
+	}
+	newName, newMods, ok := splitTypeModifiers(newType)
+	if !ok {
+		return true
+	}
+
+	switch {
+	case isVarcharType(oldName) && (newName == "text" || isVarcharType(newName) && widens(oldMods, newMods, 0)):
+		return false
+	case oldName == "text" && isVarcharType(newName) && len(newMods) == 0:
+		return false
+	case isNumericType(oldName) && isNumericType(newName):
+		if len(newMods) == 0 {
+			return false
+		}
+		if len(oldMods) == 0 {
+			return true
+		}
+		return !widens(oldMods[:1], newMods[:1], 0) || scale(oldMods) != scale(newMods)
+	case oldName == "cidr" && newName == "inet":
+		return false
+	}
+	return true
+}
+
+// splitTypeModifiers returns a lowercase type name and its numeric modifiers
+func splitTypeModifiers(columnType string) (string, []int, bool) {
+	match := typeModifierPattern.FindStringSubmatch(strings.ToLower(strings.Join(strings.Fields(columnType), " ")))
+	if match == nil {
+		return "", nil, false
+	}
+	var mods []int
+	if strings.TrimSpace(match[2]) != "" {
+		for _, part := range strings.Split(match[2], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return "", nil, false
+			}
+			mods = append(mods, n)
+		}
+	}
+	return strings.TrimSpace(match[1]), mods, true
+}
+
+func isVarcharType(name string) bool {
+	return name == "varchar" || name == "character varying"
+}
+
+func isNumericType(name string) bool {
+	return name == "numeric" || name == "decimal"
+}
+
+// widens reports whether the modifier at i of newMods allows at least what
+// oldMods does; no modifier means no limit
+func widens(oldMods, newMods []int, i int) bool {
+	if len(newMods) <= i {
+		return true
+	}
+	return len(oldMods) > i && newMods[i] >= oldMods[i]
+}
+
+// scale returns the scale of numeric modifiers, which defaults to 0
+func scale(mods []int) int {
+	if len(mods) > 1 {
+		return mods[1]
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/locks"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestAddColumnLock(t *testing.T) {
+	constant := "0"
+	volatile := "gen_random_uuid()"
+	tests := []struct {
+		name          string
+		col           database.Column
+		serverVersion int
+		rewrite       bool
+	}{
+		{"no default", database.Column{Name: "note", Type: "text", Nullable: true}, 100000, false},
+		{"constant default on 11", database.Column{Name: "n", Type: "integer", Default: &constant}, 110000, false},
+		{"constant default on unknown version", database.Column{Name: "n", Type: "integer", Default: &constant}, 0, false},
+		{"constant default on 10", database.Column{Name: "n", Type: "integer", Default: &constant}, 100000, true},
+		{"volatile default", database.Column{Name: "id", Type: "uuid", Default: &volatile}, 160000, true},
+		{"serial", database.Column{Name: "seq", Type: "bigserial"}, 160000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, rewrite := addColumnLock(tt.col, tt.serverVersion)
+			if mode != locks.LockAccessExclusive {
+				t.Errorf("Expected ACCESS EXCLUSIVE, got %s", mode)
+			}
+			if rewrite != tt.rewrite {
+				t.Errorf("Expected rewrite %v, got %v", tt.rewrite, rewrite)
+			}
+		})
+	}
+}
+
+func TestAddForeignKeyLock(t *testing.T) {
+	if mode := addForeignKeyLock(0); mode != locks.LockShareRowExclusive {
+		t.Errorf("Expected SHARE ROW EXCLUSIVE on an unknown version, got %s", mode)
+	}
+	if mode := addForeignKeyLock(90400); mode != locks.LockAccessExclusive {
+		t.Errorf("Expected ACCESS EXCLUSIVE before 9.5, got %s", mode)
+	}
+}
+
+func TestAlterTypeRewrites(t *testing.T) {
+	tests := []struct {
+		oldType, newType string
+		rewrite          bool
+	}{
+		{"varchar(50)", "varchar(100)", false},
+		{"character varying(50)", "varchar", false},
+		{"varchar(100)", "varchar(50)", true},
+		{"varchar", "varchar(50)", true},
+		{"varchar(50)", "text", false},
+		{"text", "varchar", false},
+		{"text", "varchar(50)", true},
+		{"numeric(10,2)", "numeric(12,2)", false},
+		{"numeric(10,2)", "numeric", false},
+		{"numeric(10,2)", "numeric(12,3)", true},
+		{"numeric(10,2)", "numeric(8,2)", true},
+		{"cidr", "inet", false},
+		{"integer", "bigint", true},
+		{"text", "jsonb", true},
+	}
+	for _, tt := range tests {
+		if got := alterTypeRewrites(tt.oldType, tt.newType); got != tt.rewrite {
+			t.Errorf("alterTypeRewrites(%q, %q) = %v, want %v", tt.oldType, tt.newType, got, tt.rewrite)
+		}
+	}
+}
+
+func TestValidateSchemaDiffLockLevels(t *testing.T) {
+	constant := "'pending'"
+	before := &database.Schema{
+		Dialect:       database.DialectPostgres,
+		ServerVersion: 160000,
+		Tables: []database.Table{
+			{Name: "orders", Columns: []database.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "total", Type: "integer"},
+			}},
+		},
+	}
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{{
+			TableName:    "orders",
+			AddedColumns: []database.Column{{Name: "status", Type: "text", Nullable: true, Default: &constant}},
+			ModifiedColumns: []schema.ColumnDiff{{
+				ColumnName: "total",
+				Old:        database.Column{Name: "total", Type: "integer"},
+				New:        database.Column{Name: "total", Type: "bigint"},
+				Changes:    []string{"type"},
+			}},
+		}},
+	}
+
+	results := ValidateSchemaDiffWithOptions(diff, before, nil, ValidationOptions{})
+	var addColumn, alterType *SafetyClassification
+	for _, result := range results {
+		if result.Safety == nil {
+			continue
+		}
+		if result.Safety.Rewrite {
+			alterType = result.Safety
+		} else if result.Safety.LockLevel != "" {
+			addColumn = result.Safety
+		}
+	}
+	if addColumn == nil || addColumn.LockLevel != "ACCESS EXCLUSIVE" {
+		t.Errorf("Expected the added column to take ACCESS EXCLUSIVE without a rewrite, got %+v", addColumn)
+	}
+	if alterType == nil || alterType.LockLevel != "ACCESS EXCLUSIVE" {
+		t.Errorf("Expected the type change to rewrite under ACCESS EXCLUSIVE, got %+v", alterType)
+	}
+
+	summary := SummarizeSafety(results)
+	if summary.LockLevels["ACCESS EXCLUSIVE"] != 2 || summary.Rewrites != 1 {
+		t.Errorf("Expected 2 ACCESS EXCLUSIVE operations and 1 rewrite, got %+v", summary)
+	}
+
+	before.Dialect = database.DialectSQLite
+	for _, result := range ValidateSchemaDiffWithOptions(diff, before, nil, ValidationOptions{}) {
+		if result.Safety != nil && result.Safety.LockLevel != "" {
+			t.Errorf("Expected no lock levels on SQLite, got %q", result.Safety.LockLevel)
+		}
+	}
+}
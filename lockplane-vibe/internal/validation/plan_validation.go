@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"os"
 
 	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/style"
 )
 
 // PlanValidationIssue represents a validation error or warning for plans
@@ -29,18 +31,20 @@ type PlanValidationResult struct {
 func RunValidatePlan(args []string) {
 	fs := flag.NewFlagSet("validate plan", flag.ExitOnError)
 	formatFlag := fs.String("format", "text", "Output format: text or json")
+	allowEmptyFlag := fs.Bool("allow-empty-plan", false, "Accept a plan without steps")
+	allowInsecureFlag := fs.Bool("allow-insecure-plan-url", false, "Allow fetching the plan over plain http://")
 
 	// Custom usage function
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: lockplane validate plan [options] <file>\n\n")
-		fmt.Fprintf(os.Stderr, "Validate a migration plan JSON file against the Lockplane plan schema.\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
+		fmt.Fprintf(style.Stderr, "Usage: lockplane validate plan [options] <file>\n\n")
+		fmt.Fprintf(style.Stderr, "Validate a migration plan JSON file against the Lockplane plan schema.\n\n")
+		fmt.Fprintf(style.Stderr, "Options:\n")
 		fs.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  # Validate plan file (text output)\n")
-		fmt.Fprintf(os.Stderr, "  lockplane validate plan migration.json\n\n")
-		fmt.Fprintf(os.Stderr, "  # Validate with JSON output\n")
-		fmt.Fprintf(os.Stderr, "  lockplane validate plan --format json migration.json\n\n")
+		fmt.Fprintf(style.Stderr, "\nExamples:\n")
+		fmt.Fprintf(style.Stderr, "  # Validate plan file (text output)\n")
+		fmt.Fprintf(style.Stderr, "  lockplane validate plan migration.json\n\n")
+		fmt.Fprintf(style.Stderr, "  # Validate with JSON output\n")
+		fmt.Fprintf(style.Stderr, "  lockplane validate plan --format json migration.json\n\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -55,7 +59,10 @@ func RunValidatePlan(args []string) {
 	path := fs.Arg(0)
 
 	// Load and validate the plan
-	_, err := planner.LoadJSONPlan(path)
+	_, err := planner.LoadJSONPlanFromSourceWithOptions(context.Background(), path, os.Stdin, planner.PlanLoadOptions{
+		AllowEmpty:        *allowEmptyFlag,
+		AllowInsecureHTTP: *allowInsecureFlag,
+	})
 	if err != nil {
 		if *formatFlag == "json" {
 			// Output as JSON for programmatic consumption
@@ -75,8 +82,8 @@ func RunValidatePlan(args []string) {
 			jsonBytes, _ := json.MarshalIndent(result, "", "  ")
 			fmt.Println(string(jsonBytes))
 		} else {
-			fmt.Fprintf(os.Stderr, "✗ Plan validation failed: %s\n\n", path)
-			fmt.Fprintf(os.Stderr, "  %s\n", err.Error())
+			fmt.Fprintf(style.Stderr, "✗ Plan validation failed: %s\n\n", path)
+			fmt.Fprintf(style.Stderr, "  %s\n", err.Error())
 		}
 		os.Exit(1)
 	}
@@ -93,6 +100,6 @@ func RunValidatePlan(args []string) {
 		}
 		fmt.Println(string(jsonBytes))
 	} else {
-		fmt.Fprintf(os.Stderr, "✓ Plan is valid: %s\n", path)
+		fmt.Fprintf(style.Stderr, "✓ Plan is valid: %s\n", path)
 	}
 }
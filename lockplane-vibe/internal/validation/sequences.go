@@ -0,0 +1,221 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// AlterSequenceValidator validates changing a sequence's options in place
+type AlterSequenceValidator struct {
+	SequenceName string
+	Changes      []string // As in schema.SequenceDiff.Changes
+	// Restart is the value the sequence is restarted at, when Changes has "restart"
+	Restart *int64
+	// LastValue is the last value the sequence returned (nil = unknown or never used)
+	LastValue *int64
+	// Backward reports that the restart hands out values the sequence
+	// already returned (see schema.RestartMovesBackward)
+	Backward bool
+}
+
+func (v *AlterSequenceValidator) Validate() ValidationResult {
+	result := ValidationResult{
+		Valid:      true,
+		Reversible: true,
+		Errors:     []string{},
+		Warnings:   []string{},
+		Reasons: []string{
+			fmt.Sprintf("Alter %s of sequence %s in place; its current value is kept", strings.Join(v.Changes, ", "), v.SequenceName),
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelSafe,
+			RollbackDescription: "Rollback restores the previous options",
+		},
+	}
+
+	if !slices.Contains(v.Changes, "restart") || v.Restart == nil {
+		return result
+	}
+
+	result.Reasons = []string{fmt.Sprintf("Restart sequence %s at %d", v.SequenceName, *v.Restart)}
+	result.Safety.RollbackDescription = "Rollback restarts the sequence where it was; values handed out in between are returned again"
+	if !v.Backward {
+		result.Safety.Level = SafetyLevelReview
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("Restarting sequence %s at %d skips the values before it", v.SequenceName, *v.Restart))
+		return result
+	}
+
+	lastValue := "its last value"
+	if v.LastValue != nil {
+		lastValue = fmt.Sprintf("its last value %d", *v.LastValue)
+	}
+	result.Warnings = append(result.Warnings,
+		fmt.Sprintf("Restarting sequence %s at %d moves it back past %s: it hands out values it already returned, and inserts using them can fail with duplicate keys",
+			v.SequenceName, *v.Restart, lastValue))
+	result.Safety.Level = SafetyLevelDangerous
+	result.Safety.BreakingChange = true
+	result.Safety.SaferAlternatives = []string{
+		fmt.Sprintf("Restart the sequence past the largest key in use, e.g. SELECT setval('%s', (SELECT max(id) FROM <table>))", v.SequenceName),
+		"Remove the RESTART from the schema files once it has been applied",
+	}
+	return result
+}
+
+// DropSequenceValidator validates dropping a sequence
+type DropSequenceValidator struct {
+	Sequence database.Sequence
+}
+
+func (v *DropSequenceValidator) Validate() ValidationResult {
+	name := v.Sequence.QualifiedName()
+	return ValidationResult{
+		Valid:      true,
+		Reversible: true,
+		Errors:     []string{},
+		Warnings: []string{
+			fmt.Sprintf("Dropping sequence %s breaks the column defaults and queries that call nextval() on it", name),
+		},
+		Reasons: []string{
+			fmt.Sprintf("Drop sequence %s", name),
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelLossy,
+			BreakingChange:      true,
+			RollbackDataLoss:    true,
+			RollbackDescription: "Rollback recreates the sequence at its introspected position; values handed out after that are returned again",
+			SaferAlternatives: []string{
+				"Remove the defaults and code that use the sequence before dropping it",
+			},
+		},
+	}
+}
+
+// validateSequences validates the sequence changes of a diff
+func validateSequences(diff *schema.SchemaDiff) []ValidationResult {
+	var results []ValidationResult
+	for _, seqDiff := range diff.ModifiedSequences {
+		results = append(results, sequenceDiffValidator(seqDiff).Validate())
+	}
+	for _, seq := range diff.RemovedSequences {
+		validator := &DropSequenceValidator{Sequence: seq}
+		results = append(results, validator.Validate())
+	}
+	return results
+}
+
+// sequenceStepValidator returns the validator of a plan step that alters a
+// sequence, from the details the planner recorded
+func sequenceStepValidator(details map[string]string) *AlterSequenceValidator {
+	validator := &AlterSequenceValidator{
+		SequenceName: details["name"],
+		Changes:      strings.Split(details["changes"], ","),
+		Restart:      parseInt64Detail(details["restart"]),
+		LastValue:    parseInt64Detail(details["last_value"]),
+		Backward:     details["restart_direction"] == "backward",
+	}
+	return validator
+}
+
+// parseInt64Detail parses a numeric operation detail, or returns nil when it
+// is missing or not a number
+func parseInt64Detail(value string) *int64 {
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// sequenceDiffValidator returns the validator of a changed sequence
+func sequenceDiffValidator(seqDiff schema.SequenceDiff) *AlterSequenceValidator {
+	validator := &AlterSequenceValidator{
+		SequenceName: seqDiff.SequenceName,
+		Changes:      seqDiff.Changes,
+		LastValue:    seqDiff.Old.LastValue,
+	}
+	if slices.Contains(seqDiff.Changes, "restart") && seqDiff.New.Restart != nil {
+		validator.Restart = seqDiff.New.Restart
+		validator.Backward = schema.RestartMovesBackward(seqDiff.Old, *seqDiff.New.Restart)
+	}
+	return validator
+}
+
+// SequenceDataTypeValidator validates that the target server accepts the
+// data type of a created or altered sequence
+type SequenceDataTypeValidator struct {
+	SequenceName  string
+	DataType      string
+	ServerVersion int // server_version_num of the target (0 = unknown)
+}
+
+func (v *SequenceDataTypeValidator) Validate() ValidationResult {
+	feature := postgres.FeatureSequenceDataType
+	if feature.SupportedBy(v.ServerVersion) {
+		return ValidationResult{
+			Valid:      true,
+			Reversible: true,
+			Errors:     []string{},
+			Warnings:   []string{},
+			Reasons:    []string{"Target server supports sequence data types"},
+		}
+	}
+
+	return ValidationResult{
+		Valid:      false,
+		Reversible: true,
+		Errors: []string{
+			fmt.Sprintf("Sequence %s is declared AS %s, which requires PostgreSQL %s or later (target server is %s)",
+				v.SequenceName, v.DataType, feature.MinVersionLabel(), postgres.FormatServerVersion(v.ServerVersion)),
+		},
+		Warnings: []string{},
+		Reasons: []string{
+			"PostgreSQL versions before 10 only have bigint sequences and reject CREATE SEQUENCE ... AS",
+		},
+		Safety: &SafetyClassification{
+			Level: SafetyLevelDangerous,
+			SaferAlternatives: []string{
+				"Drop the AS clause and bound the sequence with MAXVALUE instead",
+				"Upgrade the target server to PostgreSQL 10 or later",
+			},
+		},
+	}
+}
+
+// validateSequenceServerSupport checks the data types of created and altered
+// sequences against the version of the server they will run on. Sequences
+// are bigint by default, and CREATE SEQUENCE only says AS for other types.
+func validateSequenceServerSupport(diff *schema.SchemaDiff, serverVersion int) []ValidationResult {
+	if serverVersion == 0 {
+		return nil
+	}
+
+	var results []ValidationResult
+	check := func(seq database.Sequence) {
+		validator := &SequenceDataTypeValidator{
+			SequenceName:  seq.QualifiedName(),
+			DataType:      schema.EffectiveSequenceSettings(seq).DataType,
+			ServerVersion: serverVersion,
+		}
+		if result := validator.Validate(); !result.Valid {
+			results = append(results, result)
+		}
+	}
+	for _, seq := range diff.AddedSequences {
+		if schema.EffectiveSequenceSettings(seq).DataType != "bigint" {
+			check(seq)
+		}
+	}
+	for _, seqDiff := range diff.ModifiedSequences {
+		if slices.Contains(seqDiff.Changes, "data_type") {
+			check(seqDiff.New)
+		}
+	}
+	return results
+}
@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestAnalyzePlanStep_SequenceRestart(t *testing.T) {
+	lastValue, cache := int64(500), int64(20)
+	current := &database.Schema{Sequences: []database.Sequence{{Name: "order_numbers", LastValue: &lastValue}}}
+
+	tests := []struct {
+		name    string
+		restart int64
+		want    SafetyLevel
+	}{
+		{"backward", 100, SafetyLevelDangerous},
+		{"current value", 500, SafetyLevelDangerous},
+		{"forward", 1000, SafetyLevelReview},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restart := tt.restart
+			desired := &database.Schema{Sequences: []database.Sequence{{Name: "order_numbers", Restart: &restart}}}
+			diff := schema.DiffSchemas(current, desired)
+			plan, err := planner.GeneratePlanWithHash(diff, current, postgres.NewDriver())
+			if err != nil {
+				t.Fatalf("GeneratePlan returned error: %v", err)
+			}
+			if len(plan.Steps) != 1 {
+				t.Fatalf("Expected 1 step, got %d", len(plan.Steps))
+			}
+
+			result := AnalyzePlanStep(plan.Steps[0], diff)
+			if result == nil || result.Safety == nil {
+				t.Fatal("Expected a validation result for ALTER SEQUENCE")
+			}
+			if result.Safety.Level != tt.want {
+				t.Errorf("Expected safety level %v, got %v", tt.want, result.Safety.Level)
+			}
+
+			destructive := len(FindDestructiveSteps(plan, diff)) > 0
+			if destructive != (tt.want == SafetyLevelDangerous) {
+				t.Errorf("FindDestructiveSteps reported destructive = %v for level %v", destructive, tt.want)
+			}
+
+			// The diff-based validation agrees with the step
+			results := ValidateSchemaDiff(diff)
+			if len(results) != 1 || results[0].Safety.Level != tt.want {
+				t.Errorf("Expected one diff result at level %v, got %+v", tt.want, results)
+			}
+		})
+	}
+
+	desired := &database.Schema{Sequences: []database.Sequence{{Name: "order_numbers", Cache: &cache}}}
+	for _, result := range ValidateSchemaDiff(schema.DiffSchemas(current, desired)) {
+		if result.Safety.Level != SafetyLevelSafe {
+			t.Errorf("Expected changing the cache to be safe, got %v", result.Safety.Level)
+		}
+	}
+}
+
+func TestValidateSchemaDiff_DropSequence(t *testing.T) {
+	current := &database.Schema{Sequences: []database.Sequence{{Name: "order_numbers"}}}
+	results := ValidateSchemaDiff(schema.DiffSchemas(current, &database.Schema{}))
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Safety.Level != SafetyLevelLossy || !results[0].Safety.BreakingChange {
+		t.Errorf("Expected dropping a sequence to be a lossy breaking change, got %+v", results[0].Safety)
+	}
+}
+
+func TestValidateServerSupport_SequenceDataType(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		AddedSequences: []database.Sequence{
+			{Name: "invoice_numbers", DataType: "integer"},
+			{Name: "order_numbers"},
+		},
+		ModifiedSequences: []schema.SequenceDiff{
+			{
+				SequenceName: "ticket_numbers",
+				Old:          database.Sequence{Name: "ticket_numbers"},
+				New:          database.Sequence{Name: "ticket_numbers", DataType: "smallint"},
+				Changes:      []string{"data_type"},
+			},
+		},
+	}
+	source := &database.Schema{Dialect: database.DialectPostgres}
+
+	results := ValidateServerSupport(diff, source, ValidationOptions{ServerVersion: 90624})
+	if len(results) != 2 {
+		t.Fatalf("Expected the integer and smallint sequences to be rejected on PostgreSQL 9.6, got %+v", results)
+	}
+	if !strings.Contains(results[0].Errors[0], "invoice_numbers is declared AS integer, which requires PostgreSQL 10 or later (target server is 9.6.24)") {
+		t.Errorf("Unexpected error: %v", results[0].Errors)
+	}
+	if !strings.Contains(results[1].Errors[0], "ticket_numbers is declared AS smallint") {
+		t.Errorf("Unexpected error: %v", results[1].Errors)
+	}
+
+	if results := ValidateServerSupport(diff, source, ValidationOptions{ServerVersion: 100000}); len(results) != 0 {
+		t.Errorf("Expected sequence data types to be accepted on PostgreSQL 10, got %+v", results)
+	}
+}
@@ -0,0 +1,260 @@
+package validation
+
+import (
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/internal/parser"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// AnalyzePlanStep runs the matching validators for a plan step: the one for
+// the step's operation, when it has one, and the one for each of its
+// statements. The riskiest result wins. The diff is optional; when it is
+// available, validators get the full table and column definitions.
+// Returns nil when nothing in the step has a validator.
+func AnalyzePlanStep(step planner.PlanStep, diff *schema.SchemaDiff) *ValidationResult {
+	if len(step.SQL) == 0 {
+		return nil
+	}
+
+	var riskiest *ValidationResult
+	consider := func(validator OperationValidator) {
+		if validator == nil {
+			return
+		}
+		result := validator.Validate()
+		if riskiest == nil || riskiest.Safety == nil ||
+			(result.Safety != nil && riskRank[result.Safety.Level] > riskRank[riskiest.Safety.Level]) {
+			riskiest = &result
+		}
+	}
+	consider(operationValidator(step, diff))
+	for _, stmt := range step.SQL {
+		consider(statementValidator(step, stmt, diff))
+	}
+	return riskiest
+}
+
+// operationValidator returns the validator for the operation a planner
+// generated step records, whatever the quoting of its SQL, or nil
+func operationValidator(step planner.PlanStep, diff *schema.SchemaDiff) OperationValidator {
+	op := step.Operation
+	if op == nil {
+		return nil
+	}
+	switch op.Kind {
+	case planner.OperationDropTable:
+		cascade := false
+		for _, stmt := range step.SQL {
+			cascade = cascade || parser.ContainsSQL(stmt, "CASCADE")
+		}
+		return dropTableValidator(op.Table, cascade, diff)
+
+	case planner.OperationDropColumn:
+		return dropColumnValidator(op.Table, op.Column, diff)
+
+	case planner.OperationAlterColumn:
+		// Plans loaded from a file have no diff, so the type change comes
+		// from the operation
+		if op.Details["new_type"] == "" {
+			return nil
+		}
+		return &AlterColumnTypeValidator{
+			TableName:  op.Table,
+			ColumnName: op.Column,
+			OldType:    op.Details["old_type"],
+			NewType:    op.Details["new_type"],
+		}
+
+	case planner.OperationSoftDropColumn:
+		return &SoftDropColumnValidator{
+			TableName: op.Table,
+			Column:    database.Column{Name: op.Column},
+			Tombstone: op.Details["tombstone"],
+		}
+
+	case planner.OperationAlterSequence:
+		return sequenceStepValidator(op.Details)
+
+	case planner.OperationDropSequence:
+		seq := database.Sequence{Name: op.Details["name"]}
+		if diff != nil {
+			for _, removed := range diff.RemovedSequences {
+				if removed.QualifiedName() == seq.Name {
+					seq = removed
+					break
+				}
+			}
+		}
+		return &DropSequenceValidator{Sequence: seq}
+	}
+	return nil
+}
+
+// statementValidator returns the validator for one statement of a step, or nil
+func statementValidator(step planner.PlanStep, stmt string, diff *schema.SchemaDiff) OperationValidator {
+	switch {
+	case parser.ContainsSQL(stmt, "DROP TABLE"):
+		name, err := parser.ExtractTableNameFromDrop(stmt)
+		if err != nil {
+			return &UnparsedStatementValidator{Statement: stmt, Operation: "DROP TABLE"}
+		}
+		return dropTableValidator(name, parser.ContainsSQL(stmt, "CASCADE"), diff)
+
+	case parser.ContainsSQL(stmt, "DROP COLUMN"):
+		tableName, columnName, err := parser.ExtractTableAndColumnFromDropColumn(stmt)
+		if err != nil {
+			return &UnparsedStatementValidator{Statement: stmt, Operation: "DROP COLUMN"}
+		}
+		return dropColumnValidator(tableName, columnName, diff)
+
+	case parser.IsTruncate(stmt):
+		tableName, err := parser.ExtractTableNameFromTruncate(stmt)
+		if err != nil {
+			return &UnparsedStatementValidator{Statement: stmt, Operation: "TRUNCATE"}
+		}
+		return &TruncateTableValidator{TableName: tableName}
+
+	case parser.ContainsSQL(stmt, "ADD COLUMN"):
+		tableName, columnName, err := parser.ExtractTableAndColumnFromAddColumn(stmt)
+		if err != nil {
+			return nil
+		}
+		tableDiff := findTableDiff(diff, tableName)
+		if tableDiff == nil {
+			return nil
+		}
+		for _, added := range tableDiff.AddedColumns {
+			if added.Name == columnName {
+				addValidator := &AddColumnValidator{TableName: tableName, Column: added}
+				if step.Operation != nil {
+					addValidator.DeferNotNull = step.Operation.Details["not_null"] == "deferred"
+					addValidator.Backfill = step.Operation.Details["backfill"]
+				}
+				return addValidator
+			}
+		}
+
+	case parser.ContainsSQL(stmt, "ALTER COLUMN") && parser.ContainsSQL(stmt, " TYPE "):
+		tableName, columnName, err := parser.ExtractTableAndColumnFromAlterType(stmt)
+		if err != nil {
+			return nil
+		}
+		if tableDiff := findTableDiff(diff, tableName); tableDiff != nil {
+			for _, colDiff := range tableDiff.ModifiedColumns {
+				if colDiff.ColumnName == columnName {
+					return &AlterColumnTypeValidator{
+						TableName:  tableName,
+						ColumnName: columnName,
+						OldType:    colDiff.Old.Type,
+						NewType:    colDiff.New.Type,
+					}
+				}
+			}
+		}
+		// Without the old type the change can't be shown to be safe
+		newType, err := parser.ExtractTypeFromAlterType(stmt)
+		if err != nil {
+			return nil
+		}
+		return &AlterColumnTypeValidator{TableName: tableName, ColumnName: columnName, NewType: newType}
+
+	case parser.ContainsSQL(stmt, "ENABLE ROW LEVEL SECURITY"), parser.ContainsSQL(stmt, "DISABLE ROW LEVEL SECURITY"):
+		tableName, err := parser.ExtractTableNameFromAlter(stmt)
+		if err != nil {
+			return nil
+		}
+		return &AlterRLSValidator{
+			TableName: tableName,
+			Enable:    parser.ContainsSQL(stmt, "ENABLE ROW LEVEL SECURITY"),
+		}
+
+	case parser.ContainsSQL(stmt, "REPLICA IDENTITY"):
+		tableName, identity, err := parser.ExtractTableAndReplicaIdentity(stmt)
+		if err != nil {
+			return nil
+		}
+		return &SetReplicaIdentityValidator{TableName: tableName, ReplicaIdentity: &identity}
+
+	case parser.ContainsSQL(stmt, "SET TABLESPACE"):
+		kind, name, tablespace, err := parser.ExtractObjectAndTablespaceFromSetTablespace(stmt)
+		if err != nil {
+			return nil
+		}
+		tsValidator := &SetTablespaceValidator{TableName: name, Tablespace: &tablespace}
+		if kind == "INDEX" {
+			tsValidator.IndexName = name
+			if step.Operation != nil {
+				tsValidator.TableName = step.Operation.Table
+			}
+		}
+		return tsValidator
+	}
+	return nil
+}
+
+// dropTableValidator validates dropping the table name, using the table's
+// definition from the diff when there is one
+func dropTableValidator(name string, cascade bool, diff *schema.SchemaDiff) OperationValidator {
+	table := database.Table{Name: name}
+	if diff != nil {
+		for _, removed := range diff.RemovedTables {
+			if removed.QualifiedName() == name {
+				table = removed
+				break
+			}
+		}
+	}
+	return &DropTableValidator{Table: table, Cascade: cascade}
+}
+
+// dropColumnValidator validates dropping a column, using its definition from
+// the diff when there is one
+func dropColumnValidator(tableName, columnName string, diff *schema.SchemaDiff) OperationValidator {
+	column := database.Column{Name: columnName}
+	if tableDiff := findTableDiff(diff, tableName); tableDiff != nil {
+		for _, removed := range tableDiff.RemovedColumns {
+			if removed.Name == columnName {
+				column = removed
+				break
+			}
+		}
+	}
+	return &DropColumnValidator{TableName: tableName, Column: column}
+}
+
+// findTableDiff returns the diff for a modified table, or nil
+func findTableDiff(diff *schema.SchemaDiff, tableName string) *schema.TableDiff {
+	if diff == nil {
+		return nil
+	}
+	for i := range diff.ModifiedTables {
+		if diff.ModifiedTables[i].TableName == tableName {
+			return &diff.ModifiedTables[i]
+		}
+	}
+	return nil
+}
+
+// DestructiveStep is a plan step the safety classifier marks as dangerous or data-losing
+type DestructiveStep struct {
+	Index  int // Zero-based position in the plan
+	Step   planner.PlanStep
+	Result *ValidationResult
+}
+
+// FindDestructiveSteps returns the plan steps that are dangerous or cause data loss.
+// The diff is optional and only used to give validators full definitions.
+func FindDestructiveSteps(plan *planner.Plan, diff *schema.SchemaDiff) []DestructiveStep {
+	var destructive []DestructiveStep
+	for i, step := range plan.Steps {
+		result := AnalyzePlanStep(step, diff)
+		if result == nil || result.Safety == nil {
+			continue
+		}
+		if result.Safety.Level == SafetyLevelDangerous || result.Safety.DataLoss {
+			destructive = append(destructive, DestructiveStep{Index: i, Step: step, Result: result})
+		}
+	}
+	return destructive
+}
@@ -0,0 +1,235 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/planner"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestAnalyzePlanStep_UsesDiff(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "users",
+				AddedColumns: []database.Column{
+					{Name: "age", Type: "integer", Nullable: false},
+				},
+			},
+		},
+	}
+	step := planner.PlanStep{
+		Description: "Add column age to table users",
+		SQL:         []string{"ALTER TABLE users ADD COLUMN age integer NOT NULL"},
+	}
+
+	result := AnalyzePlanStep(step, diff)
+	if result == nil {
+		t.Fatal("expected validation result for ADD COLUMN with diff")
+	}
+	if result.Valid {
+		t.Error("expected NOT NULL column without default to be invalid")
+	}
+
+	if AnalyzePlanStep(step, nil) != nil {
+		t.Error("expected no result for ADD COLUMN without diff")
+	}
+}
+
+func TestAnalyzePlanStep_DeferredNotNull(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:    "users",
+		AddedColumns: []database.Column{{Name: "age", Type: "integer"}},
+	}}}
+	plan, err := planner.GeneratePlanWithOptions(diff, nil, postgres.NewDriver(),
+		planner.PlanOptions{Backfill: map[string]string{"users.age": "0"}})
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	result := AnalyzePlanStep(plan.Steps[0], diff)
+	if result == nil || !result.Valid {
+		t.Fatalf("expected the backfilled ADD COLUMN step to be valid, got %+v", result)
+	}
+}
+
+func TestFindDestructiveSteps(t *testing.T) {
+	plan := &planner.Plan{
+		Steps: []planner.PlanStep{
+			{Description: "Create table posts", SQL: []string{"CREATE TABLE posts (id integer)"}},
+			{Description: "Drop column legacy from table users", SQL: []string{"ALTER TABLE users DROP COLUMN legacy"}},
+			{Description: "Enable row level security on table users", SQL: []string{"ALTER TABLE users ENABLE ROW LEVEL SECURITY"}},
+			{Description: "Drop table old_logs", SQL: []string{"DROP TABLE old_logs"}},
+		},
+	}
+
+	destructive := FindDestructiveSteps(plan, nil)
+	if len(destructive) != 2 {
+		t.Fatalf("Expected 2 destructive steps, got %d", len(destructive))
+	}
+	if destructive[0].Index != 1 || destructive[1].Index != 3 {
+		t.Errorf("Expected steps 1 and 3 to be destructive, got %d and %d", destructive[0].Index, destructive[1].Index)
+	}
+	if destructive[1].Result.Safety.Level != SafetyLevelDangerous {
+		t.Errorf("Expected DROP TABLE to be dangerous, got %s", destructive[1].Result.Safety.Level)
+	}
+}
+
+func TestFindDestructiveSteps_SoftDrop(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:      "users",
+		RemovedColumns: []database.Column{{Name: "email", Type: "text", Nullable: true}},
+	}}}
+	plan, err := planner.GeneratePlanWithOptions(diff, nil, postgres.NewDriver(), planner.PlanOptions{SoftDropColumns: true})
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	result := AnalyzePlanStep(plan.Steps[0], diff)
+	if result == nil || result.Safety.Level != SafetyLevelSafe || !result.Reversible {
+		t.Fatalf("Expected the soft drop to be safe and reversible, got %+v", result)
+	}
+	if destructive := FindDestructiveSteps(plan, diff); len(destructive) != 0 {
+		t.Errorf("Expected no destructive steps, got %+v", destructive)
+	}
+
+	// Dropping the tombstone later loses its data
+	cleanup := &planner.Plan{Steps: []planner.PlanStep{
+		{Description: "Drop column", SQL: []string{"ALTER TABLE users DROP COLUMN deleted_email_20260314"}},
+	}}
+	destructive := FindDestructiveSteps(cleanup, nil)
+	if len(destructive) != 1 || destructive[0].Result.Safety.Level != SafetyLevelLossy {
+		t.Errorf("Expected the tombstone drop to be lossy, got %+v", destructive)
+	}
+}
+
+func TestFindDestructiveSteps_QuotedNames(t *testing.T) {
+	plan := &planner.Plan{
+		Steps: []planner.PlanStep{
+			{Description: "Drop table Users", SQL: []string{`DROP TABLE "Users"`}},
+			{Description: "Drop column order from table t", SQL: []string{`ALTER TABLE t DROP COLUMN "order"`}},
+			{Description: "Drop table audit.Log", SQL: []string{`DROP TABLE audit."Log" CASCADE`}},
+		},
+	}
+	destructive := FindDestructiveSteps(plan, nil)
+	if len(destructive) != 3 {
+		t.Fatalf("Expected every quoted drop to be destructive, got %d", len(destructive))
+	}
+	if level := destructive[0].Result.Safety.Level; level != SafetyLevelDangerous {
+		t.Errorf("Expected DROP TABLE to be dangerous, got %s", level)
+	}
+
+	// The planner's own steps are classified from their operation, using the
+	// diff's definitions under the unquoted names
+	diff := &schema.SchemaDiff{
+		RemovedTables: []database.Table{{Name: "Users", Columns: []database.Column{{Name: "id", Type: "integer"}}}},
+		ModifiedTables: []schema.TableDiff{{
+			TableName:      "t",
+			RemovedColumns: []database.Column{{Name: "order", Type: "integer", Nullable: true}},
+		}},
+	}
+	generated, err := planner.GeneratePlan(diff, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+	destructive = FindDestructiveSteps(generated, diff)
+	if len(destructive) != 2 {
+		t.Fatalf("Expected both generated drops to be destructive, got %+v", generated.Steps)
+	}
+	for _, d := range destructive {
+		if d.Step.Operation == nil {
+			t.Errorf("Expected step %q to carry its operation", d.Step.Description)
+		}
+	}
+}
+
+func TestFindDestructiveSteps_TypeNarrowingWithoutDiff(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName: "users",
+		ModifiedColumns: []schema.ColumnDiff{{
+			ColumnName: "age",
+			Old:        database.Column{Name: "age", Type: "bigint"},
+			New:        database.Column{Name: "age", Type: "integer"},
+			Changes:    []string{"type"},
+		}},
+	}}}
+	plan, err := planner.GeneratePlanWithHash(diff, nil, postgres.NewDriver())
+	if err != nil {
+		t.Fatalf("Failed to generate plan: %v", err)
+	}
+
+	// A plan loaded with --plan-file has no diff; the step's operation
+	// records the type change
+	destructive := FindDestructiveSteps(plan, nil)
+	if len(destructive) != 1 {
+		t.Fatalf("Expected the narrowing to be destructive without a diff, got %+v", destructive)
+	}
+	if warnings := destructive[0].Result.Warnings; len(warnings) != 1 || warnings[0] != "Type conversion bigint → integer might lose data or fail" {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+
+	// A hand-written step can't show its conversion is safe
+	handWritten := &planner.Plan{Steps: []planner.PlanStep{{
+		Description: "Change type",
+		SQL:         []string{"ALTER TABLE users ALTER COLUMN age TYPE integer USING age::integer"},
+	}}}
+	destructive = FindDestructiveSteps(handWritten, nil)
+	if len(destructive) != 1 {
+		t.Fatalf("Expected a type change of unknown origin to be destructive, got %+v", destructive)
+	}
+	if warnings := destructive[0].Result.Warnings; len(warnings) != 1 || warnings[0] != "Type conversion the current type → integer might lose data or fail" {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+}
+
+func TestFindDestructiveSteps_EveryStatement(t *testing.T) {
+	plan := &planner.Plan{Steps: []planner.PlanStep{{
+		Description: "Archive users",
+		SQL:         []string{"CREATE TABLE users_archive AS SELECT * FROM users", "DROP TABLE users"},
+	}}}
+	destructive := FindDestructiveSteps(plan, nil)
+	if len(destructive) != 1 || !destructive[0].Result.Safety.DataLoss {
+		t.Errorf("Expected a later DROP TABLE in a step to be destructive, got %+v", destructive)
+	}
+}
+
+func TestFindDestructiveSteps_CaseInsensitive(t *testing.T) {
+	for _, stmt := range []string{
+		"drop table users",
+		"Drop Table users",
+		"alter table users drop column email",
+		"ALTER TABLE users Drop Column email",
+		"TRUNCATE users",
+		"truncate table users",
+	} {
+		plan := &planner.Plan{Steps: []planner.PlanStep{{Description: "Destructive", SQL: []string{stmt}}}}
+		destructive := FindDestructiveSteps(plan, nil)
+		if len(destructive) != 1 || !destructive[0].Result.Safety.DataLoss {
+			t.Errorf("Expected %q to be destructive, got %+v", stmt, destructive)
+		}
+	}
+}
+
+func TestFindDestructiveSteps_UnparsedFailsClosed(t *testing.T) {
+	for _, stmt := range []string{
+		"DROP TABLE",
+		"ALTER TABLE users DROP COLUMN",
+		"TRUNCATE",
+	} {
+		plan := &planner.Plan{Steps: []planner.PlanStep{{Description: "Unparsed", SQL: []string{stmt}}}}
+		destructive := FindDestructiveSteps(plan, nil)
+		if len(destructive) != 1 || destructive[0].Result.Safety.Level != SafetyLevelDangerous {
+			t.Errorf("Expected unparsed %q to be treated as dangerous, got %+v", stmt, destructive)
+		}
+	}
+
+	plan := &planner.Plan{Steps: []planner.PlanStep{{
+		Description: "Add column truncated_at",
+		SQL:         []string{"ALTER TABLE users ADD COLUMN truncated_at timestamp"},
+	}}}
+	if destructive := FindDestructiveSteps(plan, nil); len(destructive) != 0 {
+		t.Errorf("Expected adding truncated_at not to be destructive, got %+v", destructive)
+	}
+}
@@ -6,9 +6,16 @@ package validation
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/lockplane/lockplane/database"
+	"github.com/lockplane/lockplane/database/postgres"
+	"github.com/lockplane/lockplane/internal/locks"
+	"github.com/lockplane/lockplane/internal/planner"
 	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/lockplane/lockplane/internal/style"
 )
 
 // SafetyLevel represents how safe a migration operation is
@@ -40,21 +47,22 @@ func (s SafetyLevel) String() string {
 	}
 }
 
-// Icon returns the emoji icon for the safety level
+// Icon returns the emoji icon for the safety level, or a plain symbol when
+// emoji are disabled
 func (s SafetyLevel) Icon() string {
 	switch s {
 	case SafetyLevelSafe:
-		return "✅"
+		return style.Icon("✅", "✓")
 	case SafetyLevelReview:
-		return "⚠️"
+		return style.Icon("⚠️", "!")
 	case SafetyLevelLossy:
-		return "🔶"
+		return style.Icon("🔶", "~")
 	case SafetyLevelDangerous:
-		return "❌"
+		return style.Icon("❌", "✗")
 	case SafetyLevelMultiPhase:
-		return "🔄"
+		return style.Icon("🔄", "↻")
 	default:
-		return "❓"
+		return style.Icon("❓", "?")
 	}
 }
 
@@ -68,6 +76,14 @@ type SafetyClassification struct {
 	LockContention      bool        // Will this hold heavyweight locks?
 	RollbackDescription string      // What happens on rollback?
 	SaferAlternatives   []string    // Suggested safer approaches
+	AffectedObjects     []string    // Dependent objects changed or dropped along with this operation
+	AffectedRows        int64       // Estimated rows affected (0 = unknown)
+	// LockLevel is the PostgreSQL lock the operation takes, e.g. "ACCESS
+	// EXCLUSIVE"; empty when unknown or on SQLite
+	LockLevel string `json:"lock_level,omitempty"`
+	// Rewrite reports whether the operation rewrites the whole table (or
+	// index) while holding LockLevel
+	Rewrite bool `json:"rewrite,omitempty"`
 }
 
 // ValidationResult contains the outcome of validating a migration operation
@@ -78,6 +94,11 @@ type ValidationResult struct {
 	Warnings   []string              // Non-blocking concerns
 	Reasons    []string              // Why this validation passed/failed
 	Safety     *SafetyClassification `json:"safety,omitempty"` // Safety analysis
+	// Code identifies results that need their own diagnostic code, such as
+	// CodeImmutableTable; empty for the codes derived from Safety
+	Code string `json:"code,omitempty"`
+	// Source is where the changed object is defined, when it was loaded from SQL files
+	Source *database.SourceLocation `json:"-"`
 }
 
 // OperationValidator validates whether a diff operation is safe and reversible
@@ -89,6 +110,11 @@ type OperationValidator interface {
 type AddColumnValidator struct {
 	TableName string
 	Column    database.Column
+	// DeferNotNull reports that the plan adds a NOT NULL column as nullable
+	// and makes it NOT NULL in a separate step (PostgreSQL)
+	DeferNotNull bool
+	// Backfill is the SQL expression that fills the column in existing rows, if any
+	Backfill string
 }
 
 func (v *AddColumnValidator) Validate() ValidationResult {
@@ -100,8 +126,10 @@ func (v *AddColumnValidator) Validate() ValidationResult {
 		Reasons:    []string{},
 	}
 
+	hasDefault := v.Column.Default != nil && *v.Column.Default != ""
+
 	// Check if column is safe to add and classify safety
-	if !v.Column.Nullable && (v.Column.Default == nil || *v.Column.Default == "") {
+	if !v.Column.Nullable && !hasDefault && (v.Backfill == "" || !v.DeferNotNull) {
 		// NOT NULL without DEFAULT - dangerous
 		result.Valid = false
 		result.Errors = append(result.Errors,
@@ -121,13 +149,36 @@ func (v *AddColumnValidator) Validate() ValidationResult {
 			SaferAlternatives: []string{
 				"Add column as nullable first",
 				"Add column with DEFAULT value",
+				fmt.Sprintf("Backfill existing rows with --backfill %s.%s=<expression> (PostgreSQL)", v.TableName, v.Column.Name),
 				"Use multi-phase: add nullable, backfill, make NOT NULL",
 			},
 		}
+	} else if !v.Column.Nullable && !hasDefault {
+		// NOT NULL with a backfill - safe once every row has a value
+		result.Reasons = append(result.Reasons,
+			fmt.Sprintf("Column '%s' is added as nullable, backfilled with %s, then made NOT NULL in a separate step",
+				v.Column.Name, v.Backfill))
+
+		result.Safety = &SafetyClassification{
+			Level:               SafetyLevelReview,
+			BreakingChange:      false,
+			DataLoss:            false,
+			RollbackDataLoss:    true,
+			RequiresMultiPhase:  false,
+			LockContention:      true, // The backfill rewrites every row and SET NOT NULL scans the table
+			RollbackDescription: "Rollback will drop column. Data written to this column will be lost.",
+			SaferAlternatives: []string{
+				"Add column with a DEFAULT value to avoid rewriting existing rows",
+			},
+		}
 	} else if v.Column.Nullable {
 		// Nullable column - safe
 		result.Reasons = append(result.Reasons,
 			fmt.Sprintf("Column '%s' is nullable - safe to add", v.Column.Name))
+		if v.Backfill != "" {
+			result.Reasons = append(result.Reasons,
+				fmt.Sprintf("Existing rows are backfilled with %s", v.Backfill))
+		}
 
 		result.Safety = &SafetyClassification{
 			Level:               SafetyLevelSafe,
@@ -139,10 +190,18 @@ func (v *AddColumnValidator) Validate() ValidationResult {
 			RollbackDescription: "Rollback will drop column. Data written to this column will be lost.",
 			SaferAlternatives:   []string{},
 		}
-	} else if v.Column.Default != nil && *v.Column.Default != "" {
+	} else {
 		// NOT NULL with DEFAULT - safe
 		result.Reasons = append(result.Reasons,
 			fmt.Sprintf("Column '%s' has DEFAULT value - safe to add", v.Column.Name))
+		if v.DeferNotNull {
+			result.Reasons = append(result.Reasons,
+				fmt.Sprintf("Column '%s' is added as nullable with its DEFAULT, then made NOT NULL in a separate step", v.Column.Name))
+		}
+		if v.Backfill != "" {
+			result.Reasons = append(result.Reasons,
+				fmt.Sprintf("Existing rows are backfilled with %s instead of the DEFAULT", v.Backfill))
+		}
 
 		result.Safety = &SafetyClassification{
 			Level:               SafetyLevelSafe,
@@ -180,13 +239,7 @@ func (v *AddForeignKeyValidator) Validate() ValidationResult {
 	}
 
 	// Check if referenced table exists
-	var refTable *database.Table
-	for i := range v.TargetSchema.Tables {
-		if v.TargetSchema.Tables[i].Name == v.ForeignKey.ReferencedTable {
-			refTable = &v.TargetSchema.Tables[i]
-			break
-		}
-	}
+	refTable := database.FindTable(v.TargetSchema, v.ForeignKey.ReferencedTable)
 
 	if refTable == nil {
 		result.Valid = false
@@ -233,11 +286,20 @@ func (v *AddForeignKeyValidator) Validate() ValidationResult {
 
 // ValidateAddedColumns validates columns being added to a table
 func ValidateAddedColumns(tableName string, columns []database.Column) []ValidationResult {
+	return validateAddedColumns(tableName, columns, false, nil)
+}
+
+// validateAddedColumns validates columns added to an existing table. When
+// deferNotNull is set, the plan adds NOT NULL columns (other than primary
+// keys) as nullable and makes them NOT NULL in a separate step.
+func validateAddedColumns(tableName string, columns []database.Column, deferNotNull bool, backfill map[string]string) []ValidationResult {
 	var results []ValidationResult
 	for _, col := range columns {
 		validator := &AddColumnValidator{
-			TableName: tableName,
-			Column:    col,
+			TableName:    tableName,
+			Column:       col,
+			DeferNotNull: deferNotNull && !col.Nullable && !col.IsPrimaryKey,
+			Backfill:     backfill[planner.BackfillKey(tableName, col.Name)],
 		}
 		results = append(results, validator.Validate())
 	}
@@ -266,31 +328,111 @@ func ValidateSchemaDiff(diff *schema.SchemaDiff) []ValidationResult {
 
 // ValidateSchemaDiffWithSchema validates an entire schema diff with access to target schema
 func ValidateSchemaDiffWithSchema(diff *schema.SchemaDiff, targetSchema *database.Schema) []ValidationResult {
+	return ValidateSchemaDiffWithSchemas(diff, nil, targetSchema, false)
+}
+
+// ValidateSchemaDiffWithSchemas validates an entire schema diff with access to both schemas.
+// The source schema is used to find objects that depend on dropped tables, and cascade
+// reports whether removed tables will be dropped with CASCADE.
+func ValidateSchemaDiffWithSchemas(diff *schema.SchemaDiff, sourceSchema, targetSchema *database.Schema, cascade bool) []ValidationResult {
+	return ValidateSchemaDiffWithOptions(diff, sourceSchema, targetSchema, ValidationOptions{Cascade: cascade})
+}
+
+// ValidationOptions describes how the plan for a diff is generated, for
+// checks that depend on it
+type ValidationOptions struct {
+	// Cascade reports whether removed tables will be dropped with CASCADE
+	Cascade bool
+	// Backfill is the plan's planner.PlanOptions.Backfill
+	Backfill map[string]string
+	// SoftDropColumns reports whether removed columns are renamed to
+	// tombstones instead of dropped (planner.PlanOptions.SoftDropColumns)
+	SoftDropColumns bool
+	// ImmutableTables maps tables plans may create but never alter or drop
+	// to where each was declared immutable
+	ImmutableTables map[string]string
+	// AllowImmutableChange is the reason given for changing immutable tables
+	// anyway; the changes are then warnings instead of errors
+	AllowImmutableChange string
+	// ServerVersion is the server_version_num of the PostgreSQL server the
+	// plan targets, used when the source schema wasn't introspected from it
+	// (e.g. the configured target_postgres_version; 0 = unknown)
+	ServerVersion int
+}
+
+// targetServerVersion returns the server_version_num the plan runs on: the
+// introspected server's when known, the configured one otherwise
+func targetServerVersion(sourceSchema *database.Schema, opts ValidationOptions) int {
+	if sourceSchema != nil && sourceSchema.ServerVersion != 0 {
+		return sourceSchema.ServerVersion
+	}
+	return opts.ServerVersion
+}
+
+// ValidateSchemaDiffWithOptions validates an entire schema diff like
+// ValidateSchemaDiffWithSchemas, for a plan generated with opts
+func ValidateSchemaDiffWithOptions(diff *schema.SchemaDiff, sourceSchema, targetSchema *database.Schema, opts ValidationOptions) []ValidationResult {
+	cascade := opts.Cascade
 	var results []ValidationResult
 
+	// SQLite can't alter nullability, so it adds NOT NULL columns inline.
+	// Nor does it have table locks, so only PostgreSQL results get a lock level.
+	postgresTarget := planDialect(sourceSchema, targetSchema) != database.DialectSQLite
+	deferNotNull := postgresTarget
+	serverVersion := targetServerVersion(sourceSchema, opts)
+	lock := func(result ValidationResult, mode locks.LockMode, rewrite bool) ValidationResult {
+		if !postgresTarget {
+			return result
+		}
+		return withLock(result, mode, rewrite)
+	}
+
 	// Validate removed tables (dangerous)
 	for _, table := range diff.RemovedTables {
+		var dependents []string
+		for _, dep := range schema.FindTableDependents(sourceSchema, table.Name) {
+			dependents = append(dependents, dep.String())
+		}
 		validator := &DropTableValidator{
-			Table: table,
+			Table:      table,
+			Dependents: dependents,
+			Cascade:    cascade,
 			// TODO: Get row count from shadow DB analysis
 		}
-		results = append(results, validator.Validate())
+		results = append(results, lock(locate(validator.Validate(), table.Source), locks.LockAccessExclusive, false))
 	}
 
 	// Validate modified tables
 	for _, tableDiff := range diff.ModifiedTables {
 		// Validate added columns
-		addedResults := ValidateAddedColumns(tableDiff.TableName, tableDiff.AddedColumns)
+		addedResults := validateAddedColumns(tableDiff.TableName, tableDiff.AddedColumns, deferNotNull, opts.Backfill)
+		for i := range addedResults {
+			addedResults[i].Source = tableDiff.AddedColumns[i].Source
+			mode, rewrite := addColumnLock(tableDiff.AddedColumns[i], serverVersion)
+			addedResults[i] = lock(addedResults[i], mode, rewrite)
+		}
 		results = append(results, addedResults...)
 
+		// Table-level changes point at the table's definition
+		tableSource := findTableSource(targetSchema, tableDiff.TableName)
+
 		// Validate removed columns (dangerous)
 		for _, col := range tableDiff.RemovedColumns {
+			if opts.SoftDropColumns {
+				validator := &SoftDropColumnValidator{
+					TableName: tableDiff.TableName,
+					Column:    col,
+					Tombstone: schema.TombstoneColumnName(col.Name, time.Now()),
+				}
+				results = append(results, lock(locate(validator.Validate(), col.Source), locks.LockAccessExclusive, false))
+				continue
+			}
 			validator := &DropColumnValidator{
 				TableName: tableDiff.TableName,
 				Column:    col,
 				// TODO: Get row count and column size from shadow DB analysis
 			}
-			results = append(results, validator.Validate())
+			results = append(results, lock(locate(validator.Validate(), col.Source), locks.LockAccessExclusive, false))
 		}
 
 		// Validate modified columns (type changes)
@@ -311,7 +453,8 @@ func ValidateSchemaDiffWithSchema(diff *schema.SchemaDiff, targetSchema *databas
 					OldType:    colDiff.Old.Type,
 					NewType:    colDiff.New.Type,
 				}
-				results = append(results, validator.Validate())
+				rewrite := alterTypeRewrites(colDiff.Old.Type, colDiff.New.Type)
+				results = append(results, lock(locate(validator.Validate(), colDiff.New.Source), locks.LockAccessExclusive, rewrite))
 			}
 
 			// TODO: Validate other column changes (nullable → NOT NULL, etc.)
@@ -323,21 +466,62 @@ func ValidateSchemaDiffWithSchema(diff *schema.SchemaDiff, targetSchema *databas
 				TableName: tableDiff.TableName,
 				Enable:    tableDiff.RLSEnabled,
 			}
-			results = append(results, validator.Validate())
+			results = append(results, lock(locate(validator.Validate(), tableSource), locks.LockAccessExclusive, false))
+		}
+
+		// Validate tablespace moves (rewrite the object under lock)
+		if tableDiff.TablespaceChanged {
+			validator := &SetTablespaceValidator{
+				TableName:         tableDiff.TableName,
+				Tablespace:        tableDiff.Tablespace,
+				DefaultTablespace: diff.DefaultTablespace,
+			}
+			results = append(results, lock(locate(validator.Validate(), tableSource), locks.LockAccessExclusive, true))
+		}
+		if tableDiff.ReplicaIdentityChanged {
+			validator := &SetReplicaIdentityValidator{
+				TableName:       tableDiff.TableName,
+				ReplicaIdentity: tableDiff.ReplicaIdentity,
+			}
+			results = append(results, lock(locate(validator.Validate(), tableSource), locks.LockAccessExclusive, false))
+		}
+		for _, idx := range tableDiff.MovedIndexes {
+			validator := &SetTablespaceValidator{
+				TableName:         tableDiff.TableName,
+				IndexName:         idx.Name,
+				Tablespace:        idx.Tablespace,
+				DefaultTablespace: diff.DefaultTablespace,
+			}
+			results = append(results, lock(locate(validator.Validate(), idx.Source), locks.LockAccessExclusive, true))
 		}
 
 		// Validate added foreign keys if we have the target schema
 		if targetSchema != nil {
 			fkResults := ValidateAddedForeignKeys(tableDiff.TableName, tableDiff.AddedForeignKeys, targetSchema)
+			for i := range fkResults {
+				fkResults[i].Source = foreignKeySource(tableDiff.AddedForeignKeys[i], tableSource)
+				fkResults[i] = lock(fkResults[i], addForeignKeyLock(serverVersion), false)
+			}
 			results = append(results, fkResults...)
 		}
 	}
 
+	results = append(results, validateSequences(diff)...)
+
+	results = append(results, validateImmutableTables(diff, sourceSchema, opts)...)
+
+	// Validate options the target server may not support
+	results = append(results, ValidateServerSupport(diff, sourceSchema, opts)...)
+
 	// Validate foreign keys in added tables
 	if targetSchema != nil {
 		for _, table := range diff.AddedTables {
 			if len(table.ForeignKeys) > 0 {
 				fkResults := ValidateAddedForeignKeys(table.Name, table.ForeignKeys, targetSchema)
+				for i := range fkResults {
+					fkResults[i].Source = foreignKeySource(table.ForeignKeys[i], table.Source)
+					fkResults[i] = lock(fkResults[i], addForeignKeyLock(serverVersion), false)
+				}
 				results = append(results, fkResults...)
 			}
 		}
@@ -346,6 +530,28 @@ func ValidateSchemaDiffWithSchema(diff *schema.SchemaDiff, targetSchema *databas
 	return results
 }
 
+// locate records where the object a result is about is defined
+func locate(result ValidationResult, source *database.SourceLocation) ValidationResult {
+	result.Source = source
+	return result
+}
+
+// findTableSource returns where a table is defined in s, if known
+func findTableSource(s *database.Schema, name string) *database.SourceLocation {
+	if table := database.FindTable(s, name); table != nil {
+		return table.Source
+	}
+	return nil
+}
+
+// foreignKeySource returns where a foreign key is defined, falling back to its table
+func foreignKeySource(fk database.ForeignKey, tableSource *database.SourceLocation) *database.SourceLocation {
+	if fk.Source != nil {
+		return fk.Source
+	}
+	return tableSource
+}
+
 // DropColumnValidator validates dropping a column
 type DropColumnValidator struct {
 	TableName  string
@@ -381,9 +587,28 @@ func (v *DropColumnValidator) Validate() ValidationResult {
 		},
 	}
 
+	// A tombstone was soft-dropped earlier, so applications no longer use it;
+	// dropping it only loses the data kept for recovery
+	if original, date, ok := schema.ParseTombstoneColumnName(v.Column.Name); ok {
+		result.Warnings = []string{
+			fmt.Sprintf("Dropping tombstone column '%s.%s' permanently loses the data of '%s', soft-dropped on %s",
+				v.TableName, v.Column.Name, original, date.Format("2006-01-02")),
+		}
+		result.Reasons = []string{
+			"The column was soft-dropped (renamed) earlier, so dropping it doesn't break applications",
+		}
+		result.Safety.Level = SafetyLevelLossy
+		result.Safety.BreakingChange = false
+		result.Safety.RequiresMultiPhase = false
+		result.Safety.SaferAlternatives = []string{
+			"Use a later --older-than cutoff to keep tombstones around longer",
+		}
+	}
+
 	if v.RowCount > 0 {
 		result.Warnings = append(result.Warnings,
 			fmt.Sprintf("Estimated impact: %d rows", v.RowCount))
+		result.Safety.AffectedRows = v.RowCount
 	}
 
 	if v.ColumnSize > 0 {
@@ -395,10 +620,42 @@ func (v *DropColumnValidator) Validate() ValidationResult {
 	return result
 }
 
+// SoftDropColumnValidator validates soft-dropping a column, which renames it
+// to a tombstone instead of dropping it
+type SoftDropColumnValidator struct {
+	TableName string
+	Column    database.Column
+	Tombstone string // Name the column is renamed to
+}
+
+func (v *SoftDropColumnValidator) Validate() ValidationResult {
+	return ValidationResult{
+		Valid:      true,
+		Reversible: true,
+		Errors:     []string{},
+		Warnings:   []string{},
+		Reasons: []string{
+			fmt.Sprintf("Column '%s.%s' is renamed to '%s', keeping its data until lockplane cleanup-tombstones drops it",
+				v.TableName, v.Column.Name, v.Tombstone),
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelSafe,
+			BreakingChange:      false,
+			DataLoss:            false,
+			RollbackDataLoss:    false,
+			RequiresMultiPhase:  false,
+			LockContention:      false, // Renaming only changes the catalog
+			RollbackDescription: "Rename the column back; its data is unchanged",
+		},
+	}
+}
+
 // DropTableValidator validates dropping a table
 type DropTableValidator struct {
-	Table    database.Table
-	RowCount int64 // Optional: from shadow DB analysis
+	Table      database.Table
+	RowCount   int64    // Optional: from shadow DB analysis
+	Dependents []string // Objects in other tables that reference this table
+	Cascade    bool     // Whether the table is dropped with CASCADE
 }
 
 func (v *DropTableValidator) Validate() ValidationResult {
@@ -431,23 +688,106 @@ func (v *DropTableValidator) Validate() ValidationResult {
 	if v.RowCount > 0 {
 		result.Warnings = append(result.Warnings,
 			fmt.Sprintf("Estimated impact: %d rows will be lost", v.RowCount))
+		result.Safety.AffectedRows = v.RowCount
+	}
+
+	if len(v.Dependents) > 0 {
+		result.Safety.AffectedObjects = v.Dependents
+		if v.Cascade {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("DROP TABLE ... CASCADE will also drop: %s", strings.Join(v.Dependents, ", ")))
+		} else {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Dependent objects will be dropped before the table: %s", strings.Join(v.Dependents, ", ")))
+		}
+	}
+	if v.Cascade {
+		result.Reasons = append(result.Reasons,
+			"CASCADE silently drops every object that depends on the table")
 	}
 
 	return result
 }
 
+// TruncateTableValidator validates emptying a table with TRUNCATE
+type TruncateTableValidator struct {
+	TableName string
+}
+
+func (v *TruncateTableValidator) Validate() ValidationResult {
+	return ValidationResult{
+		Valid:      true, // Valid but dangerous
+		Reversible: false,
+		Errors:     []string{},
+		Warnings: []string{
+			fmt.Sprintf("Truncating table '%s' will permanently delete all rows", v.TableName),
+		},
+		Reasons: []string{
+			"TRUNCATE is irreversible - deleted rows cannot be recovered",
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelDangerous,
+			BreakingChange:      false,
+			DataLoss:            true,
+			RollbackDataLoss:    false, // Can't rollback
+			LockContention:      true,  // Holds AccessExclusive lock
+			RollbackDescription: "Cannot rollback - all rows are permanently deleted",
+			SaferAlternatives: []string{
+				"Export table data to backup before truncating",
+				"DELETE in batches so the operation can be stopped part way",
+			},
+		},
+	}
+}
+
+// UnparsedStatementValidator classifies a statement that looks destructive
+// but whose target could not be extracted. It fails closed: the statement is
+// treated as dangerous rather than silently passing the safety gate.
+type UnparsedStatementValidator struct {
+	Statement string
+	Operation string // The destructive operation that matched, e.g. "DROP TABLE"
+}
+
+func (v *UnparsedStatementValidator) Validate() ValidationResult {
+	return ValidationResult{
+		Valid:      true, // Valid but dangerous
+		Reversible: false,
+		Errors:     []string{},
+		Warnings: []string{
+			fmt.Sprintf("Could not parse %s statement, treating it as destructive: %s", v.Operation, v.Statement),
+		},
+		Reasons: []string{
+			fmt.Sprintf("%s may permanently lose data and its target could not be determined", v.Operation),
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelDangerous,
+			BreakingChange:      true,
+			DataLoss:            true,
+			RollbackDataLoss:    false, // Can't rollback
+			RollbackDescription: "Cannot rollback - data removed by the statement may be lost",
+			SaferAlternatives: []string{
+				"Review the statement manually before applying it",
+			},
+		},
+	}
+}
+
 // AlterColumnTypeValidator validates changing a column's type
 type AlterColumnTypeValidator struct {
 	TableName  string
 	ColumnName string
-	OldType    string
+	OldType    string // Empty when unknown, which is never a safe conversion
 	NewType    string
 }
 
 func (v *AlterColumnTypeValidator) Validate() ValidationResult {
 	// Analyze type conversion safety
-	conversionSafe := isTypeConversionSafe(v.OldType, v.NewType)
-	rollbackSafe := isTypeConversionSafe(v.NewType, v.OldType)
+	conversionSafe := v.OldType != "" && isTypeConversionSafe(v.OldType, v.NewType)
+	rollbackSafe := v.OldType != "" && isTypeConversionSafe(v.NewType, v.OldType)
+	oldType := v.OldType
+	if oldType == "" {
+		oldType = "the current type"
+	}
 
 	var level SafetyLevel
 	var alternatives []string
@@ -458,7 +798,7 @@ func (v *AlterColumnTypeValidator) Validate() ValidationResult {
 		level = SafetyLevelDangerous
 		valid = false
 		warnings = []string{
-			fmt.Sprintf("Type conversion %s → %s might lose data or fail", v.OldType, v.NewType),
+			fmt.Sprintf("Type conversion %s → %s might lose data or fail", oldType, v.NewType),
 		}
 		alternatives = []string{
 			"Use multi-phase: add new column → backfill → dual-write → migrate reads → drop old",
@@ -469,7 +809,7 @@ func (v *AlterColumnTypeValidator) Validate() ValidationResult {
 		level = SafetyLevelLossy
 		valid = true
 		warnings = []string{
-			fmt.Sprintf("Rollback will convert %s → %s, data might not fit", v.NewType, v.OldType),
+			fmt.Sprintf("Rollback will convert %s → %s, data might not fit", v.NewType, oldType),
 		}
 		alternatives = []string{
 			"Test rollback on shadow DB to verify data fits old type",
@@ -486,7 +826,7 @@ func (v *AlterColumnTypeValidator) Validate() ValidationResult {
 		Errors:     []string{},
 		Warnings:   warnings,
 		Reasons: []string{
-			fmt.Sprintf("Changing column type: %s → %s", v.OldType, v.NewType),
+			fmt.Sprintf("Changing column type: %s → %s", oldType, v.NewType),
 		},
 		Safety: &SafetyClassification{
 			Level:              level,
@@ -497,7 +837,7 @@ func (v *AlterColumnTypeValidator) Validate() ValidationResult {
 			LockContention:     true,
 			RollbackDescription: fmt.Sprintf(
 				"Rollback will convert %s → %s. Data might not fit old type.",
-				v.NewType, v.OldType,
+				v.NewType, oldType,
 			),
 			SaferAlternatives: alternatives,
 		},
@@ -550,6 +890,338 @@ func (v *AlterRLSValidator) Validate() ValidationResult {
 	}
 }
 
+// SetTablespaceValidator validates moving a table or index to another tablespace
+type SetTablespaceValidator struct {
+	TableName  string
+	IndexName  string  // Empty when the table itself is moved
+	Tablespace *string // nil moves the object back to the default tablespace
+	// DefaultTablespace is the database's default tablespace (empty = pg_default)
+	DefaultTablespace string
+}
+
+func (v *SetTablespaceValidator) Validate() ValidationResult {
+	tablespace := database.TablespaceOrDefault(v.Tablespace, v.DefaultTablespace)
+
+	object := fmt.Sprintf("table %s", v.TableName)
+	if v.IndexName != "" {
+		object = fmt.Sprintf("index %s on table %s", v.IndexName, v.TableName)
+	}
+
+	return ValidationResult{
+		Valid:      true,
+		Reversible: true,
+		Errors:     []string{},
+		Warnings: []string{
+			fmt.Sprintf("Moving %s to tablespace '%s' rewrites it and holds an ACCESS EXCLUSIVE lock until the copy finishes", object, tablespace),
+		},
+		Reasons: []string{
+			fmt.Sprintf("SET TABLESPACE physically copies %s to the new tablespace", object),
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelReview,
+			BreakingChange:      false,
+			DataLoss:            false,
+			RollbackDataLoss:    false,
+			RequiresMultiPhase:  false,
+			LockContention:      true, // Blocks reads and writes during the copy
+			RollbackDescription: "Rollback moves the object back to its previous tablespace (another full copy)",
+			SaferAlternatives: []string{
+				"Schedule the move during a maintenance window sized to the object's on-disk size",
+				"Use pg_repack or a similar tool to move large tables online",
+			},
+		},
+	}
+}
+
+// SetReplicaIdentityValidator validates changing a table's REPLICA IDENTITY
+type SetReplicaIdentityValidator struct {
+	TableName       string
+	ReplicaIdentity *string // nil resets the table to DEFAULT
+}
+
+func (v *SetReplicaIdentityValidator) Validate() ValidationResult {
+	identity := schema.NormalizeReplicaIdentity(v.ReplicaIdentity)
+	if identity == "" {
+		identity = database.ReplicaIdentityDefault
+	}
+
+	warnings := []string{
+		fmt.Sprintf("Changing the replica identity of table %s changes which old-row values logical replication consumers receive for UPDATE and DELETE", v.TableName),
+	}
+	switch identity {
+	case database.ReplicaIdentityFull:
+		warnings = append(warnings, "REPLICA IDENTITY FULL writes the entire old row to WAL for every UPDATE and DELETE")
+	case database.ReplicaIdentityNothing:
+		warnings = append(warnings, "With REPLICA IDENTITY NOTHING, UPDATE and DELETE fail on tables published for logical replication")
+	}
+
+	return ValidationResult{
+		Valid:      true,
+		Reversible: true,
+		Errors:     []string{},
+		Warnings:   warnings,
+		Reasons: []string{
+			fmt.Sprintf("Set replica identity of table %s to %s", v.TableName, identity),
+		},
+		Safety: &SafetyClassification{
+			Level:               SafetyLevelReview,
+			BreakingChange:      false,
+			DataLoss:            false,
+			RollbackDataLoss:    false,
+			RequiresMultiPhase:  false,
+			LockContention:      false, // Catalog-only change; the ACCESS EXCLUSIVE lock is brief
+			RollbackDescription: "Rollback restores the previous replica identity",
+			SaferAlternatives: []string{
+				"Coordinate the change with owners of logical replication or CDC consumers of this table",
+			},
+		},
+	}
+}
+
+// planDialect returns the dialect a plan for the schemas is generated for:
+// the target schema's when it has one, like lockplane plan, otherwise the
+// source database's
+func planDialect(sourceSchema, targetSchema *database.Schema) database.Dialect {
+	if targetSchema != nil && targetSchema.Dialect != "" && targetSchema.Dialect != database.DialectUnknown {
+		return targetSchema.Dialect
+	}
+	if sourceSchema != nil {
+		return sourceSchema.Dialect
+	}
+	return database.DialectUnknown
+}
+
+// ValidateServerSupport checks the indexes, columns and sequences a diff
+// creates or changes against the capability matrix of the server the plan
+// runs on (see postgres.Feature), and returns a failed result for each one
+// the server can't create. The version comes from the introspected source
+// schema, or else opts.ServerVersion; without either, only SQLite
+// limitations are checked.
+func ValidateServerSupport(diff *schema.SchemaDiff, sourceSchema *database.Schema, opts ValidationOptions) []ValidationResult {
+	serverVersion := targetServerVersion(sourceSchema, opts)
+	var results []ValidationResult
+	results = append(results, validateIndexServerSupport(diff, sourceSchema, serverVersion)...)
+	results = append(results, validateColumnServerSupport(diff, sourceSchema, serverVersion)...)
+	results = append(results, validateSequenceServerSupport(diff, serverVersion)...)
+	return results
+}
+
+// validateIndexServerSupport checks new and recreated indexes against the
+// database they will be created on. The dialect is only known when the
+// source schema was loaded.
+func validateIndexServerSupport(diff *schema.SchemaDiff, sourceSchema *database.Schema, serverVersion int) []ValidationResult {
+	if sourceSchema == nil {
+		return nil
+	}
+
+	var results []ValidationResult
+	check := func(tableName string, idx database.Index) {
+		if len(idx.IncludeColumns) > 0 {
+			validator := &IncludeColumnsValidator{
+				TableName:     tableName,
+				IndexName:     idx.Name,
+				Dialect:       sourceSchema.Dialect,
+				ServerVersion: serverVersion,
+			}
+			if result := validator.Validate(); !result.Valid {
+				results = append(results, result)
+			}
+		}
+		if idx.NullsNotDistinct && idx.Unique {
+			validator := &NullsNotDistinctValidator{
+				TableName:     tableName,
+				IndexName:     idx.Name,
+				Dialect:       sourceSchema.Dialect,
+				ServerVersion: serverVersion,
+			}
+			if result := validator.Validate(); !result.Valid {
+				results = append(results, result)
+			}
+		}
+	}
+
+	for _, table := range diff.AddedTables {
+		for _, idx := range table.Indexes {
+			check(table.Name, idx)
+		}
+	}
+	for _, tableDiff := range diff.ModifiedTables {
+		for _, idx := range tableDiff.AddedIndexes {
+			check(tableDiff.TableName, idx)
+		}
+		for _, idxDiff := range tableDiff.RecreatedIndexes {
+			check(tableDiff.TableName, idxDiff.New)
+		}
+	}
+	return results
+}
+
+// IncludeColumnsValidator validates that the target database supports
+// covering indexes (INCLUDE columns)
+type IncludeColumnsValidator struct {
+	TableName     string
+	IndexName     string
+	Dialect       database.Dialect
+	ServerVersion int // server_version_num of a PostgreSQL target (0 = unknown)
+}
+
+func (v *IncludeColumnsValidator) Validate() ValidationResult {
+	var reason string
+	var alternatives []string
+	switch {
+	case v.Dialect == database.DialectSQLite:
+		reason = fmt.Sprintf("Index '%s' on table '%s' has INCLUDE columns, which SQLite does not support",
+			v.IndexName, v.TableName)
+		alternatives = []string{"Add the included columns to the index key, or drop the INCLUDE clause for SQLite targets"}
+	case !postgres.FeatureIncludeColumns.SupportedBy(v.ServerVersion):
+		reason = fmt.Sprintf("Index '%s' on table '%s' has INCLUDE columns, which require PostgreSQL %s or later (target server is %s)",
+			v.IndexName, v.TableName, postgres.FeatureIncludeColumns.MinVersionLabel(), postgres.FormatServerVersion(v.ServerVersion))
+		alternatives = []string{
+			"Upgrade the target server to PostgreSQL 11 or later",
+			"Add the included columns to the index key instead",
+		}
+	default:
+		return ValidationResult{
+			Valid:      true,
+			Reversible: true,
+			Errors:     []string{},
+			Warnings:   []string{},
+			Reasons:    []string{"Target database supports INCLUDE columns"},
+		}
+	}
+
+	return ValidationResult{
+		Valid:      false,
+		Reversible: true,
+		Errors:     []string{reason},
+		Warnings:   []string{},
+		Reasons: []string{
+			"Covering indexes store non-key columns so index-only scans can return them; the target cannot create them",
+		},
+		Safety: &SafetyClassification{
+			Level:             SafetyLevelDangerous,
+			SaferAlternatives: alternatives,
+		},
+	}
+}
+
+// NullsNotDistinctValidator validates that the target database supports
+// UNIQUE NULLS NOT DISTINCT
+type NullsNotDistinctValidator struct {
+	TableName     string
+	IndexName     string
+	Dialect       database.Dialect
+	ServerVersion int // server_version_num of a PostgreSQL target (0 = unknown)
+}
+
+func (v *NullsNotDistinctValidator) Validate() ValidationResult {
+	var reason, why string
+	var alternatives []string
+	switch {
+	case v.Dialect == database.DialectSQLite:
+		reason = fmt.Sprintf("Index '%s' on table '%s' uses NULLS NOT DISTINCT, which SQLite does not support",
+			v.IndexName, v.TableName)
+		why = "SQLite unique indexes always treat NULLs as distinct, so the index would allow duplicate NULLs and show up as changed on every plan"
+		alternatives = []string{
+			"Make the indexed columns NOT NULL, or drop NULLS NOT DISTINCT for SQLite targets",
+		}
+	case !postgres.FeatureNullsNotDistinct.SupportedBy(v.ServerVersion):
+		reason = fmt.Sprintf("Index '%s' on table '%s' uses NULLS NOT DISTINCT, which requires PostgreSQL %s or later (target server is %s)",
+			v.IndexName, v.TableName, postgres.FeatureNullsNotDistinct.MinVersionLabel(), postgres.FormatServerVersion(v.ServerVersion))
+		why = "PostgreSQL versions before 15 reject the NULLS NOT DISTINCT clause"
+		alternatives = []string{
+			"Upgrade the target server to PostgreSQL 15 or later",
+			"Use a partial unique index (WHERE column IS NULL) or a COALESCE expression index to treat NULLs as equal",
+		}
+	default:
+		return ValidationResult{
+			Valid:      true,
+			Reversible: true,
+			Errors:     []string{},
+			Warnings:   []string{},
+			Reasons:    []string{"Target server supports NULLS NOT DISTINCT"},
+		}
+	}
+
+	return ValidationResult{
+		Valid:      false,
+		Reversible: true,
+		Errors:     []string{reason},
+		Warnings:   []string{},
+		Reasons:    []string{why},
+		Safety: &SafetyClassification{
+			Level:             SafetyLevelDangerous,
+			SaferAlternatives: alternatives,
+		},
+	}
+}
+
+// validateColumnServerSupport checks column storage resets against the version
+// of the server they will run on
+func validateColumnServerSupport(diff *schema.SchemaDiff, sourceSchema *database.Schema, serverVersion int) []ValidationResult {
+	if sourceSchema == nil || serverVersion == 0 || sourceSchema.Dialect == database.DialectSQLite {
+		return nil
+	}
+
+	var results []ValidationResult
+	for _, tableDiff := range diff.ModifiedTables {
+		for _, colDiff := range tableDiff.ModifiedColumns {
+			if !slices.Contains(colDiff.Changes, "storage") || schema.ColumnStorage(colDiff.New) != "" {
+				continue
+			}
+			validator := &StorageDefaultValidator{
+				TableName:     tableDiff.TableName,
+				ColumnName:    colDiff.ColumnName,
+				ServerVersion: serverVersion,
+			}
+			if result := validator.Validate(); !result.Valid {
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+// StorageDefaultValidator validates that the target server can restore a
+// column's default storage with SET STORAGE DEFAULT
+type StorageDefaultValidator struct {
+	TableName     string
+	ColumnName    string
+	ServerVersion int // server_version_num of the target (0 = unknown)
+}
+
+func (v *StorageDefaultValidator) Validate() ValidationResult {
+	if postgres.FeatureStorageDefault.SupportedBy(v.ServerVersion) {
+		return ValidationResult{
+			Valid:      true,
+			Reversible: true,
+			Errors:     []string{},
+			Warnings:   []string{},
+			Reasons:    []string{"Target server supports SET STORAGE DEFAULT"},
+		}
+	}
+
+	return ValidationResult{
+		Valid:      false,
+		Reversible: true,
+		Errors: []string{
+			fmt.Sprintf("Restoring the default storage of column '%s.%s' requires PostgreSQL %s or later (target server is %s)",
+				v.TableName, v.ColumnName, postgres.FeatureStorageDefault.MinVersionLabel(), postgres.FormatServerVersion(v.ServerVersion)),
+		},
+		Warnings: []string{},
+		Reasons: []string{
+			"PostgreSQL versions before 16 reject SET STORAGE DEFAULT",
+		},
+		Safety: &SafetyClassification{
+			Level: SafetyLevelDangerous,
+			SaferAlternatives: []string{
+				"Declare the type's default storage mode explicitly (e.g. STORAGE EXTENDED for text)",
+				"Upgrade the target server to PostgreSQL 16 or later",
+			},
+		},
+	}
+}
+
 // isTypeConversionSafe checks if type conversion is safe (widening)
 func isTypeConversionSafe(from, to string) bool {
 	// Widening conversions (safe)
@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/lockplane/lockplane/database"
@@ -111,6 +113,76 @@ func TestAddColumnValidator_NotNullWithEmptyDefault(t *testing.T) {
 	}
 }
 
+func TestAddColumnValidator_NotNullWithBackfill(t *testing.T) {
+	validator := &AddColumnValidator{
+		TableName:    "users",
+		Column:       database.Column{Name: "email", Type: "text"},
+		DeferNotNull: true,
+		Backfill:     "lower(name)",
+	}
+
+	result := validator.Validate()
+	if !result.Valid {
+		t.Fatalf("Expected backfilled NOT NULL column to be valid, got errors: %v", result.Errors)
+	}
+	if result.Safety.Level != SafetyLevelReview || !result.Safety.LockContention {
+		t.Errorf("Expected the backfill to need review for lock contention, got %+v", result.Safety)
+	}
+	if !slices.Contains(result.Reasons, "Column 'email' is added as nullable, backfilled with lower(name), then made NOT NULL in a separate step") {
+		t.Errorf("Expected the reasons to explain the transformation, got %v", result.Reasons)
+	}
+
+	// SQLite adds NOT NULL columns inline, so a backfill can't help
+	validator.DeferNotNull = false
+	if result := validator.Validate(); result.Valid {
+		t.Error("Expected a NOT NULL column without DEFAULT to be invalid when NOT NULL can't be deferred")
+	}
+}
+
+func TestValidateSchemaDiffWithOptions_DeferredNotNull(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName: "users",
+		AddedColumns: []database.Column{
+			{Name: "status", Type: "text", Default: stringPtr("'active'")},
+			{Name: "email", Type: "text"},
+		},
+	}}}
+	opts := ValidationOptions{Backfill: map[string]string{"users.email": "''"}}
+
+	results := ValidateSchemaDiffWithOptions(diff, nil, &database.Schema{Dialect: database.DialectPostgres}, opts)
+	if !AllValid(results) {
+		t.Fatalf("Expected all columns to be valid, got %+v", results)
+	}
+	if !slices.Contains(results[0].Reasons, "Column 'status' is added as nullable with its DEFAULT, then made NOT NULL in a separate step") {
+		t.Errorf("Expected the reasons to explain the transformation, got %v", results[0].Reasons)
+	}
+
+	results = ValidateSchemaDiffWithOptions(diff, nil, &database.Schema{Dialect: database.DialectSQLite}, opts)
+	if AllValid(results) {
+		t.Error("Expected the backfilled column to be invalid for SQLite")
+	}
+	if len(results[0].Reasons) != 2 {
+		t.Errorf("Expected SQLite to keep NOT NULL inline, got reasons %v", results[0].Reasons)
+	}
+}
+
+func TestValidateSchemaDiffWithOptions_SoftDropColumns(t *testing.T) {
+	diff := &schema.SchemaDiff{ModifiedTables: []schema.TableDiff{{
+		TableName:      "users",
+		RemovedColumns: []database.Column{{Name: "email", Type: "text"}},
+	}}}
+
+	results := ValidateSchemaDiffWithOptions(diff, nil, nil, ValidationOptions{})
+	if results[0].Safety.Level != SafetyLevelDangerous {
+		t.Errorf("Expected dropping a column to be dangerous, got %s", results[0].Safety.Level)
+	}
+
+	results = ValidateSchemaDiffWithOptions(diff, nil, nil, ValidationOptions{SoftDropColumns: true})
+	if results[0].Safety.Level != SafetyLevelSafe || !results[0].Reversible {
+		t.Errorf("Expected soft-dropping a column to be safe and reversible, got %+v", results[0])
+	}
+}
+
 func TestValidateAddedColumns(t *testing.T) {
 	columns := []database.Column{
 		{
@@ -472,6 +544,25 @@ func TestAlterRLSValidator_Disable(t *testing.T) {
 	}
 }
 
+func TestSetReplicaIdentityValidator(t *testing.T) {
+	full := "full"
+	validator := &SetReplicaIdentityValidator{TableName: "events", ReplicaIdentity: &full}
+
+	result := validator.Validate()
+	if !result.Valid || !result.Reversible {
+		t.Fatalf("expected replica identity change to be valid and reversible: %#v", result)
+	}
+	if result.Safety == nil || result.Safety.Level != SafetyLevelReview {
+		t.Fatalf("expected replica identity change to need review: %#v", result.Safety)
+	}
+	if len(result.Warnings) != 2 || !strings.Contains(result.Warnings[1], "REPLICA IDENTITY FULL") {
+		t.Errorf("expected a WAL volume warning for FULL, got %v", result.Warnings)
+	}
+	if result.Reasons[0] != "Set replica identity of table events to FULL" {
+		t.Errorf("unexpected reason: %q", result.Reasons[0])
+	}
+}
+
 func TestValidateSchemaDiff_RLSChange(t *testing.T) {
 	diff := &schema.SchemaDiff{
 		ModifiedTables: []schema.TableDiff{
@@ -498,3 +589,284 @@ func TestValidateSchemaDiff_RLSChange(t *testing.T) {
 		t.Fatalf("unexpected reason: %#v", result.Reasons)
 	}
 }
+
+func TestValidateSchemaDiffWithSchemas_DropTableDependents(t *testing.T) {
+	users := database.Table{Name: "users"}
+	before := &database.Schema{
+		Tables: []database.Table{
+			users,
+			{
+				Name: "posts",
+				ForeignKeys: []database.ForeignKey{
+					{Name: "fk_posts_author", Columns: []string{"author_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				},
+			},
+		},
+	}
+	diff := &schema.SchemaDiff{RemovedTables: []database.Table{users}}
+
+	results := ValidateSchemaDiffWithSchemas(diff, before, nil, false)
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	result := results[0]
+	if result.Safety == nil || result.Safety.Level != SafetyLevelDangerous {
+		t.Fatalf("Expected dangerous safety level, got %+v", result.Safety)
+	}
+	if len(result.Safety.AffectedObjects) != 1 || result.Safety.AffectedObjects[0] != "foreign key fk_posts_author on posts" {
+		t.Errorf("Expected dependent foreign key in affected objects, got %v", result.Safety.AffectedObjects)
+	}
+
+	found := false
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning, "dropped before the table") && strings.Contains(warning, "fk_posts_author") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected warning listing dependents, got %v", result.Warnings)
+	}
+}
+
+func TestDropTableValidator_Cascade(t *testing.T) {
+	validator := &DropTableValidator{
+		Table:      database.Table{Name: "users"},
+		Dependents: []string{"foreign key fk_posts_author on posts"},
+		Cascade:    true,
+	}
+
+	result := validator.Validate()
+	if result.Safety.Level != SafetyLevelDangerous {
+		t.Errorf("Expected CASCADE drop to be dangerous, got %s", result.Safety.Level)
+	}
+
+	found := false
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning, "CASCADE will also drop") && strings.Contains(warning, "fk_posts_author on posts") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected CASCADE warning listing dependents, got %v", result.Warnings)
+	}
+}
+
+func TestValidateSchemaDiff_TablespaceMoveIsReview(t *testing.T) {
+	archive := "archive"
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{TableName: "events", TablespaceChanged: true, Tablespace: &archive},
+		},
+	}
+
+	results := ValidateSchemaDiff(diff)
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Safety == nil || results[0].Safety.Level != SafetyLevelReview {
+		t.Fatalf("Expected review safety level, got %+v", results[0].Safety)
+	}
+	if !results[0].Safety.LockContention {
+		t.Error("Expected tablespace move to report lock contention")
+	}
+	if !strings.Contains(results[0].Warnings[0], "tablespace 'archive'") {
+		t.Errorf("Expected warning to name the tablespace, got %v", results[0].Warnings)
+	}
+}
+
+func TestValidateSchemaDiff_NullsNotDistinctRequiresPostgres15(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "users",
+				AddedIndexes: []database.Index{
+					{Name: "users_email_key", Columns: []string{"email"}, Unique: true, NullsNotDistinct: true},
+				},
+			},
+		},
+	}
+
+	// PostgreSQL 14 target
+	results := ValidateSchemaDiffWithSchemas(diff, &database.Schema{ServerVersion: 140009}, nil, false)
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Valid {
+		t.Error("Expected NULLS NOT DISTINCT to be rejected on PostgreSQL 14")
+	}
+	if len(results[0].Errors) == 0 || !strings.Contains(results[0].Errors[0], "PostgreSQL 15") || !strings.Contains(results[0].Errors[0], "14.9") {
+		t.Errorf("Expected error naming the required and actual versions, got %v", results[0].Errors)
+	}
+
+	// PostgreSQL 15 target
+	if results := ValidateSchemaDiffWithSchemas(diff, &database.Schema{ServerVersion: 150004}, nil, false); len(results) != 0 {
+		t.Errorf("Expected no results on PostgreSQL 15, got %+v", results)
+	}
+
+	// Unknown server version (source schema loaded from a file)
+	if results := ValidateSchemaDiffWithSchemas(diff, &database.Schema{}, nil, false); len(results) != 0 {
+		t.Errorf("Expected no results when the server version is unknown, got %+v", results)
+	}
+
+	// SQLite target
+	results = ValidateSchemaDiffWithSchemas(diff, &database.Schema{Dialect: database.DialectSQLite}, nil, false)
+	if len(results) != 1 || results[0].Valid {
+		t.Fatalf("Expected NULLS NOT DISTINCT to be rejected on SQLite, got %+v", results)
+	}
+	if !strings.Contains(results[0].Errors[0], "SQLite does not support") {
+		t.Errorf("Expected error naming SQLite, got %v", results[0].Errors)
+	}
+}
+
+func TestValidateServerSupport_ConfiguredVersion(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "users",
+				AddedIndexes: []database.Index{
+					{Name: "users_email_key", Columns: []string{"email"}, Unique: true, NullsNotDistinct: true},
+				},
+			},
+		},
+	}
+	files := &database.Schema{Dialect: database.DialectPostgres}
+
+	// Schema files: the configured version is checked
+	results := ValidateServerSupport(diff, files, ValidationOptions{ServerVersion: 130000})
+	if len(results) != 1 || results[0].Valid {
+		t.Fatalf("Expected NULLS NOT DISTINCT to be rejected for a PostgreSQL 13 target, got %+v", results)
+	}
+	if !strings.Contains(results[0].Errors[0], "requires PostgreSQL 15 or later (target server is 13)") {
+		t.Errorf("Expected error naming the required and configured versions, got %v", results[0].Errors)
+	}
+
+	// The same check runs as part of the full validation
+	if results := ValidateSchemaDiffWithOptions(diff, files, nil, ValidationOptions{ServerVersion: 130000}); AllValid(results) {
+		t.Errorf("Expected full validation to fail for a PostgreSQL 13 target, got %+v", results)
+	}
+
+	// An introspected server takes precedence over the configured version
+	live := &database.Schema{Dialect: database.DialectPostgres, ServerVersion: 150004}
+	if results := ValidateServerSupport(diff, live, ValidationOptions{ServerVersion: 130000}); len(results) != 0 {
+		t.Errorf("Expected the PostgreSQL 15 server to support NULLS NOT DISTINCT, got %+v", results)
+	}
+
+	// Without a version nothing is rejected
+	if results := ValidateServerSupport(diff, files, ValidationOptions{}); len(results) != 0 {
+		t.Errorf("Expected no results without a server version, got %+v", results)
+	}
+}
+
+func TestValidateSchemaDiff_IncludeColumnsSupport(t *testing.T) {
+	diff := &schema.SchemaDiff{
+		AddedTables: []database.Table{
+			{
+				Name:    "orders",
+				Indexes: []database.Index{{Name: "orders_user_id_idx", Columns: []string{"user_id"}, IncludeColumns: []string{"status"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		source  *database.Schema
+		wantErr string
+	}{
+		{"sqlite target", &database.Schema{Dialect: database.DialectSQLite}, "SQLite does not support"},
+		{"postgres 10 target", &database.Schema{Dialect: database.DialectPostgres, ServerVersion: 100023}, "PostgreSQL 11 or later (target server is 10.23)"},
+		{"postgres 11 target", &database.Schema{Dialect: database.DialectPostgres, ServerVersion: 110000}, ""},
+		{"unknown postgres version", &database.Schema{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rejected []ValidationResult
+			for _, result := range ValidateSchemaDiffWithSchemas(diff, tt.source, nil, false) {
+				if !result.Valid {
+					rejected = append(rejected, result)
+				}
+			}
+			if tt.wantErr == "" {
+				if len(rejected) != 0 {
+					t.Errorf("Expected covering index to be accepted, got %+v", rejected)
+				}
+				return
+			}
+			if len(rejected) != 1 || !strings.Contains(rejected[0].Errors[0], tt.wantErr) {
+				t.Errorf("Expected one error containing %q, got %+v", tt.wantErr, rejected)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaDiff_StorageDefaultRequiresPostgres16(t *testing.T) {
+	external := "EXTERNAL"
+	diff := &schema.SchemaDiff{
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName: "documents",
+				ModifiedColumns: []schema.ColumnDiff{
+					{
+						ColumnName: "body",
+						Old:        database.Column{Name: "body", Type: "text", Storage: &external},
+						New:        database.Column{Name: "body", Type: "text"},
+						Changes:    []string{"storage"},
+					},
+				},
+			},
+		},
+	}
+
+	// PostgreSQL 15 target
+	results := ValidateSchemaDiffWithSchemas(diff, &database.Schema{ServerVersion: 150004}, nil, false)
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Valid {
+		t.Error("Expected SET STORAGE DEFAULT to be rejected on PostgreSQL 15")
+	}
+	if len(results[0].Errors) == 0 || !strings.Contains(results[0].Errors[0], "PostgreSQL 16") || !strings.Contains(results[0].Errors[0], "15.4") {
+		t.Errorf("Expected error naming the required and actual versions, got %v", results[0].Errors)
+	}
+
+	// PostgreSQL 16 target
+	if results := ValidateSchemaDiffWithSchemas(diff, &database.Schema{ServerVersion: 160002}, nil, false); len(results) != 0 {
+		t.Errorf("Expected no results on PostgreSQL 16, got %+v", results)
+	}
+
+	// An explicit storage mode works on any version
+	diff.ModifiedTables[0].ModifiedColumns[0].Old.Storage = nil
+	diff.ModifiedTables[0].ModifiedColumns[0].New.Storage = &external
+	if results := ValidateSchemaDiffWithSchemas(diff, &database.Schema{ServerVersion: 150004}, nil, false); len(results) != 0 {
+		t.Errorf("Expected no results for an explicit storage mode, got %+v", results)
+	}
+}
+
+func TestValidateSchemaDiffWithSchemas_Sources(t *testing.T) {
+	usersSource := &database.SourceLocation{File: "schema/users.lp.sql", Line: 1, Column: 1}
+	emailSource := &database.SourceLocation{File: "schema/users.lp.sql", Line: 3, Column: 3}
+	oldSource := &database.SourceLocation{File: "schema/old.lp.sql", Line: 1, Column: 1}
+	after := &database.Schema{
+		Tables: []database.Table{{Name: "users", Source: usersSource}},
+	}
+	diff := &schema.SchemaDiff{
+		RemovedTables: []database.Table{{Name: "old", Source: oldSource}},
+		ModifiedTables: []schema.TableDiff{
+			{
+				TableName:    "users",
+				AddedColumns: []database.Column{{Name: "email", Type: "text", Nullable: true, Source: emailSource}},
+				RLSChanged:   true,
+				RLSEnabled:   true,
+			},
+		},
+	}
+
+	results := ValidateSchemaDiffWithSchemas(diff, nil, after, false)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, want := range []*database.SourceLocation{oldSource, emailSource, usersSource} {
+		if results[i].Source != want {
+			t.Errorf("Result %d: expected source %v, got %v", i, want, results[i].Source)
+		}
+	}
+}
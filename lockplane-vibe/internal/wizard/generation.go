@@ -16,60 +16,87 @@ import (
 
 const defaultConfigSchemaPath = "schema"
 
-// GenerateFiles creates the lockplane.toml and .env files
+// defaultShadowSchema is the shadow schema used when reusing the primary
+// PostgreSQL database
+const defaultShadowSchema = "lockplane_shadow"
+
+// GenerateFiles creates the lockplane.toml and .env files in the current directory
 func GenerateFiles(environments []EnvironmentInput) (*InitResult, error) {
+	return GenerateFilesInDir(".", environments)
+}
+
+// GenerateFilesInDir creates or updates lockplane.toml, the schema directory,
+// .env files and .gitignore in dir, the directory holding the project's config
+func GenerateFilesInDir(dir string, environments []EnvironmentInput) (*InitResult, error) {
+	return GenerateFilesWithOptions(dir, environments, GenerateOptions{})
+}
+
+// GenerateOptions controls how GenerateFilesWithOptions writes files
+type GenerateOptions struct {
+	// DryRun computes the generated files without touching the filesystem.
+	// The would-be contents are returned in InitResult.Files.
+	DryRun bool
+}
+
+// GenerateFilesWithOptions is GenerateFilesInDir with options. Every file it
+// writes, or would write in a dry run, is listed in InitResult.Files along
+// with its previous content.
+func GenerateFilesWithOptions(dir string, environments []EnvironmentInput, opts GenerateOptions) (*InitResult, error) {
 	result := &InitResult{
 		EnvFiles: []string{},
 	}
 
-	// Generate or update lockplane.toml in current directory
-	configPath := "lockplane.toml"
-	fileExists := false
-	if _, err := os.Stat(configPath); err == nil {
-		fileExists = true
-	}
-
-	schemaDir, err := generateLockplaneTOML(configPath, environments)
+	// Generate or update lockplane.toml
+	configPath := filepath.Join(dir, "lockplane.toml")
+	configContent, schemaDir := lockplaneTOMLContent(configPath, environments)
+	configFile, err := result.writeFile(configPath, configContent, 0644, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate lockplane.toml: %w", err)
 	}
 	if schemaDir == "" {
 		schemaDir = defaultConfigSchemaPath
 	}
+	if !filepath.IsAbs(schemaDir) {
+		schemaDir = filepath.Join(dir, schemaDir)
+	}
 	existed := false
 	if info, err := os.Stat(schemaDir); err == nil && info.IsDir() {
 		existed = true
 	}
-	if err := os.MkdirAll(schemaDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create schema directory %s: %w", schemaDir, err)
+	if !opts.DryRun {
+		if err := os.MkdirAll(schemaDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create schema directory %s: %w", schemaDir, err)
+		}
 	}
 	result.SchemaDir = schemaDir
 	result.SchemaDirCreated = !existed
 
 	result.ConfigPath = configPath
-	if fileExists {
+	if configFile.Existed {
 		result.ConfigUpdated = true
 	} else {
 		result.ConfigCreated = true
 	}
 
-	// Generate .env files
+	// Generate .env files with restrictive permissions (owner read/write only)
 	for _, env := range environments {
-		envFilePath := fmt.Sprintf(".env.%s", env.Name)
-		if err := generateEnvFile(envFilePath, env); err != nil {
+		envFilePath := filepath.Join(dir, ".env."+env.Name)
+		if _, err := result.writeFile(envFilePath, envFileContent(env), 0600, opts); err != nil {
 			return nil, fmt.Errorf("failed to generate %s: %w", envFilePath, err)
 		}
 		result.EnvFiles = append(result.EnvFiles, envFilePath)
 	}
 
 	// Create or update .env.example
-	examplePath := ".env.example"
+	examplePath := filepath.Join(dir, ".env.example")
 	exampleExists := false
 	if _, err := os.Stat(examplePath); err == nil {
 		exampleExists = true
 	}
-	if err := createOrUpdateEnvExample(environments); err != nil {
-		return nil, fmt.Errorf("failed to create/update .env.example: %w", err)
+	if content, changed := envExampleContent(examplePath, environments); changed {
+		if _, err := result.writeFile(examplePath, content, 0644, opts); err != nil {
+			return nil, fmt.Errorf("failed to create/update .env.example: %w", err)
+		}
 	}
 	if exampleExists {
 		result.EnvExampleUpdated = true
@@ -78,14 +105,17 @@ func GenerateFiles(environments []EnvironmentInput) (*InitResult, error) {
 	}
 
 	// Update .gitignore
-	if err := updateGitignore(); err != nil {
-		return nil, fmt.Errorf("failed to update .gitignore: %w", err)
+	gitignorePath := filepath.Join(dir, ".gitignore")
+	if content, changed := gitignoreContent(gitignorePath); changed {
+		if _, err := result.writeFile(gitignorePath, content, 0644, opts); err != nil {
+			return nil, fmt.Errorf("failed to update .gitignore: %w", err)
+		}
 	}
 	result.GitignoreUpdated = true
 
 	// Create SQLite database files if needed
 	for _, env := range environments {
-		if env.DatabaseType == "sqlite" {
+		if env.DatabaseType == "sqlite" && !opts.DryRun {
 			dbPath := env.FilePath
 			shadowPath := ""
 
@@ -114,6 +144,33 @@ func GenerateFiles(environments []EnvironmentInput) (*InitResult, error) {
 	return result, nil
 }
 
+// writeFile records content as the new content of path in r.Files and, unless
+// this is a dry run, writes it
+func (r *InitResult) writeFile(path, content string, perm os.FileMode, opts GenerateOptions) (GeneratedFile, error) {
+	file := GeneratedFile{Path: path, Content: content}
+	if data, err := os.ReadFile(path); err == nil {
+		file.Previous = string(data)
+		file.Existed = true
+	}
+	if !opts.DryRun {
+		if err := os.WriteFile(path, []byte(content), perm); err != nil {
+			return file, err
+		}
+	}
+	r.Files = append(r.Files, file)
+	return file, nil
+}
+
+// File returns the generated file at path, or nil if none was generated there
+func (r *InitResult) File(path string) *GeneratedFile {
+	for i := range r.Files {
+		if r.Files[i].Path == path {
+			return &r.Files[i]
+		}
+	}
+	return nil
+}
+
 // createSQLiteDatabaseFile creates an empty SQLite database file
 func createSQLiteDatabaseFile(filePath string) error {
 	// Skip if file already exists
@@ -147,7 +204,9 @@ func createSQLiteDatabaseFile(filePath string) error {
 	return nil
 }
 
-func generateLockplaneTOML(path string, newEnvironments []EnvironmentInput) (string, error) {
+// lockplaneTOMLContent returns the content of the lockplane.toml at path
+// with newEnvironments merged in, and the schema path it configures
+func lockplaneTOMLContent(path string, newEnvironments []EnvironmentInput) (string, string) {
 	cfg := lockplaneConfig{
 		SchemaPath:   defaultConfigSchemaPath,
 		Environments: make(map[string]tomlEnvironment),
@@ -218,10 +277,7 @@ func generateLockplaneTOML(path string, newEnvironments []EnvironmentInput) (str
 		cfg.Schemas = []string{"public"}
 	}
 
-	if err := writeLockplaneConfig(path, cfg); err != nil {
-		return "", err
-	}
-	return cfg.SchemaPath, nil
+	return formatLockplaneConfig(cfg), cfg.SchemaPath
 }
 
 // tomlEnvironment represents an environment in the TOML file
@@ -238,7 +294,7 @@ type lockplaneConfig struct {
 	Environments       map[string]tomlEnvironment `toml:"environments"`
 }
 
-func writeLockplaneConfig(path string, cfg lockplaneConfig) error {
+func formatLockplaneConfig(cfg lockplaneConfig) string {
 	var b strings.Builder
 
 	b.WriteString("# Lockplane Configuration\n")
@@ -276,7 +332,7 @@ func writeLockplaneConfig(path string, cfg lockplaneConfig) error {
 		b.WriteString(fmt.Sprintf("# %s\n\n", env.Comment))
 	}
 
-	return os.WriteFile(path, []byte(b.String()), 0644)
+	return b.String()
 }
 
 func inferDialectFromEnvironments(envs []EnvironmentInput) string {
@@ -315,6 +371,11 @@ func formatStringSlice(values []string) string {
 }
 
 func generateEnvFile(path string, env EnvironmentInput) error {
+	// Write with restrictive permissions (owner read/write only)
+	return os.WriteFile(path, []byte(envFileContent(env)), 0600)
+}
+
+func envFileContent(env EnvironmentInput) string {
 	var b strings.Builder
 
 	b.WriteString(fmt.Sprintf("# Lockplane Environment: %s\n", env.Name))
@@ -332,6 +393,10 @@ func generateEnvFile(path string, env EnvironmentInput) error {
 		if strings.TrimSpace(env.ShadowSchema) != "" {
 			b.WriteString("# Shadow schema (reuses the primary database)\n")
 			b.WriteString(fmt.Sprintf("SHADOW_SCHEMA=%s\n", env.ShadowSchema))
+			if !env.SharedShadowSchema {
+				b.WriteString("# Validate in a new schema per run (<schema>_<user>_<pid>) so concurrent runs don't collide\n")
+				b.WriteString("SHADOW_SCHEMA_PER_RUN=true\n")
+			}
 		} else {
 			shadowConnStr := BuildPostgresShadowConnectionString(env)
 			b.WriteString("# Shadow database (always configured for PostgreSQL - safe migrations)\n")
@@ -364,13 +429,22 @@ func generateEnvFile(path string, env EnvironmentInput) error {
 		b.WriteString(fmt.Sprintf("LIBSQL_SHADOW_DB_PATH=%s\n", shadowConnStr))
 	}
 
-	// Write with restrictive permissions (owner read/write only)
-	return os.WriteFile(path, []byte(b.String()), 0600)
+	return b.String()
 }
 
-func createOrUpdateEnvExample(environments []EnvironmentInput) error {
-	examplePath := ".env.example"
+func createOrUpdateEnvExample(examplePath string, environments []EnvironmentInput) error {
+	content, changed := envExampleContent(examplePath, environments)
+	if !changed {
+		return nil
+	}
+	// Write with standard permissions (readable by all, writable by owner)
+	return os.WriteFile(examplePath, []byte(content), 0644)
+}
 
+// envExampleContent returns the content of the .env.example at examplePath
+// with any missing variables for environments appended, and whether
+// anything was added
+func envExampleContent(examplePath string, environments []EnvironmentInput) (string, bool) {
 	// Read existing .env.example if it exists
 	existingContent := ""
 	if data, err := os.ReadFile(examplePath); err == nil {
@@ -409,7 +483,7 @@ func createOrUpdateEnvExample(environments []EnvironmentInput) error {
 
 	// If nothing needs to be added, we're done
 	if !needsPostgres && !needsSQLite && !needsLibSQL {
-		return nil
+		return existingContent, false
 	}
 
 	// Build the content to append
@@ -437,7 +511,9 @@ func createOrUpdateEnvExample(environments []EnvironmentInput) error {
 		}
 		if !hasShadowSchema {
 			b.WriteString("# Optional: reuse the primary database and isolate via schema\n")
-			b.WriteString("SHADOW_SCHEMA=lockplane_shadow\n")
+			b.WriteString(fmt.Sprintf("SHADOW_SCHEMA=%s\n", defaultShadowSchema))
+			b.WriteString("# Optional: validate in a new schema per run so concurrent runs don't collide\n")
+			b.WriteString("SHADOW_SCHEMA_PER_RUN=true\n")
 		}
 	}
 
@@ -467,36 +543,51 @@ func createOrUpdateEnvExample(environments []EnvironmentInput) error {
 	}
 
 	// Append to existing content
-	newContent := existingContent + b.String()
-
-	// Write with standard permissions (readable by all, writable by owner)
-	return os.WriteFile(examplePath, []byte(newContent), 0644)
+	return existingContent + b.String(), true
 }
 
-func updateGitignore() error {
-	gitignorePath := ".gitignore"
+func updateGitignore(gitignorePath string) error {
+	content, changed := gitignoreContent(gitignorePath)
+	if !changed {
+		return nil
+	}
+	return os.WriteFile(gitignorePath, []byte(content), 0644)
+}
 
+// gitignoreContent returns the content of the .gitignore at gitignorePath
+// with lockplane's environment files ignored, and whether it changed
+func gitignoreContent(gitignorePath string) (string, bool) {
 	// Read existing .gitignore if it exists
 	content := ""
 	if data, err := os.ReadFile(gitignorePath); err == nil {
 		content = string(data)
 	}
 
-	// Check if .env.* pattern already exists
-	if strings.Contains(content, ".env.*") || strings.Contains(content, ".env.") {
-		// Already has the pattern, don't add again
-		return nil
+	patterns := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		patterns[strings.TrimSpace(line)] = true
 	}
 
-	// Append the lockplane section
-	lockplaneSection := `
+	switch {
+	case patterns[".env.*"] || patterns[".env.*.local"]:
+		// Already ignores the per-developer .env.<name>.local files
+		return content, false
+	case strings.Contains(content, ".env."):
+		// Environment files are already handled; only the local overrides are missing
+		content += `
+# Lockplane per-developer overrides (added by lockplane init)
+.env.*.local
+`
+	default:
+		// Append the lockplane section
+		content += `
 # Lockplane environment files (added by lockplane init)
 # DO NOT remove - contains database credentials
 .env.*
+.env.*.local
 !.env.*.example
 `
+	}
 
-	content += lockplaneSection
-
-	return os.WriteFile(gitignorePath, []byte(content), 0644)
+	return content, true
 }
@@ -273,7 +273,7 @@ func TestUpdateGitignoreExisting(t *testing.T) {
 		t.Fatalf("failed to create .gitignore: %v", err)
 	}
 
-	if err := updateGitignore(); err != nil {
+	if err := updateGitignore(".gitignore"); err != nil {
 		t.Fatalf("updateGitignore() error = %v", err)
 	}
 
@@ -317,7 +317,7 @@ func TestUpdateGitignoreAlreadyHasPattern(t *testing.T) {
 		t.Fatalf("failed to create .gitignore: %v", err)
 	}
 
-	if err := updateGitignore(); err != nil {
+	if err := updateGitignore(".gitignore"); err != nil {
 		t.Fatalf("updateGitignore() error = %v", err)
 	}
 
@@ -333,6 +333,31 @@ func TestUpdateGitignoreAlreadyHasPattern(t *testing.T) {
 	}
 }
 
+func TestUpdateGitignoreAddsLocalPattern(t *testing.T) {
+	gitignorePath := filepath.Join(t.TempDir(), ".gitignore")
+
+	// Only some environment files are ignored, so .env.<name>.local files would be committed
+	if err := os.WriteFile(gitignorePath, []byte("*.log\n.env.staging\n"), 0644); err != nil {
+		t.Fatalf("failed to create .gitignore: %v", err)
+	}
+	for range 2 {
+		if err := updateGitignore(gitignorePath); err != nil {
+			t.Fatalf("updateGitignore() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(gitignorePath)
+	if err != nil {
+		t.Fatalf("failed to read .gitignore: %v", err)
+	}
+	if count := strings.Count(string(content), ".env.*.local"); count != 1 {
+		t.Errorf(".env.*.local appears %d times, want 1:\n%s", count, content)
+	}
+	if !strings.Contains(string(content), ".env.staging") {
+		t.Error(".gitignore should preserve existing content")
+	}
+}
+
 func TestGetEnvironmentNames(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "lockplane.toml")
@@ -504,6 +529,78 @@ func TestGenerateFilesPreservesExistingEnvironments(t *testing.T) {
 	}
 }
 
+func TestGenerateEnvFileShadowSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := EnvironmentInput{
+		Name:         "local",
+		DatabaseType: "postgres",
+		Host:         "localhost",
+		Port:         "5432",
+		Database:     "app",
+		User:         "app",
+		Password:     "secret",
+		ShadowSchema: "lockplane_shadow",
+	}
+
+	path := filepath.Join(tmpDir, ".env.local")
+	if err := generateEnvFile(path, env); err != nil {
+		t.Fatalf("generateEnvFile() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read env file: %v", err)
+	}
+	if !strings.Contains(string(content), "SHADOW_SCHEMA=lockplane_shadow\n") {
+		t.Error("expected SHADOW_SCHEMA in env file")
+	}
+	if !strings.Contains(string(content), "SHADOW_SCHEMA_PER_RUN=true\n") {
+		t.Error("expected schema reuse to default to a new schema per run")
+	}
+
+	env.SharedShadowSchema = true
+	if err := generateEnvFile(path, env); err != nil {
+		t.Fatalf("generateEnvFile() error = %v", err)
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read env file: %v", err)
+	}
+	if strings.Contains(string(content), "SHADOW_SCHEMA_PER_RUN") {
+		t.Error("expected shared shadow schema to omit SHADOW_SCHEMA_PER_RUN")
+	}
+}
+
+func TestGenerateEnvFileUnixSocket(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := EnvironmentInput{
+		Name:         "local",
+		DatabaseType: "postgres",
+		Host:         "/var/run/postgresql",
+		Port:         "5432",
+		Database:     "app",
+		User:         "me",
+		SSLMode:      "disable",
+		ShadowDBPort: "5432",
+	}
+
+	path := filepath.Join(tmpDir, ".env.local")
+	if err := generateEnvFile(path, env); err != nil {
+		t.Fatalf("generateEnvFile() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read env file: %v", err)
+	}
+	for _, want := range []string{
+		"POSTGRES_URL=postgresql://me@/app?host=/var/run/postgresql&port=5432&sslmode=disable\n",
+		"POSTGRES_SHADOW_URL=postgresql://me@/app_shadow?host=/var/run/postgresql&port=5432&sslmode=disable\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected env file to contain %q, got:\n%s", want, content)
+		}
+	}
+}
+
 func TestCreateOrUpdateEnvExampleNew(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalDir, err := os.Getwd()
@@ -527,7 +624,7 @@ func TestCreateOrUpdateEnvExampleNew(t *testing.T) {
 			DatabaseType: "postgres",
 		},
 	}
-	if err := createOrUpdateEnvExample(envs); err != nil {
+	if err := createOrUpdateEnvExample(".env.example", envs); err != nil {
 		t.Fatalf("createOrUpdateEnvExample() error = %v", err)
 	}
 
@@ -588,7 +685,7 @@ func TestCreateOrUpdateEnvExampleUpdate(t *testing.T) {
 			DatabaseType: "libsql",
 		},
 	}
-	if err := createOrUpdateEnvExample(envsWithLibSQL); err != nil {
+	if err := createOrUpdateEnvExample(".env.example", envsWithLibSQL); err != nil {
 		t.Fatalf("createOrUpdateEnvExample() error = %v", err)
 	}
 
@@ -650,7 +747,7 @@ func TestCreateOrUpdateEnvExampleIdempotent(t *testing.T) {
 			DatabaseType: "libsql",
 		},
 	}
-	if err := createOrUpdateEnvExample(envsWithLibSQL); err != nil {
+	if err := createOrUpdateEnvExample(".env.example", envsWithLibSQL); err != nil {
 		t.Fatalf("createOrUpdateEnvExample() error = %v", err)
 	}
 
@@ -718,7 +815,7 @@ func TestCreateOrUpdateEnvExampleSQLite(t *testing.T) {
 			DatabaseType: "sqlite",
 		},
 	}
-	if err := createOrUpdateEnvExample(envs); err != nil {
+	if err := createOrUpdateEnvExample(".env.example", envs); err != nil {
 		t.Fatalf("createOrUpdateEnvExample() error = %v", err)
 	}
 
@@ -769,7 +866,7 @@ func TestCreateOrUpdateEnvExampleLibSQL(t *testing.T) {
 			DatabaseType: "libsql",
 		},
 	}
-	if err := createOrUpdateEnvExample(envs); err != nil {
+	if err := createOrUpdateEnvExample(".env.example", envs); err != nil {
 		t.Fatalf("createOrUpdateEnvExample() error = %v", err)
 	}
 
@@ -942,3 +1039,61 @@ func TestGenerateFilesUpdatesExistingEnvironment(t *testing.T) {
 		t.Error("config should not contain old description")
 	}
 }
+
+func TestGenerateFilesDryRun(t *testing.T) {
+	dir := t.TempDir()
+	existing := "# Hand-edited\nschema_path = \"db\"\n\n[environments.local]\ndescription = \"Local\"\n"
+	configPath := filepath.Join(dir, "lockplane.toml")
+	if err := os.WriteFile(configPath, []byte(existing), 0644); err != nil {
+		t.Fatalf("failed to write lockplane.toml: %v", err)
+	}
+
+	envs := []EnvironmentInput{{Name: "staging", DatabaseType: "sqlite", FilePath: filepath.Join(dir, "staging.db")}}
+	result, err := GenerateFilesWithOptions(dir, envs, GenerateOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("GenerateFilesWithOptions() error = %v", err)
+	}
+
+	// Nothing is written
+	data, err := os.ReadFile(configPath)
+	if err != nil || string(data) != existing {
+		t.Errorf("expected lockplane.toml to be untouched, got %q (%v)", data, err)
+	}
+	for _, name := range []string{".env.staging", ".env.example", ".gitignore", "db", "staging.db"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be created in a dry run", name)
+		}
+	}
+
+	// The would-be contents are returned
+	config := result.File(configPath)
+	if config == nil {
+		t.Fatalf("expected lockplane.toml in result files, got %+v", result.Files)
+	}
+	if !config.Existed || config.Previous != existing {
+		t.Errorf("expected previous lockplane.toml content, got %+v", config)
+	}
+	if !strings.Contains(config.Content, "[environments.staging]") || !strings.Contains(config.Content, "[environments.local]") {
+		t.Errorf("expected both environments in new lockplane.toml, got:\n%s", config.Content)
+	}
+	if !result.ConfigUpdated {
+		t.Error("expected config to be reported as updated")
+	}
+	envFile := result.File(filepath.Join(dir, ".env.staging"))
+	if envFile == nil || envFile.Existed || !strings.Contains(envFile.Content, "SQLITE_DB_PATH=") {
+		t.Errorf("expected new .env.staging in result files, got %+v", envFile)
+	}
+
+	// Writing for real produces the previewed content
+	written, err := GenerateFilesInDir(dir, envs)
+	if err != nil {
+		t.Fatalf("GenerateFilesInDir() error = %v", err)
+	}
+	data, err = os.ReadFile(configPath)
+	if err != nil || string(data) != config.Content {
+		t.Errorf("expected written lockplane.toml to match the dry run, got %q (%v)", data, err)
+	}
+	if len(written.Files) != len(result.Files) {
+		t.Errorf("expected %d files written, got %d", len(result.Files), len(written.Files))
+	}
+}
@@ -1,6 +1,8 @@
 package wizard
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbles/textinput"
 )
 
@@ -12,7 +14,7 @@ const (
 	StateCheckExisting
 	StateSelectExisting
 	StateDatabaseType
-	StatePostgresInputMethod // Choose between individual fields or connection string
+	StatePostgresInputMethod // Choose between individual fields, connection string or Unix socket
 	StateConnectionDetails
 	StateTestConnection
 	StateShadowPreview // Show shadow DB configuration preview
@@ -47,9 +49,23 @@ type WizardModel struct {
 	connectionError      error
 	retryChoice          int // 0=retry, 1=edit, 2=quit
 
+	// Waiting for a database that is still starting up
+	connectionRetryWindow time.Duration
+	connectionTestStarted time.Time
+	connectionAttempt     int
+	waitingForDatabase    bool
+
+	// PostgreSQL shadow mode choice
+	shadowModeChoice int // 0=separate database, 1=schema per run, 2=shared schema
+
 	// Add another environment choice
 	addAnotherChoice int // 0=add another, 1=finish and save
 
+	// Preview of the lockplane.toml changes shown on the summary screen
+	configDiff       string
+	configPreviewErr error
+	summaryScroll    int
+
 	// Input fields (using bubbletea textinput)
 	inputs     []textinput.Model
 	focusIndex int
@@ -57,7 +73,7 @@ type WizardModel struct {
 	// Database type selection
 	dbTypeIndex int
 
-	// Postgres input method selection (0=individual fields, 1=connection string)
+	// Postgres input method selection (0=individual fields, 1=connection string, 2=Unix socket)
 	postgresInputMethod int
 
 	// Validation
@@ -90,6 +106,9 @@ type EnvironmentInput struct {
 	SSLMode      string
 	ShadowDBPort string
 	ShadowSchema string
+	// SharedShadowSchema reuses ShadowSchema across runs instead of creating a
+	// unique schema per run
+	SharedShadowSchema bool
 
 	// SQLite fields
 	FilePath string
@@ -115,6 +134,18 @@ type InitResult struct {
 	GitignoreUpdated  bool
 	EnvExampleCreated bool
 	EnvExampleUpdated bool
+
+	// Files lists every file written, or in a dry run every file that
+	// would be written, in the order they were generated
+	Files []GeneratedFile
+}
+
+// GeneratedFile is a file written by GenerateFilesWithOptions
+type GeneratedFile struct {
+	Path     string
+	Content  string
+	Previous string // Content before generation, empty if the file didn't exist
+	Existed  bool
 }
 
 // DatabaseType represents a database option
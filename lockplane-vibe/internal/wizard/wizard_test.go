@@ -1,10 +1,17 @@
 package wizard
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lib/pq"
 )
 
 func TestNew(t *testing.T) {
@@ -104,6 +111,32 @@ func TestCollectInputValuesClearsErrors(t *testing.T) {
 	}
 }
 
+func TestCollectInputValuesUnixSocket(t *testing.T) {
+	m := New()
+	m.state = StateConnectionDetails
+	m.currentEnv.DatabaseType = "postgres"
+	m.postgresInputMethod = 2
+	m.initializeInputs()
+	m.inputs[1].SetValue("/tmp")
+	m.inputs[4].SetValue("me")
+
+	if err := m.collectInputValues(); err != nil {
+		t.Fatalf("expected collectInputValues to succeed: %v", err)
+	}
+	want := "postgresql://me@/lockplane?host=/tmp&port=5432&sslmode=disable"
+	if got := BuildPostgresConnectionString(m.currentEnv); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if m.currentEnv.ShadowDBPort != "5432" {
+		t.Errorf("expected the shadow database on the same server, got port %q", m.currentEnv.ShadowDBPort)
+	}
+
+	m.inputs[1].SetValue("tmp")
+	if err := m.collectInputValues(); err == nil || m.errors["host"] == "" {
+		t.Errorf("expected a relative socket directory to be rejected, got %v", err)
+	}
+}
+
 func TestHandleUpDown(t *testing.T) {
 	m := New()
 	m.state = StateDatabaseType
@@ -189,6 +222,45 @@ func TestHandleEnterTestConnectionSuccess(t *testing.T) {
 	}
 }
 
+func TestShadowPreviewModeChoice(t *testing.T) {
+	tests := []struct {
+		downPresses int
+		wantSchema  string
+		wantShared  bool
+	}{
+		{0, "", false},
+		{1, "lockplane_shadow", false},
+		{2, "lockplane_shadow", true},
+		{5, "lockplane_shadow", true}, // clamped to the last option
+	}
+
+	for _, tt := range tests {
+		m := New()
+		m.state = StateShadowPreview
+		m.currentEnv.Name = "test"
+		m.currentEnv.DatabaseType = "postgres"
+
+		for i := 0; i < tt.downPresses; i++ {
+			m.handleDown()
+		}
+		if tt.downPresses > 0 && !strings.Contains(m.View(), "Shadow Schema") {
+			t.Errorf("%d presses: expected schema mode to be previewed", tt.downPresses)
+		}
+
+		newModel, _ := m.handleEnter()
+		m = *newModel.(*WizardModel)
+
+		env := m.environments[0]
+		if env.ShadowSchema != tt.wantSchema || env.SharedShadowSchema != tt.wantShared {
+			t.Errorf("%d presses: got schema %q shared=%v, want %q shared=%v",
+				tt.downPresses, env.ShadowSchema, env.SharedShadowSchema, tt.wantSchema, tt.wantShared)
+		}
+		if m.shadowModeChoice != 0 {
+			t.Errorf("%d presses: expected choice to reset, got %d", tt.downPresses, m.shadowModeChoice)
+		}
+	}
+}
+
 func TestHandleEnterTestConnectionFailedRetry(t *testing.T) {
 	m := New()
 	m.state = StateTestConnection
@@ -211,6 +283,51 @@ func TestHandleEnterTestConnectionFailedRetry(t *testing.T) {
 	}
 }
 
+func TestConnectionTestWaitsForStartingDatabase(t *testing.T) {
+	m := New()
+	m.state = StateTestConnection
+	m.startConnectionTest()
+
+	newModel, cmd := m.Update(connectionTestResultMsg{err: syscall.ECONNREFUSED})
+	m = *newModel.(*WizardModel)
+	if !m.testingConnection || !m.waitingForDatabase {
+		t.Fatalf("expected to keep waiting on connection refused, got testing=%v waiting=%v", m.testingConnection, m.waitingForDatabase)
+	}
+	if m.connectionTestResult != "" {
+		t.Errorf("expected no result while waiting, got %q", m.connectionTestResult)
+	}
+	if cmd == nil {
+		t.Error("expected a retry to be scheduled")
+	}
+	if view := m.renderTestConnection(); !strings.Contains(view, "Waiting for database to start") {
+		t.Errorf("expected waiting status in view, got:\n%s", view)
+	}
+
+	// Once the window has passed the error is shown
+	m.connectionTestStarted = time.Now().Add(-time.Minute)
+	newModel, _ = m.Update(connectionTestResultMsg{err: syscall.ECONNREFUSED})
+	m = *newModel.(*WizardModel)
+	if m.testingConnection || m.waitingForDatabase || m.connectionTestResult != "failed" {
+		t.Errorf("expected failure after the retry window, got testing=%v waiting=%v result=%q", m.testingConnection, m.waitingForDatabase, m.connectionTestResult)
+	}
+}
+
+func TestConnectionTestFailsImmediatelyOnAuthError(t *testing.T) {
+	m := New()
+	m.state = StateTestConnection
+	m.startConnectionTest()
+
+	authErr := &pq.Error{Code: "28P01", Message: "password authentication failed"}
+	newModel, _ := m.Update(connectionTestResultMsg{err: authErr})
+	m = *newModel.(*WizardModel)
+	if m.testingConnection || m.connectionTestResult != "failed" {
+		t.Errorf("expected immediate failure, got testing=%v result=%q", m.testingConnection, m.connectionTestResult)
+	}
+	if !errors.Is(m.connectionError, authErr) {
+		t.Errorf("expected the authentication error to be shown, got %v", m.connectionError)
+	}
+}
+
 func TestHandleEnterTestConnectionFailedEdit(t *testing.T) {
 	m := New()
 	m.state = StateTestConnection
@@ -286,6 +403,54 @@ func TestExistingConfigDetection(t *testing.T) {
 	}
 }
 
+func TestCheckForExistingConfigInParentDirectory(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "lockplane.toml"), []byte("[environments.local]\n"), 0644); err != nil {
+		t.Fatalf("failed to write lockplane.toml: %v", err)
+	}
+	subdir := filepath.Join(root, "apps", "api")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatalf("failed to create subdirectory: %v", err)
+	}
+	t.Chdir(subdir)
+
+	msg, ok := checkForExistingConfig().(existingConfigMsg)
+	if !ok {
+		t.Fatalf("expected existingConfigMsg")
+	}
+	if msg.path != filepath.Join("..", "..", "lockplane.toml") {
+		t.Fatalf("expected config found in project root, got %q", msg.path)
+	}
+	if len(msg.envNames) != 1 || msg.envNames[0] != "local" {
+		t.Fatalf("expected existing environment local, got %v", msg.envNames)
+	}
+
+	// New environments are saved next to the existing config
+	m := New()
+	m.existingConfigPath = msg.path
+	m.environments = []EnvironmentInput{{Name: "staging", DatabaseType: "sqlite", FilePath: "staging.db"}}
+	created, ok := m.createFiles()().(fileCreationResultMsg)
+	if !ok || created.err != nil {
+		t.Fatalf("expected files to be created, got %+v", created)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".env.staging")); err != nil {
+		t.Errorf("expected .env.staging in project root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(subdir, "lockplane.toml")); !os.IsNotExist(err) {
+		t.Errorf("expected no lockplane.toml in subdirectory, got err=%v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "lockplane.toml"))
+	if err != nil {
+		t.Fatalf("failed to read lockplane.toml: %v", err)
+	}
+	if !strings.Contains(string(content), "[environments.staging]") {
+		t.Errorf("expected staging environment added to root config, got:\n%s", content)
+	}
+}
+
 func TestDuplicateEnvironmentNameReturnsToCheckExisting(t *testing.T) {
 	m := New()
 	m.state = StateConnectionDetails
@@ -342,3 +507,67 @@ func TestNoExistingConfig(t *testing.T) {
 		t.Errorf("expected state to be StateWelcome when no config exists, got %v", m.state)
 	}
 }
+
+func TestSaveAndFinishPreviewsExistingConfigChanges(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	existing := "# Keep me\ndefault_environment = \"local\"\nschema_path = \"schema\"\n\n[environments.local]\ndescription = \"Local\"\n"
+	if err := os.WriteFile("lockplane.toml", []byte(existing), 0644); err != nil {
+		t.Fatalf("failed to write lockplane.toml: %v", err)
+	}
+
+	m := New()
+	m.existingConfigPath = "lockplane.toml"
+	m.existingEnvNames = []string{"local"}
+	m.state = StateAddAnother
+	m.addAnotherChoice = 1
+	m.environments = []EnvironmentInput{{Name: "staging", DatabaseType: "sqlite", FilePath: "staging.db"}}
+
+	newModel, cmd := m.handleEnter()
+	m = *newModel.(*WizardModel)
+	if m.state != StateSummary || cmd != nil {
+		t.Fatalf("expected the summary before saving, got state %v", m.state)
+	}
+	if data, _ := os.ReadFile("lockplane.toml"); string(data) != existing {
+		t.Errorf("expected lockplane.toml to be untouched by the preview, got %q", data)
+	}
+	for _, want := range []string{"--- lockplane.toml (current)", "+++ lockplane.toml (new)", "-# Keep me", "+[environments.staging]"} {
+		if !strings.Contains(m.configDiff, want) {
+			t.Errorf("expected diff to contain %q, got:\n%s", want, m.configDiff)
+		}
+	}
+	view := m.view()
+	if !strings.Contains(view, "Changes to lockplane.toml") || !strings.Contains(view, "-# Keep me") {
+		t.Errorf("expected summary to show the diff, got:\n%s", view)
+	}
+
+	// Long diffs scroll
+	m.height = 10
+	lines := m.configDiffLines()
+	if len(lines) <= m.diffHeight() {
+		t.Fatalf("expected diff longer than %d lines, got %d", m.diffHeight(), len(lines))
+	}
+	if !strings.Contains(m.view(), fmt.Sprintf("Lines 1-5 of %d", len(lines))) {
+		t.Errorf("expected scroll indicator, got:\n%s", m.view())
+	}
+	for i := 0; i < len(lines); i++ {
+		m.handleDown()
+	}
+	if m.summaryScroll != m.maxSummaryScroll() {
+		t.Errorf("expected scrolling to stop at %d, got %d", m.maxSummaryScroll(), m.summaryScroll)
+	}
+	if !strings.Contains(m.view(), lines[len(lines)-1]) {
+		t.Errorf("expected the last diff line after scrolling, got:\n%s", m.view())
+	}
+	m.handleUp()
+	if m.summaryScroll != m.maxSummaryScroll()-1 {
+		t.Errorf("expected scrolling up by one line, got %d", m.summaryScroll)
+	}
+
+	// Enter saves
+	newModel, cmd = m.handleEnter()
+	m = *newModel.(*WizardModel)
+	if m.state != StateCreating || cmd == nil {
+		t.Fatalf("expected saving after the preview, got state %v", m.state)
+	}
+}
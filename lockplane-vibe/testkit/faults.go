@@ -0,0 +1,194 @@
+// Package testkit helps programs embedding lockplane test their handling of
+// partial failures.
+//
+// A FaultInjector wraps a database/sql driver, connector or *sql.DB so that
+// chosen statements fail, fail with a PostgreSQL SQLSTATE, or are delayed,
+// without crafting invalid SQL:
+//
+//	faults := &testkit.FaultInjector{FailOnExec: 7}
+//	db := testkit.WrapDB(base, dsn, faults)
+//	// The 7th statement executed through db fails with ErrInjectedFault
+//
+// Only Exec calls are affected; queries always pass through, so schema
+// introspection keeps working against a faulty database.
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrInjectedFault is returned by a failing statement when FaultInjector.Err is not set
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultInjector decides which statements executed through a wrapped database
+// fail or are delayed. Only Exec calls are affected; queries always pass
+// through.
+type FaultInjector struct {
+	// FailOnExec fails the Nth matching Exec call (1-based; 0 = never fail)
+	FailOnExec int
+	// Err is returned by the failing call (default ErrInjectedFault). Use
+	// SQLStateError to simulate a PostgreSQL error code.
+	Err error
+	// Latency delays every matching Exec call. The delay ends early when the
+	// statement's context is cancelled.
+	Latency time.Duration
+	// Match limits injection to statements containing this substring
+	// (case-insensitive); empty matches every statement
+	Match string
+
+	mu       sync.Mutex
+	matched  int
+	executed []string
+}
+
+// Executed returns the statements that ran successfully, in order
+func (f *FaultInjector) Executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.executed...)
+}
+
+// inject applies the configured latency and failure to a statement
+func (f *FaultInjector) inject(ctx context.Context, query string) error {
+	if f.Match != "" && !strings.Contains(strings.ToUpper(query), strings.ToUpper(f.Match)) {
+		return nil
+	}
+
+	f.mu.Lock()
+	f.matched++
+	fail := f.FailOnExec > 0 && f.matched == f.FailOnExec
+	f.mu.Unlock()
+
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if fail {
+		if f.Err != nil {
+			return f.Err
+		}
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+// record notes a statement that ran successfully
+func (f *FaultInjector) record(query string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.executed = append(f.executed, query)
+}
+
+// SQLStateError returns a PostgreSQL error with the given SQLSTATE code, as
+// reported by lib/pq (e.g. "55P03" for lock_not_available)
+func SQLStateError(code, message string) error {
+	return &pq.Error{Code: pq.ErrorCode(code), Message: message}
+}
+
+// WrapConnector returns a connector whose connections, opened by connector,
+// execute statements through faults
+func WrapConnector(connector driver.Connector, faults *FaultInjector) driver.Connector {
+	return &faultyConnector{connector: connector, faults: faults}
+}
+
+// WrapDriver returns a connector that opens dsn with drv and executes
+// statements through faults. Pass it to sql.OpenDB.
+func WrapDriver(drv driver.Driver, dsn string, faults *FaultInjector) driver.Connector {
+	return WrapConnector(dsnConnector{dsn: dsn, driver: drv}, faults)
+}
+
+// WrapDB opens a new database that connects like db and executes statements
+// through faults. database/sql doesn't expose a database's DSN, so the caller
+// passes the one db was opened with. db stays usable and is not closed; the
+// caller closes both.
+func WrapDB(db *sql.DB, dsn string, faults *FaultInjector) *sql.DB {
+	return sql.OpenDB(WrapDriver(db.Driver(), dsn, faults))
+}
+
+// dsnConnector opens connections to dsn with driver, like sql.Open does
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(c.dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// faultyConnector wraps the connections of an underlying connector in faultyConn
+type faultyConnector struct {
+	connector driver.Connector
+	faults    *FaultInjector
+}
+
+func (c *faultyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyConn{Conn: conn, faults: c.faults}, nil
+}
+
+func (c *faultyConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// faultyConn injects faults into Exec calls and passes everything else through
+type faultyConn struct {
+	driver.Conn
+	faults *FaultInjector
+}
+
+func (c *faultyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.faults.inject(ctx, query); err != nil {
+		return nil, err
+	}
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, errors.New("testkit: underlying driver does not support ExecContext")
+	}
+	result, err := execer.ExecContext(ctx, query, args)
+	if err == nil {
+		c.faults.record(query)
+	}
+	return result, err
+}
+
+func (c *faultyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *faultyConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+}
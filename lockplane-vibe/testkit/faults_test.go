@@ -0,0 +1,74 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite opens a shared in-memory SQLite database standing in for a
+// caller's own database
+func openSQLite(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	dsn := "file:" + t.Name() + "?mode=memory&cache=shared"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	db.SetMaxIdleConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, dsn
+}
+
+func TestWrapDB(t *testing.T) {
+	base, dsn := openSQLite(t)
+	faults := &FaultInjector{FailOnExec: 2, Match: "create table"}
+	db := WrapDB(base, dsn, faults)
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE a (id integer)"); err != nil {
+		t.Fatalf("Expected the first statement to succeed, got: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO a VALUES (1)"); err != nil {
+		t.Fatalf("Expected a statement that doesn't match to succeed, got: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE b (id integer)"); !errors.Is(err, ErrInjectedFault) {
+		t.Fatalf("Expected the second matching statement to fail, got: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE c (id integer)"); err != nil {
+		t.Fatalf("Expected only the Nth statement to fail, got: %v", err)
+	}
+
+	want := []string{"CREATE TABLE a (id integer)", "INSERT INTO a VALUES (1)", "CREATE TABLE c (id integer)"}
+	if got := faults.Executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected executed statements %v, got %v", want, got)
+	}
+
+	// Queries pass through, and the caller's database sees the same data
+	var count int
+	if err := base.QueryRowContext(ctx, "SELECT count(*) FROM a").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the wrapped database's writes in the caller's database, got %d (%v)", count, err)
+	}
+}
+
+func TestWrapConnectorSQLState(t *testing.T) {
+	base, dsn := openSQLite(t)
+	faults := &FaultInjector{FailOnExec: 1, Err: SQLStateError("55P03", "could not obtain lock")}
+	db := sql.OpenDB(WrapConnector(dsnConnector{dsn: dsn, driver: base.Driver()}, faults))
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err := db.ExecContext(context.Background(), "CREATE TABLE a (id integer)")
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "55P03" {
+		t.Fatalf("Expected SQLSTATE 55P03, got: %v", err)
+	}
+	if executed := faults.Executed(); len(executed) != 0 {
+		t.Errorf("Expected the failed statement not to be recorded, got %v", executed)
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewOTelTracer returns a Tracer that records spans with an OpenTelemetry
+// TracerProvider. Programs embedding lockplane pass their own provider to
+// SetTracer so that lockplane's spans are exported alongside theirs.
+func NewOTelTracer(provider trace.TracerProvider) Tracer {
+	return otelTracer{tracer: provider.Tracer(instrumentationLib)}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+// Start begins a span as a child of the span in ctx, or a new trace if none
+func (t otelTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(otelAttributes(attrs)...))
+	return ctx, otelSpan{span: span}
+}
+
+// otelSpan adapts an OpenTelemetry span to Span
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attrs ...Attribute) {
+	s.span.SetAttributes(otelAttributes(attrs)...)
+}
+
+func (s otelSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) SpanContext() SpanContext {
+	return spanContextFromOTel(s.span.SpanContext())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+func otelAttributes(attrs []Attribute) []attribute.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			out = append(out, attribute.String(attr.Key, v))
+		case int64:
+			out = append(out, attribute.Int64(attr.Key, v))
+		case bool:
+			out = append(out, attribute.Bool(attr.Key, v))
+		case float64:
+			out = append(out, attribute.Float64(attr.Key, v))
+		default:
+			out = append(out, attribute.String(attr.Key, fmt.Sprint(v)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,245 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Standard OpenTelemetry environment variables read by InitFromEnv
+const (
+	EnvEndpoint        = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvTracesEndpoint  = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	EnvHeaders         = "OTEL_EXPORTER_OTLP_HEADERS"
+	EnvTracesHeaders   = "OTEL_EXPORTER_OTLP_TRACES_HEADERS"
+	EnvProtocol        = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvTracesProtocol  = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+	EnvTimeout         = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	EnvServiceName     = "OTEL_SERVICE_NAME"
+	EnvSDKDisabled     = "OTEL_SDK_DISABLED"
+	EnvTracesExporter  = "OTEL_TRACES_EXPORTER"
+	EnvTraceparent     = "TRACEPARENT"
+	defaultServiceName = "lockplane"
+	defaultTimeout     = 10 * time.Second
+	instrumentationLib = "github.com/lockplane/lockplane"
+)
+
+// OTLPConfig configures the OTLP/HTTP exporter
+type OTLPConfig struct {
+	Endpoint    string            // Full URL spans are POSTed to (e.g. http://localhost:4318/v1/traces)
+	Headers     map[string]string // Extra request headers (e.g. authentication)
+	ServiceName string            // service.name resource attribute (default "lockplane")
+	Timeout     time.Duration     // Per-request timeout (default 10s)
+	Client      *http.Client      // HTTP client (default: a client using Timeout)
+}
+
+// ShutdownFunc stops tracing and reports any export failure
+type ShutdownFunc func(ctx context.Context) error
+
+// InitFromEnv installs an OTLP tracer when OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. When neither is set, or when
+// OTEL_SDK_DISABLED=true or OTEL_TRACES_EXPORTER=none, tracing stays disabled
+// and the returned shutdown function does nothing.
+func InitFromEnv() (ShutdownFunc, error) {
+	noop := func(context.Context) error { return nil }
+
+	if strings.EqualFold(os.Getenv(EnvSDKDisabled), "true") || strings.EqualFold(os.Getenv(EnvTracesExporter), "none") {
+		return noop, nil
+	}
+
+	cfg, ok, err := otlpConfigFromEnv()
+	if err != nil || !ok {
+		return noop, err
+	}
+
+	tracer, err := NewOTLPTracer(cfg)
+	if err != nil {
+		return noop, err
+	}
+	SetTracer(tracer)
+	return func(ctx context.Context) error {
+		SetTracer(nil)
+		return tracer.Shutdown(ctx)
+	}, nil
+}
+
+// otlpConfigFromEnv builds an exporter configuration from the standard
+// OpenTelemetry environment variables; ok is false when no endpoint is set
+func otlpConfigFromEnv() (cfg OTLPConfig, ok bool, err error) {
+	endpoint := strings.TrimSpace(os.Getenv(EnvTracesEndpoint))
+	if endpoint == "" {
+		base := strings.TrimSpace(os.Getenv(EnvEndpoint))
+		if base == "" {
+			return OTLPConfig{}, false, nil
+		}
+		// The generic endpoint is a base URL; the signal path is appended
+		endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return OTLPConfig{}, false, fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+
+	protocol := os.Getenv(EnvTracesProtocol)
+	if protocol == "" {
+		protocol = os.Getenv(EnvProtocol)
+	}
+	if protocol == "grpc" {
+		return OTLPConfig{}, false, fmt.Errorf("OTLP protocol %q is not supported: lockplane exports traces over OTLP/HTTP (use port 4318)", protocol)
+	}
+
+	headers, err := parseOTLPHeaders(os.Getenv(EnvHeaders))
+	if err != nil {
+		return OTLPConfig{}, false, err
+	}
+	tracesHeaders, err := parseOTLPHeaders(os.Getenv(EnvTracesHeaders))
+	if err != nil {
+		return OTLPConfig{}, false, err
+	}
+	for k, v := range tracesHeaders {
+		headers[k] = v
+	}
+
+	timeout := defaultTimeout
+	if raw := strings.TrimSpace(os.Getenv(EnvTimeout)); raw != "" {
+		ms, err := strconv.Atoi(raw)
+		if err != nil || ms <= 0 {
+			return OTLPConfig{}, false, fmt.Errorf("invalid %s %q: expected milliseconds", EnvTimeout, raw)
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	return OTLPConfig{
+		Endpoint:    endpoint,
+		Headers:     headers,
+		ServiceName: os.Getenv(EnvServiceName),
+		Timeout:     timeout,
+	}, true, nil
+}
+
+// parseOTLPHeaders parses the "key1=value1,key2=value2" header format, with
+// URL-encoded values
+func parseOTLPHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q: expected key=value", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", pair, err)
+		}
+		headers[strings.TrimSpace(key)] = decoded
+	}
+	return headers, nil
+}
+
+// OTLPTracer records spans with the OpenTelemetry SDK and exports them over
+// OTLP/HTTP.
+//
+// Ended spans are queued and exported in batches in the background, so
+// ending a span never waits on the collector. Shutdown flushes the queue,
+// which is how spans recorded before the CLI exits are delivered. After the
+// first failed export, further batches are dropped so that an unreachable
+// collector cannot slow down a migration; the error is reported by Shutdown.
+type OTLPTracer struct {
+	Tracer
+	provider *sdktrace.TracerProvider
+	exporter *failFastExporter
+}
+
+// NewOTLPTracer creates a tracer exporting to cfg.Endpoint
+func NewOTLPTracer(cfg OTLPConfig) (*OTLPTracer, error) {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpointURL(cfg.Endpoint),
+		otlptracehttp.WithHeaders(cfg.Headers),
+		otlptracehttp.WithTimeout(cfg.Timeout),
+	}
+	if cfg.Client != nil {
+		opts = append(opts, otlptracehttp.WithHTTPClient(cfg.Client))
+	}
+	client, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	exporter := &failFastExporter{SpanExporter: client}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	return &OTLPTracer{
+		Tracer:   NewOTelTracer(provider),
+		provider: provider,
+		exporter: exporter,
+	}, nil
+}
+
+// Shutdown exports any queued spans, stops the exporter and returns the first
+// export error, if any
+func (t *OTLPTracer) Shutdown(ctx context.Context) error {
+	if err := t.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to export traces: %w", err)
+	}
+	if err := t.exporter.failure(); err != nil {
+		return fmt.Errorf("failed to export traces: %w", err)
+	}
+	return nil
+}
+
+// failFastExporter records the first export failure and drops every batch
+// after it. Failures are returned by OTLPTracer.Shutdown instead of being
+// logged by the SDK in the middle of a migration's output.
+type failFastExporter struct {
+	sdktrace.SpanExporter
+
+	mu  sync.Mutex
+	err error
+}
+
+func (e *failFastExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.failure() != nil {
+		return nil
+	}
+	if err := e.SpanExporter.ExportSpans(ctx, spans); err != nil {
+		e.mu.Lock()
+		if e.err == nil {
+			e.err = err
+		}
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+func (e *failFastExporter) failure() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
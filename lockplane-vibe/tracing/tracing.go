@@ -0,0 +1,204 @@
+// Package tracing provides optional span instrumentation for lockplane.
+//
+// Tracing is disabled unless a Tracer is installed, either by the CLI via
+// InitFromEnv (which honours the standard OTEL_EXPORTER_OTLP_* variables) or
+// by a program embedding lockplane via SetTracer, typically with
+// NewOTelTracer and the program's own OpenTelemetry TracerProvider. While
+// disabled, Start returns a no-op span and does not allocate, so instrumented
+// code paths carry no overhead.
+//
+// The active span is carried in the context the way OpenTelemetry carries it,
+// so spans started under a span from the embedding program's SDK, or under a
+// remote parent (see ContextWithRemoteParent), join the caller's trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute is a key/value pair attached to a span
+type Attribute struct {
+	Key   string
+	Value any // string, int64, bool or float64
+}
+
+// String returns a string-valued attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer-valued attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Int64 returns an integer-valued attribute
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean-valued attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// TraceID identifies a trace (W3C Trace Context, 16 bytes)
+type TraceID = trace.TraceID
+
+// SpanID identifies a span within a trace (W3C Trace Context, 8 bytes)
+type SpanID = trace.SpanID
+
+// SpanContext identifies a span so that child spans can reference it
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both the trace and span IDs are non-zero
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), sc.otel()), carrier)
+	return carrier.Get(traceparentHeader)
+}
+
+// otel converts the span context to its OpenTelemetry form
+func (sc SpanContext) otel() trace.SpanContext {
+	var flags trace.TraceFlags
+	if sc.Sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    sc.TraceID,
+		SpanID:     sc.SpanID,
+		TraceFlags: flags,
+	})
+}
+
+func spanContextFromOTel(sc trace.SpanContext) SpanContext {
+	return SpanContext{
+		TraceID: sc.TraceID(),
+		SpanID:  sc.SpanID(),
+		Sampled: sc.IsSampled(),
+	}
+}
+
+// propagator reads and writes W3C traceparent values
+var propagator = propagation.TraceContext{}
+
+const traceparentHeader = "traceparent"
+
+// ParseTraceparent parses a W3C traceparent header value
+// (e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+func ParseTraceparent(value string) (SpanContext, error) {
+	sc := remoteSpanContext(context.Background(), value)
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	return spanContextFromOTel(sc), nil
+}
+
+func remoteSpanContext(ctx context.Context, traceparent string) trace.SpanContext {
+	carrier := propagation.MapCarrier{traceparentHeader: strings.TrimSpace(traceparent)}
+	return trace.SpanContextFromContext(propagator.Extract(ctx, carrier))
+}
+
+// Span records a single timed operation
+type Span interface {
+	// SetAttributes adds or overwrites attributes on the span
+	SetAttributes(attrs ...Attribute)
+	// RecordError marks the span as failed and records the error message
+	RecordError(err error)
+	// SpanContext returns the identifiers of this span
+	SpanContext() SpanContext
+	// End completes the span; calls after the first have no effect
+	End()
+}
+
+// Tracer creates spans
+type Tracer interface {
+	// Start begins a span as a child of the span in ctx (if any) and returns
+	// a context carrying the new span
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var globalTracer atomic.Pointer[tracerHolder]
+
+// SetTracer installs the tracer used by Start. Passing nil disables tracing.
+// Programs embedding lockplane can use this to bridge spans into their own
+// tracing SDK.
+func SetTracer(t Tracer) {
+	if t == nil {
+		globalTracer.Store(nil)
+		return
+	}
+	globalTracer.Store(&tracerHolder{tracer: t})
+}
+
+// Enabled reports whether a tracer is installed
+func Enabled() bool {
+	return globalTracer.Load() != nil
+}
+
+// Start begins a span using the installed tracer. When tracing is disabled it
+// returns ctx unchanged and a no-op span.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	holder := globalTracer.Load()
+	if holder == nil {
+		return ctx, noopSpan{}
+	}
+	return holder.tracer.Start(ctx, name, attrs...)
+}
+
+// ContextWithSpanContext returns a context whose spans become children of sc.
+// Tracer implementations use this to propagate the active span.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return trace.ContextWithSpanContext(ctx, sc.otel())
+}
+
+// SpanContextFromContext returns the active span context, if any. Spans
+// started by an OpenTelemetry SDK, including the embedding program's own, are
+// found as well as lockplane's.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	return spanContextFromOTel(sc), sc.IsValid()
+}
+
+// ContextWithRemoteParent returns a context whose spans join the trace
+// described by a W3C traceparent value, such as one propagated from a caller
+func ContextWithRemoteParent(ctx context.Context, traceparent string) (context.Context, error) {
+	sc := remoteSpanContext(ctx, traceparent)
+	if !sc.IsValid() {
+		return ctx, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc), nil
+}
+
+// NoopSpan returns a span that records nothing, for callers that skip
+// building attributes while tracing is disabled
+func NoopSpan() Span {
+	return noopSpan{}
+}
+
+// noopSpan is returned while tracing is disabled
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) SpanContext() SpanContext   { return SpanContext{} }
+func (noopSpan) End()                       {}
@@ -0,0 +1,359 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStart_DisabledIsNoop(t *testing.T) {
+	SetTracer(nil)
+
+	ctx := context.Background()
+	got, span := Start(ctx, "noop", String("k", "v"))
+	if got != ctx {
+		t.Error("expected context to be returned unchanged while tracing is disabled")
+	}
+	if _, ok := span.(noopSpan); !ok {
+		t.Errorf("expected noop span, got %T", span)
+	}
+	if Enabled() {
+		t.Error("expected tracing to be disabled")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	const value = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseTraceparent(value)
+	if err != nil {
+		t.Fatalf("ParseTraceparent returned error: %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected trace ID %s", sc.TraceID)
+	}
+	if sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("unexpected span ID %s", sc.SpanID)
+	}
+	if !sc.Sampled {
+		t.Error("expected sampled flag to be set")
+	}
+	if sc.Traceparent() != value {
+		t.Errorf("expected round trip to %s, got %s", value, sc.Traceparent())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+// collector is a fake OTLP/HTTP endpoint that records exported spans
+type collector struct {
+	mu      sync.Mutex
+	spans   []*tracepb.Span
+	headers http.Header
+	status  int
+	release chan struct{} // when set, requests wait until it is closed
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.release != nil {
+		<-c.release
+	}
+	body, _ := io.ReadAll(r.Body)
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers = r.Header.Clone()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+	}
+}
+
+func (c *collector) span(name string) *tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.spans)
+}
+
+func spanAttribute(span *tracepb.Span, key string) *commonpb.AnyValue {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+func newTestOTLPTracer(t *testing.T, cfg OTLPConfig) *OTLPTracer {
+	t.Helper()
+	tracer, err := NewOTLPTracer(cfg)
+	if err != nil {
+		t.Fatalf("NewOTLPTracer returned error: %v", err)
+	}
+	return tracer
+}
+
+func TestOTLPTracer_ExportsSpanTree(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	tracer := newTestOTLPTracer(t, OTLPConfig{
+		Endpoint: server.URL + "/v1/traces",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, err := ContextWithRemoteParent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ContextWithRemoteParent returned error: %v", err)
+	}
+
+	ctx, parent := Start(ctx, "parent", String("db.system", "postgres"))
+	_, child := Start(ctx, "child", Int("lockplane.step.index", 1))
+	child.RecordError(errors.New("relation does not exist"))
+	child.End()
+	parent.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	parentSpan := c.span("parent")
+	childSpan := c.span("child")
+	if parentSpan == nil || childSpan == nil {
+		t.Fatalf("expected both spans to be exported, got %d span(s)", c.count())
+	}
+
+	if got := hex.EncodeToString(parentSpan.TraceId); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected parent to join the remote trace, got %s", got)
+	}
+	if got := hex.EncodeToString(parentSpan.ParentSpanId); got != "00f067aa0ba902b7" {
+		t.Errorf("expected parent to reference the remote span, got %s", got)
+	}
+	if !bytes.Equal(childSpan.TraceId, parentSpan.TraceId) || !bytes.Equal(childSpan.ParentSpanId, parentSpan.SpanId) {
+		t.Error("expected child span to be nested under parent span")
+	}
+
+	if v := spanAttribute(parentSpan, "db.system"); v.GetStringValue() != "postgres" {
+		t.Error("expected db.system attribute on parent span")
+	}
+	if v := spanAttribute(childSpan, "lockplane.step.index"); v == nil || v.GetIntValue() != 1 {
+		t.Error("expected integer step index attribute on child span")
+	}
+	if childSpan.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || childSpan.Status.GetMessage() != "relation does not exist" {
+		t.Errorf("expected error status on child span, got %v", childSpan.Status)
+	}
+	if parentSpan.Status.GetCode() != tracepb.Status_STATUS_CODE_UNSET {
+		t.Errorf("expected unset status on parent span, got %v", parentSpan.Status)
+	}
+
+	if got := c.headers.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("expected configured headers to be sent, got %q", got)
+	}
+}
+
+func TestOTLPTracer_EndDoesNotWaitForExport(t *testing.T) {
+	c := &collector{release: make(chan struct{})}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	tracer := newTestOTLPTracer(t, OTLPConfig{Endpoint: server.URL + "/v1/traces"})
+
+	ended := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, span := tracer.Start(context.Background(), "step")
+			span.End()
+		}
+		close(ended)
+	}()
+
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected End to return while the collector is busy")
+	}
+
+	close(c.release)
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if c.count() != 3 {
+		t.Errorf("expected queued spans to be exported on Shutdown, got %d", c.count())
+	}
+}
+
+func TestOTLPTracer_ExportFailureReportedOnShutdown(t *testing.T) {
+	c := &collector{status: http.StatusBadRequest}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	tracer := newTestOTLPTracer(t, OTLPConfig{Endpoint: server.URL + "/v1/traces"})
+
+	_, first := tracer.Start(context.Background(), "first")
+	first.End()
+	if err := tracer.provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush returned error: %v", err)
+	}
+	_, second := tracer.Start(context.Background(), "second")
+	second.End()
+
+	if err := tracer.Shutdown(context.Background()); err == nil {
+		t.Fatal("expected Shutdown to report the failed export")
+	}
+	if c.count() != 1 {
+		t.Errorf("expected spans after a failed export to be dropped, got %d", c.count())
+	}
+}
+
+func TestNewOTelTracer_JoinsEmbeddingProgramSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	SetTracer(NewOTelTracer(provider))
+	defer SetTracer(nil)
+
+	// The embedding program starts a span with its own SDK tracer
+	ctx, appSpan := provider.Tracer("app").Start(context.Background(), "deploy")
+
+	if sc, ok := SpanContextFromContext(ctx); !ok || sc.SpanID != appSpan.SpanContext().SpanID() {
+		t.Fatal("expected the embedding program's span to be the active span")
+	}
+
+	_, span := Start(ctx, "lockplane apply")
+	span.End()
+	appSpan.End()
+
+	var child sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "lockplane apply" {
+			child = s
+		}
+	}
+	if child == nil {
+		t.Fatal("expected the lockplane span to be recorded by the embedding program's provider")
+	}
+	if child.Parent().SpanID() != appSpan.SpanContext().SpanID() || child.SpanContext().TraceID() != appSpan.SpanContext().TraceID() {
+		t.Error("expected the lockplane span to be a child of the embedding program's span")
+	}
+}
+
+func TestInitFromEnv_DisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv(EnvEndpoint, "")
+	t.Setenv(EnvTracesEndpoint, "")
+
+	shutdown, err := InitFromEnv()
+	if err != nil {
+		t.Fatalf("InitFromEnv returned error: %v", err)
+	}
+	if Enabled() {
+		t.Error("expected tracing to stay disabled without an endpoint")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("expected no-op shutdown, got %v", err)
+	}
+}
+
+func TestInitFromEnv_SDKDisabled(t *testing.T) {
+	t.Setenv(EnvEndpoint, "http://localhost:4318")
+	t.Setenv(EnvSDKDisabled, "true")
+
+	if _, err := InitFromEnv(); err != nil {
+		t.Fatalf("InitFromEnv returned error: %v", err)
+	}
+	if Enabled() {
+		t.Error("expected OTEL_SDK_DISABLED to keep tracing disabled")
+	}
+}
+
+func TestOTLPConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvEndpoint, "http://collector:4318/")
+	t.Setenv(EnvTracesEndpoint, "")
+	t.Setenv(EnvHeaders, "x-api-key=abc%20123, x-team=db")
+	t.Setenv(EnvTracesHeaders, "x-team=platform")
+	t.Setenv(EnvTimeout, "2500")
+	t.Setenv(EnvServiceName, "migrations")
+
+	cfg, ok, err := otlpConfigFromEnv()
+	if err != nil {
+		t.Fatalf("otlpConfigFromEnv returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected configuration to be enabled")
+	}
+	if cfg.Endpoint != "http://collector:4318/v1/traces" {
+		t.Errorf("expected signal path to be appended, got %s", cfg.Endpoint)
+	}
+	if cfg.Headers["x-api-key"] != "abc 123" {
+		t.Errorf("expected URL-decoded header value, got %q", cfg.Headers["x-api-key"])
+	}
+	if cfg.Headers["x-team"] != "platform" {
+		t.Errorf("expected traces headers to override generic headers, got %q", cfg.Headers["x-team"])
+	}
+	if cfg.Timeout.Milliseconds() != 2500 {
+		t.Errorf("expected 2500ms timeout, got %s", cfg.Timeout)
+	}
+	if cfg.ServiceName != "migrations" {
+		t.Errorf("expected service name from env, got %q", cfg.ServiceName)
+	}
+
+	// The traces-specific endpoint is used as-is
+	t.Setenv(EnvTracesEndpoint, "http://traces:4318/custom")
+	cfg, _, err = otlpConfigFromEnv()
+	if err != nil {
+		t.Fatalf("otlpConfigFromEnv returned error: %v", err)
+	}
+	if cfg.Endpoint != "http://traces:4318/custom" {
+		t.Errorf("expected traces endpoint to be used verbatim, got %s", cfg.Endpoint)
+	}
+
+	t.Setenv(EnvProtocol, "grpc")
+	if _, _, err := otlpConfigFromEnv(); err == nil {
+		t.Error("expected error for unsupported grpc protocol")
+	}
+}